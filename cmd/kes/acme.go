@@ -0,0 +1,117 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/kes/edge"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// acmeManagers caches ACME certificate managers by their
+// config. The TLS config is re-created on every reload but
+// the ACME certificate manager is reused such that it does
+// not start further certificate renewals.
+var acmeManagers = struct {
+	lock     sync.Mutex
+	managers map[string]*autocert.Manager
+	current  *autocert.Manager // The manager used by the most recent TLS config
+}{
+	managers: map[string]*autocert.Manager{},
+}
+
+// acmeManager returns the ACME certificate manager for
+// the given config.
+func acmeManager(config *edge.ACMEConfig) *autocert.Manager {
+	key := strings.Join([]string{
+		strings.Join(config.Domains, ","),
+		config.Email,
+		config.Directory,
+		config.CacheDir,
+	}, "\x00")
+
+	acmeManagers.lock.Lock()
+	defer acmeManagers.lock.Unlock()
+
+	manager, ok := acmeManagers.managers[key]
+	if !ok {
+		manager = &autocert.Manager{
+			Prompt:     autocert.AcceptTOS, // Accepting the ToS is required by the config
+			Cache:      autocert.DirCache(config.CacheDir),
+			HostPolicy: autocert.HostWhitelist(config.Domains...),
+			Email:      config.Email,
+		}
+		if config.Directory != "" {
+			manager.Client = &acme.Client{DirectoryURL: config.Directory}
+		}
+		acmeManagers.managers[key] = manager
+	}
+	acmeManagers.current = manager
+	return manager
+}
+
+// setACMECertificate configures tlsConfig to present the
+// certificate obtained via ACME, and renewed automatically,
+// instead of a static certificate.
+//
+// If the "tls-alpn-01" challenge is used, challenge requests
+// from the ACME CA are answered without requiring a client
+// certificate.
+func setACMECertificate(tlsConfig *tls.Config, config *edge.ACMEConfig) {
+	manager := acmeManager(config)
+
+	tlsConfig.Certificates = nil
+	tlsConfig.GetCertificate = manager.GetCertificate
+	if config.Challenge == "" || config.Challenge == "tls-alpn-01" {
+		tlsConfig.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			for _, proto := range hello.SupportedProtos {
+				if proto == acme.ALPNProto {
+					return &tls.Config{
+						GetCertificate: manager.GetCertificate,
+						NextProtos:     []string{acme.ALPNProto},
+						MinVersion:     tls.VersionTLS12,
+					}, nil
+				}
+			}
+			return nil, nil
+		}
+	}
+}
+
+// serveACMEChallenges answers ACME "http-01" challenges on
+// the given address until ctx is done. Any other request is
+// rejected.
+func serveACMEChallenges(ctx context.Context, addr string) {
+	srv := &http.Server{
+		Addr: addr,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			acmeManagers.lock.Lock()
+			manager := acmeManagers.current
+			acmeManagers.lock.Unlock()
+
+			if manager == nil {
+				http.NotFound(w, r)
+				return
+			}
+			manager.HTTPHandler(http.NotFoundHandler()).ServeHTTP(w, r)
+		}),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Printf("failed to serve ACME challenges: %v", err)
+	}
+}
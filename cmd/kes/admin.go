@@ -0,0 +1,459 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/internal/tui"
+	flag "github.com/spf13/pflag"
+)
+
+const adminCmdUsage = `Usage:
+    kes admin <command>
+
+Commands:
+    add                      Add enclave admins.
+    ls                       List enclave admins.
+    rm                       Remove enclave admins.
+
+    drill                    Start or stop a failover drill.
+    reload                   Reload the server configuration.
+
+Options:
+    -h, --help               Print command line options.
+`
+
+func adminCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, adminCmdUsage) }
+
+	subCmds := commands{
+		"add": addAdminCmd,
+		"ls":  lsAdminCmd,
+		"rm":  rmAdminCmd,
+
+		"drill":  drillCmd,
+		"reload": reloadCmd,
+	}
+
+	if len(args) < 2 {
+		cmd.Usage()
+		cli.Exit(2)
+	}
+	if cmd, ok := subCmds[args[1]]; ok {
+		cmd(args[1:])
+		return
+	}
+
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes admin --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatalf("%q is not an admin command. See 'kes admin --help'", cmd.Arg(0))
+	}
+	cmd.Usage()
+	cli.Exit(2)
+}
+
+const addAdminCmdUsage = `Usage:
+    kes admin add [options] <identity>...
+
+Adds the identities as additional admins of the enclave. An admin
+can perform any operation within its enclave. Additional admins
+can add and remove other additional admins but not the admin the
+enclave has been created with.
+
+The identities must not exist yet.
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes admin add 736bf58626441e3e134a2daf2e6a8441b40e1abc0eac510878168c8aac9f2b0b
+`
+
+func addAdminCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, addAdminCmdUsage) }
+
+	var (
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes admin add --help'", err)
+	}
+	if cmd.NArg() == 0 {
+		cli.Fatal("no identity specified. See 'kes admin add --help'")
+	}
+
+	enclave := newEnclave(enclaveName, insecureSkipVerify)
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	for _, identity := range cmd.Args() {
+		if err := send(ctx, enclave, http.MethodPost, "/v1/admin/add/"+identity, nil, nil, nil); err != nil {
+			if errors.Is(err, context.Canceled) {
+				cli.Exit(1)
+			}
+			cli.Fatalf("failed to add admin %q: %v", identity, err)
+		}
+	}
+}
+
+const lsAdminCmdUsage = `Usage:
+    kes admin ls [options]
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+        --json               Print admins in JSON format.
+        --color <when>       Specify when to use colored output. The automatic
+                             mode only enables colors if an interactive terminal
+                             is detected - colors are automatically disabled if
+                             the output goes to a pipe.
+                             Possible values: *auto*, never, always.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes admin ls
+`
+
+func lsAdminCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, lsAdminCmdUsage) }
+
+	var (
+		jsonFlag           bool
+		colorFlag          colorOption
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.BoolVar(&jsonFlag, "json", false, "Print admins in JSON format")
+	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes admin ls --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatal("too many arguments. See 'kes admin ls --help'")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	type Admin struct {
+		Identity  kes.Identity `json:"identity"`
+		Primary   bool         `json:"primary,omitempty"`
+		CreatedAt time.Time    `json:"created_at,omitempty"`
+		CreatedBy kes.Identity `json:"created_by,omitempty"`
+	}
+	type Response struct {
+		Admins []Admin `json:"admins"`
+	}
+	enclave := newEnclave(enclaveName, insecureSkipVerify)
+	var resp Response
+	if err := send(ctx, enclave, http.MethodGet, "/v1/admin/list", nil, nil, &resp); err != nil {
+		if errors.Is(err, context.Canceled) {
+			cli.Exit(1)
+		}
+		cli.Fatalf("failed to list admins: %v", err)
+	}
+
+	if jsonFlag {
+		encoder := json.NewEncoder(os.Stdout)
+		if isTerm(os.Stdout) {
+			encoder.SetIndent("", "  ")
+		}
+		encoder.Encode(resp.Admins)
+		return
+	}
+
+	headerStyle := tui.NewStyle()
+	dateStyle := tui.NewStyle()
+	if colorFlag.Colorize() {
+		const ColorDate tui.Color = "#5f8700"
+		headerStyle = headerStyle.Underline(true).Bold(true)
+		dateStyle = dateStyle.Foreground(ColorDate)
+	}
+	fmt.Printf("%s %s %s\n",
+		headerStyle.Render(fmt.Sprintf("%-19s", "Date Created")),
+		headerStyle.Render(fmt.Sprintf("%-64s", "Identity")),
+		headerStyle.Render("Role"),
+	)
+	for _, admin := range resp.Admins {
+		year, month, day := admin.CreatedAt.Local().Date()
+		hour, min, sec := admin.CreatedAt.Local().Clock()
+
+		role := "Admin"
+		if admin.Primary {
+			role = "Enclave Admin"
+		}
+		fmt.Printf("%s %s %s\n",
+			dateStyle.Render(fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d", year, month, day, hour, min, sec)),
+			fmt.Sprintf("%-64s", admin.Identity.String()),
+			role,
+		)
+	}
+}
+
+const rmAdminCmdUsage = `Usage:
+    kes admin rm [options] <identity>...
+
+Removes additional admins from the enclave. The admin the enclave
+has been created with cannot be removed.
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes admin rm 736bf58626441e3e134a2daf2e6a8441b40e1abc0eac510878168c8aac9f2b0b
+`
+
+func rmAdminCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, rmAdminCmdUsage) }
+
+	var (
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes admin rm --help'", err)
+	}
+	if cmd.NArg() == 0 {
+		cli.Fatal("no identity specified. See 'kes admin rm --help'")
+	}
+
+	enclave := newEnclave(enclaveName, insecureSkipVerify)
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	for _, identity := range cmd.Args() {
+		if err := send(ctx, enclave, http.MethodDelete, "/v1/admin/remove/"+identity, nil, nil, nil); err != nil {
+			if errors.Is(err, context.Canceled) {
+				cli.Exit(1)
+			}
+			cli.Fatalf("failed to remove admin %q: %v", identity, err)
+		}
+	}
+}
+
+const drillCmdUsage = `Usage:
+    kes admin drill [options]
+
+Injects controlled failures into the server the request is sent
+to such that operators can rehearse failover and verify that
+monitoring and alerting work as expected. The drill is rolled
+back automatically once its duration has passed.
+
+Without any option, the currently running drill is shown.
+
+Scenarios:
+    backend-outage           Key and secret operations fail as if the
+                             key store was unreachable.
+    cert-expiry              The server presents an expired TLS
+                             certificate. Since new connections fail,
+                             such a drill can only end by its duration.
+    latency                  All requests are delayed by --latency.
+
+Options:
+        --scenario <name>    Start a drill for the given scenario.
+        --duration <d>       Roll back the drill after the given duration.
+                             Defaults to 5m. At most 1h.
+        --latency <d>        Delay requests by the given duration during a
+                             latency drill. Defaults to 1s. At most 1m.
+        --stop               Stop the running drill.
+    -k, --insecure           Skip TLS certificate validation.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes admin drill --scenario backend-outage --duration 10m
+    $ kes admin drill --scenario latency --latency 500ms
+    $ kes admin drill --stop
+`
+
+func drillCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, drillCmdUsage) }
+
+	var (
+		scenario           string
+		duration           time.Duration
+		latency            time.Duration
+		stop               bool
+		insecureSkipVerify bool
+	)
+	cmd.StringVar(&scenario, "scenario", "", "Start a drill for the given scenario")
+	cmd.DurationVar(&duration, "duration", 0, "Roll back the drill after the given duration")
+	cmd.DurationVar(&latency, "latency", 0, "Delay requests by the given duration")
+	cmd.BoolVar(&stop, "stop", false, "Stop the running drill")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes admin drill --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatal("too many arguments. See 'kes admin drill --help'")
+	}
+	if stop && scenario != "" {
+		cli.Fatal("'--stop' and '--scenario' must not be specified both. See 'kes admin drill --help'")
+	}
+	if scenario == "" && (cmd.Changed("duration") || cmd.Changed("latency")) {
+		cli.Fatal("no scenario specified. See 'kes admin drill --help'")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	enclave := newClient(insecureSkipVerify).Enclave("")
+	switch {
+	case stop:
+		if err := send(ctx, enclave, http.MethodPost, "/v1/drill/stop", nil, nil, nil); err != nil {
+			if errors.Is(err, context.Canceled) {
+				cli.Exit(1)
+			}
+			cli.Fatalf("failed to stop drill: %v", err)
+		}
+	case scenario != "":
+		type Request struct {
+			Scenario string `json:"scenario"`
+			Duration string `json:"duration,omitempty"`
+			Latency  string `json:"latency,omitempty"`
+		}
+		type Response struct {
+			Scenario string    `json:"scenario"`
+			Until    time.Time `json:"until"`
+		}
+		req := Request{Scenario: scenario}
+		if cmd.Changed("duration") {
+			req.Duration = duration.String()
+		}
+		if cmd.Changed("latency") {
+			req.Latency = latency.String()
+		}
+		var resp Response
+		if err := send(ctx, enclave, http.MethodPost, "/v1/drill/start", nil, req, &resp); err != nil {
+			if errors.Is(err, context.Canceled) {
+				cli.Exit(1)
+			}
+			cli.Fatalf("failed to start drill: %v", err)
+		}
+		printDrill(resp.Scenario, resp.Until)
+	default:
+		type Drill struct {
+			Scenario string    `json:"scenario"`
+			Until    time.Time `json:"until"`
+		}
+		type Response struct {
+			Drill *Drill `json:"drill"`
+		}
+		var resp Response
+		if err := send(ctx, enclave, http.MethodGet, "/v1/status", nil, nil, &resp); err != nil {
+			if errors.Is(err, context.Canceled) {
+				cli.Exit(1)
+			}
+			cli.Fatalf("failed to fetch server status: %v", err)
+		}
+		if resp.Drill == nil {
+			fmt.Println("no drill running")
+			return
+		}
+		printDrill(resp.Drill.Scenario, resp.Drill.Until)
+	}
+}
+
+const reloadCmdUsage = `Usage:
+    kes admin reload [options]
+
+Makes the server the request is sent to re-read its configuration
+file, like sending a SIGHUP signal. TLS certificates, policies,
+identities, cache and log settings are applied without dropping
+connections.
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes admin reload
+`
+
+func reloadCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, reloadCmdUsage) }
+
+	var insecureSkipVerify bool
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes admin reload --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatal("too many arguments. See 'kes admin reload --help'")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	enclave := newClient(insecureSkipVerify).Enclave("")
+	if err := send(ctx, enclave, http.MethodPost, "/v1/admin/reload", nil, nil, nil); err != nil {
+		if errors.Is(err, context.Canceled) {
+			cli.Exit(1)
+		}
+		cli.Fatalf("failed to reload server configuration: %v", err)
+	}
+}
+
+// printDrill prints the scenario of a running drill and
+// when it is rolled back.
+func printDrill(scenario string, until time.Time) {
+	var faint, red tui.Style
+	if isTerm(os.Stdout) {
+		faint = faint.Faint(true)
+		red = red.Foreground(tui.Color("#ff0000")).Bold(true)
+	}
+	fmt.Println(red.Render(scenario), faint.Render(fmt.Sprintf("until %s (%s)", until.Local().Format(time.RFC1123), time.Until(until).Round(time.Second))))
+}
@@ -28,46 +28,94 @@ func complete(cmd string) bool {
 		return false
 	}
 
-	completion := map[string][]string{
-		cmd:             {"server", "init", "enclave", "key", "policy", "identity", "log", "status", "metric", "update"},
-		cmd + " server": {"--config", "--addr", "--auth"},
-		cmd + " init":   {"--config", "--force"},
-		cmd + " log":    {"--audit", "--error", "--json", "--insecure"},
-		cmd + " status": {"--short", "--api", "--json", "--color", "--insecure"},
-		cmd + " metric": {"--rate", "--insecure"},
-		cmd + " update": {"--downgrade", "--output", "--os", "--arch", "--minisign-key", "--insecure"},
-
-		cmd + " enclave":        {"create", "info", "rm"},
-		cmd + " enclave create": {"--insecure"},
-		cmd + " enclave info":   {"--insecure", "--json", "--color"},
-		cmd + " enclave rm":     {"--insecure"},
-
-		cmd + " key":         {"create", "import", "info", "ls", "rm", "encrypt", "decrypt", "dek"},
+	for _, candidate := range completeLine(completions(cmd), line) {
+		fmt.Println(candidate)
+	}
+	return true
+}
+
+// completions returns the completion candidates of all
+// commands of the given binary name. Each map entry maps a
+// command to its sub-commands and options.
+func completions(cmd string) map[string][]string {
+	return map[string][]string{
+		cmd:                {"server", "init", "enclave", "key", "policy", "identity", "log", "status", "metric", "maintenance", "admin", "report", "shell", "config", "validate", "mirror", "update"},
+		cmd + " server":    {"--config", "--addr", "--auth", "--validate"},
+		cmd + " validate":  {"--offline", "--timeout"},
+		cmd + " init":      {"--config", "--force"},
+		cmd + " log":       {"stats", "retention", "purge", "tls", "--audit", "--error", "--json", "--insecure"},
+		cmd + " log stats": {"--since", "--daily", "--json", "--color", "--enclave", "--insecure"},
+		cmd + " log tls":   {"--since", "--json", "--color", "--enclave", "--insecure"},
+
+		cmd + " log retention": {"--json", "--enclave", "--insecure"},
+		cmd + " log purge":     {"--before", "--enclave", "--insecure"},
+		cmd + " status":        {"--short", "--api", "--json", "--color", "--insecure"},
+		cmd + " metric":        {"--rate", "--insecure"},
+		cmd + " shell":         {"--enclave", "--insecure"},
+		cmd + " mirror":        {"check"},
+		cmd + " mirror check":  {"--config", "--enclave", "--quiet"},
+		cmd + " update":        {"--downgrade", "--output", "--os", "--arch", "--minisign-key", "--insecure"},
+
+		cmd + " config":         {"migrate"},
+		cmd + " config migrate": {"--output"},
+
+		cmd + " enclave":         {"create", "info", "update", "rename", "suspend", "resume", "rm"},
+		cmd + " enclave create":  {"--key-retention", "--audit-hold", "--max-keys", "--max-identities", "--request-rate", "--description", "--label", "--default-policy", "--algorithm", "--min-key-size", "--min-rsa-key-size", "--kdf", "--insecure"},
+		cmd + " enclave info":    {"--insecure", "--json", "--color"},
+		cmd + " enclave update":  {"--key-retention", "--audit-hold", "--max-keys", "--max-identities", "--request-rate", "--audit-retention", "--legal-hold", "--description", "--label", "--default-policy", "--algorithm", "--min-key-size", "--min-rsa-key-size", "--kdf", "--insecure"},
+		cmd + " enclave rename":  {"--insecure"},
+		cmd + " enclave suspend": {"--insecure"},
+		cmd + " enclave resume":  {"--insecure"},
+		cmd + " enclave rm":      {"--insecure"},
+
+		cmd + " key":         {"create", "import", "copy", "claim", "info", "ls", "rm", "restore", "purge", "encrypt", "decrypt", "dek"},
 		cmd + " key create":  {"--enclave", "--insecure"},
 		cmd + " key import":  {"--enclave", "--insecure"},
+		cmd + " key copy":    {"--from-enclave", "--to-enclave", "--move", "--insecure"},
+		cmd + " key claim":   {"--json", "--insecure"},
 		cmd + " key info":    {"--enclave", "--insecure", "--json", "--color"},
 		cmd + " key ls":      {"--enclave", "--insecure", "--json", "--color"},
 		cmd + " key rm":      {"--enclave", "--insecure"},
-		cmd + " key encrypt": {"--enclave", "--insecure"},
-		cmd + " key decrypt": {"--enclave", "--insecure"},
-		cmd + " key dek":     {"--enclave", "--insecure"},
-
-		cmd + " policy":        {"create", "assign", "info", "ls", "rm", "show"},
-		cmd + " policy create": {"--enclave", "--insecure"},
-		cmd + " policy assign": {"--enclave", "--insecure"},
-		cmd + " policy info":   {"--enclave", "--insecure", "--json", "--color"},
-		cmd + " policy ls":     {"--enclave", "--insecure", "--json", "--color"},
-		cmd + " policy rm":     {"--enclave", "--insecure"},
-		cmd + " policy show":   {"--enclave", "--insecure", "--json"},
-
-		cmd + " identity":      {"new", "of", "info", "ls", "rm"},
-		cmd + " identity new":  {"--key", "--cert", "--force", "--ip", "--dns", "--expiry", "--encrypt"},
-		cmd + " identity of":   {},
-		cmd + " identity info": {"--enclave", "--insecure", "--json", "--color"},
-		cmd + " identity ls":   {"--enclave", "--insecure", "--json", "--color"},
-		cmd + " identity rm":   {"--enclave", "--insecure"},
+		cmd + " key restore": {"--list", "--enclave", "--insecure", "--json", "--color"},
+		cmd + " key purge":   {"--enclave", "--insecure"},
+		cmd + " key encrypt": {"--context", "--enclave", "--insecure"},
+		cmd + " key decrypt": {"--context", "--enclave", "--insecure"},
+		cmd + " key dek":     {"--context", "--enclave", "--insecure"},
+
+		cmd + " policy":            {"create", "assign", "info", "ls", "rm", "show", "duplicates", "merge"},
+		cmd + " policy create":     {"--enclave", "--insecure"},
+		cmd + " policy assign":     {"--enclave", "--insecure", "--ttl", "--expires-at", "--label"},
+		cmd + " policy info":       {"--enclave", "--insecure", "--json", "--color"},
+		cmd + " policy ls":         {"--enclave", "--insecure", "--json", "--color"},
+		cmd + " policy rm":         {"--enclave", "--insecure"},
+		cmd + " policy show":       {"--enclave", "--insecure", "--json"},
+		cmd + " policy duplicates": {"--enclave", "--insecure", "--json", "--color"},
+		cmd + " policy merge":      {"--enclave", "--insecure"},
+
+		cmd + " identity":       {"new", "of", "info", "ls", "add", "renew", "rm"},
+		cmd + " identity new":   {"--key", "--cert", "--force", "--ip", "--dns", "--expiry", "--encrypt"},
+		cmd + " identity of":    {},
+		cmd + " identity info":  {"--enclave", "--insecure", "--json", "--color"},
+		cmd + " identity ls":    {"--enclave", "--insecure", "--json", "--color", "--label"},
+		cmd + " identity add":   {"--enclave", "--insecure", "--policy", "--ttl", "--expires-at", "--label", "--json"},
+		cmd + " identity renew": {"--enclave", "--insecure", "--ttl", "--expires-at", "--json"},
+		cmd + " identity rm":    {"--enclave", "--insecure"},
+
+		cmd + " admin":        {"add", "ls", "rm", "drill", "reload"},
+		cmd + " admin add":    {"--enclave", "--insecure"},
+		cmd + " admin ls":     {"--enclave", "--insecure", "--json", "--color"},
+		cmd + " admin rm":     {"--enclave", "--insecure"},
+		cmd + " admin drill":  {"--scenario", "--duration", "--latency", "--stop", "--insecure"},
+		cmd + " admin reload": {"--insecure"},
+
+		cmd + " report":        {"access"},
+		cmd + " report access": {"--format", "--enclave", "--insecure"},
 	}
+}
 
+// completeLine returns all completion candidates for the
+// last word of the given line.
+func completeLine(completion map[string][]string, line string) []string {
 	fields := strings.Fields(line)
 	cmds := make([]string, 0, len(fields))
 	for _, field := range fields {
@@ -83,15 +131,16 @@ func complete(cmd string) bool {
 			match = key
 		}
 	}
+	var matches []string
 	if candidates, ok := completion[match]; ok {
 		line = strings.TrimSpace(strings.TrimPrefix(line, match))
 		for _, candidate := range candidates {
 			if strings.HasPrefix(candidate, line) {
-				fmt.Println(candidate)
+				matches = append(matches, candidate)
 			}
 		}
 	}
-	return true
+	return matches
 }
 
 func installAutoCompletion() {
@@ -9,8 +9,7 @@ import (
 	"os"
 	"strings"
 
-	tui "github.com/charmbracelet/lipgloss"
-	"github.com/muesli/termenv"
+	"github.com/minio/kes/internal/tui"
 	flag "github.com/spf13/pflag"
 )
 
@@ -37,16 +36,14 @@ func (c *colorOption) String() string { return c.value }
 func (c *colorOption) Set(value string) error {
 	switch strings.ToLower(value) {
 	case "always":
-		if p := tui.ColorProfile(); p == termenv.Ascii {
-			tui.SetColorProfile(termenv.ANSI256)
-		}
+		tui.EnableColors()
 		c.value = value
 		return nil
 	case "auto", "":
 		c.value = value
 		return nil
 	case "never":
-		tui.SetColorProfile(termenv.Ascii)
+		tui.DisableColors()
 		c.value = value
 		return nil
 	default:
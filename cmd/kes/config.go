@@ -0,0 +1,117 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/minio/kes/edge"
+	"github.com/minio/kes/internal/cli"
+	flag "github.com/spf13/pflag"
+)
+
+const configCmdUsage = `Usage:
+    kes config <command>
+
+Commands:
+    migrate                  Upgrade a server config file to the current schema.
+
+Options:
+    -h, --help               Print command line options.
+`
+
+func configCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, configCmdUsage) }
+
+	subCmds := commands{
+		"migrate": configMigrateCmd,
+	}
+
+	if len(args) < 2 {
+		cmd.Usage()
+		cli.Exit(2)
+	}
+	if cmd, ok := subCmds[args[1]]; ok {
+		cmd(args[1:])
+		return
+	}
+
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes config --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatalf("%q is not a config command. See 'kes config --help'", cmd.Arg(0))
+	}
+	cmd.Usage()
+	cli.Exit(2)
+}
+
+const configMigrateCmdUsage = `Usage:
+    kes config migrate [options] <config>
+
+Upgrades a KES server config file to the current schema version
+and prints it to STDOUT. A deprecation warning is printed to STDERR
+for each field that has been renamed or is not supported anymore.
+
+Config files without a 'version' field are treated as version 'v0'.
+The server still accepts them but may stop doing so in the future.
+
+Options:
+    -o, --output <PATH>      Write the upgraded config file to PATH
+                             instead of STDOUT.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes config migrate config.yml
+    $ kes config migrate -o config.v1.yml config.yml
+`
+
+func configMigrateCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, configMigrateCmdUsage) }
+
+	var outputPath string
+	cmd.StringVarP(&outputPath, "output", "o", "", "Write the upgraded config file to PATH")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes config migrate --help'", err)
+	}
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no config file specified. See 'kes config migrate --help'")
+	case cmd.NArg() > 1:
+		cli.Fatal("too many arguments. See 'kes config migrate --help'")
+	}
+
+	file, err := os.Open(cmd.Arg(0))
+	if err != nil {
+		cli.Fatalf("failed to read config file: %v", err)
+	}
+	config, warnings, err := edge.MigrateServerConfigYAML(file)
+	if err != nil {
+		cli.Fatalf("failed to migrate config file: %v", err)
+	}
+	file.Close()
+
+	for _, warning := range warnings {
+		fmt.Fprintln(os.Stderr, "Warning:", warning)
+	}
+	if outputPath == "" {
+		os.Stdout.Write(config)
+		return
+	}
+	if err = os.WriteFile(outputPath, config, 0o600); err != nil {
+		cli.Fatalf("failed to write config file: %v", err)
+	}
+}
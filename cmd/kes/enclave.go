@@ -9,12 +9,16 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
+	"time"
 
-	tui "github.com/charmbracelet/lipgloss"
 	"github.com/minio/kes-go"
 	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/internal/tui"
 	flag "github.com/spf13/pflag"
 )
 
@@ -24,7 +28,12 @@ const enclaveCmdUsage = `Usage:
 Commands:
     create                   Create a new enclave.
     info                     Get information about an enclave. 
+    update                   Change the settings of an enclave.
+    rename                   Rename an enclave.
+    suspend                  Suspend an enclave.
+    resume                   Resume a suspended enclave.
     rm                       Delete an enclave.
+    reencrypt                Re-encrypt an enclave with new root keys.
 
 Options:
     -h, --help               Print command line options.
@@ -35,14 +44,19 @@ func enclaveCmd(args []string) {
 	cmd.Usage = func() { fmt.Fprint(os.Stderr, enclaveCmdUsage) }
 
 	subCmds := commands{
-		"create": createEnclaveCmd,
-		"info":   describeEnclaveCmd,
-		"rm":     deleteEnclaveCmd,
+		"create":    createEnclaveCmd,
+		"info":      describeEnclaveCmd,
+		"update":    updateEnclaveCmd,
+		"rename":    renameEnclaveCmd,
+		"suspend":   suspendEnclaveCmd,
+		"resume":    resumeEnclaveCmd,
+		"rm":        deleteEnclaveCmd,
+		"reencrypt": reencryptEnclaveCmd,
 	}
 
 	if len(args) < 2 {
 		cmd.Usage()
-		os.Exit(2)
+		cli.Exit(2)
 	}
 	if cmd, ok := subCmds[args[1]]; ok {
 		cmd(args[1:])
@@ -51,7 +65,7 @@ func enclaveCmd(args []string) {
 
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(2)
+			cli.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes enclave --help'", err)
 	}
@@ -59,32 +73,89 @@ func enclaveCmd(args []string) {
 		cli.Fatalf("%q is not a enclave command. See 'kes enclave --help'", cmd.Arg(0))
 	}
 	cmd.Usage()
-	os.Exit(2)
+	cli.Exit(2)
 }
 
 const createEnclaveCmdUsage = `Usage:
     kes enclave create [options] <name> <identity>
 
 Options:
-    -k, --insecure           Skip TLS certificate validation.
-    -h, --help               Print command line options.
+        --key-retention <duration>  Retain deleted keys for the given duration
+                                    such that they can be restored.
+        --audit-hold                Keep deleted keys under audit hold until
+                                    the key retention has passed. Requires
+                                    a key retention and cannot be undone.
+        --max-keys <n>              Limit the number of keys in the enclave.
+        --max-identities <n>        Limit the number of identities in the
+                                    enclave.
+        --request-rate <n>          Limit the requests per second the enclave
+                                    serves.
+        --description <text>        Describe the enclave.
+        --label <name=value>        Label the enclave. May be specified
+                                    multiple times.
+        --default-policy <name>     Assign identities added without a policy
+                                    to the given policy.
+        --algorithm <name>          Only approve the given encryption algorithm
+                                    for keys. May be specified multiple times.
+        --min-key-size <bits>       Reject symmetric, ECDSA and derived keys
+                                    smaller than the given size.
+        --min-rsa-key-size <bits>   Reject RSA keys smaller than the given size.
+        --kdf <name>                Only approve the given key derivation
+                                    function. May be specified multiple times.
+    -k, --insecure                  Skip TLS certificate validation.
+    -h, --help                      Print command line options.
 
 Examples:
     $ kes enclave create tenant-1 5f2f4ef3e0e340a07fc330f58ef0a1c4d661e564ab10795f9231f75fcfe572f1
+    $ kes enclave create --key-retention 168h tenant-1 5f2f4ef3e0e340a07fc330f58ef0a1c4d661e564ab10795f9231f75fcfe572f1
+    $ kes enclave create --key-retention 8760h --audit-hold tenant-1 5f2f4ef3e0e340a07fc330f58ef0a1c4d661e564ab10795f9231f75fcfe572f1
+    $ kes enclave create --max-keys 1000 --request-rate 100 tenant-1 5f2f4ef3e0e340a07fc330f58ef0a1c4d661e564ab10795f9231f75fcfe572f1
+    $ kes enclave create --description "Tenant 1" --label tier=gold tenant-1 5f2f4ef3e0e340a07fc330f58ef0a1c4d661e564ab10795f9231f75fcfe572f1
+    $ kes enclave create --algorithm AES256-GCM_SHA256 --min-rsa-key-size 3072 tenant-1 5f2f4ef3e0e340a07fc330f58ef0a1c4d661e564ab10795f9231f75fcfe572f1
 `
 
 func createEnclaveCmd(args []string) {
 	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
 	cmd.Usage = func() { fmt.Fprint(os.Stderr, createEnclaveCmdUsage) }
 
-	var insecureSkipVerify bool
+	var (
+		keyRetention       time.Duration
+		auditHold          bool
+		maxKeys            int
+		maxIdentities      int
+		requestRate        float64
+		description        string
+		labelFlag          []string
+		defaultPolicy      string
+		crypto             cryptoPolicyFlags
+		insecureSkipVerify bool
+	)
+	cmd.DurationVar(&keyRetention, "key-retention", 0, "Retain deleted keys for the given duration")
+	cmd.BoolVar(&auditHold, "audit-hold", false, "Keep deleted keys under audit hold")
+	cmd.IntVar(&maxKeys, "max-keys", 0, "Limit the number of keys in the enclave")
+	cmd.IntVar(&maxIdentities, "max-identities", 0, "Limit the number of identities in the enclave")
+	cmd.Float64Var(&requestRate, "request-rate", 0, "Limit the requests per second the enclave serves")
+	cmd.StringVar(&description, "description", "", "Describe the enclave")
+	cmd.StringArrayVar(&labelFlag, "label", nil, "Label the enclave")
+	cmd.StringVar(&defaultPolicy, "default-policy", "", "Assign identities added without a policy to the given policy")
+	crypto.Register(cmd)
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(2)
+			cli.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes enclave create --help'", err)
 	}
+	if keyRetention < 0 {
+		cli.Fatal("invalid key retention: retention must not be negative. See 'kes enclave create --help'")
+	}
+	if auditHold && keyRetention == 0 {
+		cli.Fatal("'--audit-hold' requires a '--key-retention'. See 'kes enclave create --help'")
+	}
+	if maxKeys < 0 || maxIdentities < 0 || requestRate < 0 {
+		cli.Fatal("invalid quota: quota must not be negative. See 'kes enclave create --help'")
+	}
+	labels := parseLabelFlag(labelFlag, "kes enclave create")
 
 	switch {
 	case cmd.NArg() == 0:
@@ -101,9 +172,44 @@ func createEnclaveCmd(args []string) {
 	name := cmd.Arg(0)
 	admin := cmd.Arg(1)
 	client := newClient(insecureSkipVerify)
-	if err := client.CreateEnclave(ctx, name, kes.Identity(admin)); err != nil {
+
+	var err error
+	if keyRetention > 0 || maxKeys > 0 || maxIdentities > 0 || requestRate > 0 || description != "" || len(labels) > 0 || defaultPolicy != "" || crypto.Changed(cmd) {
+		type Request struct {
+			Admin         kes.Identity      `json:"admin"`
+			KeyRetention  string            `json:"key_retention,omitempty"`
+			AuditHold     bool              `json:"audit_hold,omitempty"`
+			MaxKeys       int               `json:"max_keys,omitempty"`
+			MaxIdentities int               `json:"max_identities,omitempty"`
+			RequestRate   float64           `json:"request_rate,omitempty"`
+			Description   string            `json:"description,omitempty"`
+			Labels        map[string]string `json:"labels,omitempty"`
+			DefaultPolicy string            `json:"default_policy,omitempty"`
+			CryptoPolicy  *cryptoPolicy     `json:"crypto_policy,omitempty"`
+		}
+		req := Request{
+			Admin:         kes.Identity(admin),
+			AuditHold:     auditHold,
+			MaxKeys:       maxKeys,
+			MaxIdentities: maxIdentities,
+			RequestRate:   requestRate,
+			Description:   description,
+			Labels:        labels,
+			DefaultPolicy: defaultPolicy,
+		}
+		if crypto.Changed(cmd) {
+			req.CryptoPolicy = crypto.Policy(cmd)
+		}
+		if keyRetention > 0 {
+			req.KeyRetention = keyRetention.String()
+		}
+		err = send(ctx, client.Enclave(""), http.MethodPost, "/v1/enclave/create/"+name, nil, req, nil)
+	} else {
+		err = client.CreateEnclave(ctx, name, kes.Identity(admin))
+	}
+	if err != nil {
 		if errors.Is(err, context.Canceled) {
-			os.Exit(1)
+			cli.Exit(1)
 		}
 		cli.Fatalf("failed to create enclave '%s': %v", name, err)
 	}
@@ -141,7 +247,7 @@ func describeEnclaveCmd(args []string) {
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(2)
+			cli.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes enclave info --help'", err)
 	}
@@ -157,11 +263,44 @@ func describeEnclaveCmd(args []string) {
 	if cmd.NArg() > 0 {
 		name = cmd.Arg(0)
 	}
-	client := newClient(insecureSkipVerify)
-	info, err := client.DescribeEnclave(ctx, name)
+	type EnclaveInfo struct {
+		Name         string       `json:"name"`
+		CreatedAt    time.Time    `json:"created_at"`
+		CreatedBy    kes.Identity `json:"created_by"`
+		KeyRetention string       `json:"key_retention,omitempty"`
+		AuditHold    bool         `json:"audit_hold,omitempty"`
+
+		Keys          int     `json:"keys"`
+		Identities    int     `json:"identities"`
+		MaxKeys       int     `json:"max_keys,omitempty"`
+		MaxIdentities int     `json:"max_identities,omitempty"`
+		RequestRate   float64 `json:"request_rate,omitempty"`
+
+		AuditRetention string `json:"audit_retention,omitempty"`
+		AuditLegalHold bool   `json:"audit_legal_hold,omitempty"`
+
+		Description string            `json:"description,omitempty"`
+		Labels      map[string]string `json:"labels,omitempty"`
+
+		DefaultPolicy string `json:"default_policy,omitempty"`
+		CryptoPolicy  *struct {
+			Algorithms    []string `json:"algorithms,omitempty"`
+			MinKeySize    int      `json:"min_key_size,omitempty"`
+			MinRSAKeySize int      `json:"min_rsa_key_size,omitempty"`
+			KDFs          []string `json:"kdfs,omitempty"`
+		} `json:"crypto_policy,omitempty"`
+
+		Suspended   bool       `json:"suspended,omitempty"`
+		SuspendedAt *time.Time `json:"suspended_at,omitempty"`
+	}
+	var (
+		enclave = newClient(insecureSkipVerify).Enclave("")
+		info    EnclaveInfo
+	)
+	err := send(ctx, enclave, http.MethodGet, "/v1/enclave/describe/"+name, nil, nil, &info)
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
-			os.Exit(1)
+			cli.Exit(1)
 		}
 		cli.Fatalf("failed to describe enclave '%s': %v", name, err)
 	}
@@ -186,6 +325,25 @@ func describeEnclaveCmd(args []string) {
 		faint.Render(fmt.Sprintf("%-11s", "Enclave")),
 		enclaveStyle.Render(info.Name),
 	)
+	if info.Description != "" {
+		fmt.Println(
+			faint.Render(fmt.Sprintf("%-11s", "Description")),
+			info.Description,
+		)
+	}
+	printLabels(faint, info.Labels)
+	if info.Suspended {
+		status := "suspended"
+		if info.SuspendedAt != nil {
+			year, month, day := info.SuspendedAt.Date()
+			hour, min, sec := info.SuspendedAt.Clock()
+			status += fmt.Sprintf(" since %04d-%02d-%02d %02d:%02d:%02d", year, month, day, hour, min, sec)
+		}
+		fmt.Println(
+			faint.Render(fmt.Sprintf("%-11s", "Status")),
+			status,
+		)
+	}
 	fmt.Println(
 		faint.Render(fmt.Sprintf("%-11s", "Created At")),
 		fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d", year, month, day, hour, min, sec),
@@ -194,6 +352,477 @@ func describeEnclaveCmd(args []string) {
 		faint.Render(fmt.Sprintf("%-11s", "Created By")),
 		info.CreatedBy,
 	)
+	if info.KeyRetention != "" {
+		fmt.Println(
+			faint.Render(fmt.Sprintf("%-11s", "Key Trash")),
+			"retained for "+info.KeyRetention,
+		)
+	}
+	if info.AuditHold {
+		fmt.Println(
+			faint.Render(fmt.Sprintf("%-11s", "Audit Hold")),
+			"enabled",
+		)
+	}
+
+	quota := func(usage, max int) string {
+		if max <= 0 {
+			return fmt.Sprintf("%d (unlimited)", usage)
+		}
+		return fmt.Sprintf("%d of %d", usage, max)
+	}
+	fmt.Println(
+		faint.Render(fmt.Sprintf("%-11s", "Keys")),
+		quota(info.Keys, info.MaxKeys),
+	)
+	fmt.Println(
+		faint.Render(fmt.Sprintf("%-11s", "Identities")),
+		quota(info.Identities, info.MaxIdentities),
+	)
+	if info.RequestRate > 0 {
+		fmt.Println(
+			faint.Render(fmt.Sprintf("%-11s", "Rate Limit")),
+			strconv.FormatFloat(info.RequestRate, 'f', -1, 64)+" req/s",
+		)
+	}
+	if info.AuditRetention != "" {
+		fmt.Println(
+			faint.Render(fmt.Sprintf("%-11s", "Audit Stats")),
+			"retained for "+info.AuditRetention,
+		)
+	}
+	if info.AuditLegalHold {
+		fmt.Println(
+			faint.Render(fmt.Sprintf("%-11s", "Legal Hold")),
+			"enabled",
+		)
+	}
+	if info.DefaultPolicy != "" {
+		fmt.Println(
+			faint.Render(fmt.Sprintf("%-11s", "Policy")),
+			info.DefaultPolicy+" (default)",
+		)
+	}
+	if p := info.CryptoPolicy; p != nil {
+		if len(p.Algorithms) > 0 {
+			fmt.Println(
+				faint.Render(fmt.Sprintf("%-11s", "Algorithms")),
+				strings.Join(p.Algorithms, ", "),
+			)
+		}
+		if p.MinKeySize > 0 {
+			fmt.Println(
+				faint.Render(fmt.Sprintf("%-11s", "Min Key")),
+				strconv.Itoa(p.MinKeySize)+" bit",
+			)
+		}
+		if p.MinRSAKeySize > 0 {
+			fmt.Println(
+				faint.Render(fmt.Sprintf("%-11s", "Min RSA Key")),
+				strconv.Itoa(p.MinRSAKeySize)+" bit",
+			)
+		}
+		if len(p.KDFs) > 0 {
+			fmt.Println(
+				faint.Render(fmt.Sprintf("%-11s", "KDFs")),
+				strings.Join(p.KDFs, ", "),
+			)
+		}
+	}
+}
+
+const updateEnclaveCmdUsage = `Usage:
+    kes enclave update [options] <name>
+
+Options:
+        --key-retention <duration>  Retain deleted keys for the given duration
+                                    such that they can be restored. A duration
+                                    of 0 disables the key retention.
+        --audit-hold                Keep deleted keys under audit hold until
+                                    the key retention has passed. Once enabled,
+                                    the audit hold cannot be disabled and the
+                                    key retention cannot be reduced.
+        --max-keys <n>              Limit the number of keys in the enclave.
+                                    A limit of 0 removes the limit.
+        --max-identities <n>        Limit the number of identities in the
+                                    enclave. A limit of 0 removes the limit.
+        --request-rate <n>          Limit the requests per second the enclave
+                                    serves. A rate of 0 removes the limit.
+        --audit-retention <days>    Retain audit statistics for the given
+                                    duration, like 90d. A duration of 0
+                                    restores the server's default retention.
+        --legal-hold                Keep audit statistics under legal hold such
+                                    that they are neither pruned nor purged.
+                                    Use --legal-hold=false to release it.
+        --description <text>        Describe the enclave. An empty description
+                                    removes the description.
+        --label <name=value>        Label the enclave. May be specified multiple
+                                    times. Replaces all existing labels.
+        --default-policy <name>     Assign identities added without a policy
+                                    to the given policy. An empty name removes
+                                    the default policy.
+        --algorithm <name>          Only approve the given encryption algorithm
+                                    for keys. May be specified multiple times.
+                                    An empty name approves all algorithms.
+        --min-key-size <bits>       Reject symmetric, ECDSA and derived keys
+                                    smaller than the given size.
+        --min-rsa-key-size <bits>   Reject RSA keys smaller than the given size.
+        --kdf <name>                Only approve the given key derivation
+                                    function. May be specified multiple times.
+                                    An empty name approves all functions.
+    -k, --insecure                  Skip TLS certificate validation.
+    -h, --help                      Print command line options.
+
+Examples:
+    $ kes enclave update --key-retention 168h tenant-1
+    $ kes enclave update --key-retention 0 tenant-1
+    $ kes enclave update --key-retention 8760h --audit-hold tenant-1
+    $ kes enclave update --max-keys 5000 --max-identities 100 tenant-1
+    $ kes enclave update --audit-retention 365d --legal-hold tenant-1
+    $ kes enclave update --description "Tenant 1 (EU)" --label region=eu tenant-1
+    $ kes enclave update --default-policy app tenant-1
+    $ kes enclave update --algorithm AES256-GCM_SHA256 --kdf HKDF-SHA256 tenant-1
+`
+
+func updateEnclaveCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, updateEnclaveCmdUsage) }
+
+	var (
+		keyRetention       time.Duration
+		auditHold          bool
+		maxKeys            int
+		maxIdentities      int
+		requestRate        float64
+		auditRetention     string
+		legalHold          bool
+		description        string
+		labelFlag          []string
+		defaultPolicy      string
+		crypto             cryptoPolicyFlags
+		insecureSkipVerify bool
+	)
+	cmd.DurationVar(&keyRetention, "key-retention", 0, "Retain deleted keys for the given duration")
+	cmd.BoolVar(&auditHold, "audit-hold", false, "Keep deleted keys under audit hold")
+	cmd.IntVar(&maxKeys, "max-keys", 0, "Limit the number of keys in the enclave")
+	cmd.IntVar(&maxIdentities, "max-identities", 0, "Limit the number of identities in the enclave")
+	cmd.Float64Var(&requestRate, "request-rate", 0, "Limit the requests per second the enclave serves")
+	cmd.StringVar(&auditRetention, "audit-retention", "", "Retain audit statistics for the given duration")
+	cmd.BoolVar(&legalHold, "legal-hold", false, "Keep audit statistics under legal hold")
+	cmd.StringVar(&description, "description", "", "Describe the enclave")
+	cmd.StringArrayVar(&labelFlag, "label", nil, "Label the enclave")
+	cmd.StringVar(&defaultPolicy, "default-policy", "", "Assign identities added without a policy to the given policy")
+	crypto.Register(cmd)
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes enclave update --help'", err)
+	}
+
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no enclave name specified. See 'kes enclave update --help'")
+	case cmd.NArg() > 1:
+		cli.Fatal("too many arguments. See 'kes enclave update --help'")
+	}
+	if !cmd.Changed("key-retention") && !cmd.Changed("audit-hold") && !cmd.Changed("max-keys") && !cmd.Changed("max-identities") && !cmd.Changed("request-rate") && !cmd.Changed("audit-retention") && !cmd.Changed("legal-hold") && !cmd.Changed("description") && !cmd.Changed("label") && !cmd.Changed("default-policy") && !crypto.Changed(cmd) {
+		cli.Fatal("no enclave setting specified. See 'kes enclave update --help'")
+	}
+	if keyRetention < 0 {
+		cli.Fatal("invalid key retention: retention must not be negative. See 'kes enclave update --help'")
+	}
+	if maxKeys < 0 || maxIdentities < 0 || requestRate < 0 {
+		cli.Fatal("invalid quota: quota must not be negative. See 'kes enclave update --help'")
+	}
+	labels := parseLabelFlag(labelFlag, "kes enclave update")
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	type Request struct {
+		KeyRetention  *string  `json:"key_retention,omitempty"`
+		AuditHold     *bool    `json:"audit_hold,omitempty"`
+		MaxKeys       *int     `json:"max_keys,omitempty"`
+		MaxIdentities *int     `json:"max_identities,omitempty"`
+		RequestRate   *float64 `json:"request_rate,omitempty"`
+
+		AuditRetention *string `json:"audit_retention,omitempty"`
+		AuditLegalHold *bool   `json:"audit_legal_hold,omitempty"`
+
+		Description *string            `json:"description,omitempty"`
+		Labels      *map[string]string `json:"labels,omitempty"`
+
+		DefaultPolicy *string       `json:"default_policy,omitempty"`
+		CryptoPolicy  *cryptoPolicy `json:"crypto_policy,omitempty"`
+	}
+	var req Request
+	if cmd.Changed("key-retention") {
+		retention := keyRetention.String()
+		req.KeyRetention = &retention
+	}
+	if cmd.Changed("audit-hold") {
+		req.AuditHold = &auditHold
+	}
+	if cmd.Changed("max-keys") {
+		req.MaxKeys = &maxKeys
+	}
+	if cmd.Changed("max-identities") {
+		req.MaxIdentities = &maxIdentities
+	}
+	if cmd.Changed("request-rate") {
+		req.RequestRate = &requestRate
+	}
+	if cmd.Changed("audit-retention") {
+		req.AuditRetention = &auditRetention
+	}
+	if cmd.Changed("legal-hold") {
+		req.AuditLegalHold = &legalHold
+	}
+	if cmd.Changed("description") {
+		req.Description = &description
+	}
+	if cmd.Changed("label") {
+		req.Labels = &labels
+	}
+	if cmd.Changed("default-policy") {
+		req.DefaultPolicy = &defaultPolicy
+	}
+	if crypto.Changed(cmd) {
+		req.CryptoPolicy = crypto.Policy(cmd)
+	}
+	name := cmd.Arg(0)
+	enclave := newClient(insecureSkipVerify).Enclave("")
+	err := send(ctx, enclave, http.MethodPost, "/v1/enclave/update/"+name, nil, req, nil)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			cli.Exit(1)
+		}
+		cli.Fatalf("failed to update enclave '%s': %v", name, err)
+	}
+}
+
+// cryptoPolicy is the JSON representation of an enclave's
+// crypto policy. Unset fields are omitted such that updating
+// a crypto policy only changes the specified fields.
+type cryptoPolicy struct {
+	Algorithms    *[]string `json:"algorithms,omitempty"`
+	MinKeySize    *int      `json:"min_key_size,omitempty"`
+	MinRSAKeySize *int      `json:"min_rsa_key_size,omitempty"`
+	KDFs          *[]string `json:"kdfs,omitempty"`
+}
+
+// cryptoPolicyFlags are the command line options
+// for specifying an enclave's crypto policy.
+type cryptoPolicyFlags struct {
+	algorithms    []string
+	minKeySize    int
+	minRSAKeySize int
+	kdfs          []string
+}
+
+// Register adds the crypto policy options to the flag set.
+func (f *cryptoPolicyFlags) Register(cmd *flag.FlagSet) {
+	cmd.StringArrayVar(&f.algorithms, "algorithm", nil, "Only approve the given encryption algorithm")
+	cmd.IntVar(&f.minKeySize, "min-key-size", 0, "Reject symmetric, ECDSA and derived keys smaller than the given size")
+	cmd.IntVar(&f.minRSAKeySize, "min-rsa-key-size", 0, "Reject RSA keys smaller than the given size")
+	cmd.StringArrayVar(&f.kdfs, "kdf", nil, "Only approve the given key derivation function")
+}
+
+// Changed reports whether any crypto policy option is set.
+func (f *cryptoPolicyFlags) Changed(cmd *flag.FlagSet) bool {
+	return cmd.Changed("algorithm") || cmd.Changed("min-key-size") || cmd.Changed("min-rsa-key-size") || cmd.Changed("kdf")
+}
+
+// Policy returns a crypto policy that only contains
+// the options set on the command line. Empty algorithm
+// and KDF names are ignored.
+func (f *cryptoPolicyFlags) Policy(cmd *flag.FlagSet) *cryptoPolicy {
+	nonEmpty := func(values []string) *[]string {
+		list := []string{}
+		for _, v := range values {
+			if v != "" {
+				list = append(list, v)
+			}
+		}
+		return &list
+	}
+
+	var p cryptoPolicy
+	if cmd.Changed("algorithm") {
+		p.Algorithms = nonEmpty(f.algorithms)
+	}
+	if cmd.Changed("min-key-size") {
+		p.MinKeySize = &f.minKeySize
+	}
+	if cmd.Changed("min-rsa-key-size") {
+		p.MinRSAKeySize = &f.minRSAKeySize
+	}
+	if cmd.Changed("kdf") {
+		p.KDFs = nonEmpty(f.kdfs)
+	}
+	return &p
+}
+
+// parseLabelFlag parses the given 'name=value' labels.
+// It terminates the program if a label is malformed.
+func parseLabelFlag(values []string, command string) map[string]string {
+	if len(values) == 0 {
+		return nil
+	}
+	labels := make(map[string]string, len(values))
+	for _, label := range values {
+		name, value, ok := strings.Cut(label, "=")
+		if !ok {
+			cli.Fatalf("invalid label '%s': expected 'name=value'. See '%s --help'", label, command)
+		}
+		labels[name] = value
+	}
+	return labels
+}
+
+const renameEnclaveCmdUsage = `Usage:
+    kes enclave rename [options] <name> <new-name>
+
+Renames an enclave. The enclave keeps its keys, policies and
+identities. Clients have to use the new name once the enclave
+has been renamed. The default enclave and enclaves that are
+being re-encrypted cannot be renamed.
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes enclave rename tenant-1 tenant-eu-1
+`
+
+func renameEnclaveCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, renameEnclaveCmdUsage) }
+
+	var insecureSkipVerify bool
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes enclave rename --help'", err)
+	}
+
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no enclave name specified. See 'kes enclave rename --help'")
+	case cmd.NArg() == 1:
+		cli.Fatal("no new enclave name specified. See 'kes enclave rename --help'")
+	case cmd.NArg() > 2:
+		cli.Fatal("too many arguments. See 'kes enclave rename --help'")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	type Request struct {
+		Name string `json:"name"`
+	}
+	name, newName := cmd.Arg(0), cmd.Arg(1)
+	enclave := newClient(insecureSkipVerify).Enclave("")
+	err := send(ctx, enclave, http.MethodPost, "/v1/enclave/rename/"+name, nil, Request{Name: newName}, nil)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			cli.Exit(1)
+		}
+		cli.Fatalf("failed to rename enclave '%s': %v", name, err)
+	}
+}
+
+const suspendEnclaveCmdUsage = `Usage:
+    kes enclave suspend [options] <name>...
+
+Suspends one or multiple enclaves. A suspended enclave rejects
+all requests but retains its keys, policies and identities until
+it is resumed. The default enclave cannot be suspended.
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes enclave suspend tenant-1
+`
+
+func suspendEnclaveCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, suspendEnclaveCmdUsage) }
+
+	var insecureSkipVerify bool
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes enclave suspend --help'", err)
+	}
+	if cmd.NArg() == 0 {
+		cli.Fatal("no enclave name specified. See 'kes enclave suspend --help'")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	enclave := newClient(insecureSkipVerify).Enclave("")
+	for _, name := range cmd.Args() {
+		if err := send(ctx, enclave, http.MethodPost, "/v1/enclave/suspend/"+name, nil, nil, nil); err != nil {
+			if errors.Is(err, context.Canceled) {
+				cli.Exit(1)
+			}
+			cli.Fatalf("failed to suspend enclave '%s': %v", name, err)
+		}
+	}
+}
+
+const resumeEnclaveCmdUsage = `Usage:
+    kes enclave resume [options] <name>...
+
+Resumes one or multiple suspended enclaves.
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes enclave resume tenant-1
+`
+
+func resumeEnclaveCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, resumeEnclaveCmdUsage) }
+
+	var insecureSkipVerify bool
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes enclave resume --help'", err)
+	}
+	if cmd.NArg() == 0 {
+		cli.Fatal("no enclave name specified. See 'kes enclave resume --help'")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	enclave := newClient(insecureSkipVerify).Enclave("")
+	for _, name := range cmd.Args() {
+		if err := send(ctx, enclave, http.MethodPost, "/v1/enclave/resume/"+name, nil, nil, nil); err != nil {
+			if errors.Is(err, context.Canceled) {
+				cli.Exit(1)
+			}
+			cli.Fatalf("failed to resume enclave '%s': %v", name, err)
+		}
+	}
 }
 
 const deleteEnclaveCmdUsage = `Usage:
@@ -215,7 +844,7 @@ func deleteEnclaveCmd(args []string) {
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(2)
+			cli.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes enclave delete --help'", err)
 	}
@@ -231,9 +860,126 @@ func deleteEnclaveCmd(args []string) {
 	for _, name := range cmd.Args() {
 		if err := client.DeleteEnclave(ctx, name); err != nil {
 			if errors.Is(err, context.Canceled) {
-				os.Exit(1)
+				cli.Exit(1)
 			}
 			cli.Fatalf("failed to delete enclave '%s': %v", name, err)
 		}
 	}
 }
+
+const reencryptEnclaveCmdUsage = `Usage:
+    kes enclave reencrypt [options] <name>
+
+Replaces the root encryption keys of an enclave with new keys and
+re-encrypts all enclave entries in the background. The enclave
+remains available during the re-encryption. An interrupted
+re-encryption is resumed when running the command again.
+
+Options:
+    -s, --status             Show the progress of the re-encryption
+                             instead of starting it.
+    -w, --wait               Wait until the re-encryption completes.
+        --json               Print the re-encryption status in JSON format.
+    -k, --insecure           Skip TLS certificate validation.
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes enclave reencrypt --wait tenant-1
+    $ kes enclave reencrypt --status tenant-1
+`
+
+func reencryptEnclaveCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, reencryptEnclaveCmdUsage) }
+
+	var (
+		statusFlag         bool
+		waitFlag           bool
+		jsonFlag           bool
+		insecureSkipVerify bool
+	)
+	cmd.BoolVarP(&statusFlag, "status", "s", false, "Show the progress of the re-encryption")
+	cmd.BoolVarP(&waitFlag, "wait", "w", false, "Wait until the re-encryption completes")
+	cmd.BoolVar(&jsonFlag, "json", false, "Print the re-encryption status in JSON format")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes enclave reencrypt --help'", err)
+	}
+
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no enclave name specified. See 'kes enclave reencrypt --help'")
+	case cmd.NArg() > 1:
+		cli.Fatal("too many arguments. See 'kes enclave reencrypt --help'")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	type Response struct {
+		Enclave    string     `json:"enclave"`
+		State      string     `json:"state"`
+		Total      int        `json:"total"`
+		Done       int        `json:"done"`
+		StartedAt  *time.Time `json:"started_at,omitempty"`
+		FinishedAt *time.Time `json:"finished_at,omitempty"`
+		Error      string     `json:"error,omitempty"`
+	}
+	var (
+		name    = cmd.Arg(0)
+		enclave = newClient(insecureSkipVerify).Enclave("")
+		status  Response
+	)
+	if statusFlag {
+		if err := send(ctx, enclave, http.MethodGet, "/v1/enclave/reencrypt/status/"+name, nil, nil, &status); err != nil {
+			if errors.Is(err, context.Canceled) {
+				cli.Exit(1)
+			}
+			cli.Fatalf("failed to get re-encryption status of enclave '%s': %v", name, err)
+		}
+	} else {
+		if err := send(ctx, enclave, http.MethodPost, "/v1/enclave/reencrypt/start/"+name, nil, nil, &status); err != nil {
+			if errors.Is(err, context.Canceled) {
+				cli.Exit(1)
+			}
+			cli.Fatalf("failed to re-encrypt enclave '%s': %v", name, err)
+		}
+	}
+
+	for waitFlag && status.State == "running" {
+		if !jsonFlag && isTerm(os.Stdout) {
+			fmt.Printf("\rRe-encrypting '%s': %d/%d entries", name, status.Done, status.Total)
+		}
+		select {
+		case <-ctx.Done():
+			cli.Exit(1)
+		case <-time.After(1 * time.Second):
+		}
+		if err := send(ctx, enclave, http.MethodGet, "/v1/enclave/reencrypt/status/"+name, nil, nil, &status); err != nil {
+			if errors.Is(err, context.Canceled) {
+				cli.Exit(1)
+			}
+			cli.Fatalf("failed to get re-encryption status of enclave '%s': %v", name, err)
+		}
+	}
+	if waitFlag && !jsonFlag && isTerm(os.Stdout) {
+		fmt.Println()
+	}
+
+	if jsonFlag || !isTerm(os.Stdout) {
+		if err := json.NewEncoder(os.Stdout).Encode(status); err != nil {
+			cli.Fatal(err)
+		}
+	} else {
+		fmt.Printf("Enclave '%s': re-encryption %s (%d/%d entries)\n", status.Enclave, status.State, status.Done, status.Total)
+		if status.Error != "" {
+			fmt.Println("Error:", status.Error)
+		}
+	}
+	if status.State == "failed" {
+		cli.Exit(1)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/internal/tui"
+	flag "github.com/spf13/pflag"
+)
+
+const serverFeaturesCmdUsage = `Usage:
+    kes server features [options]
+
+Prints the version, build information, supported features and
+API levels of a KES server. The server only returns its version
+unless the client is allowed to access the /v1/version API.
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+        --json               Print the server features in JSON format.
+        --color <when>       Specify when to use colored output. The automatic
+                             mode only enables colors if an interactive terminal
+                             is detected - colors are automatically disabled if
+                             the output goes to a pipe.
+                             Possible values: *auto*, never, always.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes server features
+    $ kes server features --json | jq .features.fips
+`
+
+func serverFeaturesCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, serverFeaturesCmdUsage) }
+
+	var (
+		jsonFlag           bool
+		colorFlag          colorOption
+		insecureSkipVerify bool
+	)
+	cmd.BoolVar(&jsonFlag, "json", false, "Print the server features in JSON format")
+	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes server features --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatal("too many arguments. See 'kes server features --help'")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	type Response struct {
+		Version   string          `json:"version"`
+		Commit    string          `json:"commit"`
+		BuildDate time.Time       `json:"build_date,omitempty"`
+		GoVersion string          `json:"go_version,omitempty"`
+		Features  map[string]bool `json:"features"`
+		APILevels []string        `json:"api_levels"`
+	}
+	var manifest Response
+	if err := send(ctx, newEnclave("", insecureSkipVerify), http.MethodGet, "/v1/version", nil, nil, &manifest); err != nil {
+		if errors.Is(err, context.Canceled) {
+			cli.Exit(1)
+		}
+		cli.Fatalf("failed to fetch server features: %v", err)
+	}
+	if manifest.Features == nil {
+		cli.Fatal("failed to fetch server features: not allowed to access the server manifest")
+	}
+	if jsonFlag {
+		encoder := json.NewEncoder(os.Stdout)
+		if isTerm(os.Stdout) {
+			encoder.SetIndent("", "  ")
+		}
+		if err := encoder.Encode(manifest); err != nil {
+			cli.Fatal(err)
+		}
+		return
+	}
+
+	var faint, enabled, disabled tui.Style
+	if colorFlag.Colorize() {
+		const (
+			ColorEnabled  tui.Color = "#00d700"
+			ColorDisabled tui.Color = "#ac0000"
+		)
+		faint = faint.Faint(true).Bold(true)
+		enabled = enabled.Foreground(ColorEnabled)
+		disabled = disabled.Foreground(ColorDisabled)
+	}
+
+	fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Version")), manifest.Version)
+	fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Commit")), manifest.Commit)
+	if !manifest.BuildDate.IsZero() {
+		fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Build Date")), manifest.BuildDate.Format(time.RFC3339))
+	}
+	if manifest.GoVersion != "" {
+		fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Go")), manifest.GoVersion)
+	}
+	fmt.Println(faint.Render(fmt.Sprintf("%-11s", "API Levels")), strings.Join(manifest.APILevels, ", "))
+	fmt.Println()
+
+	features := make([]string, 0, len(manifest.Features))
+	for feature := range manifest.Features {
+		features = append(features, feature)
+	}
+	sort.Strings(features)
+
+	fmt.Println(faint.Render("Features"))
+	for _, feature := range features {
+		if manifest.Features[feature] {
+			fmt.Println(" ", enabled.Render("✔"), feature)
+		} else {
+			fmt.Println(" ", disabled.Render("✘"), faint.Render(feature))
+		}
+	}
+}
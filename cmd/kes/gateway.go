@@ -18,25 +18,29 @@ import (
 	"os/signal"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
 	"syscall"
 	"time"
 
-	tui "github.com/charmbracelet/lipgloss"
 	"github.com/minio/kes-go"
 	"github.com/minio/kes/edge"
 	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/audit"
 	"github.com/minio/kes/internal/auth"
 	"github.com/minio/kes/internal/cli"
 	"github.com/minio/kes/internal/cpu"
 	"github.com/minio/kes/internal/fips"
 	"github.com/minio/kes/internal/https"
+	"github.com/minio/kes/internal/k8s"
 	"github.com/minio/kes/internal/key"
 	"github.com/minio/kes/internal/keystore"
 	"github.com/minio/kes/internal/log"
 	"github.com/minio/kes/internal/metric"
 	"github.com/minio/kes/internal/sys"
+	"github.com/minio/kes/internal/tui"
+	"github.com/minio/kes/kv"
 )
 
 type gatewayConfig struct {
@@ -69,7 +73,17 @@ func startGateway(cliConfig gatewayConfig) {
 	if err != nil {
 		cli.Fatal(err)
 	}
-	gwConfig, err := newGatewayConfig(ctx, config, tlsConfig)
+	maintenance := &api.Maintenance{}
+	maintenance.SetReadOnly(config.ReadOnly)
+	drill := &api.Drill{}
+	auditStats := &audit.Stats{}
+	if config.Log.Stats != "" {
+		if err = auditStats.Load(config.Log.Stats); err != nil {
+			cli.Fatalf("failed to load audit statistics: %v", err)
+		}
+		go saveAuditStats(ctx, auditStats, config.Log.Stats)
+	}
+	gwConfig, err := newGatewayConfig(ctx, config, tlsConfig, maintenance, drill, auditStats)
 	if err != nil {
 		cli.Fatal(err)
 	}
@@ -80,11 +94,93 @@ func startGateway(cliConfig gatewayConfig) {
 	}
 	cli.Println(buffer.String())
 
+	// reload re-reads the server config and replaces the
+	// server's API handler and TLS config. In-flight requests
+	// are still served by the previous handler. Reloads are
+	// triggered by SIGHUP or the /v1/admin/reload API.
+	var (
+		reloadLock sync.Mutex
+		reload     func(context.Context) error
+	)
+	gwConfig.Reload = func(ctx context.Context) error { return reload(ctx) }
+
+	listeners, err := newListeners(config, tlsConfig)
+	if err != nil {
+		cli.Fatal(err)
+	}
+	shutdownDelay, shutdownTimeout := shutdownConfig(config)
 	server := https.NewServer(&https.Config{
-		Addr:      config.Addr,
-		Handler:   api.NewEdgeRouter(gwConfig),
-		TLSConfig: tlsConfig,
+		Addr:            config.Addr,
+		Handler:         api.NewEdgeRouter(gwConfig),
+		TLSConfig:       tlsConfig,
+		Listeners:       listeners,
+		ShutdownTimeout: shutdownTimeout,
 	})
+	drill.ExpireCertificate = server.SetExpiredCertificate
+	gwConfig.Metrics.RegisterCertificate(server.NotAfter)
+	gwConfig.Metrics.RegisterRevocationFailures(revocationStats.Failures)
+
+	// The TLS watcher is restarted on every reload since
+	// the TLS files may have changed.
+	watchCtx, stopWatch := context.WithCancel(ctx)
+	defer func() { stopWatch() }()
+	go watchCertificates(watchCtx, server, config, cliConfig.TLSAuth)
+
+	reload = func(reloadCtx context.Context) error {
+		reloadLock.Lock()
+		defer reloadLock.Unlock()
+
+		config, err := loadGatewayConfig(cliConfig)
+		if err != nil {
+			return fmt.Errorf("failed to read server config: %v", err)
+		}
+		tlsConfig, err := newTLSConfig(config, cliConfig.TLSAuth)
+		if err != nil {
+			return fmt.Errorf("failed to initialize TLS config: %v", err)
+		}
+		listeners, err := newListeners(config, tlsConfig)
+		if err != nil {
+			return err
+		}
+		newConfig, err := reloadGatewayConfig(ctx, reloadCtx, config, tlsConfig, maintenance, drill, auditStats)
+		if err != nil {
+			return fmt.Errorf("failed to initialize server API: %v", err)
+		}
+		newConfig.Reload = gwConfig.Reload
+		newConfig.Metrics.RegisterCertificate(server.NotAfter)
+		newConfig.Metrics.RegisterRevocationFailures(revocationStats.Failures)
+
+		delay, timeout := shutdownConfig(config)
+		err = server.Update(&https.Config{
+			Addr:            config.Addr,
+			Handler:         api.NewEdgeRouter(newConfig),
+			TLSConfig:       tlsConfig,
+			Listeners:       listeners,
+			ShutdownTimeout: timeout,
+		})
+		if err != nil {
+			stopGatewayConfig(newConfig)
+			return fmt.Errorf("failed to update server configuration: %v", err)
+		}
+		maintenance.SetReadOnly(config.ReadOnly)
+		stopGatewayConfig(gwConfig)
+		gwConfig = newConfig
+		shutdownDelay = delay
+
+		stopWatch()
+		watchCtx, stopWatch = context.WithCancel(ctx)
+		go watchCertificates(watchCtx, server, config, cliConfig.TLSAuth)
+
+		buffer, err := gatewayMessage(config, tlsConfig, mlock)
+		if err != nil {
+			log.Print(err)
+			cli.Println("Reloading configuration completed.")
+		} else {
+			cli.Println(buffer.String())
+		}
+		return nil
+	}
+
 	go func(ctx context.Context) {
 		if runtime.GOOS == "windows" {
 			return
@@ -100,74 +196,108 @@ func startGateway(cliConfig gatewayConfig) {
 				return
 			case <-sighup:
 				cli.Println("SIGHUP signal received. Reloading configuration...")
-				config, err := loadGatewayConfig(cliConfig)
-				if err != nil {
-					log.Printf("failed to read server config: %v", err)
-					continue
-				}
-				tlsConfig, err := newTLSConfig(config, cliConfig.TLSAuth)
-				if err != nil {
-					log.Printf("failed to initialize TLS config: %v", err)
-					continue
-				}
-				gwConfig, err := newGatewayConfig(ctx, config, tlsConfig)
-				if err != nil {
-					log.Printf("failed to initialize server API: %v", err)
-					continue
-				}
-				err = server.Update(&https.Config{
-					Addr:      config.Addr,
-					Handler:   api.NewEdgeRouter(gwConfig),
-					TLSConfig: tlsConfig,
-				})
-				if err != nil {
-					log.Printf("failed to update server configuration: %v", err)
-					continue
-				}
-				buffer, err := gatewayMessage(config, tlsConfig, mlock)
-				if err != nil {
+				if err := reload(ctx); err != nil {
 					log.Print(err)
-					cli.Println("Reloading configuration after SIGHUP signal completed.")
-				} else {
-					cli.Println(buffer.String())
 				}
 			}
 		}
 	}(ctx)
 
-	go func(ctx context.Context) {
-		ticker := time.NewTicker(15 * time.Minute)
-		defer ticker.Stop()
+	if config.TLS.ACME != nil && config.TLS.ACME.Challenge == "http-01" {
+		go serveACMEChallenges(ctx, config.TLS.ACME.HTTPAddr)
+	}
 
-		for {
-			select {
-			case <-ctx.Done():
-			case <-ticker.C:
-				tlsConfig, err := newTLSConfig(config, cliConfig.TLSAuth)
-				if err != nil {
-					log.Printf("failed to reload TLS configuration: %v", err)
-					continue
-				}
-				if err = server.UpdateTLS(tlsConfig); err != nil {
-					log.Printf("failed to update TLS configuration: %v", err)
-				}
-			}
+	// On SIGINT or SIGTERM, the server reports that it is not
+	// ready anymore, keeps accepting connections for the shutdown
+	// delay and then drains in-flight requests. A second signal
+	// terminates the server immediately.
+	serverCtx, stopServer := context.WithCancel(context.Background())
+	defer stopServer()
+	go func() {
+		<-ctx.Done()
+		cancelCtx() // Restore the default signal handling
+
+		reloadLock.Lock()
+		delay := shutdownDelay
+		reloadLock.Unlock()
+
+		maintenance.SetShuttingDown()
+		if delay > 0 {
+			cli.Printf("Shutting down. Draining in-flight requests in %v...\n", delay)
+			time.Sleep(delay)
+		} else {
+			cli.Println("Shutting down. Draining in-flight requests...")
 		}
-	}(ctx)
+		stopServer()
+	}()
 
-	if err := server.Start(ctx); err != nil && err != http.ErrServerClosed {
+	if config.ProbeAddr != "" {
+		go serveProbes(serverCtx, config.ProbeAddr, server.Handler())
+	}
+	if err := server.Start(serverCtx); err != nil && err != http.ErrServerClosed {
 		cli.Fatal(err)
 	}
 }
 
-func description(config *edge.ServerConfig) (kind string, endpoint []string, err error) {
-	if config.KeyStore == nil {
+// skipsAuth reports whether some API accepts requests without
+// verifying the client identity such that clients must be able
+// to connect without a certificate. Only APIs that are configured
+// with 'skip_auth: true' explicitly relax the TLS handshake. The
+// probes are served without client certificate on the separate
+// probe listener instead. See: serveProbes.
+func skipsAuth(config *edge.ServerConfig) bool {
+	if config.API != nil {
+		for _, api := range config.API.Paths {
+			if api.InsecureSkipAuth {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// serveProbes serves the liveness and readiness probes of
+// the handler on the given address via plain HTTP until
+// ctx is done.
+func serveProbes(ctx context.Context, addr string, handler http.Handler) {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           api.ProbeHandler(handler),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Printf("failed to serve probes: %v", err)
+	}
+}
+
+// shutdownConfig returns the delay after which the server
+// stops accepting new connections once it is shutting down
+// and the max. time it waits for in-flight requests.
+func shutdownConfig(config *edge.ServerConfig) (delay, timeout time.Duration) {
+	if config.Shutdown == nil {
+		return 0, 0
+	}
+	return config.Shutdown.Delay, config.Shutdown.Timeout
+}
+
+func description(store edge.KeyStore) (kind string, endpoint []string, err error) {
+	if store == nil {
 		return "", nil, errors.New("no KMS backend specified")
 	}
 
-	switch kms := config.KeyStore.(type) {
+	switch kms := store.(type) {
 	case *edge.FSKeyStore:
 		kind = "Filesystem"
+		switch {
+		case kms.AWSKMS != nil:
+			kind = "Filesystem (AWS KMS)"
+		case kms.GCPKMS != nil:
+			kind = "Filesystem (GCP Cloud KMS)"
+		}
 		if abs, err := filepath.Abs(kms.Path); err == nil {
 			endpoint = []string{abs}
 		} else {
@@ -179,21 +309,80 @@ func description(config *edge.ServerConfig) (kind string, endpoint []string, err
 	case *edge.VaultKeyStore:
 		kind = "Hashicorp Vault"
 		endpoint = []string{kms.Endpoint}
+	case *edge.EtcdKeyStore:
+		kind = "etcd"
+		endpoint = kms.Endpoints
+	case *edge.SQLKeyStore:
+		switch kms.Driver {
+		case "postgres":
+			kind = "PostgreSQL"
+		case "mysql":
+			kind = "MySQL"
+		default:
+			kind = "SQL"
+		}
+		table := kms.Table
+		if table == "" {
+			table = "kes_keys"
+		}
+		endpoint = []string{"Table: " + table}
 	case *edge.FortanixKeyStore:
 		kind = "Fortanix SDKMS"
 		endpoint = []string{kms.Endpoint}
 	case *edge.AWSSecretsManagerKeyStore:
 		kind = "AWS SecretsManager"
 		endpoint = []string{kms.Endpoint}
+	case *edge.AWSCloudHSMKeyStore:
+		kind = "AWS CloudHSM"
+		module := kms.Module
+		if module == "" {
+			module = "/opt/cloudhsm/lib/libcloudhsm_pkcs11.so"
+		}
+		endpoint = []string{"Module: " + module}
 	case *edge.KeySecureKeyStore:
 		kind = "Gemalto KeySecure"
 		endpoint = []string{kms.Endpoint}
+	case *edge.RESTKeyStore:
+		kind = "REST"
+		endpoint = []string{kms.Endpoint}
+	case *edge.OnePasswordKeyStore:
+		kind = "1Password"
+		endpoint = []string{kms.Endpoint}
+	case *edge.ConjurKeyStore:
+		kind = "CyberArk Conjur"
+		endpoint = []string{kms.Endpoint}
+	case *edge.IBMKeyProtectKeyStore:
+		kind = "IBM KeyProtect"
+		endpoint = []string{kms.Endpoint}
 	case *edge.GCPSecretManagerKeyStore:
 		kind = "GCP SecretManager"
 		endpoint = []string{"Project: " + kms.ProjectID}
+	case *edge.GCPCloudKMSKeyStore:
+		kind = "GCP Cloud KMS"
+		endpoint = []string{"Key: " + kms.Key, "Path: " + kms.Path}
 	case *edge.AzureKeyVaultKeyStore:
 		kind = "Azure KeyVault"
+		if kms.ManagedHSM {
+			kind = "Azure Managed HSM"
+		}
 		endpoint = []string{kms.Endpoint}
+	case *edge.OCIVaultKeyStore:
+		kind = "OCI Vault"
+		endpoint = []string{"Vault: " + kms.VaultID}
+	case *edge.AlibabaSecretsManagerKeyStore:
+		kind = "Alibaba SecretsManager"
+		if kms.Endpoint != "" {
+			endpoint = []string{kms.Endpoint}
+		} else {
+			endpoint = []string{"Region: " + kms.Region}
+		}
+	case *edge.PKCS11KeyStore:
+		kind = "PKCS #11"
+		if kms.TokenLabel != "" {
+			endpoint = []string{"Token: " + kms.TokenLabel}
+		} else {
+			endpoint = []string{fmt.Sprintf("Slot: %d", kms.Slot)}
+		}
 	default:
 		return "", nil, fmt.Errorf("unknown KMS backend %T", kms)
 	}
@@ -201,12 +390,27 @@ func description(config *edge.ServerConfig) (kind string, endpoint []string, err
 }
 
 // policySetFromConfig returns an in-memory PolicySet
-// from the given ServerConfig.
+// from the given ServerConfig. Each enclave has its own
+// policies.
 func policySetFromConfig(config *edge.ServerConfig) (auth.PolicySet, error) {
+	policies, err := newPolicySet(config.Admin, config.Policies)
+	if err != nil || len(config.Enclaves) == 0 {
+		return policies, err
+	}
+	enclaves := make(map[string]auth.PolicySet, len(config.Enclaves))
+	for name, enclave := range config.Enclaves {
+		if enclaves[name], err = newPolicySet(config.Admin, enclave.Policies); err != nil {
+			return nil, fmt.Errorf("enclave '%s': %v", name, err)
+		}
+	}
+	return api.EnclavePolicies(policies, enclaves), nil
+}
+
+func newPolicySet(admin kes.Identity, config map[string]edge.Policy) (auth.PolicySet, error) {
 	policies := &policySet{
 		policies: make(map[string]*auth.Policy),
 	}
-	for name, policy := range config.Policies {
+	for name, policy := range config {
 		if _, ok := policies.policies[name]; ok {
 			return nil, fmt.Errorf("policy %q already exists", name)
 		}
@@ -215,7 +419,7 @@ func policySetFromConfig(config *edge.ServerConfig) (auth.PolicySet, error) {
 			Allow:     policy.Allow,
 			Deny:      policy.Deny,
 			CreatedAt: time.Now().UTC(),
-			CreatedBy: config.Admin,
+			CreatedBy: admin,
 		}
 	}
 	return policies, nil
@@ -289,15 +493,30 @@ func (i *policyIterator) Name() string { return i.current }
 func (i *policyIterator) Close() error { return nil }
 
 // identitySetFromConfig returns an in-memory IdentitySet
-// from the given ServerConfig.
+// from the given ServerConfig. Each enclave has its own
+// identities but shares the admin.
 func identitySetFromConfig(config *edge.ServerConfig) (auth.IdentitySet, error) {
+	identities, err := newIdentitySet(config, config.Policies)
+	if err != nil || len(config.Enclaves) == 0 {
+		return identities, err
+	}
+	enclaves := make(map[string]auth.IdentitySet, len(config.Enclaves))
+	for name, enclave := range config.Enclaves {
+		if enclaves[name], err = newIdentitySet(config, enclave.Policies); err != nil {
+			return nil, fmt.Errorf("enclave '%s': %v", name, err)
+		}
+	}
+	return api.EnclaveIdentities(identities, enclaves), nil
+}
+
+func newIdentitySet(config *edge.ServerConfig, policies map[string]edge.Policy) (auth.IdentitySet, error) {
 	identities := &identitySet{
 		admin:     config.Admin,
 		createdAt: time.Now().UTC(),
 		roles:     map[kes.Identity]auth.IdentityInfo{},
 	}
 
-	for name, policy := range config.Policies {
+	for name, policy := range policies {
 		for _, id := range policy.Identities {
 			if id.IsUnknown() {
 				continue
@@ -426,6 +645,7 @@ func loadGatewayConfig(gConfig gatewayConfig) (*edge.ServerConfig, error) {
 	}
 	if gConfig.Address != "" {
 		config.Addr = gConfig.Address
+		config.Listeners = nil // CLI flags take precedence over the config file
 	}
 	if gConfig.PrivateKey != "" {
 		config.TLS.PrivateKey = gConfig.PrivateKey
@@ -433,9 +653,13 @@ func loadGatewayConfig(gConfig gatewayConfig) (*edge.ServerConfig, error) {
 	if gConfig.Certificate != "" {
 		config.TLS.Certificate = gConfig.Certificate
 	}
+	if gConfig.PrivateKey != "" && gConfig.Certificate != "" {
+		config.TLS.CertManager = nil // CLI flags take precedence over the config file
+		config.TLS.ACME = nil
+	}
 
 	// Set config defaults
-	if config.Addr == "" {
+	if config.Addr == "" && len(config.Listeners) == 0 {
 		config.Addr = "0.0.0.0:7373"
 	}
 	if config.Cache.Expiry == 0 {
@@ -444,11 +668,23 @@ func loadGatewayConfig(gConfig gatewayConfig) (*edge.ServerConfig, error) {
 	if config.Cache.ExpiryUnused == 0 {
 		config.Cache.ExpiryUnused = 30 * time.Second
 	}
+	if config.TLS.ReloadInterval <= 0 {
+		config.TLS.ReloadInterval = 30 * time.Second
+	}
 
 	// Verify config
 	if config.Admin.IsUnknown() {
 		return nil, errors.New("no admin identity specified")
 	}
+	if config.TLS.CertManager != nil {
+		if config.TLS.CertManager.ReloadInterval <= 0 {
+			config.TLS.CertManager.ReloadInterval = 30 * time.Second
+		}
+		return config, nil
+	}
+	if config.TLS.ACME != nil {
+		return config, nil
+	}
 	if config.TLS.PrivateKey == "" {
 		return nil, errors.New("no TLS private key specified")
 	}
@@ -459,9 +695,26 @@ func loadGatewayConfig(gConfig gatewayConfig) (*edge.ServerConfig, error) {
 }
 
 func newTLSConfig(config *edge.ServerConfig, auth string) (*tls.Config, error) {
-	certificate, err := https.CertificateFromFile(config.TLS.Certificate, config.TLS.PrivateKey, config.TLS.Password)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read TLS certificate: %v", err)
+	var (
+		certificate tls.Certificate
+		caCerts     []byte
+		err         error
+	)
+	if config.TLS.CertManager != nil {
+		secret, err := certManagerSecret(context.Background(), config.TLS.CertManager)
+		if err != nil {
+			return nil, err
+		}
+		certificate, err = https.CertificateFromPEM(secret.Data["tls.crt"], secret.Data["tls.key"], config.TLS.Password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read TLS certificate from secret '%s/%s': %v", secret.Namespace, secret.Name, err)
+		}
+		caCerts = secret.Data["ca.crt"]
+	} else if config.TLS.ACME == nil {
+		certificate, err = https.CertificateFromFile(config.TLS.Certificate, config.TLS.PrivateKey, config.TLS.Password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read TLS certificate: %v", err)
+		}
 	}
 	if certificate.Leaf != nil {
 		if len(certificate.Leaf.DNSNames) == 0 && len(certificate.Leaf.IPAddresses) == 0 {
@@ -479,46 +732,400 @@ func newTLSConfig(config *edge.ServerConfig, auth string) (*tls.Config, error) {
 			return nil, fmt.Errorf("failed to read TLS CA certificates: %v", err)
 		}
 	}
+	if len(caCerts) > 0 {
+		// The trust bundle issued by cert-manager is used in
+		// addition to any CA certificates specified explicitly.
+		if rootCAs == nil {
+			if rootCAs, _ = x509.SystemCertPool(); rootCAs == nil {
+				rootCAs = x509.NewCertPool()
+			}
+		}
+		if !rootCAs.AppendCertsFromPEM(caCerts) {
+			return nil, errors.New("failed to read TLS CA certificates: secret contains no valid 'ca.crt'")
+		}
+	}
+	clientCAs := rootCAs
+	if len(config.TLS.ClientCAPaths) > 0 {
+		paths := config.TLS.ClientCAPaths
+		if config.TLS.CAPath != "" {
+			paths = append([]string{config.TLS.CAPath}, paths...)
+		}
+		if clientCAs, err = https.CertPoolFromFiles(paths...); err != nil {
+			return nil, fmt.Errorf("failed to read TLS client CA certificates: %v", err)
+		}
+		if len(caCerts) > 0 && !clientCAs.AppendCertsFromPEM(caCerts) {
+			return nil, errors.New("failed to read TLS CA certificates: secret contains no valid 'ca.crt'")
+		}
+	}
+
+	minVersion, cipherSuites := config.TLS.MinVersion, config.TLS.CipherSuites
+	if minVersion == 0 {
+		minVersion = tls.VersionTLS12
+	}
+	if len(cipherSuites) == 0 {
+		cipherSuites = fips.TLSCiphers()
+	}
+	if err = checkCipherSuites(certificate.Leaf, minVersion, cipherSuites); err != nil {
+		return nil, fmt.Errorf("invalid TLS config: %v", err)
+	}
+
 	var clientAuth tls.ClientAuthType
 	switch strings.ToLower(auth) {
 	case "", "on":
 		clientAuth = tls.RequireAndVerifyClientCert
-		if config.API != nil {
-			for _, api := range config.API.Paths {
-				if api.InsecureSkipAuth {
-					clientAuth = tls.VerifyClientCertIfGiven
-					break
-				}
-			}
+		if skipsAuth(config) {
+			clientAuth = tls.VerifyClientCertIfGiven
 		}
 	case "off":
 		clientAuth = tls.RequireAnyClientCert
-		if config.API != nil {
-			for _, api := range config.API.Paths {
-				if api.InsecureSkipAuth {
-					clientAuth = tls.RequestClientCert
-					break
-				}
-			}
+		if skipsAuth(config) {
+			clientAuth = tls.RequestClientCert
 		}
 	default:
 		return nil, fmt.Errorf("invalid option for --auth: %s", auth)
 	}
+	if config.TLS.APIKeys || config.TLS.OIDC != nil { // Clients may authenticate with an API key or JWT instead of a certificate
+		switch clientAuth {
+		case tls.RequireAnyClientCert:
+			clientAuth = tls.RequestClientCert
+		case tls.RequireAndVerifyClientCert:
+			clientAuth = tls.VerifyClientCertIfGiven
+		}
+	}
 
-	return &tls.Config{
+	tlsConfig := &tls.Config{
 		Certificates: []tls.Certificate{certificate},
 		ClientAuth:   clientAuth,
 		RootCAs:      rootCAs,
-		ClientCAs:    rootCAs,
+		ClientCAs:    clientCAs,
 
-		MinVersion:       tls.VersionTLS12,
-		CipherSuites:     fips.TLSCiphers(),
+		MinVersion:       minVersion,
+		CipherSuites:     cipherSuites,
 		CurvePreferences: fips.TLSCurveIDs(),
-	}, nil
+	}
+	if config.TLS.ACME != nil {
+		setACMECertificate(tlsConfig, config.TLS.ACME)
+	}
+	if config.TLS.Revocation != nil {
+		checker, err := https.NewRevocationChecker(&https.RevocationConfig{
+			CRLs:          config.TLS.Revocation.CRLs,
+			OCSP:          config.TLS.Revocation.OCSP,
+			OCSPResponder: config.TLS.Revocation.OCSPResponder,
+			CacheTTL:      config.TLS.Revocation.CacheTTL,
+			FailOpen:      config.TLS.Revocation.FailOpen,
+			CRLSoftFail:   config.TLS.Revocation.CRLSoftFail,
+			Stats:         revocationStats,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize certificate revocation checks: %v", err)
+		}
+		tlsConfig.VerifyConnection = checker.VerifyConnection
+	}
+	return tlsConfig, nil
+}
+
+// revocationStats counts the failed client certificate
+// revocation checks of all TLS configs, including the
+// ones replaced on reload.
+var revocationStats = &https.RevocationStats{}
+
+// newListeners returns the HTTPS listeners of the server config.
+// Listeners without their own TLS configuration use tlsConfig.
+// Otherwise, their TLS configuration is derived from tlsConfig
+// but with their own certificate, CA certificates and TLS policy,
+// if specified.
+func newListeners(config *edge.ServerConfig, tlsConfig *tls.Config) ([]https.Listener, error) {
+	if len(config.Listeners) == 0 {
+		return nil, nil
+	}
+
+	listeners := make([]https.Listener, 0, len(config.Listeners))
+	for _, l := range config.Listeners {
+		listener := https.Listener{
+			Network: l.Network,
+			Addr:    l.Addr,
+		}
+		if l.TLS != nil {
+			listener.TLSConfig = tlsConfig.Clone()
+
+			var leaf *x509.Certificate
+			if len(tlsConfig.Certificates) > 0 {
+				leaf = tlsConfig.Certificates[0].Leaf
+			}
+			if l.TLS.PrivateKey != "" {
+				certificate, err := https.CertificateFromFile(l.TLS.Certificate, l.TLS.PrivateKey, l.TLS.Password)
+				if err != nil {
+					return nil, fmt.Errorf("failed to read TLS certificate of listener '%s': %v", l.Addr, err)
+				}
+				if certificate.Leaf != nil && len(certificate.Leaf.DNSNames) == 0 && len(certificate.Leaf.IPAddresses) == 0 {
+					return nil, fmt.Errorf("invalid TLS certificate of listener '%s': certificate does not contain any DNS or IP address as SAN", l.Addr)
+				}
+				listener.TLSConfig.Certificates = []tls.Certificate{certificate}
+				listener.TLSConfig.GetCertificate = nil // Don't present any ACME certificate
+				listener.TLSConfig.GetConfigForClient = nil
+				leaf = certificate.Leaf
+			}
+			if l.TLS.CAPath != "" {
+				rootCAs, err := https.CertPoolFromFile(l.TLS.CAPath)
+				if err != nil {
+					return nil, fmt.Errorf("failed to read TLS CA certificates of listener '%s': %v", l.Addr, err)
+				}
+				listener.TLSConfig.RootCAs, listener.TLSConfig.ClientCAs = rootCAs, rootCAs
+			}
+			if l.TLS.CAPath != "" || len(l.TLS.ClientCAPaths) > 0 {
+				caPath, clientCAPaths := l.TLS.CAPath, l.TLS.ClientCAPaths
+				if caPath == "" {
+					caPath = config.TLS.CAPath
+				}
+				if len(clientCAPaths) == 0 {
+					clientCAPaths = config.TLS.ClientCAPaths
+				}
+				if len(clientCAPaths) > 0 {
+					paths := clientCAPaths
+					if caPath != "" {
+						paths = append([]string{caPath}, paths...)
+					}
+					clientCAs, err := https.CertPoolFromFiles(paths...)
+					if err != nil {
+						return nil, fmt.Errorf("failed to read TLS client CA certificates of listener '%s': %v", l.Addr, err)
+					}
+					listener.TLSConfig.ClientCAs = clientCAs
+				}
+			}
+			if l.TLS.MinVersion != 0 {
+				listener.TLSConfig.MinVersion = l.TLS.MinVersion
+			}
+			if len(l.TLS.CipherSuites) > 0 {
+				listener.TLSConfig.CipherSuites = l.TLS.CipherSuites
+			}
+			if err := checkCipherSuites(leaf, listener.TLSConfig.MinVersion, listener.TLSConfig.CipherSuites); err != nil {
+				return nil, fmt.Errorf("invalid TLS config of listener '%s': %v", l.Addr, err)
+			}
+		}
+		listeners = append(listeners, listener)
+	}
+	return listeners, nil
+}
+
+// checkCipherSuites returns an error if TLS 1.2 is accepted but
+// none of the cipher suites can be used with the certificate's
+// key such that TLS 1.2 clients could not connect. It does nothing
+// if the certificate is not known in advance, e.g. when obtained
+// via ACME.
+func checkCipherSuites(certificate *x509.Certificate, minVersion uint16, cipherSuites []uint16) error {
+	if certificate == nil || minVersion >= tls.VersionTLS13 {
+		return nil
+	}
+
+	var prefixes []string
+	switch certificate.PublicKeyAlgorithm {
+	case x509.RSA:
+		prefixes = []string{"TLS_ECDHE_RSA_", "TLS_RSA_"}
+	case x509.ECDSA, x509.Ed25519:
+		prefixes = []string{"TLS_ECDHE_ECDSA_"}
+	default:
+		return nil
+	}
+	for _, id := range cipherSuites {
+		name := tls.CipherSuiteName(id)
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(name, prefix) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("none of the TLS 1.2 cipher suites supports the certificate's %v key: either add a cipher suite or set the min. TLS version to 1.3", certificate.PublicKeyAlgorithm)
 }
 
-func newGatewayConfig(ctx context.Context, config *edge.ServerConfig, tlsConfig *tls.Config) (*api.EdgeRouterConfig, error) {
-	rConfig := &api.EdgeRouterConfig{}
+// certManagerSecret fetches the Kubernetes TLS secret
+// referenced by the given cert-manager configuration.
+func certManagerSecret(ctx context.Context, config *edge.CertManagerConfig) (*k8s.Secret, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	client, err := k8s.InCluster()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to kubernetes: %v", err)
+	}
+	secret, err := client.Secret(ctx, config.Namespace, config.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch cert-manager secret '%s': %v", config.Secret, err)
+	}
+	return secret, nil
+}
+
+// watchCertManager periodically checks whether the cert-manager
+// secret has been updated and, if so, reloads the server's TLS
+// configuration. It prints the server's new identity on every
+// certificate rotation such that it can be allowed by any KES
+// client or peer.
+func watchCertManager(ctx context.Context, server *https.Server, config *edge.ServerConfig, auth string) {
+	secret, err := certManagerSecret(ctx, config.TLS.CertManager)
+	if err != nil {
+		log.Print(err)
+	}
+	var version string
+	if secret != nil {
+		version = secret.ResourceVersion
+	}
+
+	ticker := time.NewTicker(config.TLS.CertManager.ReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			secret, err := certManagerSecret(ctx, config.TLS.CertManager)
+			if err != nil {
+				log.Print(err)
+				continue
+			}
+			if secret.ResourceVersion == version {
+				continue
+			}
+
+			tlsConfig, err := newTLSConfig(config, auth)
+			if err != nil {
+				log.Printf("failed to reload TLS configuration: %v", err)
+				continue
+			}
+			if err = server.UpdateTLS(tlsConfig); err != nil {
+				log.Printf("failed to update TLS configuration: %v", err)
+				continue
+			}
+			version = secret.ResourceVersion
+			cli.Printf("Reloaded TLS certificate from secret '%s/%s'. Server identity: %s\n", secret.Namespace, secret.Name, serverIdentity(tlsConfig))
+		}
+	}
+}
+
+// watchCertificates periodically checks whether the server's
+// TLS private key, certificate, CA or client CA certificates, including
+// those of its listeners, have changed and, if so, reloads
+// the server's TLS configuration. Certificates loaded from
+// a Kubernetes secret are watched by watchCertManager while
+// certificates obtained via ACME are renewed automatically.
+//
+// The server keeps its current TLS configuration if the new
+// one cannot be loaded, e.g. since the private key has not
+// been replaced yet, and tries again on the next check.
+func watchCertificates(ctx context.Context, server *https.Server, config *edge.ServerConfig, auth string) {
+	if config.TLS.CertManager != nil {
+		go watchCertManager(ctx, server, config, auth)
+	}
+
+	var files []string
+	if config.TLS.CertManager == nil && config.TLS.ACME == nil {
+		files = append(files, config.TLS.PrivateKey, config.TLS.Certificate)
+	}
+	if config.TLS.CAPath != "" {
+		files = append(files, config.TLS.CAPath)
+	}
+	files = append(files, config.TLS.ClientCAPaths...)
+	for _, l := range config.Listeners {
+		if l.TLS == nil {
+			continue
+		}
+		if l.TLS.PrivateKey != "" {
+			files = append(files, l.TLS.PrivateKey, l.TLS.Certificate)
+		}
+		if l.TLS.CAPath != "" {
+			files = append(files, l.TLS.CAPath)
+		}
+		files = append(files, l.TLS.ClientCAPaths...)
+	}
+	if len(files) == 0 {
+		return
+	}
+
+	fingerprint := fileFingerprint(files)
+	ticker := time.NewTicker(config.TLS.ReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			f := fileFingerprint(files)
+			if f == fingerprint {
+				continue
+			}
+
+			tlsConfig, err := newTLSConfig(config, auth)
+			if err != nil {
+				log.Printf("failed to reload TLS configuration: %v", err)
+				continue
+			}
+			listeners, err := newListeners(config, tlsConfig)
+			if err != nil {
+				log.Printf("failed to reload TLS configuration: %v", err)
+				continue
+			}
+			if err = server.UpdateTLS(tlsConfig); err != nil {
+				log.Printf("failed to update TLS configuration: %v", err)
+				continue
+			}
+			if err = server.UpdateListeners(listeners); err != nil {
+				log.Printf("failed to update TLS configuration: %v", err)
+				continue
+			}
+			fingerprint = f
+			cli.Printf("Reloaded TLS certificate. Server identity: %s\n", serverIdentity(tlsConfig))
+		}
+	}
+}
+
+// fileFingerprint returns a fingerprint of the given files
+// and directories that changes whenever any of them, or any
+// file within one of the directories, gets modified.
+func fileFingerprint(files []string) string {
+	var fingerprint strings.Builder
+	add := func(stat os.FileInfo) {
+		fmt.Fprintf(&fingerprint, "%s:%d:%d;", stat.Name(), stat.Size(), stat.ModTime().UnixNano())
+	}
+	for _, file := range files {
+		stat, err := os.Stat(file)
+		if err != nil {
+			fmt.Fprintf(&fingerprint, "%s:%v;", file, err)
+			continue
+		}
+		add(stat)
+		if !stat.IsDir() {
+			continue
+		}
+		entries, err := os.ReadDir(file)
+		if err != nil {
+			fmt.Fprintf(&fingerprint, "%s:%v;", file, err)
+			continue
+		}
+		for _, entry := range entries {
+			if stat, err := os.Stat(filepath.Join(file, entry.Name())); err == nil {
+				add(stat)
+			}
+		}
+	}
+	return fingerprint.String()
+}
+
+// serverIdentity returns the KES identity of the
+// server's TLS certificate, if any.
+func serverIdentity(tlsConfig *tls.Config) kes.Identity {
+	if len(tlsConfig.Certificates) == 0 || tlsConfig.Certificates[0].Leaf == nil {
+		return ""
+	}
+	h := sha256.Sum256(tlsConfig.Certificates[0].Leaf.RawSubjectPublicKeyInfo)
+	return kes.Identity(hex.EncodeToString(h[:]))
+}
+
+func newGatewayConfig(ctx context.Context, config *edge.ServerConfig, tlsConfig *tls.Config, maintenance *api.Maintenance, drill *api.Drill, auditStats *audit.Stats) (*api.EdgeRouterConfig, error) {
+	rConfig := &api.EdgeRouterConfig{
+		APIKeys:     config.TLS.APIKeys,
+		Compression: config.KeyStoreCompression != sys.NoCompression,
+		Maintenance: maintenance,
+		Drill:       drill,
+		AuditStats:  auditStats,
+	}
 
 	if config.Log.Error {
 		rConfig.ErrorLog = log.New(os.Stderr, "Error: ", log.Ldate|log.Ltime|log.Lmsgprefix)
@@ -531,14 +1138,56 @@ func newGatewayConfig(ctx context.Context, config *edge.ServerConfig, tlsConfig
 		rConfig.AuditLog = log.New(ioutil.Discard, "", 0)
 	}
 
+	if config.TLS.OIDC != nil {
+		rConfig.OIDC = &auth.OIDC{
+			Issuer:        config.TLS.OIDC.Issuer,
+			Audience:      config.TLS.OIDC.Audience,
+			JWKSURL:       config.TLS.OIDC.JWKSURL,
+			IdentityClaim: config.TLS.OIDC.IdentityClaim,
+			PolicyClaim:   config.TLS.OIDC.PolicyClaim,
+		}
+	}
+	if config.TLS.SPIFFE != nil {
+		rConfig.SPIFFE = &auth.SPIFFE{
+			TrustDomains: config.TLS.SPIFFE.TrustDomains,
+		}
+	}
+	if config.TLS.ClientPolicy != nil {
+		rConfig.ClientTLS = &auth.ClientTLSPolicy{
+			MinVersion:    config.TLS.ClientPolicy.MinVersion,
+			MinRSAKeySize: config.TLS.ClientPolicy.MinRSAKeySize,
+			Curves:        config.TLS.ClientPolicy.Curves,
+			Enforce:       config.TLS.ClientPolicy.Enforce,
+		}
+		rConfig.TLSReport = &audit.TLSReport{}
+	}
+	for _, hook := range config.PolicyHooks {
+		module, err := os.ReadFile(hook.Module)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read policy hook '%s': %v", hook.Name, err)
+		}
+		policyHook, err := auth.NewPolicyHook(hook.Name, module, hook.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid policy hook '%s': %v", hook.Name, err)
+		}
+		rConfig.PolicyHooks = append(rConfig.PolicyHooks, policyHook)
+	}
+	if config.Receipts != nil {
+		signer, err := https.PrivateKeyFromFile(config.Receipts.PrivateKey, config.Receipts.Password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load receipt signing key: %v", err)
+		}
+		rConfig.ReceiptSigner = signer
+	}
 	if len(config.TLS.Proxies) != 0 {
 		rConfig.Proxy = &auth.TLSProxy{
 			CertHeader: http.CanonicalHeaderKey(config.TLS.ForwardCertHeader),
 		}
-		if tlsConfig.ClientAuth == tls.RequireAndVerifyClientCert {
+		if tlsConfig.ClientAuth == tls.RequireAndVerifyClientCert || tlsConfig.ClientAuth == tls.VerifyClientCertIfGiven {
 			rConfig.Proxy.VerifyOptions = &x509.VerifyOptions{
-				Roots: tlsConfig.RootCAs,
+				Roots: tlsConfig.ClientCAs,
 			}
+			rConfig.Proxy.VerifyConnection = tlsConfig.VerifyConnection
 		}
 		for _, identity := range config.TLS.Proxies {
 			if !identity.IsUnknown() {
@@ -561,6 +1210,7 @@ func newGatewayConfig(ctx context.Context, config *edge.ServerConfig, tlsConfig
 			rConfig.APIConfig[k] = api.Config{
 				Timeout:          v.Timeout,
 				InsecureSkipAuth: v.InsecureSkipAuth,
+				RequireAuth:      v.RequireAuth,
 			}
 		}
 	}
@@ -575,46 +1225,307 @@ func newGatewayConfig(ctx context.Context, config *edge.ServerConfig, tlsConfig
 		return nil, err
 	}
 
-	conn, err := config.KeyStore.Connect(ctx)
+	var cacheStats *kv.CacheStats
+	if config.Cache.KeyStore != nil {
+		cacheStats = &kv.CacheStats{}
+	}
+	conn, err := connectKeyStore(ctx, &edge.EnclaveConfig{
+		KeyStore:               config.KeyStore,
+		KeyStoreReplicas:       config.KeyStoreReplicas,
+		KeyStoreMirror:         config.KeyStoreMirror,
+		KeyStoreConnect:        config.KeyStoreConnect,
+		KeyStoreRetry:          config.KeyStoreRetry,
+		KeyStoreCircuitBreaker: config.KeyStoreCircuitBreaker,
+		KeyStoreCompression:    config.KeyStoreCompression,
+	}, config.Cache.KeyStore, cacheStats, rConfig.ErrorLog)
 	if err != nil {
 		return nil, err
 	}
-	rConfig.Keys = keystore.NewCache(ctx, conn, &keystore.CacheConfig{
+	lazy, _ := conn.(*keystore.LazyStore)
+
+	cacheConfig := &keystore.CacheConfig{
 		Expiry:        config.Cache.Expiry,
 		ExpiryUnused:  config.Cache.ExpiryUnused,
 		ExpiryOffline: config.Cache.ExpiryOffline,
-	})
+		DeleteExpired: config.KeyExpiry.DeleteInterval,
+	}
+	rConfig.Keys = keystore.NewCache(ctx, conn, cacheConfig)
+	if len(config.Enclaves) > 0 {
+		rConfig.Enclaves = make(map[string]*keystore.Cache, len(config.Enclaves))
+	}
+	for name, enclave := range config.Enclaves {
+		conn, err := connectKeyStore(ctx, enclave, config.Cache.KeyStore, cacheStats, rConfig.ErrorLog)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to keystore of enclave '%s': %v", name, err)
+		}
+		rConfig.Enclaves[name] = keystore.NewCache(ctx, conn, cacheConfig)
+		if enclave.KeyStoreCompression != sys.NoCompression {
+			rConfig.Compression = true
+		}
+	}
+	rConfig.KeyPools = make(map[string]*keystore.Pool, len(config.KeyPools))
+	for _, pool := range config.KeyPools {
+		algorithm := pool.Algorithm
+		if algorithm == kes.KeyAlgorithmUndefined {
+			algorithm = defaultKeyAlgorithm()
+		}
+		rConfig.KeyPools[pool.Prefix] = keystore.NewPool(rConfig.Keys, &keystore.PoolConfig{
+			Prefix:    pool.Prefix,
+			Size:      pool.Size,
+			Algorithm: algorithm,
+			Owner:     config.Admin,
+		})
+	}
 
-	for _, k := range config.Keys {
-		var algorithm kes.KeyAlgorithm
-		if fips.Enabled || cpu.HasAESGCM() {
-			algorithm = kes.AES256_GCM_SHA256
-		} else {
-			algorithm = kes.XCHACHA20_POLY1305
+	if lazy == nil {
+		if err = createKeys(ctx, rConfig.Keys, config); err != nil {
+			return nil, err
 		}
+		for _, pool := range rConfig.KeyPools {
+			pool.Start(ctx)
+		}
+	} else {
+		// Once connected, create the keys specified in the config.
+		// If the KES server cannot connect to the keystore within
+		// the retry period, it gives up and exits.
+		go func() {
+			select {
+			case <-ctx.Done():
+				return
+			case <-lazy.Done():
+			}
+			if err := lazy.Err(); err != nil {
+				if ctx.Err() == nil {
+					cli.Fatalf("failed to connect to keystore: %v", err)
+				}
+				return
+			}
+			if err := createKeys(ctx, rConfig.Keys, config); err != nil {
+				cli.Fatal(err)
+			}
+			for _, pool := range rConfig.KeyPools {
+				pool.Start(ctx)
+			}
+		}()
+	}
 
-		key, err := key.Random(algorithm, config.Admin)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create key '%s': %v", k.Name, err)
+	if config.RateLimit != nil {
+		identities := rConfig.Identities
+		rConfig.RateLimit = &api.RateLimit{
+			Rate:  config.RateLimit.Rate,
+			Burst: config.RateLimit.Burst,
+			Known: func(r *http.Request) bool {
+				identity := auth.Identify(r)
+				if admin, err := identities.Admin(r.Context()); err == nil && identity == admin {
+					return true
+				}
+				_, err := identities.Get(r.Context(), identity)
+				return err == nil
+			},
 		}
-		if err = rConfig.Keys.Create(ctx, k.Name, key); err != nil && !errors.Is(err, kes.ErrKeyExists) {
-			return nil, fmt.Errorf("failed to create key '%s': %v", k.Name, err)
+		if len(config.RateLimit.Identities) > 0 {
+			rConfig.RateLimit.Identities = make(map[kes.Identity]api.RateLimitRule, len(config.RateLimit.Identities))
+			for identity, rule := range config.RateLimit.Identities {
+				rConfig.RateLimit.Identities[identity] = api.RateLimitRule{Rate: rule.Rate, Burst: rule.Burst}
+			}
+		}
+		if len(config.RateLimit.Policies) > 0 {
+			rConfig.RateLimit.Policies = make(map[string]api.RateLimitRule, len(config.RateLimit.Policies))
+			for policy, rule := range config.RateLimit.Policies {
+				rConfig.RateLimit.Policies[policy] = api.RateLimitRule{Rate: rule.Rate, Burst: rule.Burst}
+			}
+			rConfig.RateLimit.Policy = func(r *http.Request) string { return auth.PolicyOf(r, identities) }
+		}
+		if len(config.RateLimit.Enclaves) > 0 {
+			rConfig.RateLimit.Enclaves = make(map[string]api.RateLimitRule, len(config.RateLimit.Enclaves))
+			for enclave, rule := range config.RateLimit.Enclaves {
+				rConfig.RateLimit.Enclaves[enclave] = api.RateLimitRule{Rate: rule.Rate, Burst: rule.Burst}
+			}
+		}
+	}
+	if config.Crypto != nil {
+		rConfig.AEADPool = cpu.NewPool(config.Crypto.AEAD.Workers, config.Crypto.AEAD.Queue)
+		rConfig.UnwrapPool = cpu.NewPool(config.Crypto.Unwrap.Workers, config.Crypto.Unwrap.Queue)
+	}
+	if config.Limits != nil {
+		rConfig.RequestLimit = &api.RequestLimit{
+			MaxBody:      config.Limits.MaxBody,
+			Pool:         cpu.NewPool(config.Limits.MaxRequests, config.Limits.Queue),
+			QueueTimeout: config.Limits.QueueTimeout,
 		}
 	}
 
 	rConfig.Metrics = metric.New()
+	rConfig.Metrics.RegisterPool("aead", rConfig.AEADPool)
+	rConfig.Metrics.RegisterPool("unwrap", rConfig.UnwrapPool)
+	rConfig.Metrics.RegisterReadOnly(maintenance.ReadOnly)
+	rConfig.Metrics.RegisterCache(cacheStats)
+	if rConfig.RateLimit != nil {
+		rConfig.Metrics.RegisterRateLimit(rConfig.RateLimit.Rejected)
+	}
+	if rConfig.RequestLimit != nil {
+		rConfig.Metrics.RegisterRequestLimit(rConfig.RequestLimit.Pool, rConfig.RequestLimit.Shed)
+	}
 	rConfig.AuditLog.Add(rConfig.Metrics.AuditEventCounter())
 	rConfig.ErrorLog.Add(rConfig.Metrics.ErrorEventCounter())
+	rConfig.AuditLog.Add(auditStats)
 	return rConfig, nil
 }
 
+// stopGatewayConfig stops all background go routines
+// of the keystores of the given config.
+// reloadGatewayConfig returns a new EdgeRouterConfig like
+// newGatewayConfig. The returned config, e.g. its keystore
+// caches and key pools, is bound to ctx, the server's context,
+// and not to reloadCtx. Otherwise, its background tasks would
+// stop once a reload request has been served.
+//
+// If reloadCtx is canceled before the config is ready, e.g.
+// while connecting to the keystores, reloadGatewayConfig
+// returns the context error and stops the config as soon as
+// it is ready.
+func reloadGatewayConfig(ctx, reloadCtx context.Context, config *edge.ServerConfig, tlsConfig *tls.Config, maintenance *api.Maintenance, drill *api.Drill, auditStats *audit.Stats) (*api.EdgeRouterConfig, error) {
+	type Result struct {
+		Config *api.EdgeRouterConfig
+		Err    error
+	}
+	ch := make(chan Result, 1)
+	go func() {
+		rConfig, err := newGatewayConfig(ctx, config, tlsConfig, maintenance, drill, auditStats)
+		ch <- Result{Config: rConfig, Err: err}
+	}()
+
+	select {
+	case r := <-ch:
+		return r.Config, r.Err
+	case <-reloadCtx.Done():
+		go func() {
+			if r := <-ch; r.Err == nil {
+				stopGatewayConfig(r.Config)
+			}
+		}()
+		return nil, reloadCtx.Err()
+	}
+}
+
+func stopGatewayConfig(config *api.EdgeRouterConfig) {
+	config.Keys.Stop()
+	for _, enclave := range config.Enclaves {
+		enclave.Stop()
+	}
+}
+
+// connectKeyStore connects to the keystore as specified by
+// the connect config. If the keystore should be connected
+// lazily, it returns a *keystore.LazyStore that connects
+// in the background.
+//
+// If there are replicas, read requests are distributed across
+// them. If there is a mirror, modifications are mirrored to it
+// and mirror errors are logged to errorLog. If the retry or
+// breaker config is not nil, requests to the keystore, each
+// replica and the mirror are retried resp. guarded by a circuit
+// breaker. Keys written to the keystore, its replicas and its
+// mirror are compressed as specified by the config. If the
+// cache config is not nil, the keystore gets
+// wrapped by a kv.Cache that counts its hits and misses in
+// stats.
+func connectKeyStore(ctx context.Context, config *edge.EnclaveConfig, cache *edge.KeyStoreCacheConfig, stats *kv.CacheStats, errorLog *log.Logger) (kv.Store[string, []byte], error) {
+	var (
+		retry, breaker = config.KeyStoreRetry, config.KeyStoreCircuitBreaker
+		connect        = config.KeyStoreConnect
+	)
+	connectTo := func(ctx context.Context, store edge.KeyStore) (kv.Store[string, []byte], error) {
+		s, err := store.Connect(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if retry != nil || breaker != nil {
+			s = edge.WithRetry(s, retry, breaker)
+		}
+		return s, nil
+	}
+	connectFn := func(ctx context.Context) (kv.Store[string, []byte], error) {
+		s, err := connectTo(ctx, config.KeyStore)
+		if err != nil {
+			return nil, err
+		}
+		if len(config.KeyStoreReplicas) > 0 {
+			replicas := make([]kv.Store[string, []byte], 0, len(config.KeyStoreReplicas))
+			for _, replica := range config.KeyStoreReplicas {
+				conn, err := connectTo(ctx, replica)
+				if err != nil {
+					return nil, err
+				}
+				replicas = append(replicas, conn)
+			}
+			s = edge.WithReplicas(s, replicas...)
+		}
+		if config.KeyStoreMirror != nil {
+			mirror, err := connectTo(ctx, config.KeyStoreMirror)
+			if err != nil {
+				return nil, err
+			}
+			s = edge.WithMirror(s, mirror, func(err error) { errorLog.Print(err) })
+		}
+		if s, err = edge.WithCompression(s, config.KeyStoreCompression); err != nil {
+			return nil, err
+		}
+		if cache == nil {
+			return s, nil
+		}
+		return kv.NewCache(s, &kv.CacheConfig{
+			Size:           cache.Size,
+			Expiry:         cache.Expiry,
+			NegativeExpiry: cache.NegativeExpiry,
+			NotExists: func(err error) bool {
+				return errors.Is(err, kes.ErrKeyNotFound) || errors.Is(err, kv.ErrNotExists)
+			},
+			Stats: stats,
+		}).Recoverable(), nil
+	}
+
+	if connect != nil && connect.Lazy {
+		return keystore.ConnectLazy(ctx, connectFn, connect.Retry), nil
+	}
+
+	var connectRetry time.Duration
+	if connect != nil {
+		connectRetry = connect.Retry
+	}
+	return keystore.Connect(ctx, connectFn, connectRetry)
+}
+
+// createKeys creates the keys specified in the config
+// unless they exist already.
+func createKeys(ctx context.Context, keys *keystore.Cache, config *edge.ServerConfig) error {
+	for _, k := range config.Keys {
+		key, err := key.Random(defaultKeyAlgorithm(), config.Admin)
+		if err != nil {
+			return fmt.Errorf("failed to create key '%s': %v", k.Name, err)
+		}
+		if err = keys.Create(ctx, k.Name, key); err != nil && !errors.Is(err, kes.ErrKeyExists) {
+			return fmt.Errorf("failed to create key '%s': %v", k.Name, err)
+		}
+	}
+	return nil
+}
+
+// defaultKeyAlgorithm returns the fastest encryption
+// algorithm available.
+func defaultKeyAlgorithm() kes.KeyAlgorithm {
+	if fips.Enabled || cpu.HasAESGCM() {
+		return kes.AES256_GCM_SHA256
+	}
+	return kes.XCHACHA20_POLY1305
+}
+
 func gatewayMessage(config *edge.ServerConfig, tlsConfig *tls.Config, mlock bool) (*cli.Buffer, error) {
-	ip, port := serverAddr(config.Addr)
-	ifaceIPs := listeningOnV4(ip)
-	if len(ifaceIPs) == 0 {
-		return nil, errors.New("failed to listen on network interfaces")
+	endpoints, err := serverEndpoints(config)
+	if err != nil {
+		return nil, err
 	}
-	kmsKind, kmsEndpoints, err := description(config)
+	kmsKind, kmsEndpoints, err := description(config.KeyStore)
 	if err != nil {
 		return nil, err
 	}
@@ -630,15 +1541,40 @@ func gatewayMessage(config *edge.ServerConfig, tlsConfig *tls.Config, mlock bool
 	buffer := new(cli.Buffer)
 	buffer.Stylef(item, "%-12s", "Copyright").Sprintf("%-22s", "MinIO, Inc.").Styleln(faint, "https://min.io")
 	buffer.Stylef(item, "%-12s", "License").Sprintf("%-22s", "GNU AGPLv3").Styleln(faint, "https://www.gnu.org/licenses/agpl-3.0.html")
-	buffer.Stylef(item, "%-12s", "Version").Sprintf("%-22s", sys.BinaryInfo().Version).Stylef(faint, "%s/%s\n", runtime.GOOS, runtime.GOARCH)
+	platform := runtime.GOOS + "/" + runtime.GOARCH
+	if sys.Minimal {
+		platform += " (minimal)"
+	}
+	buffer.Stylef(item, "%-12s", "Version").Sprintf("%-22s", sys.BinaryInfo().Version).Styleln(faint, platform)
 	buffer.Sprintln()
 	buffer.Stylef(item, "%-12s", "KMS").Sprintf("%s: %s\n", kmsKind, kmsEndpoints[0])
 	for _, endpoint := range kmsEndpoints[1:] {
 		buffer.Sprintf("%-12s", " ").Sprint(strings.Repeat(" ", len(kmsKind))).Sprintf("  %s\n", endpoint)
 	}
-	buffer.Stylef(item, "%-12s", "Endpoints").Sprintf("https://%s:%s\n", ifaceIPs[0], port)
-	for _, ifaceIP := range ifaceIPs[1:] {
-		buffer.Sprintf("%-12s", " ").Sprintf("https://%s:%s\n", ifaceIP, port)
+	enclaves := make([]string, 0, len(config.Enclaves))
+	for name := range config.Enclaves {
+		enclaves = append(enclaves, name)
+	}
+	sort.Strings(enclaves)
+	for i, name := range enclaves {
+		kind, endpoints, err := description(config.Enclaves[name].KeyStore)
+		if err != nil {
+			return nil, err
+		}
+		label := "Enclaves"
+		if i > 0 {
+			label = " "
+		}
+		buffer.Stylef(item, "%-12s", label).Sprintf("%s: %s", name, kind)
+		if len(endpoints) > 0 {
+			buffer.Styleln(faint, " "+endpoints[0])
+		} else {
+			buffer.Sprintln()
+		}
+	}
+	buffer.Stylef(item, "%-12s", "Endpoints").Sprintln(endpoints[0])
+	for _, endpoint := range endpoints[1:] {
+		buffer.Sprintf("%-12s", " ").Sprintln(endpoint)
 	}
 	buffer.Sprintln()
 	if r, err := hex.DecodeString(config.Admin.String()); err == nil && len(r) == sha256.Size {
@@ -646,8 +1582,34 @@ func gatewayMessage(config *edge.ServerConfig, tlsConfig *tls.Config, mlock bool
 	} else {
 		buffer.Stylef(item, "%-12s", "Admin").Sprintf("%-22s", "_").Styleln(faint, "[ disabled ]")
 	}
-	if tlsConfig.ClientAuth == tls.RequireAndVerifyClientCert {
-		buffer.Stylef(item, "%-12s", "Mutual TLS").Sprint("on").Styleln(faint, "Verify client certificates")
+	if config.TLS.CertManager != nil {
+		buffer.Stylef(item, "%-12s", "Identity").Sprintln(serverIdentity(tlsConfig))
+		buffer.Stylef(item, "%-12s", "TLS").Sprintf("%-22s", "cert-manager").Stylef(faint, "Reload TLS certificate every %v\n", config.TLS.CertManager.ReloadInterval)
+	}
+	if acme := config.TLS.ACME; acme != nil {
+		buffer.Stylef(item, "%-12s", "TLS").Sprintf("%-22s", "acme").Styleln(faint, "Obtain TLS certificate for "+strings.Join(acme.Domains, ", "))
+	}
+	switch tlsConfig.ClientAuth {
+	case tls.RequireAndVerifyClientCert:
+		buffer.Stylef(item, "%-12s", "Mutual TLS").Sprintf("%-22s", "on").Styleln(faint, "Verify client certificates")
+	case tls.VerifyClientCertIfGiven:
+		buffer.Stylef(item, "%-12s", "Mutual TLS").Sprintf("%-22s", "optional").Styleln(faint, "Verify client certificates, if presented")
+	}
+	if config.TLS.APIKeys {
+		buffer.Stylef(item, "%-12s", "API Keys").Sprintf("%-22s", "on").Styleln(faint, "Accept API keys as bearer tokens")
+	}
+	if policy := config.TLS.ClientPolicy; policy != nil {
+		if policy.Enforce {
+			buffer.Stylef(item, "%-12s", "Client TLS").Sprintf("%-22s", "enforce").Styleln(faint, "Reject clients that do not meet the TLS requirements")
+		} else {
+			buffer.Stylef(item, "%-12s", "Client TLS").Sprintf("%-22s", "report").Styleln(faint, "Report clients that do not meet the TLS requirements")
+		}
+	}
+	if config.TLS.SPIFFE != nil {
+		buffer.Stylef(item, "%-12s", "SPIFFE").Sprintf("%-22s", "on").Stylef(faint, "Map SPIFFE IDs of %s to identities\n", strings.Join(config.TLS.SPIFFE.TrustDomains, ", "))
+	}
+	if config.TLS.OIDC != nil {
+		buffer.Stylef(item, "%-12s", "OIDC").Sprintf("%-22s", "on").Stylef(faint, "Accept JWTs issued by %s\n", config.TLS.OIDC.Issuer)
 	}
 	switch {
 	case runtime.GOOS == "linux" && mlock:
@@ -659,3 +1621,30 @@ func gatewayMessage(config *edge.ServerConfig, tlsConfig *tls.Config, mlock bool
 	}
 	return buffer, nil
 }
+
+// serverEndpoints returns the URLs of all endpoints the
+// server listens on. A TCP listener on 0.0.0.0 has one
+// endpoint per network interface.
+func serverEndpoints(config *edge.ServerConfig) ([]string, error) {
+	listeners := config.Listeners
+	if len(listeners) == 0 {
+		listeners = []edge.Listener{{Network: "tcp", Addr: config.Addr}}
+	}
+
+	var endpoints []string
+	for _, l := range listeners {
+		if l.Network == "unix" {
+			endpoints = append(endpoints, "unix://"+l.Addr)
+			continue
+		}
+		ip, port := serverAddr(l.Addr)
+		ifaceIPs := listeningOnV4(ip)
+		if len(ifaceIPs) == 0 {
+			return nil, errors.New("failed to listen on network interfaces")
+		}
+		for _, ifaceIP := range ifaceIPs {
+			endpoints = append(endpoints, fmt.Sprintf("https://%s:%s", ifaceIP, port))
+		}
+	}
+	return endpoints, nil
+}
@@ -0,0 +1,50 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/minio/kes/edge"
+)
+
+var skipsAuthTests = []struct {
+	Paths     map[string]edge.APIPathConfig
+	SkipsAuth bool
+}{
+	{Paths: nil, SkipsAuth: false}, // 0
+	{ // 1
+		Paths: map[string]edge.APIPathConfig{
+			"/healthz": {},
+			"/readyz":  {},
+		},
+		SkipsAuth: false,
+	},
+	{ // 2
+		Paths: map[string]edge.APIPathConfig{
+			"/healthz":   {RequireAuth: true},
+			"/v1/status": {InsecureSkipAuth: true},
+		},
+		SkipsAuth: true,
+	},
+	{ // 3
+		Paths: map[string]edge.APIPathConfig{
+			"/readyz": {InsecureSkipAuth: true},
+		},
+		SkipsAuth: true,
+	},
+}
+
+func TestSkipsAuth(t *testing.T) {
+	for i, test := range skipsAuthTests {
+		config := &edge.ServerConfig{}
+		if test.Paths != nil {
+			config.API = &edge.APIConfig{Paths: test.Paths}
+		}
+		if skips := skipsAuth(config); skips != test.SkipsAuth {
+			t.Fatalf("Test %d: got '%v' - want '%v'", i, skips, test.SkipsAuth)
+		}
+	}
+}
@@ -5,27 +5,33 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"crypto/rand"
 	"crypto/sha256"
 	"crypto/x509"
 	"encoding/hex"
+	"encoding/json"
 	"encoding/pem"
 	"errors"
 	"fmt"
 	"net"
+	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"sort"
 	"strings"
 	"time"
 
-	tui "github.com/charmbracelet/lipgloss"
 	"github.com/minio/kes-go"
 	"github.com/minio/kes/internal/cli"
 	"github.com/minio/kes/internal/https"
+	"github.com/minio/kes/internal/tui"
 	flag "github.com/spf13/pflag"
+	"golang.org/x/crypto/pkcs12"
 	"golang.org/x/term"
 )
 
@@ -37,6 +43,8 @@ Commands:
     of                       Compute a KES identity from a certificate.
     info                     Get information about a KES identity.
     ls                       List KES identities.
+    add                      Add a KES identity to an enclave.
+    renew                    Extend the lifetime of a KES identity.
     rm                       Remove a KES identity.
 
 Options:
@@ -48,16 +56,18 @@ func identityCmd(args []string) {
 	cmd.Usage = func() { fmt.Fprint(os.Stderr, identityCmdUsage) }
 
 	subCmds := commands{
-		"new":  newIdentityCmd,
-		"of":   ofIdentityCmd,
-		"info": infoIdentityCmd,
-		"ls":   lsIdentityCmd,
-		"rm":   rmIdentityCmd,
+		"new":   newIdentityCmd,
+		"of":    ofIdentityCmd,
+		"info":  infoIdentityCmd,
+		"ls":    lsIdentityCmd,
+		"add":   addIdentityCmd,
+		"renew": renewIdentityCmd,
+		"rm":    rmIdentityCmd,
 	}
 
 	if len(args) < 2 {
 		cmd.Usage()
-		os.Exit(2)
+		cli.Exit(2)
 	}
 	if cmd, ok := subCmds[args[1]]; ok {
 		cmd(args[1:])
@@ -66,7 +76,7 @@ func identityCmd(args []string) {
 
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(2)
+			cli.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes identity --help'", err)
 	}
@@ -74,7 +84,7 @@ func identityCmd(args []string) {
 		cli.Fatalf("%q is not an identity command. See 'kes identity --help'", cmd.Arg(0))
 	}
 	cmd.Usage()
-	os.Exit(2)
+	cli.Exit(2)
 }
 
 const newIdentityCmdUsage = `Usage:
@@ -124,7 +134,7 @@ func newIdentityCmd(args []string) {
 	cmd.BoolVar(&encrypt, "encrypt", false, "Encrypt the private key with a password")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(2)
+			cli.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes identity new --help'", err)
 	}
@@ -268,12 +278,19 @@ const ofIdentityCmdUsage = `Usage:
     kes identity of <api-key>
     kes identity of <certificate>
 
+Computes the identity of an API key or a certificate. The
+certificate may be PEM or DER encoded or a PKCS#12 bundle,
+usually with a .p12 or .pfx file extension. If the PKCS#12
+bundle is password-protected, the password is read from the
+terminal.
+
 Options:
     -h, --help               Print command line options.
 
 Examples:
     $ kes identity of kes:v1:ACQpoGqx3rHHjT938Hfu5hVVQJHZWSqVI2Xp1KlYxFVw
     $ kes identity of client.crt
+    $ kes identity of client.p12
 `
 
 func ofIdentityCmd(args []string) {
@@ -282,7 +299,7 @@ func ofIdentityCmd(args []string) {
 
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(2)
+			cli.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes identity of --help'", err)
 	}
@@ -299,17 +316,11 @@ func ofIdentityCmd(args []string) {
 		identity = key.Identity()
 	} else {
 		filename := cmd.Arg(0)
-		pemBlock, err := os.ReadFile(filename)
+		data, err := os.ReadFile(filename)
 		if err != nil {
 			cli.Fatal(err)
 		}
-		pemBlock, err = https.FilterPEM(pemBlock, func(b *pem.Block) bool { return b.Type == "CERTIFICATE" })
-		if err != nil {
-			cli.Fatalf("failed to parse certificate in '%s': %v", filename, err)
-		}
-
-		next, _ := pem.Decode(pemBlock)
-		cert, err := x509.ParseCertificate(next.Bytes)
+		cert, err := parseCertificateFile(filename, data)
 		if err != nil {
 			cli.Fatalf("failed to parse certificate in '%s': %v", filename, err)
 		}
@@ -327,6 +338,71 @@ func ofIdentityCmd(args []string) {
 	}
 }
 
+// parseCertificateFile parses the certificate stored in the
+// file. It accepts PEM and DER encoded certificates as well
+// as PKCS#12 bundles. If the PKCS#12 bundle is encrypted with
+// a non-empty password, it asks for the password.
+func parseCertificateFile(filename string, data []byte) (*x509.Certificate, error) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext != ".p12" && ext != ".pfx" {
+		if bytes.Contains(data, []byte("-----BEGIN")) {
+			pemBlock, err := https.FilterPEM(data, func(b *pem.Block) bool { return b.Type == "CERTIFICATE" })
+			if err != nil {
+				return nil, err
+			}
+			next, _ := pem.Decode(pemBlock)
+			if next == nil {
+				return nil, errors.New("no PEM-encoded certificate found")
+			}
+			return x509.ParseCertificate(next.Bytes)
+		}
+		if cert, err := x509.ParseCertificate(data); err == nil {
+			return cert, nil
+		}
+	}
+
+	blocks, err := pkcs12.ToPEM(data, "")
+	if errors.Is(err, pkcs12.ErrIncorrectPassword) {
+		if !isTerm(os.Stdin) || !isTerm(os.Stderr) {
+			return nil, errors.New("PKCS#12 bundle is password-protected")
+		}
+		fmt.Fprintf(os.Stderr, "Enter password for '%s': ", filename)
+		password, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr) // Add the newline again
+		if err != nil {
+			return nil, fmt.Errorf("failed to read password: %v", err)
+		}
+		blocks, err = pkcs12.ToPEM(data, string(password))
+		if errors.Is(err, pkcs12.ErrIncorrectPassword) {
+			return nil, errors.New("incorrect password")
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// A PKCS#12 bundle may contain the certificate chain.
+	// Hence, we return the first non-CA certificate.
+	var certs []*x509.Certificate
+	for _, block := range blocks {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		if !cert.IsCA {
+			return cert, nil
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("PKCS#12 bundle contains no certificate")
+	}
+	return certs[0], nil
+}
+
 const infoIdentityCmdUsage = `Usage:
     kes identity info [options] [<identity>]
 
@@ -363,7 +439,7 @@ func infoIdentityCmd(args []string) {
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(2)
+			cli.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes policy ls --help'", err)
 	}
@@ -392,10 +468,29 @@ func infoIdentityCmd(args []string) {
 
 	enclave := newEnclave(enclaveName, insecureSkipVerify)
 	if cmd.NArg() == 0 {
-		info, policy, err := enclave.DescribeSelf(ctx)
-		if err != nil {
+		type Policy struct {
+			Allow     []string  `json:"allow"`
+			Deny      []string  `json:"deny"`
+			CreatedAt time.Time `json:"created_at"`
+		}
+		type Response struct {
+			Identity  kes.Identity      `json:"identity"`
+			IsAdmin   bool              `json:"admin"`
+			Policy    string            `json:"policy_name"`
+			CreatedAt time.Time         `json:"created_at"`
+			CreatedBy kes.Identity      `json:"created_by"`
+			Labels    map[string]string `json:"labels"`
+
+			PolicyInfo Policy `json:"policy"`
+		}
+		var info Response
+		if err := send(ctx, enclave, http.MethodGet, "/v1/identity/self/describe", nil, nil, &info); err != nil {
+			if errors.Is(err, context.Canceled) {
+				cli.Exit(1)
+			}
 			cli.Fatal(err)
 		}
+		policy := info.PolicyInfo
 		year, month, day := info.CreatedAt.Date()
 		hour, min, sec := info.CreatedAt.Clock()
 
@@ -415,9 +510,10 @@ func infoIdentityCmd(args []string) {
 		if !info.CreatedBy.IsUnknown() {
 			fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Created By")), info.CreatedBy)
 		}
+		printLabels(faint, info.Labels)
 		if info.Policy != "" {
-			year, month, day := policy.Info.CreatedAt.Date()
-			hour, min, sec := policy.Info.CreatedAt.Clock()
+			year, month, day := policy.CreatedAt.Date()
+			hour, min, sec := policy.CreatedAt.Clock()
 
 			fmt.Println()
 			fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Policy")), policyStyle.Render(info.Policy))
@@ -439,8 +535,19 @@ func infoIdentityCmd(args []string) {
 			}
 		}
 	} else {
-		info, err := enclave.DescribeIdentity(ctx, kes.Identity(cmd.Arg(0)))
-		if err != nil {
+		type Response struct {
+			IsAdmin   bool              `json:"admin"`
+			Policy    string            `json:"policy"`
+			CreatedAt time.Time         `json:"created_at"`
+			CreatedBy kes.Identity      `json:"created_by"`
+			Labels    map[string]string `json:"labels"`
+		}
+		var info Response
+		identity := kes.Identity(cmd.Arg(0))
+		if err := send(ctx, enclave, http.MethodGet, "/v1/identity/describe/"+identity.String(), nil, nil, &info); err != nil {
+			if errors.Is(err, context.Canceled) {
+				cli.Exit(1)
+			}
 			cli.Fatal(err)
 		}
 		year, month, day := info.CreatedAt.Date()
@@ -448,7 +555,7 @@ func infoIdentityCmd(args []string) {
 
 		fmt.Println(
 			faint.Render(fmt.Sprintf("%-11s", "Identity")),
-			identityStyle.Render(info.Identity.String()),
+			identityStyle.Render(identity.String()),
 		)
 		if info.Policy != "" {
 			fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Policy")), policyStyle.Render(info.Policy))
@@ -465,6 +572,26 @@ func infoIdentityCmd(args []string) {
 		if !info.CreatedBy.IsUnknown() {
 			fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Created By")), info.CreatedBy)
 		}
+		printLabels(faint, info.Labels)
+	}
+}
+
+// printLabels prints the given identity or
+// enclave labels sorted by name.
+func printLabels(faint tui.Style, labels map[string]string) {
+	if len(labels) == 0 {
+		return
+	}
+	sortedLabels := make([]string, 0, len(labels))
+	for label, value := range labels {
+		sortedLabels = append(sortedLabels, label+"="+value)
+	}
+	sort.Strings(sortedLabels)
+
+	name := "Labels"
+	for _, label := range sortedLabels {
+		fmt.Println(faint.Render(fmt.Sprintf("%-11s", name)), label)
+		name = ""
 	}
 }
 
@@ -472,6 +599,8 @@ const lsIdentityCmdUsage = `Usage:
     kes identity ls [options] [<pattern>]
 
 Options:
+        --label <name=value> Only list identities with the given label. May
+                             be specified multiple times.
     -k, --insecure           Skip TLS certificate validation.
         --json               Print identities in JSON format.
         --color <when>       Specify when to use colored output. The automatic
@@ -486,6 +615,7 @@ Options:
 Examples:
     $ kes identity ls
     $ kes identity ls 'b804befd*'
+    $ kes identity ls --label team=storage
 `
 
 func lsIdentityCmd(args []string) {
@@ -495,16 +625,18 @@ func lsIdentityCmd(args []string) {
 	var (
 		jsonFlag           bool
 		colorFlag          colorOption
+		labelFlag          []string
 		insecureSkipVerify bool
 		enclaveName        string
 	)
 	cmd.BoolVar(&jsonFlag, "json", false, "Print identities in JSON format")
+	cmd.StringArrayVar(&labelFlag, "label", nil, "Only list identities with the given label")
 	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(2)
+			cli.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes identity ls --help'", err)
 	}
@@ -521,62 +653,309 @@ func lsIdentityCmd(args []string) {
 	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
 	defer cancelCtx()
 
+	query := url.Values{}
+	for _, label := range labelFlag {
+		if !strings.Contains(label, "=") {
+			cli.Fatalf("invalid label '%s': expected 'name=value'. See 'kes identity ls --help'", label)
+		}
+		query.Add("label", label)
+	}
+
 	enclave := newEnclave(enclaveName, insecureSkipVerify)
-	identities, err := enclave.ListIdentities(ctx, pattern)
+	resp, err := do(ctx, enclave, http.MethodGet, "/v1/identity/list/"+pattern, query, nil)
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
-			os.Exit(1)
+			cli.Exit(1)
 		}
 		cli.Fatalf("failed to list identities: %v", err)
 	}
-	defer identities.Close()
+	defer resp.Body.Close()
 
-	if jsonFlag {
-		if _, err = identities.WriteTo(os.Stdout); err != nil {
-			cli.Fatal(err)
-		}
-		if err = identities.Close(); err != nil {
-			cli.Fatal(err)
+	type Response struct {
+		Identity  kes.Identity `json:"identity"`
+		IsAdmin   bool         `json:"admin"`
+		Policy    string       `json:"policy"`
+		CreatedAt time.Time    `json:"created_at,omitempty"`
+		CreatedBy kes.Identity `json:"created_by,omitempty"`
+		ExpiresAt time.Time    `json:"expires_at,omitempty"`
+
+		Err string `json:"error,omitempty"`
+	}
+	var (
+		scanner     = bufio.NewScanner(resp.Body)
+		sortedInfos []Response
+	)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
 		}
-	} else {
-		sortedInfos, err := identities.Values(0)
-		if err != nil {
+
+		var info Response
+		if err = json.Unmarshal(line, &info); err != nil {
 			cli.Fatalf("failed to list identities: %v", err)
 		}
-		if len(sortedInfos) > 0 {
-			sort.Slice(sortedInfos, func(i, j int) bool {
-				return strings.Compare(sortedInfos[i].Policy, sortedInfos[j].Policy) < 0
-			})
-
-			headerStyle := tui.NewStyle()
-			dateStyle := tui.NewStyle()
-			policyStyle := tui.NewStyle()
-			if colorFlag.Colorize() {
-				const (
-					ColorDate   tui.Color = "#5f8700"
-					ColorPolicy tui.Color = "#2E42D1"
-				)
-				headerStyle = headerStyle.Underline(true).Bold(true)
-				dateStyle = dateStyle.Foreground(ColorDate)
-				policyStyle = policyStyle.Foreground(ColorPolicy)
+		if info.Err != "" {
+			cli.Fatalf("failed to list identities: %s", info.Err)
+		}
+		if jsonFlag {
+			os.Stdout.Write(line)
+			os.Stdout.Write([]byte{'\n'})
+			continue
+		}
+		sortedInfos = append(sortedInfos, info)
+	}
+	if err = scanner.Err(); err != nil {
+		cli.Fatalf("failed to list identities: %v", err)
+	}
+	if len(sortedInfos) == 0 {
+		return
+	}
+
+	sort.Slice(sortedInfos, func(i, j int) bool {
+		return strings.Compare(sortedInfos[i].Policy, sortedInfos[j].Policy) < 0
+	})
+
+	headerStyle := tui.NewStyle()
+	dateStyle := tui.NewStyle()
+	policyStyle := tui.NewStyle()
+	expiredStyle := tui.NewStyle()
+	if colorFlag.Colorize() {
+		const (
+			ColorDate    tui.Color = "#5f8700"
+			ColorPolicy  tui.Color = "#2E42D1"
+			ColorExpired tui.Color = "#d70000"
+		)
+		headerStyle = headerStyle.Underline(true).Bold(true)
+		dateStyle = dateStyle.Foreground(ColorDate)
+		policyStyle = policyStyle.Foreground(ColorPolicy)
+		expiredStyle = expiredStyle.Foreground(ColorExpired)
+	}
+
+	fmt.Printf("%s %s %s %s\n",
+		headerStyle.Render(fmt.Sprintf("%-19s", "Date Created")),
+		headerStyle.Render(fmt.Sprintf("%-64s", "Identity")),
+		headerStyle.Render(fmt.Sprintf("%-15s", "Policy")),
+		headerStyle.Render("Expires In"),
+	)
+	for _, info := range sortedInfos {
+		year, month, day := info.CreatedAt.Local().Date()
+		hour, min, sec := info.CreatedAt.Local().Clock()
+
+		lifetime := "never"
+		if !info.ExpiresAt.IsZero() {
+			if remaining := time.Until(info.ExpiresAt); remaining > 0 {
+				lifetime = remaining.Round(time.Second).String()
+			} else {
+				lifetime = expiredStyle.Render("expired")
 			}
+		}
+		fmt.Printf("%s %s %s %s\n",
+			dateStyle.Render(fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d", year, month, day, hour, min, sec)),
+			fmt.Sprintf("%-64s", info.Identity.String()),
+			policyStyle.Render(fmt.Sprintf("%-15s", info.Policy)),
+			lifetime,
+		)
+	}
+}
 
-			fmt.Printf("%s %s %s\n",
-				headerStyle.Render(fmt.Sprintf("%-19s", "Date Created")),
-				headerStyle.Render(fmt.Sprintf("%-64s", "Identity")),
-				headerStyle.Render("Policy"),
-			)
-			for _, info := range sortedInfos {
-				year, month, day := info.CreatedAt.Local().Date()
-				hour, min, sec := info.CreatedAt.Local().Clock()
-
-				fmt.Printf("%s %s %s\n",
-					dateStyle.Render(fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d", year, month, day, hour, min, sec)),
-					fmt.Sprintf("%-64s", info.Identity.String()),
-					policyStyle.Render(fmt.Sprintf("%-15s", info.Policy)),
-				)
+const addIdentityCmdUsage = `Usage:
+    kes identity add [options] <identity>...
+
+Adds one or multiple identities to an enclave and assigns them to
+a policy in one step. Unless a policy is specified, the identities
+are assigned to the enclave's default policy. Existing identities
+are not modified.
+
+Options:
+        --policy <name>      Assign the identities to the given policy
+                             instead of the enclave's default policy.
+        --ttl <duration>     Expire the identities after the given duration.
+        --expires-at <time>  Expire the identities at the given RFC 3339 time.
+        --label <name=value> Label the identities. May be specified multiple
+                             times.
+        --json               Print the identities in JSON format.
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes identity add 736bf58626441e3e134a2daf2e6a8441b40e1abc0eac510878168c8aac9f2b0b
+    $ kes identity add --policy my-policy --ttl 24h 736bf58626441e3e134a2daf2e6a8441b40e1abc0eac510878168c8aac9f2b0b
+`
+
+func addIdentityCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, addIdentityCmdUsage) }
+
+	var (
+		policy             string
+		ttl                time.Duration
+		expiresAt          string
+		labelFlag          []string
+		jsonFlag           bool
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.StringVar(&policy, "policy", "", "Assign the identities to the given policy")
+	cmd.DurationVar(&ttl, "ttl", 0, "Expire the identities after the given duration")
+	cmd.StringVar(&expiresAt, "expires-at", "", "Expire the identities at the given RFC 3339 time")
+	cmd.StringArrayVar(&labelFlag, "label", nil, "Label the identities")
+	cmd.BoolVar(&jsonFlag, "json", false, "Print the identities in JSON format")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes identity add --help'", err)
+	}
+	if cmd.NArg() == 0 {
+		cli.Fatal("no identity specified. See 'kes identity add --help'")
+	}
+	if ttl < 0 {
+		cli.Fatal("invalid TTL: TTL must not be negative. See 'kes identity add --help'")
+	}
+	if ttl > 0 && expiresAt != "" {
+		cli.Fatal("'--ttl' and '--expires-at' cannot be specified both. See 'kes identity add --help'")
+	}
+	labels := parseLabelFlag(labelFlag, "kes identity add")
+
+	type Request struct {
+		Policy    string            `json:"policy,omitempty"`
+		ExpiresAt string            `json:"expires_at,omitempty"`
+		TTL       string            `json:"ttl,omitempty"`
+		Labels    map[string]string `json:"labels,omitempty"`
+	}
+	type Response struct {
+		Identity  kes.Identity `json:"identity"`
+		Policy    string       `json:"policy"`
+		ExpiresAt time.Time    `json:"expires_at,omitempty"`
+	}
+	req := Request{
+		Policy:    policy,
+		ExpiresAt: expiresAt,
+		Labels:    labels,
+	}
+	if ttl > 0 {
+		req.TTL = ttl.String()
+	}
+
+	enclave := newEnclave(enclaveName, insecureSkipVerify)
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	encoder := json.NewEncoder(os.Stdout)
+	for _, identity := range cmd.Args() {
+		var resp Response
+		if err := send(ctx, enclave, http.MethodPost, "/v1/identity/create/"+identity, nil, req, &resp); err != nil {
+			if errors.Is(err, context.Canceled) {
+				cli.Exit(1)
+			}
+			cli.Fatalf("failed to add identity %q: %v", identity, err)
+		}
+		if jsonFlag {
+			if err := encoder.Encode(resp); err != nil {
+				cli.Fatal(err)
+			}
+			continue
+		}
+		fmt.Printf("%s assigned to policy '%s'\n", resp.Identity, resp.Policy)
+	}
+}
+
+const renewIdentityCmdUsage = `Usage:
+    kes identity renew [options] <identity>...
+
+Changes when one or multiple identities expire. The identities
+remain assigned to their policies. Expired identities can be
+renewed as well. An identity cannot renew itself.
+
+Either --ttl or --expires-at must be specified.
+
+Options:
+        --ttl <duration>     Expire the identities after the given duration.
+        --expires-at <time>  Expire the identities at the given RFC 3339 time.
+        --json               Print the new expiry in JSON format.
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes identity renew --ttl 24h 736bf58626441e3e134a2daf2e6a8441b40e1abc0eac510878168c8aac9f2b0b
+`
+
+func renewIdentityCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, renewIdentityCmdUsage) }
+
+	var (
+		ttl                time.Duration
+		expiresAt          string
+		jsonFlag           bool
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.DurationVar(&ttl, "ttl", 0, "Expire the identities after the given duration")
+	cmd.StringVar(&expiresAt, "expires-at", "", "Expire the identities at the given RFC 3339 time")
+	cmd.BoolVar(&jsonFlag, "json", false, "Print the new expiry in JSON format")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes identity renew --help'", err)
+	}
+	if cmd.NArg() == 0 {
+		cli.Fatal("no identity specified. See 'kes identity renew --help'")
+	}
+	if ttl < 0 {
+		cli.Fatal("invalid TTL: TTL must not be negative. See 'kes identity renew --help'")
+	}
+	if ttl > 0 && expiresAt != "" {
+		cli.Fatal("'--ttl' and '--expires-at' cannot be specified both. See 'kes identity renew --help'")
+	}
+	if ttl == 0 && expiresAt == "" {
+		cli.Fatal("no expiry specified: use '--ttl' or '--expires-at'. See 'kes identity renew --help'")
+	}
+
+	type Request struct {
+		ExpiresAt string `json:"expires_at,omitempty"`
+		TTL       string `json:"ttl,omitempty"`
+	}
+	type Response struct {
+		Identity  kes.Identity `json:"identity"`
+		Policy    string       `json:"policy"`
+		ExpiresAt time.Time    `json:"expires_at"`
+	}
+	req := Request{ExpiresAt: expiresAt}
+	if ttl > 0 {
+		req.TTL = ttl.String()
+	}
+
+	enclave := newEnclave(enclaveName, insecureSkipVerify)
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	encoder := json.NewEncoder(os.Stdout)
+	for _, identity := range cmd.Args() {
+		var resp Response
+		if err := send(ctx, enclave, http.MethodPost, "/v1/identity/renew/"+identity, nil, req, &resp); err != nil {
+			if errors.Is(err, context.Canceled) {
+				cli.Exit(1)
+			}
+			cli.Fatalf("failed to renew identity %q: %v", identity, err)
+		}
+		if jsonFlag {
+			if err := encoder.Encode(resp); err != nil {
+				cli.Fatal(err)
 			}
+			continue
 		}
+		fmt.Printf("%s expires at %s\n", resp.Identity, resp.ExpiresAt.Local().Format(time.RFC3339))
 	}
 }
 
@@ -605,7 +984,7 @@ func rmIdentityCmd(args []string) {
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(2)
+			cli.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes identity rm --help'", err)
 	}
@@ -620,7 +999,7 @@ func rmIdentityCmd(args []string) {
 	for _, identity := range cmd.Args() {
 		if err := enclave.DeleteIdentity(ctx, kes.Identity(identity)); err != nil {
 			if errors.Is(err, context.Canceled) {
-				os.Exit(1)
+				cli.Exit(1)
 			}
 			cli.Fatalf("failed to remove identity %q: %v", identity, err)
 		}
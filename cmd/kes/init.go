@@ -9,15 +9,16 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
-	tui "github.com/charmbracelet/lipgloss"
 	"github.com/minio/kes-go"
 	"github.com/minio/kes/internal/auth"
 	"github.com/minio/kes/internal/cli"
 	"github.com/minio/kes/internal/https"
 	"github.com/minio/kes/internal/sys"
 	"github.com/minio/kes/internal/sys/fs"
+	"github.com/minio/kes/internal/tui"
 	flag "github.com/spf13/pflag"
 	"golang.org/x/term"
 )
@@ -46,7 +47,7 @@ func initCmd(args []string) {
 	cmd.StringVar(&configFlag, "config", "", "Path to the initial configuration file")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(2)
+			cli.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes init --help'", err)
 	}
@@ -76,6 +77,9 @@ func initCmd(args []string) {
 	if config.System.Admin.Identity.Value().IsUnknown() {
 		cli.Fatal("invalid configuration: system identity cannot be empty")
 	}
+	if _, err = sys.ParseCompression(config.Compression.Value()); err != nil {
+		cli.Fatalf("invalid configuration: %v", err)
+	}
 	for enclaveName, enclave := range config.Enclave {
 		identities := map[kes.Identity]string{}
 		for policyName, policy := range enclave.Policy {
@@ -114,6 +118,39 @@ func initCmd(args []string) {
 	if _, err = https.CertificateFromFile(config.TLS.Certificate.Value(), config.TLS.PrivateKey.Value(), config.TLS.Password.Value()); err != nil {
 		cli.Fatalf("failed to load TLS certificate: %v", err)
 	}
+	var sni map[string]fs.SNIConfig
+	if len(config.TLS.SNI) > 0 {
+		sni = make(map[string]fs.SNIConfig, len(config.TLS.SNI))
+	}
+	for serverName, c := range config.TLS.SNI {
+		serverName = strings.ToLower(strings.TrimSpace(serverName))
+		if _, ok := sni[serverName]; ok {
+			cli.Fatalf("invalid SNI configuration: server name '%s' is defined multiple times", serverName)
+		}
+		if c.Enclave.Value() == "" {
+			cli.Fatalf("invalid SNI configuration for '%s': no enclave specified", serverName)
+		}
+		if (c.PrivateKey.Value() == "") != (c.Certificate.Value() == "") {
+			cli.Fatalf("invalid SNI configuration for '%s': TLS private key and certificate must be specified both", serverName)
+		}
+		if c.Certificate.Value() != "" {
+			if _, err = https.CertificateFromFile(c.Certificate.Value(), c.PrivateKey.Value(), c.Password.Value()); err != nil {
+				cli.Fatalf("failed to load TLS certificate for '%s': %v", serverName, err)
+			}
+		}
+		sni[serverName] = fs.SNIConfig{
+			Enclave:     c.Enclave,
+			PrivateKey:  c.PrivateKey,
+			Certificate: c.Certificate,
+			Password:    c.Password,
+		}
+	}
+
+	if config.Receipts.PrivateKey.Value() != "" {
+		if _, err = https.PrivateKeyFromFile(config.Receipts.PrivateKey.Value(), config.Receipts.Password.Value()); err != nil {
+			cli.Fatalf("failed to load receipt signing key: %v", err)
+		}
+	}
 
 	sealer, err := sys.SealFromEnvironment(config.Unseal.Environment.Name)
 	if err != nil {
@@ -125,6 +162,11 @@ func initCmd(args []string) {
 		Certificate:       config.TLS.Certificate,
 		Password:          config.TLS.Password,
 		VerifyClientCerts: config.TLS.Client.VerifyCerts,
+		APIKeys:           config.TLS.Client.APIKeys,
+		Compression:       config.Compression,
+		SNI:               sni,
+		ReceiptKey:        config.Receipts.PrivateKey,
+		ReceiptPassword:   config.Receipts.Password,
 	}
 	seal := &fs.SealConfig{
 		SysAdmin: config.System.Admin.Identity.Value(),
@@ -162,7 +204,7 @@ func initCmd(args []string) {
 				cli.Fatalf("failed to init enclave '%s': failed to create policy '%s': %v", name, policyName, err)
 			}
 			for _, identity := range policy.Identity {
-				if err = enc.AssignPolicy(context.Background(), policyName, identity.Value()); err != nil {
+				if err = enc.AssignPolicy(context.Background(), policyName, identity.Value(), time.Time{}, nil); err != nil {
 					cli.Fatalf("failed to init enclave '%s': failed to assign policy '%s' to identity '%v': %v", name, policyName, identity.Value(), err)
 				}
 			}
@@ -5,19 +5,29 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
-	tui "github.com/charmbracelet/lipgloss"
 	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/api"
 	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/internal/key"
+	"github.com/minio/kes/internal/tui"
 	flag "github.com/spf13/pflag"
 )
 
@@ -27,13 +37,27 @@ const keyCmdUsage = `Usage:
 Commands:
     create                   Create a new crypto key.
     import                   Import a crypto key.
+    export                   Export a crypto key wrapped for key escrow.
+    copy                     Copy or move a crypto key to another enclave.
+    claim                    Claim a pre-created crypto key from a key pool.
     info                     Get information about a crypto key. 
     ls                       List crypto keys.
     rm                       Delete a crypto key.
+    restore                  Restore a deleted crypto key.
+    purge                    Permanently delete a deleted crypto key.
+    expire                   Change when a crypto key expires.
+    tag                      Add or remove crypto key tags.
+    ceremony                 Create a crypto key from multiple custodians.
 
     encrypt                  Encrypt a message.
     decrypt                  Decrypt an encrypted message.
     dek                      Generate a new data encryption key.
+    hmac                     Compute the HMAC of a message.
+    derive                   Derive a key from a crypto key.
+
+    sign                     Sign a message.
+    verify                   Verify a message signature.
+    public                   Print the public key of an asymmetric key.
 
 Options:
     -h, --help               Print command line options.
@@ -44,20 +68,36 @@ func keyCmd(args []string) {
 	cmd.Usage = func() { fmt.Fprint(os.Stderr, keyCmdUsage) }
 
 	subCmds := commands{
-		"create": createKeyCmd,
-		"import": importKeyCmd,
-		"info":   describeKeyCmd,
-		"ls":     lsKeyCmd,
-		"rm":     rmKeyCmd,
+		"create":  createKeyCmd,
+		"import":  importKeyCmd,
+		"export":  exportKeyCmd,
+		"copy":    copyKeyCmd,
+		"claim":   claimKeyCmd,
+		"info":    describeKeyCmd,
+		"ls":      lsKeyCmd,
+		"rm":      rmKeyCmd,
+		"restore": restoreKeyCmd,
+		"recover": restoreKeyCmd,
+		"purge":   purgeKeyCmd,
+		"expire":  expireKeyCmd,
+		"tag":     tagKeyCmd,
+
+		"ceremony": ceremonyKeyCmd,
 
 		"encrypt": encryptKeyCmd,
 		"decrypt": decryptKeyCmd,
 		"dek":     dekCmd,
+		"hmac":    hmacKeyCmd,
+		"derive":  deriveKeyCmd,
+
+		"sign":   signKeyCmd,
+		"verify": verifyKeyCmd,
+		"public": publicKeyCmd,
 	}
 
 	if len(args) < 2 {
 		cmd.Usage()
-		os.Exit(2)
+		cli.Exit(2)
 	}
 	if cmd, ok := subCmds[args[1]]; ok {
 		cmd(args[1:])
@@ -66,7 +106,7 @@ func keyCmd(args []string) {
 
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(2)
+			cli.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes key --help'", err)
 	}
@@ -74,13 +114,28 @@ func keyCmd(args []string) {
 		cli.Fatalf("%q is not a key command. See 'kes key --help'", cmd.Arg(0))
 	}
 	cmd.Usage()
-	os.Exit(2)
+	cli.Exit(2)
 }
 
 const createKeyCmdUsage = `Usage:
     kes key create [options] <name>...
 
+Creates one or multiple keys. Multiple keys are created in batches
+with as few requests as possible. If some keys cannot be created,
+all remaining keys are still created and the failed ones reported.
+
 Options:
+    -t, --type <type>        Create an asymmetric key of the given type.
+                             Either: RSA-2048, RSA-3072, RSA-4096,
+                             ECDSA-P256 or ECDSA-P384.
+        --algorithm <alg>    Encrypt data with the given algorithm. Either:
+                             AES256-GCM_SHA256, XCHACHA20-POLY1305 or the
+                             nonce-misuse resistant AES256-GCM-SIV. By
+                             default, the server picks the algorithm that
+                             is fastest on its hardware.
+        --ttl <duration>     Expire the key after the given duration.
+        --expires-at <time>  Expire the key at the given RFC 3339 time.
+        --tag <name=value>   Tag the key. May be specified multiple times.
     -k, --insecure           Skip TLS certificate validation.
     -e, --enclave <name>     Operate within the specified enclave.
 
@@ -89,6 +144,11 @@ Options:
 Examples:
     $ kes key create my-key
     $ kes key create my-key1 my-key2
+    $ kes key create --type ECDSA-P256 my-signing-key
+    $ kes key create --algorithm XCHACHA20-POLY1305 my-key
+    $ kes key create --algorithm AES256-GCM-SIV my-key
+    $ kes key create --ttl 720h my-temp-key
+    $ kes key create --tag env=prod --tag tenant=acme my-key
 `
 
 func createKeyCmd(args []string) {
@@ -96,14 +156,24 @@ func createKeyCmd(args []string) {
 	cmd.Usage = func() { fmt.Fprint(os.Stderr, createKeyCmdUsage) }
 
 	var (
+		keyType            string
+		algorithm          string
+		ttl                time.Duration
+		expiresAt          string
+		tags               []string
 		insecureSkipVerify bool
 		enclaveName        string
 	)
+	cmd.StringVarP(&keyType, "type", "t", "", "Create an asymmetric key of the given type")
+	cmd.StringVar(&algorithm, "algorithm", "", "Encrypt data with the given algorithm")
+	cmd.DurationVar(&ttl, "ttl", 0, "Expire the key after the given duration")
+	cmd.StringVar(&expiresAt, "expires-at", "", "Expire the key at the given RFC 3339 time")
+	cmd.StringArrayVar(&tags, "tag", nil, "Tag the key")
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(2)
+			cli.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes key create --help'", err)
 	}
@@ -111,25 +181,72 @@ func createKeyCmd(args []string) {
 	if cmd.NArg() == 0 {
 		cli.Fatal("no key name specified. See 'kes key create --help'")
 	}
+	if keyType != "" && algorithm != "" {
+		cli.Fatal("'--type' and '--algorithm' cannot be specified both. See 'kes key create --help'")
+	}
+	if ttl < 0 {
+		cli.Fatal("invalid TTL: TTL must not be negative. See 'kes key create --help'")
+	}
+	if ttl > 0 && expiresAt != "" {
+		cli.Fatal("'--ttl' and '--expires-at' cannot be specified both. See 'kes key create --help'")
+	}
+	for _, tag := range tags {
+		if !strings.Contains(tag, "=") {
+			cli.Fatalf("invalid tag '%s': expected 'name=value'. See 'kes key create --help'", tag)
+		}
+	}
+
+	query := url.Values{}
+	if keyType != "" {
+		query.Set("type", keyType)
+	}
+	if algorithm != "" {
+		query.Set("algorithm", algorithm)
+	}
+	if ttl > 0 {
+		query.Set("ttl", ttl.String())
+	}
+	if expiresAt != "" {
+		query.Set("expires_at", expiresAt)
+	}
+	if len(tags) > 0 {
+		query["tag"] = tags
+	}
 
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
 	defer cancel()
 
 	enclave := newEnclave(enclaveName, insecureSkipVerify)
-	for _, name := range cmd.Args() {
-		if err := enclave.CreateKey(ctx, name); err != nil {
+	createKey := func(name string) error {
+		if len(query) == 0 {
+			return enclave.CreateKey(ctx, name)
+		}
+		return send(ctx, enclave, http.MethodPost, "/v1/key/create/"+name, query, nil, nil)
+	}
+	if cmd.NArg() == 1 {
+		if err := createKey(cmd.Arg(0)); err != nil {
 			if errors.Is(err, context.Canceled) {
-				os.Exit(1)
+				cli.Exit(1)
 			}
-			cli.Fatalf("failed to create key %q: %v", name, err)
+			cli.Fatalf("failed to create key %q: %v", cmd.Arg(0), err)
 		}
+		return
 	}
+	bulkKeyOp(ctx, enclave, http.MethodPost, "/v1/key/bulk/create/", query, cmd.Args(), "create", createKey)
 }
 
 const importKeyCmdUsage = `Usage:
-    kes key import [options] <name> [<key>]
+    kes key import [options] <name> <key>
+
+Imports a crypto key. The key format is detected automatically unless
+specified explicitly. If <key> is '-' the key is read from standard
+input.
 
 Options:
+    -f, --format <format>    Key format. Either: base64, hex, jwk or pkcs8.
+                             Symmetric keys must be 256 bits long. JWKs
+                             and PKCS #8 keys may contain an RSA or ECDSA
+                             private key.
     -k, --insecure           Skip TLS certificate validation.
     -e, --enclave <name>     Operate within the specified enclave.
 
@@ -137,6 +254,8 @@ Options:
 
 Examples:
     $ kes key import my-key-2 Xlnr/nOgAWE5cA7GAsl3L2goCvmfs6KE0gNgB1T93wE=
+    $ kes key import --format hex my-key-3 5e59ebfe73a00161397...
+    $ kes key import my-signing-key - < private.pem
 `
 
 func importKeyCmd(args []string) {
@@ -144,14 +263,16 @@ func importKeyCmd(args []string) {
 	cmd.Usage = func() { fmt.Fprint(os.Stderr, importKeyCmdUsage) }
 
 	var (
+		formatFlag         string
 		insecureSkipVerify bool
 		enclaveName        string
 	)
+	cmd.StringVarP(&formatFlag, "format", "f", "", "Key format")
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(2)
+			cli.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes key import --help'", err)
 	}
@@ -165,7 +286,16 @@ func importKeyCmd(args []string) {
 		cli.Fatal("too many arguments. See 'kes key import --help'")
 	}
 	name := cmd.Arg(0)
-	key, err := base64.StdEncoding.DecodeString(cmd.Arg(1))
+	encoded := readMessage(cmd.Arg(1))
+
+	format := key.DetectFormat(encoded)
+	if formatFlag != "" {
+		var err error
+		if format, err = key.ParseFormat(formatFlag); err != nil {
+			cli.Fatalf("invalid key format: %v. See 'kes key import --help'", err)
+		}
+	}
+	material, err := key.DecodeMaterial(format, encoded)
 	if err != nil {
 		cli.Fatalf("invalid key: %v. See 'kes key import --help'", err)
 	}
@@ -174,14 +304,281 @@ func importKeyCmd(args []string) {
 	defer cancel()
 
 	enclave := newEnclave(enclaveName, insecureSkipVerify)
-	if err = enclave.ImportKey(ctx, name, key); err != nil {
+	if material.Type == key.Symmetric && material.Algorithm == kes.KeyAlgorithmUndefined {
+		err = enclave.ImportKey(ctx, name, material.Bytes)
+	} else {
+		type Request struct {
+			Bytes     []byte        `json:"bytes"`
+			Type      key.Type      `json:"type,omitempty"`
+			Algorithm key.Algorithm `json:"algorithm,omitempty"`
+		}
+		err = send(ctx, enclave, http.MethodPost, "/v1/key/import/"+name, nil, Request{
+			Bytes:     material.Bytes,
+			Type:      material.Type,
+			Algorithm: key.Algorithm(material.Algorithm),
+		}, nil)
+	}
+	if err != nil {
 		if errors.Is(err, context.Canceled) {
-			os.Exit(1)
+			cli.Exit(1)
 		}
 		cli.Fatalf("failed to import %q: %v", name, err)
 	}
 }
 
+const exportKeyCmdUsage = `Usage:
+    kes key export [options] --wrap-with <public-key> <name>
+
+Exports a crypto key for key escrow. The key material is encrypted
+with a random AES-256-GCM key that is encrypted with RSA-OAEP-SHA256
+under the given RSA public key. Only the holder of the corresponding
+RSA private key can decrypt the exported key material.
+
+The exported key is printed in JSON format.
+
+Options:
+        --wrap-with <file>   PEM-encoded RSA public key, at least 2048 bits.
+    -o, --output <file>      Write the exported key to the given file.
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes key export --wrap-with escrow.pem my-key
+    $ kes key export --wrap-with escrow.pem -o my-key.json my-key
+`
+
+func exportKeyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, exportKeyCmdUsage) }
+
+	var (
+		wrapWith           string
+		outputFile         string
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.StringVar(&wrapWith, "wrap-with", "", "PEM-encoded RSA public key")
+	cmd.StringVarP(&outputFile, "output", "o", "", "Write the exported key to the given file")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes key export --help'", err)
+	}
+
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no key name specified. See 'kes key export --help'")
+	case cmd.NArg() > 1:
+		cli.Fatal("too many arguments. See 'kes key export --help'")
+	}
+	if wrapWith == "" {
+		cli.Fatal("no RSA public key specified. See 'kes key export --help'")
+	}
+	publicKey, err := os.ReadFile(wrapWith)
+	if err != nil {
+		cli.Fatal(err)
+	}
+	if _, err = key.ParseRSAPublicKey(publicKey); err != nil {
+		cli.Fatalf("failed to read '%s': %v", wrapWith, err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	type Request struct {
+		PublicKey string `json:"public_key"`
+	}
+	var (
+		name     = cmd.Arg(0)
+		enclave  = newEnclave(enclaveName, insecureSkipVerify)
+		exported json.RawMessage
+	)
+	err = send(ctx, enclave, http.MethodPost, "/v1/key/export/"+name, nil, Request{
+		PublicKey: string(publicKey),
+	}, &exported)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			cli.Exit(1)
+		}
+		cli.Fatalf("failed to export %q: %v", name, err)
+	}
+
+	var buffer bytes.Buffer
+	if err = json.Indent(&buffer, exported, "", "  "); err != nil {
+		cli.Fatalf("failed to export %q: %v", name, err)
+	}
+	buffer.WriteByte('\n')
+	if outputFile == "" {
+		os.Stdout.Write(buffer.Bytes())
+		return
+	}
+	if err = os.WriteFile(outputFile, buffer.Bytes(), 0o600); err != nil {
+		cli.Fatal(err)
+	}
+}
+
+const copyKeyCmdUsage = `Usage:
+    kes key copy [options] --to-enclave <name> <key> [<new-name>]
+
+Copies a crypto key from one enclave to another enclave on the same
+server. The key material never leaves the server. Optionally, the
+key can be stored under a new name within the destination enclave.
+
+With --move, the key is deleted from the source enclave once it has
+been copied.
+
+The request must be allowed by the policies of both enclaves.
+
+Options:
+        --from-enclave <name>  The enclave containing the key.
+        --to-enclave <name>    The enclave to copy the key to.
+        --move                 Delete the key from the source enclave.
+    -k, --insecure             Skip TLS certificate validation.
+
+    -h, --help                 Print command line options.
+
+Examples:
+    $ kes key copy --from-enclave tenant-1 --to-enclave tenant-2 my-key
+    $ kes key copy --from-enclave tenant-1 --to-enclave tenant-2 --move my-key
+`
+
+func copyKeyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, copyKeyCmdUsage) }
+
+	var (
+		fromEnclave        string
+		toEnclave          string
+		moveFlag           bool
+		insecureSkipVerify bool
+	)
+	cmd.StringVar(&fromEnclave, "from-enclave", "", "The enclave containing the key")
+	cmd.StringVar(&toEnclave, "to-enclave", "", "The enclave to copy the key to")
+	cmd.BoolVar(&moveFlag, "move", false, "Delete the key from the source enclave")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes key copy --help'", err)
+	}
+
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no key name specified. See 'kes key copy --help'")
+	case cmd.NArg() > 2:
+		cli.Fatal("too many arguments. See 'kes key copy --help'")
+	}
+	if !cmd.Changed("to-enclave") {
+		cli.Fatal("no destination enclave specified. See 'kes key copy --help'")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	type Request struct {
+		Enclave string `json:"enclave"`
+		Name    string `json:"name,omitempty"`
+	}
+	var (
+		name    = cmd.Arg(0)
+		enclave = newEnclave(fromEnclave, insecureSkipVerify)
+		apiPath = "/v1/key/copy/"
+	)
+	if moveFlag {
+		apiPath = "/v1/key/move/"
+	}
+	err := send(ctx, enclave, http.MethodPost, apiPath+name, nil, Request{
+		Enclave: toEnclave,
+		Name:    cmd.Arg(1),
+	}, nil)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			cli.Exit(1)
+		}
+		if moveFlag {
+			cli.Fatalf("failed to move %q: %v", name, err)
+		}
+		cli.Fatalf("failed to copy %q: %v", name, err)
+	}
+}
+
+const claimKeyCmdUsage = `Usage:
+    kes key claim [options] <prefix>
+
+Claims a crypto key from the key pool with the given prefix and
+prints its name. The KES server pre-creates the keys of a key
+pool in the background. Each key is handed out at most once.
+
+Key pools are only supported by KES edge servers.
+
+Options:
+        --json               Print the key name in JSON format.
+    -k, --insecure           Skip TLS certificate validation.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes key claim minio-
+`
+
+func claimKeyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, claimKeyCmdUsage) }
+
+	var (
+		jsonFlag           bool
+		insecureSkipVerify bool
+	)
+	cmd.BoolVar(&jsonFlag, "json", false, "Print the key name in JSON format")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes key claim --help'", err)
+	}
+
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no key pool specified. See 'kes key claim --help'")
+	case cmd.NArg() > 1:
+		cli.Fatal("too many arguments. See 'kes key claim --help'")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	type Response struct {
+		Name string `json:"name"`
+	}
+	var (
+		prefix = cmd.Arg(0)
+		client = newClient(insecureSkipVerify)
+		resp   Response
+	)
+	if err := send(ctx, client.Enclave(""), http.MethodPost, "/v1/key/claim/"+prefix, nil, nil, &resp); err != nil {
+		if errors.Is(err, context.Canceled) {
+			cli.Exit(1)
+		}
+		cli.Fatalf("failed to claim key from pool %q: %v", prefix, err)
+	}
+	if jsonFlag {
+		encoder := json.NewEncoder(os.Stdout)
+		if isTerm(os.Stdout) {
+			encoder.SetIndent("", "  ")
+		}
+		encoder.Encode(resp)
+		return
+	}
+	fmt.Println(resp.Name)
+}
+
 const describeKeyCmdUsage = `Usage:
     kes key info [options] <name>
 
@@ -194,11 +591,14 @@ Options:
                              the output goes to a pipe.
                              Possible values: *auto*, never, always.
     -e, --enclave <name>     Operate within the specified enclave.
+        --usage              Show how often the key has been used since
+                             the server started.
 
     -h, --help               Print command line options.
 
 Examples:
     $ kes key info my-key
+    $ kes key info --usage my-key
 `
 
 func describeKeyCmd(args []string) {
@@ -210,14 +610,16 @@ func describeKeyCmd(args []string) {
 		colorFlag          colorOption
 		insecureSkipVerify bool
 		enclaveName        string
+		usageFlag          bool
 	)
 	cmd.BoolVar(&jsonFlag, "json", false, "Print identities in JSON format")
 	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	cmd.BoolVar(&usageFlag, "usage", false, "Show key usage counters")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(2)
+			cli.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes key info --help'", err)
 	}
@@ -232,15 +634,39 @@ func describeKeyCmd(args []string) {
 	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
 	defer cancelCtx()
 
+	type KeyUsage struct {
+		Encrypt  uint64     `json:"encrypt"`
+		Decrypt  uint64     `json:"decrypt"`
+		Generate uint64     `json:"generate"`
+		LastUsed *time.Time `json:"last_used,omitempty"`
+		Since    time.Time  `json:"since"`
+	}
+	type KeyInfo struct {
+		Name      string            `json:"name"`
+		ID        string            `json:"id,omitempty"`
+		Type      string            `json:"type,omitempty"`
+		Algorithm key.Algorithm     `json:"algorithm,omitempty"`
+		CreatedAt time.Time         `json:"created_at,omitempty"`
+		CreatedBy kes.Identity      `json:"created_by,omitempty"`
+		ExpiresAt time.Time         `json:"expires_at,omitempty"`
+		Tags      map[string]string `json:"tags,omitempty"`
+		Usage     *KeyUsage         `json:"usage,omitempty"`
+	}
+
 	name := cmd.Arg(0)
 	enclave := newEnclave(enclaveName, insecureSkipVerify)
-	info, err := enclave.DescribeKey(ctx, name)
+
+	var info KeyInfo
+	err := send(ctx, enclave, http.MethodGet, "/v1/key/describe/"+name, nil, nil, &info)
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
-			os.Exit(1)
+			cli.Exit(1)
 		}
 		cli.Fatalf("failed to describe keys: %v", err)
 	}
+	if !usageFlag {
+		info.Usage = nil
+	}
 	if jsonFlag {
 		if err = json.NewEncoder(os.Stdout).Encode(info); err != nil {
 			cli.Fatalf("failed to describe keys: %v", err)
@@ -267,7 +693,13 @@ func describeKeyCmd(args []string) {
 			info.ID,
 		)
 	}
-	if info.Algorithm != kes.KeyAlgorithmUndefined {
+	if info.Type != "" {
+		fmt.Println(
+			faint.Render(fmt.Sprintf("%-11s", "Type")),
+			info.Type,
+		)
+	}
+	if kes.KeyAlgorithm(info.Algorithm) != kes.KeyAlgorithmUndefined {
 		fmt.Println(
 			faint.Render(fmt.Sprintf("%-11s", "Algorithm")),
 			info.Algorithm,
@@ -288,26 +720,74 @@ func describeKeyCmd(args []string) {
 			info.CreatedBy,
 		)
 	}
+	if !info.ExpiresAt.IsZero() {
+		year, month, day := info.ExpiresAt.Date()
+		hour, min, sec := info.ExpiresAt.Clock()
+		fmt.Println(
+			faint.Render(fmt.Sprintf("%-11s", "Expires At")),
+			fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d", year, month, day, hour, min, sec),
+		)
+	}
+	if len(info.Tags) > 0 {
+		tags := make([]string, 0, len(info.Tags))
+		for tag, value := range info.Tags {
+			tags = append(tags, tag+"="+value)
+		}
+		sort.Strings(tags)
+
+		label := "Tags"
+		for _, tag := range tags {
+			fmt.Println(faint.Render(fmt.Sprintf("%-11s", label)), tag)
+			label = ""
+		}
+	}
+	if usageFlag {
+		if info.Usage == nil {
+			cli.Fatal("server does not report key usage")
+		}
+		lastUsed := "never"
+		if info.Usage.LastUsed != nil {
+			lastUsed = info.Usage.LastUsed.Local().Format("2006-01-02 15:04:05")
+		}
+		fmt.Println()
+		fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Encrypt")), info.Usage.Encrypt)
+		fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Decrypt")), info.Usage.Decrypt)
+		fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Generate")), info.Usage.Generate)
+		fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Last Used")), lastUsed)
+		fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Since")), info.Usage.Since.Local().Format("2006-01-02 15:04:05"))
+	}
 }
 
 const lsKeyCmdUsage = `Usage:
     kes key ls [options] [<pattern>]
 
 Options:
-    -k, --insecure           Skip TLS certificate validation.
-        --json               Print keys in JSON format. 
-        --color <when>       Specify when to use colored output. The automatic
-                             mode only enables colors if an interactive terminal
-                             is detected - colors are automatically disabled if
-                             the output goes to a pipe.
-                             Possible values: *auto*, never, always.
-    -e, --enclave <name>     Operate within the specified enclave.
-
-    -h, --help               Print command line options.
+    -k, --insecure              Skip TLS certificate validation.
+        --json                  Print keys in JSON format. 
+        --color <when>          Specify when to use colored output. The automatic
+                                mode only enables colors if an interactive terminal
+                                is detected - colors are automatically disabled if
+                                the output goes to a pipe.
+                                Possible values: *auto*, never, always.
+    -e, --enclave <name>        Operate within the specified enclave.
+
+        --limit <n>             List at most <n> keys. If more keys are available,
+                                a continue token for the next page is printed.
+        --continue-token <t>    Continue listing after the previous page.
+        --sort <order>          Sort keys by name.
+                                Possible values: *asc*, desc.
+        --tag <name=value>      Only list keys with the given tag. May be
+                                specified multiple times.
+
+    -h, --help                  Print command line options.
 
 Examples:
     $ kes key ls
     $ kes key ls 'my-key*'
+    $ kes key ls 'payments/'
+    $ kes key ls --limit 1000
+    $ kes key ls --limit 1000 --continue-token bXkta2V5LTAwOTk5
+    $ kes key ls --tag env=prod
 `
 
 func lsKeyCmd(args []string) {
@@ -319,14 +799,22 @@ func lsKeyCmd(args []string) {
 		colorFlag          colorOption
 		insecureSkipVerify bool
 		enclaveName        string
+		limitFlag          int
+		continueFlag       string
+		sortFlag           string
+		tagFlag            []string
 	)
 	cmd.BoolVar(&jsonFlag, "json", false, "Print identities in JSON format")
 	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	cmd.IntVar(&limitFlag, "limit", 0, "List at most <n> keys")
+	cmd.StringVar(&continueFlag, "continue-token", "", "Continue listing after the previous page")
+	cmd.StringVar(&sortFlag, "sort", "asc", "Sort keys by name")
+	cmd.StringArrayVar(&tagFlag, "tag", nil, "Only list keys with the given tag")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(2)
+			cli.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes key ls --help'", err)
 	}
@@ -334,78 +822,127 @@ func lsKeyCmd(args []string) {
 	if cmd.NArg() > 1 {
 		cli.Fatal("too many arguments. See 'kes key ls --help'")
 	}
+	if limitFlag < 0 {
+		cli.Fatal("invalid --limit: must not be negative. See 'kes key ls --help'")
+	}
+	if sortFlag != "asc" && sortFlag != "desc" {
+		cli.Fatalf("invalid --sort '%s': must be either 'asc' or 'desc'. See 'kes key ls --help'", sortFlag)
+	}
 
 	pattern := "*"
 	if cmd.NArg() == 1 {
 		pattern = cmd.Arg(0)
 	}
 
+	query := url.Values{}
+	query.Set("sort", sortFlag)
+	if limitFlag > 0 {
+		query.Set("limit", strconv.Itoa(limitFlag))
+	}
+	if continueFlag != "" {
+		query.Set("continue", continueFlag)
+	}
+	for _, tag := range tagFlag {
+		if !strings.Contains(tag, "=") {
+			cli.Fatalf("invalid tag '%s': expected 'name=value'. See 'kes key ls --help'", tag)
+		}
+		query.Add("tag", tag)
+	}
+
 	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
 	defer cancelCtx()
 
 	enclave := newEnclave(enclaveName, insecureSkipVerify)
-	iterator, err := enclave.ListKeys(ctx, pattern)
+	resp, err := do(ctx, enclave, http.MethodGet, "/v1/key/list/"+pattern, query, nil)
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
-			os.Exit(1)
+			cli.Exit(1)
 		}
 		cli.Fatalf("failed to list keys: %v", err)
 	}
-	defer iterator.Close()
+	defer resp.Body.Close()
 
-	if jsonFlag {
-		if _, err = iterator.WriteTo(os.Stdout); err != nil {
-			cli.Fatal(err)
-		}
-		if err = iterator.Close(); err != nil {
-			cli.Fatal(err)
+	headerStyle := tui.NewStyle()
+	dateStyle := tui.NewStyle()
+	if colorFlag.Colorize() {
+		const ColorDate tui.Color = "#5f8700"
+		headerStyle = headerStyle.Underline(true).Bold(true)
+		dateStyle = dateStyle.Foreground(ColorDate)
+	}
+
+	// The server sends one key per line. Print each key as
+	// soon as it arrives instead of buffering the entire list.
+	type Response struct {
+		Name      string        `json:"name"`
+		ID        string        `json:"id,omitempty"`
+		Algorithm key.Algorithm `json:"algorithm,omitempty"`
+		CreatedAt time.Time     `json:"created_at,omitempty"`
+		CreatedBy kes.Identity  `json:"created_by,omitempty"`
+
+		Err string `json:"error,omitempty"`
+	}
+	var (
+		scanner    = bufio.NewScanner(resp.Body)
+		hasPrinted bool
+	)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
 		}
-	} else {
-		keys, err := iterator.Values(0)
-		if err != nil {
+
+		var key Response
+		if err = json.Unmarshal(line, &key); err != nil {
 			cli.Fatalf("failed to list keys: %v", err)
 		}
-		if err = iterator.Close(); err != nil {
-			cli.Fatalf("failed to list keys: %v", err)
+		if key.Err != "" {
+			cli.Fatalf("failed to list keys: %s", key.Err)
 		}
 
-		if len(keys) > 0 {
-			sort.Slice(keys, func(i, j int) bool {
-				return strings.Compare(keys[i].Name, keys[j].Name) < 0
-			})
-
-			headerStyle := tui.NewStyle()
-			dateStyle := tui.NewStyle()
-			if colorFlag.Colorize() {
-				const ColorDate tui.Color = "#5f8700"
-				headerStyle = headerStyle.Underline(true).Bold(true)
-				dateStyle = dateStyle.Foreground(ColorDate)
-			}
-
+		if jsonFlag {
+			os.Stdout.Write(line)
+			os.Stdout.Write([]byte{'\n'})
+			continue
+		}
+		if !hasPrinted {
+			hasPrinted = true
 			fmt.Println(
 				headerStyle.Render(fmt.Sprintf("%-19s", "Date Created")),
 				headerStyle.Render("Key"),
 			)
-			for _, key := range keys {
-				var date string
-				if key.CreatedAt.IsZero() {
-					date = fmt.Sprintf("%5s%s%5s", " ", "<unknown>", " ")
-				} else {
-					year, month, day := key.CreatedAt.Local().Date()
-					hour, min, sec := key.CreatedAt.Local().Clock()
-					date = fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d", year, month, day, hour, min, sec)
-				}
-				fmt.Printf("%s %s\n", dateStyle.Render(date), key.Name)
-			}
 		}
+		var date string
+		if key.CreatedAt.IsZero() {
+			date = fmt.Sprintf("%5s%s%5s", " ", "<unknown>", " ")
+		} else {
+			year, month, day := key.CreatedAt.Local().Date()
+			hour, min, sec := key.CreatedAt.Local().Clock()
+			date = fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d", year, month, day, hour, min, sec)
+		}
+		fmt.Printf("%s %s\n", dateStyle.Render(date), key.Name)
+	}
+	if err = scanner.Err(); err != nil {
+		cli.Fatalf("failed to list keys: %v", err)
+	}
 
+	if token := resp.Header.Get(api.ContinueTokenHeader); token != "" {
+		fmt.Fprintf(os.Stderr, "\nMore keys available. Use '--continue-token %s' to list the next page.\n", token)
 	}
 }
 
 const rmKeyCmdUsage = `Usage:
     kes key rm [options] <name>...
 
+Removes one or multiple keys. Multiple keys are removed in batches
+with as few requests as possible. If some keys cannot be removed,
+all remaining keys are still removed and the failed ones reported.
+
+If the enclave retains deleted keys, removed keys are moved to the
+key trash. They can be restored with 'kes key restore' until they
+get purged.
+
 Options:
+        --purge              Permanently delete the keys from the key trash.
     -k, --insecure           Skip X.509 certificate validation during TLS handshake.
     -e, --enclave <name>     Operate within the specified enclave.
 
@@ -414,6 +951,7 @@ Options:
 Examples:
     $ kes key rm my-key
     $ kes key rm my-key1 my-key2
+    $ kes key rm --purge my-key
 `
 
 func rmKeyCmd(args []string) {
@@ -421,14 +959,16 @@ func rmKeyCmd(args []string) {
 	cmd.Usage = func() { fmt.Fprint(os.Stderr, rmKeyCmdUsage) }
 
 	var (
+		purgeFlag          bool
 		insecureSkipVerify bool
 		enclaveName        string
 	)
+	cmd.BoolVar(&purgeFlag, "purge", false, "Permanently delete the keys from the key trash")
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(2)
+			cli.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes key rm --help'", err)
 	}
@@ -440,66 +980,734 @@ func rmKeyCmd(args []string) {
 	defer cancelCtx()
 
 	enclave := newEnclave(enclaveName, insecureSkipVerify)
-	for _, name := range cmd.Args() {
-		if err := enclave.DeleteKey(ctx, name); err != nil {
+	if purgeFlag {
+		for _, name := range cmd.Args() {
+			if err := send(ctx, enclave, http.MethodDelete, "/v1/key/purge/"+name, nil, nil, nil); err != nil {
+				if errors.Is(err, context.Canceled) {
+					cli.Exit(1)
+				}
+				cli.Fatalf("failed to purge key %q: %v", name, err)
+			}
+		}
+		return
+	}
+	deleteKey := func(name string) error { return enclave.DeleteKey(ctx, name) }
+	if cmd.NArg() == 1 {
+		if err := deleteKey(cmd.Arg(0)); err != nil {
 			if errors.Is(err, context.Canceled) {
-				os.Exit(1)
+				cli.Exit(1)
 			}
-			cli.Fatalf("failed to remove key %q: %v", name, err)
+			cli.Fatalf("failed to remove key %q: %v", cmd.Arg(0), err)
 		}
+		return
 	}
+	bulkKeyOp(ctx, enclave, http.MethodDelete, "/v1/key/bulk/delete/", nil, cmd.Args(), "remove", deleteKey)
 }
 
-const encryptKeyCmdUsage = `Usage:
-    kes key encrypt [options] <name> <message>
+const restoreKeyCmdUsage = `Usage:
+    kes key restore [options] <name>...
+    kes key restore [options] --list [<pattern>]
+
+Restores one or multiple deleted keys from the key trash of an
+enclave. Deleted keys can only be restored if the enclave retains
+deleted keys and the key has not been purged yet.
+
+When connected to a KES edge server, restores keys that have been
+deleted softly by the keystore backend, like Azure KeyVault or AWS
+SecretsManager, and have not been purged yet.
+
+'kes key recover' is an alias for 'kes key restore'.
 
 Options:
+    -l, --list               List the keys in the key trash.
+        --json               Print deleted keys in JSON format.
+        --color <when>       Specify when to use colored output. The automatic
+                             mode only enables colors if an interactive terminal
+                             is detected - colors are automatically disabled if
+                             the output goes to a pipe.
+                             Possible values: *auto*, never, always.
     -k, --insecure           Skip TLS certificate validation.
     -e, --enclave <name>     Operate within the specified enclave.
 
     -h, --help               Print command line options.
 
 Examples:
-    $ kes key encrypt my-key "Hello World"
+    $ kes key restore my-key
+    $ kes key restore --list 'my-key*'
 `
 
-func encryptKeyCmd(args []string) {
+func restoreKeyCmd(args []string) {
 	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
-	cmd.Usage = func() { fmt.Fprint(os.Stderr, encryptKeyCmdUsage) }
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, restoreKeyCmdUsage) }
 
 	var (
+		listFlag           bool
+		jsonFlag           bool
+		colorFlag          colorOption
 		insecureSkipVerify bool
 		enclaveName        string
 	)
+	cmd.BoolVarP(&listFlag, "list", "l", false, "List the keys in the key trash")
+	cmd.BoolVar(&jsonFlag, "json", false, "Print deleted keys in JSON format")
+	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(2)
+			cli.Exit(2)
 		}
-		cli.Fatalf("%v. See 'kes key encrypt --help'", err)
+		cli.Fatalf("%v. See 'kes key restore --help'", err)
 	}
-
 	switch {
-	case cmd.NArg() == 0:
-		cli.Fatal("no key name specified. See 'kes key encrypt --help'")
-	case cmd.NArg() == 1:
-		cli.Fatal("no message specified. See 'kes key encrypt --help'")
-	case cmd.NArg() > 2:
-		cli.Fatal("too many arguments. See 'kes key encrypt --help'")
+	case listFlag && cmd.NArg() > 1:
+		cli.Fatal("too many arguments. See 'kes key restore --help'")
+	case !listFlag && cmd.NArg() == 0:
+		cli.Fatal("no key name specified. See 'kes key restore --help'")
+	}
+
+	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancelCtx()
+
+	enclave := newEnclave(enclaveName, insecureSkipVerify)
+	if !listFlag {
+		for _, name := range cmd.Args() {
+			if err := send(ctx, enclave, http.MethodPost, "/v1/key/restore/"+name, nil, nil, nil); err != nil {
+				if errors.Is(err, context.Canceled) {
+					cli.Exit(1)
+				}
+				cli.Fatalf("failed to restore key %q: %v", name, err)
+			}
+		}
+		return
+	}
+
+	pattern := "*"
+	if cmd.NArg() == 1 {
+		pattern = cmd.Arg(0)
+	}
+	resp, err := do(ctx, enclave, http.MethodGet, "/v1/key/deleted/"+pattern, nil, nil)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			cli.Exit(1)
+		}
+		cli.Fatalf("failed to list deleted keys: %v", err)
+	}
+	defer resp.Body.Close()
+
+	headerStyle := tui.NewStyle()
+	dateStyle := tui.NewStyle()
+	if colorFlag.Colorize() {
+		const ColorDate tui.Color = "#5f8700"
+		headerStyle = headerStyle.Underline(true).Bold(true)
+		dateStyle = dateStyle.Foreground(ColorDate)
+	}
+	formatDate := func(t time.Time) string {
+		if t.IsZero() { // The keystore may not report when a key gets purged
+			return fmt.Sprintf("%-19s", "-")
+		}
+		year, month, day := t.Local().Date()
+		hour, min, sec := t.Local().Clock()
+		return fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d", year, month, day, hour, min, sec)
+	}
+
+	type Response struct {
+		Name      string    `json:"name"`
+		ID        string    `json:"id,omitempty"`
+		DeletedAt time.Time `json:"deleted_at,omitempty"`
+		PurgeAt   time.Time `json:"purge_at,omitempty"`
+
+		Err string `json:"error,omitempty"`
+	}
+	var (
+		scanner    = bufio.NewScanner(resp.Body)
+		hasPrinted bool
+	)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var key Response
+		if err = json.Unmarshal(line, &key); err != nil {
+			cli.Fatalf("failed to list deleted keys: %v", err)
+		}
+		if key.Err != "" {
+			cli.Fatalf("failed to list deleted keys: %s", key.Err)
+		}
+
+		if jsonFlag {
+			os.Stdout.Write(line)
+			os.Stdout.Write([]byte{'\n'})
+			continue
+		}
+		if !hasPrinted {
+			hasPrinted = true
+			fmt.Println(
+				headerStyle.Render(fmt.Sprintf("%-19s", "Date Deleted")),
+				headerStyle.Render(fmt.Sprintf("%-19s", "Purged At")),
+				headerStyle.Render("Key"),
+			)
+		}
+		fmt.Printf("%s %s %s\n", dateStyle.Render(formatDate(key.DeletedAt)), dateStyle.Render(formatDate(key.PurgeAt)), key.Name)
+	}
+	if err = scanner.Err(); err != nil {
+		cli.Fatalf("failed to list deleted keys: %v", err)
+	}
+}
+
+const purgeKeyCmdUsage = `Usage:
+    kes key purge [options] <name>...
+
+Permanently deletes one or multiple deleted keys from the key trash
+of an enclave. Purged keys cannot be restored.
+
+When connected to a KES edge server, purges keys that have been
+deleted softly by the keystore backend, like Azure KeyVault or AWS
+SecretsManager. A key name cannot be used again until the deleted
+key has been either restored or purged.
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes key purge my-key
+    $ kes key purge my-key1 my-key2
+`
+
+func purgeKeyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, purgeKeyCmdUsage) }
+
+	var (
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes key purge --help'", err)
+	}
+	if cmd.NArg() == 0 {
+		cli.Fatal("no key name specified. See 'kes key purge --help'")
+	}
+
+	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancelCtx()
+
+	enclave := newEnclave(enclaveName, insecureSkipVerify)
+	for _, name := range cmd.Args() {
+		if err := send(ctx, enclave, http.MethodDelete, "/v1/key/purge/"+name, nil, nil, nil); err != nil {
+			if errors.Is(err, context.Canceled) {
+				cli.Exit(1)
+			}
+			cli.Fatalf("failed to purge key %q: %v", name, err)
+		}
+	}
+}
+
+// bulkKeyOp creates or removes all named keys using the bulk
+// key API at apiPath. It sends at most maxBulkKeys names per
+// request and falls back to calling fn for each key if the
+// server does not implement the bulk key API.
+//
+// A failed operation on one key does not abort the operation
+// on the remaining keys. Instead, bulkKeyOp reports all failed
+// keys and exits if at least one operation has failed.
+func bulkKeyOp(ctx context.Context, enclave *kes.Enclave, method, apiPath string, query url.Values, names []string, op string, fn func(string) error) {
+	const maxBulkKeys = 1000
+
+	type Response struct {
+		Name    string `json:"name"`
+		Status  int    `json:"status"`
+		Message string `json:"message"`
+	}
+	var failed int
+	for len(names) > 0 {
+		batch := names
+		if len(batch) > maxBulkKeys {
+			batch = batch[:maxBulkKeys]
+		}
+		names = names[len(batch):]
+
+		var responses []Response
+		err := send(ctx, enclave, method, apiPath, query, batch, &responses)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				cli.Exit(1)
+			}
+			if kerr, ok := err.(kes.Error); !ok || kerr.Status() != http.StatusNotImplemented {
+				cli.Fatalf("failed to %s keys: %v", op, err)
+			}
+
+			// The server does not support bulk key operations.
+			responses = responses[:0]
+			for _, name := range batch {
+				response := Response{Name: name, Status: http.StatusOK}
+				if err := fn(name); err != nil {
+					if errors.Is(err, context.Canceled) {
+						cli.Exit(1)
+					}
+					response.Status, response.Message = http.StatusInternalServerError, err.Error()
+				}
+				responses = append(responses, response)
+			}
+		}
+		for _, response := range responses {
+			if response.Status != http.StatusOK {
+				cli.Errorf("failed to %s key %q: %s", op, response.Name, response.Message)
+				failed++
+			}
+		}
+	}
+	if failed > 0 {
+		cli.Exit(1)
+	}
+}
+
+const expireKeyCmdUsage = `Usage:
+    kes key expire [options] <name>...
+
+Options:
+        --ttl <duration>     Expire the key after the given duration.
+        --expires-at <time>  Expire the key at the given RFC 3339 time.
+        --never              Remove the key's expiry.
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes key expire --ttl 720h my-key
+    $ kes key expire --expires-at 2030-01-01T00:00:00Z my-key1 my-key2
+    $ kes key expire --never my-key
+`
+
+func expireKeyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, expireKeyCmdUsage) }
+
+	var (
+		ttl                time.Duration
+		expiresAt          string
+		never              bool
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.DurationVar(&ttl, "ttl", 0, "Expire the key after the given duration")
+	cmd.StringVar(&expiresAt, "expires-at", "", "Expire the key at the given RFC 3339 time")
+	cmd.BoolVar(&never, "never", false, "Remove the key's expiry")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes key expire --help'", err)
+	}
+	if cmd.NArg() == 0 {
+		cli.Fatal("no key name specified. See 'kes key expire --help'")
+	}
+	if ttl < 0 {
+		cli.Fatal("invalid TTL: TTL must not be negative. See 'kes key expire --help'")
+	}
+
+	var n int
+	for _, set := range []bool{ttl > 0, expiresAt != "", never} {
+		if set {
+			n++
+		}
+	}
+	switch {
+	case n == 0:
+		cli.Fatal("no expiry specified. See 'kes key expire --help'")
+	case n > 1:
+		cli.Fatal("'--ttl', '--expires-at' and '--never' cannot be combined. See 'kes key expire --help'")
+	}
+
+	type Request struct {
+		ExpiresAt string `json:"expires_at,omitempty"`
+		TTL       string `json:"ttl,omitempty"`
+	}
+	req := Request{ExpiresAt: expiresAt}
+	if ttl > 0 {
+		req.TTL = ttl.String()
+	}
+
+	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancelCtx()
+
+	enclave := newEnclave(enclaveName, insecureSkipVerify)
+	for _, name := range cmd.Args() {
+		if err := send(ctx, enclave, http.MethodPost, "/v1/key/expire/"+name, nil, req, nil); err != nil {
+			if errors.Is(err, context.Canceled) {
+				cli.Exit(1)
+			}
+			cli.Fatalf("failed to change expiry of key %q: %v", name, err)
+		}
+	}
+}
+
+const tagKeyCmdUsage = `Usage:
+    kes key tag [options] <name> [<tag>=<value>...]
+
+Adds tags to or removes tags from a crypto key. Existing
+tags with the same name are replaced.
+
+Options:
+        --rm <tag>           Remove the tag. May be specified multiple times.
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes key tag my-key env=prod tenant=acme
+    $ kes key tag --rm tenant my-key
+`
+
+func tagKeyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, tagKeyCmdUsage) }
+
+	var (
+		removeFlag         []string
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.StringArrayVar(&removeFlag, "rm", nil, "Remove the tag")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes key tag --help'", err)
+	}
+	if cmd.NArg() == 0 {
+		cli.Fatal("no key name specified. See 'kes key tag --help'")
+	}
+	if cmd.NArg() == 1 && len(removeFlag) == 0 {
+		cli.Fatal("no tags specified. See 'kes key tag --help'")
+	}
+
+	type Request struct {
+		Set    map[string]string `json:"set,omitempty"`
+		Remove []string          `json:"remove,omitempty"`
+	}
+	req := Request{Remove: removeFlag}
+	for _, arg := range cmd.Args()[1:] {
+		tag, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			cli.Fatalf("invalid tag '%s': expected 'name=value'. See 'kes key tag --help'", arg)
+		}
+		if req.Set == nil {
+			req.Set = map[string]string{}
+		}
+		req.Set[tag] = value
+	}
+
+	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancelCtx()
+
+	name := cmd.Arg(0)
+	enclave := newEnclave(enclaveName, insecureSkipVerify)
+	if err := send(ctx, enclave, http.MethodPost, "/v1/key/tag/"+name, nil, req, nil); err != nil {
+		if errors.Is(err, context.Canceled) {
+			cli.Exit(1)
+		}
+		cli.Fatalf("failed to tag key %q: %v", name, err)
+	}
+}
+
+const ceremonyKeyCmdUsage = `Usage:
+    kes key ceremony <command>
+
+Commands:
+    begin                    Start a new key ceremony.
+    contribute               Contribute entropy to a key ceremony.
+
+Options:
+    -h, --help               Print command line options.
+`
+
+func ceremonyKeyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, ceremonyKeyCmdUsage) }
+
+	subCmds := commands{
+		"begin":      beginCeremonyCmd,
+		"contribute": contributeCeremonyCmd,
+	}
+
+	if len(args) < 2 {
+		cmd.Usage()
+		cli.Exit(2)
+	}
+	if cmd, ok := subCmds[args[1]]; ok {
+		cmd(args[1:])
+		return
+	}
+
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes key ceremony --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatalf("%q is not a key ceremony command. See 'kes key ceremony --help'", cmd.Arg(0))
+	}
+	cmd.Usage()
+	cli.Exit(2)
+}
+
+const beginCeremonyCmdUsage = `Usage:
+    kes key ceremony begin [options] <name>
+
+Starts a key ceremony for a new crypto key. The key gets created
+once the specified number of custodians has contributed entropy
+within the ceremony window. No single custodian, including the
+one starting the ceremony, knows or controls the key.
+
+Options:
+    -n, --custodians <n>     Number of custodians that have to contribute.
+                             At least 2. (default: 2)
+        --window <duration>  Time custodians have to contribute. At most
+                             24h. (default: 1h)
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes key ceremony begin --custodians 3 --window 30m my-root-key
+`
+
+func beginCeremonyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, beginCeremonyCmdUsage) }
+
+	var (
+		custodians         int
+		window             time.Duration
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.IntVarP(&custodians, "custodians", "n", 2, "Number of custodians that have to contribute")
+	cmd.DurationVar(&window, "window", 1*time.Hour, "Time custodians have to contribute")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes key ceremony begin --help'", err)
+	}
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no key name specified. See 'kes key ceremony begin --help'")
+	case cmd.NArg() > 1:
+		cli.Fatal("too many arguments. See 'kes key ceremony begin --help'")
+	}
+
+	type Request struct {
+		Custodians int    `json:"custodians"`
+		Window     string `json:"window"`
+	}
+	type Response struct {
+		Custodians int       `json:"custodians"`
+		Deadline   time.Time `json:"deadline"`
+	}
+
+	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancelCtx()
+
+	name := cmd.Arg(0)
+	enclave := newEnclave(enclaveName, insecureSkipVerify)
+
+	var resp Response
+	req := Request{Custodians: custodians, Window: window.String()}
+	if err := send(ctx, enclave, http.MethodPost, "/v1/key/ceremony/begin/"+name, nil, req, &resp); err != nil {
+		if errors.Is(err, context.Canceled) {
+			cli.Exit(1)
+		}
+		cli.Fatalf("failed to start key ceremony for %q: %v", name, err)
+	}
+	if !isTerm(os.Stdout) {
+		if err := json.NewEncoder(os.Stdout).Encode(resp); err != nil {
+			cli.Fatal(err)
+		}
+		return
+	}
+	fmt.Printf("Started key ceremony for %q: %d custodians have to contribute until %s\n", name, resp.Custodians, resp.Deadline.Local().Format(time.RFC1123))
+}
+
+const contributeCeremonyCmdUsage = `Usage:
+    kes key ceremony contribute [options] <name> [<entropy>]
+
+Contributes entropy to the key ceremony for the named key. By default,
+32 bytes of entropy are generated randomly. Custom entropy has to be
+base64-encoded and at least 32 bytes long. If <entropy> is '-' it is
+read from standard input.
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes key ceremony contribute my-root-key
+`
+
+func contributeCeremonyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, contributeCeremonyCmdUsage) }
+
+	var (
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes key ceremony contribute --help'", err)
+	}
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no key name specified. See 'kes key ceremony contribute --help'")
+	case cmd.NArg() > 2:
+		cli.Fatal("too many arguments. See 'kes key ceremony contribute --help'")
+	}
+
+	var entropy []byte
+	if cmd.NArg() == 2 {
+		var err error
+		encoded := readMessage(cmd.Arg(1))
+		if entropy, err = base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded))); err != nil {
+			cli.Fatalf("invalid entropy: %v. See 'kes key ceremony contribute --help'", err)
+		}
+	} else {
+		entropy = make([]byte, 32)
+		if _, err := rand.Read(entropy); err != nil {
+			cli.Fatalf("failed to generate entropy: %v", err)
+		}
+	}
+
+	type Request struct {
+		Entropy []byte `json:"entropy"`
+	}
+	type Response struct {
+		Custodians    int       `json:"custodians"`
+		Deadline      time.Time `json:"deadline"`
+		Complete      bool      `json:"complete"`
+		Contributions []struct {
+			Identity   kes.Identity `json:"identity"`
+			Commitment []byte       `json:"commitment"`
+			Time       time.Time    `json:"time"`
+		} `json:"contributions"`
+	}
+
+	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancelCtx()
+
+	name := cmd.Arg(0)
+	enclave := newEnclave(enclaveName, insecureSkipVerify)
+
+	var resp Response
+	if err := send(ctx, enclave, http.MethodPost, "/v1/key/ceremony/contribute/"+name, nil, Request{Entropy: entropy}, &resp); err != nil {
+		if errors.Is(err, context.Canceled) {
+			cli.Exit(1)
+		}
+		cli.Fatalf("failed to contribute to key ceremony for %q: %v", name, err)
+	}
+	if !isTerm(os.Stdout) {
+		if err := json.NewEncoder(os.Stdout).Encode(resp); err != nil {
+			cli.Fatal(err)
+		}
+		return
+	}
+
+	for _, c := range resp.Contributions {
+		fmt.Printf("%s  %s  %x\n", c.Time.Local().Format(time.RFC3339), c.Identity, c.Commitment)
+	}
+	if resp.Complete {
+		fmt.Printf("Key ceremony complete: created key %q\n", name)
+	} else {
+		fmt.Printf("Waiting for %d more custodians until %s\n", resp.Custodians-len(resp.Contributions), resp.Deadline.Local().Format(time.RFC1123))
+	}
+}
+
+const encryptKeyCmdUsage = `Usage:
+    kes key encrypt [options] <name> <message>
+
+Options:
+        --context <key=value> Bind the ciphertext to the given context.
+                             May be specified multiple times. The same
+                             context must be provided for decryption.
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes key encrypt my-key "Hello World"
+    $ kes key encrypt --context bucket=my-bucket my-key "Hello World"
+`
+
+func encryptKeyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, encryptKeyCmdUsage) }
+
+	var (
+		contextFlag        []string
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.StringArrayVar(&contextFlag, "context", nil, "Bind the ciphertext to the given context")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes key encrypt --help'", err)
+	}
+
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no key name specified. See 'kes key encrypt --help'")
+	case cmd.NArg() == 1:
+		cli.Fatal("no message specified. See 'kes key encrypt --help'")
+	case cmd.NArg() > 2:
+		cli.Fatal("too many arguments. See 'kes key encrypt --help'")
 	}
 
 	name := cmd.Arg(0)
 	message := cmd.Arg(1)
+	associatedData, err := parseContext(contextFlag)
+	if err != nil {
+		cli.Fatalf("%v. See 'kes key encrypt --help'", err)
+	}
 
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
 	defer cancel()
 
 	enclave := newEnclave(enclaveName, insecureSkipVerify)
-	ciphertext, err := enclave.Encrypt(ctx, name, []byte(message), nil)
+	ciphertext, err := enclave.Encrypt(ctx, name, []byte(message), associatedData)
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
-			os.Exit(1)
+			cli.Exit(1)
 		}
 		cli.Fatalf("failed to encrypt message: %v", err)
 	}
@@ -514,7 +1722,13 @@ func encryptKeyCmd(args []string) {
 const decryptKeyCmdUsage = `Usage:
     kes key decrypt [options] <name> <ciphertext> [<context>]
 
+Decrypts a ciphertext. If the ciphertext is bound to a context, the
+same context must be provided, either via '--context' or as base64
+encoded <context>. Otherwise, decryption fails.
+
 Options:
+        --context <key=value> Context the ciphertext is bound to. May be
+                             specified multiple times.
     -k, --insecure           Skip TLS certificate validation.
     -e, --enclave <name>     Operate within the specified enclave.
 
@@ -523,6 +1737,7 @@ Options:
 Examples:
     $ CIPHERTEXT=$(kes key dek my-key | jq -r .ciphertext)
     $ kes key decrypt my-key "$CIPHERTEXT"
+    $ kes key decrypt --context bucket=my-bucket my-key "$CIPHERTEXT"
 `
 
 func decryptKeyCmd(args []string) {
@@ -530,14 +1745,16 @@ func decryptKeyCmd(args []string) {
 	cmd.Usage = func() { fmt.Fprint(os.Stderr, decryptKeyCmdUsage) }
 
 	var (
+		contextFlag        []string
 		insecureSkipVerify bool
 		enclaveName        string
 	)
+	cmd.StringArrayVar(&contextFlag, "context", nil, "Context the ciphertext is bound to")
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(2)
+			cli.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes key decrypt --help'", err)
 	}
@@ -557,7 +1774,13 @@ func decryptKeyCmd(args []string) {
 		cli.Fatalf("invalid ciphertext: %v. See 'kes key decrypt --help'", err)
 	}
 
-	var associatedData []byte
+	if cmd.NArg() == 3 && len(contextFlag) > 0 {
+		cli.Fatal("'--context' and <context> cannot be specified both. See 'kes key decrypt --help'")
+	}
+	associatedData, err := parseContext(contextFlag)
+	if err != nil {
+		cli.Fatalf("%v. See 'kes key decrypt --help'", err)
+	}
 	if cmd.NArg() == 3 {
 		associatedData, err = base64.StdEncoding.DecodeString(cmd.Arg(2))
 		if err != nil {
@@ -572,7 +1795,10 @@ func decryptKeyCmd(args []string) {
 	plaintext, err := enclave.Decrypt(ctx, name, ciphertext, associatedData)
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
-			os.Exit(1)
+			cli.Exit(1)
+		}
+		if errors.Is(err, kes.ErrDecrypt) {
+			cli.Fatal("failed to decrypt ciphertext: the ciphertext is not authentic or the context does not match the context used for encryption")
 		}
 		cli.Fatalf("failed to decrypt ciphertext: %v", err)
 	}
@@ -588,6 +1814,9 @@ const dekCmdUsage = `Usage:
     kes key dek <name> [<context>]
 
 Options:
+        --context <key=value> Bind the ciphertext to the given context.
+                             May be specified multiple times. The same
+                             context must be provided for decryption.
     -k, --insecure           Skip TLS certificate validation.
     -e, --enclave <name>     Operate within the specified enclave.
 
@@ -595,6 +1824,7 @@ Options:
 
 Examples:
     $ kes key dek my-key
+    $ kes key dek --context bucket=my-bucket --context object=my-object my-key
 `
 
 func dekCmd(args []string) {
@@ -602,14 +1832,16 @@ func dekCmd(args []string) {
 	cmd.Usage = func() { fmt.Fprint(os.Stderr, dekCmdUsage) }
 
 	var (
+		contextFlag        []string
 		insecureSkipVerify bool
 		enclaveName        string
 	)
+	cmd.StringArrayVar(&contextFlag, "context", nil, "Bind the ciphertext to the given context")
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(2)
+			cli.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes key dek --help'", err)
 	}
@@ -621,7 +1853,13 @@ func dekCmd(args []string) {
 		cli.Fatal("too many arguments. See 'kes key dek --help'")
 	}
 
-	var associatedData []byte
+	if cmd.NArg() == 2 && len(contextFlag) > 0 {
+		cli.Fatal("'--context' and <context> cannot be specified both. See 'kes key dek --help'")
+	}
+	associatedData, err := parseContext(contextFlag)
+	if err != nil {
+		cli.Fatalf("%v. See 'kes key dek --help'", err)
+	}
 	name := cmd.Arg(0)
 	if cmd.NArg() == 2 {
 		b, err := base64.StdEncoding.DecodeString(cmd.Arg(1))
@@ -638,7 +1876,7 @@ func dekCmd(args []string) {
 	key, err := enclave.GenerateKey(ctx, name, associatedData)
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
-			os.Exit(1)
+			cli.Exit(1)
 		}
 		cli.Fatalf("failed to derive key: %v", err)
 	}
@@ -655,3 +1893,409 @@ func dekCmd(args []string) {
 		fmt.Printf(format, plaintext, ciphertext)
 	}
 }
+
+const hmacKeyCmdUsage = `Usage:
+    kes key hmac [options] <name> <message>
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+    If <message> is '-', the message is read from standard input.
+
+Examples:
+    $ kes key hmac my-key "Hello World"
+    $ kes key hmac my-key - < message.txt
+`
+
+func hmacKeyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, hmacKeyCmdUsage) }
+
+	var (
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes key hmac --help'", err)
+	}
+
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no key name specified. See 'kes key hmac --help'")
+	case cmd.NArg() == 1:
+		cli.Fatal("no message specified. See 'kes key hmac --help'")
+	case cmd.NArg() > 2:
+		cli.Fatal("too many arguments. See 'kes key hmac --help'")
+	}
+
+	name := cmd.Arg(0)
+	message := readMessage(cmd.Arg(1))
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	type Request struct {
+		Message []byte `json:"message"`
+	}
+	type Response struct {
+		HMAC []byte `json:"hmac"`
+	}
+	var resp Response
+	enclave := newEnclave(enclaveName, insecureSkipVerify)
+	if err := send(ctx, enclave, http.MethodPost, "/v1/key/hmac/"+name, nil, Request{Message: message}, &resp); err != nil {
+		if errors.Is(err, context.Canceled) {
+			cli.Exit(1)
+		}
+		cli.Fatalf("failed to compute HMAC: %v", err)
+	}
+
+	if isTerm(os.Stdout) {
+		fmt.Printf("\nhmac: %s\n", base64.StdEncoding.EncodeToString(resp.HMAC))
+	} else {
+		fmt.Printf(`{"hmac":"%s"}`, base64.StdEncoding.EncodeToString(resp.HMAC))
+	}
+}
+
+const deriveKeyCmdUsage = `Usage:
+    kes key derive [options] <name> <label> [<context>]
+
+Derives a key from the crypto key <name>, the <label> and the optional
+<context> using HKDF-SHA256. The same label and context always derive
+the same key. Hence, derived keys do not have to be stored.
+
+Options:
+    -l, --length <n>         Length of the derived key in bytes. Must be
+                             between 16 and 64. (default: 32)
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+    If <context> is '-', the context is read from standard input.
+
+Examples:
+    $ kes key derive my-key object-key my-bucket/my-object
+    $ kes key derive --length 64 my-key object-key - < context.bin
+`
+
+func deriveKeyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, deriveKeyCmdUsage) }
+
+	var (
+		length             int
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.IntVarP(&length, "length", "l", 32, "Length of the derived key in bytes")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes key derive --help'", err)
+	}
+
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no key name specified. See 'kes key derive --help'")
+	case cmd.NArg() == 1:
+		cli.Fatal("no label specified. See 'kes key derive --help'")
+	case cmd.NArg() > 3:
+		cli.Fatal("too many arguments. See 'kes key derive --help'")
+	}
+	if length < key.MinDeriveLen || length > key.MaxDeriveLen {
+		cli.Fatal("invalid key length: length must be between 16 and 64. See 'kes key derive --help'")
+	}
+
+	name, label := cmd.Arg(0), cmd.Arg(1)
+	var derivationContext []byte
+	if cmd.NArg() == 3 {
+		derivationContext = readMessage(cmd.Arg(2))
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	type Request struct {
+		Label   string `json:"label"`
+		Context []byte `json:"context,omitempty"`
+		Length  int    `json:"length"`
+	}
+	type Response struct {
+		Key []byte `json:"key"`
+	}
+	var resp Response
+	enclave := newEnclave(enclaveName, insecureSkipVerify)
+	err := send(ctx, enclave, http.MethodPost, "/v1/key/derive/"+name, nil, Request{
+		Label:   label,
+		Context: derivationContext,
+		Length:  length,
+	}, &resp)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			cli.Exit(1)
+		}
+		cli.Fatalf("failed to derive key: %v", err)
+	}
+
+	if isTerm(os.Stdout) {
+		fmt.Printf("\nkey: %s\n", base64.StdEncoding.EncodeToString(resp.Key))
+	} else {
+		fmt.Printf(`{"key":"%s"}`, base64.StdEncoding.EncodeToString(resp.Key))
+	}
+}
+
+const signKeyCmdUsage = `Usage:
+    kes key sign [options] <name> <message>
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+    If <message> is '-', the message is read from standard input.
+
+Examples:
+    $ kes key sign my-signing-key "Hello World"
+    $ kes key sign my-signing-key - < artifact.tar.gz
+`
+
+func signKeyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, signKeyCmdUsage) }
+
+	var (
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes key sign --help'", err)
+	}
+
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no key name specified. See 'kes key sign --help'")
+	case cmd.NArg() == 1:
+		cli.Fatal("no message specified. See 'kes key sign --help'")
+	case cmd.NArg() > 2:
+		cli.Fatal("too many arguments. See 'kes key sign --help'")
+	}
+
+	name := cmd.Arg(0)
+	message := readMessage(cmd.Arg(1))
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	type Request struct {
+		Message []byte `json:"message"`
+	}
+	type Response struct {
+		Signature []byte `json:"signature"`
+	}
+	var resp Response
+	enclave := newEnclave(enclaveName, insecureSkipVerify)
+	if err := send(ctx, enclave, http.MethodPost, "/v1/key/sign/"+name, nil, Request{Message: message}, &resp); err != nil {
+		if errors.Is(err, context.Canceled) {
+			cli.Exit(1)
+		}
+		cli.Fatalf("failed to sign message: %v", err)
+	}
+
+	if isTerm(os.Stdout) {
+		fmt.Printf("\nsignature: %s\n", base64.StdEncoding.EncodeToString(resp.Signature))
+	} else {
+		fmt.Printf(`{"signature":"%s"}`, base64.StdEncoding.EncodeToString(resp.Signature))
+	}
+}
+
+const verifyKeyCmdUsage = `Usage:
+    kes key verify [options] <name> <message> <signature>
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+    If <message> is '-', the message is read from standard input.
+    The command exits with a non-zero exit code if the signature
+    is not valid.
+
+Examples:
+    $ SIGNATURE=$(kes key sign my-signing-key "Hello World" | jq -r .signature)
+    $ kes key verify my-signing-key "Hello World" "$SIGNATURE"
+`
+
+func verifyKeyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, verifyKeyCmdUsage) }
+
+	var (
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes key verify --help'", err)
+	}
+
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no key name specified. See 'kes key verify --help'")
+	case cmd.NArg() == 1:
+		cli.Fatal("no message specified. See 'kes key verify --help'")
+	case cmd.NArg() == 2:
+		cli.Fatal("no signature specified. See 'kes key verify --help'")
+	case cmd.NArg() > 3:
+		cli.Fatal("too many arguments. See 'kes key verify --help'")
+	}
+
+	name := cmd.Arg(0)
+	message := readMessage(cmd.Arg(1))
+	signature, err := base64.StdEncoding.DecodeString(cmd.Arg(2))
+	if err != nil {
+		cli.Fatalf("invalid signature: %v. See 'kes key verify --help'", err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	type Request struct {
+		Message   []byte `json:"message"`
+		Signature []byte `json:"signature"`
+	}
+	type Response struct {
+		Valid bool `json:"valid"`
+	}
+	var resp Response
+	enclave := newEnclave(enclaveName, insecureSkipVerify)
+	if err = send(ctx, enclave, http.MethodPost, "/v1/key/verify/"+name, nil, Request{Message: message, Signature: signature}, &resp); err != nil {
+		if errors.Is(err, context.Canceled) {
+			cli.Exit(1)
+		}
+		cli.Fatalf("failed to verify signature: %v", err)
+	}
+
+	if isTerm(os.Stdout) {
+		fmt.Printf("\nvalid: %v\n", resp.Valid)
+	} else {
+		fmt.Printf(`{"valid":%v}`, resp.Valid)
+	}
+	if !resp.Valid {
+		cli.Exit(1)
+	}
+}
+
+const publicKeyCmdUsage = `Usage:
+    kes key public [options] <name>
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes key public my-signing-key > public.pem
+`
+
+func publicKeyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, publicKeyCmdUsage) }
+
+	var (
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes key public --help'", err)
+	}
+
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no key name specified. See 'kes key public --help'")
+	case cmd.NArg() > 1:
+		cli.Fatal("too many arguments. See 'kes key public --help'")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	type Response struct {
+		Type      string `json:"type"`
+		PublicKey string `json:"public_key"`
+	}
+	var resp Response
+	enclave := newEnclave(enclaveName, insecureSkipVerify)
+	if err := send(ctx, enclave, http.MethodGet, "/v1/key/public/"+cmd.Arg(0), nil, nil, &resp); err != nil {
+		if errors.Is(err, context.Canceled) {
+			cli.Exit(1)
+		}
+		cli.Fatalf("failed to fetch public key: %v", err)
+	}
+	fmt.Print(resp.PublicKey)
+}
+
+// parseContext parses the given 'key=value' pairs and returns
+// their canonical encoding that can be used as associated data.
+//
+// The context is encoded as JSON object with sorted keys. Hence,
+// the same pairs always produce the same associated data, no
+// matter in which order they have been specified. It returns
+// nil if no pairs are specified.
+func parseContext(pairs []string) ([]byte, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	context := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid context '%s': expected 'key=value'", pair)
+		}
+		if _, ok := context[key]; ok {
+			return nil, fmt.Errorf("invalid context: key '%s' is specified multiple times", key)
+		}
+		context[key] = value
+	}
+	return json.Marshal(context)
+}
+
+// readMessage returns the message itself or, if
+// message is '-', the data read from standard input.
+func readMessage(message string) []byte {
+	if message != "-" {
+		return []byte(message)
+	}
+	b, err := io.ReadAll(io.LimitReader(os.Stdin, 1<<20))
+	if err != nil {
+		cli.Fatalf("failed to read message: %v", err)
+	}
+	return b
+}
@@ -6,17 +6,20 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"sort"
 	"strconv"
 	"time"
 
-	tui "github.com/charmbracelet/lipgloss"
 	"github.com/minio/kes-go"
 	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/internal/tui"
 
 	flag "github.com/spf13/pflag"
 )
@@ -24,6 +27,12 @@ import (
 const logCmdUsage = `Usage:
     kes log <command>
 
+Commands:
+    stats                    Print audit statistics.
+    retention                Print the retention of audit statistics.
+    purge                    Purge audit statistics.
+    tls                      Print clients violating the TLS requirements.
+
 Options:
     --audit                  Print audit logs. (default)
     --error                  Print error logs.
@@ -35,12 +44,32 @@ Options:
 Examples:
     $ kes log
     $ kes log --error
+    $ kes log stats --since 30d
+    $ kes log purge --before 90d
+    $ kes log tls --since 7d
 `
 
 func logCmd(args []string) {
 	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
 	cmd.Usage = func() { fmt.Fprint(os.Stderr, logCmdUsage) }
 
+	if len(args) > 1 && args[1] == "stats" {
+		logStatsCmd(args[1:])
+		return
+	}
+	if len(args) > 1 && args[1] == "retention" {
+		logRetentionCmd(args[1:])
+		return
+	}
+	if len(args) > 1 && args[1] == "purge" {
+		logPurgeCmd(args[1:])
+		return
+	}
+	if len(args) > 1 && args[1] == "tls" {
+		logTLSCmd(args[1:])
+		return
+	}
+
 	var (
 		auditFlag          bool
 		errorFlag          bool
@@ -53,7 +82,7 @@ func logCmd(args []string) {
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(2)
+			cli.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes log --help'", err)
 	}
@@ -76,7 +105,7 @@ func logCmd(args []string) {
 		stream, err := client.AuditLog(ctx)
 		if err != nil {
 			if errors.Is(err, context.Canceled) {
-				os.Exit(1)
+				cli.Exit(1)
 			}
 			cli.Fatalf("failed to connect to error log: %v", err)
 		}
@@ -93,7 +122,7 @@ func logCmd(args []string) {
 		stream, err := client.ErrorLog(ctx)
 		if err != nil {
 			if errors.Is(err, context.Canceled) {
-				os.Exit(1)
+				cli.Exit(1)
 			}
 			cli.Fatalf("failed to connect to error log: %v", err)
 		}
@@ -108,7 +137,7 @@ func logCmd(args []string) {
 		}
 	default:
 		cmd.Usage()
-		os.Exit(2)
+		cli.Exit(2)
 	}
 }
 
@@ -169,7 +198,7 @@ func printAuditLog(stream *kes.AuditStream) {
 	}
 	if err := stream.Close(); err != nil {
 		if errors.Is(err, context.Canceled) {
-			os.Exit(1)
+			cli.Exit(1)
 		}
 		cli.Fatal(err)
 	}
@@ -181,8 +210,426 @@ func printErrorLog(stream *kes.ErrorStream) {
 	}
 	if err := stream.Close(); err != nil {
 		if errors.Is(err, context.Canceled) {
-			os.Exit(1)
+			cli.Exit(1)
 		}
 		cli.Fatal(err)
 	}
 }
+
+const logStatsCmdUsage = `Usage:
+    kes log stats [options]
+
+Prints how many requests each identity has sent to each API and
+how many of them failed. The statistics are aggregated from the
+server's audit log into daily rollups.
+
+The --since flag accepts either a duration, like 72h or 30d, or
+a RFC 3339 timestamp. By default, it prints the statistics of
+the current day.
+
+Options:
+    --since <duration>       Print statistics since the given point in time.
+    --daily                  Print statistics per day.
+    --json                   Print statistics in JSON format.
+    --color <when>           Specify when to use colored output. The automatic
+                             mode only enables colors if an interactive terminal
+                             is detected - colors are automatically disabled if
+                             the output goes to a pipe.
+                             Possible values: *auto*, never, always.
+
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes log stats --since 30d
+    $ kes log stats --since 2023-06-01T00:00:00Z --daily
+`
+
+func logStatsCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, logStatsCmdUsage) }
+
+	var (
+		sinceFlag          string
+		dailyFlag          bool
+		jsonFlag           bool
+		colorFlag          colorOption
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.StringVar(&sinceFlag, "since", "", "Print statistics since the given point in time")
+	cmd.BoolVar(&dailyFlag, "daily", false, "Print statistics per day")
+	cmd.BoolVar(&jsonFlag, "json", false, "Print statistics in JSON format")
+	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes log stats --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatal("too many arguments. See 'kes log stats --help'")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	type Rollup struct {
+		Day      string       `json:"day,omitempty"`
+		Identity kes.Identity `json:"identity"`
+		APIPath  string       `json:"path"`
+		Requests uint64       `json:"requests"`
+		Errors   uint64       `json:"errors"`
+	}
+	type Response struct {
+		Since time.Time `json:"since"`
+		Stats []Rollup  `json:"stats"`
+	}
+	var query url.Values
+	if sinceFlag != "" {
+		query = url.Values{"since": []string{sinceFlag}}
+	}
+	enclave := newEnclave(enclaveName, insecureSkipVerify)
+	var resp Response
+	if err := send(ctx, enclave, http.MethodGet, "/v1/log/stats", query, nil, &resp); err != nil {
+		if errors.Is(err, context.Canceled) {
+			cli.Exit(1)
+		}
+		cli.Fatalf("failed to fetch audit statistics: %v", err)
+	}
+
+	stats := resp.Stats
+	if !dailyFlag {
+		type Key struct {
+			Identity kes.Identity
+			APIPath  string
+		}
+		totals := map[Key]*Rollup{}
+		for _, r := range resp.Stats {
+			key := Key{Identity: r.Identity, APIPath: r.APIPath}
+			total, ok := totals[key]
+			if !ok {
+				total = &Rollup{Identity: r.Identity, APIPath: r.APIPath}
+				totals[key] = total
+			}
+			total.Requests += r.Requests
+			total.Errors += r.Errors
+		}
+		stats = make([]Rollup, 0, len(totals))
+		for _, total := range totals {
+			stats = append(stats, *total)
+		}
+		sort.Slice(stats, func(i, j int) bool {
+			if stats[i].Identity != stats[j].Identity {
+				return stats[i].Identity < stats[j].Identity
+			}
+			return stats[i].APIPath < stats[j].APIPath
+		})
+	}
+
+	if jsonFlag {
+		encoder := json.NewEncoder(os.Stdout)
+		if isTerm(os.Stdout) {
+			encoder.SetIndent("", "  ")
+		}
+		encoder.Encode(stats)
+		return
+	}
+	if len(stats) == 0 {
+		fmt.Printf("No requests since %s\n", resp.Since.Local().Format(time.DateTime))
+		return
+	}
+
+	headerStyle := tui.NewStyle()
+	errorStyle := tui.NewStyle()
+	if colorFlag.Colorize() {
+		const ColorError tui.Color = "#ac0000"
+		headerStyle = headerStyle.Underline(true).Bold(true)
+		errorStyle = errorStyle.Foreground(ColorError)
+	}
+	if dailyFlag {
+		fmt.Print(headerStyle.Render(fmt.Sprintf("%-10s", "Day")), " ")
+	}
+	fmt.Printf("%s %s %s %s %s\n",
+		headerStyle.Render(fmt.Sprintf("%-64s", "Identity")),
+		headerStyle.Render(fmt.Sprintf("%-30s", "API")),
+		headerStyle.Render(fmt.Sprintf("%10s", "Requests")),
+		headerStyle.Render(fmt.Sprintf("%8s", "Errors")),
+		headerStyle.Render(fmt.Sprintf("%10s", "Error Rate")),
+	)
+	for _, r := range stats {
+		if dailyFlag {
+			fmt.Printf("%-10s ", r.Day)
+		}
+		errorRate := fmt.Sprintf("%9.2f%%", 100*float64(r.Errors)/float64(r.Requests))
+		if r.Errors > 0 {
+			errorRate = errorStyle.Render(errorRate)
+		}
+		fmt.Printf("%-64s %-30s %10d %8d %s\n", r.Identity, r.APIPath, r.Requests, r.Errors, errorRate)
+	}
+}
+
+const logTLSCmdUsage = `Usage:
+    kes log tls [options]
+
+Prints all clients that have violated the server's minimum TLS
+requirements, like the minimum TLS version or client certificate
+key size. Depending on the server configuration, requests of such
+clients are either rejected or just reported.
+
+The --since flag accepts either a duration, like 72h or 30d, or
+a RFC 3339 timestamp. By default, it prints all clients observed
+in the last 7 days.
+
+Options:
+    --since <duration>       Print clients observed since the given point in time.
+    --json                   Print clients in JSON format.
+    --color <when>           Specify when to use colored output. The automatic
+                             mode only enables colors if an interactive terminal
+                             is detected - colors are automatically disabled if
+                             the output goes to a pipe.
+                             Possible values: *auto*, never, always.
+
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes log tls
+    $ kes log tls --since 30d --json
+`
+
+func logTLSCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, logTLSCmdUsage) }
+
+	var (
+		sinceFlag          string
+		jsonFlag           bool
+		colorFlag          colorOption
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.StringVar(&sinceFlag, "since", "7d", "Print clients observed since the given point in time")
+	cmd.BoolVar(&jsonFlag, "json", false, "Print clients in JSON format")
+	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes log tls --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatal("too many arguments. See 'kes log tls --help'")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	type Client struct {
+		Identity  kes.Identity `json:"identity"`
+		IP        string       `json:"ip,omitempty"`
+		Reason    string       `json:"reason"`
+		Requests  uint64       `json:"requests"`
+		FirstSeen time.Time    `json:"first_seen"`
+		LastSeen  time.Time    `json:"last_seen"`
+	}
+	type Response struct {
+		Since   time.Time `json:"since"`
+		Enforce bool      `json:"enforce"`
+		Clients []Client  `json:"clients"`
+	}
+	enclave := newEnclave(enclaveName, insecureSkipVerify)
+	var resp Response
+	if err := send(ctx, enclave, http.MethodGet, "/v1/log/tls", url.Values{"since": []string{sinceFlag}}, nil, &resp); err != nil {
+		if errors.Is(err, context.Canceled) {
+			cli.Exit(1)
+		}
+		cli.Fatalf("failed to fetch client TLS report: %v", err)
+	}
+
+	if jsonFlag {
+		encoder := json.NewEncoder(os.Stdout)
+		if isTerm(os.Stdout) {
+			encoder.SetIndent("", "  ")
+		}
+		encoder.Encode(resp.Clients)
+		return
+	}
+	if len(resp.Clients) == 0 {
+		fmt.Printf("No clients violated the TLS requirements since %s\n", resp.Since.Local().Format(time.DateTime))
+		return
+	}
+
+	headerStyle := tui.NewStyle()
+	if colorFlag.Colorize() {
+		headerStyle = headerStyle.Underline(true).Bold(true)
+	}
+	fmt.Printf("%s %s %s %s %s\n",
+		headerStyle.Render(fmt.Sprintf("%-64s", "Identity")),
+		headerStyle.Render(fmt.Sprintf("%-15s", "IP")),
+		headerStyle.Render(fmt.Sprintf("%10s", "Requests")),
+		headerStyle.Render(fmt.Sprintf("%-19s", "Last Seen")),
+		headerStyle.Render("Reason"),
+	)
+	for _, c := range resp.Clients {
+		fmt.Printf("%-64s %-15s %10d %-19s %s\n", c.Identity, c.IP, c.Requests, c.LastSeen.Local().Format(time.DateTime), c.Reason)
+	}
+	if resp.Enforce {
+		fmt.Println("\nRequests of these clients are rejected.")
+	} else {
+		fmt.Println("\nRequests of these clients are reported but not rejected.")
+	}
+}
+
+const logRetentionCmdUsage = `Usage:
+    kes log retention [options]
+
+Prints how long the server retains the audit statistics of the
+enclave, whether they are under legal hold and since when audit
+statistics are available.
+
+The retention and legal hold of an enclave can be changed with
+'kes enclave update'.
+
+Options:
+    --json                   Print the retention in JSON format.
+
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes log retention
+    $ kes log retention --enclave tenant-1
+`
+
+func logRetentionCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, logRetentionCmdUsage) }
+
+	var (
+		jsonFlag           bool
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.BoolVar(&jsonFlag, "json", false, "Print the retention in JSON format")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes log retention --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatal("too many arguments. See 'kes log retention --help'")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	type Response struct {
+		Retention int    `json:"retention_days"`
+		LegalHold bool   `json:"legal_hold,omitempty"`
+		Oldest    string `json:"oldest,omitempty"`
+		Rollups   int    `json:"rollups"`
+	}
+	var resp Response
+	enclave := newEnclave(enclaveName, insecureSkipVerify)
+	if err := send(ctx, enclave, http.MethodGet, "/v1/log/retention", nil, nil, &resp); err != nil {
+		if errors.Is(err, context.Canceled) {
+			cli.Exit(1)
+		}
+		cli.Fatalf("failed to fetch audit retention: %v", err)
+	}
+
+	if jsonFlag {
+		encoder := json.NewEncoder(os.Stdout)
+		if isTerm(os.Stdout) {
+			encoder.SetIndent("", "  ")
+		}
+		if err := encoder.Encode(resp); err != nil {
+			cli.Fatal(err)
+		}
+		return
+	}
+
+	fmt.Printf("%-11s %d days\n", "Retention", resp.Retention)
+	if resp.LegalHold {
+		fmt.Printf("%-11s %s\n", "Legal Hold", "enabled")
+	}
+	if resp.Oldest != "" {
+		fmt.Printf("%-11s %s\n", "Oldest", resp.Oldest)
+	}
+	fmt.Printf("%-11s %d\n", "Rollups", resp.Rollups)
+}
+
+const logPurgeCmdUsage = `Usage:
+    kes log purge [options]
+
+Purges the audit statistics of the enclave before the given point
+in time. Audit statistics under legal hold cannot be purged.
+
+The --before flag accepts either a duration, like 72h or 90d, or
+a RFC 3339 timestamp.
+
+Options:
+    --before <duration>      Purge statistics before the given point in time.
+
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes log purge --before 90d
+    $ kes log purge --before 2023-06-01T00:00:00Z --enclave tenant-1
+`
+
+func logPurgeCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, logPurgeCmdUsage) }
+
+	var (
+		beforeFlag         string
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.StringVar(&beforeFlag, "before", "", "Purge statistics before the given point in time")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes log purge --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatal("too many arguments. See 'kes log purge --help'")
+	}
+	if beforeFlag == "" {
+		cli.Fatal("no point in time specified. See 'kes log purge --help'")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	type Response struct {
+		Before time.Time `json:"before"`
+		Purged int       `json:"purged"`
+	}
+	var resp Response
+	enclave := newEnclave(enclaveName, insecureSkipVerify)
+	if err := send(ctx, enclave, http.MethodDelete, "/v1/log/purge", url.Values{"before": []string{beforeFlag}}, nil, &resp); err != nil {
+		if errors.Is(err, context.Canceled) {
+			cli.Exit(1)
+		}
+		cli.Fatalf("failed to purge audit statistics: %v", err)
+	}
+	fmt.Printf("Purged %d rollups before %s.\n", resp.Purged, resp.Before.Format(time.DateOnly))
+}
@@ -40,11 +40,25 @@ Commands:
     log                      Print error and audit log events.
     status                   Print server status.
     metric                   Print server metrics.
+    maintenance              Manage server maintenance mode.
+    admin                    Manage admins and run failover drills.
+    report                   Print access review reports.
+    shell                    Start an interactive shell.
 
+    config                   Upgrade server config files.
+    validate                 Validate a server config file.
     migrate                  Migrate KMS data.
+    mirror                   Check keystore mirrors.
+    test                     Run conformance tests.
     update                   Update KES binary.
 
 Options:
+        --timeout <duration> Abort requests if the server does not respond
+                             within the given duration, e.g. 10s.
+        --retry <n>          Retry failed requests up to n times with an
+                             exponential backoff.
+        --as <identity>      Send requests on behalf of the given identity.
+                             Only the admin can impersonate identities.
     -v, --version            Print version information.
         --auto-completion    Install auto-completion for this shell.
     -h, --help               Print command line options.
@@ -58,31 +72,11 @@ func main() {
 	cmd := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
 	cmd.Usage = func() { fmt.Fprint(os.Stderr, usage) }
 
-	subCmds := commands{
-		"server": serverCmd,
-		"init":   initCmd,
-
-		"enclave":  enclaveCmd,
-		"key":      keyCmd,
-		"secret":   secretCmd,
-		"policy":   policyCmd,
-		"identity": identityCmd,
-
-		"log":    logCmd,
-		"status": statusCmd,
-		"metric": metricCmd,
-
-		"migrate": migrateCmd,
-		"update":  updateCmd,
-	}
+	subCmds := kesCommands()
 
 	if len(os.Args) < 2 {
 		cmd.Usage()
-		os.Exit(2)
-	}
-	if subCmd, ok := subCmds[os.Args[1]]; ok {
-		subCmd(os.Args[1:])
-		return
+		cli.Exit(2)
 	}
 
 	var (
@@ -91,15 +85,32 @@ func main() {
 	)
 	cmd.BoolVarP(&showVersion, "version", "v", false, "Print version information.")
 	cmd.BoolVar(&autoCompletion, "auto-completion", false, "Install auto-completion for this shell")
-	if err := cmd.Parse(os.Args); err != nil {
+	cmd.DurationVar(&requestTimeout, "timeout", 0, "Abort requests if the server does not respond in time")
+	cmd.IntVar(&requestRetry, "retry", 0, "Retry failed requests up to n times")
+	cmd.StringVar(&requestAs, "as", "", "Send requests on behalf of the given identity")
+	cmd.SetInterspersed(false) // Stop parsing at the first command
+	if err := cmd.Parse(os.Args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(2)
+			cli.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes --help'", err)
-
 	}
-	if cmd.NArg() > 1 {
-		cli.Fatalf("%q is not a kes command. See 'kes --help'", cmd.Arg(1))
+	if requestTimeout < 0 {
+		cli.Fatal("invalid timeout: timeout must not be negative. See 'kes --help'")
+	}
+	if requestRetry < 0 {
+		cli.Fatal("invalid retry: number of retries must not be negative. See 'kes --help'")
+	}
+	if cmd.Changed("as") && kes.Identity(strings.TrimSpace(requestAs)).IsUnknown() {
+		cli.Fatal("invalid identity: identity must not be empty. See 'kes --help'")
+	}
+	if cmd.NArg() > 0 {
+		subCmd, ok := subCmds[cmd.Arg(0)]
+		if !ok {
+			cli.Fatalf("%q is not a kes command. See 'kes --help'", cmd.Arg(0))
+		}
+		subCmd(cmd.Args())
+		return
 	}
 	if showVersion {
 		buildInfo := sys.BinaryInfo()
@@ -111,18 +122,72 @@ func main() {
 		return
 	}
 	cmd.Usage()
-	os.Exit(2)
+	cli.Exit(2)
+}
+
+// kesCommands returns all kes commands.
+func kesCommands() commands {
+	return commands{
+		"server": serverCmd,
+		"init":   initCmd,
+
+		"enclave":  enclaveCmd,
+		"key":      keyCmd,
+		"secret":   secretCmd,
+		"policy":   policyCmd,
+		"identity": identityCmd,
+
+		"log":         logCmd,
+		"status":      statusCmd,
+		"metric":      metricCmd,
+		"maintenance": maintenanceCmd,
+		"admin":       adminCmd,
+		"report":      reportCmd,
+		"shell":       shellCmd,
+
+		"config":   configCmd,
+		"validate": validateCmd,
+		"migrate":  migrateCmd,
+		"mirror":   mirrorCmd,
+		"test":     testCmd,
+		"update":   updateCmd,
+	}
 }
 
+// newClient returns a new client for the KES server specified
+// by the environment. Within an interactive shell, it returns
+// the client of the shell session instead such that commands
+// reuse its connection and credentials.
 func newClient(insecureSkipVerify bool) *kes.Client {
+	if session != nil {
+		return session.Client(insecureSkipVerify)
+	}
+	return loadClient(insecureSkipVerify)
+}
+
+// loadClient returns a new client for the KES server specified
+// by the environment.
+func loadClient(insecureSkipVerify bool) *kes.Client {
 	const DefaultServer = "https://127.0.0.1:7373"
 	const (
 		EnvServer     = "KES_SERVER"
 		EnvAPIKey     = "KES_API_KEY"
+		EnvAPIKeyAuth = "KES_API_KEY_AUTH"
 		EnvClientKey  = "KES_CLIENT_KEY"
 		EnvClientCert = "KES_CLIENT_CERT"
 	)
 
+	// KES_SERVER may contain a comma-separated list of endpoints
+	// of the same KES cluster. All endpoints are passed to the
+	// client as single address. See: newHTTPClient
+	addr := DefaultServer
+	if env, ok := os.LookupEnv(EnvServer); ok {
+		if len(https.ParseEndpoints(env)) == 0 {
+			cli.Fatalf("no KES server. Environment variable '%s' is empty", EnvServer)
+		}
+		addr = env
+	}
+
 	if apiKey, ok := os.LookupEnv(EnvAPIKey); ok {
 		if _, ok = os.LookupEnv(EnvClientCert); ok {
 			cli.Fatalf("two conflicting environment variables set: unset either '%s' or '%s'", EnvAPIKey, EnvClientCert)
@@ -134,19 +199,33 @@ func newClient(insecureSkipVerify bool) *kes.Client {
 		if err != nil {
 			cli.Fatalf("invalid API key: %v", err)
 		}
+
+		// By default, the client generates a TLS client certificate from
+		// the API key. Alternatively, it sends the API key as bearer token
+		// to servers that accept API keys instead of client certificates.
+		switch mode := strings.ToLower(strings.TrimSpace(os.Getenv(EnvAPIKeyAuth))); mode {
+		case "", "cert":
+		case "bearer":
+			client := newHTTPClient(kes.NewClientWithConfig(addr, &tls.Config{
+				InsecureSkipVerify: insecureSkipVerify,
+			}))
+			client.HTTPClient.Transport = &bearerTransport{
+				Transport: client.HTTPClient.Transport,
+				APIKey:    key,
+			}
+			return client
+		default:
+			cli.Fatalf("invalid API key authentication '%s': environment variable '%s' must be either 'cert' or 'bearer'", mode, EnvAPIKeyAuth)
+		}
+
 		cert, err := kes.GenerateCertificate(key)
 		if err != nil {
 			cli.Fatalf("failed to generate client certificate from API key: %v", err)
 		}
-
-		addr := DefaultServer
-		if env, ok := os.LookupEnv(EnvServer); ok {
-			addr = env
-		}
-		return kes.NewClientWithConfig(addr, &tls.Config{
+		return newHTTPClient(kes.NewClientWithConfig(addr, &tls.Config{
 			Certificates:       []tls.Certificate{cert},
 			InsecureSkipVerify: insecureSkipVerify,
-		})
+		}))
 	}
 
 	certPath, ok := os.LookupEnv(EnvClientCert)
@@ -207,14 +286,10 @@ func newClient(insecureSkipVerify bool) *kes.Client {
 		cli.Fatalf("failed to load TLS private key or certificate: %v", err)
 	}
 
-	addr := DefaultServer
-	if env, ok := os.LookupEnv(EnvServer); ok {
-		addr = env
-	}
-	return kes.NewClientWithConfig(addr, &tls.Config{
+	return newHTTPClient(kes.NewClientWithConfig(addr, &tls.Config{
 		Certificates:       []tls.Certificate{cert},
 		InsecureSkipVerify: insecureSkipVerify,
-	})
+	}))
 }
 
 func newEnclave(name string, insecureSkipVerify bool) *kes.Enclave {
@@ -225,6 +300,45 @@ func newEnclave(name string, insecureSkipVerify bool) *kes.Enclave {
 	return client.Enclave(name)
 }
 
+// newHTTPClient wraps the client's transport such that
+// requests time out and get retried as specified by the
+// global --timeout and --retry flags and such that the
+// client backs off once it has exhausted the server's
+// rate limit. If the global --as flag is set, requests
+// impersonate the specified identity.
+//
+// If the client's endpoint is a comma-separated list of
+// endpoints, requests are sent to the fastest endpoint
+// and fail over to the remaining ones on connection errors.
+func newHTTPClient(client *kes.Client) *kes.Client {
+	var endpoints []string
+	for _, endpoint := range client.Endpoints {
+		endpoints = append(endpoints, https.ParseEndpoints(endpoint)...)
+	}
+	client.Endpoints = endpoints
+	if len(endpoints) > 1 {
+		// The failover transport picks the endpoint. The client
+		// must not pick an endpoint at random on its own.
+		client.Endpoints = endpoints[:1]
+		client.HTTPClient.Transport = &https.FailoverTransport{
+			Transport: client.HTTPClient.Transport,
+			Endpoints: endpoints,
+		}
+	}
+	client.HTTPClient.Transport = &retryTransport{
+		Transport: client.HTTPClient.Transport,
+		Timeout:   requestTimeout,
+		Retry:     requestRetry,
+	}
+	if identity := strings.TrimSpace(requestAs); identity != "" {
+		client.HTTPClient.Transport = &impersonateTransport{
+			Transport: client.HTTPClient.Transport,
+			Identity:  kes.Identity(identity),
+		}
+	}
+	return client
+}
+
 func isTerm(f *os.File) bool { return term.IsTerminal(int(f.Fd())) }
 
 func decodePrivateKey(pemBlock []byte) (*pem.Block, error) {
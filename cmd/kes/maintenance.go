@@ -0,0 +1,141 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/minio/kes/internal/cli"
+	flag "github.com/spf13/pflag"
+)
+
+const maintenanceCmdUsage = `Usage:
+    kes maintenance <command>
+
+Commands:
+    read-only                Show or change the read-only mode.
+
+Options:
+    -h, --help               Print command line options.
+`
+
+func maintenanceCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, maintenanceCmdUsage) }
+
+	subCmds := commands{
+		"read-only": readOnlyCmd,
+	}
+
+	if len(args) < 2 {
+		cmd.Usage()
+		cli.Exit(2)
+	}
+	if cmd, ok := subCmds[args[1]]; ok {
+		cmd(args[1:])
+		return
+	}
+
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes maintenance --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatalf("%q is not a maintenance command. See 'kes maintenance --help'", cmd.Arg(0))
+	}
+	cmd.Usage()
+	cli.Exit(2)
+}
+
+const readOnlyCmdUsage = `Usage:
+    kes maintenance read-only [options] [on|off]
+
+Shows whether the server is in read-only mode or, if 'on' or 'off'
+is specified, enables or disables the read-only mode.
+
+In read-only mode, the server rejects requests that would create,
+modify or delete keys, secrets, policies, identities or enclaves.
+Crypto operations, like encrypt or decrypt, are still served.
+
+The mode only applies to the server the request is sent to. It
+is reset when the server restarts.
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes maintenance read-only
+    $ kes maintenance read-only on
+`
+
+func readOnlyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, readOnlyCmdUsage) }
+
+	var insecureSkipVerify bool
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes maintenance read-only --help'", err)
+	}
+	if cmd.NArg() > 1 {
+		cli.Fatal("too many arguments. See 'kes maintenance read-only --help'")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	enclave := newClient(insecureSkipVerify).Enclave("")
+	if cmd.NArg() == 0 {
+		type Response struct {
+			ReadOnly bool `json:"read_only"`
+		}
+		var resp Response
+		if err := send(ctx, enclave, http.MethodGet, "/v1/status", nil, nil, &resp); err != nil {
+			if errors.Is(err, context.Canceled) {
+				cli.Exit(1)
+			}
+			cli.Fatalf("failed to fetch server status: %v", err)
+		}
+		if resp.ReadOnly {
+			fmt.Println("on")
+		} else {
+			fmt.Println("off")
+		}
+		return
+	}
+
+	var readOnly bool
+	switch strings.ToLower(cmd.Arg(0)) {
+	case "on":
+		readOnly = true
+	case "off":
+		readOnly = false
+	default:
+		cli.Fatalf("invalid argument '%s': expected 'on' or 'off'. See 'kes maintenance read-only --help'", cmd.Arg(0))
+	}
+
+	type Request struct {
+		ReadOnly bool `json:"read_only"`
+	}
+	if err := send(ctx, enclave, http.MethodPost, "/v1/maintenance/read-only", nil, Request{ReadOnly: readOnly}, nil); err != nil {
+		if errors.Is(err, context.Canceled) {
+			cli.Exit(1)
+		}
+		cli.Fatalf("failed to change read-only mode: %v", err)
+	}
+}
@@ -16,9 +16,9 @@ import (
 	"time"
 
 	"aead.dev/mem"
-	tui "github.com/charmbracelet/lipgloss"
 	"github.com/minio/kes-go"
 	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/internal/tui"
 	flag "github.com/spf13/pflag"
 )
 
@@ -44,7 +44,7 @@ func metricCmd(args []string) {
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(2)
+			cli.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes metric --help'", err)
 	}
@@ -5,12 +5,15 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"sync/atomic"
 	"time"
 
@@ -18,6 +21,7 @@ import (
 	"github.com/minio/kes-go"
 	"github.com/minio/kes/edge"
 	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/kv"
 	flag "github.com/spf13/pflag"
 	"golang.org/x/term"
 )
@@ -35,11 +39,19 @@ Options:
     --merge                  Merge the source into the target by only migrating
                              those keys that do not exist at the target.
 
+    --rate <n>               Migrate at most n keys per second, up to 1000000.
+                             By default, keys are migrated as fast as possible.
+    --resume <PATH>          Record migrated keys in the given file and skip keys
+                             recorded by a previous, interrupted migration. Keys
+                             that exist at the target and are identical to the
+                             source key are considered migrated.
+
     -q, --quiet              Do not print progress information.
     -h, --help               Print command line options.
 
 Examples:
     $ kes migrate --from vault-config.yml --to aws-config.yml
+    $ kes migrate --from gemalto-config.yml --to vault-config.yml --rate 50 --resume migrate.log
 `
 
 func migrateCmd(args []string) {
@@ -51,16 +63,20 @@ func migrateCmd(args []string) {
 		toPath    string
 		force     bool
 		merge     bool
+		rate      int
+		resume    string
 		quietFlag bool
 	)
 	cmd.StringVar(&fromPath, "from", "", "Path to the config file of the migration source")
 	cmd.StringVar(&toPath, "to", "", "Path to the config file of the migration target")
 	cmd.BoolVarP(&force, "force", "f", false, "Overwrite existing keys at the migration target")
 	cmd.BoolVar(&merge, "merge", false, "Only migrate keys that don't exist at the migration target")
+	cmd.IntVar(&rate, "rate", 0, "Migrate at most n keys per second")
+	cmd.StringVar(&resume, "resume", "", "Record migrated keys and skip keys migrated previously")
 	cmd.BoolVarP(&quietFlag, "quiet", "q", false, "Do not print progress information")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(2)
+			cli.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes migrate --help'", err)
 	}
@@ -76,6 +92,9 @@ func migrateCmd(args []string) {
 	if force && merge {
 		cli.Fatal("mutually exclusive options '--force' and '--merge' specified")
 	}
+	if err := verifyMigrateRate(rate); err != nil {
+		cli.Fatalf("%v. See 'kes migrate --help'", err)
+	}
 
 	quiet := quiet(quietFlag)
 	pattern := cmd.Arg(0)
@@ -116,14 +135,44 @@ func migrateCmd(args []string) {
 		cli.Fatal(err)
 	}
 
+	// If resuming a migration, skip all keys that have been
+	// migrated already and record all keys we migrate now.
+	var (
+		migrated map[string]struct{}
+		journal  *os.File
+	)
+	if resume != "" {
+		if migrated, err = readMigrateJournal(resume); err != nil {
+			cli.Fatalf("failed to read '--resume' file: %v", err)
+		}
+		journal, err = os.OpenFile(resume, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			cli.Fatalf("failed to open '--resume' file: %v", err)
+		}
+		defer journal.Close()
+	}
+
+	var limiter <-chan time.Time
+	if rate > 0 {
+		ticker := time.NewTicker(time.Second / time.Duration(rate))
+		defer ticker.Stop()
+		limiter = ticker.C
+	}
+
 	var (
 		n        uint64
 		uiTicker = time.NewTicker(100 * time.Millisecond)
 	)
 	defer uiTicker.Stop()
 
-	// Now, we start listing the keys at the source.
-	iterator, err := src.List(ctx)
+	// Now, we start listing the keys at the source. We only
+	// list keys that start with the literal prefix of the
+	// pattern since no other key can match.
+	prefix := pattern
+	if i := strings.IndexAny(pattern, `*?[\`); i >= 0 {
+		prefix = pattern[:i]
+	}
+	iterator, err := src.List(ctx, prefix)
 	if err != nil {
 		cli.Fatal(err)
 	}
@@ -152,29 +201,26 @@ func migrateCmd(args []string) {
 		if ok, _ := filepath.Match(pattern, name); !ok {
 			continue
 		}
-
-		key, err := src.Get(ctx, name)
-		if err != nil {
-			quiet.ClearLine()
-			cli.Fatalf("failed to migrate %q: %v\nMigrated keys: %d", name, err, atomic.LoadUint64(&n))
-		}
-
-		err = dst.Create(ctx, name, key)
-		if merge && errors.Is(err, kes.ErrKeyExists) {
-			continue // Do not increment the counter since we skip this key
+		if _, ok := migrated[name]; ok {
+			continue
 		}
-		if force && errors.Is(err, kes.ErrKeyExists) { // Try to overwrite the key
-			if err = dst.Delete(ctx, name); err != nil {
+		if limiter != nil {
+			select {
+			case <-limiter:
+			case <-ctx.Done():
 				quiet.ClearLine()
-				cli.Fatalf("failed to migrate %q: %v\nMigrated keys: %d", name, err, atomic.LoadUint64(&n))
+				cli.Fatalf("migration aborted: %v\nMigrated keys: %d", ctx.Err(), atomic.LoadUint64(&n))
 			}
-			err = dst.Create(ctx, name, key)
 		}
+
+		ok, err := migrateKey(ctx, src, dst, name, force, merge, journal)
 		if err != nil {
 			quiet.ClearLine()
 			cli.Fatalf("failed to migrate %q: %v\nMigrated keys: %d", name, err, atomic.LoadUint64(&n))
 		}
-		atomic.AddUint64(&n, 1)
+		if ok { // Do not increment the counter if we skipped this key
+			atomic.AddUint64(&n, 1)
+		}
 	}
 	if err = iterator.Close(); err != nil {
 		quiet.ClearLine()
@@ -184,10 +230,112 @@ func migrateCmd(args []string) {
 
 	// At the end we show how many keys we have migrated successfully.
 	msg := fmt.Sprintf("Migrated keys: %d ", atomic.LoadUint64(&n))
+	if len(migrated) > 0 {
+		msg += fmt.Sprintf("(%d keys migrated previously) ", len(migrated))
+	}
 	quiet.ClearMessage(msg)
 	quiet.Println(msg)
 }
 
+// maxMigrateRate is the max. number of keys per second
+// 'kes migrate --rate' accepts.
+const maxMigrateRate = 1_000_000
+
+// verifyMigrateRate returns an error if rate is not a valid
+// '--rate' value. A rate of zero means no rate limit.
+func verifyMigrateRate(rate int) error {
+	if rate < 0 {
+		return errors.New("invalid rate: rate must not be negative")
+	}
+	if rate > maxMigrateRate {
+		return fmt.Errorf("invalid rate: rate must not exceed %d keys per second", maxMigrateRate)
+	}
+	return nil
+}
+
+// migrateKey migrates the key with the given name from src to
+// dst and records it in the journal, if not nil. It reports
+// whether the key has been migrated. If the key exists at dst,
+// migrateKey skips it if merge is true and replaces it if force
+// is true.
+//
+// When resuming a migration, i.e. if journal is not nil, keys
+// that exist at dst and are identical to the source key are
+// considered migrated. A previous migration may have created
+// them but got interrupted before recording them.
+func migrateKey(ctx context.Context, src, dst kv.Store[string, []byte], name string, force, merge bool, journal *os.File) (bool, error) {
+	key, err := src.Get(ctx, name)
+	if err != nil {
+		return false, err
+	}
+
+	err = dst.Create(ctx, name, key)
+	if errors.Is(err, kes.ErrKeyExists) {
+		switch {
+		case journal != nil && isMigratedKey(ctx, dst, name, key):
+			err = nil
+		case merge:
+			return false, recordMigratedKey(journal, name)
+		case force:
+			if err = dst.Delete(ctx, name); err != nil {
+				return false, err
+			}
+			err = dst.Create(ctx, name, key)
+		}
+	}
+	if err != nil {
+		return false, err
+	}
+	if err = recordMigratedKey(journal, name); err != nil {
+		return false, fmt.Errorf("failed to record key: %v", err)
+	}
+	return true, nil
+}
+
+// isMigratedKey reports whether dst contains the given key
+// under the given name.
+func isMigratedKey(ctx context.Context, dst kv.Store[string, []byte], name string, key []byte) bool {
+	value, err := dst.Get(ctx, name)
+	return err == nil && bytes.Equal(value, key)
+}
+
+// readMigrateJournal reads the names of all keys recorded in
+// the given journal file. The file contains one key name per
+// line. It returns an empty set if the file does not exist.
+func readMigrateJournal(filename string) (map[string]struct{}, error) {
+	file, err := os.Open(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]struct{}{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	names := map[string]struct{}{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if name := scanner.Text(); name != "" {
+			names[name] = struct{}{}
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// recordMigratedKey appends the key name to the journal
+// file, if not nil, such that an interrupted migration
+// can be resumed.
+func recordMigratedKey(journal *os.File, name string) error {
+	if journal == nil {
+		return nil
+	}
+	_, err := journal.WriteString(name + "\n")
+	return err
+}
+
 // quiet is a boolean flag.Value that can print
 // to STDOUT.
 //
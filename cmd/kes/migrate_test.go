@@ -0,0 +1,124 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/keystore/mem"
+)
+
+var verifyMigrateRateTests = []struct {
+	Rate       int
+	ShouldFail bool
+}{
+	{Rate: 0},                    // 0
+	{Rate: 1},                    // 1
+	{Rate: 50},                   // 2
+	{Rate: maxMigrateRate},       // 3
+	{Rate: -1, ShouldFail: true}, // 4
+	{Rate: maxMigrateRate + 1, ShouldFail: true}, // 5
+	{Rate: 1_000_000_001, ShouldFail: true},      // 6
+}
+
+func TestVerifyMigrateRate(t *testing.T) {
+	for i, test := range verifyMigrateRateTests {
+		err := verifyMigrateRate(test.Rate)
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: rate '%d' should be invalid", i, test.Rate)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: rate '%d' should be valid: %v", i, test.Rate, err)
+		}
+	}
+}
+
+func TestMigrateKeyResume(t *testing.T) {
+	ctx := context.Background()
+
+	var src, dst mem.Store
+	for _, name := range []string{"key-1", "key-2", "key-3"} {
+		if err := src.Create(ctx, name, []byte(name)); err != nil {
+			t.Fatalf("Failed to create source key '%s': %v", name, err)
+		}
+	}
+
+	// A previous migration has created 'key-1' but got interrupted
+	// before recording it. 'key-2' exists at the target but differs
+	// from the source key.
+	if err := dst.Create(ctx, "key-1", []byte("key-1")); err != nil {
+		t.Fatalf("Failed to create target key: %v", err)
+	}
+	if err := dst.Create(ctx, "key-2", []byte("other")); err != nil {
+		t.Fatalf("Failed to create target key: %v", err)
+	}
+
+	// Without a journal, existing keys are not considered migrated.
+	if _, err := migrateKey(ctx, &src, &dst, "key-1", false, false, nil); !errors.Is(err, kes.ErrKeyExists) {
+		t.Fatalf("Migrating existing key without journal: got '%v' - want '%v'", err, kes.ErrKeyExists)
+	}
+
+	filename := filepath.Join(t.TempDir(), "migrate.log")
+	journal, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		t.Fatalf("Failed to open journal: %v", err)
+	}
+	defer journal.Close()
+
+	if ok, err := migrateKey(ctx, &src, &dst, "key-1", false, false, journal); err != nil || !ok {
+		t.Fatalf("Failed to resume migration of 'key-1': got '%v' and '%v' - want 'true' and '<nil>'", ok, err)
+	}
+	if _, err = migrateKey(ctx, &src, &dst, "key-2", false, false, journal); !errors.Is(err, kes.ErrKeyExists) {
+		t.Fatalf("Migrating modified key: got '%v' - want '%v'", err, kes.ErrKeyExists)
+	}
+	if ok, err := migrateKey(ctx, &src, &dst, "key-2", false, true, journal); err != nil || ok {
+		t.Fatalf("Failed to merge 'key-2': got '%v' and '%v' - want 'false' and '<nil>'", ok, err)
+	}
+	if ok, err := migrateKey(ctx, &src, &dst, "key-3", false, false, journal); err != nil || !ok {
+		t.Fatalf("Failed to migrate 'key-3': got '%v' and '%v' - want 'true' and '<nil>'", ok, err)
+	}
+
+	migrated, err := readMigrateJournal(filename)
+	if err != nil {
+		t.Fatalf("Failed to read journal: %v", err)
+	}
+	if len(migrated) != 3 {
+		t.Fatalf("Journal mismatch: got %d keys - want %d", len(migrated), 3)
+	}
+	for _, name := range []string{"key-1", "key-2", "key-3"} {
+		if _, ok := migrated[name]; !ok {
+			t.Fatalf("Journal does not contain '%s'", name)
+		}
+	}
+	if value, err := dst.Get(ctx, "key-2"); err != nil || string(value) != "other" {
+		t.Fatalf("Merging modified the target key 'key-2': got '%s' and '%v'", value, err)
+	}
+}
+
+func TestReadMigrateJournal(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "migrate.log")
+	migrated, err := readMigrateJournal(filename)
+	if err != nil {
+		t.Fatalf("Failed to read non-existing journal: %v", err)
+	}
+	if len(migrated) != 0 {
+		t.Fatalf("Non-existing journal is not empty: got %d keys", len(migrated))
+	}
+
+	if err = os.WriteFile(filename, []byte("key-1\n\nkey-2\nkey-1\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write journal: %v", err)
+	}
+	if migrated, err = readMigrateJournal(filename); err != nil {
+		t.Fatalf("Failed to read journal: %v", err)
+	}
+	if len(migrated) != 2 {
+		t.Fatalf("Journal mismatch: got %d keys - want %d", len(migrated), 2)
+	}
+}
@@ -0,0 +1,253 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/edge"
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/kv"
+	flag "github.com/spf13/pflag"
+)
+
+const mirrorCmdUsage = `Usage:
+    kes mirror <command>
+
+Commands:
+    check                    Check a keystore and its mirror for inconsistencies.
+
+Options:
+    -h, --help               Print command line options.
+`
+
+func mirrorCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, mirrorCmdUsage) }
+
+	subCmds := commands{
+		"check": mirrorCheckCmd,
+	}
+
+	if len(args) < 2 {
+		cmd.Usage()
+		cli.Exit(2)
+	}
+	if cmd, ok := subCmds[args[1]]; ok {
+		cmd(args[1:])
+		return
+	}
+
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes mirror --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatalf("%q is not a mirror command. See 'kes mirror --help'", cmd.Arg(0))
+	}
+	cmd.Usage()
+	cli.Exit(2)
+}
+
+const mirrorCheckCmdUsage = `Usage:
+    kes mirror check [options] [<pattern>]
+
+Compares the keys at the keystore of a KES server config with the
+keys at its mirror and prints every key that is missing at the
+mirror, differs from the keystore or exists only at the mirror.
+It exits with a non-zero exit code if any inconsistency is found.
+
+Run it before switching from the keystore to its mirror to verify
+that all keys have been migrated.
+
+Options:
+    --config <PATH>          Path to the KES server config file.
+    -e, --enclave <name>     Check the keystore of the given enclave.
+
+    -q, --quiet              Do not print progress information.
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes mirror check --config config.yml
+    $ kes mirror check --config config.yml 'my-key*'
+`
+
+func mirrorCheckCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, mirrorCheckCmdUsage) }
+
+	var (
+		configPath  string
+		enclaveName string
+		quietFlag   bool
+	)
+	cmd.StringVar(&configPath, "config", "", "Path to the server config file")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Check the keystore of the given enclave")
+	cmd.BoolVarP(&quietFlag, "quiet", "q", false, "Do not print progress information")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes mirror check --help'", err)
+	}
+	if cmd.NArg() > 1 {
+		cli.Fatal("too many arguments. See 'kes mirror check --help'")
+	}
+	if configPath == "" {
+		cli.Fatal("no config file specified. Use '--config' to specify a config file")
+	}
+
+	quiet := quiet(quietFlag)
+	pattern := cmd.Arg(0)
+	if pattern == "" {
+		pattern = "*"
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Kill, os.Interrupt)
+	defer cancel()
+
+	file, err := os.Open(configPath)
+	if err != nil {
+		cli.Fatalf("failed to read config file: %v", err)
+	}
+	config, err := edge.ReadServerConfigYAML(file)
+	if err != nil {
+		cli.Fatalf("failed to read config file: %v", err)
+	}
+	file.Close()
+
+	store, mirror := config.KeyStore, config.KeyStoreMirror
+	if enclaveName != "" {
+		enclave, ok := config.Enclaves[enclaveName]
+		if !ok {
+			cli.Fatalf("enclave '%s' does not exist", enclaveName)
+		}
+		store, mirror = enclave.KeyStore, enclave.KeyStoreMirror
+	}
+	if mirror == nil {
+		cli.Fatal("no keystore mirror specified")
+	}
+
+	src, err := store.Connect(ctx)
+	if err != nil {
+		cli.Fatalf("failed to connect to keystore: %v", err)
+	}
+	dst, err := mirror.Connect(ctx)
+	if err != nil {
+		cli.Fatalf("failed to connect to mirror: %v", err)
+	}
+
+	// Only keys that start with the literal prefix
+	// of the pattern can match.
+	prefix := pattern
+	if i := strings.IndexAny(pattern, `*?[\`); i >= 0 {
+		prefix = pattern[:i]
+	}
+
+	var (
+		n            int
+		inconsistent int
+		names        = map[string]struct{}{}
+	)
+	report := func(kind, name string) {
+		quiet.ClearLine()
+		fmt.Printf("%-9s %s\n", kind, name)
+		inconsistent++
+	}
+
+	// First, compare all keys at the keystore with
+	// the corresponding keys at the mirror.
+	const BatchSize = 100
+	batch := make([]string, 0, BatchSize)
+	compare := func() {
+		if len(batch) == 0 {
+			return
+		}
+		values, errs := src.GetMany(ctx, batch)
+		mirrored, mirrorErrs := dst.GetMany(ctx, batch)
+		for i, name := range batch {
+			if errs[i] != nil {
+				if isNotExists(errs[i]) { // Deleted while checking
+					continue
+				}
+				quiet.ClearLine()
+				cli.Fatalf("failed to check %q: %v", name, errs[i])
+			}
+			switch err := mirrorErrs[i]; {
+			case isNotExists(err):
+				report("missing", name)
+			case err != nil:
+				quiet.ClearLine()
+				cli.Fatalf("failed to check %q at mirror: %v", name, err)
+			case !bytes.Equal(values[i], mirrored[i]):
+				report("differs", name)
+			}
+		}
+		n += len(batch)
+		quiet.ClearLine()
+		quiet.Printf("Checked keys: %d", n)
+		batch = batch[:0]
+	}
+
+	iter, err := src.List(ctx, prefix)
+	if err != nil {
+		cli.Fatalf("failed to list keys: %v", err)
+	}
+	for name, ok := iter.Next(); ok; name, ok = iter.Next() {
+		if ok, _ := filepath.Match(pattern, name); !ok {
+			continue
+		}
+		names[name] = struct{}{}
+		if batch = append(batch, name); len(batch) == BatchSize {
+			compare()
+		}
+	}
+	if err = iter.Close(); err != nil {
+		quiet.ClearLine()
+		cli.Fatalf("failed to list keys: %v", err)
+	}
+	compare()
+
+	// Then, find all keys that only exist at the mirror.
+	iter, err = dst.List(ctx, prefix)
+	if err != nil {
+		quiet.ClearLine()
+		cli.Fatalf("failed to list keys at mirror: %v", err)
+	}
+	for name, ok := iter.Next(); ok; name, ok = iter.Next() {
+		if ok, _ := filepath.Match(pattern, name); !ok {
+			continue
+		}
+		if _, ok := names[name]; !ok {
+			report("orphaned", name)
+		}
+	}
+	if err = iter.Close(); err != nil {
+		quiet.ClearLine()
+		cli.Fatalf("failed to list keys at mirror: %v", err)
+	}
+
+	quiet.ClearLine()
+	quiet.Printf("Checked keys: %d\n", n)
+	if inconsistent > 0 {
+		cli.Fatalf("found %d inconsistent keys", inconsistent)
+	}
+}
+
+// isNotExists reports whether err indicates that
+// a key does not exist.
+func isNotExists(err error) bool {
+	return errors.Is(err, kes.ErrKeyNotFound) || errors.Is(err, kv.ErrNotExists)
+}
@@ -9,15 +9,16 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"sort"
 	"strings"
 	"time"
 
-	tui "github.com/charmbracelet/lipgloss"
 	"github.com/minio/kes-go"
 	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/internal/tui"
 	flag "github.com/spf13/pflag"
 )
 
@@ -31,6 +32,8 @@ Commands:
     ls                       List policies.
     rm                       Remove a policy.
     show                     Display a policy.
+    duplicates               List policies with identical rules.
+    merge                    Merge identical policies into one.
 
 Options:
     -h, --help               Print command line options.
@@ -47,10 +50,13 @@ func policyCmd(args []string) {
 		"ls":     lsPolicyCmd,
 		"rm":     rmPolicyCmd,
 		"show":   showPolicyCmd,
+
+		"duplicates": duplicatesPolicyCmd,
+		"merge":      mergePolicyCmd,
 	}
 	if len(args) < 2 {
 		cmd.Usage()
-		os.Exit(2)
+		cli.Exit(2)
 	}
 	if cmd, ok := subCmds[args[1]]; ok {
 		cmd(args[1:])
@@ -58,7 +64,7 @@ func policyCmd(args []string) {
 	}
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(2)
+			cli.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes policy --help'", err)
 	}
@@ -66,20 +72,27 @@ func policyCmd(args []string) {
 		cli.Fatalf("%q is not a policy command. See 'kes policy --help'", cmd.Arg(0))
 	}
 	cmd.Usage()
-	os.Exit(2)
+	cli.Exit(2)
 }
 
 const createPolicyCmdUsage = `Usage:
     kes policy create [options] <name> <path>
 
+Creates a new policy or replaces an existing one. Before replacing an
+existing policy, the server computes which identities gain or lose
+access to which API paths. If the new policy grants access to any API
+path not accessible before, --force is required.
+
 Options:
+    -f, --force              Replace the policy even if it broadens access.
     -k, --insecure           Skip TLS certificate validation.
     -e, --enclave <name>     Operate within the specified enclave.
 
     -h, --help               Print command line options.
 
 Examples:
-    $ kes policy add my-policy ./policy.json
+    $ kes policy create my-policy ./policy.json
+    $ kes policy create --force my-policy ./policy.json
 `
 
 func createPolicyCmd(args []string) {
@@ -87,14 +100,16 @@ func createPolicyCmd(args []string) {
 	cmd.Usage = func() { fmt.Fprint(os.Stderr, createPolicyCmdUsage) }
 
 	var (
+		forceFlag          bool
 		insecureSkipVerify bool
 		enclaveName        string
 	)
+	cmd.BoolVarP(&forceFlag, "force", "f", false, "Replace the policy even if it broadens access")
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(2)
+			cli.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes policy create --help'", err)
 	}
@@ -124,18 +139,93 @@ func createPolicyCmd(args []string) {
 	defer cancelCtx()
 
 	enclave := newEnclave(enclaveName, insecureSkipVerify)
+
+	type Request struct {
+		Allow []string `json:"allow,omitempty"`
+		Deny  []string `json:"deny,omitempty"`
+	}
+	type Response struct {
+		Exists     bool           `json:"exists"`
+		Identities []kes.Identity `json:"identities"`
+		Granted    []string       `json:"granted"`
+		Revoked    []string       `json:"revoked"`
+	}
+	var diff Response
+	err = send(ctx, enclave, http.MethodPost, "/v1/policy/diff/"+name, nil, Request{
+		Allow: policy.Allow,
+		Deny:  policy.Deny,
+	}, &diff)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			cli.Exit(1)
+		}
+		// Servers that cannot compute a policy diff still accept
+		// the policy. However, the impact remains unknown.
+		if kerr, ok := err.(kes.Error); !ok || kerr.Status() != http.StatusNotImplemented {
+			cli.Fatalf("failed to analyze policy %q: %v", name, err)
+		}
+	}
+	if diff.Exists && (len(diff.Granted) > 0 || len(diff.Revoked) > 0) {
+		printPolicyDiff(name, diff.Identities, diff.Granted, diff.Revoked)
+		if len(diff.Granted) > 0 && !forceFlag {
+			cli.Fatalf("policy %q broadens access. Use '--force' to replace it anyway", name)
+		}
+	}
+
 	if err := enclave.SetPolicy(ctx, name, &policy); err != nil {
 		if errors.Is(err, context.Canceled) {
-			os.Exit(1)
+			cli.Exit(1)
 		}
 		cli.Fatalf("failed to create policy %q: %v", name, err)
 	}
 }
 
+// printPolicyDiff prints which identities gain and lose
+// access to which API paths to OS stderr.
+func printPolicyDiff(name string, identities []kes.Identity, granted, revoked []string) {
+	var green, red, faint tui.Style
+	if isTerm(os.Stderr) {
+		green = green.Foreground(tui.Color("#00d700"))
+		red = red.Foreground(tui.Color("#ac0000"))
+		faint = faint.Faint(true)
+	}
+
+	fmt.Fprintf(os.Stderr, "Replacing policy %q changes access of ", name)
+	switch len(identities) {
+	case 0:
+		fmt.Fprintln(os.Stderr, "no assigned identities:")
+	case 1:
+		fmt.Fprintln(os.Stderr, "1 identity:")
+	default:
+		fmt.Fprintf(os.Stderr, "%d identities:\n", len(identities))
+	}
+	for _, identity := range identities {
+		fmt.Fprintln(os.Stderr, " ", faint.Render(identity.String()))
+	}
+	fmt.Fprintln(os.Stderr)
+	for _, path := range granted {
+		fmt.Fprintln(os.Stderr, green.Render("  + "+path))
+	}
+	for _, path := range revoked {
+		fmt.Fprintln(os.Stderr, red.Render("  - "+path))
+	}
+}
+
 const assignPolicyCmdUsage = `Usage:
     kes policy assign [options] <policy> <identity>...
 
+Once an identity has expired, the server rejects any request issued
+by it. Expired identities remain assigned until they are removed.
+
+Labels, like the team or service an identity belongs to, are free-form
+and have no effect on what an identity is allowed to do. Assigning a
+policy again replaces all existing labels of an identity.
+
 Options:
+        --ttl <duration>     Expire the identities after the given duration.
+        --expires-at <time>  Expire the identities at the given RFC 3339 time.
+        --label <name=value> Label the identities. May be specified multiple
+                             times.
     -k, --insecure           Skip TLS certificate validation.
     -e, --enclave <name>     Operate within the specified enclave.
 
@@ -143,6 +233,8 @@ Options:
 
 Examples:
     $ kes policy assign my-policy 032dc24c353f1baf782660635ade933c601095ba462a44d1484a511c4271e212
+    $ kes policy assign --ttl 1h my-policy 032dc24c353f1baf782660635ade933c601095ba462a44d1484a511c4271e212
+    $ kes policy assign --label team=storage my-policy 032dc24c353f1baf782660635ade933c601095ba462a44d1484a511c4271e212
 `
 
 func assignPolicyCmd(args []string) {
@@ -150,14 +242,20 @@ func assignPolicyCmd(args []string) {
 	cmd.Usage = func() { fmt.Fprint(os.Stderr, assignPolicyCmdUsage) }
 
 	var (
+		ttl                time.Duration
+		expiresAt          string
+		labelFlag          []string
 		insecureSkipVerify bool
 		enclaveName        string
 	)
+	cmd.DurationVar(&ttl, "ttl", 0, "Expire the identities after the given duration")
+	cmd.StringVar(&expiresAt, "expires-at", "", "Expire the identities at the given RFC 3339 time")
+	cmd.StringArrayVar(&labelFlag, "label", nil, "Label the identities")
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(2)
+			cli.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes policy assign --help'", err)
 	}
@@ -168,6 +266,23 @@ func assignPolicyCmd(args []string) {
 	if cmd.NArg() == 1 {
 		cli.Fatal("no identity specified. See 'kes policy assign --help'")
 	}
+	if ttl < 0 {
+		cli.Fatal("invalid TTL: TTL must not be negative. See 'kes policy assign --help'")
+	}
+	if ttl > 0 && expiresAt != "" {
+		cli.Fatal("'--ttl' and '--expires-at' cannot be specified both. See 'kes policy assign --help'")
+	}
+	var labels map[string]string
+	for _, label := range labelFlag {
+		name, value, ok := strings.Cut(label, "=")
+		if !ok {
+			cli.Fatalf("invalid label '%s': expected 'name=value'. See 'kes policy assign --help'", label)
+		}
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[name] = value
+	}
 
 	policy := cmd.Arg(0)
 	enclave := newEnclave(enclaveName, insecureSkipVerify)
@@ -175,10 +290,30 @@ func assignPolicyCmd(args []string) {
 	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
 	defer cancelCtx()
 
+	type Request struct {
+		Identity  kes.Identity      `json:"identity"`
+		ExpiresAt string            `json:"expires_at,omitempty"`
+		TTL       string            `json:"ttl,omitempty"`
+		Labels    map[string]string `json:"labels,omitempty"`
+	}
+	assignPolicy := func(identity kes.Identity) error {
+		if ttl == 0 && expiresAt == "" && len(labels) == 0 {
+			return enclave.AssignPolicy(ctx, policy, identity)
+		}
+		req := Request{
+			Identity:  identity,
+			ExpiresAt: expiresAt,
+			Labels:    labels,
+		}
+		if ttl > 0 {
+			req.TTL = ttl.String()
+		}
+		return send(ctx, enclave, http.MethodPost, "/v1/policy/assign/"+policy, nil, req, nil)
+	}
 	for _, identity := range cmd.Args()[1:] { // cmd.Arg(0) is the policy
-		if err := enclave.AssignPolicy(ctx, policy, kes.Identity(identity)); err != nil {
+		if err := assignPolicy(kes.Identity(identity)); err != nil {
 			if errors.Is(err, context.Canceled) {
-				os.Exit(1)
+				cli.Exit(1)
 			}
 			cli.Fatalf("failed to assign policy %q to %q: %v", policy, identity, err)
 		}
@@ -221,7 +356,7 @@ func lsPolicyCmd(args []string) {
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(2)
+			cli.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes policy ls --help'", err)
 	}
@@ -242,7 +377,7 @@ func lsPolicyCmd(args []string) {
 	policies, err := enclave.ListPolicies(ctx, pattern)
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
-			os.Exit(1)
+			cli.Exit(1)
 		}
 		cli.Fatalf("failed to list policies: %v", err)
 	}
@@ -316,7 +451,7 @@ func rmPolicyCmd(args []string) {
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(2)
+			cli.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes policy rm --help'", err)
 	}
@@ -331,7 +466,7 @@ func rmPolicyCmd(args []string) {
 	for _, name := range cmd.Args() {
 		if err := enclave.DeletePolicy(ctx, name); err != nil {
 			if errors.Is(err, context.Canceled) {
-				os.Exit(1)
+				cli.Exit(1)
 			}
 			cli.Fatalf("failed to delete policy %q: %v", name, err)
 		}
@@ -373,7 +508,7 @@ func infoPolicyCmd(args []string) {
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(2)
+			cli.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes policy show --help'", err)
 	}
@@ -389,7 +524,7 @@ func infoPolicyCmd(args []string) {
 	info, err := enclave.DescribePolicy(ctx, name)
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
-			os.Exit(1)
+			cli.Exit(1)
 		}
 		cli.Fatal(err)
 	}
@@ -451,7 +586,7 @@ func showPolicyCmd(args []string) {
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(2)
+			cli.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes policy show --help'", err)
 	}
@@ -468,7 +603,7 @@ func showPolicyCmd(args []string) {
 	policy, err := enclave.GetPolicy(ctx, name)
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
-			os.Exit(1)
+			cli.Exit(1)
 		}
 		cli.Fatalf("failed to show policy '%s': %v", name, err)
 	}
@@ -530,3 +665,178 @@ func showPolicyCmd(args []string) {
 		}
 	}
 }
+
+const duplicatesPolicyCmdUsage = `Usage:
+    kes policy duplicates [options]
+
+Lists groups of policies that consist of the same allow and deny
+rules. The order of rules does not matter. Identical policies can
+be merged with 'kes policy merge'.
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+        --json               Print duplicates in JSON format.
+        --color <when>       Specify when to use colored output. The automatic
+                             mode only enables colors if an interactive terminal
+                             is detected - colors are automatically disabled if
+                             the output goes to a pipe.
+                             Possible values: *auto*, never, always.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes policy duplicates
+`
+
+func duplicatesPolicyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, duplicatesPolicyCmdUsage) }
+
+	var (
+		jsonFlag           bool
+		colorFlag          colorOption
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.BoolVar(&jsonFlag, "json", false, "Print duplicates in JSON format")
+	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes policy duplicates --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatal("too many arguments. See 'kes policy duplicates --help'")
+	}
+
+	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancelCtx()
+
+	type Duplicate struct {
+		Policies   []string `json:"policies"`
+		Allow      []string `json:"allow,omitempty"`
+		Deny       []string `json:"deny,omitempty"`
+		Identities int      `json:"identities"`
+	}
+	type Response struct {
+		Duplicates []Duplicate `json:"duplicates"`
+	}
+	var resp Response
+	enclave := newEnclave(enclaveName, insecureSkipVerify)
+	if err := send(ctx, enclave, http.MethodGet, "/v1/policy/duplicates", nil, nil, &resp); err != nil {
+		if errors.Is(err, context.Canceled) {
+			cli.Exit(1)
+		}
+		cli.Fatalf("failed to list duplicate policies: %v", err)
+	}
+
+	if jsonFlag {
+		encoder := json.NewEncoder(os.Stdout)
+		if isTerm(os.Stdout) {
+			encoder.SetIndent("", "  ")
+		}
+		if err := encoder.Encode(resp); err != nil {
+			cli.Fatal(err)
+		}
+		return
+	}
+	if len(resp.Duplicates) == 0 {
+		fmt.Fprintln(os.Stderr, "No duplicate policies found.")
+		return
+	}
+
+	var faint, policyStyle tui.Style
+	if colorFlag.Colorize() {
+		const ColorPolicy tui.Color = "#2e42d1"
+		faint = faint.Faint(true)
+		policyStyle = policyStyle.Foreground(ColorPolicy)
+	}
+	for i, duplicate := range resp.Duplicates {
+		if i > 0 {
+			fmt.Println()
+		}
+		policies := make([]string, 0, len(duplicate.Policies))
+		for _, name := range duplicate.Policies {
+			policies = append(policies, policyStyle.Render(name))
+		}
+		fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Policies")), strings.Join(policies, ", "))
+		fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Identities")), duplicate.Identities)
+		for _, rule := range duplicate.Allow {
+			fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Allow")), rule)
+		}
+		for _, rule := range duplicate.Deny {
+			fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Deny")), rule)
+		}
+	}
+}
+
+const mergePolicyCmdUsage = `Usage:
+    kes policy merge [options] <target> <policy>...
+
+Merges one or multiple policies into the target policy. All identities
+assigned to one of the policies get assigned to the target policy.
+Then the merged policies are removed.
+
+Only policies that consist of the same allow and deny rules as the
+target policy can be merged. Hence, merging policies never changes
+what any identity is allowed to do.
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes policy merge my-policy my-policy-copy1 my-policy-copy2
+`
+
+func mergePolicyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, mergePolicyCmdUsage) }
+
+	var (
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes policy merge --help'", err)
+	}
+	if cmd.NArg() == 0 {
+		cli.Fatal("no target policy specified. See 'kes policy merge --help'")
+	}
+	if cmd.NArg() == 1 {
+		cli.Fatal("no policy to merge specified. See 'kes policy merge --help'")
+	}
+
+	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancelCtx()
+
+	type Request struct {
+		Policies []string `json:"policies"`
+	}
+	type Response struct {
+		Identities []kes.Identity `json:"identities"`
+	}
+	var (
+		target = cmd.Arg(0)
+		resp   Response
+	)
+	enclave := newEnclave(enclaveName, insecureSkipVerify)
+	if err := send(ctx, enclave, http.MethodPost, "/v1/policy/merge/"+target, nil, Request{Policies: cmd.Args()[1:]}, &resp); err != nil {
+		if errors.Is(err, context.Canceled) {
+			cli.Exit(1)
+		}
+		cli.Fatalf("failed to merge policies into %q: %v", target, err)
+	}
+	fmt.Fprintf(os.Stderr, "Merged %d policies into %q and reassigned %d identities.\n", cmd.NArg()-1, target, len(resp.Identities))
+}
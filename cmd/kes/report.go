@@ -0,0 +1,168 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/cli"
+	flag "github.com/spf13/pflag"
+)
+
+const reportCmdUsage = `Usage:
+    kes report <command>
+
+Commands:
+    access                   Print which identity can access which API.
+
+Options:
+    -h, --help               Print command line options.
+`
+
+func reportCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, reportCmdUsage) }
+
+	subCmds := commands{
+		"access": accessReportCmd,
+	}
+
+	if len(args) < 2 {
+		cmd.Usage()
+		cli.Exit(2)
+	}
+	if cmd, ok := subCmds[args[1]]; ok {
+		cmd(args[1:])
+		return
+	}
+
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes report --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatalf("%q is not a report command. See 'kes report --help'", cmd.Arg(0))
+	}
+	cmd.Usage()
+	cli.Exit(2)
+}
+
+const accessReportCmdUsage = `Usage:
+    kes report access [options]
+
+Prints, for every identity within the enclave, which APIs it can
+access based on its policy. The report is a matrix with one row
+per identity and one column per API. Each cell is either 'full',
+'partial' or 'none'. An identity has partial access to an API if
+its policy allows the API only for some arguments, like a subset
+of key names.
+
+Options:
+        --format <format>    Print the report in the given format.
+                             Possible values: *csv*, json.
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes report access > access.csv
+    $ kes report access --format json
+`
+
+func accessReportCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, accessReportCmdUsage) }
+
+	var (
+		formatFlag         string
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.StringVar(&formatFlag, "format", "csv", "Print the report in the given format")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes report access --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatal("too many arguments. See 'kes report access --help'")
+	}
+	if formatFlag != "csv" && formatFlag != "json" {
+		cli.Fatalf("invalid format '%s'. See 'kes report access --help'", formatFlag)
+	}
+
+	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancelCtx()
+
+	type Identity struct {
+		Identity  kes.Identity      `json:"identity"`
+		Policy    string            `json:"policy,omitempty"`
+		IsAdmin   bool              `json:"admin,omitempty"`
+		ExpiresAt time.Time         `json:"expires_at,omitempty"`
+		Access    map[string]string `json:"access"`
+	}
+	type Response struct {
+		APIs       []string   `json:"apis"`
+		Identities []Identity `json:"identities"`
+	}
+	var resp Response
+	enclave := newEnclave(enclaveName, insecureSkipVerify)
+	if err := send(ctx, enclave, http.MethodGet, "/v1/report/access", nil, nil, &resp); err != nil {
+		if errors.Is(err, context.Canceled) {
+			cli.Exit(1)
+		}
+		cli.Fatalf("failed to create access report: %v", err)
+	}
+
+	if formatFlag == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		if isTerm(os.Stdout) {
+			encoder.SetIndent("", "  ")
+		}
+		if err := encoder.Encode(resp); err != nil {
+			cli.Fatal(err)
+		}
+		return
+	}
+
+	writer := csv.NewWriter(os.Stdout)
+	writer.Write(append([]string{"identity", "policy", "admin", "expires_at"}, resp.APIs...))
+	for _, identity := range resp.Identities {
+		var expiresAt string
+		if !identity.ExpiresAt.IsZero() {
+			expiresAt = identity.ExpiresAt.Format(time.RFC3339)
+		}
+		record := make([]string, 0, 4+len(resp.APIs))
+		record = append(record, identity.Identity.String(), identity.Policy, strconv.FormatBool(identity.IsAdmin), expiresAt)
+		for _, api := range resp.APIs {
+			access, ok := identity.Access[api]
+			if !ok {
+				access = "none"
+			}
+			record = append(record, access)
+		}
+		writer.Write(record)
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		cli.Fatalf("failed to write access report: %v", err)
+	}
+}
@@ -0,0 +1,438 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/auth"
+)
+
+// send sends an HTTP request to the KES server API at apiPath
+// within the given enclave. If req is not nil, it gets sent as
+// JSON request body. If resp is not nil, send decodes the JSON
+// response body into resp.
+//
+// It tries all endpoints of the enclave until one responds and
+// converts error responses into kes.Error values.
+//
+// send is used for server APIs that are not yet exposed by the
+// KES SDK.
+func send(ctx context.Context, enclave *kes.Enclave, method, apiPath string, query url.Values, req, resp any) error {
+	response, err := do(ctx, enclave, method, apiPath, query, req)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if resp == nil {
+		return nil
+	}
+	return json.NewDecoder(mem.LimitReader(response.Body, 1*mem.MiB)).Decode(resp)
+}
+
+// do is like send but returns the response instead of
+// decoding it. The caller must close the response body.
+//
+// do is used for server APIs that stream their response.
+func do(ctx context.Context, enclave *kes.Enclave, method, apiPath string, query url.Values, req any) (*http.Response, error) {
+	var body []byte
+	if req != nil {
+		var err error
+		if body, err = json.Marshal(req); err != nil {
+			return nil, err
+		}
+	}
+	if enclave.Name != "" {
+		if query == nil {
+			query = url.Values{}
+		}
+		query.Set("enclave", enclave.Name)
+	}
+
+	var err error
+	for _, endpoint := range enclave.Endpoints {
+		var u *url.URL
+		if u, err = url.Parse(endpoint); err != nil {
+			return nil, err
+		}
+		u = u.JoinPath(apiPath)
+		if strings.HasSuffix(apiPath, "/") && !strings.HasSuffix(u.Path, "/") {
+			u.Path += "/"
+		}
+		u.RawQuery = query.Encode()
+
+		var r *http.Request
+		if r, err = http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body)); err != nil {
+			return nil, err
+		}
+		if req != nil {
+			r.Header.Set("Content-Type", "application/json")
+		}
+
+		var response *http.Response
+		if response, err = enclave.HTTPClient.Do(r); err != nil {
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				return nil, err
+			}
+			continue // Try the next endpoint
+		}
+		if response.StatusCode >= 400 {
+			defer response.Body.Close()
+			return nil, parseErrorResponse(response)
+		}
+		return response, nil
+	}
+	if err == nil {
+		err = errors.New("no KES server endpoint")
+	}
+	return nil, err
+}
+
+// parseErrorResponse returns a kes.Error containing
+// the response status code and error message.
+func parseErrorResponse(resp *http.Response) error {
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		type Response struct {
+			Message string `json:"message"`
+		}
+		var response Response
+		if err := json.NewDecoder(mem.LimitReader(resp.Body, 1*mem.MiB)).Decode(&response); err != nil {
+			return err
+		}
+		switch {
+		case resp.StatusCode == kes.ErrKeyNotFound.Status() && response.Message == kes.ErrKeyNotFound.Error():
+			return kes.ErrKeyNotFound
+		case resp.StatusCode == kes.ErrKeyExists.Status() && response.Message == kes.ErrKeyExists.Error():
+			return kes.ErrKeyExists
+		case resp.StatusCode == kes.ErrNotAllowed.Status() && response.Message == kes.ErrNotAllowed.Error():
+			return kes.ErrNotAllowed
+		}
+		return kes.NewError(resp.StatusCode, response.Message)
+	}
+
+	var sb strings.Builder
+	if _, err := io.Copy(&sb, mem.LimitReader(resp.Body, 1*mem.MiB)); err != nil {
+		return err
+	}
+	return kes.NewError(resp.StatusCode, sb.String())
+}
+
+// Global request options set by the 'kes --timeout',
+// 'kes --retry' and 'kes --as' command line flags.
+var (
+	requestTimeout time.Duration
+	requestRetry   int
+	requestAs      string
+)
+
+// impersonateTransport is an http.RoundTripper that sends
+// requests on behalf of another identity. The server only
+// accepts such requests from its admin.
+type impersonateTransport struct {
+	// Transport is the underlying http.RoundTripper.
+	Transport http.RoundTripper
+
+	// Identity is the impersonated identity.
+	Identity kes.Identity
+}
+
+// RoundTrip sends the request on behalf of t.Identity.
+func (t *impersonateTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set(auth.ImpersonateHeader, t.Identity.String())
+	return t.Transport.RoundTrip(req)
+}
+
+// bearerTransport is an http.RoundTripper that authenticates
+// requests with an API key sent as bearer token instead of a
+// TLS client certificate.
+type bearerTransport struct {
+	// Transport is the underlying http.RoundTripper.
+	Transport http.RoundTripper
+
+	// APIKey is the API key sent as bearer token.
+	APIKey kes.APIKey
+}
+
+// RoundTrip sends the request with t.APIKey as bearer token.
+func (t *bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.APIKey.String())
+	return t.Transport.RoundTrip(req)
+}
+
+// retryTransport is an http.RoundTripper that aborts
+// requests when the server does not respond in time and
+// retries requests that failed due to a network error or
+// because the server was temporarily unavailable.
+//
+// Only GET and HEAD requests are retried in any of these
+// cases. Any other request may have changed the server's
+// state already. Hence, it is only retried when it failed
+// before it has been written to the connection.
+//
+// It also honors the server's rate limit headers. Once
+// the server reports that no requests remain, it holds
+// back further requests until the server can accept the
+// next request.
+type retryTransport struct {
+	// Transport is the underlying http.RoundTripper.
+	Transport http.RoundTripper
+
+	// Timeout is the time a single attempt may take
+	// until the server responds. Once the response
+	// headers have been received, the response body
+	// is not limited by Timeout.
+	//
+	// The zero value means no timeout.
+	Timeout time.Duration
+
+	// Retry is the number of times a request gets
+	// sent again before giving up.
+	Retry int
+
+	lock          sync.Mutex
+	throttleUntil time.Time
+	quotaWarning  sync.Once
+}
+
+// RoundTrip sends the request and retries it, with an
+// exponential backoff, at most t.Retry times.
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	const (
+		MinRetryDelay = 250 * time.Millisecond
+		MaxRetryDelay = 5 * time.Second
+	)
+
+	resp, sent, err := t.roundTrip(req)
+	delay := MinRetryDelay
+	for i := 0; i < t.Retry && retryable(req, resp, sent, err); i++ {
+		// A request with a body can only be retried if
+		// we can obtain a fresh copy of its body.
+		if req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				break
+			}
+			body, bErr := req.GetBody()
+			if bErr != nil {
+				break
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+		if resp != nil {
+			io.Copy(io.Discard, mem.LimitReader(resp.Body, 1*mem.MiB))
+			resp.Body.Close()
+		}
+
+		wait := delay + time.Duration(rand.Int63n(int64(delay/2)))
+		if after, ok := retryAfter(resp); ok {
+			wait = after
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+		if delay *= 2; delay > MaxRetryDelay {
+			delay = MaxRetryDelay
+		}
+		resp, sent, err = t.roundTrip(req)
+	}
+	return resp, err
+}
+
+// roundTrip sends the request once. It cancels the request
+// if no response has been received within t.Timeout.
+//
+// It reports whether any part of the request has been
+// written to the connection.
+func (t *retryTransport) roundTrip(req *http.Request) (*http.Response, bool, error) {
+	if err := t.throttle(req.Context()); err != nil {
+		return nil, false, err
+	}
+
+	var sent atomic.Bool
+	trace := &httptrace.ClientTrace{
+		WroteHeaderField: func(string, []string) { sent.Store(true) },
+		WroteHeaders:     func() { sent.Store(true) },
+	}
+	if t.Timeout <= 0 {
+		resp, err := t.Transport.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+		if err == nil {
+			t.observe(resp)
+		}
+		return resp, sent.Load(), err
+	}
+
+	ctx, cancel := context.WithCancel(req.Context())
+	timer := time.AfterFunc(t.Timeout, cancel)
+	resp, err := t.Transport.RoundTrip(req.WithContext(httptrace.WithClientTrace(ctx, trace)))
+	if !timer.Stop() && req.Context().Err() == nil {
+		if err == nil {
+			resp.Body.Close()
+		}
+		cancel()
+		return nil, sent.Load(), &timeoutError{After: t.Timeout}
+	}
+	if err != nil {
+		cancel()
+		return nil, sent.Load(), err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	t.observe(resp)
+	return resp, true, nil
+}
+
+// throttle waits until the server's rate limit has been
+// reset if the server has reported that no requests remain.
+func (t *retryTransport) throttle(ctx context.Context) error {
+	t.lock.Lock()
+	until := t.throttleUntil
+	t.lock.Unlock()
+
+	wait := time.Until(until)
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// observe inspects the rate limit and quota headers of the
+// response. If no requests remain, subsequent requests are
+// held back until the server can accept the next request.
+// If the enclave has reached its key quota, it prints a
+// warning once.
+func (t *retryTransport) observe(resp *http.Response) {
+	const MaxThrottle = 1 * time.Minute
+
+	if keys, err := strconv.Atoi(resp.Header.Get("X-Quota-Keys-Remaining")); err == nil && keys <= 0 {
+		t.quotaWarning.Do(func() {
+			fmt.Fprintln(os.Stderr, "Warning: the enclave has reached its key quota. No further keys can be created.")
+		})
+	}
+
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil || remaining > 0 {
+		return
+	}
+	var until time.Time
+	if after, ok := retryAfter(resp); ok {
+		until = time.Now().Add(after)
+	} else {
+		// The server refills its token bucket continuously.
+		// Hence, the next request can be sent after 1/limit
+		// of the time until the bucket is full again.
+		reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+		if err != nil {
+			return
+		}
+		limit, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+		if err != nil || limit <= 0 {
+			return
+		}
+		until = time.Now().Add(time.Until(time.Unix(reset, 0)) / time.Duration(limit))
+	}
+	if max := time.Now().Add(MaxThrottle); until.After(max) {
+		until = max
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if until.After(t.throttleUntil) {
+		t.throttleUntil = until
+	}
+}
+
+// retryAfter returns the duration the server has asked the
+// client to wait, through the Retry-After header, before
+// sending the next request.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// retryable reports whether a request that failed with the
+// given response or error should be retried.
+//
+// Requests other than GET and HEAD are only retried if
+// they failed before they have been sent.
+func retryable(req *http.Request, resp *http.Response, sent bool, err error) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		if err == nil || sent {
+			return false
+		}
+	}
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// timeoutError is returned by a retryTransport when the
+// server did not respond in time. It implements net.Error.
+type timeoutError struct {
+	After time.Duration
+}
+
+func (e *timeoutError) Error() string {
+	return fmt.Sprintf("server did not respond within %v", e.After)
+}
+
+func (*timeoutError) Timeout() bool { return true }
+
+func (*timeoutError) Temporary() bool { return true }
+
+// cancelBody is a response body that cancels the
+// request's context once it gets closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,69 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+var retryTransportTests = []struct {
+	Method   string
+	Sent     bool // Whether the request reaches the server
+	Requests int
+}{
+	{Method: http.MethodGet, Sent: true, Requests: 2},    // 0
+	{Method: http.MethodHead, Sent: true, Requests: 2},   // 1
+	{Method: http.MethodPost, Sent: true, Requests: 1},   // 2
+	{Method: http.MethodDelete, Sent: true, Requests: 1}, // 3
+	{Method: http.MethodGet, Sent: false, Requests: 2},   // 4
+	{Method: http.MethodPost, Sent: false, Requests: 2},  // 5
+	{Method: http.MethodPut, Sent: false, Requests: 2},   // 6
+}
+
+func TestRetryTransport(t *testing.T) {
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	for i, test := range retryTransportTests {
+		requests.Store(0)
+
+		var transport http.RoundTripper = http.DefaultTransport
+		if !test.Sent {
+			transport = roundTripFunc(func(*http.Request) (*http.Response, error) {
+				requests.Add(1)
+				return nil, errors.New("connection refused")
+			})
+		}
+		client := http.Client{
+			Transport: &retryTransport{
+				Transport: transport,
+				Retry:     1,
+			},
+		}
+
+		req, err := http.NewRequest(test.Method, server.URL, http.NoBody)
+		if err != nil {
+			t.Fatalf("Test %d: failed to create request: %v", i, err)
+		}
+		if resp, err := client.Do(req); err == nil {
+			resp.Body.Close()
+		}
+		if n := requests.Load(); n != int64(test.Requests) {
+			t.Fatalf("Test %d: invalid number of requests: got '%d' - want '%d'", i, n, test.Requests)
+		}
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
@@ -18,10 +18,10 @@ import (
 	"time"
 
 	"aead.dev/mem"
-	tui "github.com/charmbracelet/lipgloss"
 	"github.com/minio/kes-go"
 	"github.com/minio/kes/internal/cli"
 	"github.com/minio/kes/internal/secret"
+	"github.com/minio/kes/internal/tui"
 	flag "github.com/spf13/pflag"
 	"golang.org/x/term"
 )
@@ -54,7 +54,7 @@ func secretCmd(args []string) {
 
 	if len(args) < 2 {
 		cmd.Usage()
-		os.Exit(2)
+		cli.Exit(2)
 	}
 	if cmd, ok := subCmds[args[1]]; ok {
 		cmd(args[1:])
@@ -63,7 +63,7 @@ func secretCmd(args []string) {
 
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(2)
+			cli.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes key --help'", err)
 	}
@@ -71,7 +71,7 @@ func secretCmd(args []string) {
 		cli.Fatalf("%q is not a key command. See 'kes key --help'", cmd.Arg(0))
 	}
 	cmd.Usage()
-	os.Exit(2)
+	cli.Exit(2)
 }
 
 const createSecretCmdUsage = `Usage:
@@ -106,7 +106,7 @@ func createSecretCmd(args []string) {
 	cmd.StringVar(&filename, "file", "", "Use the file contet as secret value")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(2)
+			cli.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes secret create --help'", err)
 	}
@@ -153,7 +153,7 @@ func createSecretCmd(args []string) {
 	enclave := newEnclave(enclaveName, insecureSkipVerify)
 	if err := enclave.CreateSecret(ctx, name, value, nil); err != nil {
 		if errors.Is(err, context.Canceled) {
-			os.Exit(1)
+			cli.Exit(1)
 		}
 		cli.Fatalf("failed to create secret %q: %v", name, err)
 	}
@@ -194,7 +194,7 @@ func describeSecretCmd(args []string) {
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(2)
+			cli.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes secret info --help'", err)
 	}
@@ -214,7 +214,7 @@ func describeSecretCmd(args []string) {
 	info, err := enclave.DescribeSecret(ctx, name)
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
-			os.Exit(1)
+			cli.Exit(1)
 		}
 		cli.Fatalf("failed to describe keys: %v", err)
 	}
@@ -297,7 +297,7 @@ func showSecretCmd(args []string) {
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(2)
+			cli.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes secret show --help'", err)
 	}
@@ -317,7 +317,7 @@ func showSecretCmd(args []string) {
 	secret, info, err := enclave.ReadSecret(ctx, name)
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
-			os.Exit(1)
+			cli.Exit(1)
 		}
 		cli.Fatalf("failed to describe keys: %v", err)
 	}
@@ -408,7 +408,7 @@ func deleteSecretCmd(args []string) {
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(2)
+			cli.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes secret rm --help'", err)
 	}
@@ -423,7 +423,7 @@ func deleteSecretCmd(args []string) {
 	for _, name := range cmd.Args() {
 		if err := enclave.DeleteSecret(ctx, name); err != nil {
 			if errors.Is(err, context.Canceled) {
-				os.Exit(1)
+				cli.Exit(1)
 			}
 			cli.Fatalf("failed to remove secret %q: %v", name, err)
 		}
@@ -466,7 +466,7 @@ func lsSecretCmd(args []string) {
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(2)
+			cli.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes secret ls --help'", err)
 	}
@@ -487,7 +487,7 @@ func lsSecretCmd(args []string) {
 	iterator, err := enclave.ListSecrets(ctx, pattern)
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
-			os.Exit(1)
+			cli.Exit(1)
 		}
 		cli.Fatalf("failed to list secrets: %v", err)
 	}
@@ -6,6 +6,7 @@ package main
 
 import (
 	"context"
+	"crypto"
 	"crypto/tls"
 	"crypto/x509"
 	"errors"
@@ -17,12 +18,14 @@ import (
 	"os/signal"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"syscall"
 	"time"
 
-	tui "github.com/charmbracelet/lipgloss"
+	"github.com/minio/kes-go"
 	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/audit"
 	"github.com/minio/kes/internal/auth"
 	"github.com/minio/kes/internal/cli"
 	"github.com/minio/kes/internal/fips"
@@ -32,11 +35,13 @@ import (
 	"github.com/minio/kes/internal/metric"
 	"github.com/minio/kes/internal/sys"
 	"github.com/minio/kes/internal/sys/fs"
+	"github.com/minio/kes/internal/tui"
 	flag "github.com/spf13/pflag"
 )
 
 const serverCmdUsage = `Usage:
     kes server [options]
+    kes server features [options]
 
 Options:
     --addr <IP:PORT>         The address of the server (default: 0.0.0.0:7373)
@@ -55,6 +60,9 @@ Options:
                                 Require and verify      : --auth=on (default)
                                 Require but don't verify: --auth=off
 
+    --validate               Validate the config file, including a connection
+                             check of the keystores, and exit. See 'kes validate'
+
     -h, --help               Show list of command-line options
 
 Starts a KES server. The server address can be specified in the config file but
@@ -65,8 +73,12 @@ The client TLS verification can be disabled by setting --auth=off. The server th
 accepts arbitrary client certificates but still maps them to policies. So, it disables
 authentication but not authorization.
 
+The 'kes server features' command prints the version and the features
+supported by a running KES server.
+
 Examples:
     $ kes server --config config.yml --auth =off
+    $ kes server features
 `
 
 type serverConfig struct {
@@ -78,6 +90,11 @@ type serverConfig struct {
 }
 
 func serverCmd(args []string) {
+	if len(args) > 1 && args[1] == "features" {
+		serverFeaturesCmd(args[1:])
+		return
+	}
+
 	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
 	cmd.Usage = func() { fmt.Fprint(os.Stderr, serverCmdUsage) }
 
@@ -87,15 +104,17 @@ func serverCmd(args []string) {
 		tlsKeyFlag   string
 		tlsCertFlag  string
 		mtlsAuthFlag string
+		validateFlag bool
 	)
 	cmd.StringVar(&addrFlag, "addr", "", "The address of the server")
 	cmd.StringVar(&configFlag, "config", "", "Path to the server configuration file")
 	cmd.StringVar(&tlsKeyFlag, "key", "", "Path to the TLS private key")
 	cmd.StringVar(&tlsCertFlag, "cert", "", "Path to the TLS certificate")
 	cmd.StringVar(&mtlsAuthFlag, "auth", "", "Controls how the server handles mTLS authentication")
+	cmd.BoolVar(&validateFlag, "validate", false, "Validate the config file and exit")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(2)
+			cli.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes server --help'", err)
 	}
@@ -103,6 +122,19 @@ func serverCmd(args []string) {
 	if cmd.NArg() > 1 {
 		cli.Fatal("too many arguments. See 'kes server --help'")
 	}
+	if validateFlag {
+		if cmd.NArg() > 0 || configFlag == "" {
+			cli.Fatal("'--validate' requires a config file specified by '--config'. See 'kes server --help'")
+		}
+		validateGatewayConfig(gatewayConfig{
+			Address:     addrFlag,
+			ConfigFile:  configFlag,
+			PrivateKey:  tlsKeyFlag,
+			Certificate: tlsCertFlag,
+			TLSAuth:     mtlsAuthFlag,
+		}, false, 10*time.Second)
+		return
+	}
 	if cmd.NArg() == 0 {
 		startGateway(gatewayConfig{
 			Address:     addrFlag,
@@ -179,10 +211,27 @@ func startServer(path string, sConfig serverConfig) {
 		cli.Fatal("failed to load TLS certificate: certificate does not contain any DNS or IP address as SAN")
 	}
 
+	sniCertificates, err := loadSNICertificates(init)
+	if err != nil {
+		cli.Fatal(err)
+	}
+	sniEnclaves := make(map[string]string, len(init.SNI))
+	for serverName, sni := range init.SNI {
+		sniEnclaves[strings.ToLower(serverName)] = sni.Enclave.Value()
+	}
+
 	clientAuth := tls.RequireAnyClientCert
 	if init.VerifyClientCerts.Value() {
 		clientAuth = tls.RequireAndVerifyClientCert
 	}
+	if init.APIKeys.Value() { // Clients may authenticate with an API key instead of a certificate
+		switch clientAuth {
+		case tls.RequireAnyClientCert:
+			clientAuth = tls.RequestClientCert
+		case tls.RequireAndVerifyClientCert:
+			clientAuth = tls.VerifyClientCertIfGiven
+		}
+	}
 
 	var proxy *auth.TLSProxy
 	if len(init.ProxyIdentities) != 0 {
@@ -199,32 +248,81 @@ func startServer(path string, sConfig serverConfig) {
 		}
 	}
 
+	var receiptSigner crypto.Signer
+	if init.ReceiptKey.Value() != "" {
+		receiptSigner, err = https.PrivateKeyFromFile(init.ReceiptKey.Value(), init.ReceiptPassword.Value())
+		if err != nil {
+			cli.Fatalf("failed to load receipt signing key: %v", err)
+		}
+	}
+
 	vault, err := fs.Open(path)
 	if err != nil {
 		cli.Fatalf("failed to initialize vault: %v", err)
 	}
+	compression, err := sys.ParseCompression(init.Compression.Value())
+	if err != nil {
+		cli.Fatalf("failed to initialize vault: %v", err)
+	}
 
+	maintenance := &api.Maintenance{}
+	drill := &api.Drill{}
 	metrics := metric.New()
+	metrics.RegisterReadOnly(maintenance.ReadOnly)
 	log.Default().Add(metrics.ErrorEventCounter())
 	auditLog.Add(metrics.AuditEventCounter())
 
+	auditStats := &audit.Stats{
+		EnclaveRetention: func(name string) (int, bool, error) {
+			locker := vault.RLocker()
+			locker.Lock()
+			defer locker.Unlock()
+
+			info, err := vault.GetEnclaveInfo(ctx, name)
+			if errors.Is(err, kes.ErrEnclaveNotFound) {
+				return 0, false, nil // Audit statistics of deleted enclaves use the default retention
+			}
+			if err != nil {
+				return 0, false, err
+			}
+			return info.AuditRetention, info.AuditLegalHold, nil
+		},
+	}
+	auditStatsFile := filepath.Join(path, ".audit-stats")
+	if err = auditStats.Load(auditStatsFile); err != nil {
+		cli.Fatalf("failed to load audit statistics: %v", err)
+	}
+	auditLog.Add(auditStats)
+	go saveAuditStats(ctx, auditStats, auditStatsFile)
+
 	server := https.NewServer(&https.Config{
 		Addr: init.Address.Value(),
 		Handler: api.NewRouter(&api.RouterConfig{
-			Vault:    vault,
-			Proxy:    proxy,
-			AuditLog: auditLog,
-			ErrorLog: log.Default(),
-			Metrics:  metrics,
+			Vault:       vault,
+			Proxy:       proxy,
+			AuditLog:    auditLog,
+			AuditStats:  auditStats,
+			ErrorLog:    log.Default(),
+			Metrics:     metrics,
+			SNI:         sniEnclaves,
+			Maintenance: maintenance,
+			Drill:       drill,
+
+			ReceiptSigner: receiptSigner,
+			APIKeys:       init.APIKeys.Value(),
+			Compression:   compression,
 		}),
 		TLSConfig: &tls.Config{
 			MinVersion:       tls.VersionTLS12,
 			Certificates:     []tls.Certificate{certificate},
+			GetCertificate:   sniCertificate(sniCertificates),
 			CipherSuites:     fips.TLSCiphers(),
 			CurvePreferences: fips.TLSCurveIDs(),
 			ClientAuth:       clientAuth,
 		},
 	})
+	drill.ExpireCertificate = server.SetExpiredCertificate
+	metrics.RegisterCertificate(server.NotAfter)
 	go func(ctx context.Context) {
 		ticker := time.NewTicker(15 * time.Minute)
 		defer ticker.Stop()
@@ -243,9 +341,15 @@ func startServer(path string, sConfig serverConfig) {
 					// Therefore, we require at least one SAN for the server certificate.
 					xlog.Print("failed to load TLS certificate: certificate does not contain any DNS or IP address as SAN")
 				}
+				if certs, err := loadSNICertificates(init); err != nil {
+					xlog.Print(err)
+				} else {
+					sniCertificates = certs
+				}
 				c := &tls.Config{
 					MinVersion:       tls.VersionTLS12,
 					Certificates:     []tls.Certificate{certificate},
+					GetCertificate:   sniCertificate(sniCertificates),
 					CipherSuites:     fips.TLSCiphers(),
 					CurvePreferences: fips.TLSCurveIDs(),
 					ClientAuth:       clientAuth,
@@ -274,12 +378,31 @@ func startServer(path string, sConfig serverConfig) {
 	var buffer cli.Buffer
 	buffer.Stylef(item, "%-12s", "Copyright").Sprintf("%-22s", "MinIO, Inc.").Styleln(faint, "https://min.io")
 	buffer.Stylef(item, "%-12s", "License").Sprintf("%-22s", "GNU AGPLv3").Styleln(faint, "https://www.gnu.org/licenses/agpl-3.0.html")
-	buffer.Stylef(item, "%-12s", "Version").Sprintf("%-22s", sys.BinaryInfo().Version).Stylef(faint, "%s/%s\n", runtime.GOOS, runtime.GOARCH)
+	platform := runtime.GOOS + "/" + runtime.GOARCH
+	if sys.Minimal {
+		platform += " (minimal)"
+	}
+	buffer.Stylef(item, "%-12s", "Version").Sprintf("%-22s", sys.BinaryInfo().Version).Styleln(faint, platform)
 	buffer.Sprintln()
 	buffer.Stylef(item, "%-12s", "Endpoints").Sprintf("https://%s:%s\n", ifaceIPs[0], port)
 	for _, ifaceIP := range ifaceIPs[1:] {
 		buffer.Sprintf("%-12s", " ").Sprintf("https://%s:%s\n", ifaceIP, port)
 	}
+	if len(sniEnclaves) > 0 {
+		serverNames := make([]string, 0, len(sniEnclaves))
+		for serverName := range sniEnclaves {
+			serverNames = append(serverNames, serverName)
+		}
+		sort.Strings(serverNames)
+
+		buffer.Stylef(item, "%-12s", "SNI")
+		for i, serverName := range serverNames {
+			if i > 0 {
+				buffer.Sprintf("%-12s", " ")
+			}
+			buffer.Sprintf("https://%s:%s ", serverName, port).Stylef(faint, "=> enclave '%s'\n", sniEnclaves[serverName])
+		}
+	}
 	buffer.Sprintln()
 	if clientAuth == tls.RequireAndVerifyClientCert {
 		buffer.Stylef(item, "%-12s", "Mutual TLS").Sprint("on").Styleln(faint, "Verify client certificates")
@@ -299,6 +422,41 @@ func startServer(path string, sConfig serverConfig) {
 	}
 }
 
+// loadSNICertificates loads the TLS certificates of all
+// server names (SNI) in the init config that specify their
+// own certificate. The returned map keys are lower-case
+// server names.
+func loadSNICertificates(init *fs.InitConfig) (map[string]*tls.Certificate, error) {
+	certificates := make(map[string]*tls.Certificate, len(init.SNI))
+	for serverName, sni := range init.SNI {
+		if sni.Certificate.Value() == "" {
+			continue
+		}
+		certificate, err := https.CertificateFromFile(sni.Certificate.Value(), sni.PrivateKey.Value(), sni.Password.Value())
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS certificate for '%s': %v", serverName, err)
+		}
+		certificates[strings.ToLower(serverName)] = &certificate
+	}
+	return certificates, nil
+}
+
+// sniCertificate returns a function that selects the TLS
+// certificate for the server name (SNI) sent by the client.
+// If no certificate for the server name exists, it selects
+// the default certificate.
+func sniCertificate(certificates map[string]*tls.Certificate) func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if len(certificates) == 0 {
+		return nil
+	}
+	return func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		if certificate, ok := certificates[strings.ToLower(hello.ServerName)]; ok {
+			return certificate, nil
+		}
+		return nil, nil // Use the default certificate
+	}
+}
+
 // listeningOnV4 returns a list of the system IPv4 interface
 // addresses an TCP/IP listener with the given IP is listening
 // on.
@@ -355,3 +513,26 @@ func serverAddr(addr string) (ip net.IP, port string) {
 	}
 	return ip, port
 }
+
+// saveAuditStats periodically prunes the audit statistics
+// and saves them to the given file until ctx is done. It
+// saves them one last time before returning.
+func saveAuditStats(ctx context.Context, stats *audit.Stats, filename string) {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := stats.Save(filename); err != nil {
+				xlog.Printf("failed to save audit statistics: %v", err)
+			}
+			return
+		case now := <-ticker.C:
+			stats.Prune(now)
+			if err := stats.Save(filename); err != nil {
+				xlog.Printf("failed to save audit statistics: %v", err)
+			}
+		}
+	}
+}
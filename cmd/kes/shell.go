@@ -0,0 +1,439 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/internal/https"
+	flag "github.com/spf13/pflag"
+	"golang.org/x/term"
+)
+
+const shellCmdUsage = `Usage:
+    kes shell [options]
+
+Starts an interactive shell. Within the shell, any kes command
+can be executed without the 'kes' prefix. The shell connects once
+and retains the server connection, enclave and credentials across
+commands.
+
+Shell commands:
+    use <enclave>            Use the enclave for subsequent commands.
+    connect <address>        Connect to another KES server or a comma-
+                             separated list of KES servers.
+    history                  Print the command history.
+    help                     Print this help.
+    exit, quit               Exit the shell.
+
+Press TAB to complete commands, options, key and policy names.
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes shell
+    kes> key ls
+    kes> use tenant-1
+    kes:tenant-1> key info my-key
+`
+
+// session is the interactive shell session. It is nil
+// unless kes commands are executed within 'kes shell'.
+var session *shellSession
+
+func shellCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, shellCmdUsage) }
+
+	var (
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes shell --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatal("too many arguments. See 'kes shell --help'")
+	}
+	if session != nil {
+		cli.Fatal("already within a kes shell")
+	}
+
+	if enclaveName != "" {
+		os.Setenv("KES_ENCLAVE", enclaveName)
+	}
+	session = &shellSession{
+		insecureSkipVerify: insecureSkipVerify,
+		clients:            map[bool]*kes.Client{},
+	}
+
+	// Load the client before entering the shell such that
+	// invalid credentials terminate the shell right away and
+	// encrypted private keys only require the password once.
+	session.Client(insecureSkipVerify)
+	session.Run(kesCommands())
+}
+
+// shellExit is the panic value used to unwind a command
+// that has called cli.Exit within the shell.
+type shellExit int
+
+// shellSession is an interactive shell session. It caches
+// the clients, and therefore server connections and
+// credentials, across commands.
+type shellSession struct {
+	insecureSkipVerify bool
+
+	lock    sync.Mutex
+	clients map[bool]*kes.Client
+	history []string
+}
+
+// Client returns the session's client. It loads the client
+// from the environment when called for the first time.
+func (s *shellSession) Client(insecureSkipVerify bool) *kes.Client {
+	insecureSkipVerify = insecureSkipVerify || s.insecureSkipVerify
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if client, ok := s.clients[insecureSkipVerify]; ok {
+		return client
+	}
+	client := loadClient(insecureSkipVerify)
+	s.clients[insecureSkipVerify] = client
+	return client
+}
+
+// Connect changes the server all subsequent commands
+// connect to. It retains the session's credentials.
+func (s *shellSession) Connect(addr string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	os.Setenv("KES_SERVER", addr)
+	for _, client := range s.clients {
+		client.Endpoints = https.ParseEndpoints(addr)
+	}
+}
+
+// Run reads and executes commands until the input
+// is closed or an exit command is entered.
+func (s *shellSession) Run(cmds commands) {
+	// Within the shell, commands must not terminate the process.
+	// Instead, cli.Exit unwinds the command which returns control
+	// to the shell.
+	cli.Exit = func(code int) { panic(shellExit(code)) }
+
+	// Ignore interrupts while the shell is running. A command
+	// that listens for interrupts itself still receives them.
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+	defer signal.Stop(sigCh)
+	go func() {
+		for range sigCh {
+		}
+	}()
+
+	if !isTerm(os.Stdin) {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			if !s.exec(cmds, scanner.Text(), os.Stdout) {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			cli.Fatalf("failed to read command: %v", err)
+		}
+		return
+	}
+
+	t := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stdout}, s.prompt())
+	t.AutoCompleteCallback = func(line string, pos int, key rune) (string, int, bool) {
+		if key != '\t' {
+			return "", 0, false
+		}
+		return s.complete(t, line, pos)
+	}
+	for {
+		state, err := term.MakeRaw(int(os.Stdin.Fd()))
+		if err != nil {
+			cli.Fatalf("failed to start shell: %v", err)
+		}
+		if width, height, err := term.GetSize(int(os.Stdin.Fd())); err == nil {
+			t.SetSize(width, height)
+		}
+		line, err := t.ReadLine()
+		term.Restore(int(os.Stdin.Fd()), state)
+
+		if errors.Is(err, io.EOF) {
+			fmt.Println()
+			return
+		}
+		if err != nil {
+			cli.Fatalf("failed to read command: %v", err)
+		}
+		if !s.exec(cmds, line, os.Stdout) {
+			return
+		}
+		t.SetPrompt(s.prompt())
+	}
+}
+
+// exec executes the command line. It reports whether
+// the shell should continue reading commands.
+func (s *shellSession) exec(cmds commands, line string, w io.Writer) bool {
+	args, err := splitArgs(line)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return true
+	}
+	if len(args) > 0 && args[0] == "kes" {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return true
+	}
+	s.history = append(s.history, strings.TrimSpace(line))
+
+	switch args[0] {
+	case "exit", "quit":
+		return false
+	case "help":
+		fmt.Fprint(w, shellCmdUsage)
+		return true
+	case "history":
+		for i, line := range s.history {
+			fmt.Fprintf(w, "%5d  %s\n", i+1, line)
+		}
+		return true
+	case "use":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, "Error: usage: use <enclave>")
+			return true
+		}
+		os.Setenv("KES_ENCLAVE", args[1])
+		return true
+	case "connect":
+		if len(args) != 2 || len(https.ParseEndpoints(args[1])) == 0 {
+			fmt.Fprintln(os.Stderr, "Error: usage: connect <address>[,<address>...]")
+			return true
+		}
+		s.Connect(args[1])
+		return true
+	case "shell":
+		fmt.Fprintln(os.Stderr, "Error: already within a kes shell")
+		return true
+	}
+
+	cmd, ok := cmds[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: %q is not a kes command. Type 'help' for shell commands\n", args[0])
+		return true
+	}
+	run(cmd, args)
+	return true
+}
+
+// run executes the command and recovers from
+// any cli.Exit called by the command.
+func run(cmd func([]string), args []string) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(shellExit); !ok {
+				panic(r)
+			}
+		}
+	}()
+	cmd(args)
+}
+
+// prompt returns the shell prompt. It contains
+// the current enclave, if any.
+func (s *shellSession) prompt() string {
+	if enclave := os.Getenv("KES_ENCLAVE"); enclave != "" {
+		return "kes:" + enclave + "> "
+	}
+	return "kes> "
+}
+
+// complete completes the word before the cursor. It completes
+// commands and options as well as key and policy names fetched
+// from the server. Multiple candidates are completed up to their
+// longest common prefix or printed if there is none.
+func (s *shellSession) complete(t *term.Terminal, line string, pos int) (string, int, bool) {
+	head := line[:pos]
+	cmdLine := head
+	if !strings.HasPrefix(cmdLine, "kes ") {
+		cmdLine = "kes " + cmdLine
+	}
+	candidates := completeLine(completions("kes"), cmdLine)
+
+	fields := strings.Fields(head)
+	if len(fields) > 0 && fields[0] == "kes" {
+		fields = fields[1:]
+	}
+	var word string
+	if !strings.HasSuffix(head, " ") && len(fields) > 0 {
+		word, fields = fields[len(fields)-1], fields[:len(fields)-1]
+	}
+	if !strings.HasPrefix(word, "-") {
+		candidates = append(candidates, s.completeName(fields, word)...)
+	}
+	if len(candidates) == 0 {
+		return line, pos, true
+	}
+	sort.Strings(candidates)
+
+	completion := candidates[0]
+	if len(candidates) > 1 {
+		completion = commonPrefix(candidates)
+		if len(completion) <= len(word) {
+			t.Write([]byte(strings.Join(candidates, "  ") + "\n"))
+			return line, pos, true
+		}
+	} else {
+		completion += " "
+	}
+	head = head[:len(head)-len(word)] + completion
+	return head + line[pos:], len(head), true
+}
+
+// completeName returns all key or policy names starting with
+// prefix if the command accepts key or policy names.
+func (s *shellSession) completeName(fields []string, prefix string) []string {
+	if len(fields) != 2 {
+		return nil
+	}
+
+	var names []string
+	switch fields[0] + " " + fields[1] {
+	case "key info", "key rm", "key restore", "key purge", "key encrypt", "key decrypt", "key dek":
+		names = s.listNames(func(ctx context.Context, enclave *kes.Enclave) (nameIter, error) {
+			return enclave.ListKeys(ctx, prefix+"*")
+		})
+	case "policy info", "policy rm", "policy show", "policy assign":
+		names = s.listNames(func(ctx context.Context, enclave *kes.Enclave) (nameIter, error) {
+			return enclave.ListPolicies(ctx, prefix+"*")
+		})
+	}
+	return names
+}
+
+// nameIter is an iterator over key or policy names.
+type nameIter interface {
+	Next() bool
+	Name() string
+	Close() error
+}
+
+// listNames returns up to 100 names listed by the given
+// function. It returns no names if the server is not
+// reachable within a short period of time.
+func (s *shellSession) listNames(list func(context.Context, *kes.Enclave) (nameIter, error)) []string {
+	const (
+		Timeout  = 3 * time.Second
+		MaxNames = 100
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+
+	iter, err := list(ctx, s.Client(false).Enclave(os.Getenv("KES_ENCLAVE")))
+	if err != nil {
+		return nil
+	}
+	defer iter.Close()
+
+	var names []string
+	for len(names) < MaxNames && iter.Next() {
+		names = append(names, iter.Name())
+	}
+	return names
+}
+
+// commonPrefix returns the longest common
+// prefix of all strings.
+func commonPrefix(s []string) string {
+	prefix := s[0]
+	for _, v := range s[1:] {
+		for !strings.HasPrefix(v, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
+// splitArgs splits the command line into its arguments.
+// Arguments are separated by whitespaces unless quoted
+// with single or double quotes or escaped with a backslash.
+func splitArgs(line string) ([]string, error) {
+	var (
+		args    []string
+		arg     strings.Builder
+		inArg   bool
+		quote   rune
+		escaped bool
+	)
+	for _, r := range line {
+		switch {
+		case escaped:
+			arg.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inArg = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote %q", quote)
+	}
+	if escaped {
+		return nil, errors.New("incomplete escape sequence")
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
@@ -10,6 +10,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
@@ -17,9 +18,9 @@ import (
 	"time"
 
 	"aead.dev/mem"
-	tui "github.com/charmbracelet/lipgloss"
 	"github.com/minio/kes-go"
 	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/internal/tui"
 	flag "github.com/spf13/pflag"
 )
 
@@ -58,7 +59,7 @@ func statusCmd(args []string) {
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(2)
+			cli.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes status --help'", err)
 	}
@@ -70,11 +71,28 @@ func statusCmd(args []string) {
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
 	defer cancel()
 
+	// We fetch the status with a raw request since the
+	// SDK does not expose the keystore health, yet.
+	var status struct {
+		kes.State
+
+		KeyStore *struct {
+			Status        string `json:"status"`
+			Reachable     bool   `json:"reachable"`
+			Available     bool   `json:"available"`
+			Authenticated bool   `json:"authenticated"`
+			ReadOnly      bool   `json:"read_only"`
+			LatencyP50    int64  `json:"latency_p50,omitempty"`
+			LatencyP90    int64  `json:"latency_p90,omitempty"`
+			LatencyP99    int64  `json:"latency_p99,omitempty"`
+			Error         string `json:"error,omitempty"`
+		} `json:"keystore,omitempty"`
+	}
 	start := time.Now()
-	status, err := client.Status(ctx)
+	err := send(ctx, client.Enclave(""), http.MethodGet, "/v1/status", nil, nil, &status)
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
-			os.Exit(1)
+			cli.Exit(1)
 		}
 		cli.Fatal(err)
 	}
@@ -168,6 +186,42 @@ func statusCmd(args []string) {
 			faint.Render(fmt.Sprintf("%3s %-6s", "·", "Stack")),
 			mem.FormatSize(mem.Size(status.StackAlloc), 'D', 1),
 		)
+		if ks := status.KeyStore; ks != nil {
+			fmt.Println(
+				faint.Render(fmt.Sprintf("  %-8s", "KeyStore")),
+				ks.Status,
+			)
+			var state []string
+			if !ks.Reachable {
+				state = append(state, "unreachable")
+			} else if !ks.Available {
+				state = append(state, "unavailable")
+			}
+			if ks.Available && !ks.Authenticated {
+				state = append(state, "unauthenticated")
+			}
+			if ks.ReadOnly {
+				state = append(state, "read-only")
+			}
+			if len(state) > 0 {
+				fmt.Println(
+					faint.Render(fmt.Sprintf("%3s %-6s", "·", "State")),
+					strings.Join(state, ", "),
+				)
+			}
+			if ks.Available {
+				fmt.Println(
+					faint.Render(fmt.Sprintf("%3s %-6s", "·", "Latency")),
+					fmt.Sprintf("p50 %dms, p90 %dms, p99 %dms", ks.LatencyP50, ks.LatencyP90, ks.LatencyP99),
+				)
+			}
+			if ks.Error != "" {
+				fmt.Println(
+					faint.Render(fmt.Sprintf("%3s %-6s", "·", "Error")),
+					ks.Error,
+				)
+			}
+		}
 	}
 
 	if apiFlag {
@@ -0,0 +1,228 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/minio/kes/edge"
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/internal/keystore/conformance"
+	"github.com/minio/kes/internal/tui"
+	flag "github.com/spf13/pflag"
+)
+
+const testCmdUsage = `Usage:
+    kes test <command>
+
+Commands:
+    backend                  Run conformance tests against a keystore.
+
+Options:
+    -h, --help               Print command line options.
+`
+
+func testCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, testCmdUsage) }
+
+	subCmds := commands{
+		"backend": testBackendCmd,
+	}
+
+	if len(args) < 2 {
+		cmd.Usage()
+		cli.Exit(2)
+	}
+	if cmd, ok := subCmds[args[1]]; ok {
+		cmd(args[1:])
+		return
+	}
+
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes test --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatalf("%q is not a test command. See 'kes test --help'", cmd.Arg(0))
+	}
+	cmd.Usage()
+	cli.Exit(2)
+}
+
+const testBackendCmdUsage = `Usage:
+    kes test backend [options] <config-file>
+
+Runs the keystore conformance tests against the keystore
+specified in the KES config file and prints a report.
+
+The tests only create, modify and delete keys with a random
+'kes-conformance-' prefix. Other keys are not touched. Hence,
+the tests can be run against keystores that are in use.
+
+Options:
+        --timeout <duration> Abort the tests if they don't complete within
+                             the given duration. Default: 5m
+        --json               Print the report in JSON format.
+        --color <when>       Specify when to use colored output. The automatic
+                             mode only enables colors if an interactive terminal
+                             is detected - colors are automatically disabled if
+                             the output goes to a pipe.
+                             Possible values: *auto*, never, always.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes test backend ./config.yml
+    $ kes test backend --json ./config.yml
+`
+
+func testBackendCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, testBackendCmdUsage) }
+
+	var (
+		jsonFlag    bool
+		colorFlag   colorOption
+		timeoutFlag time.Duration
+	)
+	cmd.BoolVar(&jsonFlag, "json", false, "Print the report in JSON format")
+	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
+	cmd.DurationVar(&timeoutFlag, "timeout", 5*time.Minute, "Abort the tests if they don't complete in time")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes test backend --help'", err)
+	}
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no config file specified. See 'kes test backend --help'")
+	case cmd.NArg() > 1:
+		cli.Fatal("too many arguments. See 'kes test backend --help'")
+	}
+	if timeoutFlag <= 0 {
+		cli.Fatal("invalid timeout: timeout must be positive. See 'kes test backend --help'")
+	}
+
+	file, err := os.Open(cmd.Arg(0))
+	if err != nil {
+		cli.Fatal(err)
+	}
+	config, err := edge.ReadServerConfigYAML(file)
+	file.Close()
+	if err != nil {
+		cli.Fatalf("failed to read config file: %v", err)
+	}
+	kind, endpoints, err := description(config.KeyStore)
+	if err != nil {
+		cli.Fatal(err)
+	}
+	backend := kind
+	if len(endpoints) > 0 {
+		backend += " (" + strings.Join(endpoints, ", ") + ")"
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+	ctx, cancelTimeout := context.WithTimeout(ctx, timeoutFlag)
+	defer cancelTimeout()
+
+	store, err := config.KeyStore.Connect(ctx)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			cli.Exit(1)
+		}
+		cli.Fatalf("failed to connect to keystore: %v", err)
+	}
+	report, err := conformance.Run(ctx, store)
+	if err != nil {
+		cli.Fatal(err)
+	}
+
+	if jsonFlag {
+		type Result struct {
+			Name     string        `json:"name"`
+			Passed   bool          `json:"passed"`
+			Duration time.Duration `json:"duration"`
+			Err      string        `json:"error,omitempty"`
+		}
+		type Response struct {
+			Backend string   `json:"backend"`
+			Passed  bool     `json:"passed"`
+			Prefix  string   `json:"prefix"`
+			Results []Result `json:"results"`
+		}
+		resp := Response{
+			Backend: backend,
+			Passed:  report.Passed(),
+			Prefix:  report.Prefix,
+			Results: make([]Result, 0, len(report.Results)),
+		}
+		for _, r := range report.Results {
+			result := Result{
+				Name:     r.Name,
+				Passed:   r.Passed(),
+				Duration: r.Duration,
+			}
+			if r.Err != nil {
+				result.Err = r.Err.Error()
+			}
+			resp.Results = append(resp.Results, result)
+		}
+
+		encoder := json.NewEncoder(os.Stdout)
+		if isTerm(os.Stdout) {
+			encoder.SetIndent("", "  ")
+		}
+		if err = encoder.Encode(resp); err != nil {
+			cli.Fatal(err)
+		}
+	} else {
+		var (
+			passStyle = tui.NewStyle()
+			failStyle = tui.NewStyle()
+		)
+		if colorFlag.Colorize() {
+			const (
+				ColorPass tui.Color = "#00d700"
+				ColorFail tui.Color = "#ac0000"
+			)
+			passStyle = passStyle.Foreground(ColorPass).Bold(true)
+			failStyle = failStyle.Foreground(ColorFail).Bold(true)
+		}
+
+		fmt.Printf("Backend: %s\n\n", backend)
+		var failed int
+		for _, r := range report.Results {
+			if r.Passed() {
+				fmt.Printf("%s  %-8s %s\n", passStyle.Render("PASS"), r.Name, r.Duration.Round(time.Millisecond))
+				continue
+			}
+			failed++
+			fmt.Printf("%s  %-8s %s\n", failStyle.Render("FAIL"), r.Name, r.Duration.Round(time.Millisecond))
+			fmt.Printf("      %v\n", r.Err)
+		}
+		fmt.Println()
+		if failed == 0 {
+			fmt.Printf("%s  %d/%d tests passed\n", passStyle.Render("OK"), len(report.Results), len(report.Results))
+		} else {
+			fmt.Printf("%s  %d/%d tests failed\n", failStyle.Render("FAIL"), failed, len(report.Results))
+			fmt.Printf("\nKeys starting with '%s' may have to be removed manually.\n", report.Prefix)
+		}
+	}
+	if !report.Passed() {
+		cli.Exit(1)
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"time"
+
+	"github.com/minio/kes/edge"
+	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/internal/https"
+	flag "github.com/spf13/pflag"
+)
+
+const validateCmdUsage = `Usage:
+    kes validate [options] <config>
+
+Validates a KES server config file. It checks that the config file
+is well-formed, that the TLS certificates, policy hooks and receipt
+signing key can be loaded and, unless --offline is specified, that
+the keystores are reachable. Nothing is created at the keystores.
+
+Each problem found is printed to STDERR. The command exits with a
+non-zero exit code if the config file is invalid and prints nothing
+otherwise. 'kes server --validate' is equivalent.
+
+Options:
+    --offline                Do not connect to the keystores.
+    --timeout <duration>     Give up connecting to a keystore after the
+                             given duration. (default: 10s)
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes validate config.yml
+    $ kes validate --offline config.yml
+`
+
+func validateCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, validateCmdUsage) }
+
+	var (
+		offline bool
+		timeout time.Duration
+	)
+	cmd.BoolVar(&offline, "offline", false, "Do not connect to the keystores")
+	cmd.DurationVar(&timeout, "timeout", 10*time.Second, "Give up connecting to a keystore after the given duration")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			cli.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes validate --help'", err)
+	}
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no config file specified. See 'kes validate --help'")
+	case cmd.NArg() > 1:
+		cli.Fatal("too many arguments. See 'kes validate --help'")
+	}
+	if timeout <= 0 {
+		cli.Fatal("invalid timeout: timeout must be positive. See 'kes validate --help'")
+	}
+
+	validateGatewayConfig(gatewayConfig{ConfigFile: cmd.Arg(0)}, offline, timeout)
+}
+
+// validateGatewayConfig validates the server config file and
+// exits with a non-zero exit code if it is invalid. Unless
+// offline is true, it checks that all keystores are reachable.
+func validateGatewayConfig(cliConfig gatewayConfig, offline bool, timeout time.Duration) {
+	config, err := loadGatewayConfig(cliConfig)
+	if err != nil {
+		cli.Fatal(err)
+	}
+
+	var failed bool
+	report := func(err error) {
+		cli.Errorf("%v", err)
+		failed = true
+	}
+
+	if tlsConfig, err := newTLSConfig(config, cliConfig.TLSAuth); err != nil {
+		report(err)
+	} else if _, err = newListeners(config, tlsConfig); err != nil {
+		report(err)
+	}
+	for _, hook := range config.PolicyHooks {
+		module, err := os.ReadFile(hook.Module)
+		if err != nil {
+			report(fmt.Errorf("failed to read policy hook '%s': %v", hook.Name, err))
+			continue
+		}
+		policyHook, err := auth.NewPolicyHook(hook.Name, module, hook.Timeout)
+		if err != nil {
+			report(fmt.Errorf("invalid policy hook '%s': %v", hook.Name, err))
+			continue
+		}
+		policyHook.Close()
+	}
+	if config.Receipts != nil {
+		if _, err = https.PrivateKeyFromFile(config.Receipts.PrivateKey, config.Receipts.Password); err != nil {
+			report(fmt.Errorf("failed to load receipt signing key: %v", err))
+		}
+	}
+	if _, err = policySetFromConfig(config); err != nil {
+		report(err)
+	}
+	if _, err = identitySetFromConfig(config); err != nil {
+		report(err)
+	}
+
+	if !offline {
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+		defer cancel()
+
+		check := func(name string, store edge.KeyStore) {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			conn, err := store.Connect(ctx)
+			if err != nil {
+				report(fmt.Errorf("failed to connect to %s: %v", name, err))
+				return
+			}
+			if _, err = conn.Status(ctx); err != nil {
+				report(fmt.Errorf("%s is not available: %v", name, err))
+			}
+		}
+		checkAll := func(name string, store edge.KeyStore, replicas []edge.KeyStore, mirror edge.KeyStore) {
+			check(name, store)
+			for i, replica := range replicas {
+				check(fmt.Sprintf("replica %d of %s", i, name), replica)
+			}
+			if mirror != nil {
+				check("mirror of "+name, mirror)
+			}
+		}
+
+		checkAll("keystore", config.KeyStore, config.KeyStoreReplicas, config.KeyStoreMirror)
+		names := make([]string, 0, len(config.Enclaves))
+		for name := range config.Enclaves {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			enclave := config.Enclaves[name]
+			checkAll(fmt.Sprintf("keystore of enclave '%s'", name), enclave.KeyStore, enclave.KeyStoreReplicas, enclave.KeyStoreMirror)
+		}
+	}
+	if failed {
+		cli.Exit(1)
+	}
+}
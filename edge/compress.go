@@ -0,0 +1,194 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package edge
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+
+	"aead.dev/mem"
+	"github.com/klauspost/compress/zstd"
+	"github.com/minio/kes/internal/sys"
+	"github.com/minio/kes/kv"
+)
+
+// WithCompression returns a kv.Store that compresses values
+// with the given compression algorithm before writing them
+// to the store.
+//
+// Compressed values are base64-encoded and prefixed with the
+// name of the compression algorithm, e.g. "zstd:". Hence, they
+// can be stored by keystores that only accept text. Values
+// without such a prefix, e.g. values written before enabling
+// compression, are returned as they are. Compressed values
+// are decompressed even if c is sys.NoCompression. Therefore,
+// compression can be enabled and disabled for existing
+// keystores.
+func WithCompression(store kv.Store[string, []byte], c sys.Compression) (kv.Store[string, []byte], error) {
+	switch c {
+	case sys.NoCompression, sys.Zstd:
+	default:
+		return nil, errors.New("edge: invalid compression algorithm '" + string(c) + "'")
+	}
+
+	s := &compressStore{
+		store:       store,
+		compression: c,
+	}
+	if _, ok := store.(kv.Recoverer[string]); ok {
+		return &recoverableCompressStore{compressStore: s}, nil
+	}
+	return s, nil
+}
+
+// zstdPrefix is the prefix of zstd compressed values.
+var zstdPrefix = []byte("zstd:")
+
+// Shared zstd encoder and decoder. Both are safe for
+// concurrent use when only using EncodeAll/DecodeAll.
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+	zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0), zstd.WithDecoderMaxMemory(uint64(1*mem.MiB)))
+)
+
+type compressStore struct {
+	store       kv.Store[string, []byte]
+	compression sys.Compression
+}
+
+var (
+	_ kv.Store[string, []byte] = (*compressStore)(nil)
+	_ kv.Watcher[string]       = (*compressStore)(nil)
+)
+
+func (s *compressStore) Status(ctx context.Context) (kv.State, error) {
+	return s.store.Status(ctx)
+}
+
+func (s *compressStore) Create(ctx context.Context, key string, value []byte) error {
+	return s.store.Create(ctx, key, s.compress(value))
+}
+
+func (s *compressStore) Set(ctx context.Context, key string, value []byte) error {
+	return s.store.Set(ctx, key, s.compress(value))
+}
+
+func (s *compressStore) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := s.store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return decompress(value)
+}
+
+func (s *compressStore) GetMany(ctx context.Context, keys []string) ([][]byte, []error) {
+	values, errs := s.store.GetMany(ctx, keys)
+	for i, value := range values {
+		if errs[i] == nil {
+			values[i], errs[i] = decompress(value)
+		}
+	}
+	return values, errs
+}
+
+// Update compares the decompressed value of the entry with
+// oldValue since the entry may or may not be compressed. It
+// compares and swaps the stored values at the underlying
+// store. Hence, Update is atomic if the underlying store
+// supports compare-and-swap natively.
+func (s *compressStore) Update(ctx context.Context, key string, oldValue, newValue []byte) error {
+	stored, err := s.store.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	value, err := decompress(stored)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(value, oldValue) {
+		return kv.ErrConflict
+	}
+	return s.store.Update(ctx, key, stored, s.compress(newValue))
+}
+
+func (s *compressStore) Delete(ctx context.Context, key string) error {
+	return s.store.Delete(ctx, key)
+}
+
+func (s *compressStore) DeleteMany(ctx context.Context, keys []string) []error {
+	return s.store.DeleteMany(ctx, keys)
+}
+
+func (s *compressStore) List(ctx context.Context, prefix string) (kv.Iter[string], error) {
+	return s.store.List(ctx, prefix)
+}
+
+// Watch calls the Watch method of the underlying store,
+// if it is a kv.Watcher. Otherwise, it returns nil.
+func (s *compressStore) Watch(ctx context.Context, f func(string)) error {
+	if w, ok := s.store.(kv.Watcher[string]); ok {
+		return w.Watch(ctx, f)
+	}
+	return nil
+}
+
+// recoverableCompressStore is a compressStore with an
+// underlying kv.Store that deletes entries softly.
+type recoverableCompressStore struct {
+	*compressStore
+}
+
+var _ kv.Recoverer[string] = (*recoverableCompressStore)(nil)
+
+func (s *recoverableCompressStore) ListDeleted(ctx context.Context) (kv.Iter[kv.Deleted[string]], error) {
+	return s.store.(kv.Recoverer[string]).ListDeleted(ctx)
+}
+
+func (s *recoverableCompressStore) Recover(ctx context.Context, key string) error {
+	return s.store.(kv.Recoverer[string]).Recover(ctx, key)
+}
+
+func (s *recoverableCompressStore) Purge(ctx context.Context, key string) error {
+	return s.store.(kv.Recoverer[string]).Purge(ctx, key)
+}
+
+// compress compresses the value using zstd and returns
+// the base64-encoded result prefixed with zstdPrefix.
+//
+// It returns the value as is if the store does not
+// compress values.
+func (s *compressStore) compress(value []byte) []byte {
+	if s.compression == sys.NoCompression {
+		return value
+	}
+	compressed := zstdEncoder.EncodeAll(value, nil)
+
+	dst := make([]byte, len(zstdPrefix)+base64.StdEncoding.EncodedLen(len(compressed)))
+	copy(dst, zstdPrefix)
+	base64.StdEncoding.Encode(dst[len(zstdPrefix):], compressed)
+	return dst
+}
+
+// decompress decompresses the value if it starts with
+// zstdPrefix. Otherwise, it returns the value as is.
+func decompress(value []byte) ([]byte, error) {
+	if !bytes.HasPrefix(value, zstdPrefix) {
+		return value, nil
+	}
+	value = value[len(zstdPrefix):]
+
+	compressed := make([]byte, base64.StdEncoding.DecodedLen(len(value)))
+	n, err := base64.StdEncoding.Decode(compressed, value)
+	if err != nil {
+		return nil, errors.New("edge: invalid compressed value: " + err.Error())
+	}
+	plaintext, err := zstdDecoder.DecodeAll(compressed[:n], nil)
+	if err != nil {
+		return nil, errors.New("edge: invalid compressed value: " + err.Error())
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package edge
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/minio/kes/internal/keystore/mem"
+	"github.com/minio/kes/internal/sys"
+	"github.com/minio/kes/kv"
+)
+
+func TestWithCompression(t *testing.T) {
+	ctx := context.Background()
+	value := bytes.Repeat([]byte(`{"version":"v1","bytes":"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}`), 4)
+
+	store := &mem.Store{}
+	s, err := WithCompression(store, sys.Zstd)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	// Values are written compressed.
+	if err = s.Create(ctx, "my-key", value); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	stored, err := store.Get(ctx, "my-key")
+	if err != nil {
+		t.Fatalf("Failed to get key: %v", err)
+	}
+	if !bytes.HasPrefix(stored, zstdPrefix) || len(stored) >= len(value) {
+		t.Fatalf("Key has not been compressed: got '%s'", stored)
+	}
+	if v, err := s.Get(ctx, "my-key"); err != nil || !bytes.Equal(v, value) {
+		t.Fatalf("Failed to get key: got '%s' - '%v'", v, err)
+	}
+
+	// Values written before enabling compression are read as they are.
+	if err = store.Create(ctx, "plain-key", []byte("plain-value")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	values, errs := s.GetMany(ctx, []string{"my-key", "plain-key"})
+	if errs[0] != nil || !bytes.Equal(values[0], value) {
+		t.Fatalf("Failed to get key: got '%s' - '%v'", values[0], errs[0])
+	}
+	if errs[1] != nil || !bytes.Equal(values[1], []byte("plain-value")) {
+		t.Fatalf("Failed to get uncompressed key: got '%s' - '%v'", values[1], errs[1])
+	}
+
+	// Updates compare the decompressed values.
+	if err = s.Update(ctx, "plain-key", []byte("other-value"), []byte("new-value")); !errors.Is(err, kv.ErrConflict) {
+		t.Fatalf("Updating key with wrong old value: got '%v' - want '%v'", err, kv.ErrConflict)
+	}
+	if err = s.Update(ctx, "plain-key", []byte("plain-value"), []byte("new-value")); err != nil {
+		t.Fatalf("Failed to update key: %v", err)
+	}
+	if stored, err = store.Get(ctx, "plain-key"); err != nil || !bytes.HasPrefix(stored, zstdPrefix) {
+		t.Fatalf("Updated key has not been compressed: got '%s' - '%v'", stored, err)
+	}
+
+	// Compressed values are read even once compression is disabled.
+	s, err = WithCompression(store, sys.NoCompression)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	if v, err := s.Get(ctx, "my-key"); err != nil || !bytes.Equal(v, value) {
+		t.Fatalf("Failed to get compressed key: got '%s' - '%v'", v, err)
+	}
+	if err = s.Create(ctx, "new-key", value); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if stored, err = store.Get(ctx, "new-key"); err != nil || !bytes.Equal(stored, value) {
+		t.Fatalf("Key has been compressed: got '%s' - '%v'", stored, err)
+	}
+
+	if _, err = WithCompression(store, "gzip"); err == nil {
+		t.Fatal("Created store with invalid compression algorithm")
+	}
+}
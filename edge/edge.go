@@ -5,7 +5,6 @@
 package edge
 
 import (
-	"fmt"
 	"io"
 
 	"gopkg.in/yaml.v3"
@@ -19,14 +18,12 @@ func ReadServerConfigYAML(r io.Reader) (*ServerConfig, error) {
 		return nil, err
 	}
 
-	version, err := findVersion(&node)
-	if err != nil {
+	// Configs of older schema versions are upgraded
+	// transparently. Use MigrateServerConfigYAML to
+	// obtain the deprecation warnings.
+	if _, err := migrate(&node); err != nil {
 		return nil, err
 	}
-	const Version = "v1"
-	if version != "" && version != Version {
-		return nil, fmt.Errorf("edge: invalid server config version '%s'", version)
-	}
 
 	var y yml
 	if err := node.Decode(&y); err != nil {
@@ -162,7 +162,7 @@ func testingContext(t *testing.T) (context.Context, context.CancelFunc) {
 }
 
 func clean(ctx context.Context, store kv.Store[string, []byte], t *testing.T) {
-	iter, err := store.List(ctx)
+	iter, err := store.List(ctx, "")
 	if err != nil {
 		t.Fatalf("Cleanup: failed to list keys: %v", err)
 	}
@@ -0,0 +1,341 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+//go:build !minimal
+// +build !minimal
+
+package edge
+
+import (
+	"context"
+	"errors"
+
+	"github.com/minio/kes/internal/keystore/alibaba"
+	"github.com/minio/kes/internal/keystore/aws"
+	"github.com/minio/kes/internal/keystore/azure"
+	"github.com/minio/kes/internal/keystore/cloudhsm"
+	"github.com/minio/kes/internal/keystore/conjur"
+	"github.com/minio/kes/internal/keystore/envelope"
+	"github.com/minio/kes/internal/keystore/etcd"
+	"github.com/minio/kes/internal/keystore/fortanix"
+	"github.com/minio/kes/internal/keystore/gcp"
+	"github.com/minio/kes/internal/keystore/gemalto"
+	"github.com/minio/kes/internal/keystore/ibm"
+	"github.com/minio/kes/internal/keystore/oci"
+	"github.com/minio/kes/internal/keystore/onepassword"
+	"github.com/minio/kes/internal/keystore/pkcs11"
+	"github.com/minio/kes/internal/keystore/rest"
+	sqlstore "github.com/minio/kes/internal/keystore/sql"
+	"github.com/minio/kes/kv"
+)
+
+// Connect returns a kv.Store that stores key-value pairs on an etcd cluster.
+func (s *EtcdKeyStore) Connect(ctx context.Context) (kv.Store[string, []byte], error) {
+	return etcd.Connect(ctx, &etcd.Config{
+		Endpoints: s.Endpoints,
+		Prefix:    s.Prefix,
+		Login: etcd.Credentials{
+			Username: s.Username,
+			Password: s.Password,
+		},
+		Certificate: s.CertificateFile,
+		PrivateKey:  s.PrivateKeyFile,
+		CAPath:      s.CAPath,
+		LeaseTTL:    s.LeaseTTL,
+	})
+}
+
+// Connect returns a kv.Store that stores key-value pairs in a SQL database.
+func (s *SQLKeyStore) Connect(ctx context.Context) (kv.Store[string, []byte], error) {
+	return sqlstore.Connect(ctx, &sqlstore.Config{
+		Driver:          s.Driver,
+		DSN:             s.DSN,
+		Table:           s.Table,
+		EncryptionKey:   s.EncryptionKey,
+		MaxOpenConns:    s.MaxOpenConns,
+		MaxIdleConns:    s.MaxIdleConns,
+		ConnMaxLifetime: s.ConnMaxLifetime,
+	})
+}
+
+// Connect returns a kv.Store that stores key-value pairs on a Fortanix SDKMS server.
+func (s *FortanixKeyStore) Connect(ctx context.Context) (kv.Store[string, []byte], error) {
+	return fortanix.Connect(ctx, &fortanix.Config{
+		Endpoint:          s.Endpoint,
+		FailoverEndpoints: s.FailoverEndpoints,
+		GroupID:           s.GroupID,
+		APIKey:            fortanix.APIKey(s.APIKey),
+		APIKeyFile:        s.APIKeyFile,
+		CAPath:            s.CAPath,
+		PrivateKey:        s.PrivateKey,
+		Certificate:       s.Certificate,
+		ServerName:        s.ServerName,
+	})
+}
+
+// Connect returns a kv.Store that stores key-value pairs on a Gemalto KeySecure instance.
+func (s *KeySecureKeyStore) Connect(ctx context.Context) (kv.Store[string, []byte], error) {
+	return gemalto.Connect(ctx, &gemalto.Config{
+		Endpoint:    s.Endpoint,
+		CAPath:      s.CAPath,
+		PrivateKey:  s.PrivateKey,
+		Certificate: s.Certificate,
+		ServerName:  s.ServerName,
+		Login: gemalto.Credentials{
+			Token:  s.Token,
+			Domain: s.Domain,
+		},
+	})
+}
+
+// Connect returns a kv.Store that stores key-value pairs at a generic REST API.
+func (s *RESTKeyStore) Connect(ctx context.Context) (kv.Store[string, []byte], error) {
+	return rest.Connect(ctx, &rest.Config{
+		Endpoint:    s.Endpoint,
+		Header:      s.Header,
+		Create:      rest.Request(s.Create),
+		Get:         rest.Request(s.Get),
+		Delete:      rest.Request(s.Delete),
+		List:        rest.Request(s.List),
+		ValuePath:   s.ValuePath,
+		NamesPath:   s.NamesPath,
+		NameField:   s.NameField,
+		CAPath:      s.CAPath,
+		PrivateKey:  s.PrivateKey,
+		Certificate: s.Certificate,
+	})
+}
+
+// Connect returns a kv.Store that stores key-value pairs as 1Password items.
+func (s *OnePasswordKeyStore) Connect(ctx context.Context) (kv.Store[string, []byte], error) {
+	return onepassword.Connect(ctx, &onepassword.Config{
+		Endpoint: s.Endpoint,
+		Token:    s.Token,
+		VaultID:  s.VaultID,
+		Tag:      s.Tag,
+		CAPath:   s.CAPath,
+	})
+}
+
+// Connect returns a kv.Store that stores key-value pairs as CyberArk Conjur variables.
+func (s *ConjurKeyStore) Connect(ctx context.Context) (kv.Store[string, []byte], error) {
+	return conjur.Connect(ctx, &conjur.Config{
+		Endpoint:    s.Endpoint,
+		Account:     s.Account,
+		Policy:      s.Policy,
+		CAPath:      s.CAPath,
+		PrivateKey:  s.PrivateKey,
+		Certificate: s.Certificate,
+		ServerName:  s.ServerName,
+		Login: conjur.Credentials{
+			Login:  s.Login,
+			APIKey: s.APIKey,
+		},
+	})
+}
+
+// Connect returns a kv.Store that stores key-value pairs as IBM Key Protect standard keys.
+func (s *IBMKeyProtectKeyStore) Connect(ctx context.Context) (kv.Store[string, []byte], error) {
+	return ibm.Connect(ctx, &ibm.Config{
+		Endpoint:    s.Endpoint,
+		InstanceID:  s.InstanceID,
+		IAMEndpoint: s.IAMEndpoint,
+		CAPath:      s.CAPath,
+		Login: ibm.Credentials{
+			APIKey:    s.APIKey,
+			ServiceID: s.ServiceID,
+		},
+	})
+}
+
+// Connect returns a kv.Store that stores key-value pairs on GCP SecretManager.
+func (s *GCPSecretManagerKeyStore) Connect(ctx context.Context) (kv.Store[string, []byte], error) {
+	return gcp.Connect(ctx, &gcp.Config{
+		Endpoint:  s.Endpoint,
+		ProjectID: s.ProjectID,
+		Scopes:    s.Scopes,
+		Credentials: gcp.Credentials{
+			ClientID: s.ClientID,
+			Client:   s.ClientEmail,
+			KeyID:    s.KeyID,
+			Key:      s.Key,
+		},
+	})
+}
+
+// wrap returns a kv.Store that encrypts values with data keys wrapped
+// by the configured KMS before writing them to the given store.
+func (s *FSKeyStore) wrap(ctx context.Context, store kv.Store[string, []byte]) (kv.Store[string, []byte], error) {
+	var (
+		wrapper envelope.KeyWrapper
+		err     error
+	)
+	switch {
+	case s.AWSKMS != nil:
+		wrapper, err = aws.ConnectKMS(ctx, &aws.KMSConfig{
+			Addr:   s.AWSKMS.Endpoint,
+			Region: s.AWSKMS.Region,
+			KeyID:  s.AWSKMS.KeyID,
+			Login: aws.Credentials{
+				AccessKey:    s.AWSKMS.AccessKey,
+				SecretKey:    s.AWSKMS.SecretKey,
+				SessionToken: s.AWSKMS.SessionToken,
+			},
+		})
+	case s.GCPKMS != nil:
+		wrapper, err = gcp.ConnectKMS(ctx, &gcp.KMSConfig{
+			Endpoint: s.GCPKMS.Endpoint,
+			Key:      s.GCPKMS.Key,
+			Scopes:   s.GCPKMS.Scopes,
+			Credentials: gcp.Credentials{
+				ClientID: s.GCPKMS.ClientID,
+				Client:   s.GCPKMS.ClientEmail,
+				KeyID:    s.GCPKMS.KeyID,
+				Key:      s.GCPKMS.PrivateKey,
+			},
+		})
+	default:
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return envelope.NewStore(store, wrapper), nil
+}
+
+// Connect returns a kv.Store that stores key-value pairs, encrypted with
+// data keys wrapped by GCP Cloud KMS, in a path on the filesystem.
+func (s *GCPCloudKMSKeyStore) Connect(ctx context.Context) (kv.Store[string, []byte], error) {
+	store := &FSKeyStore{
+		Path: s.Path,
+		GCPKMS: &GCPKMSConfig{
+			Endpoint:    s.Endpoint,
+			Key:         s.Key,
+			Scopes:      s.Scopes,
+			ClientEmail: s.ClientEmail,
+			ClientID:    s.ClientID,
+			KeyID:       s.KeyID,
+			PrivateKey:  s.PrivateKey,
+		},
+	}
+	return store.Connect(ctx)
+}
+
+// Connect returns a kv.Store that stores key-value pairs on AWS SecretsManager.
+func (s *AWSSecretsManagerKeyStore) Connect(ctx context.Context) (kv.Store[string, []byte], error) {
+	return aws.Connect(ctx, &aws.Config{
+		Addr:     s.Endpoint,
+		Region:   s.Region,
+		KMSKeyID: s.KMSKey,
+		Login: aws.Credentials{
+			AccessKey:    s.AccessKey,
+			SecretKey:    s.SecretKey,
+			SessionToken: s.SessionToken,
+		},
+	})
+}
+
+// Connect returns a kv.Store that stores key-value pairs on an AWS CloudHSM cluster.
+func (s *AWSCloudHSMKeyStore) Connect(ctx context.Context) (kv.Store[string, []byte], error) {
+	return cloudhsm.Connect(ctx, &cloudhsm.Config{
+		Module:      s.Module,
+		Username:    s.Username,
+		Password:    s.Password,
+		WrappingKey: s.WrappingKey,
+		Application: s.Application,
+	})
+}
+
+// Connect returns a kv.Store that stores key-value pairs on Azure KeyVault.
+func (s *AzureKeyVaultKeyStore) Connect(ctx context.Context) (kv.Store[string, []byte], error) {
+	if (s.TenantID != "" || s.ClientID != "" || s.ClientSecret != "") && s.ManagedIdentityClientID != "" {
+		return nil, errors.New("edge: failed to connect to Azure KeyVault: more than one authentication method specified")
+	}
+	switch {
+	case s.TenantID != "" || s.ClientID != "" || s.ClientSecret != "":
+		creds := azure.Credentials{
+			TenantID: s.TenantID,
+			ClientID: s.ClientID,
+			Secret:   s.ClientSecret,
+		}
+		if s.ManagedHSM {
+			return azure.ConnectManagedHSMWithCredentials(ctx, s.Endpoint, s.WrappingKey, creds)
+		}
+		return azure.ConnectWithCredentials(ctx, s.Endpoint, creds)
+	case s.ManagedIdentityClientID != "":
+		creds := azure.ManagedIdentity{
+			ClientID: s.ManagedIdentityClientID,
+		}
+		if s.ManagedHSM {
+			return azure.ConnectManagedHSMWithIdentity(ctx, s.Endpoint, s.WrappingKey, creds)
+		}
+		return azure.ConnectWithIdentity(ctx, s.Endpoint, creds)
+	default:
+		return nil, errors.New("edge: failed to connect to Azure KeyVault: no authentication method specified")
+	}
+}
+
+// Connect returns a kv.Store that stores key-value pairs on Alibaba Cloud Secrets Manager.
+func (s *AlibabaSecretsManagerKeyStore) Connect(ctx context.Context) (kv.Store[string, []byte], error) {
+	if (s.AccessKeyID != "" || s.AccessKeySecret != "") && s.ECSRAMRole != "" {
+		return nil, errors.New("edge: failed to connect to Alibaba Secrets Manager: more than one authentication method specified")
+	}
+	return alibaba.Connect(ctx, &alibaba.Config{
+		Region:   s.Region,
+		Endpoint: s.Endpoint,
+		KMSKeyID: s.KMSKeyID,
+		Login: alibaba.Credentials{
+			AccessKeyID:     s.AccessKeyID,
+			AccessKeySecret: s.AccessKeySecret,
+		},
+		ECSRAMRole:      s.ECSRAMRole,
+		RoleARN:         s.RoleARN,
+		RoleSessionName: s.RoleSessionName,
+	})
+}
+
+// Connect returns a kv.Store that stores key-value pairs on OCI Vault.
+func (s *OCIVaultKeyStore) Connect(ctx context.Context) (kv.Store[string, []byte], error) {
+	var methods int
+	if s.TenancyID != "" || s.UserID != "" || s.Fingerprint != "" || s.PrivateKey != "" {
+		methods++
+	}
+	if s.ConfigFile != "" {
+		methods++
+	}
+	if s.InstancePrincipal {
+		methods++
+	}
+	if methods > 1 {
+		return nil, errors.New("edge: failed to connect to OCI Vault: more than one authentication method specified")
+	}
+	return oci.Connect(ctx, &oci.Config{
+		Region:         s.Region,
+		CompartmentID:  s.CompartmentID,
+		VaultID:        s.VaultID,
+		KeyID:          s.KeyID,
+		DeletionPeriod: s.DeletionPeriod,
+		Login: oci.Credentials{
+			TenancyID:   s.TenancyID,
+			UserID:      s.UserID,
+			Fingerprint: s.Fingerprint,
+			PrivateKey:  s.PrivateKey,
+		},
+		ConfigFile:        s.ConfigFile,
+		Profile:           s.Profile,
+		InstancePrincipal: s.InstancePrincipal,
+	})
+}
+
+// Connect returns a kv.Store that stores key-value pairs on a PKCS #11 token.
+func (s *PKCS11KeyStore) Connect(ctx context.Context) (kv.Store[string, []byte], error) {
+	return pkcs11.Connect(ctx, &pkcs11.Config{
+		Module:      s.Module,
+		Slot:        s.Slot,
+		TokenLabel:  s.TokenLabel,
+		PIN:         s.PIN,
+		WrappingKey: s.WrappingKey,
+		Application: s.Application,
+	})
+}
@@ -0,0 +1,104 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+//go:build minimal
+// +build minimal
+
+package edge
+
+import (
+	"context"
+	"errors"
+
+	"github.com/minio/kes/kv"
+)
+
+// errMinimal is returned when connecting to a keystore
+// that is not supported by the minimal build profile.
+var errMinimal = errors.New("edge: keystore is not supported by minimal build. Rebuild without the 'minimal' build tag")
+
+// Connect returns an error since etcd is not supported by minimal builds.
+func (s *EtcdKeyStore) Connect(context.Context) (kv.Store[string, []byte], error) {
+	return nil, errMinimal
+}
+
+// Connect returns an error since SQL databases are not supported by minimal builds.
+func (s *SQLKeyStore) Connect(context.Context) (kv.Store[string, []byte], error) {
+	return nil, errMinimal
+}
+
+// Connect returns an error since Fortanix SDKMS is not supported by minimal builds.
+func (s *FortanixKeyStore) Connect(context.Context) (kv.Store[string, []byte], error) {
+	return nil, errMinimal
+}
+
+// Connect returns an error since Gemalto KeySecure is not supported by minimal builds.
+func (s *KeySecureKeyStore) Connect(context.Context) (kv.Store[string, []byte], error) {
+	return nil, errMinimal
+}
+
+// Connect returns an error since generic REST APIs are not supported by minimal builds.
+func (s *RESTKeyStore) Connect(context.Context) (kv.Store[string, []byte], error) {
+	return nil, errMinimal
+}
+
+// Connect returns an error since 1Password is not supported by minimal builds.
+func (s *OnePasswordKeyStore) Connect(context.Context) (kv.Store[string, []byte], error) {
+	return nil, errMinimal
+}
+
+// Connect returns an error since CyberArk Conjur is not supported by minimal builds.
+func (s *ConjurKeyStore) Connect(context.Context) (kv.Store[string, []byte], error) {
+	return nil, errMinimal
+}
+
+// Connect returns an error since IBM Key Protect is not supported by minimal builds.
+func (s *IBMKeyProtectKeyStore) Connect(context.Context) (kv.Store[string, []byte], error) {
+	return nil, errMinimal
+}
+
+// wrap returns an error since KMS wrapping is not supported by minimal builds.
+func (s *FSKeyStore) wrap(context.Context, kv.Store[string, []byte]) (kv.Store[string, []byte], error) {
+	return nil, errMinimal
+}
+
+// Connect returns an error since GCP Cloud KMS is not supported by minimal builds.
+func (s *GCPCloudKMSKeyStore) Connect(context.Context) (kv.Store[string, []byte], error) {
+	return nil, errMinimal
+}
+
+// Connect returns an error since GCP SecretManager is not supported by minimal builds.
+func (s *GCPSecretManagerKeyStore) Connect(context.Context) (kv.Store[string, []byte], error) {
+	return nil, errMinimal
+}
+
+// Connect returns an error since AWS SecretsManager is not supported by minimal builds.
+func (s *AWSSecretsManagerKeyStore) Connect(context.Context) (kv.Store[string, []byte], error) {
+	return nil, errMinimal
+}
+
+// Connect returns an error since Azure KeyVault is not supported by minimal builds.
+func (s *AzureKeyVaultKeyStore) Connect(context.Context) (kv.Store[string, []byte], error) {
+	return nil, errMinimal
+}
+
+// Connect returns an error since Alibaba Cloud Secrets Manager is not supported by minimal builds.
+func (s *AlibabaSecretsManagerKeyStore) Connect(context.Context) (kv.Store[string, []byte], error) {
+	return nil, errMinimal
+}
+
+// Connect returns an error since OCI Vault is not supported by minimal builds.
+func (s *OCIVaultKeyStore) Connect(context.Context) (kv.Store[string, []byte], error) {
+	return nil, errMinimal
+}
+
+// Connect returns an error since PKCS #11 is not supported by minimal builds.
+func (s *PKCS11KeyStore) Connect(context.Context) (kv.Store[string, []byte], error) {
+	return nil, errMinimal
+}
+
+// Connect returns an error since AWS CloudHSM is not supported by minimal builds.
+func (s *AWSCloudHSMKeyStore) Connect(context.Context) (kv.Store[string, []byte], error) {
+	return nil, errMinimal
+}
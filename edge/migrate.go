@@ -0,0 +1,223 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package edge
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Server config schema versions.
+const (
+	// versionLegacy is the schema version of server config
+	// files without any version. It is equal to "v0".
+	versionLegacy = "v0"
+
+	// versionCurrent is the current schema version.
+	versionCurrent = "v1"
+)
+
+// MigrateServerConfigYAML reads a server config from r and
+// upgrades it to the current schema version. It returns the
+// upgraded config as YAML and a deprecation warning for each
+// field that has been renamed or is not supported anymore.
+//
+// Unsupported fields are kept as they are but ignored by
+// ReadServerConfigYAML.
+func MigrateServerConfigYAML(r io.Reader) ([]byte, []string, error) {
+	var node yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&node); err != nil {
+		return nil, nil, err
+	}
+
+	warnings, err := migrate(&node)
+	if err != nil {
+		return nil, nil, err
+	}
+	warnings = append(warnings, unknownFields(&node)...)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err = encoder.Encode(&node); err != nil {
+		return nil, nil, err
+	}
+	if err = encoder.Close(); err != nil {
+		return nil, nil, err
+	}
+	return buf.Bytes(), warnings, nil
+}
+
+// migrate upgrades the YAML document root to the current
+// schema version and returns a deprecation warning for each
+// field that has been renamed.
+func migrate(root *yaml.Node) ([]string, error) {
+	version, err := findVersion(root)
+	if err != nil {
+		return nil, err
+	}
+	switch version {
+	case "", versionLegacy:
+		doc := root.Content[0]
+		warnings := migrateLegacy(doc)
+		setVersion(doc, versionCurrent)
+		return warnings, nil
+	case versionCurrent:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("edge: invalid server config version '%s'", version)
+	}
+}
+
+// migrateLegacy upgrades a server config document without
+// any version to the v1 schema:
+//   - 'root' has been replaced by 'admin.identity'.
+//   - 'keys' has been renamed to 'keystore' if it is a keystore
+//     configuration and not a list of keys.
+//   - 'policy.<name>.paths' has been renamed to 'policy.<name>.allow'.
+func migrateLegacy(doc *yaml.Node) []string {
+	var warnings []string
+	if key, value := lookup(doc, "root"); key != nil {
+		if admin, _ := lookup(doc, "admin"); admin == nil {
+			key.Value = "admin"
+			*value = yaml.Node{
+				Kind:    yaml.MappingNode,
+				Tag:     "!!map",
+				Content: []*yaml.Node{scalar("identity"), {Kind: value.Kind, Tag: value.Tag, Value: value.Value, Style: value.Style}},
+			}
+		}
+		warnings = append(warnings, fmt.Sprintf("line %d: 'root' is deprecated: use 'admin.identity' instead", key.Line))
+	}
+	if key, value := lookup(doc, "keys"); key != nil && value.Kind == yaml.MappingNode {
+		if keystore, _ := lookup(doc, "keystore"); keystore == nil {
+			key.Value = "keystore"
+		}
+		warnings = append(warnings, fmt.Sprintf("line %d: 'keys' is deprecated as keystore configuration: use 'keystore' instead", key.Line))
+	}
+	if _, policies := lookup(doc, "policy"); policies != nil && policies.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(policies.Content); i += 2 {
+			name, policy := policies.Content[i], policies.Content[i+1]
+			if key, _ := lookup(policy, "paths"); key != nil {
+				if allow, _ := lookup(policy, "allow"); allow == nil {
+					key.Value = "allow"
+				}
+				warnings = append(warnings, fmt.Sprintf("line %d: 'policy.%s.paths' is deprecated: use 'policy.%s.allow' instead", key.Line, name.Value, name.Value))
+			}
+		}
+	}
+	return warnings
+}
+
+// unknownFields returns a warning for each field of the YAML
+// document root that is not part of the current schema.
+func unknownFields(root *yaml.Node) []string {
+	if root.Kind != yaml.DocumentNode || len(root.Content) != 1 {
+		return nil
+	}
+	return findUnknownFields(root.Content[0], reflect.TypeOf(yml{}), "")
+}
+
+var unmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+
+// findUnknownFields returns a warning for each field of n,
+// and its children, that is not a field of the type t.
+func findUnknownFields(n *yaml.Node, t reflect.Type, path string) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(unmarshalerType) {
+		return nil
+	}
+
+	var warnings []string
+	switch t.Kind() {
+	case reflect.Slice:
+		if n.Kind == yaml.SequenceNode {
+			for _, item := range n.Content {
+				warnings = append(warnings, findUnknownFields(item, t.Elem(), path)...)
+			}
+		}
+	case reflect.Map:
+		if n.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				warnings = append(warnings, findUnknownFields(n.Content[i+1], t.Elem(), path+"."+n.Content[i].Value)...)
+			}
+		}
+	case reflect.Struct:
+		if n.Kind != yaml.MappingNode {
+			return nil
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			field, ok := lookupField(t, key.Value)
+			if !ok {
+				name := strings.TrimPrefix(path+"."+key.Value, ".")
+				warnings = append(warnings, fmt.Sprintf("line %d: '%s' is not supported anymore and will be ignored", key.Line, name))
+				continue
+			}
+			if field.Type != nil {
+				warnings = append(warnings, findUnknownFields(value, field.Type, path+"."+key.Value)...)
+			}
+		}
+	}
+	return warnings
+}
+
+// lookupField returns the field of the struct type t with
+// the given YAML name. If t has an inline map, any name is
+// a field of t and the returned field has the map's value
+// type.
+func lookupField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, opts, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if opts == "inline" && field.Type.Kind() == reflect.Map {
+			return reflect.StructField{Type: field.Type.Elem()}, true
+		}
+		if tag == "" {
+			tag = strings.ToLower(field.Name)
+		}
+		if strings.TrimSpace(tag) == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// setVersion sets the version field of the mapping
+// node doc. If doc has no version field, it gets
+// added as first field.
+func setVersion(doc *yaml.Node, version string) {
+	if _, value := lookup(doc, "version"); value != nil {
+		value.Value, value.Tag, value.Style = version, "!!str", 0
+		return
+	}
+	doc.Content = append([]*yaml.Node{scalar("version"), scalar(version)}, doc.Content...)
+}
+
+// lookup returns the key and value node of the given
+// field of the mapping node n, or nil if n has no such
+// field.
+func lookup(n *yaml.Node, field string) (key, value *yaml.Node) {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == field {
+			return n.Content[i], n.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// scalar returns a new YAML string node.
+func scalar(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
@@ -0,0 +1,75 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package edge
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMigrateServerConfigYAML(t *testing.T) {
+	const Filename = "./testdata/legacy-v0.yml"
+
+	file, err := os.Open(Filename)
+	if err != nil {
+		t.Fatalf("Failed to access file '%s': %v", Filename, err)
+	}
+	defer file.Close()
+
+	config, warnings, err := MigrateServerConfigYAML(file)
+	if err != nil {
+		t.Fatalf("Failed to migrate file '%s': %v", Filename, err)
+	}
+
+	wantWarnings := []string{
+		"line 2: 'root' is deprecated: use 'admin.identity' instead",
+		"line 20: 'keys' is deprecated as keystore configuration: use 'keystore' instead",
+		"line 10: 'policy.my-app.paths' is deprecated: use 'policy.my-app.allow' instead",
+		"line 18: 'log.trace' is not supported anymore and will be ignored",
+	}
+	if len(warnings) != len(wantWarnings) {
+		t.Fatalf("Invalid warnings: got '%v' - want '%v'", warnings, wantWarnings)
+	}
+	for i := range warnings {
+		if warnings[i] != wantWarnings[i] {
+			t.Fatalf("Invalid warning %d: got '%s' - want '%s'", i, warnings[i], wantWarnings[i])
+		}
+	}
+
+	for _, s := range []string{"version: v1\n", "admin:\n  identity: ", "keystore:\n  fs:", "    allow:\n"} {
+		if !bytes.Contains(config, []byte(s)) {
+			t.Fatalf("Upgraded config does not contain '%s':\n%s", s, config)
+		}
+	}
+	if _, err = ReadServerConfigYAML(bytes.NewReader(config)); err != nil {
+		t.Fatalf("Failed to read upgraded config: %v", err)
+	}
+
+	// Migrating an upgraded config must not
+	// change it anymore.
+	again, warnings, err := MigrateServerConfigYAML(bytes.NewReader(config))
+	if err != nil {
+		t.Fatalf("Failed to migrate upgraded config: %v", err)
+	}
+	if !bytes.Equal(again, config) {
+		t.Fatalf("Upgraded config has changed:\n%s\n- want -\n%s", again, config)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "'log.trace'") {
+		t.Fatalf("Invalid warnings: got '%v' - want only 'log.trace' warning", warnings)
+	}
+}
+
+func TestMigrateServerConfigYAML_InvalidVersion(t *testing.T) {
+	const Config = "version: v2\naddress: 0.0.0.0:7373\n"
+
+	if _, _, err := MigrateServerConfigYAML(strings.NewReader(Config)); err == nil {
+		t.Fatal("Migrated config with invalid version")
+	}
+	if _, err := ReadServerConfigYAML(strings.NewReader(Config)); err == nil {
+		t.Fatal("Read config with invalid version")
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package edge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/kv"
+)
+
+// WithMirror returns a kv.Store that applies any modification
+// to the primary store and the mirror while read requests are
+// served by the primary store only. It allows migrating from
+// one KeyStore to another without downtime by mirroring all
+// changes to the new KeyStore while the existing entries get
+// copied, e.g. using 'kes migrate'.
+//
+// The primary store is the source of truth. A request fails
+// only if the primary store fails. A mirror error, e.g. when
+// the mirror is unavailable, is passed to onError, if not nil,
+// and does not affect the request. Hence, the mirror may miss
+// some changes. Use 'kes mirror check' to find inconsistencies
+// before switching to the mirror.
+func WithMirror(primary, mirror kv.Store[string, []byte], onError func(error)) kv.Store[string, []byte] {
+	if onError == nil {
+		onError = func(error) {}
+	}
+	s := &mirrorStore{
+		primary: primary,
+		mirror:  mirror,
+		onError: onError,
+	}
+	if _, ok := primary.(kv.Recoverer[string]); ok {
+		return &recoverableMirrorStore{mirrorStore: s}
+	}
+	return s
+}
+
+type mirrorStore struct {
+	primary kv.Store[string, []byte]
+	mirror  kv.Store[string, []byte]
+	onError func(error)
+}
+
+var (
+	_ kv.Store[string, []byte] = (*mirrorStore)(nil)
+	_ kv.Watcher[string]       = (*mirrorStore)(nil)
+)
+
+// Status returns the status of the primary store.
+func (s *mirrorStore) Status(ctx context.Context) (kv.State, error) {
+	return s.primary.Status(ctx)
+}
+
+func (s *mirrorStore) Create(ctx context.Context, key string, value []byte) error {
+	if err := s.primary.Create(ctx, key, value); err != nil {
+		return err
+	}
+	s.report(key, s.mirror.Create(ctx, key, value))
+	return nil
+}
+
+func (s *mirrorStore) Set(ctx context.Context, key string, value []byte) error {
+	if err := s.primary.Set(ctx, key, value); err != nil {
+		return err
+	}
+	s.report(key, s.mirror.Set(ctx, key, value))
+	return nil
+}
+
+func (s *mirrorStore) Get(ctx context.Context, key string) ([]byte, error) {
+	return s.primary.Get(ctx, key)
+}
+
+func (s *mirrorStore) GetMany(ctx context.Context, keys []string) ([][]byte, []error) {
+	return s.primary.GetMany(ctx, keys)
+}
+
+func (s *mirrorStore) Update(ctx context.Context, key string, oldValue, newValue []byte) error {
+	if err := s.primary.Update(ctx, key, oldValue, newValue); err != nil {
+		return err
+	}
+	s.report(key, s.mirror.Update(ctx, key, oldValue, newValue))
+	return nil
+}
+
+func (s *mirrorStore) Delete(ctx context.Context, key string) error {
+	if err := s.primary.Delete(ctx, key); err != nil {
+		return err
+	}
+	if err := s.mirror.Delete(ctx, key); !isNotExists(err) {
+		s.report(key, err)
+	}
+	return nil
+}
+
+func (s *mirrorStore) DeleteMany(ctx context.Context, keys []string) []error {
+	errs := s.primary.DeleteMany(ctx, keys)
+
+	deleted := make([]string, 0, len(keys))
+	for i, err := range errs {
+		if err == nil {
+			deleted = append(deleted, keys[i])
+		}
+	}
+	if len(deleted) > 0 {
+		for i, err := range s.mirror.DeleteMany(ctx, deleted) {
+			if !isNotExists(err) {
+				s.report(deleted[i], err)
+			}
+		}
+	}
+	return errs
+}
+
+func (s *mirrorStore) List(ctx context.Context, prefix string) (kv.Iter[string], error) {
+	return s.primary.List(ctx, prefix)
+}
+
+// Watch calls the Watch method of the primary store,
+// if it is a kv.Watcher. Otherwise, it returns nil.
+func (s *mirrorStore) Watch(ctx context.Context, f func(string)) error {
+	if w, ok := s.primary.(kv.Watcher[string]); ok {
+		return w.Watch(ctx, f)
+	}
+	return nil
+}
+
+// report passes a non-nil mirror error for
+// the given key to the onError function.
+func (s *mirrorStore) report(key string, err error) {
+	if err != nil {
+		s.onError(fmt.Errorf("edge: failed to mirror '%s': %w", key, err))
+	}
+}
+
+// recoverableMirrorStore is a mirrorStore with a primary
+// kv.Store that deletes entries softly.
+type recoverableMirrorStore struct {
+	*mirrorStore
+}
+
+var _ kv.Recoverer[string] = (*recoverableMirrorStore)(nil)
+
+func (s *recoverableMirrorStore) ListDeleted(ctx context.Context) (kv.Iter[kv.Deleted[string]], error) {
+	return s.primary.(kv.Recoverer[string]).ListDeleted(ctx)
+}
+
+// Recover recovers the deleted entry at the primary store
+// and creates it at the mirror again. The mirror may not
+// support soft deletion.
+func (s *recoverableMirrorStore) Recover(ctx context.Context, key string) error {
+	if err := s.primary.(kv.Recoverer[string]).Recover(ctx, key); err != nil {
+		return err
+	}
+	value, err := s.primary.Get(ctx, key)
+	if err == nil {
+		err = s.mirror.Create(ctx, key, value)
+	}
+	s.report(key, err)
+	return nil
+}
+
+func (s *recoverableMirrorStore) Purge(ctx context.Context, key string) error {
+	return s.primary.(kv.Recoverer[string]).Purge(ctx, key)
+}
+
+// isNotExists reports whether err indicates that
+// an entry does not exist.
+func isNotExists(err error) bool {
+	return errors.Is(err, kes.ErrKeyNotFound) || errors.Is(err, kv.ErrNotExists)
+}
@@ -0,0 +1,65 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package edge
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/keystore/mem"
+)
+
+func TestWithMirror(t *testing.T) {
+	ctx := context.Background()
+	primary, mirror := &mem.Store{}, &mem.Store{}
+
+	var mirrorErrs []error
+	s := WithMirror(primary, mirror, func(err error) { mirrorErrs = append(mirrorErrs, err) })
+
+	if err := s.Create(ctx, "my-key", []byte("my-value")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	for _, store := range []*mem.Store{primary, mirror} {
+		if value, err := store.Get(ctx, "my-key"); err != nil || !bytes.Equal(value, []byte("my-value")) {
+			t.Fatalf("Key has not been mirrored: got '%s' - '%v'", value, err)
+		}
+	}
+
+	// A key that exists at the mirror but not at the
+	// primary must not fail the request.
+	if err := mirror.Create(ctx, "other-key", []byte("stale-value")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if err := s.Create(ctx, "other-key", []byte("other-value")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if len(mirrorErrs) != 1 || !errors.Is(mirrorErrs[0], kes.ErrKeyExists) {
+		t.Fatalf("Invalid mirror errors: got '%v' - want '%v'", mirrorErrs, kes.ErrKeyExists)
+	}
+	if value, err := s.Get(ctx, "other-key"); err != nil || !bytes.Equal(value, []byte("other-value")) {
+		t.Fatalf("Failed to get key from primary: got '%s' - '%v'", value, err)
+	}
+
+	// Deleting keys that don't exist at the mirror is
+	// not an error.
+	if err := mirror.Delete(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to delete key: %v", err)
+	}
+	if err := s.Delete(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to delete key: %v", err)
+	}
+	if errs := s.DeleteMany(ctx, []string{"other-key"}); errs[0] != nil {
+		t.Fatalf("Failed to delete key: %v", errs[0])
+	}
+	if len(mirrorErrs) != 1 {
+		t.Fatalf("Invalid mirror errors: got '%v' - want 1 error", mirrorErrs)
+	}
+	if _, err := mirror.Get(ctx, "other-key"); !errors.Is(err, kes.ErrKeyNotFound) {
+		t.Fatalf("Key has not been deleted at mirror: got '%v' - want '%v'", err, kes.ErrKeyNotFound)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package edge
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/minio/kes/kv"
+)
+
+// WithReplicas returns a kv.Store that sends requests that
+// modify entries to the primary store and distributes read
+// requests across the read-only replicas in a round-robin
+// fashion. If there are no replicas, it returns primary.
+//
+// Replicas may lag behind the primary store. Hence, a read
+// request that fails on a replica, for example since the
+// entry has not been replicated yet, is sent to the primary
+// store again.
+func WithReplicas(primary kv.Store[string, []byte], replicas ...kv.Store[string, []byte]) kv.Store[string, []byte] {
+	if len(replicas) == 0 {
+		return primary
+	}
+
+	s := &replicaStore{
+		primary:  primary,
+		replicas: replicas,
+	}
+	if _, ok := primary.(kv.Recoverer[string]); ok {
+		return &recoverableReplicaStore{replicaStore: s}
+	}
+	return s
+}
+
+type replicaStore struct {
+	primary  kv.Store[string, []byte]
+	replicas []kv.Store[string, []byte]
+	next     uint64
+}
+
+var (
+	_ kv.Store[string, []byte] = (*replicaStore)(nil)
+	_ kv.Watcher[string]       = (*replicaStore)(nil)
+)
+
+// Status returns the status of the primary store.
+func (s *replicaStore) Status(ctx context.Context) (kv.State, error) {
+	return s.primary.Status(ctx)
+}
+
+func (s *replicaStore) Create(ctx context.Context, key string, value []byte) error {
+	return s.primary.Create(ctx, key, value)
+}
+
+func (s *replicaStore) Set(ctx context.Context, key string, value []byte) error {
+	return s.primary.Set(ctx, key, value)
+}
+
+func (s *replicaStore) Get(ctx context.Context, key string) ([]byte, error) {
+	if value, err := s.replica().Get(ctx, key); err == nil {
+		return value, nil
+	}
+	return s.primary.Get(ctx, key)
+}
+
+func (s *replicaStore) GetMany(ctx context.Context, keys []string) ([][]byte, []error) {
+	values, errs := s.replica().GetMany(ctx, keys)
+
+	var (
+		failed  []string
+		indices []int
+	)
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, keys[i])
+			indices = append(indices, i)
+		}
+	}
+	if len(failed) == 0 {
+		return values, errs
+	}
+
+	v, e := s.primary.GetMany(ctx, failed)
+	for j, i := range indices {
+		values[i], errs[i] = v[j], e[j]
+	}
+	return values, errs
+}
+
+func (s *replicaStore) Update(ctx context.Context, key string, oldValue, newValue []byte) error {
+	return s.primary.Update(ctx, key, oldValue, newValue)
+}
+
+func (s *replicaStore) Delete(ctx context.Context, key string) error {
+	return s.primary.Delete(ctx, key)
+}
+
+func (s *replicaStore) DeleteMany(ctx context.Context, keys []string) []error {
+	return s.primary.DeleteMany(ctx, keys)
+}
+
+func (s *replicaStore) List(ctx context.Context, prefix string) (kv.Iter[string], error) {
+	if iter, err := s.replica().List(ctx, prefix); err == nil {
+		return iter, nil
+	}
+	return s.primary.List(ctx, prefix)
+}
+
+// Watch calls the Watch method of the primary store,
+// if it is a kv.Watcher. Otherwise, it returns nil.
+func (s *replicaStore) Watch(ctx context.Context, f func(string)) error {
+	if w, ok := s.primary.(kv.Watcher[string]); ok {
+		return w.Watch(ctx, f)
+	}
+	return nil
+}
+
+// replica returns the replica that should serve
+// the next read request.
+func (s *replicaStore) replica() kv.Store[string, []byte] {
+	n := atomic.AddUint64(&s.next, 1)
+	return s.replicas[n%uint64(len(s.replicas))]
+}
+
+// recoverableReplicaStore is a replicaStore with a
+// primary kv.Store that deletes entries softly.
+type recoverableReplicaStore struct {
+	*replicaStore
+}
+
+var _ kv.Recoverer[string] = (*recoverableReplicaStore)(nil)
+
+func (s *recoverableReplicaStore) ListDeleted(ctx context.Context) (kv.Iter[kv.Deleted[string]], error) {
+	return s.primary.(kv.Recoverer[string]).ListDeleted(ctx)
+}
+
+func (s *recoverableReplicaStore) Recover(ctx context.Context, key string) error {
+	return s.primary.(kv.Recoverer[string]).Recover(ctx, key)
+}
+
+func (s *recoverableReplicaStore) Purge(ctx context.Context, key string) error {
+	return s.primary.(kv.Recoverer[string]).Purge(ctx, key)
+}
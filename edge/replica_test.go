@@ -0,0 +1,57 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package edge
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/keystore/mem"
+)
+
+func TestWithReplicas(t *testing.T) {
+	ctx := context.Background()
+	primary, replica := &mem.Store{}, &mem.Store{}
+	s := WithReplicas(primary, replica)
+
+	// Writes go to the primary.
+	if err := s.Create(ctx, "my-key", []byte("my-value")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if _, err := replica.Get(ctx, "my-key"); !errors.Is(err, kes.ErrKeyNotFound) {
+		t.Fatalf("Key has been created at replica: got '%v' - want '%v'", err, kes.ErrKeyNotFound)
+	}
+
+	// Reads fall back to the primary if the key has not
+	// been replicated yet.
+	if value, err := s.Get(ctx, "my-key"); err != nil || !bytes.Equal(value, []byte("my-value")) {
+		t.Fatalf("Failed to get key: got '%s' - '%v'", value, err)
+	}
+
+	// Reads are served by the replica.
+	if err := replica.Create(ctx, "my-key", []byte("replica-value")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if value, err := s.Get(ctx, "my-key"); err != nil || !bytes.Equal(value, []byte("replica-value")) {
+		t.Fatalf("Failed to get key from replica: got '%s' - '%v'", value, err)
+	}
+
+	if err := primary.Create(ctx, "other-key", []byte("other-value")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	values, errs := s.GetMany(ctx, []string{"my-key", "other-key", "unknown-key"})
+	if errs[0] != nil || !bytes.Equal(values[0], []byte("replica-value")) {
+		t.Fatalf("Failed to get key from replica: got '%s' - '%v'", values[0], errs[0])
+	}
+	if errs[1] != nil || !bytes.Equal(values[1], []byte("other-value")) {
+		t.Fatalf("Failed to get key from primary: got '%s' - '%v'", values[1], errs[1])
+	}
+	if !errors.Is(errs[2], kes.ErrKeyNotFound) {
+		t.Fatalf("Getting non-existing key: got '%v' - want '%v'", errs[2], kes.ErrKeyNotFound)
+	}
+}
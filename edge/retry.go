@@ -0,0 +1,410 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package edge
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/minio/kes/kv"
+)
+
+// RetryConfig is a structure containing the configuration
+// for retrying KeyStore requests that fail due to transient
+// errors.
+type RetryConfig struct {
+	// MaxAttempts is the max. number of attempts, including
+	// the first one, for a single KeyStore request. If less
+	// than 2, requests are not retried.
+	MaxAttempts int
+
+	// MinDelay is the delay before the first retry. It
+	// doubles with every retry until it reaches MaxDelay.
+	// If zero, defaults to 100ms.
+	MinDelay time.Duration
+
+	// MaxDelay is the max. delay between two attempts.
+	// If zero, defaults to 5s.
+	MaxDelay time.Duration
+
+	_ [0]int
+}
+
+// CircuitBreakerConfig is a structure containing the
+// configuration of a KeyStore circuit breaker.
+//
+// Once a KeyStore fails a number of consecutive requests
+// due to transient errors, the circuit breaker opens and
+// requests fail immediately, without reaching the KeyStore,
+// until the timeout has elapsed. Then, the circuit breaker
+// lets a single request pass. If it succeeds, the circuit
+// breaker closes again. Otherwise, it stays open for
+// another timeout period.
+type CircuitBreakerConfig struct {
+	// Threshold is the number of consecutive failed
+	// requests after which the circuit breaker opens.
+	// If zero, defaults to 5.
+	Threshold int
+
+	// Timeout is the time period the circuit breaker
+	// remains open. If zero, defaults to 30s.
+	Timeout time.Duration
+
+	_ [0]int
+}
+
+// errCircuitOpen is returned by a KeyStore wrapped by
+// WithRetry while its circuit breaker is open.
+var errCircuitOpen = &kv.Unavailable{Err: errors.New("edge: keystore circuit breaker is open")}
+
+// WithRetry returns a kv.Store that retries requests to
+// the store that fail due to transient errors, like
+// kv.Unreachable errors or network timeouts, with an
+// exponential backoff and jitter. If breaker is not nil,
+// requests fail immediately while the store keeps failing.
+//
+// Only requests that read entries are retried. Requests
+// that create, modify or delete entries pass the circuit
+// breaker but are never retried since a request may have
+// failed after the store has applied it. For example, a
+// retried delete would fail with kv.ErrNotExists. Status requests are
+// neither retried nor subject to the circuit breaker
+// such that health checks report the actual state of
+// the store.
+func WithRetry(store kv.Store[string, []byte], retry *RetryConfig, breaker *CircuitBreakerConfig) kv.Store[string, []byte] {
+	s := &retryStore{
+		store:       store,
+		maxAttempts: 1,
+	}
+	if retry != nil {
+		s.maxAttempts = retry.MaxAttempts
+		s.minDelay, s.maxDelay = retry.MinDelay, retry.MaxDelay
+		if s.minDelay == 0 {
+			s.minDelay = 100 * time.Millisecond
+		}
+		if s.maxDelay == 0 {
+			s.maxDelay = 5 * time.Second
+		}
+		if s.maxDelay < s.minDelay {
+			s.maxDelay = s.minDelay
+		}
+	}
+	if breaker != nil {
+		s.breaker = &circuitBreaker{
+			threshold: breaker.Threshold,
+			timeout:   breaker.Timeout,
+		}
+		if s.breaker.threshold == 0 {
+			s.breaker.threshold = 5
+		}
+		if s.breaker.timeout == 0 {
+			s.breaker.timeout = 30 * time.Second
+		}
+	}
+
+	if _, ok := store.(kv.Recoverer[string]); ok {
+		return &recoverableRetryStore{retryStore: s}
+	}
+	return s
+}
+
+type retryStore struct {
+	store kv.Store[string, []byte]
+
+	maxAttempts        int
+	minDelay, maxDelay time.Duration
+	breaker            *circuitBreaker // May be nil
+}
+
+var (
+	_ kv.Store[string, []byte] = (*retryStore)(nil)
+	_ kv.Watcher[string]       = (*retryStore)(nil)
+)
+
+func (s *retryStore) Status(ctx context.Context) (kv.State, error) {
+	return s.store.Status(ctx)
+}
+
+func (s *retryStore) Create(ctx context.Context, key string, value []byte) error {
+	return s.do(ctx, false, func() error { return s.store.Create(ctx, key, value) })
+}
+
+func (s *retryStore) Set(ctx context.Context, key string, value []byte) error {
+	return s.do(ctx, false, func() error { return s.store.Set(ctx, key, value) })
+}
+
+func (s *retryStore) Get(ctx context.Context, key string) (value []byte, err error) {
+	err = s.do(ctx, true, func() (err error) {
+		value, err = s.store.Get(ctx, key)
+		return err
+	})
+	return value, err
+}
+
+func (s *retryStore) GetMany(ctx context.Context, keys []string) ([][]byte, []error) {
+	values := make([][]byte, len(keys))
+	errs := s.doMany(ctx, true, keys, func(keys []string, indices []int, errs []error) {
+		v, e := s.store.GetMany(ctx, keys)
+		for j, i := range indices {
+			values[i], errs[i] = v[j], e[j]
+		}
+	})
+	return values, errs
+}
+
+func (s *retryStore) Update(ctx context.Context, key string, oldValue, newValue []byte) error {
+	return s.do(ctx, false, func() error { return s.store.Update(ctx, key, oldValue, newValue) })
+}
+
+func (s *retryStore) Delete(ctx context.Context, key string) error {
+	return s.do(ctx, false, func() error { return s.store.Delete(ctx, key) })
+}
+
+func (s *retryStore) DeleteMany(ctx context.Context, keys []string) []error {
+	return s.doMany(ctx, false, keys, func(keys []string, indices []int, errs []error) {
+		for j, err := range s.store.DeleteMany(ctx, keys) {
+			errs[indices[j]] = err
+		}
+	})
+}
+
+func (s *retryStore) List(ctx context.Context, prefix string) (iter kv.Iter[string], err error) {
+	err = s.do(ctx, true, func() (err error) {
+		iter, err = s.store.List(ctx, prefix)
+		return err
+	})
+	return iter, err
+}
+
+// Watch calls the Watch method of the underlying store,
+// if it is a kv.Watcher. Otherwise, it returns nil.
+func (s *retryStore) Watch(ctx context.Context, f func(string)) error {
+	if w, ok := s.store.(kv.Watcher[string]); ok {
+		return w.Watch(ctx, f)
+	}
+	return nil
+}
+
+// do calls f until it succeeds, fails with a non-transient
+// error or, if retry is true, the max. number of attempts
+// is reached.
+func (s *retryStore) do(ctx context.Context, retry bool, f func() error) error {
+	ok, probe := s.breaker.Allow()
+	if !ok {
+		return errCircuitOpen
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = f(); err == nil || !isTransient(err) {
+			break
+		}
+		if !retry || attempt >= s.maxAttempts || !s.wait(ctx, attempt) {
+			break
+		}
+	}
+	s.breaker.Done(probe, err)
+	return err
+}
+
+// doMany is like do but for batch requests. It calls f with
+// all keys first and then, if retry is true, retries f with
+// the keys that failed due to transient errors. The indices
+// passed to f map the keys to their positions within the
+// errs slice.
+func (s *retryStore) doMany(ctx context.Context, retry bool, keys []string, f func(keys []string, indices []int, errs []error)) []error {
+	errs := make([]error, len(keys))
+	ok, probe := s.breaker.Allow()
+	if !ok {
+		for i := range errs {
+			errs[i] = errCircuitOpen
+		}
+		return errs
+	}
+
+	all, indices := keys, make([]int, len(keys))
+	for i := range indices {
+		indices[i] = i
+	}
+	for attempt := 1; ; attempt++ {
+		f(keys, indices, errs)
+
+		var retryIndices []int
+		for _, i := range indices {
+			if errs[i] != nil && isTransient(errs[i]) {
+				retryIndices = append(retryIndices, i)
+			}
+		}
+		if !retry || len(retryIndices) == 0 || attempt >= s.maxAttempts || !s.wait(ctx, attempt) {
+			break
+		}
+		keys, indices = make([]string, 0, len(retryIndices)), retryIndices
+		for _, i := range indices {
+			keys = append(keys, all[i])
+		}
+	}
+
+	// The batch request has failed if any key has failed
+	// due to a transient error.
+	var err error
+	for _, e := range errs {
+		if e != nil && isTransient(e) {
+			err = e
+			break
+		}
+		if errors.Is(e, context.Canceled) {
+			err = e
+		}
+	}
+	s.breaker.Done(probe, err)
+	return errs
+}
+
+// wait waits for the backoff delay after the given attempt.
+// It returns false if the ctx is done before.
+func (s *retryStore) wait(ctx context.Context, attempt int) bool {
+	delay := s.minDelay
+	for i := 1; i < attempt && delay < s.maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > s.maxDelay {
+		delay = s.maxDelay
+	}
+	delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1)) // Add jitter
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// recoverableRetryStore is a retryStore wrapping a
+// kv.Store that deletes entries softly.
+type recoverableRetryStore struct {
+	*retryStore
+}
+
+var _ kv.Recoverer[string] = (*recoverableRetryStore)(nil)
+
+func (s *recoverableRetryStore) ListDeleted(ctx context.Context) (iter kv.Iter[kv.Deleted[string]], err error) {
+	err = s.do(ctx, true, func() (err error) {
+		iter, err = s.store.(kv.Recoverer[string]).ListDeleted(ctx)
+		return err
+	})
+	return iter, err
+}
+
+func (s *recoverableRetryStore) Recover(ctx context.Context, key string) error {
+	return s.do(ctx, false, func() error { return s.store.(kv.Recoverer[string]).Recover(ctx, key) })
+}
+
+func (s *recoverableRetryStore) Purge(ctx context.Context, key string) error {
+	return s.do(ctx, false, func() error { return s.store.(kv.Recoverer[string]).Purge(ctx, key) })
+}
+
+// isTransient reports whether err is a transient error
+// such that a request may succeed when retried.
+func isTransient(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	if _, ok := kv.IsUnreachable(err); ok {
+		return true
+	}
+	if _, ok := kv.IsUnavailable(err); ok {
+		return true
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	// Some keystores return HTTP errors of the KMS as is.
+	var statusErr interface{ Status() int }
+	if errors.As(err, &statusErr) {
+		switch statusErr.Status() {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+	}
+	return false
+}
+
+// circuitBreaker tracks consecutive failures of a
+// KeyStore. A nil circuitBreaker is always closed.
+type circuitBreaker struct {
+	threshold int
+	timeout   time.Duration
+
+	lock      sync.Mutex
+	failures  int       // Number of consecutive failures
+	openUntil time.Time // Point in time until the circuit breaker remains open
+	probing   bool      // Whether a request is probing the KeyStore while half-open
+}
+
+// Allow reports whether a request may be sent to the
+// KeyStore. If ok is true, the caller must call Done
+// once the request has completed. If probe is true,
+// the request is the single request probing the
+// KeyStore while the circuit breaker is half-open.
+func (b *circuitBreaker) Allow() (ok, probe bool) {
+	if b == nil {
+		return true, false
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.failures < b.threshold {
+		return true, false // Closed
+	}
+	if time.Now().Before(b.openUntil) || b.probing {
+		return false, false // Open or half-open with a probe in-flight
+	}
+	b.probing = true
+	return true, true
+}
+
+// Done records the outcome of a request allowed by
+// Allow. The probe flag must be the one returned by
+// Allow and err is the error of the request, if any.
+//
+// Only the probe can close an open circuit breaker.
+// Requests that have been canceled by the client are
+// neither recorded as success nor as failure.
+func (b *circuitBreaker) Done(probe bool, err error) {
+	if b == nil {
+		return
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if probe {
+		b.probing = false
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		return
+	case err == nil || !isTransient(err):
+		if probe || b.failures < b.threshold {
+			b.failures = 0
+		}
+	default:
+		if b.failures++; b.failures >= b.threshold && (probe || b.failures == b.threshold) {
+			b.openUntil = time.Now().Add(b.timeout)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package edge
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/keystore/mem"
+	"github.com/minio/kes/kv"
+)
+
+func TestWithRetry(t *testing.T) {
+	ctx := context.Background()
+	store := &flakyStore{}
+	if err := store.Store.Create(ctx, "my-key", []byte("my-value")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	s := WithRetry(store, &RetryConfig{MaxAttempts: 3, MinDelay: time.Millisecond}, nil)
+
+	// Reads are retried until the max. number of attempts.
+	store.failures = 2
+	if _, err := s.Get(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to get key: %v", err)
+	}
+	store.failures = 3
+	if _, err := s.Get(ctx, "my-key"); !isTransient(err) {
+		t.Fatalf("Getting key: got '%v' - want transient error", err)
+	}
+
+	// Non-transient errors and writes are not retried.
+	store.failures, store.calls = 0, 0
+	if _, err := s.Get(ctx, "other-key"); !errors.Is(err, kes.ErrKeyNotFound) {
+		t.Fatalf("Getting non-existing key: got '%v' - want '%v'", err, kes.ErrKeyNotFound)
+	}
+	store.failures = 1
+	if err := s.Create(ctx, "other-key", []byte("my-value")); !isTransient(err) {
+		t.Fatalf("Creating key: got '%v' - want transient error", err)
+	}
+	if store.calls != 2 {
+		t.Fatalf("Invalid number of calls: got '%d' - want '%d'", store.calls, 2)
+	}
+
+	// Deletes are not retried. A retried delete that has been
+	// applied by the first attempt would fail with ErrNotExists.
+	store.failures, store.calls = 1, 0
+	if err := s.Delete(ctx, "my-key"); !isTransient(err) {
+		t.Fatalf("Deleting key: got '%v' - want transient error", err)
+	}
+	if store.calls != 1 {
+		t.Fatalf("Invalid number of calls: got '%d' - want '%d'", store.calls, 1)
+	}
+	store.failures, store.calls = 1, 0
+	if errs := s.DeleteMany(ctx, []string{"my-key", "other-key"}); !isTransient(errs[0]) {
+		t.Fatalf("Deleting key: got '%v' - want transient error", errs[0])
+	}
+	if store.calls != 1 {
+		t.Fatalf("Invalid number of calls: got '%d' - want '%d'", store.calls, 1)
+	}
+	if err := store.Store.Create(ctx, "my-key", []byte("my-value")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+
+	// Batch requests only retry the failed keys.
+	store.failures, store.calls = 1, 0
+	values, errs := s.GetMany(ctx, []string{"my-key", "other-key"})
+	if errs[0] != nil || string(values[0]) != "my-value" {
+		t.Fatalf("Failed to get key: got '%s' - '%v'", values[0], errs[0])
+	}
+	if !errors.Is(errs[1], kes.ErrKeyNotFound) {
+		t.Fatalf("Getting non-existing key: got '%v' - want '%v'", errs[1], kes.ErrKeyNotFound)
+	}
+	if store.calls != 2 {
+		t.Fatalf("Invalid number of calls: got '%d' - want '%d'", store.calls, 2)
+	}
+}
+
+func TestWithRetryCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	store := &flakyStore{failures: 2}
+	if err := store.Store.Create(ctx, "my-key", []byte("my-value")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	s := WithRetry(store, nil, &CircuitBreakerConfig{Threshold: 2, Timeout: 50 * time.Millisecond})
+
+	for i := 0; i < 2; i++ {
+		if _, err := s.Get(ctx, "my-key"); !isTransient(err) || errors.Is(err, errCircuitOpen) {
+			t.Fatalf("Getting key: got '%v' - want transient error", err)
+		}
+	}
+	if _, err := s.Get(ctx, "my-key"); !errors.Is(err, errCircuitOpen) {
+		t.Fatalf("Getting key: got '%v' - want '%v'", err, errCircuitOpen)
+	}
+	if store.calls != 2 {
+		t.Fatalf("Request passed the open circuit breaker: got '%d' calls - want '%d'", store.calls, 2)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if _, err := s.Get(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to get key once the circuit breaker timeout elapsed: %v", err)
+	}
+	if _, err := s.Get(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to get key once the circuit breaker has closed: %v", err)
+	}
+}
+
+func TestCircuitBreakerProbe(t *testing.T) {
+	b := &circuitBreaker{threshold: 1, timeout: 10 * time.Millisecond}
+
+	slow, _ := b.Allow() // Admitted while closed, completes later
+	if !slow {
+		t.Fatal("Closed circuit breaker rejected request")
+	}
+	if ok, probe := b.Allow(); !ok || probe {
+		t.Fatalf("Closed circuit breaker: got '%v' and probe '%v' - want 'true' and probe 'false'", ok, probe)
+	}
+	b.Done(false, errFlaky)
+	if ok, _ := b.Allow(); ok {
+		t.Fatal("Open circuit breaker allowed request")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	ok, probe := b.Allow()
+	if !ok || !probe {
+		t.Fatalf("Half-open circuit breaker: got '%v' and probe '%v' - want 'true' and probe 'true'", ok, probe)
+	}
+
+	// Requests admitted before the circuit breaker has opened
+	// must neither close it nor allow a second probe.
+	b.Done(false, nil)
+	if ok, _ := b.Allow(); ok {
+		t.Fatal("Half-open circuit breaker allowed a second probe")
+	}
+
+	// A canceled probe does not close the circuit breaker.
+	b.Done(true, context.Canceled)
+	if ok, probe = b.Allow(); !ok || !probe {
+		t.Fatalf("Half-open circuit breaker: got '%v' and probe '%v' - want 'true' and probe 'true'", ok, probe)
+	}
+	if ok, _ := b.Allow(); ok {
+		t.Fatal("Half-open circuit breaker allowed a second probe")
+	}
+
+	b.Done(true, nil)
+	if ok, probe = b.Allow(); !ok || probe {
+		t.Fatalf("Closed circuit breaker: got '%v' and probe '%v' - want 'true' and probe 'false'", ok, probe)
+	}
+}
+
+// flakyStore is a kv.Store that fails the next
+// requests with a transient error.
+type flakyStore struct {
+	mem.Store
+
+	failures int // Number of requests to fail
+	calls    int // Number of requests
+}
+
+var errFlaky = &kv.Unreachable{Err: errors.New("connection timed out")}
+
+func (s *flakyStore) fail() bool {
+	s.calls++
+	if s.failures > 0 {
+		s.failures--
+		return true
+	}
+	return false
+}
+
+func (s *flakyStore) Create(ctx context.Context, name string, value []byte) error {
+	if s.fail() {
+		return errFlaky
+	}
+	return s.Store.Create(ctx, name, value)
+}
+
+func (s *flakyStore) Get(ctx context.Context, name string) ([]byte, error) {
+	if s.fail() {
+		return nil, errFlaky
+	}
+	return s.Store.Get(ctx, name)
+}
+
+func (s *flakyStore) Delete(ctx context.Context, name string) error {
+	if s.fail() {
+		s.Store.Delete(ctx, name) // Fail after the store has applied the request
+		return errFlaky
+	}
+	return s.Store.Delete(ctx, name)
+}
+
+func (s *flakyStore) DeleteMany(ctx context.Context, names []string) []error {
+	errs := make([]error, len(names))
+	fail := s.fail()
+	for i, name := range names {
+		errs[i] = s.Store.Delete(ctx, name)
+		if fail && i == 0 {
+			errs[i] = errFlaky
+		}
+	}
+	return errs
+}
+
+func (s *flakyStore) GetMany(ctx context.Context, names []string) ([][]byte, []error) {
+	values := make([][]byte, len(names))
+	errs := make([]error, len(names))
+	fail := s.fail()
+	for i, name := range names {
+		if fail && i == 0 {
+			errs[i] = errFlaky
+			continue
+		}
+		values[i], errs[i] = s.Store.Get(ctx, name)
+	}
+	return values, errs
+}
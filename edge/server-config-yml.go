@@ -5,13 +5,23 @@
 package edge
 
 import (
+	"crypto/tls"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"math"
+	"net/url"
 	"os"
+	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
+	"aead.dev/mem"
 	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/fips"
+	"github.com/minio/kes/internal/key"
+	"github.com/minio/kes/internal/sys"
 	"gopkg.in/yaml.v3"
 )
 
@@ -20,29 +30,98 @@ type yml struct {
 
 	Addr env[string] `yaml:"address"`
 
+	Listen []struct {
+		Addr env[string] `yaml:"address"`
+		TLS  *struct {
+			PrivateKey   env[string]   `yaml:"key"`
+			Certificate  env[string]   `yaml:"cert"`
+			CAPath       env[string]   `yaml:"ca"`
+			Password     env[string]   `yaml:"password"`
+			MinVersion   env[string]   `yaml:"min_version"`
+			CipherSuites []env[string] `yaml:"cipher_suites"`
+			ClientCAs    []env[string] `yaml:"client_cas"`
+		} `yaml:"tls"`
+	} `yaml:"listen"`
+
+	ProbeAddr env[string] `yaml:"probe_address"`
+
 	Admin struct {
 		Identity env[kes.Identity] `yaml:"identity"`
 	} `yaml:"admin"`
 
+	ReadOnly env[bool] `yaml:"read_only"`
+
 	TLS struct {
 		PrivateKey  env[string] `yaml:"key"`
 		Certificate env[string] `yaml:"cert"`
 		CAPath      env[string] `yaml:"ca"`
 		Password    env[string] `yaml:"password"`
 
+		MinVersion   env[string]   `yaml:"min_version"`
+		CipherSuites []env[string] `yaml:"cipher_suites"`
+		ClientCAs    []env[string] `yaml:"client_cas"`
+
+		Reload env[time.Duration] `yaml:"reload"`
+
+		CertManager struct {
+			Secret    env[string]        `yaml:"secret"`
+			Namespace env[string]        `yaml:"namespace"`
+			Reload    env[time.Duration] `yaml:"reload"`
+		} `yaml:"certmanager"`
+
+		ACME struct {
+			Domains   []env[string] `yaml:"domains"`
+			Email     env[string]   `yaml:"email"`
+			Directory env[string]   `yaml:"directory"`
+			Cache     env[string]   `yaml:"cache"`
+			Challenge env[string]   `yaml:"challenge"`
+			HTTPAddr  env[string]   `yaml:"http_address"`
+			AcceptTOS env[bool]     `yaml:"accept_tos"`
+		} `yaml:"acme"`
+
+		Revocation struct {
+			CRLs          []env[string]      `yaml:"crl"`
+			OCSP          env[bool]          `yaml:"ocsp"`
+			OCSPResponder env[string]        `yaml:"ocsp_responder"`
+			Cache         env[time.Duration] `yaml:"cache"`
+			FailOpen      env[bool]          `yaml:"fail_open"`
+			CRLSoftFail   env[bool]          `yaml:"crl_soft_fail"`
+		} `yaml:"revocation"`
+
 		Proxy struct {
 			Identities []env[kes.Identity] `yaml:"identities"`
 			Header     struct {
 				ClientCert env[string] `yaml:"cert"`
 			} `yaml:"header"`
 		} `yaml:"proxy"`
+
+		Client struct {
+			APIKeys env[bool] `yaml:"api_key"`
+
+			OIDC struct {
+				Issuer   env[string] `yaml:"issuer"`
+				Audience env[string] `yaml:"audience"`
+				JWKSURL  env[string] `yaml:"jwks_url"`
+				Claims   struct {
+					Identity env[string] `yaml:"identity"`
+					Policy   env[string] `yaml:"policy"`
+				} `yaml:"claims"`
+			} `yaml:"oidc"`
+
+			SPIFFE struct {
+				TrustDomains []env[string] `yaml:"trust_domains"`
+			} `yaml:"spiffe"`
+
+			Require struct {
+				Version    env[string]   `yaml:"version"`
+				RSAKeySize env[int]      `yaml:"rsa_key_size"`
+				Curves     []env[string] `yaml:"curves"`
+				Mode       env[string]   `yaml:"mode"`
+			} `yaml:"require"`
+		} `yaml:"client"`
 	} `yaml:"tls"`
 
-	Policies map[string]struct {
-		Allow      []string            `yaml:"allow"`
-		Deny       []string            `yaml:"deny"`
-		Identities []env[kes.Identity] `yaml:"identities"`
-	} `yaml:"policy"`
+	Policies map[string]ymlPolicy `yaml:"policy"`
 
 	Cache struct {
 		Expiry struct {
@@ -50,102 +129,140 @@ type yml struct {
 			Unused  env[time.Duration] `yaml:"unused"`
 			Offline env[time.Duration] `yaml:"offline"`
 		} `yaml:"expiry"`
+
+		KeyStore *struct {
+			Size           env[int]           `yaml:"size"`
+			Expiry         env[time.Duration] `yaml:"expiry"`
+			NegativeExpiry env[time.Duration] `yaml:"negative_expiry"`
+		} `yaml:"keystore"`
 	} `yaml:"cache"`
 
+	Expiry struct {
+		Delete env[time.Duration] `yaml:"delete"`
+	} `yaml:"expiry"`
+
 	API struct {
 		Paths map[string]struct {
-			InsecureSkipAuth env[bool]          `yaml:"skip_auth"`
+			InsecureSkipAuth *env[bool]         `yaml:"skip_auth"`
 			Timeout          env[time.Duration] `yaml:"timeout"`
 		} `yaml:",inline"`
 	} `yaml:"api"`
 
+	Crypto struct {
+		AEAD struct {
+			Workers env[int] `yaml:"workers"`
+			Queue   env[int] `yaml:"queue"`
+		} `yaml:"aead"`
+		Unwrap struct {
+			Workers env[int] `yaml:"workers"`
+			Queue   env[int] `yaml:"queue"`
+		} `yaml:"unwrap"`
+	} `yaml:"crypto"`
+
+	Receipts struct {
+		PrivateKey env[string] `yaml:"key"`
+		Password   env[string] `yaml:"password"`
+	} `yaml:"receipts"`
+
+	RateLimit struct {
+		Rate       env[float64]            `yaml:"rate"`
+		Burst      env[int]                `yaml:"burst"`
+		Identities map[string]ymlRateLimit `yaml:"identities"`
+		Policies   map[string]ymlRateLimit `yaml:"policies"`
+		Enclaves   map[string]ymlRateLimit `yaml:"enclaves"`
+	} `yaml:"rate_limit"`
+
+	Limits struct {
+		MaxBody      env[string]        `yaml:"max_body_size"`
+		MaxRequests  env[int]           `yaml:"max_requests"`
+		Queue        env[int]           `yaml:"queue"`
+		QueueTimeout env[time.Duration] `yaml:"queue_timeout"`
+	} `yaml:"limits"`
+
+	Shutdown struct {
+		Delay   env[time.Duration] `yaml:"delay"`
+		Timeout env[time.Duration] `yaml:"timeout"`
+	} `yaml:"shutdown"`
+
 	Log struct {
 		Error env[string] `yaml:"error"`
 		Audit env[string] `yaml:"audit"`
+		Stats env[string] `yaml:"stats"`
 	} `yaml:"log"`
 
 	Keys []struct {
 		Name env[string] `yaml:"name"`
 	} `yaml:"keys"`
 
-	KeyStore struct {
-		FS *struct {
-			Path env[string] `yaml:"path"`
-		}
-		KES *struct {
-			Endpoint []env[string] `yaml:"endpoint"`
-			Enclave  env[string]   `yaml:"enclave"`
-			TLS      struct {
-				Certificate env[string] `yaml:"cert"`
-				PrivateKey  env[string] `yaml:"key"`
-				CAPath      env[string] `yaml:"ca"`
-			} `yaml:"tls"`
-		} `yaml:"kes"`
+	KeyPools []struct {
+		Prefix    env[string] `yaml:"prefix"`
+		Size      env[int]    `yaml:"size"`
+		Algorithm env[string] `yaml:"algorithm"`
+	} `yaml:"key_pools"`
 
-		Vault *struct {
-			Endpoint   env[string] `yaml:"endpoint"`
-			Engine     env[string] `yaml:"engine"`
-			APIVersion env[string] `yaml:"version"`
-			Namespace  env[string] `yaml:"namespace"`
-			Prefix     env[string] `yaml:"prefix"`
-
-			AppRole *struct {
-				Engine env[string] `yaml:"engine"`
-				ID     env[string] `yaml:"id"`
-				Secret env[string] `yaml:"secret"`
-			} `yaml:"approle"`
-
-			Kubernetes *struct {
-				Engine env[string] `yaml:"engine"`
-				Role   env[string] `yaml:"role"`
-				JWT    env[string] `yaml:"jwt"` // Can be either a JWT or a path to a file containing a JWT
-			} `yaml:"kubernetes"`
+	PolicyHooks []struct {
+		Name    env[string]        `yaml:"name"`
+		Module  env[string]        `yaml:"module"`
+		Timeout env[time.Duration] `yaml:"timeout"`
+	} `yaml:"policy_hooks"`
 
-			TLS struct {
-				PrivateKey  env[string] `yaml:"key"`
-				Certificate env[string] `yaml:"cert"`
-				CAPath      env[string] `yaml:"ca"`
-			} `yaml:"tls"`
+	KeyStore ymlKeyStore `yaml:"keystore"`
 
-			Status struct {
-				Ping env[time.Duration] `yaml:"ping"`
-			} `yaml:"status"`
-		} `yaml:"vault"`
+	Enclaves map[string]struct {
+		KeyStore ymlKeyStore          `yaml:"keystore"`
+		Policies map[string]ymlPolicy `yaml:"policy"`
+	} `yaml:"enclaves"`
+}
 
-		Fortanix *struct {
-			SDKMS *struct {
-				Endpoint env[string] `yaml:"endpoint"`
-				GroupID  env[string] `yaml:"group_id"`
+// ymlPolicy is the YAML representation of a policy.
+type ymlPolicy struct {
+	Allow      []string            `yaml:"allow"`
+	Deny       []string            `yaml:"deny"`
+	Identities []env[kes.Identity] `yaml:"identities"`
+}
 
-				Login struct {
-					APIKey env[string] `yaml:"key"`
-				} `yaml:"credentials"`
+// ymlKeyStore is the YAML representation of a keystore config.
+type ymlKeyStore struct {
+	Connect struct {
+		Lazy  env[bool]          `yaml:"lazy"`
+		Retry env[time.Duration] `yaml:"retry"`
+	} `yaml:"connect"`
+
+	Compression env[string] `yaml:"compression"`
+
+	Retry *struct {
+		MaxAttempts env[int]           `yaml:"max_attempts"`
+		Delay       env[time.Duration] `yaml:"delay"`
+		MaxDelay    env[time.Duration] `yaml:"max_delay"`
+	} `yaml:"retry"`
+
+	CircuitBreaker *struct {
+		Failures env[int]           `yaml:"failures"`
+		Timeout  env[time.Duration] `yaml:"timeout"`
+	} `yaml:"circuit_breaker"`
+
+	Replicas []ymlKeyStore `yaml:"replicas"`
+	Mirror   *ymlKeyStore  `yaml:"mirror"`
 
-				TLS struct {
-					CAPath env[string] `yaml:"ca"`
-				} `yaml:"tls"`
-			} `yaml:"sdkms"`
-		} `yaml:"fortanix"`
+	FS *struct {
+		Path env[string] `yaml:"path"`
 
-		Gemalto *struct {
-			KeySecure *struct {
+		KMS *struct {
+			AWS *struct {
 				Endpoint env[string] `yaml:"endpoint"`
+				Region   env[string] `yaml:"region"`
+				KeyID    env[string] `yaml:"key"`
 
 				Login struct {
-					Token  env[string] `yaml:"token"`
-					Domain env[string] `yaml:"domain"`
+					AccessKey    env[string] `yaml:"accesskey"`
+					SecretKey    env[string] `yaml:"secretkey"`
+					SessionToken env[string] `yaml:"token"`
 				} `yaml:"credentials"`
+			} `yaml:"aws"`
 
-				TLS struct {
-					CAPath env[string] `yaml:"ca"`
-				} `yaml:"tls"`
-			} `yaml:"keysecure"`
-		} `yaml:"gemalto"`
-
-		GCP *struct {
-			SecretManager *struct {
-				ProjectID   env[string]   `yaml:"project_id"`
+			GCP *struct {
 				Endpoint    env[string]   `yaml:"endpoint"`
+				Key         env[string]   `yaml:"key"`
 				Scopes      []env[string] `yaml:"scopes"`
 				Credentials struct {
 					Client   env[string] `yaml:"client_email"`
@@ -153,37 +270,338 @@ type yml struct {
 					KeyID    env[string] `yaml:"private_key_id"`
 					Key      env[string] `yaml:"private_key"`
 				} `yaml:"credentials"`
-			} `yaml:"secretmanager"`
-		} `yaml:"gcp"`
+			} `yaml:"gcp"`
+		} `yaml:"kms"`
+	}
+	KES *struct {
+		Endpoint []env[string] `yaml:"endpoint"`
+		Enclave  env[string]   `yaml:"enclave"`
+		TLS      struct {
+			Certificate env[string] `yaml:"cert"`
+			PrivateKey  env[string] `yaml:"key"`
+			CAPath      env[string] `yaml:"ca"`
+		} `yaml:"tls"`
+	} `yaml:"kes"`
 
-		AWS *struct {
-			SecretsManager *struct {
-				Endpoint env[string] `yaml:"endpoint"`
-				Region   env[string] `yaml:"region"`
-				KmsKey   env[string] ` yaml:"kmskey"`
+	Etcd *struct {
+		Endpoint    []env[string]      `yaml:"endpoint"`
+		Prefix      env[string]        `yaml:"prefix"`
+		LeaseTTL    env[time.Duration] `yaml:"lease_ttl"`
+		Credentials *struct {
+			Username env[string] `yaml:"username"`
+			Password env[string] `yaml:"password"`
+		} `yaml:"credentials"`
+		TLS *struct {
+			Certificate env[string] `yaml:"cert"`
+			PrivateKey  env[string] `yaml:"key"`
+			CAPath      env[string] `yaml:"ca"`
+		} `yaml:"tls"`
+	} `yaml:"etcd"`
 
-				Login struct {
-					AccessKey    env[string] `yaml:"accesskey"`
-					SecretKey    env[string] `yaml:"secretkey"`
-					SessionToken env[string] `yaml:"token"`
-				} `yaml:"credentials"`
-			} `yaml:"secretsmanager"`
+	SQL *struct {
+		Driver        env[string] `yaml:"driver"`
+		DSN           env[string] `yaml:"dsn"`
+		Table         env[string] `yaml:"table"`
+		EncryptionKey env[string] `yaml:"encryption_key"`
+		Pool          *struct {
+			MaxOpenConns    env[int]           `yaml:"max_open_conns"`
+			MaxIdleConns    env[int]           `yaml:"max_idle_conns"`
+			ConnMaxLifetime env[time.Duration] `yaml:"conn_max_lifetime"`
+		} `yaml:"pool"`
+	} `yaml:"sql"`
+
+	Vault *struct {
+		Endpoint   env[string] `yaml:"endpoint"`
+		Engine     env[string] `yaml:"engine"`
+		APIVersion env[string] `yaml:"version"`
+		Namespace  env[string] `yaml:"namespace"`
+		Prefix     env[string] `yaml:"prefix"`
+
+		SoftDelete     env[bool]              `yaml:"soft_delete"`
+		CustomMetadata map[string]env[string] `yaml:"custom_metadata"`
+
+		AppRole *struct {
+			Engine env[string] `yaml:"engine"`
+			ID     env[string] `yaml:"id"`
+			Secret env[string] `yaml:"secret"`
+		} `yaml:"approle"`
+
+		Kubernetes *struct {
+			Engine  env[string] `yaml:"engine"`
+			Role    env[string] `yaml:"role"`
+			JWT     env[string] `yaml:"jwt"`      // Can be either a JWT or a path to a file containing a JWT
+			JWTFile env[string] `yaml:"jwt_file"` // Path to a file containing a JWT that is re-read on every login
+		} `yaml:"kubernetes"`
+
+		AWS *struct {
+			Engine       env[string] `yaml:"engine"`
+			Role         env[string] `yaml:"role"`
+			ServerID     env[string] `yaml:"server_id"`
+			Region       env[string] `yaml:"region"`
+			AccessKey    env[string] `yaml:"access_key"`
+			SecretKey    env[string] `yaml:"secret_key"`
+			SessionToken env[string] `yaml:"session_token"`
 		} `yaml:"aws"`
 
-		Azure *struct {
-			KeyVault *struct {
-				Endpoint    env[string] `yaml:"endpoint"`
-				Credentials *struct {
-					TenantID env[string] `yaml:"tenant_id"`
-					ClientID env[string] `yaml:"client_id"`
-					Secret   env[string] `yaml:"client_secret"`
-				} `yaml:"credentials"`
-				ManagedIdentity *struct {
-					ClientID env[string] `yaml:"client_id"`
-				} `yaml:"managed_identity"`
-			} `yaml:"keyvault"`
-		} `yaml:"azure"`
-	} `yaml:"keystore"`
+		Cert *struct {
+			Engine env[string] `yaml:"engine"`
+			Name   env[string] `yaml:"name"`
+		} `yaml:"cert"`
+
+		TLS struct {
+			PrivateKey  env[string] `yaml:"key"`
+			Certificate env[string] `yaml:"cert"`
+			CAPath      env[string] `yaml:"ca"`
+			ServerName  env[string] `yaml:"server_name"`
+		} `yaml:"tls"`
+
+		Status struct {
+			Ping env[time.Duration] `yaml:"ping"`
+		} `yaml:"status"`
+
+		Watch struct {
+			Interval env[time.Duration] `yaml:"interval"`
+		} `yaml:"watch"`
+	} `yaml:"vault"`
+
+	Fortanix *struct {
+		SDKMS *struct {
+			Endpoint          env[string]   `yaml:"endpoint"`
+			FailoverEndpoints []env[string] `yaml:"failover_endpoints"`
+			GroupID           env[string]   `yaml:"group_id"`
+
+			Login struct {
+				APIKey     env[string] `yaml:"key"`
+				APIKeyFile env[string] `yaml:"key_file"`
+			} `yaml:"credentials"`
+
+			TLS struct {
+				PrivateKey  env[string] `yaml:"key"`
+				Certificate env[string] `yaml:"cert"`
+				CAPath      env[string] `yaml:"ca"`
+				ServerName  env[string] `yaml:"server_name"`
+			} `yaml:"tls"`
+		} `yaml:"sdkms"`
+	} `yaml:"fortanix"`
+
+	Gemalto *struct {
+		KeySecure *struct {
+			Endpoint env[string] `yaml:"endpoint"`
+
+			Login struct {
+				Token  env[string] `yaml:"token"`
+				Domain env[string] `yaml:"domain"`
+			} `yaml:"credentials"`
+
+			TLS struct {
+				PrivateKey  env[string] `yaml:"key"`
+				Certificate env[string] `yaml:"cert"`
+				CAPath      env[string] `yaml:"ca"`
+				ServerName  env[string] `yaml:"server_name"`
+			} `yaml:"tls"`
+		} `yaml:"keysecure"`
+	} `yaml:"gemalto"`
+
+	Conjur *struct {
+		Endpoint env[string] `yaml:"endpoint"`
+		Account  env[string] `yaml:"account"`
+		Policy   env[string] `yaml:"policy"`
+
+		Login struct {
+			Login  env[string] `yaml:"login"`
+			APIKey env[string] `yaml:"api_key"`
+		} `yaml:"credentials"`
+
+		TLS struct {
+			PrivateKey  env[string] `yaml:"key"`
+			Certificate env[string] `yaml:"cert"`
+			CAPath      env[string] `yaml:"ca"`
+			ServerName  env[string] `yaml:"server_name"`
+		} `yaml:"tls"`
+	} `yaml:"conjur"`
+
+	OnePassword *struct {
+		Connect *struct {
+			Endpoint env[string] `yaml:"endpoint"`
+			Token    env[string] `yaml:"token"`
+			VaultID  env[string] `yaml:"vault_id"`
+			Tag      env[string] `yaml:"tag"`
+
+			TLS struct {
+				CAPath env[string] `yaml:"ca"`
+			} `yaml:"tls"`
+		} `yaml:"connect"`
+	} `yaml:"onepassword"`
+
+	REST *struct {
+		Endpoint env[string]            `yaml:"endpoint"`
+		Header   map[string]env[string] `yaml:"header"`
+
+		Create struct {
+			Method env[string] `yaml:"method"`
+			Path   env[string] `yaml:"path"`
+			Body   env[string] `yaml:"body"`
+		} `yaml:"create"`
+
+		Get struct {
+			Method env[string] `yaml:"method"`
+			Path   env[string] `yaml:"path"`
+			Value  env[string] `yaml:"value"`
+		} `yaml:"get"`
+
+		Delete struct {
+			Method env[string] `yaml:"method"`
+			Path   env[string] `yaml:"path"`
+		} `yaml:"delete"`
+
+		List struct {
+			Method    env[string] `yaml:"method"`
+			Path      env[string] `yaml:"path"`
+			Names     env[string] `yaml:"names"`
+			NameField env[string] `yaml:"name_field"`
+		} `yaml:"list"`
+
+		TLS struct {
+			PrivateKey  env[string] `yaml:"key"`
+			Certificate env[string] `yaml:"cert"`
+			CAPath      env[string] `yaml:"ca"`
+		} `yaml:"tls"`
+	} `yaml:"rest"`
+
+	IBM *struct {
+		KeyProtect *struct {
+			Endpoint    env[string] `yaml:"endpoint"`
+			InstanceID  env[string] `yaml:"instance_id"`
+			IAMEndpoint env[string] `yaml:"iam_endpoint"`
+
+			Login struct {
+				APIKey    env[string] `yaml:"api_key"`
+				ServiceID env[string] `yaml:"service_id"`
+			} `yaml:"credentials"`
+
+			TLS struct {
+				CAPath env[string] `yaml:"ca"`
+			} `yaml:"tls"`
+		} `yaml:"keyprotect"`
+	} `yaml:"ibm"`
+
+	GCP *struct {
+		SecretManager *struct {
+			ProjectID   env[string]   `yaml:"project_id"`
+			Endpoint    env[string]   `yaml:"endpoint"`
+			Scopes      []env[string] `yaml:"scopes"`
+			Credentials struct {
+				Client   env[string] `yaml:"client_email"`
+				ClientID env[string] `yaml:"client_id"`
+				KeyID    env[string] `yaml:"private_key_id"`
+				Key      env[string] `yaml:"private_key"`
+			} `yaml:"credentials"`
+		} `yaml:"secretmanager"`
+
+		CloudKMS *struct {
+			Key         env[string]   `yaml:"key"`
+			Path        env[string]   `yaml:"path"`
+			Endpoint    env[string]   `yaml:"endpoint"`
+			Scopes      []env[string] `yaml:"scopes"`
+			Credentials struct {
+				Client   env[string] `yaml:"client_email"`
+				ClientID env[string] `yaml:"client_id"`
+				KeyID    env[string] `yaml:"private_key_id"`
+				Key      env[string] `yaml:"private_key"`
+			} `yaml:"credentials"`
+		} `yaml:"cloudkms"`
+	} `yaml:"gcp"`
+
+	AWS *struct {
+		SecretsManager *struct {
+			Endpoint env[string] `yaml:"endpoint"`
+			Region   env[string] `yaml:"region"`
+			KmsKey   env[string] ` yaml:"kmskey"`
+
+			Login struct {
+				AccessKey    env[string] `yaml:"accesskey"`
+				SecretKey    env[string] `yaml:"secretkey"`
+				SessionToken env[string] `yaml:"token"`
+			} `yaml:"credentials"`
+		} `yaml:"secretsmanager"`
+
+		CloudHSM *struct {
+			Module      env[string] `yaml:"module"`
+			WrappingKey env[string] `yaml:"wrapping_key"`
+			Application env[string] `yaml:"application"`
+
+			Login struct {
+				Username env[string] `yaml:"username"`
+				Password env[string] `yaml:"password"`
+			} `yaml:"credentials"`
+		} `yaml:"cloudhsm"`
+	} `yaml:"aws"`
+
+	Azure *struct {
+		KeyVault *struct {
+			Endpoint    env[string] `yaml:"endpoint"`
+			ManagedHSM  env[bool]   `yaml:"managed_hsm"`
+			WrappingKey env[string] `yaml:"wrapping_key"`
+			Credentials *struct {
+				TenantID env[string] `yaml:"tenant_id"`
+				ClientID env[string] `yaml:"client_id"`
+				Secret   env[string] `yaml:"client_secret"`
+			} `yaml:"credentials"`
+			ManagedIdentity *struct {
+				ClientID env[string] `yaml:"client_id"`
+			} `yaml:"managed_identity"`
+		} `yaml:"keyvault"`
+	} `yaml:"azure"`
+
+	Alibaba *struct {
+		SecretsManager *struct {
+			Region   env[string] `yaml:"region"`
+			Endpoint env[string] `yaml:"endpoint"`
+			KMSKeyID env[string] `yaml:"kmskey"`
+
+			Login *struct {
+				AccessKeyID     env[string] `yaml:"access_key_id"`
+				AccessKeySecret env[string] `yaml:"access_key_secret"`
+			} `yaml:"credentials"`
+
+			ECSRAMRole env[string] `yaml:"ecs_ram_role"`
+
+			RAMRole *struct {
+				ARN         env[string] `yaml:"arn"`
+				SessionName env[string] `yaml:"session_name"`
+			} `yaml:"ram_role"`
+		} `yaml:"secretsmanager"`
+	} `yaml:"alibaba"`
+
+	OCI *struct {
+		Vault *struct {
+			Region         env[string]        `yaml:"region"`
+			CompartmentID  env[string]        `yaml:"compartment_id"`
+			VaultID        env[string]        `yaml:"vault_id"`
+			KeyID          env[string]        `yaml:"key_id"`
+			DeletionPeriod env[time.Duration] `yaml:"deletion_period"`
+			Credentials    *struct {
+				TenancyID   env[string] `yaml:"tenancy_id"`
+				UserID      env[string] `yaml:"user_id"`
+				Fingerprint env[string] `yaml:"fingerprint"`
+				PrivateKey  env[string] `yaml:"private_key"`
+			} `yaml:"credentials"`
+			ConfigFile *struct {
+				Path    env[string] `yaml:"path"`
+				Profile env[string] `yaml:"profile"`
+			} `yaml:"config_file"`
+			InstancePrincipal env[bool] `yaml:"instance_principal"`
+		} `yaml:"vault"`
+	} `yaml:"oci"`
+
+	PKCS11 *struct {
+		Module      env[string] `yaml:"module"`
+		Slot        env[uint]   `yaml:"slot"`
+		TokenLabel  env[string] `yaml:"token_label"`
+		PIN         env[string] `yaml:"pin"`
+		WrappingKey env[string] `yaml:"wrapping_key"`
+		Application env[string] `yaml:"application"`
+	} `yaml:"pkcs11"`
 }
 
 func findVersion(root *yaml.Node) (string, error) {
@@ -217,17 +635,103 @@ func findVersion(root *yaml.Node) (string, error) {
 }
 
 func ymlToServerConfig(y *yml) (*ServerConfig, error) {
-	if y.Version != "" && y.Version != "v1" {
+	if y.Version != "" && y.Version != versionCurrent {
 		return nil, fmt.Errorf("edge: invalid version '%s'", y.Version)
 	}
 	if y.Admin.Identity.Value.IsUnknown() {
 		return nil, errors.New("edge: invalid admin identity: no admin identity")
 	}
-	if y.TLS.PrivateKey.Value == "" {
-		return nil, errors.New("edge: invalid tls config: no private key")
+	acme, err := ymlToACMEConfig(y)
+	if err != nil {
+		return nil, err
+	}
+	revocation, err := ymlToRevocationConfig(y)
+	if err != nil {
+		return nil, err
+	}
+	if acme != nil {
+		if y.TLS.PrivateKey.Value != "" || y.TLS.Certificate.Value != "" {
+			return nil, errors.New("edge: invalid tls config: private key and certificate are obtained via ACME")
+		}
+		if y.TLS.CertManager.Secret.Value != "" {
+			return nil, errors.New("edge: invalid tls config: 'acme' and 'certmanager' must not be specified both")
+		}
+	}
+	if y.TLS.CertManager.Secret.Value == "" {
+		if y.TLS.CertManager.Namespace.Value != "" {
+			return nil, errors.New("edge: invalid tls config: no cert-manager secret")
+		}
+		if y.TLS.PrivateKey.Value == "" && acme == nil {
+			return nil, errors.New("edge: invalid tls config: no private key")
+		}
+		if y.TLS.Certificate.Value == "" && acme == nil {
+			return nil, errors.New("edge: invalid tls config: no certificate")
+		}
+	} else {
+		if y.TLS.PrivateKey.Value != "" || y.TLS.Certificate.Value != "" {
+			return nil, errors.New("edge: invalid tls config: private key and certificate are loaded from cert-manager secret")
+		}
+		if y.TLS.CertManager.Reload.Value < 0 {
+			return nil, fmt.Errorf("edge: invalid tls config: invalid cert-manager reload interval '%v'", y.TLS.CertManager.Reload.Value)
+		}
+	}
+
+	if y.TLS.Reload.Value < 0 {
+		return nil, fmt.Errorf("edge: invalid tls config: invalid reload interval '%v'", y.TLS.Reload.Value)
+	}
+	minVersion, cipherSuites, err := ymlToTLSPolicy(y.TLS.MinVersion, y.TLS.CipherSuites)
+	if err != nil {
+		return nil, fmt.Errorf("edge: invalid tls config: %v", err)
+	}
+	clientCAs, err := ymlToClientCAs(y.TLS.ClientCAs)
+	if err != nil {
+		return nil, fmt.Errorf("edge: invalid tls config: %v", err)
+	}
+
+	listeners, err := ymlToListeners(y)
+	if err != nil {
+		return nil, err
+	}
+
+	if oidc := y.TLS.Client.OIDC; oidc.Issuer.Value == "" {
+		if oidc.Audience.Value != "" || oidc.JWKSURL.Value != "" || oidc.Claims.Identity.Value != "" || oidc.Claims.Policy.Value != "" {
+			return nil, errors.New("edge: invalid tls config: no OIDC issuer")
+		}
+	} else if oidc.Audience.Value == "" {
+		return nil, errors.New("edge: invalid tls config: no OIDC audience")
+	}
+
+	for _, domain := range y.TLS.Client.SPIFFE.TrustDomains {
+		if d := strings.TrimPrefix(domain.Value, "spiffe://"); d == "" || strings.ContainsAny(d, "/:") {
+			return nil, fmt.Errorf("edge: invalid tls config: invalid SPIFFE trust domain '%s'", domain.Value)
+		}
+	}
+
+	if y.KeyStore.Connect.Retry.Value < 0 {
+		return nil, fmt.Errorf("edge: invalid keystore config: invalid connect retry '%v'", y.KeyStore.Connect.Retry.Value)
+	}
+	retry, breaker, err := ymlToRetryConfig(&y.KeyStore)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := parseTLSVersion(y.TLS.Client.Require.Version.Value); err != nil {
+		return nil, fmt.Errorf("edge: invalid tls config: %v", err)
+	}
+	if y.TLS.Client.Require.RSAKeySize.Value < 0 {
+		return nil, fmt.Errorf("edge: invalid tls config: invalid RSA key size '%d'", y.TLS.Client.Require.RSAKeySize.Value)
+	}
+	for _, curve := range y.TLS.Client.Require.Curves {
+		switch strings.ToUpper(curve.Value) {
+		case "P-256", "P-384", "P-521", "ED25519":
+		default:
+			return nil, fmt.Errorf("edge: invalid tls config: unsupported curve '%s'", curve.Value)
+		}
 	}
-	if y.TLS.Certificate.Value == "" {
-		return nil, errors.New("edge: invalid tls config: no certificate")
+	switch strings.ToLower(y.TLS.Client.Require.Mode.Value) {
+	case "", "enforce", "report":
+	default:
+		return nil, fmt.Errorf("edge: invalid tls config: invalid mode '%s': must be either 'enforce' or 'report'", y.TLS.Client.Require.Mode.Value)
 	}
 
 	for _, proxy := range y.TLS.Proxy.Identities {
@@ -236,17 +740,8 @@ func ymlToServerConfig(y *yml) (*ServerConfig, error) {
 		}
 	}
 
-	for name, policy := range y.Policies {
-		for _, identity := range policy.Identities {
-			if identity.Value == y.Admin.Identity.Value {
-				return nil, fmt.Errorf("edge: invalid policy '%s': identity '%s' is already admin", name, identity.Value)
-			}
-			for _, proxy := range y.TLS.Proxy.Identities {
-				if identity.Value == proxy.Value {
-					return nil, fmt.Errorf("edge: invalid policy '%s': identity '%s' is already a TLS proxy", name, identity.Value)
-				}
-			}
-		}
+	if err := verifyYMLPolicies(y, y.Policies); err != nil {
+		return nil, err
 	}
 
 	if y.Cache.Expiry.Any.Value < 0 {
@@ -258,6 +753,21 @@ func ymlToServerConfig(y *yml) (*ServerConfig, error) {
 	if y.Cache.Expiry.Offline.Value < 0 {
 		return nil, fmt.Errorf("edge: invalid offline cache expiry '%v'", y.Cache.Expiry.Offline.Value)
 	}
+	if y.Cache.KeyStore != nil {
+		if y.Cache.KeyStore.Size.Value < 0 {
+			return nil, fmt.Errorf("edge: invalid keystore cache size '%d'", y.Cache.KeyStore.Size.Value)
+		}
+		if y.Cache.KeyStore.Expiry.Value < 0 {
+			return nil, fmt.Errorf("edge: invalid keystore cache expiry '%v'", y.Cache.KeyStore.Expiry.Value)
+		}
+		if y.Cache.KeyStore.NegativeExpiry.Value < 0 {
+			return nil, fmt.Errorf("edge: invalid keystore cache negative expiry '%v'", y.Cache.KeyStore.NegativeExpiry.Value)
+		}
+	}
+
+	if y.Expiry.Delete.Value < 0 {
+		return nil, fmt.Errorf("edge: invalid key expiry delete interval '%v'", y.Expiry.Delete.Value)
+	}
 
 	if v := strings.ToLower(strings.TrimSpace(y.Log.Error.Value)); v != "on" && v != "off" && v != "" {
 		return nil, fmt.Errorf("edge: invalid error log config '%v'", y.Log.Error.Value)
@@ -272,6 +782,34 @@ func ymlToServerConfig(y *yml) (*ServerConfig, error) {
 		}
 	}
 
+	if y.Crypto.AEAD.Workers.Value < 0 {
+		return nil, fmt.Errorf("edge: invalid number of AEAD workers '%d'", y.Crypto.AEAD.Workers.Value)
+	}
+	if y.Crypto.AEAD.Queue.Value < 0 {
+		return nil, fmt.Errorf("edge: invalid AEAD queue size '%d'", y.Crypto.AEAD.Queue.Value)
+	}
+	if y.Crypto.Unwrap.Workers.Value < 0 {
+		return nil, fmt.Errorf("edge: invalid number of unwrap workers '%d'", y.Crypto.Unwrap.Workers.Value)
+	}
+	if y.Crypto.Unwrap.Queue.Value < 0 {
+		return nil, fmt.Errorf("edge: invalid unwrap queue size '%d'", y.Crypto.Unwrap.Queue.Value)
+	}
+
+	rateLimit, err := ymlToRateLimit(y)
+	if err != nil {
+		return nil, err
+	}
+	limits, err := ymlToLimits(y)
+	if err != nil {
+		return nil, err
+	}
+	if y.Shutdown.Delay.Value < 0 {
+		return nil, fmt.Errorf("edge: invalid shutdown config: invalid delay '%v'", y.Shutdown.Delay.Value)
+	}
+	if y.Shutdown.Timeout.Value < 0 {
+		return nil, fmt.Errorf("edge: invalid shutdown config: invalid timeout '%v'", y.Shutdown.Timeout.Value)
+	}
+
 	if len(y.Keys) > 0 {
 		names := make(map[string]struct{}, len(y.Keys))
 		for _, key := range y.Keys {
@@ -282,31 +820,232 @@ func ymlToServerConfig(y *yml) (*ServerConfig, error) {
 		}
 	}
 
-	keystore, err := ymlToKeyStore(y)
+	const MaxKeyPoolSize = 10000
+	keyPools := make([]KeyPool, 0, len(y.KeyPools))
+	for _, pool := range y.KeyPools {
+		if pool.Prefix.Value == "" {
+			return nil, errors.New("edge: invalid key pool config: no prefix specified")
+		}
+		for _, p := range keyPools {
+			if p.Prefix == pool.Prefix.Value {
+				return nil, fmt.Errorf("edge: invalid key pool config: pool '%s' is defined multiple times", p.Prefix)
+			}
+		}
+		if pool.Size.Value <= 0 || pool.Size.Value > MaxKeyPoolSize {
+			return nil, fmt.Errorf("edge: invalid key pool config: invalid size '%d' of pool '%s'", pool.Size.Value, pool.Prefix.Value)
+		}
+		algorithm, err := key.ParseAlgorithm(pool.Algorithm.Value)
+		if err != nil {
+			return nil, fmt.Errorf("edge: invalid key pool config: pool '%s': %v", pool.Prefix.Value, err)
+		}
+		keyPools = append(keyPools, KeyPool{
+			Prefix:    pool.Prefix.Value,
+			Size:      pool.Size.Value,
+			Algorithm: algorithm,
+		})
+	}
+
+	policyHooks := make([]PolicyHook, 0, len(y.PolicyHooks))
+	for _, hook := range y.PolicyHooks {
+		if hook.Name.Value == "" {
+			return nil, errors.New("edge: invalid policy hook config: no name specified")
+		}
+		for _, h := range policyHooks {
+			if h.Name == hook.Name.Value {
+				return nil, fmt.Errorf("edge: invalid policy hook config: hook '%s' is defined multiple times", h.Name)
+			}
+		}
+		if hook.Module.Value == "" {
+			return nil, fmt.Errorf("edge: invalid policy hook config: no module specified for hook '%s'", hook.Name.Value)
+		}
+		if hook.Timeout.Value < 0 {
+			return nil, fmt.Errorf("edge: invalid policy hook config: invalid timeout '%v' of hook '%s'", hook.Timeout.Value, hook.Name.Value)
+		}
+		policyHooks = append(policyHooks, PolicyHook{
+			Name:    hook.Name.Value,
+			Module:  hook.Module.Value,
+			Timeout: hook.Timeout.Value,
+		})
+	}
+
+	keystore, err := ymlToKeyStore(&y.KeyStore)
+	if err != nil {
+		return nil, err
+	}
+	replicas, err := ymlToReplicas(&y.KeyStore)
+	if err != nil {
+		return nil, err
+	}
+	mirror, err := ymlToMirror(&y.KeyStore)
+	if err != nil {
+		return nil, err
+	}
+	compression, err := ymlToCompression(&y.KeyStore)
 	if err != nil {
 		return nil, err
 	}
 
+	var enclaves map[string]*EnclaveConfig
+	if len(y.Enclaves) > 0 {
+		enclaves = make(map[string]*EnclaveConfig, len(y.Enclaves))
+	}
+	for name, enclave := range y.Enclaves {
+		if name == "" || name == "default" || strings.ContainsAny(name, "/\\ ") {
+			return nil, fmt.Errorf("edge: invalid enclave config: invalid enclave name '%s'", name)
+		}
+		if enclave.KeyStore.Connect.Retry.Value < 0 {
+			return nil, fmt.Errorf("edge: invalid enclave config: enclave '%s': invalid connect retry '%v'", name, enclave.KeyStore.Connect.Retry.Value)
+		}
+		if err := verifyYMLPolicies(y, enclave.Policies); err != nil {
+			return nil, fmt.Errorf("edge: invalid enclave config: enclave '%s': %v", name, strings.TrimPrefix(err.Error(), "edge: "))
+		}
+		ks, err := ymlToKeyStore(&enclave.KeyStore)
+		if err != nil {
+			return nil, fmt.Errorf("edge: invalid enclave config: enclave '%s': %v", name, strings.TrimPrefix(err.Error(), "edge: "))
+		}
+		retry, breaker, err := ymlToRetryConfig(&enclave.KeyStore)
+		if err != nil {
+			return nil, fmt.Errorf("edge: invalid enclave config: enclave '%s': %v", name, strings.TrimPrefix(err.Error(), "edge: "))
+		}
+		replicas, err := ymlToReplicas(&enclave.KeyStore)
+		if err != nil {
+			return nil, fmt.Errorf("edge: invalid enclave config: enclave '%s': %v", name, strings.TrimPrefix(err.Error(), "edge: "))
+		}
+		mirror, err := ymlToMirror(&enclave.KeyStore)
+		if err != nil {
+			return nil, fmt.Errorf("edge: invalid enclave config: enclave '%s': %v", name, strings.TrimPrefix(err.Error(), "edge: "))
+		}
+		compression, err := ymlToCompression(&enclave.KeyStore)
+		if err != nil {
+			return nil, fmt.Errorf("edge: invalid enclave config: enclave '%s': %v", name, strings.TrimPrefix(err.Error(), "edge: "))
+		}
+		enclaves[name] = &EnclaveConfig{
+			KeyStore:         ks,
+			KeyStoreReplicas: replicas,
+			KeyStoreMirror:   mirror,
+			KeyStoreConnect: &ConnectConfig{
+				Lazy:  enclave.KeyStore.Connect.Lazy.Value,
+				Retry: enclave.KeyStore.Connect.Retry.Value,
+			},
+			KeyStoreRetry:          retry,
+			KeyStoreCircuitBreaker: breaker,
+			KeyStoreCompression:    compression,
+			Policies:               ymlToPolicies(enclave.Policies),
+		}
+	}
+
 	c := &ServerConfig{
-		Addr:  y.Addr.Value,
-		Admin: y.Admin.Identity.Value,
+		Addr:      y.Addr.Value,
+		Listeners: listeners,
+		ProbeAddr: y.ProbeAddr.Value,
+		Admin:     y.Admin.Identity.Value,
+		ReadOnly:  y.ReadOnly.Value,
 		TLS: &TLSConfig{
 			PrivateKey:        y.TLS.PrivateKey.Value,
 			Certificate:       y.TLS.Certificate.Value,
 			Password:          y.TLS.Password.Value,
 			CAPath:            y.TLS.CAPath.Value,
+			MinVersion:        minVersion,
+			CipherSuites:      cipherSuites,
+			ClientCAPaths:     clientCAs,
+			ReloadInterval:    y.TLS.Reload.Value,
 			ForwardCertHeader: y.TLS.Proxy.Header.ClientCert.Value,
+			APIKeys:           y.TLS.Client.APIKeys.Value,
+			Revocation:        revocation,
 		},
 		Cache: &CacheConfig{
 			Expiry:        y.Cache.Expiry.Any.Value,
 			ExpiryUnused:  y.Cache.Expiry.Unused.Value,
 			ExpiryOffline: y.Cache.Expiry.Offline.Value,
 		},
+		KeyExpiry: &KeyExpiryConfig{
+			DeleteInterval: y.Expiry.Delete.Value,
+		},
 		Log: &LogConfig{
 			Error: strings.TrimSpace(strings.ToLower(y.Log.Error.Value)) != "off", // default is "on" behavior
 			Audit: strings.TrimSpace(strings.ToLower(y.Log.Audit.Value)) == "on",  // default is "off" behavior
+			Stats: strings.TrimSpace(y.Log.Stats.Value),
+		},
+		Crypto: &CryptoConfig{
+			AEAD: WorkerPoolConfig{
+				Workers: y.Crypto.AEAD.Workers.Value,
+				Queue:   y.Crypto.AEAD.Queue.Value,
+			},
+			Unwrap: WorkerPoolConfig{
+				Workers: y.Crypto.Unwrap.Workers.Value,
+				Queue:   y.Crypto.Unwrap.Queue.Value,
+			},
 		},
-		KeyStore: keystore,
+		KeyStore:         keystore,
+		KeyStoreReplicas: replicas,
+		KeyStoreMirror:   mirror,
+		KeyStoreConnect: &ConnectConfig{
+			Lazy:  y.KeyStore.Connect.Lazy.Value,
+			Retry: y.KeyStore.Connect.Retry.Value,
+		},
+		KeyStoreRetry:          retry,
+		KeyStoreCircuitBreaker: breaker,
+		KeyStoreCompression:    compression,
+		Enclaves:               enclaves,
+	}
+	if y.Cache.KeyStore != nil {
+		c.Cache.KeyStore = &KeyStoreCacheConfig{
+			Size:           y.Cache.KeyStore.Size.Value,
+			Expiry:         y.Cache.KeyStore.Expiry.Value,
+			NegativeExpiry: y.Cache.KeyStore.NegativeExpiry.Value,
+		}
+	}
+	if y.Receipts.PrivateKey.Value != "" {
+		c.Receipts = &ReceiptConfig{
+			PrivateKey: y.Receipts.PrivateKey.Value,
+			Password:   y.Receipts.Password.Value,
+		}
+	}
+	c.RateLimit = rateLimit
+	c.Limits = limits
+	if y.Shutdown.Delay.Value > 0 || y.Shutdown.Timeout.Value > 0 {
+		c.Shutdown = &ShutdownConfig{
+			Delay:   y.Shutdown.Delay.Value,
+			Timeout: y.Shutdown.Timeout.Value,
+		}
+	}
+	if y.TLS.CertManager.Secret.Value != "" {
+		c.TLS.CertManager = &CertManagerConfig{
+			Secret:         y.TLS.CertManager.Secret.Value,
+			Namespace:      y.TLS.CertManager.Namespace.Value,
+			ReloadInterval: y.TLS.CertManager.Reload.Value,
+		}
+	}
+	if acme != nil {
+		c.TLS.ACME = acme
+	}
+	if oidc := y.TLS.Client.OIDC; oidc.Issuer.Value != "" {
+		c.TLS.OIDC = &OIDCConfig{
+			Issuer:        oidc.Issuer.Value,
+			Audience:      oidc.Audience.Value,
+			JWKSURL:       oidc.JWKSURL.Value,
+			IdentityClaim: oidc.Claims.Identity.Value,
+			PolicyClaim:   oidc.Claims.Policy.Value,
+		}
+	}
+	if len(y.TLS.Client.SPIFFE.TrustDomains) > 0 {
+		c.TLS.SPIFFE = &SPIFFEConfig{
+			TrustDomains: make([]string, 0, len(y.TLS.Client.SPIFFE.TrustDomains)),
+		}
+		for _, domain := range y.TLS.Client.SPIFFE.TrustDomains {
+			c.TLS.SPIFFE.TrustDomains = append(c.TLS.SPIFFE.TrustDomains, strings.TrimPrefix(domain.Value, "spiffe://"))
+		}
+	}
+	if require := y.TLS.Client.Require; require.Version.Value != "" || require.RSAKeySize.Value > 0 || len(require.Curves) > 0 {
+		version, _ := parseTLSVersion(require.Version.Value)
+		c.TLS.ClientPolicy = &ClientTLSConfig{
+			MinVersion:    version,
+			MinRSAKeySize: require.RSAKeySize.Value,
+			Enforce:       strings.ToLower(require.Mode.Value) != "report",
+		}
+		for _, curve := range require.Curves {
+			c.TLS.ClientPolicy.Curves = append(c.TLS.ClientPolicy.Curves, curve.Value)
+		}
 	}
 	if len(y.TLS.Proxy.Identities) > 0 {
 		c.TLS.Proxies = make([]kes.Identity, 0, len(y.TLS.Proxy.Identities))
@@ -314,27 +1053,16 @@ func ymlToServerConfig(y *yml) (*ServerConfig, error) {
 			c.TLS.Proxies = append(c.TLS.Proxies, proxy.Value)
 		}
 	}
-	if len(y.Policies) > 0 {
-		c.Policies = make(map[string]Policy, len(y.Policies))
-		for name, policy := range y.Policies {
-			identities := make([]kes.Identity, 0, len(policy.Identities))
-			for _, id := range policy.Identities {
-				identities = append(identities, id.Value)
-			}
-			c.Policies[name] = Policy{
-				Allow:      policy.Allow,
-				Deny:       policy.Deny,
-				Identities: identities,
-			}
-		}
-	}
+	c.Policies = ymlToPolicies(y.Policies)
 	if len(y.API.Paths) > 0 {
 		paths := make(map[string]APIPathConfig, len(y.API.Paths))
 		for path, api := range y.API.Paths {
-			paths[path] = APIPathConfig{
-				InsecureSkipAuth: api.InsecureSkipAuth.Value,
-				Timeout:          api.Timeout.Value,
+			config := APIPathConfig{Timeout: api.Timeout.Value}
+			if api.InsecureSkipAuth != nil {
+				config.InsecureSkipAuth = api.InsecureSkipAuth.Value
+				config.RequireAuth = !api.InsecureSkipAuth.Value
 			}
+			paths[path] = config
 		}
 		c.API = &APIConfig{
 			Paths: paths,
@@ -351,29 +1079,466 @@ func ymlToServerConfig(y *yml) (*ServerConfig, error) {
 			c.Keys = append(c.Keys, Key{Name: key.Name.Value})
 		}
 	}
+	if len(keyPools) > 0 {
+		c.KeyPools = keyPools
+	}
+	if len(policyHooks) > 0 {
+		c.PolicyHooks = policyHooks
+	}
 	return c, nil
 }
 
-func ymlToKeyStore(y *yml) (KeyStore, error) {
-	var keystore KeyStore
+// ymlToACMEConfig returns the ACME configuration or nil
+// if no ACME configuration is specified.
+func ymlToACMEConfig(y *yml) (*ACMEConfig, error) {
+	acme := y.TLS.ACME
+	if len(acme.Domains) == 0 {
+		if acme.Email.Value != "" || acme.Directory.Value != "" || acme.Cache.Value != "" || acme.Challenge.Value != "" || acme.HTTPAddr.Value != "" {
+			return nil, errors.New("edge: invalid acme config: no domains specified")
+		}
+		return nil, nil
+	}
+	if !acme.AcceptTOS.Value {
+		return nil, errors.New("edge: invalid acme config: terms of service of the ACME CA have not been accepted: set 'accept_tos' to true")
+	}
+	if acme.Cache.Value == "" {
+		return nil, errors.New("edge: invalid acme config: no cache directory specified")
+	}
 
-	// FS Keystore
-	if y.KeyStore.FS != nil {
-		if y.KeyStore.FS.Path.Value == "" {
-			return nil, errors.New("edge: invalid fs keystore: no path specified")
+	config := &ACMEConfig{
+		Domains:   make([]string, 0, len(acme.Domains)),
+		Email:     acme.Email.Value,
+		Directory: acme.Directory.Value,
+		CacheDir:  acme.Cache.Value,
+		Challenge: strings.ToLower(acme.Challenge.Value),
+		HTTPAddr:  acme.HTTPAddr.Value,
+	}
+	for _, domain := range acme.Domains {
+		if domain.Value == "" || strings.ContainsAny(domain.Value, "/:* ") {
+			return nil, fmt.Errorf("edge: invalid acme config: invalid domain '%s'", domain.Value)
+		}
+		config.Domains = append(config.Domains, domain.Value)
+	}
+	switch config.Challenge {
+	case "", "tls-alpn-01":
+		if config.HTTPAddr != "" {
+			return nil, errors.New("edge: invalid acme config: 'http_address' requires the 'http-01' challenge")
 		}
-		keystore = &FSKeyStore{
-			Path: y.KeyStore.FS.Path.Value,
+	case "http-01":
+		if config.HTTPAddr == "" {
+			config.HTTPAddr = "0.0.0.0:80"
 		}
+	default:
+		return nil, fmt.Errorf("edge: invalid acme config: invalid challenge '%s': must be either 'tls-alpn-01' or 'http-01'", acme.Challenge.Value)
+	}
+	return config, nil
+}
+
+// ymlToLimits returns the request limit config, or nil if
+// requests are not limited. The max. body size is either a
+// number of bytes or a size with a unit, like "1MiB". The
+// queue timeout defaults to 5s.
+func ymlToLimits(y *yml) (*LimitConfig, error) {
+	limits := y.Limits
+	config := &LimitConfig{
+		MaxRequests:  limits.MaxRequests.Value,
+		Queue:        limits.Queue.Value,
+		QueueTimeout: limits.QueueTimeout.Value,
+	}
+	if s := strings.TrimSpace(limits.MaxBody.Value); s != "" {
+		size, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			var memSize mem.Size
+			if memSize, err = mem.ParseSize(s); err != nil {
+				return nil, fmt.Errorf("edge: invalid limits config: invalid max. body size '%s'", s)
+			}
+			size = int64(memSize)
+		}
+		if size <= 0 {
+			return nil, fmt.Errorf("edge: invalid limits config: invalid max. body size '%s': size must be positive", s)
+		}
+		config.MaxBody = size
+	}
+	if config.MaxRequests < 0 {
+		return nil, fmt.Errorf("edge: invalid limits config: invalid max. number of requests '%d'", config.MaxRequests)
+	}
+	if config.Queue < 0 {
+		return nil, fmt.Errorf("edge: invalid limits config: invalid queue size '%d'", config.Queue)
+	}
+	if config.QueueTimeout < 0 {
+		return nil, fmt.Errorf("edge: invalid limits config: invalid queue timeout '%v'", config.QueueTimeout)
+	}
+	if config.MaxRequests == 0 && (config.Queue > 0 || config.QueueTimeout > 0) {
+		return nil, errors.New("edge: invalid limits config: 'queue' and 'queue_timeout' require 'max_requests'")
+	}
+	if config.MaxRequests > 0 && config.QueueTimeout == 0 {
+		config.QueueTimeout = 5 * time.Second
+	}
+	if config.MaxBody == 0 && config.MaxRequests == 0 {
+		return nil, nil
+	}
+	return config, nil
+}
+
+// ymlRateLimit is the YAML representation of a rate limit
+// of an identity, policy or enclave.
+type ymlRateLimit struct {
+	Rate  env[float64] `yaml:"rate"`
+	Burst env[int]     `yaml:"burst"`
+}
+
+// ymlToRateLimit returns the rate limit config, or nil if
+// no requests are limited. Identity, policy and enclave
+// rate limits must have a positive rate. The burst defaults
+// to one second worth of requests.
+func ymlToRateLimit(y *yml) (*RateLimitConfig, error) {
+	rateLimit := y.RateLimit
+	if rateLimit.Rate.Value < 0 {
+		return nil, fmt.Errorf("edge: invalid rate limit '%v'", rateLimit.Rate.Value)
+	}
+	if rateLimit.Burst.Value < 0 {
+		return nil, fmt.Errorf("edge: invalid rate limit burst '%d'", rateLimit.Burst.Value)
+	}
+
+	toRule := func(limit ymlRateLimit) (RateLimitRule, error) {
+		if limit.Rate.Value <= 0 {
+			return RateLimitRule{}, fmt.Errorf("invalid rate '%v': rate must be positive", limit.Rate.Value)
+		}
+		if limit.Burst.Value < 0 {
+			return RateLimitRule{}, fmt.Errorf("invalid burst '%d'", limit.Burst.Value)
+		}
+		rule := RateLimitRule{Rate: limit.Rate.Value, Burst: limit.Burst.Value}
+		if rule.Burst == 0 { // By default, allow bursts of one second worth of requests
+			rule.Burst = int(math.Ceil(rule.Rate))
+		}
+		return rule, nil
+	}
+
+	config := &RateLimitConfig{}
+	if rateLimit.Rate.Value > 0 {
+		rule, err := toRule(ymlRateLimit{Rate: rateLimit.Rate, Burst: rateLimit.Burst})
+		if err != nil {
+			return nil, fmt.Errorf("edge: invalid rate limit: %v", err)
+		}
+		config.Rate, config.Burst = rule.Rate, rule.Burst
+	}
+	for identity, limit := range rateLimit.Identities {
+		if identity == "" {
+			return nil, errors.New("edge: invalid rate limit: empty identity")
+		}
+		rule, err := toRule(limit)
+		if err != nil {
+			return nil, fmt.Errorf("edge: invalid rate limit for identity '%s': %v", identity, err)
+		}
+		if config.Identities == nil {
+			config.Identities = make(map[kes.Identity]RateLimitRule, len(rateLimit.Identities))
+		}
+		config.Identities[kes.Identity(identity)] = rule
+	}
+	for policy, limit := range rateLimit.Policies {
+		if _, ok := y.Policies[policy]; !ok {
+			return nil, fmt.Errorf("edge: invalid rate limit: policy '%s' does not exist", policy)
+		}
+		rule, err := toRule(limit)
+		if err != nil {
+			return nil, fmt.Errorf("edge: invalid rate limit for policy '%s': %v", policy, err)
+		}
+		if config.Policies == nil {
+			config.Policies = make(map[string]RateLimitRule, len(rateLimit.Policies))
+		}
+		config.Policies[policy] = rule
+	}
+	for enclave, limit := range rateLimit.Enclaves {
+		if enclave == "" {
+			return nil, errors.New("edge: invalid rate limit: empty enclave name")
+		}
+		if _, ok := y.Enclaves[enclave]; !ok && len(y.Enclaves) > 0 && enclave != "default" {
+			return nil, fmt.Errorf("edge: invalid rate limit: enclave '%s' does not exist", enclave)
+		}
+		rule, err := toRule(limit)
+		if err != nil {
+			return nil, fmt.Errorf("edge: invalid rate limit for enclave '%s': %v", enclave, err)
+		}
+		if config.Enclaves == nil {
+			config.Enclaves = make(map[string]RateLimitRule, len(rateLimit.Enclaves))
+		}
+		config.Enclaves[enclave] = rule
+	}
+
+	if config.Rate == 0 && len(config.Identities) == 0 && len(config.Policies) == 0 && len(config.Enclaves) == 0 {
+		return nil, nil
+	}
+	return config, nil
+}
+
+// ymlToRevocationConfig returns the client certificate revocation
+// config, or nil if neither CRLs nor OCSP are configured.
+func ymlToRevocationConfig(y *yml) (*RevocationConfig, error) {
+	revocation := y.TLS.Revocation
+	if len(revocation.CRLs) == 0 && !revocation.OCSP.Value {
+		if revocation.OCSPResponder.Value != "" {
+			return nil, errors.New("edge: invalid revocation config: 'ocsp_responder' requires 'ocsp' to be enabled")
+		}
+		return nil, nil
+	}
+	if revocation.Cache.Value < 0 {
+		return nil, fmt.Errorf("edge: invalid revocation config: invalid cache duration '%v'", revocation.Cache.Value)
+	}
+	if revocation.OCSPResponder.Value != "" {
+		if !revocation.OCSP.Value {
+			return nil, errors.New("edge: invalid revocation config: 'ocsp_responder' requires 'ocsp' to be enabled")
+		}
+		if u, err := url.Parse(revocation.OCSPResponder.Value); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("edge: invalid revocation config: invalid OCSP responder '%s'", revocation.OCSPResponder.Value)
+		}
+	}
+
+	config := &RevocationConfig{
+		CRLs:          make([]string, 0, len(revocation.CRLs)),
+		OCSP:          revocation.OCSP.Value,
+		OCSPResponder: revocation.OCSPResponder.Value,
+		CacheTTL:      revocation.Cache.Value,
+		FailOpen:      revocation.FailOpen.Value,
+		CRLSoftFail:   revocation.CRLSoftFail.Value,
+	}
+	for _, crl := range revocation.CRLs {
+		if crl.Value == "" {
+			return nil, errors.New("edge: invalid revocation config: invalid CRL: empty path")
+		}
+		if strings.Contains(crl.Value, "://") {
+			if u, err := url.Parse(crl.Value); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return nil, fmt.Errorf("edge: invalid revocation config: invalid CRL URL '%s'", crl.Value)
+			}
+		}
+		config.CRLs = append(config.CRLs, crl.Value)
+	}
+	return config, nil
+}
+
+// ymlToListeners returns the listeners of the server config.
+// A listener address with an "unix://" prefix refers to a
+// UNIX domain socket. It returns nil if no listener is
+// specified.
+func ymlToListeners(y *yml) ([]Listener, error) {
+	if len(y.Listen) == 0 {
+		return nil, nil
+	}
+	if y.Addr.Value != "" {
+		return nil, errors.New("edge: invalid listen config: 'address' and 'listen' must not be specified both")
+	}
+
+	listeners := make([]Listener, 0, len(y.Listen))
+	for _, l := range y.Listen {
+		listener := Listener{
+			Network: "tcp",
+			Addr:    l.Addr.Value,
+		}
+		if strings.HasPrefix(listener.Addr, "unix://") {
+			listener.Network, listener.Addr = "unix", strings.TrimPrefix(listener.Addr, "unix://")
+		}
+		if listener.Addr == "" {
+			return nil, errors.New("edge: invalid listen config: no address specified")
+		}
+		for _, other := range listeners {
+			if other.Network == listener.Network && other.Addr == listener.Addr {
+				return nil, fmt.Errorf("edge: invalid listen config: address '%s' is specified multiple times", l.Addr.Value)
+			}
+		}
+		if l.TLS != nil {
+			// A listener either has its own private key and certificate
+			// or uses the server's.
+			if l.TLS.PrivateKey.Value == "" && (l.TLS.Certificate.Value != "" || l.TLS.Password.Value != "") {
+				return nil, fmt.Errorf("edge: invalid listen config: no TLS private key for address '%s'", l.Addr.Value)
+			}
+			if l.TLS.Certificate.Value == "" && l.TLS.PrivateKey.Value != "" {
+				return nil, fmt.Errorf("edge: invalid listen config: no TLS certificate for address '%s'", l.Addr.Value)
+			}
+			minVersion, cipherSuites, err := ymlToTLSPolicy(l.TLS.MinVersion, l.TLS.CipherSuites)
+			if err != nil {
+				return nil, fmt.Errorf("edge: invalid listen config: address '%s': %v", l.Addr.Value, err)
+			}
+			if minVersion == 0 && len(cipherSuites) > 0 {
+				// The listener inherits the server's min. version.
+				if v, _ := parseTLSVersion(y.TLS.MinVersion.Value); v == tls.VersionTLS13 {
+					return nil, fmt.Errorf("edge: invalid listen config: address '%s': cipher suites cannot be configured for TLS 1.3", l.Addr.Value)
+				}
+			}
+			clientCAs, err := ymlToClientCAs(l.TLS.ClientCAs)
+			if err != nil {
+				return nil, fmt.Errorf("edge: invalid listen config: address '%s': %v", l.Addr.Value, err)
+			}
+			listener.TLS = &ListenerTLSConfig{
+				PrivateKey:    l.TLS.PrivateKey.Value,
+				Certificate:   l.TLS.Certificate.Value,
+				Password:      l.TLS.Password.Value,
+				CAPath:        l.TLS.CAPath.Value,
+				MinVersion:    minVersion,
+				CipherSuites:  cipherSuites,
+				ClientCAPaths: clientCAs,
+			}
+		}
+		listeners = append(listeners, listener)
+	}
+	return listeners, nil
+}
+
+// ymlToRetryConfig returns the keystore retry and circuit breaker
+// configuration. Each of them is nil if not specified.
+func ymlToRetryConfig(y *ymlKeyStore) (*RetryConfig, *CircuitBreakerConfig, error) {
+	var (
+		retry   *RetryConfig
+		breaker *CircuitBreakerConfig
+	)
+	if y.Retry != nil {
+		if y.Retry.MaxAttempts.Value < 0 {
+			return nil, nil, fmt.Errorf("edge: invalid keystore config: invalid retry max attempts '%d'", y.Retry.MaxAttempts.Value)
+		}
+		if y.Retry.Delay.Value < 0 {
+			return nil, nil, fmt.Errorf("edge: invalid keystore config: invalid retry delay '%v'", y.Retry.Delay.Value)
+		}
+		if y.Retry.MaxDelay.Value < 0 {
+			return nil, nil, fmt.Errorf("edge: invalid keystore config: invalid retry max delay '%v'", y.Retry.MaxDelay.Value)
+		}
+		retry = &RetryConfig{
+			MaxAttempts: y.Retry.MaxAttempts.Value,
+			MinDelay:    y.Retry.Delay.Value,
+			MaxDelay:    y.Retry.MaxDelay.Value,
+		}
+		if retry.MaxAttempts == 0 {
+			retry.MaxAttempts = 3
+		}
+	}
+	if y.CircuitBreaker != nil {
+		if y.CircuitBreaker.Failures.Value < 0 {
+			return nil, nil, fmt.Errorf("edge: invalid keystore config: invalid circuit breaker failures '%d'", y.CircuitBreaker.Failures.Value)
+		}
+		if y.CircuitBreaker.Timeout.Value < 0 {
+			return nil, nil, fmt.Errorf("edge: invalid keystore config: invalid circuit breaker timeout '%v'", y.CircuitBreaker.Timeout.Value)
+		}
+		breaker = &CircuitBreakerConfig{
+			Threshold: y.CircuitBreaker.Failures.Value,
+			Timeout:   y.CircuitBreaker.Timeout.Value,
+		}
+	}
+	return retry, breaker, nil
+}
+
+// ymlToReplicas returns the read-only replicas of a
+// keystore, if any.
+func ymlToReplicas(y *ymlKeyStore) ([]KeyStore, error) {
+	if len(y.Replicas) == 0 {
+		return nil, nil
+	}
+	replicas := make([]KeyStore, 0, len(y.Replicas))
+	for i := range y.Replicas {
+		replica := &y.Replicas[i]
+		if len(replica.Replicas) > 0 || replica.Mirror != nil {
+			return nil, fmt.Errorf("edge: invalid keystore config: replica %d: replicas cannot have replicas or a mirror", i)
+		}
+		if replica.Retry != nil || replica.CircuitBreaker != nil || replica.Connect.Lazy.Value || replica.Connect.Retry.Value != 0 || replica.Compression.Value != "" {
+			return nil, fmt.Errorf("edge: invalid keystore config: replica %d: connect, retry, circuit breaker and compression are inherited from the keystore", i)
+		}
+		ks, err := ymlToKeyStore(replica)
+		if err != nil {
+			return nil, fmt.Errorf("edge: invalid keystore config: replica %d: %v", i, strings.TrimPrefix(err.Error(), "edge: "))
+		}
+		replicas = append(replicas, ks)
+	}
+	return replicas, nil
+}
+
+// ymlToMirror returns the mirror of a keystore, if any.
+func ymlToMirror(y *ymlKeyStore) (KeyStore, error) {
+	if y.Mirror == nil {
+		return nil, nil
+	}
+	mirror := y.Mirror
+	if len(mirror.Replicas) > 0 || mirror.Mirror != nil {
+		return nil, errors.New("edge: invalid keystore config: mirror cannot have replicas or a mirror")
+	}
+	if mirror.Retry != nil || mirror.CircuitBreaker != nil || mirror.Connect.Lazy.Value || mirror.Connect.Retry.Value != 0 || mirror.Compression.Value != "" {
+		return nil, errors.New("edge: invalid keystore config: mirror: connect, retry, circuit breaker and compression are inherited from the keystore")
+	}
+	ks, err := ymlToKeyStore(mirror)
+	if err != nil {
+		return nil, fmt.Errorf("edge: invalid keystore config: mirror: %v", strings.TrimPrefix(err.Error(), "edge: "))
+	}
+	return ks, nil
+}
+
+// ymlToCompression returns the compression algorithm
+// of a keystore.
+func ymlToCompression(y *ymlKeyStore) (sys.Compression, error) {
+	compression, err := sys.ParseCompression(y.Compression.Value)
+	if err != nil {
+		return "", fmt.Errorf("edge: invalid keystore config: invalid compression '%s'", y.Compression.Value)
+	}
+	return compression, nil
+}
+
+func ymlToKeyStore(y *ymlKeyStore) (KeyStore, error) {
+	var keystore KeyStore
+
+	// FS Keystore
+	if y.FS != nil {
+		if y.FS.Path.Value == "" {
+			return nil, errors.New("edge: invalid fs keystore: no path specified")
+		}
+		s := &FSKeyStore{
+			Path: y.FS.Path.Value,
+		}
+		if kms := y.FS.KMS; kms != nil {
+			if kms.AWS == nil && kms.GCP == nil {
+				return nil, errors.New("edge: invalid fs keystore: invalid kms config: no KMS specified")
+			}
+			if kms.AWS != nil && kms.GCP != nil {
+				return nil, errors.New("edge: invalid fs keystore: invalid kms config: more than one KMS specified")
+			}
+			if kms.AWS != nil {
+				if kms.AWS.Region.Value == "" {
+					return nil, errors.New("edge: invalid fs keystore: invalid aws kms config: no region specified")
+				}
+				if kms.AWS.KeyID.Value == "" {
+					return nil, errors.New("edge: invalid fs keystore: invalid aws kms config: no key specified")
+				}
+				s.AWSKMS = &AWSKMSConfig{
+					Endpoint:     kms.AWS.Endpoint.Value,
+					Region:       kms.AWS.Region.Value,
+					KeyID:        kms.AWS.KeyID.Value,
+					AccessKey:    kms.AWS.Login.AccessKey.Value,
+					SecretKey:    kms.AWS.Login.SecretKey.Value,
+					SessionToken: kms.AWS.Login.SessionToken.Value,
+				}
+			}
+			if kms.GCP != nil {
+				if kms.GCP.Key.Value == "" {
+					return nil, errors.New("edge: invalid fs keystore: invalid gcp kms config: no key specified")
+				}
+				var scopes []string
+				for _, scope := range kms.GCP.Scopes {
+					scopes = append(scopes, scope.Value)
+				}
+				s.GCPKMS = &GCPKMSConfig{
+					Endpoint:    kms.GCP.Endpoint.Value,
+					Key:         kms.GCP.Key.Value,
+					Scopes:      scopes,
+					ClientEmail: kms.GCP.Credentials.Client.Value,
+					ClientID:    kms.GCP.Credentials.ClientID.Value,
+					KeyID:       kms.GCP.Credentials.KeyID.Value,
+					PrivateKey:  kms.GCP.Credentials.Key.Value,
+				}
+			}
+		}
+		keystore = s
 	}
 
 	// KES Keystore
-	if y.KeyStore.KES != nil {
+	if y.KES != nil {
 		if keystore != nil {
 			return nil, errors.New("edge: invalid keystore config: more than once keystore specified")
 		}
-		endpoints := make([]string, 0, len(y.KeyStore.KES.Endpoint))
-		for _, endpoint := range y.KeyStore.KES.Endpoint {
+		endpoints := make([]string, 0, len(y.KES.Endpoint))
+		for _, endpoint := range y.KES.Endpoint {
 			if e := strings.TrimSpace(endpoint.Value); e != "" {
 				endpoints = append(endpoints, e)
 			}
@@ -381,234 +1546,754 @@ func ymlToKeyStore(y *yml) (KeyStore, error) {
 		if len(endpoints) == 0 {
 			return nil, errors.New("edge: invalid kes keystore: no endpoint specified")
 		}
-		if y.KeyStore.KES.TLS.PrivateKey.Value == "" {
+		if y.KES.TLS.PrivateKey.Value == "" {
 			return nil, errors.New("edge: invalid kes keystore: no TLS private key specified")
 		}
-		if y.KeyStore.KES.TLS.Certificate.Value == "" {
+		if y.KES.TLS.Certificate.Value == "" {
 			return nil, errors.New("edge: invalid kes keystore: no TLS certificate specified")
 		}
 		keystore = &KESKeyStore{
 			Endpoints:       endpoints,
-			Enclave:         y.KeyStore.KES.Enclave.Value,
-			PrivateKeyFile:  y.KeyStore.KES.TLS.PrivateKey.Value,
-			CertificateFile: y.KeyStore.KES.TLS.Certificate.Value,
-			CAPath:          y.KeyStore.KES.TLS.CAPath.Value,
+			Enclave:         y.KES.Enclave.Value,
+			PrivateKeyFile:  y.KES.TLS.PrivateKey.Value,
+			CertificateFile: y.KES.TLS.Certificate.Value,
+			CAPath:          y.KES.TLS.CAPath.Value,
+		}
+	}
+
+	// etcd Keystore
+	if y.Etcd != nil {
+		if keystore != nil {
+			return nil, errors.New("edge: invalid keystore config: more than once keystore specified")
+		}
+		endpoints := make([]string, 0, len(y.Etcd.Endpoint))
+		for _, endpoint := range y.Etcd.Endpoint {
+			if e := strings.TrimSpace(endpoint.Value); e != "" {
+				endpoints = append(endpoints, e)
+			}
+		}
+		if len(endpoints) == 0 {
+			return nil, errors.New("edge: invalid etcd keystore: no endpoint specified")
+		}
+		if y.Etcd.LeaseTTL.Value < 0 {
+			return nil, errors.New("edge: invalid etcd keystore: lease TTL is negative")
+		}
+		s := &EtcdKeyStore{
+			Endpoints: endpoints,
+			Prefix:    y.Etcd.Prefix.Value,
+			LeaseTTL:  y.Etcd.LeaseTTL.Value,
+		}
+		if y.Etcd.Credentials != nil {
+			if y.Etcd.Credentials.Username.Value == "" {
+				return nil, errors.New("edge: invalid etcd keystore: no username specified")
+			}
+			s.Username = y.Etcd.Credentials.Username.Value
+			s.Password = y.Etcd.Credentials.Password.Value
+		}
+		if y.Etcd.TLS != nil {
+			if (y.Etcd.TLS.Certificate.Value == "") != (y.Etcd.TLS.PrivateKey.Value == "") {
+				return nil, errors.New("edge: invalid etcd keystore: TLS certificate and private key must be specified together")
+			}
+			s.CertificateFile = y.Etcd.TLS.Certificate.Value
+			s.PrivateKeyFile = y.Etcd.TLS.PrivateKey.Value
+			s.CAPath = y.Etcd.TLS.CAPath.Value
 		}
+		keystore = s
+	}
+
+	// SQL Keystore
+	if y.SQL != nil {
+		if keystore != nil {
+			return nil, errors.New("edge: invalid keystore config: more than once keystore specified")
+		}
+		switch y.SQL.Driver.Value {
+		case "postgres", "mysql":
+		case "":
+			return nil, errors.New("edge: invalid sql keystore: no driver specified")
+		default:
+			return nil, fmt.Errorf("edge: invalid sql keystore: unsupported driver '%s'", y.SQL.Driver.Value)
+		}
+		if y.SQL.DSN.Value == "" {
+			return nil, errors.New("edge: invalid sql keystore: no data source name specified")
+		}
+		if y.SQL.EncryptionKey.Value == "" {
+			return nil, errors.New("edge: invalid sql keystore: no encryption key specified")
+		}
+		encryptionKey, err := hex.DecodeString(y.SQL.EncryptionKey.Value)
+		if err != nil || len(encryptionKey) != 32 {
+			return nil, errors.New("edge: invalid sql keystore: encryption key must be a hex-encoded 256 bit key")
+		}
+		s := &SQLKeyStore{
+			Driver:        y.SQL.Driver.Value,
+			DSN:           y.SQL.DSN.Value,
+			Table:         y.SQL.Table.Value,
+			EncryptionKey: encryptionKey,
+		}
+		if y.SQL.Pool != nil {
+			if y.SQL.Pool.MaxOpenConns.Value < 0 {
+				return nil, errors.New("edge: invalid sql keystore: max. number of open connections is negative")
+			}
+			if y.SQL.Pool.MaxIdleConns.Value < 0 {
+				return nil, errors.New("edge: invalid sql keystore: max. number of idle connections is negative")
+			}
+			if y.SQL.Pool.ConnMaxLifetime.Value < 0 {
+				return nil, errors.New("edge: invalid sql keystore: max. connection lifetime is negative")
+			}
+			s.MaxOpenConns = y.SQL.Pool.MaxOpenConns.Value
+			s.MaxIdleConns = y.SQL.Pool.MaxIdleConns.Value
+			s.ConnMaxLifetime = y.SQL.Pool.ConnMaxLifetime.Value
+		}
+		keystore = s
 	}
 
 	// Hashicorp Vault Keystore
-	if y.KeyStore.Vault != nil {
+	if y.Vault != nil {
 		if keystore != nil {
 			return nil, errors.New("edge: invalid keystore config: more than once keystore specified")
 		}
-		if y.KeyStore.Vault.Endpoint.Value == "" {
+		if y.Vault.Endpoint.Value == "" {
 			return nil, errors.New("edge: invalid vault keystore: no endpoint specified")
 		}
-		if y.KeyStore.Vault.AppRole == nil && y.KeyStore.Vault.Kubernetes == nil {
+		var methods int
+		for _, ok := range []bool{y.Vault.AppRole != nil, y.Vault.Kubernetes != nil, y.Vault.AWS != nil, y.Vault.Cert != nil} {
+			if ok {
+				methods++
+			}
+		}
+		if methods == 0 {
 			return nil, errors.New("edge: invalid vault keystore: no authentication method specified")
 		}
-		if y.KeyStore.Vault.AppRole != nil && y.KeyStore.Vault.Kubernetes != nil {
+		if y.Vault.APIVersion.Value == "v1" && y.Vault.SoftDelete.Value {
+			return nil, errors.New("edge: invalid vault keystore: soft delete requires K/V engine version v2")
+		}
+		if y.Vault.APIVersion.Value == "v1" && len(y.Vault.CustomMetadata) > 0 {
+			return nil, errors.New("edge: invalid vault keystore: custom metadata requires K/V engine version v2")
+		}
+		if methods > 1 {
 			return nil, errors.New("edge: invalid vault keystore: more than one authentication method specified")
 		}
-		if y.KeyStore.Vault.AppRole != nil {
-			if y.KeyStore.Vault.AppRole.ID.Value == "" {
+		if y.Vault.AppRole != nil {
+			if y.Vault.AppRole.ID.Value == "" {
 				return nil, errors.New("edge: invalid vault keystore: invalid approle config: no approle ID specified")
 			}
-			if y.KeyStore.Vault.AppRole.Secret.Value == "" {
+			if y.Vault.AppRole.Secret.Value == "" {
 				return nil, errors.New("edge: invalid vault keystore: invalid approle config: no approle secret specified")
 			}
 		}
-		if y.KeyStore.Vault.Kubernetes != nil {
-			if y.KeyStore.Vault.Kubernetes.JWT.Value == "" {
+		if y.Vault.Kubernetes != nil {
+			if y.Vault.Kubernetes.JWT.Value == "" && y.Vault.Kubernetes.JWTFile.Value == "" {
 				return nil, errors.New("edge: invalid vault keystore: invalid kubernetes config: no JWT specified")
 			}
+			if y.Vault.Kubernetes.JWT.Value != "" && y.Vault.Kubernetes.JWTFile.Value != "" {
+				return nil, errors.New("edge: invalid vault keystore: invalid kubernetes config: JWT and JWT file specified")
+			}
 
 			// If the passed JWT value contains a path separator we assume it's a file.
 			// We always check for '/' and the OS-specific one make cover cases where
 			// a path is specified using '/' but the underlying OS is e.g. windows.
-			if jwt := y.KeyStore.Vault.Kubernetes.JWT.Value; strings.ContainsRune(jwt, '/') || strings.ContainsRune(jwt, os.PathSeparator) {
-				b, err := os.ReadFile(y.KeyStore.Vault.Kubernetes.JWT.Value)
+			if jwt := y.Vault.Kubernetes.JWT.Value; strings.ContainsRune(jwt, '/') || strings.ContainsRune(jwt, os.PathSeparator) {
+				b, err := os.ReadFile(y.Vault.Kubernetes.JWT.Value)
 				if err != nil {
-					return nil, fmt.Errorf("edge: failed to read vault kubernetes JWT from '%s': %v", y.KeyStore.Vault.Kubernetes.JWT.Value, err)
+					return nil, fmt.Errorf("edge: failed to read vault kubernetes JWT from '%s': %v", y.Vault.Kubernetes.JWT.Value, err)
 				}
-				y.KeyStore.Vault.Kubernetes.JWT.Value = string(b)
+				y.Vault.Kubernetes.JWT.Value = string(b)
 			}
 		}
-		if y.KeyStore.Vault.TLS.PrivateKey.Value != "" && y.KeyStore.Vault.TLS.Certificate.Value == "" {
+		if y.Vault.AWS != nil {
+			if y.Vault.AWS.Role.Value == "" {
+				return nil, errors.New("edge: invalid vault keystore: invalid aws config: no role specified")
+			}
+			if (y.Vault.AWS.AccessKey.Value == "") != (y.Vault.AWS.SecretKey.Value == "") {
+				return nil, errors.New("edge: invalid vault keystore: invalid aws config: access key and secret key must be specified both")
+			}
+		}
+		if y.Vault.Cert != nil && y.Vault.TLS.Certificate.Value == "" {
+			return nil, errors.New("edge: invalid vault keystore: invalid cert config: no TLS client certificate provided")
+		}
+		if y.Vault.TLS.PrivateKey.Value != "" && y.Vault.TLS.Certificate.Value == "" {
 			return nil, errors.New("edge: invalid vault keystore: invalid tls config: no TLS certificate provided")
 		}
-		if y.KeyStore.Vault.TLS.PrivateKey.Value == "" && y.KeyStore.Vault.TLS.Certificate.Value != "" {
+		if y.Vault.TLS.PrivateKey.Value == "" && y.Vault.TLS.Certificate.Value != "" {
 			return nil, errors.New("edge: invalid vault keystore: invalid tls config: no TLS private key provided")
 		}
 		s := &VaultKeyStore{
-			Endpoint:    y.KeyStore.Vault.Endpoint.Value,
-			Namespace:   y.KeyStore.Vault.Namespace.Value,
-			APIVersion:  y.KeyStore.Vault.APIVersion.Value,
-			Engine:      y.KeyStore.Vault.Engine.Value,
-			Prefix:      y.KeyStore.Vault.Prefix.Value,
-			PrivateKey:  y.KeyStore.Vault.TLS.PrivateKey.Value,
-			Certificate: y.KeyStore.Vault.TLS.Certificate.Value,
-			CAPath:      y.KeyStore.Vault.TLS.CAPath.Value,
-			StatusPing:  y.KeyStore.Vault.Status.Ping.Value,
-		}
-		if y.KeyStore.Vault.AppRole != nil {
+			Endpoint:      y.Vault.Endpoint.Value,
+			Namespace:     y.Vault.Namespace.Value,
+			APIVersion:    y.Vault.APIVersion.Value,
+			Engine:        y.Vault.Engine.Value,
+			Prefix:        y.Vault.Prefix.Value,
+			SoftDelete:    y.Vault.SoftDelete.Value,
+			PrivateKey:    y.Vault.TLS.PrivateKey.Value,
+			Certificate:   y.Vault.TLS.Certificate.Value,
+			CAPath:        y.Vault.TLS.CAPath.Value,
+			ServerName:    y.Vault.TLS.ServerName.Value,
+			StatusPing:    y.Vault.Status.Ping.Value,
+			WatchInterval: y.Vault.Watch.Interval.Value,
+		}
+		if len(y.Vault.CustomMetadata) > 0 {
+			s.CustomMetadata = make(map[string]string, len(y.Vault.CustomMetadata))
+			for k, v := range y.Vault.CustomMetadata {
+				s.CustomMetadata[k] = v.Value
+			}
+		}
+		if y.Vault.AppRole != nil {
 			s.AppRole = &VaultAppRoleAuth{
-				Engine: y.KeyStore.Vault.AppRole.Engine.Value,
-				ID:     y.KeyStore.Vault.AppRole.ID.Value,
-				Secret: y.KeyStore.Vault.AppRole.Secret.Value,
+				Engine: y.Vault.AppRole.Engine.Value,
+				ID:     y.Vault.AppRole.ID.Value,
+				Secret: y.Vault.AppRole.Secret.Value,
 			}
 		}
-		if y.KeyStore.Vault.Kubernetes != nil {
+		if y.Vault.Kubernetes != nil {
 			s.Kubernetes = &VaultKubernetesAuth{
-				Engine: y.KeyStore.Vault.Kubernetes.Engine.Value,
-				JWT:    y.KeyStore.Vault.Kubernetes.JWT.Value,
-				Role:   y.KeyStore.Vault.Kubernetes.Role.Value,
+				Engine:  y.Vault.Kubernetes.Engine.Value,
+				JWT:     y.Vault.Kubernetes.JWT.Value,
+				JWTFile: y.Vault.Kubernetes.JWTFile.Value,
+				Role:    y.Vault.Kubernetes.Role.Value,
+			}
+		}
+		if y.Vault.AWS != nil {
+			s.AWS = &VaultAWSAuth{
+				Engine:       y.Vault.AWS.Engine.Value,
+				Role:         y.Vault.AWS.Role.Value,
+				ServerID:     y.Vault.AWS.ServerID.Value,
+				Region:       y.Vault.AWS.Region.Value,
+				AccessKey:    y.Vault.AWS.AccessKey.Value,
+				SecretKey:    y.Vault.AWS.SecretKey.Value,
+				SessionToken: y.Vault.AWS.SessionToken.Value,
+			}
+		}
+		if y.Vault.Cert != nil {
+			s.Cert = &VaultCertAuth{
+				Engine: y.Vault.Cert.Engine.Value,
+				Name:   y.Vault.Cert.Name.Value,
 			}
 		}
 		keystore = s
 	}
 
 	// Fortanix SDKMS
-	if y.KeyStore.Fortanix != nil && y.KeyStore.Fortanix.SDKMS != nil {
+	if y.Fortanix != nil && y.Fortanix.SDKMS != nil {
 		if keystore != nil {
 			return nil, errors.New("edge: invalid keystore config: more than once keystore specified")
 		}
-		if y.KeyStore.Fortanix.SDKMS.Endpoint.Value == "" {
+		if y.Fortanix.SDKMS.Endpoint.Value == "" {
 			return nil, errors.New("edge: invalid fortanix SDKMS keystore: no endpoint specified")
 		}
-		if y.KeyStore.Fortanix.SDKMS.Login.APIKey.Value == "" {
+		for _, endpoint := range y.Fortanix.SDKMS.FailoverEndpoints {
+			if endpoint.Value == "" {
+				return nil, errors.New("edge: invalid fortanix SDKMS keystore: invalid failover endpoint: endpoint is empty")
+			}
+		}
+		if y.Fortanix.SDKMS.Login.APIKey.Value == "" && y.Fortanix.SDKMS.Login.APIKeyFile.Value == "" {
 			return nil, errors.New("edge: invalid fortanix SDKMS keystore: no API key specified")
 		}
-		keystore = &FortanixKeyStore{
-			Endpoint: y.KeyStore.Fortanix.SDKMS.Endpoint.Value,
-			GroupID:  y.KeyStore.Fortanix.SDKMS.GroupID.Value,
-			APIKey:   y.KeyStore.Fortanix.SDKMS.Login.APIKey.Value,
-			CAPath:   y.KeyStore.Fortanix.SDKMS.TLS.CAPath.Value,
+		if y.Fortanix.SDKMS.Login.APIKey.Value != "" && y.Fortanix.SDKMS.Login.APIKeyFile.Value != "" {
+			return nil, errors.New("edge: invalid fortanix SDKMS keystore: API key and API key file specified")
+		}
+		if y.Fortanix.SDKMS.TLS.PrivateKey.Value != "" && y.Fortanix.SDKMS.TLS.Certificate.Value == "" {
+			return nil, errors.New("edge: invalid fortanix SDKMS keystore: invalid tls config: no TLS certificate provided")
+		}
+		if y.Fortanix.SDKMS.TLS.PrivateKey.Value == "" && y.Fortanix.SDKMS.TLS.Certificate.Value != "" {
+			return nil, errors.New("edge: invalid fortanix SDKMS keystore: invalid tls config: no TLS private key provided")
+		}
+		s := &FortanixKeyStore{
+			Endpoint:    y.Fortanix.SDKMS.Endpoint.Value,
+			GroupID:     y.Fortanix.SDKMS.GroupID.Value,
+			APIKey:      y.Fortanix.SDKMS.Login.APIKey.Value,
+			APIKeyFile:  y.Fortanix.SDKMS.Login.APIKeyFile.Value,
+			CAPath:      y.Fortanix.SDKMS.TLS.CAPath.Value,
+			PrivateKey:  y.Fortanix.SDKMS.TLS.PrivateKey.Value,
+			Certificate: y.Fortanix.SDKMS.TLS.Certificate.Value,
+			ServerName:  y.Fortanix.SDKMS.TLS.ServerName.Value,
 		}
+		for _, endpoint := range y.Fortanix.SDKMS.FailoverEndpoints {
+			s.FailoverEndpoints = append(s.FailoverEndpoints, endpoint.Value)
+		}
+		keystore = s
 	}
 
 	// Thales CipherTrust / Gemalto KeySecure
-	if y.KeyStore.Gemalto != nil && y.KeyStore.Gemalto.KeySecure != nil {
+	if y.Gemalto != nil && y.Gemalto.KeySecure != nil {
 		if keystore != nil {
 			return nil, errors.New("edge: invalid keystore config: more than once keystore specified")
 		}
-		if y.KeyStore.Gemalto.KeySecure.Endpoint.Value == "" {
+		if y.Gemalto.KeySecure.Endpoint.Value == "" {
 			return nil, errors.New("edge: invalid gemalto keysecure keystore: no endpoint specified")
 		}
-		if y.KeyStore.Gemalto.KeySecure.Login.Token.Value == "" {
+		if y.Gemalto.KeySecure.Login.Token.Value == "" {
 			return nil, errors.New("edge: invalid gemalto keysecure keystore: no token specified")
 		}
+		if y.Gemalto.KeySecure.TLS.PrivateKey.Value != "" && y.Gemalto.KeySecure.TLS.Certificate.Value == "" {
+			return nil, errors.New("edge: invalid gemalto keysecure keystore: invalid tls config: no TLS certificate provided")
+		}
+		if y.Gemalto.KeySecure.TLS.PrivateKey.Value == "" && y.Gemalto.KeySecure.TLS.Certificate.Value != "" {
+			return nil, errors.New("edge: invalid gemalto keysecure keystore: invalid tls config: no TLS private key provided")
+		}
 		keystore = &KeySecureKeyStore{
-			Endpoint: y.KeyStore.Gemalto.KeySecure.Endpoint.Value,
-			Token:    y.KeyStore.Gemalto.KeySecure.Login.Token.Value,
-			Domain:   y.KeyStore.Gemalto.KeySecure.Login.Domain.Value,
-			CAPath:   y.KeyStore.Gemalto.KeySecure.TLS.CAPath.Value,
+			Endpoint:    y.Gemalto.KeySecure.Endpoint.Value,
+			Token:       y.Gemalto.KeySecure.Login.Token.Value,
+			Domain:      y.Gemalto.KeySecure.Login.Domain.Value,
+			CAPath:      y.Gemalto.KeySecure.TLS.CAPath.Value,
+			PrivateKey:  y.Gemalto.KeySecure.TLS.PrivateKey.Value,
+			Certificate: y.Gemalto.KeySecure.TLS.Certificate.Value,
+			ServerName:  y.Gemalto.KeySecure.TLS.ServerName.Value,
+		}
+	}
+
+	// CyberArk Conjur
+	if y.Conjur != nil {
+		if keystore != nil {
+			return nil, errors.New("edge: invalid keystore config: more than once keystore specified")
+		}
+		if y.Conjur.Endpoint.Value == "" {
+			return nil, errors.New("edge: invalid conjur keystore: no endpoint specified")
+		}
+		if y.Conjur.Account.Value == "" {
+			return nil, errors.New("edge: invalid conjur keystore: no account specified")
+		}
+		if y.Conjur.Login.Login.Value == "" {
+			return nil, errors.New("edge: invalid conjur keystore: no login specified")
+		}
+		if y.Conjur.Login.APIKey.Value == "" {
+			return nil, errors.New("edge: invalid conjur keystore: no API key specified")
+		}
+		if y.Conjur.TLS.PrivateKey.Value != "" && y.Conjur.TLS.Certificate.Value == "" {
+			return nil, errors.New("edge: invalid conjur keystore: invalid tls config: no TLS certificate provided")
+		}
+		if y.Conjur.TLS.PrivateKey.Value == "" && y.Conjur.TLS.Certificate.Value != "" {
+			return nil, errors.New("edge: invalid conjur keystore: invalid tls config: no TLS private key provided")
+		}
+		keystore = &ConjurKeyStore{
+			Endpoint:    y.Conjur.Endpoint.Value,
+			Account:     y.Conjur.Account.Value,
+			Login:       y.Conjur.Login.Login.Value,
+			APIKey:      y.Conjur.Login.APIKey.Value,
+			Policy:      y.Conjur.Policy.Value,
+			CAPath:      y.Conjur.TLS.CAPath.Value,
+			PrivateKey:  y.Conjur.TLS.PrivateKey.Value,
+			Certificate: y.Conjur.TLS.Certificate.Value,
+			ServerName:  y.Conjur.TLS.ServerName.Value,
+		}
+	}
+
+	// 1Password Connect
+	if y.OnePassword != nil && y.OnePassword.Connect != nil {
+		if keystore != nil {
+			return nil, errors.New("edge: invalid keystore config: more than once keystore specified")
+		}
+		if y.OnePassword.Connect.Endpoint.Value == "" {
+			return nil, errors.New("edge: invalid 1password keystore: no endpoint specified")
+		}
+		if y.OnePassword.Connect.Token.Value == "" {
+			return nil, errors.New("edge: invalid 1password keystore: no token specified")
+		}
+		if y.OnePassword.Connect.VaultID.Value == "" {
+			return nil, errors.New("edge: invalid 1password keystore: no vault ID specified")
+		}
+		keystore = &OnePasswordKeyStore{
+			Endpoint: y.OnePassword.Connect.Endpoint.Value,
+			Token:    y.OnePassword.Connect.Token.Value,
+			VaultID:  y.OnePassword.Connect.VaultID.Value,
+			Tag:      y.OnePassword.Connect.Tag.Value,
+			CAPath:   y.OnePassword.Connect.TLS.CAPath.Value,
+		}
+	}
+
+	// Generic REST API
+	if y.REST != nil {
+		if keystore != nil {
+			return nil, errors.New("edge: invalid keystore config: more than once keystore specified")
+		}
+		if y.REST.Endpoint.Value == "" {
+			return nil, errors.New("edge: invalid rest keystore: no endpoint specified")
+		}
+		if y.REST.Create.Path.Value == "" {
+			return nil, errors.New("edge: invalid rest keystore: no create request specified")
+		}
+		if y.REST.Get.Path.Value == "" {
+			return nil, errors.New("edge: invalid rest keystore: no get request specified")
+		}
+		if y.REST.Delete.Path.Value == "" {
+			return nil, errors.New("edge: invalid rest keystore: no delete request specified")
+		}
+		if y.REST.List.Path.Value == "" {
+			return nil, errors.New("edge: invalid rest keystore: no list request specified")
+		}
+		if (y.REST.TLS.PrivateKey.Value == "") != (y.REST.TLS.Certificate.Value == "") {
+			return nil, errors.New("edge: invalid rest keystore: TLS private key and certificate must be specified both")
+		}
+
+		var header map[string]string
+		if len(y.REST.Header) > 0 {
+			header = make(map[string]string, len(y.REST.Header))
+			for k, v := range y.REST.Header {
+				header[k] = v.Value
+			}
+		}
+		keystore = &RESTKeyStore{
+			Endpoint: y.REST.Endpoint.Value,
+			Header:   header,
+			Create: RESTRequest{
+				Method: y.REST.Create.Method.Value,
+				Path:   y.REST.Create.Path.Value,
+				Body:   y.REST.Create.Body.Value,
+			},
+			Get: RESTRequest{
+				Method: y.REST.Get.Method.Value,
+				Path:   y.REST.Get.Path.Value,
+			},
+			Delete: RESTRequest{
+				Method: y.REST.Delete.Method.Value,
+				Path:   y.REST.Delete.Path.Value,
+			},
+			List: RESTRequest{
+				Method: y.REST.List.Method.Value,
+				Path:   y.REST.List.Path.Value,
+			},
+			ValuePath:   y.REST.Get.Value.Value,
+			NamesPath:   y.REST.List.Names.Value,
+			NameField:   y.REST.List.NameField.Value,
+			CAPath:      y.REST.TLS.CAPath.Value,
+			PrivateKey:  y.REST.TLS.PrivateKey.Value,
+			Certificate: y.REST.TLS.Certificate.Value,
+		}
+	}
+
+	// IBM Key Protect
+	if y.IBM != nil && y.IBM.KeyProtect != nil {
+		if keystore != nil {
+			return nil, errors.New("edge: invalid keystore config: more than once keystore specified")
+		}
+		if y.IBM.KeyProtect.Endpoint.Value == "" {
+			return nil, errors.New("edge: invalid ibm:keyprotect keystore: no endpoint specified")
+		}
+		if y.IBM.KeyProtect.InstanceID.Value == "" {
+			return nil, errors.New("edge: invalid ibm:keyprotect keystore: no instance ID specified")
+		}
+		if y.IBM.KeyProtect.Login.APIKey.Value == "" {
+			return nil, errors.New("edge: invalid ibm:keyprotect keystore: no API key specified")
+		}
+		keystore = &IBMKeyProtectKeyStore{
+			Endpoint:    y.IBM.KeyProtect.Endpoint.Value,
+			InstanceID:  y.IBM.KeyProtect.InstanceID.Value,
+			IAMEndpoint: y.IBM.KeyProtect.IAMEndpoint.Value,
+			APIKey:      y.IBM.KeyProtect.Login.APIKey.Value,
+			ServiceID:   y.IBM.KeyProtect.Login.ServiceID.Value,
+			CAPath:      y.IBM.KeyProtect.TLS.CAPath.Value,
 		}
 	}
 
 	// GCP SecretManager
-	if y.KeyStore.GCP != nil && y.KeyStore.GCP.SecretManager != nil {
+	if y.GCP != nil && y.GCP.SecretManager != nil {
 		if keystore != nil {
 			return nil, errors.New("edge: invalid keystore config: more than once keystore specified")
 		}
-		if y.KeyStore.GCP.SecretManager.ProjectID.Value == "" {
+		if y.GCP.SecretManager.ProjectID.Value == "" {
 			return nil, errors.New("edge: invalid GCP secretmanager keystore: no project ID specified")
 		}
 		var scopes []string
-		if len(y.KeyStore.GCP.SecretManager.Scopes) > 0 {
+		if len(y.GCP.SecretManager.Scopes) > 0 {
 			scopes = make([]string, 0, len(scopes))
-			for _, scope := range y.KeyStore.GCP.SecretManager.Scopes {
+			for _, scope := range y.GCP.SecretManager.Scopes {
 				scopes = append(scopes, scope.Value)
 			}
 		}
 		keystore = &GCPSecretManagerKeyStore{
-			ProjectID:   y.KeyStore.GCP.SecretManager.ProjectID.Value,
-			Endpoint:    y.KeyStore.GCP.SecretManager.Endpoint.Value,
-			ClientEmail: y.KeyStore.GCP.SecretManager.Credentials.Client.Value,
-			ClientID:    y.KeyStore.GCP.SecretManager.Credentials.ClientID.Value,
-			KeyID:       y.KeyStore.GCP.SecretManager.Credentials.KeyID.Value,
-			Key:         y.KeyStore.GCP.SecretManager.Credentials.Key.Value,
+			ProjectID:   y.GCP.SecretManager.ProjectID.Value,
+			Endpoint:    y.GCP.SecretManager.Endpoint.Value,
+			ClientEmail: y.GCP.SecretManager.Credentials.Client.Value,
+			ClientID:    y.GCP.SecretManager.Credentials.ClientID.Value,
+			KeyID:       y.GCP.SecretManager.Credentials.KeyID.Value,
+			Key:         y.GCP.SecretManager.Credentials.Key.Value,
+			Scopes:      scopes,
+		}
+	}
+
+	// GCP Cloud KMS
+	if y.GCP != nil && y.GCP.CloudKMS != nil {
+		if keystore != nil {
+			return nil, errors.New("edge: invalid keystore config: more than once keystore specified")
+		}
+		if y.GCP.CloudKMS.Key.Value == "" {
+			return nil, errors.New("edge: invalid GCP cloudkms keystore: no key specified")
+		}
+		if y.GCP.CloudKMS.Path.Value == "" {
+			return nil, errors.New("edge: invalid GCP cloudkms keystore: no path specified")
+		}
+		var scopes []string
+		for _, scope := range y.GCP.CloudKMS.Scopes {
+			scopes = append(scopes, scope.Value)
+		}
+		keystore = &GCPCloudKMSKeyStore{
+			Key:         y.GCP.CloudKMS.Key.Value,
+			Path:        y.GCP.CloudKMS.Path.Value,
+			Endpoint:    y.GCP.CloudKMS.Endpoint.Value,
+			ClientEmail: y.GCP.CloudKMS.Credentials.Client.Value,
+			ClientID:    y.GCP.CloudKMS.Credentials.ClientID.Value,
+			KeyID:       y.GCP.CloudKMS.Credentials.KeyID.Value,
+			PrivateKey:  y.GCP.CloudKMS.Credentials.Key.Value,
 			Scopes:      scopes,
 		}
 	}
 
 	// AWS SecretsManager
-	if y.KeyStore.AWS != nil && y.KeyStore.AWS.SecretsManager != nil {
+	if y.AWS != nil && y.AWS.SecretsManager != nil {
 		if keystore != nil {
 			return nil, errors.New("edge: invalid keystore config: more than once keystore specified")
 		}
-		if y.KeyStore.AWS.SecretsManager.Endpoint.Value == "" {
+		if y.AWS.SecretsManager.Endpoint.Value == "" {
 			return nil, errors.New("edge: invalid AWS secretsmanager keystore: no endpoint specified")
 		}
-		if y.KeyStore.AWS.SecretsManager.Region.Value == "" {
+		if y.AWS.SecretsManager.Region.Value == "" {
 			return nil, errors.New("edge: invalid AWS secretsmanager keystore: no region specified")
 		}
 		keystore = &AWSSecretsManagerKeyStore{
-			Endpoint:     y.KeyStore.AWS.SecretsManager.Endpoint.Value,
-			Region:       y.KeyStore.AWS.SecretsManager.Region.Value,
-			KMSKey:       y.KeyStore.AWS.SecretsManager.KmsKey.Value,
-			AccessKey:    y.KeyStore.AWS.SecretsManager.Login.AccessKey.Value,
-			SecretKey:    y.KeyStore.AWS.SecretsManager.Login.SecretKey.Value,
-			SessionToken: y.KeyStore.AWS.SecretsManager.Login.SessionToken.Value,
+			Endpoint:     y.AWS.SecretsManager.Endpoint.Value,
+			Region:       y.AWS.SecretsManager.Region.Value,
+			KMSKey:       y.AWS.SecretsManager.KmsKey.Value,
+			AccessKey:    y.AWS.SecretsManager.Login.AccessKey.Value,
+			SecretKey:    y.AWS.SecretsManager.Login.SecretKey.Value,
+			SessionToken: y.AWS.SecretsManager.Login.SessionToken.Value,
+		}
+	}
+
+	// AWS CloudHSM
+	if y.AWS != nil && y.AWS.CloudHSM != nil {
+		if keystore != nil {
+			return nil, errors.New("edge: invalid keystore config: more than once keystore specified")
+		}
+		if y.AWS.CloudHSM.Login.Username.Value == "" {
+			return nil, errors.New("edge: invalid AWS cloudhsm keystore: no crypto user specified")
+		}
+		if y.AWS.CloudHSM.Login.Password.Value == "" {
+			return nil, errors.New("edge: invalid AWS cloudhsm keystore: no password specified")
+		}
+		if y.AWS.CloudHSM.WrappingKey.Value == "" {
+			return nil, errors.New("edge: invalid AWS cloudhsm keystore: no wrapping key specified")
+		}
+		keystore = &AWSCloudHSMKeyStore{
+			Module:      y.AWS.CloudHSM.Module.Value,
+			Username:    y.AWS.CloudHSM.Login.Username.Value,
+			Password:    y.AWS.CloudHSM.Login.Password.Value,
+			WrappingKey: y.AWS.CloudHSM.WrappingKey.Value,
+			Application: y.AWS.CloudHSM.Application.Value,
 		}
 	}
 
 	// Azure KeyVault
-	if y.KeyStore.Azure != nil && y.KeyStore.Azure.KeyVault != nil {
+	if y.Azure != nil && y.Azure.KeyVault != nil {
 		if keystore != nil {
 			return nil, errors.New("edge: invalid keystore config: more than once keystore specified")
 		}
-		if y.KeyStore.Azure.KeyVault.Endpoint.Value == "" {
+		if y.Azure.KeyVault.Endpoint.Value == "" {
 			return nil, errors.New("edge: invalid Azure keyvault keystore: no endpoint specified")
 		}
-		if y.KeyStore.Azure.KeyVault.Credentials == nil && y.KeyStore.Azure.KeyVault.ManagedIdentity == nil {
+		if y.Azure.KeyVault.ManagedHSM.Value && y.Azure.KeyVault.WrappingKey.Value == "" {
+			return nil, errors.New("edge: invalid Azure keyvault keystore: no Managed HSM wrapping key specified")
+		}
+		if !y.Azure.KeyVault.ManagedHSM.Value && y.Azure.KeyVault.WrappingKey.Value != "" {
+			return nil, errors.New("edge: invalid Azure keyvault keystore: wrapping key requires Managed HSM")
+		}
+		if y.Azure.KeyVault.Credentials == nil && y.Azure.KeyVault.ManagedIdentity == nil {
 			return nil, errors.New("edge: invalid Azure keyvault keystore: no authentication method specified")
 		}
-		if y.KeyStore.Azure.KeyVault.Credentials != nil && y.KeyStore.Azure.KeyVault.ManagedIdentity != nil {
+		if y.Azure.KeyVault.Credentials != nil && y.Azure.KeyVault.ManagedIdentity != nil {
 			return nil, errors.New("edge: invalid Azure keyvault keystore: more than one authentication method specified")
 		}
-		if y.KeyStore.Azure.KeyVault.Credentials != nil {
-			if y.KeyStore.Azure.KeyVault.Credentials.TenantID.Value == "" {
+		if y.Azure.KeyVault.Credentials != nil {
+			if y.Azure.KeyVault.Credentials.TenantID.Value == "" {
 				return nil, errors.New("edge: invalid Azure keyvault keystore: no tenant ID specified")
 			}
-			if y.KeyStore.Azure.KeyVault.Credentials.ClientID.Value == "" {
+			if y.Azure.KeyVault.Credentials.ClientID.Value == "" {
 				return nil, errors.New("edge: invalid Azure keyvault keystore: no client ID specified")
 			}
-			if y.KeyStore.Azure.KeyVault.Credentials.Secret.Value == "" {
+			if y.Azure.KeyVault.Credentials.Secret.Value == "" {
 				return nil, errors.New("edge: invalid Azure keyvault keystore: no client secret specified")
 			}
 		}
-		if y.KeyStore.Azure.KeyVault.ManagedIdentity != nil {
-			if y.KeyStore.Azure.KeyVault.ManagedIdentity.ClientID.Value == "" {
+		if y.Azure.KeyVault.ManagedIdentity != nil {
+			if y.Azure.KeyVault.ManagedIdentity.ClientID.Value == "" {
 				return nil, errors.New("edge: invalid Azure keyvault keystore: no client ID specified")
 			}
 		}
 		s := &AzureKeyVaultKeyStore{
-			Endpoint: y.KeyStore.Azure.KeyVault.Endpoint.Value,
+			Endpoint:    y.Azure.KeyVault.Endpoint.Value,
+			ManagedHSM:  y.Azure.KeyVault.ManagedHSM.Value,
+			WrappingKey: y.Azure.KeyVault.WrappingKey.Value,
+		}
+		if y.Azure.KeyVault.Credentials != nil {
+			s.TenantID = y.Azure.KeyVault.Credentials.TenantID.Value
+			s.ClientID = y.Azure.KeyVault.Credentials.ClientID.Value
+			s.ClientSecret = y.Azure.KeyVault.Credentials.Secret.Value
+		}
+		if y.Azure.KeyVault.ManagedIdentity != nil {
+			s.ManagedIdentityClientID = y.Azure.KeyVault.ManagedIdentity.ClientID.Value
+		}
+		keystore = s
+	}
+
+	// Alibaba Cloud Secrets Manager
+	if y.Alibaba != nil && y.Alibaba.SecretsManager != nil {
+		if keystore != nil {
+			return nil, errors.New("edge: invalid keystore config: more than once keystore specified")
+		}
+		sm := y.Alibaba.SecretsManager
+		if sm.Region.Value == "" {
+			return nil, errors.New("edge: invalid alibaba:secretsmanager keystore: no region specified")
 		}
-		if y.KeyStore.Azure.KeyVault.Credentials != nil {
-			s.TenantID = y.KeyStore.Azure.KeyVault.Credentials.TenantID.Value
-			s.ClientID = y.KeyStore.Azure.KeyVault.Credentials.ClientID.Value
-			s.ClientSecret = y.KeyStore.Azure.KeyVault.Credentials.Secret.Value
+		if sm.Login == nil && sm.ECSRAMRole.Value == "" {
+			return nil, errors.New("edge: invalid alibaba:secretsmanager keystore: no authentication method specified")
 		}
-		if y.KeyStore.Azure.KeyVault.ManagedIdentity != nil {
-			s.ManagedIdentityClientID = y.KeyStore.Azure.KeyVault.ManagedIdentity.ClientID.Value
+		if sm.Login != nil && sm.ECSRAMRole.Value != "" {
+			return nil, errors.New("edge: invalid alibaba:secretsmanager keystore: more than one authentication method specified")
+		}
+		s := &AlibabaSecretsManagerKeyStore{
+			Region:     sm.Region.Value,
+			Endpoint:   sm.Endpoint.Value,
+			KMSKeyID:   sm.KMSKeyID.Value,
+			ECSRAMRole: sm.ECSRAMRole.Value,
+		}
+		if sm.Login != nil {
+			if sm.Login.AccessKeyID.Value == "" {
+				return nil, errors.New("edge: invalid alibaba:secretsmanager keystore: no access key ID specified")
+			}
+			if sm.Login.AccessKeySecret.Value == "" {
+				return nil, errors.New("edge: invalid alibaba:secretsmanager keystore: no access key secret specified")
+			}
+			s.AccessKeyID = sm.Login.AccessKeyID.Value
+			s.AccessKeySecret = sm.Login.AccessKeySecret.Value
+		}
+		if sm.RAMRole != nil {
+			if sm.RAMRole.ARN.Value == "" {
+				return nil, errors.New("edge: invalid alibaba:secretsmanager keystore: no RAM role ARN specified")
+			}
+			s.RoleARN = sm.RAMRole.ARN.Value
+			s.RoleSessionName = sm.RAMRole.SessionName.Value
 		}
 		keystore = s
 	}
 
+	// OCI Vault
+	if y.OCI != nil && y.OCI.Vault != nil {
+		if keystore != nil {
+			return nil, errors.New("edge: invalid keystore config: more than once keystore specified")
+		}
+		vault := y.OCI.Vault
+		if vault.CompartmentID.Value == "" {
+			return nil, errors.New("edge: invalid OCI vault keystore: no compartment ID specified")
+		}
+		if vault.VaultID.Value == "" {
+			return nil, errors.New("edge: invalid OCI vault keystore: no vault ID specified")
+		}
+		if vault.KeyID.Value == "" {
+			return nil, errors.New("edge: invalid OCI vault keystore: no master encryption key ID specified")
+		}
+		if vault.DeletionPeriod.Value != 0 && vault.DeletionPeriod.Value < 24*time.Hour {
+			return nil, errors.New("edge: invalid OCI vault keystore: deletion period must be at least 24h")
+		}
+		var methods int
+		if vault.Credentials != nil {
+			methods++
+		}
+		if vault.ConfigFile != nil {
+			methods++
+		}
+		if vault.InstancePrincipal.Value {
+			methods++
+		}
+		if methods == 0 {
+			return nil, errors.New("edge: invalid OCI vault keystore: no authentication method specified")
+		}
+		if methods > 1 {
+			return nil, errors.New("edge: invalid OCI vault keystore: more than one authentication method specified")
+		}
+		s := &OCIVaultKeyStore{
+			Region:            vault.Region.Value,
+			CompartmentID:     vault.CompartmentID.Value,
+			VaultID:           vault.VaultID.Value,
+			KeyID:             vault.KeyID.Value,
+			DeletionPeriod:    vault.DeletionPeriod.Value,
+			InstancePrincipal: vault.InstancePrincipal.Value,
+		}
+		if vault.Credentials != nil {
+			if vault.Region.Value == "" {
+				return nil, errors.New("edge: invalid OCI vault keystore: no region specified")
+			}
+			if vault.Credentials.TenancyID.Value == "" {
+				return nil, errors.New("edge: invalid OCI vault keystore: no tenancy ID specified")
+			}
+			if vault.Credentials.UserID.Value == "" {
+				return nil, errors.New("edge: invalid OCI vault keystore: no user ID specified")
+			}
+			if vault.Credentials.Fingerprint.Value == "" {
+				return nil, errors.New("edge: invalid OCI vault keystore: no API key fingerprint specified")
+			}
+			if vault.Credentials.PrivateKey.Value == "" {
+				return nil, errors.New("edge: invalid OCI vault keystore: no API signing key specified")
+			}
+			s.TenancyID = vault.Credentials.TenancyID.Value
+			s.UserID = vault.Credentials.UserID.Value
+			s.Fingerprint = vault.Credentials.Fingerprint.Value
+			s.PrivateKey = vault.Credentials.PrivateKey.Value
+		}
+		if vault.ConfigFile != nil {
+			if vault.ConfigFile.Path.Value == "" {
+				return nil, errors.New("edge: invalid OCI vault keystore: no config file path specified")
+			}
+			s.ConfigFile = vault.ConfigFile.Path.Value
+			s.Profile = vault.ConfigFile.Profile.Value
+		}
+		keystore = s
+	}
+
+	// PKCS #11
+	if y.PKCS11 != nil {
+		if keystore != nil {
+			return nil, errors.New("edge: invalid keystore config: more than once keystore specified")
+		}
+		if y.PKCS11.Module.Value == "" {
+			return nil, errors.New("edge: invalid pkcs11 keystore: no module specified")
+		}
+		if y.PKCS11.PIN.Value == "" {
+			return nil, errors.New("edge: invalid pkcs11 keystore: no PIN specified")
+		}
+		if y.PKCS11.WrappingKey.Value == "" {
+			return nil, errors.New("edge: invalid pkcs11 keystore: no wrapping key specified")
+		}
+		keystore = &PKCS11KeyStore{
+			Module:      y.PKCS11.Module.Value,
+			Slot:        y.PKCS11.Slot.Value,
+			TokenLabel:  y.PKCS11.TokenLabel.Value,
+			PIN:         y.PKCS11.PIN.Value,
+			WrappingKey: y.PKCS11.WrappingKey.Value,
+			Application: y.PKCS11.Application.Value,
+		}
+	}
+
 	if keystore == nil {
 		return nil, errors.New("edge: no keystore specified")
 	}
 	return keystore, nil
 }
 
+// env is a YAML value that may refer to its actual value:
+//   - "${VAR}" is replaced by the value of the env. variable VAR.
+//   - "env://VAR" is replaced by the value of the env. variable VAR.
+//   - "file://PATH" is replaced by the content of the file at PATH
+//     without a trailing newline.
+//
+// Otherwise, any "${VAR}" within the value is replaced by the
+// value of the env. variable VAR.
 type env[T any] struct {
-	Var   string
+	Var   string // Name of the env. variable for "${VAR}" values
+	Ref   string // Original value for all other references
 	Value T
 }
 
 func (r env[T]) MarshalYAML() (any, error) {
+	if r.Ref != "" {
+		return r.Ref, nil
+	}
 	if env := strings.TrimSpace(r.Var); env != "" {
 		switch p, s := strings.HasPrefix(env, "${"), strings.HasSuffix(env, "}"); {
 		case p && s:
@@ -623,14 +2308,44 @@ func (r env[T]) MarshalYAML() (any, error) {
 }
 
 func (r *env[T]) UnmarshalYAML(node *yaml.Node) error {
-	var env string
-	if v := strings.TrimSpace(node.Value); strings.HasPrefix(v, "${") && strings.HasSuffix(v, "}") {
-		env = strings.TrimSpace(v[2 : len(v)-1])
-		v, ok := os.LookupEnv(env)
-		if !ok {
-			return fmt.Errorf("edge: line '%d' in YAML document: referenced env. variable '%s' not found", node.Line, env)
+	var env, ref string
+	if node.Kind == yaml.ScalarNode {
+		switch v := strings.TrimSpace(node.Value); {
+		case strings.HasPrefix(v, "${") && strings.HasSuffix(v, "}") && strings.Count(v, "${") == 1:
+			env = strings.TrimSpace(v[2 : len(v)-1])
+			v, ok := os.LookupEnv(env)
+			if !ok {
+				return fmt.Errorf("edge: line '%d' in YAML document: referenced env. variable '%s' not found", node.Line, env)
+			}
+			node.Value = v
+		case strings.HasPrefix(v, "env://"):
+			ref = v
+			v, ok := os.LookupEnv(strings.TrimPrefix(v, "env://"))
+			if !ok {
+				return fmt.Errorf("edge: line '%d' in YAML document: referenced env. variable '%s' not found", node.Line, strings.TrimPrefix(ref, "env://"))
+			}
+			node.Value = v
+		case strings.HasPrefix(v, "file://"):
+			ref = v
+			b, err := os.ReadFile(strings.TrimPrefix(v, "file://"))
+			if err != nil {
+				return fmt.Errorf("edge: line '%d' in YAML document: failed to read referenced file: %v", node.Line, err)
+			}
+			node.Value = strings.TrimSuffix(strings.TrimSuffix(string(b), "\n"), "\r")
+		case strings.Contains(v, "${"):
+			ref = node.Value
+			v, err := expandEnv(node.Value)
+			if err != nil {
+				return fmt.Errorf("edge: line '%d' in YAML document: %v", node.Line, err)
+			}
+			node.Value = v
+		}
+		// The replaced value may not be a string, e.g. a number
+		// referenced by an env. variable. Let the decoder resolve
+		// its type unless the value is a string.
+		if (env != "" || ref != "") && reflect.TypeOf((*T)(nil)).Elem().Kind() != reflect.String {
+			node.Tag, node.Style = "", 0
 		}
-		node.Value = v
 	}
 
 	var v T
@@ -638,6 +2353,163 @@ func (r *env[T]) UnmarshalYAML(node *yaml.Node) error {
 		return err
 	}
 	r.Var = env
+	r.Ref = ref
 	r.Value = v
 	return nil
 }
+
+// expandEnv replaces every "${VAR}" within s with the
+// value of the env. variable VAR. It returns an error
+// if a referenced env. variable does not exist.
+func expandEnv(s string) (string, error) {
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		j := strings.IndexByte(s[i:], '}')
+		if j < 0 {
+			return "", fmt.Errorf("unterminated env. variable reference '%s'", s[i:])
+		}
+		name := strings.TrimSpace(s[i+2 : i+j])
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("referenced env. variable '%s' not found", name)
+		}
+		b.WriteString(s[:i])
+		b.WriteString(v)
+		s = s[i+j+1:]
+	}
+}
+
+// parseTLSVersion parses a TLS version, like "1.2" or
+// "1.3". It returns 0 for an empty string.
+// ymlToTLSPolicy parses the TLS min. version and cipher suites.
+// Only secure TLS 1.2 cipher suites can be configured and, if
+// the FIPS mode is enabled, only FIPS compliant ones. Cipher
+// suites cannot be configured if TLS 1.2 is not accepted.
+func ymlToTLSPolicy(version env[string], cipherSuites []env[string]) (uint16, []uint16, error) {
+	minVersion, err := parseTLSVersion(version.Value)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(cipherSuites) == 0 {
+		return minVersion, nil, nil
+	}
+	if minVersion == tls.VersionTLS13 {
+		return 0, nil, errors.New("cipher suites cannot be configured for TLS 1.3")
+	}
+
+	suites := make([]uint16, 0, len(cipherSuites))
+	for _, name := range cipherSuites {
+		id, err := parseCipherSuite(name.Value)
+		if err != nil {
+			return 0, nil, err
+		}
+		for _, s := range suites {
+			if s == id {
+				return 0, nil, fmt.Errorf("cipher suite '%s' is specified multiple times", name.Value)
+			}
+		}
+		suites = append(suites, id)
+	}
+	return minVersion, suites, nil
+}
+
+// ymlToClientCAs returns the paths of the client CA bundles.
+func ymlToClientCAs(clientCAs []env[string]) ([]string, error) {
+	if len(clientCAs) == 0 {
+		return nil, nil
+	}
+	paths := make([]string, 0, len(clientCAs))
+	for _, ca := range clientCAs {
+		if ca.Value == "" {
+			return nil, errors.New("invalid client CA: empty path")
+		}
+		paths = append(paths, ca.Value)
+	}
+	return paths, nil
+}
+
+// parseCipherSuite returns the ID of the TLS 1.2 cipher suite
+// with the given IANA name, e.g. "TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384".
+func parseCipherSuite(name string) (uint16, error) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	for _, suite := range tls.InsecureCipherSuites() {
+		if suite.Name == name {
+			return 0, fmt.Errorf("cipher suite '%s' is insecure", name)
+		}
+	}
+	for _, suite := range tls.CipherSuites() {
+		if suite.Name != name {
+			continue
+		}
+
+		var tls12 bool
+		for _, v := range suite.SupportedVersions {
+			tls12 = tls12 || v == tls.VersionTLS12
+		}
+		if !tls12 {
+			return 0, fmt.Errorf("cipher suite '%s' is a TLS 1.3 cipher suite: TLS 1.3 cipher suites cannot be configured", name)
+		}
+		for _, id := range fips.TLSCiphers() {
+			if id == suite.ID {
+				return suite.ID, nil
+			}
+		}
+		return 0, fmt.Errorf("cipher suite '%s' is not supported in FIPS mode", name)
+	}
+	return 0, fmt.Errorf("unknown cipher suite '%s'", name)
+}
+
+func parseTLSVersion(s string) (uint16, error) {
+	switch strings.TrimSpace(strings.TrimPrefix(strings.ToLower(strings.TrimSpace(s)), "tls")) {
+	case "":
+		return 0, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("invalid TLS version '%s': must be either '1.2' or '1.3'", s)
+	}
+}
+
+// verifyYMLPolicies returns an error if any identity of the
+// policies is the admin or a TLS proxy identity.
+func verifyYMLPolicies(y *yml, policies map[string]ymlPolicy) error {
+	for name, policy := range policies {
+		for _, identity := range policy.Identities {
+			if identity.Value == y.Admin.Identity.Value {
+				return fmt.Errorf("edge: invalid policy '%s': identity '%s' is already admin", name, identity.Value)
+			}
+			for _, proxy := range y.TLS.Proxy.Identities {
+				if identity.Value == proxy.Value {
+					return fmt.Errorf("edge: invalid policy '%s': identity '%s' is already a TLS proxy", name, identity.Value)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func ymlToPolicies(policies map[string]ymlPolicy) map[string]Policy {
+	if len(policies) == 0 {
+		return nil
+	}
+	c := make(map[string]Policy, len(policies))
+	for name, policy := range policies {
+		identities := make([]kes.Identity, 0, len(policy.Identities))
+		for _, id := range policy.Identities {
+			identities = append(identities, id.Value)
+		}
+		c[name] = Policy{
+			Allow:      policy.Allow,
+			Deny:       policy.Deny,
+			Identities: identities,
+		}
+	}
+	return c
+}
@@ -10,14 +10,10 @@ import (
 	"time"
 
 	"github.com/minio/kes-go"
-	"github.com/minio/kes/internal/keystore/aws"
-	"github.com/minio/kes/internal/keystore/azure"
-	"github.com/minio/kes/internal/keystore/fortanix"
 	"github.com/minio/kes/internal/keystore/fs"
-	"github.com/minio/kes/internal/keystore/gcp"
-	"github.com/minio/kes/internal/keystore/gemalto"
 	kesstore "github.com/minio/kes/internal/keystore/kes"
 	"github.com/minio/kes/internal/keystore/vault"
+	"github.com/minio/kes/internal/sys"
 	"github.com/minio/kes/kv"
 )
 
@@ -38,36 +34,215 @@ type ServerConfig struct {
 	// interface.
 	Addr string
 
+	// Listeners are the network addresses the KES server
+	// listens on, each with an optional TLS configuration.
+	// If empty, the KES server listens on Addr only.
+	Listeners []Listener
+
+	// ProbeAddr is an optional TCP address of a plain HTTP
+	// listener that only serves the /healthz and /readyz
+	// probes. Orchestrators can probe the server on this
+	// address without a client certificate while the TLS
+	// listeners keep requiring one. If empty, the probes
+	// are only served by the TLS listeners.
+	ProbeAddr string
+
 	// Admin is the KES server admin identity.
 	Admin kes.Identity
 
+	// ReadOnly controls whether the KES server starts in
+	// read-only mode. In read-only mode, the server rejects
+	// requests that modify keys, policies or identities but
+	// still serves crypto operations.
+	ReadOnly bool
+
 	// TLS contains the KES server TLS configuration.
 	TLS *TLSConfig
 
 	// Cache contains the KES server cache configuration.
 	Cache *CacheConfig
 
+	// KeyExpiry contains the KES server key expiry configuration.
+	KeyExpiry *KeyExpiryConfig
+
 	// Log contains the KES server logging configuration.
 	Log *LogConfig
 
 	API *APIConfig
 
+	// Crypto contains the KES server crypto configuration.
+	Crypto *CryptoConfig
+
+	// Receipts contains the decrypt receipt configuration.
+	// If nil, decrypt responses do not contain receipts.
+	Receipts *ReceiptConfig
+
+	// RateLimit contains the KES server rate limit
+	// configuration. If nil, requests are not limited.
+	RateLimit *RateLimitConfig
+
+	// Limits contains the KES server request size and
+	// concurrency limits. If nil, requests are not limited.
+	Limits *LimitConfig
+
+	// Shutdown contains the KES server shutdown configuration.
+	// If nil, in-flight requests are drained for 1 second.
+	Shutdown *ShutdownConfig
+
 	// Policies contains the KES server policy definitions
 	// and statical identity assignments.
 	Policies map[string]Policy
 
+	// PolicyHooks contains WebAssembly policy hooks that
+	// are evaluated, in order, once a request is allowed
+	// by the policy of its identity.
+	PolicyHooks []PolicyHook
+
 	// Keys contains pre-defined keys that the KES server will
 	// either create, or expect to exist, before accepting requests.
 	Keys []Key
 
+	// KeyPools contains key pools from which clients can
+	// claim keys that the KES server pre-creates in the
+	// background.
+	KeyPools []KeyPool
+
 	// KeyStore contains the KES server keystore configuration.
 	// The KeyStore manages the keys used by the KES server for
 	// encryption and decryption.
 	KeyStore KeyStore
 
+	// KeyStoreReplicas are optional read-only replicas of
+	// the KeyStore, like Vault performance standbys. Read
+	// requests are distributed across the replicas while
+	// requests that modify keys are sent to the KeyStore.
+	KeyStoreReplicas []KeyStore
+
+	// KeyStoreMirror is an optional KeyStore to which the
+	// KES server mirrors any modification of the KeyStore.
+	// It allows migrating from one KeyStore to another
+	// without downtime. Read requests are not sent to the
+	// mirror.
+	KeyStoreMirror KeyStore
+
+	// KeyStoreConnect controls how the KES server connects
+	// to its KeyStore at startup.
+	KeyStoreConnect *ConnectConfig
+
+	// KeyStoreRetry controls whether and how the KES server
+	// retries KeyStore requests that fail due to transient
+	// errors. If nil, requests are not retried.
+	KeyStoreRetry *RetryConfig
+
+	// KeyStoreCircuitBreaker is an optional circuit breaker
+	// configuration for the KeyStore. If nil, no circuit
+	// breaker is used.
+	KeyStoreCircuitBreaker *CircuitBreakerConfig
+
+	// KeyStoreCompression is the compression algorithm applied
+	// to keys before they are written to the KeyStore, its
+	// replicas and its mirror. Uncompressed keys are read as
+	// they are.
+	KeyStoreCompression sys.Compression
+
+	// Enclaves contains enclaves, by name, with a separate
+	// keystore. Requests for any of these enclaves are
+	// served by the enclave's keystore instead of KeyStore.
+	Enclaves map[string]*EnclaveConfig
+
 	_ [0]int // force usage of struct composite literals with field names
 }
 
+// EnclaveConfig is a structure that holds the configuration
+// of an enclave with its own keystore.
+type EnclaveConfig struct {
+	// KeyStore contains the enclave's keystore configuration.
+	KeyStore KeyStore
+
+	// KeyStoreReplicas are optional read-only replicas
+	// of the enclave's KeyStore.
+	KeyStoreReplicas []KeyStore
+
+	// KeyStoreMirror is an optional mirror of the
+	// enclave's KeyStore.
+	KeyStoreMirror KeyStore
+
+	// KeyStoreConnect controls how the KES server connects
+	// to the enclave's KeyStore at startup.
+	KeyStoreConnect *ConnectConfig
+
+	// KeyStoreRetry controls whether and how the KES server
+	// retries requests to the enclave's KeyStore.
+	KeyStoreRetry *RetryConfig
+
+	// KeyStoreCircuitBreaker is an optional circuit breaker
+	// configuration for the enclave's KeyStore.
+	KeyStoreCircuitBreaker *CircuitBreakerConfig
+
+	// KeyStoreCompression is the compression algorithm applied
+	// to keys before they are written to the enclave's KeyStore.
+	KeyStoreCompression sys.Compression
+
+	// Policies contains the enclave's policies, by name,
+	// and the identities assigned to them. Policies and
+	// identities of other enclaves do not apply to the
+	// enclave. Only the admin has access to all enclaves.
+	Policies map[string]Policy
+
+	_ [0]int
+}
+
+// Listener is a network address, either a TCP address or a
+// UNIX domain socket, a KES server listens on.
+type Listener struct {
+	// Network is the listener's network. It is either "tcp"
+	// or "unix".
+	Network string
+
+	// Addr is either a TCP address, like "0.0.0.0:7373",
+	// or the path of a UNIX domain socket.
+	Addr string
+
+	// TLS is an optional TLS configuration for the listener.
+	// If nil, the listener uses the server's TLS configuration.
+	TLS *ListenerTLSConfig
+}
+
+// ListenerTLSConfig is a structure that holds the TLS
+// configuration of a listener.
+type ListenerTLSConfig struct {
+	// PrivateKey is the path to the listener's TLS private key.
+	PrivateKey string
+
+	// Certificate is the path to the listener's TLS certificate.
+	Certificate string
+
+	// Password is an optional password to decrypt the
+	// listener's private key.
+	Password string
+
+	// CAPath is an optional path to a X.509 certificate or
+	// directory containing X.509 certificates that the KES
+	// server uses as authorities when verifying certificates
+	// of clients connecting to the listener.
+	CAPath string
+
+	// MinVersion is the minimum TLS version the listener
+	// accepts. If 0, the server's MinVersion is used.
+	MinVersion uint16
+
+	// CipherSuites are the TLS 1.2 cipher suites the listener
+	// accepts. If empty, the server's CipherSuites are used.
+	CipherSuites []uint16
+
+	// ClientCAPaths are optional paths to CA bundles that the
+	// KES server uses, in addition to CAPath, as authorities
+	// when verifying certificates of clients connecting to
+	// the listener. If empty, the server's ClientCAPaths are
+	// used.
+	ClientCAPaths []string
+}
+
 // TLSConfig is a structure that holds the TLS configuration
 // for a KES server.
 type TLSConfig struct {
@@ -90,6 +265,29 @@ type TLSConfig struct {
 	// certificates.
 	CAPath string
 
+	// MinVersion is the minimum TLS version the KES server
+	// accepts. It is either tls.VersionTLS12 or tls.VersionTLS13.
+	// If 0, defaults to TLS 1.2.
+	MinVersion uint16
+
+	// CipherSuites are the TLS 1.2 cipher suites the KES server
+	// accepts. TLS 1.3 cipher suites are not configurable. If
+	// empty, a default list of secure cipher suites is used.
+	CipherSuites []uint16
+
+	// ClientCAPaths are optional paths to CA bundles, files or
+	// directories, that the KES server uses, in addition to
+	// CAPath, as authorities when verifying client certificates.
+	ClientCAPaths []string
+
+	// ReloadInterval is the interval at which the KES server
+	// checks whether its TLS private key, certificate or CA
+	// certificates, including those of its listeners, have
+	// changed. If so, the server reloads its TLS configuration.
+	//
+	// If <= 0, defaults to 30 seconds.
+	ReloadInterval time.Duration
+
 	// Proxies contains a list of TLS proxy identities.
 	// The KES identity of any TLS/HTTPS proxy sitting directly
 	// in-front of KES has to be included in this list. A KES
@@ -102,6 +300,219 @@ type TLSConfig struct {
 	// to KES.
 	ForwardCertHeader string
 
+	// APIKeys controls whether clients may authenticate
+	// with an API key, sent as bearer token, instead of
+	// a TLS client certificate. The identity of such a
+	// client is the identity of its API key.
+	APIKeys bool
+
+	// OIDC is an optional OpenID Connect configuration.
+	// If set, clients may authenticate with a JWT issued
+	// by the OIDC provider, sent as bearer token, instead
+	// of a TLS client certificate.
+	OIDC *OIDCConfig
+
+	// SPIFFE is an optional SPIFFE configuration. If set,
+	// the identity of a client certificate with a SPIFFE
+	// ID of a trusted domain is its SPIFFE ID instead of
+	// the hash of its public key.
+	SPIFFE *SPIFFEConfig
+
+	// ClientPolicy is an optional policy that specifies
+	// the minimum TLS version and client certificate key
+	// strength required from clients.
+	ClientPolicy *ClientTLSConfig
+
+	// CertManager is an optional cert-manager configuration.
+	// If set, the KES server loads its TLS private key and
+	// certificate, and optionally its CA certificate, from
+	// the referenced Kubernetes secret instead of the
+	// PrivateKey and Certificate files.
+	CertManager *CertManagerConfig
+
+	// ACME is an optional ACME configuration. If set, the
+	// KES server obtains and renews its TLS certificate from
+	// an ACME CA, like Let's Encrypt, instead of loading the
+	// PrivateKey and Certificate files.
+	ACME *ACMEConfig
+
+	// Revocation is an optional configuration for checking
+	// whether client certificates have been revoked.
+	Revocation *RevocationConfig
+
+	_ [0]int
+}
+
+// CertManagerConfig is a structure that holds the configuration
+// for loading TLS credentials from a Kubernetes TLS secret issued
+// by cert-manager.
+type CertManagerConfig struct {
+	// Secret is the name of the Kubernetes TLS secret.
+	// It must contain a "tls.key" and "tls.crt" entry
+	// and may contain a "ca.crt" entry.
+	Secret string
+
+	// Namespace is the Kubernetes namespace of the secret.
+	// If empty, the namespace of the KES pod is used.
+	Namespace string
+
+	// ReloadInterval is the interval at which the KES server
+	// checks whether the secret has been updated. If so, the
+	// server reloads its TLS credentials.
+	//
+	// If <= 0, defaults to 30 seconds.
+	ReloadInterval time.Duration
+
+	_ [0]int
+}
+
+// ACMEConfig is a structure that holds the configuration
+// for obtaining TLS certificates from an ACME CA.
+type ACMEConfig struct {
+	// Domains are the domain names the KES server obtains
+	// certificates for. The KES server rejects TLS connections
+	// for any other domain.
+	Domains []string
+
+	// Email is an optional contact email address of the
+	// ACME account. The CA may use it to notify about
+	// certificate expiry or account problems.
+	Email string
+
+	// Directory is the ACME directory URL of the CA.
+	// If empty, Let's Encrypt is used.
+	Directory string
+
+	// CacheDir is the directory where the ACME account key
+	// and the certificates are stored such that they don't
+	// have to be obtained again on restart.
+	CacheDir string
+
+	// Challenge is the ACME challenge type. It is either
+	// "tls-alpn-01" or "http-01". If empty, "tls-alpn-01"
+	// is used.
+	//
+	// The "tls-alpn-01" challenge requires that the CA can
+	// reach the KES server on port 443.
+	Challenge string
+
+	// HTTPAddr is the address the KES server listens on for
+	// "http-01" challenges. The CA sends challenges to port 80.
+	// If empty, defaults to "0.0.0.0:80".
+	HTTPAddr string
+
+	_ [0]int
+}
+
+// RevocationConfig is a structure that holds the configuration
+// for checking whether client certificates have been revoked,
+// either via CRLs, OCSP or both.
+type RevocationConfig struct {
+	// CRLs are paths or http(s) URLs of certificate revocation
+	// lists. A client certificate is checked against all CRLs
+	// issued by its CA.
+	CRLs []string
+
+	// OCSP controls whether the KES server queries an OCSP
+	// responder for the revocation status of client certificates.
+	OCSP bool
+
+	// OCSPResponder is an optional OCSP responder URL. If empty,
+	// the responder specified by the client certificate is used.
+	OCSPResponder string
+
+	// CacheTTL is the time period CRLs and OCSP responses are
+	// cached, at most. If 0, defaults to 5 minutes.
+	CacheTTL time.Duration
+
+	// FailOpen controls whether client certificates are accepted
+	// if their revocation status cannot be determined.
+	FailOpen bool
+
+	// CRLSoftFail controls whether CRLs that cannot be fetched
+	// or have expired are skipped instead of rejecting client
+	// certificates issued by the CRL's issuer.
+	CRLSoftFail bool
+
+	_ [0]int
+}
+
+// OIDCConfig is a structure that holds the configuration
+// for authenticating clients with JWTs issued by an OpenID
+// Connect provider.
+type OIDCConfig struct {
+	// Issuer is the URL of the OIDC provider. The server
+	// only accepts JWTs issued by this provider.
+	Issuer string
+
+	// Audience is the audience JWTs must be issued for.
+	Audience string
+
+	// JWKSURL is an optional URL of the provider's JSON
+	// Web Key Set. If empty, it is discovered from the
+	// issuer's OpenID configuration.
+	JWKSURL string
+
+	// IdentityClaim is the JWT claim whose value is the
+	// client's KES identity, prefixed with the issuer as
+	// "oidc:<issuer>:<claim>". If empty, defaults to "sub".
+	IdentityClaim string
+
+	// PolicyClaim is an optional JWT claim whose value is
+	// the name of the policy the client is assigned to.
+	PolicyClaim string
+
+	_ [0]int
+}
+
+// SPIFFEConfig is a structure that holds the configuration
+// for mapping X.509-SVIDs to identities.
+type SPIFFEConfig struct {
+	// TrustDomains is the list of trusted SPIFFE domains,
+	// like "example.org".
+	TrustDomains []string
+
+	_ [0]int
+}
+
+// ClientTLSConfig is a structure that holds the minimum
+// TLS requirements for client connections.
+type ClientTLSConfig struct {
+	// MinVersion is the minimum TLS version. If zero,
+	// any TLS version supported by the server is accepted.
+	MinVersion uint16
+
+	// MinRSAKeySize is the minimum size of RSA client
+	// certificate keys in bits.
+	MinRSAKeySize int
+
+	// Curves are the accepted elliptic curves for client
+	// certificate keys. If empty, all curves are accepted
+	// except the ones considered weak.
+	Curves []string
+
+	// Enforce controls whether connections that do not
+	// meet the requirements are rejected or just reported.
+	Enforce bool
+
+	_ [0]int
+}
+
+// ConnectConfig is a structure that holds the configuration
+// for connecting to the KeyStore at startup.
+type ConnectConfig struct {
+	// Lazy controls whether the KES server starts serving
+	// requests before it has connected to the KeyStore.
+	// Until connected, the KES server is not ready and
+	// requests that access the KeyStore fail.
+	Lazy bool
+
+	// Retry is the duration the KES server keeps retrying,
+	// with an exponential backoff, to connect to the
+	// KeyStore before giving up. If zero, the KES server
+	// does not retry.
+	Retry time.Duration
+
 	_ [0]int
 }
 
@@ -131,6 +542,52 @@ type CacheConfig struct {
 	// cache expiry periods apply.
 	ExpiryOffline time.Duration
 
+	// KeyStore is an optional configuration of the
+	// keystore cache. If not nil, the KES server keeps
+	// recently used keystore entries in memory and
+	// remembers keys that do not exist at the keystore.
+	KeyStore *KeyStoreCacheConfig
+
+	_ [0]int
+}
+
+// KeyStoreCacheConfig is a structure containing the
+// configuration of the keystore cache.
+type KeyStoreCacheConfig struct {
+	// Size is the maximum number of keystore entries
+	// kept in memory. Once the cache is full, the least
+	// recently used entry is evicted.
+	//
+	// If zero, the number of entries is not limited.
+	Size int
+
+	// Expiry is the time period after which cached
+	// keystore entries are discarded.
+	//
+	// If zero, entries are only discarded once they
+	// are evicted.
+	Expiry time.Duration
+
+	// NegativeExpiry is the time period the KES server
+	// remembers that a key does not exist at the keystore.
+	//
+	// If zero, non-existing keys are not cached.
+	NegativeExpiry time.Duration
+
+	_ [0]int
+}
+
+// KeyExpiryConfig is a structure that holds the key expiry
+// configuration for a KES server.
+type KeyExpiryConfig struct {
+	// DeleteInterval is the time period after which the KES
+	// server deletes all expired keys from the keystore.
+	//
+	// Expired keys cannot be used for any cryptographic
+	// operation. If DeleteInterval is zero, they remain
+	// at the keystore until they get deleted explicitly.
+	DeleteInterval time.Duration
+
 	_ [0]int
 }
 
@@ -145,6 +602,11 @@ type LogConfig struct {
 	// It does not en/disable audit logging in general.
 	Audit bool
 
+	// Stats is the path of the file the KES server saves its daily
+	// audit statistics to. If empty, audit statistics are kept in
+	// memory only and are lost when the server restarts.
+	Stats string
+
 	_ [0]int
 }
 
@@ -176,9 +638,151 @@ type APIPathConfig struct {
 	// like metrics.
 	InsecureSkipAuth bool
 
+	// RequireAuth controls whether the API verifies client
+	// identities even if it does not by default, like the
+	// /healthz and /readyz probes. It is true if the config
+	// file explicitly sets 'skip_auth: false'.
+	RequireAuth bool
+
+	_ [0]int
+}
+
+// ReceiptConfig is a structure that holds the configuration
+// for signing decrypt receipts.
+type ReceiptConfig struct {
+	// PrivateKey is the path to the private key used
+	// to sign receipts. Either an Ed25519, ECDSA or RSA
+	// private key.
+	PrivateKey string
+
+	// Password is an optional password to decrypt
+	// the private key.
+	Password string
+
+	_ [0]int
+}
+
+// CryptoConfig is a structure that holds the crypto configuration
+// for a KES server.
+type CryptoConfig struct {
+	// AEAD limits the number of concurrent encrypt and
+	// generate operations.
+	AEAD WorkerPoolConfig
+
+	// Unwrap limits the number of concurrent decrypt
+	// operations.
+	Unwrap WorkerPoolConfig
+
+	_ [0]int
+}
+
+// WorkerPoolConfig is a structure that holds the configuration
+// of a pool of workers that run crypto operations.
+type WorkerPoolConfig struct {
+	// Workers is the max. number of operations that run
+	// concurrently. If zero, the number of concurrent
+	// operations is not limited.
+	Workers int
+
+	// Queue is the max. number of operations that wait
+	// for a worker. Operations are rejected while the
+	// queue is full. If zero, the queue is not limited
+	// and operations wait until they time out.
+	Queue int
+
+	_ [0]int
+}
+
+// LimitConfig is a structure that holds the request size and
+// concurrency limits of a KES server.
+type LimitConfig struct {
+	// MaxBody is the max. size of a request body in bytes.
+	// APIs that accept only smaller request bodies keep their
+	// own limit. If 0, the API defaults are used.
+	MaxBody int64
+
+	// MaxRequests is the max. number of requests the server
+	// serves concurrently. If 0, the number of concurrent
+	// requests is not limited.
+	MaxRequests int
+
+	// Queue is the max. number of requests that wait until
+	// they can be served. If 0, the number of waiting requests
+	// is not limited.
+	Queue int
+
+	// QueueTimeout is the max. time a request waits until it
+	// can be served.
+	QueueTimeout time.Duration
+
 	_ [0]int
 }
 
+// ShutdownConfig is a structure that holds the graceful
+// shutdown configuration of a KES server.
+//
+// On shutdown, the server reports that it is not ready
+// anymore, waits for Delay and then stops accepting new
+// connections. In-flight requests are drained for at
+// most Timeout before all connections get closed.
+type ShutdownConfig struct {
+	// Delay is the time period the server keeps accepting
+	// new connections after it has reported that it is not
+	// ready anymore, e.g. to give a load balancer time to
+	// stop sending requests to it.
+	Delay time.Duration
+
+	// Timeout is the max. time period the server waits for
+	// in-flight requests to complete. If 0, defaults to 1
+	// second.
+	Timeout time.Duration
+
+	_ [0]int
+}
+
+// RateLimitConfig is a structure that holds the rate
+// limit configuration for a KES server.
+type RateLimitConfig struct {
+	// Rate is the number of requests per second each
+	// identity can send on average. If 0, identities
+	// without a rate limit of their own, or of their
+	// policy, are not limited.
+	Rate float64
+
+	// Burst is the max. number of requests each identity
+	// can send at once.
+	Burst int
+
+	// Identities contains the rate limits of individual
+	// identities. They take precedence over Policies and
+	// the Rate.
+	Identities map[kes.Identity]RateLimitRule
+
+	// Policies contains the rate limits of all identities
+	// assigned to a policy, by policy name. Each identity
+	// is limited separately.
+	Policies map[string]RateLimitRule
+
+	// Enclaves contains the rate limits of requests to
+	// an enclave, by enclave name. All identities share
+	// the enclave's rate limit.
+	Enclaves map[string]RateLimitRule
+
+	_ [0]int
+}
+
+// RateLimitRule is the rate limit of an identity,
+// policy or enclave.
+type RateLimitRule struct {
+	// Rate is the number of requests per second
+	// that can be sent on average.
+	Rate float64
+
+	// Burst is the max. number of requests that
+	// can be sent at once.
+	Burst int
+}
+
 // Policy is a structure defining a KES policy.
 //
 // Any request issued by a KES identity is validated
@@ -214,6 +818,42 @@ type Key struct {
 	_ [0]int
 }
 
+// PolicyHook is a structure defining a WebAssembly module
+// that decides whether requests get accepted.
+type PolicyHook struct {
+	// Name is the name of the policy hook.
+	Name string
+
+	// Module is the path to the WebAssembly module.
+	Module string
+
+	// Timeout is the maximum time the policy hook may
+	// take to decide. If zero, a default timeout is used.
+	Timeout time.Duration
+
+	_ [0]int
+}
+
+// KeyPool is a structure defining a pool of keys that
+// the KES server pre-creates in the background. Clients
+// claim keys from the pool instead of creating them.
+type KeyPool struct {
+	// Prefix is the name prefix of the pool's keys.
+	// It identifies the pool.
+	Prefix string
+
+	// Size is the number of unclaimed keys the KES
+	// server tries to keep available.
+	Size int
+
+	// Algorithm is the encryption algorithm of the
+	// pool's keys. If undefined, the KES server uses
+	// the fastest algorithm available.
+	Algorithm kes.KeyAlgorithm
+
+	_ [0]int
+}
+
 // KeyStore is a KES keystore configuration.
 //
 // Concrete instances implement Connect to return
@@ -227,7 +867,10 @@ type KeyStore interface {
 // FSKeyStore is a structure containing the configuration
 // for a simple filesystem keystore.
 //
-// A FSKeyStore should only be used when testing a KES server.
+// Without a KMS, keys are stored as plaintext files. Such
+// a FSKeyStore should only be used when testing a KES server.
+// With a KMS, each key is encrypted with its own data key that
+// is wrapped by the KMS.
 type FSKeyStore struct {
 	// Path is the path to the directory that
 	// contains the keys.
@@ -236,12 +879,88 @@ type FSKeyStore struct {
 	// will be created.
 	Path string
 
+	// AWSKMS is an optional AWS KMS configuration.
+	// If set, keys are encrypted with data keys
+	// wrapped by AWS KMS.
+	AWSKMS *AWSKMSConfig
+
+	// GCPKMS is an optional GCP Cloud KMS configuration.
+	// If set, keys are encrypted with data keys wrapped
+	// by GCP Cloud KMS.
+	GCPKMS *GCPKMSConfig
+
 	_ [0]int
 }
 
+// AWSKMSConfig is a structure containing the configuration
+// for wrapping data keys with AWS KMS.
+type AWSKMSConfig struct {
+	// Endpoint is an optional AWS KMS endpoint. If
+	// empty, the regional endpoint is used.
+	Endpoint string
+
+	// Region is the AWS region.
+	Region string
+
+	// KeyID is the ID, ARN or alias of the symmetric
+	// AWS KMS key.
+	KeyID string
+
+	// AccessKey is the access key for authenticating to AWS.
+	AccessKey string
+
+	// SecretKey is the secret key for authenticating to AWS.
+	SecretKey string
+
+	// SessionToken is an optional session token for
+	// authenticating to AWS.
+	SessionToken string
+}
+
+// GCPKMSConfig is a structure containing the configuration
+// for wrapping data keys with GCP Cloud KMS.
+type GCPKMSConfig struct {
+	// Endpoint is an optional GCP Cloud KMS endpoint.
+	Endpoint string
+
+	// Key is the resource name of the GCP Cloud KMS key.
+	Key string
+
+	// Scopes are GCP OAuth2 scopes for accessing
+	// GCP APIs. If empty, defaults to the GCP
+	// default scopes.
+	Scopes []string
+
+	// ClientEmail is the Client email of the
+	// GCP service account.
+	ClientEmail string
+
+	// ClientID is the Client ID of the GCP
+	// service account.
+	ClientID string
+
+	// KeyID is the private key ID of the GCP
+	// service account.
+	KeyID string
+
+	// PrivateKey is the private key of the GCP
+	// service account.
+	PrivateKey string
+}
+
 // Connect returns a kv.Store that stores key-value pairs in a path on the filesystem.
-func (s *FSKeyStore) Connect(context.Context) (kv.Store[string, []byte], error) {
-	return fs.NewStore(s.Path)
+func (s *FSKeyStore) Connect(ctx context.Context) (kv.Store[string, []byte], error) {
+	if s.AWSKMS != nil && s.GCPKMS != nil {
+		return nil, errors.New("edge: failed to connect to fs keystore: more than one KMS specified")
+	}
+	store, err := fs.NewStore(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	if s.AWSKMS == nil && s.GCPKMS == nil {
+		return store, nil
+	}
+	return s.wrap(ctx, store)
 }
 
 // KESKeyStore is a structure containing the configuration
@@ -286,6 +1005,100 @@ func (s *KESKeyStore) Connect(ctx context.Context) (kv.Store[string, []byte], er
 	})
 }
 
+// EtcdKeyStore is a structure containing the
+// configuration for an etcd cluster.
+type EtcdKeyStore struct {
+	// Endpoints is a set of etcd client endpoints.
+	//
+	// If multiple endpoints are provided, requests
+	// fail over to the next endpoint once one becomes
+	// unreachable.
+	Endpoints []string
+
+	// Prefix is an optional prefix of all etcd
+	// keys written by the KES server.
+	//
+	// If empty, defaults to "/kes/".
+	Prefix string
+
+	// Username is an optional etcd user name
+	// used to authenticate if etcd authentication
+	// is enabled.
+	Username string
+
+	// Password is the password of the etcd user.
+	Password string
+
+	// CertificateFile is an optional path to a mTLS
+	// client certificate file used to authenticate
+	// to etcd.
+	CertificateFile string
+
+	// PrivateKeyFile is an optional path to a mTLS
+	// private key used to authenticate to etcd.
+	PrivateKeyFile string
+
+	// CAPath is an optional path to the root
+	// CA certificate(s) for verifying the TLS
+	// certificates of etcd.
+	//
+	// If empty, the OS default root CA set is
+	// used.
+	CAPath string
+
+	// LeaseTTL is the TTL of the lease the KES
+	// server uses to register itself as member.
+	//
+	// If 0, defaults to 30 seconds.
+	LeaseTTL time.Duration
+
+	_ [0]int
+}
+
+// SQLKeyStore is a structure containing the
+// configuration for a PostgreSQL or MySQL database.
+type SQLKeyStore struct {
+	// Driver is the database driver. Either
+	// "postgres" or "mysql".
+	Driver string
+
+	// DSN is the driver-specific data source
+	// name used to connect to the database.
+	DSN string
+
+	// Table is an optional name of the table
+	// that contains the keys.
+	//
+	// If empty, defaults to "kes_keys".
+	Table string
+
+	// EncryptionKey is the 256 bit AES key
+	// used to encrypt keys before they are
+	// stored in the database.
+	EncryptionKey []byte
+
+	// MaxOpenConns is the maximum number of
+	// open database connections.
+	//
+	// If 0, the number of open connections
+	// is not limited.
+	MaxOpenConns int
+
+	// MaxIdleConns is the maximum number of
+	// idle database connections.
+	//
+	// If 0, defaults to 2.
+	MaxIdleConns int
+
+	// ConnMaxLifetime is the maximum amount
+	// of time a connection may be reused.
+	//
+	// If 0, connections are reused forever.
+	ConnMaxLifetime time.Duration
+
+	_ [0]int
+}
+
 // VaultKeyStore is a structure containing the configuration
 // for Hashicorp Vault.
 type VaultKeyStore struct {
@@ -299,7 +1112,9 @@ type VaultKeyStore struct {
 
 	// APIVersion is the API version of the Hashicorp Vault
 	// K/V engine. Valid values are: "v1" and "v2".
-	// If empty, defaults to "v1".
+	// If empty, the API version is discovered from the
+	// engine mount and defaults to "v1" if the mount
+	// information is not accessible.
 	APIVersion string
 
 	// Engine is the Hashicorp Vault K/V engine path.
@@ -312,6 +1127,16 @@ type VaultKeyStore struct {
 	// level.
 	Prefix string
 
+	// SoftDelete controls whether keys are deleted softly.
+	// If true, a deleted key can be recovered until it gets
+	// purged. It requires a K/V v2 engine.
+	SoftDelete bool
+
+	// CustomMetadata is an optional set of key-value pairs
+	// attached as custom metadata to every created key.
+	// It requires a K/V v2 engine.
+	CustomMetadata map[string]string
+
 	// AppRole contains the Vault AppRole authentication
 	// method credentials.
 	AppRole *VaultAppRoleAuth
@@ -320,6 +1145,15 @@ type VaultKeyStore struct {
 	// method credentials.
 	Kubernetes *VaultKubernetesAuth
 
+	// AWS contains the Vault AWS IAM authentication
+	// method configuration.
+	AWS *VaultAWSAuth
+
+	// Cert contains the Vault TLS certificate authentication
+	// method configuration. It requires a TLS client
+	// certificate and private key.
+	Cert *VaultCertAuth
+
 	// PrivateKey is an optional path to a
 	// TLS private key file containing a
 	// TLS private key for mTLS authentication.
@@ -342,11 +1176,24 @@ type VaultKeyStore struct {
 	// used.
 	CAPath string
 
+	// ServerName is an optional TLS server name
+	// (SNI) used to verify the TLS certificate of
+	// the Hashicorp Vault server.
+	//
+	// If empty, the endpoint host name is used.
+	ServerName string
+
 	// StatusPing controls how often to Vault health status
 	// is checked.
 	// If not set, defaults to 10s.
 	StatusPing time.Duration
 
+	// WatchInterval controls how often Vault is polled
+	// for keys changed by other KES servers such that
+	// stale keys get evicted from the key cache.
+	// If not set, keys are not watched.
+	WatchInterval time.Duration
+
 	_ [0]int
 }
 
@@ -381,14 +1228,65 @@ type VaultKubernetesAuth struct {
 	// the JWT for for authenticating via the kubernetes authentication
 	// method.
 	JWT string
+
+	// JWTFile is a path to a file containing the JWT, e.g. a
+	// projected service account token. In contrast to JWT,
+	// the file is re-read whenever KES re-authenticates such
+	// that rotated tokens are used.
+	JWTFile string
+}
+
+// VaultAWSAuth is a structure containing the configuration
+// for the Hashicorp Vault AWS IAM authentication method.
+type VaultAWSAuth struct {
+	// Engine is the AWS authentication engine path.
+	// If empty, defaults to "aws".
+	Engine string
+
+	// Role is the login role for authenticating via the
+	// AWS authentication method.
+	Role string
+
+	// ServerID is an optional value of the
+	// X-Vault-AWS-IAM-Server-ID header.
+	ServerID string
+
+	// Region is the AWS STS region. If empty,
+	// defaults to "us-east-1".
+	Region string
+
+	// AccessKey, SecretKey and SessionToken are optional
+	// static AWS credentials. If empty, the AWS credentials
+	// are fetched from the environment or the instance
+	// metadata service.
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+}
+
+// VaultCertAuth is a structure containing the configuration
+// for the Hashicorp Vault TLS certificate authentication method.
+type VaultCertAuth struct {
+	// Engine is the TLS certificate authentication engine path.
+	// If empty, defaults to "cert".
+	Engine string
+
+	// Name is an optional certificate role name.
+	Name string
 }
 
 // Connect returns a kv.Store that stores key-value pairs on a Hashicorp Vault server.
 func (s *VaultKeyStore) Connect(ctx context.Context) (kv.Store[string, []byte], error) {
-	if s.AppRole == nil && s.Kubernetes == nil {
+	var methods int
+	for _, ok := range []bool{s.AppRole != nil, s.Kubernetes != nil, s.AWS != nil, s.Cert != nil} {
+		if ok {
+			methods++
+		}
+	}
+	if methods == 0 {
 		return nil, errors.New("edge: failed to connect to hashicorp vault: no authentication method specified")
 	}
-	if s.AppRole != nil && s.Kubernetes != nil {
+	if methods > 1 {
 		return nil, errors.New("edge: failed to connect to hashicorp vault: more than one authentication method specified")
 	}
 	c := &vault.Config{
@@ -397,10 +1295,14 @@ func (s *VaultKeyStore) Connect(ctx context.Context) (kv.Store[string, []byte],
 		APIVersion:      s.APIVersion,
 		Namespace:       s.Namespace,
 		Prefix:          s.Prefix,
+		SoftDelete:      s.SoftDelete,
+		CustomMetadata:  s.CustomMetadata,
 		PrivateKey:      s.PrivateKey,
 		Certificate:     s.Certificate,
 		CAPath:          s.CAPath,
+		ServerName:      s.ServerName,
 		StatusPingAfter: s.StatusPing,
+		WatchInterval:   s.WatchInterval,
 	}
 	if s.AppRole != nil {
 		c.AppRole = vault.AppRole{
@@ -411,12 +1313,34 @@ func (s *VaultKeyStore) Connect(ctx context.Context) (kv.Store[string, []byte],
 	}
 	if s.Kubernetes != nil {
 		c.K8S = vault.Kubernetes{
-			Engine: s.Kubernetes.Engine,
-			Role:   s.Kubernetes.Role,
-			JWT:    s.Kubernetes.JWT,
+			Engine:  s.Kubernetes.Engine,
+			Role:    s.Kubernetes.Role,
+			JWT:     s.Kubernetes.JWT,
+			JWTFile: s.Kubernetes.JWTFile,
+		}
+	}
+	if s.AWS != nil {
+		c.AWS = &vault.AWSIAM{
+			Engine:       s.AWS.Engine,
+			Role:         s.AWS.Role,
+			ServerID:     s.AWS.ServerID,
+			Region:       s.AWS.Region,
+			AccessKey:    s.AWS.AccessKey,
+			SecretKey:    s.AWS.SecretKey,
+			SessionToken: s.AWS.SessionToken,
+		}
+	}
+	if s.Cert != nil {
+		c.Cert = &vault.Cert{
+			Engine: s.Cert.Engine,
+			Name:   s.Cert.Name,
 		}
 	}
-	return vault.Connect(ctx, c)
+	store, err := vault.Connect(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	return store.Recoverable(), nil
 }
 
 // FortanixKeyStore is a structure containing the
@@ -425,6 +1349,11 @@ type FortanixKeyStore struct {
 	// Endpoint is the endpoint of the Fortanix KMS.
 	Endpoint string
 
+	// FailoverEndpoints is an optional list of Fortanix
+	// KMS endpoints, e.g. of other regions, used if the
+	// Endpoint is not available.
+	FailoverEndpoints []string
+
 	// GroupID is the ID of the access control group.
 	GroupID string
 
@@ -432,25 +1361,41 @@ type FortanixKeyStore struct {
 	// the Fortanix KMS.
 	APIKey string
 
+	// APIKeyFile is an optional path to a file containing
+	// the API key. The file is read again once it changes.
+	// Hence, the API key can be rotated without a restart.
+	APIKeyFile string
+
 	// CAPath is an optional path to the root
 	// CA certificate(s) for verifying the TLS
-	// certificate of the Hashicorp Vault server.
+	// certificate of the Fortanix KMS.
 	//
 	// If empty, the OS default root CA set is
 	// used.
 	CAPath string
 
-	_ [0]int
-}
+	// PrivateKey is an optional path to a
+	// TLS private key file containing a
+	// TLS private key for mTLS authentication.
+	//
+	// If empty, mTLS authentication is disabled.
+	PrivateKey string
 
-// Connect returns a kv.Store that stores key-value pairs on a Fortanix SDKMS server.
-func (s *FortanixKeyStore) Connect(ctx context.Context) (kv.Store[string, []byte], error) {
-	return fortanix.Connect(ctx, &fortanix.Config{
-		Endpoint: s.Endpoint,
-		GroupID:  s.GroupID,
-		APIKey:   fortanix.APIKey(s.APIKey),
-		CAPath:   s.CAPath,
-	})
+	// Certificate is an optional path to a
+	// TLS certificate file containing a
+	// TLS certificate for mTLS authentication.
+	//
+	// If empty, mTLS authentication is disabled.
+	Certificate string
+
+	// ServerName is an optional TLS server name
+	// (SNI) used to verify the TLS certificate of
+	// the Fortanix KMS.
+	//
+	// If empty, the endpoint host name is used.
+	ServerName string
+
+	_ [0]int
 }
 
 // KeySecureKeyStore is a structure containing the
@@ -477,19 +1422,223 @@ type KeySecureKeyStore struct {
 	// used.
 	CAPath string
 
+	// PrivateKey is an optional path to a
+	// TLS private key file containing a
+	// TLS private key for mTLS authentication.
+	//
+	// If empty, mTLS authentication is disabled.
+	PrivateKey string
+
+	// Certificate is an optional path to a
+	// TLS certificate file containing a
+	// TLS certificate for mTLS authentication.
+	//
+	// If empty, mTLS authentication is disabled.
+	Certificate string
+
+	// ServerName is an optional TLS server name
+	// (SNI) used to verify the TLS certificate of
+	// the KeySecure server.
+	//
+	// If empty, the endpoint host name is used.
+	ServerName string
+
+	_ [0]int
+}
+
+// ConjurKeyStore is a structure containing the
+// configuration for CyberArk Conjur.
+type ConjurKeyStore struct {
+	// Endpoint is the Conjur appliance URL.
+	Endpoint string
+
+	// Account is the Conjur organization account.
+	Account string
+
+	// Login is the Conjur host or user, e.g. host/kes,
+	// used to authenticate to Conjur.
+	Login string
+
+	// APIKey is the API key of the Login.
+	APIKey string
+
+	// Policy is the Conjur policy branch that contains
+	// the keys as variables. The Login must be allowed
+	// to update this policy branch. If empty, defaults
+	// to the root policy.
+	Policy string
+
+	// CAPath is an optional path to the root
+	// CA certificate(s) for verifying the TLS
+	// certificate of the Conjur server.
+	//
+	// If empty, the OS default root CA set is
+	// used.
+	CAPath string
+
+	// PrivateKey is an optional path to a
+	// TLS private key file containing a
+	// TLS private key for mTLS authentication.
+	//
+	// If empty, mTLS authentication is disabled.
+	PrivateKey string
+
+	// Certificate is an optional path to a
+	// TLS certificate file containing a
+	// TLS certificate for mTLS authentication.
+	//
+	// If empty, mTLS authentication is disabled.
+	Certificate string
+
+	// ServerName is an optional TLS server name
+	// (SNI) used to verify the TLS certificate of
+	// the Conjur server.
+	//
+	// If empty, the endpoint host name is used.
+	ServerName string
+
 	_ [0]int
 }
 
-// Connect returns a kv.Store that stores key-value pairs on a Gemalto KeySecure instance.
-func (s *KeySecureKeyStore) Connect(ctx context.Context) (kv.Store[string, []byte], error) {
-	return gemalto.Connect(ctx, &gemalto.Config{
-		Endpoint: s.Endpoint,
-		CAPath:   s.CAPath,
-		Login: gemalto.Credentials{
-			Token:  s.Token,
-			Domain: s.Domain,
-		},
-	})
+// RESTKeyStore is a structure containing the
+// configuration for a generic REST API secret
+// store.
+type RESTKeyStore struct {
+	// Endpoint is the REST API base URL.
+	Endpoint string
+
+	// Header contains HTTP headers, e.g. an
+	// Authorization header, that are sent with
+	// every request.
+	Header map[string]string
+
+	// Create, Get, Delete and List are the
+	// request templates for creating, fetching,
+	// deleting and listing keys.
+	Create RESTRequest
+	Get    RESTRequest
+	Delete RESTRequest
+	List   RESTRequest
+
+	// ValuePath is the dot-separated path of
+	// the key value within the JSON response
+	// of get requests. If empty, the entire
+	// response body is the key value.
+	ValuePath string
+
+	// NamesPath is the dot-separated path of
+	// the JSON array of key names within the
+	// response of list requests.
+	NamesPath string
+
+	// NameField is the field of a JSON object
+	// that contains the key name if the list
+	// response contains an array of objects.
+	NameField string
+
+	// CAPath is an optional path to the root
+	// CA certificate(s) for verifying the TLS
+	// certificate of the REST API.
+	//
+	// If empty, the OS default root CA set is
+	// used.
+	CAPath string
+
+	// PrivateKey is an optional path to a
+	// TLS private key file containing a
+	// TLS private key for mTLS authentication.
+	//
+	// If empty, mTLS authentication is disabled.
+	PrivateKey string
+
+	// Certificate is an optional path to a
+	// TLS certificate file containing a
+	// TLS certificate for mTLS authentication.
+	//
+	// If empty, mTLS authentication is disabled.
+	Certificate string
+
+	_ [0]int
+}
+
+// RESTRequest describes an HTTP request sent to
+// a REST API. The Path and Body are templates
+// executed with the key name as {{ .Name }} and
+// the base64-encoded key value as {{ .Value }}.
+type RESTRequest struct {
+	Method string
+	Path   string
+	Body   string
+}
+
+// OnePasswordKeyStore is a structure containing the
+// configuration for a 1Password Connect server.
+type OnePasswordKeyStore struct {
+	// Endpoint is the 1Password Connect server URL.
+	Endpoint string
+
+	// Token is the 1Password Connect access token.
+	Token string
+
+	// VaultID is the UUID of the 1Password vault
+	// that contains the keys.
+	VaultID string
+
+	// Tag is the tag of 1Password items that are
+	// keys. If empty, defaults to "kes".
+	Tag string
+
+	// CAPath is an optional path to the root
+	// CA certificate(s) for verifying the TLS
+	// certificate of the 1Password Connect server.
+	//
+	// If empty, the OS default root CA set is
+	// used.
+	CAPath string
+
+	_ [0]int
+}
+
+// IBMKeyProtectKeyStore is a structure containing the
+// configuration for IBM Key Protect or IBM Hyper Protect
+// Crypto Services.
+type IBMKeyProtectKeyStore struct {
+	// Endpoint is the Key Protect or Hyper Protect
+	// Crypto Services API endpoint, e.g.
+	// https://us-south.kms.cloud.ibm.com
+	Endpoint string
+
+	// InstanceID is the ID of the Key Protect or
+	// Hyper Protect Crypto Services instance.
+	InstanceID string
+
+	// IAMEndpoint is an optional IBM Cloud IAM
+	// endpoint, e.g. a private endpoint.
+	//
+	// If empty, https://iam.cloud.ibm.com is used.
+	IAMEndpoint string
+
+	// APIKey is an IBM Cloud API key of either a
+	// user or a service ID used to authenticate
+	// to IBM Cloud IAM.
+	APIKey string
+
+	// ServiceID is an optional service ID, e.g.
+	// ServiceId-<UUID>.
+	//
+	// If not empty, the APIKey must belong to
+	// this service ID.
+	ServiceID string
+
+	// CAPath is an optional path to the root
+	// CA certificate(s) for verifying the TLS
+	// certificate of the API endpoint.
+	//
+	// If empty, the OS default root CA set is
+	// used.
+	CAPath string
+
+	_ [0]int
 }
 
 // GCPSecretManagerKeyStore is a structure containing the
@@ -531,19 +1680,55 @@ type GCPSecretManagerKeyStore struct {
 	_ [0]int
 }
 
-// Connect returns a kv.Store that stores key-value pairs on GCP SecretManager.
-func (s *GCPSecretManagerKeyStore) Connect(ctx context.Context) (kv.Store[string, []byte], error) {
-	return gcp.Connect(ctx, &gcp.Config{
-		Endpoint:  s.Endpoint,
-		ProjectID: s.ProjectID,
-		Scopes:    s.Scopes,
-		Credentials: gcp.Credentials{
-			ClientID: s.ClientID,
-			Client:   s.ClientEmail,
-			KeyID:    s.KeyID,
-			Key:      s.Key,
-		},
-	})
+// GCPCloudKMSKeyStore is a structure containing the
+// configuration for GCP Cloud KMS.
+//
+// Keys are stored in a directory on the filesystem.
+// Each key is encrypted with its own data key that
+// is wrapped by a GCP Cloud KMS key.
+type GCPCloudKMSKeyStore struct {
+	// Key is the resource name of the GCP Cloud KMS
+	// key used to wrap data keys.
+	Key string
+
+	// Path is the path to the directory that
+	// contains the encrypted keys.
+	//
+	// If the directory does not exist, it
+	// will be created.
+	Path string
+
+	// Endpoint is the GCP Cloud KMS endpoint. If
+	// empty, defaults to:
+	//   cloudkms.googleapis.com:443
+	Endpoint string
+
+	// Scopes are GCP OAuth2 scopes for accessing
+	// GCP APIs. If empty, defaults to the GCP
+	// default scopes.
+	Scopes []string
+
+	// ClientEmail is the Client email of the
+	// GCP service account used to access
+	// Cloud KMS.
+	ClientEmail string
+
+	// ClientID is the Client ID of the GCP
+	// service account used to access
+	// Cloud KMS.
+	ClientID string
+
+	// KeyID is the private key ID of the GCP
+	// service account used to access
+	// Cloud KMS.
+	KeyID string
+
+	// PrivateKey is the private key of the GCP
+	// service account used to access
+	// Cloud KMS.
+	PrivateKey string
+
+	_ [0]int
 }
 
 // AWSSecretsManagerKeyStore is a structure containing the
@@ -577,18 +1762,33 @@ type AWSSecretsManagerKeyStore struct {
 	_ [0]int
 }
 
-// Connect returns a kv.Store that stores key-value pairs on AWS SecretsManager.
-func (s *AWSSecretsManagerKeyStore) Connect(ctx context.Context) (kv.Store[string, []byte], error) {
-	return aws.Connect(ctx, &aws.Config{
-		Addr:     s.Endpoint,
-		Region:   s.Region,
-		KMSKeyID: s.KMSKey,
-		Login: aws.Credentials{
-			AccessKey:    s.AccessKey,
-			SecretKey:    s.SecretKey,
-			SessionToken: s.SessionToken,
-		},
-	})
+// AWSCloudHSMKeyStore is a structure containing the
+// configuration for an AWS CloudHSM cluster.
+type AWSCloudHSMKeyStore struct {
+	// Module is the path to the CloudHSM PKCS #11
+	// library. If empty, defaults to:
+	//  /opt/cloudhsm/lib/libcloudhsm_pkcs11.so
+	Module string
+
+	// Username is the name of the CloudHSM crypto
+	// user (CU).
+	Username string
+
+	// Password is the password of the crypto user.
+	Password string
+
+	// WrappingKey is the label of the AES key
+	// on the cluster used to encrypt keys.
+	WrappingKey string
+
+	// Application is an optional name that
+	// separates keys of multiple KES deployments
+	// sharing the same cluster.
+	//
+	// If empty, defaults to "kes".
+	Application string
+
+	_ [0]int
 }
 
 // AzureKeyVaultKeyStore is a structure containing the
@@ -612,28 +1812,156 @@ type AzureKeyVaultKeyStore struct {
 	// Azure managed identity that access the KeyVault.
 	ManagedIdentityClientID string
 
+	// ManagedHSM indicates whether Endpoint refers to an
+	// Azure Managed HSM instead of a KeyVault.
+	//
+	// Managed HSM does not support secrets. Instead, keys
+	// are encrypted with the WrappingKey inside the HSM.
+	ManagedHSM bool
+
+	// WrappingKey is the name of the Managed HSM AES key
+	// used to encrypt and decrypt keys. It is required
+	// if ManagedHSM is true.
+	WrappingKey string
+
 	_ [0]int
 }
 
-// Connect returns a kv.Store that stores key-value pairs on Azure KeyVault.
-func (s *AzureKeyVaultKeyStore) Connect(ctx context.Context) (kv.Store[string, []byte], error) {
-	if (s.TenantID != "" || s.ClientID != "" || s.ClientSecret != "") && s.ManagedIdentityClientID != "" {
-		return nil, errors.New("edge: failed to connect to Azure KeyVault: more than one authentication method specified")
-	}
-	switch {
-	case s.TenantID != "" || s.ClientID != "" || s.ClientSecret != "":
-		creds := azure.Credentials{
-			TenantID: s.TenantID,
-			ClientID: s.ClientID,
-			Secret:   s.ClientSecret,
-		}
-		return azure.ConnectWithCredentials(ctx, s.Endpoint, creds)
-	case s.ManagedIdentityClientID != "":
-		creds := azure.ManagedIdentity{
-			ClientID: s.ManagedIdentityClientID,
-		}
-		return azure.ConnectWithIdentity(ctx, s.Endpoint, creds)
-	default:
-		return nil, errors.New("edge: failed to connect to Azure KeyVault: no authentication method specified")
-	}
+// AlibabaSecretsManagerKeyStore is a structure containing
+// the configuration for Alibaba Cloud KMS Secrets Manager.
+type AlibabaSecretsManagerKeyStore struct {
+	// Region is the Alibaba Cloud region ID,
+	// e.g. cn-hangzhou.
+	Region string
+
+	// Endpoint is an optional KMS endpoint, e.g.
+	// a VPC endpoint.
+	//
+	// If empty, the public KMS endpoint of the
+	// region is used.
+	Endpoint string
+
+	// KMSKeyID is an optional ID of the KMS key
+	// used to encrypt secrets.
+	//
+	// If empty, the KMS service key is used.
+	KMSKeyID string
+
+	// AccessKeyID is the access key ID of a RAM
+	// user.
+	AccessKeyID string
+
+	// AccessKeySecret is the access key secret
+	// of a RAM user.
+	AccessKeySecret string
+
+	// ECSRAMRole is the name of a RAM role attached
+	// to the ECS instance. If not empty, the access
+	// key credentials are obtained from the instance
+	// metadata service.
+	ECSRAMRole string
+
+	// RoleARN is an optional ARN of a RAM role that
+	// is assumed using either the access key or the
+	// ECS RAM role credentials.
+	RoleARN string
+
+	// RoleSessionName is an optional session name
+	// used when assuming the RoleARN.
+	//
+	// If empty, defaults to "kes".
+	RoleSessionName string
+
+	_ [0]int
+}
+
+// OCIVaultKeyStore is a structure containing the
+// configuration for Oracle Cloud Infrastructure Vault.
+type OCIVaultKeyStore struct {
+	// Region is the OCI region, e.g. us-ashburn-1.
+	// If empty, defaults to the region of the compute
+	// instance when using instance principals or the
+	// region of the ConfigFile profile.
+	Region string
+
+	// CompartmentID is the OCID of the compartment
+	// containing the vault.
+	CompartmentID string
+
+	// VaultID is the OCID of the vault.
+	VaultID string
+
+	// KeyID is the OCID of the vault master encryption
+	// key used to encrypt secrets.
+	KeyID string
+
+	// DeletionPeriod is the time deleted secrets remain
+	// pending for deletion. If zero, defaults to 30 days.
+	DeletionPeriod time.Duration
+
+	// TenancyID is the OCID of the tenancy of the
+	// user that owns the API signing key.
+	TenancyID string
+
+	// UserID is the OCID of the user that owns the
+	// API signing key.
+	UserID string
+
+	// Fingerprint is the fingerprint of the API
+	// signing key.
+	Fingerprint string
+
+	// PrivateKey is the PEM-encoded API signing key
+	// or a path to it.
+	PrivateKey string
+
+	// ConfigFile is an optional path to an OCI SDK and
+	// CLI configuration file, e.g. ~/.oci/config,
+	// containing the API signing key credentials.
+	ConfigFile string
+
+	// Profile is the ConfigFile profile. If empty,
+	// defaults to the DEFAULT profile.
+	Profile string
+
+	// InstancePrincipal enables authentication via the
+	// OCI instance principal of the compute instance
+	// instead of an API signing key.
+	InstancePrincipal bool
+
+	_ [0]int
+}
+
+// PKCS11KeyStore is a structure containing the
+// configuration for a PKCS #11 token, e.g. an HSM.
+type PKCS11KeyStore struct {
+	// Module is the path to the PKCS #11 module
+	// provided by the HSM vendor.
+	Module string
+
+	// Slot is the ID of the slot containing the
+	// token. It is ignored if TokenLabel is set.
+	Slot uint
+
+	// TokenLabel is an optional label of the
+	// token. If set, the token is selected by
+	// its label instead of its slot ID.
+	TokenLabel string
+
+	// PIN is the user PIN used to log into
+	// the token.
+	PIN string
+
+	// WrappingKey is the label of the AES key
+	// on the token used to encrypt keys.
+	WrappingKey string
+
+	// Application is an optional name that
+	// separates keys of multiple KES deployments
+	// sharing the same token.
+	//
+	// If empty, defaults to "kes".
+	Application string
+
+	_ [0]int
 }
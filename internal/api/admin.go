@@ -0,0 +1,233 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/audit"
+	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/sys"
+)
+
+func addAdmin(config *RouterConfig) API {
+	const (
+		Method  = http.MethodPost
+		APIPath = "/v1/admin/add/"
+		MaxBody = 0
+		Timeout = 15 * time.Second
+		Verify  = true
+	)
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := nameFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		identity := kes.Identity(name)
+		if identity.IsUnknown() {
+			return kes.NewError(http.StatusBadRequest, "identity is unknown")
+		}
+
+		if err = Sync(config.Vault.RLocker(), func() error {
+			enclave, err := enclaveFromRequest(config.Vault, r)
+			if err != nil {
+				return err
+			}
+			return Sync(enclave.Locker(), func() error {
+				if err = verifyEnclaveAdmin(config, enclave, r); err != nil {
+					return err
+				}
+				sysAdmin, err := config.Vault.Admin(r.Context())
+				if err != nil {
+					return err
+				}
+				if identity == sysAdmin {
+					return kes.NewError(http.StatusBadRequest, "cannot add system admin")
+				}
+				return enclave.AddAdmin(r.Context(), identity, auth.Identify(r))
+			})
+		}); err != nil {
+			return err
+		}
+
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func removeAdmin(config *RouterConfig) API {
+	const (
+		Method  = http.MethodDelete
+		APIPath = "/v1/admin/remove/"
+		MaxBody = 0
+		Timeout = 15 * time.Second
+		Verify  = true
+	)
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := nameFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		identity := kes.Identity(name)
+		if self := auth.Identify(r); self == identity {
+			return kes.NewError(http.StatusForbidden, "identity cannot remove itself")
+		}
+
+		if err = Sync(config.Vault.RLocker(), func() error {
+			enclave, err := enclaveFromRequest(config.Vault, r)
+			if err != nil {
+				return err
+			}
+			return Sync(enclave.Locker(), func() error {
+				if err = verifyEnclaveAdmin(config, enclave, r); err != nil {
+					return err
+				}
+				return enclave.RemoveAdmin(r.Context(), identity)
+			})
+		}); err != nil {
+			return err
+		}
+
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func listAdmin(config *RouterConfig) API {
+	const (
+		Method      = http.MethodGet
+		APIPath     = "/v1/admin/list"
+		MaxBody     = 0
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	type Admin struct {
+		Identity  kes.Identity `json:"identity"`
+		Primary   bool         `json:"primary,omitempty"`
+		CreatedAt time.Time    `json:"created_at,omitempty"`
+		CreatedBy kes.Identity `json:"created_by,omitempty"`
+	}
+	type Response struct {
+		Admins []Admin `json:"admins"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		resp, err := VSync(config.Vault.RLocker(), func() (Response, error) {
+			enclave, err := enclaveFromRequest(config.Vault, r)
+			if err != nil {
+				return Response{}, err
+			}
+			return VSync(enclave.RLocker(), func() (Response, error) {
+				if err = verifyEnclaveAdmin(config, enclave, r); err != nil {
+					return Response{}, err
+				}
+
+				primary, err := enclave.Admin(r.Context())
+				if err != nil {
+					return Response{}, err
+				}
+				info, err := enclave.GetIdentity(r.Context(), primary)
+				if err != nil {
+					return Response{}, err
+				}
+				admins := []Admin{{
+					Identity:  primary,
+					Primary:   true,
+					CreatedAt: info.CreatedAt,
+					CreatedBy: info.CreatedBy,
+				}}
+
+				iterator, err := enclave.ListIdentities(r.Context())
+				if err != nil {
+					return Response{}, err
+				}
+				defer iterator.Close()
+
+				for iterator.Next() {
+					info, err := enclave.GetIdentity(r.Context(), iterator.Identity())
+					if err != nil {
+						return Response{}, err
+					}
+					if info.IsAdmin {
+						admins = append(admins, Admin{
+							Identity:  iterator.Identity(),
+							CreatedAt: info.CreatedAt,
+							CreatedBy: info.CreatedBy,
+						})
+					}
+				}
+				if err = iterator.Close(); err != nil {
+					return Response{}, err
+				}
+				sort.SliceStable(admins[1:], func(i, j int) bool {
+					return admins[i+1].Identity < admins[j+1].Identity
+				})
+				return Response{Admins: admins}, nil
+			})
+		})
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(resp)
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+// verifyEnclaveAdmin returns an error if the request has
+// neither been sent by the system admin nor by an admin
+// of the enclave.
+func verifyEnclaveAdmin(config *RouterConfig, enclave *sys.Enclave, r *http.Request) error {
+	identity := auth.Identify(r)
+	sysAdmin, err := config.Vault.Admin(r.Context())
+	if err != nil {
+		return err
+	}
+	if identity == sysAdmin {
+		return nil
+	}
+	info, err := enclave.GetIdentity(r.Context(), identity)
+	if errors.Is(err, kes.ErrIdentityNotFound) {
+		return kes.ErrNotAllowed
+	}
+	if err != nil {
+		return err
+	}
+	if !info.IsAdmin {
+		return kes.ErrNotAllowed
+	}
+	return nil
+}
@@ -13,6 +13,8 @@ import (
 	"time"
 
 	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/keystore"
 	"github.com/minio/kes/internal/sys"
 )
 
@@ -33,6 +35,12 @@ type Config struct {
 	// cases for APIs that don't expose sensitive information,
 	// like metrics.
 	InsecureSkipAuth bool
+
+	// RequireAuth controls whether the API verifies client
+	// identities even if it does not by default, like the
+	// liveness and readiness probes. It is ignored if
+	// InsecureSkipAuth is true.
+	RequireAuth bool
 }
 
 // API describes a KES server API.
@@ -68,6 +76,10 @@ func (a API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		Fail(w, fmt.Errorf("api: patch mismatch: received '%s' - expected '%s'", r.URL.Path, a.Path))
 		return
 	}
+	if a.MaxBody > 0 && r.ContentLength > a.MaxBody {
+		Fail(w, kes.NewError(http.StatusRequestEntityTooLarge, "request body too large"))
+		return
+	}
 	r.Body = http.MaxBytesReader(w, r.Body, a.MaxBody)
 
 	if a.Timeout > 0 {
@@ -97,6 +109,20 @@ func nameFromRequest(r *http.Request, apiPath string) (string, error) {
 	return name, nil
 }
 
+// pathFromRequest strips the API path from the request URL, verifies
+// that the remaining path is a valid hierarchical name, via verifyPath,
+// and returns the remaining path.
+func pathFromRequest(r *http.Request, apiPath string) (string, error) {
+	name := strings.TrimPrefix(r.URL.Path, apiPath)
+	if len(name) == len(r.URL.Path) {
+		return "", fmt.Errorf("api: patch mismatch: received '%s' - expected '%s'", r.URL.Path, apiPath)
+	}
+	if err := verifyPath(name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
 // patternFromRequest strips the API path from the request URL, verifies
 // that the remaining path is a valid pattern, via verifyPattern, and returns
 // the remaining path.
@@ -139,13 +165,40 @@ func verifyName(name string) error {
 	return nil
 }
 
+// verifyPath reports whether the hierarchical name is
+// valid. Keys, secrets and policies have hierarchical
+// names, like 'payments/prod/db-key'.
+//
+// A valid hierarchical name consists of one or more
+// valid names, as defined by verifyName, separated by
+// '/'. It must neither start nor end with a '/'.
+func verifyPath(name string) error {
+	const MaxLength = 128 // Some arbitrary but reasonable limit
+
+	if name == "" {
+		return kes.NewError(http.StatusBadRequest, "invalid argument: name is empty")
+	}
+	if len(name) > MaxLength {
+		return kes.NewError(http.StatusBadRequest, "invalid argument: name is too long")
+	}
+	for _, segment := range strings.Split(name, "/") {
+		if segment == "" {
+			return kes.NewError(http.StatusBadRequest, "invalid argument: name contains empty path segment")
+		}
+		if err := verifyName(segment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // verifyPattern reports whether the pattern is valid.
 //
 // A valid pattern must only contain numbers (0-9),
-// letters (a-z and A-Z) and '-', '_' as well as '*'
-// characters.
+// letters (a-z and A-Z) and '-', '_', '/' as well as
+// '*' characters.
 func verifyPattern(pattern string) error {
-	const MaxLength = 80 // Some arbitrary but reasonable limit
+	const MaxLength = 128 // Some arbitrary but reasonable limit
 
 	if pattern == "" {
 		return kes.NewError(http.StatusBadRequest, "invalid argument: pattern is empty")
@@ -153,13 +206,14 @@ func verifyPattern(pattern string) error {
 	if len(pattern) > MaxLength {
 		return kes.NewError(http.StatusBadRequest, "invalid argument: pattern is too long")
 	}
-	for _, r := range pattern { // Valid characters are: [ 0-9 , A-Z , a-z , - , _ , * ]
+	for _, r := range pattern { // Valid characters are: [ 0-9 , A-Z , a-z , - , _ , / , * ]
 		switch {
 		case r >= '0' && r <= '9':
 		case r >= 'A' && r <= 'Z':
 		case r >= 'a' && r <= 'z':
 		case r == '-':
 		case r == '_':
+		case r == '/':
 		case r == '*':
 		default:
 			return kes.NewError(http.StatusBadRequest, "invalid argument: pattern contains invalid character")
@@ -168,6 +222,18 @@ func verifyPattern(pattern string) error {
 	return nil
 }
 
+// matchName reports whether the name matches the list
+// pattern. Patterns are matched as described by auth.Match.
+// A pattern ending with '/' matches all names with this
+// prefix. For example, 'payments/' matches 'payments/db-key'
+// as well as 'payments/prod/db-key'.
+func matchName(pattern, name string) bool {
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "**"
+	}
+	return auth.Match(pattern, name)
+}
+
 // enclaveFromRequest parses the enclave name from the request URL
 // and returns the corresponding enclave present at the vault.
 func enclaveFromRequest(vault *sys.Vault, req *http.Request) (*sys.Enclave, error) {
@@ -181,6 +247,26 @@ func enclaveFromRequest(vault *sys.Vault, req *http.Request) (*sys.Enclave, erro
 	return vault.GetEnclave(req.Context(), name)
 }
 
+// keysFromRequest returns the keystore of the enclave
+// specified by the request's 'enclave' query parameter.
+// If the enclave is empty or the default enclave, it
+// returns the server's keystore.
+//
+// It returns kes.ErrEnclaveNotFound if no keystore for
+// the enclave exists. However, if no enclave has its
+// own keystore, any enclave is served by the server's
+// keystore.
+func keysFromRequest(config *EdgeRouterConfig, req *http.Request) (*keystore.Cache, error) {
+	name := req.URL.Query().Get("enclave")
+	if name == "" || name == sys.DefaultEnclaveName || len(config.Enclaves) == 0 {
+		return config.Keys, nil
+	}
+	if keys, ok := config.Enclaves[name]; ok {
+		return keys, nil
+	}
+	return nil, kes.ErrEnclaveNotFound
+}
+
 // Sync calls f while holding the given lock and
 // releases the lock once f has been finished.
 //
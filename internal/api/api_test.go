@@ -5,10 +5,36 @@
 package api
 
 import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/cpu"
+	"github.com/minio/kes/internal/key"
+	"github.com/minio/kes/internal/keystore"
+	"github.com/minio/kes/internal/keystore/mem"
+	"github.com/minio/kes/internal/log"
+	"github.com/minio/kes/internal/metric"
+	"github.com/minio/kes/internal/sys"
+	"github.com/minio/kes/kv"
 )
 
 func TestVerifyName(t *testing.T) {
@@ -23,6 +49,26 @@ func TestVerifyName(t *testing.T) {
 	}
 }
 
+func TestVerifyPath(t *testing.T) {
+	for i, test := range verifyPathTests {
+		err := verifyPath(test.Name)
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d should have failed", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: name '%s' is valid but got rejected: %v", i, test.Name, err)
+		}
+	}
+}
+
+func TestMatchName(t *testing.T) {
+	for i, test := range matchNameTests {
+		if match := matchName(test.Pattern, test.Name); match != test.Match {
+			t.Fatalf("Test %d: got '%v' - want '%v'", i, match, test.Match)
+		}
+	}
+}
+
 func TestPatternName(t *testing.T) {
 	for i, test := range verifyPatternTests {
 		err := verifyPattern(test.Pattern)
@@ -75,6 +121,41 @@ func TestPatternFromRequest(t *testing.T) {
 	}
 }
 
+func TestEnclaveFromSNI(t *testing.T) {
+	sni := map[string]string{"tenant-1.kes.example.com": "tenant-1"}
+	for i, test := range enclaveFromSNITests {
+		req := &http.Request{
+			URL: &url.URL{Path: "/v1/key/create/my-key", RawQuery: test.Query},
+			TLS: &tls.ConnectionState{ServerName: test.ServerName},
+		}
+		err := enclaveFromSNI(sni, req)
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d should have failed", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to route request: %v", i, err)
+		}
+		if enclave := req.URL.Query().Get("enclave"); !test.ShouldFail && enclave != test.Enclave {
+			t.Fatalf("Test %d: enclave mismatch: got '%s' - want '%s'", i, enclave, test.Enclave)
+		}
+	}
+}
+
+var enclaveFromSNITests = []struct {
+	ServerName string
+	Query      string
+	Enclave    string
+	ShouldFail bool
+}{
+	{ServerName: "tenant-1.kes.example.com", Query: "", Enclave: "tenant-1"},                 // 0
+	{ServerName: "TENANT-1.kes.example.com", Query: "", Enclave: "tenant-1"},                 // 1
+	{ServerName: "tenant-1.kes.example.com", Query: "enclave=tenant-1", Enclave: "tenant-1"}, // 2
+	{ServerName: "kes.example.com", Query: "enclave=tenant-2", Enclave: "tenant-2"},          // 3
+	{ServerName: "", Query: "", Enclave: ""},                                                 // 4
+
+	{ServerName: "tenant-1.kes.example.com", Query: "enclave=tenant-2", ShouldFail: true}, // 5
+}
+
 var (
 	verifyNameTests = []struct {
 		Name       string
@@ -99,6 +180,37 @@ var (
 		{Name: strings.Repeat("a", 81), ShouldFail: true}, // 15
 	}
 
+	verifyPathTests = []struct {
+		Name       string
+		ShouldFail bool
+	}{
+		{Name: "my-key"},               // 0
+		{Name: "payments/db-key"},      // 1
+		{Name: "payments/prod/db-key"}, // 2
+
+		{Name: "", ShouldFail: true},                       // 3
+		{Name: "/my-key", ShouldFail: true},                // 4
+		{Name: "my-key/", ShouldFail: true},                // 5
+		{Name: "payments//db-key", ShouldFail: true},       // 6
+		{Name: "payments/../db-key", ShouldFail: true},     // 7
+		{Name: "payments/*", ShouldFail: true},             // 8
+		{Name: strings.Repeat("a/", 64), ShouldFail: true}, // 9
+	}
+
+	matchNameTests = []struct {
+		Pattern string
+		Name    string
+		Match   bool
+	}{
+		{Pattern: "*", Name: "my-key", Match: true},                         // 0
+		{Pattern: "*", Name: "payments/db-key", Match: false},               // 1
+		{Pattern: "payments/*", Name: "payments/db-key", Match: true},       // 2
+		{Pattern: "payments/*", Name: "payments/prod/db-key", Match: false}, // 3
+		{Pattern: "payments/", Name: "payments/prod/db-key", Match: true},   // 4
+		{Pattern: "payments/", Name: "payments", Match: false},              // 5
+		{Pattern: "**", Name: "payments/prod/db-key", Match: true},          // 6
+	}
+
 	verifyPatternTests = []struct {
 		Pattern    string
 		ShouldFail bool
@@ -114,15 +226,16 @@ var (
 		{Pattern: "my*"},       // 8
 		{Pattern: "_-*"},       // 9
 		{Pattern: "*-*"},       // 10
+		{Pattern: "key/"},      // 11
+		{Pattern: "key/**"},    // 12
 
-		{Pattern: "", ShouldFail: true},                      // 11
-		{Pattern: "my.key", ShouldFail: true},                // 12
-		{Pattern: "key/", ShouldFail: true},                  // 13
-		{Pattern: "", ShouldFail: true},                      // 14
-		{Pattern: "☰", ShouldFail: true},                     // 15
-		{Pattern: "hel<lo", ShouldFail: true},                // 16
-		{Pattern: "Εmacs", ShouldFail: true},                 // 17 - greek Ε
-		{Pattern: strings.Repeat("a", 81), ShouldFail: true}, // 18
+		{Pattern: "", ShouldFail: true},                       // 13
+		{Pattern: "my.key", ShouldFail: true},                 // 14
+		{Pattern: "", ShouldFail: true},                       // 15
+		{Pattern: "☰", ShouldFail: true},                      // 16
+		{Pattern: "hel<lo", ShouldFail: true},                 // 17
+		{Pattern: "Εmacs", ShouldFail: true},                  // 18 - greek Ε
+		{Pattern: strings.Repeat("a", 129), ShouldFail: true}, // 19
 	}
 
 	nameFromRequestTests = []struct {
@@ -193,3 +306,1194 @@ var (
 		},
 	}
 )
+
+func TestKeyUsage(t *testing.T) {
+	usage := newKeyUsage()
+	if u := usage.Get("default/my-key"); u.Encrypt != 0 || u.LastUsed != nil {
+		t.Fatalf("Unused key has usage: %+v", u)
+	}
+
+	usage.Add("default/my-key", opEncrypt, 1)
+	usage.Add("default/my-key", opDecrypt, 3)
+	usage.Add("default/my-key", opGenerate, 2)
+	usage.Add("default/my-key", opDecrypt, 0)
+
+	u := usage.Get("default/my-key")
+	if u.Encrypt != 1 || u.Decrypt != 3 || u.Generate != 2 {
+		t.Fatalf("Usage mismatch: got %+v", u)
+	}
+	if u.LastUsed == nil || u.LastUsed.Before(u.Since) {
+		t.Fatalf("Invalid last used timestamp: got '%v' - since '%v'", u.LastUsed, u.Since)
+	}
+	if u := usage.Get("tenant-1/my-key"); u.LastUsed != nil {
+		t.Fatalf("Usage is not scoped to enclave: %+v", u)
+	}
+
+	usage.Delete("default/my-key")
+	if u := usage.Get("default/my-key"); u.Decrypt != 0 || u.LastUsed != nil {
+		t.Fatalf("Deleted key has usage: %+v", u)
+	}
+}
+
+var isMutationTests = []struct {
+	Method   string
+	Path     string
+	Mutation bool
+}{
+	{Method: http.MethodGet, Path: "/v1/key/describe/my-key"},
+	{Method: http.MethodGet, Path: "/v1/status"},
+	{Method: http.MethodPost, Path: "/v1/key/encrypt/my-key"},
+	{Method: http.MethodPost, Path: "/v1/key/bulk/decrypt/my-key"},
+	{Method: http.MethodPost, Path: "/v1/maintenance/read-only"},
+	{Method: http.MethodPost, Path: "/v1/drill/start"},
+	{Method: http.MethodPost, Path: "/v1/key/create/my-key", Mutation: true},
+	{Method: http.MethodPost, Path: "/v1/key/bulk/create/", Mutation: true},
+	{Method: http.MethodDelete, Path: "/v1/key/delete/my-key", Mutation: true},
+	{Method: http.MethodPost, Path: "/v1/policy/write/my-policy", Mutation: true},
+}
+
+func TestIsMutation(t *testing.T) {
+	for i, test := range isMutationTests {
+		req := &http.Request{
+			Method: test.Method,
+			URL:    &url.URL{Path: test.Path},
+		}
+		if mutation := isMutation(req); mutation != test.Mutation {
+			t.Fatalf("Test %d: got '%v' - want '%v'", i, mutation, test.Mutation)
+		}
+	}
+}
+
+var canImpersonateTests = []struct {
+	Method      string
+	Path        string
+	Impersonate bool
+}{
+	{Method: http.MethodGet, Path: "/v1/key/describe/my-key", Impersonate: true},      // 0
+	{Method: http.MethodGet, Path: "/v1/identity/self/describe", Impersonate: true},   // 1
+	{Method: http.MethodPost, Path: "/v1/key/encrypt/my-key", Impersonate: true},      // 2
+	{Method: http.MethodPost, Path: "/v1/key/bulk/decrypt/my-key", Impersonate: true}, // 3
+	{Method: http.MethodPost, Path: "/v1/key/create/my-key"},                          // 4
+	{Method: http.MethodPost, Path: "/v1/key/ceremony/contribute/my-key"},             // 5
+	{Method: http.MethodDelete, Path: "/v1/key/delete/my-key"},                        // 6
+	{Method: http.MethodPost, Path: "/v1/maintenance/read-only"},                      // 7
+	{Method: http.MethodPost, Path: "/v1/drill/start"},                                // 8
+	{Method: http.MethodPost, Path: "/v1/admin/reload"},                               // 9
+}
+
+func TestCanImpersonate(t *testing.T) {
+	for i, test := range canImpersonateTests {
+		req := &http.Request{
+			Method: test.Method,
+			URL:    &url.URL{Path: test.Path},
+		}
+		if impersonate := canImpersonate(req); impersonate != test.Impersonate {
+			t.Fatalf("Test %d: got '%v' - want '%v'", i, impersonate, test.Impersonate)
+		}
+	}
+}
+
+var parseDrillTests = []struct {
+	Scenario string
+	Duration string
+	Latency  string
+
+	WantDuration time.Duration
+	WantLatency  time.Duration
+	ShouldFail   bool
+}{
+	{Scenario: DrillBackendOutage, WantDuration: defaultDrillDuration},                                                  // 0
+	{Scenario: DrillLatency, Duration: "10m", WantDuration: 10 * time.Minute, WantLatency: defaultDrillLatency},         // 1
+	{Scenario: DrillLatency, Latency: "250ms", WantDuration: defaultDrillDuration, WantLatency: 250 * time.Millisecond}, // 2
+	{Scenario: DrillCertExpiry, Latency: "1s", WantDuration: defaultDrillDuration},                                      // 3
+	{Scenario: "", ShouldFail: true},                                  // 4
+	{Scenario: "disk-full", ShouldFail: true},                         // 5
+	{Scenario: DrillBackendOutage, Duration: "2h", ShouldFail: true},  // 6
+	{Scenario: DrillBackendOutage, Duration: "-1m", ShouldFail: true}, // 7
+	{Scenario: DrillLatency, Latency: "5m", ShouldFail: true},         // 8
+}
+
+func TestParseDrill(t *testing.T) {
+	for i, test := range parseDrillTests {
+		duration, latency, err := parseDrill(test.Scenario, test.Duration, test.Latency)
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: should fail but passed", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to parse drill: %v", i, err)
+		}
+		if err == nil && (duration != test.WantDuration || latency != test.WantLatency) {
+			t.Fatalf("Test %d: got '%v' and '%v' - want '%v' and '%v'", i, duration, latency, test.WantDuration, test.WantLatency)
+		}
+	}
+}
+
+func TestDrill(t *testing.T) {
+	var expired bool
+	drill := &Drill{
+		ExpireCertificate: func(e bool) error { expired = e; return nil },
+	}
+	req := &http.Request{
+		Method: http.MethodPost,
+		URL:    &url.URL{Path: "/v1/key/encrypt/my-key"},
+	}
+
+	if err := drill.Start(DrillBackendOutage, time.Hour, 0); err != nil {
+		t.Fatalf("Failed to start drill: %v", err)
+	}
+	if scenario, _ := drill.Scenario(); scenario != DrillBackendOutage {
+		t.Fatalf("Invalid scenario: got '%s' - want '%s'", scenario, DrillBackendOutage)
+	}
+	if !drill.inject(httptest.NewRecorder(), req) {
+		t.Fatal("Request has not failed during backend outage drill")
+	}
+
+	if err := drill.Start(DrillCertExpiry, time.Hour, 0); err != nil {
+		t.Fatalf("Failed to start drill: %v", err)
+	}
+	if !expired {
+		t.Fatal("Certificate has not been expired")
+	}
+	if drill.inject(httptest.NewRecorder(), req) {
+		t.Fatal("Request has failed during cert expiry drill")
+	}
+	if err := drill.Stop(); err != nil {
+		t.Fatalf("Failed to stop drill: %v", err)
+	}
+	if expired {
+		t.Fatal("Certificate has not been restored")
+	}
+
+	if err := drill.Start(DrillCertExpiry, 10*time.Millisecond, 0); err != nil {
+		t.Fatalf("Failed to start drill: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if scenario, _ := drill.Scenario(); scenario != "" {
+		t.Fatalf("Drill '%s' has not been rolled back", scenario)
+	}
+	drill.lock.Lock()
+	defer drill.lock.Unlock()
+	if expired {
+		t.Fatal("Certificate has not been restored after rollback")
+	}
+}
+
+var parseLabelsTests = []struct {
+	Values     []string
+	Labels     map[string]string
+	ShouldFail bool
+}{
+	{Values: nil, Labels: nil}, // 0
+	{Values: []string{"team=storage"}, Labels: map[string]string{"team": "storage"}},                    // 1
+	{Values: []string{"team=storage", "env="}, Labels: map[string]string{"team": "storage", "env": ""}}, // 2
+	{Values: []string{"team"}, ShouldFail: true},                                                        // 3
+	{Values: []string{"=storage"}, ShouldFail: true},                                                    // 4
+	{Values: []string{"team=storage\n"}, ShouldFail: true},                                              // 5
+}
+
+func TestParseLabels(t *testing.T) {
+	for i, test := range parseLabelsTests {
+		labels, err := parseLabels(test.Values)
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: should fail but passed", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to parse labels: %v", i, err)
+		}
+		if err == nil && len(labels) != len(test.Labels) {
+			t.Fatalf("Test %d: got '%v' - want '%v'", i, labels, test.Labels)
+		}
+		for label, value := range test.Labels {
+			if v, ok := labels[label]; !ok || v != value {
+				t.Fatalf("Test %d: got '%v' - want '%v'", i, labels, test.Labels)
+			}
+		}
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	const Identity = "d0fb8f5be9ac7abc8a4ba4dd2d8d0ca9a39b5bb1a4b5ad1e2be60d1cad8e4f42"
+
+	limit := &RateLimit{Rate: 2, Burst: 3}
+	now := time.Now()
+	for i := 3; i > 0; i-- {
+		_, remaining, reset, retryAfter, ok := limit.take(Identity, "", "", now)
+		if !ok {
+			t.Fatalf("Request %d got rejected", 3-i)
+		}
+		if remaining != i-1 {
+			t.Fatalf("Invalid remaining requests: got '%d' - want '%d'", remaining, i-1)
+		}
+		if reset.Before(now) {
+			t.Fatalf("Invalid reset time: '%v' is before '%v'", reset, now)
+		}
+
+		// Once no requests remain, the client should wait for the
+		// next token, not until the bucket is full again.
+		if remaining > 0 && retryAfter != 0 {
+			t.Fatalf("Request %d: invalid retry after: got '%v' - want '%v'", 3-i, retryAfter, 0)
+		}
+		if remaining == 0 && retryAfter != 500*time.Millisecond {
+			t.Fatalf("Request %d: invalid retry after: got '%v' - want '%v'", 3-i, retryAfter, 500*time.Millisecond)
+		}
+	}
+
+	_, _, _, retryAfter, ok := limit.take(Identity, "", "", now)
+	if ok {
+		t.Fatal("Request should have been rejected")
+	}
+	if retryAfter <= 0 || retryAfter > 500*time.Millisecond {
+		t.Fatalf("Invalid retry after: got '%v' - want '%v'", retryAfter, 500*time.Millisecond)
+	}
+	if _, _, _, _, ok = limit.take("other", "", "", now); !ok {
+		t.Fatal("Request of another identity got rejected")
+	}
+
+	now = now.Add(time.Second)
+	_, remaining, _, _, ok := limit.take(Identity, "", "", now)
+	if !ok {
+		t.Fatal("Request got rejected after tokens have been refilled")
+	}
+	if remaining != 1 {
+		t.Fatalf("Invalid remaining requests: got '%d' - want '%d'", remaining, 1)
+	}
+}
+
+func TestRateLimitRules(t *testing.T) {
+	limit := &RateLimit{
+		Rate:       1,
+		Burst:      1,
+		Identities: map[kes.Identity]RateLimitRule{"vip": {Rate: 1, Burst: 3}},
+		Policies:   map[string]RateLimitRule{"batch": {Rate: 1, Burst: 2}},
+		Enclaves:   map[string]RateLimitRule{"tenant-1": {Rate: 1, Burst: 4}},
+	}
+	now := time.Now()
+
+	take := func(identity kes.Identity, policy, enclave string, n int) (burst int) {
+		for i := 0; i < n; i++ {
+			var ok bool
+			if burst, _, _, _, ok = limit.take(identity, policy, enclave, now); !ok {
+				t.Fatalf("Request %d of '%s' to enclave '%s' got rejected", i, identity, enclave)
+			}
+		}
+		if _, _, _, _, ok := limit.take(identity, policy, enclave, now); ok {
+			t.Fatalf("Request %d of '%s' to enclave '%s' should have been rejected", n, identity, enclave)
+		}
+		return burst
+	}
+	if burst := take("vip", "batch", "", 3); burst != 3 { // Identity limits take precedence over policy limits
+		t.Fatalf("Invalid burst: got '%d' - want '%d'", burst, 3)
+	}
+	if burst := take("app-1", "batch", "", 2); burst != 2 {
+		t.Fatalf("Invalid burst: got '%d' - want '%d'", burst, 2)
+	}
+	if burst := take("app-2", "", "", 1); burst != 1 {
+		t.Fatalf("Invalid burst: got '%d' - want '%d'", burst, 1)
+	}
+	if identity, enclave := limit.Rejected(); identity != 3 || enclave != 0 {
+		t.Fatalf("Invalid number of rejected requests: got '%d' and '%d' - want '%d' and '%d'", identity, enclave, 3, 0)
+	}
+
+	// All identities share the enclave's token bucket.
+	limit.Rate, limit.Burst = 0, 0
+	for _, identity := range []kes.Identity{"app-3", "app-4", "app-5", "app-6"} {
+		if _, _, _, _, ok := limit.take(identity, "", "tenant-1", now); !ok {
+			t.Fatalf("Request of '%s' to enclave 'tenant-1' got rejected", identity)
+		}
+	}
+	if _, _, _, retryAfter, ok := limit.take("app-7", "", "tenant-1", now); ok || retryAfter <= 0 {
+		t.Fatal("Request to enclave 'tenant-1' should have been rejected")
+	}
+	if _, _, _, _, ok := limit.take("app-7", "", "tenant-2", now); !ok {
+		t.Fatal("Request to enclave 'tenant-2' got rejected")
+	}
+	if _, enclave := limit.Rejected(); enclave != 1 {
+		t.Fatalf("Invalid number of rejected enclave requests: got '%d' - want '%d'", enclave, 1)
+	}
+}
+
+func TestRateLimitUnknownIdentity(t *testing.T) {
+	rateLimit := &RateLimit{
+		Rate:  1,
+		Burst: 2,
+		Known: func(*http.Request) bool { return false },
+	}
+	handler := limit(rateLimit, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Requests with new, unknown API keys from the same client IP
+	// share one token bucket.
+	for i := 0; i < 3; i++ {
+		key, err := kes.GenerateAPIKey(nil)
+		if err != nil {
+			t.Fatalf("Failed to generate API key: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodGet, "https://127.0.0.1:7373/v1/status", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		req.Header.Set("Authorization", "Bearer "+key.String())
+
+		want := http.StatusOK
+		if i == 2 {
+			want = http.StatusTooManyRequests
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Fatalf("Request %d: got status '%d' - want '%d'", i, w.Code, want)
+		}
+	}
+
+	// Unauthenticated requests of other clients do not share
+	// the token bucket.
+	req := httptest.NewRequest(http.MethodGet, "https://127.0.0.1:7373/v1/status", nil)
+	req.RemoteAddr = "10.0.0.2:1234"
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Request of another client: got status '%d' - want '%d'", w.Code, http.StatusOK)
+	}
+}
+
+func TestRateLimitMaxBuckets(t *testing.T) {
+	limit := &RateLimit{Rate: 1, Burst: 1}
+	now := time.Now()
+	for i := 0; i < maxBuckets+10; i++ {
+		limit.take(kes.Identity("ip:10.0.0."+strconv.Itoa(i)), "", "", now)
+	}
+	if n := len(limit.buckets); n != maxBuckets {
+		t.Fatalf("Invalid number of token buckets: got '%d' - want '%d'", n, maxBuckets)
+	}
+	if n := limit.lru.Len(); n != maxBuckets {
+		t.Fatalf("Invalid LRU size: got '%d' - want '%d'", n, maxBuckets)
+	}
+	if _, ok := limit.buckets["ip:10.0.0.0"]; ok {
+		t.Fatal("Least recently used token bucket has not been evicted")
+	}
+}
+
+func TestRequestLimitShed(t *testing.T) {
+	limit := &RequestLimit{
+		Pool:         cpu.NewPool(1, 1),
+		QueueTimeout: 50 * time.Millisecond,
+	}
+	release := make(chan struct{})
+	handler := shed(limit, "/v1/key/decrypt/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	done := make(chan int, 2)
+	for i := 0; i < 2; i++ { // One request is served, one waits in the queue
+		go func() {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/key/decrypt/my-key", nil))
+			done <- w.Code
+		}()
+	}
+	for limit.Pool.Active() != 1 || limit.Pool.Queued() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	w := httptest.NewRecorder() // The queue is full
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/key/decrypt/my-key", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Invalid status code: got '%d' - want '%d'", w.Code, http.StatusServiceUnavailable)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Fatal("Response of shed request has no Retry-After header")
+	}
+
+	w = httptest.NewRecorder() // Health checks are never shed
+	shed(limit, "/v1/ready", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/ready", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Invalid status code: got '%d' - want '%d'", w.Code, http.StatusOK)
+	}
+
+	if code := <-done; code != http.StatusServiceUnavailable { // The queued request times out
+		t.Fatalf("Invalid status code: got '%d' - want '%d'", code, http.StatusServiceUnavailable)
+	}
+	close(release)
+	if code := <-done; code != http.StatusOK {
+		t.Fatalf("Invalid status code: got '%d' - want '%d'", code, http.StatusOK)
+	}
+	if n := limit.Shed(); n != 2 {
+		t.Fatalf("Invalid number of shed requests: got '%d' - want '%d'", n, 2)
+	}
+}
+
+func TestRequestLimitMaxBody(t *testing.T) {
+	limit := &RequestLimit{MaxBody: 1024}
+	for _, test := range []struct{ MaxBody, Want int64 }{
+		{MaxBody: 0, Want: 0},
+		{MaxBody: 512, Want: 512},
+		{MaxBody: 1 << 20, Want: 1024},
+	} {
+		if got := limit.maxBody(test.MaxBody); got != test.Want {
+			t.Fatalf("Invalid max. body size for '%d': got '%d' - want '%d'", test.MaxBody, got, test.Want)
+		}
+	}
+
+	a := API{
+		Method:  http.MethodPost,
+		Path:    "/v1/key/decrypt/",
+		MaxBody: 1024,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }),
+	}
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/key/decrypt/my-key", strings.NewReader(strings.Repeat("a", 2048))))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Invalid status code: got '%d' - want '%d'", w.Code, http.StatusRequestEntityTooLarge)
+	}
+}
+
+func TestProbes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	config := &EdgeRouterConfig{
+		Keys: keystore.NewCache(ctx, &mem.Store{}, &keystore.CacheConfig{}),
+	}
+	maintenance := &Maintenance{}
+	healthz, readyz := edgeHealthz(config), edgeReadyz(config, maintenance)
+	if healthz.Verify || readyz.Verify {
+		t.Fatal("Probes require authentication by default")
+	}
+
+	probe := func(a API) int {
+		w := httptest.NewRecorder()
+		a.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, a.Path, nil))
+		return w.Code
+	}
+	if code := probe(healthz); code != http.StatusOK {
+		t.Fatalf("Invalid liveness status: got '%d' - want '%d'", code, http.StatusOK)
+	}
+	if code := probe(readyz); code != http.StatusOK {
+		t.Fatalf("Invalid readiness status: got '%d' - want '%d'", code, http.StatusOK)
+	}
+
+	maintenance.SetShuttingDown()
+	if code := probe(healthz); code != http.StatusOK {
+		t.Fatalf("Invalid liveness status while shutting down: got '%d' - want '%d'", code, http.StatusOK)
+	}
+	if code := probe(readyz); code != http.StatusServiceUnavailable {
+		t.Fatalf("Invalid readiness status while shutting down: got '%d' - want '%d'", code, http.StatusServiceUnavailable)
+	}
+
+	config.APIConfig = map[string]Config{"/healthz": {Timeout: time.Second}}
+	if healthz = edgeHealthz(config); healthz.Verify {
+		t.Fatal("Liveness probe requires authentication although not configured")
+	}
+
+	config.APIConfig = map[string]Config{"/readyz": {RequireAuth: true}}
+	if readyz = edgeReadyz(config, &Maintenance{}); !readyz.Verify {
+		t.Fatal("Readiness probe does not require authentication although configured")
+	}
+	if code := probe(readyz); code == http.StatusOK {
+		t.Fatal("Readiness probe accepted unauthenticated request")
+	}
+}
+
+type unavailableStore struct{ mem.Store }
+
+func (*unavailableStore) Status(context.Context) (kv.State, error) {
+	return kv.State{}, errors.New("vault: dial tcp 10.0.0.1:8200: connection refused")
+}
+
+func TestReadyzHidesKeystoreError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var errLog strings.Builder
+	config := &EdgeRouterConfig{
+		Keys:     keystore.NewCache(ctx, &unavailableStore{}, &keystore.CacheConfig{}),
+		ErrorLog: log.New(&errLog, "", 0),
+	}
+	readyz := edgeReadyz(config, &Maintenance{})
+
+	w := httptest.NewRecorder()
+	readyz.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, readyz.Path, nil))
+	if w.Code != http.StatusBadGateway {
+		t.Fatalf("Invalid readiness status: got '%d' - want '%d'", w.Code, http.StatusBadGateway)
+	}
+	if body := w.Body.String(); strings.Contains(body, "10.0.0.1") || !strings.Contains(body, "keystore unavailable") {
+		t.Fatalf("Readiness probe exposes keystore error: %s", body)
+	}
+	if !strings.Contains(errLog.String(), "10.0.0.1") {
+		t.Fatal("Readiness probe did not log keystore error")
+	}
+}
+
+func TestProbesBypassRateLimit(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := httptest.NewTLSServer(NewEdgeRouter(&EdgeRouterConfig{
+		Keys:      keystore.NewCache(ctx, &mem.Store{}, &keystore.CacheConfig{}),
+		AuditLog:  log.New(io.Discard, "", 0),
+		ErrorLog:  log.New(io.Discard, "", 0),
+		Metrics:   metric.New(),
+		RateLimit: &RateLimit{Rate: 1, Burst: 1},
+	}))
+	defer server.Close()
+
+	serve := func(path string) int {
+		resp, err := server.Client().Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := serve("/version"); code == http.StatusTooManyRequests {
+		t.Fatalf("Request got rejected: got status '%d'", code)
+	}
+	if code := serve("/version"); code != http.StatusTooManyRequests {
+		t.Fatalf("Request should have been rejected: got status '%d' - want '%d'", code, http.StatusTooManyRequests)
+	}
+	for _, path := range []string{"/healthz", "/readyz", "/healthz"} {
+		if code := serve(path); code != http.StatusOK {
+			t.Fatalf("Probe '%s' got rejected: got status '%d' - want '%d'", path, code, http.StatusOK)
+		}
+	}
+}
+
+func TestHealth(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	newCache := func(unreachable bool) *keystore.Cache {
+		if unreachable {
+			return keystore.NewCache(ctx, &unreachableStore{}, &keystore.CacheConfig{})
+		}
+		return keystore.NewCache(ctx, &mem.Store{}, &keystore.CacheConfig{})
+	}
+	for i, test := range healthTests {
+		config := &EdgeRouterConfig{
+			Keys:      newCache(test.Unreachable),
+			Enclaves:  map[string]*keystore.Cache{"tenant-1": newCache(test.EnclaveUnreachable)},
+			APIConfig: map[string]Config{"/v1/health": {InsecureSkipAuth: true}},
+		}
+		maintenance := &Maintenance{}
+		if test.ShuttingDown {
+			maintenance.SetShuttingDown()
+		}
+		api := edgeHealth(config, maintenance)
+
+		w := httptest.NewRecorder()
+		api.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, api.Path+"?"+test.Query, nil))
+		if w.Code != test.StatusCode {
+			t.Fatalf("Test %d: status code mismatch: got '%d' - want '%d'", i, w.Code, test.StatusCode)
+		}
+
+		var response struct {
+			Status   string `json:"status"`
+			Services map[string]struct {
+				Status      string `json:"status"`
+				Unreachable bool   `json:"unreachable"`
+			} `json:"services"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("Test %d: failed to decode response: %v", i, err)
+		}
+		if response.Status != test.Status {
+			t.Fatalf("Test %d: status mismatch: got '%s' - want '%s'", i, response.Status, test.Status)
+		}
+		if len(response.Services) != len(test.Services) {
+			t.Fatalf("Test %d: services mismatch: got %d services - want %d", i, len(response.Services), len(test.Services))
+		}
+		for name, status := range test.Services {
+			service, ok := response.Services[name]
+			if !ok {
+				t.Fatalf("Test %d: service '%s' is missing", i, name)
+			}
+			if service.Status != status {
+				t.Fatalf("Test %d: service '%s' status mismatch: got '%s' - want '%s'", i, name, service.Status, status)
+			}
+			if unreachable := status == healthNotServing; service.Unreachable != unreachable {
+				t.Fatalf("Test %d: service '%s' unreachable mismatch: got '%v' - want '%v'", i, name, service.Unreachable, unreachable)
+			}
+		}
+	}
+}
+
+// unreachableStore is a kv.Store that is never reachable.
+type unreachableStore struct {
+	mem.Store
+}
+
+func (*unreachableStore) Status(context.Context) (kv.State, error) {
+	return kv.State{}, &kv.Unreachable{Err: errors.New("connection refused")}
+}
+
+var healthTests = []struct {
+	Query              string
+	Unreachable        bool
+	EnclaveUnreachable bool
+	ShuttingDown       bool
+
+	StatusCode int
+	Status     string
+	Services   map[string]string
+}{
+	{ // 0
+		StatusCode: http.StatusOK,
+		Status:     healthServing,
+		Services:   map[string]string{"keystore": healthServing, "cache": healthServing, "keystore/tenant-1": healthServing},
+	},
+	{ // 1
+		Unreachable: true,
+		StatusCode:  http.StatusServiceUnavailable,
+		Status:      healthNotServing,
+		Services:    map[string]string{"keystore": healthNotServing, "cache": healthServing, "keystore/tenant-1": healthServing},
+	},
+	{ // 2
+		EnclaveUnreachable: true,
+		StatusCode:         http.StatusOK,
+		Status:             healthServing,
+		Services:           map[string]string{"keystore": healthServing, "cache": healthServing, "keystore/tenant-1": healthNotServing},
+	},
+	{ // 3
+		ShuttingDown: true,
+		StatusCode:   http.StatusServiceUnavailable,
+		Status:       healthNotServing,
+		Services:     map[string]string{"keystore": healthServing, "cache": healthServing, "keystore/tenant-1": healthServing},
+	},
+	{ // 4
+		Query:      "service=keystore",
+		StatusCode: http.StatusOK,
+		Status:     healthServing,
+		Services:   map[string]string{"keystore": healthServing},
+	},
+	{ // 5
+		Query:       "service=keystore",
+		Unreachable: true,
+		StatusCode:  http.StatusServiceUnavailable,
+		Status:      healthNotServing,
+		Services:    map[string]string{"keystore": healthNotServing},
+	},
+	{ // 6
+		Query:       "service=cache",
+		Unreachable: true,
+		StatusCode:  http.StatusOK,
+		Status:      healthServing,
+		Services:    map[string]string{"cache": healthServing},
+	},
+	{ // 7
+		Query:              "service=keystore/tenant-1",
+		EnclaveUnreachable: true,
+		StatusCode:         http.StatusServiceUnavailable,
+		Status:             healthNotServing,
+		Services:           map[string]string{"keystore/tenant-1": healthNotServing},
+	},
+	{ // 8
+		Query:        "service=keystore/tenant-1",
+		ShuttingDown: true,
+		StatusCode:   http.StatusOK,
+		Status:       healthServing,
+		Services:     map[string]string{"keystore/tenant-1": healthServing},
+	},
+	{ // 9
+		Query:      "service=unknown",
+		StatusCode: http.StatusNotFound,
+		Status:     healthServiceUnknown,
+	},
+	{ // 10
+		Query:       "service=keystore/tenant-2",
+		Unreachable: true,
+		StatusCode:  http.StatusNotFound,
+		Status:      healthServiceUnknown,
+	},
+}
+
+func TestTransferKey(t *testing.T) {
+	const (
+		Enclave = "tenant-1"
+		Admin   = kes.Identity("3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22")
+	)
+	ctx := context.Background()
+
+	rootKey, err := key.Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to generate root key: %v", err)
+	}
+	vault := sys.NewVault(sys.NewVaultFS(t.TempDir(), rootKey, sys.NoCompression))
+	enclaves := map[string]*sys.Enclave{}
+	for _, name := range []string{sys.DefaultEnclaveName, Enclave} {
+		if _, err = vault.CreateEnclave(ctx, name, Admin); err != nil {
+			t.Fatalf("Failed to create enclave '%s': %v", name, err)
+		}
+		if enclaves[name], err = vault.GetEnclave(ctx, name); err != nil {
+			t.Fatalf("Failed to get enclave '%s': %v", name, err)
+		}
+	}
+
+	certificate, identity := newClientCertificate(t)
+	setPolicy := func(enclave string, allow ...string) {
+		if err := enclaves[enclave].SetPolicy(ctx, "transfer", auth.Policy{Allow: allow, CreatedBy: Admin}); err != nil {
+			t.Fatalf("Failed to create policy: %v", err)
+		}
+	}
+	setPolicy(sys.DefaultEnclaveName, "/v1/key/copy/*", "/v1/key/move/*")
+	setPolicy(Enclave, "/v1/key/copy/*", "/v1/key/move/*", "/v1/key/create/allowed-*")
+	for _, enclave := range enclaves {
+		if err = enclave.CreateIdentity(ctx, "transfer", identity, time.Time{}, nil); err != nil {
+			t.Fatalf("Failed to create identity: %v", err)
+		}
+	}
+	dataKey, err := key.Random(kes.AES256_GCM_SHA256, Admin)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	if err = enclaves[sys.DefaultEnclaveName].CreateKey(ctx, "my-key", dataKey); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+
+	transfer := func(newName string, move bool) error {
+		path := "/v1/key/copy/my-key"
+		if move {
+			path = "/v1/key/move/my-key"
+		}
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{certificate}}
+		return transferKey(vault, req, "my-key", Enclave, newName, move)
+	}
+	if err = transfer("allowed-1", false); err != nil {
+		t.Fatalf("Failed to copy key: %v", err)
+	}
+	if err = transfer("other-key", false); !errors.Is(err, kes.ErrNotAllowed) {
+		t.Fatalf("Copying key without create permission: got '%v' - want '%v'", err, kes.ErrNotAllowed)
+	}
+	if _, err = enclaves[Enclave].GetKey(ctx, "other-key"); err == nil {
+		t.Fatal("Copied key without create permission")
+	}
+	if err = transfer("allowed-2", true); !errors.Is(err, kes.ErrNotAllowed) {
+		t.Fatalf("Moving key without delete permission: got '%v' - want '%v'", err, kes.ErrNotAllowed)
+	}
+
+	setPolicy(sys.DefaultEnclaveName, "/v1/key/copy/*", "/v1/key/move/*", "/v1/key/delete/my-key")
+	if err = transfer("allowed-2", true); err != nil {
+		t.Fatalf("Failed to move key: %v", err)
+	}
+	if _, err = enclaves[sys.DefaultEnclaveName].GetKey(ctx, "my-key"); err == nil {
+		t.Fatal("Moved key still exists in source enclave")
+	}
+	for _, name := range []string{"allowed-1", "allowed-2"} {
+		if _, err = enclaves[Enclave].GetKey(ctx, name); err != nil {
+			t.Fatalf("Failed to get transferred key '%s': %v", name, err)
+		}
+	}
+}
+
+// newClientCertificate returns a self-signed client
+// certificate and its identity.
+func newClientCertificate(t *testing.T) (*x509.Certificate, kes.Identity) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate private key: %v", err)
+	}
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "kes-client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, &template, &template, publicKey, privateKey)
+	if err != nil {
+		t.Fatalf("Failed to create client certificate: %v", err)
+	}
+	certificate, err := x509.ParseCertificate(raw)
+	if err != nil {
+		t.Fatalf("Failed to parse client certificate: %v", err)
+	}
+	h := sha256.Sum256(certificate.RawSubjectPublicKeyInfo)
+	return certificate, kes.Identity(hex.EncodeToString(h[:]))
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2023, time.June, 15, 12, 30, 0, 0, time.UTC)
+	for i, test := range parseSinceTests {
+		since, err := parseSince(test.Since, now)
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: should have failed", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to parse since: %v", i, err)
+		}
+		if err == nil && !since.Equal(test.Time) {
+			t.Fatalf("Test %d: got '%v' - want '%v'", i, since, test.Time)
+		}
+	}
+}
+
+var parseSinceTests = []struct {
+	Since      string
+	Time       time.Time
+	ShouldFail bool
+}{
+	{Since: "", Time: time.Date(2023, time.June, 15, 0, 0, 0, 0, time.UTC)},                    // 0
+	{Since: "30d", Time: time.Date(2023, time.May, 16, 12, 30, 0, 0, time.UTC)},                // 1
+	{Since: "72h", Time: time.Date(2023, time.June, 12, 12, 30, 0, 0, time.UTC)},               // 2
+	{Since: "2023-06-01T00:00:00Z", Time: time.Date(2023, time.June, 1, 0, 0, 0, 0, time.UTC)}, // 3
+	{Since: "-1d", ShouldFail: true},                                                           // 4
+	{Since: "30 days", ShouldFail: true},                                                       // 5
+	{Since: "d", ShouldFail: true},                                                             // 6
+}
+
+func TestEdgeFeatures(t *testing.T) {
+	config := &EdgeRouterConfig{}
+	features := newManifestResponse(edgeFeatures(config)).Features
+	for _, feature := range []string{"oidc", "api_keys", "wasm_hooks", "enclave_keystores", "compression", "clustering", "kmip", "plugins"} {
+		enabled, ok := features[feature]
+		if !ok {
+			t.Fatalf("Feature '%s' is not reported", feature)
+		}
+		if enabled {
+			t.Fatalf("Feature '%s' is enabled although not configured", feature)
+		}
+	}
+	if !features["asymmetric_keys"] {
+		t.Fatal("Feature 'asymmetric_keys' is not enabled")
+	}
+
+	config = &EdgeRouterConfig{
+		OIDC:        &auth.OIDC{},
+		APIKeys:     true,
+		PolicyHooks: []*auth.PolicyHook{{}},
+		Enclaves:    map[string]*keystore.Cache{"tenant-1": nil},
+	}
+	features = newManifestResponse(edgeFeatures(config)).Features
+	for _, feature := range []string{"oidc", "api_keys", "wasm_hooks", "enclave_keystores"} {
+		if !features[feature] {
+			t.Fatalf("Feature '%s' is not enabled although configured", feature)
+		}
+	}
+}
+
+func TestLogAuditHold(t *testing.T) {
+	var errLog strings.Builder
+	config := &RouterConfig{ErrorLog: log.New(&errLog, "", 0)}
+
+	req := httptest.NewRequest(http.MethodDelete, "/v1/key/purge/my-key?enclave="+url.QueryEscape("tenant-1\nkes: forged entry"), nil)
+	logAuditHold(config, req, "purge key %q", "my-key")
+	if n := strings.Count(errLog.String(), "\n"); n != 1 {
+		t.Fatalf("Audit hold log contains '%d' lines: %q", n, errLog.String())
+	}
+	if !strings.Contains(errLog.String(), `in enclave "tenant-1\nkes: forged entry"`) {
+		t.Fatalf("Audit hold log does not quote the enclave: %q", errLog.String())
+	}
+}
+
+func TestAPIKeyDisabled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The server accepts TLS connections without client certificate
+	// since clients may authenticate with a JWT. Hence, it has to
+	// reject API keys explicitly.
+	server := httptest.NewTLSServer(NewEdgeRouter(&EdgeRouterConfig{
+		Keys:     keystore.NewCache(ctx, &mem.Store{}, &keystore.CacheConfig{}),
+		OIDC:     &auth.OIDC{},
+		AuditLog: log.New(io.Discard, "", 0),
+		ErrorLog: log.New(io.Discard, "", 0),
+		Metrics:  metric.New(),
+	}))
+	defer server.Close()
+
+	key, err := kes.GenerateAPIKey(nil)
+	if err != nil {
+		t.Fatalf("Failed to generate API key: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/v1/key/describe/my-key", nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+key.String())
+
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Invalid status: got '%d' - want '%d'", resp.StatusCode, http.StatusUnauthorized)
+	}
+	if body, _ := io.ReadAll(resp.Body); !strings.Contains(string(body), "not authenticated") {
+		t.Fatalf("Invalid response: %s", body)
+	}
+}
+
+func TestProbeHandler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The router only accepts requests forwarded by a TLS
+	// proxy. Probes sent to the probe handler are served
+	// nevertheless.
+	server := httptest.NewServer(ProbeHandler(NewEdgeRouter(&EdgeRouterConfig{
+		Keys:     keystore.NewCache(ctx, &mem.Store{}, &keystore.CacheConfig{}),
+		Proxy:    &auth.TLSProxy{},
+		AuditLog: log.New(io.Discard, "", 0),
+		ErrorLog: log.New(io.Discard, "", 0),
+		Metrics:  metric.New(),
+	})))
+	defer server.Close()
+
+	for path, want := range map[string]int{
+		"/healthz":   http.StatusOK,
+		"/readyz":    http.StatusOK,
+		"/v1/status": http.StatusNotFound,
+		"/v1/ready":  http.StatusNotFound,
+	} {
+		resp, err := server.Client().Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Fatalf("Invalid status of '%s': got '%d' - want '%d'", path, resp.StatusCode, want)
+		}
+	}
+}
+
+func TestEnclavePolicies(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	alice, err := kes.GenerateAPIKey(nil)
+	if err != nil {
+		t.Fatalf("Failed to generate API key: %v", err)
+	}
+	bob, err := kes.GenerateAPIKey(nil)
+	if err != nil {
+		t.Fatalf("Failed to generate API key: %v", err)
+	}
+	policy := &auth.Policy{Allow: []string{"/v1/key/describe/*"}}
+
+	// Alice is assigned to a policy of the default enclave and
+	// Bob to a policy with the same name of the 'tenant' enclave.
+	server := httptest.NewTLSServer(NewEdgeRouter(&EdgeRouterConfig{
+		Keys: keystore.NewCache(ctx, &mem.Store{}, &keystore.CacheConfig{}),
+		Enclaves: map[string]*keystore.Cache{
+			"tenant": keystore.NewCache(ctx, &mem.Store{}, &keystore.CacheConfig{}),
+		},
+		Policies: EnclavePolicies(testPolicySet{"my-policy": policy}, map[string]auth.PolicySet{
+			"tenant": testPolicySet{"my-policy": policy},
+		}),
+		Identities: EnclaveIdentities(testIdentitySet{alice.Identity(): "my-policy"}, map[string]auth.IdentitySet{
+			"tenant": testIdentitySet{bob.Identity(): "my-policy"},
+		}),
+		APIKeys:  true,
+		AuditLog: log.New(io.Discard, "", 0),
+		ErrorLog: log.New(io.Discard, "", 0),
+		Metrics:  metric.New(),
+	}))
+	defer server.Close()
+
+	for i, test := range []struct {
+		Key     kes.APIKey
+		Enclave string
+		Status  int
+	}{
+		{Key: alice, Enclave: "", Status: http.StatusNotFound},                     // 0
+		{Key: alice, Enclave: sys.DefaultEnclaveName, Status: http.StatusNotFound}, // 1
+		{Key: alice, Enclave: "tenant", Status: http.StatusForbidden},              // 2
+		{Key: bob, Enclave: "", Status: http.StatusForbidden},                      // 3
+		{Key: bob, Enclave: "tenant", Status: http.StatusNotFound},                 // 4
+		{Key: bob, Enclave: "unknown", Status: http.StatusNotFound},                // 5
+	} {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/v1/key/describe/my-key?enclave="+test.Enclave, nil)
+		if err != nil {
+			t.Fatalf("Test %d: failed to create request: %v", i, err)
+		}
+		req.Header.Set("Authorization", "Bearer "+test.Key.String())
+
+		resp, err := server.Client().Do(req)
+		if err != nil {
+			t.Fatalf("Test %d: failed to send request: %v", i, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != test.Status {
+			t.Fatalf("Test %d: invalid status: got '%d' - want '%d': %s", i, resp.StatusCode, test.Status, body)
+		}
+	}
+}
+
+func TestEdgeManifest(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	alice, err := kes.GenerateAPIKey(nil)
+	if err != nil {
+		t.Fatalf("Failed to generate API key: %v", err)
+	}
+	bob, err := kes.GenerateAPIKey(nil)
+	if err != nil {
+		t.Fatalf("Failed to generate API key: %v", err)
+	}
+	newServer := func(apiConfig map[string]Config) *httptest.Server {
+		return httptest.NewTLSServer(NewEdgeRouter(&EdgeRouterConfig{
+			Keys: keystore.NewCache(ctx, &mem.Store{}, &keystore.CacheConfig{}),
+			Policies: testPolicySet{
+				"my-policy": &auth.Policy{Allow: []string{"/v1/version"}},
+			},
+			Identities: testIdentitySet{alice.Identity(): "my-policy"},
+			APIKeys:    true,
+			APIConfig:  apiConfig,
+			AuditLog:   log.New(io.Discard, "", 0),
+			ErrorLog:   log.New(io.Discard, "", 0),
+			Metrics:    metric.New(),
+		}))
+	}
+	server := newServer(nil)
+	defer server.Close()
+	skipAuthServer := newServer(map[string]Config{"/v1/version": {InsecureSkipAuth: true}})
+	defer skipAuthServer.Close()
+
+	for i, test := range []struct {
+		Server   *httptest.Server
+		Key      kes.APIKey
+		Manifest bool
+	}{
+		{Server: server, Key: alice, Manifest: true},       // 0
+		{Server: server, Key: bob, Manifest: false},        // 1
+		{Server: skipAuthServer, Key: bob, Manifest: true}, // 2
+	} {
+		req, err := http.NewRequest(http.MethodGet, test.Server.URL+"/v1/version", nil)
+		if err != nil {
+			t.Fatalf("Test %d: failed to create request: %v", i, err)
+		}
+		req.Header.Set("Authorization", "Bearer "+test.Key.String())
+
+		resp, err := test.Server.Client().Do(req)
+		if err != nil {
+			t.Fatalf("Test %d: failed to send request: %v", i, err)
+		}
+		var response map[string]any
+		err = json.NewDecoder(resp.Body).Decode(&response)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("Test %d: failed to decode response: %v", i, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Test %d: invalid status: got '%d' - want '%d'", i, resp.StatusCode, http.StatusOK)
+		}
+		if _, ok := response["version"]; !ok {
+			t.Fatalf("Test %d: response does not contain the server version", i)
+		}
+		if test.Manifest {
+			for _, field := range []string{"commit", "go_version", "features", "api_levels"} {
+				if _, ok := response[field]; !ok {
+					t.Fatalf("Test %d: manifest does not contain '%s'", i, field)
+				}
+			}
+		} else if len(response) != 1 {
+			t.Fatalf("Test %d: unauthenticated response contains more than the server version: %v", i, response)
+		}
+	}
+}
+
+// testPolicySet is an auth.PolicySet that contains
+// a fixed set of policies.
+type testPolicySet map[string]*auth.Policy
+
+func (s testPolicySet) Set(context.Context, string, *auth.Policy) error { return kes.ErrNotAllowed }
+
+func (s testPolicySet) Get(_ context.Context, name string) (*auth.Policy, error) {
+	if policy, ok := s[name]; ok {
+		return policy, nil
+	}
+	return nil, kes.ErrPolicyNotFound
+}
+
+func (s testPolicySet) Delete(context.Context, string) error { return kes.ErrNotAllowed }
+
+func (s testPolicySet) List(context.Context) (auth.PolicyIterator, error) {
+	return nil, kes.ErrNotAllowed
+}
+
+// testIdentitySet is an auth.IdentitySet that contains
+// a fixed set of identities and their policies.
+type testIdentitySet map[kes.Identity]string
+
+func (s testIdentitySet) Admin(context.Context) (kes.Identity, error) { return "admin", nil }
+
+func (s testIdentitySet) Assign(context.Context, string, kes.Identity) error {
+	return kes.ErrNotAllowed
+}
+
+func (s testIdentitySet) Get(_ context.Context, identity kes.Identity) (auth.IdentityInfo, error) {
+	if policy, ok := s[identity]; ok {
+		return auth.IdentityInfo{Policy: policy}, nil
+	}
+	return auth.IdentityInfo{}, kes.ErrIdentityNotFound
+}
+
+func (s testIdentitySet) Delete(context.Context, kes.Identity) error { return kes.ErrNotAllowed }
+
+func (s testIdentitySet) List(context.Context) (auth.IdentityIterator, error) {
+	return nil, kes.ErrNotAllowed
+}
+
+func TestEdgeExpireKey(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	certificate, identity := newClientCertificate(t)
+	dataKey, err := key.Random(kes.AES256_GCM_SHA256, identity)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	expiresAt := time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	for i, test := range []struct {
+		Store  kv.Store[string, []byte]
+		Status int
+	}{
+		{Store: &mem.Store{}, Status: http.StatusOK},                            // 0
+		{Store: noUpdateStore{&mem.Store{}}, Status: http.StatusNotImplemented}, // 1
+	} {
+		keys := keystore.NewCache(ctx, test.Store, &keystore.CacheConfig{})
+		if err = keys.Create(ctx, "my-key", dataKey); err != nil {
+			t.Fatalf("Test %d: failed to create key: %v", i, err)
+		}
+		a := edgeExpireKey(&EdgeRouterConfig{
+			Keys:       keys,
+			Identities: adminIdentitySet{admin: identity},
+			Metrics:    metric.New(),
+			AuditLog:   log.New(io.Discard, "", 0),
+		})
+
+		req := httptest.NewRequest(a.Method, a.Path+"my-key", strings.NewReader(`{"expires_at":"`+expiresAt.Format(time.RFC3339)+`"}`))
+		req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{certificate}}
+
+		w := httptest.NewRecorder()
+		a.Handler.ServeHTTP(w, req)
+		if w.Code != test.Status {
+			t.Fatalf("Test %d: invalid status code: got '%d' - want '%d': %s", i, w.Code, test.Status, w.Body)
+		}
+		if test.Status != http.StatusOK {
+			continue
+		}
+
+		k, err := keys.Get(ctx, "my-key")
+		if err != nil {
+			t.Fatalf("Test %d: failed to get key: %v", i, err)
+		}
+		if !k.ExpiresAt().Equal(expiresAt) {
+			t.Fatalf("Test %d: invalid expiry: got '%v' - want '%v'", i, k.ExpiresAt(), expiresAt)
+		}
+	}
+}
+
+// noUpdateStore is a kv.Store that cannot update
+// entries in place.
+type noUpdateStore struct {
+	*mem.Store
+}
+
+func (noUpdateStore) Update(context.Context, string, []byte, []byte) error { return kv.ErrNotSupported }
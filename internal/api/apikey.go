@@ -0,0 +1,26 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/minio/kes/internal/auth"
+)
+
+// apiKeys returns a handler that accepts API keys, sent as
+// bearer tokens by clients without a client certificate,
+// if enabled is true. Otherwise, requests that present an
+// API key are rejected when their identity gets verified.
+//
+// If enabled is false, apiKeys returns f.
+func apiKeys(enabled bool, f http.Handler) http.Handler {
+	if !enabled {
+		return f
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.ServeHTTP(w, auth.WithAPIKeys(r))
+	})
+}
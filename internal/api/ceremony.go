@@ -0,0 +1,457 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/audit"
+	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/cpu"
+	"github.com/minio/kes/internal/fips"
+	"github.com/minio/kes/internal/key"
+	"github.com/minio/kes/internal/sys"
+)
+
+// Key ceremony limits.
+const (
+	defaultCeremonyWindow = 1 * time.Hour
+	maxCeremonyWindow     = 24 * time.Hour
+	maxCeremonies         = 100 // Max. number of ongoing ceremonies per enclave
+)
+
+var (
+	errCeremonyNotFound  = kes.NewError(http.StatusNotFound, "key ceremony does not exist")
+	errTooManyCeremonies = kes.NewError(http.StatusTooManyRequests, "too many ongoing key ceremonies")
+)
+
+// ceremonies keeps track of all ongoing key ceremonies.
+//
+// Ceremonies are kept in memory only. A ceremony that
+// has not been completed when the server restarts has
+// to be started again.
+type ceremonies struct {
+	lock       sync.Mutex
+	ceremonies map[ceremonyID]*key.Ceremony
+}
+
+// ceremonyID identifies the ceremony for a key within
+// an enclave.
+type ceremonyID struct {
+	Enclave string
+	Name    string
+}
+
+// Add adds a new ceremony for the given key ID. It returns an
+// error if there is an ongoing ceremony for the same key ID
+// or if there are too many ongoing ceremonies within the
+// enclave.
+func (c *ceremonies) Add(id ceremonyID, ceremony *key.Ceremony) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.ceremonies == nil {
+		c.ceremonies = map[ceremonyID]*key.Ceremony{}
+	}
+	var n int
+	for k, v := range c.ceremonies {
+		if v.Expired() {
+			delete(c.ceremonies, k)
+			continue
+		}
+		if k.Enclave == id.Enclave {
+			n++
+		}
+	}
+	if _, ok := c.ceremonies[id]; ok {
+		return kes.NewError(http.StatusBadRequest, "key ceremony already in progress")
+	}
+	if n >= maxCeremonies {
+		return errTooManyCeremonies
+	}
+	c.ceremonies[id] = ceremony
+	return nil
+}
+
+// Get returns the ongoing ceremony for the given key ID.
+func (c *ceremonies) Get(id ceremonyID) (*key.Ceremony, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	ceremony, ok := c.ceremonies[id]
+	if !ok {
+		return nil, errCeremonyNotFound
+	}
+	if ceremony.Expired() {
+		delete(c.ceremonies, id)
+		return nil, key.ErrCeremonyExpired
+	}
+	return ceremony, nil
+}
+
+// Delete removes the ceremony for the given key ID.
+func (c *ceremonies) Delete(id ceremonyID) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	delete(c.ceremonies, id)
+}
+
+// ceremonyIDFromRequest returns the ID of the ceremony for
+// the named key within the enclave specified by the request.
+func ceremonyIDFromRequest(r *http.Request, name string) ceremonyID {
+	enclave := r.URL.Query().Get("enclave")
+	if enclave == "" {
+		enclave = sys.DefaultEnclaveName
+	}
+	return ceremonyID{Enclave: enclave, Name: name}
+}
+
+// ceremonyRequest is the request body for starting
+// a new key ceremony.
+type ceremonyRequest struct {
+	Custodians int    `json:"custodians"`
+	Window     string `json:"window"`
+}
+
+// ceremonyResponse is the response body of a key ceremony
+// contribution. It contains the audit records of all
+// contributions received so far.
+type ceremonyResponse struct {
+	Custodians    int                    `json:"custodians"`
+	Deadline      time.Time              `json:"deadline"`
+	Complete      bool                   `json:"complete"`
+	Contributions []contributionResponse `json:"contributions,omitempty"`
+}
+
+type contributionResponse struct {
+	Identity   kes.Identity `json:"identity"`
+	Commitment []byte       `json:"commitment"`
+	Time       time.Time    `json:"time"`
+}
+
+// newCeremony parses the request body and returns a new key
+// ceremony owned by the requesting identity.
+//
+// The key uses the fastest encryption algorithm available
+// that is approved by the crypto policy, if not nil.
+func newCeremony(r *http.Request, crypto *sys.CryptoPolicy) (*key.Ceremony, error) {
+	var req ceremonyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, kes.NewError(http.StatusBadRequest, err.Error())
+	}
+	window := defaultCeremonyWindow
+	if req.Window != "" {
+		var err error
+		if window, err = time.ParseDuration(req.Window); err != nil {
+			return nil, kes.NewError(http.StatusBadRequest, "invalid key ceremony window: "+err.Error())
+		}
+		if window <= 0 || window > maxCeremonyWindow {
+			return nil, kes.NewError(http.StatusBadRequest, "invalid key ceremony window: window must be between 0s and 24h")
+		}
+	}
+
+	var algorithm kes.KeyAlgorithm
+	if fips.Enabled || cpu.HasAESGCM() {
+		algorithm = kes.AES256_GCM_SHA256
+	} else {
+		algorithm = kes.XCHACHA20_POLY1305
+	}
+	if crypto != nil {
+		algorithm = crypto.Algorithm(algorithm)
+	}
+	return key.NewCeremony(algorithm, req.Custodians, window, auth.Identify(r))
+}
+
+// contribute parses the request body and adds the contribution
+// of the requesting identity to the ceremony.
+//
+// If the ceremony is already complete and the requesting identity
+// has contributed to it, contribute returns the resulting key
+// again such that custodians can retry storing the key.
+func contribute(r *http.Request, ceremony *key.Ceremony) (key.Key, ceremonyResponse, error) {
+	type Request struct {
+		Entropy []byte `json:"entropy"`
+	}
+	identity := auth.Identify(r)
+	k, complete := ceremony.Result(identity)
+	if !complete {
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return key.Key{}, ceremonyResponse{}, kes.NewError(http.StatusBadRequest, err.Error())
+		}
+
+		var err error
+		if k, complete, err = ceremony.Contribute(identity, req.Entropy); err != nil {
+			return key.Key{}, ceremonyResponse{}, err
+		}
+	}
+
+	resp := ceremonyResponse{
+		Custodians: ceremony.Custodians(),
+		Deadline:   ceremony.Deadline(),
+		Complete:   complete,
+	}
+	for _, c := range ceremony.Contributions() {
+		resp.Contributions = append(resp.Contributions, contributionResponse{
+			Identity:   c.Identity,
+			Commitment: c.Commitment,
+			Time:       c.Time,
+		})
+	}
+	return k, resp, nil
+}
+
+func beginCeremony(config *RouterConfig, ceremonies *ceremonies) API {
+	const (
+		Method      = http.MethodPost
+		APIPath     = "/v1/key/ceremony/begin/"
+		MaxBody     = int64(1 * mem.KiB)
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	type Response struct {
+		Custodians int       `json:"custodians"`
+		Deadline   time.Time `json:"deadline"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := pathFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		var ceremony *key.Ceremony
+		if err = Sync(config.Vault.RLocker(), func() error {
+			enclave, err := enclaveFromRequest(config.Vault, r)
+			if err != nil {
+				return err
+			}
+			return Sync(enclave.RLocker(), func() error {
+				if err = enclave.VerifyRequest(r); err != nil {
+					return err
+				}
+				if ceremony, err = newCeremony(r, enclave.CryptoPolicy()); err != nil {
+					return err
+				}
+				if _, err = enclave.GetKey(r.Context(), name); err == nil {
+					return kes.ErrKeyExists
+				}
+				if !errors.Is(err, kes.ErrKeyNotFound) {
+					return err
+				}
+				return ceremonies.Add(ceremonyIDFromRequest(r, name), ceremony)
+			})
+		}); err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{
+			Custodians: ceremony.Custodians(),
+			Deadline:   ceremony.Deadline(),
+		})
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func contributeCeremony(config *RouterConfig, ceremonies *ceremonies) API {
+	const (
+		Method      = http.MethodPost
+		APIPath     = "/v1/key/ceremony/contribute/"
+		MaxBody     = int64(1 * mem.KiB)
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := pathFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		resp, err := VSync(config.Vault.RLocker(), func() (ceremonyResponse, error) {
+			enclave, err := enclaveFromRequest(config.Vault, r)
+			if err != nil {
+				return ceremonyResponse{}, err
+			}
+			return VSync(enclave.Locker(), func() (ceremonyResponse, error) {
+				if err = enclave.VerifyRequest(r); err != nil {
+					return ceremonyResponse{}, err
+				}
+
+				id := ceremonyIDFromRequest(r, name)
+				ceremony, err := ceremonies.Get(id)
+				if err != nil {
+					return ceremonyResponse{}, err
+				}
+				key, resp, err := contribute(r, ceremony)
+				if err != nil || !resp.Complete {
+					return resp, err
+				}
+
+				// Only remove the ceremony once the key has been created.
+				// Otherwise, the custodians could not retry.
+				if err = enclave.CreateKey(r.Context(), name, key); err != nil {
+					return ceremonyResponse{}, err
+				}
+				ceremonies.Delete(id)
+				return resp, nil
+			})
+		})
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(resp)
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func edgeBeginCeremony(config *EdgeRouterConfig, ceremonies *ceremonies) API {
+	var (
+		Method      = http.MethodPost
+		APIPath     = "/v1/key/ceremony/begin/"
+		MaxBody     = int64(1 * mem.KiB)
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	if c, ok := config.APIConfig[APIPath]; ok {
+		if c.Timeout > 0 {
+			Timeout = c.Timeout
+		}
+	}
+	type Response struct {
+		Custodians int       `json:"custodians"`
+		Deadline   time.Time `json:"deadline"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := pathFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		if err = auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
+			return err
+		}
+		store, err := keysFromRequest(config, r)
+		if err != nil {
+			return err
+		}
+
+		ceremony, err := newCeremony(r, nil)
+		if err != nil {
+			return err
+		}
+		if _, err = store.Get(r.Context(), name); err == nil {
+			return kes.ErrKeyExists
+		}
+		if !errors.Is(err, kes.ErrKeyNotFound) {
+			return err
+		}
+		if err = ceremonies.Add(ceremonyIDFromRequest(r, name), ceremony); err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{
+			Custodians: ceremony.Custodians(),
+			Deadline:   ceremony.Deadline(),
+		})
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func edgeContributeCeremony(config *EdgeRouterConfig, ceremonies *ceremonies) API {
+	var (
+		Method      = http.MethodPost
+		APIPath     = "/v1/key/ceremony/contribute/"
+		MaxBody     = int64(1 * mem.KiB)
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	if c, ok := config.APIConfig[APIPath]; ok {
+		if c.Timeout > 0 {
+			Timeout = c.Timeout
+		}
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := pathFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		if err = auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
+			return err
+		}
+		store, err := keysFromRequest(config, r)
+		if err != nil {
+			return err
+		}
+
+		id := ceremonyIDFromRequest(r, name)
+		ceremony, err := ceremonies.Get(id)
+		if err != nil {
+			return err
+		}
+		key, resp, err := contribute(r, ceremony)
+		if err != nil {
+			return err
+		}
+		if resp.Complete {
+			// Only remove the ceremony once the key has been created.
+			// Otherwise, the custodians could not retry.
+			if err = store.Create(r.Context(), name, key); err != nil {
+				return err
+			}
+			ceremonies.Delete(id)
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(resp)
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
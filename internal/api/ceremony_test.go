@@ -0,0 +1,38 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/key"
+)
+
+func TestCeremoniesLimit(t *testing.T) {
+	var c ceremonies
+	for i := 0; i < maxCeremonies; i++ {
+		ceremony, err := key.NewCeremony(kes.AES256_GCM_SHA256, 2, time.Minute, "admin")
+		if err != nil {
+			t.Fatalf("Failed to start key ceremony: %v", err)
+		}
+		if err = c.Add(ceremonyID{Enclave: "tenant-1", Name: "key-" + strconv.Itoa(i)}, ceremony); err != nil {
+			t.Fatalf("Failed to add ceremony %d: %v", i, err)
+		}
+	}
+
+	ceremony, err := key.NewCeremony(kes.AES256_GCM_SHA256, 2, time.Minute, "admin")
+	if err != nil {
+		t.Fatalf("Failed to start key ceremony: %v", err)
+	}
+	if err = c.Add(ceremonyID{Enclave: "tenant-1", Name: "my-key"}, ceremony); err != errTooManyCeremonies {
+		t.Fatalf("Adding too many ceremonies: got '%v' - want '%v'", err, errTooManyCeremonies)
+	}
+	if err = c.Add(ceremonyID{Enclave: "tenant-2", Name: "my-key"}, ceremony); err != nil {
+		t.Fatalf("Failed to add ceremony to other enclave: %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/minio/kes/internal/audit"
+	"github.com/minio/kes/internal/auth"
+)
+
+// checkClientTLS returns a handler that checks whether
+// requests comply with the client TLS policy. Requests
+// that violate the policy are marked as such, and
+// therefore rejected if the policy is enforced, and
+// recorded in the report, if not nil.
+//
+// If policy is nil, checkClientTLS returns f.
+func checkClientTLS(policy *auth.ClientTLSPolicy, report *audit.TLSReport, f http.Handler) http.Handler {
+	if policy == nil {
+		return f
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reason := policy.Check(r)
+		if reason == "" {
+			f.ServeHTTP(w, r)
+			return
+		}
+
+		if report != nil {
+			var ip string
+			if addr := auth.ForwardedIPFromContext(r.Context()); addr != nil {
+				ip = addr.String()
+			} else if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+				ip = host
+			}
+			report.Add(auth.Identify(r), ip, reason, time.Now())
+		}
+		f.ServeHTTP(w, auth.MarkTLSViolation(r, reason, policy.Enforce))
+	})
+}
@@ -0,0 +1,462 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/audit"
+	"github.com/minio/kes/internal/auth"
+)
+
+// Failure scenarios that can be injected by a Drill.
+const (
+	// DrillBackendOutage makes all APIs that access the
+	// key store fail as if the key store was unreachable.
+	DrillBackendOutage = "backend-outage"
+
+	// DrillCertExpiry makes the server present an expired
+	// TLS certificate such that clients cannot establish
+	// new connections.
+	DrillCertExpiry = "cert-expiry"
+
+	// DrillLatency delays all requests by a fixed duration.
+	DrillLatency = "latency"
+)
+
+// Limits and defaults for drills.
+const (
+	defaultDrillDuration = 5 * time.Minute
+	maxDrillDuration     = 1 * time.Hour
+	defaultDrillLatency  = 1 * time.Second
+	maxDrillLatency      = 1 * time.Minute
+)
+
+// errDrillOutage is returned by APIs that access the key
+// store while a backend-outage drill is running.
+var errDrillOutage = kes.NewError(http.StatusBadGateway, "drill: key store is not reachable")
+
+// drillAPIs contains the API paths that access the key
+// store. They fail during a backend-outage drill.
+var drillAPIs = []string{
+	"/v1/key/",
+	"/v1/secret/",
+	"/v1/ready",
+}
+
+// A Drill injects controlled failures into a server such
+// that operators can rehearse failover and verify their
+// monitoring. A drill only affects the server it runs on
+// and is rolled back automatically once its deadline has
+// passed.
+//
+// A nil Drill never injects any failures.
+type Drill struct {
+	// ExpireCertificate is called with true when a cert-expiry
+	// drill starts and with false once it ends. If nil, the
+	// cert-expiry scenario is not supported.
+	ExpireCertificate func(expired bool) error
+
+	lock  sync.Mutex
+	timer *time.Timer
+	state atomic.Pointer[drillState]
+}
+
+// drillState describes a running drill.
+type drillState struct {
+	Scenario string
+	Latency  time.Duration
+	Until    time.Time
+}
+
+// Start starts a drill for the given scenario that is rolled
+// back after the given duration. The latency is only used by
+// the latency scenario.
+//
+// Any running drill is stopped before the new one starts.
+func (d *Drill) Start(scenario string, duration, latency time.Duration) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if err := d.stop(); err != nil {
+		return err
+	}
+	if scenario == DrillCertExpiry {
+		if d.ExpireCertificate == nil {
+			return kes.NewError(http.StatusNotImplemented, "drill: scenario '"+scenario+"' is not supported")
+		}
+		if err := d.ExpireCertificate(true); err != nil {
+			return err
+		}
+	}
+
+	state := &drillState{
+		Scenario: scenario,
+		Latency:  latency,
+		Until:    time.Now().Add(duration).UTC(),
+	}
+	d.state.Store(state)
+	d.timer = time.AfterFunc(duration, func() {
+		d.lock.Lock()
+		defer d.lock.Unlock()
+
+		if d.state.Load() == state {
+			d.stop()
+		}
+	})
+	return nil
+}
+
+// Stop stops the running drill, if any.
+func (d *Drill) Stop() error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.stop()
+}
+
+func (d *Drill) stop() error {
+	state := d.state.Swap(nil)
+	if state == nil {
+		return nil
+	}
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	if state.Scenario == DrillCertExpiry {
+		return d.ExpireCertificate(false)
+	}
+	return nil
+}
+
+// Scenario returns the scenario of the running drill and
+// when it ends. It returns an empty scenario if no drill
+// is running.
+func (d *Drill) Scenario() (string, time.Time) {
+	if state := d.running(); state != nil {
+		return state.Scenario, state.Until
+	}
+	return "", time.Time{}
+}
+
+// drillInfo describes a running drill in the server status.
+type drillInfo struct {
+	Scenario string    `json:"scenario"`
+	Until    time.Time `json:"until"`
+}
+
+// newDrillInfo returns a description of the running
+// drill or nil if no drill is running.
+func newDrillInfo(d *Drill) *drillInfo {
+	state := d.running()
+	if state == nil {
+		return nil
+	}
+	return &drillInfo{
+		Scenario: state.Scenario,
+		Until:    state.Until,
+	}
+}
+
+// running returns the state of the running drill or
+// nil if no drill is running.
+func (d *Drill) running() *drillState {
+	if d == nil {
+		return nil
+	}
+	return d.state.Load()
+}
+
+// inject injects the failure of the running drill, if any,
+// into the request. It reports whether the request has been
+// answered and must not be handled any further.
+func (d *Drill) inject(w http.ResponseWriter, req *http.Request) bool {
+	state := d.running()
+	if state == nil || strings.HasPrefix(req.URL.Path, "/v1/drill/") {
+		return false
+	}
+
+	switch state.Scenario {
+	case DrillLatency:
+		timer := time.NewTimer(state.Latency)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+		}
+	case DrillBackendOutage:
+		for _, path := range drillAPIs {
+			if strings.HasPrefix(req.URL.Path, path) {
+				Fail(w, errDrillOutage)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseDrill parses a drill scenario and its duration
+// and latency.
+func parseDrill(scenario, duration, latency string) (time.Duration, time.Duration, error) {
+	switch scenario {
+	case DrillBackendOutage, DrillCertExpiry, DrillLatency:
+	case "":
+		return 0, 0, kes.NewError(http.StatusBadRequest, "drill: no scenario specified")
+	default:
+		return 0, 0, kes.NewError(http.StatusBadRequest, "drill: invalid scenario '"+scenario+"'")
+	}
+
+	d := defaultDrillDuration
+	if duration != "" {
+		var err error
+		if d, err = time.ParseDuration(duration); err != nil {
+			return 0, 0, kes.NewError(http.StatusBadRequest, "drill: invalid duration: "+err.Error())
+		}
+		if d <= 0 || d > maxDrillDuration {
+			return 0, 0, kes.NewError(http.StatusBadRequest, "drill: duration must be positive and at most "+maxDrillDuration.String())
+		}
+	}
+
+	var l time.Duration
+	if scenario == DrillLatency {
+		l = defaultDrillLatency
+		if latency != "" {
+			var err error
+			if l, err = time.ParseDuration(latency); err != nil {
+				return 0, 0, kes.NewError(http.StatusBadRequest, "drill: invalid latency: "+err.Error())
+			}
+			if l <= 0 || l > maxDrillLatency {
+				return 0, 0, kes.NewError(http.StatusBadRequest, "drill: latency must be positive and at most "+maxDrillLatency.String())
+			}
+		}
+	}
+	return d, l, nil
+}
+
+func startDrill(config *RouterConfig, drill *Drill) API {
+	const (
+		Method      = http.MethodPost
+		APIPath     = "/v1/drill/start"
+		MaxBody     = int64(1 * mem.KiB)
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	type Request struct {
+		Scenario string `json:"scenario"`
+		Duration string `json:"duration,omitempty"`
+		Latency  string `json:"latency,omitempty"`
+	}
+	type Response struct {
+		Scenario string    `json:"scenario"`
+		Until    time.Time `json:"until"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		if err := Sync(config.Vault.RLocker(), func() error {
+			sysAdmin, err := config.Vault.Admin(r.Context())
+			if err != nil {
+				return err
+			}
+			if auth.Identify(r) != sysAdmin {
+				return kes.ErrNotAllowed
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		duration, latency, err := parseDrill(req.Scenario, req.Duration, req.Latency)
+		if err != nil {
+			return err
+		}
+		if err = drill.Start(req.Scenario, duration, latency); err != nil {
+			return err
+		}
+		scenario, until := drill.Scenario()
+		config.ErrorLog.Printf("drill '%s' started by '%s': rolling back at %s", scenario, auth.Identify(r), until.Format(time.RFC3339))
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{
+			Scenario: scenario,
+			Until:    until,
+		})
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func stopDrill(config *RouterConfig, drill *Drill) API {
+	const (
+		Method  = http.MethodPost
+		APIPath = "/v1/drill/stop"
+		MaxBody = 0
+		Timeout = 15 * time.Second
+		Verify  = true
+	)
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		if err := Sync(config.Vault.RLocker(), func() error {
+			sysAdmin, err := config.Vault.Admin(r.Context())
+			if err != nil {
+				return err
+			}
+			if auth.Identify(r) != sysAdmin {
+				return kes.ErrNotAllowed
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		if scenario, _ := drill.Scenario(); scenario != "" {
+			if err := drill.Stop(); err != nil {
+				return err
+			}
+			config.ErrorLog.Printf("drill '%s' stopped by '%s'", scenario, auth.Identify(r))
+		}
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func edgeStartDrill(config *EdgeRouterConfig, drill *Drill) API {
+	var (
+		Method      = http.MethodPost
+		APIPath     = "/v1/drill/start"
+		MaxBody     = int64(1 * mem.KiB)
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	if c, ok := config.APIConfig[APIPath]; ok {
+		if c.Timeout > 0 {
+			Timeout = c.Timeout
+		}
+	}
+	type Request struct {
+		Scenario string `json:"scenario"`
+		Duration string `json:"duration,omitempty"`
+		Latency  string `json:"latency,omitempty"`
+	}
+	type Response struct {
+		Scenario string    `json:"scenario"`
+		Until    time.Time `json:"until"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		if err := verifyEdgeAdmin(r, config); err != nil {
+			return err
+		}
+
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		duration, latency, err := parseDrill(req.Scenario, req.Duration, req.Latency)
+		if err != nil {
+			return err
+		}
+		if err = drill.Start(req.Scenario, duration, latency); err != nil {
+			return err
+		}
+		scenario, until := drill.Scenario()
+		config.ErrorLog.Printf("drill '%s' started by '%s': rolling back at %s", scenario, auth.Identify(r), until.Format(time.RFC3339))
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{
+			Scenario: scenario,
+			Until:    until,
+		})
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func edgeStopDrill(config *EdgeRouterConfig, drill *Drill) API {
+	var (
+		Method  = http.MethodPost
+		APIPath = "/v1/drill/stop"
+		MaxBody int64
+		Timeout = 15 * time.Second
+		Verify  = true
+	)
+	if c, ok := config.APIConfig[APIPath]; ok {
+		if c.Timeout > 0 {
+			Timeout = c.Timeout
+		}
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		if err := verifyEdgeAdmin(r, config); err != nil {
+			return err
+		}
+
+		if scenario, _ := drill.Scenario(); scenario != "" {
+			if err := drill.Stop(); err != nil {
+				return err
+			}
+			config.ErrorLog.Printf("drill '%s' stopped by '%s'", scenario, auth.Identify(r))
+		}
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+// verifyEdgeAdmin returns an error if the request has not
+// been sent by the admin of the edge server.
+func verifyEdgeAdmin(r *http.Request, config *EdgeRouterConfig) error {
+	if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
+		return err
+	}
+	admin, err := config.Identities.Admin(r.Context())
+	if err != nil {
+		return err
+	}
+	if auth.Identify(r) != admin {
+		return kes.ErrNotAllowed
+	}
+	return nil
+}
@@ -6,13 +6,18 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"aead.dev/mem"
 	"github.com/minio/kes-go"
 	"github.com/minio/kes/internal/audit"
 	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/key"
 	"github.com/minio/kes/internal/sys"
 )
 
@@ -25,7 +30,19 @@ func createEnclave(config *RouterConfig) API {
 		Verify  = true
 	)
 	type Request struct {
-		Admin kes.Identity `json:"admin"`
+		Admin        kes.Identity `json:"admin"`
+		KeyRetention string       `json:"key_retention"` // optional
+		AuditHold    bool         `json:"audit_hold"`    // optional
+
+		MaxKeys       int     `json:"max_keys"`       // optional
+		MaxIdentities int     `json:"max_identities"` // optional
+		RequestRate   float64 `json:"request_rate"`   // optional
+
+		Description string            `json:"description"` // optional
+		Labels      map[string]string `json:"labels"`      // optional
+
+		DefaultPolicy string        `json:"default_policy"` // optional
+		CryptoPolicy  *cryptoPolicy `json:"crypto_policy"`  // optional
 	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
 		name, err := nameFromRequest(r, APIPath)
@@ -55,10 +72,51 @@ func createEnclave(config *RouterConfig) API {
 			if req.Admin == sysAdmin {
 				return kes.NewError(http.StatusBadRequest, "admin identity cannot be system admin")
 			}
+			retention, err := parseKeyRetention(req.KeyRetention)
+			if err != nil {
+				return err
+			}
+			if req.AuditHold && retention <= 0 {
+				return kes.NewError(http.StatusBadRequest, "audit hold requires a key retention")
+			}
+			if err = verifyQuota(req.MaxKeys, req.MaxIdentities, req.RequestRate); err != nil {
+				return err
+			}
+			if err = verifyDescription(req.Description); err != nil {
+				return err
+			}
+			if err = verifyLabels(req.Labels); err != nil {
+				return err
+			}
+			if req.DefaultPolicy != "" {
+				if err = verifyName(req.DefaultPolicy); err != nil {
+					return err
+				}
+			}
+			var crypto sys.CryptoPolicy
+			if req.CryptoPolicy != nil {
+				if crypto, err = req.CryptoPolicy.Parse(); err != nil {
+					return err
+				}
+			}
 			if _, err = config.Vault.CreateEnclave(r.Context(), name, req.Admin); err != nil {
 				return err
 			}
-			return nil
+			if retention > 0 || req.MaxKeys > 0 || req.MaxIdentities > 0 || req.RequestRate > 0 || req.Description != "" || len(req.Labels) > 0 || req.DefaultPolicy != "" || !crypto.IsZero() {
+				_, err = config.Vault.UpdateEnclave(r.Context(), name, func(info *sys.EnclaveInfo) error {
+					info.KeyRetention = retention
+					info.AuditHold = req.AuditHold
+					info.MaxKeys = req.MaxKeys
+					info.MaxIdentities = req.MaxIdentities
+					info.RequestRate = req.RequestRate
+					info.Description = req.Description
+					info.Labels = req.Labels
+					info.DefaultPolicy = req.DefaultPolicy
+					info.CryptoPolicy = crypto
+					return nil
+				})
+			}
+			return err
 		}); err != nil {
 			return err
 		}
@@ -86,9 +144,32 @@ func describeEnclave(config *RouterConfig) API {
 		ContentType = "application/json"
 	)
 	type Response struct {
-		Name      string       `json:"name"`
-		CreatedAt time.Time    `json:"created_at"`
-		CreatedBy kes.Identity `json:"created_by"`
+		Name         string       `json:"name"`
+		CreatedAt    time.Time    `json:"created_at"`
+		CreatedBy    kes.Identity `json:"created_by"`
+		KeyRetention string       `json:"key_retention,omitempty"`
+		AuditHold    bool         `json:"audit_hold,omitempty"`
+
+		Keys          int     `json:"keys"`
+		Identities    int     `json:"identities"`
+		MaxKeys       int     `json:"max_keys,omitempty"`
+		MaxIdentities int     `json:"max_identities,omitempty"`
+		RequestRate   float64 `json:"request_rate,omitempty"`
+
+		AuditRetention string `json:"audit_retention,omitempty"`
+		AuditLegalHold bool   `json:"audit_legal_hold,omitempty"`
+
+		Description string            `json:"description,omitempty"`
+		Labels      map[string]string `json:"labels,omitempty"`
+
+		DefaultPolicy string        `json:"default_policy,omitempty"`
+		CryptoPolicy  *cryptoPolicy `json:"crypto_policy,omitempty"`
+
+		Suspended   bool       `json:"suspended,omitempty"`
+		SuspendedAt *time.Time `json:"suspended_at,omitempty"`
+	}
+	type Usage struct {
+		Keys, Identities int
 	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
 		name, err := nameFromRequest(r, APIPath)
@@ -96,6 +177,7 @@ func describeEnclave(config *RouterConfig) API {
 			return err
 		}
 
+		var usage Usage
 		info, err := VSync(config.Vault.RLocker(), func() (sys.EnclaveInfo, error) {
 			sysAdmin, err := config.Vault.Admin(r.Context())
 			if err != nil {
@@ -104,7 +186,18 @@ func describeEnclave(config *RouterConfig) API {
 			if identity := auth.Identify(r); identity != sysAdmin {
 				return sys.EnclaveInfo{}, kes.ErrNotAllowed
 			}
-			return config.Vault.GetEnclaveInfo(r.Context(), name)
+			info, err := config.Vault.GetEnclaveInfo(r.Context(), name)
+			if err != nil {
+				return sys.EnclaveInfo{}, err
+			}
+			enclave, err := config.Vault.GetEnclave(r.Context(), name)
+			if err != nil {
+				return sys.EnclaveInfo{}, err
+			}
+			return info, Sync(enclave.RLocker(), func() (err error) {
+				usage.Keys, usage.Identities, err = enclave.Usage(r.Context())
+				return err
+			})
 		})
 		if err != nil {
 			return err
@@ -112,11 +205,236 @@ func describeEnclave(config *RouterConfig) API {
 
 		w.Header().Set("Content-Type", ContentType)
 		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(Response{
+		resp := Response{
 			Name:      info.Name,
 			CreatedAt: info.CreatedAt,
 			CreatedBy: info.CreatedBy,
-		})
+		}
+		if info.KeyRetention > 0 {
+			resp.KeyRetention = info.KeyRetention.String()
+		}
+		resp.AuditHold = info.AuditHold
+		resp.Keys, resp.Identities = usage.Keys, usage.Identities
+		resp.MaxKeys, resp.MaxIdentities, resp.RequestRate = info.MaxKeys, info.MaxIdentities, info.RequestRate
+		if info.AuditRetention > 0 {
+			resp.AuditRetention = strconv.Itoa(info.AuditRetention) + "d"
+		}
+		resp.AuditLegalHold = info.AuditLegalHold
+		resp.Description, resp.Labels = info.Description, info.Labels
+		resp.DefaultPolicy = info.DefaultPolicy
+		if !info.CryptoPolicy.IsZero() {
+			resp.CryptoPolicy = newCryptoPolicy(info.CryptoPolicy)
+		}
+		if info.Suspended {
+			resp.Suspended, resp.SuspendedAt = true, &info.SuspendedAt
+		}
+		json.NewEncoder(w).Encode(resp)
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func updateEnclave(config *RouterConfig) API {
+	const (
+		Method  = http.MethodPost
+		APIPath = "/v1/enclave/update/"
+		MaxBody = int64(1 * mem.MiB)
+		Timeout = 15 * time.Second
+		Verify  = true
+	)
+	type Request struct {
+		KeyRetention  *string  `json:"key_retention"`
+		AuditHold     *bool    `json:"audit_hold"`
+		MaxKeys       *int     `json:"max_keys"`
+		MaxIdentities *int     `json:"max_identities"`
+		RequestRate   *float64 `json:"request_rate"`
+
+		AuditRetention *string `json:"audit_retention"`
+		AuditLegalHold *bool   `json:"audit_legal_hold"`
+
+		Description *string            `json:"description"`
+		Labels      *map[string]string `json:"labels"`
+
+		DefaultPolicy *string `json:"default_policy"`
+		CryptoPolicy  *struct {
+			Algorithms    *[]string `json:"algorithms"`
+			MinKeySize    *int      `json:"min_key_size"`
+			MinRSAKeySize *int      `json:"min_rsa_key_size"`
+			KDFs          *[]string `json:"kdfs"`
+		} `json:"crypto_policy"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := nameFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+
+		var req Request
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		if req.KeyRetention == nil && req.AuditHold == nil && req.MaxKeys == nil && req.MaxIdentities == nil && req.RequestRate == nil && req.AuditRetention == nil && req.AuditLegalHold == nil && req.Description == nil && req.Labels == nil && req.DefaultPolicy == nil && req.CryptoPolicy == nil {
+			return kes.NewError(http.StatusBadRequest, "invalid argument: no enclave setting specified")
+		}
+		if req.Description != nil {
+			if err = verifyDescription(*req.Description); err != nil {
+				return err
+			}
+		}
+		if req.Labels != nil {
+			if err = verifyLabels(*req.Labels); err != nil {
+				return err
+			}
+		}
+		if req.DefaultPolicy != nil && *req.DefaultPolicy != "" {
+			if err = verifyName(*req.DefaultPolicy); err != nil {
+				return err
+			}
+		}
+		var algorithms []key.Algorithm
+		if req.CryptoPolicy != nil && req.CryptoPolicy.Algorithms != nil {
+			if algorithms, err = parseAlgorithms(*req.CryptoPolicy.Algorithms); err != nil {
+				return err
+			}
+		}
+		var retention time.Duration
+		if req.KeyRetention != nil {
+			if retention, err = parseKeyRetention(*req.KeyRetention); err != nil {
+				return err
+			}
+		}
+		var auditRetention int
+		if req.AuditRetention != nil {
+			if auditRetention, err = parseAuditRetention(*req.AuditRetention); err != nil {
+				return err
+			}
+		}
+
+		if err = Sync(config.Vault.Locker(), func() error {
+			sysAdmin, err := config.Vault.Admin(r.Context())
+			if err != nil {
+				return err
+			}
+			if identity := auth.Identify(r); identity != sysAdmin {
+				return kes.ErrNotAllowed
+			}
+			_, err = config.Vault.UpdateEnclave(r.Context(), name, func(info *sys.EnclaveInfo) error {
+				if req.KeyRetention != nil {
+					info.KeyRetention = retention
+				}
+				if req.AuditHold != nil {
+					info.AuditHold = *req.AuditHold
+				}
+				if req.MaxKeys != nil {
+					info.MaxKeys = *req.MaxKeys
+				}
+				if req.MaxIdentities != nil {
+					info.MaxIdentities = *req.MaxIdentities
+				}
+				if req.RequestRate != nil {
+					info.RequestRate = *req.RequestRate
+				}
+				if req.AuditRetention != nil {
+					info.AuditRetention = auditRetention
+				}
+				if req.AuditLegalHold != nil {
+					info.AuditLegalHold = *req.AuditLegalHold
+				}
+				if req.Description != nil {
+					info.Description = *req.Description
+				}
+				if req.Labels != nil {
+					info.Labels = *req.Labels
+				}
+				if req.DefaultPolicy != nil {
+					info.DefaultPolicy = *req.DefaultPolicy
+				}
+				if req.CryptoPolicy != nil {
+					if req.CryptoPolicy.Algorithms != nil {
+						info.CryptoPolicy.Algorithms = algorithms
+					}
+					if req.CryptoPolicy.MinKeySize != nil {
+						info.CryptoPolicy.MinKeySize = *req.CryptoPolicy.MinKeySize
+					}
+					if req.CryptoPolicy.MinRSAKeySize != nil {
+						info.CryptoPolicy.MinRSAKeySize = *req.CryptoPolicy.MinRSAKeySize
+					}
+					if req.CryptoPolicy.KDFs != nil {
+						info.CryptoPolicy.KDFs = *req.CryptoPolicy.KDFs
+					}
+					if err := info.CryptoPolicy.Verify(); err != nil {
+						return err
+					}
+				}
+				return verifyQuota(info.MaxKeys, info.MaxIdentities, info.RequestRate)
+			})
+			return err
+		}); err != nil {
+			if errors.Is(err, sys.ErrAuditHold) {
+				logAuditHold(config, r, "weaken the audit hold of enclave %q", name)
+			}
+			return err
+		}
+
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func renameEnclave(config *RouterConfig) API {
+	const (
+		Method  = http.MethodPost
+		APIPath = "/v1/enclave/rename/"
+		MaxBody = int64(1 * mem.KiB)
+		Timeout = 15 * time.Second
+		Verify  = true
+	)
+	type Request struct {
+		Name string `json:"name"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := nameFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+
+		var req Request
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		if err = verifyName(req.Name); err != nil {
+			return err
+		}
+
+		if err = Sync(config.Vault.Locker(), func() error {
+			sysAdmin, err := config.Vault.Admin(r.Context())
+			if err != nil {
+				return err
+			}
+			if identity := auth.Identify(r); identity != sysAdmin {
+				return kes.ErrNotAllowed
+			}
+			return config.Vault.RenameEnclave(r.Context(), name, req.Name)
+		}); err != nil {
+			return err
+		}
+
+		w.WriteHeader(http.StatusOK)
 		return nil
 	}
 	return API{
@@ -129,6 +447,248 @@ func describeEnclave(config *RouterConfig) API {
 	}
 }
 
+func suspendEnclave(config *RouterConfig) API {
+	const (
+		Method  = http.MethodPost
+		APIPath = "/v1/enclave/suspend/"
+		MaxBody = 0
+		Timeout = 15 * time.Second
+		Verify  = true
+	)
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := nameFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+
+		if err = Sync(config.Vault.Locker(), func() error {
+			sysAdmin, err := config.Vault.Admin(r.Context())
+			if err != nil {
+				return err
+			}
+			if identity := auth.Identify(r); identity != sysAdmin {
+				return kes.ErrNotAllowed
+			}
+			return config.Vault.SuspendEnclave(r.Context(), name)
+		}); err != nil {
+			return err
+		}
+
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func resumeEnclave(config *RouterConfig) API {
+	const (
+		Method  = http.MethodPost
+		APIPath = "/v1/enclave/resume/"
+		MaxBody = 0
+		Timeout = 15 * time.Second
+		Verify  = true
+	)
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := nameFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+
+		if err = Sync(config.Vault.Locker(), func() error {
+			sysAdmin, err := config.Vault.Admin(r.Context())
+			if err != nil {
+				return err
+			}
+			if identity := auth.Identify(r); identity != sysAdmin {
+				return kes.ErrNotAllowed
+			}
+			return config.Vault.ResumeEnclave(r.Context(), name)
+		}); err != nil {
+			return err
+		}
+
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+// cryptoPolicy is the JSON representation
+// of a sys.CryptoPolicy.
+type cryptoPolicy struct {
+	Algorithms    []string `json:"algorithms,omitempty"`
+	MinKeySize    int      `json:"min_key_size,omitempty"`
+	MinRSAKeySize int      `json:"min_rsa_key_size,omitempty"`
+	KDFs          []string `json:"kdfs,omitempty"`
+}
+
+func newCryptoPolicy(p sys.CryptoPolicy) *cryptoPolicy {
+	algorithms := make([]string, 0, len(p.Algorithms))
+	for _, a := range p.Algorithms {
+		algorithms = append(algorithms, a.String())
+	}
+	return &cryptoPolicy{
+		Algorithms:    algorithms,
+		MinKeySize:    p.MinKeySize,
+		MinRSAKeySize: p.MinRSAKeySize,
+		KDFs:          p.KDFs,
+	}
+}
+
+// Parse parses the crypto policy and returns an
+// error if it is not a valid sys.CryptoPolicy.
+func (p *cryptoPolicy) Parse() (sys.CryptoPolicy, error) {
+	algorithms, err := parseAlgorithms(p.Algorithms)
+	if err != nil {
+		return sys.CryptoPolicy{}, err
+	}
+	policy := sys.CryptoPolicy{
+		Algorithms:    algorithms,
+		MinKeySize:    p.MinKeySize,
+		MinRSAKeySize: p.MinRSAKeySize,
+		KDFs:          p.KDFs,
+	}
+	if err = policy.Verify(); err != nil {
+		return sys.CryptoPolicy{}, err
+	}
+	return policy, nil
+}
+
+// parseAlgorithms parses the given symmetric
+// key algorithms. Empty values are ignored.
+func parseAlgorithms(values []string) ([]key.Algorithm, error) {
+	var algorithms []key.Algorithm
+	for _, v := range values {
+		if v == "" {
+			continue
+		}
+		a, err := key.ParseAlgorithm(v)
+		if err != nil {
+			return nil, err
+		}
+		algorithms = append(algorithms, key.Algorithm(a))
+	}
+	return algorithms, nil
+}
+
+// verifyDescription returns an error if the
+// enclave description is too long.
+func verifyDescription(description string) error {
+	const MaxLength = 1024
+	if len(description) > MaxLength {
+		return kes.NewError(http.StatusBadRequest, "invalid argument: description is too long")
+	}
+	return nil
+}
+
+// logAuditHold logs an attempt to bypass the audit hold
+// of an enclave to the error log such that it is visible
+// to auditors.
+//
+// All values are quoted since the enclave is taken from
+// the request as is. Otherwise, a client could inject
+// arbitrary log lines.
+func logAuditHold(config *RouterConfig, r *http.Request, format string, v ...any) {
+	enclave := r.URL.Query().Get("enclave")
+	if enclave == "" {
+		enclave = sys.DefaultEnclaveName
+	}
+	config.ErrorLog.Printf("kes: audit hold: identity %q tried to %s in enclave %q", auth.Identify(r), fmt.Sprintf(format, v...), enclave)
+}
+
+// parseKeyRetention parses the key retention of an enclave.
+// An empty string or zero means that deleted keys are not
+// retained.
+func parseKeyRetention(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	retention, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, kes.NewError(http.StatusBadRequest, "invalid key retention: "+err.Error())
+	}
+	if retention < 0 {
+		return 0, kes.NewError(http.StatusBadRequest, "invalid key retention: retention must not be negative")
+	}
+	return retention, nil
+}
+
+// parseAuditRetention parses the audit retention of an
+// enclave, either as number of days, like "90d", or as
+// duration, like "2160h", and returns it in days. Durations
+// are rounded up to full days. An empty string or zero
+// means that the server's default retention applies.
+func parseAuditRetention(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	var (
+		days int
+		err  error
+	)
+	if v, ok := strings.CutSuffix(s, "d"); ok {
+		days, err = strconv.Atoi(v)
+	} else {
+		var d time.Duration
+		if d, err = time.ParseDuration(s); err == nil {
+			days = int((d + 24*time.Hour - 1) / (24 * time.Hour))
+		}
+	}
+	if err != nil {
+		return 0, kes.NewError(http.StatusBadRequest, "invalid audit retention: '"+s+"' is not a duration")
+	}
+	if days < 0 {
+		return 0, kes.NewError(http.StatusBadRequest, "invalid audit retention: retention must not be negative")
+	}
+	return days, nil
+}
+
+// verifyQuota returns an error if any of the enclave
+// quotas is negative. A quota of zero means no limit.
+func verifyQuota(maxKeys, maxIdentities int, requestRate float64) error {
+	if maxKeys < 0 {
+		return kes.NewError(http.StatusBadRequest, "invalid key quota: quota must not be negative")
+	}
+	if maxIdentities < 0 {
+		return kes.NewError(http.StatusBadRequest, "invalid identity quota: quota must not be negative")
+	}
+	if requestRate < 0 {
+		return kes.NewError(http.StatusBadRequest, "invalid request quota: rate must not be negative")
+	}
+	return nil
+}
+
+// KeyQuotaRemainingHeader is the number of keys that can
+// still be created within an enclave until it has reached
+// its key quota. It is sent with responses of APIs that
+// create keys if the enclave has a key quota.
+const KeyQuotaRemainingHeader = "X-Quota-Keys-Remaining"
+
+// setKeyQuotaHeader sets the KeyQuotaRemainingHeader if the
+// enclave has a key quota. The header is best effort. It is
+// omitted if the remaining quota cannot be determined.
+func setKeyQuotaHeader(w http.ResponseWriter, r *http.Request, enclave *sys.Enclave) {
+	remaining, ok, err := enclave.KeyQuotaRemaining(r.Context())
+	if err == nil && ok {
+		w.Header().Set(KeyQuotaRemainingHeader, strconv.Itoa(remaining))
+	}
+}
+
 func deleteEnclave(config *RouterConfig) API {
 	const (
 		Method  = http.MethodDelete
@@ -153,6 +713,9 @@ func deleteEnclave(config *RouterConfig) API {
 			}
 			return config.Vault.DeleteEnclave(r.Context(), name)
 		}); err != nil {
+			if errors.Is(err, sys.ErrAuditHold) {
+				logAuditHold(config, r, "delete enclave %q", name)
+			}
 			return err
 		}
 
@@ -168,3 +731,120 @@ func deleteEnclave(config *RouterConfig) API {
 		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
 	}
 }
+
+// reencryptResponse is the JSON representation
+// of a sys.ReencryptStatus.
+type reencryptResponse struct {
+	Enclave    string     `json:"enclave"`
+	State      string     `json:"state"`
+	Total      int        `json:"total"`
+	Done       int        `json:"done"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+func newReencryptResponse(status sys.ReencryptStatus) reencryptResponse {
+	resp := reencryptResponse{
+		Enclave: status.Enclave,
+		State:   status.State,
+		Total:   status.Total,
+		Done:    status.Done,
+		Error:   status.Err,
+	}
+	if !status.StartedAt.IsZero() {
+		resp.StartedAt = &status.StartedAt
+	}
+	if !status.FinishedAt.IsZero() {
+		resp.FinishedAt = &status.FinishedAt
+	}
+	return resp
+}
+
+func reencryptEnclave(config *RouterConfig) API {
+	const (
+		Method      = http.MethodPost
+		APIPath     = "/v1/enclave/reencrypt/start/"
+		MaxBody     = 0
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := nameFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+
+		status, err := VSync(config.Vault.Locker(), func() (sys.ReencryptStatus, error) {
+			sysAdmin, err := config.Vault.Admin(r.Context())
+			if err != nil {
+				return sys.ReencryptStatus{}, err
+			}
+			if identity := auth.Identify(r); identity != sysAdmin {
+				return sys.ReencryptStatus{}, kes.ErrNotAllowed
+			}
+			return config.Vault.ReencryptEnclave(r.Context(), name)
+		})
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(newReencryptResponse(status))
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func reencryptionStatus(config *RouterConfig) API {
+	const (
+		Method      = http.MethodGet
+		APIPath     = "/v1/enclave/reencrypt/status/"
+		MaxBody     = 0
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := nameFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+
+		status, err := VSync(config.Vault.RLocker(), func() (sys.ReencryptStatus, error) {
+			sysAdmin, err := config.Vault.Admin(r.Context())
+			if err != nil {
+				return sys.ReencryptStatus{}, err
+			}
+			if identity := auth.Identify(r); identity != sysAdmin {
+				return sys.ReencryptStatus{}, kes.ErrNotAllowed
+			}
+			return config.Vault.ReencryptionStatus(r.Context(), name)
+		})
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(newReencryptResponse(status))
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/minio/kes/internal/auth"
+)
+
+// federate returns a handler that verifies JWTs, sent as
+// bearer tokens by clients without a client certificate,
+// and executes such requests on behalf of the identity
+// the JWT maps to. Requests with an invalid JWT are
+// rejected.
+//
+// If oidc is nil, federate returns f.
+func federate(oidc *auth.OIDC, f http.Handler) http.Handler {
+	if oidc == nil {
+		return f
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil {
+			for _, cert := range r.TLS.PeerCertificates {
+				if !cert.IsCA { // Client certificates take precedence
+					f.ServeHTTP(w, r)
+					return
+				}
+			}
+		}
+
+		token, ok := auth.Token(r)
+		if !ok {
+			f.ServeHTTP(w, r)
+			return
+		}
+		federated, err := oidc.Verify(r.Context(), token)
+		if err != nil {
+			Fail(w, err)
+			return
+		}
+		f.ServeHTTP(w, auth.Federate(r, federated))
+	})
+}
+
+// federateSPIFFE returns a handler that executes requests,
+// whose client certificate contains a SPIFFE ID of a trusted
+// domain, on behalf of the SPIFFE ID.
+//
+// If spiffe is nil, federateSPIFFE returns f.
+func federateSPIFFE(spiffe *auth.SPIFFE, f http.Handler) http.Handler {
+	if spiffe == nil {
+		return f
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if identity, ok := spiffe.Identity(r); ok {
+			r = auth.Federate(r, auth.Federated{Identity: identity})
+		}
+		f.ServeHTTP(w, r)
+	})
+}
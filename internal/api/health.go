@@ -5,15 +5,17 @@
 package api
 
 import (
+	"encoding/json"
 	"net/http"
 	"time"
 
 	"github.com/minio/kes-go"
 	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/keystore"
 	"github.com/minio/kes/kv"
 )
 
-func edgeReady(config *EdgeRouterConfig) API {
+func edgeReady(config *EdgeRouterConfig, maintenance *Maintenance) API {
 	var (
 		Method  = http.MethodGet
 		APIPath = "/v1/ready"
@@ -32,14 +34,111 @@ func edgeReady(config *EdgeRouterConfig) API {
 			Fail(w, err)
 			return
 		}
+		if err := checkReady(r, config, maintenance); err != nil {
+			Fail(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Verify:  Verify,
+		Timeout: Timeout,
+		Handler: handler,
+	}
+}
+
+// errKeystoreUnavailable is returned by the readiness APIs
+// instead of the keystore error itself. The keystore error
+// may contain backend details, like hostnames or paths,
+// that must not be exposed to unauthenticated clients.
+var (
+	errKeystoreUnavailable = kes.NewError(http.StatusBadGateway, "keystore unavailable")
+	errKeystoreTimeout     = kes.NewError(http.StatusGatewayTimeout, "keystore unavailable")
+)
 
-		_, err := config.Keys.Status(r.Context())
-		if _, ok := kv.IsUnreachable(err); ok {
-			Fail(w, kes.NewError(http.StatusGatewayTimeout, err.Error()))
+// checkReady returns an error if the server is shutting
+// down or its keystore is not reachable. Keystore errors
+// are logged but not returned to the client.
+func checkReady(r *http.Request, config *EdgeRouterConfig, maintenance *Maintenance) error {
+	if maintenance.ShuttingDown() {
+		return errShuttingDown
+	}
+
+	_, err := config.Keys.Status(r.Context())
+	if err == nil {
+		return nil
+	}
+	config.ErrorLog.Printf("kes: readiness check failed: %v", err)
+	if _, ok := kv.IsUnreachable(err); ok {
+		return errKeystoreTimeout
+	}
+	return errKeystoreUnavailable
+}
+
+// edgeHealthz returns the liveness probe API. It reports
+// whether the server is able to serve requests at all,
+// regardless of whether its keystore is reachable, and
+// does not require authentication unless its config
+// requires it explicitly.
+func edgeHealthz(config *EdgeRouterConfig) API {
+	var (
+		Method  = http.MethodGet
+		APIPath = "/healthz"
+		MaxBody int64
+		Timeout = 15 * time.Second
+		Verify  = false
+	)
+	if c, ok := config.APIConfig[APIPath]; ok {
+		if c.Timeout > 0 {
+			Timeout = c.Timeout
+		}
+		Verify = c.RequireAuth && !c.InsecureSkipAuth
+	}
+	var handler http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
+		if err := auth.VerifyRequest(r, config.Policies, config.Identities); Verify && err != nil {
+			Fail(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Verify:  Verify,
+		Timeout: Timeout,
+		Handler: handler,
+	}
+}
+
+// edgeReadyz returns the readiness probe API. It reports
+// whether the server is not shutting down and can reach
+// its keystore, and does not require authentication
+// unless its config requires it explicitly.
+func edgeReadyz(config *EdgeRouterConfig, maintenance *Maintenance) API {
+	var (
+		Method  = http.MethodGet
+		APIPath = "/readyz"
+		MaxBody int64
+		Timeout = 15 * time.Second
+		Verify  = false
+	)
+	if c, ok := config.APIConfig[APIPath]; ok {
+		if c.Timeout > 0 {
+			Timeout = c.Timeout
+		}
+		Verify = c.RequireAuth && !c.InsecureSkipAuth
+	}
+	var handler http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
+		if err := auth.VerifyRequest(r, config.Policies, config.Identities); Verify && err != nil {
+			Fail(w, err)
 			return
 		}
-		if err != nil {
-			Fail(w, kes.NewError(http.StatusBadGateway, err.Error()))
+		if err := checkReady(r, config, maintenance); err != nil {
+			Fail(w, err)
 			return
 		}
 		w.WriteHeader(http.StatusOK)
@@ -53,3 +152,113 @@ func edgeReady(config *EdgeRouterConfig) API {
 		Handler: handler,
 	}
 }
+
+// Health status values as defined by the gRPC health
+// checking protocol (grpc.health.v1).
+const (
+	healthServing        = "SERVING"
+	healthNotServing     = "NOT_SERVING"
+	healthServiceUnknown = "SERVICE_UNKNOWN"
+)
+
+func edgeHealth(config *EdgeRouterConfig, maintenance *Maintenance) API {
+	var (
+		Method      = http.MethodGet
+		APIPath     = "/v1/health"
+		MaxBody     int64
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	if c, ok := config.APIConfig[APIPath]; ok {
+		if c.Timeout > 0 {
+			Timeout = c.Timeout
+		}
+		Verify = !c.InsecureSkipAuth
+	}
+	type Service struct {
+		Status      string `json:"status"`
+		Latency     int64  `json:"latency,omitempty"`
+		Unreachable bool   `json:"unreachable,omitempty"`
+		Offline     bool   `json:"offline,omitempty"`
+		Error       string `json:"error,omitempty"`
+	}
+	type Response struct {
+		Status   string             `json:"status"`
+		Services map[string]Service `json:"services,omitempty"`
+	}
+	var handler http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
+		if err := auth.VerifyRequest(r, config.Policies, config.Identities); Verify && err != nil {
+			Fail(w, err)
+			return
+		}
+
+		// The keystore is the only service that can stop serving.
+		// The cache keeps serving keys from memory while the
+		// keystore is offline.
+		keystoreHealth := func(keys *keystore.Cache) Service {
+			service := Service{Status: healthServing}
+			if state, err := keys.Status(r.Context()); err != nil {
+				service.Status = healthNotServing
+				service.Error = err.Error()
+				_, service.Unreachable = kv.IsUnreachable(err)
+			} else {
+				service.Latency = state.Latency.Milliseconds()
+				if service.Latency == 0 { // Make sure we actually send a latency even if the key store respond time is < 1ms.
+					service.Latency = 1
+				}
+			}
+			return service
+		}
+		keys := keystoreHealth(config.Keys)
+		services := map[string]Service{
+			"keystore": keys,
+			"cache":    {Status: healthServing, Offline: config.Keys.Offline()},
+		}
+
+		// Enclaves with their own keystore are reported as
+		// separate services. They do not affect the overall
+		// status since the server keeps serving all other
+		// enclaves.
+		for name, keys := range config.Enclaves {
+			services["keystore/"+name] = keystoreHealth(keys)
+		}
+
+		response := Response{
+			Status:   keys.Status,
+			Services: services,
+		}
+		if maintenance.ShuttingDown() {
+			response.Status = healthNotServing
+		}
+		if name := r.URL.Query().Get("service"); name != "" {
+			service, ok := services[name]
+			if !ok {
+				w.Header().Set("Content-Type", ContentType)
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(Response{Status: healthServiceUnknown})
+				return
+			}
+			response = Response{
+				Status:   service.Status,
+				Services: map[string]Service{name: service},
+			}
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		if response.Status != healthServing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		} else {
+			w.WriteHeader(http.StatusOK)
+		}
+		json.NewEncoder(w).Encode(response)
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Verify:  Verify,
+		Timeout: Timeout,
+		Handler: handler,
+	}
+}
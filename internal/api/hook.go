@@ -0,0 +1,25 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/minio/kes/internal/auth"
+)
+
+// policyHooks returns a handler that attaches the policy
+// hooks to requests such that they get evaluated once a
+// request is allowed by its policy.
+//
+// If hooks is empty, policyHooks returns f.
+func policyHooks(hooks []*auth.PolicyHook, f http.Handler) http.Handler {
+	if len(hooks) == 0 {
+		return f
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.ServeHTTP(w, auth.WithPolicyHooks(r, hooks))
+	})
+}
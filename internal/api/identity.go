@@ -6,10 +6,13 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"path"
+	"strings"
 	"time"
 
+	"aead.dev/mem"
 	"github.com/minio/kes-go"
 	"github.com/minio/kes/internal/audit"
 	"github.com/minio/kes/internal/auth"
@@ -25,10 +28,12 @@ func describeIdentity(config *RouterConfig) API {
 		ContentType = "application/json"
 	)
 	type Response struct {
-		IsAdmin   bool         `json:"admin,omitempty"`
-		Policy    string       `json:"policy"`
-		CreatedAt time.Time    `json:"created_at,omitempty"`
-		CreatedBy kes.Identity `json:"created_by,omitempty"`
+		IsAdmin   bool              `json:"admin,omitempty"`
+		Policy    string            `json:"policy"`
+		CreatedAt time.Time         `json:"created_at,omitempty"`
+		CreatedBy kes.Identity      `json:"created_by,omitempty"`
+		ExpiresAt time.Time         `json:"expires_at,omitempty"`
+		Labels    map[string]string `json:"labels,omitempty"`
 	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
 		name, err := nameFromRequest(r, APIPath)
@@ -59,6 +64,8 @@ func describeIdentity(config *RouterConfig) API {
 			Policy:    info.Policy,
 			CreatedAt: info.CreatedAt,
 			CreatedBy: info.CreatedBy,
+			ExpiresAt: info.ExpiresAt,
+			Labels:    info.Labels,
 		})
 		return nil
 	}
@@ -147,8 +154,10 @@ func selfDescribeIdentity(config *RouterConfig) API {
 		PolicyName string       `json:"policy_name,omitempty"`
 		CreatedAt  time.Time    `json:"created_at,omitempty"`
 		CreatedBy  kes.Identity `json:"created_by,omitempty"`
+		ExpiresAt  time.Time    `json:"expires_at,omitempty"`
 
-		Policy InlinePolicy `json:"policy"`
+		Labels map[string]string `json:"labels,omitempty"`
+		Policy InlinePolicy      `json:"policy"`
 	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
 		response, err := VSync(config.Vault.RLocker(), func() (Response, error) {
@@ -175,6 +184,8 @@ func selfDescribeIdentity(config *RouterConfig) API {
 					IsAdmin:    info.IsAdmin,
 					CreatedAt:  info.CreatedAt,
 					CreatedBy:  info.CreatedBy,
+					ExpiresAt:  info.ExpiresAt,
+					Labels:     info.Labels,
 					Policy: InlinePolicy{
 						Allow:     policy.Allow,
 						Deny:      policy.Deny,
@@ -273,6 +284,186 @@ func edgeSelfDescribeIdentity(config *EdgeRouterConfig) API {
 	}
 }
 
+func createIdentity(config *RouterConfig) API {
+	const (
+		Method      = http.MethodPost
+		APIPath     = "/v1/identity/create/"
+		MaxBody     = int64(8 * mem.KiB)
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	type Request struct {
+		Policy    string            `json:"policy,omitempty"` // optional, defaults to the enclave's default policy
+		ExpiresAt string            `json:"expires_at,omitempty"`
+		TTL       string            `json:"ttl,omitempty"`
+		Labels    map[string]string `json:"labels,omitempty"`
+	}
+	type Response struct {
+		Identity  kes.Identity `json:"identity"`
+		Policy    string       `json:"policy"`
+		ExpiresAt time.Time    `json:"expires_at,omitempty"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := nameFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+
+		var req Request
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		if req.Policy != "" {
+			if err = verifyName(req.Policy); err != nil {
+				return err
+			}
+		}
+		expiresAt, err := parseExpiry(req.ExpiresAt, req.TTL)
+		if err != nil {
+			return err
+		}
+		if err = verifyLabels(req.Labels); err != nil {
+			return err
+		}
+
+		identity := kes.Identity(name)
+		if identity.IsUnknown() {
+			return kes.NewError(http.StatusBadRequest, "identity is unknown")
+		}
+		if self := auth.Identify(r); self == identity {
+			return kes.NewError(http.StatusForbidden, "identity cannot create itself")
+		}
+		info, err := VSync(config.Vault.RLocker(), func() (auth.IdentityInfo, error) {
+			enclave, err := enclaveFromRequest(config.Vault, r)
+			if err != nil {
+				return auth.IdentityInfo{}, err
+			}
+			return VSync(enclave.Locker(), func() (auth.IdentityInfo, error) {
+				if err = enclave.VerifyRequest(r); err != nil {
+					return auth.IdentityInfo{}, err
+				}
+				admin, err := config.Vault.Admin(r.Context())
+				if err != nil {
+					return auth.IdentityInfo{}, err
+				}
+				if admin == identity {
+					return auth.IdentityInfo{}, kes.NewError(http.StatusBadRequest, "cannot create system admin")
+				}
+				if err = enclave.CreateIdentity(r.Context(), req.Policy, identity, expiresAt, req.Labels); err != nil {
+					return auth.IdentityInfo{}, err
+				}
+				return enclave.GetIdentity(r.Context(), identity)
+			})
+		})
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{
+			Identity:  identity,
+			Policy:    info.Policy,
+			ExpiresAt: info.ExpiresAt,
+		})
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func renewIdentity(config *RouterConfig) API {
+	const (
+		Method      = http.MethodPost
+		APIPath     = "/v1/identity/renew/"
+		MaxBody     = int64(1 * mem.KiB)
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	type Request struct {
+		ExpiresAt string `json:"expires_at,omitempty"`
+		TTL       string `json:"ttl,omitempty"`
+	}
+	type Response struct {
+		Identity  kes.Identity `json:"identity"`
+		Policy    string       `json:"policy"`
+		ExpiresAt time.Time    `json:"expires_at"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := nameFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+
+		var req Request
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		if req.ExpiresAt == "" && req.TTL == "" {
+			return kes.NewError(http.StatusBadRequest, "no expiry or TTL specified")
+		}
+		expiresAt, err := parseExpiry(req.ExpiresAt, req.TTL)
+		if err != nil {
+			return err
+		}
+
+		identity := kes.Identity(name)
+		if self := auth.Identify(r); self == identity {
+			return kes.NewError(http.StatusForbidden, "identity cannot renew itself")
+		}
+		info, err := VSync(config.Vault.RLocker(), func() (auth.IdentityInfo, error) {
+			enclave, err := enclaveFromRequest(config.Vault, r)
+			if err != nil {
+				return auth.IdentityInfo{}, err
+			}
+			return VSync(enclave.Locker(), func() (auth.IdentityInfo, error) {
+				if err = enclave.VerifyRequest(r); err != nil {
+					return auth.IdentityInfo{}, err
+				}
+				admin, err := config.Vault.Admin(r.Context())
+				if err != nil {
+					return auth.IdentityInfo{}, err
+				}
+				if admin == identity {
+					return auth.IdentityInfo{}, kes.NewError(http.StatusBadRequest, "cannot renew system admin")
+				}
+				if err = enclave.RenewIdentity(r.Context(), identity, expiresAt); err != nil {
+					return auth.IdentityInfo{}, err
+				}
+				return enclave.GetIdentity(r.Context(), identity)
+			})
+		})
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{
+			Identity:  identity,
+			Policy:    info.Policy,
+			ExpiresAt: info.ExpiresAt,
+		})
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
 func deleteIdentity(config *RouterConfig) API {
 	const (
 		Method  = http.MethodDelete
@@ -334,11 +525,13 @@ func listIdentity(config *RouterConfig) API {
 		ContentType = "application/x-ndjson"
 	)
 	type Response struct {
-		Identity  kes.Identity `json:"identity"`
-		IsAdmin   bool         `json:"admin"`
-		Policy    string       `json:"policy"`
-		CreatedAt time.Time    `json:"created_at,omitempty"`
-		CreatedBy kes.Identity `json:"created_by,omitempty"`
+		Identity  kes.Identity      `json:"identity"`
+		IsAdmin   bool              `json:"admin"`
+		Policy    string            `json:"policy"`
+		CreatedAt time.Time         `json:"created_at,omitempty"`
+		CreatedBy kes.Identity      `json:"created_by,omitempty"`
+		ExpiresAt time.Time         `json:"expires_at,omitempty"`
+		Labels    map[string]string `json:"labels,omitempty"`
 
 		Err string `json:"error,omitempty"`
 	}
@@ -347,6 +540,10 @@ func listIdentity(config *RouterConfig) API {
 		if err != nil {
 			return err
 		}
+		labels, err := parseLabels(r.URL.Query()["label"])
+		if err != nil {
+			return err
+		}
 
 		hasWritten, err := VSync(config.Vault.RLocker(), func() (bool, error) {
 			enclave, err := enclaveFromRequest(config.Vault, r)
@@ -373,6 +570,9 @@ func listIdentity(config *RouterConfig) API {
 					if err != nil {
 						return hasWritten, err
 					}
+					if !hasLabels(info, labels) {
+						continue
+					}
 					if !hasWritten {
 						hasWritten = true
 						w.Header().Set("Content-Type", ContentType)
@@ -385,6 +585,8 @@ func listIdentity(config *RouterConfig) API {
 						Policy:    info.Policy,
 						CreatedAt: info.CreatedAt,
 						CreatedBy: info.CreatedBy,
+						ExpiresAt: info.ExpiresAt,
+						Labels:    info.Labels,
 					})
 					if err != nil {
 						return hasWritten, err
@@ -504,3 +706,62 @@ func edgeListIdentity(config *EdgeRouterConfig) API {
 		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
 	}
 }
+
+// Limits for identity labels.
+const (
+	maxLabels        = 16
+	maxLabelLen      = 128
+	maxLabelValueLen = 256
+)
+
+// parseLabels parses a list of 'name=value' labels. It
+// returns nil if the list is empty.
+func parseLabels(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	labels := make(map[string]string, len(values))
+	for _, s := range values {
+		label, value, ok := strings.Cut(s, "=")
+		if !ok {
+			return nil, kes.NewError(http.StatusBadRequest, fmt.Sprintf("invalid argument: invalid label '%s': expected 'name=value'", s))
+		}
+		labels[label] = value
+	}
+	if err := verifyLabels(labels); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+// verifyLabels returns an error if there are too many
+// labels or if a label name or value is invalid.
+func verifyLabels(labels map[string]string) error {
+	if len(labels) > maxLabels {
+		return kes.NewError(http.StatusBadRequest, "invalid argument: too many labels")
+	}
+	for label, value := range labels {
+		if label == "" {
+			return kes.NewError(http.StatusBadRequest, "invalid argument: label name must not be empty")
+		}
+		if len(label) > maxLabelLen || strings.ContainsAny(label, "=\n") {
+			return kes.NewError(http.StatusBadRequest, fmt.Sprintf("invalid argument: invalid label name '%s'", label))
+		}
+		if len(value) > maxLabelValueLen || strings.Contains(value, "\n") {
+			return kes.NewError(http.StatusBadRequest, fmt.Sprintf("invalid argument: invalid value of label '%s'", label))
+		}
+	}
+	return nil
+}
+
+// hasLabels reports whether the identity has all
+// the given labels.
+func hasLabels(info auth.IdentityInfo, labels map[string]string) bool {
+	for label, value := range labels {
+		if v, ok := info.Labels[label]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
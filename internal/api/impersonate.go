@@ -0,0 +1,112 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/auth"
+)
+
+// impersonate returns a handler that executes requests,
+// which specify an identity in the auth.ImpersonateHeader,
+// on behalf of this identity. Only requests that pass the
+// verify function may impersonate other identities.
+//
+// Impersonation allows admins to debug policy problems
+// without obtaining the credentials of other identities.
+// Hence, only requests that do not modify any state may
+// impersonate other identities. Otherwise, an admin could,
+// for example, contribute to a key ceremony on behalf of
+// several custodians. The audit log records the identity
+// that has sent the request as well as the impersonated
+// identity.
+func impersonate(verify func(*http.Request) error, f http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimSpace(r.Header.Get(auth.ImpersonateHeader))
+		if name == "" {
+			f.ServeHTTP(w, r)
+			return
+		}
+
+		identity := kes.Identity(name)
+		if identity.IsUnknown() {
+			Fail(w, kes.NewError(http.StatusBadRequest, "impersonated identity is unknown"))
+			return
+		}
+		if err := verify(r); err != nil {
+			Fail(w, err)
+			return
+		}
+		if !canImpersonate(r) {
+			Fail(w, errImpersonateMutation)
+			return
+		}
+		f.ServeHTTP(w, auth.Impersonate(r, identity))
+	})
+}
+
+// errImpersonateMutation is returned when a request that
+// may modify state tries to impersonate another identity.
+var errImpersonateMutation = kes.NewError(http.StatusForbidden, "impersonation is only allowed for read-only requests")
+
+// canImpersonate reports whether the request may be sent
+// on behalf of another identity. Besides requests that
+// modify keys, policies or identities, admin requests that
+// change the server's mode, e.g. read-only mode or drills,
+// may not impersonate other identities.
+func canImpersonate(req *http.Request) bool {
+	if isMutation(req) {
+		return false
+	}
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		return true
+	}
+	switch {
+	case strings.HasPrefix(req.URL.Path, "/v1/maintenance/"),
+		strings.HasPrefix(req.URL.Path, "/v1/drill/"),
+		strings.HasPrefix(req.URL.Path, "/v1/admin/"):
+		return false
+	}
+	return true
+}
+
+// verifyImpersonation returns an error if the request has
+// not been sent by the system admin. Only the system admin
+// may impersonate other identities. Federated identities
+// never act as admin.
+func verifyImpersonation(config *RouterConfig) func(*http.Request) error {
+	return func(r *http.Request) error {
+		return Sync(config.Vault.RLocker(), func() error {
+			sysAdmin, err := config.Vault.Admin(r.Context())
+			if err != nil {
+				return err
+			}
+			if auth.IsFederated(r) || auth.Identify(r) != sysAdmin {
+				return kes.NewError(http.StatusForbidden, "only the admin can impersonate identities")
+			}
+			return nil
+		})
+	}
+}
+
+// edgeVerifyImpersonation returns an error if the request
+// has not been sent by the admin of the edge server. Only
+// the admin may impersonate other identities. Federated
+// identities never act as admin.
+func edgeVerifyImpersonation(config *EdgeRouterConfig) func(*http.Request) error {
+	return func(r *http.Request) error {
+		admin, err := config.Identities.Admin(r.Context())
+		if err != nil {
+			return err
+		}
+		if auth.IsFederated(r) || auth.Identify(r) != admin {
+			return kes.NewError(http.StatusForbidden, "only the admin can impersonate identities")
+		}
+		return nil
+	}
+}
@@ -5,10 +5,15 @@
 package api
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
 	"net/http"
-	"path"
+	"sort"
+	"strings"
 	"time"
 
 	"aead.dev/mem"
@@ -18,6 +23,8 @@ import (
 	"github.com/minio/kes/internal/cpu"
 	"github.com/minio/kes/internal/fips"
 	"github.com/minio/kes/internal/key"
+	"github.com/minio/kes/internal/sys"
+	"github.com/minio/kes/kv"
 )
 
 func createKey(config *RouterConfig) API {
@@ -29,7 +36,7 @@ func createKey(config *RouterConfig) API {
 		Verify  = true
 	)
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
-		name, err := nameFromRequest(r, APIPath)
+		name, err := pathFromRequest(r, APIPath)
 		if err != nil {
 			return err
 		}
@@ -43,18 +50,15 @@ func createKey(config *RouterConfig) API {
 					return err
 				}
 
-				var algorithm kes.KeyAlgorithm
-				if fips.Enabled || cpu.HasAESGCM() {
-					algorithm = kes.AES256_GCM_SHA256
-				} else {
-					algorithm = kes.XCHACHA20_POLY1305
-				}
-
-				key, err := key.Random(algorithm, auth.Identify(r))
+				key, err := newKey(r, enclave.CryptoPolicy())
 				if err != nil {
 					return err
 				}
-				return enclave.CreateKey(r.Context(), name, key)
+				if err = enclave.CreateKey(r.Context(), name, key); err != nil {
+					return err
+				}
+				setKeyQuotaHeader(w, r, enclave)
+				return nil
 			})
 		}); err != nil {
 			return err
@@ -86,30 +90,77 @@ func edgeCreateKey(config *EdgeRouterConfig) API {
 		}
 	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
-		name, err := nameFromRequest(r, APIPath)
+		name, err := pathFromRequest(r, APIPath)
 		if err != nil {
 			return err
 		}
 		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
 			return err
 		}
+		store, err := keysFromRequest(config, r)
+		if err != nil {
+			return err
+		}
 
-		var algorithm kes.KeyAlgorithm
-		if fips.Enabled || cpu.HasAESGCM() {
-			algorithm = kes.AES256_GCM_SHA256
-		} else {
-			algorithm = kes.XCHACHA20_POLY1305
+		key, err := newKey(r, nil)
+		if err != nil {
+			return err
+		}
+		if err = store.Create(r.Context(), name, key); err != nil {
+			return err
 		}
 
-		key, err := key.Random(algorithm, auth.Identify(r))
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func edgeClaimKey(config *EdgeRouterConfig) API {
+	var (
+		Method      = http.MethodPost
+		APIPath     = "/v1/key/claim/"
+		MaxBody     int64
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	if c, ok := config.APIConfig[APIPath]; ok {
+		if c.Timeout > 0 {
+			Timeout = c.Timeout
+		}
+	}
+	type Response struct {
+		Name string `json:"name"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		prefix, err := pathFromRequest(r, APIPath)
 		if err != nil {
 			return err
 		}
-		if err = config.Keys.Create(r.Context(), name, key); err != nil {
+		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
+			return err
+		}
+
+		pool, ok := config.KeyPools[prefix]
+		if !ok {
+			return kes.NewError(http.StatusNotFound, "key pool not found")
+		}
+		name, err := pool.Claim(r.Context())
+		if err != nil {
 			return err
 		}
 
+		w.Header().Set("Content-Type", ContentType)
 		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{Name: name})
 		return nil
 	}
 	return API{
@@ -131,11 +182,12 @@ func importKey(config *RouterConfig) API {
 		Verify  = true
 	)
 	type Request struct {
-		Bytes     []byte           `json:"bytes"`
-		Algorithm kes.KeyAlgorithm `json:"algorithm"`
+		Bytes     []byte        `json:"bytes"`
+		Type      key.Type      `json:"type"`
+		Algorithm key.Algorithm `json:"algorithm"`
 	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
-		name, err := nameFromRequest(r, APIPath)
+		name, err := pathFromRequest(r, APIPath)
 		if err != nil {
 			return err
 		}
@@ -153,14 +205,15 @@ func importKey(config *RouterConfig) API {
 				if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
 					return kes.NewError(http.StatusBadRequest, err.Error())
 				}
-				if len(req.Bytes) != key.Len(req.Algorithm) {
-					return kes.NewError(http.StatusBadRequest, "invalid key size")
-				}
-				key, err := key.New(req.Algorithm, req.Bytes, auth.Identify(r))
+				key, err := importedKey(r, req.Type, kes.KeyAlgorithm(req.Algorithm), req.Bytes)
 				if err != nil {
 					return err
 				}
-				return enclave.CreateKey(r.Context(), name, key)
+				if err = enclave.CreateKey(r.Context(), name, key); err != nil {
+					return err
+				}
+				setKeyQuotaHeader(w, r, enclave)
+				return nil
 			})
 		}); err != nil {
 			return err
@@ -192,30 +245,32 @@ func edgeImportKey(config *EdgeRouterConfig) API {
 		}
 	}
 	type Request struct {
-		Bytes     []byte           `json:"bytes"`
-		Algorithm kes.KeyAlgorithm `json:"algorithm"`
+		Bytes     []byte        `json:"bytes"`
+		Type      key.Type      `json:"type"`
+		Algorithm key.Algorithm `json:"algorithm"`
 	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
-		name, err := nameFromRequest(r, APIPath)
+		name, err := pathFromRequest(r, APIPath)
 		if err != nil {
 			return err
 		}
 		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
 			return err
 		}
+		store, err := keysFromRequest(config, r)
+		if err != nil {
+			return err
+		}
 
 		var req Request
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			return kes.NewError(http.StatusBadRequest, err.Error())
 		}
-		if len(req.Bytes) != key.Len(req.Algorithm) {
-			return kes.NewError(http.StatusBadRequest, "invalid key size")
-		}
-		key, err := key.New(req.Algorithm, req.Bytes, auth.Identify(r))
+		key, err := importedKey(r, req.Type, kes.KeyAlgorithm(req.Algorithm), req.Bytes)
 		if err != nil {
 			return err
 		}
-		if err = config.Keys.Create(r.Context(), name, key); err != nil {
+		if err = store.Create(r.Context(), name, key); err != nil {
 			return err
 		}
 
@@ -232,7 +287,7 @@ func edgeImportKey(config *EdgeRouterConfig) API {
 	}
 }
 
-func describeKey(config *RouterConfig) API {
+func describeKey(config *RouterConfig, usage *keyUsage) API {
 	const (
 		Method      = http.MethodGet
 		APIPath     = "/v1/key/describe/"
@@ -242,14 +297,18 @@ func describeKey(config *RouterConfig) API {
 		ContentType = "application/json"
 	)
 	type Response struct {
-		Name      string           `json:"name"`
-		ID        string           `json:"id,omitempty"`
-		Algorithm kes.KeyAlgorithm `json:"algorithm,omitempty"`
-		CreatedAt time.Time        `json:"created_at,omitempty"`
-		CreatedBy kes.Identity     `json:"created_by,omitempty"`
+		Name      string            `json:"name"`
+		ID        string            `json:"id,omitempty"`
+		Type      key.Type          `json:"type,omitempty"`
+		Algorithm key.Algorithm     `json:"algorithm,omitempty"`
+		CreatedAt time.Time         `json:"created_at,omitempty"`
+		CreatedBy kes.Identity      `json:"created_by,omitempty"`
+		ExpiresAt time.Time         `json:"expires_at,omitempty"`
+		Tags      map[string]string `json:"tags,omitempty"`
+		Usage     *usageResponse    `json:"usage,omitempty"`
 	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
-		name, err := nameFromRequest(r, APIPath)
+		name, err := pathFromRequest(r, APIPath)
 		if err != nil {
 			return err
 		}
@@ -269,14 +328,19 @@ func describeKey(config *RouterConfig) API {
 			return err
 		}
 
+		stats := usage.Get(usageID(r, name))
 		w.Header().Set("Content-Type", ContentType)
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(Response{
 			Name:      name,
 			ID:        key.ID(),
-			Algorithm: key.Algorithm(),
+			Type:      key.Type(),
+			Algorithm: keyAlgorithm(key.Algorithm()),
 			CreatedAt: key.CreatedAt(),
 			CreatedBy: key.CreatedBy(),
+			ExpiresAt: key.ExpiresAt(),
+			Tags:      key.Tags(),
+			Usage:     &stats,
 		})
 		return nil
 	}
@@ -290,7 +354,7 @@ func describeKey(config *RouterConfig) API {
 	}
 }
 
-func edgeDescribeKey(config *EdgeRouterConfig) API {
+func edgeDescribeKey(config *EdgeRouterConfig, usage *keyUsage) API {
 	var (
 		Method  = http.MethodGet
 		APIPath = "/v1/key/describe/"
@@ -304,33 +368,46 @@ func edgeDescribeKey(config *EdgeRouterConfig) API {
 		}
 	}
 	type Response struct {
-		Name      string           `json:"name"`
-		ID        string           `json:"id,omitempty"`
-		Algorithm kes.KeyAlgorithm `json:"algorithm,omitempty"`
-		CreatedAt time.Time        `json:"created_at,omitempty"`
-		CreatedBy kes.Identity     `json:"created_by,omitempty"`
+		Name      string            `json:"name"`
+		ID        string            `json:"id,omitempty"`
+		Type      key.Type          `json:"type,omitempty"`
+		Algorithm key.Algorithm     `json:"algorithm,omitempty"`
+		CreatedAt time.Time         `json:"created_at,omitempty"`
+		CreatedBy kes.Identity      `json:"created_by,omitempty"`
+		ExpiresAt time.Time         `json:"expires_at,omitempty"`
+		Tags      map[string]string `json:"tags,omitempty"`
+		Usage     *usageResponse    `json:"usage,omitempty"`
 	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
-		name, err := nameFromRequest(r, APIPath)
+		name, err := pathFromRequest(r, APIPath)
 		if err != nil {
 			return err
 		}
 		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
 			return err
 		}
-		key, err := config.Keys.Get(r.Context(), name)
+		store, err := keysFromRequest(config, r)
+		if err != nil {
+			return err
+		}
+		key, err := store.Get(r.Context(), name)
 		if err != nil {
 			return err
 		}
 
+		stats := usage.Get(usageID(r, name))
 		w.Header().Set("Content-Length", "application/json")
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(Response{
 			Name:      name,
 			ID:        key.ID(),
-			Algorithm: key.Algorithm(),
+			Type:      key.Type(),
+			Algorithm: keyAlgorithm(key.Algorithm()),
 			CreatedAt: key.CreatedAt(),
 			CreatedBy: key.CreatedBy(),
+			ExpiresAt: key.ExpiresAt(),
+			Tags:      key.Tags(),
+			Usage:     &stats,
 		})
 		return nil
 	}
@@ -344,7 +421,7 @@ func edgeDescribeKey(config *EdgeRouterConfig) API {
 	}
 }
 
-func deleteKey(config *RouterConfig) API {
+func deleteKey(config *RouterConfig, usage *keyUsage) API {
 	const (
 		Method  = http.MethodDelete
 		APIPath = "/v1/key/delete/"
@@ -353,7 +430,7 @@ func deleteKey(config *RouterConfig) API {
 		Verify  = true
 	)
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
-		name, err := nameFromRequest(r, APIPath)
+		name, err := pathFromRequest(r, APIPath)
 		if err != nil {
 			return err
 		}
@@ -372,6 +449,7 @@ func deleteKey(config *RouterConfig) API {
 			return err
 		}
 
+		usage.Delete(usageID(r, name))
 		w.WriteHeader(http.StatusOK)
 		return nil
 	}
@@ -385,28 +463,79 @@ func deleteKey(config *RouterConfig) API {
 	}
 }
 
-func edgeDeleteKey(config *EdgeRouterConfig) API {
-	var (
-		Method  = http.MethodDelete
-		APIPath = "/v1/key/delete/"
-		MaxBody int64
+func restoreKey(config *RouterConfig) API {
+	const (
+		Method  = http.MethodPost
+		APIPath = "/v1/key/restore/"
+		MaxBody = 0
 		Timeout = 15 * time.Second
 		Verify  = true
 	)
-	if c, ok := config.APIConfig[APIPath]; ok {
-		if c.Timeout > 0 {
-			Timeout = c.Timeout
-		}
-	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
-		name, err := nameFromRequest(r, APIPath)
+		name, err := pathFromRequest(r, APIPath)
 		if err != nil {
 			return err
 		}
-		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
+		if err = Sync(config.Vault.RLocker(), func() error {
+			enclave, err := enclaveFromRequest(config.Vault, r)
+			if err != nil {
+				return err
+			}
+			return Sync(enclave.Locker(), func() error {
+				if err = enclave.VerifyRequest(r); err != nil {
+					return err
+				}
+				if err = enclave.RestoreKey(r.Context(), name); err != nil {
+					return err
+				}
+				setKeyQuotaHeader(w, r, enclave)
+				return nil
+			})
+		}); err != nil {
+			return err
+		}
+
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func purgeKey(config *RouterConfig) API {
+	const (
+		Method  = http.MethodDelete
+		APIPath = "/v1/key/purge/"
+		MaxBody = 0
+		Timeout = 15 * time.Second
+		Verify  = true
+	)
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := pathFromRequest(r, APIPath)
+		if err != nil {
 			return err
 		}
-		if err := config.Keys.Delete(r.Context(), name); err != nil {
+		if err = Sync(config.Vault.RLocker(), func() error {
+			enclave, err := enclaveFromRequest(config.Vault, r)
+			if err != nil {
+				return err
+			}
+			return Sync(enclave.Locker(), func() error {
+				if err = enclave.VerifyRequest(r); err != nil {
+					return err
+				}
+				return enclave.PurgeKey(r.Context(), name)
+			})
+		}); err != nil {
+			if errors.Is(err, sys.ErrAuditHold) {
+				logAuditHold(config, r, "purge key %q", name)
+			}
 			return err
 		}
 
@@ -423,63 +552,142 @@ func edgeDeleteKey(config *EdgeRouterConfig) API {
 	}
 }
 
-func generateKey(config *RouterConfig) API {
+func listDeletedKey(config *RouterConfig) API {
 	const (
-		Method      = http.MethodPost
-		APIPath     = "/v1/key/generate/"
-		MaxBody     = int64(1 * mem.MiB)
+		Method      = http.MethodGet
+		APIPath     = "/v1/key/deleted/"
+		MaxBody     = 0
 		Timeout     = 15 * time.Second
 		Verify      = true
-		ContentType = "application/json"
+		ContentType = "application/x-ndjson"
 	)
-	type Request struct {
-		Context []byte `json:"context"` // optional
-	}
 	type Response struct {
-		Plaintext  []byte `json:"plaintext"`
-		Ciphertext []byte `json:"ciphertext"`
+		Name      string    `json:"name,omitempty"`
+		ID        string    `json:"id,omitempty"`
+		DeletedAt time.Time `json:"deleted_at,omitempty"`
+		PurgeAt   time.Time `json:"purge_at,omitempty"`
+		AuditHold bool      `json:"audit_hold,omitempty"`
+
+		Err string `json:"error,omitempty"`
 	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
-		name, err := nameFromRequest(r, APIPath)
+		pattern, err := patternFromRequest(r, APIPath)
 		if err != nil {
 			return err
 		}
 
-		key, err := VSync(config.Vault.RLocker(), func() (key.Key, error) {
+		// Listing deleted keys purges expired ones.
+		// Hence, it locks the enclave for writes.
+		hasWritten, err := VSync(config.Vault.RLocker(), func() (bool, error) {
 			enclave, err := enclaveFromRequest(config.Vault, r)
 			if err != nil {
-				return key.Key{}, err
+				return false, err
 			}
-			return VSync(enclave.RLocker(), func() (key.Key, error) {
+			return VSync(enclave.Locker(), func() (bool, error) {
 				if err = enclave.VerifyRequest(r); err != nil {
-					return key.Key{}, err
+					return false, err
 				}
-				return enclave.GetKey(r.Context(), name)
+
+				iterator, err := enclave.ListDeletedKeys(r.Context())
+				if err != nil {
+					return false, err
+				}
+				defer iterator.Close()
+
+				var names []string
+				for name, next := iterator.Next(); next; name, next = iterator.Next() {
+					if matchName(pattern, name) {
+						names = append(names, name)
+					}
+				}
+				if err = iterator.Close(); err != nil {
+					return false, err
+				}
+				sort.Strings(names)
+
+				var hasWritten bool
+				encoder := json.NewEncoder(w)
+				for _, name := range names {
+					deleted, err := enclave.GetDeletedKey(r.Context(), name)
+					if errors.Is(err, kes.ErrKeyNotFound) {
+						continue // Purged since retention has been exceeded
+					}
+					if err != nil {
+						return hasWritten, err
+					}
+					if !hasWritten {
+						hasWritten = true
+						w.Header().Set("Content-Type", ContentType)
+						w.WriteHeader(http.StatusOK)
+					}
+					err = encoder.Encode(Response{
+						Name:      name,
+						ID:        deleted.Key.ID(),
+						DeletedAt: deleted.DeletedAt,
+						PurgeAt:   deleted.PurgeAt(enclave.KeyRetention()),
+						AuditHold: enclave.AuditHold(),
+					})
+					if err != nil {
+						return hasWritten, err
+					}
+				}
+				return hasWritten, nil
 			})
 		})
 		if err != nil {
+			if hasWritten {
+				json.NewEncoder(w).Encode(Response{Err: err.Error()})
+				return nil
+			}
 			return err
 		}
+		if !hasWritten {
+			w.Header().Set("Content-Type", ContentType)
+			w.WriteHeader(http.StatusOK)
+		}
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
 
-		var req Request
-		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
-			return kes.NewError(http.StatusBadRequest, err.Error())
+func edgeDeleteKey(config *EdgeRouterConfig, usage *keyUsage) API {
+	var (
+		Method  = http.MethodDelete
+		APIPath = "/v1/key/delete/"
+		MaxBody int64
+		Timeout = 15 * time.Second
+		Verify  = true
+	)
+	if c, ok := config.APIConfig[APIPath]; ok {
+		if c.Timeout > 0 {
+			Timeout = c.Timeout
 		}
-		dataKey := make([]byte, 32)
-		if _, err = rand.Read(dataKey); err != nil {
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := pathFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
 			return err
 		}
-		ciphertext, err := key.Wrap(dataKey, req.Context)
+		store, err := keysFromRequest(config, r)
 		if err != nil {
 			return err
 		}
+		if err := store.Delete(r.Context(), name); err != nil {
+			return err
+		}
 
-		w.Header().Set("Content-Type", ContentType)
+		usage.Delete(usageID(r, name))
 		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(Response{
-			Plaintext:  dataKey,
-			Ciphertext: ciphertext,
-		})
 		return nil
 	}
 	return API{
@@ -492,123 +700,78 @@ func generateKey(config *RouterConfig) API {
 	}
 }
 
-func edgeGenerateKey(config *EdgeRouterConfig) API {
+func edgeRestoreKey(config *EdgeRouterConfig) API {
 	var (
-		Method      = http.MethodPost
-		APIPath     = "/v1/key/generate/"
-		MaxBody     = 1 * mem.MiB
-		Timeout     = 15 * time.Second
-		Verify      = true
-		ContentType = "application/json"
+		Method  = http.MethodPost
+		APIPath = "/v1/key/restore/"
+		MaxBody int64
+		Timeout = 15 * time.Second
+		Verify  = true
 	)
 	if c, ok := config.APIConfig[APIPath]; ok {
 		if c.Timeout > 0 {
 			Timeout = c.Timeout
 		}
 	}
-	type Request struct {
-		Context []byte `json:"context"` // optional
-	}
-	type Response struct {
-		Plaintext  []byte `json:"plaintext"`
-		Ciphertext []byte `json:"ciphertext"`
-	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
-		name, err := nameFromRequest(r, APIPath)
+		name, err := pathFromRequest(r, APIPath)
 		if err != nil {
 			return err
 		}
 		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
 			return err
 		}
-
-		var req Request
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			return kes.NewError(http.StatusBadRequest, err.Error())
-		}
-		key, err := config.Keys.Get(r.Context(), name)
+		store, err := keysFromRequest(config, r)
 		if err != nil {
 			return err
 		}
-		dataKey := make([]byte, 32)
-		if _, err = rand.Read(dataKey); err != nil {
-			return err
-		}
-		ciphertext, err := key.Wrap(dataKey, req.Context)
-		if err != nil {
+		if err := store.Recover(r.Context(), name); err != nil {
 			return err
 		}
 
-		w.Header().Set("Content-Type", ContentType)
 		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(Response{
-			Plaintext:  dataKey,
-			Ciphertext: ciphertext,
-		})
 		return nil
 	}
 	return API{
 		Method:  Method,
 		Path:    APIPath,
-		MaxBody: int64(MaxBody),
+		MaxBody: MaxBody,
 		Timeout: Timeout,
 		Verify:  Verify,
 		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
 	}
 }
 
-func encryptKey(config *RouterConfig) API {
-	const (
-		Method      = http.MethodPost
-		APIPath     = "/v1/key/encrypt/"
-		MaxBody     = int64(1 * mem.MiB)
-		Timeout     = 15 * time.Second
-		Verify      = true
-		ContentType = "application/json"
+func edgePurgeKey(config *EdgeRouterConfig) API {
+	var (
+		Method  = http.MethodDelete
+		APIPath = "/v1/key/purge/"
+		MaxBody int64
+		Timeout = 15 * time.Second
+		Verify  = true
 	)
-	type Request struct {
-		Plaintext []byte `json:"plaintext"`
-		Context   []byte `json:"context"` // optional
-	}
-	type Response struct {
-		Ciphertext []byte `json:"ciphertext"`
+	if c, ok := config.APIConfig[APIPath]; ok {
+		if c.Timeout > 0 {
+			Timeout = c.Timeout
+		}
 	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
-		name, err := nameFromRequest(r, APIPath)
+		name, err := pathFromRequest(r, APIPath)
 		if err != nil {
 			return err
 		}
-
-		key, err := VSync(config.Vault.RLocker(), func() (key.Key, error) {
-			enclave, err := enclaveFromRequest(config.Vault, r)
-			if err != nil {
-				return key.Key{}, err
-			}
-			return VSync(enclave.RLocker(), func() (key.Key, error) {
-				if err = enclave.VerifyRequest(r); err != nil {
-					return key.Key{}, err
-				}
-				return enclave.GetKey(r.Context(), name)
-			})
-		})
-		if err != nil {
+		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
 			return err
 		}
-
-		var req Request
-		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
-			return kes.NewError(http.StatusBadRequest, err.Error())
-		}
-		ciphertext, err := key.Wrap(req.Plaintext, req.Context)
+		store, err := keysFromRequest(config, r)
 		if err != nil {
 			return err
 		}
+		if err := store.Purge(r.Context(), name); err != nil {
+			return err
+		}
 
-		w.Header().Set("Content-Type", ContentType)
 		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(Response{
-			Ciphertext: ciphertext,
-		})
 		return nil
 	}
 	return API{
@@ -621,54 +784,72 @@ func encryptKey(config *RouterConfig) API {
 	}
 }
 
-func edgeEncryptKey(config *EdgeRouterConfig) API {
+func edgeListDeletedKey(config *EdgeRouterConfig) API {
 	var (
-		Method      = http.MethodPost
-		APIPath     = "/v1/key/encrypt/"
-		MaxBody     = int64(1 * mem.MiB)
+		Method      = http.MethodGet
+		APIPath     = "/v1/key/deleted/"
+		MaxBody     int64
 		Timeout     = 15 * time.Second
 		Verify      = true
-		ContentType = "application/json"
+		ContentType = "application/x-ndjson"
 	)
 	if c, ok := config.APIConfig[APIPath]; ok {
 		if c.Timeout > 0 {
 			Timeout = c.Timeout
 		}
 	}
-	type Request struct {
-		Plaintext []byte `json:"plaintext"`
-		Context   []byte `json:"context"` // optional
-	}
 	type Response struct {
-		Ciphertext []byte `json:"ciphertext"`
+		Name      string    `json:"name,omitempty"`
+		DeletedAt time.Time `json:"deleted_at,omitempty"`
+		PurgeAt   time.Time `json:"purge_at,omitempty"`
+
+		Err string `json:"error,omitempty"`
 	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
-		name, err := nameFromRequest(r, APIPath)
+		pattern, err := patternFromRequest(r, APIPath)
 		if err != nil {
 			return err
 		}
 		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
 			return err
 		}
-
-		var req Request
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			return kes.NewError(http.StatusBadRequest, err.Error())
-		}
-		key, err := config.Keys.Get(r.Context(), name)
+		store, err := keysFromRequest(config, r)
 		if err != nil {
 			return err
 		}
-		ciphertext, err := key.Wrap(req.Plaintext, req.Context)
+
+		iterator, err := store.ListDeleted(r.Context())
 		if err != nil {
 			return err
 		}
+		defer iterator.Close()
+
+		var keys []kv.Deleted[string]
+		for key, next := iterator.Next(); next; key, next = iterator.Next() {
+			if matchName(pattern, key.Key) {
+				keys = append(keys, key)
+			}
+		}
+		if err = iterator.Close(); err != nil {
+			return err
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i].Key < keys[j].Key })
 
 		w.Header().Set("Content-Type", ContentType)
 		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(Response{
-			Ciphertext: ciphertext,
-		})
+
+		encoder := json.NewEncoder(w)
+		for _, key := range keys {
+			err = encoder.Encode(Response{
+				Name:      key.Key,
+				DeletedAt: key.DeletedAt,
+				PurgeAt:   key.PurgeAt,
+			})
+			if err != nil {
+				encoder.Encode(Response{Err: err.Error()})
+				return nil
+			}
+		}
 		return nil
 	}
 	return API{
@@ -681,57 +862,53 @@ func edgeEncryptKey(config *EdgeRouterConfig) API {
 	}
 }
 
-func decryptKey(config *RouterConfig) API {
+func bulkCreateKey(config *RouterConfig) API {
 	const (
 		Method      = http.MethodPost
-		APIPath     = "/v1/key/decrypt/"
+		APIPath     = "/v1/key/bulk/create/"
 		MaxBody     = int64(1 * mem.MiB)
-		Timeout     = 15 * time.Second
+		Timeout     = 1 * time.Minute
 		Verify      = true
 		ContentType = "application/json"
 	)
-	type Request struct {
-		Ciphertext []byte `json:"ciphertext"`
-		Context    []byte `json:"context"` // optional
-	}
-	type Response struct {
-		Plaintext []byte `json:"plaintext"`
-	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
-		name, err := nameFromRequest(r, APIPath)
+		names, err := bulkNamesFromRequest(r, APIPath)
 		if err != nil {
 			return err
 		}
-		key, err := VSync(config.Vault.RLocker(), func() (key.Key, error) {
+
+		responses, err := VSync(config.Vault.RLocker(), func() ([]bulkResponse, error) {
 			enclave, err := enclaveFromRequest(config.Vault, r)
 			if err != nil {
-				return key.Key{}, err
+				return nil, err
 			}
-			return VSync(enclave.RLocker(), func() (key.Key, error) {
-				if err = enclave.VerifyRequest(r); err != nil {
-					return key.Key{}, err
+			return VSync(enclave.Locker(), func() ([]bulkResponse, error) {
+				errs, err := verifyBulkRequest(enclave, r, "/v1/key/create/", names)
+				if err != nil {
+					return nil, err
 				}
-				return enclave.GetKey(r.Context(), name)
+				responses := make([]bulkResponse, 0, len(names))
+				for i, name := range names {
+					err := errs[i]
+					if err == nil {
+						var k key.Key
+						if k, err = newKey(r, enclave.CryptoPolicy()); err == nil {
+							err = enclave.CreateKey(r.Context(), name, k)
+						}
+					}
+					responses = append(responses, newBulkResponse(name, err))
+				}
+				setKeyQuotaHeader(w, r, enclave)
+				return responses, nil
 			})
 		})
 		if err != nil {
 			return err
 		}
 
-		var req Request
-		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
-			return kes.NewError(http.StatusBadRequest, err.Error())
-		}
-		plaintext, err := key.Unwrap(req.Ciphertext, req.Context)
-		if err != nil {
-			return err
-		}
-
 		w.Header().Set("Content-Type", ContentType)
 		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(Response{
-			Plaintext: plaintext,
-		})
+		json.NewEncoder(w).Encode(responses)
 		return nil
 	}
 	return API{
@@ -744,12 +921,12 @@ func decryptKey(config *RouterConfig) API {
 	}
 }
 
-func edgeDecryptKey(config *EdgeRouterConfig) API {
+func edgeBulkCreateKey(config *EdgeRouterConfig) API {
 	var (
 		Method      = http.MethodPost
-		APIPath     = "/v1/key/decrypt/"
+		APIPath     = "/v1/key/bulk/create/"
 		MaxBody     = int64(1 * mem.MiB)
-		Timeout     = 15 * time.Second
+		Timeout     = 1 * time.Minute
 		Verify      = true
 		ContentType = "application/json"
 	)
@@ -758,40 +935,35 @@ func edgeDecryptKey(config *EdgeRouterConfig) API {
 			Timeout = c.Timeout
 		}
 	}
-	type Request struct {
-		Ciphertext []byte `json:"ciphertext"`
-		Context    []byte `json:"context"` // optional
-	}
-	type Response struct {
-		Plaintext []byte `json:"plaintext"`
-	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
-		name, err := nameFromRequest(r, APIPath)
+		store, err := keysFromRequest(config, r)
 		if err != nil {
 			return err
 		}
-		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
-			return err
-		}
 
-		var req Request
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			return err
-		}
-		key, err := config.Keys.Get(r.Context(), name)
+		names, err := bulkNamesFromRequest(r, APIPath)
 		if err != nil {
 			return err
 		}
-		plaintext, err := key.Unwrap(req.Ciphertext, req.Context)
-		if err != nil {
-			return err
+
+		responses := make([]bulkResponse, 0, len(names))
+		for _, name := range names {
+			err := verifyPath(name)
+			if err == nil {
+				err = auth.VerifyRequest(keyRequest(r, "/v1/key/create/", name), config.Policies, config.Identities)
+			}
+			if err == nil {
+				var k key.Key
+				if k, err = newKey(r, nil); err == nil {
+					err = store.Create(r.Context(), name, k)
+				}
+			}
+			responses = append(responses, newBulkResponse(name, err))
 		}
 
 		w.Header().Set("Content-Type", ContentType)
 		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(Response{
-			Plaintext: plaintext,
-		})
+		json.NewEncoder(w).Encode(responses)
 		return nil
 	}
 	return API{
@@ -804,66 +976,49 @@ func edgeDecryptKey(config *EdgeRouterConfig) API {
 	}
 }
 
-func bulkDecryptKey(config *RouterConfig) API {
+func bulkDeleteKey(config *RouterConfig, usage *keyUsage) API {
 	const (
-		Method      = http.MethodPost
-		APIPath     = "/v1/key/bulk/decrypt/"
+		Method      = http.MethodDelete
+		APIPath     = "/v1/key/bulk/delete/"
 		MaxBody     = int64(1 * mem.MiB)
-		Timeout     = 15 * time.Second
+		Timeout     = 1 * time.Minute
 		Verify      = true
 		ContentType = "application/json"
-		MaxRequests = 1000 // For now, we limit the number of decryption requests in a single API call to 1000.
 	)
-	type Request struct {
-		Ciphertext []byte `json:"ciphertext"`
-		Context    []byte `json:"context"` // optional
-	}
-	type Response struct {
-		Plaintext []byte `json:"plaintext"`
-	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
-		name, err := nameFromRequest(r, APIPath)
+		names, err := bulkNamesFromRequest(r, APIPath)
 		if err != nil {
 			return err
 		}
 
-		key, err := VSync(config.Vault.RLocker(), func() (key.Key, error) {
+		responses, err := VSync(config.Vault.RLocker(), func() ([]bulkResponse, error) {
 			enclave, err := enclaveFromRequest(config.Vault, r)
 			if err != nil {
-				return key.Key{}, err
+				return nil, err
 			}
-			return VSync(config.Vault.RLocker(), func() (key.Key, error) {
-				if err = enclave.VerifyRequest(r); err != nil {
-					return key.Key{}, err
+			return VSync(enclave.Locker(), func() ([]bulkResponse, error) {
+				errs, err := verifyBulkRequest(enclave, r, "/v1/key/delete/", names)
+				if err != nil {
+					return nil, err
 				}
-				return enclave.GetKey(r.Context(), name)
+				responses := make([]bulkResponse, 0, len(names))
+				for i, name := range names {
+					err := errs[i]
+					if err == nil {
+						err = enclave.DeleteKey(r.Context(), name)
+					}
+					if err == nil {
+						usage.Delete(usageID(r, name))
+					}
+					responses = append(responses, newBulkResponse(name, err))
+				}
+				return responses, nil
 			})
 		})
 		if err != nil {
 			return err
 		}
 
-		var (
-			requests  []Request
-			responses []Response
-		)
-		if err = json.NewDecoder(r.Body).Decode(&requests); err != nil {
-			return kes.NewError(http.StatusBadRequest, err.Error())
-		}
-		if len(requests) > MaxRequests {
-			return kes.NewError(http.StatusBadRequest, "too many ciphertexts")
-		}
-		responses = make([]Response, 0, len(requests))
-		for _, req := range requests {
-			plaintext, err := key.Unwrap(req.Ciphertext, req.Context)
-			if err != nil {
-				return err
-			}
-			responses = append(responses, Response{
-				Plaintext: plaintext,
-			})
-		}
-
 		w.Header().Set("Content-Type", ContentType)
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(responses)
@@ -879,60 +1034,56 @@ func bulkDecryptKey(config *RouterConfig) API {
 	}
 }
 
-func edgeBulkDecryptKey(config *EdgeRouterConfig) API {
+func edgeBulkDeleteKey(config *EdgeRouterConfig, usage *keyUsage) API {
 	var (
-		Method      = http.MethodPost
-		APIPath     = "/v1/key/bulk/decrypt/"
+		Method      = http.MethodDelete
+		APIPath     = "/v1/key/bulk/delete/"
 		MaxBody     = int64(1 * mem.MiB)
-		Timeout     = 15 * time.Second
+		Timeout     = 1 * time.Minute
 		Verify      = true
 		ContentType = "application/json"
-		MaxRequests = 1000 // For now, we limit the number of decryption requests in a single API call to 1000.
 	)
 	if c, ok := config.APIConfig[APIPath]; ok {
 		if c.Timeout > 0 {
 			Timeout = c.Timeout
 		}
 	}
-	type Request struct {
-		Ciphertext []byte `json:"ciphertext"`
-		Context    []byte `json:"context"` // optional
-	}
-	type Response struct {
-		Plaintext []byte `json:"plaintext"`
-	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
-		name, err := nameFromRequest(r, APIPath)
+		store, err := keysFromRequest(config, r)
 		if err != nil {
 			return err
 		}
-		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
-			return err
-		}
 
-		key, err := config.Keys.Get(r.Context(), name)
+		names, err := bulkNamesFromRequest(r, APIPath)
 		if err != nil {
 			return err
 		}
+
+		// First, verify all names and then delete all
+		// permitted keys with a single batch operation.
 		var (
-			requests  []Request
-			responses []Response
+			responses = make([]bulkResponse, len(names))
+			permitted = make([]string, 0, len(names))
+			indices   = make([]int, 0, len(names))
 		)
-		if err = json.NewDecoder(r.Body).Decode(&requests); err != nil {
-			return kes.NewError(http.StatusBadRequest, err.Error())
-		}
-		if len(requests) > MaxRequests {
-			return kes.NewError(http.StatusBadRequest, "too many ciphertexts")
-		}
-		responses = make([]Response, 0, len(requests))
-		for _, req := range requests {
-			plaintext, err := key.Unwrap(req.Ciphertext, req.Context)
+		for i, name := range names {
+			err := verifyPath(name)
+			if err == nil {
+				err = auth.VerifyRequest(keyRequest(r, "/v1/key/delete/", name), config.Policies, config.Identities)
+			}
 			if err != nil {
-				return err
+				responses[i] = newBulkResponse(name, err)
+				continue
 			}
-			responses = append(responses, Response{
-				Plaintext: plaintext,
-			})
+			permitted = append(permitted, name)
+			indices = append(indices, i)
+		}
+		for j, err := range store.DeleteMany(r.Context(), permitted) {
+			name := names[indices[j]]
+			if err == nil {
+				usage.Delete(usageID(r, name))
+			}
+			responses[indices[j]] = newBulkResponse(name, err)
 		}
 
 		w.Header().Set("Content-Type", ContentType)
@@ -950,86 +1101,208 @@ func edgeBulkDecryptKey(config *EdgeRouterConfig) API {
 	}
 }
 
-func listKey(config *RouterConfig) API {
+func generateKey(config *RouterConfig, usage *keyUsage) API {
 	const (
-		Method      = http.MethodGet
-		APIPath     = "/v1/key/list/"
-		MaxBody     = 0
+		Method      = http.MethodPost
+		APIPath     = "/v1/key/generate/"
+		MaxBody     = int64(1 * mem.MiB)
 		Timeout     = 15 * time.Second
 		Verify      = true
-		ContentType = "application/x-ndjson"
+		ContentType = "application/json"
 	)
+	type Request struct {
+		Context []byte `json:"context"` // optional
+	}
 	type Response struct {
-		Name      string           `json:"name,omitempty"`
-		ID        string           `json:"id,omitempty"`
-		Algorithm kes.KeyAlgorithm `json:"algorithm,omitempty"`
-		CreatedAt time.Time        `json:"created_at,omitempty"`
-		CreatedBy kes.Identity     `json:"created_by,omitempty"`
-
-		Err string `json:"error,omitempty"`
+		Plaintext  []byte `json:"plaintext"`
+		Ciphertext []byte `json:"ciphertext"`
 	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
-		pattern, err := patternFromRequest(r, APIPath)
+		name, err := pathFromRequest(r, APIPath)
 		if err != nil {
 			return err
 		}
 
-		hasWritten, err := VSync(config.Vault.RLocker(), func() (bool, error) {
+		key, err := VSync(config.Vault.RLocker(), func() (key.Key, error) {
 			enclave, err := enclaveFromRequest(config.Vault, r)
 			if err != nil {
-				return false, err
+				return key.Key{}, err
 			}
-			return VSync(enclave.RLocker(), func() (bool, error) {
+			return VSync(enclave.RLocker(), func() (key.Key, error) {
 				if err = enclave.VerifyRequest(r); err != nil {
-					return false, err
-				}
-
-				iterator, err := enclave.ListKeys(r.Context())
-				if err != nil {
-					return false, err
+					return key.Key{}, err
 				}
-				defer iterator.Close()
+				return cryptoKey(r.Context(), enclave, name)
+			})
+		})
+		if err != nil {
+			return err
+		}
 
-				var hasWritten bool
-				encoder := json.NewEncoder(w)
-				for name, next := iterator.Next(); next; name, next = iterator.Next() {
-					if ok, _ := path.Match(pattern, name); !ok || name == "" {
-						continue
-					}
-					key, err := enclave.GetKey(r.Context(), name)
-					if err != nil {
-						return hasWritten, err
-					}
-					if !hasWritten {
-						hasWritten = true
-						w.Header().Set("Content-Type", ContentType)
-						w.WriteHeader(http.StatusOK)
-					}
+		var req Request
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		dataKey := make([]byte, 32)
+		if _, err = rand.Read(dataKey); err != nil {
+			return err
+		}
+		ciphertext, err := cpu.VDo(r.Context(), config.AEADPool, func() ([]byte, error) {
+			return key.Wrap(dataKey, req.Context)
+		})
+		if err != nil {
+			return err
+		}
 
-					err = encoder.Encode(Response{
-						Name:      name,
-						ID:        key.ID(),
-						Algorithm: key.Algorithm(),
-						CreatedAt: key.CreatedAt(),
-						CreatedBy: key.CreatedBy(),
-					})
-					if err != nil {
-						return hasWritten, err
-					}
+		usage.Add(usageID(r, name), opGenerate, 1)
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{
+			Plaintext:  dataKey,
+			Ciphertext: ciphertext,
+		})
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func edgeGenerateKey(config *EdgeRouterConfig, usage *keyUsage) API {
+	var (
+		Method      = http.MethodPost
+		APIPath     = "/v1/key/generate/"
+		MaxBody     = 1 * mem.MiB
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	if c, ok := config.APIConfig[APIPath]; ok {
+		if c.Timeout > 0 {
+			Timeout = c.Timeout
+		}
+	}
+	type Request struct {
+		Context []byte `json:"context"` // optional
+	}
+	type Response struct {
+		Plaintext  []byte `json:"plaintext"`
+		Ciphertext []byte `json:"ciphertext"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := pathFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
+			return err
+		}
+		store, err := keysFromRequest(config, r)
+		if err != nil {
+			return err
+		}
+
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		key, err := store.Get(r.Context(), name)
+		if err != nil {
+			return err
+		}
+		dataKey := make([]byte, 32)
+		if _, err = rand.Read(dataKey); err != nil {
+			return err
+		}
+		ciphertext, err := cpu.VDo(r.Context(), config.AEADPool, func() ([]byte, error) {
+			return key.Wrap(dataKey, req.Context)
+		})
+		if err != nil {
+			return err
+		}
+
+		usage.Add(usageID(r, name), opGenerate, 1)
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{
+			Plaintext:  dataKey,
+			Ciphertext: ciphertext,
+		})
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: int64(MaxBody),
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func encryptKey(config *RouterConfig, usage *keyUsage) API {
+	const (
+		Method      = http.MethodPost
+		APIPath     = "/v1/key/encrypt/"
+		MaxBody     = int64(1 * mem.MiB)
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	type Request struct {
+		Plaintext []byte `json:"plaintext"`
+		Context   []byte `json:"context"` // optional
+	}
+	type Response struct {
+		Ciphertext []byte `json:"ciphertext"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := pathFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+
+		key, err := VSync(config.Vault.RLocker(), func() (key.Key, error) {
+			enclave, err := enclaveFromRequest(config.Vault, r)
+			if err != nil {
+				return key.Key{}, err
+			}
+			return VSync(enclave.RLocker(), func() (key.Key, error) {
+				if err = enclave.VerifyRequest(r); err != nil {
+					return key.Key{}, err
 				}
-				return hasWritten, iterator.Close()
+				return cryptoKey(r.Context(), enclave, name)
 			})
 		})
 		if err != nil {
-			if hasWritten {
-				json.NewEncoder(w).Encode(Response{Err: err.Error()})
-				return nil
-			}
 			return err
 		}
-		if !hasWritten {
-			w.WriteHeader(http.StatusOK)
+
+		var req Request
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		ciphertext, err := cpu.VDo(r.Context(), config.AEADPool, func() ([]byte, error) {
+			return key.Wrap(req.Plaintext, req.Context)
+		})
+		if err != nil {
+			return err
 		}
+
+		usage.Add(usageID(r, name), opEncrypt, 1)
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{
+			Ciphertext: ciphertext,
+		})
 		return nil
 	}
 	return API{
@@ -1042,71 +1315,62 @@ func listKey(config *RouterConfig) API {
 	}
 }
 
-func edgeListKey(config *EdgeRouterConfig) API {
+func edgeEncryptKey(config *EdgeRouterConfig, usage *keyUsage) API {
 	var (
-		Method      = http.MethodGet
-		APIPath     = "/v1/key/list/"
-		MaxBody     int64
+		Method      = http.MethodPost
+		APIPath     = "/v1/key/encrypt/"
+		MaxBody     = int64(1 * mem.MiB)
 		Timeout     = 15 * time.Second
 		Verify      = true
-		ContentType = "application/x-ndjson"
+		ContentType = "application/json"
 	)
 	if c, ok := config.APIConfig[APIPath]; ok {
 		if c.Timeout > 0 {
 			Timeout = c.Timeout
 		}
 	}
+	type Request struct {
+		Plaintext []byte `json:"plaintext"`
+		Context   []byte `json:"context"` // optional
+	}
 	type Response struct {
-		Name string `json:"name,omitempty"`
-		Err  string `json:"error,omitempty"`
+		Ciphertext []byte `json:"ciphertext"`
 	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
-		pattern, err := patternFromRequest(r, APIPath)
+		name, err := pathFromRequest(r, APIPath)
 		if err != nil {
 			return err
 		}
 		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
 			return err
 		}
-
-		iterator, err := config.Keys.List(r.Context())
+		store, err := keysFromRequest(config, r)
 		if err != nil {
 			return err
 		}
-		defer iterator.Close()
-
-		var (
-			hasWritten bool
-			encoder    = json.NewEncoder(w)
-		)
-		for {
-			name, ok := iterator.Next()
-			if !ok {
-				break
-			}
-			if ok, _ = path.Match(pattern, name); !ok || name == "" {
-				continue
-			}
-			if !hasWritten {
-				w.Header().Set("Content-Type", ContentType)
-			}
-			hasWritten = true
 
-			if err = encoder.Encode(Response{Name: name}); err != nil {
-				return nil
-			}
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
 		}
-		if err = iterator.Close(); err != nil {
-			if hasWritten {
-				encoder.Encode(Response{Err: err.Error()})
-				return nil
-			}
+		key, err := store.Get(r.Context(), name)
+		if err != nil {
 			return err
 		}
-		if !hasWritten {
-			w.Header().Set("Content-Type", ContentType)
-			w.WriteHeader(http.StatusOK)
+		ciphertext, err := cpu.VDo(r.Context(), config.AEADPool, func() ([]byte, error) {
+			return key.Wrap(req.Plaintext, req.Context)
+		})
+		if err != nil {
+			return err
 		}
+
+		usage.Add(usageID(r, name), opEncrypt, 1)
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{
+			Ciphertext: ciphertext,
+		})
 		return nil
 	}
 	return API{
@@ -1118,3 +1382,2016 @@ func edgeListKey(config *EdgeRouterConfig) API {
 		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
 	}
 }
+
+func decryptKey(config *RouterConfig, usage *keyUsage) API {
+	const (
+		Method      = http.MethodPost
+		APIPath     = "/v1/key/decrypt/"
+		MaxBody     = int64(1 * mem.MiB)
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	type Request struct {
+		Ciphertext []byte `json:"ciphertext"`
+		Context    []byte `json:"context"` // optional
+	}
+	type Response struct {
+		Plaintext []byte   `json:"plaintext"`
+		Receipt   *receipt `json:"receipt,omitempty"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := pathFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		key, err := VSync(config.Vault.RLocker(), func() (key.Key, error) {
+			enclave, err := enclaveFromRequest(config.Vault, r)
+			if err != nil {
+				return key.Key{}, err
+			}
+			return VSync(enclave.RLocker(), func() (key.Key, error) {
+				if err = enclave.VerifyRequest(r); err != nil {
+					return key.Key{}, err
+				}
+				return cryptoKey(r.Context(), enclave, name)
+			})
+		})
+		if err != nil {
+			return err
+		}
+
+		var req Request
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		plaintext, err := cpu.VDo(r.Context(), config.UnwrapPool, func() ([]byte, error) {
+			return key.Unwrap(req.Ciphertext, req.Context)
+		})
+		if err != nil {
+			return err
+		}
+
+		usage.Add(usageID(r, name), opDecrypt, 1)
+
+		receipt, err := decryptReceipt(config.ReceiptSigner, r, name, &key, req.Ciphertext)
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{
+			Plaintext: plaintext,
+			Receipt:   receipt,
+		})
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func edgeDecryptKey(config *EdgeRouterConfig, usage *keyUsage) API {
+	var (
+		Method      = http.MethodPost
+		APIPath     = "/v1/key/decrypt/"
+		MaxBody     = int64(1 * mem.MiB)
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	if c, ok := config.APIConfig[APIPath]; ok {
+		if c.Timeout > 0 {
+			Timeout = c.Timeout
+		}
+	}
+	type Request struct {
+		Ciphertext []byte `json:"ciphertext"`
+		Context    []byte `json:"context"` // optional
+	}
+	type Response struct {
+		Plaintext []byte   `json:"plaintext"`
+		Receipt   *receipt `json:"receipt,omitempty"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := pathFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
+			return err
+		}
+		store, err := keysFromRequest(config, r)
+		if err != nil {
+			return err
+		}
+
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return err
+		}
+		key, err := store.Get(r.Context(), name)
+		if err != nil {
+			return err
+		}
+		plaintext, err := cpu.VDo(r.Context(), config.UnwrapPool, func() ([]byte, error) {
+			return key.Unwrap(req.Ciphertext, req.Context)
+		})
+		if err != nil {
+			return err
+		}
+
+		usage.Add(usageID(r, name), opDecrypt, 1)
+
+		receipt, err := decryptReceipt(config.ReceiptSigner, r, name, &key, req.Ciphertext)
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{
+			Plaintext: plaintext,
+			Receipt:   receipt,
+		})
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func bulkDecryptKey(config *RouterConfig, usage *keyUsage) API {
+	const (
+		Method      = http.MethodPost
+		APIPath     = "/v1/key/bulk/decrypt/"
+		MaxBody     = int64(1 * mem.MiB)
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+		MaxRequests = 1000 // For now, we limit the number of decryption requests in a single API call to 1000.
+	)
+	type Request struct {
+		Ciphertext []byte `json:"ciphertext"`
+		Context    []byte `json:"context"` // optional
+	}
+	type Response struct {
+		Plaintext []byte   `json:"plaintext"`
+		Receipt   *receipt `json:"receipt,omitempty"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := pathFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+
+		key, err := VSync(config.Vault.RLocker(), func() (key.Key, error) {
+			enclave, err := enclaveFromRequest(config.Vault, r)
+			if err != nil {
+				return key.Key{}, err
+			}
+			return VSync(config.Vault.RLocker(), func() (key.Key, error) {
+				if err = enclave.VerifyRequest(r); err != nil {
+					return key.Key{}, err
+				}
+				return cryptoKey(r.Context(), enclave, name)
+			})
+		})
+		if err != nil {
+			return err
+		}
+
+		var (
+			requests  []Request
+			responses []Response
+		)
+		if err = json.NewDecoder(r.Body).Decode(&requests); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		if len(requests) > MaxRequests {
+			return kes.NewError(http.StatusBadRequest, "too many ciphertexts")
+		}
+		responses = make([]Response, 0, len(requests))
+		for _, req := range requests {
+			plaintext, err := cpu.VDo(r.Context(), config.UnwrapPool, func() ([]byte, error) {
+				return key.Unwrap(req.Ciphertext, req.Context)
+			})
+			if err != nil {
+				return err
+			}
+			receipt, err := decryptReceipt(config.ReceiptSigner, r, name, &key, req.Ciphertext)
+			if err != nil {
+				return err
+			}
+			responses = append(responses, Response{
+				Plaintext: plaintext,
+				Receipt:   receipt,
+			})
+		}
+
+		usage.Add(usageID(r, name), opDecrypt, len(responses))
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(responses)
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func edgeBulkDecryptKey(config *EdgeRouterConfig, usage *keyUsage) API {
+	var (
+		Method      = http.MethodPost
+		APIPath     = "/v1/key/bulk/decrypt/"
+		MaxBody     = int64(1 * mem.MiB)
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+		MaxRequests = 1000 // For now, we limit the number of decryption requests in a single API call to 1000.
+	)
+	if c, ok := config.APIConfig[APIPath]; ok {
+		if c.Timeout > 0 {
+			Timeout = c.Timeout
+		}
+	}
+	type Request struct {
+		Ciphertext []byte `json:"ciphertext"`
+		Context    []byte `json:"context"` // optional
+	}
+	type Response struct {
+		Plaintext []byte   `json:"plaintext"`
+		Receipt   *receipt `json:"receipt,omitempty"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := pathFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
+			return err
+		}
+		store, err := keysFromRequest(config, r)
+		if err != nil {
+			return err
+		}
+
+		key, err := store.Get(r.Context(), name)
+		if err != nil {
+			return err
+		}
+		var (
+			requests  []Request
+			responses []Response
+		)
+		if err = json.NewDecoder(r.Body).Decode(&requests); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		if len(requests) > MaxRequests {
+			return kes.NewError(http.StatusBadRequest, "too many ciphertexts")
+		}
+		responses = make([]Response, 0, len(requests))
+		for _, req := range requests {
+			plaintext, err := cpu.VDo(r.Context(), config.UnwrapPool, func() ([]byte, error) {
+				return key.Unwrap(req.Ciphertext, req.Context)
+			})
+			if err != nil {
+				return err
+			}
+			receipt, err := decryptReceipt(config.ReceiptSigner, r, name, &key, req.Ciphertext)
+			if err != nil {
+				return err
+			}
+			responses = append(responses, Response{
+				Plaintext: plaintext,
+				Receipt:   receipt,
+			})
+		}
+
+		usage.Add(usageID(r, name), opDecrypt, len(responses))
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(responses)
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func listKey(config *RouterConfig) API {
+	const (
+		Method      = http.MethodGet
+		APIPath     = "/v1/key/list/"
+		MaxBody     = 0
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/x-ndjson"
+	)
+	type Response struct {
+		Name      string            `json:"name,omitempty"`
+		ID        string            `json:"id,omitempty"`
+		Algorithm key.Algorithm     `json:"algorithm,omitempty"`
+		CreatedAt time.Time         `json:"created_at,omitempty"`
+		CreatedBy kes.Identity      `json:"created_by,omitempty"`
+		Tags      map[string]string `json:"tags,omitempty"`
+
+		Err string `json:"error,omitempty"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		pattern, err := patternFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		opts, err := listOptionsFromRequest(r)
+		if err != nil {
+			return err
+		}
+
+		hasWritten, err := VSync(config.Vault.RLocker(), func() (bool, error) {
+			enclave, err := enclaveFromRequest(config.Vault, r)
+			if err != nil {
+				return false, err
+			}
+			return VSync(enclave.RLocker(), func() (bool, error) {
+				if err = enclave.VerifyRequest(r); err != nil {
+					return false, err
+				}
+
+				iterator, err := enclave.ListKeys(r.Context())
+				if err != nil {
+					return false, err
+				}
+				defer iterator.Close()
+
+				var page kv.Iter[string] = iterator
+				if opts.paginate {
+					var match func(string) (bool, error)
+					if len(opts.Tags) > 0 {
+						match = func(name string) (bool, error) {
+							key, err := enclave.GetKey(r.Context(), name)
+							if err != nil {
+								return false, err
+							}
+							return hasTags(key, opts.Tags), nil
+						}
+					}
+					names, token, err := listPage(iterator, pattern, opts, match)
+					if err != nil {
+						return false, err
+					}
+					if token != "" {
+						w.Header().Set(ContinueTokenHeader, token)
+					}
+					page = &sliceIter{names: names}
+				}
+
+				var hasWritten bool
+				encoder := json.NewEncoder(w)
+				for name, next := page.Next(); next; name, next = page.Next() {
+					if !matchName(pattern, name) || name == "" {
+						continue
+					}
+					key, err := enclave.GetKey(r.Context(), name)
+					if err != nil {
+						return hasWritten, err
+					}
+					if !hasTags(key, opts.Tags) {
+						continue
+					}
+					if !hasWritten {
+						hasWritten = true
+						w.Header().Set("Content-Type", ContentType)
+						w.WriteHeader(http.StatusOK)
+					}
+
+					err = encoder.Encode(Response{
+						Name:      name,
+						ID:        key.ID(),
+						Algorithm: keyAlgorithm(key.Algorithm()),
+						CreatedAt: key.CreatedAt(),
+						CreatedBy: key.CreatedBy(),
+						Tags:      key.Tags(),
+					})
+					if err != nil {
+						return hasWritten, err
+					}
+				}
+				return hasWritten, iterator.Close()
+			})
+		})
+		if err != nil {
+			if hasWritten {
+				json.NewEncoder(w).Encode(Response{Err: err.Error()})
+				return nil
+			}
+			return err
+		}
+		if !hasWritten {
+			w.WriteHeader(http.StatusOK)
+		}
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func edgeListKey(config *EdgeRouterConfig) API {
+	var (
+		Method      = http.MethodGet
+		APIPath     = "/v1/key/list/"
+		MaxBody     int64
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/x-ndjson"
+	)
+	if c, ok := config.APIConfig[APIPath]; ok {
+		if c.Timeout > 0 {
+			Timeout = c.Timeout
+		}
+	}
+	type Response struct {
+		Name string `json:"name,omitempty"`
+		Err  string `json:"error,omitempty"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		pattern, err := patternFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		opts, err := listOptionsFromRequest(r)
+		if err != nil {
+			return err
+		}
+		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
+			return err
+		}
+		store, err := keysFromRequest(config, r)
+		if err != nil {
+			return err
+		}
+
+		iterator, err := store.List(r.Context(), listPrefix(pattern))
+		if err != nil {
+			return err
+		}
+		defer iterator.Close()
+
+		var match func(string) (bool, error)
+		if len(opts.Tags) > 0 {
+			match = func(name string) (bool, error) {
+				key, err := store.Get(r.Context(), name)
+				if err != nil {
+					return false, err
+				}
+				return hasTags(key, opts.Tags), nil
+			}
+		}
+		var page kv.Iter[string] = iterator
+		if opts.paginate {
+			names, token, err := listPage(iterator, pattern, opts, match)
+			if err != nil {
+				return err
+			}
+			match = nil // The page only contains matching names
+			if token != "" {
+				w.Header().Set(ContinueTokenHeader, token)
+			}
+			page = &sliceIter{names: names}
+		}
+
+		var (
+			hasWritten bool
+			encoder    = json.NewEncoder(w)
+		)
+		for {
+			name, ok := page.Next()
+			if !ok {
+				break
+			}
+			if !matchName(pattern, name) || name == "" {
+				continue
+			}
+			if match != nil {
+				if ok, err = match(name); err != nil {
+					if hasWritten {
+						encoder.Encode(Response{Err: err.Error()})
+						return nil
+					}
+					return err
+				}
+				if !ok {
+					continue
+				}
+			}
+			if !hasWritten {
+				w.Header().Set("Content-Type", ContentType)
+			}
+			hasWritten = true
+
+			if err = encoder.Encode(Response{Name: name}); err != nil {
+				return nil
+			}
+		}
+		if err = iterator.Close(); err != nil {
+			if hasWritten {
+				encoder.Encode(Response{Err: err.Error()})
+				return nil
+			}
+			return err
+		}
+		if !hasWritten {
+			w.Header().Set("Content-Type", ContentType)
+			w.WriteHeader(http.StatusOK)
+		}
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func signKey(config *RouterConfig) API {
+	const (
+		Method      = http.MethodPost
+		APIPath     = "/v1/key/sign/"
+		MaxBody     = int64(1 * mem.MiB)
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	type Request struct {
+		Message []byte `json:"message"`
+	}
+	type Response struct {
+		Signature []byte `json:"signature"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := pathFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		key, err := VSync(config.Vault.RLocker(), func() (key.Key, error) {
+			enclave, err := enclaveFromRequest(config.Vault, r)
+			if err != nil {
+				return key.Key{}, err
+			}
+			return VSync(enclave.RLocker(), func() (key.Key, error) {
+				if err = enclave.VerifyRequest(r); err != nil {
+					return key.Key{}, err
+				}
+				return cryptoKey(r.Context(), enclave, name)
+			})
+		})
+		if err != nil {
+			return err
+		}
+
+		var req Request
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		signature, err := key.Sign(req.Message)
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{
+			Signature: signature,
+		})
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func edgeSignKey(config *EdgeRouterConfig) API {
+	var (
+		Method      = http.MethodPost
+		APIPath     = "/v1/key/sign/"
+		MaxBody     = int64(1 * mem.MiB)
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	if c, ok := config.APIConfig[APIPath]; ok {
+		if c.Timeout > 0 {
+			Timeout = c.Timeout
+		}
+	}
+	type Request struct {
+		Message []byte `json:"message"`
+	}
+	type Response struct {
+		Signature []byte `json:"signature"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := pathFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
+			return err
+		}
+		store, err := keysFromRequest(config, r)
+		if err != nil {
+			return err
+		}
+
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		key, err := store.Get(r.Context(), name)
+		if err != nil {
+			return err
+		}
+		signature, err := key.Sign(req.Message)
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{
+			Signature: signature,
+		})
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func hmacKey(config *RouterConfig) API {
+	const (
+		Method      = http.MethodPost
+		APIPath     = "/v1/key/hmac/"
+		MaxBody     = int64(1 * mem.MiB)
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	type Request struct {
+		Message []byte `json:"message"`
+	}
+	type Response struct {
+		HMAC []byte `json:"hmac"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := pathFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		key, err := VSync(config.Vault.RLocker(), func() (key.Key, error) {
+			enclave, err := enclaveFromRequest(config.Vault, r)
+			if err != nil {
+				return key.Key{}, err
+			}
+			return VSync(enclave.RLocker(), func() (key.Key, error) {
+				if err = enclave.VerifyRequest(r); err != nil {
+					return key.Key{}, err
+				}
+				return cryptoKey(r.Context(), enclave, name)
+			})
+		})
+		if err != nil {
+			return err
+		}
+
+		var req Request
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		sum, err := key.HMAC(req.Message)
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{
+			HMAC: sum,
+		})
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func edgeHMACKey(config *EdgeRouterConfig) API {
+	var (
+		Method      = http.MethodPost
+		APIPath     = "/v1/key/hmac/"
+		MaxBody     = int64(1 * mem.MiB)
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	if c, ok := config.APIConfig[APIPath]; ok {
+		if c.Timeout > 0 {
+			Timeout = c.Timeout
+		}
+	}
+	type Request struct {
+		Message []byte `json:"message"`
+	}
+	type Response struct {
+		HMAC []byte `json:"hmac"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := pathFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
+			return err
+		}
+		store, err := keysFromRequest(config, r)
+		if err != nil {
+			return err
+		}
+
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		key, err := store.Get(r.Context(), name)
+		if err != nil {
+			return err
+		}
+		sum, err := key.HMAC(req.Message)
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{
+			HMAC: sum,
+		})
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func deriveKey(config *RouterConfig) API {
+	const (
+		Method      = http.MethodPost
+		APIPath     = "/v1/key/derive/"
+		MaxBody     = int64(1 * mem.MiB)
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	type Request struct {
+		Label   string `json:"label"`
+		Context []byte `json:"context"` // optional
+		Length  int    `json:"length"`  // optional
+	}
+	type Response struct {
+		Key []byte `json:"key"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := pathFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		var crypto sys.CryptoPolicy
+		k, err := VSync(config.Vault.RLocker(), func() (key.Key, error) {
+			enclave, err := enclaveFromRequest(config.Vault, r)
+			if err != nil {
+				return key.Key{}, err
+			}
+			return VSync(enclave.RLocker(), func() (key.Key, error) {
+				if err = enclave.VerifyRequest(r); err != nil {
+					return key.Key{}, err
+				}
+				crypto = *enclave.CryptoPolicy()
+				return cryptoKey(r.Context(), enclave, name)
+			})
+		})
+		if err != nil {
+			return err
+		}
+
+		var req Request
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		if req.Length == 0 {
+			req.Length = key.Size
+		}
+		if err = crypto.VerifyDerive(sys.HKDF_SHA256, req.Length); err != nil {
+			return err
+		}
+		derivedKey, err := k.Derive(req.Label, req.Context, req.Length)
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{
+			Key: derivedKey,
+		})
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func edgeDeriveKey(config *EdgeRouterConfig) API {
+	var (
+		Method      = http.MethodPost
+		APIPath     = "/v1/key/derive/"
+		MaxBody     = int64(1 * mem.MiB)
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	if c, ok := config.APIConfig[APIPath]; ok {
+		if c.Timeout > 0 {
+			Timeout = c.Timeout
+		}
+	}
+	type Request struct {
+		Label   string `json:"label"`
+		Context []byte `json:"context"` // optional
+		Length  int    `json:"length"`  // optional
+	}
+	type Response struct {
+		Key []byte `json:"key"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := pathFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
+			return err
+		}
+		store, err := keysFromRequest(config, r)
+		if err != nil {
+			return err
+		}
+
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		if req.Length == 0 {
+			req.Length = key.Size
+		}
+		k, err := store.Get(r.Context(), name)
+		if err != nil {
+			return err
+		}
+		derivedKey, err := k.Derive(req.Label, req.Context, req.Length)
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{
+			Key: derivedKey,
+		})
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func verifyKey(config *RouterConfig) API {
+	const (
+		Method      = http.MethodPost
+		APIPath     = "/v1/key/verify/"
+		MaxBody     = int64(1 * mem.MiB)
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	type Request struct {
+		Message   []byte `json:"message"`
+		Signature []byte `json:"signature"`
+	}
+	type Response struct {
+		Valid bool `json:"valid"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := pathFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		key, err := VSync(config.Vault.RLocker(), func() (key.Key, error) {
+			enclave, err := enclaveFromRequest(config.Vault, r)
+			if err != nil {
+				return key.Key{}, err
+			}
+			return VSync(enclave.RLocker(), func() (key.Key, error) {
+				if err = enclave.VerifyRequest(r); err != nil {
+					return key.Key{}, err
+				}
+				return cryptoKey(r.Context(), enclave, name)
+			})
+		})
+		if err != nil {
+			return err
+		}
+
+		var req Request
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		valid, err := key.Verify(req.Message, req.Signature)
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{
+			Valid: valid,
+		})
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func edgeVerifyKey(config *EdgeRouterConfig) API {
+	var (
+		Method      = http.MethodPost
+		APIPath     = "/v1/key/verify/"
+		MaxBody     = int64(1 * mem.MiB)
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	if c, ok := config.APIConfig[APIPath]; ok {
+		if c.Timeout > 0 {
+			Timeout = c.Timeout
+		}
+	}
+	type Request struct {
+		Message   []byte `json:"message"`
+		Signature []byte `json:"signature"`
+	}
+	type Response struct {
+		Valid bool `json:"valid"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := pathFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
+			return err
+		}
+		store, err := keysFromRequest(config, r)
+		if err != nil {
+			return err
+		}
+
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		key, err := store.Get(r.Context(), name)
+		if err != nil {
+			return err
+		}
+		valid, err := key.Verify(req.Message, req.Signature)
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{
+			Valid: valid,
+		})
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func publicKey(config *RouterConfig) API {
+	const (
+		Method      = http.MethodGet
+		APIPath     = "/v1/key/public/"
+		MaxBody     = 0
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	type Response struct {
+		Type      key.Type `json:"type"`
+		PublicKey string   `json:"public_key"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := pathFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		key, err := VSync(config.Vault.RLocker(), func() (key.Key, error) {
+			enclave, err := enclaveFromRequest(config.Vault, r)
+			if err != nil {
+				return key.Key{}, err
+			}
+			return VSync(enclave.RLocker(), func() (key.Key, error) {
+				if err = enclave.VerifyRequest(r); err != nil {
+					return key.Key{}, err
+				}
+				return enclave.GetKey(r.Context(), name)
+			})
+		})
+		if err != nil {
+			return err
+		}
+		publicKey, err := key.PublicKey()
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{
+			Type:      key.Type(),
+			PublicKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey})),
+		})
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func edgePublicKey(config *EdgeRouterConfig) API {
+	var (
+		Method      = http.MethodGet
+		APIPath     = "/v1/key/public/"
+		MaxBody     int64
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	if c, ok := config.APIConfig[APIPath]; ok {
+		if c.Timeout > 0 {
+			Timeout = c.Timeout
+		}
+	}
+	type Response struct {
+		Type      key.Type `json:"type"`
+		PublicKey string   `json:"public_key"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := pathFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
+			return err
+		}
+		store, err := keysFromRequest(config, r)
+		if err != nil {
+			return err
+		}
+		key, err := store.Get(r.Context(), name)
+		if err != nil {
+			return err
+		}
+		publicKey, err := key.PublicKey()
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{
+			Type:      key.Type(),
+			PublicKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey})),
+		})
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func exportKey(config *RouterConfig) API {
+	const (
+		Method      = http.MethodPost
+		APIPath     = "/v1/key/export/"
+		MaxBody     = int64(64 * mem.KiB)
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	type Request struct {
+		PublicKey string `json:"public_key"` // PEM-encoded RSA public key
+	}
+	type Response struct {
+		Name          string        `json:"name"`
+		Type          key.Type      `json:"type"`
+		Algorithm     key.Algorithm `json:"algorithm,omitempty"`
+		CreatedAt     time.Time     `json:"created_at,omitempty"`
+		CreatedBy     kes.Identity  `json:"created_by,omitempty"`
+		WrapAlgorithm string        `json:"wrap_algorithm"`
+		WrappedKey    []byte        `json:"wrapped_key"`
+		Ciphertext    []byte        `json:"ciphertext"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := pathFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		key, err := VSync(config.Vault.RLocker(), func() (key.Key, error) {
+			enclave, err := enclaveFromRequest(config.Vault, r)
+			if err != nil {
+				return key.Key{}, err
+			}
+			return VSync(enclave.RLocker(), func() (key.Key, error) {
+				if err = enclave.VerifyRequest(r); err != nil {
+					return key.Key{}, err
+				}
+				return enclave.GetKey(r.Context(), name)
+			})
+		})
+		if err != nil {
+			return err
+		}
+
+		var req Request
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		exported, err := exportedKey(key, req.PublicKey)
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		resp := Response{
+			Name:          name,
+			Type:          key.Type(),
+			CreatedAt:     key.CreatedAt(),
+			CreatedBy:     key.CreatedBy(),
+			WrapAlgorithm: keyExportAlgorithm,
+			WrappedKey:    exported.WrappedKey,
+			Ciphertext:    exported.Ciphertext,
+		}
+		if !key.Type().IsAsymmetric() {
+			resp.Algorithm = keyAlgorithm(key.Algorithm())
+		}
+		json.NewEncoder(w).Encode(resp)
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func edgeExportKey(config *EdgeRouterConfig) API {
+	var (
+		Method      = http.MethodPost
+		APIPath     = "/v1/key/export/"
+		MaxBody     = int64(64 * mem.KiB)
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	if c, ok := config.APIConfig[APIPath]; ok {
+		if c.Timeout > 0 {
+			Timeout = c.Timeout
+		}
+	}
+	type Request struct {
+		PublicKey string `json:"public_key"` // PEM-encoded RSA public key
+	}
+	type Response struct {
+		Name          string        `json:"name"`
+		Type          key.Type      `json:"type"`
+		Algorithm     key.Algorithm `json:"algorithm,omitempty"`
+		CreatedAt     time.Time     `json:"created_at,omitempty"`
+		CreatedBy     kes.Identity  `json:"created_by,omitempty"`
+		WrapAlgorithm string        `json:"wrap_algorithm"`
+		WrappedKey    []byte        `json:"wrapped_key"`
+		Ciphertext    []byte        `json:"ciphertext"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := pathFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
+			return err
+		}
+		store, err := keysFromRequest(config, r)
+		if err != nil {
+			return err
+		}
+
+		var req Request
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		key, err := store.Get(r.Context(), name)
+		if err != nil {
+			return err
+		}
+		exported, err := exportedKey(key, req.PublicKey)
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		resp := Response{
+			Name:          name,
+			Type:          key.Type(),
+			CreatedAt:     key.CreatedAt(),
+			CreatedBy:     key.CreatedBy(),
+			WrapAlgorithm: keyExportAlgorithm,
+			WrappedKey:    exported.WrappedKey,
+			Ciphertext:    exported.Ciphertext,
+		}
+		if !key.Type().IsAsymmetric() {
+			resp.Algorithm = keyAlgorithm(key.Algorithm())
+		}
+		json.NewEncoder(w).Encode(resp)
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func expireKey(config *RouterConfig) API {
+	const (
+		Method  = http.MethodPost
+		APIPath = "/v1/key/expire/"
+		MaxBody = int64(1 * mem.KiB)
+		Timeout = 15 * time.Second
+		Verify  = true
+	)
+	type Request struct {
+		ExpiresAt string `json:"expires_at"`
+		TTL       string `json:"ttl"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := pathFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+
+		var req Request
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		expiresAt, err := parseExpiry(req.ExpiresAt, req.TTL)
+		if err != nil {
+			return err
+		}
+
+		if err = Sync(config.Vault.RLocker(), func() error {
+			enclave, err := enclaveFromRequest(config.Vault, r)
+			if err != nil {
+				return err
+			}
+			return Sync(enclave.Locker(), func() error {
+				if err = enclave.VerifyRequest(r); err != nil {
+					return err
+				}
+
+				key, err := enclave.GetKey(r.Context(), name)
+				if err != nil {
+					return err
+				}
+				key = key.Clone()
+				key.SetExpiresAt(expiresAt)
+				return enclave.SetKey(r.Context(), name, key)
+			})
+		}); err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func edgeExpireKey(config *EdgeRouterConfig) API {
+	var (
+		Method  = http.MethodPost
+		APIPath = "/v1/key/expire/"
+		MaxBody = int64(1 * mem.KiB)
+		Timeout = 15 * time.Second
+		Verify  = true
+	)
+	if c, ok := config.APIConfig[APIPath]; ok {
+		if c.Timeout > 0 {
+			Timeout = c.Timeout
+		}
+	}
+	type Request struct {
+		ExpiresAt string `json:"expires_at"`
+		TTL       string `json:"ttl"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := pathFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
+			return err
+		}
+
+		var req Request
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		expiresAt, err := parseExpiry(req.ExpiresAt, req.TTL)
+		if err != nil {
+			return err
+		}
+
+		store, err := keysFromRequest(config, r)
+		if err != nil {
+			return err
+		}
+		key, err := store.Get(r.Context(), name)
+		if err != nil {
+			return err
+		}
+		newKey := key.Clone()
+		newKey.SetExpiresAt(expiresAt)
+
+		// The key is only replaced if it has not been modified
+		// concurrently. Otherwise, the request fails with 409
+		// Conflict and the client may retry. Keystores that
+		// cannot update keys in place return 501 Not Implemented.
+		if err = store.Update(r.Context(), name, key, newKey); err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func tagKey(config *RouterConfig) API {
+	const (
+		Method  = http.MethodPost
+		APIPath = "/v1/key/tag/"
+		MaxBody = int64(64 * mem.KiB)
+		Timeout = 15 * time.Second
+		Verify  = true
+	)
+	type Request struct {
+		Set    map[string]string `json:"set"`
+		Remove []string          `json:"remove"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := pathFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+
+		var req Request
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		if len(req.Set) == 0 && len(req.Remove) == 0 {
+			return kes.NewError(http.StatusBadRequest, "invalid argument: no tags specified")
+		}
+		for tag, value := range req.Set {
+			if err = verifyTag(tag, value); err != nil {
+				return err
+			}
+		}
+
+		if err = Sync(config.Vault.RLocker(), func() error {
+			enclave, err := enclaveFromRequest(config.Vault, r)
+			if err != nil {
+				return err
+			}
+			return Sync(enclave.Locker(), func() error {
+				if err = enclave.VerifyRequest(r); err != nil {
+					return err
+				}
+
+				key, err := enclave.GetKey(r.Context(), name)
+				if err != nil {
+					return err
+				}
+				tags := make(map[string]string, len(key.Tags())+len(req.Set))
+				for tag, value := range key.Tags() {
+					tags[tag] = value
+				}
+				for _, tag := range req.Remove {
+					delete(tags, tag)
+				}
+				for tag, value := range req.Set {
+					tags[tag] = value
+				}
+				if len(tags) > maxTags {
+					return kes.NewError(http.StatusBadRequest, "invalid argument: too many tags")
+				}
+
+				key = key.Clone()
+				key.SetTags(tags)
+				return enclave.SetKey(r.Context(), name, key)
+			})
+		}); err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+// newKey generates a new random key for the given request.
+//
+// The key type can be specified via the optional 'type'
+// query parameter. By default, newKey generates a symmetric
+// key for the fastest encryption algorithm available that
+// is approved by the crypto policy, if not nil. Key tags can
+// be specified via repeated 'tag=name=value' query parameters.
+func newKey(r *http.Request, crypto *sys.CryptoPolicy) (key.Key, error) {
+	keyType, err := key.ParseType(r.URL.Query().Get("type"))
+	if err != nil {
+		return key.Key{}, err
+	}
+	expiresAt, err := parseExpiry(r.URL.Query().Get("expires_at"), r.URL.Query().Get("ttl"))
+	if err != nil {
+		return key.Key{}, err
+	}
+	tags, err := parseTags(r.URL.Query()["tag"])
+	if err != nil {
+		return key.Key{}, err
+	}
+	algorithm, err := key.ParseAlgorithm(r.URL.Query().Get("algorithm"))
+	if err != nil {
+		return key.Key{}, err
+	}
+	if keyType.IsAsymmetric() && algorithm != kes.KeyAlgorithmUndefined {
+		return key.Key{}, kes.NewError(http.StatusBadRequest, "invalid key algorithm: asymmetric keys do not support an encryption algorithm")
+	}
+
+	var k key.Key
+	if keyType.IsAsymmetric() {
+		k, err = key.RandomAsymmetric(keyType, auth.Identify(r))
+	} else {
+		if algorithm == kes.KeyAlgorithmUndefined {
+			if fips.Enabled || cpu.HasAESGCM() {
+				algorithm = kes.AES256_GCM_SHA256
+			} else {
+				algorithm = kes.XCHACHA20_POLY1305
+			}
+			if crypto != nil {
+				algorithm = crypto.Algorithm(algorithm)
+			}
+		}
+		k, err = key.Random(algorithm, auth.Identify(r))
+	}
+	if err != nil {
+		return key.Key{}, err
+	}
+	k.SetExpiresAt(expiresAt)
+	k.SetTags(tags)
+	return k, nil
+}
+
+// cryptoKey returns the key with the given name if it
+// complies with the enclave's crypto policy. It should be
+// used to fetch keys for cryptographic operations.
+func cryptoKey(ctx context.Context, enclave *sys.Enclave, name string) (key.Key, error) {
+	k, err := enclave.GetKey(ctx, name)
+	if err != nil {
+		return key.Key{}, err
+	}
+	if err = enclave.CryptoPolicy().VerifyKey(&k); err != nil {
+		return key.Key{}, err
+	}
+	return k, nil
+}
+
+// maxBulkKeys is the max. number of keys that can be
+// created or deleted within a single bulk API call.
+const maxBulkKeys = 1000
+
+// bulkResponse is the result of a bulk key operation for
+// a single key. Bulk operations continue when an operation
+// on one key fails. Hence, each key has its own status.
+type bulkResponse struct {
+	Name    string `json:"name"`
+	Status  int    `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// newBulkResponse returns the bulkResponse for the named
+// key and the error of the key operation, if any.
+func newBulkResponse(name string, err error) bulkResponse {
+	if err == nil {
+		return bulkResponse{Name: name, Status: http.StatusOK}
+	}
+	status := http.StatusInternalServerError
+	if s, ok := err.(StatusCode); ok {
+		status = s.Status()
+	}
+	return bulkResponse{Name: name, Status: status, Message: err.Error()}
+}
+
+// bulkNamesFromRequest decodes the list of key names from
+// the request body of a bulk key API call. The names are
+// not verified since an invalid name must only fail the
+// operation on that particular key.
+func bulkNamesFromRequest(r *http.Request, apiPath string) ([]string, error) {
+	if r.URL.Path != apiPath {
+		return nil, kes.NewError(http.StatusBadRequest, "invalid argument: unexpected key name")
+	}
+
+	var names []string
+	if err := json.NewDecoder(r.Body).Decode(&names); err != nil {
+		return nil, kes.NewError(http.StatusBadRequest, err.Error())
+	}
+	if len(names) == 0 {
+		return nil, kes.NewError(http.StatusBadRequest, "invalid argument: no keys specified")
+	}
+	if len(names) > maxBulkKeys {
+		return nil, kes.NewError(http.StatusBadRequest, "invalid argument: too many keys")
+	}
+	return names, nil
+}
+
+// verifyBulkRequest verifies the bulk request r for the named
+// keys against the policies of the enclave. Each key is verified
+// as request to the single-key API at apiPath. However, the bulk
+// request only counts as a single request towards the enclave's
+// request quota.
+//
+// It returns one error per key.
+func verifyBulkRequest(enclave *sys.Enclave, r *http.Request, apiPath string, names []string) ([]error, error) {
+	var (
+		errs    = make([]error, len(names))
+		reqs    = make([]*http.Request, 0, len(names))
+		indices = make([]int, 0, len(names))
+	)
+	for i, name := range names {
+		if err := verifyPath(name); err != nil {
+			errs[i] = err
+			continue
+		}
+		reqs = append(reqs, keyRequest(r, apiPath, name))
+		indices = append(indices, i)
+	}
+	verified, err := enclave.VerifyRequests(reqs)
+	if err != nil {
+		return nil, err
+	}
+	for j, err := range verified {
+		errs[indices[j]] = err
+	}
+	return errs, nil
+}
+
+// keyRequest returns a shallow copy of the request r with the
+// URL path of the single-key API at apiPath for the named key.
+// Bulk API calls are authorized per key, such that the same
+// policies apply as for individual API calls.
+func keyRequest(r *http.Request, apiPath, name string) *http.Request {
+	u := *r.URL
+	u.Path = apiPath + name
+	u.RawPath = ""
+
+	req := *r
+	req.URL = &u
+	return &req
+}
+
+// importedKey returns a new key of the given type from the
+// imported key bytes. For asymmetric keys, the bytes must be a
+// PKCS #8 encoded private key matching the key type.
+func importedKey(r *http.Request, keyType key.Type, algorithm kes.KeyAlgorithm, bytes []byte) (key.Key, error) {
+	if keyType.IsAsymmetric() {
+		k, err := key.NewAsymmetric(bytes, auth.Identify(r))
+		if err != nil {
+			return key.Key{}, err
+		}
+		if k.Type() != keyType {
+			return key.Key{}, kes.NewError(http.StatusBadRequest, "invalid key type: private key is a '"+k.Type().String()+"' key")
+		}
+		return k, nil
+	}
+	if len(bytes) != key.Len(algorithm) {
+		return key.Key{}, kes.NewError(http.StatusBadRequest, "invalid key size")
+	}
+	return key.New(algorithm, bytes, auth.Identify(r))
+}
+
+// keyAlgorithm converts a to a key.Algorithm such that
+// algorithms not known to kes-go, like AES-256-GCM-SIV,
+// can be JSON-encoded.
+func keyAlgorithm(a kes.KeyAlgorithm) key.Algorithm { return key.Algorithm(a) }
+
+// keyExportAlgorithm is the algorithm used to wrap
+// exported key material.
+const keyExportAlgorithm = key.ExportAlgorithm
+
+// exportedKey encrypts the key material of k for the
+// given PEM-encoded RSA public key.
+func exportedKey(k key.Key, publicKey string) (key.Exported, error) {
+	if publicKey == "" {
+		return key.Exported{}, kes.NewError(http.StatusBadRequest, "no public key specified")
+	}
+	rsaKey, err := key.ParseRSAPublicKey([]byte(publicKey))
+	if err != nil {
+		return key.Exported{}, err
+	}
+	return k.Export(rsaKey)
+}
+
+// parseExpiry parses the point in time when a key expires.
+// The expiry is either specified as RFC 3339 timestamp or as
+// time-to-live duration, relative to now, but not both.
+//
+// It returns the zero time.Time if both are empty.
+func parseExpiry(expiresAt, ttl string) (time.Time, error) {
+	switch {
+	case expiresAt != "" && ttl != "":
+		return time.Time{}, kes.NewError(http.StatusBadRequest, "expiry and TTL must not be specified both")
+	case expiresAt != "":
+		t, err := time.Parse(time.RFC3339, expiresAt)
+		if err != nil {
+			return time.Time{}, kes.NewError(http.StatusBadRequest, "invalid expiry: "+err.Error())
+		}
+		if !t.After(time.Now()) {
+			return time.Time{}, kes.NewError(http.StatusBadRequest, "invalid expiry: expiry is in the past")
+		}
+		return t.UTC(), nil
+	case ttl != "":
+		d, err := time.ParseDuration(ttl)
+		if err != nil {
+			return time.Time{}, kes.NewError(http.StatusBadRequest, "invalid TTL: "+err.Error())
+		}
+		if d <= 0 {
+			return time.Time{}, kes.NewError(http.StatusBadRequest, "invalid TTL: TTL must be positive")
+		}
+		return time.Now().Add(d).UTC(), nil
+	default:
+		return time.Time{}, nil
+	}
+}
+
+// Limits for key tags.
+const (
+	maxTags        = 64
+	maxTagLen      = 128
+	maxTagValueLen = 256
+)
+
+// parseTags parses a list of 'name=value' tags. It
+// returns nil if the list is empty.
+func parseTags(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	if len(values) > maxTags {
+		return nil, kes.NewError(http.StatusBadRequest, "invalid argument: too many tags")
+	}
+
+	tags := make(map[string]string, len(values))
+	for _, s := range values {
+		tag, value, ok := strings.Cut(s, "=")
+		if !ok {
+			return nil, kes.NewError(http.StatusBadRequest, fmt.Sprintf("invalid argument: invalid tag '%s': expected 'name=value'", s))
+		}
+		if err := verifyTag(tag, value); err != nil {
+			return nil, err
+		}
+		tags[tag] = value
+	}
+	return tags, nil
+}
+
+// verifyTag returns an error if the tag name or
+// value is invalid.
+func verifyTag(tag, value string) error {
+	if tag == "" {
+		return kes.NewError(http.StatusBadRequest, "invalid argument: tag name must not be empty")
+	}
+	if len(tag) > maxTagLen || strings.ContainsAny(tag, "=\n") {
+		return kes.NewError(http.StatusBadRequest, fmt.Sprintf("invalid argument: invalid tag name '%s'", tag))
+	}
+	if len(value) > maxTagValueLen || strings.Contains(value, "\n") {
+		return kes.NewError(http.StatusBadRequest, fmt.Sprintf("invalid argument: invalid value of tag '%s'", tag))
+	}
+	return nil
+}
+
+func copyKey(config *RouterConfig) API {
+	const (
+		Method  = http.MethodPost
+		APIPath = "/v1/key/copy/"
+		MaxBody = int64(1 * mem.KiB)
+		Timeout = 15 * time.Second
+		Verify  = true
+	)
+	type Request struct {
+		Enclave string `json:"enclave"`
+		Name    string `json:"name"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := pathFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		var req Request
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		if err = Sync(config.Vault.RLocker(), func() error {
+			return transferKey(config.Vault, r, name, req.Enclave, req.Name, false)
+		}); err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func moveKey(config *RouterConfig, usage *keyUsage) API {
+	const (
+		Method  = http.MethodPost
+		APIPath = "/v1/key/move/"
+		MaxBody = int64(1 * mem.KiB)
+		Timeout = 15 * time.Second
+		Verify  = true
+	)
+	type Request struct {
+		Enclave string `json:"enclave"`
+		Name    string `json:"name"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := pathFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		var req Request
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		if err = Sync(config.Vault.RLocker(), func() error {
+			return transferKey(config.Vault, r, name, req.Enclave, req.Name, true)
+		}); err != nil {
+			return err
+		}
+
+		usage.Delete(usageID(r, name))
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+// requestWithPath returns a copy of r with the given
+// URL path. It is used to verify that a request is allowed
+// to perform the operations of another API.
+func requestWithPath(r *http.Request, path string) *http.Request {
+	req := r.Clone(r.Context())
+	req.URL.Path = path
+	req.URL.RawPath = ""
+	return req
+}
+
+// transferKey copies the key with the given name from the
+// request's enclave to the enclave 'to' under the new name.
+// If the new name is empty, the key keeps its name. If move
+// is true, the key gets deleted from the request's enclave
+// once it has been copied.
+//
+// The key material never leaves the server. The request
+// must be allowed by both enclaves. The caller must hold
+// the vault's read lock.
+func transferKey(vault *sys.Vault, r *http.Request, name, to, newName string, move bool) error {
+	if newName == "" {
+		newName = name
+	}
+	if err := verifyPath(newName); err != nil {
+		return err
+	}
+	if to == "" {
+		to = sys.DefaultEnclaveName
+	}
+	if err := verifyName(to); err != nil {
+		return err
+	}
+
+	src, err := enclaveFromRequest(vault, r)
+	if err != nil {
+		return err
+	}
+	dst, err := vault.GetEnclave(r.Context(), to)
+	if err != nil {
+		return err
+	}
+	if src == dst && name == newName {
+		return kes.NewError(http.StatusBadRequest, "source and destination key are identical")
+	}
+
+	// Always acquire the enclave locks in the same order
+	// such that concurrent transfers in opposite directions
+	// cannot deadlock.
+	from := r.URL.Query().Get("enclave")
+	if from == "" {
+		from = sys.DefaultEnclaveName
+	}
+	first, second := src, dst
+	if to < from {
+		first, second = dst, src
+	}
+	first.Locker().Lock()
+	defer first.Locker().Unlock()
+	if second != first {
+		second.Locker().Lock()
+		defer second.Locker().Unlock()
+	}
+
+	if err = src.VerifyRequest(r); err != nil {
+		return err
+	}
+	if dst != src {
+		if err = dst.VerifyRequest(r); err != nil {
+			return err
+		}
+	}
+
+	// The request creates a key in the destination enclave and,
+	// when moving, deletes a key in the source enclave. Hence,
+	// the identity must be allowed to do so, too.
+	if err = dst.VerifyRequest(requestWithPath(r, "/v1/key/create/"+newName)); err != nil {
+		return err
+	}
+	if move {
+		if err = src.VerifyRequest(requestWithPath(r, "/v1/key/delete/"+name)); err != nil {
+			return err
+		}
+	}
+
+	k, err := src.GetKey(r.Context(), name)
+	if err != nil {
+		return err
+	}
+	if err = dst.CreateKey(r.Context(), newName, k); err != nil {
+		return err
+	}
+	if move {
+		return src.DeleteKey(r.Context(), name)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/base64"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/key"
+	"github.com/minio/kes/kv"
+)
+
+// ContinueTokenHeader is the HTTP response header containing
+// the token for requesting the next page of a paginated list.
+const ContinueTokenHeader = "Kes-Continue-Token"
+
+// MaxListLimit is the max. number of entries a client
+// can request within one page.
+const MaxListLimit = 10000
+
+// listOptions are the pagination and sort options of a
+// list API request.
+type listOptions struct {
+	Limit    int    // Max. number of entries per page. 0 means no limit
+	Continue string // Name of the last entry of the previous page
+	Desc     bool   // Sort in descending instead of ascending order
+
+	Tags map[string]string // Only list entries with all these tags
+
+	paginate bool // Whether any option has been specified
+}
+
+// listOptionsFromRequest parses the list options from
+// the request's query parameters:
+//   - limit:    max. number of entries per page.
+//   - continue: the token returned for the previous page.
+//   - sort:     either 'asc' or 'desc'.
+//   - tag:      a 'name=value' tag filter. May be repeated.
+//
+// If no option is specified, entries are listed unsorted
+// without pagination.
+func listOptionsFromRequest(r *http.Request) (listOptions, error) {
+	query := r.URL.Query()
+
+	var opts listOptions
+	if s := query.Get("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil || limit <= 0 {
+			return listOptions{}, kes.NewError(http.StatusBadRequest, "invalid argument: limit must be a positive number")
+		}
+		if limit > MaxListLimit {
+			limit = MaxListLimit
+		}
+		opts.Limit = limit
+		opts.paginate = true
+	}
+	if s := query.Get("continue"); s != "" {
+		name, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil {
+			return listOptions{}, kes.NewError(http.StatusBadRequest, "invalid argument: invalid continue token")
+		}
+		opts.Continue = string(name)
+		opts.paginate = true
+	}
+	switch s := query.Get("sort"); s {
+	case "":
+	case "asc":
+		opts.paginate = true
+	case "desc":
+		opts.Desc = true
+		opts.paginate = true
+	default:
+		return listOptions{}, kes.NewError(http.StatusBadRequest, "invalid argument: sort must be either 'asc' or 'desc'")
+	}
+
+	tags, err := parseTags(query["tag"])
+	if err != nil {
+		return listOptions{}, err
+	}
+	opts.Tags = tags
+	return opts, nil
+}
+
+// listPage reads all names matching the pattern from the
+// iterator and returns the sorted page of names selected by
+// opts as well as a token for requesting the next page. The
+// token is empty if there are no more names.
+//
+// listPage does not close the iterator. Closing it, and
+// checking its error, is left to the caller.
+//
+// If match is not nil, listPage only selects names for which
+// match returns true. It only keeps the names in memory, not
+// the entries.
+func listPage(iter kv.Iter[string], pattern string, opts listOptions, match func(string) (bool, error)) ([]string, string, error) {
+	var names []string
+	for name, ok := iter.Next(); ok; name, ok = iter.Next() {
+		if name == "" {
+			continue
+		}
+		if !matchName(pattern, name) {
+			continue
+		}
+		if opts.Continue != "" {
+			if !opts.Desc && name <= opts.Continue {
+				continue
+			}
+			if opts.Desc && name >= opts.Continue {
+				continue
+			}
+		}
+		if match != nil {
+			ok, err := match(name)
+			if err != nil {
+				return nil, "", err
+			}
+			if !ok {
+				continue
+			}
+		}
+		names = append(names, name)
+	}
+
+	if opts.Desc {
+		sort.Sort(sort.Reverse(sort.StringSlice(names)))
+	} else {
+		sort.Strings(names)
+	}
+	if opts.Limit == 0 || len(names) <= opts.Limit {
+		return names, "", nil
+	}
+	names = names[:opts.Limit]
+	return names, base64.RawURLEncoding.EncodeToString([]byte(names[len(names)-1])), nil
+}
+
+// listPrefix returns the longest prefix of the pattern
+// that does not contain any wildcard. All names matching
+// the pattern start with this prefix. Hence, stores only
+// have to list names with this prefix.
+func listPrefix(pattern string) string {
+	if i := strings.IndexAny(pattern, `*?[\`); i >= 0 {
+		return pattern[:i]
+	}
+	return pattern
+}
+
+// hasTags reports whether k has all the given tags.
+func hasTags(k key.Key, tags map[string]string) bool {
+	for name, value := range tags {
+		if !k.HasTag(name, value) {
+			return false
+		}
+	}
+	return true
+}
+
+// sliceIter is a kv.Iter over a list of names.
+type sliceIter struct {
+	names []string
+}
+
+func (i *sliceIter) Next() (string, bool) {
+	if len(i.names) == 0 {
+		return "", false
+	}
+	name := i.names[0]
+	i.names = i.names[1:]
+	return name, true
+}
+
+func (i *sliceIter) Close() error {
+	i.names = nil
+	return nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestListPage(t *testing.T) {
+	keys := []string{"key-3", "other", "key-1", "key-5", "key-2", "key-4"}
+
+	for i, test := range listPageTests {
+		u, err := url.Parse("/v1/key/list/*?" + test.Query)
+		if err != nil {
+			t.Fatalf("Test %d: failed to parse URL: %v", i, err)
+		}
+		opts, err := listOptionsFromRequest(&http.Request{URL: u})
+		if err != nil {
+			t.Fatalf("Test %d: failed to parse list options: %v", i, err)
+		}
+
+		var pages []string
+		for {
+			names, token, err := listPage(&sliceIter{names: keys}, "key-*", opts, nil)
+			if err != nil {
+				t.Fatalf("Test %d: failed to list page: %v", i, err)
+			}
+			pages = append(pages, strings.Join(names, ","))
+			if token == "" {
+				break
+			}
+			if opts, err = listOptionsFromRequest(&http.Request{URL: &url.URL{RawQuery: test.Query + "&continue=" + token}}); err != nil {
+				t.Fatalf("Test %d: failed to parse list options: %v", i, err)
+			}
+		}
+		if got := strings.Join(pages, "|"); got != test.Pages {
+			t.Fatalf("Test %d: pages mismatch: got '%s' - want '%s'", i, got, test.Pages)
+		}
+	}
+}
+
+var listPageTests = []struct {
+	Query string
+	Pages string
+}{
+	{Query: "sort=asc", Pages: "key-1,key-2,key-3,key-4,key-5"},          // 0
+	{Query: "sort=desc", Pages: "key-5,key-4,key-3,key-2,key-1"},         // 1
+	{Query: "limit=2", Pages: "key-1,key-2|key-3,key-4|key-5"},           // 2
+	{Query: "limit=2&sort=desc", Pages: "key-5,key-4|key-3,key-2|key-1"}, // 3
+	{Query: "limit=5", Pages: "key-1,key-2,key-3,key-4,key-5"},           // 4
+	{Query: "limit=4&sort=asc", Pages: "key-1,key-2,key-3,key-4|key-5"},  // 5
+}
+
+func TestListOptionsFromRequest(t *testing.T) {
+	for i, query := range []string{"limit=0", "limit=-1", "limit=abc", "sort=up", "continue=!", "tag=env", "tag==prod"} {
+		_, err := listOptionsFromRequest(&http.Request{URL: &url.URL{RawQuery: query}})
+		if err == nil {
+			t.Fatalf("Test %d: query '%s' should have been rejected", i, query)
+		}
+	}
+}
+
+func TestListOptionsTags(t *testing.T) {
+	opts, err := listOptionsFromRequest(&http.Request{URL: &url.URL{RawQuery: "tag=env%3Dprod&tag=tenant%3D"}})
+	if err != nil {
+		t.Fatalf("Failed to parse list options: %v", err)
+	}
+	if len(opts.Tags) != 2 || opts.Tags["env"] != "prod" || opts.Tags["tenant"] != "" {
+		t.Fatalf("Tags mismatch: got '%v'", opts.Tags)
+	}
+	if opts.paginate {
+		t.Fatal("Tag filter must not enable pagination")
+	}
+}
+
+func TestListPrefix(t *testing.T) {
+	for i, test := range listPrefixTests {
+		if prefix := listPrefix(test.Pattern); prefix != test.Prefix {
+			t.Fatalf("Test %d: prefix mismatch: got '%s' - want '%s'", i, prefix, test.Prefix)
+		}
+	}
+}
+
+var listPrefixTests = []struct {
+	Pattern string
+	Prefix  string
+}{
+	{Pattern: "*", Prefix: ""},                       // 0
+	{Pattern: "my-key", Prefix: "my-key"},            // 1
+	{Pattern: "tenant-a*", Prefix: "tenant-a"},       // 2
+	{Pattern: "tenant-a/*/key", Prefix: "tenant-a/"}, // 3
+	{Pattern: "tenant-a/", Prefix: "tenant-a/"},      // 4
+}
@@ -5,12 +5,18 @@
 package api
 
 import (
+	"encoding/json"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/audit"
 	"github.com/minio/kes/internal/auth"
 	"github.com/minio/kes/internal/https"
 	"github.com/minio/kes/internal/log"
+	"github.com/minio/kes/internal/sys"
 )
 
 func errorLog(config *RouterConfig) API {
@@ -173,3 +179,308 @@ func edgeAuditLog(config *EdgeRouterConfig) API {
 		Handler: config.Metrics.Count(config.Metrics.Latency(handler)),
 	}
 }
+
+func auditStats(config *RouterConfig) API {
+	const (
+		Method      = http.MethodGet
+		APIPath     = "/v1/log/stats"
+		MaxBody     = 0
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	type Response struct {
+		Since time.Time      `json:"since"`
+		Stats []audit.Rollup `json:"stats"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		if config.AuditStats == nil {
+			return errAuditStatsDisabled
+		}
+		since, err := parseSince(r.URL.Query().Get("since"), time.Now())
+		if err != nil {
+			return err
+		}
+		if err = Sync(config.Vault.RLocker(), func() error {
+			enclave, err := enclaveFromRequest(config.Vault, r)
+			if err != nil {
+				return err
+			}
+			return Sync(enclave.RLocker(), func() error { return enclave.VerifyRequest(r) })
+		}); err != nil {
+			return err
+		}
+
+		rollups := config.AuditStats.Query(since, auditEnclaves(r)...)
+		for i := range rollups {
+			rollups[i].Enclave = ""
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{
+			Since: since,
+			Stats: rollups,
+		})
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func auditRetention(config *RouterConfig) API {
+	const (
+		Method      = http.MethodGet
+		APIPath     = "/v1/log/retention"
+		MaxBody     = 0
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		if config.AuditStats == nil {
+			return errAuditStatsDisabled
+		}
+		if err := Sync(config.Vault.RLocker(), func() error {
+			enclave, err := enclaveFromRequest(config.Vault, r)
+			if err != nil {
+				return err
+			}
+			return Sync(enclave.RLocker(), func() error { return enclave.VerifyRequest(r) })
+		}); err != nil {
+			return err
+		}
+
+		status, err := config.AuditStats.Status(auditEnclaves(r)...)
+		if err != nil {
+			return err
+		}
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(status)
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func purgeAuditStats(config *RouterConfig) API {
+	const (
+		Method      = http.MethodDelete
+		APIPath     = "/v1/log/purge"
+		MaxBody     = 0
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	type Response struct {
+		Before time.Time `json:"before"`
+		Purged int       `json:"purged"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		if config.AuditStats == nil {
+			return errAuditStatsDisabled
+		}
+		if r.URL.Query().Get("before") == "" {
+			return kes.NewError(http.StatusBadRequest, "invalid argument: no point in time specified")
+		}
+		before, err := parseSince(r.URL.Query().Get("before"), time.Now())
+		if err != nil {
+			return err
+		}
+		if err = Sync(config.Vault.RLocker(), func() error {
+			enclave, err := enclaveFromRequest(config.Vault, r)
+			if err != nil {
+				return err
+			}
+			return Sync(enclave.RLocker(), func() error { return enclave.VerifyRequest(r) })
+		}); err != nil {
+			return err
+		}
+
+		n, err := config.AuditStats.Purge(before, auditEnclaves(r)...)
+		if err != nil {
+			return err
+		}
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{
+			Before: before,
+			Purged: n,
+		})
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+// auditEnclaves returns the enclave names under which audit
+// statistics of the request's enclave are recorded. Requests
+// to the default enclave may or may not specify the enclave
+// name explicitly.
+func auditEnclaves(r *http.Request) []string {
+	enclave := r.URL.Query().Get("enclave")
+	if enclave == "" || enclave == sys.DefaultEnclaveName {
+		return []string{sys.DefaultEnclaveName, ""}
+	}
+	return []string{enclave}
+}
+
+func edgeAuditStats(config *EdgeRouterConfig) API {
+	var (
+		Method      = http.MethodGet
+		APIPath     = "/v1/log/stats"
+		MaxBody     int64
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	if c, ok := config.APIConfig[APIPath]; ok {
+		if c.Timeout > 0 {
+			Timeout = c.Timeout
+		}
+	}
+	type Response struct {
+		Since time.Time      `json:"since"`
+		Stats []audit.Rollup `json:"stats"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
+			return err
+		}
+		if config.AuditStats == nil {
+			return errAuditStatsDisabled
+		}
+		since, err := parseSince(r.URL.Query().Get("since"), time.Now())
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{
+			Since: since,
+			Stats: config.AuditStats.Query(since),
+		})
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+// errAuditStatsDisabled is returned by the audit stats
+// API when the server does not collect audit statistics.
+var errAuditStatsDisabled = kes.NewError(http.StatusNotImplemented, "audit statistics are not enabled")
+
+func edgeClientTLSReport(config *EdgeRouterConfig) API {
+	var (
+		Method      = http.MethodGet
+		APIPath     = "/v1/log/tls"
+		MaxBody     int64
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	if c, ok := config.APIConfig[APIPath]; ok {
+		if c.Timeout > 0 {
+			Timeout = c.Timeout
+		}
+	}
+	type Response struct {
+		Since   time.Time           `json:"since"`
+		Enforce bool                `json:"enforce"`
+		Clients []audit.TLSOffender `json:"clients"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
+			return err
+		}
+		if config.ClientTLS == nil || config.TLSReport == nil {
+			return errClientTLSDisabled
+		}
+		since, err := parseSince(r.URL.Query().Get("since"), time.Now())
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{
+			Since:   since,
+			Enforce: config.ClientTLS.Enforce,
+			Clients: config.TLSReport.Query(since),
+		})
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+// errClientTLSDisabled is returned by the client TLS report
+// API when the server has no client TLS policy.
+var errClientTLSDisabled = kes.NewError(http.StatusNotImplemented, "client TLS policy is not enabled")
+
+// parseSince parses s as the start of an audit stats
+// query window. It accepts either an RFC 3339 timestamp
+// or a duration relative to now. In addition to Go
+// durations, like '72h', it accepts a number of days,
+// like '30d'. If s is empty, the window starts at the
+// beginning of the current day.
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return now.UTC().Truncate(24 * time.Hour), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC(), nil
+	}
+
+	var (
+		d   time.Duration
+		err error
+	)
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		var n int
+		if n, err = strconv.Atoi(days); err == nil {
+			d = time.Duration(n) * 24 * time.Hour
+		}
+	} else {
+		d, err = time.ParseDuration(s)
+	}
+	if err != nil {
+		return time.Time{}, kes.NewError(http.StatusBadRequest, "invalid since: '"+s+"' is neither a duration nor a timestamp")
+	}
+	if d < 0 {
+		return time.Time{}, kes.NewError(http.StatusBadRequest, "invalid since: duration must not be negative")
+	}
+	return now.Add(-d).UTC(), nil
+}
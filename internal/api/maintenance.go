@@ -0,0 +1,176 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/audit"
+	"github.com/minio/kes/internal/auth"
+)
+
+// ErrReadOnly is returned when a request would modify
+// server state while the server is in read-only mode.
+var ErrReadOnly = kes.NewError(http.StatusServiceUnavailable, "server is in read-only mode")
+
+// errShuttingDown is returned by readiness and health checks
+// while the server is shutting down.
+var errShuttingDown = kes.NewError(http.StatusServiceUnavailable, "server is shutting down")
+
+// Maintenance controls the maintenance mode of a server.
+//
+// In read-only mode, a server rejects all requests that
+// would create, modify or delete keys, secrets, policies,
+// identities or enclaves with ErrReadOnly. Cryptographic
+// operations, like encryption or decryption, are still
+// served.
+//
+// While shutting down, a server drains in-flight requests
+// and reports that it is not ready anymore such that load
+// balancers stop sending requests to it.
+//
+// A nil Maintenance is never in read-only mode and never
+// shutting down.
+type Maintenance struct {
+	readOnly     atomic.Bool
+	shuttingDown atomic.Bool
+}
+
+// ReadOnly reports whether the server is in read-only mode.
+func (m *Maintenance) ReadOnly() bool { return m != nil && m.readOnly.Load() }
+
+// SetReadOnly enables or disables the read-only mode.
+func (m *Maintenance) SetReadOnly(readOnly bool) { m.readOnly.Store(readOnly) }
+
+// ShuttingDown reports whether the server is shutting down.
+func (m *Maintenance) ShuttingDown() bool { return m != nil && m.shuttingDown.Load() }
+
+// SetShuttingDown marks the server as shutting down.
+// Once shutting down, a server cannot become ready again.
+func (m *Maintenance) SetShuttingDown() { m.shuttingDown.Store(true) }
+
+// readOnlyAPIs contains the API paths of non-GET APIs
+// that do not modify server state. They are still served
+// in read-only mode.
+var readOnlyAPIs = []string{
+	"/v1/key/encrypt/",
+	"/v1/key/generate/",
+	"/v1/key/decrypt/",
+	"/v1/key/bulk/decrypt/",
+	"/v1/key/sign/",
+	"/v1/key/verify/",
+	"/v1/key/hmac/",
+	"/v1/key/derive/",
+	"/v1/key/export/",
+	"/v1/policy/diff/",
+	"/v1/maintenance/read-only",
+	"/v1/drill/",
+	"/v1/admin/reload",
+}
+
+// isMutation reports whether the request may modify
+// server state.
+func isMutation(req *http.Request) bool {
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		return false
+	}
+	for _, path := range readOnlyAPIs {
+		if strings.HasPrefix(req.URL.Path, path) {
+			return false
+		}
+	}
+	return true
+}
+
+func setReadOnly(config *RouterConfig, maintenance *Maintenance) API {
+	const (
+		Method  = http.MethodPost
+		APIPath = "/v1/maintenance/read-only"
+		MaxBody = 1 << 10
+		Timeout = 15 * time.Second
+		Verify  = true
+	)
+	type Request struct {
+		ReadOnly bool `json:"read_only"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		if err := Sync(config.Vault.RLocker(), func() error {
+			sysAdmin, err := config.Vault.Admin(r.Context())
+			if err != nil {
+				return err
+			}
+			if auth.Identify(r) != sysAdmin {
+				return kes.ErrNotAllowed
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		maintenance.SetReadOnly(req.ReadOnly)
+		config.ErrorLog.Printf("read-only mode changed to '%v' by '%s'", req.ReadOnly, auth.Identify(r))
+
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func edgeSetReadOnly(config *EdgeRouterConfig, maintenance *Maintenance) API {
+	var (
+		Method  = http.MethodPost
+		APIPath = "/v1/maintenance/read-only"
+		MaxBody = int64(1 << 10)
+		Timeout = 15 * time.Second
+		Verify  = true
+	)
+	if c, ok := config.APIConfig[APIPath]; ok {
+		if c.Timeout > 0 {
+			Timeout = c.Timeout
+		}
+	}
+	type Request struct {
+		ReadOnly bool `json:"read_only"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
+			return err
+		}
+
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		maintenance.SetReadOnly(req.ReadOnly)
+		config.ErrorLog.Printf("read-only mode changed to '%v' by '%s'", req.ReadOnly, auth.Identify(r))
+
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
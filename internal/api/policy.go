@@ -6,8 +6,8 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
-	"path"
 	"time"
 
 	"aead.dev/mem"
@@ -20,15 +20,18 @@ func assignPolicy(config *RouterConfig) API {
 	const (
 		Method  = http.MethodPost
 		APIPath = "/v1/policy/assign/"
-		MaxBody = int64(1 * mem.KiB)
+		MaxBody = int64(8 * mem.KiB)
 		Timeout = 15 * time.Second
 		Verify  = true
 	)
 	type Request struct {
-		Identity kes.Identity `json:"identity"`
+		Identity  kes.Identity      `json:"identity"`
+		ExpiresAt string            `json:"expires_at,omitempty"`
+		TTL       string            `json:"ttl,omitempty"`
+		Labels    map[string]string `json:"labels,omitempty"`
 	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
-		name, err := nameFromRequest(r, APIPath)
+		name, err := pathFromRequest(r, APIPath)
 		if err != nil {
 			return err
 		}
@@ -63,7 +66,14 @@ func assignPolicy(config *RouterConfig) API {
 				if admin == req.Identity {
 					return kes.NewError(http.StatusBadRequest, "cannot assign policy to system admin")
 				}
-				return enclave.AssignPolicy(r.Context(), name, req.Identity)
+				expiresAt, err := parseExpiry(req.ExpiresAt, req.TTL)
+				if err != nil {
+					return err
+				}
+				if err = verifyLabels(req.Labels); err != nil {
+					return err
+				}
+				return enclave.AssignPolicy(r.Context(), name, req.Identity, expiresAt, req.Labels)
 			})
 		}); err != nil {
 			return err
@@ -96,7 +106,7 @@ func describePolicy(config *RouterConfig) API {
 		CreatedBy kes.Identity `json:"created_by,omitempty"`
 	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
-		name, err := nameFromRequest(r, APIPath)
+		name, err := pathFromRequest(r, APIPath)
 		if err != nil {
 			return err
 		}
@@ -154,7 +164,7 @@ func edgeDescribePolicy(config *EdgeRouterConfig) API {
 		CreatedBy kes.Identity `json:"created_by,omitempty"`
 	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
-		name, err := nameFromRequest(r, APIPath)
+		name, err := pathFromRequest(r, APIPath)
 		if err != nil {
 			return err
 		}
@@ -201,7 +211,7 @@ func readPolicy(config *RouterConfig) API {
 		CreatedBy kes.Identity `json:"created_by,omitempty"`
 	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
-		name, err := nameFromRequest(r, APIPath)
+		name, err := pathFromRequest(r, APIPath)
 		if err != nil {
 			return err
 		}
@@ -263,7 +273,7 @@ func edgeReadPolicy(config *EdgeRouterConfig) API {
 		CreatedBy kes.Identity `json:"created_by,omitempty"`
 	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
-		name, err := nameFromRequest(r, APIPath)
+		name, err := pathFromRequest(r, APIPath)
 		if err != nil {
 			return err
 		}
@@ -309,7 +319,7 @@ func writePolicy(config *RouterConfig) API {
 		Deny  []string `json:"deny,omitempty"`
 	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
-		name, err := nameFromRequest(r, APIPath)
+		name, err := pathFromRequest(r, APIPath)
 		if err != nil {
 			return err
 		}
@@ -352,6 +362,330 @@ func writePolicy(config *RouterConfig) API {
 	}
 }
 
+func diffPolicy(router *Router, config *RouterConfig) API {
+	const (
+		Method      = http.MethodPost
+		APIPath     = "/v1/policy/diff/"
+		MaxBody     = int64(1 * mem.MiB)
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	type Request struct {
+		Allow []string `json:"allow,omitempty"`
+		Deny  []string `json:"deny,omitempty"`
+	}
+	type Response struct {
+		Exists     bool           `json:"exists"`
+		Identities []kes.Identity `json:"identities,omitempty"`
+		Granted    []string       `json:"granted,omitempty"`
+		Revoked    []string       `json:"revoked,omitempty"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := pathFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+
+		var req Request
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		apis := router.API()
+		apiPaths := make([]string, 0, len(apis))
+		for _, api := range apis {
+			apiPaths = append(apiPaths, api.Path)
+		}
+
+		resp, err := VSync(config.Vault.RLocker(), func() (Response, error) {
+			enclave, err := enclaveFromRequest(config.Vault, r)
+			if err != nil {
+				return Response{}, err
+			}
+			return VSync(enclave.RLocker(), func() (Response, error) {
+				if err = enclave.VerifyRequest(r); err != nil {
+					return Response{}, err
+				}
+
+				policy, err := enclave.GetPolicy(r.Context(), name)
+				if errors.Is(err, kes.ErrPolicyNotFound) {
+					return Response{Exists: false}, nil
+				}
+				if err != nil {
+					return Response{}, err
+				}
+
+				iterator, err := enclave.ListIdentities(r.Context())
+				if err != nil {
+					return Response{}, err
+				}
+				defer iterator.Close()
+
+				var identities []kes.Identity
+				for iterator.Next() {
+					info, err := enclave.GetIdentity(r.Context(), iterator.Identity())
+					if err != nil {
+						return Response{}, err
+					}
+					if !info.IsAdmin && info.Policy == name {
+						identities = append(identities, iterator.Identity())
+					}
+				}
+				if err = iterator.Close(); err != nil {
+					return Response{}, err
+				}
+
+				diff := auth.Diff(&policy, &auth.Policy{Allow: req.Allow, Deny: req.Deny}, apiPaths)
+				return Response{
+					Exists:     true,
+					Identities: identities,
+					Granted:    diff.Granted,
+					Revoked:    diff.Revoked,
+				}, nil
+			})
+		})
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(resp)
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func duplicatePolicy(config *RouterConfig) API {
+	const (
+		Method      = http.MethodGet
+		APIPath     = "/v1/policy/duplicates"
+		MaxBody     = 0
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	type Duplicate struct {
+		Policies   []string `json:"policies"`
+		Allow      []string `json:"allow,omitempty"`
+		Deny       []string `json:"deny,omitempty"`
+		Identities int      `json:"identities"`
+	}
+	type Response struct {
+		Duplicates []Duplicate `json:"duplicates"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		resp, err := VSync(config.Vault.RLocker(), func() (Response, error) {
+			enclave, err := enclaveFromRequest(config.Vault, r)
+			if err != nil {
+				return Response{}, err
+			}
+			return VSync(enclave.RLocker(), func() (Response, error) {
+				if err = enclave.VerifyRequest(r); err != nil {
+					return Response{}, err
+				}
+
+				policyIter, err := enclave.ListPolicies(r.Context())
+				if err != nil {
+					return Response{}, err
+				}
+				defer policyIter.Close()
+
+				policies := map[string]auth.Policy{}
+				for policyIter.Next() {
+					policy, err := enclave.GetPolicy(r.Context(), policyIter.Name())
+					if err != nil {
+						return Response{}, err
+					}
+					policies[policyIter.Name()] = policy
+				}
+				if err = policyIter.Close(); err != nil {
+					return Response{}, err
+				}
+
+				identityIter, err := enclave.ListIdentities(r.Context())
+				if err != nil {
+					return Response{}, err
+				}
+				defer identityIter.Close()
+
+				assigned := map[string]int{}
+				for identityIter.Next() {
+					info, err := enclave.GetIdentity(r.Context(), identityIter.Identity())
+					if err != nil {
+						return Response{}, err
+					}
+					if !info.IsAdmin {
+						assigned[info.Policy]++
+					}
+				}
+				if err = identityIter.Close(); err != nil {
+					return Response{}, err
+				}
+
+				resp := Response{Duplicates: []Duplicate{}}
+				for _, names := range auth.Duplicates(policies) {
+					duplicate := Duplicate{
+						Policies: names,
+						Allow:    policies[names[0]].Allow,
+						Deny:     policies[names[0]].Deny,
+					}
+					for _, name := range names {
+						duplicate.Identities += assigned[name]
+					}
+					resp.Duplicates = append(resp.Duplicates, duplicate)
+				}
+				return resp, nil
+			})
+		})
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(resp)
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func mergePolicy(config *RouterConfig) API {
+	const (
+		Method      = http.MethodPost
+		APIPath     = "/v1/policy/merge/"
+		MaxBody     = int64(64 * mem.KiB)
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	type Request struct {
+		Policies []string `json:"policies"`
+	}
+	type Response struct {
+		Identities []kes.Identity `json:"identities,omitempty"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := pathFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+
+		var req Request
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		if len(req.Policies) == 0 {
+			return kes.NewError(http.StatusBadRequest, "no policies to merge specified")
+		}
+		sources := make(map[string]bool, len(req.Policies))
+		for _, source := range req.Policies {
+			if err = verifyPath(source); err != nil {
+				return err
+			}
+			if source == name {
+				return kes.NewError(http.StatusBadRequest, "cannot merge policy '"+name+"' into itself")
+			}
+			sources[source] = true
+		}
+
+		resp, err := VSync(config.Vault.RLocker(), func() (Response, error) {
+			enclave, err := enclaveFromRequest(config.Vault, r)
+			if err != nil {
+				return Response{}, err
+			}
+			return VSync(enclave.Locker(), func() (Response, error) {
+				if err = enclave.VerifyRequest(r); err != nil {
+					return Response{}, err
+				}
+
+				// Only policies with the same rules can be merged.
+				// Otherwise, reassigning identities would change
+				// what they are allowed to do.
+				target, err := enclave.GetPolicy(r.Context(), name)
+				if err != nil {
+					return Response{}, err
+				}
+				for source := range sources {
+					policy, err := enclave.GetPolicy(r.Context(), source)
+					if err != nil {
+						return Response{}, err
+					}
+					if !policy.Equal(&target) {
+						return Response{}, kes.NewError(http.StatusConflict, "policy '"+source+"' is not identical to policy '"+name+"'")
+					}
+				}
+
+				iterator, err := enclave.ListIdentities(r.Context())
+				if err != nil {
+					return Response{}, err
+				}
+				defer iterator.Close()
+
+				var (
+					identities []kes.Identity
+					infos      []auth.IdentityInfo
+				)
+				for iterator.Next() {
+					info, err := enclave.GetIdentity(r.Context(), iterator.Identity())
+					if err != nil {
+						return Response{}, err
+					}
+					if !info.IsAdmin && sources[info.Policy] {
+						identities = append(identities, iterator.Identity())
+						infos = append(infos, info)
+					}
+				}
+				if err = iterator.Close(); err != nil {
+					return Response{}, err
+				}
+
+				for i, identity := range identities {
+					if err = enclave.AssignPolicy(r.Context(), name, identity, infos[i].ExpiresAt, infos[i].Labels); err != nil {
+						return Response{}, err
+					}
+				}
+				for source := range sources {
+					if err = enclave.DeletePolicy(r.Context(), source); err != nil {
+						return Response{}, err
+					}
+				}
+				return Response{Identities: identities}, nil
+			})
+		})
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(resp)
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
 func deletePolicy(config *RouterConfig) API {
 	const (
 		Method  = http.MethodDelete
@@ -361,7 +695,7 @@ func deletePolicy(config *RouterConfig) API {
 		Verify  = true
 	)
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
-		name, err := nameFromRequest(r, APIPath)
+		name, err := pathFromRequest(r, APIPath)
 		if err != nil {
 			return err
 		}
@@ -434,7 +768,7 @@ func listPolicy(config *RouterConfig) API {
 				var hasWritten bool
 				encoder := json.NewEncoder(w)
 				for iterator.Next() {
-					if ok, _ := path.Match(pattern, iterator.Name()); !ok {
+					if !matchName(pattern, iterator.Name()) {
 						continue
 					}
 					if !hasWritten {
@@ -521,7 +855,7 @@ func edgeListPolicy(config *EdgeRouterConfig) API {
 		encoder := json.NewEncoder(w)
 		w.Header().Set("Content-Type", ContentType)
 		for iterator.Next() {
-			if ok, _ := path.Match(pattern, iterator.Name()); !ok {
+			if !matchName(pattern, iterator.Name()) {
 				continue
 			}
 			if !hasWritten {
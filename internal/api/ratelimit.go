@@ -0,0 +1,350 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"container/list"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/sys"
+)
+
+// Rate limit response headers. They are sent with every
+// API response when a RateLimit is configured such that
+// clients can throttle themselves before requests get
+// rejected.
+const (
+	// RateLimitHeader is the max. number of requests an
+	// identity can send in a burst.
+	RateLimitHeader = "X-RateLimit-Limit"
+
+	// RateLimitRemainingHeader is the number of requests
+	// the identity can send right now.
+	RateLimitRemainingHeader = "X-RateLimit-Remaining"
+
+	// RateLimitResetHeader is the point in time, as Unix
+	// time in seconds, when the identity can send a full
+	// burst of requests again.
+	RateLimitResetHeader = "X-RateLimit-Reset"
+)
+
+// Responses that leave no requests remaining, and rejected
+// requests, also carry a Retry-After header. It contains
+// the number of seconds until the identity can send its
+// next request. Clients should wait for Retry-After, not
+// until the reset time, since the bucket gets refilled
+// continuously.
+
+// errTooManyRequests is returned when an identity has
+// exceeded its rate limit.
+var errTooManyRequests = kes.NewError(http.StatusTooManyRequests, "too many requests")
+
+// A RateLimit limits how many requests an identity can
+// send. Each identity has its own token bucket that holds
+// at most Burst tokens and gets refilled with Rate tokens
+// per second. Each request consumes one token.
+//
+// Requests of identities that are not known to the server,
+// e.g. of random API keys or self-signed certificates, are
+// limited per client IP instead. Hence, clients cannot get
+// a fresh token bucket by presenting new credentials.
+//
+// Individual identities, or all identities assigned to a
+// policy, may have their own rate limit. In addition, the
+// requests to an enclave may be limited. All identities
+// share the token bucket of an enclave.
+//
+// A nil RateLimit does not limit any requests.
+type RateLimit struct {
+	// Rate is the number of requests per second an
+	// identity can send on average.
+	Rate float64
+
+	// Burst is the max. number of requests an identity
+	// can send at once.
+	Burst int
+
+	// Identities contains the rate limits of individual
+	// identities. They take precedence over Policies,
+	// Rate and Burst.
+	Identities map[kes.Identity]RateLimitRule
+
+	// Policies contains the rate limits of identities,
+	// by the name of their policy. Each identity has its
+	// own token bucket. They take precedence over Rate
+	// and Burst.
+	Policies map[string]RateLimitRule
+
+	// Enclaves contains the rate limits of requests to
+	// enclaves, by enclave name.
+	Enclaves map[string]RateLimitRule
+
+	// Policy returns the name of the policy assigned to
+	// the identity of the request, if any. If nil, Policies
+	// are ignored.
+	Policy func(*http.Request) string
+
+	// Known reports whether the identity of the request is
+	// known to the server, i.e. whether it is the admin or
+	// assigned to a policy. Identities with their own rate
+	// limit and federated identities are always known.
+	//
+	// Requests of unknown identities are limited per client
+	// IP. If nil, all identities are considered known.
+	Known func(*http.Request) bool
+
+	lock      sync.Mutex
+	buckets   map[kes.Identity]*tokenBucket
+	lru       list.List // Identities of buckets, most recently used first
+	enclaves  map[string]*tokenBucket
+	lastSweep time.Time
+
+	rejectedIdentity uint64 // Accessed atomically
+	rejectedEnclave  uint64 // Accessed atomically
+}
+
+// A RateLimitRule is the rate limit of an identity or enclave.
+type RateLimitRule struct {
+	// Rate is the number of requests per second that
+	// can be sent on average.
+	Rate float64
+
+	// Burst is the max. number of requests that can
+	// be sent at once.
+	Burst int
+}
+
+// Rejected returns the number of requests rejected since
+// an identity and an enclave, respectively, has exceeded
+// its rate limit.
+func (l *RateLimit) Rejected() (identity, enclave uint64) {
+	return atomic.LoadUint64(&l.rejectedIdentity), atomic.LoadUint64(&l.rejectedEnclave)
+}
+
+// maxBuckets is the max. number of identity token buckets.
+// Once exceeded, the least recently used bucket is evicted.
+const maxBuckets = 1 << 16
+
+// tokenBucket is the token bucket of one identity or enclave.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	rule   RateLimitRule
+	elem   *list.Element // Element of the identity in the LRU list
+}
+
+// refill refills the bucket according to rule.
+func (b *tokenBucket) refill(rule RateLimitRule, now time.Time) {
+	b.tokens = math.Min(float64(rule.Burst), b.tokens+now.Sub(b.last).Seconds()*rule.Rate)
+	b.last, b.rule = now, rule
+}
+
+// full reports whether the bucket would be full at now.
+func (b *tokenBucket) full(now time.Time) bool {
+	return b.tokens+now.Sub(b.last).Seconds()*b.rule.Rate >= float64(b.rule.Burst)
+}
+
+// retryAfter returns how long it takes until the next token
+// is available.
+func (b *tokenBucket) retryAfter() time.Duration {
+	return time.Duration((1 - b.tokens) / b.rule.Rate * float64(time.Second))
+}
+
+// reset returns when the bucket is full again.
+func (b *tokenBucket) reset() time.Time {
+	return b.last.Add(time.Duration((float64(b.rule.Burst) - b.tokens) / b.rule.Rate * float64(time.Second)))
+}
+
+// enabled reports whether l limits any requests.
+func (l *RateLimit) enabled() bool {
+	return l != nil && ((l.Rate > 0 && l.Burst > 0) || len(l.Identities) > 0 || len(l.Policies) > 0 || len(l.Enclaves) > 0)
+}
+
+// rule returns the rate limit of the identity assigned to
+// the given policy and whether the identity is limited.
+func (l *RateLimit) rule(identity kes.Identity, policy string) (RateLimitRule, bool) {
+	if rule, ok := l.Identities[identity]; ok {
+		return rule, true
+	}
+	if rule, ok := l.Policies[policy]; ok && policy != "" {
+		return rule, true
+	}
+	return RateLimitRule{Rate: l.Rate, Burst: l.Burst}, l.Rate > 0 && l.Burst > 0
+}
+
+// take takes one token from the bucket of the identity,
+// assigned to the given policy, and one from the bucket
+// of the enclave, if limited. It returns the burst and
+// number of remaining tokens of the identity's bucket, or
+// the enclave's if the identity is not limited, when the
+// bucket is full again and whether the tokens have been
+// taken. If no token is available, or the last one has
+// been taken, it returns how long the identity has to wait
+// for the next one.
+func (l *RateLimit) take(identity kes.Identity, policy, enclave string, now time.Time) (burst, remaining int, reset time.Time, retryAfter time.Duration, ok bool) {
+	identityRule, limitIdentity := l.rule(identity, policy)
+	enclaveRule, limitEnclave := l.Enclaves[enclave]
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.buckets == nil {
+		l.buckets = map[kes.Identity]*tokenBucket{}
+	}
+	if l.enclaves == nil {
+		l.enclaves = map[string]*tokenBucket{}
+	}
+	if now.Sub(l.lastSweep) > time.Minute {
+		l.sweep(now)
+	}
+
+	var identityBucket, enclaveBucket *tokenBucket
+	if limitIdentity {
+		identityBucket = l.buckets[identity]
+		if identityBucket == nil {
+			if len(l.buckets) >= maxBuckets {
+				l.evict()
+			}
+			identityBucket = &tokenBucket{tokens: float64(identityRule.Burst), last: now}
+			identityBucket.elem = l.lru.PushFront(identity)
+			l.buckets[identity] = identityBucket
+		} else {
+			l.lru.MoveToFront(identityBucket.elem)
+		}
+		identityBucket.refill(identityRule, now)
+	}
+	if limitEnclave {
+		enclaveBucket = l.enclaves[enclave]
+		if enclaveBucket == nil {
+			enclaveBucket = &tokenBucket{tokens: float64(enclaveRule.Burst), last: now}
+			l.enclaves[enclave] = enclaveBucket
+		}
+		enclaveBucket.refill(enclaveRule, now)
+	}
+
+	ok = true
+	if identityBucket != nil && identityBucket.tokens < 1 {
+		ok, retryAfter = false, identityBucket.retryAfter()
+		atomic.AddUint64(&l.rejectedIdentity, 1)
+	}
+	if enclaveBucket != nil && enclaveBucket.tokens < 1 {
+		if d := enclaveBucket.retryAfter(); ok || d > retryAfter {
+			retryAfter = d
+		}
+		ok = false
+		atomic.AddUint64(&l.rejectedEnclave, 1)
+	}
+	if ok {
+		if identityBucket != nil {
+			identityBucket.tokens--
+		}
+		if enclaveBucket != nil {
+			enclaveBucket.tokens--
+		}
+	}
+
+	b := identityBucket
+	if b == nil {
+		b = enclaveBucket
+	}
+	if b == nil {
+		return 0, 0, now, 0, true
+	}
+	if ok && b.tokens < 1 {
+		retryAfter = b.retryAfter()
+	}
+	return b.rule.Burst, int(b.tokens), b.reset(), retryAfter, ok
+}
+
+// sweep removes all buckets that are full since the
+// identities have not sent any requests recently.
+func (l *RateLimit) sweep(now time.Time) {
+	for identity, b := range l.buckets {
+		if b.full(now) {
+			l.lru.Remove(b.elem)
+			delete(l.buckets, identity)
+		}
+	}
+	for enclave, b := range l.enclaves {
+		if b.full(now) {
+			delete(l.enclaves, enclave)
+		}
+	}
+	l.lastSweep = now
+}
+
+// evict removes the least recently used identity bucket.
+func (l *RateLimit) evict() {
+	if elem := l.lru.Back(); elem != nil {
+		delete(l.buckets, l.lru.Remove(elem).(kes.Identity))
+	}
+}
+
+// identity returns the identity whose token bucket the
+// request consumes. Requests of unknown identities are
+// limited per client IP.
+func (l *RateLimit) identity(r *http.Request) kes.Identity {
+	identity := auth.Identify(r)
+	if !identity.IsUnknown() {
+		if _, ok := l.Identities[identity]; ok || l.Known == nil || auth.IsFederated(r) || l.Known(r) {
+			return identity
+		}
+	}
+
+	var ip string
+	if addr := auth.ForwardedIPFromContext(r.Context()); addr != nil {
+		ip = addr.String()
+	} else if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		ip = host
+	} else {
+		ip = r.RemoteAddr
+	}
+	return kes.Identity("ip:" + ip)
+}
+
+// limit returns a handler that rejects requests of
+// identities that have exceeded the rate limit and
+// sets the rate limit response headers.
+func limit(l *RateLimit, f http.Handler) http.Handler {
+	if !l.enabled() {
+		return f
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity := l.identity(r)
+
+		var policy string
+		if l.Policy != nil && len(l.Policies) > 0 {
+			policy = l.Policy(r)
+		}
+		enclave := r.URL.Query().Get("enclave")
+		if enclave == "" {
+			enclave = sys.DefaultEnclaveName
+		}
+		burst, remaining, reset, retryAfter, ok := l.take(identity, policy, enclave, time.Now())
+
+		if burst > 0 {
+			h := w.Header()
+			h.Set(RateLimitHeader, strconv.Itoa(burst))
+			h.Set(RateLimitRemainingHeader, strconv.Itoa(remaining))
+			h.Set(RateLimitResetHeader, strconv.FormatInt(reset.Add(time.Second-1).Unix(), 10)) // Round up to full seconds
+		}
+		if retryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		}
+		if !ok {
+			Fail(w, errTooManyRequests)
+			return
+		}
+		f.ServeHTTP(w, r)
+	})
+}
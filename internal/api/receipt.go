@@ -0,0 +1,97 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/key"
+)
+
+// receipt is a signed decrypt receipt. It proves that
+// the server has decrypted a ciphertext with a particular
+// key version on behalf of an identity.
+//
+// The signature is computed over the payload, which is
+// the JSON-encoded receiptPayload. Ed25519 keys sign the
+// payload directly. ECDSA and RSA (PKCS #1 v1.5) keys
+// sign the SHA-256 hash of the payload.
+type receipt struct {
+	Payload   []byte `json:"payload"`
+	Signature []byte `json:"signature"`
+
+	// Signer is the hex-encoded SHA-256 hash of the
+	// signing key's X.509 SubjectPublicKeyInfo.
+	Signer string `json:"signer"`
+}
+
+type receiptPayload struct {
+	Key            string       `json:"key"`
+	KeyID          string       `json:"key_id"`
+	Enclave        string       `json:"enclave,omitempty"`
+	Identity       kes.Identity `json:"identity"`
+	Time           time.Time    `json:"time"`
+	CiphertextHash []byte       `json:"ciphertext_hash"` // SHA-256 hash of the ciphertext
+}
+
+// signReceipt returns a new receipt for the payload
+// signed by the given signer.
+func signReceipt(signer crypto.Signer, payload *receiptPayload) (*receipt, error) {
+	p, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	spki, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	var signature []byte
+	if _, ok := signer.Public().(ed25519.PublicKey); ok {
+		signature, err = signer.Sign(rand.Reader, p, crypto.Hash(0))
+	} else {
+		h := sha256.Sum256(p)
+		signature, err = signer.Sign(rand.Reader, h[:], crypto.SHA256)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	h := sha256.Sum256(spki)
+	return &receipt{
+		Payload:   p,
+		Signature: signature,
+		Signer:    hex.EncodeToString(h[:]),
+	}, nil
+}
+
+// decryptReceipt returns a receipt, signed by signer, for
+// decrypting the ciphertext with the named key on behalf
+// of the request's identity. It returns nil if signer is
+// nil.
+func decryptReceipt(signer crypto.Signer, r *http.Request, name string, key *key.Key, ciphertext []byte) (*receipt, error) {
+	if signer == nil {
+		return nil, nil
+	}
+	ciphertextHash := sha256.Sum256(ciphertext)
+	return signReceipt(signer, &receiptPayload{
+		Key:            name,
+		KeyID:          key.ID(),
+		Enclave:        r.URL.Query().Get("enclave"),
+		Identity:       auth.Identify(r),
+		Time:           time.Now().UTC(),
+		CiphertextHash: ciphertextHash[:],
+	})
+}
@@ -0,0 +1,232 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/key"
+	"github.com/minio/kes/internal/keystore"
+	"github.com/minio/kes/internal/keystore/mem"
+	"github.com/minio/kes/internal/log"
+	"github.com/minio/kes/internal/metric"
+	"github.com/minio/kes/internal/sys"
+)
+
+func TestSignReceipt(t *testing.T) {
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate Ed25519 key: %v", err)
+	}
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate ECDSA key: %v", err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+
+	hash := sha256.Sum256([]byte("ciphertext"))
+	payload := &receiptPayload{
+		Key:            "my-key",
+		KeyID:          "8a4a6a5f1c3f2c8b",
+		Enclave:        "tenant-1",
+		Identity:       "3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22",
+		Time:           time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		CiphertextHash: hash[:],
+	}
+	for i, signer := range []crypto.Signer{ed25519Key, ecdsaKey, rsaKey} {
+		receipt, err := signReceipt(signer, payload)
+		if err != nil {
+			t.Fatalf("Test %d: failed to sign receipt: %v", i, err)
+		}
+
+		spki, err := x509.MarshalPKIXPublicKey(signer.Public())
+		if err != nil {
+			t.Fatalf("Test %d: failed to marshal public key: %v", i, err)
+		}
+		if h := sha256.Sum256(spki); receipt.Signer != hex.EncodeToString(h[:]) {
+			t.Fatalf("Test %d: signer mismatch: got '%s' - want '%x'", i, receipt.Signer, h)
+		}
+
+		h := sha256.Sum256(receipt.Payload)
+		switch key := signer.Public().(type) {
+		case ed25519.PublicKey:
+			if !ed25519.Verify(key, receipt.Payload, receipt.Signature) {
+				t.Fatalf("Test %d: invalid Ed25519 signature", i)
+			}
+		case *ecdsa.PublicKey:
+			if !ecdsa.VerifyASN1(key, h[:], receipt.Signature) {
+				t.Fatalf("Test %d: invalid ECDSA signature", i)
+			}
+		case *rsa.PublicKey:
+			if err = rsa.VerifyPKCS1v15(key, crypto.SHA256, h[:], receipt.Signature); err != nil {
+				t.Fatalf("Test %d: invalid RSA signature: %v", i, err)
+			}
+		}
+
+		var p receiptPayload
+		if err = json.Unmarshal(receipt.Payload, &p); err != nil {
+			t.Fatalf("Test %d: failed to parse receipt payload: %v", i, err)
+		}
+		if p.Key != payload.Key || p.KeyID != payload.KeyID || p.Enclave != payload.Enclave || p.Identity != payload.Identity || !p.Time.Equal(payload.Time) {
+			t.Fatalf("Test %d: payload mismatch: got '%v' - want '%v'", i, p, *payload)
+		}
+	}
+}
+
+func TestDecryptReceipt(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	publicKey, signer, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate Ed25519 key: %v", err)
+	}
+	certificate, identity := newClientCertificate(t)
+
+	dataKey, err := key.Random(kes.AES256_GCM_SHA256, identity)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	ciphertexts := make([][]byte, 0, 3)
+	for _, plaintext := range []string{"Hello", "World", "!"} {
+		ciphertext, err := dataKey.Wrap([]byte(plaintext), nil)
+		if err != nil {
+			t.Fatalf("Failed to encrypt message: %v", err)
+		}
+		ciphertexts = append(ciphertexts, ciphertext)
+	}
+
+	rootKey, err := key.Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to generate root key: %v", err)
+	}
+	vault := sys.NewVault(sys.NewVaultFS(t.TempDir(), rootKey, sys.NoCompression))
+	if _, err = vault.CreateEnclave(ctx, sys.DefaultEnclaveName, identity); err != nil {
+		t.Fatalf("Failed to create enclave: %v", err)
+	}
+	enclave, err := vault.GetEnclave(ctx, sys.DefaultEnclaveName)
+	if err != nil {
+		t.Fatalf("Failed to get enclave: %v", err)
+	}
+	if err = enclave.CreateKey(ctx, "my-key", dataKey); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	config := &RouterConfig{
+		Vault:         vault,
+		Metrics:       metric.New(),
+		AuditLog:      log.New(io.Discard, "", 0),
+		ReceiptSigner: signer,
+	}
+
+	keys := keystore.NewCache(ctx, &mem.Store{}, &keystore.CacheConfig{})
+	if err = keys.Create(ctx, "my-key", dataKey); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	edgeConfig := &EdgeRouterConfig{
+		Keys:          keys,
+		Identities:    adminIdentitySet{admin: identity},
+		Metrics:       metric.New(),
+		AuditLog:      log.New(io.Discard, "", 0),
+		ReceiptSigner: signer,
+	}
+
+	type Request struct {
+		Ciphertext []byte `json:"ciphertext"`
+	}
+	type Response struct {
+		Plaintext []byte   `json:"plaintext"`
+		Receipt   *receipt `json:"receipt"`
+	}
+	serve := func(a API, body any) *httptest.ResponseRecorder {
+		b, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("Failed to encode request: %v", err)
+		}
+		req := httptest.NewRequest(a.Method, a.Path+"my-key", bytes.NewReader(b))
+		req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{certificate}}
+
+		w := httptest.NewRecorder()
+		a.Handler.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: invalid status code: got '%d' - want '%d': %s", a.Path, w.Code, http.StatusOK, w.Body)
+		}
+		return w
+	}
+	verify := func(api string, r *receipt, ciphertext []byte) {
+		if r == nil {
+			t.Fatalf("%s: response does not contain a receipt", api)
+		}
+		if !ed25519.Verify(publicKey, r.Payload, r.Signature) {
+			t.Fatalf("%s: invalid receipt signature", api)
+		}
+		var p receiptPayload
+		if err := json.Unmarshal(r.Payload, &p); err != nil {
+			t.Fatalf("%s: failed to parse receipt payload: %v", api, err)
+		}
+		if h := sha256.Sum256(ciphertext); !bytes.Equal(p.CiphertextHash, h[:]) {
+			t.Fatalf("%s: ciphertext hash mismatch: got '%x' - want '%x'", api, p.CiphertextHash, h)
+		}
+		if p.Key != "my-key" || p.KeyID != dataKey.ID() || p.Identity != identity {
+			t.Fatalf("%s: payload mismatch: got '%s', '%s' and '%s' - want '%s', '%s' and '%s'", api, p.Key, p.KeyID, p.Identity, "my-key", dataKey.ID(), identity)
+		}
+	}
+
+	usage := newKeyUsage()
+	for _, a := range []API{decryptKey(config, usage), edgeDecryptKey(edgeConfig, usage)} {
+		var response Response
+		if err = json.NewDecoder(serve(a, Request{Ciphertext: ciphertexts[0]}).Body).Decode(&response); err != nil {
+			t.Fatalf("%s: failed to decode response: %v", a.Path, err)
+		}
+		verify(a.Path, response.Receipt, ciphertexts[0])
+	}
+
+	requests := make([]Request, 0, len(ciphertexts))
+	for _, ciphertext := range ciphertexts {
+		requests = append(requests, Request{Ciphertext: ciphertext})
+	}
+	for _, a := range []API{bulkDecryptKey(config, usage), edgeBulkDecryptKey(edgeConfig, usage)} {
+		var responses []Response
+		if err = json.NewDecoder(serve(a, requests).Body).Decode(&responses); err != nil {
+			t.Fatalf("%s: failed to decode response: %v", a.Path, err)
+		}
+		if len(responses) != len(ciphertexts) {
+			t.Fatalf("%s: invalid number of responses: got '%d' - want '%d'", a.Path, len(responses), len(ciphertexts))
+		}
+		for i, response := range responses {
+			verify(a.Path, response.Receipt, ciphertexts[i])
+		}
+	}
+}
+
+// adminIdentitySet is an auth.IdentitySet that only
+// contains the admin identity.
+type adminIdentitySet struct {
+	auth.IdentitySet
+	admin kes.Identity
+}
+
+func (s adminIdentitySet) Admin(context.Context) (kes.Identity, error) { return s.admin, nil }
@@ -0,0 +1,58 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/audit"
+	"github.com/minio/kes/internal/auth"
+)
+
+// errReloadNotSupported is returned by the reload API
+// if the server cannot reload its configuration.
+var errReloadNotSupported = kes.NewError(http.StatusNotImplemented, "server does not support reloading its configuration")
+
+func edgeReload(config *EdgeRouterConfig) API {
+	var (
+		Method  = http.MethodPost
+		APIPath = "/v1/admin/reload"
+		MaxBody int64
+		Timeout = 30 * time.Second
+		Verify  = true
+	)
+	if c, ok := config.APIConfig[APIPath]; ok {
+		if c.Timeout > 0 {
+			Timeout = c.Timeout
+		}
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
+			return err
+		}
+		if config.Reload == nil {
+			return errReloadNotSupported
+		}
+
+		if err := config.Reload(r.Context()); err != nil {
+			config.ErrorLog.Printf("failed to reload configuration: %v", err)
+			return kes.NewError(http.StatusInternalServerError, "failed to reload configuration: "+err.Error())
+		}
+		config.ErrorLog.Printf("configuration reloaded by '%s'", auth.Identify(r))
+
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/audit"
+	"github.com/minio/kes/internal/auth"
+)
+
+func accessReport(router *Router, config *RouterConfig) API {
+	const (
+		Method      = http.MethodGet
+		APIPath     = "/v1/report/access"
+		MaxBody     = 0
+		Timeout     = 30 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	type Identity struct {
+		Identity  kes.Identity           `json:"identity"`
+		Policy    string                 `json:"policy,omitempty"`
+		IsAdmin   bool                   `json:"admin,omitempty"`
+		ExpiresAt time.Time              `json:"expires_at,omitempty"`
+		Access    map[string]auth.Access `json:"access"`
+	}
+	type Response struct {
+		APIs       []string   `json:"apis"`
+		Identities []Identity `json:"identities"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		apis := router.API()
+		apiPaths := make([]string, 0, len(apis))
+		for _, api := range apis {
+			apiPaths = append(apiPaths, api.Path)
+		}
+		sort.Strings(apiPaths)
+
+		resp, err := VSync(config.Vault.RLocker(), func() (Response, error) {
+			enclave, err := enclaveFromRequest(config.Vault, r)
+			if err != nil {
+				return Response{}, err
+			}
+			return VSync(enclave.RLocker(), func() (Response, error) {
+				if err = enclave.VerifyRequest(r); err != nil {
+					return Response{}, err
+				}
+
+				// The enclave admin is not listed as identity.
+				admin, err := enclave.Admin(r.Context())
+				if err != nil {
+					return Response{}, err
+				}
+				var (
+					resp     = Response{APIs: apiPaths, Identities: []Identity{}}
+					policies = map[string]*auth.Policy{}
+				)
+				adminIdentity := Identity{Identity: admin, IsAdmin: true, Access: map[string]auth.Access{}}
+				for _, apiPath := range apiPaths {
+					adminIdentity.Access[apiPath] = auth.FullAccess
+				}
+				resp.Identities = append(resp.Identities, adminIdentity)
+
+				iterator, err := enclave.ListIdentities(r.Context())
+				if err != nil {
+					return Response{}, err
+				}
+				defer iterator.Close()
+
+				for iterator.Next() {
+					info, err := enclave.GetIdentity(r.Context(), iterator.Identity())
+					if err != nil {
+						return Response{}, err
+					}
+					identity := Identity{
+						Identity:  iterator.Identity(),
+						Policy:    info.Policy,
+						IsAdmin:   info.IsAdmin,
+						ExpiresAt: info.ExpiresAt,
+						Access:    map[string]auth.Access{},
+					}
+					if info.IsAdmin {
+						for _, apiPath := range apiPaths {
+							identity.Access[apiPath] = auth.FullAccess
+						}
+						resp.Identities = append(resp.Identities, identity)
+						continue
+					}
+
+					policy, ok := policies[info.Policy]
+					if !ok {
+						p, err := enclave.GetPolicy(r.Context(), info.Policy)
+						if err != nil && !errors.Is(err, kes.ErrPolicyNotFound) {
+							return Response{}, err
+						}
+						policy = &p // A policy that does not exist grants no access
+						policies[info.Policy] = policy
+					}
+					for _, apiPath := range apiPaths {
+						if access := policy.Access(apiPath); access != auth.NoAccess {
+							identity.Access[apiPath] = access
+						}
+					}
+					resp.Identities = append(resp.Identities, identity)
+				}
+				if err = iterator.Close(); err != nil {
+					return Response{}, err
+				}
+				sort.Slice(resp.Identities, func(i, j int) bool {
+					return resp.Identities[i].Identity < resp.Identities[j].Identity
+				})
+				return resp, nil
+			})
+		})
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(resp)
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
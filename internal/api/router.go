@@ -5,12 +5,16 @@
 package api
 
 import (
+	"context"
+	"crypto"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/audit"
 	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/cpu"
 	"github.com/minio/kes/internal/keystore"
 	"github.com/minio/kes/internal/log"
 	"github.com/minio/kes/internal/metric"
@@ -26,8 +30,46 @@ type RouterConfig struct {
 
 	Proxy *auth.TLSProxy
 
+	AEADPool *cpu.Pool // Limits concurrent encrypt and generate operations
+
+	UnwrapPool *cpu.Pool // Limits concurrent decrypt operations
+
+	// SNI maps lower-case TLS server names to enclaves.
+	// Requests sent to one of these server names operate
+	// within the corresponding enclave.
+	SNI map[string]string
+
+	// Maintenance controls the server's read-only mode.
+	// If nil, the server starts in read-write mode.
+	Maintenance *Maintenance
+
+	// Drill injects failures for failover drills.
+	// If nil, no drill has been started.
+	Drill *Drill
+
+	// RateLimit limits the request rate of each
+	// identity. If nil, requests are not limited.
+	RateLimit *RateLimit
+
+	// ReceiptSigner signs decrypt receipts. If nil,
+	// decrypt responses do not contain receipts.
+	ReceiptSigner crypto.Signer
+
+	// APIKeys controls whether clients may authenticate
+	// with an API key instead of a client certificate.
+	// If false, requests with an API key are rejected.
+	APIKeys bool
+
+	// Compression is the compression algorithm applied
+	// to entries before they are written to disk.
+	Compression sys.Compression
+
 	AuditLog *log.Logger
 
+	// AuditStats aggregates audit events into daily
+	// rollups. If nil, no audit statistics are kept.
+	AuditStats *audit.Stats
+
 	ErrorLog *log.Logger
 }
 
@@ -36,6 +78,20 @@ type RouterConfig struct {
 type EdgeRouterConfig struct {
 	Keys *keystore.Cache
 
+	// Enclaves contains the keystores of enclaves, by name,
+	// that have their own keystore. Requests for all other
+	// enclaves are served by Keys.
+	Enclaves map[string]*keystore.Cache
+
+	// Compression reports whether keys are compressed
+	// before they are written to the keystore of any
+	// enclave.
+	Compression bool
+
+	// KeyPools are the key pools, by prefix, from which
+	// clients can claim pre-created keys.
+	KeyPools map[string]*keystore.Pool
+
 	Policies auth.PolicySet
 
 	Identities auth.IdentitySet
@@ -44,10 +100,75 @@ type EdgeRouterConfig struct {
 
 	Proxy *auth.TLSProxy
 
+	// OIDC verifies JWTs presented by clients as bearer
+	// tokens. If nil, JWTs are not accepted.
+	OIDC *auth.OIDC
+
+	// APIKeys controls whether clients may authenticate
+	// with an API key instead of a client certificate.
+	// If false, requests with an API key are rejected.
+	APIKeys bool
+
+	// SPIFFE maps client certificates with a SPIFFE ID
+	// to identities. If nil, the identity of a client
+	// certificate is always the hash of its public key.
+	SPIFFE *auth.SPIFFE
+
+	// ClientTLS is the minimum TLS version and client
+	// certificate key strength required from clients.
+	// If nil, any TLS connection is accepted.
+	ClientTLS *auth.ClientTLSPolicy
+
+	// TLSReport records clients that violate the ClientTLS
+	// policy. If nil, offending clients are not recorded.
+	TLSReport *audit.TLSReport
+
+	// PolicyHooks are evaluated, in order, once a request
+	// is allowed by the policy of its identity.
+	PolicyHooks []*auth.PolicyHook
+
 	APIConfig map[string]Config
 
+	AEADPool *cpu.Pool // Limits concurrent encrypt and generate operations
+
+	UnwrapPool *cpu.Pool // Limits concurrent decrypt operations
+
+	// ReceiptSigner signs decrypt receipts. If nil,
+	// decrypt responses do not contain receipts.
+	ReceiptSigner crypto.Signer
+
+	// Maintenance controls the server's read-only mode.
+	// If nil, the server starts in read-write mode.
+	Maintenance *Maintenance
+
+	// Drill injects failures for failover drills.
+	// If nil, no drill has been started.
+	Drill *Drill
+
+	// RateLimit limits the request rate of each
+	// identity. If nil, requests are not limited.
+	RateLimit *RateLimit
+
+	// RequestLimit limits the request body size and
+	// the number of concurrent requests. If nil, only
+	// the body size limits of the APIs apply.
+	RequestLimit *RequestLimit
+
+	// Reload re-reads the server configuration and
+	// applies it without dropping connections. If nil,
+	// the server cannot reload its configuration.
+	//
+	// The context only bounds the reload itself. The
+	// reloaded configuration, e.g. its keystores, must
+	// outlive the context.
+	Reload func(context.Context) error
+
 	AuditLog *log.Logger
 
+	// AuditStats aggregates audit events into daily
+	// rollups. If nil, no audit statistics are kept.
+	AuditStats *audit.Stats
+
 	ErrorLog *log.Logger
 }
 
@@ -55,23 +176,52 @@ type EdgeRouterConfig struct {
 // server with the given configuration.
 func NewRouter(config *RouterConfig) *Router {
 	r := &Router{
-		handler: http.NewServeMux(),
+		handler:     http.NewServeMux(),
+		sni:         config.SNI,
+		maintenance: config.Maintenance,
+		drill:       config.Drill,
 	}
+	if r.maintenance == nil {
+		r.maintenance = &Maintenance{}
+	}
+	if r.drill == nil {
+		r.drill = &Drill{}
+	}
+	ceremonies := &ceremonies{}
+	usage := newKeyUsage()
 
 	r.api = append(r.api, version(config))
-	r.api = append(r.api, status(config))
+	r.api = append(r.api, manifest(config))
+	r.api = append(r.api, status(config, r.maintenance, r.drill))
 	r.api = append(r.api, metrics(config))
 	r.api = append(r.api, listAPI(r, config))
 
 	r.api = append(r.api, createKey(config))
 	r.api = append(r.api, importKey(config))
-	r.api = append(r.api, describeKey(config))
+	r.api = append(r.api, describeKey(config, usage))
+	r.api = append(r.api, expireKey(config))
+	r.api = append(r.api, tagKey(config))
 	r.api = append(r.api, listKey(config))
-	r.api = append(r.api, deleteKey(config))
-	r.api = append(r.api, encryptKey(config))
-	r.api = append(r.api, generateKey(config))
-	r.api = append(r.api, decryptKey(config))
-	r.api = append(r.api, bulkDecryptKey(config))
+	r.api = append(r.api, deleteKey(config, usage))
+	r.api = append(r.api, restoreKey(config))
+	r.api = append(r.api, purgeKey(config))
+	r.api = append(r.api, listDeletedKey(config))
+	r.api = append(r.api, bulkCreateKey(config))
+	r.api = append(r.api, bulkDeleteKey(config, usage))
+	r.api = append(r.api, encryptKey(config, usage))
+	r.api = append(r.api, generateKey(config, usage))
+	r.api = append(r.api, decryptKey(config, usage))
+	r.api = append(r.api, bulkDecryptKey(config, usage))
+	r.api = append(r.api, signKey(config))
+	r.api = append(r.api, verifyKey(config))
+	r.api = append(r.api, publicKey(config))
+	r.api = append(r.api, exportKey(config))
+	r.api = append(r.api, copyKey(config))
+	r.api = append(r.api, moveKey(config, usage))
+	r.api = append(r.api, hmacKey(config))
+	r.api = append(r.api, deriveKey(config))
+	r.api = append(r.api, beginCeremony(config, ceremonies))
+	r.api = append(r.api, contributeCeremony(config, ceremonies))
 
 	r.api = append(r.api, createSecret(config))
 	r.api = append(r.api, describeSecret(config))
@@ -83,23 +233,50 @@ func NewRouter(config *RouterConfig) *Router {
 	r.api = append(r.api, describePolicy(config))
 	r.api = append(r.api, readPolicy(config))
 	r.api = append(r.api, writePolicy(config))
+	r.api = append(r.api, diffPolicy(r, config))
+	r.api = append(r.api, duplicatePolicy(config))
+	r.api = append(r.api, mergePolicy(config))
 	r.api = append(r.api, deletePolicy(config))
 	r.api = append(r.api, listPolicy(config))
 
 	r.api = append(r.api, describeIdentity(config))
 	r.api = append(r.api, selfDescribeIdentity(config))
 	r.api = append(r.api, listIdentity(config))
+	r.api = append(r.api, createIdentity(config))
+	r.api = append(r.api, renewIdentity(config))
 	r.api = append(r.api, deleteIdentity(config))
 
+	r.api = append(r.api, addAdmin(config))
+	r.api = append(r.api, removeAdmin(config))
+	r.api = append(r.api, listAdmin(config))
+
+	r.api = append(r.api, accessReport(r, config))
+
 	r.api = append(r.api, createEnclave(config))
 	r.api = append(r.api, describeEnclave(config))
+	r.api = append(r.api, updateEnclave(config))
+	r.api = append(r.api, renameEnclave(config))
+	r.api = append(r.api, suspendEnclave(config))
+	r.api = append(r.api, resumeEnclave(config))
 	r.api = append(r.api, deleteEnclave(config))
+	r.api = append(r.api, reencryptEnclave(config))
+	r.api = append(r.api, reencryptionStatus(config))
 
 	r.api = append(r.api, errorLog(config))
 	r.api = append(r.api, auditLog(config))
+	r.api = append(r.api, auditStats(config))
+	r.api = append(r.api, auditRetention(config))
+	r.api = append(r.api, purgeAuditStats(config))
+
+	r.api = append(r.api, setReadOnly(config, r.maintenance))
+	r.api = append(r.api, startDrill(config, r.drill))
+	r.api = append(r.api, stopDrill(config, r.drill))
 
 	for _, a := range r.api {
-		r.handler.Handle(a.Path, proxy(config.Proxy, a))
+		r.handler.Handle(a.Path, proxy(config.Proxy, apiKeys(config.APIKeys, limit(config.RateLimit, impersonate(verifyImpersonation(config), a)))))
+		if config.AuditStats != nil {
+			config.AuditStats.Register(a.Path)
+		}
 	}
 	r.handler.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		http.NewResponseController(w).SetWriteDeadline(time.Now().Add(10 * time.Second))
@@ -112,24 +289,54 @@ func NewRouter(config *RouterConfig) *Router {
 // server with the given configuration.
 func NewEdgeRouter(config *EdgeRouterConfig) *Router {
 	r := &Router{
-		handler: http.NewServeMux(),
+		handler:     http.NewServeMux(),
+		probes:      map[string]http.Handler{},
+		maintenance: config.Maintenance,
+		drill:       config.Drill,
+	}
+	if r.maintenance == nil {
+		r.maintenance = &Maintenance{}
+	}
+	if r.drill == nil {
+		r.drill = &Drill{}
 	}
+	ceremonies := &ceremonies{}
+	usage := newKeyUsage()
 
 	r.api = append(r.api, edgeVersion(config))
-	r.api = append(r.api, edgeReady(config))
-	r.api = append(r.api, edgeStatus(config))
+	r.api = append(r.api, edgeManifest(config))
+	r.api = append(r.api, edgeReady(config, r.maintenance))
+	r.api = append(r.api, edgeHealth(config, r.maintenance))
+	r.api = append(r.api, edgeHealthz(config))
+	r.api = append(r.api, edgeReadyz(config, r.maintenance))
+	r.api = append(r.api, edgeStatus(config, r.maintenance, r.drill))
 	r.api = append(r.api, edgeMetrics(config))
 	r.api = append(r.api, edgeListAPI(r, config))
 
 	r.api = append(r.api, edgeCreateKey(config))
 	r.api = append(r.api, edgeImportKey(config))
-	r.api = append(r.api, edgeDescribeKey(config))
-	r.api = append(r.api, edgeDeleteKey(config))
+	r.api = append(r.api, edgeClaimKey(config))
+	r.api = append(r.api, edgeDescribeKey(config, usage))
+	r.api = append(r.api, edgeExpireKey(config))
+	r.api = append(r.api, edgeDeleteKey(config, usage))
+	r.api = append(r.api, edgeRestoreKey(config))
+	r.api = append(r.api, edgePurgeKey(config))
+	r.api = append(r.api, edgeListDeletedKey(config))
+	r.api = append(r.api, edgeBulkCreateKey(config))
+	r.api = append(r.api, edgeBulkDeleteKey(config, usage))
 	r.api = append(r.api, edgeListKey(config))
-	r.api = append(r.api, edgeGenerateKey(config))
-	r.api = append(r.api, edgeEncryptKey(config))
-	r.api = append(r.api, edgeDecryptKey(config))
-	r.api = append(r.api, edgeBulkDecryptKey(config))
+	r.api = append(r.api, edgeGenerateKey(config, usage))
+	r.api = append(r.api, edgeEncryptKey(config, usage))
+	r.api = append(r.api, edgeDecryptKey(config, usage))
+	r.api = append(r.api, edgeBulkDecryptKey(config, usage))
+	r.api = append(r.api, edgeSignKey(config))
+	r.api = append(r.api, edgeVerifyKey(config))
+	r.api = append(r.api, edgePublicKey(config))
+	r.api = append(r.api, edgeExportKey(config))
+	r.api = append(r.api, edgeHMACKey(config))
+	r.api = append(r.api, edgeDeriveKey(config))
+	r.api = append(r.api, edgeBeginCeremony(config, ceremonies))
+	r.api = append(r.api, edgeContributeCeremony(config, ceremonies))
 
 	r.api = append(r.api, edgeDescribePolicy(config))
 	r.api = append(r.api, edgeReadPolicy(config))
@@ -141,9 +348,27 @@ func NewEdgeRouter(config *EdgeRouterConfig) *Router {
 
 	r.api = append(r.api, edgeErrorLog(config))
 	r.api = append(r.api, edgeAuditLog(config))
-
-	for _, a := range r.api {
-		r.handler.Handle(a.Path, proxy(config.Proxy, a))
+	r.api = append(r.api, edgeAuditStats(config))
+	r.api = append(r.api, edgeClientTLSReport(config))
+
+	r.api = append(r.api, edgeSetReadOnly(config, r.maintenance))
+	r.api = append(r.api, edgeStartDrill(config, r.drill))
+	r.api = append(r.api, edgeStopDrill(config, r.drill))
+	r.api = append(r.api, edgeReload(config))
+
+	for i, a := range r.api {
+		a.MaxBody = config.RequestLimit.maxBody(a.MaxBody)
+		r.api[i] = a
+
+		if probes[a.Path] {
+			r.probes[a.Path] = a
+			r.handler.Handle(a.Path, proxy(config.Proxy, apiKeys(config.APIKeys, federate(config.OIDC, federateSPIFFE(config.SPIFFE, checkClientTLS(config.ClientTLS, config.TLSReport, a))))))
+		} else {
+			r.handler.Handle(a.Path, shed(config.RequestLimit, a.Path, proxy(config.Proxy, apiKeys(config.APIKeys, scopeEnclave(len(config.Enclaves) > 0, federate(config.OIDC, federateSPIFFE(config.SPIFFE, checkClientTLS(config.ClientTLS, config.TLSReport, limit(config.RateLimit, impersonate(edgeVerifyImpersonation(config), policyHooks(config.PolicyHooks, a)))))))))))
+		}
+		if config.AuditStats != nil {
+			config.AuditStats.Register(a.Path)
+		}
 	}
 	r.handler.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		http.NewResponseController(w).SetWriteDeadline(time.Now().Add(10 * time.Second))
@@ -152,13 +377,46 @@ func NewEdgeRouter(config *EdgeRouterConfig) *Router {
 	return r
 }
 
+// probes contains the liveness and readiness probes. They
+// are neither rate limited nor shed and bypass impersonation,
+// policy hooks and drills such that an orchestrator does not
+// restart a healthy server that is under load.
+var probes = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+}
+
+type probeContextKey struct{}
+
+// ProbeHandler returns a handler that only serves the liveness
+// and readiness probes of h. It responds to all other requests
+// with 404 Not Found.
+//
+// A Router serves probe requests received via the returned
+// handler without authentication, e.g. for a separate plain
+// HTTP listener, even if it is configured to verify that
+// requests are sent by a TLS proxy.
+func ProbeHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !probes[r.URL.Path] {
+			http.NotFound(w, r)
+			return
+		}
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), probeContextKey{}, true)))
+	})
+}
+
 // Router is an HTTP handler that implements the KES API.
 //
 // It routes incoming HTTP requests and invokes the
 // corresponding API handlers.
 type Router struct {
-	handler *http.ServeMux
-	api     []API
+	handler     *http.ServeMux
+	probes      map[string]http.Handler
+	api         []API
+	sni         map[string]string
+	maintenance *Maintenance
+	drill       *Drill
 }
 
 // ServeHTTP dispatches the request to the API handler whose
@@ -167,9 +425,53 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	if !strings.HasPrefix(req.URL.Path, "/") { // Ensure URL paths start with a '/'
 		req.URL.Path = "/" + req.URL.Path
 	}
+	if probes[req.URL.Path] {
+		if probe, ok := r.probes[req.URL.Path]; ok && req.Context().Value(probeContextKey{}) != nil {
+			probe.ServeHTTP(w, req)
+			return
+		}
+		r.handler.ServeHTTP(w, req)
+		return
+	}
+	if err := enclaveFromSNI(r.sni, req); err != nil {
+		Fail(w, err)
+		return
+	}
+	if r.maintenance.ReadOnly() && isMutation(req) {
+		Fail(w, ErrReadOnly)
+		return
+	}
+	if r.drill.inject(w, req) {
+		return
+	}
 	r.handler.ServeHTTP(w, req)
 }
 
+// enclaveFromSNI sets the enclave of the request to the
+// enclave mapped to the request's TLS server name, if any.
+//
+// It returns an error if the request explicitly specifies
+// another enclave than the one mapped to its server name.
+func enclaveFromSNI(sni map[string]string, req *http.Request) error {
+	if len(sni) == 0 || req.TLS == nil || req.TLS.ServerName == "" {
+		return nil
+	}
+	enclave, ok := sni[strings.ToLower(req.TLS.ServerName)]
+	if !ok {
+		return nil
+	}
+
+	query := req.URL.Query()
+	switch name := query.Get("enclave"); {
+	case name == "":
+		query.Set("enclave", enclave)
+		req.URL.RawQuery = query.Encode()
+	case name != enclave:
+		return kes.NewError(http.StatusForbidden, "enclave '"+name+"' is not accessible via '"+req.TLS.ServerName+"'")
+	}
+	return nil
+}
+
 // API returns a list of APIs provided by the Router.
 func (r *Router) API() []API { return r.api }
 
@@ -0,0 +1,172 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/sys"
+)
+
+type enclaveContextKey struct{}
+
+// scopeEnclave returns a handler that scopes requests to the
+// enclave specified by their 'enclave' query parameter, if
+// enabled is true. Policies and identities returned by
+// EnclavePolicies and EnclaveIdentities belong to the enclave
+// of the request.
+//
+// If enabled is false, scopeEnclave returns f.
+func scopeEnclave(enabled bool, f http.Handler) http.Handler {
+	if !enabled {
+		return f
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("enclave")
+		f.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), enclaveContextKey{}, name)))
+	})
+}
+
+// enclaveFromContext returns the name of the enclave a request
+// has been scoped to, if any. The default enclave has an empty
+// name.
+func enclaveFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(enclaveContextKey{}).(string)
+	if name == sys.DefaultEnclaveName {
+		return ""
+	}
+	return name
+}
+
+// EnclavePolicies returns a PolicySet that serves the policies
+// of the enclave a request has been scoped to from the given
+// enclave policies. Requests for the default enclave are served
+// by root.
+func EnclavePolicies(root auth.PolicySet, enclaves map[string]auth.PolicySet) auth.PolicySet {
+	return &enclavePolicies{
+		root:     root,
+		enclaves: enclaves,
+	}
+}
+
+type enclavePolicies struct {
+	root     auth.PolicySet
+	enclaves map[string]auth.PolicySet
+}
+
+var _ auth.PolicySet = (*enclavePolicies)(nil) // compiler check
+
+func (p *enclavePolicies) policies(ctx context.Context) (auth.PolicySet, error) {
+	name := enclaveFromContext(ctx)
+	if name == "" {
+		return p.root, nil
+	}
+	if policies, ok := p.enclaves[name]; ok {
+		return policies, nil
+	}
+	return nil, kes.ErrEnclaveNotFound
+}
+
+func (p *enclavePolicies) Set(ctx context.Context, name string, policy *auth.Policy) error {
+	policies, err := p.policies(ctx)
+	if err != nil {
+		return err
+	}
+	return policies.Set(ctx, name, policy)
+}
+
+func (p *enclavePolicies) Get(ctx context.Context, name string) (*auth.Policy, error) {
+	policies, err := p.policies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return policies.Get(ctx, name)
+}
+
+func (p *enclavePolicies) Delete(ctx context.Context, name string) error {
+	policies, err := p.policies(ctx)
+	if err != nil {
+		return err
+	}
+	return policies.Delete(ctx, name)
+}
+
+func (p *enclavePolicies) List(ctx context.Context) (auth.PolicyIterator, error) {
+	policies, err := p.policies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return policies.List(ctx)
+}
+
+// EnclaveIdentities returns an IdentitySet that serves the
+// identities of the enclave a request has been scoped to from
+// the given enclave identities. Requests for the default enclave
+// are served by root.
+//
+// The admin of root is the admin of all enclaves.
+func EnclaveIdentities(root auth.IdentitySet, enclaves map[string]auth.IdentitySet) auth.IdentitySet {
+	return &enclaveIdentities{
+		root:     root,
+		enclaves: enclaves,
+	}
+}
+
+type enclaveIdentities struct {
+	root     auth.IdentitySet
+	enclaves map[string]auth.IdentitySet
+}
+
+var _ auth.IdentitySet = (*enclaveIdentities)(nil) // compiler check
+
+func (i *enclaveIdentities) identities(ctx context.Context) (auth.IdentitySet, error) {
+	name := enclaveFromContext(ctx)
+	if name == "" {
+		return i.root, nil
+	}
+	if identities, ok := i.enclaves[name]; ok {
+		return identities, nil
+	}
+	return nil, kes.ErrEnclaveNotFound
+}
+
+func (i *enclaveIdentities) Admin(ctx context.Context) (kes.Identity, error) {
+	return i.root.Admin(ctx)
+}
+
+func (i *enclaveIdentities) Assign(ctx context.Context, policy string, identity kes.Identity) error {
+	identities, err := i.identities(ctx)
+	if err != nil {
+		return err
+	}
+	return identities.Assign(ctx, policy, identity)
+}
+
+func (i *enclaveIdentities) Get(ctx context.Context, identity kes.Identity) (auth.IdentityInfo, error) {
+	identities, err := i.identities(ctx)
+	if err != nil {
+		return auth.IdentityInfo{}, err
+	}
+	return identities.Get(ctx, identity)
+}
+
+func (i *enclaveIdentities) Delete(ctx context.Context, identity kes.Identity) error {
+	identities, err := i.identities(ctx)
+	if err != nil {
+		return err
+	}
+	return identities.Delete(ctx, identity)
+}
+
+func (i *enclaveIdentities) List(ctx context.Context) (auth.IdentityIterator, error) {
+	identities, err := i.identities(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return identities.List(ctx)
+}
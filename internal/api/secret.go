@@ -7,7 +7,6 @@ package api
 import (
 	"encoding/json"
 	"net/http"
-	"path"
 	"time"
 
 	"aead.dev/mem"
@@ -30,7 +29,7 @@ func createSecret(config *RouterConfig) API {
 		Bytes []byte         `json:"bytes"`
 	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
-		name, err := nameFromRequest(r, APIPath)
+		name, err := pathFromRequest(r, APIPath)
 		if err != nil {
 			return err
 		}
@@ -88,7 +87,7 @@ func describeSecret(config *RouterConfig) API {
 		CreatedBy kes.Identity   `json:"created_by"`
 	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
-		name, err := nameFromRequest(r, APIPath)
+		name, err := pathFromRequest(r, APIPath)
 		if err != nil {
 			return err
 		}
@@ -145,7 +144,7 @@ func readSecret(config *RouterConfig) API {
 		CreatedBy kes.Identity   `json:"created_by"`
 	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
-		name, err := nameFromRequest(r, APIPath)
+		name, err := pathFromRequest(r, APIPath)
 		if err != nil {
 			return err
 		}
@@ -196,7 +195,7 @@ func deleteSecret(config *RouterConfig) API {
 		Verify  = true
 	)
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
-		name, err := nameFromRequest(r, APIPath)
+		name, err := pathFromRequest(r, APIPath)
 		if err != nil {
 			return err
 		}
@@ -273,7 +272,7 @@ func listSecret(config *RouterConfig) API {
 				encoder := json.NewEncoder(w)
 				for iterator.Next() {
 					name := iterator.Name()
-					if ok, _ := path.Match(pattern, name); !ok || name == "" {
+					if !matchName(pattern, name) || name == "" {
 						continue
 					}
 					secret, err := enclave.GetSecret(r.Context(), iterator.Name())
@@ -0,0 +1,117 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/cpu"
+)
+
+// errServerBusy is returned when a request has been shed
+// since the server serves too many requests concurrently.
+var errServerBusy = kes.NewError(http.StatusServiceUnavailable, "server busy: too many concurrent requests")
+
+// A RequestLimit limits the size of request bodies and the
+// number of requests served concurrently. Requests that
+// cannot be served immediately wait for at most QueueTimeout
+// and are shed, i.e. rejected with HTTP 503, afterwards or
+// once the queue of the Pool is full.
+//
+// A nil RequestLimit does not limit any requests.
+type RequestLimit struct {
+	// MaxBody is the max. body size of any request. APIs
+	// that accept only smaller bodies keep their limit.
+	// If 0, the body size of each API is not changed.
+	MaxBody int64
+
+	// Pool limits the number of requests served
+	// concurrently. If nil, the number of concurrent
+	// requests is not limited.
+	Pool *cpu.Pool
+
+	// QueueTimeout is the max. time a request waits
+	// until it can be served. If 0, requests wait until
+	// the client gives up.
+	QueueTimeout time.Duration
+
+	shed uint64 // Accessed atomically
+}
+
+// Shed returns the number of requests that have been
+// rejected since the server has been busy.
+func (l *RequestLimit) Shed() uint64 {
+	if l == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&l.shed)
+}
+
+// maxBody returns the max. body size of an API that accepts
+// at most maxBody bytes.
+func (l *RequestLimit) maxBody(maxBody int64) int64 {
+	if l == nil || l.MaxBody <= 0 || maxBody <= l.MaxBody {
+		return maxBody
+	}
+	return l.MaxBody
+}
+
+// unshedded contains the APIs that are served even if the
+// server is busy. Health checks and monitoring should keep
+// working under load and log streams would occupy a slot
+// for as long as the client is connected.
+var unshedded = map[string]bool{
+	"/version":      true,
+	"/v1/version":   true,
+	"/healthz":      true,
+	"/readyz":       true,
+	"/v1/ready":     true,
+	"/v1/health":    true,
+	"/v1/status":    true,
+	"/v1/metrics":   true,
+	"/v1/log/error": true,
+	"/v1/log/audit": true,
+}
+
+// shed returns a handler that limits the number of requests
+// served concurrently and rejects requests that cannot be
+// served in time with HTTP 503 and a Retry-After header.
+func shed(l *RequestLimit, path string, f http.Handler) http.Handler {
+	if l == nil || l.Pool == nil || unshedded[path] {
+		return f
+	}
+	retryAfter := "1"
+	if l.QueueTimeout > time.Second {
+		retryAfter = strconv.Itoa(int(math.Ceil(l.QueueTimeout.Seconds())))
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := r.Context(), context.CancelFunc(func() {})
+		if l.QueueTimeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, l.QueueTimeout)
+		}
+		defer cancel()
+
+		err := l.Pool.Do(ctx, func() error {
+			cancel() // The timeout only applies while waiting
+			f.ServeHTTP(w, r)
+			return nil
+		})
+		if err == nil {
+			return
+		}
+		if r.Context().Err() != nil {
+			return // The client has given up
+		}
+		atomic.AddUint64(&l.shed, 1)
+		w.Header().Set("Retry-After", retryAfter)
+		Fail(w, errServerBusy)
+	})
+}
@@ -12,11 +12,11 @@ import (
 
 	"github.com/minio/kes/internal/audit"
 	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/keystore"
 	"github.com/minio/kes/internal/sys"
-	"github.com/minio/kes/kv"
 )
 
-func status(config *RouterConfig) API {
+func status(config *RouterConfig, maintenance *Maintenance, drill *Drill) API {
 	const (
 		Method      = http.MethodGet
 		APIPath     = "/v1/status"
@@ -38,6 +38,9 @@ func status(config *RouterConfig) API {
 		KeyStoreLatency     int64 `json:"keystore_latency"` // In milliseconds
 		KeyStoreUnavailable bool  `json:"keystore_unavailable,omitempty"`
 		KeyStoreUnreachable bool  `json:"keystore_unreachable,omitempty"`
+
+		ReadOnly bool       `json:"read_only,omitempty"`
+		Drill    *drillInfo `json:"drill,omitempty"`
 	}
 	startTime := time.Now().UTC()
 	var handler http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
@@ -57,8 +60,7 @@ func status(config *RouterConfig) API {
 		var memStats runtime.MemStats
 		runtime.ReadMemStats(&memStats)
 
-		w.Header().Set("Content-Type", ContentType)
-		json.NewEncoder(w).Encode(Response{
+		response := Response{
 			Version: sys.BinaryInfo().Version,
 			OS:      runtime.GOOS,
 			Arch:    runtime.GOARCH,
@@ -70,7 +72,18 @@ func status(config *RouterConfig) API {
 			StackAlloc: memStats.StackSys,
 
 			KeyStoreLatency: (1 * time.Millisecond).Milliseconds(), // The keystore is always available - set the min. latency.
-		})
+
+			ReadOnly: maintenance.ReadOnly(),
+			Drill:    newDrillInfo(drill),
+		}
+		if response.Drill != nil && response.Drill.Scenario == DrillBackendOutage {
+			response.KeyStoreLatency = 0
+			response.KeyStoreUnavailable = true
+			response.KeyStoreUnreachable = true
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		json.NewEncoder(w).Encode(response)
 	}
 	return API{
 		Method:  Method,
@@ -138,7 +151,7 @@ func listAPI(router *Router, config *RouterConfig) API {
 	}
 }
 
-func edgeStatus(config *EdgeRouterConfig) API {
+func edgeStatus(config *EdgeRouterConfig, maintenance *Maintenance, drill *Drill) API {
 	var (
 		Method      = http.MethodGet
 		APIPath     = "/v1/status"
@@ -153,6 +166,17 @@ func edgeStatus(config *EdgeRouterConfig) API {
 		}
 		Verify = !c.InsecureSkipAuth
 	}
+	type KeyStoreHealth struct {
+		Status        string `json:"status"`
+		Reachable     bool   `json:"reachable"`
+		Available     bool   `json:"available"`
+		Authenticated bool   `json:"authenticated"`
+		ReadOnly      bool   `json:"read_only"`
+		LatencyP50    int64  `json:"latency_p50,omitempty"` // Latency percentiles in milliseconds
+		LatencyP90    int64  `json:"latency_p90,omitempty"`
+		LatencyP99    int64  `json:"latency_p99,omitempty"`
+		Error         string `json:"error,omitempty"`
+	}
 	type Response struct {
 		Version    string        `json:"version"`
 		OS         string        `json:"os"`
@@ -166,6 +190,11 @@ func edgeStatus(config *EdgeRouterConfig) API {
 		KeyStoreLatency     int64 `json:"keystore_latency,omitempty"`
 		KeyStoreUnavailable bool  `json:"keystore_unavailable,omitempty"`
 		KeyStoreUnreachable bool  `json:"keystore_unreachable,omitempty"`
+
+		KeyStore *KeyStoreHealth `json:"keystore,omitempty"`
+
+		ReadOnly bool       `json:"read_only,omitempty"`
+		Drill    *drillInfo `json:"drill,omitempty"`
 	}
 
 	startTime := time.Now().UTC()
@@ -188,18 +217,45 @@ func edgeStatus(config *EdgeRouterConfig) API {
 			UsableCPUs: runtime.GOMAXPROCS(0),
 			HeapAlloc:  memStats.HeapAlloc,
 			StackAlloc: memStats.StackSys,
+
+			ReadOnly: maintenance.ReadOnly(),
+			Drill:    newDrillInfo(drill),
 		}
 
-		state, err := config.Keys.Status(r.Context())
-		if err != nil {
-			response.KeyStoreUnavailable = true
-			_, response.KeyStoreUnreachable = kv.IsUnreachable(err)
-		} else {
-			latency := state.Latency.Round(time.Millisecond)
-			if latency == 0 { // Make sure we actually send a latency even if the key store respond time is < 1ms.
-				latency = 1 * time.Millisecond
+		// Round latencies to milliseconds but make sure we actually
+		// send a latency even if the keystore responds in < 1ms.
+		milliseconds := func(latency time.Duration) int64 {
+			if latency > 0 && latency < time.Millisecond {
+				return 1
+			}
+			return latency.Round(time.Millisecond).Milliseconds()
+		}
+
+		health := config.Keys.Health(r.Context())
+		if response.Drill != nil && response.Drill.Scenario == DrillBackendOutage {
+			health = keystore.Health{Level: keystore.Unhealthy, Err: errDrillOutage}
+		}
+		response.KeyStore = &KeyStoreHealth{
+			Status:        health.Level,
+			Reachable:     health.Reachable,
+			Available:     health.Available,
+			Authenticated: health.Authenticated,
+			ReadOnly:      health.ReadOnly,
+			LatencyP50:    milliseconds(health.LatencyP50),
+			LatencyP90:    milliseconds(health.LatencyP90),
+			LatencyP99:    milliseconds(health.LatencyP99),
+		}
+		if health.Err != nil {
+			response.KeyStore.Error = health.Err.Error()
+		}
+		if health.Available {
+			response.KeyStoreLatency = milliseconds(health.Latency)
+			if response.KeyStoreLatency == 0 {
+				response.KeyStoreLatency = 1
 			}
-			response.KeyStoreLatency = latency.Milliseconds()
+		} else {
+			response.KeyStoreUnavailable = true
+			response.KeyStoreUnreachable = !health.Reachable
 		}
 
 		w.Header().Set("Content-Type", ContentType)
@@ -0,0 +1,110 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/minio/kes/internal/sys"
+)
+
+// Key operations tracked by keyUsage.
+const (
+	opEncrypt = iota
+	opDecrypt
+	opGenerate
+)
+
+// keyUsage keeps track of how often keys have been used
+// for cryptographic operations.
+//
+// Usage counters are kept in memory only and are local
+// to a single server. They are reset when the server
+// restarts. Hence, a key that has not been used since
+// the counters got reset is not necessarily unused.
+type keyUsage struct {
+	since time.Time
+	keys  sync.Map // map[string]*usageCounter
+}
+
+// usageCounter counts the operations performed with
+// a single key.
+type usageCounter struct {
+	encrypt  atomic.Uint64
+	decrypt  atomic.Uint64
+	generate atomic.Uint64
+	lastUsed atomic.Int64 // Unix time in nanoseconds
+}
+
+// usageResponse is the JSON representation of a key's
+// usage counters.
+type usageResponse struct {
+	Encrypt  uint64     `json:"encrypt"`
+	Decrypt  uint64     `json:"decrypt"`
+	Generate uint64     `json:"generate"`
+	LastUsed *time.Time `json:"last_used,omitempty"`
+	Since    time.Time  `json:"since"`
+}
+
+// newKeyUsage returns a new keyUsage that starts
+// counting at the current time.
+func newKeyUsage() *keyUsage {
+	return &keyUsage{since: time.Now().UTC()}
+}
+
+// Add records n operations of type op for the given key.
+func (u *keyUsage) Add(id string, op, n int) {
+	if n <= 0 {
+		return
+	}
+	v, ok := u.keys.Load(id)
+	if !ok {
+		v, _ = u.keys.LoadOrStore(id, new(usageCounter))
+	}
+	counter := v.(*usageCounter)
+	switch op {
+	case opEncrypt:
+		counter.encrypt.Add(uint64(n))
+	case opDecrypt:
+		counter.decrypt.Add(uint64(n))
+	case opGenerate:
+		counter.generate.Add(uint64(n))
+	}
+	counter.lastUsed.Store(time.Now().UnixNano())
+}
+
+// Get returns the usage counters of the given key.
+func (u *keyUsage) Get(id string) usageResponse {
+	resp := usageResponse{Since: u.since}
+	v, ok := u.keys.Load(id)
+	if !ok {
+		return resp
+	}
+	counter := v.(*usageCounter)
+	resp.Encrypt = counter.encrypt.Load()
+	resp.Decrypt = counter.decrypt.Load()
+	resp.Generate = counter.generate.Load()
+	if lastUsed := counter.lastUsed.Load(); lastUsed > 0 {
+		t := time.Unix(0, lastUsed).UTC()
+		resp.LastUsed = &t
+	}
+	return resp
+}
+
+// Delete removes the usage counters of the given key.
+func (u *keyUsage) Delete(id string) { u.keys.Delete(id) }
+
+// usageID returns the ID of the usage counters for the
+// named key within the enclave specified by the request.
+func usageID(r *http.Request, name string) string {
+	enclave := r.URL.Query().Get("enclave")
+	if enclave == "" {
+		enclave = sys.DefaultEnclaveName
+	}
+	return enclave + "/" + name
+}
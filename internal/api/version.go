@@ -7,12 +7,100 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+	"runtime"
 	"time"
 
 	"github.com/minio/kes/internal/audit"
+	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/fips"
 	"github.com/minio/kes/internal/sys"
 )
 
+// apiLevels are the API levels supported by the server.
+// Clients should check whether a level is supported
+// instead of comparing version strings.
+var apiLevels = []string{"v1"}
+
+// manifestResponse is the response of the /v1/version API.
+// It describes the server build and the features supported
+// by the server.
+//
+// Only authenticated clients receive the manifest. Any other
+// client only receives the server version. See: versionResponse.
+type manifestResponse struct {
+	Version   string          `json:"version"`
+	Commit    string          `json:"commit"`
+	BuildDate *time.Time      `json:"build_date,omitempty"`
+	GoVersion string          `json:"go_version"`
+	Features  map[string]bool `json:"features"`
+	APILevels []string        `json:"api_levels"`
+}
+
+// versionResponse is the response of the /v1/version API
+// for clients that are not allowed to fetch the manifest.
+type versionResponse struct {
+	Version string `json:"version"`
+}
+
+// newManifestResponse returns the manifestResponse of the
+// server binary with the given configuration-dependent
+// features. Features that depend on the build, like FIPS,
+// are added to features.
+func newManifestResponse(features map[string]bool) manifestResponse {
+	info := sys.BinaryInfo()
+
+	var buildDate *time.Time
+	if !info.Date.IsZero() {
+		buildDate = &info.Date
+	}
+
+	features["fips"] = fips.Enabled
+	features["minimal"] = sys.Minimal
+	features["asymmetric_keys"] = true
+
+	// Clustering, KMIP and plugins are not implemented
+	// by this server. They are reported explicitly such
+	// that clients can tell them apart from unknown
+	// features.
+	features["clustering"] = false
+	features["kmip"] = false
+	features["plugins"] = false
+	return manifestResponse{
+		Version:   info.Version,
+		Commit:    info.CommitID,
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+		Features:  features,
+		APILevels: apiLevels,
+	}
+}
+
+// serverFeatures returns the features of a stateful
+// server with the given configuration.
+func serverFeatures(config *RouterConfig) map[string]bool {
+	return map[string]bool{
+		"enclaves":          true,
+		"enclave_keystores": false,
+		"oidc":              false,
+		"api_keys":          config.APIKeys,
+		"wasm_hooks":        false,
+		"compression":       config.Compression != sys.NoCompression,
+	}
+}
+
+// edgeFeatures returns the features of an edge server
+// with the given configuration.
+func edgeFeatures(config *EdgeRouterConfig) map[string]bool {
+	return map[string]bool{
+		"enclaves":          false,
+		"enclave_keystores": len(config.Enclaves) > 0,
+		"oidc":              config.OIDC != nil,
+		"api_keys":          config.APIKeys,
+		"wasm_hooks":        len(config.PolicyHooks) > 0,
+		"compression":       config.Compression,
+	}
+}
+
 func version(config *RouterConfig) API {
 	const (
 		Method  = http.MethodGet
@@ -68,3 +156,75 @@ func edgeVersion(config *EdgeRouterConfig) API {
 		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
 	}
 }
+
+func manifest(config *RouterConfig) API {
+	const (
+		Method      = http.MethodGet
+		APIPath     = "/v1/version"
+		MaxBody     = 0
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	var handler http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
+		err := Sync(config.Vault.RLocker(), func() error {
+			enclave, err := enclaveFromRequest(config.Vault, r)
+			if err != nil {
+				return err
+			}
+			return Sync(enclave.RLocker(), func() error {
+				return enclave.VerifyRequest(r)
+			})
+		})
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		if err != nil {
+			json.NewEncoder(w).Encode(versionResponse{Version: sys.BinaryInfo().Version})
+			return
+		}
+		json.NewEncoder(w).Encode(newManifestResponse(serverFeatures(config)))
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func edgeManifest(config *EdgeRouterConfig) API {
+	var (
+		Method      = http.MethodGet
+		APIPath     = "/v1/version"
+		MaxBody     int64
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	if c, ok := config.APIConfig[APIPath]; ok {
+		if c.Timeout > 0 {
+			Timeout = c.Timeout
+		}
+		Verify = !c.InsecureSkipAuth
+	}
+	var handler http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		if err := auth.VerifyRequest(r, config.Policies, config.Identities); Verify && err != nil {
+			json.NewEncoder(w).Encode(versionResponse{Version: sys.BinaryInfo().Version})
+			return
+		}
+		json.NewEncoder(w).Encode(newManifestResponse(edgeFeatures(config)))
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
@@ -27,14 +27,19 @@ func Log(logger *log.Logger, h http.Handler) http.Handler {
 				ip = net.ParseIP(addr)
 			}
 		}
+		impersonator, _ := auth.Impersonator(r)
+		tlsViolation, _ := auth.TLSViolation(r)
 		w = &responseWriter{
 			rw: w,
 
-			log:       logger,
-			url:       *r.URL,
-			ip:        ip,
-			identity:  auth.Identify(r),
-			timestamp: time.Now(),
+			log:          logger,
+			url:          *r.URL,
+			ip:           ip,
+			identity:     auth.Identify(r),
+			impersonator: impersonator,
+			tlsViolation: tlsViolation,
+			annotations:  auth.HookAnnotations(r),
+			timestamp:    time.Now(),
 		}
 		h.ServeHTTP(w, r)
 	})
@@ -43,11 +48,14 @@ func Log(logger *log.Logger, h http.Handler) http.Handler {
 type responseWriter struct {
 	rw http.ResponseWriter
 
-	log       *log.Logger
-	url       url.URL
-	ip        net.IP
-	identity  kes.Identity
-	timestamp time.Time
+	log          *log.Logger
+	url          url.URL
+	ip           net.IP
+	identity     kes.Identity
+	impersonator kes.Identity      // Set if the request impersonates identity
+	tlsViolation string            // Set if the request violates the client TLS policy
+	annotations  map[string]string // Populated by policy hooks while handling the request
+	timestamp    time.Time
 
 	hasSendHeaders atomic.Bool
 }
@@ -66,10 +74,13 @@ func (w *responseWriter) WriteHeader(status int) {
 	w.rw.WriteHeader(status)
 
 	type RequestInfo struct {
-		IP       net.IP       `json:"ip,omitempty"`
-		Enclave  string       `json:"enclave,omitempty"`
-		APIPath  string       `json:"path"`
-		Identity kes.Identity `json:"identity,omitempty"`
+		IP             net.IP            `json:"ip,omitempty"`
+		Enclave        string            `json:"enclave,omitempty"`
+		APIPath        string            `json:"path"`
+		Identity       kes.Identity      `json:"identity,omitempty"`
+		ImpersonatedBy kes.Identity      `json:"impersonated_by,omitempty"`
+		TLSViolation   string            `json:"tls_violation,omitempty"`
+		Annotations    map[string]string `json:"annotations,omitempty"`
 	}
 	type ResponseInfo struct {
 		StatusCode int           `json:"code"`
@@ -84,10 +95,13 @@ func (w *responseWriter) WriteHeader(status int) {
 	json.NewEncoder(w.log.Writer()).Encode(Response{
 		Timestamp: w.timestamp,
 		Request: RequestInfo{
-			IP:       w.ip,
-			Enclave:  w.url.Query().Get("enclave"),
-			APIPath:  w.url.Path,
-			Identity: w.identity,
+			IP:             w.ip,
+			Enclave:        w.url.Query().Get("enclave"),
+			APIPath:        w.url.Path,
+			Identity:       w.identity,
+			ImpersonatedBy: w.impersonator,
+			TLSViolation:   w.tlsViolation,
+			Annotations:    w.annotations,
 		},
 		Response: ResponseInfo{
 			StatusCode: status,
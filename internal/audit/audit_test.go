@@ -0,0 +1,52 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/log"
+)
+
+func TestLogImpersonation(t *testing.T) {
+	admin, err := kes.GenerateAPIKey(nil)
+	if err != nil {
+		t.Fatalf("Failed to generate API key: %v", err)
+	}
+	const Identity kes.Identity = "3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22"
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/identity/self/describe", nil)
+	req.TLS = &tls.ConnectionState{}
+	req.Header.Set("Authorization", "Bearer "+admin.String())
+	req = auth.Impersonate(auth.WithAPIKeys(req), Identity)
+
+	var buf bytes.Buffer
+	Log(log.New(&buf, "", 0), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})).ServeHTTP(httptest.NewRecorder(), req)
+
+	var event struct {
+		Request struct {
+			Identity       kes.Identity `json:"identity"`
+			ImpersonatedBy kes.Identity `json:"impersonated_by"`
+		} `json:"request"`
+	}
+	if err = json.Unmarshal(buf.Bytes(), &event); err != nil {
+		t.Fatalf("Failed to parse audit event: %v", err)
+	}
+	if event.Request.Identity != Identity {
+		t.Fatalf("Invalid identity: got '%s' - want '%s'", event.Request.Identity, Identity)
+	}
+	if event.Request.ImpersonatedBy != admin.Identity() {
+		t.Fatalf("Invalid impersonator: got '%s' - want '%s'", event.Request.ImpersonatedBy, admin.Identity())
+	}
+}
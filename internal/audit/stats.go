@@ -0,0 +1,399 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/kes-go"
+)
+
+// DefaultStatsRetention is the default number of days
+// Stats keeps rollups for.
+const DefaultStatsRetention = 400
+
+// ErrLegalHold is returned when purging rollups of an
+// enclave that is under legal hold.
+var ErrLegalHold = kes.NewError(http.StatusForbidden, "audit events are under legal hold")
+
+// A Rollup summarizes the audit events of one identity
+// calling one API within one enclave on one day.
+type Rollup struct {
+	Day      string       `json:"day"` // Format: 2006-01-02
+	Enclave  string       `json:"enclave,omitempty"`
+	Identity kes.Identity `json:"identity"`
+	APIPath  string       `json:"path"`
+	Requests uint64       `json:"requests"`
+	Errors   uint64       `json:"errors"`
+}
+
+// Stats is an io.Writer that aggregates audit events into
+// daily rollups of requests and errors per identity, API
+// and enclave. Unlike raw audit logs, rollups are small
+// enough to be kept for months such that access reviews
+// do not require replaying audit log archives.
+//
+// Stats should be added to an audit log.Logger. Each write
+// call has to contain exactly one JSON audit event.
+type Stats struct {
+	// Retention is the number of days rollups are kept.
+	// If <= 0, DefaultStatsRetention is used.
+	Retention int
+
+	// EnclaveRetention, if not nil, returns the retention of
+	// the given enclave. It returns the number of days rollups
+	// of the enclave are kept and whether the enclave is under
+	// legal hold. Rollups under legal hold are neither pruned
+	// nor purged. If days <= 0, Retention is used.
+	//
+	// Rollups of enclaves for which EnclaveRetention returns
+	// an error are not pruned.
+	EnclaveRetention func(enclave string) (days int, legalHold bool, err error)
+
+	lock     sync.Mutex
+	apiPaths []string
+	rollups  map[rollupKey]*rollupCounter
+}
+
+type rollupKey struct {
+	Day      string
+	Enclave  string
+	Identity kes.Identity
+	APIPath  string
+}
+
+type rollupCounter struct {
+	Requests uint64
+	Errors   uint64
+}
+
+// Register registers the given API paths. Audit events
+// are attributed to the registered API path that matches
+// the event's request path. For example, an event for
+// '/v1/key/create/my-key' is attributed to the API path
+// '/v1/key/create/'.
+//
+// Once API paths have been registered, events for paths
+// that do not match any of them are attributed to '/'.
+// Otherwise, events are attributed to their request path.
+func (s *Stats) Register(apiPaths ...string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, apiPath := range apiPaths {
+		if !contains(s.apiPaths, apiPath) {
+			s.apiPaths = append(s.apiPaths, apiPath)
+		}
+	}
+	sort.Slice(s.apiPaths, func(i, j int) bool { return len(s.apiPaths[i]) > len(s.apiPaths[j]) })
+}
+
+// Write parses p as JSON audit event and adds it to the
+// corresponding rollup. It never returns an error such
+// that malformed events do not affect other audit log
+// outputs.
+func (s *Stats) Write(p []byte) (int, error) {
+	type Event struct {
+		Timestamp time.Time `json:"time"`
+		Request   struct {
+			Enclave  string       `json:"enclave"`
+			APIPath  string       `json:"path"`
+			Identity kes.Identity `json:"identity"`
+		} `json:"request"`
+		Response struct {
+			StatusCode int `json:"code"`
+		} `json:"response"`
+	}
+	var event Event
+	if err := json.Unmarshal(p, &event); err != nil {
+		return len(p), nil
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.rollups == nil {
+		s.rollups = map[rollupKey]*rollupCounter{}
+	}
+	key := rollupKey{
+		Day:      event.Timestamp.UTC().Format(time.DateOnly),
+		Enclave:  event.Request.Enclave,
+		Identity: event.Request.Identity,
+		APIPath:  s.apiPath(event.Request.APIPath),
+	}
+	counter, ok := s.rollups[key]
+	if !ok {
+		counter = new(rollupCounter)
+		s.rollups[key] = counter
+	}
+	counter.Requests++
+	if event.Response.StatusCode >= 400 {
+		counter.Errors++
+	}
+	return len(p), nil
+}
+
+// Query returns all rollups of the given enclaves since
+// the given point in time, sorted by day, enclave, identity
+// and API path. If no enclaves are specified, Query returns
+// the rollups of all enclaves.
+func (s *Stats) Query(since time.Time, enclaves ...string) []Rollup {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	day := since.UTC().Format(time.DateOnly)
+	rollups := make([]Rollup, 0, len(s.rollups))
+	for key, counter := range s.rollups {
+		if key.Day < day {
+			continue
+		}
+		if len(enclaves) > 0 && !contains(enclaves, key.Enclave) {
+			continue
+		}
+		rollups = append(rollups, Rollup{
+			Day:      key.Day,
+			Enclave:  key.Enclave,
+			Identity: key.Identity,
+			APIPath:  key.APIPath,
+			Requests: counter.Requests,
+			Errors:   counter.Errors,
+		})
+	}
+	sort.Slice(rollups, func(i, j int) bool {
+		a, b := rollups[i], rollups[j]
+		if a.Day != b.Day {
+			return a.Day < b.Day
+		}
+		if a.Enclave != b.Enclave {
+			return a.Enclave < b.Enclave
+		}
+		if a.Identity != b.Identity {
+			return a.Identity < b.Identity
+		}
+		return a.APIPath < b.APIPath
+	})
+	return rollups
+}
+
+// Prune removes all rollups older than the retention
+// period relative to now. Rollups of enclaves under legal
+// hold are not removed.
+func (s *Stats) Prune(now time.Time) {
+	s.lock.Lock()
+	enclaves := map[string]string{}
+	for key := range s.rollups {
+		enclaves[key.Enclave] = ""
+	}
+	s.lock.Unlock()
+
+	// Determine the retention of each enclave without holding
+	// the lock since EnclaveRetention may block.
+	for enclave := range enclaves {
+		retention, legalHold, err := s.retention(enclave)
+		if err != nil || legalHold {
+			delete(enclaves, enclave)
+			continue
+		}
+		enclaves[enclave] = now.UTC().AddDate(0, 0, -retention).Format(time.DateOnly)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for key := range s.rollups {
+		if day, ok := enclaves[key.Enclave]; ok && key.Day < day {
+			delete(s.rollups, key)
+		}
+	}
+}
+
+// Purge removes all rollups of the given enclaves before
+// the given point in time and returns the number of removed
+// rollups. It returns ErrLegalHold if any of the enclaves is
+// under legal hold.
+func (s *Stats) Purge(before time.Time, enclaves ...string) (int, error) {
+	for _, enclave := range enclaves {
+		_, legalHold, err := s.retention(enclave)
+		if err != nil {
+			return 0, err
+		}
+		if legalHold {
+			return 0, ErrLegalHold
+		}
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var (
+		day = before.UTC().Format(time.DateOnly)
+		n   int
+	)
+	for key := range s.rollups {
+		if key.Day < day && contains(enclaves, key.Enclave) {
+			delete(s.rollups, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// RetentionStatus describes how long, and how many, rollups
+// of an enclave are kept.
+type RetentionStatus struct {
+	Retention int    `json:"retention_days"`
+	LegalHold bool   `json:"legal_hold,omitempty"`
+	Oldest    string `json:"oldest,omitempty"` // Format: 2006-01-02
+	Rollups   int    `json:"rollups"`
+}
+
+// Status returns the RetentionStatus of the rollups of the
+// given enclaves. The enclaves are treated as one enclave,
+// e.g. the default enclave with and without explicit name,
+// such that their retention is the one of the first enclave.
+func (s *Stats) Status(enclaves ...string) (RetentionStatus, error) {
+	var status RetentionStatus
+	if len(enclaves) > 0 {
+		retention, legalHold, err := s.retention(enclaves[0])
+		if err != nil {
+			return RetentionStatus{}, err
+		}
+		status.Retention, status.LegalHold = retention, legalHold
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for key := range s.rollups {
+		if !contains(enclaves, key.Enclave) {
+			continue
+		}
+		if status.Oldest == "" || key.Day < status.Oldest {
+			status.Oldest = key.Day
+		}
+		status.Rollups++
+	}
+	return status, nil
+}
+
+// retention returns the number of days rollups of the
+// enclave are kept and whether the enclave is under
+// legal hold.
+func (s *Stats) retention(enclave string) (int, bool, error) {
+	retention := s.Retention
+	if retention <= 0 {
+		retention = DefaultStatsRetention
+	}
+	if s.EnclaveRetention == nil {
+		return retention, false, nil
+	}
+	days, legalHold, err := s.EnclaveRetention(enclave)
+	if err != nil {
+		return 0, false, err
+	}
+	if days > 0 {
+		retention = days
+	}
+	return retention, legalHold, nil
+}
+
+// Load reads rollups, previously written by Save, from
+// the given file and merges them into s. It returns no
+// error if the file does not exist.
+func (s *Stats) Load(filename string) error {
+	file, err := os.Open(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var rollups []Rollup
+	if err = json.NewDecoder(file).Decode(&rollups); err != nil && err != io.EOF {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.rollups == nil {
+		s.rollups = make(map[rollupKey]*rollupCounter, len(rollups))
+	}
+	for _, r := range rollups {
+		key := rollupKey{
+			Day:      r.Day,
+			Enclave:  r.Enclave,
+			Identity: r.Identity,
+			APIPath:  r.APIPath,
+		}
+		counter, ok := s.rollups[key]
+		if !ok {
+			counter = new(rollupCounter)
+			s.rollups[key] = counter
+		}
+		counter.Requests += r.Requests
+		counter.Errors += r.Errors
+	}
+	return nil
+}
+
+// Save writes all rollups to the given file. It replaces
+// the file atomically such that an interrupted Save does
+// not corrupt rollups saved previously.
+func (s *Stats) Save(filename string) error {
+	rollups := s.Query(time.Time{})
+
+	file, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if err = json.NewEncoder(file).Encode(rollups); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), filename)
+}
+
+// apiPath returns the registered API path matching path.
+func (s *Stats) apiPath(path string) string {
+	for _, apiPath := range s.apiPaths {
+		if apiPath == path || (strings.HasSuffix(apiPath, "/") && strings.HasPrefix(path, apiPath)) {
+			return apiPath
+		}
+	}
+	if len(s.apiPaths) > 0 {
+		return "/"
+	}
+	return path
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	stats := &Stats{}
+	stats.Register("/v1/key/create/", "/v1/key/list/", "/v1/status")
+
+	for i, event := range statsEvents {
+		if _, err := stats.Write([]byte(event)); err != nil {
+			t.Fatalf("Event %d: failed to write event: %v", i, err)
+		}
+	}
+
+	rollups := stats.Query(time.Time{})
+	if len(rollups) != len(statsRollups) {
+		t.Fatalf("Invalid number of rollups: got '%d' - want '%d'", len(rollups), len(statsRollups))
+	}
+	for i := range rollups {
+		if rollups[i] != statsRollups[i] {
+			t.Fatalf("Rollup %d: got '%v' - want '%v'", i, rollups[i], statsRollups[i])
+		}
+	}
+
+	if rollups = stats.Query(time.Date(2023, time.June, 2, 0, 0, 0, 0, time.UTC)); len(rollups) != 1 {
+		t.Fatalf("Invalid number of rollups since 2023-06-02: got '%d' - want '%d'", len(rollups), 1)
+	}
+	if rollups = stats.Query(time.Time{}, "tenant-1"); len(rollups) != 1 {
+		t.Fatalf("Invalid number of rollups of enclave 'tenant-1': got '%d' - want '%d'", len(rollups), 1)
+	}
+
+	filename := filepath.Join(t.TempDir(), ".audit-stats")
+	if err := stats.Save(filename); err != nil {
+		t.Fatalf("Failed to save stats: %v", err)
+	}
+	loaded := &Stats{}
+	if err := loaded.Load(filename); err != nil {
+		t.Fatalf("Failed to load stats: %v", err)
+	}
+	if rollups = loaded.Query(time.Time{}); len(rollups) != len(statsRollups) {
+		t.Fatalf("Invalid number of loaded rollups: got '%d' - want '%d'", len(rollups), len(statsRollups))
+	}
+
+	loaded.Prune(time.Date(2023, time.June, 2, 0, 0, 0, 0, time.UTC).AddDate(0, 0, DefaultStatsRetention))
+	if rollups = loaded.Query(time.Time{}); len(rollups) != 1 {
+		t.Fatalf("Invalid number of rollups after pruning: got '%d' - want '%d'", len(rollups), 1)
+	}
+}
+
+func TestStatsRetention(t *testing.T) {
+	stats := &Stats{
+		EnclaveRetention: func(enclave string) (int, bool, error) {
+			switch enclave {
+			case "tenant-1":
+				return 30, false, nil
+			case "tenant-2":
+				return 0, true, nil
+			default:
+				return 0, false, nil
+			}
+		},
+	}
+	for i, event := range []string{
+		`{"time":"2023-06-01T10:00:00Z","request":{"enclave":"tenant-1","path":"/v1/status","identity":"a"},"response":{"code":200}}`,
+		`{"time":"2023-06-01T10:00:00Z","request":{"enclave":"tenant-2","path":"/v1/status","identity":"a"},"response":{"code":200}}`,
+		`{"time":"2023-06-01T10:00:00Z","request":{"enclave":"tenant-3","path":"/v1/status","identity":"a"},"response":{"code":200}}`,
+		`{"time":"2023-06-05T10:00:00Z","request":{"enclave":"tenant-3","path":"/v1/status","identity":"a"},"response":{"code":200}}`,
+	} {
+		if _, err := stats.Write([]byte(event)); err != nil {
+			t.Fatalf("Event %d: failed to write event: %v", i, err)
+		}
+	}
+
+	// Rollups of tenant-1 exceed its retention of 30 days while rollups
+	// of tenant-2 are under legal hold and tenant-3 uses the default.
+	stats.Prune(time.Date(2023, time.July, 15, 0, 0, 0, 0, time.UTC))
+	if rollups := stats.Query(time.Time{}, "tenant-1"); len(rollups) != 0 {
+		t.Fatalf("Invalid number of rollups of enclave 'tenant-1': got '%d' - want '%d'", len(rollups), 0)
+	}
+	if rollups := stats.Query(time.Time{}, "tenant-2", "tenant-3"); len(rollups) != 3 {
+		t.Fatalf("Invalid number of rollups of enclaves 'tenant-2' and 'tenant-3': got '%d' - want '%d'", len(rollups), 3)
+	}
+
+	if _, err := stats.Purge(time.Date(2023, time.July, 15, 0, 0, 0, 0, time.UTC), "tenant-2"); !errors.Is(err, ErrLegalHold) {
+		t.Fatalf("Purged rollups under legal hold: %v", err)
+	}
+	n, err := stats.Purge(time.Date(2023, time.June, 2, 0, 0, 0, 0, time.UTC), "tenant-3")
+	if err != nil {
+		t.Fatalf("Failed to purge rollups: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("Invalid number of purged rollups: got '%d' - want '%d'", n, 1)
+	}
+
+	status, err := stats.Status("tenant-3")
+	if err != nil {
+		t.Fatalf("Failed to get retention status: %v", err)
+	}
+	if want := (RetentionStatus{Retention: DefaultStatsRetention, Oldest: "2023-06-05", Rollups: 1}); status != want {
+		t.Fatalf("Retention status mismatch: got '%v' - want '%v'", status, want)
+	}
+	if status, err = stats.Status("tenant-2"); err != nil || !status.LegalHold {
+		t.Fatalf("Retention status of enclave 'tenant-2' does not report legal hold: %v", err)
+	}
+}
+
+var statsEvents = []string{
+	`{"time":"2023-06-01T10:00:00Z","request":{"path":"/v1/key/create/my-key","identity":"a"},"response":{"code":200}}`,
+	`{"time":"2023-06-01T11:00:00Z","request":{"path":"/v1/key/create/my-key-2","identity":"a"},"response":{"code":409}}`,
+	`{"time":"2023-06-01T12:00:00Z","request":{"path":"/v1/status","identity":"b"},"response":{"code":200}}`,
+	`{"time":"2023-06-01T13:00:00Z","request":{"path":"/v1/unknown","identity":"b"},"response":{"code":501}}`,
+	`{"time":"2023-06-01T14:00:00Z","request":{"enclave":"tenant-1","path":"/v1/key/list/*","identity":"c"},"response":{"code":200}}`,
+	`{"time":"2023-06-02T10:00:00Z","request":{"path":"/v1/key/create/my-key","identity":"a"},"response":{"code":403}}`,
+	`not JSON`,
+}
+
+var statsRollups = []Rollup{
+	{Day: "2023-06-01", Identity: "a", APIPath: "/v1/key/create/", Requests: 2, Errors: 1},
+	{Day: "2023-06-01", Identity: "b", APIPath: "/", Requests: 1, Errors: 1},
+	{Day: "2023-06-01", Identity: "b", APIPath: "/v1/status", Requests: 1},
+	{Day: "2023-06-01", Enclave: "tenant-1", Identity: "c", APIPath: "/v1/key/list/", Requests: 1},
+	{Day: "2023-06-02", Identity: "a", APIPath: "/v1/key/create/", Requests: 1, Errors: 1},
+}
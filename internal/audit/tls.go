@@ -0,0 +1,115 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/minio/kes-go"
+)
+
+// TLSOffender describes a client that has sent requests
+// violating the server's client TLS policy.
+type TLSOffender struct {
+	Identity  kes.Identity `json:"identity"`
+	IP        string       `json:"ip,omitempty"`
+	Reason    string       `json:"reason"`
+	Requests  uint64       `json:"requests"`
+	FirstSeen time.Time    `json:"first_seen"`
+	LastSeen  time.Time    `json:"last_seen"`
+}
+
+// TLSReport keeps track of clients that violate the
+// server's client TLS policy. It helps to identify
+// clients that have to be upgraded before the policy
+// can be enforced.
+//
+// Clients that have not been observed within the
+// retention period are removed from the report.
+type TLSReport struct {
+	// Retention is the period for which offending clients
+	// are kept in the report. If <= 0, defaults to 90 days.
+	Retention time.Duration
+
+	lock      sync.Mutex
+	offenders map[tlsOffenderKey]*TLSOffender
+}
+
+type tlsOffenderKey struct {
+	Identity kes.Identity
+	Reason   string
+}
+
+// Add records a request by the identity, sent from
+// the IP address, that violates the client TLS policy
+// for the given reason.
+func (r *TLSReport) Add(identity kes.Identity, ip, reason string, now time.Time) {
+	const MaxOffenders = 10000
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.offenders == nil {
+		r.offenders = map[tlsOffenderKey]*TLSOffender{}
+	}
+	key := tlsOffenderKey{Identity: identity, Reason: reason}
+	if offender, ok := r.offenders[key]; ok {
+		offender.IP = ip
+		offender.Requests++
+		offender.LastSeen = now
+		return
+	}
+
+	r.prune(now)
+	if len(r.offenders) >= MaxOffenders {
+		return
+	}
+	r.offenders[key] = &TLSOffender{
+		Identity:  identity,
+		IP:        ip,
+		Reason:    reason,
+		Requests:  1,
+		FirstSeen: now,
+		LastSeen:  now,
+	}
+}
+
+// Query returns all offending clients observed since
+// the given point in time, sorted by identity.
+func (r *TLSReport) Query(since time.Time) []TLSOffender {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.prune(time.Now())
+	offenders := make([]TLSOffender, 0, len(r.offenders))
+	for _, offender := range r.offenders {
+		if !offender.LastSeen.Before(since) {
+			offenders = append(offenders, *offender)
+		}
+	}
+	sort.Slice(offenders, func(i, j int) bool {
+		if offenders[i].Identity != offenders[j].Identity {
+			return offenders[i].Identity < offenders[j].Identity
+		}
+		return offenders[i].Reason < offenders[j].Reason
+	})
+	return offenders
+}
+
+// prune removes all offenders not observed within
+// the retention period.
+func (r *TLSReport) prune(now time.Time) {
+	retention := r.Retention
+	if retention <= 0 {
+		retention = 90 * 24 * time.Hour
+	}
+	for key, offender := range r.offenders {
+		if now.Sub(offender.LastSeen) > retention {
+			delete(r.offenders, key)
+		}
+	}
+}
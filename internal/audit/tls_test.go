@@ -0,0 +1,38 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTLSReport(t *testing.T) {
+	now := time.Now()
+	report := &TLSReport{Retention: 30 * 24 * time.Hour}
+
+	report.Add("a", "10.0.0.1", "TLS 1.2 is below the minimum version TLS 1.3", now.Add(-60*24*time.Hour))
+	report.Add("b", "10.0.0.2", "TLS 1.2 is below the minimum version TLS 1.3", now.Add(-10*24*time.Hour))
+	report.Add("c", "10.0.0.3", "client certificate RSA key size 1024 is below the minimum size 2048", now.Add(-2*24*time.Hour))
+	report.Add("c", "10.0.0.4", "client certificate RSA key size 1024 is below the minimum size 2048", now.Add(-1*24*time.Hour))
+
+	offenders := report.Query(time.Time{})
+	if len(offenders) != 2 {
+		t.Fatalf("Invalid number of offenders: got '%d' - want '%d'", len(offenders), 2)
+	}
+	if offenders[0].Identity != "b" || offenders[1].Identity != "c" {
+		t.Fatalf("Invalid offenders: got '%s' and '%s' - want 'b' and 'c'", offenders[0].Identity, offenders[1].Identity)
+	}
+	if offenders[1].Requests != 2 {
+		t.Fatalf("Invalid number of requests: got '%d' - want '%d'", offenders[1].Requests, 2)
+	}
+	if offenders[1].IP != "10.0.0.4" {
+		t.Fatalf("Invalid IP: got '%s' - want '%s'", offenders[1].IP, "10.0.0.4")
+	}
+
+	if offenders = report.Query(now.Add(-7 * 24 * time.Hour)); len(offenders) != 1 {
+		t.Fatalf("Invalid number of offenders in the last 7 days: got '%d' - want '%d'", len(offenders), 1)
+	}
+}
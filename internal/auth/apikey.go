@@ -0,0 +1,81 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/minio/kes-go"
+)
+
+// ErrInvalidAPIKey is returned when a request presents
+// a bearer token that is not a valid API key.
+var ErrInvalidAPIKey = kes.NewError(http.StatusUnauthorized, "invalid API key")
+
+// ErrAPIKeyDisabled is returned when a request presents
+// an API key but the server does not accept API keys.
+var ErrAPIKeyDisabled = kes.NewError(http.StatusUnauthorized, "not authenticated")
+
+type apiKeyContextKey struct{}
+
+// WithAPIKeys returns a shallow copy of req that may be
+// authenticated with an API key instead of a client
+// certificate. APIKeyIdentity rejects API keys of all
+// other requests.
+func WithAPIKeys(req *http.Request) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), apiKeyContextKey{}, true))
+}
+
+// APIKeyIdentity returns the identity of the API key that
+// the request presents as bearer token in its Authorization
+// header. The identity of an API key is the hash of its
+// public key. Hence, it matches the identity of the client
+// certificate generated from the same API key.
+//
+// It reports whether the request contains a bearer token.
+// If the token is not a valid API key, it returns
+// ErrInvalidAPIKey.
+//
+// Clients that cannot manage TLS client certificates may
+// authenticate with an API key instead. If a request
+// contains both, a client certificate and an API key, the
+// client certificate takes precedence.
+//
+// API keys are only accepted if the request has been
+// returned by WithAPIKeys. Otherwise, it returns
+// ErrAPIKeyDisabled if the bearer token is an API key
+// and reports that the request contains no bearer token
+// for any other token.
+func APIKeyIdentity(req *http.Request) (kes.Identity, bool, error) {
+	token, ok := bearerToken(req)
+	if !ok {
+		return "", false, nil
+	}
+	if enabled, _ := req.Context().Value(apiKeyContextKey{}).(bool); !enabled {
+		if strings.HasPrefix(token, "kes:") {
+			return "", true, ErrAPIKeyDisabled
+		}
+		return "", false, nil
+	}
+	key, err := kes.ParseAPIKey(token)
+	if err != nil {
+		return "", true, ErrInvalidAPIKey
+	}
+	return key.Identity(), true, nil
+}
+
+// bearerToken returns the bearer token of the
+// request's Authorization header, if any.
+func bearerToken(req *http.Request) (string, bool) {
+	const Scheme = "Bearer "
+
+	header := req.Header.Get("Authorization")
+	if len(header) < len(Scheme) || !strings.EqualFold(header[:len(Scheme)], Scheme) {
+		return "", false
+	}
+	return strings.TrimSpace(header[len(Scheme):]), true
+}
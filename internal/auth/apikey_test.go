@@ -0,0 +1,104 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"crypto/tls"
+	"net/http"
+	"testing"
+
+	"github.com/minio/kes-go"
+)
+
+const testAPIKey = "kes:v1:AGaV6VXHasF0FnaB60WdCOeTZ8eTIDikL4zlN16c8NAs"
+
+var apiKeyIdentityTests = []struct {
+	Header     string
+	Identity   bool
+	ShouldFail bool
+}{
+	{Header: "", Identity: false},                             // 0
+	{Header: "Bearer " + testAPIKey, Identity: true},          // 1
+	{Header: "bearer " + testAPIKey, Identity: true},          // 2
+	{Header: "Basic dXNlcjpwYXNzd29yZA==", Identity: false},   // 3
+	{Header: "Bearer kes:v1:invalid", ShouldFail: true},       // 4
+	{Header: "Bearer " + testAPIKey[:20], ShouldFail: true},   // 5
+	{Header: "Bearer", Identity: false},                       // 6
+	{Header: "Bearer   " + testAPIKey + "  ", Identity: true}, // 7
+}
+
+func TestAPIKeyIdentity(t *testing.T) {
+	key, err := kes.ParseAPIKey(testAPIKey)
+	if err != nil {
+		t.Fatalf("Failed to parse API key: %v", err)
+	}
+
+	for i, test := range apiKeyIdentityTests {
+		req, err := http.NewRequest(http.MethodGet, "https://127.0.0.1:7373/version", nil)
+		if err != nil {
+			t.Fatalf("Test %d: failed to create request: %v", i, err)
+		}
+		if test.Header != "" {
+			req.Header.Set("Authorization", test.Header)
+		}
+
+		identity, ok, err := APIKeyIdentity(WithAPIKeys(req))
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: should have failed but succeeded", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to compute identity: %v", i, err)
+		}
+		if test.ShouldFail {
+			continue
+		}
+		if ok != test.Identity {
+			t.Fatalf("Test %d: got '%v' - want '%v'", i, ok, test.Identity)
+		}
+		if ok && identity != key.Identity() {
+			t.Fatalf("Test %d: identity mismatch: got '%v' - want '%v'", i, identity, key.Identity())
+		}
+	}
+}
+
+func TestIdentifyAPIKey(t *testing.T) {
+	key, err := kes.ParseAPIKey(testAPIKey)
+	if err != nil {
+		t.Fatalf("Failed to parse API key: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodGet, "https://127.0.0.1:7373/version", nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testAPIKey)
+	req = WithAPIKeys(req)
+
+	if identity := Identify(req); !identity.IsUnknown() {
+		t.Fatalf("Identity mismatch: got '%v' - want '%v' for non-TLS request", identity, kes.IdentityUnknown)
+	}
+	req.TLS = &tls.ConnectionState{}
+	if identity := Identify(req); identity != key.Identity() {
+		t.Fatalf("Identity mismatch: got '%v' - want '%v'", identity, key.Identity())
+	}
+}
+
+func TestAPIKeyDisabled(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "https://127.0.0.1:7373/version", nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.TLS = &tls.ConnectionState{}
+	req.Header.Set("Authorization", "Bearer "+testAPIKey)
+
+	if _, _, err = APIKeyIdentity(req); err != ErrAPIKeyDisabled {
+		t.Fatalf("Invalid error: got '%v' - want '%v'", err, ErrAPIKeyDisabled)
+	}
+	if identity := Identify(req); !identity.IsUnknown() {
+		t.Fatalf("Identity mismatch: got '%v' - want '%v' if API keys are disabled", identity, kes.IdentityUnknown)
+	}
+	if err = VerifyRequest(req, nil, nil); err != ErrAPIKeyDisabled {
+		t.Fatalf("Invalid error: got '%v' - want '%v'", err, ErrAPIKeyDisabled)
+	}
+}
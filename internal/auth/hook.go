@@ -0,0 +1,275 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/log"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// Policy hook decisions.
+const (
+	HookAllow    = "allow"
+	HookDeny     = "deny"
+	HookAnnotate = "annotate"
+)
+
+// A PolicyHook is a WebAssembly module that decides whether
+// a request, that is allowed by the policy of its identity,
+// gets accepted.
+//
+// The module must export its linear memory as "memory" and
+// the following two functions:
+//
+//	alloc(size i32) i32
+//	evaluate(ptr i32, len i32) i64
+//
+// For each request, a new module instance is created. The
+// JSON-encoded HookInput is written to the memory returned
+// by alloc and passed to evaluate. evaluate returns the
+// memory location of the JSON-encoded HookDecision as
+// ptr << 32 | len.
+//
+// The module cannot import any functions and, therefore,
+// cannot access anything but its own memory. Its memory is
+// limited to 16 MiB and its execution is terminated once
+// the hook's timeout expires.
+type PolicyHook struct {
+	name    string
+	runtime wazero.Runtime
+	module  wazero.CompiledModule
+	timeout time.Duration
+}
+
+// HookInput is the request context passed to a PolicyHook.
+type HookInput struct {
+	Method   string       `json:"method"`
+	Path     string       `json:"path"`
+	Query    string       `json:"query,omitempty"`
+	Identity kes.Identity `json:"identity"`
+	Policy   string       `json:"policy,omitempty"`
+	IP       string       `json:"ip,omitempty"`
+	Time     time.Time    `json:"time"`
+}
+
+// HookDecision is the result of a PolicyHook.
+//
+// An allow decision accepts the request without evaluating
+// any subsequent hooks. A deny decision rejects the request.
+// An annotate decision accepts the request, unless a
+// subsequent hook denies it. The annotations of all
+// evaluated hooks are recorded in the audit log.
+type HookDecision struct {
+	Decision    string            `json:"decision"`
+	Reason      string            `json:"reason,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// NewPolicyHook compiles the given WebAssembly module and
+// returns a new PolicyHook. If timeout <= 0, a default
+// timeout of 100ms is used.
+func NewPolicyHook(name string, module []byte, timeout time.Duration) (*PolicyHook, error) {
+	const (
+		DefaultTimeout = 100 * time.Millisecond
+		MaxMemoryPages = 256 // 16 MiB
+	)
+
+	// The interpreter does not generate native code and the
+	// runtime terminates any module execution once the context
+	// of the call is done.
+	ctx := context.Background()
+	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfigInterpreter().
+		WithCloseOnContextDone(true).
+		WithMemoryLimitPages(MaxMemoryPages))
+
+	m, err := runtime.CompileModule(ctx, module)
+	if err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("auth: invalid policy hook: %v", err)
+	}
+	if err = verifyHookModule(m); err != nil {
+		runtime.Close(ctx)
+		return nil, err
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &PolicyHook{
+		name:    name,
+		runtime: runtime,
+		module:  m,
+		timeout: timeout,
+	}, nil
+}
+
+// verifyHookModule returns an error if the module imports
+// anything or does not export the memory and functions
+// required by a PolicyHook.
+func verifyHookModule(m wazero.CompiledModule) error {
+	if len(m.ImportedFunctions()) > 0 || len(m.ImportedMemories()) > 0 {
+		return errors.New("auth: policy hook must not import functions or memory")
+	}
+	if _, ok := m.ExportedMemories()["memory"]; !ok {
+		return errors.New("auth: policy hook does not export 'memory'")
+	}
+
+	hasSignature := func(name string, params, results []api.ValueType) bool {
+		f, ok := m.ExportedFunctions()[name]
+		return ok && string(f.ParamTypes()) == string(params) && string(f.ResultTypes()) == string(results)
+	}
+	if !hasSignature("alloc", []api.ValueType{api.ValueTypeI32}, []api.ValueType{api.ValueTypeI32}) {
+		return errors.New("auth: policy hook does not export 'alloc(i32) i32'")
+	}
+	if !hasSignature("evaluate", []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, []api.ValueType{api.ValueTypeI64}) {
+		return errors.New("auth: policy hook does not export 'evaluate(i32, i32) i64'")
+	}
+	return nil
+}
+
+// Name returns the name of the PolicyHook.
+func (h *PolicyHook) Name() string { return h.name }
+
+// Close releases all resources held by the PolicyHook.
+func (h *PolicyHook) Close() error { return h.runtime.Close(context.Background()) }
+
+// Evaluate runs the PolicyHook for the given input and
+// returns its decision.
+func (h *PolicyHook) Evaluate(ctx context.Context, input *HookInput) (*HookDecision, error) {
+	const MaxOutputSize = 64 * 1024
+
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	in, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each evaluation uses its own, anonymous module instance
+	// such that concurrent requests cannot observe each other.
+	instance, err := h.runtime.InstantiateModule(ctx, h.module, wazero.NewModuleConfig().WithName("").WithStartFunctions())
+	if err != nil {
+		return nil, err
+	}
+	defer instance.Close(ctx)
+
+	results, err := instance.ExportedFunction("alloc").Call(ctx, uint64(len(in)))
+	if err != nil {
+		return nil, err
+	}
+	memory := instance.ExportedMemory("memory")
+	if !memory.Write(api.DecodeU32(results[0]), in) {
+		return nil, errors.New("auth: policy hook allocated invalid memory")
+	}
+
+	if results, err = instance.ExportedFunction("evaluate").Call(ctx, results[0], uint64(len(in))); err != nil {
+		return nil, err
+	}
+	ptr, n := uint32(results[0]>>32), uint32(results[0])
+	if n > MaxOutputSize {
+		return nil, errors.New("auth: policy hook decision is too large")
+	}
+	out, ok := memory.Read(ptr, n)
+	if !ok {
+		return nil, errors.New("auth: policy hook returned invalid memory")
+	}
+
+	var decision HookDecision
+	if err = json.Unmarshal(out, &decision); err != nil {
+		return nil, fmt.Errorf("auth: invalid policy hook decision: %v", err)
+	}
+	switch decision.Decision {
+	case HookAllow, HookDeny, HookAnnotate:
+		return &decision, nil
+	default:
+		return nil, fmt.Errorf("auth: invalid policy hook decision '%s'", decision.Decision)
+	}
+}
+
+type hookContextKey struct{}
+
+type hookState struct {
+	Hooks       []*PolicyHook
+	Annotations map[string]string
+}
+
+// WithPolicyHooks returns a shallow copy of req with the
+// given policy hooks. VerifyRequest evaluates these hooks
+// once the request is allowed by its policy.
+//
+// If hooks is empty, WithPolicyHooks returns req.
+func WithPolicyHooks(req *http.Request, hooks []*PolicyHook) *http.Request {
+	if len(hooks) == 0 {
+		return req
+	}
+	ctx := context.WithValue(req.Context(), hookContextKey{}, &hookState{
+		Hooks:       hooks,
+		Annotations: map[string]string{},
+	})
+	return req.WithContext(ctx)
+}
+
+// HookAnnotations returns the annotations of the policy hooks
+// evaluated for the request. The returned map is populated
+// once VerifyRequest has evaluated the hooks.
+func HookAnnotations(req *http.Request) map[string]string {
+	if s, ok := req.Context().Value(hookContextKey{}).(*hookState); ok {
+		return s.Annotations
+	}
+	return nil
+}
+
+// verifyHooks evaluates the policy hooks of the request, if
+// any. Hooks that fail are treated as deny decision.
+func verifyHooks(req *http.Request, identity kes.Identity, policy string) error {
+	s, ok := req.Context().Value(hookContextKey{}).(*hookState)
+	if !ok {
+		return nil
+	}
+
+	input := &HookInput{
+		Method:   req.Method,
+		Path:     req.URL.Path,
+		Query:    req.URL.RawQuery,
+		Identity: identity,
+		Policy:   policy,
+		Time:     time.Now().UTC(),
+	}
+	if addr := ForwardedIPFromContext(req.Context()); addr != nil {
+		input.IP = addr.String()
+	} else if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		input.IP = host
+	}
+	for _, hook := range s.Hooks {
+		decision, err := hook.Evaluate(req.Context(), input)
+		if err != nil {
+			log.Printf("auth: policy hook '%s' failed: %v", hook.Name(), err)
+			return kes.ErrNotAllowed
+		}
+		for k, v := range decision.Annotations {
+			s.Annotations[hook.Name()+"."+k] = v
+		}
+		switch decision.Decision {
+		case HookAllow:
+			return nil
+		case HookDeny:
+			if decision.Reason == "" {
+				return kes.ErrNotAllowed
+			}
+			return kes.NewError(http.StatusForbidden, "not allowed: "+decision.Reason)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"context"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/minio/kes-go"
+)
+
+var verifyHooksTests = []struct {
+	Decisions   []string
+	Annotations map[string]string
+	Err         error
+}{
+	{ // 0
+		Decisions: []string{`{"decision":"allow"}`},
+	},
+	{ // 1
+		Decisions: []string{`{"decision":"deny"}`},
+		Err:       kes.ErrNotAllowed,
+	},
+	{ // 2
+		Decisions:   []string{`{"decision":"annotate","annotations":{"tier":"gold"}}`, `{"decision":"allow"}`},
+		Annotations: map[string]string{"hook-0.tier": "gold"},
+	},
+	{ // 3
+		Decisions:   []string{`{"decision":"allow","annotations":{"a":"b"}}`, `{"decision":"deny"}`},
+		Annotations: map[string]string{"hook-0.a": "b"},
+	},
+	{ // 4
+		Decisions: []string{`{"decision":"annotate"}`, `{"decision":"deny","reason":"outside business hours"}`},
+		Err:       kes.NewError(403, "not allowed: outside business hours"),
+	},
+	{ // 5
+		Decisions: []string{`{"decision":"maybe"}`},
+		Err:       kes.ErrNotAllowed,
+	},
+	{ // 6
+		Decisions: []string{`not json`},
+		Err:       kes.ErrNotAllowed,
+	},
+}
+
+func TestVerifyHooks(t *testing.T) {
+	for i, test := range verifyHooksTests {
+		hooks := make([]*PolicyHook, 0, len(test.Decisions))
+		for j, decision := range test.Decisions {
+			hook, err := NewPolicyHook("hook-"+strconv.Itoa(j), hookModule(decision), time.Second)
+			if err != nil {
+				t.Fatalf("Test %d: failed to create policy hook: %v", i, err)
+			}
+			hooks = append(hooks, hook)
+		}
+
+		req := WithPolicyHooks(httptest.NewRequest("GET", "/v1/key/create/my-key", nil), hooks)
+		err := verifyHooks(req, "3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22", "my-policy")
+		if (err == nil) != (test.Err == nil) || err != nil && err.Error() != test.Err.Error() {
+			t.Fatalf("Test %d: got error '%v' - want '%v'", i, err, test.Err)
+		}
+		annotations := HookAnnotations(req)
+		if len(annotations) != len(test.Annotations) {
+			t.Fatalf("Test %d: got annotations '%v' - want '%v'", i, annotations, test.Annotations)
+		}
+		for k, v := range test.Annotations {
+			if annotations[k] != v {
+				t.Fatalf("Test %d: got annotations '%v' - want '%v'", i, annotations, test.Annotations)
+			}
+		}
+	}
+}
+
+func TestNewPolicyHook(t *testing.T) {
+	if _, err := NewPolicyHook("invalid", []byte("\x00asm\x01\x00\x00\x00"), 0); err == nil {
+		t.Fatal("Created policy hook from module without exports")
+	}
+	if _, err := NewPolicyHook("memory", buildHookModule(`{"decision":"allow"}`, 257, nil), 0); err == nil {
+		t.Fatal("Created policy hook from module that requires more than 16 MiB of memory")
+	}
+
+	// (import "env" "now" (func (result i64)))
+	imports := []byte("\x00asm\x01\x00\x00\x00")
+	imports = append(imports, 1, 5, 1, 0x60, 0, 1, 0x7E)
+	imports = append(imports, 2, 11, 1, 3, 'e', 'n', 'v', 3, 'n', 'o', 'w', 0x00, 0)
+	if _, err := NewPolicyHook("imports", imports, 0); err == nil {
+		t.Fatal("Created policy hook from module with imports")
+	}
+}
+
+func TestPolicyHookTimeout(t *testing.T) {
+	const Timeout = 50 * time.Millisecond
+
+	// evaluate: loop br 0 end unreachable
+	hook, err := NewPolicyHook("spin", buildHookModule(`{"decision":"allow"}`, 1, []byte{0x00, 0x03, 0x40, 0x0C, 0, 0x0B, 0x00, 0x0B}), Timeout)
+	if err != nil {
+		t.Fatalf("Failed to create policy hook: %v", err)
+	}
+	defer hook.Close()
+
+	start := time.Now()
+	if _, err = hook.Evaluate(context.Background(), &HookInput{Method: "GET", Path: "/v1/status"}); err == nil {
+		t.Fatal("Policy hook did not fail when exceeding its timeout")
+	}
+	if d := time.Since(start); d > 20*Timeout {
+		t.Fatalf("Policy hook got terminated after %v - want ~%v", d, Timeout)
+	}
+}
+
+// hookModule returns a policy hook module that ignores its
+// input and always returns the given decision:
+//
+//	(memory (export "memory") 1)
+//	(func (export "alloc") (param i32) (result i32) i32.const 1024)
+//	(func (export "evaluate") (param i32 i32) (result i64) i64.const <len(decision)>)
+//	(data (i32.const 0) "<decision>")
+func hookModule(decision string) []byte { return buildHookModule(decision, 1, nil) }
+
+// buildHookModule returns a policy hook module with the given
+// number of memory pages. If evaluate is not empty, it replaces
+// the code of the evaluate function.
+func buildHookModule(decision string, pages int, evaluate []byte) []byte {
+	section := func(id byte, payload ...[]byte) []byte {
+		var b []byte
+		for _, p := range payload {
+			b = append(b, p...)
+		}
+		return append(append([]byte{id}, leb128(uint64(len(b)))...), b...)
+	}
+	name := func(s string) []byte { return append(leb128(uint64(len(s))), s...) }
+
+	if len(evaluate) == 0 {
+		evaluate = append(append([]byte{0x00, 0x42}, leb128(uint64(len(decision)))...), 0x0B)
+	}
+	module := []byte("\x00asm\x01\x00\x00\x00")
+	module = append(module, section(1, []byte{2, 0x60, 1, 0x7F, 1, 0x7F, 0x60, 2, 0x7F, 0x7F, 1, 0x7E})...)
+	module = append(module, section(3, []byte{2, 0, 1})...)
+	module = append(module, section(5, []byte{1, 0x00}, leb128(uint64(pages)))...)
+	module = append(module, section(7,
+		[]byte{3},
+		name("memory"), []byte{0x02, 0},
+		name("alloc"), []byte{0x00, 0},
+		name("evaluate"), []byte{0x00, 1},
+	)...)
+	module = append(module, section(10,
+		[]byte{2, 5, 0x00, 0x41, 0x80, 0x08, 0x0B},
+		leb128(uint64(len(evaluate))), evaluate,
+	)...)
+	module = append(module, section(11,
+		[]byte{1, 0x00, 0x41, 0, 0x0B},
+		name(decision),
+	)...)
+	return module
+}
+
+// leb128 encodes v as signed LEB128 integer, which is
+// also a valid unsigned LEB128 encoding for small v.
+func leb128(v uint64) []byte {
+	var b []byte
+	for {
+		c := byte(v & 0x7F)
+		v >>= 7
+		if v == 0 && c&0x40 == 0 {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
@@ -18,12 +18,21 @@ import (
 	"github.com/minio/kes-go"
 )
 
+// ErrIdentityExpired is returned when a request has been
+// issued by an identity that has expired.
+var ErrIdentityExpired = kes.NewError(http.StatusForbidden, "identity has expired")
+
 // VerifyRequest verifies whether the request's identity is allowed to perform
-// the request based on the given policies.
+// the request based on the given policies. Once the policy allows the
+// request, VerifyRequest evaluates the request's policy hooks, if any.
+// See WithPolicyHooks.
 func VerifyRequest(r *http.Request, policies PolicySet, identities IdentitySet) error {
 	if r.TLS == nil {
 		return kes.NewError(http.StatusBadRequest, "insecure connection: TLS required")
 	}
+	if err := verifyTLSViolation(r); err != nil {
+		return err
+	}
 
 	var peerCertificates []*x509.Certificate
 	switch {
@@ -37,24 +46,64 @@ func VerifyRequest(r *http.Request, policies PolicySet, identities IdentitySet)
 			peerCertificates = append(peerCertificates, cert)
 		}
 	}
-	if len(peerCertificates) == 0 {
-		return kes.NewError(http.StatusBadRequest, "no client certificate is present")
-	}
 	if len(peerCertificates) > 1 {
 		return kes.NewError(http.StatusBadRequest, "too many client certificates are present")
 	}
 
 	var (
-		h        = sha256.Sum256(peerCertificates[0].RawSubjectPublicKeyInfo)
-		identity = kes.Identity(hex.EncodeToString(h[:]))
+		identity        kes.Identity
+		certIdentity    kes.Identity
+		federatedPolicy string
+		isFederated     bool
 	)
+	if len(peerCertificates) == 1 {
+		h := sha256.Sum256(peerCertificates[0].RawSubjectPublicKeyInfo)
+		certIdentity = kes.Identity(hex.EncodeToString(h[:]))
+	}
+	if federated, ok := federated(r); ok {
+		identity, federatedPolicy, isFederated = federated.Identity, federated.Policy, true
+	} else if len(peerCertificates) == 1 {
+		identity = certIdentity
+	} else {
+		apiKeyIdentity, ok, err := APIKeyIdentity(r)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return kes.NewError(http.StatusBadRequest, "no client certificate is present")
+		}
+		identity = apiKeyIdentity
+	}
+	target, impersonating := impersonated(r)
+	if impersonating {
+		identity, federatedPolicy = target, ""
+	}
 	admin, err := identities.Admin(r.Context())
 	if err != nil {
 		return err
 	}
+	if isFederated && !impersonating && identity == admin { // A JWT or SPIFFE ID never grants admin privileges
+		return kes.ErrNotAllowed
+	}
 	if identity == admin {
 		return nil
 	}
+	if certIdentity == admin && !impersonating { // The admin remains the admin even if its certificate has a SPIFFE ID
+		return nil
+	}
+	if federatedPolicy != "" { // The OIDC provider has assigned the identity to a policy
+		policy, err := policies.Get(r.Context(), federatedPolicy)
+		if errors.Is(err, kes.ErrPolicyNotFound) {
+			return kes.ErrNotAllowed
+		}
+		if err != nil {
+			return err
+		}
+		if err = policy.Verify(r); err != nil {
+			return err
+		}
+		return verifyHooks(r, identity, federatedPolicy)
+	}
 
 	info, err := identities.Get(r.Context(), identity)
 	if errors.Is(err, kes.ErrIdentityNotFound) {
@@ -63,6 +112,9 @@ func VerifyRequest(r *http.Request, policies PolicySet, identities IdentitySet)
 	if err != nil {
 		return err
 	}
+	if info.Expired() {
+		return ErrIdentityExpired
+	}
 	policy, err := policies.Get(r.Context(), info.Policy)
 	if errors.Is(err, kes.ErrPolicyNotFound) {
 		return kes.ErrNotAllowed
@@ -70,18 +122,59 @@ func VerifyRequest(r *http.Request, policies PolicySet, identities IdentitySet)
 	if err != nil {
 		return err
 	}
-	return policy.Verify(r)
+	if err = policy.Verify(r); err != nil {
+		return err
+	}
+	return verifyHooks(r, identity, info.Policy)
+}
+
+// PolicyOf returns the name of the policy assigned to the
+// identity of the given HTTP request, if any. The policy
+// assigned by an OIDC provider to a federated identity
+// takes precedence over the identities.
+func PolicyOf(req *http.Request, identities IdentitySet) string {
+	if _, ok := impersonated(req); !ok {
+		if federated, ok := federated(req); ok && federated.Policy != "" {
+			return federated.Policy
+		}
+	}
+	info, err := identities.Get(req.Context(), Identify(req))
+	if err != nil {
+		return ""
+	}
+	return info.Policy
+}
+
+// IsFederated reports whether the request has been sent
+// by a federated identity, e.g. a JWT or SPIFFE ID, and
+// does not impersonate another identity.
+func IsFederated(req *http.Request) bool {
+	if _, ok := impersonated(req); ok {
+		return false
+	}
+	_, ok := federated(req)
+	return ok
 }
 
 // Identify computes the identity of the given HTTP request.
 //
-// If the request was not sent over TLS or no client
-// certificate has been provided, Identify returns
-// IdentityUnknown.
+// If the request was not sent over TLS or neither a
+// client certificate, a verified JWT nor a valid API key
+// has been provided, Identify returns IdentityUnknown. If
+// the request impersonates another identity, Identify
+// returns the impersonated identity. If the request has
+// been federated, e.g. from a JWT or SPIFFE ID, Identify
+// returns the federated identity.
 func Identify(req *http.Request) kes.Identity {
+	if identity, ok := impersonated(req); ok {
+		return identity
+	}
 	if req.TLS == nil {
 		return kes.IdentityUnknown
 	}
+	if federated, ok := federated(req); ok {
+		return federated.Identity
+	}
 
 	var cert *x509.Certificate
 	for _, c := range req.TLS.PeerCertificates {
@@ -99,6 +192,9 @@ func Identify(req *http.Request) kes.Identity {
 		cert = c
 	}
 	if cert == nil {
+		if identity, _, err := APIKeyIdentity(req); err == nil && identity != "" {
+			return identity
+		}
 		return kes.IdentityUnknown
 	}
 
@@ -187,6 +283,23 @@ type IdentityInfo struct {
 	// CreatedBy is the identity that assigned this
 	// identity to its policy.
 	CreatedBy kes.Identity
+
+	// ExpiresAt is the point in time when the identity
+	// expires. Once expired, requests issued by the
+	// identity are rejected. The zero value indicates
+	// that the identity never expires.
+	ExpiresAt time.Time
+
+	// Labels are free-form key-value pairs, like the
+	// team or service an identity belongs to. They
+	// have no effect on what the identity can do.
+	Labels map[string]string
+}
+
+// Expired reports whether the identity has expired.
+// Admin identities never expire.
+func (i *IdentityInfo) Expired() bool {
+	return !i.IsAdmin && !i.ExpiresAt.IsZero() && !time.Now().Before(i.ExpiresAt)
 }
 
 // MarshalBinary returns the IdentityInfo's binary representation.
@@ -196,6 +309,8 @@ func (i IdentityInfo) MarshalBinary() ([]byte, error) {
 		IsAdmin   bool
 		CreatedAt time.Time
 		CreatedBy kes.Identity
+		ExpiresAt time.Time
+		Labels    map[string]string
 	}
 
 	var buffer bytes.Buffer
@@ -212,6 +327,8 @@ func (i *IdentityInfo) UnmarshalBinary(b []byte) error {
 		IsAdmin   bool
 		CreatedAt time.Time
 		CreatedBy kes.Identity
+		ExpiresAt time.Time
+		Labels    map[string]string
 	}
 
 	var value GOB
@@ -222,5 +339,7 @@ func (i *IdentityInfo) UnmarshalBinary(b []byte) error {
 	i.IsAdmin = value.IsAdmin
 	i.CreatedAt = value.CreatedAt
 	i.CreatedBy = value.CreatedBy
+	i.ExpiresAt = value.ExpiresAt
+	i.Labels = value.Labels
 	return nil
 }
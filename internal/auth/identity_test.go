@@ -0,0 +1,55 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+var identityExpiredTests = []struct {
+	Info    IdentityInfo
+	Expired bool
+}{
+	{Info: IdentityInfo{Policy: "my-policy"}, Expired: false},                                       // 0
+	{Info: IdentityInfo{Policy: "my-policy", ExpiresAt: time.Now().Add(time.Hour)}, Expired: false}, // 1
+	{Info: IdentityInfo{Policy: "my-policy", ExpiresAt: time.Now().Add(-time.Hour)}, Expired: true}, // 2
+	{Info: IdentityInfo{IsAdmin: true, ExpiresAt: time.Now().Add(-time.Hour)}, Expired: false},      // 3
+}
+
+func TestIdentityExpired(t *testing.T) {
+	for i, test := range identityExpiredTests {
+		if expired := test.Info.Expired(); expired != test.Expired {
+			t.Fatalf("Test %d: got '%v' - want '%v'", i, expired, test.Expired)
+		}
+	}
+}
+
+func TestIdentityInfoMarshalBinary(t *testing.T) {
+	info := IdentityInfo{
+		Policy:    "my-policy",
+		CreatedAt: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		ExpiresAt: time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC),
+		Labels:    map[string]string{"team": "storage"},
+	}
+	b, err := info.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Failed to marshal identity info: %v", err)
+	}
+
+	var info2 IdentityInfo
+	if err = info2.UnmarshalBinary(b); err != nil {
+		t.Fatalf("Failed to unmarshal identity info: %v", err)
+	}
+	if !info2.ExpiresAt.Equal(info.ExpiresAt) {
+		t.Fatalf("Expiry mismatch: got '%v' - want '%v'", info2.ExpiresAt, info.ExpiresAt)
+	}
+	if len(info2.Labels) != 1 || info2.Labels["team"] != "storage" {
+		t.Fatalf("Labels mismatch: got '%v' - want '%v'", info2.Labels, info.Labels)
+	}
+	if info2.Policy != info.Policy || !info2.CreatedAt.Equal(info.CreatedAt) {
+		t.Fatalf("Identity info mismatch: got '%v' - want '%v'", info2, info)
+	}
+}
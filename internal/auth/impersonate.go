@@ -0,0 +1,60 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/minio/kes-go"
+)
+
+// ImpersonateHeader is the HTTP header an admin sets to
+// send a request on behalf of another identity.
+const ImpersonateHeader = "Kes-Impersonate"
+
+type impersonationContextKey struct{}
+
+type impersonation struct {
+	Identity     kes.Identity // The impersonated identity
+	Impersonator kes.Identity // The identity that sent the request
+}
+
+// Impersonate returns a shallow copy of req that appears
+// to be sent by the given identity. Identify returns the
+// impersonated identity for the returned request and
+// Impersonator returns the identity of req.
+//
+// The caller has to ensure that the identity of req is
+// allowed to impersonate other identities.
+func Impersonate(req *http.Request, identity kes.Identity) *http.Request {
+	ctx := context.WithValue(req.Context(), impersonationContextKey{}, impersonation{
+		Identity:     identity,
+		Impersonator: Identify(req),
+	})
+	return req.WithContext(ctx)
+}
+
+// Impersonator returns the identity that has sent the
+// request on behalf of the identity returned by Identify,
+// if any. It reports whether the request impersonates
+// another identity.
+func Impersonator(req *http.Request) (kes.Identity, bool) {
+	v, ok := req.Context().Value(impersonationContextKey{}).(impersonation)
+	if !ok {
+		return "", false
+	}
+	return v.Impersonator, true
+}
+
+// impersonated returns the identity impersonated by the
+// request, if any.
+func impersonated(req *http.Request) (kes.Identity, bool) {
+	v, ok := req.Context().Value(impersonationContextKey{}).(impersonation)
+	if !ok {
+		return "", false
+	}
+	return v.Identity, true
+}
@@ -0,0 +1,455 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/kes-go"
+)
+
+// ErrInvalidToken is returned when a request presents a
+// bearer token that is not a valid JWT of the configured
+// OIDC issuer.
+var ErrInvalidToken = kes.NewError(http.StatusUnauthorized, "invalid token")
+
+// OIDC verifies JSON Web Tokens (JWT) issued by an OpenID
+// Connect (OIDC) provider and maps their claims to KES
+// identities and policies.
+//
+// Clients, like Kubernetes pods, can authenticate with a
+// JWT issued by a trusted OIDC provider, sent as bearer
+// token, instead of a TLS client certificate.
+type OIDC struct {
+	// Issuer is the URL of the OIDC provider. A JWT
+	// is only accepted if its "iss" claim is equal
+	// to Issuer.
+	Issuer string
+
+	// Audience is the required audience of a JWT.
+	// A JWT is only accepted if its "aud" claim
+	// contains Audience.
+	Audience string
+
+	// JWKSURL is the URL of the provider's JSON Web
+	// Key Set (JWKS) used to verify JWT signatures.
+	//
+	// If empty, the URL is discovered from the issuer's
+	// OpenID configuration.
+	JWKSURL string
+
+	// IdentityClaim is the claim whose value is used as
+	// the KES identity of a verified JWT. Identities can
+	// be assigned to policies like any other identity.
+	//
+	// The identity is namespaced by the issuer, i.e.
+	// "oidc:<issuer>:<claim>", such that a JWT cannot
+	// claim the identity of a client certificate or of
+	// another issuer.
+	//
+	// If empty, defaults to the "sub" claim.
+	IdentityClaim string
+
+	// PolicyClaim is an optional claim whose value is
+	// the name of the KES policy a verified JWT is
+	// assigned to. If set and present in a JWT, the JWT
+	// is assigned to this policy regardless of any
+	// policy its identity is assigned to.
+	PolicyClaim string
+
+	// Client is the HTTP client used to fetch the
+	// JWKS. If nil, http.DefaultClient is used.
+	Client *http.Client
+
+	lock      sync.RWMutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+// Federated describes the KES identity and policy
+// a verified JWT has been mapped to.
+type Federated struct {
+	// Identity is the federated identity, e.g.
+	// "oidc:<issuer>:<sub>" for a JWT or the SPIFFE ID
+	// of a X.509-SVID.
+	Identity kes.Identity
+
+	// Policy is the value of the JWT's policy claim,
+	// if any.
+	Policy string
+}
+
+// Token returns the JWT the request presents as bearer
+// token in its Authorization header, if any. API keys
+// are not considered JWTs.
+func Token(req *http.Request) (string, bool) {
+	token, ok := bearerToken(req)
+	if !ok || strings.HasPrefix(token, "kes:") || strings.Count(token, ".") != 2 {
+		return "", false
+	}
+	return token, true
+}
+
+// Verify verifies the JWT and returns the identity and
+// policy it maps to. It returns ErrInvalidToken if the
+// JWT is malformed, expired, not signed by the issuer or
+// issued for a different audience.
+func (o *OIDC) Verify(ctx context.Context, token string) (Federated, error) {
+	const Leeway = time.Minute
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Federated{}, ErrInvalidToken
+	}
+	var header struct {
+		Algorithm string `json:"alg"`
+		KeyID     string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Federated{}, ErrInvalidToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Federated{}, ErrInvalidToken
+	}
+
+	key, err := o.publicKey(ctx, header.KeyID)
+	if err != nil {
+		return Federated{}, err
+	}
+	if err = verifySignature(header.Algorithm, key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return Federated{}, ErrInvalidToken
+	}
+
+	var claims map[string]any
+	if err = decodeSegment(parts[1], &claims); err != nil {
+		return Federated{}, ErrInvalidToken
+	}
+	if iss, _ := claims["iss"].(string); iss != o.Issuer {
+		return Federated{}, ErrInvalidToken
+	}
+	if o.Audience != "" && !hasAudience(claims["aud"], o.Audience) {
+		return Federated{}, ErrInvalidToken
+	}
+
+	now := time.Now()
+	exp, ok := claims["exp"].(float64)
+	if !ok || now.After(time.Unix(int64(exp), 0).Add(Leeway)) {
+		return Federated{}, ErrInvalidToken
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(Leeway).Before(time.Unix(int64(nbf), 0)) {
+		return Federated{}, ErrInvalidToken
+	}
+
+	identityClaim := o.IdentityClaim
+	if identityClaim == "" {
+		identityClaim = "sub"
+	}
+	identity, _ := claims[identityClaim].(string)
+	if identity == "" || kes.Identity(identity).IsUnknown() {
+		return Federated{}, ErrInvalidToken
+	}
+
+	var policy string
+	if o.PolicyClaim != "" {
+		policy, _ = claims[o.PolicyClaim].(string)
+	}
+	return Federated{
+		Identity: OIDCIdentity(o.Issuer, identity),
+		Policy:   policy,
+	}, nil
+}
+
+// OIDCIdentity returns the KES identity of a JWT issued
+// by the given issuer with the given identity claim.
+//
+// The identity is prefixed with "oidc:" and the issuer
+// such that it never collides with the identity of a
+// client certificate, an API key or another issuer.
+func OIDCIdentity(issuer, claim string) kes.Identity {
+	return kes.Identity("oidc:" + issuer + ":" + claim)
+}
+
+// publicKey returns the JWKS public key with the given key ID.
+// It fetches the JWKS if the key ID is not known yet, but at
+// most once a minute.
+func (o *OIDC) publicKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	const RefreshInterval = time.Minute
+
+	o.lock.RLock()
+	key, ok := o.lookup(kid)
+	fetchedAt := o.fetchedAt
+	o.lock.RUnlock()
+	if ok {
+		return key, nil
+	}
+	if time.Since(fetchedAt) < RefreshInterval {
+		return nil, ErrInvalidToken
+	}
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if key, ok = o.lookup(kid); ok { // Another request may have fetched the JWKS already
+		return key, nil
+	}
+	if time.Since(o.fetchedAt) < RefreshInterval {
+		return nil, ErrInvalidToken
+	}
+	keys, err := o.fetchKeys(ctx)
+	o.fetchedAt = time.Now()
+	if err != nil {
+		return nil, kes.NewError(http.StatusBadGateway, fmt.Sprintf("failed to fetch JWKS: %v", err))
+	}
+	o.keys = keys
+
+	if key, ok = o.lookup(kid); ok {
+		return key, nil
+	}
+	return nil, ErrInvalidToken
+}
+
+// lookup returns the public key with the given key ID. If the
+// key ID is empty, it returns the only key of the JWKS, if any.
+func (o *OIDC) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(o.keys) == 1 {
+		for _, key := range o.keys {
+			return key, true
+		}
+	}
+	key, ok := o.keys[kid]
+	return key, ok
+}
+
+// fetchKeys fetches the JWKS of the issuer. It discovers the
+// JWKS URL from the issuer's OpenID configuration if no JWKS
+// URL has been specified.
+func (o *OIDC) fetchKeys(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	jwksURL := o.JWKSURL
+	if jwksURL == "" {
+		var config struct {
+			JWKSURL string `json:"jwks_uri"`
+		}
+		if err := o.get(ctx, strings.TrimSuffix(o.Issuer, "/")+"/.well-known/openid-configuration", &config); err != nil {
+			return nil, err
+		}
+		if config.JWKSURL == "" {
+			return nil, errors.New("OpenID configuration contains no 'jwks_uri'")
+		}
+		jwksURL = config.JWKSURL
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Type  string `json:"kty"`
+			KeyID string `json:"kid"`
+			Use   string `json:"use"`
+			Curve string `json:"crv"`
+			N     string `json:"n"`
+			E     string `json:"e"`
+			X     string `json:"x"`
+			Y     string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := o.get(ctx, jwksURL, &jwks); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		switch k.Type {
+		case "RSA":
+			n, err := base64.RawURLEncoding.DecodeString(k.N)
+			if err != nil {
+				return nil, fmt.Errorf("invalid RSA key '%s': %v", k.KeyID, err)
+			}
+			e, err := base64.RawURLEncoding.DecodeString(k.E)
+			if err != nil {
+				return nil, fmt.Errorf("invalid RSA key '%s': %v", k.KeyID, err)
+			}
+			exponent := new(big.Int).SetBytes(e)
+			if !exponent.IsInt64() || exponent.Int64() <= 1 || exponent.Int64() > math.MaxInt32 || exponent.Bit(0) == 0 {
+				return nil, fmt.Errorf("invalid RSA key '%s': invalid public exponent", k.KeyID)
+			}
+			keys[k.KeyID] = &rsa.PublicKey{
+				N: new(big.Int).SetBytes(n),
+				E: int(exponent.Int64()),
+			}
+		case "EC":
+			var curve elliptic.Curve
+			switch k.Curve {
+			case "P-256":
+				curve = elliptic.P256()
+			case "P-384":
+				curve = elliptic.P384()
+			case "P-521":
+				curve = elliptic.P521()
+			default:
+				continue
+			}
+			x, err := base64.RawURLEncoding.DecodeString(k.X)
+			if err != nil {
+				return nil, fmt.Errorf("invalid EC key '%s': %v", k.KeyID, err)
+			}
+			y, err := base64.RawURLEncoding.DecodeString(k.Y)
+			if err != nil {
+				return nil, fmt.Errorf("invalid EC key '%s': %v", k.KeyID, err)
+			}
+			key := &ecdsa.PublicKey{
+				Curve: curve,
+				X:     new(big.Int).SetBytes(x),
+				Y:     new(big.Int).SetBytes(y),
+			}
+			if !curve.IsOnCurve(key.X, key.Y) {
+				return nil, fmt.Errorf("invalid EC key '%s': point is not on curve", k.KeyID)
+			}
+			keys[k.KeyID] = key
+		case "OKP":
+			if k.Curve != "Ed25519" {
+				continue
+			}
+			x, err := base64.RawURLEncoding.DecodeString(k.X)
+			if err != nil || len(x) != ed25519.PublicKeySize {
+				return nil, fmt.Errorf("invalid Ed25519 key '%s'", k.KeyID)
+			}
+			keys[k.KeyID] = ed25519.PublicKey(x)
+		}
+	}
+	return keys, nil
+}
+
+// get fetches the JSON document at the given URL.
+func (o *OIDC) get(ctx context.Context, url string, v any) error {
+	const MaxSize = 1 << 20
+
+	client := o.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, MaxSize)).Decode(v)
+}
+
+// verifySignature verifies the JWS signature of the
+// message with the public key and algorithm.
+func verifySignature(alg string, key crypto.PublicKey, message, signature []byte) error {
+	var h hash.Hash
+	var hashAlg crypto.Hash
+	switch alg {
+	case "RS256", "PS256", "ES256":
+		h, hashAlg = sha256.New(), crypto.SHA256
+	case "RS384", "PS384", "ES384":
+		h, hashAlg = sha512.New384(), crypto.SHA384
+	case "RS512", "PS512", "ES512":
+		h, hashAlg = sha512.New(), crypto.SHA512
+	case "EdDSA":
+		if key, ok := key.(ed25519.PublicKey); ok && ed25519.Verify(key, message, signature) {
+			return nil
+		}
+		return ErrInvalidToken
+	default:
+		return ErrInvalidToken
+	}
+	h.Write(message)
+	digest := h.Sum(nil)
+
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if strings.HasPrefix(alg, "RS") {
+			return rsa.VerifyPKCS1v15(key, hashAlg, digest, signature)
+		}
+		if strings.HasPrefix(alg, "PS") {
+			return rsa.VerifyPSS(key, hashAlg, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		}
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if !strings.HasPrefix(alg, "ES") || len(signature) != 2*size {
+			return ErrInvalidToken
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if ecdsa.Verify(key, digest, r, s) {
+			return nil
+		}
+	}
+	return ErrInvalidToken
+}
+
+// decodeSegment decodes a base64url-encoded JWT segment.
+func decodeSegment(segment string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// hasAudience reports whether the JWT "aud" claim, either a
+// string or a list of strings, contains the audience.
+func hasAudience(aud any, audience string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == audience
+	case []any:
+		for _, a := range aud {
+			if s, ok := a.(string); ok && s == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type federationContextKey struct{}
+
+// Federate returns a shallow copy of req that has been
+// sent by the federated identity. Identify returns the
+// federated identity for the returned request unless
+// the request impersonates another identity.
+func Federate(req *http.Request, federated Federated) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), federationContextKey{}, federated))
+}
+
+// federated returns the federated identity and policy
+// of the request, if any.
+func federated(req *http.Request) (Federated, bool) {
+	v, ok := req.Context().Value(federationContextKey{}).(Federated)
+	return v, ok
+}
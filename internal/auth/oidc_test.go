@@ -0,0 +1,248 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/minio/kes-go"
+)
+
+var oidcVerifyTests = []struct {
+	KeyID      string
+	Claims     map[string]any
+	Identity   kes.Identity
+	Policy     string
+	ShouldFail bool
+}{
+	{ // 0
+		KeyID:    "key-1",
+		Claims:   map[string]any{"iss": "{{issuer}}", "aud": "kes", "sub": "system:serviceaccount:default:minio"},
+		Identity: "system:serviceaccount:default:minio",
+	},
+	{ // 1
+		KeyID:    "key-1",
+		Claims:   map[string]any{"iss": "{{issuer}}", "aud": []string{"vault", "kes"}, "sub": "minio", "kes_policy": "my-policy"},
+		Identity: "minio",
+		Policy:   "my-policy",
+	},
+	{ // 2
+		KeyID:      "key-1",
+		Claims:     map[string]any{"iss": "https://example.com", "aud": "kes", "sub": "minio"},
+		ShouldFail: true, // Wrong issuer
+	},
+	{ // 3
+		KeyID:      "key-1",
+		Claims:     map[string]any{"iss": "{{issuer}}", "aud": "vault", "sub": "minio"},
+		ShouldFail: true, // Wrong audience
+	},
+	{ // 4
+		KeyID:      "key-1",
+		Claims:     map[string]any{"iss": "{{issuer}}", "aud": "kes", "sub": "minio", "exp": time.Now().Add(-time.Hour).Unix()},
+		ShouldFail: true, // Expired
+	},
+	{ // 5
+		KeyID:      "key-2",
+		Claims:     map[string]any{"iss": "{{issuer}}", "aud": "kes", "sub": "minio"},
+		ShouldFail: true, // Unknown key
+	},
+	{ // 6
+		KeyID:      "key-1",
+		Claims:     map[string]any{"iss": "{{issuer}}", "aud": "kes"},
+		ShouldFail: true, // No identity claim
+	},
+}
+
+func TestOIDCVerify(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, _ *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"issuer": server.URL, "jwks_uri": server.URL + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, _ *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kty": "EC",
+				"kid": "key-1",
+				"use": "sig",
+				"crv": "P-256",
+				"x":   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+				"y":   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+			}},
+		})
+	})
+
+	oidc := &OIDC{
+		Issuer:      server.URL,
+		Audience:    "kes",
+		PolicyClaim: "kes_policy",
+		Client:      server.Client(),
+	}
+	for i, test := range oidcVerifyTests {
+		if test.Claims["iss"] == "{{issuer}}" {
+			test.Claims["iss"] = server.URL
+		}
+		if _, ok := test.Claims["exp"]; !ok {
+			test.Claims["exp"] = time.Now().Add(time.Hour).Unix()
+		}
+		token := signJWT(t, key, test.KeyID, test.Claims)
+
+		federated, err := oidc.Verify(context.Background(), token)
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: should have failed but succeeded", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to verify token: %v", i, err)
+		}
+		if test.ShouldFail {
+			continue
+		}
+		if identity := OIDCIdentity(server.URL, test.Identity.String()); federated.Identity != identity {
+			t.Fatalf("Test %d: identity mismatch: got '%v' - want '%v'", i, federated.Identity, identity)
+		}
+		if federated.Policy != test.Policy {
+			t.Fatalf("Test %d: policy mismatch: got '%v' - want '%v'", i, federated.Policy, test.Policy)
+		}
+	}
+}
+
+var fetchRSAKeyTests = []struct {
+	E          []byte
+	ShouldFail bool
+}{
+	{E: []byte{0x01, 0x00, 0x01}},                               // 0
+	{E: []byte{0x03}},                                           // 1
+	{E: []byte{0x01}, ShouldFail: true},                         // 2
+	{E: []byte{0x00}, ShouldFail: true},                         // 3
+	{E: []byte{0x01, 0x00, 0x00}, ShouldFail: true},             // 4
+	{E: []byte{0x01, 0x00, 0x00, 0x00, 0x01}, ShouldFail: true}, // 5
+}
+
+func TestFetchRSAKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	for i, test := range fetchRSAKeyTests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			json.NewEncoder(w).Encode(map[string]any{
+				"keys": []map[string]string{{
+					"kty": "RSA",
+					"kid": "key-1",
+					"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+					"e":   base64.RawURLEncoding.EncodeToString(test.E),
+				}},
+			})
+		}))
+		oidc := &OIDC{
+			Issuer:  server.URL,
+			JWKSURL: server.URL,
+			Client:  server.Client(),
+		}
+		_, err := oidc.fetchKeys(context.Background())
+		server.Close()
+
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: should have failed but succeeded", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to fetch key: %v", i, err)
+		}
+	}
+}
+
+func TestVerifyRequestFederatedAdmin(t *testing.T) {
+	const Admin = "3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22"
+
+	req, err := http.NewRequest(http.MethodGet, "https://127.0.0.1:7373/version", nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.TLS = new(tls.ConnectionState)
+	req = Federate(req, Federated{Identity: Admin})
+
+	if !IsFederated(req) {
+		t.Fatal("Request should be federated")
+	}
+	if err = VerifyRequest(req, nil, adminIdentitySet(Admin)); err != kes.ErrNotAllowed {
+		t.Fatalf("Federated admin identity: got '%v' - want '%v'", err, kes.ErrNotAllowed)
+	}
+}
+
+func TestIdentifyFederated(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "https://127.0.0.1:7373/version", nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req = Federate(req, Federated{Identity: "system:serviceaccount:default:minio"})
+
+	req.TLS = nil
+	if identity := Identify(req); !identity.IsUnknown() {
+		t.Fatalf("Identity mismatch: got '%v' - want '%v'", identity, kes.IdentityUnknown)
+	}
+	req.TLS = new(tls.ConnectionState)
+	if identity := Identify(req); identity != "system:serviceaccount:default:minio" {
+		t.Fatalf("Identity mismatch: got '%v' - want '%v'", identity, "system:serviceaccount:default:minio")
+	}
+}
+
+// adminIdentitySet is an IdentitySet that only
+// contains the admin identity.
+type adminIdentitySet kes.Identity
+
+func (s adminIdentitySet) Admin(context.Context) (kes.Identity, error) { return kes.Identity(s), nil }
+
+func (adminIdentitySet) Assign(context.Context, string, kes.Identity) error {
+	return kes.ErrNotAllowed
+}
+
+func (adminIdentitySet) Get(context.Context, kes.Identity) (IdentityInfo, error) {
+	return IdentityInfo{}, kes.ErrIdentityNotFound
+}
+
+func (adminIdentitySet) Delete(context.Context, kes.Identity) error { return kes.ErrNotAllowed }
+
+func (adminIdentitySet) List(context.Context) (IdentityIterator, error) {
+	return nil, kes.ErrNotAllowed
+}
+
+func signJWT(t *testing.T, key *ecdsa.PrivateKey, kid string, claims map[string]any) string {
+	header, err := json.Marshal(map[string]string{"alg": "ES256", "typ": "JWT", "kid": kid})
+	if err != nil {
+		t.Fatalf("Failed to encode JWT header: %v", err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("Failed to encode JWT claims: %v", err)
+	}
+	message := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	digest := sha256.Sum256([]byte(message))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatalf("Failed to sign JWT: %v", err)
+	}
+	signature := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	return message + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
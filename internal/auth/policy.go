@@ -11,7 +11,10 @@ import (
 	"encoding/gob"
 	"net/http"
 	"path"
+	"sort"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/minio/kes-go"
 )
@@ -135,22 +138,358 @@ func (p *Policy) UnmarshalBinary(b []byte) error {
 }
 
 // Verify reports whether the given HTTP request is allowed.
-// It returns no error if:
+// Patterns are matched against the URL path as described
+// by Match. It returns no error if:
 //
 //	(1) No deny pattern matches the URL path *AND*
 //	(2) At least one allow pattern matches the URL path.
 //
 // Otherwise, Verify returns ErrNotAllowed.
 func (p *Policy) Verify(r *http.Request) error {
+	if !p.allows(r.URL.Path) {
+		return kes.ErrNotAllowed
+	}
+	return nil
+}
+
+// allows reports whether the policy allows requests
+// to the given URL path.
+func (p *Policy) allows(urlPath string) bool {
 	for _, pattern := range p.Deny {
-		if ok, err := path.Match(pattern, r.URL.Path); ok && err == nil {
-			return kes.ErrNotAllowed
+		if Match(pattern, urlPath) {
+			return false
+		}
+	}
+	for _, pattern := range p.Allow {
+		if Match(pattern, urlPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match reports whether name matches the pattern. The
+// pattern syntax is the same as for path.Match. Hence,
+// a '*' matches any sequence of characters within one
+// path segment but not the '/' separator. In addition,
+// a '**' matches any sequence of characters, including
+// '/'. For example:
+//
+//	/v1/key/create/payments/*   matches /v1/key/create/payments/db-key
+//	                            but not /v1/key/create/payments/prod/db-key
+//	/v1/key/create/payments/**  matches both
+//
+// Match returns false if the pattern is malformed or if
+// matching the name would be too expensive.
+func Match(pattern, name string) bool {
+	// A pattern with '**' is matched segment-free in
+	// O(len(pattern) * len(name)) steps. Otherwise, a
+	// '**' could be expanded in exponentially many ways.
+	const MaxCost = 1 << 20
+
+	if !strings.Contains(pattern, "**") {
+		ok, err := path.Match(pattern, name)
+		return ok && err == nil
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return false // Malformed pattern
+	}
+
+	tokens, runes := matchTokens(pattern), []rune(name)
+	if len(tokens)*(len(runes)+1) > MaxCost {
+		return false
+	}
+
+	// matched[j] reports whether the pattern tokens processed
+	// so far match the first j runes of the name.
+	matched, next := make([]bool, len(runes)+1), make([]bool, len(runes)+1)
+	matched[0] = true
+	for _, token := range tokens {
+		switch token {
+		case "**":
+			next[0] = matched[0]
+			for j := 1; j <= len(runes); j++ {
+				next[j] = matched[j] || next[j-1]
+			}
+		case "*":
+			next[0] = matched[0]
+			for j := 1; j <= len(runes); j++ {
+				next[j] = matched[j] || next[j-1] && runes[j-1] != '/'
+			}
+		default:
+			next[0] = false
+			for j := 1; j <= len(runes); j++ {
+				next[j] = matched[j-1] && matchRune(token, runes[j-1])
+			}
+		}
+		matched, next = next, matched
+	}
+	return matched[len(runes)]
+}
+
+// matchTokens splits the well-formed pattern into tokens
+// that either match a single rune - a literal rune, a '?',
+// an escaped rune or a character class - or a sequence
+// of runes - a '*' or a '**'.
+func matchTokens(pattern string) []string {
+	var tokens []string
+	for i := 0; i < len(pattern); {
+		j := i + 1
+		switch pattern[i] {
+		case '*':
+			for j < len(pattern) && pattern[j] == '*' {
+				j++
+			}
+			if j-i > 1 {
+				tokens = append(tokens, "**")
+			} else {
+				tokens = append(tokens, "*")
+			}
+			i = j
+			continue
+		case '\\':
+			_, n := utf8.DecodeRuneInString(pattern[j:])
+			j += n
+		case '[':
+			if j < len(pattern) && pattern[j] == '^' {
+				j++
+			}
+			for pattern[j] != ']' {
+				if pattern[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			j++
+		default:
+			_, n := utf8.DecodeRuneInString(pattern[i:])
+			j = i + n
+		}
+		tokens = append(tokens, pattern[i:j])
+		i = j
+	}
+	return tokens
+}
+
+// matchRune reports whether the single-rune token
+// matches r.
+func matchRune(token string, r rune) bool {
+	if c, n := utf8.DecodeRuneInString(token); n == len(token) && c != '?' && c != '\\' && c != '[' {
+		return c == r
+	}
+	ok, _ := path.Match(token, string(r))
+	return ok
+}
+
+// PolicyDiff describes how access changes when one
+// policy gets replaced by another one.
+type PolicyDiff struct {
+	// Granted contains all API paths, or path patterns,
+	// that become accessible.
+	Granted []string
+
+	// Revoked contains all API paths, or path patterns,
+	// that become inaccessible.
+	Revoked []string
+}
+
+// Broadened reports whether the new policy grants
+// access to any API path not accessible before.
+func (d *PolicyDiff) Broadened() bool { return len(d.Granted) > 0 }
+
+// Diff compares the access granted by the old and the new
+// policy. An API path that ends with a '/', and hence takes
+// an argument, is compared for any argument.
+//
+// Since policies consist of glob patterns, Diff does not
+// compare all possible URL paths. Instead, it evaluates both
+// policies for the given API paths and for all patterns of
+// both policies, where a pattern is treated as literal path.
+// For example, replacing the allow pattern "/v1/key/create/my-*"
+// with "/v1/key/create/*" grants access to "/v1/key/create/*".
+//
+// For API paths that take an argument, Diff also compares
+// hierarchical arguments, like "/v1/key/create/*/**".
+func Diff(old, new *Policy, apiPaths []string) PolicyDiff {
+	candidates := make([]string, 0, 2*len(apiPaths)+len(old.Allow)+len(old.Deny)+len(new.Allow)+len(new.Deny))
+	for _, apiPath := range apiPaths {
+		if strings.HasSuffix(apiPath, "/") {
+			candidates = append(candidates, apiPath+"*/**")
+			apiPath += "*"
+		}
+		candidates = append(candidates, apiPath)
+	}
+	candidates = append(candidates, old.Allow...)
+	candidates = append(candidates, old.Deny...)
+	candidates = append(candidates, new.Allow...)
+	candidates = append(candidates, new.Deny...)
+	sort.Strings(candidates)
+
+	var (
+		diff PolicyDiff
+		prev string
+	)
+	for i, candidate := range candidates {
+		if i > 0 && candidate == prev {
+			continue
 		}
+		prev = candidate
+
+		switch before, after := old.allows(candidate), new.allows(candidate); {
+		case !before && after:
+			diff.Granted = append(diff.Granted, candidate)
+		case before && !after:
+			diff.Revoked = append(diff.Revoked, candidate)
+		}
+	}
+	return diff
+}
+
+// Equal reports whether p and q consist of the same allow
+// and deny rules. The order of rules and repeated rules
+// are ignored.
+func (p *Policy) Equal(q *Policy) bool { return ruleSet(p) == ruleSet(q) }
+
+// Duplicates returns the names of all policies that consist
+// of the same allow and deny rules as at least one other
+// policy, grouped by their rule set.
+//
+// The names within a group are sorted and the groups are
+// sorted by their first name. Policies without duplicates
+// are not returned.
+func Duplicates(policies map[string]Policy) [][]string {
+	groups := map[string][]string{}
+	for name, policy := range policies {
+		policy := policy
+		key := ruleSet(&policy)
+		groups[key] = append(groups[key], name)
+	}
+
+	var duplicates [][]string
+	for _, names := range groups {
+		if len(names) < 2 {
+			continue
+		}
+		sort.Strings(names)
+		duplicates = append(duplicates, names)
+	}
+	sort.Slice(duplicates, func(i, j int) bool { return duplicates[i][0] < duplicates[j][0] })
+	return duplicates
+}
+
+// ruleSet returns a canonical representation of the
+// policy's allow and deny rules.
+func ruleSet(p *Policy) string {
+	canonical := func(rules []string) []string {
+		rules = append(make([]string, 0, len(rules)), rules...)
+		sort.Strings(rules)
+
+		n := 0
+		for i, rule := range rules {
+			if i > 0 && rule == rules[n-1] {
+				continue
+			}
+			rules[n] = rule
+			n++
+		}
+		return rules[:n]
+	}
+
+	var s strings.Builder
+	for _, rule := range canonical(p.Allow) {
+		s.WriteString("allow\x00")
+		s.WriteString(rule)
+		s.WriteByte(0)
+	}
+	for _, rule := range canonical(p.Deny) {
+		s.WriteString("deny\x00")
+		s.WriteString(rule)
+		s.WriteByte(0)
+	}
+	return s.String()
+}
+
+// Access describes to which extent a policy grants
+// access to an API path.
+type Access int
+
+// All access levels.
+const (
+	// NoAccess indicates that a policy grants no access
+	// to an API path.
+	NoAccess Access = iota
+
+	// PartialAccess indicates that a policy grants access to
+	// an API path that takes an argument, like a key name,
+	// but only for some arguments. For example, the allow
+	// pattern "/v1/key/create/my-*" grants partial access
+	// to "/v1/key/create/".
+	PartialAccess
+
+	// FullAccess indicates that a policy grants access to
+	// an API path for any argument.
+	FullAccess
+)
+
+// String returns the Access' string representation.
+func (a Access) String() string {
+	switch a {
+	case NoAccess:
+		return "none"
+	case PartialAccess:
+		return "partial"
+	case FullAccess:
+		return "full"
+	default:
+		return "invalid"
+	}
+}
+
+// MarshalText returns the Access' text representation.
+func (a Access) MarshalText() ([]byte, error) { return []byte(a.String()), nil }
+
+// Access returns to which extent the policy grants access
+// to the given API path. An API path that ends with a '/',
+// and hence takes an argument, is evaluated for any argument.
+//
+// Like Diff, Access treats patterns as literal paths when
+// determining whether a pattern applies to some but not
+// all arguments of an API path.
+func (p *Policy) Access(apiPath string) Access {
+	if !strings.HasSuffix(apiPath, "/") {
+		if p.allows(apiPath) {
+			return FullAccess
+		}
+		return NoAccess
+	}
+
+	if p.allows(apiPath + "*") {
+		for _, pattern := range p.Deny {
+			if candidate, ok := instantiate(pattern, apiPath); ok && Match(pattern, candidate) {
+				return PartialAccess
+			}
+		}
+		return FullAccess
 	}
 	for _, pattern := range p.Allow {
-		if ok, err := path.Match(pattern, r.URL.Path); ok && err == nil {
-			return nil
+		if candidate, ok := instantiate(pattern, apiPath); ok && p.allows(candidate) {
+			return PartialAccess
 		}
 	}
-	return kes.ErrNotAllowed
+	return NoAccess
+}
+
+// instantiate returns the URL path of the API path, that ends
+// with a '/', for the argument specified by the pattern. For
+// example, the pattern "/v1/key/*/my-key" instantiates the API
+// path "/v1/key/create/" as "/v1/key/create/my-key".
+//
+// It reports whether the pattern specifies an argument.
+func instantiate(pattern, apiPath string) (string, bool) {
+	n := strings.Count(apiPath, "/")
+	segments := strings.SplitN(pattern, "/", n+1)
+	if len(segments) <= n || segments[n] == "" {
+		return "", false
+	}
+	return apiPath + segments[n], true
 }
@@ -0,0 +1,205 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var policyDiffTests = []struct {
+	Old, New  Policy
+	APIPaths  []string
+	Granted   []string
+	Revoked   []string
+	Broadened bool
+}{
+	{ // 0
+		Old: Policy{Allow: []string{"/v1/key/create/*"}},
+		New: Policy{Allow: []string{"/v1/key/create/*"}},
+	},
+	{ // 1
+		Old:       Policy{Allow: []string{"/v1/key/create/my-*"}},
+		New:       Policy{Allow: []string{"/v1/key/create/*"}},
+		Granted:   []string{"/v1/key/create/*"},
+		Broadened: true,
+	},
+	{ // 2
+		Old:     Policy{Allow: []string{"/v1/key/create/*"}},
+		New:     Policy{Allow: []string{"/v1/key/create/my-*"}},
+		Revoked: []string{"/v1/key/create/*"},
+	},
+	{ // 3
+		Old:       Policy{Allow: []string{"/v1/key/*/*"}, Deny: []string{"/v1/key/delete/*"}},
+		New:       Policy{Allow: []string{"/v1/key/*/*"}},
+		APIPaths:  []string{"/v1/key/create/", "/v1/key/delete/", "/v1/status"},
+		Granted:   []string{"/v1/key/delete/*"},
+		Broadened: true,
+	},
+	{ // 4
+		Old:       Policy{Allow: []string{"/v1/key/create/*"}},
+		New:       Policy{Allow: []string{"/v1/status"}},
+		APIPaths:  []string{"/v1/key/create/", "/v1/status"},
+		Granted:   []string{"/v1/status"},
+		Revoked:   []string{"/v1/key/create/*"},
+		Broadened: true,
+	},
+	{ // 5
+		Old:       Policy{Allow: []string{"/v1/key/create/*"}},
+		New:       Policy{Allow: []string{"/v1/key/create/**"}},
+		APIPaths:  []string{"/v1/key/create/"},
+		Granted:   []string{"/v1/key/create/*/**"},
+		Broadened: true,
+	},
+}
+
+func TestPolicyDiff(t *testing.T) {
+	for i, test := range policyDiffTests {
+		diff := Diff(&test.Old, &test.New, test.APIPaths)
+		if !reflect.DeepEqual(diff.Granted, test.Granted) {
+			t.Fatalf("Test %d: granted mismatch: got '%v' - want '%v'", i, diff.Granted, test.Granted)
+		}
+		if !reflect.DeepEqual(diff.Revoked, test.Revoked) {
+			t.Fatalf("Test %d: revoked mismatch: got '%v' - want '%v'", i, diff.Revoked, test.Revoked)
+		}
+		if diff.Broadened() != test.Broadened {
+			t.Fatalf("Test %d: got broadened '%v' - want '%v'", i, diff.Broadened(), test.Broadened)
+		}
+	}
+}
+
+var matchTests = []struct {
+	Pattern string
+	Name    string
+	Match   bool
+}{
+	{Pattern: "/v1/key/create/*", Name: "/v1/key/create/my-key", Match: true},                         // 0
+	{Pattern: "/v1/key/create/*", Name: "/v1/key/create/payments/db-key", Match: false},               // 1
+	{Pattern: "/v1/key/create/payments/*", Name: "/v1/key/create/payments/db-key", Match: true},       // 2
+	{Pattern: "/v1/key/create/payments/*", Name: "/v1/key/create/payments/prod/db-key", Match: false}, // 3
+	{Pattern: "/v1/key/create/payments/**", Name: "/v1/key/create/payments/prod/db-key", Match: true}, // 4
+	{Pattern: "/v1/key/create/**", Name: "/v1/key/create/my-key", Match: true},                        // 5
+	{Pattern: "/v1/key/*/payments/**", Name: "/v1/key/decrypt/payments/prod/db-key", Match: true},     // 6
+	{Pattern: "/v1/key/*/payments/**", Name: "/v1/key/decrypt/billing/prod/db-key", Match: false},     // 7
+	{Pattern: "/v1/key/**/db-key", Name: "/v1/key/create/payments/prod/db-key", Match: true},          // 8
+	{Pattern: "/v1/key/**/db-key", Name: "/v1/key/create/payments/prod/db-key2", Match: false},        // 9
+	{Pattern: "/v1/key/create/**-key", Name: "/v1/key/create/payments/db-key", Match: true},           // 10
+	{Pattern: "/v1/key/create/[", Name: "/v1/key/create/[", Match: false},                             // 11
+	{Pattern: "/v1/key/**/[", Name: "/v1/key/create/[", Match: false},                                 // 12
+	{Pattern: "/v1/key/**/[a-c]?-*", Name: "/v1/key/create/payments/db-key", Match: false},            // 13
+	{Pattern: "/v1/key/**/[a-d]?-*", Name: "/v1/key/create/payments/db-key", Match: true},             // 14
+	{Pattern: "/v1/key/**/\\*", Name: "/v1/key/create/*", Match: true},                                // 15
+	{Pattern: "/v1/key/**/\\*", Name: "/v1/key/create/my-key", Match: false},                          // 16
+	{Pattern: "/v1/key/**/*/**/db-key", Name: "/v1/key/create/payments/prod/db-key", Match: true},     // 17
+	{Pattern: "/v1/key/**/**/**/db-key", Name: "/v1/key/db-key", Match: false},                        // 18
+	{Pattern: "/v1/key/**/schlüssel", Name: "/v1/key/create/payments/schlüssel", Match: true},         // 19
+}
+
+func TestMatch(t *testing.T) {
+	for i, test := range matchTests {
+		if match := Match(test.Pattern, test.Name); match != test.Match {
+			t.Fatalf("Test %d: got '%v' - want '%v'", i, match, test.Match)
+		}
+	}
+}
+
+func TestMatchCost(t *testing.T) {
+	pattern := strings.Repeat("/**a", 8) + "/**b"
+	name := strings.Repeat("/a", 4096)
+
+	start := time.Now()
+	if Match(pattern, name) {
+		t.Fatalf("'%s' should not match", pattern)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("Matching a pattern with %d '**' took %v", strings.Count(pattern, "**"), d)
+	}
+	if !Match(pattern, name+"/b") {
+		t.Fatalf("'%s' should match", pattern)
+	}
+}
+
+func TestPolicyDuplicates(t *testing.T) {
+	policies := map[string]Policy{
+		"a": {Allow: []string{"/v1/key/create/*", "/v1/key/delete/*"}},
+		"b": {Allow: []string{"/v1/key/delete/*", "/v1/key/create/*", "/v1/key/create/*"}},
+		"c": {Allow: []string{"/v1/key/create/*", "/v1/key/delete/*"}, Deny: []string{"/v1/key/delete/my-key"}},
+		"d": {Allow: []string{"/v1/status"}},
+		"e": {Allow: []string{"/v1/status"}},
+		"f": {Deny: []string{"/v1/status"}},
+	}
+	want := [][]string{{"a", "b"}, {"d", "e"}}
+
+	if duplicates := Duplicates(policies); !reflect.DeepEqual(duplicates, want) {
+		t.Fatalf("Duplicates mismatch: got '%v' - want '%v'", duplicates, want)
+	}
+}
+
+var policyAccessTests = []struct {
+	Policy  Policy
+	APIPath string
+	Access  Access
+}{
+	{ // 0
+		Policy:  Policy{Allow: []string{"/v1/status"}},
+		APIPath: "/v1/status",
+		Access:  FullAccess,
+	},
+	{ // 1
+		Policy:  Policy{Allow: []string{"/v1/status"}},
+		APIPath: "/v1/metrics",
+		Access:  NoAccess,
+	},
+	{ // 2
+		Policy:  Policy{Allow: []string{"/v1/key/create/*"}},
+		APIPath: "/v1/key/create/",
+		Access:  FullAccess,
+	},
+	{ // 3
+		Policy:  Policy{Allow: []string{"/v1/key/create/my-*"}},
+		APIPath: "/v1/key/create/",
+		Access:  PartialAccess,
+	},
+	{ // 4
+		Policy:  Policy{Allow: []string{"/v1/key/*/my-key"}},
+		APIPath: "/v1/key/create/",
+		Access:  PartialAccess,
+	},
+	{ // 5
+		Policy:  Policy{Allow: []string{"/v1/key/*/my-key"}},
+		APIPath: "/v1/policy/read/",
+		Access:  NoAccess,
+	},
+	{ // 6
+		Policy:  Policy{Allow: []string{"/v1/**"}},
+		APIPath: "/v1/key/create/",
+		Access:  FullAccess,
+	},
+	{ // 7
+		Policy:  Policy{Allow: []string{"/v1/key/*/*"}, Deny: []string{"/v1/key/delete/*"}},
+		APIPath: "/v1/key/delete/",
+		Access:  NoAccess,
+	},
+	{ // 8
+		Policy:  Policy{Allow: []string{"/v1/key/*/*"}, Deny: []string{"/v1/key/*/prod-*"}},
+		APIPath: "/v1/key/delete/",
+		Access:  PartialAccess,
+	},
+	{ // 9
+		Policy:  Policy{Allow: []string{"/v1/key/create/my-*"}, Deny: []string{"/v1/key/create/my-*"}},
+		APIPath: "/v1/key/create/",
+		Access:  NoAccess,
+	},
+}
+
+func TestPolicyAccess(t *testing.T) {
+	for i, test := range policyAccessTests {
+		if access := test.Policy.Access(test.APIPath); access != test.Access {
+			t.Fatalf("Test %d: got access '%v' - want '%v'", i, access, test.Access)
+		}
+	}
+}
@@ -6,6 +6,7 @@ package auth
 
 import (
 	"context"
+	"crypto/tls"
 	"crypto/x509"
 	"encoding/pem"
 	"net"
@@ -46,6 +47,14 @@ type TLSProxy struct {
 	// If it is nil the client certificate won't be verified.
 	VerifyOptions *x509.VerifyOptions
 
+	// VerifyConnection, if not nil, is called with the verified
+	// certificate chains of the actual kes client - e.g. to check
+	// whether the client certificate has been revoked. If it
+	// returns an error, the request is rejected.
+	//
+	// It is only called if VerifyOptions is not nil.
+	VerifyConnection func(tls.ConnectionState) error
+
 	lock       sync.RWMutex
 	identities map[kes.Identity]bool
 }
@@ -128,6 +137,13 @@ func (p *TLSProxy) Verify(req *http.Request) error {
 	}
 
 	if len(peerCertificates) == 0 {
+		// A client without a certificate may authenticate
+		// with an API key or JWT. Such a request has not been
+		// sent by a TLS proxy since a proxy always has to
+		// present its client certificate.
+		if _, ok := bearerToken(req); ok {
+			return nil
+		}
 		return kes.NewError(http.StatusBadRequest, "no client certificate is present")
 	}
 	if len(peerCertificates) > 1 {
@@ -165,6 +181,12 @@ func (p *TLSProxy) Verify(req *http.Request) error {
 				// message here. For new we can just return 403 forbidden.
 				return kes.NewError(http.StatusForbidden, "")
 			}
+			if p.VerifyConnection != nil {
+				state := tls.ConnectionState{PeerCertificates: req.TLS.PeerCertificates, VerifiedChains: req.TLS.VerifiedChains}
+				if err = p.VerifyConnection(state); err != nil {
+					return kes.NewError(http.StatusForbidden, "")
+				}
+			}
 		}
 
 		// We also propagate the client remote address if the proxy
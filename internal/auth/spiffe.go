@@ -0,0 +1,87 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"crypto/x509"
+	"net/http"
+	"strings"
+
+	"github.com/minio/kes-go"
+)
+
+// SPIFFE maps client certificates with a SPIFFE ID to
+// identities. The identity of such a client certificate
+// is its SPIFFE ID, e.g. "spiffe://example.org/minio",
+// instead of the hash of its public key.
+//
+// Policies can be assigned to SPIFFE IDs such that
+// certificate rotation, for example by SPIRE, does not
+// change the client's identity.
+type SPIFFE struct {
+	// TrustDomains is the list of SPIFFE trust domains,
+	// like "example.org". Only SPIFFE IDs of these trust
+	// domains are mapped to identities.
+	TrustDomains []string
+}
+
+// Identity returns the SPIFFE ID of the request's client
+// certificate as identity. It reports whether the client
+// certificate contains a SPIFFE ID of any trust domain.
+func (s *SPIFFE) Identity(req *http.Request) (kes.Identity, bool) {
+	if req.TLS == nil {
+		return "", false
+	}
+
+	var cert *x509.Certificate
+	for _, c := range req.TLS.PeerCertificates {
+		if c.IsCA {
+			continue
+		}
+		if cert != nil {
+			return "", false // Ambiguous client certificates
+		}
+		cert = c
+	}
+	if cert == nil {
+		return "", false
+	}
+
+	id, ok := SPIFFEID(cert)
+	if !ok {
+		return "", false
+	}
+	for _, domain := range s.TrustDomains {
+		if strings.EqualFold(strings.TrimPrefix(domain, "spiffe://"), trustDomain(id)) {
+			return kes.Identity(id), true
+		}
+	}
+	return "", false
+}
+
+// SPIFFEID returns the SPIFFE ID of the certificate, if any.
+//
+// A X.509-SVID contains exactly one URI SAN with the
+// "spiffe" scheme. Certificates with multiple URI SANs
+// are not considered X.509-SVIDs.
+func SPIFFEID(cert *x509.Certificate) (string, bool) {
+	if len(cert.URIs) != 1 {
+		return "", false
+	}
+	uri := cert.URIs[0]
+	if !strings.EqualFold(uri.Scheme, "spiffe") || uri.Host == "" || uri.User != nil || uri.RawQuery != "" || uri.Fragment != "" {
+		return "", false
+	}
+	return uri.String(), true
+}
+
+// trustDomain returns the trust domain of the SPIFFE ID.
+func trustDomain(id string) string {
+	domain := strings.TrimPrefix(id, "spiffe://")
+	if i := strings.IndexByte(domain, '/'); i >= 0 {
+		domain = domain[:i]
+	}
+	return domain
+}
@@ -0,0 +1,53 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/minio/kes-go"
+)
+
+var spiffeIdentityTests = []struct {
+	URIs     []string
+	Identity kes.Identity
+}{
+	{URIs: nil, Identity: ""}, // 0
+	{URIs: []string{"spiffe://example.org/ns/default/sa/minio"}, Identity: "spiffe://example.org/ns/default/sa/minio"}, // 1
+	{URIs: []string{"spiffe://EXAMPLE.org/minio"}, Identity: "spiffe://EXAMPLE.org/minio"},                             // 2
+	{URIs: []string{"spiffe://other.org/minio"}, Identity: ""},                                                         // 3
+	{URIs: []string{"https://example.org/minio"}, Identity: ""},                                                        // 4
+	{URIs: []string{"spiffe://example.org/a", "spiffe://example.org/b"}, Identity: ""},                                 // 5
+	{URIs: []string{"spiffe://example.org.evil.com/minio"}, Identity: ""},                                              // 6
+}
+
+func TestSPIFFEIdentity(t *testing.T) {
+	spiffe := &SPIFFE{TrustDomains: []string{"example.org"}}
+	for i, test := range spiffeIdentityTests {
+		cert := new(x509.Certificate)
+		for _, uri := range test.URIs {
+			u, err := url.Parse(uri)
+			if err != nil {
+				t.Fatalf("Test %d: failed to parse URI: %v", i, err)
+			}
+			cert.URIs = append(cert.URIs, u)
+		}
+		req := &http.Request{
+			TLS: &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}},
+		}
+
+		identity, ok := spiffe.Identity(req)
+		if ok != (test.Identity != "") {
+			t.Fatalf("Test %d: got '%v' - want '%v'", i, ok, test.Identity != "")
+		}
+		if identity != test.Identity {
+			t.Fatalf("Test %d: identity mismatch: got '%v' - want '%v'", i, identity, test.Identity)
+		}
+	}
+}
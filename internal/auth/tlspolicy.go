@@ -0,0 +1,135 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/minio/kes-go"
+)
+
+// DefaultCurves are the elliptic curves accepted for client
+// certificate keys if a ClientTLSPolicy specifies no curves.
+var DefaultCurves = []string{"P-256", "P-384", "P-521", "Ed25519"}
+
+// A ClientTLSPolicy specifies the minimum TLS version and
+// client certificate key strength required from clients.
+//
+// Requests that violate the policy are marked as such and
+// recorded in the audit log. If the policy is enforced,
+// VerifyRequest rejects such requests. Otherwise, the policy
+// only reports violations such that operators can identify
+// offending clients before enforcing the policy.
+type ClientTLSPolicy struct {
+	// MinVersion is the minimum TLS version, e.g.
+	// tls.VersionTLS13. If zero, any TLS version
+	// supported by the server is accepted.
+	MinVersion uint16
+
+	// MinRSAKeySize is the minimum size of RSA client
+	// certificate keys in bits. If zero, RSA keys of
+	// any size are accepted.
+	MinRSAKeySize int
+
+	// Curves is the list of accepted elliptic curves for
+	// ECDSA and EdDSA client certificate keys, like "P-256"
+	// or "Ed25519". If empty, DefaultCurves are accepted.
+	Curves []string
+
+	// Enforce controls whether requests that violate the
+	// policy are rejected or only reported.
+	Enforce bool
+}
+
+// Check returns a description of how the request violates
+// the policy. It returns an empty string if the request
+// complies with the policy.
+func (p *ClientTLSPolicy) Check(req *http.Request) string {
+	if req.TLS == nil {
+		return ""
+	}
+	if p.MinVersion != 0 && req.TLS.Version < p.MinVersion {
+		return fmt.Sprintf("%s is below the minimum version %s", tls.VersionName(req.TLS.Version), tls.VersionName(p.MinVersion))
+	}
+
+	curves := p.Curves
+	if len(curves) == 0 {
+		curves = DefaultCurves
+	}
+	for _, cert := range req.TLS.PeerCertificates {
+		if cert.IsCA {
+			continue
+		}
+		switch key := cert.PublicKey.(type) {
+		case *rsa.PublicKey:
+			if size := key.N.BitLen(); size < p.MinRSAKeySize {
+				return fmt.Sprintf("client certificate RSA key size %d is below the minimum size %d", size, p.MinRSAKeySize)
+			}
+		case *ecdsa.PublicKey:
+			if curve := key.Curve.Params().Name; !containsFold(curves, curve) {
+				return fmt.Sprintf("client certificate curve %s is not accepted", curve)
+			}
+		case ed25519.PublicKey:
+			if !containsFold(curves, "Ed25519") {
+				return "client certificate curve Ed25519 is not accepted"
+			}
+		default:
+			return fmt.Sprintf("client certificate key type %T is not accepted", key)
+		}
+	}
+	return ""
+}
+
+type tlsViolationContextKey struct{}
+
+type tlsViolation struct {
+	Reason  string
+	Enforce bool
+}
+
+// MarkTLSViolation returns a shallow copy of req that is
+// marked as violating a ClientTLSPolicy for the given reason.
+// If enforce is true, VerifyRequest rejects the request.
+func MarkTLSViolation(req *http.Request, reason string, enforce bool) *http.Request {
+	ctx := context.WithValue(req.Context(), tlsViolationContextKey{}, tlsViolation{
+		Reason:  reason,
+		Enforce: enforce,
+	})
+	return req.WithContext(ctx)
+}
+
+// TLSViolation returns the reason why the request violates
+// a ClientTLSPolicy, if any. It reports whether the request
+// has been marked as violating a policy.
+func TLSViolation(req *http.Request) (string, bool) {
+	v, ok := req.Context().Value(tlsViolationContextKey{}).(tlsViolation)
+	return v.Reason, ok
+}
+
+// verifyTLSViolation returns an error if the request has
+// been marked as violating an enforced ClientTLSPolicy.
+func verifyTLSViolation(req *http.Request) error {
+	v, ok := req.Context().Value(tlsViolationContextKey{}).(tlsViolation)
+	if !ok || !v.Enforce {
+		return nil
+	}
+	return kes.NewError(http.StatusForbidden, "client TLS rejected: "+v.Reason)
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
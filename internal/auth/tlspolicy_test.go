@@ -0,0 +1,89 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/minio/kes-go"
+)
+
+func TestClientTLSPolicy(t *testing.T) {
+	rsa1024, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	p224, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate ECDSA key: %v", err)
+	}
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate ECDSA key: %v", err)
+	}
+	ed, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate Ed25519 key: %v", err)
+	}
+
+	tests := []struct {
+		Policy    ClientTLSPolicy
+		Version   uint16
+		Key       crypto.PublicKey
+		Violation bool
+	}{
+		{Policy: ClientTLSPolicy{}, Version: tls.VersionTLS12, Key: rsa1024.Public(), Violation: false},                           // 0
+		{Policy: ClientTLSPolicy{MinRSAKeySize: 2048}, Version: tls.VersionTLS12, Key: rsa1024.Public(), Violation: true},         // 1
+		{Policy: ClientTLSPolicy{MinRSAKeySize: 1024}, Version: tls.VersionTLS12, Key: rsa1024.Public(), Violation: false},        // 2
+		{Policy: ClientTLSPolicy{MinVersion: tls.VersionTLS13}, Version: tls.VersionTLS12, Key: p256.Public(), Violation: true},   // 3
+		{Policy: ClientTLSPolicy{MinVersion: tls.VersionTLS13}, Version: tls.VersionTLS13, Key: p256.Public(), Violation: false},  // 4
+		{Policy: ClientTLSPolicy{}, Version: tls.VersionTLS13, Key: p224.Public(), Violation: true},                               // 5
+		{Policy: ClientTLSPolicy{Curves: []string{"P-384"}}, Version: tls.VersionTLS13, Key: p256.Public(), Violation: true},      // 6
+		{Policy: ClientTLSPolicy{Curves: []string{"p-256"}}, Version: tls.VersionTLS13, Key: p256.Public(), Violation: false},     // 7
+		{Policy: ClientTLSPolicy{}, Version: tls.VersionTLS13, Key: ed, Violation: false},                                         // 8
+		{Policy: ClientTLSPolicy{Curves: []string{"P-256"}}, Version: tls.VersionTLS13, Key: ed, Violation: true},                 // 9
+		{Policy: ClientTLSPolicy{MinVersion: tls.VersionTLS13, MinRSAKeySize: 2048}, Version: tls.VersionTLS13, Violation: false}, // 10
+	}
+	for i, test := range tests {
+		state := &tls.ConnectionState{Version: test.Version}
+		if test.Key != nil {
+			state.PeerCertificates = []*x509.Certificate{{PublicKey: test.Key}}
+		}
+		req := &http.Request{TLS: state}
+
+		if violation := test.Policy.Check(req); (violation != "") != test.Violation {
+			t.Fatalf("Test %d: got violation '%s' - want violation '%v'", i, violation, test.Violation)
+		}
+	}
+}
+
+func TestVerifyTLSViolation(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "https://127.0.0.1:7373/version", nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	if err = verifyTLSViolation(req); err != nil {
+		t.Fatalf("Request without violation has been rejected: %v", err)
+	}
+	if err = verifyTLSViolation(MarkTLSViolation(req, "TLS 1.2 is below the minimum version TLS 1.3", false)); err != nil {
+		t.Fatalf("Reported violation has been rejected: %v", err)
+	}
+
+	err = verifyTLSViolation(MarkTLSViolation(req, "TLS 1.2 is below the minimum version TLS 1.3", true))
+	var kErr kes.Error
+	if !errors.As(err, &kErr) || kErr.Status() != http.StatusForbidden {
+		t.Fatalf("Enforced violation has not been rejected: got '%v'", err)
+	}
+}
@@ -4,7 +4,7 @@ import (
 	"fmt"
 	"strings"
 
-	tui "github.com/charmbracelet/lipgloss"
+	"github.com/minio/kes/internal/tui"
 )
 
 // A Buffer is used to efficiently build a string
@@ -8,27 +8,40 @@ import (
 	"fmt"
 	"os"
 
-	tui "github.com/charmbracelet/lipgloss"
+	"github.com/minio/kes/internal/tui"
 )
 
 var errPrefix = tui.NewStyle().Foreground(tui.Color("#ac0000")).Render("Error: ")
 
+// Exit terminates the program with the given status
+// code. By default, Exit is os.Exit. An interactive
+// shell may replace it to keep running once a single
+// command has failed.
+var Exit = os.Exit
+
 // Fatal writes an error prefix and the operands
 // to OS stderr. Then, Fatal terminates the program by
-// calling os.Exit(1).
+// calling Exit(1).
 func Fatal(v ...any) {
 	fmt.Fprint(os.Stderr, errPrefix)
 	fmt.Fprint(os.Stderr, v...)
 	fmt.Fprintln(os.Stderr)
-	os.Exit(1)
+	Exit(1)
 }
 
 // Fatalf writes an error prefix and the operands,
 // formated according to the format specifier, to OS stderr.
-// Then, Fatalf terminates the program by calling os.Exit(1).
+// Then, Fatalf terminates the program by calling Exit(1).
 func Fatalf(format string, v ...any) {
 	fmt.Fprintf(os.Stderr, errPrefix+format+"\n", v...)
-	os.Exit(1)
+	Exit(1)
+}
+
+// Errorf writes an error prefix and the operands,
+// formated according to the format specifier, to OS stderr.
+// Unlike Fatalf, it does not terminate the program.
+func Errorf(format string, v ...any) {
+	fmt.Fprintf(os.Stderr, errPrefix+format+"\n", v...)
 }
 
 // Print formats using the default formats for its operands and
@@ -21,6 +21,8 @@ type InitConfig struct {
 
 	Address yml.String `yaml:"address"`
 
+	Compression yml.String `yaml:"compression"`
+
 	System struct {
 		Admin struct {
 			Identity yml.Identity `yaml:"identity"`
@@ -41,9 +43,22 @@ type InitConfig struct {
 
 		Client struct {
 			VerifyCerts yml.Bool `yaml:"verify_cert"`
+			APIKeys     yml.Bool `yaml:"api_key"`
 		} `yaml:"client"`
+
+		SNI map[string]struct {
+			Enclave     yml.String `yaml:"enclave"`
+			PrivateKey  yml.String `yaml:"key"`
+			Certificate yml.String `yaml:"cert"`
+			Password    yml.String `yaml:"password"`
+		} `yaml:"sni"`
 	} `yaml:"tls"`
 
+	Receipts struct {
+		PrivateKey yml.String `yaml:"key"`
+		Password   yml.String `yaml:"password"`
+	} `yaml:"receipts"`
+
 	Unseal struct {
 		Environment struct {
 			Name string `yaml:"name"`
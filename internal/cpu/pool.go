@@ -0,0 +1,152 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package cpu
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/minio/kes-go"
+)
+
+// ErrPoolBusy is returned by a Pool when an operation cannot
+// be queued since too many operations are waiting already.
+var ErrPoolBusy = kes.NewError(http.StatusServiceUnavailable, "server busy: too many concurrent crypto operations")
+
+// NewPool returns a new Pool that runs at most workers
+// operations concurrently. At most queue operations wait
+// for a worker to become available. If queue is zero,
+// the number of waiting operations is not limited.
+//
+// If workers <= 0, NewPool returns nil. A nil Pool
+// does not limit the number of concurrent operations.
+func NewPool(workers, queue int) *Pool {
+	if workers <= 0 {
+		return nil
+	}
+	if queue < 0 {
+		queue = 0
+	}
+	return &Pool{
+		workers: make(chan struct{}, workers),
+		queue:   int64(queue),
+	}
+}
+
+// A Pool limits the number of operations, like
+// AEAD encryption or decryption, that run concurrently.
+//
+// Limiting the number of concurrent operations bounds
+// the amount of memory and CPU time the operations can
+// consume at the same time. Operations that cannot run
+// immediately are queued until a worker becomes available.
+type Pool struct {
+	workers chan struct{}
+	queue   int64
+
+	active   atomic.Int64
+	queued   atomic.Int64
+	rejected atomic.Uint64
+	waited   atomic.Int64 // Total wait time in nanoseconds
+}
+
+// Workers returns the max. number of concurrent operations.
+// It returns 0 if the Pool does not limit concurrency.
+func (p *Pool) Workers() int {
+	if p == nil {
+		return 0
+	}
+	return cap(p.workers)
+}
+
+// Active returns the number of operations currently running.
+func (p *Pool) Active() int {
+	if p == nil {
+		return 0
+	}
+	return int(p.active.Load())
+}
+
+// Queued returns the number of operations currently waiting
+// for a worker.
+func (p *Pool) Queued() int {
+	if p == nil {
+		return 0
+	}
+	return int(p.queued.Load())
+}
+
+// Rejected returns the number of operations that have been
+// rejected since the queue was full.
+func (p *Pool) Rejected() uint64 {
+	if p == nil {
+		return 0
+	}
+	return p.rejected.Load()
+}
+
+// WaitTime returns the total time operations have been
+// waiting for a worker.
+func (p *Pool) WaitTime() time.Duration {
+	if p == nil {
+		return 0
+	}
+	return time.Duration(p.waited.Load())
+}
+
+// Do waits until a worker becomes available and runs f.
+//
+// It returns ErrPoolBusy if the queue is full and the
+// ctx error if ctx is done before a worker becomes
+// available. Otherwise, it returns the error returned
+// by f, if any.
+func (p *Pool) Do(ctx context.Context, f func() error) error {
+	if p == nil {
+		return f()
+	}
+
+	select {
+	case p.workers <- struct{}{}:
+	default:
+		if n := p.queued.Add(1); p.queue > 0 && n > p.queue {
+			p.queued.Add(-1)
+			p.rejected.Add(1)
+			return ErrPoolBusy
+		}
+
+		start := time.Now()
+		select {
+		case p.workers <- struct{}{}:
+			p.queued.Add(-1)
+			p.waited.Add(int64(time.Since(start)))
+		case <-ctx.Done():
+			p.queued.Add(-1)
+			p.waited.Add(int64(time.Since(start)))
+			return ctx.Err()
+		}
+	}
+
+	p.active.Add(1)
+	defer func() {
+		p.active.Add(-1)
+		<-p.workers
+	}()
+	return f()
+}
+
+// VDo waits until a worker of the Pool p becomes available
+// and runs f. It returns the value and error returned by f.
+//
+// Refer to Pool.Do for more information.
+func VDo[V any](ctx context.Context, p *Pool, f func() (V, error)) (V, error) {
+	var v V
+	err := p.Do(ctx, func() (err error) {
+		v, err = f()
+		return err
+	})
+	return v, err
+}
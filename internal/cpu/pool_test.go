@@ -0,0 +1,84 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package cpu
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPool(t *testing.T) {
+	pool := NewPool(1, 1)
+
+	var (
+		running = make(chan struct{})
+		release = make(chan struct{})
+		done    = make(chan error, 2)
+	)
+	go func() {
+		done <- pool.Do(context.Background(), func() error {
+			close(running)
+			<-release
+			return nil
+		})
+	}()
+	<-running
+
+	// The single worker is busy. Hence, the next operation
+	// gets queued and the one after that gets rejected.
+	go func() { done <- pool.Do(context.Background(), func() error { return nil }) }()
+	for pool.Queued() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	if err := pool.Do(context.Background(), func() error { return nil }); !errors.Is(err, ErrPoolBusy) {
+		t.Fatalf("Queue is full: got err '%v' - want '%v'", err, ErrPoolBusy)
+	}
+	if n := pool.Rejected(); n != 1 {
+		t.Fatalf("Invalid number of rejected operations: got '%d' - want '%d'", n, 1)
+	}
+
+	close(release)
+	for i := 0; i < 2; i++ {
+		if err := <-done; err != nil {
+			t.Fatalf("Operation failed: %v", err)
+		}
+	}
+	if pool.Active() != 0 || pool.Queued() != 0 {
+		t.Fatalf("Pool is not idle: %d active - %d queued", pool.Active(), pool.Queued())
+	}
+}
+
+func TestPoolCanceled(t *testing.T) {
+	pool := NewPool(1, 0)
+
+	release := make(chan struct{})
+	defer close(release)
+	go pool.Do(context.Background(), func() error {
+		<-release
+		return nil
+	})
+	for pool.Active() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := pool.Do(ctx, func() error { return nil }); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Operation did not time out: got err '%v' - want '%v'", err, context.DeadlineExceeded)
+	}
+}
+
+func TestNilPool(t *testing.T) {
+	var pool *Pool
+	if pool != NewPool(0, 0) {
+		t.Fatal("Pool without workers is not nil")
+	}
+	v, err := VDo(context.Background(), pool, func() (int, error) { return 1, nil })
+	if err != nil || v != 1 {
+		t.Fatalf("Operation failed: got '%d' - '%v'", v, err)
+	}
+}
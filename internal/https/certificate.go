@@ -2,6 +2,7 @@ package https
 
 import (
 	"bytes"
+	"crypto"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/pem"
@@ -19,11 +20,59 @@ import (
 // it is vulnerable to padding oracle attacks that can let an attacker recover
 // the plaintext.
 func CertificateFromFile(certFile, keyFile, password string) (tls.Certificate, error) {
-	certBytes, err := readCertificate(certFile)
+	certBytes, err := os.ReadFile(certFile)
 	if err != nil {
 		return tls.Certificate{}, err
 	}
-	keyBytes, err := readPrivateKey(keyFile, password)
+	keyBytes, err := os.ReadFile(keyFile)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return CertificateFromPEM(certBytes, keyBytes, password)
+}
+
+// PrivateKeyFromFile reads and parses the PEM-encoded private key
+// from the given keyFile. The private key must be either a PKCS #8,
+// PKCS #1 RSA or SEC 1 EC private key.
+//
+// If the private key is an encrypted PEM block, it uses the given
+// password to decrypt the private key.
+func PrivateKeyFromFile(keyFile, password string) (crypto.Signer, error) {
+	keyPEM, err := readPrivateKey(keyFile, password)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("https: no PEM-encoded private key found")
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		if signer, ok := key.(crypto.Signer); ok {
+			return signer, nil
+		}
+		return nil, errors.New("https: unsupported private key type")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return nil, errors.New("https: failed to parse private key")
+}
+
+// CertificateFromPEM parses the PEM-encoded private key and
+// X.509 certificate, for example, retrieved from a Kubernetes
+// TLS secret.
+//
+// If the private key is an encrypted PEM block, it uses the
+// given password to decrypt the private key.
+func CertificateFromPEM(certPEM, keyPEM []byte, password string) (tls.Certificate, error) {
+	certBytes, err := FilterPEM(certPEM, func(b *pem.Block) bool { return b.Type == "CERTIFICATE" })
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	keyBytes, err := decodePrivateKey(keyPEM, password)
 	if err != nil {
 		return tls.Certificate{}, err
 	}
@@ -80,7 +129,16 @@ func readPrivateKey(keyFile, password string) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	pemBlock, err = FilterPEM(pemBlock, func(b *pem.Block) bool {
+	return decodePrivateKey(pemBlock, password)
+}
+
+// decodePrivateKey returns the first PEM-encoded private
+// key within the given PEM blocks.
+//
+// It decrypts the private key using the given password
+// if the private key is an encrypted PEM block.
+func decodePrivateKey(pemBlock []byte, password string) ([]byte, error) {
+	pemBlock, err := FilterPEM(pemBlock, func(b *pem.Block) bool {
 		return b.Type == "CERTIFICATE" || b.Type == "PRIVATE KEY" || strings.HasSuffix(b.Type, " PRIVATE KEY")
 	})
 	if err != nil {
@@ -32,6 +32,18 @@ func TestReadPrivateKey(t *testing.T) {
 	}
 }
 
+func TestPrivateKeyFromFile(t *testing.T) {
+	for i, test := range readPrivateKeyTests {
+		_, err := PrivateKeyFromFile(test.FilePath, test.Password)
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to load private key %q: %v", i, test.FilePath, err)
+		}
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: loading private key %q should have failed", i, test.FilePath)
+		}
+	}
+}
+
 var readCertificateTests = []struct {
 	FilePath   string
 	ShouldFail bool
@@ -24,35 +24,52 @@ import (
 // It returns the first error it encounters, if any, when parsing
 // a X.509 certificate file.
 func CertPoolFromFile(filename string) (*x509.CertPool, error) {
-	stat, err := os.Stat(filename)
-	if err != nil {
-		return nil, err
-	}
+	return CertPoolFromFiles(filename)
+}
 
+// CertPoolFromFiles returns a X.509 certificate pool that contains
+// all system root certificates from x509.SystemCertPool and the
+// certificates loaded from the given files or directories.
+//
+// It returns the first error it encounters, if any, when parsing
+// a X.509 certificate file.
+func CertPoolFromFiles(filenames ...string) (*x509.CertPool, error) {
 	pool, _ := x509.SystemCertPool()
 	if pool == nil {
 		pool = x509.NewCertPool()
 	}
-	if !stat.IsDir() {
-		if err = appendCertificate(pool, filename); err != nil {
+	for _, filename := range filenames {
+		if err := appendCertificates(pool, filename); err != nil {
 			return nil, err
 		}
-		return pool, nil
+	}
+	return pool, nil
+}
+
+// appendCertificates adds the certificate filename or, if filename
+// is a directory, all certificates within filename to the pool.
+func appendCertificates(pool *x509.CertPool, filename string) error {
+	stat, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+	if !stat.IsDir() {
+		return appendCertificate(pool, filename)
 	}
 
 	files, err := os.ReadDir(filename)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	for _, file := range files {
 		if file.IsDir() {
 			continue
 		}
 		if err = appendCertificate(pool, filepath.Join(filename, file.Name())); err != nil {
-			return nil, err
+			return err
 		}
 	}
-	return pool, nil
+	return nil
 }
 
 // appendCertificate parses the given file as X.509
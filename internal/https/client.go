@@ -0,0 +1,89 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package https
+
+import (
+	"crypto/tls"
+	"os"
+	"sync"
+	"time"
+)
+
+// ClientCertificate returns a function that can be used as
+// tls.Config.GetClientCertificate. It loads the client
+// certificate from the certFile and the private key from
+// the keyFile.
+//
+// The returned function reloads the certificate and the
+// private key whenever one of the files has been modified.
+// Hence, a rotated client certificate is used for all new
+// TLS connections without restarting the client. If the
+// rotated certificate cannot be loaded, for example since
+// only one of the two files has been replaced yet, the
+// previous certificate is used until the next attempt
+// succeeds.
+//
+// It returns an error if the initial certificate cannot
+// be loaded.
+func ClientCertificate(certFile, keyFile string) (func(*tls.CertificateRequestInfo) (*tls.Certificate, error), error) {
+	r := &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+	certModTime, keyModTime, err := r.modTime()
+	if err != nil {
+		return nil, err
+	}
+	certificate, err := CertificateFromFile(certFile, keyFile, "")
+	if err != nil {
+		return nil, err
+	}
+	r.certificate = &certificate
+	r.certModTime, r.keyModTime = certModTime, keyModTime
+	return r.GetClientCertificate, nil
+}
+
+// certReloader reloads a TLS certificate once its
+// certificate or private key file has changed.
+type certReloader struct {
+	certFile, keyFile string
+
+	lock        sync.Mutex
+	certificate *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+}
+
+// GetClientCertificate returns the current client certificate.
+func (r *certReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	certModTime, keyModTime, err := r.modTime()
+	if err != nil || (certModTime.Equal(r.certModTime) && keyModTime.Equal(r.keyModTime)) {
+		return r.certificate, nil
+	}
+	certificate, err := CertificateFromFile(r.certFile, r.keyFile, "")
+	if err != nil {
+		return r.certificate, nil
+	}
+	r.certificate = &certificate
+	r.certModTime, r.keyModTime = certModTime, keyModTime
+	return r.certificate, nil
+}
+
+// modTime returns the modification times of the
+// certificate and private key file.
+func (r *certReloader) modTime() (certModTime, keyModTime time.Time, err error) {
+	certStat, err := os.Stat(r.certFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	keyStat, err := os.Stat(r.keyFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return certStat.ModTime(), keyStat.ModTime(), nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package https
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestClientCertificate(t *testing.T) {
+	var (
+		dir      = t.TempDir()
+		certFile = filepath.Join(dir, "client.crt")
+		keyFile  = filepath.Join(dir, "client.key")
+	)
+	oldCert := writeCertificate(t, certFile, keyFile, "old", time.Now().Add(-time.Hour))
+
+	getClientCertificate, err := ClientCertificate(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("Failed to load client certificate: %v", err)
+	}
+	cert, err := getClientCertificate(nil)
+	if err != nil {
+		t.Fatalf("Failed to get client certificate: %v", err)
+	}
+	if !bytes.Equal(cert.Certificate[0], oldCert) {
+		t.Fatal("Client certificate does not match initial certificate")
+	}
+
+	newCert := writeCertificate(t, certFile, keyFile, "new", time.Now())
+	if cert, err = getClientCertificate(nil); err != nil {
+		t.Fatalf("Failed to get client certificate: %v", err)
+	}
+	if !bytes.Equal(cert.Certificate[0], newCert) {
+		t.Fatal("Client certificate has not been reloaded")
+	}
+
+	// A broken certificate file must not replace
+	// the current certificate.
+	if err = os.WriteFile(certFile, []byte("invalid"), 0o600); err != nil {
+		t.Fatalf("Failed to write certificate: %v", err)
+	}
+	if err = os.Chtimes(certFile, time.Now().Add(time.Hour), time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Failed to change modification time: %v", err)
+	}
+	if cert, err = getClientCertificate(nil); err != nil {
+		t.Fatalf("Failed to get client certificate: %v", err)
+	}
+	if !bytes.Equal(cert.Certificate[0], newCert) {
+		t.Fatal("Client certificate has been replaced by invalid certificate")
+	}
+}
+
+// writeCertificate writes a new self-signed certificate and
+// its private key to the given files, sets their modification
+// time and returns the DER-encoded certificate.
+func writeCertificate(t *testing.T, certFile, keyFile, commonName string, modTime time.Time) []byte {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate private key: %v", err)
+	}
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, &template, &template, privateKey.Public(), privateKey)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	privPKCS8, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatalf("Failed to encode private key: %v", err)
+	}
+
+	if err = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}), 0o600); err != nil {
+		t.Fatalf("Failed to write certificate: %v", err)
+	}
+	if err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privPKCS8}), 0o600); err != nil {
+		t.Fatalf("Failed to write private key: %v", err)
+	}
+	for _, file := range []string{certFile, keyFile} {
+		if err = os.Chtimes(file, modTime, modTime); err != nil {
+			t.Fatalf("Failed to change modification time: %v", err)
+		}
+	}
+	return cert
+}
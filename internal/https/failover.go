@@ -0,0 +1,231 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package https
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ParseEndpoints parses s as comma-separated list of server
+// endpoints, like "https://kes-1:7373,https://kes-2:7373".
+// Endpoints without a scheme default to HTTPS. Whitespaces
+// and empty list entries are ignored.
+func ParseEndpoints(s string) []string {
+	var endpoints []string
+	for _, endpoint := range strings.Split(s, ",") {
+		endpoint = strings.TrimSpace(endpoint)
+		if endpoint == "" {
+			continue
+		}
+		if !strings.Contains(endpoint, "://") {
+			endpoint = "https://" + endpoint
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints
+}
+
+// FailoverTransport is an http.RoundTripper that sends requests
+// to one of multiple, equivalent server endpoints.
+//
+// It health-checks all endpoints and prefers the one that
+// responds fastest. If an endpoint cannot be reached, it
+// transparently sends the request to the next endpoint. Only
+// requests that are sent to one of its endpoints are handled.
+// All other requests are passed to the underlying transport
+// as they are.
+//
+// Requests with a body can only be sent to another endpoint
+// if the body can be obtained again. See http.Request.GetBody.
+type FailoverTransport struct {
+	// Transport is the underlying http.RoundTripper.
+	// If nil, http.DefaultTransport is used.
+	Transport http.RoundTripper
+
+	// Endpoints are the server endpoints, like
+	// https://127.0.0.1:7373.
+	Endpoints []string
+
+	// ProbePath is the API path used to health-check the
+	// endpoints. Any response, even an error response,
+	// indicates that an endpoint is reachable. If empty,
+	// "/version" is used.
+	ProbePath string
+
+	// ProbeInterval is the time after which the endpoints
+	// are health-checked again. If <= 0, the endpoints are
+	// only health-checked once.
+	ProbeInterval time.Duration
+
+	init  sync.Once
+	hosts map[string]bool
+
+	lock     sync.Mutex
+	order    []*url.URL
+	probedAt time.Time
+	probing  bool
+}
+
+// RoundTrip sends the request to the preferred endpoint and
+// fails over to the next endpoint on connection errors.
+func (t *FailoverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.init.Do(t.parse)
+	if !t.hosts[req.URL.Scheme+"://"+req.URL.Host] {
+		return t.transport().RoundTrip(req)
+	}
+
+	var err error
+	for i, endpoint := range t.endpoints(req.Context()) {
+		r := req.Clone(req.Context())
+		if i > 0 && req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return nil, err
+			}
+			if r.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+		r.URL.Scheme, r.URL.Host, r.Host = endpoint.Scheme, endpoint.Host, ""
+
+		var resp *http.Response
+		if resp, err = t.transport().RoundTrip(r); err == nil {
+			return resp, nil
+		}
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+		t.demote(endpoint)
+	}
+	if err == nil {
+		err = errors.New("https: no server endpoint")
+	}
+	return nil, err
+}
+
+func (t *FailoverTransport) transport() http.RoundTripper {
+	if t.Transport != nil {
+		return t.Transport
+	}
+	return http.DefaultTransport
+}
+
+// parse parses the endpoints in their initial order.
+func (t *FailoverTransport) parse() {
+	t.hosts = make(map[string]bool, len(t.Endpoints))
+	for _, endpoint := range t.Endpoints {
+		u, err := url.Parse(endpoint)
+		if err != nil || u.Host == "" {
+			continue
+		}
+		t.hosts[u.Scheme+"://"+u.Host] = true
+		t.order = append(t.order, u)
+	}
+}
+
+// endpoints returns the endpoints in their preferred order.
+// The first call health-checks all endpoints before returning.
+// Once the probe interval has passed, subsequent calls trigger
+// a health-check in the background.
+func (t *FailoverTransport) endpoints(ctx context.Context) []*url.URL {
+	t.lock.Lock()
+	switch {
+	case t.probedAt.IsZero():
+		t.lock.Unlock()
+		t.probe(ctx)
+		t.lock.Lock()
+	case t.ProbeInterval > 0 && !t.probing && time.Since(t.probedAt) > t.ProbeInterval:
+		t.probing = true
+		go t.probe(context.Background())
+	}
+	defer t.lock.Unlock()
+	return append(make([]*url.URL, 0, len(t.order)), t.order...)
+}
+
+// probe health-checks all endpoints concurrently and orders
+// them by their latency. Unreachable endpoints are ordered
+// last.
+func (t *FailoverTransport) probe(ctx context.Context) {
+	const Timeout = 3 * time.Second
+
+	probePath := t.ProbePath
+	if probePath == "" {
+		probePath = "/version"
+	}
+
+	t.lock.Lock()
+	endpoints := append(make([]*url.URL, 0, len(t.order)), t.order...)
+	t.lock.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	latency := make([]time.Duration, len(endpoints))
+	var wg sync.WaitGroup
+	for i, endpoint := range endpoints {
+		wg.Add(1)
+		go func(i int, endpoint *url.URL) {
+			defer wg.Done()
+
+			latency[i] = -1
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(endpoint.String(), "/")+probePath, nil)
+			if err != nil {
+				return
+			}
+			start := time.Now()
+			resp, err := t.transport().RoundTrip(req)
+			if err != nil {
+				return
+			}
+			latency[i] = time.Since(start)
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+			resp.Body.Close()
+		}(i, endpoint)
+	}
+	wg.Wait()
+
+	order := make([]int, len(endpoints))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := latency[order[i]], latency[order[j]]
+		if a < 0 || b < 0 {
+			return a >= 0 && b < 0
+		}
+		return a < b
+	})
+	sorted := make([]*url.URL, 0, len(endpoints))
+	for _, i := range order {
+		sorted = append(sorted, endpoints[i])
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.order = sorted
+	t.probedAt = time.Now()
+	t.probing = false
+}
+
+// demote moves the endpoint to the end of the
+// preferred order since it could not be reached.
+func (t *FailoverTransport) demote(endpoint *url.URL) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for i, u := range t.order {
+		if u == endpoint {
+			t.order = append(append(t.order[:i:i], t.order[i+1:]...), u)
+			return
+		}
+	}
+}
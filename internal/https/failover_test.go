@@ -0,0 +1,89 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package https
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var parseEndpointsTests = []struct {
+	Value     string
+	Endpoints []string
+}{
+	{Value: "", Endpoints: nil}, // 0
+	{Value: "127.0.0.1:7373", Endpoints: []string{"https://127.0.0.1:7373"}},         // 1
+	{Value: "https://127.0.0.1:7373", Endpoints: []string{"https://127.0.0.1:7373"}}, // 2
+	{ // 3
+		Value:     "https://kes-1:7373, kes-2:7373,,",
+		Endpoints: []string{"https://kes-1:7373", "https://kes-2:7373"},
+	},
+}
+
+func TestParseEndpoints(t *testing.T) {
+	for i, test := range parseEndpointsTests {
+		endpoints := ParseEndpoints(test.Value)
+		if len(endpoints) != len(test.Endpoints) {
+			t.Fatalf("Test %d: got %d endpoints - want %d", i, len(endpoints), len(test.Endpoints))
+		}
+		for j := range endpoints {
+			if endpoints[j] != test.Endpoints[j] {
+				t.Fatalf("Test %d: got endpoint '%s' - want '%s'", i, endpoints[j], test.Endpoints[j])
+			}
+		}
+	}
+}
+
+func TestFailoverTransport(t *testing.T) {
+	newServer := func(name string, delay time.Duration) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(delay)
+			io.WriteString(w, name)
+		}))
+	}
+	slow := newServer("slow", 200*time.Millisecond)
+	defer slow.Close()
+	fast := newServer("fast", 0)
+	defer fast.Close()
+	offline := newServer("offline", 0)
+	offline.Close()
+
+	client := http.Client{
+		Transport: &FailoverTransport{
+			Endpoints: []string{offline.URL, slow.URL, fast.URL},
+		},
+	}
+	get := func(url string) string {
+		resp, err := client.Post(url, "text/plain", strings.NewReader("body"))
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("Failed to read response: %v", err)
+		}
+		return string(body)
+	}
+
+	if name := get(offline.URL + "/v1/status"); name != "fast" {
+		t.Fatalf("Request has not been sent to fastest endpoint: got '%s'", name)
+	}
+	fast.Close()
+	if name := get(offline.URL + "/v1/status"); name != "slow" {
+		t.Fatalf("Request has not failed over to remaining endpoint: got '%s'", name)
+	}
+
+	other := newServer("other", 0)
+	defer other.Close()
+	if name := get(other.URL + "/v1/status"); name != "other" {
+		t.Fatalf("Request to unrelated server has been redirected: got '%s'", name)
+	}
+}
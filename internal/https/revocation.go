@@ -0,0 +1,443 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package https
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// RevocationConfig is a structure containing configuration
+// fields for a RevocationChecker.
+type RevocationConfig struct {
+	// CRLs are paths or http(s) URLs of certificate revocation
+	// lists. A client certificate is checked against all CRLs
+	// issued, and signed, by the certificate's issuer.
+	CRLs []string
+
+	// OCSP controls whether client certificates are checked
+	// by querying an OCSP responder.
+	OCSP bool
+
+	// OCSPResponder is an optional OCSP responder URL. If
+	// empty, the OCSP responder of the client certificate,
+	// if any, is queried.
+	OCSPResponder string
+
+	// CacheTTL is the time period CRLs and OCSP responses are
+	// cached, at most. CRLs are re-fetched and OCSP responses
+	// are discarded once they expire, even before CacheTTL.
+	// CRLs are re-fetched in the background while the cached
+	// CRL is still valid.
+	//
+	// If 0, defaults to 5 minutes.
+	CacheTTL time.Duration
+
+	// FailOpen controls whether client certificates are
+	// accepted when their revocation status cannot be
+	// determined, e.g. since the OCSP responder is not
+	// reachable. By default, such certificates are rejected.
+	FailOpen bool
+
+	// CRLSoftFail controls whether CRLs that are not available,
+	// e.g. since they cannot be fetched or have expired, are
+	// skipped. Client certificates are still checked against
+	// all other CRLs and via OCSP, if enabled. By default, the
+	// revocation status of certificates issued by the CRL's
+	// issuer is unknown while the CRL is not available.
+	CRLSoftFail bool
+
+	// Client is the HTTP client used to fetch CRLs and to
+	// query OCSP responders. If nil, a client with a 10s
+	// timeout is used.
+	Client *http.Client
+
+	// Stats, if not nil, counts the failed revocation checks.
+	// Multiple RevocationCheckers may share the same
+	// RevocationStats.
+	Stats *RevocationStats
+}
+
+// RevocationStats counts the revocation checks that failed
+// since the revocation status of a client certificate could
+// not be determined, and the CRLs that could not be refreshed.
+// It is safe for concurrent use.
+type RevocationStats struct {
+	failures uint64 // Accessed atomically
+}
+
+// Failures returns the number of failed revocation checks.
+func (s *RevocationStats) Failures() uint64 { return atomic.LoadUint64(&s.failures) }
+
+// RevocationChecker checks whether client certificates have
+// been revoked using CRLs and/or OCSP. It caches CRLs and
+// OCSP responses.
+type RevocationChecker struct {
+	config RevocationConfig
+
+	lock sync.Mutex
+	crls map[string]*crlEntry
+	ocsp map[string]*ocspEntry
+}
+
+type crlEntry struct {
+	crl       *x509.RevocationList // Nil if the CRL has never been fetched
+	fetchedAt time.Time            // Time of the last successful fetch
+
+	attemptedAt time.Time     // Time of the last fetch attempt
+	err         error         // Error of the last fetch attempt, if any
+	fetching    chan struct{} // Closed once the in-flight fetch completes. Nil if none.
+}
+
+const (
+	// revocationCheckTimeout bounds the revocation checks
+	// performed during a TLS handshake.
+	revocationCheckTimeout = 3 * time.Second
+
+	// crlRetryDelay is the min. time between two attempts
+	// to fetch a CRL that could not be fetched.
+	crlRetryDelay = 30 * time.Second
+)
+
+type ocspEntry struct {
+	status    int
+	expiresAt time.Time
+}
+
+// NewRevocationChecker returns a new RevocationChecker. It
+// loads all CRL files immediately and returns an error if
+// one cannot be loaded. CRLs fetched via http(s) are loaded
+// once they are needed.
+func NewRevocationChecker(config *RevocationConfig) (*RevocationChecker, error) {
+	c := &RevocationChecker{
+		config: *config,
+		crls:   map[string]*crlEntry{},
+		ocsp:   map[string]*ocspEntry{},
+	}
+	if c.config.CacheTTL == 0 {
+		c.config.CacheTTL = 5 * time.Minute
+	}
+	if c.config.Client == nil {
+		c.config.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if c.config.Stats == nil {
+		c.config.Stats = &RevocationStats{}
+	}
+
+	now := time.Now()
+	for _, source := range c.config.CRLs {
+		if isURL(source) {
+			continue
+		}
+		crl, err := c.fetchCRL(context.Background(), source)
+		if err != nil {
+			return nil, err
+		}
+		c.crls[source] = &crlEntry{crl: crl, fetchedAt: now, attemptedAt: now}
+	}
+	return c, nil
+}
+
+// VerifyConnection checks whether the verified client
+// certificate of the TLS connection has been revoked.
+// It can be used as tls.Config.VerifyConnection.
+//
+// Connections without a verified client certificate are
+// not checked.
+func (c *RevocationChecker) VerifyConnection(state tls.ConnectionState) error {
+	ctx, cancel := context.WithTimeout(context.Background(), revocationCheckTimeout)
+	defer cancel()
+
+	for _, chain := range state.VerifiedChains {
+		if len(chain) < 2 {
+			continue // The client certificate is trusted directly
+		}
+		if err := c.Check(ctx, chain[0], chain[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Check returns an error if the certificate, issued by issuer,
+// has been revoked. Unless FailOpen is set, it also returns an
+// error if the certificate's revocation status is unknown.
+func (c *RevocationChecker) Check(ctx context.Context, certificate, issuer *x509.Certificate) error {
+	err := c.check(ctx, certificate, issuer)
+	if err == nil || errors.Is(err, errRevoked) {
+		return err
+	}
+
+	atomic.AddUint64(&c.config.Stats.failures, 1)
+	if c.config.FailOpen {
+		return nil
+	}
+	return fmt.Errorf("https: failed to check revocation status of certificate '%s': %v", certificate.Subject, err)
+}
+
+var errRevoked = errors.New("https: certificate has been revoked")
+
+func (c *RevocationChecker) check(ctx context.Context, certificate, issuer *x509.Certificate) error {
+	for _, source := range c.config.CRLs {
+		crl, err := c.crl(ctx, source)
+		if err == nil && !issuedBy(crl, issuer) {
+			continue // The CRL has not been issued by the certificate's issuer
+		}
+		if err == nil && hasExpired(crl, time.Now()) {
+			err = fmt.Errorf("CRL '%s' has expired", source)
+		}
+		if err != nil {
+			// An unavailable CRL only affects certificates
+			// of its issuer. If the CRL has never been fetched,
+			// its issuer is not known and it may apply to any
+			// certificate.
+			if !c.mayApply(source, issuer) {
+				continue
+			}
+			if c.config.CRLSoftFail {
+				atomic.AddUint64(&c.config.Stats.failures, 1)
+				continue
+			}
+			return err
+		}
+		for _, revoked := range crl.RevokedCertificateEntries {
+			if revoked.SerialNumber.Cmp(certificate.SerialNumber) == 0 {
+				return errRevoked
+			}
+		}
+	}
+	if c.config.OCSP {
+		status, err := c.ocspStatus(ctx, certificate, issuer)
+		if err != nil {
+			return err
+		}
+		switch status {
+		case ocsp.Good:
+		case ocsp.Revoked:
+			return errRevoked
+		default:
+			return errors.New("OCSP responder does not know the certificate")
+		}
+	}
+	return nil
+}
+
+// crl returns the cached CRL of the given source. It
+// re-fetches the CRL in the background once it has been
+// cached for longer than the cache TTL. The cached CRL is
+// used until it expires.
+//
+// Concurrent calls share a single fetch. Only calls without
+// a valid cached CRL wait for the fetch to complete. A CRL
+// that cannot be fetched is not fetched again for at least
+// crlRetryDelay.
+func (c *RevocationChecker) crl(ctx context.Context, source string) (*x509.RevocationList, error) {
+	now := time.Now()
+
+	c.lock.Lock()
+	entry, ok := c.crls[source]
+	if !ok {
+		entry = &crlEntry{}
+		c.crls[source] = entry
+	}
+	crl, err := entry.crl, entry.err
+	valid := crl != nil && !hasExpired(crl, now)
+	stale := !valid || now.Sub(entry.fetchedAt) >= c.config.CacheTTL
+	if stale && entry.fetching == nil && (entry.err == nil || now.Sub(entry.attemptedAt) >= c.retryDelay()) {
+		entry.fetching = make(chan struct{})
+		go c.fetchCRLEntry(source, entry)
+	}
+	fetching := entry.fetching
+	c.lock.Unlock()
+
+	if valid {
+		return crl, nil
+	}
+	if fetching == nil {
+		if err == nil { // The CRL has been fetched but is expired
+			return crl, nil
+		}
+		return nil, err
+	}
+
+	select {
+	case <-fetching:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("failed to fetch CRL '%s': %v", source, ctx.Err())
+	}
+
+	c.lock.Lock()
+	crl, err = entry.crl, entry.err
+	c.lock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return crl, nil
+}
+
+// fetchCRLEntry fetches the CRL of the given source and
+// updates the entry. It closes the entry's fetching channel
+// once done.
+func (c *RevocationChecker) fetchCRLEntry(source string, entry *crlEntry) {
+	crl, err := c.fetchCRL(context.Background(), source)
+	now := time.Now()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	entry.attemptedAt, entry.err = now, err
+	if err == nil {
+		entry.crl, entry.fetchedAt = crl, now
+	} else if entry.crl != nil && !hasExpired(entry.crl, now) {
+		atomic.AddUint64(&c.config.Stats.failures, 1) // The cached CRL is used until it expires
+	}
+	close(entry.fetching)
+	entry.fetching = nil
+}
+
+// retryDelay returns the min. time between two attempts
+// to fetch a CRL that could not be fetched.
+func (c *RevocationChecker) retryDelay() time.Duration {
+	if c.config.CacheTTL < crlRetryDelay {
+		return c.config.CacheTTL
+	}
+	return crlRetryDelay
+}
+
+// mayApply reports whether the CRL of the given source may
+// have been issued by issuer. It only returns false if the
+// CRL has been fetched before and has a different issuer.
+func (c *RevocationChecker) mayApply(source string, issuer *x509.Certificate) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	entry, ok := c.crls[source]
+	return !ok || entry.crl == nil || issuedBy(entry.crl, issuer)
+}
+
+// fetchCRL reads and parses the CRL file or fetches it
+// if source is a http(s) URL.
+func (c *RevocationChecker) fetchCRL(ctx context.Context, source string) (*x509.RevocationList, error) {
+	var (
+		b   []byte
+		err error
+	)
+	if isURL(source) {
+		b, err = c.get(ctx, http.MethodGet, source, "", nil)
+	} else {
+		b, err = os.ReadFile(source)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch CRL '%s': %v", source, err)
+	}
+	if block, _ := pem.Decode(b); block != nil && block.Type == "X509 CRL" {
+		b = block.Bytes
+	}
+	crl, err := x509.ParseRevocationList(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CRL '%s': %v", source, err)
+	}
+	return crl, nil
+}
+
+// ocspStatus returns the cached OCSP status of the certificate
+// or queries the OCSP responder.
+func (c *RevocationChecker) ocspStatus(ctx context.Context, certificate, issuer *x509.Certificate) (int, error) {
+	key := string(issuer.RawSubjectPublicKeyInfo) + "/" + certificate.SerialNumber.String()
+	now := time.Now()
+
+	c.lock.Lock()
+	entry, ok := c.ocsp[key]
+	c.lock.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.status, nil
+	}
+
+	responder := c.config.OCSPResponder
+	if responder == "" {
+		if len(certificate.OCSPServer) == 0 {
+			return 0, errors.New("certificate does not specify an OCSP responder")
+		}
+		responder = certificate.OCSPServer[0]
+	}
+	req, err := ocsp.CreateRequest(certificate, issuer, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create OCSP request: %v", err)
+	}
+	b, err := c.get(ctx, http.MethodPost, responder, "application/ocsp-request", req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query OCSP responder '%s': %v", responder, err)
+	}
+	resp, err := ocsp.ParseResponseForCert(b, certificate, issuer)
+	if err != nil {
+		return 0, fmt.Errorf("invalid response from OCSP responder '%s': %v", responder, err)
+	}
+
+	expiresAt := now.Add(c.config.CacheTTL)
+	if !resp.NextUpdate.IsZero() && resp.NextUpdate.Before(expiresAt) {
+		expiresAt = resp.NextUpdate
+	}
+	c.lock.Lock()
+	for k, e := range c.ocsp { // Remove expired responses to limit the cache size
+		if !now.Before(e.expiresAt) {
+			delete(c.ocsp, k)
+		}
+	}
+	c.ocsp[key] = &ocspEntry{status: resp.Status, expiresAt: expiresAt}
+	c.lock.Unlock()
+	return resp.Status, nil
+}
+
+// get sends a HTTP request to url and returns the response body.
+func (c *RevocationChecker) get(ctx context.Context, method, url, contentType string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := c.config.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	const MaxSize = 32 << 20 // CRLs may be large
+	return io.ReadAll(io.LimitReader(resp.Body, MaxSize))
+}
+
+// issuedBy reports whether the CRL has been issued,
+// and signed, by issuer.
+func issuedBy(crl *x509.RevocationList, issuer *x509.Certificate) bool {
+	return bytes.Equal(crl.RawIssuer, issuer.RawSubject) && crl.CheckSignatureFrom(issuer) == nil
+}
+
+// hasExpired reports whether the CRL is expired at now,
+// i.e. whether a newer CRL should have been issued.
+func hasExpired(crl *x509.RevocationList, now time.Time) bool {
+	return !crl.NextUpdate.IsZero() && !now.Before(crl.NextUpdate)
+}
+
+func isURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
@@ -0,0 +1,352 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package https
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+func TestRevocationCheckerCRL(t *testing.T) {
+	ca, caKey := newCA(t)
+	good, revoked := newClientCertificate(t, ca, caKey, 1), newClientCertificate(t, ca, caKey, 2)
+
+	filename := newCRL(t, ca, caKey, time.Now().Add(time.Hour), revoked)
+
+	checker, err := NewRevocationChecker(&RevocationConfig{CRLs: []string{filename}})
+	if err != nil {
+		t.Fatalf("Failed to create revocation checker: %v", err)
+	}
+	if err = checker.Check(context.Background(), good, ca); err != nil {
+		t.Fatalf("Rejected certificate that has not been revoked: %v", err)
+	}
+	if err = checker.Check(context.Background(), revoked, ca); err == nil {
+		t.Fatal("Accepted revoked certificate")
+	}
+
+	// CRLs of other CAs do not apply.
+	otherCA, otherKey := newCA(t)
+	if err = checker.Check(context.Background(), newClientCertificate(t, otherCA, otherKey, 2), otherCA); err != nil {
+		t.Fatalf("Rejected certificate of CA without CRL: %v", err)
+	}
+
+	if _, err = NewRevocationChecker(&RevocationConfig{CRLs: []string{filepath.Join(t.TempDir(), "missing.crl")}}); err == nil {
+		t.Fatal("Created revocation checker with non-existing CRL")
+	}
+}
+
+func TestRevocationCheckerCRLFailure(t *testing.T) {
+	ca, caKey := newCA(t)
+	otherCA, otherKey := newCA(t)
+	var (
+		good    = newClientCertificate(t, ca, caKey, 1)
+		revoked = newClientCertificate(t, ca, caKey, 2)
+		other   = newClientCertificate(t, otherCA, otherKey, 1)
+	)
+
+	// The CRL of the other CA has expired. It must only
+	// affect certificates issued by the other CA.
+	crls := []string{
+		newCRL(t, ca, caKey, time.Now().Add(time.Hour), revoked),
+		newCRL(t, otherCA, otherKey, time.Now().Add(-time.Minute)),
+	}
+	stats := &RevocationStats{}
+	checker, err := NewRevocationChecker(&RevocationConfig{CRLs: crls, Stats: stats})
+	if err != nil {
+		t.Fatalf("Failed to create revocation checker: %v", err)
+	}
+	if err = checker.Check(context.Background(), good, ca); err != nil {
+		t.Fatalf("Rejected certificate although its CRL is available: %v", err)
+	}
+	if err = checker.Check(context.Background(), revoked, ca); err == nil {
+		t.Fatal("Accepted revoked certificate")
+	}
+	if err = checker.Check(context.Background(), other, otherCA); err == nil {
+		t.Fatal("Accepted certificate with unknown revocation status")
+	}
+	if n := stats.Failures(); n != 1 {
+		t.Fatalf("Invalid number of failures: got %d - want %d", n, 1)
+	}
+
+	// A CRL that has never been fetched may apply to any certificate.
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	crls = append(crls, server.URL+"/ca.crl")
+	checker, err = NewRevocationChecker(&RevocationConfig{CRLs: crls, Stats: stats})
+	if err != nil {
+		t.Fatalf("Failed to create revocation checker: %v", err)
+	}
+	if err = checker.Check(context.Background(), good, ca); err == nil {
+		t.Fatal("Accepted certificate although a CRL is not available")
+	}
+
+	checker, err = NewRevocationChecker(&RevocationConfig{CRLs: crls, CRLSoftFail: true, Stats: stats})
+	if err != nil {
+		t.Fatalf("Failed to create revocation checker: %v", err)
+	}
+	if err = checker.Check(context.Background(), good, ca); err != nil {
+		t.Fatalf("Rejected certificate although CRL soft fail is enabled: %v", err)
+	}
+	if err = checker.Check(context.Background(), other, otherCA); err != nil {
+		t.Fatalf("Rejected certificate although CRL soft fail is enabled: %v", err)
+	}
+	if err = checker.Check(context.Background(), revoked, ca); err == nil {
+		t.Fatal("Accepted revoked certificate")
+	}
+	if n := stats.Failures(); n != 5 {
+		t.Fatalf("Invalid number of failures: got %d - want %d", n, 5)
+	}
+}
+
+func TestRevocationCheckerCRLFetch(t *testing.T) {
+	ca, caKey := newCA(t)
+	filename := newCRL(t, ca, caKey, time.Now().Add(time.Hour))
+	crl, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("Failed to read CRL: %v", err)
+	}
+
+	var (
+		requests uint64
+		fail     uint32
+		release  = make(chan struct{})
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddUint64(&requests, 1)
+		<-release
+		if atomic.LoadUint32(&fail) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(crl)
+	}))
+	defer server.Close()
+
+	checker, err := NewRevocationChecker(&RevocationConfig{CRLs: []string{server.URL + "/ca.crl"}})
+	if err != nil {
+		t.Fatalf("Failed to create revocation checker: %v", err)
+	}
+
+	// Checks do not wait for the CRL beyond their deadline.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err = checker.Check(ctx, newClientCertificate(t, ca, caKey, 1), ca); err == nil {
+		t.Fatal("Accepted certificate although the CRL has not been fetched")
+	}
+
+	// Concurrent checks share a single fetch.
+	var (
+		wg   sync.WaitGroup
+		errs = make([]error, 10)
+	)
+	for i := range errs {
+		certificate := newClientCertificate(t, ca, caKey, int64(i+1))
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = checker.Check(context.Background(), certificate, ca)
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("Check %d: rejected certificate that has not been revoked: %v", i, err)
+		}
+	}
+	if n := atomic.LoadUint64(&requests); n != 1 {
+		t.Fatalf("Invalid number of CRL requests: got '%d' - want '%d'", n, 1)
+	}
+
+	// A CRL that cannot be fetched is not fetched again
+	// on every check.
+	atomic.StoreUint32(&fail, 1)
+	checker, err = NewRevocationChecker(&RevocationConfig{CRLs: []string{server.URL + "/ca.crl"}})
+	if err != nil {
+		t.Fatalf("Failed to create revocation checker: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err = checker.Check(context.Background(), newClientCertificate(t, ca, caKey, 1), ca); err == nil {
+			t.Fatal("Accepted certificate although the CRL cannot be fetched")
+		}
+	}
+	if n := atomic.LoadUint64(&requests); n != 2 {
+		t.Fatalf("Invalid number of CRL requests: got '%d' - want '%d'", n, 2)
+	}
+}
+
+func TestRevocationCheckerOCSP(t *testing.T) {
+	ca, caKey := newCA(t)
+	good, revoked := newClientCertificate(t, ca, caKey, 1), newClientCertificate(t, ca, caKey, 2)
+
+	var requests int
+	responder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		body, _ := io.ReadAll(r.Body)
+		req, err := ocsp.ParseRequest(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		status := ocsp.Good
+		if req.SerialNumber.Cmp(revoked.SerialNumber) == 0 {
+			status = ocsp.Revoked
+		}
+		resp, err := ocsp.CreateResponse(ca, ca, ocsp.Response{
+			Status:       status,
+			SerialNumber: req.SerialNumber,
+			ThisUpdate:   time.Now().Add(-time.Minute),
+			NextUpdate:   time.Now().Add(time.Hour),
+			RevokedAt:    time.Now().Add(-time.Minute),
+		}, caKey)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Write(resp)
+	}))
+	defer responder.Close()
+
+	stats := &RevocationStats{}
+	checker, err := NewRevocationChecker(&RevocationConfig{OCSP: true, OCSPResponder: responder.URL, Stats: stats})
+	if err != nil {
+		t.Fatalf("Failed to create revocation checker: %v", err)
+	}
+	if err = checker.Check(context.Background(), good, ca); err != nil {
+		t.Fatalf("Rejected certificate that has not been revoked: %v", err)
+	}
+	if err = checker.Check(context.Background(), good, ca); err != nil {
+		t.Fatalf("Rejected certificate that has not been revoked: %v", err)
+	}
+	if requests != 1 {
+		t.Fatalf("OCSP response has not been cached: got %d requests - want %d", requests, 1)
+	}
+	if err = checker.Check(context.Background(), revoked, ca); err == nil {
+		t.Fatal("Accepted revoked certificate")
+	}
+	if n := stats.Failures(); n != 0 {
+		t.Fatalf("Invalid number of failures: got %d - want %d", n, 0)
+	}
+
+	// The revocation status cannot be determined if
+	// the OCSP responder is not reachable.
+	responder.Close()
+	unknown := newClientCertificate(t, ca, caKey, 3)
+	if err = checker.Check(context.Background(), unknown, ca); err == nil {
+		t.Fatal("Accepted certificate with unknown revocation status")
+	}
+	if n := stats.Failures(); n != 1 {
+		t.Fatalf("Invalid number of failures: got %d - want %d", n, 1)
+	}
+
+	checker, err = NewRevocationChecker(&RevocationConfig{OCSP: true, OCSPResponder: responder.URL, FailOpen: true, Stats: stats})
+	if err != nil {
+		t.Fatalf("Failed to create revocation checker: %v", err)
+	}
+	if err = checker.Check(context.Background(), unknown, ca); err != nil {
+		t.Fatalf("Rejected certificate with unknown revocation status although failing open: %v", err)
+	}
+	if n := stats.Failures(); n != 2 {
+		t.Fatalf("Invalid number of failures: got %d - want %d", n, 2)
+	}
+}
+
+func newCA(t *testing.T) (*x509.Certificate, crypto.Signer) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate private key: %v", err)
+	}
+	template := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "KES Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, &template, &template, privateKey.Public(), privateKey)
+	if err != nil {
+		t.Fatalf("Failed to create CA certificate: %v", err)
+	}
+	ca, err := x509.ParseCertificate(raw)
+	if err != nil {
+		t.Fatalf("Failed to parse CA certificate: %v", err)
+	}
+	return ca, privateKey
+}
+
+func newClientCertificate(t *testing.T, ca *x509.Certificate, caKey crypto.Signer, serial int64) *x509.Certificate {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate private key: %v", err)
+	}
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "kes-client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, &template, ca, privateKey.Public(), caKey)
+	if err != nil {
+		t.Fatalf("Failed to create client certificate: %v", err)
+	}
+	certificate, err := x509.ParseCertificate(raw)
+	if err != nil {
+		t.Fatalf("Failed to parse client certificate: %v", err)
+	}
+	return certificate
+}
+
+// newCRL writes a CRL, issued by ca, that revokes the given
+// certificates to a temp. file and returns its path.
+func newCRL(t *testing.T, ca *x509.Certificate, caKey crypto.Signer, nextUpdate time.Time, revoked ...*x509.Certificate) string {
+	entries := make([]x509.RevocationListEntry, 0, len(revoked))
+	for _, certificate := range revoked {
+		entries = append(entries, x509.RevocationListEntry{
+			SerialNumber:   certificate.SerialNumber,
+			RevocationTime: time.Now(),
+		})
+	}
+	crl, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:                    big.NewInt(1),
+		ThisUpdate:                time.Now().Add(-time.Hour),
+		NextUpdate:                nextUpdate,
+		RevokedCertificateEntries: entries,
+	}, ca, caKey)
+	if err != nil {
+		t.Fatalf("Failed to create CRL: %v", err)
+	}
+	file, err := os.CreateTemp(t.TempDir(), "*.crl")
+	if err != nil {
+		t.Fatalf("Failed to create CRL file: %v", err)
+	}
+	defer file.Close()
+
+	if _, err = file.Write(crl); err != nil {
+		t.Fatalf("Failed to write CRL: %v", err)
+	}
+	return file.Name()
+}
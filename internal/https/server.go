@@ -6,12 +6,21 @@ package https
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"errors"
 	"fmt"
+	"io/fs"
+	"math/big"
 	"net"
 	"net/http"
+	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/minio/kes/internal/fips"
@@ -33,6 +42,35 @@ type Config struct {
 	Handler http.Handler
 
 	// TLSConfig provides the TLS configuration.
+	//
+	// If TLSConfig.GetConfigForClient is not nil, it is called
+	// for every new TLS connection and, if it returns a non-nil
+	// TLS config, the returned config is used instead.
+	TLSConfig *tls.Config
+
+	// Listeners specifies the network addresses the server
+	// listens on. If empty, the server listens on Addr only.
+	Listeners []Listener
+
+	// ShutdownTimeout is the max. time period the server
+	// waits for in-flight requests to complete once it is
+	// shut down. If 0, defaults to 1 second.
+	ShutdownTimeout time.Duration
+}
+
+// Listener is a network address of a HTTPS server.
+type Listener struct {
+	// Network is the listener's network. It is either
+	// "tcp" or "unix". If empty, "tcp" is used.
+	Network string
+
+	// Addr is either a TCP address in the form "host:port"
+	// or the path of a UNIX domain socket.
+	Addr string
+
+	// TLSConfig is an optional TLS configuration for the
+	// listener. If nil, the server's TLS configuration is
+	// used.
 	TLSConfig *tls.Config
 }
 
@@ -40,9 +78,11 @@ type Config struct {
 // the given config.
 func NewServer(config *Config) *Server {
 	srv := &Server{
-		addr:      config.Addr,
-		tlsConfig: config.TLSConfig,
+		addr:            config.Addr,
+		listeners:       cloneListeners(config.Listeners),
+		shutdownTimeout: config.ShutdownTimeout,
 	}
+	srv.tlsConfig = srv.trackCertificate(config.TLSConfig.Clone())
 
 	srv.handler = &muxHandler{
 		lock:    srv.lock.RLocker(),
@@ -56,8 +96,17 @@ type Server struct {
 	addr      string
 	handler   *muxHandler
 	tlsConfig *tls.Config
+	listeners []Listener
+	expired   *tls.Certificate // If non-nil, presented instead of the server certificate
+
+	shutdownTimeout time.Duration
 
 	lock sync.RWMutex
+
+	// notAfter is the expiry, as Unix time, of the certificate
+	// most recently returned by the TLS config's GetCertificate.
+	// Must be accessed atomically.
+	notAfter int64
 }
 
 // Update updates the Server's configuration or
@@ -70,8 +119,13 @@ func (s *Server) Update(config *Config) error {
 	if config.Addr != s.addr {
 		return fmt.Errorf("https: failed to update server: '%s' does match existing server address", config.Addr)
 	}
+	if err := s.matchListeners(config.Listeners); err != nil {
+		return err
+	}
 
-	s.tlsConfig = config.TLSConfig.Clone()
+	s.tlsConfig = s.trackCertificate(config.TLSConfig.Clone())
+	s.listeners = cloneListeners(config.Listeners)
+	s.shutdownTimeout = config.ShutdownTimeout
 	s.handler.Handler = config.Handler
 	if s.handler.Handler == nil {
 		s.handler.Handler = http.NewServeMux()
@@ -79,6 +133,11 @@ func (s *Server) Update(config *Config) error {
 	return nil
 }
 
+// Handler returns the Server's HTTP handler. The returned
+// handler always dispatches requests to the handler of the
+// Server's current configuration. See Update.
+func (s *Server) Handler() http.Handler { return s.handler }
+
 // UpdateTLS updates the Server's TLS configuration
 // or returns a non-nil error explaining why the
 // server configuration couldn't be updated.
@@ -86,56 +145,262 @@ func (s *Server) UpdateTLS(config *tls.Config) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
-	s.tlsConfig = config.Clone()
+	s.tlsConfig = s.trackCertificate(config.Clone())
+	return nil
+}
+
+// UpdateListeners updates the TLS configuration of the
+// Server's listeners or returns a non-nil error explaining
+// why the listeners couldn't be updated. The listeners
+// must match the Server's existing listeners.
+func (s *Server) UpdateListeners(listeners []Listener) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.matchListeners(listeners); err != nil {
+		return err
+	}
+	s.listeners = cloneListeners(listeners)
+	return nil
+}
+
+// matchListeners returns an error if the addresses of the
+// listeners don't match the Server's listeners. Listeners
+// cannot be added or removed once the Server has started.
+func (s *Server) matchListeners(listeners []Listener) error {
+	if len(listeners) != len(s.listeners) {
+		return errors.New("https: failed to update server: listeners do not match existing server listeners")
+	}
+	for i, l := range listeners {
+		if network(l) != network(s.listeners[i]) || l.Addr != s.listeners[i].Addr {
+			return fmt.Errorf("https: failed to update server: '%s' does not match existing listener address", l.Addr)
+		}
+	}
+	return nil
+}
+
+// NotAfter returns the expiry of the Server's TLS certificate.
+// If the Server's TLS configuration has no static certificate
+// but obtains certificates dynamically, e.g. via ACME, it
+// returns the expiry of the certificate presented most
+// recently.
+//
+// It returns the zero time if the expiry is not known.
+func (s *Server) NotAfter() time.Time {
+	s.lock.RLock()
+	config := s.tlsConfig
+	s.lock.RUnlock()
+
+	if config != nil && len(config.Certificates) > 0 {
+		if leaf := leafCertificate(&config.Certificates[0]); leaf != nil {
+			return leaf.NotAfter
+		}
+	}
+	if notAfter := atomic.LoadInt64(&s.notAfter); notAfter > 0 {
+		return time.Unix(notAfter, 0)
+	}
+	return time.Time{}
+}
+
+// trackCertificate wraps the GetCertificate function of
+// config, if any, such that the Server keeps track of the
+// expiry of the certificates it returns.
+func (s *Server) trackCertificate(config *tls.Config) *tls.Config {
+	if config == nil || config.GetCertificate == nil {
+		return config
+	}
+
+	getCertificate := config.GetCertificate
+	config.GetCertificate = func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		certificate, err := getCertificate(hello)
+		if err == nil && certificate != nil {
+			if leaf := leafCertificate(certificate); leaf != nil {
+				atomic.StoreInt64(&s.notAfter, leaf.NotAfter.Unix())
+			}
+		}
+		return certificate, err
+	}
+	return config
+}
+
+// leafCertificate returns the parsed leaf certificate of
+// the given certificate chain or nil if it is empty or
+// invalid.
+func leafCertificate(certificate *tls.Certificate) *x509.Certificate {
+	if certificate.Leaf != nil {
+		return certificate.Leaf
+	}
+	if len(certificate.Certificate) == 0 {
+		return nil
+	}
+	leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+	if err != nil {
+		return nil
+	}
+	return leaf
+}
+
+// SetExpiredCertificate makes the Server present an
+// expired, self-signed certificate to all new TLS
+// connections, if expired is true. Otherwise, the
+// Server presents its actual certificate again.
+//
+// It can be used to rehearse how clients and monitoring
+// react to an expired server certificate.
+func (s *Server) SetExpiredCertificate(expired bool) error {
+	if !expired {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		s.expired = nil
+		return nil
+	}
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	template := x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject:      pkix.Name{CommonName: "kes-drill-expired"},
+		NotBefore:    now.Add(-48 * time.Hour),
+		NotAfter:     now.Add(-24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// Keep the SANs of the actual certificate such that clients
+	// fail because the certificate has expired and not because
+	// of a name mismatch.
+	if s.tlsConfig != nil && len(s.tlsConfig.Certificates) > 0 {
+		if chain := s.tlsConfig.Certificates[0].Certificate; len(chain) > 0 {
+			if leaf, err := x509.ParseCertificate(chain[0]); err == nil {
+				template.Subject = leaf.Subject
+				template.DNSNames = leaf.DNSNames
+				template.IPAddresses = leaf.IPAddresses
+			}
+		}
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, &template, &template, privateKey.Public(), privateKey)
+	if err != nil {
+		return err
+	}
+	s.expired = &tls.Certificate{
+		Certificate: [][]byte{cert},
+		PrivateKey:  privateKey,
+	}
 	return nil
 }
 
 // Start starts the HTTPS server by listening on the
-// Server's address.
+// Server's listeners or, if it has none, on the Server's
+// address.
 //
 // If the server address is empty, ":https" is used.
 //
 // Start blocks until the given ctx.Done() channel returns.
 // It always returns a non-nil error. Once ctx.Done()
-// returns, the Server gets closed and, if gracefully
-// shutdown, Start returns http.ErrServerClosed.
+// returns, the Server stops accepting new connections and
+// waits for in-flight requests to complete, for at most
+// the shutdown timeout, before it gets closed. If all
+// in-flight requests have completed, Start returns
+// http.ErrServerClosed.
 func (s *Server) Start(ctx context.Context) error {
-	addr := s.addr
-	if addr == "" {
-		addr = ":https"
-	}
-	listener, err := tls.Listen("tcp", addr, &tls.Config{
-		MinVersion:       tls.VersionTLS12,
-		CipherSuites:     fips.TLSCiphers(),
-		CurvePreferences: fips.TLSCurveIDs(),
-
-		NextProtos: []string{"h2", "http/1.1"}, // Prefer HTTP/2 but also support HTTP/1.1
-		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
-			s.lock.RLock()
-			defer s.lock.RUnlock()
-			return s.tlsConfig, nil
-		},
-	})
-	if err != nil {
-		return err
+	s.lock.RLock()
+	listeners := cloneListeners(s.listeners)
+	s.lock.RUnlock()
+
+	if len(listeners) == 0 {
+		addr := s.addr
+		if addr == "" {
+			addr = ":https"
+		}
+		listeners = []Listener{{Network: "tcp", Addr: addr}}
+	}
+
+	netListeners := make([]net.Listener, 0, len(listeners))
+	for i, l := range listeners {
+		listener, err := listen(l)
+		if err != nil {
+			for _, listener := range netListeners {
+				listener.Close()
+			}
+			return err
+		}
+
+		i := i
+		netListeners = append(netListeners, tls.NewListener(listener, &tls.Config{
+			MinVersion:       tls.VersionTLS12,
+			CipherSuites:     fips.TLSCiphers(),
+			CurvePreferences: fips.TLSCurveIDs(),
+
+			NextProtos: []string{"h2", "http/1.1"}, // Prefer HTTP/2 but also support HTTP/1.1
+			GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+				s.lock.RLock()
+				defer s.lock.RUnlock()
+
+				config := s.tlsConfig
+				if i < len(s.listeners) && s.listeners[i].TLSConfig != nil {
+					config = s.listeners[i].TLSConfig
+				}
+
+				// The TLS config may select a different config for
+				// some clients, e.g. for ACME TLS-ALPN-01 challenges.
+				if config.GetConfigForClient != nil {
+					if c, err := config.GetConfigForClient(hello); c != nil || err != nil {
+						return c, err
+					}
+				}
+				if s.expired != nil {
+					config = config.Clone()
+					config.Certificates = []tls.Certificate{*s.expired}
+					config.GetCertificate = nil
+				}
+				return config, nil
+			},
+		}))
 	}
 
+	// In-flight requests must not be canceled when ctx is
+	// done since they should complete while the server
+	// shuts down.
+	baseCtx, cancelBase := context.WithCancel(context.Background())
+	defer cancelBase()
+
 	srv := &http.Server{
 		Handler:           s.handler,
 		ReadHeaderTimeout: 5 * time.Second,
 		WriteTimeout:      0 * time.Second, // explicitly set no write timeout - see timeout handler.
 		IdleTimeout:       90 * time.Second,
-		BaseContext:       func(net.Listener) context.Context { return ctx },
+		BaseContext:       func(net.Listener) context.Context { return baseCtx },
 		ErrorLog:          log.Default().Log(),
 	}
-	srvCh := make(chan error, 1)
-	go func() { srvCh <- srv.Serve(listener) }()
+	srvCh := make(chan error, len(netListeners))
+	for _, listener := range netListeners {
+		go func(listener net.Listener) { srvCh <- srv.Serve(listener) }(listener)
+	}
 
 	select {
 	case err := <-srvCh:
+		srv.Close()
 		return err
 	case <-ctx.Done():
-		graceCtx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		s.lock.RLock()
+		timeout := s.shutdownTimeout
+		s.lock.RUnlock()
+		if timeout <= 0 {
+			timeout = 1 * time.Second
+		}
+
+		graceCtx, cancel := context.WithTimeout(context.Background(), timeout)
 		defer cancel()
 
 		err := srv.Shutdown(graceCtx)
@@ -149,6 +414,46 @@ func (s *Server) Start(ctx context.Context) error {
 	}
 }
 
+// listen announces on the listener's network address.
+// A stale UNIX domain socket, left behind by a server
+// that hasn't been shutdown gracefully, gets removed.
+func listen(l Listener) (net.Listener, error) {
+	if network(l) == "unix" {
+		if stat, err := os.Lstat(l.Addr); err == nil && stat.Mode()&fs.ModeSocket != 0 {
+			if conn, err := net.Dial("unix", l.Addr); err == nil {
+				conn.Close()
+				return nil, fmt.Errorf("https: failed to listen on '%s': address already in use", l.Addr)
+			}
+			if err = os.Remove(l.Addr); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return net.Listen(network(l), l.Addr)
+}
+
+// network returns the network of the listener l.
+func network(l Listener) string {
+	if l.Network == "" {
+		return "tcp"
+	}
+	return l.Network
+}
+
+// cloneListeners returns a copy of the listeners with
+// cloned TLS configurations.
+func cloneListeners(listeners []Listener) []Listener {
+	if len(listeners) == 0 {
+		return nil
+	}
+	clone := make([]Listener, 0, len(listeners))
+	for _, l := range listeners {
+		l.TLSConfig = l.TLSConfig.Clone()
+		clone = append(clone, l)
+	}
+	return clone
+}
+
 type muxHandler struct {
 	lock sync.Locker
 	http.Handler
@@ -0,0 +1,173 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package https
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestServerNotAfter(t *testing.T) {
+	notAfter := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	certificate := newCertificate(t, notAfter)
+
+	server := NewServer(&Config{
+		TLSConfig: &tls.Config{Certificates: []tls.Certificate{certificate}},
+	})
+	if got := server.NotAfter(); !got.Equal(notAfter) {
+		t.Fatalf("Invalid expiry: got '%v' - want '%v'", got, notAfter)
+	}
+
+	// Certificates obtained dynamically are only known
+	// once presented to a client.
+	notAfter = notAfter.Add(24 * time.Hour)
+	certificate = newCertificate(t, notAfter)
+	err := server.UpdateTLS(&tls.Config{
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) { return &certificate, nil },
+	})
+	if err != nil {
+		t.Fatalf("Failed to update TLS config: %v", err)
+	}
+	if got := server.NotAfter(); !got.IsZero() {
+		t.Fatalf("Invalid expiry: got '%v' - want zero time", got)
+	}
+	if _, err = server.tlsConfig.GetCertificate(&tls.ClientHelloInfo{ServerName: "kes.local"}); err != nil {
+		t.Fatalf("Failed to get certificate: %v", err)
+	}
+	if got := server.NotAfter(); !got.Equal(notAfter) {
+		t.Fatalf("Invalid expiry: got '%v' - want '%v'", got, notAfter)
+	}
+}
+
+func TestServerUpdateListeners(t *testing.T) {
+	server := NewServer(&Config{
+		Listeners: []Listener{
+			{Addr: "127.0.0.1:7373"},
+			{Network: "unix", Addr: "/tmp/kes.sock"},
+		},
+	})
+
+	config := &tls.Config{ServerName: "kes.local"}
+	err := server.UpdateListeners([]Listener{
+		{Network: "tcp", Addr: "127.0.0.1:7373"},
+		{Network: "unix", Addr: "/tmp/kes.sock", TLSConfig: config},
+	})
+	if err != nil {
+		t.Fatalf("Failed to update listeners: %v", err)
+	}
+	if c := server.listeners[1].TLSConfig; c == nil || c.ServerName != config.ServerName {
+		t.Fatalf("Listener TLS config has not been updated: got '%v'", c)
+	}
+
+	if err = server.UpdateListeners([]Listener{{Addr: "127.0.0.1:7373"}}); err == nil {
+		t.Fatal("Updated listeners although a listener has been removed")
+	}
+	err = server.UpdateListeners([]Listener{
+		{Addr: "127.0.0.1:7373"},
+		{Addr: "/tmp/kes.sock"},
+	})
+	if err == nil {
+		t.Fatal("Updated listeners although the network of a listener has changed")
+	}
+}
+
+func TestServerShutdown(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "kes.sock")
+	certificate := newCertificate(t, time.Now().Add(time.Hour))
+
+	started, release := make(chan struct{}), make(chan struct{})
+	server := NewServer(&Config{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+			if err := r.Context().Err(); err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}),
+		TLSConfig:       &tls.Config{Certificates: []tls.Certificate{certificate}},
+		Listeners:       []Listener{{Network: "unix", Addr: socket}},
+		ShutdownTimeout: 5 * time.Second,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() { errCh <- server.Start(ctx) }()
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				for {
+					conn, err := d.DialContext(ctx, "unix", socket)
+					if err == nil || ctx.Err() != nil {
+						return conn, err
+					}
+					time.Sleep(10 * time.Millisecond) // Wait for the server to start
+				}
+			},
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+		Timeout: 10 * time.Second,
+	}
+	respCh := make(chan int, 1)
+	go func() {
+		resp, err := client.Get("https://kes.local/")
+		if err != nil {
+			respCh <- 0
+			return
+		}
+		resp.Body.Close()
+		respCh <- resp.StatusCode
+	}()
+
+	<-started
+	cancel() // Shut down the server while a request is in-flight
+	select {
+	case err := <-errCh:
+		t.Fatalf("Server stopped before in-flight request completed: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	if code := <-respCh; code != http.StatusOK {
+		t.Fatalf("In-flight request failed: got status '%d' - want '%d'", code, http.StatusOK)
+	}
+	if err := <-errCh; err != http.ErrServerClosed {
+		t.Fatalf("Server has not been shut down gracefully: %v", err)
+	}
+}
+
+func newCertificate(t *testing.T, notAfter time.Time) tls.Certificate {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate private key: %v", err)
+	}
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "kes.local"},
+		DNSNames:     []string{"kes.local"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, &template, &template, privateKey.Public(), privateKey)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{cert}, PrivateKey: privateKey}
+}
@@ -0,0 +1,197 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package k8s implements a minimal Kubernetes API client
+// for reading resources, like Secrets, from within a
+// Kubernetes cluster.
+package k8s
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"aead.dev/mem"
+)
+
+// ServiceAccountPath is the directory that contains the
+// service account token, CA certificate and namespace
+// of a Kubernetes pod.
+const ServiceAccountPath = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// InCluster returns a new Client that communicates with the
+// Kubernetes API server of the cluster the current process
+// is running in.
+//
+// It uses the pod's service account for authentication and
+// returns an error if the process is not running within a
+// Kubernetes pod.
+func InCluster() (*Client, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("k8s: not running within a kubernetes cluster")
+	}
+
+	caCert, err := os.ReadFile(filepath.Join(ServiceAccountPath, "ca.crt"))
+	if err != nil {
+		return nil, fmt.Errorf("k8s: failed to read service account CA certificate: %v", err)
+	}
+	rootCAs := x509.NewCertPool()
+	if !rootCAs.AppendCertsFromPEM(caCert) {
+		return nil, errors.New("k8s: failed to read service account CA certificate: no PEM-encoded certificate found")
+	}
+
+	namespace, err := os.ReadFile(filepath.Join(ServiceAccountPath, "namespace"))
+	if err != nil {
+		return nil, fmt.Errorf("k8s: failed to read service account namespace: %v", err)
+	}
+	return &Client{
+		Endpoint:  "https://" + net.JoinHostPort(host, port),
+		Namespace: strings.TrimSpace(string(namespace)),
+		TokenFile: filepath.Join(ServiceAccountPath, "token"),
+		HTTPClient: http.Client{
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+				DialContext: (&net.Dialer{
+					Timeout:   10 * time.Second,
+					KeepAlive: 30 * time.Second,
+				}).DialContext,
+				ForceAttemptHTTP2:   true,
+				MaxIdleConns:        10,
+				IdleConnTimeout:     90 * time.Second,
+				TLSHandshakeTimeout: 10 * time.Second,
+				TLSClientConfig: &tls.Config{
+					MinVersion: tls.VersionTLS12,
+					RootCAs:    rootCAs,
+				},
+			},
+		},
+	}, nil
+}
+
+// Client is a Kubernetes API client.
+type Client struct {
+	// Endpoint is the Kubernetes API server endpoint.
+	Endpoint string
+
+	// Namespace is the default namespace used when
+	// no namespace is specified explicitly.
+	Namespace string
+
+	// TokenFile is the path to a file containing the
+	// bearer token used to authenticate to the API
+	// server.
+	//
+	// The file is read on every request since projected
+	// service account tokens get rotated by the kubelet.
+	TokenFile string
+
+	// HTTPClient is the underlying HTTP client.
+	HTTPClient http.Client
+}
+
+// Secret is a Kubernetes Secret.
+type Secret struct {
+	// Name is the name of the Secret.
+	Name string
+
+	// Namespace is the namespace of the Secret.
+	Namespace string
+
+	// Type is the Kubernetes secret type, e.g.
+	// "kubernetes.io/tls".
+	Type string
+
+	// ResourceVersion is the version of the Secret.
+	// It changes whenever the Secret gets updated.
+	ResourceVersion string
+
+	// Data contains the Secret's key-value pairs.
+	Data map[string][]byte
+}
+
+// Secret returns the Secret with the given name within
+// the given namespace. If namespace is empty, the
+// Client's default namespace is used.
+func (c *Client) Secret(ctx context.Context, namespace, name string) (*Secret, error) {
+	type Response struct {
+		Metadata struct {
+			Name            string `json:"name"`
+			Namespace       string `json:"namespace"`
+			ResourceVersion string `json:"resourceVersion"`
+		} `json:"metadata"`
+		Type string            `json:"type"`
+		Data map[string][]byte `json:"data"`
+	}
+	if namespace == "" {
+		namespace = c.Namespace
+	}
+	if namespace == "" {
+		return nil, errors.New("k8s: no namespace specified")
+	}
+
+	url, err := url.JoinPath(c.Endpoint, path.Join("/api/v1/namespaces", url.PathEscape(namespace), "secrets", url.PathEscape(name)))
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.TokenFile != "" {
+		token, err := os.ReadFile(c.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("k8s: failed to read service account token: %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, parseErrorResponse(resp)
+	}
+
+	const MaxSize = 1 * mem.MiB // Kubernetes limits Secrets to 1 MiB
+	var response Response
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(&response); err != nil {
+		return nil, err
+	}
+	return &Secret{
+		Name:            response.Metadata.Name,
+		Namespace:       response.Metadata.Namespace,
+		Type:            response.Type,
+		ResourceVersion: response.Metadata.ResourceVersion,
+		Data:            response.Data,
+	}, nil
+}
+
+// parseErrorResponse returns an error containing
+// the Kubernetes status message, if any.
+func parseErrorResponse(resp *http.Response) error {
+	type Response struct {
+		Message string `json:"message"`
+	}
+	var response Response
+	if err := json.NewDecoder(mem.LimitReader(resp.Body, 1*mem.MiB)).Decode(&response); err != nil || response.Message == "" {
+		return fmt.Errorf("k8s: %s", http.StatusText(resp.StatusCode))
+	}
+	return fmt.Errorf("k8s: %s: %s", http.StatusText(resp.StatusCode), response.Message)
+}
@@ -0,0 +1,73 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClientSecret(t *testing.T) {
+	const (
+		Token     = "my-service-account-token"
+		Namespace = "minio"
+		Name      = "kes-tls"
+		Version   = "4711"
+	)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+Token {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"kind":"Status","message":"Unauthorized"}`))
+			return
+		}
+		if r.URL.Path != "/api/v1/namespaces/"+Namespace+"/secrets/"+Name {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"kind":"Status","message":"secrets \"` + Name + `\" not found"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+  "kind": "Secret",
+  "metadata": {"name": "kes-tls", "namespace": "minio", "resourceVersion": "4711"},
+  "type": "kubernetes.io/tls",
+  "data": {"tls.crt": "Y2VydA==", "tls.key": "a2V5"}
+}`))
+	}))
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte(Token+"\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write token file: %v", err)
+	}
+	client := &Client{
+		Endpoint:   server.URL,
+		Namespace:  Namespace,
+		TokenFile:  tokenFile,
+		HTTPClient: *server.Client(),
+	}
+
+	secret, err := client.Secret(context.Background(), "", Name)
+	if err != nil {
+		t.Fatalf("Failed to fetch secret: %v", err)
+	}
+	if secret.Name != Name || secret.Namespace != Namespace {
+		t.Fatalf("Invalid secret: got '%s/%s' - want '%s/%s'", secret.Namespace, secret.Name, Namespace, Name)
+	}
+	if secret.ResourceVersion != Version {
+		t.Fatalf("Invalid secret: got resource version '%s' - want '%s'", secret.ResourceVersion, Version)
+	}
+	if !bytes.Equal(secret.Data["tls.crt"], []byte("cert")) || !bytes.Equal(secret.Data["tls.key"], []byte("key")) {
+		t.Fatalf("Invalid secret: invalid data: %v", secret.Data)
+	}
+
+	if _, err = client.Secret(context.Background(), Namespace, "unknown"); err == nil {
+		t.Fatal("Fetching a non-existing secret succeeded")
+	}
+}
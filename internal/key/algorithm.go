@@ -0,0 +1,56 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package key
+
+import "github.com/minio/kes-go"
+
+// AES256_GCM_SIV is the AES-256-GCM-SIV (RFC 8452) key
+// algorithm. In contrast to AES256_GCM_SHA256, it is
+// nonce-misuse resistant. It is not FIPS 140-2 approved.
+//
+// The kes.KeyAlgorithm enum does not define AES256_GCM_SIV.
+// Hence, its text representation must not be used to encode
+// keys or algorithms. Use Algorithm instead.
+const AES256_GCM_SIV = kes.XCHACHA20_POLY1305 + 1
+
+// Algorithm is a kes.KeyAlgorithm that, in contrast
+// to kes.KeyAlgorithm, can also be encoded when it is
+// an algorithm not known to kes-go, like AES256_GCM_SIV.
+//
+// Algorithm uses the same text representation as
+// kes.KeyAlgorithm for all algorithms defined by kes-go.
+type Algorithm kes.KeyAlgorithm
+
+// String returns the Algorithm's string representation.
+func (a Algorithm) String() string {
+	if kes.KeyAlgorithm(a) == AES256_GCM_SIV {
+		return "AES256-GCM-SIV"
+	}
+	return kes.KeyAlgorithm(a).String()
+}
+
+// MarshalText returns the Algorithm's text representation.
+// It returns an error if the Algorithm isn't valid.
+func (a Algorithm) MarshalText() ([]byte, error) {
+	if kes.KeyAlgorithm(a) == AES256_GCM_SIV {
+		return []byte("AES256-GCM-SIV"), nil
+	}
+	return kes.KeyAlgorithm(a).MarshalText()
+}
+
+// UnmarshalText parses text as Algorithm text representation.
+func (a *Algorithm) UnmarshalText(text []byte) error {
+	if string(text) == "AES256-GCM-SIV" {
+		*a = Algorithm(AES256_GCM_SIV)
+		return nil
+	}
+
+	var algorithm kes.KeyAlgorithm
+	if err := algorithm.UnmarshalText(text); err != nil {
+		return err
+	}
+	*a = Algorithm(algorithm)
+	return nil
+}
@@ -0,0 +1,280 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package key
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/minio/kes-go"
+)
+
+// Type is the type of a cryptographic key.
+//
+// A key is either a symmetric key that can be used
+// for encryption and decryption or an asymmetric key
+// that can be used for computing and verifying digital
+// signatures.
+type Type string
+
+// All supported key types.
+const (
+	// Symmetric is the type of symmetric keys. Its
+	// algorithm is reported by Key.Algorithm.
+	Symmetric Type = ""
+
+	// RSA2048 is a 2048 bit RSA key. It computes
+	// RSASSA-PKCS1-v1_5 signatures using SHA-256.
+	RSA2048 Type = "RSA-2048"
+
+	// RSA3072 is a 3072 bit RSA key. It computes
+	// RSASSA-PKCS1-v1_5 signatures using SHA-256.
+	RSA3072 Type = "RSA-3072"
+
+	// RSA4096 is a 4096 bit RSA key. It computes
+	// RSASSA-PKCS1-v1_5 signatures using SHA-256.
+	RSA4096 Type = "RSA-4096"
+
+	// ECDSAP256 is an ECDSA key for the NIST P-256
+	// curve. It computes ASN.1 encoded signatures
+	// using SHA-256.
+	ECDSAP256 Type = "ECDSA-P256"
+
+	// ECDSAP384 is an ECDSA key for the NIST P-384
+	// curve. It computes ASN.1 encoded signatures
+	// using SHA-384.
+	ECDSAP384 Type = "ECDSA-P384"
+)
+
+// ErrNotSymmetric is returned when an asymmetric key is
+// used for an operation that requires a symmetric key,
+// like encryption or decryption.
+var ErrNotSymmetric = kes.NewError(http.StatusBadRequest, "key is not a symmetric key")
+
+// ErrNotAsymmetric is returned when a symmetric key is
+// used for an operation that requires an asymmetric key,
+// like computing or verifying signatures.
+var ErrNotAsymmetric = kes.NewError(http.StatusBadRequest, "key is not an asymmetric key")
+
+// ParseType parses s as key type. It ignores
+// the case of s.
+func ParseType(s string) (Type, error) {
+	switch strings.ToUpper(s) {
+	case "", "SYMMETRIC":
+		return Symmetric, nil
+	case string(RSA2048):
+		return RSA2048, nil
+	case string(RSA3072):
+		return RSA3072, nil
+	case string(RSA4096):
+		return RSA4096, nil
+	case string(ECDSAP256):
+		return ECDSAP256, nil
+	case string(ECDSAP384):
+		return ECDSAP384, nil
+	default:
+		return "", kes.NewError(http.StatusBadRequest, "invalid key type '"+s+"'")
+	}
+}
+
+// String returns the Type's string representation.
+func (t Type) String() string {
+	if t == Symmetric {
+		return "symmetric"
+	}
+	return string(t)
+}
+
+// IsAsymmetric reports whether t is an asymmetric
+// key type.
+func (t Type) IsAsymmetric() bool { return t != Symmetric }
+
+// RandomAsymmetric generates a new random asymmetric Key
+// of the given type. The returned key is owned to the
+// specified identity.
+func RandomAsymmetric(t Type, owner kes.Identity) (Key, error) {
+	var (
+		privateKey crypto.Signer
+		err        error
+	)
+	switch t {
+	case RSA2048:
+		privateKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	case RSA3072:
+		privateKey, err = rsa.GenerateKey(rand.Reader, 3072)
+	case RSA4096:
+		privateKey, err = rsa.GenerateKey(rand.Reader, 4096)
+	case ECDSAP256:
+		privateKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case ECDSAP384:
+		privateKey, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	default:
+		return Key{}, errors.New("key: invalid asymmetric key type")
+	}
+	if err != nil {
+		return Key{}, err
+	}
+	b, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return Key{}, err
+	}
+	return Key{
+		bytes:     b,
+		keyType:   t,
+		createdAt: time.Now().UTC(),
+		createdBy: owner,
+	}, nil
+}
+
+// NewAsymmetric returns a new asymmetric Key from the given
+// PKCS #8 encoded private key. The key type is determined by
+// the private key and must be one of the supported asymmetric
+// key types. The returned key is owned to the specified identity.
+func NewAsymmetric(privateKey []byte, owner kes.Identity) (Key, error) {
+	t, err := typeOf(privateKey)
+	if err != nil {
+		return Key{}, err
+	}
+	return Key{
+		bytes:     clone(privateKey...),
+		keyType:   t,
+		createdAt: time.Now().UTC(),
+		createdBy: owner,
+	}, nil
+}
+
+// typeOf returns the Type of the PKCS #8 encoded private key.
+func typeOf(privateKey []byte) (Type, error) {
+	key, err := x509.ParsePKCS8PrivateKey(privateKey)
+	if err != nil {
+		return "", kes.NewError(http.StatusBadRequest, "invalid private key: "+err.Error())
+	}
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		switch key.N.BitLen() {
+		case 2048:
+			return RSA2048, nil
+		case 3072:
+			return RSA3072, nil
+		case 4096:
+			return RSA4096, nil
+		}
+		return "", kes.NewError(http.StatusBadRequest, "invalid private key: unsupported RSA key size")
+	case *ecdsa.PrivateKey:
+		switch key.Curve {
+		case elliptic.P256():
+			return ECDSAP256, nil
+		case elliptic.P384():
+			return ECDSAP384, nil
+		}
+		return "", kes.NewError(http.StatusBadRequest, "invalid private key: unsupported ECDSA curve")
+	default:
+		return "", kes.NewError(http.StatusBadRequest, "invalid private key: unsupported key type")
+	}
+}
+
+// Sign computes a digital signature of the message.
+//
+// It returns ErrNotAsymmetric if k is a symmetric key
+// and ErrExpired if the key has expired.
+func (k *Key) Sign(message []byte) ([]byte, error) {
+	if !k.keyType.IsAsymmetric() {
+		return nil, ErrNotAsymmetric
+	}
+	if k.Expired() {
+		return nil, ErrExpired
+	}
+	privateKey, err := k.privateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	hash := k.keyType.hash()
+	h := hash.New()
+	h.Write(message)
+	return privateKey.Sign(rand.Reader, h.Sum(nil), hash)
+}
+
+// Verify reports whether signature is a valid signature
+// of the message.
+//
+// It returns ErrNotAsymmetric if k is a symmetric key
+// and ErrExpired if the key has expired.
+func (k *Key) Verify(message, signature []byte) (bool, error) {
+	if !k.keyType.IsAsymmetric() {
+		return false, ErrNotAsymmetric
+	}
+	if k.Expired() {
+		return false, ErrExpired
+	}
+	privateKey, err := k.privateKey()
+	if err != nil {
+		return false, err
+	}
+
+	hash := k.keyType.hash()
+	h := hash.New()
+	h.Write(message)
+	digest := h.Sum(nil)
+
+	switch publicKey := privateKey.Public().(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(publicKey, hash, digest, signature) == nil, nil
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(publicKey, digest, signature), nil
+	default:
+		return false, errors.New("key: invalid asymmetric key")
+	}
+}
+
+// PublicKey returns the ASN.1 DER-encoded public key
+// in PKIX form.
+//
+// It returns ErrNotAsymmetric if k is a symmetric key
+// and ErrExpired if the key has expired.
+func (k *Key) PublicKey() ([]byte, error) {
+	if !k.keyType.IsAsymmetric() {
+		return nil, ErrNotAsymmetric
+	}
+	if k.Expired() {
+		return nil, ErrExpired
+	}
+	privateKey, err := k.privateKey()
+	if err != nil {
+		return nil, err
+	}
+	return x509.MarshalPKIXPublicKey(privateKey.Public())
+}
+
+// privateKey parses the k's PKCS #8 encoded
+// private key.
+func (k *Key) privateKey() (crypto.Signer, error) {
+	privateKey, err := x509.ParsePKCS8PrivateKey(k.bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("key: invalid asymmetric key")
+	}
+	return signer, nil
+}
+
+// hash returns the hash function used to compute
+// signatures with keys of type t.
+func (t Type) hash() crypto.Hash {
+	if t == ECDSAP384 {
+		return crypto.SHA384
+	}
+	return crypto.SHA256
+}
@@ -0,0 +1,73 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package key
+
+import (
+	"crypto/x509"
+	"errors"
+	"testing"
+)
+
+var signVerifyTests = []struct {
+	Type Type
+}{
+	{Type: RSA2048},
+	{Type: ECDSAP256},
+	{Type: ECDSAP384},
+}
+
+func TestSignVerify(t *testing.T) {
+	message := []byte("Hello World")
+	for i, test := range signVerifyTests {
+		key, err := RandomAsymmetric(test.Type, "")
+		if err != nil {
+			t.Fatalf("Test %d: failed to generate key: %v", i, err)
+		}
+
+		text, err := key.MarshalText()
+		if err != nil {
+			t.Fatalf("Test %d: failed to encode key: %v", i, err)
+		}
+		if key, err = Parse(text); err != nil {
+			t.Fatalf("Test %d: failed to parse key: %v", i, err)
+		}
+		if key.Type() != test.Type {
+			t.Fatalf("Test %d: type mismatch: got '%v' - want '%v'", i, key.Type(), test.Type)
+		}
+
+		signature, err := key.Sign(message)
+		if err != nil {
+			t.Fatalf("Test %d: failed to sign message: %v", i, err)
+		}
+		if valid, err := key.Verify(message, signature); err != nil || !valid {
+			t.Fatalf("Test %d: failed to verify signature: valid=%v err=%v", i, valid, err)
+		}
+		if valid, _ := key.Verify([]byte("Hello KES"), signature); valid {
+			t.Fatalf("Test %d: signature of different message is valid", i)
+		}
+
+		publicKey, err := key.PublicKey()
+		if err != nil {
+			t.Fatalf("Test %d: failed to export public key: %v", i, err)
+		}
+		if _, err = x509.ParsePKIXPublicKey(publicKey); err != nil {
+			t.Fatalf("Test %d: failed to parse public key: %v", i, err)
+		}
+
+		if _, err = key.Wrap(message, nil); !errors.Is(err, ErrNotSymmetric) {
+			t.Fatalf("Test %d: encryption with asymmetric key: got err '%v' - want '%v'", i, err, ErrNotSymmetric)
+		}
+	}
+}
+
+func TestSignSymmetric(t *testing.T) {
+	key, err := Random(0, "")
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	if _, err = key.Sign([]byte("Hello World")); !errors.Is(err, ErrNotAsymmetric) {
+		t.Fatalf("Signing with symmetric key: got err '%v' - want '%v'", err, ErrNotAsymmetric)
+	}
+}
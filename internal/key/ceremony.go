@@ -0,0 +1,230 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package key
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/minio/kes-go"
+)
+
+// MinCustodians is the minimum number of custodians
+// that have to contribute to a key Ceremony.
+const MinCustodians = 2
+
+// Errors returned by a Ceremony.
+var (
+	// ErrCeremonyExpired is returned when contributing to a
+	// Ceremony whose ceremony window has elapsed.
+	ErrCeremonyExpired = kes.NewError(http.StatusBadRequest, "key ceremony has expired")
+
+	// ErrCeremonyDone is returned when contributing to a
+	// Ceremony that has received all contributions already.
+	ErrCeremonyDone = kes.NewError(http.StatusBadRequest, "key ceremony is already complete")
+
+	// ErrContributed is returned when an identity tries to
+	// contribute to a Ceremony more than once.
+	ErrContributed = kes.NewError(http.StatusBadRequest, "identity has already contributed to key ceremony")
+)
+
+// A Ceremony creates a key from the entropy contributed
+// by multiple custodians. No single custodian, including
+// the one that started the Ceremony, knows or controls
+// the resulting key.
+//
+// The key is derived from a server-generated seed and the
+// entropy of all custodians once the required number of
+// distinct custodians has contributed within the ceremony
+// window.
+type Ceremony struct {
+	seed       []byte
+	custodians int
+	algorithm  kes.KeyAlgorithm
+	createdAt  time.Time
+	createdBy  kes.Identity
+	deadline   time.Time
+
+	lock          sync.Mutex
+	contributions []contribution
+	key           *Key // The resulting key once complete
+}
+
+// Contribution is the audit record of a single contribution
+// to a Ceremony. It contains a commitment to the contributed
+// entropy but not the entropy itself.
+type Contribution struct {
+	Identity   kes.Identity
+	Commitment []byte
+	Time       time.Time
+}
+
+type contribution struct {
+	Contribution
+	entropy []byte
+}
+
+// NewCeremony returns a new Ceremony for a key of the given
+// algorithm that requires contributions from the given number
+// of custodians within the ceremony window. The resulting key
+// is owned by the given identity.
+func NewCeremony(algorithm kes.KeyAlgorithm, custodians int, window time.Duration, owner kes.Identity) (*Ceremony, error) {
+	if custodians < MinCustodians {
+		return nil, kes.NewError(http.StatusBadRequest, "key ceremony requires at least 2 custodians")
+	}
+	if window <= 0 {
+		return nil, kes.NewError(http.StatusBadRequest, "invalid key ceremony window")
+	}
+	if Len(algorithm) != Size {
+		return nil, errors.New("key: invalid key algorithm")
+	}
+	seed, err := randomBytes(Size)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	return &Ceremony{
+		seed:       seed,
+		custodians: custodians,
+		algorithm:  algorithm,
+		createdAt:  now,
+		createdBy:  owner,
+		deadline:   now.Add(window),
+	}, nil
+}
+
+// Custodians returns the number of custodians that have
+// to contribute to the Ceremony.
+func (c *Ceremony) Custodians() int { return c.custodians }
+
+// CreatedBy returns the identity that started the Ceremony.
+func (c *Ceremony) CreatedBy() kes.Identity { return c.createdBy }
+
+// Deadline returns the point in time when the ceremony
+// window elapses.
+func (c *Ceremony) Deadline() time.Time { return c.deadline }
+
+// Expired reports whether the ceremony window has elapsed.
+// A complete Ceremony expires as well such that its key
+// does not stay in memory forever.
+func (c *Ceremony) Expired() bool {
+	return !time.Now().Before(c.deadline)
+}
+
+// Result returns the resulting key and true if the Ceremony
+// is complete, has not expired and the given identity has
+// contributed to it. Custodians can use it to retry storing
+// the key if storing it failed before.
+func (c *Ceremony) Result(identity kes.Identity) (Key, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.key == nil || !time.Now().Before(c.deadline) {
+		return Key{}, false
+	}
+	for _, contribution := range c.contributions {
+		if contribution.Identity == identity {
+			return *c.key, true
+		}
+	}
+	return Key{}, false
+}
+
+// Contributions returns the audit records of all
+// contributions received so far.
+func (c *Ceremony) Contributions() []Contribution {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	records := make([]Contribution, 0, len(c.contributions))
+	for _, contribution := range c.contributions {
+		records = append(records, contribution.Contribution)
+	}
+	return records
+}
+
+// Contribute adds the entropy contributed by the given
+// identity to the Ceremony. The entropy must be at least
+// 32 bytes long. Each identity can contribute only once.
+//
+// Once the last custodian has contributed, Contribute
+// returns the resulting key and true.
+func (c *Ceremony) Contribute(identity kes.Identity, entropy []byte) (Key, bool, error) {
+	if len(entropy) < Size {
+		return Key{}, false, kes.NewError(http.StatusBadRequest, "key ceremony contribution must be at least 32 bytes")
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if len(c.contributions) >= c.custodians {
+		return Key{}, false, ErrCeremonyDone
+	}
+	now := time.Now().UTC()
+	if !now.Before(c.deadline) {
+		return Key{}, false, ErrCeremonyExpired
+	}
+	for _, contribution := range c.contributions {
+		if contribution.Identity == identity {
+			return Key{}, false, ErrContributed
+		}
+	}
+
+	commitment := sha256.Sum256(entropy)
+	c.contributions = append(c.contributions, contribution{
+		Contribution: Contribution{
+			Identity:   identity,
+			Commitment: commitment[:],
+			Time:       now,
+		},
+		entropy: clone(entropy...),
+	})
+	if len(c.contributions) < c.custodians {
+		return Key{}, false, nil
+	}
+
+	// All custodians have contributed. The key does not depend
+	// on the order of the contributions.
+	contributions := make([]contribution, len(c.contributions))
+	copy(contributions, c.contributions)
+	sort.Slice(contributions, func(i, j int) bool {
+		return contributions[i].Identity < contributions[j].Identity
+	})
+
+	// Each identity and entropy is length-prefixed such that
+	// different contributions always produce different inputs.
+	var length [4]byte
+	mac := hmac.New(sha256.New, c.seed)
+	mac.Write([]byte("KES key ceremony"))
+	for _, contribution := range contributions {
+		binary.BigEndian.PutUint32(length[:], uint32(len(contribution.Identity)))
+		mac.Write(length[:])
+		mac.Write([]byte(contribution.Identity))
+
+		binary.BigEndian.PutUint32(length[:], uint32(len(contribution.entropy)))
+		mac.Write(length[:])
+		mac.Write(contribution.entropy)
+	}
+	key, err := New(c.algorithm, mac.Sum(nil), c.createdBy)
+	if err != nil {
+		return Key{}, false, err
+	}
+	key.createdAt = now
+
+	// Discard all secret material that is no longer needed.
+	for i := range c.contributions {
+		c.contributions[i].entropy = nil
+	}
+	c.seed = nil
+	c.key = &key
+	return key, true, nil
+}
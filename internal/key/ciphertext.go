@@ -59,7 +59,7 @@ func (c *ciphertext) MarshalBinary() ([]byte, error) {
 
 	var b []byte
 	b = msgp.AppendArrayHeader(b, Items)
-	b = msgp.AppendString(b, c.Algorithm.String())
+	b = msgp.AppendString(b, Algorithm(c.Algorithm).String())
 	b = msgp.AppendString(b, c.ID)
 	b = msgp.AppendBytes(b, c.IV)
 	b = msgp.AppendBytes(b, c.Nonce)
@@ -108,12 +108,12 @@ func (c *ciphertext) UnmarshalBinary(b []byte) error {
 		return kes.ErrDecrypt
 	}
 
-	var alg kes.KeyAlgorithm
+	var alg Algorithm
 	if err = alg.UnmarshalText([]byte(algorithm)); err != nil {
 		return kes.ErrDecrypt
 	}
 
-	c.Algorithm = alg
+	c.Algorithm = kes.KeyAlgorithm(alg)
 	c.ID = id
 	c.IV = iv[:]
 	c.Nonce = nonce[:]
@@ -0,0 +1,114 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package key
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+
+	"github.com/minio/kes-go"
+)
+
+// ExportAlgorithm is the algorithm used to wrap exported
+// key material.
+//
+// The key material is encrypted with AES-256-GCM using a
+// random, ephemeral key and an all-zero nonce. The ephemeral
+// key is encrypted with RSA-OAEP using SHA-256. Since each
+// ephemeral key is used only once, the fixed nonce is safe.
+const ExportAlgorithm = "RSA-OAEP-256+AES-256-GCM"
+
+// MinExportKeySize is the minimum size, in bits, of RSA
+// public keys used to wrap exported key material.
+const MinExportKeySize = 2048
+
+// Exported is key material wrapped for an RSA public key.
+type Exported struct {
+	// WrappedKey is the ephemeral AES-256 key encrypted
+	// with RSA-OAEP-SHA256.
+	WrappedKey []byte
+
+	// Ciphertext is the key material encrypted with
+	// AES-256-GCM using the ephemeral key. It contains
+	// the raw bytes of symmetric keys and the PKCS #8
+	// encoded private key of asymmetric keys.
+	Ciphertext []byte
+}
+
+// ParseRSAPublicKey parses a PEM-encoded RSA public key in
+// either PKIX or PKCS #1 form that can be used to export
+// key material.
+func ParseRSAPublicKey(pemPublicKey []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(pemPublicKey)
+	if block == nil {
+		return nil, kes.NewError(http.StatusBadRequest, "invalid public key: no PEM block found")
+	}
+
+	var publicKey *rsa.PublicKey
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, kes.NewError(http.StatusBadRequest, "invalid public key: "+err.Error())
+		}
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return nil, kes.NewError(http.StatusBadRequest, "invalid public key: not an RSA public key")
+		}
+		publicKey = rsaKey
+	case "RSA PUBLIC KEY":
+		key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, kes.NewError(http.StatusBadRequest, "invalid public key: "+err.Error())
+		}
+		publicKey = key
+	default:
+		return nil, kes.NewError(http.StatusBadRequest, "invalid public key: unsupported PEM type '"+block.Type+"'")
+	}
+	if publicKey.N.BitLen() < MinExportKeySize {
+		return nil, kes.NewError(http.StatusBadRequest, "invalid public key: RSA key must be at least 2048 bits")
+	}
+	return publicKey, nil
+}
+
+// Export encrypts the key material of k for the given RSA
+// public key. Only the owner of the corresponding private
+// key can decrypt the exported key material.
+//
+// It returns ErrExpired if the key has expired.
+func (k *Key) Export(publicKey *rsa.PublicKey) (Exported, error) {
+	if k.Expired() {
+		return Exported{}, ErrExpired
+	}
+
+	ephemeralKey, err := randomBytes(32)
+	if err != nil {
+		return Exported{}, err
+	}
+	block, err := aes.NewCipher(ephemeralKey)
+	if err != nil {
+		return Exported{}, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return Exported{}, err
+	}
+	wrappedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, publicKey, ephemeralKey, nil)
+	if err != nil {
+		return Exported{}, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	return Exported{
+		WrappedKey: wrappedKey,
+		Ciphertext: aead.Seal(nil, nonce, k.bytes, nil),
+	}, nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package key
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/minio/kes-go"
+)
+
+func TestExport(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal RSA public key: %v", err)
+	}
+	publicKey, err := ParseRSAPublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if err != nil {
+		t.Fatalf("Failed to parse RSA public key: %v", err)
+	}
+
+	for _, keyType := range []Type{Symmetric, ECDSAP256, RSA2048} {
+		var key Key
+		if keyType == Symmetric {
+			key, err = Random(kes.AES256_GCM_SHA256, "")
+		} else {
+			key, err = RandomAsymmetric(keyType, "")
+		}
+		if err != nil {
+			t.Fatalf("Failed to generate '%v' key: %v", keyType, err)
+		}
+
+		exported, err := key.Export(publicKey)
+		if err != nil {
+			t.Fatalf("Failed to export '%v' key: %v", keyType, err)
+		}
+		ephemeralKey, err := rsa.DecryptOAEP(sha256.New(), nil, privateKey, exported.WrappedKey, nil)
+		if err != nil {
+			t.Fatalf("Failed to unwrap ephemeral key of '%v' key: %v", keyType, err)
+		}
+		block, err := aes.NewCipher(ephemeralKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			t.Fatal(err)
+		}
+		material, err := aead.Open(nil, make([]byte, aead.NonceSize()), exported.Ciphertext, nil)
+		if err != nil {
+			t.Fatalf("Failed to decrypt '%v' key material: %v", keyType, err)
+		}
+		if !bytes.Equal(material, key.bytes) {
+			t.Fatalf("Key material of '%v' key does not match", keyType)
+		}
+	}
+
+	key, err := Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	key.SetExpiresAt(time.Now().Add(-time.Minute))
+	if _, err = key.Export(publicKey); !errors.Is(err, ErrExpired) {
+		t.Fatalf("Exported expired key: got err '%v' - want '%v'", err, ErrExpired)
+	}
+}
@@ -0,0 +1,278 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package key
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/minio/kes-go"
+)
+
+// Format is an encoding format of key material, e.g.
+// when importing keys exported by other KMS systems.
+type Format string
+
+// All supported key formats.
+const (
+	// Base64 is a base64-encoded raw symmetric key.
+	Base64 Format = "base64"
+
+	// Hex is a hex-encoded raw symmetric key.
+	Hex Format = "hex"
+
+	// JWK is a JSON Web Key (RFC 7517). It may either
+	// be a symmetric ("oct") or a private RSA or EC key.
+	JWK Format = "jwk"
+
+	// PKCS8 is a PEM-encoded PKCS #8 private key.
+	PKCS8 Format = "pkcs8"
+)
+
+// ParseFormat parses s as key format. It ignores
+// the case of s.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case Base64, Hex, JWK, PKCS8:
+		return f, nil
+	default:
+		return "", errors.New("key: invalid key format '" + s + "'")
+	}
+}
+
+// DetectFormat returns the Format of the encoded key
+// material. It falls back to Base64 if the material
+// does not match any other format.
+func DetectFormat(b []byte) Format {
+	b = bytes.TrimSpace(b)
+	switch {
+	case bytes.HasPrefix(b, []byte("{")):
+		return JWK
+	case bytes.HasPrefix(b, []byte("-----BEGIN")):
+		return PKCS8
+	case len(b) == 2*Size && isHex(b):
+		return Hex
+	default:
+		return Base64
+	}
+}
+
+// Material is decoded key material that can be used to
+// create a new Key.
+type Material struct {
+	// Type is the key type. For asymmetric keys, Bytes
+	// contains the PKCS #8 encoded private key.
+	Type Type
+
+	// Algorithm is the algorithm of a symmetric key.
+	Algorithm kes.KeyAlgorithm
+
+	// Bytes is either the raw symmetric key or the
+	// PKCS #8 encoded private key.
+	Bytes []byte
+}
+
+// DecodeMaterial decodes the key material b encoded in the
+// given format. It verifies that the key material is a valid
+// key of a supported type, length and algorithm.
+func DecodeMaterial(format Format, b []byte) (Material, error) {
+	b = bytes.TrimSpace(b)
+	switch format {
+	case Base64:
+		raw, err := base64.StdEncoding.DecodeString(string(b))
+		if err != nil {
+			return Material{}, errors.New("key: invalid base64 key: " + err.Error())
+		}
+		return newSymmetricMaterial(kes.KeyAlgorithmUndefined, raw)
+	case Hex:
+		raw, err := hex.DecodeString(string(b))
+		if err != nil {
+			return Material{}, errors.New("key: invalid hex key: " + err.Error())
+		}
+		return newSymmetricMaterial(kes.KeyAlgorithmUndefined, raw)
+	case JWK:
+		return decodeJWK(b)
+	case PKCS8:
+		block, _ := pem.Decode(b)
+		if block == nil {
+			return Material{}, errors.New("key: invalid PKCS #8 key: no PEM block found")
+		}
+		if block.Type != "PRIVATE KEY" {
+			return Material{}, errors.New("key: invalid PKCS #8 key: unsupported PEM block '" + block.Type + "'")
+		}
+		t, err := typeOf(block.Bytes)
+		if err != nil {
+			return Material{}, err
+		}
+		return Material{Type: t, Bytes: block.Bytes}, nil
+	default:
+		return Material{}, errors.New("key: invalid key format '" + string(format) + "'")
+	}
+}
+
+func newSymmetricMaterial(algorithm kes.KeyAlgorithm, raw []byte) (Material, error) {
+	if len(raw) != Len(algorithm) {
+		return Material{}, errors.New("key: invalid key size: symmetric keys must be 256 bits")
+	}
+	return Material{Type: Symmetric, Algorithm: algorithm, Bytes: raw}, nil
+}
+
+// decodeJWK decodes a symmetric ("oct") or private RSA
+// or EC JSON Web Key.
+func decodeJWK(b []byte) (Material, error) {
+	type JSON struct {
+		Kty string `json:"kty"`
+		Alg string `json:"alg"`
+		K   string `json:"k"`
+
+		Crv string `json:"crv"`
+		X   string `json:"x"`
+		Y   string `json:"y"`
+		D   string `json:"d"`
+
+		N string `json:"n"`
+		E string `json:"e"`
+		P string `json:"p"`
+		Q string `json:"q"`
+	}
+	var jwk JSON
+	if err := json.Unmarshal(b, &jwk); err != nil {
+		return Material{}, errors.New("key: invalid JWK: " + err.Error())
+	}
+	decode := func(s string) ([]byte, error) {
+		if s == "" {
+			return nil, errors.New("key: invalid JWK: missing key parameter")
+		}
+		v, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+		if err != nil {
+			return nil, errors.New("key: invalid JWK: " + err.Error())
+		}
+		return v, nil
+	}
+	decodeInt := func(s string) (*big.Int, error) {
+		v, err := decode(s)
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Int).SetBytes(v), nil
+	}
+
+	var privateKey any
+	switch jwk.Kty {
+	case "oct":
+		var algorithm kes.KeyAlgorithm
+		switch jwk.Alg {
+		case "":
+			algorithm = kes.KeyAlgorithmUndefined
+		case "A256GCM":
+			algorithm = kes.AES256_GCM_SHA256
+		case "XC20P":
+			algorithm = kes.XCHACHA20_POLY1305
+		default:
+			return Material{}, errors.New("key: invalid JWK: unsupported algorithm '" + jwk.Alg + "'")
+		}
+		raw, err := decode(jwk.K)
+		if err != nil {
+			return Material{}, err
+		}
+		return newSymmetricMaterial(algorithm, raw)
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return Material{}, errors.New("key: invalid JWK: unsupported curve '" + jwk.Crv + "'")
+		}
+		x, err := decodeInt(jwk.X)
+		if err != nil {
+			return Material{}, err
+		}
+		y, err := decodeInt(jwk.Y)
+		if err != nil {
+			return Material{}, err
+		}
+		d, err := decodeInt(jwk.D)
+		if err != nil {
+			return Material{}, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return Material{}, errors.New("key: invalid JWK: public key is not on curve")
+		}
+		privateKey = &ecdsa.PrivateKey{
+			PublicKey: ecdsa.PublicKey{Curve: curve, X: x, Y: y},
+			D:         d,
+		}
+	case "RSA":
+		n, err := decodeInt(jwk.N)
+		if err != nil {
+			return Material{}, err
+		}
+		e, err := decodeInt(jwk.E)
+		if err != nil {
+			return Material{}, err
+		}
+		d, err := decodeInt(jwk.D)
+		if err != nil {
+			return Material{}, err
+		}
+		p, err := decodeInt(jwk.P)
+		if err != nil {
+			return Material{}, err
+		}
+		q, err := decodeInt(jwk.Q)
+		if err != nil {
+			return Material{}, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return Material{}, errors.New("key: invalid JWK: invalid RSA public exponent")
+		}
+		rsaKey := &rsa.PrivateKey{
+			PublicKey: rsa.PublicKey{N: n, E: int(e.Int64())},
+			D:         d,
+			Primes:    []*big.Int{p, q},
+		}
+		if err = rsaKey.Validate(); err != nil {
+			return Material{}, errors.New("key: invalid JWK: " + err.Error())
+		}
+		rsaKey.Precompute()
+		privateKey = rsaKey
+	case "":
+		return Material{}, errors.New("key: invalid JWK: missing key type")
+	default:
+		return Material{}, errors.New("key: invalid JWK: unsupported key type '" + jwk.Kty + "'")
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return Material{}, errors.New("key: invalid JWK: " + err.Error())
+	}
+	t, err := typeOf(der)
+	if err != nil {
+		return Material{}, err
+	}
+	return Material{Type: t, Bytes: der}, nil
+}
+
+func isHex(b []byte) bool {
+	for _, c := range b {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
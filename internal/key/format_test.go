@@ -0,0 +1,148 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package key
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"testing"
+
+	"github.com/minio/kes-go"
+)
+
+var decodeMaterialTests = []struct {
+	Material   string
+	Format     Format
+	Type       Type
+	Algorithm  kes.KeyAlgorithm
+	ShouldFail bool
+}{
+	{ // 0
+		Material: "Xlnr/nOgAWE5cA7GAsl3L2goCvmfs6KE0gNgB1T93wE=",
+		Format:   Base64,
+		Type:     Symmetric,
+	},
+	{ // 1
+		Material: "5e59ebfe73a0016139700ec602c9772f68280af99fb3a284d203600754fddf01",
+		Format:   Hex,
+		Type:     Symmetric,
+	},
+	{ // 2
+		Material: `{"kty":"oct","k":"Xlnr_nOgAWE5cA7GAsl3L2goCvmfs6KE0gNgB1T93wE"}`,
+		Format:   JWK,
+		Type:     Symmetric,
+	},
+	{ // 3
+		Material:  `{"kty":"oct","alg":"A256GCM","k":"Xlnr_nOgAWE5cA7GAsl3L2goCvmfs6KE0gNgB1T93wE"}`,
+		Format:    JWK,
+		Type:      Symmetric,
+		Algorithm: kes.AES256_GCM_SHA256,
+	},
+	{ // 4
+		Material:   "Xlnr/nOgAWE5cA7GAsl3L2goCvmfs6KE0gNgB1T9", // 30 bytes
+		Format:     Base64,
+		ShouldFail: true,
+	},
+	{ // 5
+		Material:   `{"kty":"oct","alg":"HS256","k":"Xlnr_nOgAWE5cA7GAsl3L2goCvmfs6KE0gNgB1T93wE"}`,
+		Format:     JWK,
+		ShouldFail: true,
+	},
+	{ // 6
+		Material:   `{"kty":"OKP","crv":"Ed25519"}`,
+		Format:     JWK,
+		ShouldFail: true,
+	},
+	{ // 7
+		Material:   "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n",
+		Format:     PKCS8,
+		ShouldFail: true,
+	},
+}
+
+func TestDecodeMaterial(t *testing.T) {
+	want := mustDecodeB64("Xlnr/nOgAWE5cA7GAsl3L2goCvmfs6KE0gNgB1T93wE=")
+	for i, test := range decodeMaterialTests {
+		if format := DetectFormat([]byte(test.Material)); format != test.Format {
+			t.Fatalf("Test %d: format mismatch: got '%s' - want '%s'", i, format, test.Format)
+		}
+
+		material, err := DecodeMaterial(test.Format, []byte(test.Material))
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: decoding should have failed", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to decode key material: %v", i, err)
+		}
+		if test.ShouldFail {
+			continue
+		}
+		if material.Type != test.Type {
+			t.Fatalf("Test %d: type mismatch: got '%v' - want '%v'", i, material.Type, test.Type)
+		}
+		if material.Algorithm != test.Algorithm {
+			t.Fatalf("Test %d: algorithm mismatch: got '%v' - want '%v'", i, material.Algorithm, test.Algorithm)
+		}
+		if !bytes.Equal(material.Bytes, want) {
+			t.Fatalf("Test %d: key mismatch: got '%x' - want '%x'", i, material.Bytes, want)
+		}
+	}
+}
+
+func TestDecodeAsymmetricMaterial(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate private key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatalf("Failed to encode private key: %v", err)
+	}
+
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if format := DetectFormat(pemKey); format != PKCS8 {
+		t.Fatalf("Format mismatch: got '%s' - want '%s'", format, PKCS8)
+	}
+	material, err := DecodeMaterial(PKCS8, pemKey)
+	if err != nil {
+		t.Fatalf("Failed to decode PKCS #8 key: %v", err)
+	}
+	if material.Type != ECDSAP256 {
+		t.Fatalf("Type mismatch: got '%v' - want '%v'", material.Type, ECDSAP256)
+	}
+	if !bytes.Equal(material.Bytes, der) {
+		t.Fatal("PKCS #8 key mismatch")
+	}
+
+	encode := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	jwk := `{"kty":"EC","crv":"P-256","x":"` + encode(privateKey.X.FillBytes(make([]byte, 32))) +
+		`","y":"` + encode(privateKey.Y.FillBytes(make([]byte, 32))) +
+		`","d":"` + encode(privateKey.D.FillBytes(make([]byte, 32))) + `"}`
+	if material, err = DecodeMaterial(JWK, []byte(jwk)); err != nil {
+		t.Fatalf("Failed to decode JWK: %v", err)
+	}
+	if material.Type != ECDSAP256 {
+		t.Fatalf("Type mismatch: got '%v' - want '%v'", material.Type, ECDSAP256)
+	}
+
+	key, err := NewAsymmetric(material.Bytes, "")
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	signature, err := key.Sign([]byte("Hello World"))
+	if err != nil {
+		t.Fatalf("Failed to sign message: %v", err)
+	}
+	digest := sha256.Sum256([]byte("Hello World"))
+	if !ecdsa.VerifyASN1(&privateKey.PublicKey, digest[:], signature) {
+		t.Fatal("Signature of imported key is invalid")
+	}
+}
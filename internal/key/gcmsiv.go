@@ -0,0 +1,62 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package key
+
+import (
+	"errors"
+
+	"github.com/google/tink/go/aead/subtle"
+)
+
+const (
+	gcmSIVKeySize   = 256 / 8
+	gcmSIVNonceSize = subtle.AESGCMSIVNonceSize
+)
+
+// gcmSIV is an AES-256-GCM-SIV cipher, as specified in
+// RFC 8452, backed by the Tink implementation.
+//
+// Unlike a cipher.AEAD, it generates a random nonce
+// when sealing a message since Tink does not accept
+// nonces chosen by the caller.
+type gcmSIV struct {
+	aead *subtle.AESGCMSIV
+}
+
+// newGCMSIV returns a new AES-256-GCM-SIV cipher that
+// uses the given key-generating key.
+func newGCMSIV(key []byte) (gcmSIV, error) {
+	if len(key) != gcmSIVKeySize {
+		return gcmSIV{}, errors.New("key: invalid AES-256-GCM-SIV key size")
+	}
+	aead, err := subtle.NewAESGCMSIV(key)
+	if err != nil {
+		return gcmSIV{}, err
+	}
+	return gcmSIV{aead: aead}, nil
+}
+
+// Seal encrypts and authenticates the plaintext and
+// authenticates the associatedData. It returns the
+// random nonce and the ciphertext.
+func (g gcmSIV) Seal(plaintext, associatedData []byte) (nonce, ciphertext []byte, err error) {
+	sealed, err := g.aead.Encrypt(plaintext, associatedData)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sealed[:gcmSIVNonceSize], sealed[gcmSIVNonceSize:], nil
+}
+
+// Open decrypts and authenticates the ciphertext and
+// authenticates the associatedData.
+func (g gcmSIV) Open(nonce, ciphertext, associatedData []byte) ([]byte, error) {
+	if len(nonce) != gcmSIVNonceSize {
+		return nil, errors.New("key: invalid AES-256-GCM-SIV nonce size")
+	}
+	sealed := make([]byte, 0, len(nonce)+len(ciphertext))
+	sealed = append(sealed, nonce...)
+	sealed = append(sealed, ciphertext...)
+	return g.aead.Decrypt(sealed, associatedData)
+}
@@ -0,0 +1,103 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package key
+
+import (
+	"bytes"
+	"testing"
+)
+
+// gcmSIVTests are AES-256-GCM-SIV test vectors from
+// RFC 8452, Appendix C.2.
+var gcmSIVTests = []struct {
+	Key            string
+	Nonce          string
+	Plaintext      string
+	AssociatedData string
+	Ciphertext     string
+}{
+	{ // 0
+		Key:        "0100000000000000000000000000000000000000000000000000000000000000",
+		Nonce:      "030000000000000000000000",
+		Ciphertext: "07f5f4169bbf55a8400cd47ea6fd400f",
+	},
+	{ // 1
+		Key:        "0100000000000000000000000000000000000000000000000000000000000000",
+		Nonce:      "030000000000000000000000",
+		Plaintext:  "0100000000000000",
+		Ciphertext: "c2ef328e5c71c83b843122130f7364b761e0b97427e3df28",
+	},
+	{ // 2
+		Key:        "0100000000000000000000000000000000000000000000000000000000000000",
+		Nonce:      "030000000000000000000000",
+		Plaintext:  "010000000000000000000000",
+		Ciphertext: "9aab2aeb3faa0a34aea8e2b18ca50da9ae6559e48fd10f6e5c9ca17e",
+	},
+	{ // 3
+		Key:        "0100000000000000000000000000000000000000000000000000000000000000",
+		Nonce:      "030000000000000000000000",
+		Plaintext:  "01000000000000000000000000000000",
+		Ciphertext: "85a01b63025ba19b7fd3ddfc033b3e76c9eac6fa700942702e90862383c6c366",
+	},
+	{ // 4
+		Key:            "0100000000000000000000000000000000000000000000000000000000000000",
+		Nonce:          "030000000000000000000000",
+		Plaintext:      "0200000000000000",
+		AssociatedData: "01",
+		Ciphertext:     "1de22967237a813291213f267e3b452f02d01ae33e4ec854",
+	},
+	{ // 5
+		Key:            "0100000000000000000000000000000000000000000000000000000000000000",
+		Nonce:          "030000000000000000000000",
+		Plaintext:      "020000000000000000000000",
+		AssociatedData: "01",
+		Ciphertext:     "163d6f9cc1b346cd453a2e4cc1a4a19ae800941ccdc57cc8413c277f",
+	},
+}
+
+func TestGCMSIV(t *testing.T) {
+	for i, test := range gcmSIVTests {
+		var (
+			nonce          = mustDecodeHex(test.Nonce)
+			plaintext      = mustDecodeHex(test.Plaintext)
+			associatedData = mustDecodeHex(test.AssociatedData)
+			ciphertext     = mustDecodeHex(test.Ciphertext)
+		)
+		aead, err := newGCMSIV(mustDecodeHex(test.Key))
+		if err != nil {
+			t.Fatalf("Test %d: failed to create AES-256-GCM-SIV: %v", i, err)
+		}
+
+		decrypted, err := aead.Open(nonce, ciphertext, associatedData)
+		if err != nil {
+			t.Fatalf("Test %d: failed to decrypt: %v", i, err)
+		}
+		if !bytes.Equal(decrypted, plaintext) {
+			t.Fatalf("Test %d: plaintext mismatch: got '%x' - want '%x'", i, decrypted, plaintext)
+		}
+
+		ciphertext[0] ^= 1
+		if _, err = aead.Open(nonce, ciphertext, associatedData); err == nil {
+			t.Fatalf("Test %d: decrypting a modified ciphertext should have failed", i)
+		}
+
+		sealNonce, sealed, err := aead.Seal(plaintext, associatedData)
+		if err != nil {
+			t.Fatalf("Test %d: failed to encrypt: %v", i, err)
+		}
+		if len(sealNonce) != gcmSIVNonceSize {
+			t.Fatalf("Test %d: invalid nonce size: got '%d' - want '%d'", i, len(sealNonce), gcmSIVNonceSize)
+		}
+		if len(sealed) != len(ciphertext) {
+			t.Fatalf("Test %d: invalid ciphertext size: got '%d' - want '%d'", i, len(sealed), len(ciphertext))
+		}
+		if decrypted, err = aead.Open(sealNonce, sealed, associatedData); err != nil {
+			t.Fatalf("Test %d: failed to decrypt: %v", i, err)
+		}
+		if !bytes.Equal(decrypted, plaintext) {
+			t.Fatalf("Test %d: plaintext mismatch: got '%x' - want '%x'", i, decrypted, plaintext)
+		}
+	}
+}
@@ -13,10 +13,14 @@ import (
 	"crypto/sha256"
 	"crypto/subtle"
 	"encoding"
+	"encoding/binary"
 	"encoding/gob"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"io"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/minio/kes-go"
@@ -24,8 +28,13 @@ import (
 	"github.com/minio/kes/internal/fips"
 	"golang.org/x/crypto/chacha20"
 	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
 )
 
+// ErrExpired is returned when an expired key is used
+// for a cryptographic operation.
+var ErrExpired = kes.NewError(http.StatusBadRequest, "key has expired")
+
 const (
 	// MaxSize is the maximum byte size of an encoded key.
 	MaxSize = 1 << 20
@@ -50,6 +59,8 @@ func Len(a kes.KeyAlgorithm) int {
 		return 256 / 8
 	case kes.XCHACHA20_POLY1305:
 		return 256 / 8
+	case AES256_GCM_SIV:
+		return 256 / 8
 	case kes.KeyAlgorithmUndefined:
 		return 256 / 8 // For generic/unknown keys, return 256 bit.
 	default:
@@ -57,6 +68,33 @@ func Len(a kes.KeyAlgorithm) int {
 	}
 }
 
+// ParseAlgorithm parses s as symmetric key algorithm. It
+// ignores the case of s. An empty s is parsed as
+// KeyAlgorithmUndefined.
+//
+// In FIPS mode, ParseAlgorithm only accepts FIPS 140-2
+// compliant algorithms.
+func ParseAlgorithm(s string) (kes.KeyAlgorithm, error) {
+	switch strings.ToUpper(s) {
+	case "":
+		return kes.KeyAlgorithmUndefined, nil
+	case "AES256-GCM_SHA256", "AES256-GCM", "AES-256-GCM":
+		return kes.AES256_GCM_SHA256, nil
+	case "XCHACHA20-POLY1305", "XCHACHA20":
+		if fips.Enabled {
+			return kes.KeyAlgorithmUndefined, kes.NewError(http.StatusBadRequest, "invalid key algorithm '"+s+"': not supported in FIPS mode")
+		}
+		return kes.XCHACHA20_POLY1305, nil
+	case "AES256-GCM-SIV", "AES-256-GCM-SIV":
+		if fips.Enabled {
+			return kes.KeyAlgorithmUndefined, kes.NewError(http.StatusBadRequest, "invalid key algorithm '"+s+"': not supported in FIPS mode")
+		}
+		return AES256_GCM_SIV, nil
+	default:
+		return kes.KeyAlgorithmUndefined, kes.NewError(http.StatusBadRequest, "invalid key algorithm '"+s+"'")
+	}
+}
+
 // New returns an new Key for the given cryptographic algorithm.
 // The key len must match algorithm's key size. The returned key
 // is owned to the specified identity.
@@ -90,13 +128,16 @@ func randomBytes(length int) ([]byte, error) {
 	return b, nil
 }
 
-// Key is a symmetric cryptographic key.
+// Key is a symmetric or asymmetric cryptographic key.
 type Key struct {
 	bytes []byte
 
+	keyType   Type
 	algorithm kes.KeyAlgorithm
 	createdAt time.Time
 	createdBy kes.Identity
+	expiresAt time.Time
+	tags      map[string]string
 }
 
 var (
@@ -106,10 +147,37 @@ var (
 	_ encoding.BinaryUnmarshaler = (*Key)(nil)
 )
 
+// Type returns the key's type. Symmetric keys can be used
+// for encryption and decryption while asymmetric keys can be
+// used for computing and verifying signatures.
+func (k *Key) Type() Type { return k.keyType }
+
 // Algorithm returns the cryptographic algorithm for which the
 // key can be used.
 func (k *Key) Algorithm() kes.KeyAlgorithm { return k.algorithm }
 
+// Size returns the size of the key in bits. For RSA keys,
+// it is the size of the modulus and for ECDSA keys the size
+// of the curve.
+func (k *Key) Size() int {
+	switch k.keyType {
+	case Symmetric:
+		return 8 * Len(k.algorithm)
+	case RSA2048:
+		return 2048
+	case RSA3072:
+		return 3072
+	case RSA4096:
+		return 4096
+	case ECDSAP256:
+		return 256
+	case ECDSAP384:
+		return 384
+	default:
+		return 0
+	}
+}
+
 // CreatedAt returns the point in time when the key has
 // been created.
 func (k *Key) CreatedAt() time.Time { return k.createdAt }
@@ -117,6 +185,35 @@ func (k *Key) CreatedAt() time.Time { return k.createdAt }
 // CreatedBy returns the identity that created the key.
 func (k *Key) CreatedBy() kes.Identity { return k.createdBy }
 
+// ExpiresAt returns the point in time when the key expires.
+// It returns the zero time.Time if the key never expires.
+func (k *Key) ExpiresAt() time.Time { return k.expiresAt }
+
+// SetExpiresAt sets the point in time when the key expires.
+// The zero time.Time means that the key never expires.
+func (k *Key) SetExpiresAt(t time.Time) { k.expiresAt = t.UTC() }
+
+// Tags returns the key's tags. The returned map must
+// not be modified.
+func (k *Key) Tags() map[string]string { return k.tags }
+
+// HasTag reports whether the key has a tag with the
+// given name and value.
+func (k *Key) HasTag(name, value string) bool {
+	v, ok := k.tags[name]
+	return ok && v == value
+}
+
+// SetTags replaces the key's tags with a copy of the
+// given tags.
+func (k *Key) SetTags(tags map[string]string) { k.tags = cloneTags(tags) }
+
+// Expired reports whether the key has expired. An expired key
+// cannot be used for any cryptographic operation.
+func (k *Key) Expired() bool {
+	return !k.expiresAt.IsZero() && !time.Now().Before(k.expiresAt)
+}
+
 // ID returns the k's key ID.
 func (k *Key) ID() string {
 	const Size = 128 / 8
@@ -128,16 +225,19 @@ func (k *Key) ID() string {
 func (k *Key) Clone() Key {
 	return Key{
 		bytes:     clone(k.bytes...),
+		keyType:   k.Type(),
 		algorithm: k.Algorithm(),
 		createdAt: k.CreatedAt(),
 		createdBy: k.CreatedBy(),
+		expiresAt: k.ExpiresAt(),
+		tags:      cloneTags(k.tags),
 	}
 }
 
 // Equal returns true if and only if both keys
 // are identical.
 func (k *Key) Equal(other Key) bool {
-	if k.Algorithm() != other.Algorithm() {
+	if k.Type() != other.Type() || k.Algorithm() != other.Algorithm() {
 		return false
 	}
 	return subtle.ConstantTimeCompare(k.bytes, other.bytes) == 1
@@ -146,38 +246,50 @@ func (k *Key) Equal(other Key) bool {
 // MarshalText returns the key's text representation.
 func (k Key) MarshalText() ([]byte, error) {
 	type JSON struct {
-		Version   version          `json:"version"`
-		Bytes     []byte           `json:"bytes"`
-		Algorithm kes.KeyAlgorithm `json:"algorithm,omitempty"`
-		CreatedAt time.Time        `json:"created_at,omitempty"`
-		CreatedBy kes.Identity     `json:"created_by,omitempty"`
+		Version   version           `json:"version"`
+		Bytes     []byte            `json:"bytes"`
+		Type      Type              `json:"type,omitempty"`
+		Algorithm Algorithm         `json:"algorithm,omitempty"`
+		CreatedAt time.Time         `json:"created_at,omitempty"`
+		CreatedBy kes.Identity      `json:"created_by,omitempty"`
+		ExpiresAt time.Time         `json:"expires_at,omitempty"`
+		Tags      map[string]string `json:"tags,omitempty"`
 	}
 	return json.Marshal(JSON{
 		Version:   v1,
 		Bytes:     k.bytes,
-		Algorithm: k.Algorithm(),
+		Type:      k.Type(),
+		Algorithm: Algorithm(k.Algorithm()),
 		CreatedAt: k.CreatedAt(),
 		CreatedBy: k.CreatedBy(),
+		ExpiresAt: k.ExpiresAt(),
+		Tags:      k.tags,
 	})
 }
 
 // UnmarshalText parses and decodes text as encoded key.
 func (k *Key) UnmarshalText(text []byte) error {
 	type JSON struct {
-		Version   version          `json:"version"`
-		Bytes     []byte           `json:"bytes"`
-		Algorithm kes.KeyAlgorithm `json:"algorithm"`
-		CreatedAt time.Time        `json:"created_at"`
-		CreatedBy kes.Identity     `json:"created_by"`
+		Version   version           `json:"version"`
+		Bytes     []byte            `json:"bytes"`
+		Type      Type              `json:"type"`
+		Algorithm Algorithm         `json:"algorithm"`
+		CreatedAt time.Time         `json:"created_at"`
+		CreatedBy kes.Identity      `json:"created_by"`
+		ExpiresAt time.Time         `json:"expires_at"`
+		Tags      map[string]string `json:"tags"`
 	}
 	var value JSON
 	if err := json.Unmarshal(text, &value); err != nil {
 		return err
 	}
 	k.bytes = value.Bytes
-	k.algorithm = value.Algorithm
+	k.keyType = value.Type
+	k.algorithm = kes.KeyAlgorithm(value.Algorithm)
 	k.createdAt = value.CreatedAt
 	k.createdBy = value.CreatedBy
+	k.expiresAt = value.ExpiresAt
+	k.tags = value.Tags
 	return nil
 }
 
@@ -186,18 +298,24 @@ func (k Key) MarshalBinary() ([]byte, error) {
 	type GOB struct {
 		Version   version
 		Bytes     []byte
-		Algorithm kes.KeyAlgorithm
+		Type      Type
+		Algorithm Algorithm
 		CreatedAt time.Time
 		CreatedBy kes.Identity
+		ExpiresAt time.Time
+		Tags      map[string]string
 	}
 
 	var buffer bytes.Buffer
 	err := gob.NewEncoder(&buffer).Encode(GOB{
 		Version:   v1,
 		Bytes:     k.bytes,
-		Algorithm: k.Algorithm(),
+		Type:      k.Type(),
+		Algorithm: Algorithm(k.Algorithm()),
 		CreatedAt: k.CreatedAt(),
 		CreatedBy: k.CreatedBy(),
+		ExpiresAt: k.ExpiresAt(),
+		Tags:      k.tags,
 	})
 	return buffer.Bytes(), err
 }
@@ -207,9 +325,12 @@ func (k *Key) UnmarshalBinary(b []byte) error {
 	type GOB struct {
 		Version   version
 		Bytes     []byte
-		Algorithm kes.KeyAlgorithm
+		Type      Type
+		Algorithm Algorithm
 		CreatedAt time.Time
 		CreatedBy kes.Identity
+		ExpiresAt time.Time
+		Tags      map[string]string
 	}
 
 	var value GOB
@@ -217,9 +338,12 @@ func (k *Key) UnmarshalBinary(b []byte) error {
 		return err
 	}
 	k.bytes = value.Bytes
-	k.algorithm = value.Algorithm
+	k.keyType = value.Type
+	k.algorithm = kes.KeyAlgorithm(value.Algorithm)
 	k.createdAt = value.CreatedAt
 	k.createdBy = value.CreatedBy
+	k.expiresAt = value.ExpiresAt
+	k.tags = value.Tags
 	return nil
 }
 
@@ -228,7 +352,15 @@ func (k *Key) UnmarshalBinary(b []byte) error {
 //
 // To unwrap the ciphertext the same associatedData
 // has to be provided again.
+//
+// It returns ErrExpired if the key has expired.
 func (k *Key) Wrap(plaintext, associatedData []byte) ([]byte, error) {
+	if k.keyType.IsAsymmetric() {
+		return nil, ErrNotSymmetric
+	}
+	if k.Expired() {
+		return nil, ErrExpired
+	}
 	iv, err := randomBytes(16)
 	if err != nil {
 		return nil, err
@@ -242,21 +374,31 @@ func (k *Key) Wrap(plaintext, associatedData []byte) ([]byte, error) {
 			algorithm = kes.XCHACHA20_POLY1305
 		}
 	}
-	cipher, err := newAEAD(algorithm, k.bytes, iv)
-	if err != nil {
-		return nil, err
-	}
-
-	nonce, err := randomBytes(cipher.NonceSize())
-	if err != nil {
-		return nil, err
+	var nonce, sealed []byte
+	if algorithm == AES256_GCM_SIV { // AES-256-GCM-SIV generates the nonce itself
+		cipher, err := newSIV(k.bytes, iv)
+		if err != nil {
+			return nil, err
+		}
+		if nonce, sealed, err = cipher.Seal(plaintext, associatedData); err != nil {
+			return nil, err
+		}
+	} else {
+		cipher, err := newAEAD(algorithm, k.bytes, iv)
+		if err != nil {
+			return nil, err
+		}
+		if nonce, err = randomBytes(cipher.NonceSize()); err != nil {
+			return nil, err
+		}
+		sealed = cipher.Seal(nil, nonce, plaintext, associatedData)
 	}
 	ciphertext := ciphertext{
 		Algorithm: algorithm,
 		ID:        k.ID(),
 		IV:        iv,
 		Nonce:     nonce,
-		Bytes:     cipher.Seal(nil, nonce, plaintext, associatedData),
+		Bytes:     sealed,
 	}
 	return ciphertext.MarshalBinary()
 }
@@ -266,7 +408,15 @@ func (k *Key) Wrap(plaintext, associatedData []byte) ([]byte, error) {
 //
 // It verifies that the associatedData matches the
 // value used when the ciphertext has been generated.
+//
+// It returns ErrExpired if the key has expired.
 func (k *Key) Unwrap(ciphertext, associatedData []byte) ([]byte, error) {
+	if k.keyType.IsAsymmetric() {
+		return nil, ErrNotSymmetric
+	}
+	if k.Expired() {
+		return nil, ErrExpired
+	}
 	text, err := decodeCiphertext(ciphertext)
 	if err != nil {
 		return nil, kes.ErrDecrypt
@@ -279,6 +429,18 @@ func (k *Key) Unwrap(ciphertext, associatedData []byte) ([]byte, error) {
 		return nil, kes.ErrDecrypt
 	}
 
+	if text.Algorithm == AES256_GCM_SIV {
+		cipher, err := newSIV(k.bytes, text.IV)
+		if err != nil {
+			return nil, kes.ErrDecrypt
+		}
+		plaintext, err := cipher.Open(text.Nonce, text.Bytes, associatedData)
+		if err != nil {
+			return nil, kes.ErrDecrypt
+		}
+		return plaintext, nil
+	}
+
 	cipher, err := newAEAD(text.Algorithm, k.bytes, text.IV)
 	if err != nil {
 		return nil, kes.ErrDecrypt
@@ -290,6 +452,76 @@ func (k *Key) Unwrap(ciphertext, associatedData []byte) ([]byte, error) {
 	return plaintext, nil
 }
 
+// HMAC computes the HMAC-SHA256 of the message.
+//
+// It does not use the key bytes directly but derives
+// a dedicated HMAC key. Hence, computing an HMAC does
+// not reveal any information about the encryption key.
+//
+// It returns ErrNotSymmetric if k is an asymmetric key
+// and ErrExpired if the key has expired.
+func (k *Key) HMAC(message []byte) ([]byte, error) {
+	if k.keyType.IsAsymmetric() {
+		return nil, ErrNotSymmetric
+	}
+	if k.Expired() {
+		return nil, ErrExpired
+	}
+
+	mac := hmac.New(sha256.New, k.bytes)
+	mac.Write([]byte("KES HMAC-SHA256 key"))
+	hmacKey := mac.Sum(nil)
+
+	mac = hmac.New(sha256.New, hmacKey)
+	mac.Write(message)
+	return mac.Sum(nil), nil
+}
+
+// Limits for the length of derived keys.
+const (
+	MinDeriveLen = 16
+	MaxDeriveLen = 64
+)
+
+// Derive derives a new key of the given length from k,
+// the label and the context using HKDF-SHA256. The same
+// label and context always produce the same derived key.
+//
+// Like HMAC, Derive does not use the key bytes directly
+// but a dedicated derivation salt. Hence, derived keys
+// do not reveal any information about the encryption
+// key or any HMAC computed by k.
+//
+// It returns ErrNotSymmetric if k is an asymmetric key
+// and ErrExpired if the key has expired.
+func (k *Key) Derive(label string, context []byte, length int) ([]byte, error) {
+	if k.keyType.IsAsymmetric() {
+		return nil, ErrNotSymmetric
+	}
+	if k.Expired() {
+		return nil, ErrExpired
+	}
+	if label == "" {
+		return nil, kes.NewError(http.StatusBadRequest, "invalid key derivation label: label is empty")
+	}
+	if length < MinDeriveLen || length > MaxDeriveLen {
+		return nil, kes.NewError(http.StatusBadRequest, "invalid key derivation length: length must be between 16 and 64 bytes")
+	}
+
+	// The label is length-prefixed such that no two
+	// (label, context) pairs produce the same info.
+	info := make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+len(label)+len(context))
+	info = info[:binary.PutUvarint(info, uint64(len(label)))]
+	info = append(info, label...)
+	info = append(info, context...)
+
+	derivedKey := make([]byte, length)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, k.bytes, []byte("KES key derivation"), info), derivedKey); err != nil {
+		return nil, err
+	}
+	return derivedKey, nil
+}
+
 // newAEAD returns a new AEAD cipher that implements the given
 // algorithm and is initialized with the given key and iv.
 func newAEAD(algorithm kes.KeyAlgorithm, Key, IV []byte) (cipher.AEAD, error) {
@@ -318,6 +550,29 @@ func newAEAD(algorithm kes.KeyAlgorithm, Key, IV []byte) (cipher.AEAD, error) {
 	}
 }
 
+// newSIV returns a new AES-256-GCM-SIV cipher that is
+// initialized with the given key and iv. AES-256-GCM-SIV
+// is not a FIPS 140-2 approved algorithm.
+func newSIV(Key, IV []byte) (gcmSIV, error) {
+	if fips.Enabled {
+		return gcmSIV{}, kes.ErrDecrypt
+	}
+	mac := hmac.New(sha256.New, Key)
+	mac.Write(IV)
+	return newGCMSIV(mac.Sum(nil))
+}
+
+func cloneTags(tags map[string]string) map[string]string {
+	if len(tags) == 0 {
+		return nil
+	}
+	c := make(map[string]string, len(tags))
+	for k, v := range tags {
+		c[k] = v
+	}
+	return c
+}
+
 func clone(b ...byte) []byte {
 	c := make([]byte, 0, len(b))
 	return append(c, b...)
@@ -42,6 +42,13 @@ var parseTests = []struct {
 		CreatedAt: mustDecodeTime("2009-11-10T23:00:00Z"),
 		CreatedBy: "189d9de5331e3ee8abe9e4bd40d474ad621d79ccf83a711f6ac68050eb15a52a",
 	},
+	{
+		Raw:       `{"bytes":"9ew6BCae3+13sniOUwttEJ62amg98YXc0OW0WBhNiCY=","algorithm":"AES256-GCM-SIV","created_at":"2009-11-10T23:00:00Z","created_by":"189d9de5331e3ee8abe9e4bd40d474ad621d79ccf83a711f6ac68050eb15a52a"}`,
+		Bytes:     mustDecodeHex("f5ec3a04269edfed77b2788e530b6d109eb66a683df185dcd0e5b458184d8826"),
+		Algorithm: AES256_GCM_SIV,
+		CreatedAt: mustDecodeTime("2009-11-10T23:00:00Z"),
+		CreatedBy: "189d9de5331e3ee8abe9e4bd40d474ad621d79ccf83a711f6ac68050eb15a52a",
+	},
 
 	{Raw: `"bytes":"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}`, ShouldFail: true}, // Missing: {
 	{Raw: `{bytes":"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}`, ShouldFail: true}, // Missing first: "
@@ -75,6 +82,37 @@ func TestParse(t *testing.T) {
 	}
 }
 
+var parseAlgorithmTests = []struct {
+	Algorithm  string
+	Result     kes.KeyAlgorithm
+	ShouldFail bool
+}{
+	{Algorithm: "", Result: kes.KeyAlgorithmUndefined},
+	{Algorithm: "AES256-GCM_SHA256", Result: kes.AES256_GCM_SHA256},
+	{Algorithm: "aes-256-gcm", Result: kes.AES256_GCM_SHA256},
+	{Algorithm: "XCHACHA20-POLY1305", Result: kes.XCHACHA20_POLY1305},
+	{Algorithm: "xchacha20", Result: kes.XCHACHA20_POLY1305},
+	{Algorithm: "AES256-GCM-SIV", Result: AES256_GCM_SIV},
+	{Algorithm: "aes-256-gcm-siv", Result: AES256_GCM_SIV},
+	{Algorithm: "AES-128-GCM", ShouldFail: true},
+	{Algorithm: "RSA-2048", ShouldFail: true},
+}
+
+func TestParseAlgorithm(t *testing.T) {
+	for i, test := range parseAlgorithmTests {
+		algorithm, err := ParseAlgorithm(test.Algorithm)
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to parse algorithm: %v", i, err)
+		}
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: parsing should have failed but succeeded", i)
+		}
+		if err == nil && algorithm != test.Result {
+			t.Fatalf("Test %d: algorithm mismatch: got '%v' - want '%v'", i, algorithm, test.Result)
+		}
+	}
+}
+
 var keyWrapTests = []struct {
 	KeyLen         int
 	AssociatedData []byte
@@ -89,7 +127,7 @@ var keyWrapTests = []struct {
 }
 
 func TestKeyWrap(t *testing.T) {
-	algorithms := []kes.KeyAlgorithm{kes.AES256_GCM_SHA256, kes.XCHACHA20_POLY1305}
+	algorithms := []kes.KeyAlgorithm{kes.AES256_GCM_SHA256, kes.XCHACHA20_POLY1305, AES256_GCM_SIV}
 	for _, a := range algorithms {
 		key, err := Random(a, "")
 		if err != nil {
@@ -111,6 +149,9 @@ func TestKeyWrap(t *testing.T) {
 				t.Logf("Test %d: Secret: %x\n", i, key.bytes)
 				t.Fatalf("Test %d: Original plaintext does not match unwrapped plaintext", i)
 			}
+			if _, err = key.Unwrap(ciphertext, append(test.AssociatedData, 0)); err != kes.ErrDecrypt {
+				t.Fatalf("Test %d: Unwrapping with different associated data: got err '%v' - want '%v'", i, err, kes.ErrDecrypt)
+			}
 		}
 	}
 }
@@ -148,57 +189,69 @@ var keyUnwrapTests = []struct {
 		Ciphertext:     string(mustDecodeB64("lbJYQ0hBQ0hBMjAtUE9MWTEzMDXZIDY2Njg3YWFkZjg2MmJkNzc2YzhmYzE4YjhlOWY4ZTIwxBBAr+aptD4x2+qfOhiErbnkxAxYs8RmNC1JJXD1hiHEIJ2KqM0jjkME7ndx8nyVseesN83Np0rM5ejVUun+fNFu")),
 		AssociatedData: nil,
 	},
-
 	{ // 5
+		Algorithm:      AES256_GCM_SIV,
+		Ciphertext:     string(mustDecodeB64("la5BRVMyNTYtR0NNLVNJVtkgNjY2ODdhYWRmODYyYmQ3NzZjOGZjMThiOGU5ZjhlMjDEECrU9Hw+jhrq0fVsaGvZ4DbEDJLXeYoXms1IP6kaLsQgHANnaKuae6JGFc0eSzAJN33IsuNI9ploTo0C9MDKkUc=")),
+		AssociatedData: nil,
+	},
+	{ // 6
+		Algorithm:      kes.AES256_GCM_SHA256,
+		Ciphertext:     string(mustDecodeB64("la5BRVMyNTYtR0NNLVNJVtkgNjY2ODdhYWRmODYyYmQ3NzZjOGZjMThiOGU5ZjhlMjDEECrU9Hw+jhrq0fVsaGvZ4DbEDJLXeYoXms1IP6kaLsQgHANnaKuae6JGFc0eSzAJN33IsuNI9ploTo0C9MDKkUc=")),
+		AssociatedData: nil,
+		ShouldFail:     true, // algorithm mismatch
+		Err:            kes.ErrDecrypt,
+	},
+
+	{ // 7
 		Algorithm:      kes.KeyAlgorithmUndefined,
 		Ciphertext:     `{"aead":"AES-256-GCM","iv":"xLxIN3tSCkg2xMafuvwUwg==","nonce":"gu0mGwUkwcvMEoi5","bytes":"WVgRjeIJm3w50C/l+y7y2i6mbNg5NCAqN1zvOYWZKmc="}`,
 		AssociatedData: nil,
 		ShouldFail:     true, // Invalid algorithm
 		Err:            kes.ErrDecrypt,
 	},
-	{ // 6
+	{ // 8
 		Algorithm:      kes.KeyAlgorithmUndefined,
 		Ciphertext:     `{"aead":"AES-256-GCM-HMAC-SHA-256","iv":"EjOY4JKqjIrPmQ5z1KSR8zlhggY=","nonce":"gu0mGwUkwcvMEoi5","bytes":"WVgRjeIJm3w50C/l+y7y2i6mbNg5NCAqN1zvOYWZKmc="}`,
 		AssociatedData: nil,
 		ShouldFail:     true, // invalid IV length
 		Err:            kes.ErrDecrypt,
 	},
-	{ // 7
+	{ // 9
 		Algorithm:      kes.KeyAlgorithmUndefined,
 		Ciphertext:     `{"aead":"ChaCha20Poly1305","iv":"s3fSZ6vk5m+DfQA8yZWeUg==","nonce":"SXAbms731/c=","bytes":"cw22HjLq/4cx8507SW4hhSrYbDiMuRao4b5+GE+XfbE="}`,
 		AssociatedData: nil,
 		ShouldFail:     true, // invalid nonce length
 		Err:            kes.ErrDecrypt,
 	},
-	{ // 8
+	{ // 10
 		Algorithm:      kes.KeyAlgorithmUndefined,
 		Ciphertext:     `{"aead":"AES-256-GCM-HMAC-SHA-256","iv":"xLxIN3tSCkg2xMafuvwUwg==","nonce":"efY+4kYF9n8=","bytes":"WVgRjeIJm3w50C/l+y7y2i6mbNg5NCAqN1zvOYWZKmc="}`,
 		AssociatedData: nil,
 		ShouldFail:     true, // invalid nonce length
 		Err:            kes.ErrDecrypt,
 	},
-	{ // 9
+	{ // 11
 		Algorithm:      kes.KeyAlgorithmUndefined,
 		Ciphertext:     `{"aead":"AES-256-GCM-HMAC-SHA-256","iv":"xLxIN3tSCkg2xMafuvwUwg==","nonce":"gu0mGwUkwcvMEoi5","bytes":"QTza1g5oX3f9cGJMbY1xJwWPj1F7R2VnNl6XpFKYQy0="}`,
 		AssociatedData: nil,
 		ShouldFail:     true, // ciphertext not authentic
 		Err:            kes.ErrDecrypt,
 	},
-	{ // 10
+	{ // 12
 		Algorithm:      kes.KeyAlgorithmUndefined,
 		Ciphertext:     `{"aead":"ChaCha20Poly1305","iv":"s3fSZ6vk5m+DfQA8yZWeUg==","nonce":"8/kHMnCMs3h9NZ2a","bytes":"TTi8pkO+Jh1JWAHvPxZeUk/iVoBPUCE4ZSVGBy3fW2s="}`,
 		AssociatedData: nil,
 		ShouldFail:     true, // ciphertext not authentic
 		Err:            kes.ErrDecrypt,
 	},
-	{ // 11
+	{ // 13
 		Algorithm:      kes.KeyAlgorithmUndefined,
 		Ciphertext:     `{"aead":"AES-256-GCM-HMAC-SHA-256" "iv":"xLxIN3tSCkg2xMafuvwUwg==","nonce":"gu0mGwUkwcvMEoi5","bytes":"WVgRjeIJm3w50C/l+y7y2i6mbNg5NCAqN1zvOYWZKmc="}`,
 		AssociatedData: nil,
 		ShouldFail:     true, // invalid JSON
 		Err:            kes.ErrDecrypt,
 	},
-	{ // 12
+	{ // 14
 		Algorithm:      kes.KeyAlgorithmUndefined,
 		Ciphertext:     `{"aead":"AES-256-GCM-HMAC-SHA-256", "id":"00010203040506070809101112131415", "iv":"xLxIN3tSCkg2xMafuvwUwg==","nonce":"gu0mGwUkwcvMEoi5","bytes":"WVgRjeIJm3w50C/l+y7y2i6mbNg5NCAqN1zvOYWZKmc="}`,
 		AssociatedData: nil,
@@ -253,3 +306,221 @@ func mustDecodeB64(s string) []byte {
 	}
 	return b
 }
+
+func TestKeyHMAC(t *testing.T) {
+	key, err := New(kes.AES256_GCM_SHA256, make([]byte, 32), "")
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	sum, err := key.HMAC([]byte("Hello World"))
+	if err != nil {
+		t.Fatalf("Failed to compute HMAC: %v", err)
+	}
+	sum2, err := key.HMAC([]byte("Hello World"))
+	if err != nil {
+		t.Fatalf("Failed to compute HMAC: %v", err)
+	}
+	if !bytes.Equal(sum, sum2) {
+		t.Fatalf("HMAC is not deterministic: %x != %x", sum, sum2)
+	}
+	if sum3, _ := key.HMAC([]byte("Hello KES")); bytes.Equal(sum, sum3) {
+		t.Fatal("HMAC of different messages are equal")
+	}
+}
+
+func TestKeyDerive(t *testing.T) {
+	key, err := New(kes.AES256_GCM_SHA256, make([]byte, 32), "")
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	derivedKey, err := key.Derive("object-key", []byte("my-bucket/my-object"), 32)
+	if err != nil {
+		t.Fatalf("Failed to derive key: %v", err)
+	}
+	if len(derivedKey) != 32 {
+		t.Fatalf("Derived key length mismatch: got '%d' - want '%d'", len(derivedKey), 32)
+	}
+	derivedKey2, err := key.Derive("object-key", []byte("my-bucket/my-object"), 32)
+	if err != nil {
+		t.Fatalf("Failed to derive key: %v", err)
+	}
+	if !bytes.Equal(derivedKey, derivedKey2) {
+		t.Fatalf("Key derivation is not deterministic: %x != %x", derivedKey, derivedKey2)
+	}
+	if k, _ := key.Derive("object-key", []byte("my-bucket/my-object2"), 32); bytes.Equal(derivedKey, k) {
+		t.Fatal("Derived keys of different contexts are equal")
+	}
+	if k, _ := key.Derive("object-keym", []byte("y-bucket/my-object"), 32); bytes.Equal(derivedKey, k) {
+		t.Fatal("Derived keys of different labels are equal")
+	}
+	if _, err = key.Derive("", nil, 32); err == nil {
+		t.Fatal("Key derivation with empty label succeeded")
+	}
+	if _, err = key.Derive("object-key", nil, MaxDeriveLen+1); err == nil {
+		t.Fatal("Key derivation with invalid length succeeded")
+	}
+}
+
+func TestKeyExpiry(t *testing.T) {
+	key, err := New(kes.AES256_GCM_SHA256, make([]byte, 32), "")
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	ciphertext, err := key.Wrap([]byte("Hello World"), nil)
+	if err != nil {
+		t.Fatalf("Failed to encrypt message: %v", err)
+	}
+
+	key.SetExpiresAt(time.Now().Add(-1 * time.Second))
+	text, err := key.MarshalText()
+	if err != nil {
+		t.Fatalf("Failed to encode key: %v", err)
+	}
+	k, err := Parse(text)
+	if err != nil {
+		t.Fatalf("Failed to decode key: %v", err)
+	}
+	if !k.ExpiresAt().Equal(key.ExpiresAt()) {
+		t.Fatalf("Key expiry mismatch: got '%v' - want '%v'", k.ExpiresAt(), key.ExpiresAt())
+	}
+
+	if !key.Expired() {
+		t.Fatal("Key has not expired")
+	}
+	if _, err = key.Wrap([]byte("Hello World"), nil); err != ErrExpired {
+		t.Fatalf("Encryption with expired key: got err '%v' - want '%v'", err, ErrExpired)
+	}
+	if _, err = key.Unwrap(ciphertext, nil); err != ErrExpired {
+		t.Fatalf("Decryption with expired key: got err '%v' - want '%v'", err, ErrExpired)
+	}
+
+	key.SetExpiresAt(time.Time{})
+	if _, err = key.Unwrap(ciphertext, nil); err != nil {
+		t.Fatalf("Failed to decrypt message: %v", err)
+	}
+}
+
+func TestKeyTags(t *testing.T) {
+	key, err := New(kes.AES256_GCM_SHA256, make([]byte, 32), "")
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	tags := map[string]string{"env": "prod", "tenant": "acme"}
+	key.SetTags(tags)
+	tags["env"] = "dev"
+	if !key.HasTag("env", "prod") {
+		t.Fatal("Key tags are not copied")
+	}
+
+	text, err := key.MarshalText()
+	if err != nil {
+		t.Fatalf("Failed to encode key: %v", err)
+	}
+	binary, err := key.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Failed to encode key: %v", err)
+	}
+	var k Key
+	for _, decode := range []func() error{
+		func() error { return k.UnmarshalText(text) },
+		func() error { return k.UnmarshalBinary(binary) },
+	} {
+		k = Key{}
+		if err = decode(); err != nil {
+			t.Fatalf("Failed to decode key: %v", err)
+		}
+		if len(k.Tags()) != 2 || !k.HasTag("env", "prod") || !k.HasTag("tenant", "acme") {
+			t.Fatalf("Key tags mismatch: got '%v' - want '%v'", k.Tags(), key.Tags())
+		}
+	}
+
+	clone := key.Clone()
+	clone.SetTags(nil)
+	if len(key.Tags()) != 2 {
+		t.Fatal("Clone shares tags with original key")
+	}
+}
+
+func TestCeremony(t *testing.T) {
+	ceremony, err := NewCeremony(kes.AES256_GCM_SHA256, 3, time.Minute, "admin")
+	if err != nil {
+		t.Fatalf("Failed to start key ceremony: %v", err)
+	}
+
+	custodians := []kes.Identity{"custodian-1", "custodian-2", "custodian-3"}
+	for i, identity := range custodians {
+		entropy, err := randomBytes(Size)
+		if err != nil {
+			t.Fatalf("Failed to generate entropy: %v", err)
+		}
+		key, done, err := ceremony.Contribute(identity, entropy)
+		if err != nil {
+			t.Fatalf("Custodian %d: failed to contribute: %v", i, err)
+		}
+		if _, _, err = ceremony.Contribute(identity, entropy); err != ErrContributed && err != ErrCeremonyDone {
+			t.Fatalf("Custodian %d: contributed twice: %v", i, err)
+		}
+		if last := i == len(custodians)-1; done != last {
+			t.Fatalf("Custodian %d: ceremony complete: got '%v' - want '%v'", i, done, last)
+		}
+		if !done {
+			continue
+		}
+
+		if key.CreatedBy() != "admin" || key.Algorithm() != kes.AES256_GCM_SHA256 {
+			t.Fatalf("Invalid key: created by '%s' with algorithm '%v'", key.CreatedBy(), key.Algorithm())
+		}
+		if _, err = key.Wrap([]byte("Hello World"), nil); err != nil {
+			t.Fatalf("Failed to encrypt message: %v", err)
+		}
+	}
+	if n := len(ceremony.Contributions()); n != len(custodians) {
+		t.Fatalf("Invalid number of contributions: got '%d' - want '%d'", n, len(custodians))
+	}
+	if _, _, err = ceremony.Contribute("custodian-4", make([]byte, Size)); err != ErrCeremonyDone {
+		t.Fatalf("Contributing to complete ceremony: got err '%v' - want '%v'", err, ErrCeremonyDone)
+	}
+	if _, ok := ceremony.Result("custodian-4"); ok {
+		t.Fatal("Non-custodian obtained the result of the ceremony")
+	}
+	if key, ok := ceremony.Result("custodian-1"); !ok || key.CreatedBy() != "admin" {
+		t.Fatal("Custodian failed to obtain the result of the ceremony")
+	}
+}
+
+func TestCeremonyContributionSplit(t *testing.T) {
+	// The contributions of both ceremonies only differ in
+	// where the split between identity and entropy falls.
+	entropy := make([]byte, Size+1)
+	entropy[0] = 'x'
+
+	first, err := NewCeremony(kes.AES256_GCM_SHA256, 2, time.Minute, "admin")
+	if err != nil {
+		t.Fatalf("Failed to start key ceremony: %v", err)
+	}
+	second, err := NewCeremony(kes.AES256_GCM_SHA256, 2, time.Minute, "admin")
+	if err != nil {
+		t.Fatalf("Failed to start key ceremony: %v", err)
+	}
+	second.seed = first.seed
+
+	if _, _, err = first.Contribute("custodian-1", entropy); err != nil {
+		t.Fatalf("Failed to contribute: %v", err)
+	}
+	if _, _, err = second.Contribute("custodian-1x", entropy[1:]); err != nil {
+		t.Fatalf("Failed to contribute: %v", err)
+	}
+
+	last := make([]byte, Size)
+	firstKey, _, err := first.Contribute("custodian-2", last)
+	if err != nil {
+		t.Fatalf("Failed to contribute: %v", err)
+	}
+	secondKey, _, err := second.Contribute("custodian-2", last)
+	if err != nil {
+		t.Fatalf("Failed to contribute: %v", err)
+	}
+	if bytes.Equal(firstKey.bytes, secondKey.bytes) {
+		t.Fatal("Contributions that only differ in where the split falls produced the same key")
+	}
+}
@@ -0,0 +1,292 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package alibaba
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"aead.dev/mem"
+	xhttp "github.com/minio/kes/internal/http"
+)
+
+// credentialProvider provides the credentials used
+// to sign Alibaba Cloud API requests.
+type credentialProvider interface {
+	// Credentials returns the credentials for
+	// signing the next request.
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+// apiError is an Alibaba Cloud API error response.
+type apiError struct {
+	StatusCode int
+	Code       string `json:"Code"`
+	Message    string `json:"Message"`
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%s: %s (%d)", e.Code, e.Message, e.StatusCode)
+}
+
+type client struct {
+	xhttp.Retry
+	Provider credentialProvider
+}
+
+// Call invokes the RPC-style API action at the given endpoint
+// with the given API version and parameters. It signs the
+// request with the credentials of the client's provider and
+// decodes the JSON response into v.
+//
+// If the API returns an error, Call returns an *apiError.
+func (c *client) Call(ctx context.Context, endpoint, version, action string, params url.Values, v any) error {
+	creds, err := c.Provider.Credentials(ctx)
+	if err != nil {
+		return err
+	}
+	return c.call(ctx, endpoint, version, action, params, creds, v)
+}
+
+func (c *client) call(ctx context.Context, endpoint, version, action string, params url.Values, creds Credentials, v any) error {
+	var nonce [16]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	for k, vs := range params {
+		query[k] = vs
+	}
+	query.Set("Action", action)
+	query.Set("Version", version)
+	query.Set("Format", "JSON")
+	query.Set("AccessKeyId", creds.AccessKeyID)
+	query.Set("SignatureMethod", "HMAC-SHA1")
+	query.Set("SignatureVersion", "1.0")
+	query.Set("SignatureNonce", hex.EncodeToString(nonce[:]))
+	query.Set("Timestamp", time.Now().UTC().Format("2006-01-02T15:04:05Z"))
+	if creds.SecurityToken != "" {
+		query.Set("SecurityToken", creds.SecurityToken)
+	}
+	query.Set("Signature", signature(http.MethodPost, query, creds.AccessKeySecret))
+
+	body := canonicalQuery(query)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/", xhttp.RetryReader(strings.NewReader(body)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	const MaxSize = 32 * mem.MiB // A response should not exceed 32 MiB
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &apiError{StatusCode: resp.StatusCode}
+		if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(apiErr); err != nil || apiErr.Code == "" {
+			apiErr.Code, apiErr.Message = resp.Status, http.StatusText(resp.StatusCode)
+		}
+		return apiErr
+	}
+	if v == nil {
+		return nil
+	}
+	return json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(v)
+}
+
+// signature returns the Alibaba Cloud RPC signature (v1.0)
+// of the request parameters using the access key secret.
+func signature(method string, query url.Values, secret string) string {
+	stringToSign := method + "&" + percentEncode("/") + "&" + percentEncode(canonicalQuery(query))
+
+	mac := hmac.New(sha1.New, []byte(secret+"&"))
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// canonicalQuery returns the percent-encoded query
+// parameters sorted by name.
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		for _, v := range query[k] {
+			if b.Len() > 0 {
+				b.WriteByte('&')
+			}
+			b.WriteString(percentEncode(k))
+			b.WriteByte('=')
+			b.WriteString(percentEncode(v))
+		}
+	}
+	return b.String()
+}
+
+// percentEncode encodes s as specified by RFC 3986,
+// as required by the Alibaba Cloud RPC signature.
+func percentEncode(s string) string {
+	s = url.QueryEscape(s)
+	s = strings.ReplaceAll(s, "+", "%20")
+	s = strings.ReplaceAll(s, "*", "%2A")
+	return strings.ReplaceAll(s, "%7E", "~")
+}
+
+// staticCredentials is a credentialProvider that
+// always returns the same credentials.
+type staticCredentials Credentials
+
+func (c staticCredentials) Credentials(context.Context) (Credentials, error) {
+	return Credentials(c), nil
+}
+
+// temporaryCredentials is a credentialProvider that
+// obtains short-lived credentials and renews them
+// before they expire.
+type temporaryCredentials struct {
+	fetch func(context.Context) (Credentials, time.Time, error)
+
+	lock   sync.Mutex
+	creds  Credentials
+	expiry time.Time
+}
+
+func (c *temporaryCredentials) Credentials(ctx context.Context) (Credentials, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// Renew the credentials a bit before they expire
+	// to avoid requests failing due to clock skew.
+	if time.Until(c.expiry) < 5*time.Minute {
+		creds, expiry, err := c.fetch(ctx)
+		if err != nil {
+			return Credentials{}, err
+		}
+		c.creds, c.expiry = creds, expiry
+	}
+	return c.creds, nil
+}
+
+// ecsRAMRole returns a credentialProvider that obtains
+// credentials of the given RAM role attached to the
+// ECS instance from the instance metadata service.
+func ecsRAMRole(c *client, role string) *temporaryCredentials {
+	const Endpoint = "http://100.100.100.200/latest/meta-data/ram/security-credentials/"
+	type Response struct {
+		Code            string `json:"Code"`
+		AccessKeyID     string `json:"AccessKeyId"`
+		AccessKeySecret string `json:"AccessKeySecret"`
+		SecurityToken   string `json:"SecurityToken"`
+		Expiration      string `json:"Expiration"`
+	}
+
+	return &temporaryCredentials{
+		fetch: func(ctx context.Context) (Credentials, time.Time, error) {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, Endpoint+url.PathEscape(role), nil)
+			if err != nil {
+				return Credentials{}, time.Time{}, err
+			}
+			resp, err := c.Do(req)
+			if err != nil {
+				return Credentials{}, time.Time{}, fmt.Errorf("alibaba: failed to fetch ECS RAM role credentials: %v", err)
+			}
+			defer resp.Body.Close()
+
+			const MaxSize = 1 * mem.MiB
+			if resp.StatusCode != http.StatusOK {
+				body, _ := io.ReadAll(mem.LimitReader(resp.Body, MaxSize))
+				return Credentials{}, time.Time{}, fmt.Errorf("alibaba: failed to fetch ECS RAM role credentials: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+			}
+			var response Response
+			if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(&response); err != nil {
+				return Credentials{}, time.Time{}, fmt.Errorf("alibaba: failed to fetch ECS RAM role credentials: %v", err)
+			}
+			if response.Code != "Success" {
+				return Credentials{}, time.Time{}, fmt.Errorf("alibaba: failed to fetch ECS RAM role credentials: %s", response.Code)
+			}
+			expiry, err := time.Parse(time.RFC3339, response.Expiration)
+			if err != nil {
+				return Credentials{}, time.Time{}, fmt.Errorf("alibaba: invalid ECS RAM role credentials expiration: %v", err)
+			}
+			return Credentials{
+				AccessKeyID:     response.AccessKeyID,
+				AccessKeySecret: response.AccessKeySecret,
+				SecurityToken:   response.SecurityToken,
+			}, expiry, nil
+		},
+	}
+}
+
+// assumeRole returns a credentialProvider that obtains
+// credentials of the given RAM role from the STS endpoint
+// using the credentials of the given provider.
+func assumeRole(c *client, endpoint, roleARN, sessionName string, provider credentialProvider) *temporaryCredentials {
+	const (
+		Version  = "2015-04-01"
+		Duration = time.Hour
+	)
+	type Response struct {
+		Credentials struct {
+			AccessKeyID     string `json:"AccessKeyId"`
+			AccessKeySecret string `json:"AccessKeySecret"`
+			SecurityToken   string `json:"SecurityToken"`
+			Expiration      string `json:"Expiration"`
+		} `json:"Credentials"`
+	}
+
+	return &temporaryCredentials{
+		fetch: func(ctx context.Context) (Credentials, time.Time, error) {
+			creds, err := provider.Credentials(ctx)
+			if err != nil {
+				return Credentials{}, time.Time{}, err
+			}
+
+			params := url.Values{}
+			params.Set("RoleArn", roleARN)
+			params.Set("RoleSessionName", sessionName)
+			params.Set("DurationSeconds", fmt.Sprint(int(Duration.Seconds())))
+
+			var response Response
+			if err = c.call(ctx, endpoint, Version, "AssumeRole", params, creds, &response); err != nil {
+				return Credentials{}, time.Time{}, fmt.Errorf("alibaba: failed to assume RAM role '%s': %v", roleARN, err)
+			}
+			expiry, err := time.Parse(time.RFC3339, response.Credentials.Expiration)
+			if err != nil {
+				return Credentials{}, time.Time{}, fmt.Errorf("alibaba: invalid RAM role credentials expiration: %v", err)
+			}
+			if response.Credentials.AccessKeyID == "" {
+				return Credentials{}, time.Time{}, errors.New("alibaba: STS response does not contain credentials")
+			}
+			return Credentials{
+				AccessKeyID:     response.Credentials.AccessKeyID,
+				AccessKeySecret: response.Credentials.AccessKeySecret,
+				SecurityToken:   response.Credentials.SecurityToken,
+			}, expiry, nil
+		},
+	}
+}
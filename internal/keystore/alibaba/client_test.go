@@ -0,0 +1,48 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package alibaba
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestSignature(t *testing.T) {
+	// Example from the Alibaba Cloud RPC signature documentation.
+	const Signature = "OLeaidS1JvxuMvnyHOwuJ+uX5qY="
+
+	query := url.Values{}
+	query.Set("AccessKeyId", "testid")
+	query.Set("Action", "DescribeRegions")
+	query.Set("Format", "XML")
+	query.Set("SignatureMethod", "HMAC-SHA1")
+	query.Set("SignatureNonce", "3ee8c1b8-83d3-44af-a94f-4e0ad82fd6cf")
+	query.Set("SignatureVersion", "1.0")
+	query.Set("Timestamp", "2016-02-23T12:46:24Z")
+	query.Set("Version", "2014-05-26")
+
+	if s := signature("GET", query, "testsecret"); s != Signature {
+		t.Fatalf("Invalid signature: got '%s' - want '%s'", s, Signature)
+	}
+}
+
+var percentEncodeTests = []struct {
+	Value   string
+	Encoded string
+}{
+	{Value: "my-key", Encoded: "my-key"},                                 // 0
+	{Value: "a b", Encoded: "a%20b"},                                     // 1
+	{Value: "a*b", Encoded: "a%2Ab"},                                     // 2
+	{Value: "a~b", Encoded: "a~b"},                                       // 3
+	{Value: "2016-02-23T12:46:24Z", Encoded: "2016-02-23T12%3A46%3A24Z"}, // 4
+}
+
+func TestPercentEncode(t *testing.T) {
+	for i, test := range percentEncodeTests {
+		if s := percentEncode(test.Value); s != test.Encoded {
+			t.Fatalf("Test %d: got '%s' - want '%s'", i, s, test.Encoded)
+		}
+	}
+}
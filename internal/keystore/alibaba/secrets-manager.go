@@ -0,0 +1,392 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package alibaba implements a key store that stores keys
+// as secrets on Alibaba Cloud KMS Secrets Manager.
+package alibaba
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minio/kes-go"
+	xhttp "github.com/minio/kes/internal/http"
+	"github.com/minio/kes/internal/keystore/batch"
+	"github.com/minio/kes/internal/keystore/cas"
+	"github.com/minio/kes/kv"
+)
+
+// Credentials are Alibaba Cloud access key credentials
+// of a RAM user or temporary STS credentials.
+type Credentials struct {
+	AccessKeyID     string // The access key ID
+	AccessKeySecret string // The access key secret
+	SecurityToken   string // Optional STS security token
+}
+
+// Config is a structure containing configuration
+// options for connecting to Alibaba Cloud KMS.
+type Config struct {
+	// Region is the Alibaba Cloud region ID,
+	// e.g. cn-hangzhou.
+	Region string
+
+	// Endpoint is an optional KMS endpoint, e.g. a VPC
+	// endpoint. If empty, the public endpoint of the
+	// region, https://kms.<region>.aliyuncs.com, is used.
+	Endpoint string
+
+	// KMSKeyID is an optional ID of the KMS key used to
+	// encrypt secrets. If empty, the KMS service key is
+	// used.
+	KMSKeyID string
+
+	// Login are the access key credentials of a RAM user.
+	// They are ignored if ECSRAMRole is not empty.
+	Login Credentials
+
+	// ECSRAMRole is the name of the RAM role attached to
+	// the ECS instance. If not empty, the credentials of
+	// this role are fetched from the instance metadata
+	// service.
+	ECSRAMRole string
+
+	// RoleARN is an optional ARN of a RAM role that is
+	// assumed via STS using either the Login or the ECS
+	// RAM role credentials.
+	RoleARN string
+
+	// RoleSessionName is the session name when assuming
+	// the RoleARN. If empty, defaults to "kes".
+	RoleSessionName string
+
+	// STSEndpoint is an optional STS endpoint used to
+	// assume the RoleARN. If empty, the public endpoint
+	// of the region, https://sts.<region>.aliyuncs.com,
+	// is used.
+	STSEndpoint string
+}
+
+// Store is an Alibaba Cloud Secrets Manager secret store.
+type Store struct {
+	config Config
+	client *client
+
+	locks cas.Locker
+}
+
+var _ kv.Store[string, []byte] = (*Store)(nil)
+
+// Connect returns a Store to Alibaba Cloud KMS Secrets Manager
+// using the given config.
+func Connect(ctx context.Context, config *Config) (*Store, error) {
+	if config.Region == "" {
+		return nil, errors.New("alibaba: no region specified")
+	}
+	if config.ECSRAMRole == "" && (config.Login.AccessKeyID == "" || config.Login.AccessKeySecret == "") {
+		return nil, errors.New("alibaba: no access key or ECS RAM role specified")
+	}
+
+	c := *config
+	if c.Endpoint == "" {
+		c.Endpoint = "https://kms." + c.Region + ".aliyuncs.com"
+	}
+	if c.STSEndpoint == "" {
+		c.STSEndpoint = "https://sts." + c.Region + ".aliyuncs.com"
+	}
+	if c.RoleSessionName == "" {
+		c.RoleSessionName = "kes"
+	}
+	c.Endpoint = strings.TrimSuffix(c.Endpoint, "/")
+	c.STSEndpoint = strings.TrimSuffix(c.STSEndpoint, "/")
+
+	client := &client{
+		Retry: xhttp.Retry{
+			Client: http.Client{
+				Transport: &http.Transport{
+					Proxy: http.ProxyFromEnvironment,
+					DialContext: (&net.Dialer{
+						Timeout:   10 * time.Second,
+						KeepAlive: 10 * time.Second,
+					}).DialContext,
+					ForceAttemptHTTP2:     true,
+					MaxIdleConns:          100,
+					IdleConnTimeout:       30 * time.Second,
+					TLSHandshakeTimeout:   10 * time.Second,
+					ExpectContinueTimeout: 1 * time.Second,
+				},
+			},
+		},
+	}
+
+	var provider credentialProvider = staticCredentials(c.Login)
+	if c.ECSRAMRole != "" {
+		provider = ecsRAMRole(client, c.ECSRAMRole)
+	}
+	if c.RoleARN != "" {
+		provider = assumeRole(client, c.STSEndpoint, c.RoleARN, c.RoleSessionName, provider)
+	}
+	if _, err := provider.Credentials(ctx); err != nil {
+		return nil, err
+	}
+	client.Provider = provider
+
+	return &Store{
+		config: c,
+		client: client,
+	}, nil
+}
+
+// Status returns the current state of the Alibaba Cloud KMS
+// endpoint. In particular, whether it is reachable and the
+// network latency.
+func (s *Store) Status(ctx context.Context) (kv.State, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, s.config.Endpoint, nil)
+	if err != nil {
+		return kv.State{}, err
+	}
+
+	start := time.Now()
+	resp, err := s.client.Client.Do(req)
+	if err != nil {
+		return kv.State{}, &kv.Unreachable{Err: err}
+	}
+	resp.Body.Close()
+
+	return kv.State{
+		Latency: time.Since(start),
+	}, nil
+}
+
+// Create creates the given key-value pair as Secrets Manager
+// secret if and only if no secret with the given name exists.
+// If such a secret exists it returns kes.ErrKeyExists.
+func (s *Store) Create(ctx context.Context, name string, value []byte) error {
+	params := url.Values{}
+	params.Set("SecretName", name)
+	params.Set("SecretData", base64.StdEncoding.EncodeToString(value))
+	params.Set("SecretDataType", "binary")
+	params.Set("VersionId", "v1")
+	if s.config.KMSKeyID != "" {
+		params.Set("EncryptionKeyId", s.config.KMSKeyID)
+	}
+
+	err := s.client.Call(ctx, s.config.Endpoint, kmsVersion, "CreateSecret", params, nil)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	var apiErr *apiError
+	if errors.As(err, &apiErr) && apiErr.Code == "Rejected.ResourceExist" {
+		return kes.ErrKeyExists
+	}
+	if err != nil {
+		return fmt.Errorf("alibaba: failed to create key '%s': %v", name, err)
+	}
+	return nil
+}
+
+// Set creates the given key-value pair as Secrets Manager
+// secret if and only if no secret with the given name exists.
+// If such a secret exists it returns kes.ErrKeyExists.
+func (s *Store) Set(ctx context.Context, name string, value []byte) error {
+	return s.Create(ctx, name, value)
+}
+
+// Update replaces the value of the given key with newValue
+// if and only if its current value is equal to oldValue.
+//
+// Secrets Manager does not support compare-and-swap.
+// Hence, Update puts a new secret version, which becomes
+// the current version, while holding a lock for the key.
+// Updates of other KES servers may race.
+func (s *Store) Update(ctx context.Context, name string, oldValue, newValue []byte) error {
+	return s.locks.Update(ctx, s, name, oldValue, newValue, func(ctx context.Context, name string, _, value []byte) error {
+		var version [16]byte
+		if _, err := rand.Read(version[:]); err != nil {
+			return fmt.Errorf("alibaba: failed to update key '%s': %v", name, err)
+		}
+
+		params := url.Values{}
+		params.Set("SecretName", name)
+		params.Set("SecretData", base64.StdEncoding.EncodeToString(value))
+		params.Set("SecretDataType", "binary")
+		params.Set("VersionId", hex.EncodeToString(version[:]))
+
+		err := s.client.Call(ctx, s.config.Endpoint, kmsVersion, "PutSecretValue", params, nil)
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+		var apiErr *apiError
+		if errors.As(err, &apiErr) && apiErr.Code == "Forbidden.ResourceNotFound" {
+			return kes.ErrKeyNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("alibaba: failed to update key '%s': %v", name, err)
+		}
+		return nil
+	})
+}
+
+// Get returns the value associated with the given key.
+// If no entry for the key exists it returns kes.ErrKeyNotFound.
+func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
+	type Response struct {
+		SecretData     string `json:"SecretData"`
+		SecretDataType string `json:"SecretDataType"`
+	}
+
+	params := url.Values{}
+	params.Set("SecretName", name)
+
+	var response Response
+	err := s.client.Call(ctx, s.config.Endpoint, kmsVersion, "GetSecretValue", params, &response)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var apiErr *apiError
+	if errors.As(err, &apiErr) && apiErr.Code == "Forbidden.ResourceNotFound" {
+		return nil, kes.ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("alibaba: failed to access key '%s': %v", name, err)
+	}
+
+	if response.SecretDataType != "binary" {
+		return []byte(response.SecretData), nil
+	}
+	value, err := base64.StdEncoding.DecodeString(response.SecretData)
+	if err != nil {
+		return nil, fmt.Errorf("alibaba: failed to read key '%s': %v", name, err)
+	}
+	return value, nil
+}
+
+// GetMany returns the values associated with the given keys.
+// Alibaba Cloud Secrets Manager has no batch API. Hence, GetMany
+// fetches up to batch.Concurrency keys concurrently.
+func (s *Store) GetMany(ctx context.Context, names []string) ([][]byte, []error) {
+	return batch.Get[string, []byte](ctx, s, names)
+}
+
+// Delete removes a the value associated with the given key
+// from Secrets Manager, if it exists.
+//
+// The secret is deleted immediately without recovery window.
+func (s *Store) Delete(ctx context.Context, name string) error {
+	params := url.Values{}
+	params.Set("SecretName", name)
+	params.Set("ForceDeleteWithoutRecovery", "true")
+
+	err := s.client.Call(ctx, s.config.Endpoint, kmsVersion, "DeleteSecret", params, nil)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	var apiErr *apiError
+	if errors.As(err, &apiErr) && apiErr.Code == "Forbidden.ResourceNotFound" {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("alibaba: failed to delete key '%s': %v", name, err)
+	}
+	return nil
+}
+
+// DeleteMany deletes the given keys. It deletes up to
+// batch.Concurrency keys concurrently since Alibaba Cloud
+// Secrets Manager has no batch API.
+func (s *Store) DeleteMany(ctx context.Context, names []string) []error {
+	return batch.Delete[string, []byte](ctx, s, names)
+}
+
+// List returns a new Iterator over the names of
+// all stored keys that start with the given prefix.
+func (s *Store) List(ctx context.Context, prefix string) (kv.Iter[string], error) {
+	type Response struct {
+		SecretList struct {
+			Secret []struct {
+				SecretName string `json:"SecretName"`
+			} `json:"Secret"`
+		} `json:"SecretList"`
+		TotalCount int `json:"TotalCount"`
+	}
+
+	var cancel context.CancelCauseFunc
+	ctx, cancel = context.WithCancelCause(ctx)
+	values := make(chan string, 10)
+
+	go func() {
+		defer close(values)
+
+		const PageSize = 100
+		for page, n := 1, 0; ; page++ {
+			params := url.Values{}
+			params.Set("PageNumber", strconv.Itoa(page))
+			params.Set("PageSize", strconv.Itoa(PageSize))
+
+			var response Response
+			err := s.client.Call(ctx, s.config.Endpoint, kmsVersion, "ListSecrets", params, &response)
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				cancel(err)
+				return
+			}
+			if err != nil {
+				cancel(fmt.Errorf("alibaba: failed to list keys: %v", err))
+				return
+			}
+
+			for _, secret := range response.SecretList.Secret {
+				if !strings.HasPrefix(secret.SecretName, prefix) {
+					continue
+				}
+				select {
+				case values <- secret.SecretName:
+				case <-ctx.Done():
+					return
+				}
+			}
+			n += len(response.SecretList.Secret)
+			if len(response.SecretList.Secret) < PageSize || n >= response.TotalCount {
+				return
+			}
+		}
+	}()
+	return &iter{
+		ch:     values,
+		ctx:    ctx,
+		cancel: cancel,
+	}, nil
+}
+
+// kmsVersion is the Alibaba Cloud KMS API version.
+const kmsVersion = "2016-01-20"
+
+type iter struct {
+	ch     <-chan string
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+}
+
+func (i *iter) Next() (string, bool) {
+	select {
+	case v, ok := <-i.ch:
+		return v, ok
+	case <-i.ctx.Done():
+		return "", false
+	}
+}
+
+func (i *iter) Close() error {
+	i.cancel(context.Canceled)
+	return context.Cause(i.ctx)
+}
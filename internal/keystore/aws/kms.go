@@ -0,0 +1,169 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/minio/kes/internal/keystore/envelope"
+	"github.com/minio/kes/kv"
+)
+
+// KMSConfig is a structure containing configuration
+// options for connecting to AWS KMS.
+type KMSConfig struct {
+	// Addr is an optional HTTP address of AWS KMS.
+	// If empty, the regional endpoint is used:
+	//  kms.<region>.amazonaws.com
+	Addr string
+
+	// Region is the AWS region.
+	Region string
+
+	// KeyID is the ID, ARN or alias of the symmetric
+	// AWS KMS key used to wrap data keys.
+	KeyID string
+
+	// Login contains the AWS credentials (access/secret key).
+	Login Credentials
+}
+
+// KMS is an AWS KMS key that wraps data keys.
+type KMS struct {
+	client *kms.KMS
+	keyID  string
+}
+
+var _ envelope.KeyWrapper = (*KMS)(nil)
+
+// ConnectKMS connects to AWS KMS and checks that the
+// configured key can be used to encrypt and decrypt
+// data keys.
+func ConnectKMS(ctx context.Context, config *KMSConfig) (*KMS, error) {
+	if config.KeyID == "" {
+		return nil, errors.New("aws: no KMS key specified")
+	}
+	if config.Region == "" {
+		return nil, errors.New("aws: no region specified")
+	}
+
+	credentials := credentials.NewStaticCredentials(
+		config.Login.AccessKey,
+		config.Login.SecretKey,
+		config.Login.SessionToken,
+	)
+	if config.Login.AccessKey == "" && config.Login.SecretKey == "" && config.Login.SessionToken == "" {
+		// As for the SecretsManager, the SDK fetches the credentials
+		// from the environment, the shared credentials file or the
+		// EC2 instance metadata if no credentials are specified.
+		credentials = nil
+	}
+	awsConfig := aws.Config{
+		Region:      aws.String(config.Region),
+		Credentials: credentials,
+	}
+	if config.Addr != "" {
+		awsConfig.Endpoint = aws.String(config.Addr)
+	}
+	session, err := session.NewSessionWithOptions(session.Options{
+		Config:            awsConfig,
+		SharedConfigState: session.SharedConfigDisable,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	client := kms.New(session)
+	key, err := client.DescribeKeyWithContext(ctx, &kms.DescribeKeyInput{
+		KeyId: aws.String(config.KeyID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("aws: failed to describe KMS key '%s': %v", config.KeyID, err)
+	}
+	if key.KeyMetadata != nil {
+		if usage := aws.StringValue(key.KeyMetadata.KeyUsage); usage != kms.KeyUsageTypeEncryptDecrypt {
+			return nil, fmt.Errorf("aws: invalid KMS key '%s': key usage is '%s' but must be '%s'", config.KeyID, usage, kms.KeyUsageTypeEncryptDecrypt)
+		}
+	}
+	return &KMS{
+		client: client,
+		keyID:  config.KeyID,
+	}, nil
+}
+
+// Status returns the current state of AWS KMS.
+// In particular, whether it is reachable and the
+// network latency.
+func (k *KMS) Status(ctx context.Context) (kv.State, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.client.Endpoint, nil)
+	if err != nil {
+		return kv.State{}, err
+	}
+
+	start := time.Now()
+	if _, err = http.DefaultClient.Do(req); err != nil {
+		return kv.State{}, &kv.Unreachable{Err: err}
+	}
+	return kv.State{
+		Latency: time.Since(start),
+	}, nil
+}
+
+// Wrap encrypts the plaintext with the AWS KMS key. The
+// associated data is bound to the ciphertext as AWS KMS
+// encryption context.
+func (k *KMS) Wrap(ctx context.Context, plaintext, associatedData []byte) ([]byte, error) {
+	resp, err := k.client.EncryptWithContext(ctx, &kms.EncryptInput{
+		KeyId:             aws.String(k.keyID),
+		Plaintext:         plaintext,
+		EncryptionContext: encryptionContext(associatedData),
+	})
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("aws: failed to wrap data key: %v", err)
+	}
+	return resp.CiphertextBlob, nil
+}
+
+// Unwrap decrypts the ciphertext with the AWS KMS key.
+//
+// The ciphertext may have been encrypted with any
+// previous key material of the AWS KMS key. Hence,
+// automatic key rotation can be enabled.
+func (k *KMS) Unwrap(ctx context.Context, ciphertext, associatedData []byte) ([]byte, error) {
+	resp, err := k.client.DecryptWithContext(ctx, &kms.DecryptInput{
+		KeyId:             aws.String(k.keyID),
+		CiphertextBlob:    ciphertext,
+		EncryptionContext: encryptionContext(associatedData),
+	})
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("aws: failed to unwrap data key: %v", err)
+	}
+	return resp.Plaintext, nil
+}
+
+// encryptionContext returns the AWS KMS encryption
+// context for the given associated data.
+func encryptionContext(associatedData []byte) map[string]*string {
+	if len(associatedData) == 0 {
+		return nil
+	}
+	return map[string]*string{
+		"kes:name": aws.String(string(associatedData)),
+	}
+}
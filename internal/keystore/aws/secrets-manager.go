@@ -9,6 +9,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -17,6 +18,8 @@ import (
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/secretsmanager"
 	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/keystore/batch"
+	"github.com/minio/kes/internal/keystore/cas"
 	"github.com/minio/kes/kv"
 )
 
@@ -98,9 +101,14 @@ func Connect(ctx context.Context, config *Config) (*Store, error) {
 type Store struct {
 	config Config
 	client *secretsmanager.SecretsManager
+
+	locks cas.Locker
 }
 
-var _ kv.Store[string, []byte] = (*Store)(nil)
+var (
+	_ kv.Store[string, []byte] = (*Store)(nil)
+	_ kv.Recoverer[string]     = (*Store)(nil)
+)
 
 // Status returns the current state of the AWS SecretsManager instance.
 // In particular, whether it is reachable and the network latency.
@@ -126,6 +134,10 @@ func (s *Store) Status(ctx context.Context) (kv.State, error) {
 // If the SecretsManager.KMSKeyID is set AWS will use this key ID to
 // encrypt the values. Otherwise, AWS will use the default key ID for
 // encrypting secrets at the AWS SecretsManager.
+//
+// If a secret with the same name is scheduled for deletion, Create
+// returns kv.ErrDeleted. Such a secret has to be either restored
+// or purged before its name can be used again.
 func (s *Store) Create(ctx context.Context, name string, value []byte) error {
 	createOpt := secretsmanager.CreateSecretInput{
 		Name:         aws.String(name),
@@ -139,9 +151,11 @@ func (s *Store) Create(ctx context.Context, name string, value []byte) error {
 			return err
 		}
 		if err, ok := err.(awserr.Error); ok {
-			switch err.Code() {
-			case secretsmanager.ErrCodeResourceExistsException:
+			switch {
+			case err.Code() == secretsmanager.ErrCodeResourceExistsException:
 				return kes.ErrKeyExists
+			case isScheduledForDeletion(err):
+				return fmt.Errorf("aws: failed to create '%s': %w: %v", name, kv.ErrDeleted, err)
 			}
 		}
 		return fmt.Errorf("aws: failed to create '%s': %v", name, err)
@@ -160,6 +174,31 @@ func (s *Store) Set(ctx context.Context, name string, value []byte) error {
 	return s.Create(ctx, name, value)
 }
 
+// Update replaces the value of the given key with newValue
+// if and only if its current value is equal to oldValue.
+//
+// The AWS SecretsManager does not support compare-and-swap.
+// Hence, Update puts a new secret value while holding a lock
+// for the key. Updates of other KES servers may race.
+func (s *Store) Update(ctx context.Context, name string, oldValue, newValue []byte) error {
+	return s.locks.Update(ctx, s, name, oldValue, newValue, func(ctx context.Context, name string, _, value []byte) error {
+		_, err := s.client.PutSecretValueWithContext(ctx, &secretsmanager.PutSecretValueInput{
+			SecretId:     aws.String(name),
+			SecretString: aws.String(string(value)),
+		})
+		if err != nil {
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				return err
+			}
+			if err, ok := err.(awserr.Error); ok && err.Code() == secretsmanager.ErrCodeResourceNotFoundException {
+				return kes.ErrKeyNotFound
+			}
+			return fmt.Errorf("aws: failed to update '%s': %v", name, err)
+		}
+		return nil
+	})
+}
+
 // Get returns the value associated with the given key.
 // If no entry for key exists, it returns kes.ErrKeyNotFound.
 func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
@@ -171,11 +210,13 @@ func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
 			return nil, err
 		}
 		if err, ok := err.(awserr.Error); ok {
-			switch err.Code() {
-			case secretsmanager.ErrCodeDecryptionFailure:
+			switch {
+			case err.Code() == secretsmanager.ErrCodeDecryptionFailure:
 				return nil, fmt.Errorf("aws: cannot access '%s': %v", name, err)
-			case secretsmanager.ErrCodeResourceNotFoundException:
+			case err.Code() == secretsmanager.ErrCodeResourceNotFoundException:
 				return nil, kes.ErrKeyNotFound
+			case isScheduledForDeletion(err):
+				return nil, fmt.Errorf("aws: failed to read '%s': %w: %v", name, kv.ErrDeleted, err)
 			}
 		}
 		return nil, fmt.Errorf("aws: failed to read '%s': %v", name, err)
@@ -197,6 +238,14 @@ func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
 	return value, nil
 }
 
+// GetMany returns the values associated with the given keys. It
+// sends up to batch.Concurrency requests concurrently since the
+// AWS SecretsManager API used by KES does not support batch
+// reads.
+func (s *Store) GetMany(ctx context.Context, names []string) ([][]byte, []error) {
+	return batch.Get[string, []byte](ctx, s, names)
+}
+
 // Delete removes the key-value pair from the AWS SecretsManager, if
 // it exists.
 func (s *Store) Delete(ctx context.Context, name string) error {
@@ -218,18 +267,97 @@ func (s *Store) Delete(ctx context.Context, name string) error {
 	return nil
 }
 
+// DeleteMany deletes the given keys. AWS SecretsManager has no
+// batch delete API. Hence, DeleteMany sends up to
+// batch.Concurrency requests concurrently.
+func (s *Store) DeleteMany(ctx context.Context, names []string) []error {
+	return batch.Delete[string, []byte](ctx, s, names)
+}
+
+// ListDeleted returns a new Iterator over all secrets
+// that are scheduled for deletion.
+//
+// The AWS SecretsManager only includes secrets scheduled
+// for deletion in its listings on request. Secrets that
+// are not listed can still be restored or purged by name.
+func (s *Store) ListDeleted(ctx context.Context) (kv.Iter[kv.Deleted[string]], error) {
+	var cancel context.CancelCauseFunc
+	ctx, cancel = context.WithCancelCause(ctx)
+	values := make(chan kv.Deleted[string], 10)
+
+	go func() {
+		defer close(values)
+		err := s.client.ListSecretsPagesWithContext(ctx, &secretsmanager.ListSecretsInput{}, func(page *secretsmanager.ListSecretsOutput, lastPage bool) bool {
+			for _, secret := range page.SecretList {
+				if secret.DeletedDate == nil {
+					continue
+				}
+				values <- kv.Deleted[string]{
+					Key:       *secret.Name,
+					DeletedAt: *secret.DeletedDate,
+				}
+			}
+			return !lastPage
+		})
+		if err != nil {
+			cancel(err)
+		}
+	}()
+	return &deletedIter{
+		ch:  values,
+		ctx: ctx,
+	}, nil
+}
+
+// Recover restores the secret scheduled for deletion. It
+// returns kes.ErrKeyNotFound if no such secret exists.
+func (s *Store) Recover(ctx context.Context, name string) error {
+	_, err := s.client.RestoreSecretWithContext(ctx, &secretsmanager.RestoreSecretInput{
+		SecretId: aws.String(name),
+	})
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+		if err, ok := err.(awserr.Error); ok {
+			if err.Code() == secretsmanager.ErrCodeResourceNotFoundException {
+				return kes.ErrKeyNotFound
+			}
+		}
+		return fmt.Errorf("aws: failed to recover '%s': %v", name, err)
+	}
+	return nil
+}
+
+// Purge deletes the secret scheduled for deletion permanently.
+// It returns kes.ErrKeyNotFound if no such secret exists.
+func (s *Store) Purge(ctx context.Context, name string) error {
+	return s.Delete(ctx, name)
+}
+
 // List returns a new Iterator over the names of
-// all stored keys.
-func (s *Store) List(ctx context.Context) (kv.Iter[string], error) {
+// all stored keys that start with the given prefix.
+func (s *Store) List(ctx context.Context, prefix string) (kv.Iter[string], error) {
 	var cancel context.CancelCauseFunc
 	ctx, cancel = context.WithCancelCause(ctx)
 	values := make(chan string, 10)
 
 	go func() {
 		defer close(values)
-		err := s.client.ListSecretsPagesWithContext(ctx, &secretsmanager.ListSecretsInput{}, func(page *secretsmanager.ListSecretsOutput, lastPage bool) bool {
+		input := &secretsmanager.ListSecretsInput{}
+		if prefix != "" {
+			// SecretsManager matches the name filter against the
+			// beginning of secret names.
+			input.Filters = []*secretsmanager.Filter{{
+				Key:    aws.String(secretsmanager.FilterNameStringTypeName),
+				Values: []*string{aws.String(prefix)},
+			}}
+		}
+		err := s.client.ListSecretsPagesWithContext(ctx, input, func(page *secretsmanager.ListSecretsOutput, lastPage bool) bool {
 			for _, secret := range page.SecretList {
-				values <- *secret.Name
+				if strings.HasPrefix(*secret.Name, prefix) {
+					values <- *secret.Name
+				}
 			}
 
 			// The pagination is stopped once we return false.
@@ -264,3 +392,35 @@ func (i *iter) Next() (string, bool) {
 func (i *iter) Close() error {
 	return context.Cause(i.ctx)
 }
+
+type deletedIter struct {
+	ch  <-chan kv.Deleted[string]
+	ctx context.Context
+}
+
+func (i *deletedIter) Next() (kv.Deleted[string], bool) {
+	select {
+	case v, ok := <-i.ch:
+		return v, ok
+	case <-i.ctx.Done():
+		return kv.Deleted[string]{}, false
+	}
+}
+
+func (i *deletedIter) Close() error {
+	return context.Cause(i.ctx)
+}
+
+// isScheduledForDeletion reports whether err indicates
+// that the secret is scheduled for deletion.
+//
+// The AWS SecretsManager does not return a dedicated error
+// code for secrets scheduled for deletion. Instead, it returns
+// an InvalidRequestException with a descriptive message.
+func isScheduledForDeletion(err awserr.Error) bool {
+	if err.Code() != secretsmanager.ErrCodeInvalidRequestException {
+		return false
+	}
+	message := strings.ToLower(err.Message())
+	return strings.Contains(message, "scheduled for deletion") || strings.Contains(message, "marked for deletion")
+}
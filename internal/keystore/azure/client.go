@@ -7,9 +7,9 @@ package azure
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"math"
 	"net/http"
 	"path"
 	"strings"
@@ -262,47 +262,86 @@ func (c *client) PurgeSecret(ctx context.Context, name string) (status, error) {
 	}, nil
 }
 
-// GetFirstVersion returns the first version of a secret
-// based on its created_at timestamp.
+// RecoverSecret recovers the (soft) deleted secret with the
+// given name. It returns a status with an HTTP 200 OK status
+// code on success.
 //
-// To reduce complexity, GetFirstVersion makes some simplifying
-// assumptions. In particular, it only inspects 25 (KeyVault API default)
-// versions of the given secret. When a secret contains more then 25
-// versions GetFirstVersions returns a status with a 422 HTTP error code.
-func (c *client) GetFirstVersion(ctx context.Context, name string) (string, status, error) {
+// KeyVault may return 200 OK even though it hasn't completed
+// the recovery process. The secret may be in a transition
+// state from (soft) deleted to "active".
+func (c *client) RecoverSecret(ctx context.Context, name string) (status, error) {
+	uri := endpoint(c.Endpoint, "deletedsecrets", name, "recover") + "?api-version=7.2"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uri, nil)
+	if err != nil {
+		return status{}, err
+	}
+	req, err = autorest.CreatePreparer(c.Authorizer.WithAuthorization()).Prepare(req)
+	if err != nil {
+		return status{}, err
+	}
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return status{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		response, err := parseErrorResponse(resp)
+		if err != nil {
+			return status{}, err
+		}
+		return status{
+			StatusCode: resp.StatusCode,
+			ErrorCode:  response.Error.Inner.Code,
+			Message:    response.Error.Message,
+		}, nil
+	}
+	return status{
+		StatusCode: http.StatusOK,
+	}, nil
+}
+
+// deletedSecret describes a (soft) deleted secret.
+type deletedSecret struct {
+	Name      string
+	DeletedAt time.Time // Zero, if unknown
+	PurgeAt   time.Time // Zero, if unknown
+}
+
+// ListDeletedSecrets returns a set of (soft) deleted secrets and an
+// optional continuation link. It supports iterating over all deleted
+// secrets in pages, like ListSecrets.
+func (c *client) ListDeletedSecrets(ctx context.Context, nextLink string) ([]deletedSecret, string, status, error) {
 	type Response struct {
-		Versions []struct {
-			ID   string `json:"id"`
-			Attr struct {
-				Enabled bool  `json:"enabled"`
-				Created int64 `json:"created"`
-				Updated int64 `json:"updated"`
-			} `json:"attributes"`
+		Values []struct {
+			ID                 string `json:"id"`
+			DeletedDate        int64  `json:"deletedDate"`
+			ScheduledPurgeDate int64  `json:"scheduledPurgeDate"`
 		} `json:"value"`
-
 		NextLink string `json:"nextLink"`
 	}
-	// We only inspect 25 versions as some reasonable default limit.
-	uri := endpoint(c.Endpoint, "secrets", name, "versions") + "?api-version=7.2&maxresults=25"
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+
+	if nextLink == "" {
+		nextLink = endpoint(c.Endpoint, "deletedsecrets") + "?maxresults=25&api-version=7.2"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, nextLink, nil)
 	if err != nil {
-		return "", status{}, err
+		return nil, "", status{}, err
 	}
 	req, err = autorest.CreatePreparer(c.Authorizer.WithAuthorization()).Prepare(req)
 	if err != nil {
-		return "", status{}, err
+		return nil, "", status{}, err
 	}
 
 	resp, err := c.Client.Do(req)
 	if err != nil {
-		return "", status{}, err
+		return nil, "", status{}, err
 	}
 	if resp.StatusCode != http.StatusOK {
 		response, err := parseErrorResponse(resp)
 		if err != nil {
-			return "", status{}, err
+			return nil, "", status{}, err
 		}
-		return "", status{
+		return nil, "", status{
 			StatusCode: resp.StatusCode,
 			ErrorCode:  response.Error.Inner.Code,
 			Message:    response.Error.Message,
@@ -314,36 +353,22 @@ func (c *client) GetFirstVersion(ctx context.Context, name string) (string, stat
 	if limit < 0 || limit > MaxSize {
 		limit = MaxSize
 	}
-
 	var response Response
 	if err = json.NewDecoder(mem.LimitReader(resp.Body, limit)).Decode(&response); err != nil {
-		return "", status{}, err
-	}
-	if response.NextLink != "" {
-		return "", status{
-			StatusCode: http.StatusUnprocessableEntity,
-			ErrorCode:  "TooManyObjectVersions",
-			Message:    fmt.Sprintf("There are too many versions of %q.", name),
-		}, nil
-	}
-	if len(response.Versions) == 0 {
-		return "", status{
-			StatusCode: http.StatusNotFound,
-			ErrorCode:  "NoObjectVersions",
-			Message:    fmt.Sprintf("There are no versions of %q.", name),
-		}, nil
+		return nil, "", status{}, err
 	}
-	var (
-		id        string                 // most recent Secret ID
-		createdAt int64  = math.MaxInt64 // most recent createdAt UNIX timestamp
-	)
-	for _, v := range response.Versions {
-		if createdAt > v.Attr.Created {
-			createdAt = v.Attr.Created
-			id = v.ID
+	secrets := make([]deletedSecret, 0, len(response.Values))
+	for _, v := range response.Values {
+		secret := deletedSecret{Name: path.Base(v.ID)}
+		if v.DeletedDate > 0 {
+			secret.DeletedAt = time.Unix(v.DeletedDate, 0).UTC()
+		}
+		if v.ScheduledPurgeDate > 0 {
+			secret.PurgeAt = time.Unix(v.ScheduledPurgeDate, 0).UTC()
 		}
+		secrets = append(secrets, secret)
 	}
-	return path.Base(id), status{
+	return secrets, response.NextLink, status{
 		StatusCode: http.StatusOK,
 	}, nil
 }
@@ -410,6 +435,273 @@ func (c *client) ListSecrets(ctx context.Context, nextLink string) ([]string, st
 	}, nil
 }
 
+// hsmAPIVersion is the API version used for
+// Managed HSM requests.
+const hsmAPIVersion = "7.4"
+
+// hsmKey is an Azure Managed HSM key and its tags.
+type hsmKey struct {
+	Name string
+	Tags map[string]string
+
+	DeletedAt time.Time // Zero, if unknown or not deleted
+	PurgeAt   time.Time // Zero, if unknown or not deleted
+}
+
+// CreateKey creates a new Managed HSM AES key with the
+// given name and tags.
+//
+// It returns a status with an HTTP 200 OK status
+// code on success.
+//
+// If a key with the given name already exists then
+// Managed HSM will not return an error but create
+// another version of the key.
+func (c *client) CreateKey(ctx context.Context, name string, tags map[string]string) (status, error) {
+	type Request struct {
+		Type string            `json:"kty"`
+		Size int               `json:"key_size"`
+		Tags map[string]string `json:"tags"`
+	}
+	uri := endpoint(c.Endpoint, "keys", name, "create") + "?api-version=" + hsmAPIVersion
+	return c.send(ctx, http.MethodPost, uri, Request{
+		Type: "oct-HSM",
+		Size: 128,
+		Tags: tags,
+	}, nil, http.StatusOK)
+}
+
+// GetKey returns the latest version of the Managed
+// HSM key with the given name.
+func (c *client) GetKey(ctx context.Context, name string) (hsmKey, status, error) {
+	type Response struct {
+		Tags map[string]string `json:"tags"`
+	}
+
+	var response Response
+	uri := endpoint(c.Endpoint, "keys", name) + "?api-version=" + hsmAPIVersion
+	stat, err := c.send(ctx, http.MethodGet, uri, nil, &response, http.StatusOK)
+	if err != nil || stat.StatusCode != http.StatusOK {
+		return hsmKey{}, stat, err
+	}
+	return hsmKey{Name: name, Tags: response.Tags}, stat, nil
+}
+
+// DeleteKey issues a (soft) delete of the Managed HSM key
+// with the given name. It does not purge an already deleted
+// key.
+func (c *client) DeleteKey(ctx context.Context, name string) (status, error) {
+	uri := endpoint(c.Endpoint, "keys", name) + "?api-version=" + hsmAPIVersion
+	return c.send(ctx, http.MethodDelete, uri, nil, nil, http.StatusOK)
+}
+
+// PurgeKey purges the (soft) deleted Managed HSM key with
+// the given name.
+func (c *client) PurgeKey(ctx context.Context, name string) (status, error) {
+	uri := endpoint(c.Endpoint, "deletedkeys", name) + "?api-version=" + hsmAPIVersion
+	return c.send(ctx, http.MethodDelete, uri, nil, nil, http.StatusNoContent)
+}
+
+// RecoverKey recovers the (soft) deleted Managed HSM key
+// with the given name.
+func (c *client) RecoverKey(ctx context.Context, name string) (status, error) {
+	uri := endpoint(c.Endpoint, "deletedkeys", name, "recover") + "?api-version=" + hsmAPIVersion
+	return c.send(ctx, http.MethodPost, uri, nil, nil, http.StatusOK)
+}
+
+// ListKeys returns a set of Managed HSM keys and an optional
+// continuation link. It supports iterating over all keys in
+// pages, like ListSecrets.
+//
+// If deleted is true, ListKeys returns (soft) deleted keys
+// instead.
+func (c *client) ListKeys(ctx context.Context, nextLink string, deleted bool) ([]hsmKey, string, status, error) {
+	type Response struct {
+		Values []struct {
+			ID                 string            `json:"kid"`
+			Tags               map[string]string `json:"tags"`
+			DeletedDate        int64             `json:"deletedDate"`
+			ScheduledPurgeDate int64             `json:"scheduledPurgeDate"`
+		} `json:"value"`
+		NextLink string `json:"nextLink"`
+	}
+
+	if nextLink == "" {
+		collection := "keys"
+		if deleted {
+			collection = "deletedkeys"
+		}
+		nextLink = endpoint(c.Endpoint, collection) + "?maxresults=25&api-version=" + hsmAPIVersion
+	}
+
+	var response Response
+	stat, err := c.send(ctx, http.MethodGet, nextLink, nil, &response, http.StatusOK)
+	if err != nil || stat.StatusCode != http.StatusOK {
+		return nil, "", stat, err
+	}
+	keys := make([]hsmKey, 0, len(response.Values))
+	for _, v := range response.Values {
+		key := hsmKey{
+			Name: path.Base(v.ID),
+			Tags: v.Tags,
+		}
+		if v.DeletedDate > 0 {
+			key.DeletedAt = time.Unix(v.DeletedDate, 0).UTC()
+		}
+		if v.ScheduledPurgeDate > 0 {
+			key.PurgeAt = time.Unix(v.ScheduledPurgeDate, 0).UTC()
+		}
+		keys = append(keys, key)
+	}
+	return keys, response.NextLink, stat, nil
+}
+
+// Encrypt encrypts the plaintext with the Managed HSM AES
+// key using AES-256-GCM. The IV is generated by the HSM.
+//
+// It returns the IV, the authentication tag and the
+// ciphertext.
+func (c *client) Encrypt(ctx context.Context, key string, plaintext, associatedData []byte) (iv, tag, ciphertext []byte, stat status, err error) {
+	type Request struct {
+		Algorithm      string `json:"alg"`
+		Value          string `json:"value"`
+		AssociatedData string `json:"aad"`
+	}
+	type Response struct {
+		Value string `json:"value"`
+		IV    string `json:"iv"`
+		Tag   string `json:"tag"`
+	}
+
+	var response Response
+	uri := endpoint(c.Endpoint, "keys", key, "encrypt") + "?api-version=" + hsmAPIVersion
+	stat, err = c.send(ctx, http.MethodPost, uri, Request{
+		Algorithm:      "A256GCM",
+		Value:          base64.RawURLEncoding.EncodeToString(plaintext),
+		AssociatedData: base64.RawURLEncoding.EncodeToString(associatedData),
+	}, &response, http.StatusOK)
+	if err != nil || stat.StatusCode != http.StatusOK {
+		return nil, nil, nil, stat, err
+	}
+
+	if iv, err = decodeBase64URL(response.IV); err != nil {
+		return nil, nil, nil, status{}, err
+	}
+	if tag, err = decodeBase64URL(response.Tag); err != nil {
+		return nil, nil, nil, status{}, err
+	}
+	if ciphertext, err = decodeBase64URL(response.Value); err != nil {
+		return nil, nil, nil, status{}, err
+	}
+	return iv, tag, ciphertext, stat, nil
+}
+
+// Decrypt decrypts the ciphertext with the Managed HSM AES
+// key using AES-256-GCM.
+func (c *client) Decrypt(ctx context.Context, key string, iv, tag, ciphertext, associatedData []byte) ([]byte, status, error) {
+	type Request struct {
+		Algorithm      string `json:"alg"`
+		Value          string `json:"value"`
+		IV             string `json:"iv"`
+		Tag            string `json:"tag"`
+		AssociatedData string `json:"aad"`
+	}
+	type Response struct {
+		Value string `json:"value"`
+	}
+
+	var response Response
+	uri := endpoint(c.Endpoint, "keys", key, "decrypt") + "?api-version=" + hsmAPIVersion
+	stat, err := c.send(ctx, http.MethodPost, uri, Request{
+		Algorithm:      "A256GCM",
+		Value:          base64.RawURLEncoding.EncodeToString(ciphertext),
+		IV:             base64.RawURLEncoding.EncodeToString(iv),
+		Tag:            base64.RawURLEncoding.EncodeToString(tag),
+		AssociatedData: base64.RawURLEncoding.EncodeToString(associatedData),
+	}, &response, http.StatusOK)
+	if err != nil || stat.StatusCode != http.StatusOK {
+		return nil, stat, err
+	}
+
+	plaintext, err := decodeBase64URL(response.Value)
+	if err != nil {
+		return nil, status{}, err
+	}
+	return plaintext, stat, nil
+}
+
+// send sends an authorized request with the JSON-encoded
+// request body, if not nil, and decodes the response body
+// into response, if not nil, when the server responds
+// with the given status code.
+//
+// Otherwise, it returns a status containing the KeyVault
+// error.
+func (c *client) send(ctx context.Context, method, uri string, request, response any, code int) (status, error) {
+	var (
+		body []byte
+		err  error
+	)
+	if request != nil {
+		if body, err = json.Marshal(request); err != nil {
+			return status{}, err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, uri, xhttp.RetryReader(bytes.NewReader(body)))
+	if err != nil {
+		return status{}, err
+	}
+	req, err = autorest.CreatePreparer(c.Authorizer.WithAuthorization()).Prepare(req)
+	if err != nil {
+		return status{}, err
+	}
+	if request != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.ContentLength = int64(len(body))
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return status{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != code {
+		response, err := parseErrorResponse(resp)
+		if err != nil {
+			return status{}, err
+		}
+		errorCode := response.Error.Inner.Code
+		if errorCode == "" {
+			errorCode = response.Error.Code
+		}
+		return status{
+			StatusCode: resp.StatusCode,
+			ErrorCode:  errorCode,
+			Message:    response.Error.Message,
+		}, nil
+	}
+	if response != nil {
+		const MaxSize = 10 * mem.MiB
+		limit := mem.Size(resp.ContentLength)
+		if limit < 0 || limit > MaxSize {
+			limit = MaxSize
+		}
+		if err = json.NewDecoder(mem.LimitReader(resp.Body, limit)).Decode(response); err != nil {
+			return status{}, err
+		}
+	}
+	return status{
+		StatusCode: code,
+	}, nil
+}
+
+// decodeBase64URL decodes the base64url-encoded string
+// with or without padding.
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
 // endpoint returns an endpoint URL starting with the
 // given endpoint followed by the path elements.
 //
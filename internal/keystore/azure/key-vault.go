@@ -10,11 +10,14 @@ import (
 	"fmt"
 	"math/rand"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/Azure/go-autorest/autorest"
 	"github.com/Azure/go-autorest/autorest/azure/auth"
 	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/keystore/batch"
+	"github.com/minio/kes/internal/keystore/cas"
 	"github.com/minio/kes/kv"
 )
 
@@ -39,9 +42,14 @@ type ManagedIdentity struct {
 type Store struct {
 	endpoint string
 	client   client
+
+	locks cas.Locker
 }
 
-var _ kv.Store[string, []byte] = (*Store)(nil)
+var (
+	_ kv.Store[string, []byte] = (*Store)(nil)
+	_ kv.Recoverer[string]     = (*Store)(nil)
+)
 
 // Status returns the current state of the Azure KeyVault instance.
 // In particular, whether it is reachable and the network latency.
@@ -79,7 +87,14 @@ func (s *Store) Status(ctx context.Context) (kv.State, error) {
 // purging but will eventually give up and fail. However,
 // a subsequent create may succeed once KeyVault has purged
 // the secret completely.
+//
+// If the deleted secret cannot be purged, for example due
+// to purge protection, or KeyVault has not purged it in
+// time, Create returns kv.ErrDeleted.
 func (s *Store) Create(ctx context.Context, name string, value []byte) error {
+	if err := validName(name); err != nil {
+		return err
+	}
 	_, stat, err := s.client.GetSecret(ctx, name, "")
 	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 		return err
@@ -111,6 +126,9 @@ func (s *Store) Create(ctx context.Context, name string, value []byte) error {
 		if err != nil {
 			return fmt.Errorf("azure: failed to create '%s': failed to purge deleted secret: %v", name, err)
 		}
+		if stat.StatusCode == http.StatusForbidden {
+			return fmt.Errorf("azure: failed to create '%s': %w: insufficient permissions to purge deleted secret: %s (%s)", name, kv.ErrDeleted, stat.Message, stat.ErrorCode)
+		}
 		if stat.StatusCode != http.StatusNoContent {
 			return fmt.Errorf("azure: failed to create '%s': failed to purge deleted secret: %s (%s)", name, stat.Message, stat.ErrorCode)
 		}
@@ -139,7 +157,9 @@ func (s *Store) Create(ctx context.Context, name string, value []byte) error {
 	case stat.StatusCode == http.StatusOK:
 		return nil
 	case stat.StatusCode == http.StatusConflict && stat.ErrorCode == "ObjectIsDeletedButRecoverable":
-		return fmt.Errorf("azure: failed to create '%s': key already exists but is currently marked as deleted. Either restore or purge '%s'", name, name)
+		return fmt.Errorf("azure: failed to create '%s': %w: either restore or purge '%s'", name, kv.ErrDeleted, name)
+	case stat.StatusCode == http.StatusConflict && stat.ErrorCode == "ObjectIsBeingDeleted":
+		return fmt.Errorf("azure: failed to create '%s': %w: '%s' is still being deleted", name, kv.ErrDeleted, name)
 	case stat.StatusCode == http.StatusForbidden && stat.ErrorCode == "ForbiddenByPolicy":
 		return fmt.Errorf("azure: failed to create '%s': insufficient permissions: %s", name, stat.Message)
 	default:
@@ -170,6 +190,38 @@ func (s *Store) Set(ctx context.Context, name string, value []byte) error {
 	return s.Create(ctx, name, value)
 }
 
+// Update replaces the value of the given key with newValue
+// if and only if its current value is equal to oldValue.
+//
+// Update adds a new version of the secret while holding a
+// lock for the key. Get always reads the latest version.
+// KeyVault has no compare-and-swap primitive for secrets.
+// Hence, updates of other KES servers are not serialized.
+func (s *Store) Update(ctx context.Context, name string, oldValue, newValue []byte) error {
+	if err := validName(name); err != nil {
+		return err
+	}
+	return s.locks.Update(ctx, s, name, oldValue, newValue, func(ctx context.Context, name string, _, value []byte) error {
+		stat, err := s.client.CreateSecret(ctx, name, string(value))
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("azure: failed to update '%s': %v", name, err)
+		}
+		switch {
+		case stat.StatusCode == http.StatusOK:
+			return nil
+		case stat.StatusCode == http.StatusConflict && (stat.ErrorCode == "ObjectIsDeletedButRecoverable" || stat.ErrorCode == "ObjectIsBeingDeleted"):
+			return kes.ErrKeyNotFound
+		case stat.StatusCode == http.StatusForbidden && stat.ErrorCode == "ForbiddenByPolicy":
+			return fmt.Errorf("azure: failed to update '%s': insufficient permissions: %s", name, stat.Message)
+		default:
+			return fmt.Errorf("azure: failed to update '%s': %s (%s)", name, stat.Message, stat.ErrorCode)
+		}
+	})
+}
+
 // Delete deletes and purges the secret from KeyVault.
 //
 // A full delete is a two-step process. So, Delete first
@@ -187,6 +239,9 @@ func (s *Store) Set(ctx context.Context, name string, value []byte) error {
 // Since KeyVault only supports two-steps deletes, KES cannot
 // guarantee that a Delete operation has atomic semantics.
 func (s *Store) Delete(ctx context.Context, name string) error {
+	if err := validName(name); err != nil {
+		return err
+	}
 	// Deleting a key from KeyVault is a two-step
 	// process. First, the key has to be deleted
 	// (soft delete) and then purged. It is not
@@ -212,7 +267,7 @@ func (s *Store) Delete(ctx context.Context, name string) error {
 	if err != nil {
 		return fmt.Errorf("azure: failed to delete '%s': %v", name, err)
 	}
-	if stat.StatusCode != http.StatusNotFound {
+	if stat.StatusCode == http.StatusNotFound {
 		return kes.ErrKeyNotFound
 	}
 	if stat.StatusCode != http.StatusOK && stat.StatusCode != http.StatusNotFound {
@@ -259,43 +314,150 @@ func (s *Store) Delete(ctx context.Context, name string) error {
 	return fmt.Errorf("azure: failed to delete '%s': failed to purge deleted secret: %s (%s)", name, stat.Message, stat.ErrorCode)
 }
 
-// Get returns the first resp. oldest version of the secret.
-// It returns kes.ErrKeyNotFound if no such secret exists.
-//
-// Since Get has to fetch and filter the secrets versions first
-// before actually accessing the secret, Get may return inconsistent
-// responses when the secret is modified concurrently.
-func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
-	version, stat, err := s.client.GetFirstVersion(ctx, name)
+// DeleteMany deletes the given keys. It deletes up to
+// batch.Concurrency keys concurrently since Azure KeyVault has
+// no batch API for secrets.
+func (s *Store) DeleteMany(ctx context.Context, names []string) []error {
+	return batch.Delete[string, []byte](ctx, s, names)
+}
+
+// ListDeleted returns a new Iterator over all (soft)
+// deleted secrets that have not been purged yet.
+func (s *Store) ListDeleted(ctx context.Context) (kv.Iter[kv.Deleted[string]], error) {
+	var cancel context.CancelCauseFunc
+	ctx, cancel = context.WithCancelCause(ctx)
+	values := make(chan kv.Deleted[string], 10)
+
+	go func() {
+		defer close(values)
+
+		var nextLink string
+		for {
+			secrets, link, status, err := s.client.ListDeletedSecrets(ctx, nextLink)
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				cancel(err)
+				break
+			}
+			if err != nil {
+				cancel(fmt.Errorf("azure: failed to list deleted keys: %v", err))
+				break
+			}
+			if status.StatusCode != http.StatusOK {
+				cancel(fmt.Errorf("azure: failed to list deleted keys: %s (%s)", status.Message, status.ErrorCode))
+				break
+			}
+
+			nextLink = link
+			for _, secret := range secrets {
+				deleted := kv.Deleted[string]{
+					Key:       secret.Name,
+					DeletedAt: secret.DeletedAt,
+					PurgeAt:   secret.PurgeAt,
+				}
+				select {
+				case values <- deleted:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if nextLink == "" {
+				break
+			}
+		}
+	}()
+	return &deletedIter{
+		ch:     values,
+		ctx:    ctx,
+		cancel: cancel,
+	}, nil
+}
+
+// Recover recovers the (soft) deleted secret. It returns
+// kes.ErrKeyNotFound if no such deleted secret exists and
+// kes.ErrKeyExists if the secret is not deleted.
+func (s *Store) Recover(ctx context.Context, name string) error {
+	if err := validName(name); err != nil {
+		return err
+	}
+	stat, err := s.client.RecoverSecret(ctx, name)
 	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
-		return nil, err
+		return err
 	}
 	if err != nil {
-		return nil, fmt.Errorf("azure: failed to get '%s': failed to list versions: %v", name, err)
+		return fmt.Errorf("azure: failed to recover '%s': %v", name, err)
 	}
-	if stat.StatusCode == http.StatusNotFound && stat.ErrorCode == "NoObjectVersions" {
-		return nil, kes.ErrKeyNotFound
+	switch {
+	case stat.StatusCode == http.StatusOK:
+		return nil
+	case stat.StatusCode == http.StatusNotFound:
+		return kes.ErrKeyNotFound
+	case stat.StatusCode == http.StatusConflict && stat.ErrorCode == "ObjectIsBeingDeleted":
+		return fmt.Errorf("azure: failed to recover '%s': '%s' is still being deleted", name, name)
+	case stat.StatusCode == http.StatusConflict:
+		return kes.ErrKeyExists
+	default:
+		return fmt.Errorf("azure: failed to recover '%s': %s (%s)", name, stat.Message, stat.ErrorCode)
 	}
-	if stat.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("azure: failed to get '%s': failed to list versions: %s (%s)", name, stat.Message, stat.ErrorCode)
+}
+
+// Purge purges the (soft) deleted secret permanently. It
+// returns kes.ErrKeyNotFound if no such deleted secret
+// exists.
+func (s *Store) Purge(ctx context.Context, name string) error {
+	if err := validName(name); err != nil {
+		return err
+	}
+	stat, err := s.client.PurgeSecret(ctx, name)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("azure: failed to purge '%s': %v", name, err)
+	}
+	switch {
+	case stat.StatusCode == http.StatusNoContent:
+		return nil
+	case stat.StatusCode == http.StatusNotFound:
+		return kes.ErrKeyNotFound
+	case stat.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("azure: failed to purge '%s': insufficient permissions or purge protection enabled: %s (%s)", name, stat.Message, stat.ErrorCode)
+	default:
+		return fmt.Errorf("azure: failed to purge '%s': %s (%s)", name, stat.Message, stat.ErrorCode)
 	}
+}
 
-	value, stat, err := s.client.GetSecret(ctx, name, version)
+// Get returns the latest version of the secret.
+// It returns kes.ErrKeyNotFound if no such secret exists.
+func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
+	if err := validName(name); err != nil {
+		return nil, err
+	}
+	value, stat, err := s.client.GetSecret(ctx, name, "")
 	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 		return nil, err
 	}
 	if err != nil {
 		return nil, fmt.Errorf("azure: failed to get '%s': %v", name, err)
 	}
+	if stat.StatusCode == http.StatusNotFound {
+		return nil, kes.ErrKeyNotFound
+	}
 	if stat.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("azure: failed to get '%s': %s (%s)", name, stat.Message, stat.ErrorCode)
 	}
 	return []byte(value), nil
 }
 
+// GetMany returns the values associated with the given keys.
+// Azure KeyVault has no batch API for secrets. Hence, GetMany
+// fetches up to batch.Concurrency keys concurrently.
+func (s *Store) GetMany(ctx context.Context, names []string) ([][]byte, []error) {
+	return batch.Get[string, []byte](ctx, s, names)
+}
+
 // List returns a new Iterator over the names of
-// all stored keys.
-func (s *Store) List(ctx context.Context) (kv.Iter[string], error) {
+// all stored keys that start with the given prefix.
+func (s *Store) List(ctx context.Context, prefix string) (kv.Iter[string], error) {
 	var cancel context.CancelCauseFunc
 	ctx, cancel = context.WithCancelCause(ctx)
 	values := make(chan string, 10)
@@ -321,6 +483,9 @@ func (s *Store) List(ctx context.Context) (kv.Iter[string], error) {
 
 			nextLink = link
 			for _, secret := range secrets {
+				if !strings.HasPrefix(secret, prefix) {
+					continue
+				}
 				select {
 				case values <- secret:
 				case <-ctx.Done():
@@ -399,3 +564,39 @@ func (i *iter) Close() error {
 	i.cancel(context.Canceled)
 	return context.Cause(i.ctx)
 }
+
+type deletedIter struct {
+	ch     <-chan kv.Deleted[string]
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+}
+
+func (i *deletedIter) Next() (kv.Deleted[string], bool) {
+	select {
+	case v, ok := <-i.ch:
+		return v, ok
+	case <-i.ctx.Done():
+		return kv.Deleted[string]{}, false
+	}
+}
+
+func (i *deletedIter) Close() error {
+	i.cancel(context.Canceled)
+	return context.Cause(i.ctx)
+}
+
+// errHierarchicalName is returned when a key name
+// contains a '/' path separator.
+var errHierarchicalName = kes.NewError(http.StatusBadRequest, "azure: hierarchical key names are not supported")
+
+// validName returns an error if the name is a hierarchical
+// name, like 'payments/db-key'. KeyVault and managed HSM
+// names must not contain '/' since it separates the path
+// segments of their APIs - e.g. the secret name from its
+// version.
+func validName(name string) error {
+	if strings.ContainsRune(name, '/') {
+		return errHierarchicalName
+	}
+	return nil
+}
@@ -0,0 +1,580 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package azure
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure/auth"
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/keystore/batch"
+	"github.com/minio/kes/internal/keystore/cas"
+	"github.com/minio/kes/kv"
+)
+
+// HSMStore is an Azure Managed HSM key store.
+//
+// In contrast to KeyVault, Managed HSM does not support
+// secrets. Instead, HSMStore encrypts every key with an
+// AES wrapping key that never leaves the HSM and stores
+// the ciphertext as tags of a Managed HSM key with the
+// same name.
+//
+// Managed HSM uses its own role-based access control.
+// The identity used by KES requires the "Managed HSM
+// Crypto User" role, or an equivalent custom role, that
+// allows creating, reading, deleting and purging keys as
+// well as encrypting and decrypting with the wrapping key.
+type HSMStore struct {
+	endpoint    string
+	wrappingKey string
+	client      client
+
+	locks cas.Locker
+}
+
+var (
+	_ kv.Store[string, []byte] = (*HSMStore)(nil)
+	_ kv.Recoverer[string]     = (*HSMStore)(nil)
+)
+
+// Tags used to store the encrypted key. The ciphertext
+// is split into chunks of at most maxTagSize characters
+// stored as tags "kes-0", "kes-1", ...
+const (
+	tagIV         = "kes-iv"
+	tagAuthTag    = "kes-tag"
+	tagCiphertext = "kes-"
+
+	maxTagSize   = 256 // Max. length of a Managed HSM tag value
+	maxTags      = 15  // Max. number of tags of a Managed HSM key
+	maxChunks    = maxTags - 2
+	maxValueSize = (maxChunks * maxTagSize * 3) / 4
+)
+
+// ConnectManagedHSMWithCredentials tries to establish a connection to
+// an Azure Managed HSM instance using Azure client credentials.
+//
+// The wrappingKey is the name of the Managed HSM AES key used to
+// encrypt and decrypt keys.
+func ConnectManagedHSMWithCredentials(_ context.Context, endpoint, wrappingKey string, creds Credentials) (*HSMStore, error) {
+	const Scope = "https://managedhsm.azure.net"
+
+	if wrappingKey == "" {
+		return nil, errors.New("azure: no Managed HSM wrapping key specified")
+	}
+	c := auth.NewClientCredentialsConfig(creds.ClientID, creds.Secret, creds.TenantID)
+	c.Resource = Scope
+	token, err := c.ServicePrincipalToken()
+	if err != nil {
+		return nil, fmt.Errorf("azure: failed to obtain ServicePrincipalToken from client credentials: %v", err)
+	}
+	return &HSMStore{
+		endpoint:    endpoint,
+		wrappingKey: wrappingKey,
+		client: client{
+			Endpoint:   endpoint,
+			Authorizer: autorest.NewBearerAuthorizer(token),
+		},
+	}, nil
+}
+
+// ConnectManagedHSMWithIdentity tries to establish a connection to
+// an Azure Managed HSM instance using an Azure managed identity.
+//
+// The wrappingKey is the name of the Managed HSM AES key used to
+// encrypt and decrypt keys.
+func ConnectManagedHSMWithIdentity(_ context.Context, endpoint, wrappingKey string, msi ManagedIdentity) (*HSMStore, error) {
+	const Scope = "https://managedhsm.azure.net"
+
+	if wrappingKey == "" {
+		return nil, errors.New("azure: no Managed HSM wrapping key specified")
+	}
+	c := auth.NewMSIConfig()
+	c.Resource = Scope
+	c.ClientID = msi.ClientID
+	token, err := c.ServicePrincipalToken()
+	if err != nil {
+		return nil, fmt.Errorf("azure: failed to obtain ServicePrincipalToken from managed identity: %v", err)
+	}
+	return &HSMStore{
+		endpoint:    endpoint,
+		wrappingKey: wrappingKey,
+		client: client{
+			Endpoint:   endpoint,
+			Authorizer: autorest.NewBearerAuthorizer(token),
+		},
+	}, nil
+}
+
+// Status returns the current state of the Azure Managed HSM instance.
+// In particular, whether it is reachable and the network latency.
+func (s *HSMStore) Status(ctx context.Context) (kv.State, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.client.Endpoint, nil)
+	if err != nil {
+		return kv.State{}, err
+	}
+
+	start := time.Now()
+	if _, err = http.DefaultClient.Do(req); err != nil {
+		return kv.State{}, &kv.Unreachable{Err: err}
+	}
+	return kv.State{
+		Latency: time.Since(start),
+	}, nil
+}
+
+// Create encrypts the value with the wrapping key and stores
+// the ciphertext as Managed HSM key with the given name.
+//
+// Like KeyVault, Managed HSM does not support an atomic
+// create-only-if-not-exists. Create checks whether a key
+// with the given name exists, and if it does, returns
+// kes.ErrKeyExists.
+//
+// If the key has been deleted but not purged, Create
+// returns kv.ErrDeleted.
+func (s *HSMStore) Create(ctx context.Context, name string, value []byte) error {
+	if err := validName(name); err != nil {
+		return err
+	}
+	if len(value) > maxValueSize {
+		return fmt.Errorf("azure: failed to create '%s': value too large", name)
+	}
+
+	_, stat, err := s.client.GetKey(ctx, name)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("azure: failed to create '%s': failed to check whether '%s' already exists: %v", name, name, err)
+	}
+	switch {
+	case stat.StatusCode == http.StatusOK:
+		return kes.ErrKeyExists
+	case stat.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("azure: failed to create '%s': insufficient permissions to check whether '%s' already exists: %s (%s)", name, name, stat.Message, stat.ErrorCode)
+	case stat.StatusCode != http.StatusNotFound:
+		return fmt.Errorf("azure: failed to create '%s': failed to check whether '%s' already exists: %s (%s)", name, name, stat.Message, stat.ErrorCode)
+	}
+
+	iv, tag, ciphertext, stat, err := s.client.Encrypt(ctx, s.wrappingKey, value, []byte(name))
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("azure: failed to create '%s': failed to encrypt key: %v", name, err)
+	}
+	if stat.StatusCode != http.StatusOK {
+		return fmt.Errorf("azure: failed to create '%s': failed to encrypt key: %s (%s)", name, stat.Message, stat.ErrorCode)
+	}
+
+	stat, err = s.client.CreateKey(ctx, name, encodeTags(iv, tag, ciphertext))
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("azure: failed to create '%s': %v", name, err)
+	}
+	switch {
+	case stat.StatusCode == http.StatusOK:
+		return nil
+	case stat.StatusCode == http.StatusConflict && stat.ErrorCode == "ObjectIsDeletedButRecoverable":
+		return fmt.Errorf("azure: failed to create '%s': %w: either restore or purge '%s'", name, kv.ErrDeleted, name)
+	case stat.StatusCode == http.StatusConflict && stat.ErrorCode == "ObjectIsBeingDeleted":
+		return fmt.Errorf("azure: failed to create '%s': %w: '%s' is still being deleted", name, kv.ErrDeleted, name)
+	case stat.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("azure: failed to create '%s': insufficient permissions: %s", name, stat.Message)
+	default:
+		return fmt.Errorf("azure: failed to create '%s': %s (%s)", name, stat.Message, stat.ErrorCode)
+	}
+}
+
+// Set encrypts the value with the wrapping key and stores
+// the ciphertext as Managed HSM key with the given name.
+//
+// It behaves like Create.
+func (s *HSMStore) Set(ctx context.Context, name string, value []byte) error {
+	return s.Create(ctx, name, value)
+}
+
+// Update replaces the value of the given key with newValue
+// if and only if its current value is equal to oldValue.
+//
+// Managed HSM has no compare-and-swap primitive. Hence,
+// Update adds a new version of the HSM key, whose tags
+// contain the encrypted newValue, while holding a lock for
+// the key. Get always reads the latest version. Updates of
+// other KES servers may race.
+func (s *HSMStore) Update(ctx context.Context, name string, oldValue, newValue []byte) error {
+	if err := validName(name); err != nil {
+		return err
+	}
+	if len(newValue) > maxValueSize {
+		return fmt.Errorf("azure: failed to update '%s': value too large", name)
+	}
+	return s.locks.Update(ctx, s, name, oldValue, newValue, func(ctx context.Context, name string, _, value []byte) error {
+		iv, tag, ciphertext, stat, err := s.client.Encrypt(ctx, s.wrappingKey, value, []byte(name))
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("azure: failed to update '%s': failed to encrypt key: %v", name, err)
+		}
+		if stat.StatusCode != http.StatusOK {
+			return fmt.Errorf("azure: failed to update '%s': failed to encrypt key: %s (%s)", name, stat.Message, stat.ErrorCode)
+		}
+
+		stat, err = s.client.CreateKey(ctx, name, encodeTags(iv, tag, ciphertext))
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("azure: failed to update '%s': %v", name, err)
+		}
+		switch {
+		case stat.StatusCode == http.StatusOK:
+			return nil
+		case stat.StatusCode == http.StatusConflict && (stat.ErrorCode == "ObjectIsDeletedButRecoverable" || stat.ErrorCode == "ObjectIsBeingDeleted"):
+			return kes.ErrKeyNotFound
+		case stat.StatusCode == http.StatusForbidden:
+			return fmt.Errorf("azure: failed to update '%s': insufficient permissions: %s", name, stat.Message)
+		default:
+			return fmt.Errorf("azure: failed to update '%s': %s (%s)", name, stat.Message, stat.ErrorCode)
+		}
+	})
+}
+
+// Get returns the value associated with the given key.
+// It returns kes.ErrKeyNotFound if no such key exists.
+func (s *HSMStore) Get(ctx context.Context, name string) ([]byte, error) {
+	if err := validName(name); err != nil {
+		return nil, err
+	}
+	key, stat, err := s.client.GetKey(ctx, name)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("azure: failed to get '%s': %v", name, err)
+	}
+	if stat.StatusCode == http.StatusNotFound {
+		return nil, kes.ErrKeyNotFound
+	}
+	if stat.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("azure: failed to get '%s': %s (%s)", name, stat.Message, stat.ErrorCode)
+	}
+
+	iv, tag, ciphertext, err := decodeTags(key.Tags)
+	if err != nil {
+		return nil, fmt.Errorf("azure: failed to get '%s': %v", name, err)
+	}
+	value, stat, err := s.client.Decrypt(ctx, s.wrappingKey, iv, tag, ciphertext, []byte(name))
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("azure: failed to get '%s': failed to decrypt key: %v", name, err)
+	}
+	if stat.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("azure: failed to get '%s': failed to decrypt key: %s (%s)", name, stat.Message, stat.ErrorCode)
+	}
+	return value, nil
+}
+
+// GetMany returns the values associated with the given keys. It
+// sends up to batch.Concurrency requests concurrently since
+// Azure Managed HSM has no batch API for keys.
+func (s *HSMStore) GetMany(ctx context.Context, names []string) ([][]byte, []error) {
+	return batch.Get[string, []byte](ctx, s, names)
+}
+
+// Delete deletes and purges the key from Managed HSM.
+//
+// Managed HSM always deletes keys softly. Like for KeyVault,
+// a full delete is a two-step process. Hence, Delete cannot
+// guarantee atomic semantics.
+func (s *HSMStore) Delete(ctx context.Context, name string) error {
+	if err := validName(name); err != nil {
+		return err
+	}
+	stat, err := s.client.DeleteKey(ctx, name)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("azure: failed to delete '%s': %v", name, err)
+	}
+	if stat.StatusCode == http.StatusNotFound {
+		return kes.ErrKeyNotFound
+	}
+	if stat.StatusCode != http.StatusOK {
+		return fmt.Errorf("azure: failed to delete '%s': %s (%s)", name, stat.Message, stat.ErrorCode)
+	}
+
+	const (
+		Retry  = 7
+		Delay  = 200 * time.Millisecond
+		Jitter = 800 * time.Millisecond
+	)
+	for i := 0; i < Retry; i++ {
+		stat, err = s.client.PurgeKey(ctx, name)
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("azure: failed to delete '%s': %v", name, err)
+		}
+		switch {
+		case stat.StatusCode == http.StatusNoContent:
+			return nil
+		case stat.StatusCode == http.StatusNotFound:
+			return nil
+		case stat.StatusCode == http.StatusForbidden:
+			return nil // Purge protection enabled or no purge permission
+		case stat.StatusCode == http.StatusConflict && stat.ErrorCode == "ObjectIsBeingDeleted":
+			time.Sleep(Delay + time.Duration(rand.Int63n(Jitter.Milliseconds()))*time.Millisecond)
+			continue
+		}
+		break
+	}
+	if stat.StatusCode == http.StatusConflict && stat.ErrorCode == "ObjectIsBeingDeleted" {
+		return nil
+	}
+	return fmt.Errorf("azure: failed to delete '%s': failed to purge deleted key: %s (%s)", name, stat.Message, stat.ErrorCode)
+}
+
+// DeleteMany deletes the given keys. Azure Managed HSM has no
+// batch API for keys. Hence, DeleteMany sends up to
+// batch.Concurrency requests concurrently.
+func (s *HSMStore) DeleteMany(ctx context.Context, names []string) []error {
+	return batch.Delete[string, []byte](ctx, s, names)
+}
+
+// List returns a new Iterator over the names of
+// all stored keys that start with the given prefix.
+//
+// It ignores any Managed HSM key not created by
+// KES, like the wrapping key.
+func (s *HSMStore) List(ctx context.Context, prefix string) (kv.Iter[string], error) {
+	var cancel context.CancelCauseFunc
+	ctx, cancel = context.WithCancelCause(ctx)
+	values := make(chan string, 10)
+
+	go func() {
+		defer close(values)
+
+		var nextLink string
+		for {
+			keys, link, status, err := s.client.ListKeys(ctx, nextLink, false)
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				cancel(err)
+				break
+			}
+			if err != nil {
+				cancel(fmt.Errorf("azure: failed to list keys: %v", err))
+				break
+			}
+			if status.StatusCode != http.StatusOK {
+				cancel(fmt.Errorf("azure: failed to list keys: %s (%s)", status.Message, status.ErrorCode))
+				break
+			}
+
+			nextLink = link
+			for _, key := range keys {
+				if _, ok := key.Tags[tagIV]; !ok || !strings.HasPrefix(key.Name, prefix) {
+					continue
+				}
+				select {
+				case values <- key.Name:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if nextLink == "" {
+				break
+			}
+		}
+	}()
+	return &iter{
+		ch:     values,
+		ctx:    ctx,
+		cancel: cancel,
+	}, nil
+}
+
+// ListDeleted returns a new Iterator over all (soft)
+// deleted keys that have not been purged yet.
+func (s *HSMStore) ListDeleted(ctx context.Context) (kv.Iter[kv.Deleted[string]], error) {
+	var cancel context.CancelCauseFunc
+	ctx, cancel = context.WithCancelCause(ctx)
+	values := make(chan kv.Deleted[string], 10)
+
+	go func() {
+		defer close(values)
+
+		var nextLink string
+		for {
+			keys, link, status, err := s.client.ListKeys(ctx, nextLink, true)
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				cancel(err)
+				break
+			}
+			if err != nil {
+				cancel(fmt.Errorf("azure: failed to list deleted keys: %v", err))
+				break
+			}
+			if status.StatusCode != http.StatusOK {
+				cancel(fmt.Errorf("azure: failed to list deleted keys: %s (%s)", status.Message, status.ErrorCode))
+				break
+			}
+
+			nextLink = link
+			for _, key := range keys {
+				if _, ok := key.Tags[tagIV]; !ok {
+					continue
+				}
+				deleted := kv.Deleted[string]{
+					Key:       key.Name,
+					DeletedAt: key.DeletedAt,
+					PurgeAt:   key.PurgeAt,
+				}
+				select {
+				case values <- deleted:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if nextLink == "" {
+				break
+			}
+		}
+	}()
+	return &deletedIter{
+		ch:     values,
+		ctx:    ctx,
+		cancel: cancel,
+	}, nil
+}
+
+// Recover recovers the (soft) deleted key. It returns
+// kes.ErrKeyNotFound if no such deleted key exists and
+// kes.ErrKeyExists if the key is not deleted.
+func (s *HSMStore) Recover(ctx context.Context, name string) error {
+	if err := validName(name); err != nil {
+		return err
+	}
+	stat, err := s.client.RecoverKey(ctx, name)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("azure: failed to recover '%s': %v", name, err)
+	}
+	switch {
+	case stat.StatusCode == http.StatusOK:
+		return nil
+	case stat.StatusCode == http.StatusNotFound:
+		return kes.ErrKeyNotFound
+	case stat.StatusCode == http.StatusConflict && stat.ErrorCode == "ObjectIsBeingDeleted":
+		return fmt.Errorf("azure: failed to recover '%s': '%s' is still being deleted", name, name)
+	case stat.StatusCode == http.StatusConflict:
+		return kes.ErrKeyExists
+	default:
+		return fmt.Errorf("azure: failed to recover '%s': %s (%s)", name, stat.Message, stat.ErrorCode)
+	}
+}
+
+// Purge purges the (soft) deleted key permanently. It
+// returns kes.ErrKeyNotFound if no such deleted key
+// exists.
+func (s *HSMStore) Purge(ctx context.Context, name string) error {
+	if err := validName(name); err != nil {
+		return err
+	}
+	stat, err := s.client.PurgeKey(ctx, name)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("azure: failed to purge '%s': %v", name, err)
+	}
+	switch {
+	case stat.StatusCode == http.StatusNoContent:
+		return nil
+	case stat.StatusCode == http.StatusNotFound:
+		return kes.ErrKeyNotFound
+	case stat.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("azure: failed to purge '%s': insufficient permissions or purge protection enabled: %s (%s)", name, stat.Message, stat.ErrorCode)
+	default:
+		return fmt.Errorf("azure: failed to purge '%s': %s (%s)", name, stat.Message, stat.ErrorCode)
+	}
+}
+
+// encodeTags returns the Managed HSM key tags
+// containing the IV, authentication tag and
+// ciphertext.
+func encodeTags(iv, tag, ciphertext []byte) map[string]string {
+	tags := map[string]string{
+		tagIV:      base64.RawURLEncoding.EncodeToString(iv),
+		tagAuthTag: base64.RawURLEncoding.EncodeToString(tag),
+	}
+	value := base64.RawURLEncoding.EncodeToString(ciphertext)
+	for i := 0; len(value) > 0; i++ {
+		n := len(value)
+		if n > maxTagSize {
+			n = maxTagSize
+		}
+		tags[tagCiphertext+strconv.Itoa(i)] = value[:n]
+		value = value[n:]
+	}
+	return tags
+}
+
+// decodeTags returns the IV, authentication tag
+// and ciphertext contained in the Managed HSM key
+// tags.
+func decodeTags(tags map[string]string) (iv, tag, ciphertext []byte, err error) {
+	ivValue, ok := tags[tagIV]
+	if !ok {
+		return nil, nil, nil, errors.New("not a KES key: no IV tag")
+	}
+	tagValue, ok := tags[tagAuthTag]
+	if !ok {
+		return nil, nil, nil, errors.New("not a KES key: no authentication tag")
+	}
+
+	var value string
+	for i := 0; i < maxChunks; i++ {
+		chunk, ok := tags[tagCiphertext+strconv.Itoa(i)]
+		if !ok {
+			break
+		}
+		value += chunk
+	}
+	if value == "" {
+		return nil, nil, nil, errors.New("not a KES key: no ciphertext tag")
+	}
+
+	if iv, err = decodeBase64URL(ivValue); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid IV tag: %v", err)
+	}
+	if tag, err = decodeBase64URL(tagValue); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid authentication tag: %v", err)
+	}
+	if ciphertext, err = decodeBase64URL(value); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid ciphertext tag: %v", err)
+	}
+	return iv, tag, ciphertext, nil
+}
@@ -0,0 +1,68 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package azure
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncodeTags(t *testing.T) {
+	for i, test := range encodeTagsTests {
+		iv := bytes.Repeat([]byte{1}, 12)
+		tag := bytes.Repeat([]byte{2}, 16)
+		ciphertext := bytes.Repeat([]byte{3}, test.Size)
+
+		tags := encodeTags(iv, tag, ciphertext)
+		if len(tags) != test.Tags {
+			t.Fatalf("Test %d: got %d tags - want %d", i, len(tags), test.Tags)
+		}
+		for k, v := range tags {
+			if len(v) > maxTagSize {
+				t.Fatalf("Test %d: tag '%s' is too large: got %d - want <= %d", i, k, len(v), maxTagSize)
+			}
+		}
+
+		iv2, tag2, ciphertext2, err := decodeTags(tags)
+		if err != nil {
+			t.Fatalf("Test %d: failed to decode tags: %v", i, err)
+		}
+		if !bytes.Equal(iv, iv2) {
+			t.Fatalf("Test %d: IV mismatch: got '%x' - want '%x'", i, iv2, iv)
+		}
+		if !bytes.Equal(tag, tag2) {
+			t.Fatalf("Test %d: authentication tag mismatch: got '%x' - want '%x'", i, tag2, tag)
+		}
+		if !bytes.Equal(ciphertext, ciphertext2) {
+			t.Fatalf("Test %d: ciphertext mismatch: got '%x' - want '%x'", i, ciphertext2, ciphertext)
+		}
+	}
+}
+
+var encodeTagsTests = []struct {
+	Size int
+	Tags int
+}{
+	{Size: 1, Tags: 3},                  // 0
+	{Size: 192, Tags: 3},                // 1
+	{Size: 193, Tags: 4},                // 2
+	{Size: maxValueSize, Tags: maxTags}, // 3
+}
+
+func TestDecodeTags(t *testing.T) {
+	for i, tags := range decodeTagsTests {
+		if _, _, _, err := decodeTags(tags); err == nil {
+			t.Fatalf("Test %d: decoding should have failed", i)
+		}
+	}
+}
+
+var decodeTagsTests = []map[string]string{
+	{},                                // 0
+	{"kes-tag": "AQ", "kes-0": "AQ"},  // 1
+	{"kes-iv": "AQ", "kes-0": "AQ"},   // 2
+	{"kes-iv": "AQ", "kes-tag": "AQ"}, // 3
+	{"kes-iv": "A", "kes-tag": "AQ", "kes-0": "AQ"}, // 4
+}
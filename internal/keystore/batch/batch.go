@@ -0,0 +1,77 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package batch implements batch operations for
+// key stores that do not support them natively.
+package batch
+
+import (
+	"context"
+	"sync"
+
+	"github.com/minio/kes/kv"
+)
+
+// Concurrency is the maximum number of requests
+// a batch operation sends to a store concurrently.
+const Concurrency = 8
+
+// Get returns the values associated with the given keys.
+// The i-th value and the i-th error belong to the i-th key.
+//
+// It fetches up to Concurrency values concurrently.
+func Get[K ~string, V any](ctx context.Context, store kv.Store[K, V], keys []K) ([]V, []error) {
+	values := make([]V, len(keys))
+	errs := make([]error, len(keys))
+	run(ctx, len(keys), errs, func(i int) (err error) {
+		values[i], err = store.Get(ctx, keys[i])
+		return err
+	})
+	return values, errs
+}
+
+// Delete deletes the given keys from the store. The
+// i-th error belongs to the i-th key.
+//
+// It deletes up to Concurrency keys concurrently.
+func Delete[K ~string, V any](ctx context.Context, store kv.Store[K, V], keys []K) []error {
+	errs := make([]error, len(keys))
+	run(ctx, len(keys), errs, func(i int) error {
+		return store.Delete(ctx, keys[i])
+	})
+	return errs
+}
+
+// run calls f for 0 <= i < n with at most Concurrency
+// calls running concurrently and stores the returned
+// error at errs[i]. Once the ctx is done, it does not
+// call f anymore but sets all remaining errors to the
+// ctx error.
+func run(ctx context.Context, n int, errs []error, f func(int) error) {
+	var (
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, Concurrency)
+	)
+	for i := 0; i < n; i++ {
+		if ctx.Err() == nil {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			for ; i < n; i++ {
+				errs[i] = err
+			}
+			break
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer func() { <-sem; wg.Done() }()
+			errs[i] = f(i)
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,65 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package batch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/keystore/mem"
+)
+
+func TestGet(t *testing.T) {
+	ctx := context.Background()
+	store := &mem.Store{}
+
+	const N = 3 * Concurrency
+	names := make([]string, 0, N+1)
+	for i := 0; i < N; i++ {
+		name := fmt.Sprintf("my-key-%d", i)
+		if err := store.Create(ctx, name, []byte(name)); err != nil {
+			t.Fatalf("Failed to create key: %v", err)
+		}
+		names = append(names, name)
+	}
+	names = append(names, "my-key-missing")
+
+	values, errs := Get[string, []byte](ctx, store, names)
+	for i, name := range names[:N] {
+		if errs[i] != nil {
+			t.Fatalf("Failed to get key '%s': %v", name, errs[i])
+		}
+		if !bytes.Equal(values[i], []byte(name)) {
+			t.Fatalf("Invalid value of key '%s': got '%s' - want '%s'", name, values[i], name)
+		}
+	}
+	if !errors.Is(errs[N], kes.ErrKeyNotFound) {
+		t.Fatalf("Getting non-existing key: got '%v' - want '%v'", errs[N], kes.ErrKeyNotFound)
+	}
+}
+
+func TestDeleteCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	store := &mem.Store{}
+	if err := store.Create(context.Background(), "my-key", []byte("my-value")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+
+	errs := Delete[string, []byte](ctx, store, []string{"my-key", "my-key"})
+	for _, err := range errs {
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Deleting with canceled context: got '%v' - want '%v'", err, context.Canceled)
+		}
+	}
+	if _, err := store.Get(context.Background(), "my-key"); err != nil {
+		t.Fatalf("Key has been deleted even though the context was canceled: %v", err)
+	}
+}
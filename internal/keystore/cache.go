@@ -43,6 +43,14 @@ type CacheConfig struct {
 	// Offline caching is only used when the kv.Store
 	// is not available and ExpiryOffline > 0.
 	ExpiryOffline time.Duration
+
+	// DeleteExpired is the time interval at which keys
+	// that have expired get deleted from the kv.Store.
+	//
+	// The zero value means expired keys are not deleted.
+	// However, they cannot be used for any cryptographic
+	// operation.
+	DeleteExpired time.Duration
 }
 
 // NewCache returns a new Cache wrapping the store.
@@ -87,14 +95,20 @@ func NewCache(ctx context.Context, store kv.Store[string, []byte], config *Cache
 			c.cache.DeleteAll()
 		}
 	})
+	go c.gc(ctxGC, config.DeleteExpired, func() {
+		if offline := c.offline.Load(); !offline {
+			c.deleteExpired(ctxGC)
+		}
+	})
 	go c.gc(ctxGC, 10*time.Second, func() {
-		_, err := c.store.Status(ctxGC)
+		_, err := c.Status(ctxGC)
 		if err != nil && !errors.Is(err, context.Canceled) {
 			c.offline.Store(true)
 		} else {
 			c.offline.Store(false)
 		}
 	})
+	go c.watch(ctxGC)
 	return c
 }
 
@@ -115,6 +129,8 @@ type Cache struct {
 	// cache (with different GC config).
 	offline  atomic.Bool
 	cancelGC func() // Stops the GC
+
+	latency latencyWindow // Latencies of the most recent status checks
 }
 
 var _ kv.Store[string, key.Key] = (*Cache)(nil)
@@ -123,10 +139,19 @@ var _ kv.Store[string, key.Key] = (*Cache)(nil)
 // remove entries from the Cache.
 func (c *Cache) Stop() { c.cancelGC() }
 
+// Offline reports whether the underlying kv.Store is
+// considered offline. While offline, the Cache serves
+// keys from memory only.
+func (c *Cache) Offline() bool { return c.offline.Load() }
+
 // Status returns the current state of the underlying
 // kv.Store.
 func (c *Cache) Status(ctx context.Context) (kv.State, error) {
-	return c.store.Status(ctx)
+	state, err := c.store.Status(ctx)
+	if err == nil {
+		c.latency.Add(state.Latency)
+	}
+	return state, err
 }
 
 // Create creates a new entry at the underlying kv.Store
@@ -144,6 +169,9 @@ func (c *Cache) Create(ctx context.Context, name string, key key.Key) error {
 		if errors.Is(err, kes.ErrKeyExists) {
 			return kes.ErrKeyExists
 		}
+		if errors.Is(err, kv.ErrDeleted) {
+			return errKeyDeleted
+		}
 		log.Printf("keystore: failed to create key '%s': %v", name, err)
 		return errCreateKey
 	}
@@ -158,6 +186,45 @@ func (c *Cache) Set(ctx context.Context, name string, key key.Key) error {
 	return c.Create(ctx, name, key)
 }
 
+// Update replaces the key at the underlying kv.Store with
+// newKey if and only if the stored key is equal to oldKey.
+//
+// It returns ErrNotExists if no such entry exists and an
+// HTTP 409 Conflict error if the stored key has changed.
+func (c *Cache) Update(ctx context.Context, name string, oldKey, newKey key.Key) error {
+	oldValue, err := oldKey.MarshalText()
+	if err != nil {
+		log.Printf("keystore: failed to encode key '%s': %v", name, err)
+		return errUpdateKey
+	}
+	newValue, err := newKey.MarshalText()
+	if err != nil {
+		log.Printf("keystore: failed to encode key '%s': %v", name, err)
+		return errUpdateKey
+	}
+
+	if err = c.store.Update(ctx, name, oldValue, newValue); err != nil {
+		if errors.Is(err, kes.ErrKeyNotFound) {
+			return kes.ErrKeyNotFound
+		}
+		if errors.Is(err, kv.ErrConflict) {
+			c.cache.Delete(name) // The cached key may be stale. Fetch it again on the next access.
+			return errKeyConflict
+		}
+		if errors.Is(err, kv.ErrDeleted) {
+			return errKeyDeleted
+		}
+		if errors.Is(err, kv.ErrNotSupported) {
+			return errUpdateNotSupported
+		}
+		log.Printf("keystore: failed to update key '%s': %v", name, err)
+		return errUpdateKey
+	}
+
+	c.cache.Delete(name)
+	return nil
+}
+
 // Delete deletes the key from the underlying kv.Store.
 //
 // It returns ErrNotExists if no such entry exists.
@@ -166,6 +233,9 @@ func (c *Cache) Delete(ctx context.Context, name string) error {
 		if errors.Is(err, kes.ErrKeyNotFound) {
 			return err
 		}
+		if errors.Is(err, kv.ErrDeleted) {
+			return errKeyDeleted
+		}
 		log.Printf("keystore: failed to delete key '%s': %v", name, err)
 		return errDeleteKey
 	}
@@ -174,9 +244,30 @@ func (c *Cache) Delete(ctx context.Context, name string) error {
 	return nil
 }
 
-// List returns an Iter enumerating the stored keys.
-func (c *Cache) List(ctx context.Context) (kv.Iter[string], error) {
-	iter, err := c.store.List(ctx)
+// DeleteMany deletes the keys from the underlying kv.Store
+// with a single batch operation. The i-th error belongs to
+// the i-th name.
+func (c *Cache) DeleteMany(ctx context.Context, names []string) []error {
+	errs := c.store.DeleteMany(ctx, names)
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			c.cache.Delete(names[i])
+		case errors.Is(err, kes.ErrKeyNotFound):
+		case errors.Is(err, kv.ErrDeleted):
+			errs[i] = errKeyDeleted
+		default:
+			log.Printf("keystore: failed to delete key '%s': %v", names[i], err)
+			errs[i] = errDeleteKey
+		}
+	}
+	return errs
+}
+
+// List returns an Iter enumerating the stored keys
+// that start with the given prefix.
+func (c *Cache) List(ctx context.Context, prefix string) (kv.Iter[string], error) {
+	iter, err := c.store.List(ctx, prefix)
 	if err != nil {
 		log.Printf("keystore: failed to list keys: %v", err)
 		return nil, errListKey
@@ -184,6 +275,79 @@ func (c *Cache) List(ctx context.Context) (kv.Iter[string], error) {
 	return iter, nil
 }
 
+// ListDeleted returns an Iter enumerating the deleted
+// but recoverable keys of the underlying kv.Store.
+//
+// It returns an error if the kv.Store does not delete
+// keys softly.
+func (c *Cache) ListDeleted(ctx context.Context) (kv.Iter[kv.Deleted[string]], error) {
+	r, ok := c.recoverer()
+	if !ok {
+		return nil, errRecoverNotSupported
+	}
+	iter, err := r.ListDeleted(ctx)
+	if err != nil {
+		log.Printf("keystore: failed to list deleted keys: %v", err)
+		return nil, errListKey
+	}
+	return iter, nil
+}
+
+// Recover recovers the deleted key at the underlying
+// kv.Store.
+//
+// It returns an error if the kv.Store does not delete
+// keys softly.
+func (c *Cache) Recover(ctx context.Context, name string) error {
+	r, ok := c.recoverer()
+	if !ok {
+		return errRecoverNotSupported
+	}
+	if err := r.Recover(ctx, name); err != nil {
+		if errors.Is(err, kes.ErrKeyNotFound) || errors.Is(err, kes.ErrKeyExists) {
+			return err
+		}
+		log.Printf("keystore: failed to recover key '%s': %v", name, err)
+		return errRecoverKey
+	}
+	return nil
+}
+
+// Purge deletes the deleted key at the underlying
+// kv.Store permanently.
+//
+// It returns an error if the kv.Store does not delete
+// keys softly.
+func (c *Cache) Purge(ctx context.Context, name string) error {
+	r, ok := c.recoverer()
+	if !ok {
+		return errRecoverNotSupported
+	}
+	if err := r.Purge(ctx, name); err != nil {
+		if errors.Is(err, kes.ErrKeyNotFound) {
+			return err
+		}
+		log.Printf("keystore: failed to purge key '%s': %v", name, err)
+		return errPurgeKey
+	}
+	return nil
+}
+
+// recoverer returns the underlying kv.Store as kv.Recoverer,
+// if it deletes keys softly. A LazyStore that has not connected
+// yet is returned as is since it is not known yet whether the
+// kv.Store it connects to deletes keys softly.
+func (c *Cache) recoverer() (kv.Recoverer[string], bool) {
+	store := c.store
+	if lazy, ok := store.(*LazyStore); ok {
+		if store = lazy.Store(); store == nil {
+			return lazy, true
+		}
+	}
+	r, ok := store.(kv.Recoverer[string])
+	return r, ok
+}
+
 // Get returns the requested key. Get only fetches the key from the
 // underlying kv.Store if it isn't in the Cache.
 //
@@ -209,10 +373,53 @@ func (c *Cache) Get(ctx context.Context, name string) (key.Key, error) {
 	}
 
 	b, err := c.store.Get(ctx, name)
+	return c.add(name, b, err)
+}
+
+// GetMany returns the requested keys. The i-th key and the
+// i-th error belong to the i-th name.
+//
+// GetMany fetches all keys that aren't in the Cache with a
+// single batch operation from the underlying kv.Store.
+func (c *Cache) GetMany(ctx context.Context, names []string) ([]key.Key, []error) {
+	keys := make([]key.Key, len(names))
+	errs := make([]error, len(names))
+
+	var (
+		missing []string
+		indices []int
+	)
+	for i, name := range names {
+		if entry, ok := c.cache.Get(name); ok {
+			entry.Used.Store(true)
+			keys[i] = entry.Key
+			continue
+		}
+		missing = append(missing, name)
+		indices = append(indices, i)
+	}
+	if len(missing) == 0 {
+		return keys, errs
+	}
+
+	values, vErrs := c.store.GetMany(ctx, missing)
+	for j, i := range indices {
+		keys[i], errs[i] = c.add(missing[j], values[j], vErrs[j])
+	}
+	return keys, errs
+}
+
+// add parses the key fetched from the underlying kv.Store
+// and adds it to the Cache. The err is the error returned
+// by the kv.Store when fetching the key, if any.
+func (c *Cache) add(name string, b []byte, err error) (key.Key, error) {
 	if err != nil {
 		if errors.Is(err, kes.ErrKeyNotFound) {
 			return key.Key{}, kes.ErrKeyNotFound
 		}
+		if errors.Is(err, kv.ErrDeleted) {
+			return key.Key{}, errKeyDeleted
+		}
 		log.Printf("keystore: failed to fetch key '%s': %v", name, err)
 		return key.Key{}, errGetKey
 	}
@@ -231,6 +438,91 @@ func (c *Cache) Get(ctx context.Context, name string) (key.Key, error) {
 	return k, nil
 }
 
+// deleteExpired deletes all expired keys from the
+// underlying kv.Store.
+func (c *Cache) deleteExpired(ctx context.Context) {
+	iter, err := c.store.List(ctx, "")
+	if err != nil {
+		log.Printf("keystore: failed to list keys: %v", err)
+		return
+	}
+	defer iter.Close()
+
+	// Fetch and delete keys in batches to avoid one
+	// round trip to the kv.Store per key.
+	const BatchSize = 100
+	names := make([]string, 0, BatchSize)
+	for name, ok := iter.Next(); ok; name, ok = iter.Next() {
+		if names = append(names, name); len(names) == BatchSize {
+			c.deleteExpiredBatch(ctx, names)
+			names = names[:0]
+		}
+	}
+	if len(names) > 0 {
+		c.deleteExpiredBatch(ctx, names)
+	}
+	if err = iter.Close(); err != nil {
+		log.Printf("keystore: failed to list keys: %v", err)
+	}
+}
+
+// deleteExpiredBatch deletes all expired keys of the
+// given names from the underlying kv.Store.
+func (c *Cache) deleteExpiredBatch(ctx context.Context, names []string) {
+	values, errs := c.store.GetMany(ctx, names)
+
+	var expired []string
+	for i, b := range values {
+		if errs[i] != nil {
+			continue // The key may have been deleted in the meantime
+		}
+		if k, err := key.Parse(b); err == nil && k.Expired() {
+			expired = append(expired, names[i])
+		}
+	}
+	if len(expired) == 0 {
+		return
+	}
+	for i, err := range c.DeleteMany(ctx, expired) {
+		if err != nil && !errors.Is(err, kes.ErrKeyNotFound) {
+			log.Printf("keystore: failed to delete expired key '%s': %v", expired[i], err)
+		}
+	}
+}
+
+// watch evicts keys from the cache once the underlying kv.Store
+// reports that they have changed, if it implements kv.Watcher.
+// This keeps the caches of multiple KES servers sharing the same
+// kv.Store consistent.
+func (c *Cache) watch(ctx context.Context) {
+	store := c.store
+	if lazy, ok := store.(*LazyStore); ok {
+		select {
+		case <-ctx.Done():
+			return
+		case <-lazy.Done():
+		}
+		if store = lazy.Store(); store == nil {
+			return
+		}
+	}
+	w, ok := store.(kv.Watcher[string])
+	if !ok {
+		return
+	}
+
+	err := w.Watch(ctx, func(name string) {
+		if name == "" {
+			c.cache.DeleteAll()
+		} else {
+			c.cache.Delete(name)
+		}
+	})
+	if err != nil && !errors.Is(err, context.Canceled) {
+		log.Printf("keystore: failed to watch keys: %v", err)
+	}
+}
+
 // gc executes f periodically until the ctx.Done() channel returns.
 func (c *Cache) gc(ctx context.Context, interval time.Duration, f func()) {
 	if interval == 0 {
@@ -261,6 +553,15 @@ type entry struct {
 var (
 	errCreateKey = kes.NewError(http.StatusBadGateway, "bad gateway: failed to create key")
 	errGetKey    = kes.NewError(http.StatusBadGateway, "bad gateway: failed to access key")
+	errUpdateKey = kes.NewError(http.StatusBadGateway, "bad gateway: failed to update key")
 	errDeleteKey = kes.NewError(http.StatusBadGateway, "bad gateway: failed to delete key")
 	errListKey   = kes.NewError(http.StatusBadGateway, "bad gateway: failed to list keys")
+
+	errRecoverKey = kes.NewError(http.StatusBadGateway, "bad gateway: failed to recover key")
+	errPurgeKey   = kes.NewError(http.StatusBadGateway, "bad gateway: failed to purge key")
+
+	errKeyDeleted          = kes.NewError(http.StatusConflict, "key is deleted but recoverable: either restore or purge it")
+	errKeyConflict         = kes.NewError(http.StatusConflict, "key has been modified concurrently")
+	errRecoverNotSupported = kes.NewError(http.StatusNotImplemented, "keystore does not support recovering deleted keys")
+	errUpdateNotSupported  = kes.NewError(http.StatusNotImplemented, "keystore does not support updating keys")
 )
@@ -0,0 +1,69 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package keystore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/key"
+	"github.com/minio/kes/internal/keystore/mem"
+)
+
+func TestCacheWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := &watchStore{changed: make(chan string)}
+	cache := NewCache(ctx, store, &CacheConfig{})
+	defer cache.Stop()
+
+	k, err := key.Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	if err = cache.Create(ctx, "my-key", k); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if _, err = cache.Get(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to get key: %v", err)
+	}
+
+	// Delete the key from the underlying store, like another
+	// KES server would do, and notify the cache about it.
+	if err = store.Store.Delete(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to delete key: %v", err)
+	}
+	if _, err = cache.Get(ctx, "my-key"); err != nil {
+		t.Fatalf("Key has been evicted from cache before notification: %v", err)
+	}
+	select {
+	case store.changed <- "my-key":
+	case <-time.After(5 * time.Second):
+		t.Fatal("Cache is not watching the store")
+	}
+	store.changed <- "" // Wait until the cache has processed the first notification
+	if _, err = cache.Get(ctx, "my-key"); err == nil {
+		t.Fatal("Key has not been evicted from cache after notification")
+	}
+}
+
+type watchStore struct {
+	mem.Store
+	changed chan string
+}
+
+func (s *watchStore) Watch(ctx context.Context, f func(string)) error {
+	for {
+		select {
+		case name := <-s.changed:
+			f(name)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package cas implements compare-and-swap updates for
+// key stores that do not support compare-and-swap
+// natively.
+package cas
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/minio/kes/internal/cache"
+	"github.com/minio/kes/kv"
+)
+
+// ReplaceFunc replaces the current value of the named
+// entry, which is equal to oldValue, with newValue.
+type ReplaceFunc func(ctx context.Context, name string, oldValue, newValue []byte) error
+
+// Locker emulates compare-and-swap updates by
+// serializing all updates of the same entry.
+//
+// A Locker only serializes updates within the
+// same process. Hence, updates are not atomic
+// if multiple KES servers share the same store.
+//
+// The zero value for a Locker is ready to use.
+// A Locker must not be copied after first use.
+type Locker struct {
+	barrier cache.Barrier[string]
+}
+
+// Update replaces the value of the named entry with
+// newValue if and only if its current value is equal
+// to oldValue.
+//
+// It fetches the current value from the store and
+// calls replace to store the new value. The replace
+// function should overwrite the entry in place, e.g.
+// by adding a new version. Stores that cannot do so
+// should not emulate updates by deleting and creating
+// the entry but return kv.ErrNotSupported instead.
+//
+// It returns kv.ErrConflict if the current value
+// is not equal to oldValue.
+func (l *Locker) Update(ctx context.Context, store kv.Store[string, []byte], name string, oldValue, newValue []byte, replace ReplaceFunc) error {
+	l.barrier.Lock(name)
+	defer l.barrier.Unlock(name)
+
+	value, err := store.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(value, oldValue) {
+		return kv.ErrConflict
+	}
+	return replace(ctx, name, value, newValue)
+}
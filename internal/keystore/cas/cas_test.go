@@ -0,0 +1,44 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package cas
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/keystore/mem"
+	"github.com/minio/kes/kv"
+)
+
+func TestLockerUpdate(t *testing.T) {
+	ctx := context.Background()
+	store := &mem.Store{}
+	if err := store.Create(ctx, "my-key", []byte("my-value")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+
+	var locker Locker
+	replace := func(ctx context.Context, name string, _, value []byte) error {
+		if err := store.Delete(ctx, name); err != nil {
+			return err
+		}
+		return store.Create(ctx, name, value)
+	}
+	if err := locker.Update(ctx, store, "other-key", []byte("my-value"), []byte("new-value"), replace); !errors.Is(err, kes.ErrKeyNotFound) {
+		t.Fatalf("Updating non-existing key: got '%v' - want '%v'", err, kes.ErrKeyNotFound)
+	}
+	if err := locker.Update(ctx, store, "my-key", []byte("other-value"), []byte("new-value"), replace); !errors.Is(err, kv.ErrConflict) {
+		t.Fatalf("Updating with wrong old value: got '%v' - want '%v'", err, kv.ErrConflict)
+	}
+	if err := locker.Update(ctx, store, "my-key", []byte("my-value"), []byte("new-value"), replace); err != nil {
+		t.Fatalf("Failed to update key: %v", err)
+	}
+	if value, _ := store.Get(ctx, "my-key"); !bytes.Equal(value, []byte("new-value")) {
+		t.Fatalf("Invalid value: got '%s' - want '%s'", value, "new-value")
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package cloudhsm implements a key store that stores keys
+// on an AWS CloudHSM cluster using the CloudHSM PKCS #11
+// library.
+//
+// The CloudHSM client SDK distributes requests across all
+// HSMs of the cluster. The cluster, i.e. the HSM IP addresses
+// or the cluster ID and the cluster CA certificate, is
+// configured via the SDK's configure-pkcs11 tool. If an HSM
+// becomes unavailable, the store re-opens its session such
+// that the SDK fails over to another HSM of the cluster.
+package cloudhsm
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/minio/kes/internal/keystore/pkcs11"
+)
+
+// DefaultModule is the default path of the
+// CloudHSM PKCS #11 library.
+const DefaultModule = "/opt/cloudhsm/lib/libcloudhsm_pkcs11.so"
+
+// Config is a structure containing configuration
+// options for connecting to an AWS CloudHSM cluster.
+type Config struct {
+	// Module is the path to the CloudHSM PKCS #11
+	// library. If empty, defaults to DefaultModule.
+	Module string
+
+	// Username is the name of the CloudHSM crypto
+	// user (CU).
+	Username string
+
+	// Password is the password of the crypto user.
+	Password string
+
+	// WrappingKey is the label of the AES key on the
+	// cluster used to encrypt and decrypt keys.
+	WrappingKey string
+
+	// Application is the application of the data
+	// objects that contain keys. If empty, defaults
+	// to "kes".
+	Application string
+
+	// Retry is the number of times an operation is
+	// retried if an HSM of the cluster becomes
+	// unavailable. If <= 0, defaults to 5.
+	Retry int
+
+	// RetryDelay is the time to wait between two
+	// failover attempts. If <= 0, defaults to 1s.
+	RetryDelay time.Duration
+}
+
+// Connect loads the CloudHSM PKCS #11 library, logs into the
+// cluster as crypto user and returns a new PKCS #11 store.
+func Connect(ctx context.Context, config *Config) (*pkcs11.Store, error) {
+	if config.Username == "" {
+		return nil, errors.New("cloudhsm: no crypto user specified")
+	}
+	if config.Password == "" {
+		return nil, errors.New("cloudhsm: no password specified")
+	}
+	if config.WrappingKey == "" {
+		return nil, errors.New("cloudhsm: no wrapping key specified")
+	}
+
+	module := config.Module
+	if module == "" {
+		module = DefaultModule
+	}
+	retry := config.Retry
+	if retry <= 0 {
+		retry = 5
+	}
+	retryDelay := config.RetryDelay
+	if retryDelay <= 0 {
+		retryDelay = 1 * time.Second
+	}
+
+	// CloudHSM provides a single token per cluster and
+	// expects the PIN to be <username>:<password>.
+	return pkcs11.Connect(ctx, &pkcs11.Config{
+		Module:      module,
+		AnyToken:    true,
+		PIN:         config.Username + ":" + config.Password,
+		WrappingKey: config.WrappingKey,
+		Application: config.Application,
+		Retry:       retry,
+		RetryDelay:  retryDelay,
+	})
+}
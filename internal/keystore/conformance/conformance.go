@@ -0,0 +1,409 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package conformance implements a conformance test suite
+// for KES keystores.
+//
+// The suite checks whether a keystore implements the
+// kv.Store semantics KES relies on. It only touches
+// entries with a random name prefix such that it can
+// be run against keystores that contain other keys.
+package conformance
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/kv"
+)
+
+// Result is the result of a single conformance test.
+type Result struct {
+	Name     string        // Name of the test
+	Duration time.Duration // Time it took to run the test
+	Err      error         // Non-nil if the test failed
+}
+
+// Passed reports whether the test passed.
+func (r *Result) Passed() bool { return r.Err == nil }
+
+// Report is the result of a conformance test run.
+type Report struct {
+	Prefix  string   // Name prefix of all entries created by the tests
+	Results []Result // Results of all tests in execution order
+}
+
+// Passed reports whether all tests passed.
+func (r *Report) Passed() bool {
+	for i := range r.Results {
+		if !r.Results[i].Passed() {
+			return false
+		}
+	}
+	return true
+}
+
+// Tests is the list of conformance tests in
+// execution order.
+var Tests = []struct {
+	Name string
+	Run  func(context.Context, kv.Store[string, []byte], string) error
+}{
+	{Name: "Status", Run: testStatus},
+	{Name: "Create", Run: testCreate},
+	{Name: "Set", Run: testSet},
+	{Name: "Get", Run: testGet},
+	{Name: "GetMany", Run: testGetMany},
+	{Name: "Update", Run: testUpdate},
+	{Name: "List", Run: testList},
+	{Name: "ListNested", Run: testListNested},
+	{Name: "Delete", Run: testDelete},
+	{Name: "DeleteMany", Run: testDeleteMany},
+}
+
+// Run runs all conformance tests against the given store
+// and returns a report of the results.
+//
+// All tests use keys starting with a random prefix and
+// remove these keys once completed. If the store fails
+// to delete a key, it may have to be removed manually.
+//
+// Run stops early when the ctx is canceled and reports
+// all remaining tests as failed.
+func Run(ctx context.Context, store kv.Store[string, []byte]) (*Report, error) {
+	var random [8]byte
+	if _, err := rand.Read(random[:]); err != nil {
+		return nil, err
+	}
+	report := &Report{
+		Prefix:  "kes-conformance-" + hex.EncodeToString(random[:]) + "-",
+		Results: make([]Result, 0, len(Tests)),
+	}
+
+	for _, test := range Tests {
+		if err := ctx.Err(); err != nil {
+			report.Results = append(report.Results, Result{Name: test.Name, Err: err})
+			continue
+		}
+
+		prefix := report.Prefix + test.Name + "-"
+		start := time.Now()
+		err := test.Run(ctx, store, prefix)
+		duration := time.Since(start)
+
+		if cErr := clean(ctx, store, prefix); err == nil && cErr != nil {
+			err = cErr
+		}
+		report.Results = append(report.Results, Result{
+			Name:     test.Name,
+			Duration: duration,
+			Err:      err,
+		})
+	}
+	return report, nil
+}
+
+func testStatus(ctx context.Context, store kv.Store[string, []byte], _ string) error {
+	if _, err := store.Status(ctx); err != nil {
+		return fmt.Errorf("failed to fetch status: %v", err)
+	}
+	return nil
+}
+
+func testCreate(ctx context.Context, store kv.Store[string, []byte], prefix string) error {
+	name := prefix + "my-key"
+	if err := store.Create(ctx, name, []byte("my-value")); err != nil {
+		return fmt.Errorf("failed to create key '%s': %v", name, err)
+	}
+	if err := store.Create(ctx, name, []byte("my-value")); err == nil {
+		return fmt.Errorf("creating existing key '%s' should have failed", name)
+	}
+	return nil
+}
+
+func testSet(ctx context.Context, store kv.Store[string, []byte], prefix string) error {
+	name := prefix + "my-key"
+	if err := store.Set(ctx, name, []byte("my-value")); err != nil {
+		return fmt.Errorf("failed to set key '%s': %v", name, err)
+	}
+
+	value, err := store.Get(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to get key '%s': %v", name, err)
+	}
+	if !bytes.Equal(value, []byte("my-value")) {
+		return fmt.Errorf("value mismatch of key '%s': got '%s' - want '%s'", name, value, "my-value")
+	}
+	return nil
+}
+
+func testGet(ctx context.Context, store kv.Store[string, []byte], prefix string) error {
+	name := prefix + "my-key"
+	if _, err := store.Get(ctx, name); err == nil {
+		return fmt.Errorf("getting non-existing key '%s' should have failed", name)
+	}
+
+	// Use a value that contains all possible bytes to
+	// detect stores that don't preserve binary values.
+	want := make([]byte, 256)
+	for i := range want {
+		want[i] = byte(i)
+	}
+	if err := store.Create(ctx, name, want); err != nil {
+		return fmt.Errorf("failed to create key '%s': %v", name, err)
+	}
+	value, err := store.Get(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to get key '%s': %v", name, err)
+	}
+	if !bytes.Equal(value, want) {
+		return fmt.Errorf("value mismatch of key '%s': got '%x' - want '%x'", name, value, want)
+	}
+	return nil
+}
+
+func testGetMany(ctx context.Context, store kv.Store[string, []byte], prefix string) error {
+	const N = 3
+
+	names := make([]string, 0, N+1)
+	for i := 0; i < N; i++ {
+		name := fmt.Sprintf("%smy-key-%d", prefix, i)
+		if err := store.Create(ctx, name, []byte(name)); err != nil {
+			return fmt.Errorf("failed to create key '%s': %v", name, err)
+		}
+		names = append(names, name)
+	}
+	names = append(names, prefix+"my-key-missing")
+
+	values, errs := store.GetMany(ctx, names)
+	if len(values) != len(names) || len(errs) != len(names) {
+		return fmt.Errorf("got %d values and %d errors for %d keys", len(values), len(errs), len(names))
+	}
+	for i, name := range names[:N] {
+		if errs[i] != nil {
+			return fmt.Errorf("failed to get key '%s': %v", name, errs[i])
+		}
+		if !bytes.Equal(values[i], []byte(name)) {
+			return fmt.Errorf("value mismatch of key '%s': got '%s' - want '%s'", name, values[i], name)
+		}
+	}
+	if errs[N] == nil {
+		return fmt.Errorf("getting non-existing key '%s' should have failed", names[N])
+	}
+	return nil
+}
+
+func testUpdate(ctx context.Context, store kv.Store[string, []byte], prefix string) error {
+	name := prefix + "my-key"
+	if err := store.Create(ctx, name, []byte("my-value")); err != nil {
+		return fmt.Errorf("failed to create key '%s': %v", name, err)
+	}
+	if err := store.Update(ctx, name, []byte("my-value"), []byte("my-value")); errors.Is(err, kv.ErrNotSupported) {
+		return nil // Updates are optional
+	}
+
+	name = prefix + "my-key-2"
+	if err := store.Update(ctx, name, []byte("my-value"), []byte("my-value-2")); err == nil {
+		return fmt.Errorf("updating non-existing key '%s' should have failed", name)
+	}
+
+	if err := store.Create(ctx, name, []byte("my-value")); err != nil {
+		return fmt.Errorf("failed to create key '%s': %v", name, err)
+	}
+	if err := store.Update(ctx, name, []byte("other-value"), []byte("my-value-2")); !errors.Is(err, kv.ErrConflict) {
+		return fmt.Errorf("updating key '%s' with wrong old value should have failed with '%v': got '%v'", name, kv.ErrConflict, err)
+	}
+	if err := store.Update(ctx, name, []byte("my-value"), []byte("my-value-2")); err != nil {
+		return fmt.Errorf("failed to update key '%s': %v", name, err)
+	}
+
+	value, err := store.Get(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to get key '%s': %v", name, err)
+	}
+	if !bytes.Equal(value, []byte("my-value-2")) {
+		return fmt.Errorf("value mismatch of key '%s': got '%s' - want '%s'", name, value, "my-value-2")
+	}
+	return nil
+}
+
+func testList(ctx context.Context, store kv.Store[string, []byte], prefix string) error {
+	const N = 3
+
+	names := make(map[string]bool, N)
+	for i := 0; i < N; i++ {
+		name := fmt.Sprintf("%smy-key-%d", prefix, i)
+		if err := store.Create(ctx, name, []byte("my-value")); err != nil {
+			return fmt.Errorf("failed to create key '%s': %v", name, err)
+		}
+		names[name] = false
+	}
+
+	listed, err := list(ctx, store, prefix)
+	if err != nil {
+		return err
+	}
+	for _, name := range listed {
+		if _, ok := names[name]; !ok {
+			return fmt.Errorf("listed unknown key '%s'", name)
+		}
+		if names[name] {
+			return fmt.Errorf("listed key '%s' more than once", name)
+		}
+		names[name] = true
+	}
+	for name, found := range names {
+		if !found {
+			return fmt.Errorf("key '%s' is not listed", name)
+		}
+	}
+
+	// The store must only list keys starting with the prefix.
+	iter, err := store.List(ctx, prefix+"my-key-1")
+	if err != nil {
+		return fmt.Errorf("failed to list keys: %v", err)
+	}
+	defer iter.Close()
+
+	listed = listed[:0]
+	for name, ok := iter.Next(); ok; name, ok = iter.Next() {
+		listed = append(listed, name)
+	}
+	if err = iter.Close(); err != nil {
+		return fmt.Errorf("failed to list keys: %v", err)
+	}
+	if len(listed) != 1 || listed[0] != prefix+"my-key-1" {
+		return fmt.Errorf("listing keys with prefix '%smy-key-1' returned %v", prefix, listed)
+	}
+	return nil
+}
+
+func testListNested(ctx context.Context, store kv.Store[string, []byte], prefix string) error {
+	names := []string{
+		prefix + "my-key",
+		prefix + "payments/my-key",
+		prefix + "payments/prod/db-key",
+	}
+	for i, name := range names {
+		err := store.Create(ctx, name, []byte("my-value"))
+		if kErr := (kes.Error{}); i == 1 && errors.As(err, &kErr) && kErr.Status() == http.StatusBadRequest {
+			return nil // Stores may reject hierarchical names
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create key '%s': %v", name, err)
+		}
+	}
+
+	for _, p := range []string{prefix, prefix + "payments/"} {
+		listed, err := list(ctx, store, p)
+		if err != nil {
+			return err
+		}
+
+		var want []string
+		for _, name := range names {
+			if strings.HasPrefix(name, p) {
+				want = append(want, name)
+			}
+		}
+		sort.Strings(listed)
+		if strings.Join(listed, ",") != strings.Join(want, ",") {
+			return fmt.Errorf("listing keys with prefix '%s' returned %v - want %v", p, listed, want)
+		}
+	}
+	return nil
+}
+
+func testDelete(ctx context.Context, store kv.Store[string, []byte], prefix string) error {
+	name := prefix + "my-key"
+	if err := store.Create(ctx, name, []byte("my-value")); err != nil {
+		return fmt.Errorf("failed to create key '%s': %v", name, err)
+	}
+	if err := store.Delete(ctx, name); err != nil {
+		return fmt.Errorf("failed to delete key '%s': %v", name, err)
+	}
+	if _, err := store.Get(ctx, name); err == nil {
+		return fmt.Errorf("getting deleted key '%s' should have failed", name)
+	}
+
+	// Some stores, like cloud KMS services, only schedule a
+	// key for deletion. However, KES expects that a deleted
+	// key can be created again.
+	if err := store.Create(ctx, name, []byte("my-value")); err != nil {
+		return fmt.Errorf("failed to re-create deleted key '%s': %v", name, err)
+	}
+	return nil
+}
+
+func testDeleteMany(ctx context.Context, store kv.Store[string, []byte], prefix string) error {
+	const N = 3
+
+	names := make([]string, 0, N)
+	for i := 0; i < N; i++ {
+		name := fmt.Sprintf("%smy-key-%d", prefix, i)
+		if err := store.Create(ctx, name, []byte("my-value")); err != nil {
+			return fmt.Errorf("failed to create key '%s': %v", name, err)
+		}
+		names = append(names, name)
+	}
+
+	errs := store.DeleteMany(ctx, names)
+	if len(errs) != len(names) {
+		return fmt.Errorf("got %d errors for %d keys", len(errs), len(names))
+	}
+	for i, name := range names {
+		if errs[i] != nil {
+			return fmt.Errorf("failed to delete key '%s': %v", name, errs[i])
+		}
+		if _, err := store.Get(ctx, name); err == nil {
+			return fmt.Errorf("getting deleted key '%s' should have failed", name)
+		}
+	}
+	return nil
+}
+
+// list returns all key names of the store that
+// start with the given prefix.
+func list(ctx context.Context, store kv.Store[string, []byte], prefix string) ([]string, error) {
+	iter, err := store.List(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list keys: %v", err)
+	}
+	defer iter.Close()
+
+	var names []string
+	for name, ok := iter.Next(); ok; name, ok = iter.Next() {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	if err = iter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to list keys: %v", err)
+	}
+	return names, nil
+}
+
+// clean deletes all keys of the store that start
+// with the given prefix.
+func clean(ctx context.Context, store kv.Store[string, []byte], prefix string) error {
+	names, err := list(ctx, store, prefix)
+	if err != nil {
+		return fmt.Errorf("cleanup: %v", err)
+	}
+	for _, name := range names {
+		if err = store.Delete(ctx, name); err != nil && !errors.Is(err, kv.ErrNotExists) {
+			return fmt.Errorf("cleanup: failed to delete '%s': %v", name, err)
+		}
+	}
+	return nil
+}
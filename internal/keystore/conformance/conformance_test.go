@@ -0,0 +1,53 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package conformance
+
+import (
+	"context"
+	"testing"
+
+	"github.com/minio/kes/internal/keystore/mem"
+)
+
+func TestRun(t *testing.T) {
+	store := &mem.Store{}
+	if err := store.Create(context.Background(), "my-key", []byte("my-value")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+
+	report, err := Run(context.Background(), store)
+	if err != nil {
+		t.Fatalf("Failed to run conformance tests: %v", err)
+	}
+	if len(report.Results) != len(Tests) {
+		t.Fatalf("Result mismatch: got %d results - want %d", len(report.Results), len(Tests))
+	}
+	for _, result := range report.Results {
+		if !result.Passed() {
+			t.Errorf("Test '%s' failed: %v", result.Name, result.Err)
+		}
+	}
+
+	names, err := list(context.Background(), store, "")
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	if len(names) != 1 || names[0] != "my-key" {
+		t.Fatalf("Conformance tests did not clean up: got %v - want [my-key]", names)
+	}
+}
+
+func TestRunCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	report, err := Run(ctx, &mem.Store{})
+	if err != nil {
+		t.Fatalf("Failed to run conformance tests: %v", err)
+	}
+	if report.Passed() {
+		t.Fatal("Conformance tests should have failed")
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package conjur
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"aead.dev/mem"
+	xhttp "github.com/minio/kes/internal/http"
+)
+
+// client is a Conjur REST API client responsible
+// for obtaining and renewing access tokens.
+type client struct {
+	xhttp.Retry
+
+	lock  sync.Mutex
+	token string
+}
+
+// Authenticate obtains a new access token from the given
+// Conjur endpoint by presenting the login's API key.
+//
+// Authenticate should be called to obtain the first access
+// token. This token can then be renewed via RenewAuthToken.
+func (c *client) Authenticate(ctx context.Context, endpoint, account string, login Credentials) error {
+	url := fmt.Sprintf("%s/authn/%s/%s/authenticate", endpoint, url.PathEscape(account), url.PathEscape(login.Login))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, xhttp.RetryReader(strings.NewReader(login.APIKey)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("Accept-Encoding", "base64")
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", resp.Status, parseServerError(resp))
+	}
+
+	const MaxSize = 1 * mem.MiB // An access token should not exceed 1 MiB
+	token, err := io.ReadAll(mem.LimitReader(resp.Body, MaxSize))
+	if err != nil {
+		return err
+	}
+	if len(token) == 0 {
+		return fmt.Errorf("server response does not contain an access token")
+	}
+
+	// Conjur returns the raw JSON token unless it supports
+	// the base64 encoding requested via Accept-Encoding.
+	value := strings.TrimSpace(string(token))
+	if !strings.EqualFold(resp.Header.Get("Content-Encoding"), "base64") {
+		value = base64.StdEncoding.EncodeToString(token)
+	}
+
+	c.lock.Lock()
+	c.token = value
+	c.lock.Unlock()
+	return nil
+}
+
+// RenewAuthToken renews the client's access token before it
+// expires. It blocks until <-ctx.Done() completes.
+//
+// Conjur access tokens are valid for 8 minutes. Hence,
+// RenewAuthToken obtains a new token every 4 minutes. If
+// it fails to obtain a new access token, it keeps retrying
+// and waits for the given login.Retry delay between each
+// retry attempt.
+//
+// If login.Retry is 0 then RenewAuthToken uses a reasonable
+// default retry delay.
+func (c *client) RenewAuthToken(ctx context.Context, endpoint, account string, login Credentials) {
+	const Interval = 4 * time.Minute
+
+	if login.Retry == 0 {
+		login.Retry = 5 * time.Second
+	}
+	var (
+		timer *time.Timer
+		err   error
+	)
+	for {
+		if err != nil {
+			timer = time.NewTimer(login.Retry)
+		} else {
+			timer = time.NewTimer(Interval)
+		}
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			err = c.Authenticate(ctx, endpoint, account, login)
+			timer.Stop()
+		}
+	}
+}
+
+// AuthToken returns the HTTP Authorization header value
+// for authenticating API requests to Conjur.
+func (c *client) AuthToken() string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return fmt.Sprintf("Token token=%q", c.token)
+}
+
+// parseServerError returns the error message of a Conjur
+// API error response.
+func parseServerError(resp *http.Response) string {
+	const MaxSize = 1 * mem.MiB
+
+	// Conjur API error responses usually contain a JSON
+	// body. However, some errors - e.g. authentication
+	// failures - have an empty body. Therefore, we try
+	// to parse the JSON error message and fall back to
+	// the raw body or the HTTP status.
+	body, err := io.ReadAll(mem.LimitReader(resp.Body, MaxSize))
+	if err != nil || len(body) == 0 {
+		return http.StatusText(resp.StatusCode)
+	}
+	if strings.HasPrefix(strings.TrimSpace(resp.Header.Get("Content-Type")), "application/json") {
+		var response struct {
+			Error struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err = json.Unmarshal(body, &response); err == nil && response.Error.Message != "" {
+			return response.Error.Message
+		}
+	}
+	return strings.TrimSpace(string(body))
+}
@@ -0,0 +1,553 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package conjur implements a key store that fetches/stores
+// cryptographic keys as CyberArk Conjur variables.
+package conjur
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+	xhttp "github.com/minio/kes/internal/http"
+	"github.com/minio/kes/internal/https"
+	"github.com/minio/kes/internal/keystore/batch"
+	"github.com/minio/kes/internal/keystore/cas"
+	"github.com/minio/kes/kv"
+)
+
+// Credentials represents a Conjur login, either a
+// host or a user, and its API key that can be used
+// to obtain a short-lived access token.
+type Credentials struct {
+	Login  string        // The Conjur login, e.g. host/kes
+	APIKey string        // The API key of the login
+	Retry  time.Duration // The time to wait before trying to re-authenticate
+}
+
+// Config is a structure containing configuration
+// options for connecting to a Conjur server.
+type Config struct {
+	// Endpoint is the Conjur appliance URL.
+	Endpoint string
+
+	// Account is the Conjur organization account.
+	Account string
+
+	// Policy is the Conjur policy branch that contains
+	// the key variables, e.g. "root" or "kes". The KES
+	// login must be allowed to update this policy. If
+	// empty, the root policy is used.
+	Policy string
+
+	// CAPath is a path to the root CA certificate(s)
+	// used to verify the TLS certificate of the Conjur
+	// server. If empty, the host's root CA set is used.
+	CAPath string
+
+	// PrivateKey and Certificate are optional paths to a
+	// TLS private key and certificate for mTLS authentication
+	// to the Conjur server. The certificate is reloaded once
+	// either file changes.
+	PrivateKey  string
+	Certificate string
+
+	// ServerName is an optional TLS server name (SNI) used
+	// to verify the Conjur TLS certificate. If empty, the
+	// endpoint host name is used.
+	ServerName string
+
+	// Login credentials are used to authenticate to the
+	// Conjur server and obtain a short-lived access token.
+	Login Credentials
+}
+
+// Store is a CyberArk Conjur secret store.
+//
+// Each key is stored as Conjur variable within the
+// configured policy branch. Since Conjur variables
+// are declared via policies, creating or deleting a
+// key updates the policy branch.
+type Store struct {
+	config Config
+	client *client
+
+	locks cas.Locker
+}
+
+var _ kv.Store[string, []byte] = (*Store)(nil)
+
+// Connect returns a Store to a Conjur server
+// using the given config.
+func Connect(ctx context.Context, config *Config) (*Store, error) {
+	if config.Endpoint == "" {
+		return nil, errors.New("conjur: endpoint is empty")
+	}
+	if config.Account == "" {
+		return nil, errors.New("conjur: account is empty")
+	}
+	if (config.PrivateKey == "") != (config.Certificate == "") {
+		return nil, errors.New("conjur: TLS private key and certificate must be specified both")
+	}
+
+	var err error
+	tlsConfig := &tls.Config{
+		ServerName: config.ServerName,
+	}
+	if config.CAPath != "" {
+		tlsConfig.RootCAs, err = https.CertPoolFromFile(config.CAPath)
+		if err != nil {
+			return nil, fmt.Errorf("conjur: failed to load CA certificate: %v", err)
+		}
+	}
+	if config.Certificate != "" {
+		tlsConfig.GetClientCertificate, err = https.ClientCertificate(config.Certificate, config.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("conjur: failed to load TLS client certificate: %v", err)
+		}
+	}
+
+	c := *config
+	c.Endpoint = strings.TrimSuffix(c.Endpoint, "/")
+	if c.Policy == "" {
+		c.Policy = "root"
+	}
+
+	client := &client{
+		Retry: xhttp.Retry{
+			Client: http.Client{
+				Transport: &http.Transport{
+					TLSClientConfig: tlsConfig,
+					Proxy:           http.ProxyFromEnvironment,
+					DialContext: (&net.Dialer{
+						Timeout:   10 * time.Second,
+						KeepAlive: 10 * time.Second,
+						DualStack: true,
+					}).DialContext,
+					ForceAttemptHTTP2:     true,
+					MaxIdleConns:          100,
+					IdleConnTimeout:       30 * time.Second,
+					TLSHandshakeTimeout:   10 * time.Second,
+					ExpectContinueTimeout: 1 * time.Second,
+				},
+			},
+		},
+	}
+	if err = client.Authenticate(ctx, c.Endpoint, c.Account, c.Login); err != nil {
+		return nil, fmt.Errorf("conjur: failed to authenticate: %v", err)
+	}
+	go client.RenewAuthToken(context.Background(), c.Endpoint, c.Account, c.Login)
+	return &Store{
+		config: c,
+		client: client,
+	}, nil
+}
+
+// Status returns the current state of the Conjur server.
+// In particular, whether it is reachable and the network
+// latency.
+func (s *Store) Status(ctx context.Context) (kv.State, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.config.Endpoint, nil)
+	if err != nil {
+		return kv.State{}, err
+	}
+
+	start := time.Now()
+	resp, err := s.client.Client.Do(req)
+	if err != nil {
+		return kv.State{}, &kv.Unreachable{Err: err}
+	}
+	resp.Body.Close()
+
+	return kv.State{
+		Latency: time.Since(start),
+	}, nil
+}
+
+// Create creates the given key-value pair at Conjur if and only
+// if the given key does not exist. If such an entry already exists
+// it returns kes.ErrKeyExists.
+//
+// Create first declares the key variable by updating the policy
+// branch and then sets the variable's value.
+func (s *Store) Create(ctx context.Context, name string, value []byte) error {
+	switch _, err := s.Get(ctx, name); {
+	case err == nil:
+		return kes.ErrKeyExists
+	case !errors.Is(err, kes.ErrKeyNotFound):
+		return err
+	}
+
+	policy := fmt.Sprintf("- !variable\n  id: %q\n", name)
+	if err := s.loadPolicy(ctx, http.MethodPost, policy); err != nil {
+		return fmt.Errorf("conjur: failed to create key '%s': %v", name, err)
+	}
+
+	return s.setValue(ctx, "create", name, value)
+}
+
+// Set creates the given key-value pair at Conjur if and only
+// if the given key does not exist. If such an entry already exists
+// it returns kes.ErrKeyExists.
+func (s *Store) Set(ctx context.Context, name string, value []byte) error {
+	return s.Create(ctx, name, value)
+}
+
+// Update replaces the value of the given key with newValue
+// if and only if its current value is equal to oldValue.
+//
+// Conjur does not support compare-and-swap. Hence, Update
+// sets the new variable value while holding a lock for the
+// key. Updates of other KES servers may race.
+func (s *Store) Update(ctx context.Context, name string, oldValue, newValue []byte) error {
+	return s.locks.Update(ctx, s, name, oldValue, newValue, func(ctx context.Context, name string, _, value []byte) error {
+		return s.setValue(ctx, "update", name, value)
+	})
+}
+
+// Get returns the value associated with the given key.
+// If no entry for the key exists it returns kes.ErrKeyNotFound.
+func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
+	url := fmt.Sprintf("%s/secrets/%s/variable/%s", s.config.Endpoint, url.PathEscape(s.config.Account), s.variableID(name))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("conjur: failed to access key '%s': %v", name, err)
+	}
+	req.Header.Set("Authorization", s.client.AuthToken())
+
+	resp, err := s.client.Do(req)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("conjur: failed to access key '%s': %v", name, err)
+	}
+	defer resp.Body.Close()
+
+	// Conjur returns 404 NotFound if the variable does not
+	// exist or has no value.
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, kes.ErrKeyNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("conjur: failed to access key '%s': %s (%d)", name, parseServerError(resp), resp.StatusCode)
+	}
+
+	const MaxSize = 2 * mem.MiB
+	value, err := io.ReadAll(mem.LimitReader(resp.Body, MaxSize))
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("conjur: failed to read key '%s': %v", name, err)
+	}
+	return value, nil
+}
+
+// GetMany returns the values associated with the given keys.
+//
+// GetMany fetches all values with a single batch request. If
+// any variable does not exist or has no value, Conjur rejects
+// the entire batch request. Then, GetMany fetches the values
+// one by one to report an error for each missing key.
+func (s *Store) GetMany(ctx context.Context, names []string) ([][]byte, []error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	prefix := s.config.Account + ":variable:"
+	if s.config.Policy != "root" {
+		prefix += s.config.Policy + "/"
+	}
+	ids := make([]string, 0, len(names))
+	for _, name := range names {
+		ids = append(ids, prefix+name)
+	}
+
+	values, err := s.getMany(ctx, ids)
+	if errors.Is(err, kes.ErrKeyNotFound) {
+		return batch.Get[string, []byte](ctx, s, names)
+	}
+
+	errs := make([]error, len(names))
+	result := make([][]byte, len(names))
+	for i, name := range names {
+		switch value, ok := values[ids[i]]; {
+		case err != nil:
+			errs[i] = err
+		case !ok:
+			errs[i] = fmt.Errorf("conjur: failed to access key '%s': missing in batch response", name)
+		default:
+			result[i] = value
+		}
+	}
+	return result, errs
+}
+
+// getMany fetches the values of the given variable IDs with
+// a single batch request. It returns kes.ErrKeyNotFound if
+// any variable does not exist or has no value.
+func (s *Store) getMany(ctx context.Context, ids []string) (map[string][]byte, error) {
+	url := fmt.Sprintf("%s/secrets?variable_ids=%s", s.config.Endpoint, url.QueryEscape(strings.Join(ids, ",")))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("conjur: failed to access keys: %v", err)
+	}
+	req.Header.Set("Authorization", s.client.AuthToken())
+	req.Header.Set("Accept-Encoding", "base64") // Keys may contain arbitrary bytes
+
+	resp, err := s.client.Do(req)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("conjur: failed to access keys: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, kes.ErrKeyNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("conjur: failed to access keys: %s (%d)", parseServerError(resp), resp.StatusCode)
+	}
+
+	const MaxSize = 32 * mem.MiB
+	var response map[string]string
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(&response); err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("conjur: failed to read keys: %v", err)
+	}
+	values := make(map[string][]byte, len(response))
+	for id, value := range response {
+		b, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("conjur: failed to read keys: invalid value of '%s': %v", id, err)
+		}
+		values[id] = b
+	}
+	return values, nil
+}
+
+// Delete removes a the value associated with the given key
+// from Conjur, if it exists.
+//
+// Delete removes the key variable from the policy branch.
+// Conjur removes the variable and all its values.
+func (s *Store) Delete(ctx context.Context, name string) error {
+	policy := fmt.Sprintf("- !delete\n  record: !variable %q\n", name)
+	if err := s.loadPolicy(ctx, http.MethodPatch, policy); err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+		return fmt.Errorf("conjur: failed to delete key '%s': %v", name, err)
+	}
+	return nil
+}
+
+// DeleteMany removes the given keys from Conjur.
+//
+// DeleteMany removes all key variables from the policy
+// branch at once. If Conjur rejects the policy update,
+// DeleteMany removes the keys one by one to report an
+// error for each key that cannot be deleted.
+func (s *Store) DeleteMany(ctx context.Context, names []string) []error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	var policy strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&policy, "- !delete\n  record: !variable %q\n", name)
+	}
+	err := s.loadPolicy(ctx, http.MethodPatch, policy.String())
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		errs := make([]error, len(names))
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
+	if err != nil {
+		return batch.Delete[string, []byte](ctx, s, names)
+	}
+	return make([]error, len(names))
+}
+
+// List returns a new Iterator over the names of
+// all stored keys that start with the given prefix.
+func (s *Store) List(ctx context.Context, prefix string) (kv.Iter[string], error) {
+	type Response []struct {
+		ID string `json:"id"` // E.g. myorg:variable:kes/my-key
+	}
+
+	branch := s.config.Account + ":variable:"
+	if s.config.Policy != "root" {
+		branch += s.config.Policy + "/"
+	}
+
+	var cancel context.CancelCauseFunc
+	ctx, cancel = context.WithCancelCause(ctx)
+	values := make(chan string, 10)
+
+	go func() {
+		defer close(values)
+
+		const Limit = 200
+		for offset := 0; ; offset += Limit {
+			url := fmt.Sprintf("%s/resources/%s/variable?limit=%d&offset=%d", s.config.Endpoint, url.PathEscape(s.config.Account), Limit, offset)
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			if err != nil {
+				cancel(fmt.Errorf("conjur: failed to list keys: %v", err))
+				return
+			}
+			req.Header.Set("Authorization", s.client.AuthToken())
+
+			resp, err := s.client.Do(req)
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				cancel(err)
+				return
+			}
+			if err != nil {
+				cancel(fmt.Errorf("conjur: failed to list keys: %v", err))
+				return
+			}
+			if resp.StatusCode != http.StatusOK {
+				cancel(fmt.Errorf("conjur: failed to list keys: %s (%d)", parseServerError(resp), resp.StatusCode))
+				resp.Body.Close()
+				return
+			}
+
+			const MaxBody = 32 * mem.MiB // A page should not be larger than 32 MiB.
+			var response Response
+			err = json.NewDecoder(mem.LimitReader(resp.Body, MaxBody)).Decode(&response)
+			resp.Body.Close()
+			if err != nil {
+				if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+					cancel(err)
+				} else {
+					cancel(fmt.Errorf("conjur: failed to list keys: %v", err))
+				}
+				return
+			}
+
+			for _, v := range response {
+				name := strings.TrimPrefix(v.ID, branch)
+				if name == v.ID || name == "" {
+					continue // Variable does not belong to the policy branch
+				}
+				if !strings.HasPrefix(name, prefix) {
+					continue
+				}
+				select {
+				case values <- name:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if len(response) < Limit {
+				return
+			}
+		}
+	}()
+	return &iter{
+		ch:     values,
+		ctx:    ctx,
+		cancel: cancel,
+	}, nil
+}
+
+// loadPolicy updates the policy branch with the given
+// policy statements. The method is either POST, to add
+// statements, or PATCH, to delete records.
+func (s *Store) loadPolicy(ctx context.Context, method, policy string) error {
+	url := fmt.Sprintf("%s/policies/%s/policy/%s", s.config.Endpoint, url.PathEscape(s.config.Account), url.PathEscape(s.config.Policy))
+	req, err := http.NewRequestWithContext(ctx, method, url, xhttp.RetryReader(strings.NewReader(policy)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-yaml")
+	req.Header.Set("Authorization", s.client.AuthToken())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s (%d)", parseServerError(resp), resp.StatusCode)
+	}
+	return nil
+}
+
+// setValue sets the value of the key variable. The
+// variable has to be declared before.
+func (s *Store) setValue(ctx context.Context, op, name string, value []byte) error {
+	url := fmt.Sprintf("%s/secrets/%s/variable/%s", s.config.Endpoint, url.PathEscape(s.config.Account), s.variableID(name))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, xhttp.RetryReader(bytes.NewReader(value)))
+	if err != nil {
+		return fmt.Errorf("conjur: failed to %s key '%s': %v", op, name, err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Authorization", s.client.AuthToken())
+
+	resp, err := s.client.Do(req)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("conjur: failed to %s key '%s': %v", op, name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("conjur: failed to %s key '%s': %s (%d)", op, name, parseServerError(resp), resp.StatusCode)
+	}
+	return nil
+}
+
+// variableID returns the URL-encoded ID of the key
+// variable within the policy branch.
+func (s *Store) variableID(name string) string {
+	if s.config.Policy == "root" {
+		return url.PathEscape(name)
+	}
+	return url.PathEscape(s.config.Policy + "/" + name)
+}
+
+type iter struct {
+	ch     <-chan string
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+}
+
+func (i *iter) Next() (string, bool) {
+	select {
+	case v, ok := <-i.ch:
+		return v, ok
+	case <-i.ctx.Done():
+		return "", false
+	}
+}
+
+func (i *iter) Close() error {
+	i.cancel(context.Canceled)
+	return context.Cause(i.ctx)
+}
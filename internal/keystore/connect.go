@@ -0,0 +1,280 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package keystore
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/minio/kes/kv"
+)
+
+// Connect connects to a kv.Store using the connect function.
+//
+// If connecting fails, Connect retries with an exponential
+// backoff until it succeeds or the retry duration has passed.
+// If retry <= 0, Connect does not retry.
+func Connect(ctx context.Context, connect func(context.Context) (kv.Store[string, []byte], error), retry time.Duration) (kv.Store[string, []byte], error) {
+	return retryConnect(ctx, connect, retry, nil)
+}
+
+// ConnectLazy returns a LazyStore that connects to a kv.Store
+// in the background using the connect function. It retries
+// with an exponential backoff until it succeeds or the retry
+// duration has passed. If retry <= 0, ConnectLazy does not
+// retry.
+//
+// The returned LazyStore is not reachable until connected.
+// Hence, a server can serve status and health requests, and
+// report that it is not ready, while the kv.Store is, for
+// example, still booting.
+func ConnectLazy(ctx context.Context, connect func(context.Context) (kv.Store[string, []byte], error), retry time.Duration) *LazyStore {
+	s := &LazyStore{
+		done: make(chan struct{}),
+	}
+	go func() {
+		defer close(s.done)
+
+		store, err := retryConnect(ctx, connect, retry, s.setErr)
+
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		s.store, s.err = store, err
+	}()
+	return s
+}
+
+// retryConnect tries to connect to a kv.Store until it
+// succeeds or the retry duration has passed. It calls
+// onError, if not nil, for every failed attempt.
+func retryConnect(ctx context.Context, connect func(context.Context) (kv.Store[string, []byte], error), retry time.Duration, onError func(error)) (kv.Store[string, []byte], error) {
+	const (
+		MinDelay = 1 * time.Second
+		MaxDelay = 30 * time.Second
+	)
+
+	deadline := time.Now().Add(retry)
+	delay := MinDelay
+	for {
+		store, err := connect(ctx)
+		if err == nil {
+			return store, nil
+		}
+		if onError != nil {
+			onError(err)
+		}
+		if remaining := time.Until(deadline); remaining <= 0 {
+			return nil, err
+		} else if delay > remaining {
+			delay = remaining
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+		if delay *= 2; delay > MaxDelay {
+			delay = MaxDelay
+		}
+	}
+}
+
+// errConnecting is returned by a LazyStore that
+// has not connected to its kv.Store yet.
+var errConnecting = errors.New("keystore: connecting")
+
+// A LazyStore is a kv.Store that connects to the
+// underlying kv.Store in the background. Until
+// connected, it returns kv.Unreachable errors.
+type LazyStore struct {
+	done chan struct{}
+
+	lock  sync.RWMutex
+	store kv.Store[string, []byte]
+	err   error
+}
+
+var (
+	_ kv.Store[string, []byte] = (*LazyStore)(nil)
+	_ kv.Recoverer[string]     = (*LazyStore)(nil)
+)
+
+// Done returns a channel that is closed once the LazyStore
+// has either connected or given up connecting.
+func (s *LazyStore) Done() <-chan struct{} { return s.done }
+
+// Err returns the error that occurred while connecting,
+// if any. Once Done is closed, Err returns nil if the
+// LazyStore has connected successfully.
+func (s *LazyStore) Err() error {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.err
+}
+
+// Store returns the underlying kv.Store. It returns
+// nil if the LazyStore has not connected yet.
+func (s *LazyStore) Store() kv.Store[string, []byte] {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.store
+}
+
+// Status returns the current state of the underlying
+// kv.Store or a kv.Unreachable error if the LazyStore
+// has not connected yet.
+func (s *LazyStore) Status(ctx context.Context) (kv.State, error) {
+	store, err := s.connected()
+	if err != nil {
+		return kv.State{}, err
+	}
+	return store.Status(ctx)
+}
+
+// Create creates the entry at the underlying kv.Store.
+func (s *LazyStore) Create(ctx context.Context, key string, value []byte) error {
+	store, err := s.connected()
+	if err != nil {
+		return err
+	}
+	return store.Create(ctx, key, value)
+}
+
+// Set writes the entry to the underlying kv.Store.
+func (s *LazyStore) Set(ctx context.Context, key string, value []byte) error {
+	store, err := s.connected()
+	if err != nil {
+		return err
+	}
+	return store.Set(ctx, key, value)
+}
+
+// Get returns the value of the entry at the
+// underlying kv.Store.
+func (s *LazyStore) Get(ctx context.Context, key string) ([]byte, error) {
+	store, err := s.connected()
+	if err != nil {
+		return nil, err
+	}
+	return store.Get(ctx, key)
+}
+
+// GetMany returns the values of the entries at
+// the underlying kv.Store.
+func (s *LazyStore) GetMany(ctx context.Context, keys []string) ([][]byte, []error) {
+	store, err := s.connected()
+	if err != nil {
+		return make([][]byte, len(keys)), fill(len(keys), err)
+	}
+	return store.GetMany(ctx, keys)
+}
+
+// Update replaces the value of the entry at the
+// underlying kv.Store if it is equal to oldValue.
+func (s *LazyStore) Update(ctx context.Context, key string, oldValue, newValue []byte) error {
+	store, err := s.connected()
+	if err != nil {
+		return err
+	}
+	return store.Update(ctx, key, oldValue, newValue)
+}
+
+// Delete deletes the entry at the underlying kv.Store.
+func (s *LazyStore) Delete(ctx context.Context, key string) error {
+	store, err := s.connected()
+	if err != nil {
+		return err
+	}
+	return store.Delete(ctx, key)
+}
+
+// DeleteMany deletes the entries at the underlying
+// kv.Store.
+func (s *LazyStore) DeleteMany(ctx context.Context, keys []string) []error {
+	store, err := s.connected()
+	if err != nil {
+		return fill(len(keys), err)
+	}
+	return store.DeleteMany(ctx, keys)
+}
+
+// List returns an Iter enumerating the entries
+// of the underlying kv.Store that start with
+// the given prefix.
+func (s *LazyStore) List(ctx context.Context, prefix string) (kv.Iter[string], error) {
+	store, err := s.connected()
+	if err != nil {
+		return nil, err
+	}
+	return store.List(ctx, prefix)
+}
+
+// ListDeleted returns an Iter enumerating the deleted
+// entries of the underlying kv.Store. The underlying
+// kv.Store must be a kv.Recoverer.
+func (s *LazyStore) ListDeleted(ctx context.Context) (kv.Iter[kv.Deleted[string]], error) {
+	store, err := s.connected()
+	if err != nil {
+		return nil, err
+	}
+	return store.(kv.Recoverer[string]).ListDeleted(ctx)
+}
+
+// Recover recovers the deleted entry at the underlying
+// kv.Store. The underlying kv.Store must be a kv.Recoverer.
+func (s *LazyStore) Recover(ctx context.Context, key string) error {
+	store, err := s.connected()
+	if err != nil {
+		return err
+	}
+	return store.(kv.Recoverer[string]).Recover(ctx, key)
+}
+
+// Purge purges the deleted entry at the underlying
+// kv.Store. The underlying kv.Store must be a kv.Recoverer.
+func (s *LazyStore) Purge(ctx context.Context, key string) error {
+	store, err := s.connected()
+	if err != nil {
+		return err
+	}
+	return store.(kv.Recoverer[string]).Purge(ctx, key)
+}
+
+// connected returns the underlying kv.Store or a
+// kv.Unreachable error if not connected yet.
+func (s *LazyStore) connected() (kv.Store[string, []byte], error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.store != nil {
+		return s.store, nil
+	}
+	if s.err != nil {
+		return nil, &kv.Unreachable{Err: s.err}
+	}
+	return nil, &kv.Unreachable{Err: errConnecting}
+}
+
+// setErr records the error of a failed connection
+// attempt while the LazyStore keeps retrying.
+func (s *LazyStore) setErr(err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.err = err
+}
+
+// fill returns a slice of n errors that are all err.
+func fill(n int, err error) []error {
+	errs := make([]error, n)
+	for i := range errs {
+		errs[i] = err
+	}
+	return errs
+}
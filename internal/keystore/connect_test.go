@@ -0,0 +1,65 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package keystore
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/minio/kes/internal/keystore/mem"
+	"github.com/minio/kes/kv"
+)
+
+func TestConnect(t *testing.T) {
+	ctx := context.Background()
+
+	var attempts atomic.Int32
+	connect := func(context.Context) (kv.Store[string, []byte], error) {
+		if attempts.Add(1) < 2 {
+			return nil, errors.New("connection refused")
+		}
+		return &mem.Store{}, nil
+	}
+	if _, err := Connect(ctx, connect, 0); err == nil {
+		t.Fatal("Connect should fail without retry")
+	}
+	if _, err := Connect(ctx, connect, 5*time.Second); err != nil {
+		t.Fatalf("Connect should succeed with retry: %v", err)
+	}
+}
+
+func TestConnectLazy(t *testing.T) {
+	ctx := context.Background()
+
+	connected := make(chan struct{})
+	store := ConnectLazy(ctx, func(context.Context) (kv.Store[string, []byte], error) {
+		<-connected
+		return &mem.Store{}, nil
+	}, 0)
+
+	if _, err := store.Status(ctx); err == nil {
+		t.Fatal("Status should fail before connected")
+	} else if _, ok := kv.IsUnreachable(err); !ok {
+		t.Fatalf("Status should fail with kv.Unreachable: got '%v'", err)
+	}
+	if err := store.Create(ctx, "my-key", []byte("value")); err == nil {
+		t.Fatal("Create should fail before connected")
+	}
+
+	close(connected)
+	<-store.Done()
+	if err := store.Err(); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	if _, err := store.Status(ctx); err != nil {
+		t.Fatalf("Status should succeed once connected: %v", err)
+	}
+	if err := store.Create(ctx, "my-key", []byte("value")); err != nil {
+		t.Fatalf("Create should succeed once connected: %v", err)
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package envelope implements a key-value store that
+// encrypts values with data keys wrapped by an external
+// KMS before storing them in another key-value store.
+//
+// Each value is encrypted with its own, randomly generated,
+// AES-256 data key using AES-GCM. The data key is encrypted
+// (wrapped) by a KMS key that never leaves the KMS. Only the
+// wrapped data key, the nonce and the ciphertext are written
+// to the underlying store. Hence, the underlying store, e.g.
+// a local directory, does not have to be trusted.
+package envelope
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/minio/kes/kv"
+)
+
+// KeyWrapper wraps and unwraps data keys using a
+// key held by an external KMS.
+type KeyWrapper interface {
+	// Status returns the current state of the KMS.
+	Status(ctx context.Context) (kv.State, error)
+
+	// Wrap encrypts the plaintext data key. The
+	// associated data is authenticated but not
+	// encrypted and must be passed to Unwrap.
+	Wrap(ctx context.Context, plaintext, associatedData []byte) ([]byte, error)
+
+	// Unwrap decrypts the wrapped data key.
+	Unwrap(ctx context.Context, ciphertext, associatedData []byte) ([]byte, error)
+}
+
+// NewStore returns a new Store that encrypts values with
+// data keys wrapped by the KeyWrapper before writing them
+// to the given store.
+func NewStore(store kv.Store[string, []byte], wrapper KeyWrapper) *Store {
+	return &Store{
+		store:   store,
+		wrapper: wrapper,
+	}
+}
+
+// Store is a key-value store that encrypts values with
+// data keys wrapped by a KeyWrapper.
+type Store struct {
+	store   kv.Store[string, []byte]
+	wrapper KeyWrapper
+}
+
+var _ kv.Store[string, []byte] = (*Store)(nil)
+
+// Status returns the current state of the underlying
+// store. It returns an error if either the underlying
+// store or the KMS is not reachable or not available.
+func (s *Store) Status(ctx context.Context) (kv.State, error) {
+	state, err := s.store.Status(ctx)
+	if err != nil {
+		return kv.State{}, err
+	}
+	kmsState, err := s.wrapper.Status(ctx)
+	if err != nil {
+		return kv.State{}, err
+	}
+	if kmsState.Latency > state.Latency {
+		state.Latency = kmsState.Latency
+	}
+	return state, nil
+}
+
+// Create encrypts the value with a new data key and
+// creates a new entry with the given name if and only
+// if no such entry exists.
+//
+// It returns kes.ErrKeyExists if such an entry exists.
+func (s *Store) Create(ctx context.Context, name string, value []byte) error {
+	ciphertext, err := s.seal(ctx, name, value)
+	if err != nil {
+		return err
+	}
+	return s.store.Create(ctx, name, ciphertext)
+}
+
+// Set encrypts the value with a new data key and
+// writes the entry with the given name.
+func (s *Store) Set(ctx context.Context, name string, value []byte) error {
+	ciphertext, err := s.seal(ctx, name, value)
+	if err != nil {
+		return err
+	}
+	return s.store.Set(ctx, name, ciphertext)
+}
+
+// Update replaces the value of the entry with the given
+// name with newValue if and only if its decrypted value
+// is equal to oldValue.
+//
+// It compares and swaps the ciphertexts at the underlying
+// store. Hence, Update is atomic if the underlying store
+// supports compare-and-swap natively.
+func (s *Store) Update(ctx context.Context, name string, oldValue, newValue []byte) error {
+	ciphertext, err := s.store.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	value, err := s.open(ctx, name, ciphertext)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(value, oldValue) {
+		return kv.ErrConflict
+	}
+
+	newCiphertext, err := s.seal(ctx, name, newValue)
+	if err != nil {
+		return err
+	}
+	return s.store.Update(ctx, name, ciphertext, newCiphertext)
+}
+
+// Get returns the decrypted value of the entry with
+// the given name.
+//
+// It returns kes.ErrKeyNotFound if no such entry exists.
+func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
+	ciphertext, err := s.store.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return s.open(ctx, name, ciphertext)
+}
+
+// GetMany returns the decrypted values of the entries
+// with the given names. It fetches the ciphertexts with
+// a single batch operation from the underlying store.
+func (s *Store) GetMany(ctx context.Context, names []string) ([][]byte, []error) {
+	values, errs := s.store.GetMany(ctx, names)
+	for i, ciphertext := range values {
+		if errs[i] == nil {
+			values[i], errs[i] = s.open(ctx, names[i], ciphertext)
+		}
+	}
+	return values, errs
+}
+
+// Delete deletes the entry with the given name.
+//
+// It returns kes.ErrKeyNotFound if no such entry exists.
+func (s *Store) Delete(ctx context.Context, name string) error {
+	return s.store.Delete(ctx, name)
+}
+
+// DeleteMany deletes the entries with the given names.
+func (s *Store) DeleteMany(ctx context.Context, names []string) []error {
+	return s.store.DeleteMany(ctx, names)
+}
+
+// List returns an Iter over the names of all entries
+// that start with the given prefix.
+func (s *Store) List(ctx context.Context, prefix string) (kv.Iter[string], error) {
+	return s.store.List(ctx, prefix)
+}
+
+// envelope is the on-disk representation of an
+// encrypted value.
+type envelope struct {
+	Key        []byte `json:"key"` // The wrapped data key
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+func (s *Store) seal(ctx context.Context, name string, value []byte) ([]byte, error) {
+	var key [32]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		return nil, fmt.Errorf("envelope: failed to generate data key: %v", err)
+	}
+	aead, err := newAEAD(key[:])
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("envelope: failed to generate nonce: %v", err)
+	}
+
+	wrappedKey, err := s.wrapper.Wrap(ctx, key[:], []byte(name))
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(envelope{
+		Key:        wrappedKey,
+		Nonce:      nonce,
+		Ciphertext: aead.Seal(nil, nonce, value, []byte(name)),
+	})
+}
+
+func (s *Store) open(ctx context.Context, name string, ciphertext []byte) ([]byte, error) {
+	var e envelope
+	if err := json.Unmarshal(ciphertext, &e); err != nil {
+		return nil, fmt.Errorf("envelope: invalid ciphertext for '%s': %v", name, err)
+	}
+	key, err := s.wrapper.Unwrap(ctx, e.Key, []byte(name))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(e.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("envelope: invalid ciphertext for '%s': invalid nonce size", name)
+	}
+	plaintext, err := aead.Open(nil, e.Nonce, e.Ciphertext, []byte(name))
+	if err != nil {
+		return nil, fmt.Errorf("envelope: failed to decrypt '%s': ciphertext is not authentic", name)
+	}
+	return plaintext, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, errors.New("envelope: invalid data key size")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("envelope: %v", err)
+	}
+	return cipher.NewGCM(block)
+}
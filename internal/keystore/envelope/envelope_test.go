@@ -0,0 +1,106 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package envelope
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/minio/kes/internal/keystore/conformance"
+	"github.com/minio/kes/internal/keystore/mem"
+	"github.com/minio/kes/kv"
+)
+
+func TestConformance(t *testing.T) {
+	report, err := conformance.Run(context.Background(), NewStore(&mem.Store{}, newTestWrapper(t)))
+	if err != nil {
+		t.Fatalf("Failed to run conformance tests: %v", err)
+	}
+	for _, result := range report.Results {
+		if !result.Passed() {
+			t.Errorf("Test '%s' failed: %v", result.Name, result.Err)
+		}
+	}
+}
+
+func TestStoreEncrypts(t *testing.T) {
+	ctx := context.Background()
+	inner := &mem.Store{}
+	store := NewStore(inner, newTestWrapper(t))
+
+	value := []byte("my-secret-value")
+	if err := store.Create(ctx, "my-key", value); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	ciphertext, err := inner.Get(ctx, "my-key")
+	if err != nil {
+		t.Fatalf("Failed to fetch ciphertext: %v", err)
+	}
+	if bytes.Contains(ciphertext, value) {
+		t.Fatal("Underlying store contains plaintext value")
+	}
+
+	// Moving a ciphertext to another name must fail since the
+	// name is authenticated.
+	if err = inner.Create(ctx, "other-key", ciphertext); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if _, err = store.Get(ctx, "other-key"); err == nil {
+		t.Fatal("Decrypting a ciphertext under a different name succeeded")
+	}
+
+	plaintext, err := store.Get(ctx, "my-key")
+	if err != nil {
+		t.Fatalf("Failed to fetch key: %v", err)
+	}
+	if !bytes.Equal(plaintext, value) {
+		t.Fatalf("Invalid value: got '%s' - want '%s'", plaintext, value)
+	}
+}
+
+// testWrapper is a KeyWrapper that wraps data keys
+// with an in-memory AES-GCM key.
+type testWrapper struct {
+	aead cipher.AEAD
+}
+
+func newTestWrapper(t *testing.T) *testWrapper {
+	var key [32]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		t.Fatalf("Failed to create AES cipher: %v", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatalf("Failed to create AES-GCM: %v", err)
+	}
+	return &testWrapper{aead: aead}
+}
+
+func (*testWrapper) Status(context.Context) (kv.State, error) { return kv.State{}, nil }
+
+func (w *testWrapper) Wrap(_ context.Context, plaintext, associatedData []byte) ([]byte, error) {
+	nonce := make([]byte, w.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return w.aead.Seal(nonce, nonce, plaintext, associatedData), nil
+}
+
+func (w *testWrapper) Unwrap(_ context.Context, ciphertext, associatedData []byte) ([]byte, error) {
+	if len(ciphertext) < w.aead.NonceSize() {
+		return nil, errors.New("invalid ciphertext")
+	}
+	nonce, ciphertext := ciphertext[:w.aead.NonceSize()], ciphertext[w.aead.NonceSize():]
+	return w.aead.Open(nil, nonce, ciphertext, associatedData)
+}
@@ -0,0 +1,199 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package etcd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"aead.dev/mem"
+	xhttp "github.com/minio/kes/internal/http"
+)
+
+// codeUnauthenticated is the gRPC status code returned by
+// etcd if the auth token is invalid, e.g. expired.
+const codeUnauthenticated = 16
+
+// apiError is an etcd gRPC gateway error response.
+type apiError struct {
+	StatusCode int
+	Code       int    `json:"code"`
+	Message    string `json:"message"`
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%s (%d)", e.Message, e.StatusCode)
+}
+
+// client is an etcd v3 API client that talks to the
+// JSON gRPC gateway of an etcd cluster.
+type client struct {
+	xhttp.Retry
+	Endpoint string
+	Login    Credentials
+
+	lock  sync.RWMutex
+	token string
+}
+
+// Authenticate obtains a new auth token using the client's
+// login credentials. It is a no-op if no username is set,
+// i.e. if etcd authentication is disabled.
+func (c *client) Authenticate(ctx context.Context) error {
+	type Request struct {
+		Name     string `json:"name"`
+		Password string `json:"password"`
+	}
+	type Response struct {
+		Token string `json:"token"`
+	}
+	if c.Login.Username == "" {
+		return nil
+	}
+
+	var response Response
+	err := c.send(ctx, "/v3/auth/authenticate", "", Request{
+		Name:     c.Login.Username,
+		Password: c.Login.Password,
+	}, &response)
+	if err != nil {
+		return err
+	}
+	if response.Token == "" {
+		return errors.New("etcd: server returned no auth token")
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.token = response.Token
+	return nil
+}
+
+// Call sends the JSON-encoded request to the given etcd API
+// path and decodes the response into v. If the auth token
+// has expired, it re-authenticates and sends the request
+// again.
+func (c *client) Call(ctx context.Context, path string, request, v any) error {
+	err := c.send(ctx, path, c.authToken(), request, v)
+	if c.expired(err) {
+		if err = c.Authenticate(ctx); err != nil {
+			return err
+		}
+		err = c.send(ctx, path, c.authToken(), request, v)
+	}
+	return err
+}
+
+// Stream sends the JSON-encoded request to the given etcd
+// streaming API path, like /v3/watch, and returns the
+// response. The response body contains a sequence of JSON
+// objects and must be closed by the caller.
+func (c *client) Stream(ctx context.Context, path string, request any) (*http.Response, error) {
+	resp, err := c.stream(ctx, path, c.authToken(), request)
+	if c.expired(err) {
+		if err = c.Authenticate(ctx); err != nil {
+			return nil, err
+		}
+		resp, err = c.stream(ctx, path, c.authToken(), request)
+	}
+	return resp, err
+}
+
+func (c *client) send(ctx context.Context, path, token string, request, v any) error {
+	req, err := c.newRequest(ctx, path, token, request)
+	if err != nil {
+		return err
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return parseError(resp)
+	}
+	if v == nil {
+		return nil
+	}
+	const MaxSize = 32 * mem.MiB // A response should not exceed 32 MiB
+	return json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(v)
+}
+
+func (c *client) stream(ctx context.Context, path, token string, request any) (*http.Response, error) {
+	req, err := c.newRequest(ctx, path, token, request)
+	if err != nil {
+		return nil, err
+	}
+
+	// Streaming requests are long-lived. Hence, we use the
+	// underlying HTTP client directly instead of retrying.
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, parseError(resp)
+	}
+	return resp, nil
+}
+
+func (c *client) newRequest(ctx context.Context, path, token string, request any) (*http.Request, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Endpoint+path, xhttp.RetryReader(bytes.NewReader(body)))
+	if err != nil {
+		return nil, err
+	}
+	req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	return req, nil
+}
+
+func (c *client) authToken() string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.token
+}
+
+// expired reports whether err indicates that the auth
+// token has expired and the client should re-authenticate.
+func (c *client) expired(err error) bool {
+	var apiErr *apiError
+	return c.Login.Username != "" && errors.As(err, &apiErr) && apiErr.Code == codeUnauthenticated
+}
+
+// parseError returns an *apiError from the etcd error
+// response. If the response body does not contain a
+// gRPC gateway error, the HTTP status is used instead.
+func parseError(resp *http.Response) error {
+	const MaxSize = 1 * mem.MiB
+	body, err := io.ReadAll(mem.LimitReader(resp.Body, MaxSize))
+	if err != nil {
+		return err
+	}
+
+	apiErr := &apiError{StatusCode: resp.StatusCode}
+	if err = json.Unmarshal(body, apiErr); err != nil || apiErr.Message == "" {
+		apiErr.Message = strings.TrimSpace(string(body))
+		if apiErr.Message == "" {
+			apiErr.Message = resp.Status
+		}
+	}
+	return apiErr
+}
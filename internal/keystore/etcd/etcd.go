@@ -0,0 +1,837 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package etcd implements a key store that stores keys
+// as key-value pairs on an etcd cluster.
+//
+// Multiple KES servers can share the same etcd cluster.
+// Each server registers itself as member with a lease
+// that is kept alive as long as the server is running.
+// Servers watch the keys stored on etcd such that keys
+// deleted by one server can be evicted from the caches
+// of all other servers.
+package etcd
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/kes-go"
+	xhttp "github.com/minio/kes/internal/http"
+	"github.com/minio/kes/internal/https"
+	"github.com/minio/kes/kv"
+)
+
+// DefaultPrefix is the default prefix of all
+// etcd keys written by KES.
+const DefaultPrefix = "/kes/"
+
+// MaxTxnOps is the maximum number of operations
+// within a single etcd transaction. It matches the
+// default of the etcd --max-txn-ops flag.
+const MaxTxnOps = 128
+
+// Credentials are etcd user credentials.
+type Credentials struct {
+	Username string // The etcd user name
+	Password string // The etcd user password
+}
+
+// Config is a structure containing configuration
+// options for connecting to an etcd cluster.
+type Config struct {
+	// Endpoints are the etcd client endpoints, e.g.
+	// https://etcd-0:2379. If more than one endpoint
+	// is specified, requests fail over to the next
+	// endpoint if one becomes unreachable.
+	Endpoints []string
+
+	// Prefix is the prefix of all etcd keys written
+	// by KES. Keys are stored under <prefix>keys/ and
+	// members are registered under <prefix>members/.
+	// If empty, DefaultPrefix is used.
+	Prefix string
+
+	// Login are optional credentials used to
+	// authenticate to etcd, if etcd authentication
+	// is enabled.
+	Login Credentials
+
+	// Certificate is an optional path to a TLS client
+	// certificate used for mTLS authentication.
+	Certificate string
+
+	// PrivateKey is an optional path to the private
+	// key of the TLS client certificate.
+	PrivateKey string
+
+	// CAPath is an optional path to the root CA
+	// certificate(s) used to verify the etcd TLS
+	// certificates. If empty, the host's root CA
+	// set is used.
+	CAPath string
+
+	// LeaseTTL is the TTL of the lease of the member
+	// entry of this server. If the server fails to
+	// keep the lease alive, e.g. since it has crashed,
+	// its member entry is removed once the TTL expires.
+	// If 0, defaults to 30 seconds.
+	LeaseTTL time.Duration
+}
+
+// Store is an etcd key store.
+type Store struct {
+	config Config
+	client *client
+
+	keyPrefix    string // <prefix>keys/
+	memberPrefix string // <prefix>members/
+
+	lock    sync.Mutex
+	leaseID int64
+	closed  chan struct{}
+	wg      sync.WaitGroup
+}
+
+var (
+	_ kv.Store[string, []byte] = (*Store)(nil)
+	_ kv.Watcher[string]       = (*Store)(nil)
+)
+
+// Connect connects to the etcd cluster using the given
+// config, registers the KES server as member and returns
+// a new Store.
+func Connect(ctx context.Context, config *Config) (*Store, error) {
+	if len(config.Endpoints) == 0 {
+		return nil, errors.New("etcd: no endpoints specified")
+	}
+	if (config.Certificate == "") != (config.PrivateKey == "") {
+		return nil, errors.New("etcd: TLS client certificate and private key must be specified together")
+	}
+	if config.Login.Username == "" && config.Login.Password != "" {
+		return nil, errors.New("etcd: no username specified")
+	}
+	if config.LeaseTTL < 0 {
+		return nil, errors.New("etcd: lease TTL is negative")
+	}
+
+	c := *config
+	if c.Prefix == "" {
+		c.Prefix = DefaultPrefix
+	}
+	if !strings.HasSuffix(c.Prefix, "/") {
+		c.Prefix += "/"
+	}
+	if c.LeaseTTL == 0 {
+		c.LeaseTTL = 30 * time.Second
+	}
+	if c.LeaseTTL < time.Second {
+		c.LeaseTTL = time.Second
+	}
+	endpoints := make([]string, 0, len(c.Endpoints))
+	for _, endpoint := range c.Endpoints {
+		endpoints = append(endpoints, strings.TrimSuffix(endpoint, "/"))
+	}
+	c.Endpoints = endpoints
+
+	tlsConfig := &tls.Config{}
+	if c.Certificate != "" {
+		cert, err := https.CertificateFromFile(c.Certificate, c.PrivateKey, "")
+		if err != nil {
+			return nil, fmt.Errorf("etcd: failed to load TLS client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	if c.CAPath != "" {
+		pool, err := https.CertPoolFromFile(c.CAPath)
+		if err != nil {
+			return nil, fmt.Errorf("etcd: failed to load CA certificate: %v", err)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	var transport http.RoundTripper = &http.Transport{
+		TLSClientConfig: tlsConfig,
+		Proxy:           http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   10 * time.Second,
+			KeepAlive: 10 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       30 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+	if len(c.Endpoints) > 1 {
+		transport = &https.FailoverTransport{
+			Transport:     transport,
+			Endpoints:     c.Endpoints,
+			ProbePath:     "/health",
+			ProbeInterval: time.Minute,
+		}
+	}
+	client := &client{
+		Retry: xhttp.Retry{
+			Client: http.Client{
+				Transport: transport,
+			},
+		},
+		Endpoint: c.Endpoints[0],
+		Login:    c.Login,
+	}
+	if err := client.Authenticate(ctx); err != nil {
+		return nil, fmt.Errorf("etcd: failed to authenticate: %v", err)
+	}
+
+	s := &Store{
+		config:       c,
+		client:       client,
+		keyPrefix:    c.Prefix + "keys/",
+		memberPrefix: c.Prefix + "members/",
+		closed:       make(chan struct{}),
+	}
+	if err := s.register(ctx); err != nil {
+		return nil, err
+	}
+
+	s.wg.Add(1)
+	go s.keepAlive()
+	return s, nil
+}
+
+// Status returns the current state of the etcd cluster.
+// In particular, whether it is reachable and the network
+// latency.
+func (s *Store) Status(ctx context.Context) (kv.State, error) {
+	start := time.Now()
+	err := s.client.Call(ctx, "/v3/maintenance/status", struct{}{}, nil)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return kv.State{}, &kv.Unreachable{Err: err}
+	}
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		return kv.State{}, &kv.Unavailable{Err: err}
+	}
+	if err != nil {
+		return kv.State{}, &kv.Unreachable{Err: err}
+	}
+	return kv.State{
+		Latency: time.Since(start),
+	}, nil
+}
+
+// Create stores the given key-value pair on etcd if and
+// only if no entry with the given name exists. The check
+// and the write are executed atomically as transaction.
+// If such an entry exists it returns kes.ErrKeyExists.
+func (s *Store) Create(ctx context.Context, name string, value []byte) error {
+	type Compare struct {
+		Result         string `json:"result"`
+		Target         string `json:"target"`
+		Key            []byte `json:"key"`
+		CreateRevision int64  `json:"create_revision"`
+	}
+	type Put struct {
+		Key   []byte `json:"key"`
+		Value []byte `json:"value"`
+	}
+	type Op struct {
+		Put *Put `json:"request_put,omitempty"`
+	}
+	type Request struct {
+		Compare []Compare `json:"compare"`
+		Success []Op      `json:"success"`
+	}
+	type Response struct {
+		Succeeded bool `json:"succeeded"`
+	}
+
+	key := []byte(s.keyPrefix + name)
+	request := Request{
+		// A create revision of 0 means that the key does not exist.
+		Compare: []Compare{{Result: "EQUAL", Target: "CREATE", Key: key, CreateRevision: 0}},
+		Success: []Op{{Put: &Put{Key: key, Value: value}}},
+	}
+
+	var response Response
+	err := s.client.Call(ctx, "/v3/kv/txn", request, &response)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("etcd: failed to create key '%s': %v", name, err)
+	}
+	if !response.Succeeded {
+		return kes.ErrKeyExists
+	}
+	return nil
+}
+
+// Set stores the given key-value pair on etcd if and
+// only if no entry with the given name exists. If such
+// an entry exists it returns kes.ErrKeyExists.
+func (s *Store) Set(ctx context.Context, name string, value []byte) error {
+	return s.Create(ctx, name, value)
+}
+
+// Update replaces the value of the given key with newValue
+// if and only if its current value is equal to oldValue. The
+// comparison and the replacement are executed atomically as
+// etcd transaction. If no entry for the key exists it returns
+// kes.ErrKeyNotFound.
+func (s *Store) Update(ctx context.Context, name string, oldValue, newValue []byte) error {
+	type Compare struct {
+		Result string `json:"result"`
+		Target string `json:"target"`
+		Key    []byte `json:"key"`
+		Value  []byte `json:"value"`
+	}
+	type Put struct {
+		Key   []byte `json:"key"`
+		Value []byte `json:"value"`
+	}
+	type Op struct {
+		Put *Put `json:"request_put,omitempty"`
+	}
+	type Request struct {
+		Compare []Compare `json:"compare"`
+		Success []Op      `json:"success"`
+	}
+	type Response struct {
+		Succeeded bool `json:"succeeded"`
+	}
+
+	key := []byte(s.keyPrefix + name)
+	request := Request{
+		Compare: []Compare{{Result: "EQUAL", Target: "VALUE", Key: key, Value: oldValue}},
+		Success: []Op{{Put: &Put{Key: key, Value: newValue}}},
+	}
+
+	var response Response
+	err := s.client.Call(ctx, "/v3/kv/txn", request, &response)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("etcd: failed to update key '%s': %v", name, err)
+	}
+	if !response.Succeeded {
+		// The comparison also fails if the key does not exist.
+		if _, err = s.Get(ctx, name); err != nil {
+			return err
+		}
+		return kv.ErrConflict
+	}
+	return nil
+}
+
+// Get returns the value associated with the given key.
+// If no entry for the key exists it returns kes.ErrKeyNotFound.
+func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
+	type Request struct {
+		Key []byte `json:"key"`
+	}
+	type Response struct {
+		KVs []struct {
+			Value []byte `json:"value"`
+		} `json:"kvs"`
+	}
+
+	var response Response
+	err := s.client.Call(ctx, "/v3/kv/range", Request{Key: []byte(s.keyPrefix + name)}, &response)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("etcd: failed to access key '%s': %v", name, err)
+	}
+	if len(response.KVs) == 0 {
+		return nil, kes.ErrKeyNotFound
+	}
+	return response.KVs[0].Value, nil
+}
+
+// GetMany returns the values associated with the given keys.
+// It fetches up to MaxTxnOps values within a single etcd
+// transaction.
+func (s *Store) GetMany(ctx context.Context, names []string) ([][]byte, []error) {
+	type Range struct {
+		Key []byte `json:"key"`
+	}
+	type Op struct {
+		Range *Range `json:"request_range,omitempty"`
+	}
+	type Request struct {
+		Success []Op `json:"success"`
+	}
+	type Response struct {
+		Responses []struct {
+			Range struct {
+				KVs []struct {
+					Value []byte `json:"value"`
+				} `json:"kvs"`
+			} `json:"response_range"`
+		} `json:"responses"`
+	}
+
+	values := make([][]byte, len(names))
+	errs := make([]error, len(names))
+	for i := 0; i < len(names); i += MaxTxnOps {
+		chunk := names[i:]
+		if len(chunk) > MaxTxnOps {
+			chunk = chunk[:MaxTxnOps]
+		}
+
+		request := Request{Success: make([]Op, 0, len(chunk))}
+		for _, name := range chunk {
+			request.Success = append(request.Success, Op{Range: &Range{Key: []byte(s.keyPrefix + name)}})
+		}
+		var response Response
+		err := s.client.Call(ctx, "/v3/kv/txn", request, &response)
+		if err == nil && len(response.Responses) != len(chunk) {
+			err = fmt.Errorf("invalid response: got %d responses - want %d", len(response.Responses), len(chunk))
+		}
+		for j, name := range chunk {
+			switch {
+			case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+				errs[i+j] = err
+			case err != nil:
+				errs[i+j] = fmt.Errorf("etcd: failed to access key '%s': %v", name, err)
+			case len(response.Responses[j].Range.KVs) == 0:
+				errs[i+j] = kes.ErrKeyNotFound
+			default:
+				values[i+j] = response.Responses[j].Range.KVs[0].Value
+			}
+		}
+	}
+	return values, errs
+}
+
+// Delete removes a the value associated with the given key
+// from etcd, if it exists.
+func (s *Store) Delete(ctx context.Context, name string) error {
+	type Request struct {
+		Key []byte `json:"key"`
+	}
+
+	err := s.client.Call(ctx, "/v3/kv/deleterange", Request{Key: []byte(s.keyPrefix + name)}, nil)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("etcd: failed to delete key '%s': %v", name, err)
+	}
+	return nil
+}
+
+// DeleteMany removes the values associated with the given
+// keys from etcd, if they exist. It deletes up to MaxTxnOps
+// keys within a single etcd transaction.
+func (s *Store) DeleteMany(ctx context.Context, names []string) []error {
+	type DeleteRange struct {
+		Key []byte `json:"key"`
+	}
+	type Op struct {
+		DeleteRange *DeleteRange `json:"request_delete_range,omitempty"`
+	}
+	type Request struct {
+		Success []Op `json:"success"`
+	}
+
+	errs := make([]error, len(names))
+	for i := 0; i < len(names); i += MaxTxnOps {
+		chunk := names[i:]
+		if len(chunk) > MaxTxnOps {
+			chunk = chunk[:MaxTxnOps]
+		}
+
+		request := Request{Success: make([]Op, 0, len(chunk))}
+		for _, name := range chunk {
+			request.Success = append(request.Success, Op{DeleteRange: &DeleteRange{Key: []byte(s.keyPrefix + name)}})
+		}
+		err := s.client.Call(ctx, "/v3/kv/txn", request, nil)
+		if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("etcd: failed to delete keys: %v", err)
+		}
+		for j := range chunk {
+			errs[i+j] = err
+		}
+	}
+	return errs
+}
+
+// List returns a new Iterator over the names of
+// all stored keys that start with the given prefix.
+// It only fetches the keys within the prefix range
+// from etcd.
+func (s *Store) List(ctx context.Context, prefix string) (kv.Iter[string], error) {
+	type Request struct {
+		Key      []byte `json:"key"`
+		RangeEnd []byte `json:"range_end"`
+		Limit    int64  `json:"limit"`
+		KeysOnly bool   `json:"keys_only"`
+	}
+	type Response struct {
+		KVs []struct {
+			Key []byte `json:"key"`
+		} `json:"kvs"`
+		More bool `json:"more"`
+	}
+
+	var cancel context.CancelCauseFunc
+	ctx, cancel = context.WithCancelCause(ctx)
+	values := make(chan string, 10)
+
+	go func() {
+		defer close(values)
+
+		const Limit = 500
+		request := Request{
+			Key:      []byte(s.keyPrefix + prefix),
+			RangeEnd: prefixEnd(s.keyPrefix + prefix),
+			Limit:    Limit,
+			KeysOnly: true,
+		}
+		for {
+			var response Response
+			err := s.client.Call(ctx, "/v3/kv/range", request, &response)
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				cancel(err)
+				return
+			}
+			if err != nil {
+				cancel(fmt.Errorf("etcd: failed to list keys: %v", err))
+				return
+			}
+
+			for _, kv := range response.KVs {
+				select {
+				case values <- strings.TrimPrefix(string(kv.Key), s.keyPrefix):
+				case <-ctx.Done():
+					return
+				}
+			}
+			if !response.More || len(response.KVs) == 0 {
+				return
+			}
+			// Continue with the key right after the last one.
+			request.Key = append(response.KVs[len(response.KVs)-1].Key, 0)
+		}
+	}()
+	return &iter{
+		ch:     values,
+		ctx:    ctx,
+		cancel: cancel,
+	}, nil
+}
+
+// Watch watches all keys stored on etcd and calls f with
+// the name of every key that has been changed or deleted,
+// e.g. by another KES server sharing the same etcd cluster.
+// It calls f with an empty name if any key may have changed
+// while the watch was interrupted.
+//
+// Watch blocks until the ctx is done or the Store is closed.
+// It re-establishes the watch if it gets interrupted.
+func (s *Store) Watch(ctx context.Context, f func(name string)) error {
+	const (
+		MinDelay = 1 * time.Second
+		MaxDelay = 30 * time.Second
+	)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-s.closed:
+			cancel()
+		}
+	}()
+
+	var revision int64
+	delay := MinDelay
+	for {
+		watched, err := s.watch(ctx, &revision, f)
+		if err == nil || ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if watched {
+			delay = MinDelay
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		if delay *= 2; delay > MaxDelay {
+			delay = MaxDelay
+		}
+	}
+}
+
+// Close revokes the lease of the server's member
+// entry and stops keeping it alive.
+func (s *Store) Close() error {
+	select {
+	case <-s.closed:
+		return nil
+	default:
+		close(s.closed)
+	}
+	s.wg.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.revoke(ctx, s.leaseID)
+}
+
+// watch creates a watch for all keys, starting after the
+// given revision, and calls f for every event. It reports
+// whether the watch has been created successfully.
+func (s *Store) watch(ctx context.Context, revision *int64, f func(string)) (bool, error) {
+	type CreateRequest struct {
+		Key           []byte `json:"key"`
+		RangeEnd      []byte `json:"range_end"`
+		StartRevision int64  `json:"start_revision,omitempty"`
+	}
+	type Request struct {
+		Create CreateRequest `json:"create_request"`
+	}
+	type Response struct {
+		Result *struct {
+			Header struct {
+				Revision json.Number `json:"revision"`
+			} `json:"header"`
+			Created         bool        `json:"created"`
+			Canceled        bool        `json:"canceled"`
+			CancelReason    string      `json:"cancel_reason"`
+			CompactRevision json.Number `json:"compact_revision"`
+			Events          []struct {
+				KV struct {
+					Key         []byte      `json:"key"`
+					ModRevision json.Number `json:"mod_revision"`
+				} `json:"kv"`
+			} `json:"events"`
+		} `json:"result"`
+		Error json.RawMessage `json:"error"`
+	}
+
+	request := Request{
+		Create: CreateRequest{
+			Key:      []byte(s.keyPrefix),
+			RangeEnd: prefixEnd(s.keyPrefix),
+		},
+	}
+	if *revision > 0 {
+		request.Create.StartRevision = *revision + 1
+	}
+	resp, err := s.client.Stream(ctx, "/v3/watch", request)
+	if err != nil {
+		return false, fmt.Errorf("etcd: failed to watch keys: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var created bool
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var response Response
+		if err = decoder.Decode(&response); err != nil {
+			return created, fmt.Errorf("etcd: failed to watch keys: %v", err)
+		}
+		if len(response.Error) > 0 {
+			return created, fmt.Errorf("etcd: failed to watch keys: %s", response.Error)
+		}
+		if response.Result == nil {
+			continue
+		}
+
+		result := response.Result
+		if result.Canceled {
+			// If the revision has been compacted, we may have missed
+			// some events. Hence, we continue at the current revision
+			// and report that any key may have changed.
+			if compacted, _ := result.CompactRevision.Int64(); compacted > 0 {
+				*revision = 0
+			}
+			return created, fmt.Errorf("etcd: watch canceled: %s", result.CancelReason)
+		}
+		if result.Created {
+			created = true
+			if *revision == 0 {
+				if *revision, err = result.Header.Revision.Int64(); err != nil {
+					return created, fmt.Errorf("etcd: invalid revision: %v", err)
+				}
+				f("")
+			}
+		}
+		for _, event := range result.Events {
+			if rev, err := event.KV.ModRevision.Int64(); err == nil && rev > *revision {
+				*revision = rev
+			}
+			f(strings.TrimPrefix(string(event.KV.Key), s.keyPrefix))
+		}
+	}
+}
+
+// register grants a new lease and registers the server
+// as member with this lease.
+func (s *Store) register(ctx context.Context) error {
+	type GrantRequest struct {
+		TTL int64 `json:"TTL"`
+	}
+	type GrantResponse struct {
+		ID json.Number `json:"ID"`
+	}
+	type PutRequest struct {
+		Key   []byte `json:"key"`
+		Value []byte `json:"value"`
+		Lease int64  `json:"lease"`
+	}
+
+	var grant GrantResponse
+	if err := s.client.Call(ctx, "/v3/lease/grant", GrantRequest{TTL: int64(s.config.LeaseTTL.Seconds())}, &grant); err != nil {
+		return fmt.Errorf("etcd: failed to grant lease: %v", err)
+	}
+	leaseID, err := grant.ID.Int64()
+	if err != nil {
+		return fmt.Errorf("etcd: invalid lease ID: %v", err)
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		host = "localhost"
+	}
+	member := host + ":" + strconv.Itoa(os.Getpid())
+	started, err := time.Now().UTC().MarshalText()
+	if err != nil {
+		return err
+	}
+	err = s.client.Call(ctx, "/v3/kv/put", PutRequest{
+		Key:   []byte(s.memberPrefix + member),
+		Value: started,
+		Lease: leaseID,
+	}, nil)
+	if err != nil {
+		s.revoke(ctx, leaseID)
+		return fmt.Errorf("etcd: failed to register member '%s': %v", member, err)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.leaseID = leaseID
+	return nil
+}
+
+// keepAlive keeps the lease of the server's member entry
+// alive until the Store is closed. If the lease expires,
+// e.g. due to a network partition, it registers the server
+// again with a new lease.
+func (s *Store) keepAlive() {
+	type Request struct {
+		ID int64 `json:"ID"`
+	}
+	type Response struct {
+		Result struct {
+			TTL json.Number `json:"TTL"`
+		} `json:"result"`
+	}
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.config.LeaseTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.closed:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), s.config.LeaseTTL/3)
+		s.lock.Lock()
+		leaseID := s.leaseID
+		s.lock.Unlock()
+
+		resp, err := s.client.Stream(ctx, "/v3/lease/keepalive", Request{ID: leaseID})
+		if err != nil {
+			cancel()
+			continue
+		}
+		var response Response
+		err = json.NewDecoder(resp.Body).Decode(&response)
+		resp.Body.Close()
+		if err != nil {
+			cancel()
+			continue
+		}
+		if ttl, _ := response.Result.TTL.Int64(); ttl <= 0 {
+			s.register(ctx) // The lease has expired - register again
+		}
+		cancel()
+	}
+}
+
+// revoke revokes the given lease.
+func (s *Store) revoke(ctx context.Context, leaseID int64) error {
+	type Request struct {
+		ID int64 `json:"ID"`
+	}
+	if leaseID == 0 {
+		return nil
+	}
+	if err := s.client.Call(ctx, "/v3/lease/revoke", Request{ID: leaseID}, nil); err != nil {
+		return fmt.Errorf("etcd: failed to revoke lease: %v", err)
+	}
+	return nil
+}
+
+// prefixEnd returns the etcd range end of all
+// keys with the given prefix.
+func prefixEnd(prefix string) []byte {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return []byte{0} // All keys
+}
+
+type iter struct {
+	ch     <-chan string
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+}
+
+func (i *iter) Next() (string, bool) {
+	select {
+	case v, ok := <-i.ch:
+		return v, ok
+	case <-i.ctx.Done():
+		return "", false
+	}
+}
+
+func (i *iter) Close() error {
+	i.cancel(context.Canceled)
+	return context.Cause(i.ctx)
+}
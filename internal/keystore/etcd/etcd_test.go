@@ -0,0 +1,77 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package etcd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+var prefixEndTests = []struct {
+	Prefix string
+	End    []byte
+}{
+	{Prefix: "/kes/keys/", End: []byte("/kes/keys0")},   // 0
+	{Prefix: "a", End: []byte("b")},                     // 1
+	{Prefix: "a\xff", End: []byte("b")},                 // 2
+	{Prefix: "\xff\xff", End: []byte{0}},                // 3
+	{Prefix: "/kes/my-key", End: []byte("/kes/my-kez")}, // 4
+}
+
+func TestPrefixEnd(t *testing.T) {
+	for i, test := range prefixEndTests {
+		if end := prefixEnd(test.Prefix); !bytes.Equal(end, test.End) {
+			t.Fatalf("Test %d: got '%q' - want '%q'", i, end, test.End)
+		}
+	}
+}
+
+var parseErrorTests = []struct {
+	StatusCode int
+	Body       string
+	Code       int
+	Message    string
+}{
+	{ // 0
+		StatusCode: http.StatusUnauthorized,
+		Body:       `{"error":"etcdserver: invalid auth token","code":16,"message":"etcdserver: invalid auth token"}`,
+		Code:       codeUnauthenticated,
+		Message:    "etcdserver: invalid auth token",
+	},
+	{ // 1
+		StatusCode: http.StatusNotFound,
+		Body:       "404 page not found\n",
+		Message:    "404 page not found",
+	},
+	{ // 2
+		StatusCode: http.StatusServiceUnavailable,
+		Message:    "503 Service Unavailable",
+	},
+}
+
+func TestParseError(t *testing.T) {
+	for i, test := range parseErrorTests {
+		resp := &http.Response{
+			Status:     fmt.Sprintf("%d %s", test.StatusCode, http.StatusText(test.StatusCode)),
+			StatusCode: test.StatusCode,
+			Body:       io.NopCloser(strings.NewReader(test.Body)),
+		}
+
+		err, ok := parseError(resp).(*apiError)
+		if !ok {
+			t.Fatalf("Test %d: invalid error type: got '%T' - want '%T'", i, err, &apiError{})
+		}
+		if err.Code != test.Code {
+			t.Fatalf("Test %d: invalid error code: got '%d' - want '%d'", i, err.Code, test.Code)
+		}
+		if err.Message != test.Message {
+			t.Fatalf("Test %d: invalid error message: got '%s' - want '%s'", i, err.Message, test.Message)
+		}
+	}
+}
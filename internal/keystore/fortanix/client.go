@@ -0,0 +1,180 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package fortanix
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	xhttp "github.com/minio/kes/internal/http"
+)
+
+// client is an HTTP client that sends requests to one
+// of multiple Fortanix SDKMS endpoints.
+//
+// It sends requests to the endpoint that responded most
+// recently. If this endpoint is not reachable or not
+// available, the client fails over to the next endpoint.
+type client struct {
+	xhttp.Retry
+
+	endpoints []string
+	current   uint32 // Atomic index of the endpoint currently used
+	apiKey    *apiKeySource
+}
+
+// Endpoint returns the endpoint currently used.
+func (c *client) Endpoint() string {
+	return c.endpoints[atomic.LoadUint32(&c.current)%uint32(len(c.endpoints))]
+}
+
+// Send sends a request with the given method and body to the
+// URI of the current endpoint. It authenticates the request
+// with the application's API key.
+//
+// If the endpoint is not reachable or responds with a
+// 502, 503 or 504 status code, Send fails over to the
+// next endpoint.
+func (c *client) Send(ctx context.Context, method, uri string, body []byte) (*http.Response, error) {
+	apiKey, err := c.apiKey.Get()
+	if err != nil {
+		return nil, err
+	}
+	return c.SendWithAuth(ctx, method, uri, body, apiKey.String())
+}
+
+// SendWithAuth sends a request like Send but uses the given
+// Authorization header value instead of the API key.
+func (c *client) SendWithAuth(ctx context.Context, method, uri string, body []byte, auth string) (*http.Response, error) {
+	var (
+		start = atomic.LoadUint32(&c.current)
+		n     = uint32(len(c.endpoints))
+		err   error
+	)
+	for i := uint32(0); i < n; i++ {
+		next := (start + i) % n
+
+		var resp *http.Response
+		resp, err = c.send(ctx, method, c.endpoints[next], uri, body, auth)
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+		if err != nil {
+			continue
+		}
+		if i < n-1 && isUnavailable(resp.StatusCode) {
+			resp.Body.Close()
+			err = fmt.Errorf("'%s' is not available: %s", c.endpoints[next], resp.Status)
+			continue
+		}
+		if next != start {
+			atomic.CompareAndSwapUint32(&c.current, start, next)
+		}
+		return resp, nil
+	}
+	return nil, err
+}
+
+func (c *client) send(ctx context.Context, method, endpoint, uri string, body []byte, auth string) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = xhttp.RetryReader(bytes.NewReader(body))
+	}
+	url := strings.TrimSuffix(strings.TrimSpace(endpoint), "/") + uri
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", auth)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.Do(req)
+}
+
+// isUnavailable reports whether the HTTP status code
+// indicates that the Fortanix SDKMS endpoint is not
+// available such that another endpoint should be tried.
+func isUnavailable(code int) bool {
+	return code == http.StatusBadGateway || code == http.StatusServiceUnavailable || code == http.StatusGatewayTimeout
+}
+
+// apiKeySource provides the application's API key.
+//
+// If the API key is loaded from a file, the file is
+// read again once its modification time changes.
+// Hence, the API key can be rotated without restarting
+// the server.
+type apiKeySource struct {
+	filename string
+
+	lock    sync.Mutex
+	key     APIKey
+	modTime time.Time
+}
+
+// newAPIKeySource returns a new apiKeySource that either
+// returns the static key or reads the key from filename,
+// if not empty.
+func newAPIKeySource(key APIKey, filename string) (*apiKeySource, error) {
+	source := &apiKeySource{
+		filename: filename,
+		key:      key,
+	}
+	if filename == "" {
+		return source, nil
+	}
+	if _, err := source.Get(); err != nil {
+		return nil, err
+	}
+	return source, nil
+}
+
+// Get returns the current API key.
+func (s *apiKeySource) Get() (APIKey, error) {
+	if s.filename == "" {
+		return s.key, nil
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	stat, err := os.Stat(s.filename)
+	if err != nil {
+		if s.key != "" { // Keep using the previous key while the file is being replaced
+			return s.key, nil
+		}
+		return "", fmt.Errorf("fortanix: failed to read API key: %v", err)
+	}
+	if stat.ModTime().Equal(s.modTime) && s.key != "" {
+		return s.key, nil
+	}
+
+	b, err := os.ReadFile(s.filename)
+	if err != nil {
+		if s.key != "" {
+			return s.key, nil
+		}
+		return "", fmt.Errorf("fortanix: failed to read API key: %v", err)
+	}
+	key := APIKey(strings.TrimSpace(string(b)))
+	if key == "" {
+		if s.key != "" {
+			return s.key, nil
+		}
+		return "", fmt.Errorf("fortanix: failed to read API key: '%s' is empty", s.filename)
+	}
+	s.key, s.modTime = key, stat.ModTime()
+	return s.key, nil
+}
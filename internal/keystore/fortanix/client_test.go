@@ -0,0 +1,86 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package fortanix
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestClientFailover(t *testing.T) {
+	unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unavailable.Close()
+
+	available := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != APIKey("my-key").String() {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer available.Close()
+
+	apiKey, err := newAPIKeySource("my-key", "")
+	if err != nil {
+		t.Fatalf("Failed to create API key source: %v", err)
+	}
+	client := &client{
+		endpoints: []string{unavailable.URL, available.URL},
+		apiKey:    apiKey,
+	}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Send(context.Background(), http.MethodGet, "/sys/v1/health", nil)
+		if err != nil {
+			t.Fatalf("Test %d: failed to send request: %v", i, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNoContent {
+			t.Fatalf("Test %d: invalid status code: got '%d' - want '%d'", i, resp.StatusCode, http.StatusNoContent)
+		}
+		if endpoint := client.Endpoint(); endpoint != available.URL {
+			t.Fatalf("Test %d: invalid endpoint: got '%s' - want '%s'", i, endpoint, available.URL)
+		}
+	}
+}
+
+func TestAPIKeySourceRotation(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "api.key")
+	if err := os.WriteFile(filename, []byte("key-1\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write API key: %v", err)
+	}
+
+	source, err := newAPIKeySource("", filename)
+	if err != nil {
+		t.Fatalf("Failed to create API key source: %v", err)
+	}
+	if key, err := source.Get(); err != nil || key != "key-1" {
+		t.Fatalf("Invalid API key: got '%s' - want '%s' (err: %v)", key, "key-1", err)
+	}
+
+	if err = os.WriteFile(filename, []byte("key-2"), 0o600); err != nil {
+		t.Fatalf("Failed to write API key: %v", err)
+	}
+	modTime := time.Now().Add(time.Minute)
+	if err = os.Chtimes(filename, modTime, modTime); err != nil {
+		t.Fatalf("Failed to update modification time: %v", err)
+	}
+	if key, err := source.Get(); err != nil || key != "key-2" {
+		t.Fatalf("Invalid API key: got '%s' - want '%s' (err: %v)", key, "key-2", err)
+	}
+
+	if err = os.Remove(filename); err != nil {
+		t.Fatalf("Failed to remove API key: %v", err)
+	}
+	if key, err := source.Get(); err != nil || key != "key-2" {
+		t.Fatalf("Invalid API key: got '%s' - want '%s' (err: %v)", key, "key-2", err)
+	}
+}
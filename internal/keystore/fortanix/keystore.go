@@ -5,7 +5,6 @@
 package fortanix
 
 import (
-	"bytes"
 	"context"
 	"crypto/tls"
 	"crypto/x509"
@@ -27,7 +26,9 @@ import (
 	"aead.dev/mem"
 	"github.com/minio/kes-go"
 	xhttp "github.com/minio/kes/internal/http"
+	"github.com/minio/kes/internal/https"
 	"github.com/minio/kes/internal/key"
+	"github.com/minio/kes/internal/keystore/batch"
 	"github.com/minio/kes/kv"
 )
 
@@ -46,6 +47,11 @@ type Config struct {
 	// Endpoint is the Fortanix SDKMS instance endpoint.
 	Endpoint string
 
+	// FailoverEndpoints is an optional list of Fortanix SDKMS
+	// endpoints, e.g. of other regions, that are used, in
+	// order, if Endpoint is not reachable or not available.
+	FailoverEndpoints []string
+
 	// GroupID is ID of the Fortanix SDKMS group newly created
 	// keys will belong to.
 	//
@@ -58,18 +64,36 @@ type Config struct {
 	// operations. It is sent on each request as part of the request headers.
 	APIKey APIKey
 
+	// APIKeyFile is an optional path to a file containing the
+	// application's API key. The file is read again once it
+	// changes such that the API key can be rotated without a
+	// restart. If not empty, APIKey is ignored.
+	APIKeyFile string
+
 	// CAPath is an optional path to a CA certificate or directory
 	// containing CA certificates.
 	//
 	// If not empty, the KeyStore will use the specified CAs to
 	// verify the Fortanix SDKMS server certificate.
 	CAPath string
+
+	// PrivateKey and Certificate are optional paths to a
+	// TLS private key and certificate for mTLS authentication
+	// to the Fortanix SDKMS server. The certificate is reloaded
+	// once either file changes.
+	PrivateKey  string
+	Certificate string
+
+	// ServerName is an optional TLS server name (SNI) used
+	// to verify the Fortanix SDKMS server certificate. If
+	// empty, the endpoint host name is used.
+	ServerName string
 }
 
 // Store is a Fortanix SDKMS secret store.
 type Store struct {
 	config Config
-	client xhttp.Retry
+	client *client
 }
 
 var _ kv.Store[string, []byte] = (*Store)(nil) // compiler check
@@ -80,45 +104,65 @@ func Connect(ctx context.Context, config *Config) (*Store, error) {
 	if config.Endpoint == "" {
 		return nil, errors.New("fortanix: endpoint is empty")
 	}
+	for _, endpoint := range config.FailoverEndpoints {
+		if endpoint == "" {
+			return nil, errors.New("fortanix: failover endpoint is empty")
+		}
+	}
+	if config.APIKey == "" && config.APIKeyFile == "" {
+		return nil, errors.New("fortanix: no API key specified")
+	}
+
+	if (config.PrivateKey == "") != (config.Certificate == "") {
+		return nil, errors.New("fortanix: TLS private key and certificate must be specified both")
+	}
 
-	var tlsConfig *tls.Config
+	tlsConfig := &tls.Config{
+		ServerName: config.ServerName,
+	}
 	if config.CAPath != "" {
 		rootCAs, err := loadCustomCAs(config.CAPath)
 		if err != nil {
 			return nil, err
 		}
-		tlsConfig = &tls.Config{
-			RootCAs: rootCAs,
+		tlsConfig.RootCAs = rootCAs
+	}
+	if config.Certificate != "" {
+		getClientCertificate, err := https.ClientCertificate(config.Certificate, config.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("fortanix: failed to load TLS client certificate: %v", err)
 		}
+		tlsConfig.GetClientCertificate = getClientCertificate
 	}
 
-	client := xhttp.Retry{
-		Client: http.Client{
-			Transport: &http.Transport{
-				Proxy: http.ProxyFromEnvironment,
-				DialContext: (&net.Dialer{
-					Timeout:   30 * time.Second,
-					KeepAlive: 30 * time.Second,
-				}).DialContext,
-				ForceAttemptHTTP2:     true,
-				MaxIdleConns:          100,
-				IdleConnTimeout:       90 * time.Second,
-				TLSHandshakeTimeout:   10 * time.Second,
-				ExpectContinueTimeout: 1 * time.Second,
-				TLSClientConfig:       tlsConfig,
+	apiKey, err := newAPIKeySource(config.APIKey, config.APIKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	client := &client{
+		Retry: xhttp.Retry{
+			Client: http.Client{
+				Transport: &http.Transport{
+					Proxy: http.ProxyFromEnvironment,
+					DialContext: (&net.Dialer{
+						Timeout:   30 * time.Second,
+						KeepAlive: 30 * time.Second,
+					}).DialContext,
+					ForceAttemptHTTP2:     true,
+					MaxIdleConns:          100,
+					IdleConnTimeout:       90 * time.Second,
+					TLSHandshakeTimeout:   10 * time.Second,
+					ExpectContinueTimeout: 1 * time.Second,
+					TLSClientConfig:       tlsConfig,
+				},
 			},
 		},
+		endpoints: append([]string{config.Endpoint}, config.FailoverEndpoints...),
+		apiKey:    apiKey,
 	}
 
 	// Check if the Fortanix SDKMS endpoint is reachable
-	url := endpoint(config.Endpoint, "/sys/v1/health")
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Authorization", config.APIKey.String())
-
-	resp, err := client.Do(req)
+	resp, err := client.Send(ctx, http.MethodGet, "/sys/v1/health", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -126,18 +170,11 @@ func Connect(ctx context.Context, config *Config) (*Store, error) {
 		if err := parseErrorResponse(resp); err != nil {
 			return nil, err
 		}
-		return nil, fmt.Errorf("fortanix: failed to connect to '%s': %s (%d)", config.Endpoint, resp.Status, resp.StatusCode)
+		return nil, fmt.Errorf("fortanix: failed to connect to '%s': %s (%d)", client.Endpoint(), resp.Status, resp.StatusCode)
 	}
 
 	// Check if the authentication credentials are valid
-	url = endpoint(config.Endpoint, "/sys/v1/session/auth")
-	req, err = http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Authorization", config.APIKey.String())
-
-	resp, err = client.Do(req)
+	resp, err = client.Send(ctx, http.MethodPost, "/sys/v1/session/auth", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -145,28 +182,21 @@ func Connect(ctx context.Context, config *Config) (*Store, error) {
 		if err := parseErrorResponse(resp); err != nil {
 			return nil, err
 		}
-		return nil, fmt.Errorf("fortanix: failed to authenticate to '%s': %s (%d)", config.Endpoint, resp.Status, resp.StatusCode)
+		return nil, fmt.Errorf("fortanix: failed to authenticate to '%s': %s (%d)", client.Endpoint(), resp.Status, resp.StatusCode)
 	}
 	type Response struct {
 		Token string `json:"access_token"` // Raw bearer token - clients have to set 'Authorization: Bearer <token>'
 	}
 	var response Response
 	if err := json.NewDecoder(mem.LimitReader(resp.Body, 1*mem.MiB)).Decode(&response); err != nil {
-		return nil, fmt.Errorf("fortanix: failed to authenticate to '%s': %v", config.Endpoint, err)
+		return nil, fmt.Errorf("fortanix: failed to authenticate to '%s': %v", client.Endpoint(), err)
 	}
 
 	// Now we revoke the session we just created to cleanup any
 	// session credentials we just created. This is not strictly
 	// necessary but allows Fortanix SDKMS to garbage-collect
 	// unused credentials early.
-	url = endpoint(config.Endpoint, "/sys/v1/session/terminate")
-	req, err = http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Authorization", "Bearer "+response.Token)
-
-	resp, err = client.Do(req)
+	resp, err = client.SendWithAuth(ctx, http.MethodPost, "/sys/v1/session/terminate", nil, "Bearer "+response.Token)
 	if err != nil {
 		return nil, err
 	}
@@ -174,7 +204,7 @@ func Connect(ctx context.Context, config *Config) (*Store, error) {
 		if err := parseErrorResponse(resp); err != nil {
 			return nil, err
 		}
-		return nil, fmt.Errorf("fortanix: failed to authenticate to '%s': %s (%d)", config.Endpoint, resp.Status, resp.StatusCode)
+		return nil, fmt.Errorf("fortanix: failed to authenticate to '%s': %s (%d)", client.Endpoint(), resp.Status, resp.StatusCode)
 	}
 	return &Store{
 		config: *config,
@@ -184,19 +214,26 @@ func Connect(ctx context.Context, config *Config) (*Store, error) {
 
 // Status returns the current state of the Fortanix SDKMS instance.
 // In particular, whether it is reachable and the network latency.
+//
+// If failover endpoints are configured, the Fortanix SDKMS is
+// considered reachable if any endpoint is reachable.
 func (s *Store) Status(ctx context.Context) (kv.State, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.config.Endpoint, nil)
-	if err != nil {
-		return kv.State{}, err
-	}
+	var err error
+	for _, endpoint := range s.client.endpoints {
+		var req *http.Request
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return kv.State{}, err
+		}
 
-	start := time.Now()
-	if _, err = http.DefaultClient.Do(req); err != nil {
-		return kv.State{}, &kv.Unreachable{Err: err}
+		start := time.Now()
+		if _, err = http.DefaultClient.Do(req); err == nil {
+			return kv.State{
+				Latency: time.Since(start),
+			}, nil
+		}
 	}
-	return kv.State{
-		Latency: time.Since(start),
-	}, nil
+	return kv.State{}, &kv.Unreachable{Err: err}
 }
 
 // Create stores the given key at the Fortanix SDKMS if and only
@@ -230,15 +267,7 @@ func (s *Store) Create(ctx context.Context, name string, value []byte) error {
 		return err
 	}
 
-	url := endpoint(s.config.Endpoint, "/crypto/v1/keys")
-	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, xhttp.RetryReader(bytes.NewReader(request)))
-	if err != nil {
-		return fmt.Errorf("fortanix: failed to create key '%s': %v", name, err)
-	}
-	req.Header.Set("Authorization", s.config.APIKey.String())
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := s.client.Do(req)
+	resp, err := s.client.Send(ctx, http.MethodPut, "/crypto/v1/keys", request)
 	if err != nil {
 		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 			return err
@@ -266,6 +295,13 @@ func (s *Store) Set(ctx context.Context, name string, value []byte) error {
 	return s.Create(ctx, name, value)
 }
 
+// Update returns kv.ErrNotSupported. The value of an
+// imported SDKMS security object cannot be modified and
+// deleting and re-creating the object may lose the key.
+func (s *Store) Update(context.Context, string, []byte, []byte) error {
+	return kv.ErrNotSupported
+}
+
 // Delete deletes the key associated with the given name
 // from the Fortanix SDKMS. It may not return an error if no
 // entry for the given name exists.
@@ -283,15 +319,7 @@ func (s *Store) Delete(ctx context.Context, name string) error {
 		return fmt.Errorf("fortanix: failed to delete '%s': %v", name, err)
 	}
 
-	url := endpoint(s.config.Endpoint, "/crypto/v1/keys/export")
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, xhttp.RetryReader(bytes.NewReader(request)))
-	if err != nil {
-		return fmt.Errorf("fortanix: failed to delete '%s': %v", name, err)
-	}
-	req.Header.Set("Authorization", s.config.APIKey.String())
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := s.client.Do(req)
+	resp, err := s.client.Send(ctx, http.MethodPost, "/crypto/v1/keys/export", request)
 	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 		return err
 	}
@@ -318,14 +346,7 @@ func (s *Store) Delete(ctx context.Context, name string) error {
 	}
 
 	// Now, we can delete the key using its key ID.
-	url = endpoint(s.config.Endpoint, "/crypto/v1/keys", response.KeyID)
-	req, err = http.NewRequestWithContext(ctx, http.MethodDelete, url, nil)
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Authorization", s.config.APIKey.String())
-
-	resp, err = s.client.Do(req)
+	resp, err = s.client.Send(ctx, http.MethodDelete, path.Join("/crypto/v1/keys", response.KeyID), nil)
 	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 		return err
 	}
@@ -343,6 +364,13 @@ func (s *Store) Delete(ctx context.Context, name string) error {
 	return nil
 }
 
+// DeleteMany deletes the given keys. Fortanix SDKMS has no batch
+// delete API. Hence, DeleteMany sends up to batch.Concurrency
+// requests concurrently.
+func (s *Store) DeleteMany(ctx context.Context, names []string) []error {
+	return batch.Delete[string, []byte](ctx, s, names)
+}
+
 // Get returns the key associated with the given name.
 //
 // If there is no such entry, Get returns kes.ErrKeyNotFound.
@@ -357,15 +385,7 @@ func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
 		return nil, fmt.Errorf("fortanix: failed to fetch %q: %v", name, err)
 	}
 
-	url := endpoint(s.config.Endpoint, "/crypto/v1/keys/export")
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, xhttp.RetryReader(bytes.NewReader(request)))
-	if err != nil {
-		return nil, fmt.Errorf("fortanix: failed to fetch '%s': %v", name, err)
-	}
-	req.Header.Set("Authorization", s.config.APIKey.String())
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := s.client.Do(req)
+	resp, err := s.client.Send(ctx, http.MethodPost, "/crypto/v1/keys/export", request)
 	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 		return nil, err
 	}
@@ -401,13 +421,21 @@ func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
 	return value, nil
 }
 
-// List returns a new Iterator over the Fortanix SDKMS keys.
+// GetMany returns the values associated with the given keys. It
+// sends up to batch.Concurrency requests concurrently since
+// Fortanix SDKMS has no batch export API.
+func (s *Store) GetMany(ctx context.Context, names []string) ([][]byte, []error) {
+	return batch.Get[string, []byte](ctx, s, names)
+}
+
+// List returns a new Iterator over the Fortanix SDKMS keys
+// that start with the given prefix.
 //
 // The returned iterator may or may not reflect any
 // concurrent changes to the Fortanix SDKMS instance - i.e.
 // creates or deletes. Further, it does not provide any
 // ordering guarantees.
-func (s *Store) List(ctx context.Context) (kv.Iter[string], error) {
+func (s *Store) List(ctx context.Context, prefix string) (kv.Iter[string], error) {
 	var cancel context.CancelCauseFunc
 	ctx, cancel = context.WithCancelCause(ctx)
 	values := make(chan string, 10)
@@ -417,18 +445,11 @@ func (s *Store) List(ctx context.Context) (kv.Iter[string], error) {
 
 		var start string
 		for {
-			reqURL := endpoint(s.config.Endpoint, "/crypto/v1/keys") + "?sort=name:asc&limit=100"
+			uri := "/crypto/v1/keys?sort=name:asc&limit=100"
 			if start != "" {
-				reqURL += "&start=" + start
+				uri += "&start=" + start
 			}
-			req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
-			if err != nil {
-				cancel(fmt.Errorf("fortanix: failed to list keys: %v", err))
-				return
-			}
-			req.Header.Set("Authorization", s.config.APIKey.String())
-
-			resp, err := s.client.Do(req)
+			resp, err := s.client.Send(ctx, http.MethodGet, uri, nil)
 			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 				cancel(err)
 				return
@@ -450,6 +471,9 @@ func (s *Store) List(ctx context.Context) (kv.Iter[string], error) {
 				return
 			}
 			for _, k := range keys {
+				if !strings.HasPrefix(k.Name, prefix) {
+					continue
+				}
 				select {
 				case values <- k.Name:
 				case <-ctx.Done():
@@ -595,25 +619,3 @@ func loadCustomCAs(path string) (*x509.CertPool, error) {
 	}
 	return rootCAs, nil
 }
-
-// endpoint returns an endpoint URL starting with the
-// given endpoint followed by the path elements.
-//
-// For example:
-//   - endpoint("https://127.0.0.1:7373", "version")                => "https://127.0.0.1:7373/version"
-//   - endpoint("https://127.0.0.1:7373/", "/key/create", "my-key") => "https://127.0.0.1:7373/key/create/my-key"
-//
-// Any leading or trailing whitespaces are removed from
-// the endpoint before it is concatenated with the path
-// elements.
-//
-// The path elements will not be URL-escaped.
-func endpoint(endpoint string, elems ...string) string {
-	endpoint = strings.TrimSpace(endpoint)
-	endpoint = strings.TrimSuffix(endpoint, "/")
-
-	if len(elems) > 0 && !strings.HasPrefix(elems[0], "/") {
-		endpoint += "/"
-	}
-	return endpoint + path.Join(elems...)
-}
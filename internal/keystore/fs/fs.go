@@ -8,6 +8,7 @@
 package fs
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"io"
@@ -84,7 +85,7 @@ func (s *Store) Create(_ context.Context, name string, value []byte) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
-	filename := filepath.Join(s.dir, name)
+	filename := filepath.Join(s.dir, fileName(name))
 	switch err := s.create(filename, value); {
 	case errors.Is(err, os.ErrExist):
 		return kes.ErrKeyExists
@@ -103,19 +104,74 @@ func (s *Store) Set(ctx context.Context, name string, value []byte) error {
 	return s.Create(ctx, name, value)
 }
 
+// Update replaces the content of the named file within the
+// Conn directory with newValue if and only if its current
+// content is equal to oldValue. It returns kes.ErrKeyNotFound
+// if no such file exists.
+//
+// The new content is written to a unique temporary file
+// within the Conn directory that atomically replaces the
+// named file.
+func (s *Store) Update(ctx context.Context, name string, oldValue, newValue []byte) error {
+	if err := validName(name); err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	value, err := s.get(name)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(value, oldValue) {
+		return kv.ErrConflict
+	}
+
+	tmp, err := s.createTemp(fileName(name), newValue)
+	if err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, filepath.Join(s.dir, fileName(name))); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
 // Get reads the content of the named file within the Conn
 // directory. It returns kes.ErrKeyNotFound if no such file
 // exists.
 func (s *Store) Get(_ context.Context, name string) ([]byte, error) {
-	const MaxSize = 1 * mem.MiB
-
 	if err := validName(name); err != nil {
 		return nil, err
 	}
 	s.lock.RLock()
 	defer s.lock.RUnlock()
 
-	file, err := os.Open(filepath.Join(s.dir, name))
+	return s.get(name)
+}
+
+// GetMany reads the content of the named files within the
+// Conn directory while holding the lock once for all files.
+func (s *Store) GetMany(_ context.Context, names []string) ([][]byte, []error) {
+	values := make([][]byte, len(names))
+	errs := make([]error, len(names))
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	for i, name := range names {
+		if errs[i] = validName(name); errs[i] == nil {
+			values[i], errs[i] = s.get(name)
+		}
+	}
+	return values, errs
+}
+
+func (s *Store) get(name string) ([]byte, error) {
+	const MaxSize = 1 * mem.MiB
+
+	file, err := os.Open(filepath.Join(s.dir, fileName(name)))
 	if errors.Is(err, os.ErrNotExist) {
 		return nil, kes.ErrKeyNotFound
 	}
@@ -141,7 +197,7 @@ func (s *Store) Delete(_ context.Context, name string) error {
 	if err := validName(name); err != nil {
 		return err
 	}
-	switch err := os.Remove(filepath.Join(s.dir, name)); {
+	switch err := os.Remove(filepath.Join(s.dir, fileName(name))); {
 	case errors.Is(err, os.ErrNotExist):
 		return kes.ErrKeyNotFound
 	default:
@@ -149,15 +205,28 @@ func (s *Store) Delete(_ context.Context, name string) error {
 	}
 }
 
-// List returns a Iter over the files within the Conn directory.
-// The Iter must be closed to release any filesystem resources
-// back to the OS.
-func (s *Store) List(ctx context.Context) (kv.Iter[string], error) {
+// DeleteMany deletes the named files within the Conn directory.
+// The i-th error is kes.ErrKeyNotFound if the i-th file does not
+// exist.
+func (s *Store) DeleteMany(ctx context.Context, names []string) []error {
+	errs := make([]error, len(names))
+	for i, name := range names {
+		errs[i] = s.Delete(ctx, name)
+	}
+	return errs
+}
+
+// List returns a Iter over the files within the Conn directory
+// whose key names start with the given prefix. The Iter must be
+// closed to release any filesystem resources back to the OS.
+func (s *Store) List(ctx context.Context, prefix string) (kv.Iter[string], error) {
 	dir, err := os.Open(s.dir)
 	if err != nil {
 		return nil, err
 	}
-	return NewIter(ctx, dir), nil
+	iter := NewIter(ctx, dir)
+	iter.prefix = prefix
+	return iter, nil
 }
 
 func (s *Store) create(filename string, value []byte) error {
@@ -180,12 +249,43 @@ func (s *Store) create(filename string, value []byte) error {
 	return file.Close()
 }
 
+// createTemp writes value to a new temporary file within
+// the Conn directory and returns its path. The file name
+// starts with '.' and contains the given file name. Key
+// names cannot contain a '.'. Hence, temporary files are
+// never listed as keys, even if the process crashes before
+// removing them.
+func (s *Store) createTemp(filename string, value []byte) (string, error) {
+	file, err := os.CreateTemp(s.dir, "."+filename+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	n, err := file.Write(value)
+	if err == nil && n != len(value) {
+		err = io.ErrShortWrite
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if err == nil {
+		err = file.Close()
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
+
 // Iter is an iterator over all files within a
 // directory. It must be closed to release any
 // filesystem resources.
 type Iter struct {
 	ctx    context.Context
 	dir    fs.ReadDirFile
+	prefix string // Only names starting with prefix are returned
 	names  []fs.DirEntry
 	err    error
 	closed bool
@@ -213,10 +313,15 @@ func (i *Iter) Next() (string, bool) {
 	if i.closed || i.err != nil {
 		return "", false
 	}
-	if len(i.names) > 0 {
-		entry := i.names[0]
+	for len(i.names) > 0 {
+		filename := i.names[0].Name()
 		i.names = i.names[1:]
-		return entry.Name(), true
+		if isTempFile(filename) {
+			continue
+		}
+		if name := keyName(filename); strings.HasPrefix(name, i.prefix) {
+			return name, true
+		}
 	}
 
 	if i.ctx != nil {
@@ -240,9 +345,7 @@ func (i *Iter) Next() (string, bool) {
 		return "", false
 	}
 	if len(i.names) > 0 {
-		entry := i.names[0]
-		i.names = i.names[1:]
-		return entry.Name(), true
+		return i.Next()
 	}
 	return "", false
 }
@@ -263,9 +366,27 @@ func (i *Iter) Close() error {
 
 func validName(name string) error {
 	if name == "" || strings.IndexFunc(name, func(c rune) bool {
-		return c == '/' || c == '\\' || c == '.'
+		return c == '%' || c == '\\' || c == '.'
 	}) >= 0 {
 		return errors.New("fs: key name contains invalid character")
 	}
+	if strings.HasPrefix(name, "/") || strings.HasSuffix(name, "/") || strings.Contains(name, "//") {
+		return errors.New("fs: key name contains empty path segment")
+	}
 	return nil
 }
+
+// isTempFile reports whether the file is a temporary
+// file created when updating a key. Temporary files
+// contain a '.' which is not valid within key names.
+func isTempFile(filename string) bool { return strings.Contains(filename, ".") }
+
+// fileName returns the name of the file that stores the
+// key with the given, potentially hierarchical, name. All
+// keys are stored within one directory. Hence, the '/'
+// separators get escaped.
+func fileName(name string) string { return strings.ReplaceAll(name, "/", "%2F") }
+
+// keyName returns the name of the key stored in the given
+// file. It is the inverse of fileName.
+func keyName(filename string) string { return strings.ReplaceAll(filename, "%2F", "/") }
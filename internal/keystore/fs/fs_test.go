@@ -4,7 +4,14 @@
 
 package fs
 
-import "testing"
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/minio/kes/internal/keystore/conformance"
+)
 
 var validNameTests = []struct {
 	Name  string
@@ -18,10 +25,13 @@ var validNameTests = []struct {
 	{Name: "/my-key", Valid: false},
 	{Name: "\\my-key", Valid: false},
 	{Name: "my-key/", Valid: false},
-	{Name: "my/key", Valid: false},
+	{Name: "my//key", Valid: false},
+	{Name: "my%2Fkey", Valid: false},
 	{Name: "./my-key", Valid: false},
 	{Name: "./../my-key", Valid: false},
 	{Name: "my-key", Valid: true},
+	{Name: "my/key", Valid: true},
+	{Name: "payments/prod/db-key", Valid: true},
 }
 
 func TestValidName(t *testing.T) {
@@ -31,3 +41,73 @@ func TestValidName(t *testing.T) {
 		}
 	}
 }
+
+func TestConformance(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	report, err := conformance.Run(context.Background(), store)
+	if err != nil {
+		t.Fatalf("Failed to run conformance tests: %v", err)
+	}
+	for _, result := range report.Results {
+		if !result.Passed() {
+			t.Errorf("Test '%s' failed: %v", result.Name, result.Err)
+		}
+	}
+}
+
+func TestUpdateTempFile(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	if err = store.Create(ctx, "my/key", []byte("v1")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+
+	// Temporary files left behind by a crash during an update
+	// must neither fail later updates nor be listed as keys.
+	for _, name := range []string{"my%2Fkey.tmp", ".my%2Fkey.123.tmp"} {
+		if err = os.WriteFile(filepath.Join(dir, name), []byte("v0"), 0o600); err != nil {
+			t.Fatalf("Failed to create temporary file: %v", err)
+		}
+	}
+	if err = store.Update(ctx, "my/key", []byte("v1"), []byte("v2")); err != nil {
+		t.Fatalf("Failed to update key: %v", err)
+	}
+	if err = store.Update(ctx, "my/key", []byte("v2"), []byte("v3")); err != nil {
+		t.Fatalf("Failed to update key: %v", err)
+	}
+	if value, err := store.Get(ctx, "my/key"); err != nil || string(value) != "v3" {
+		t.Fatalf("Failed to get key: got '%s' and '%v' - want '%s'", value, err, "v3")
+	}
+
+	iter, err := store.List(ctx, "")
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	defer iter.Close()
+
+	var names []string
+	for name, ok := iter.Next(); ok; name, ok = iter.Next() {
+		names = append(names, name)
+	}
+	if err = iter.Close(); err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	if len(names) != 1 || names[0] != "my/key" {
+		t.Fatalf("Listed keys mismatch: got '%v' - want '%v'", names, []string{"my/key"})
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read directory: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Update left temporary files behind: got %d files - want %d", len(entries), 3)
+	}
+}
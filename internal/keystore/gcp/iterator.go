@@ -6,6 +6,7 @@ package gcp
 
 import (
 	"path"
+	"strings"
 
 	secretmanager "cloud.google.com/go/secretmanager/apiv1"
 	gcpiterator "google.golang.org/api/iterator"
@@ -13,6 +14,7 @@ import (
 
 type iterator struct {
 	src    *secretmanager.SecretIterator
+	prefix string // Only secrets starting with prefix are returned
 	err    error
 	closed bool
 }
@@ -22,15 +24,19 @@ func (i *iterator) Next() (string, bool) {
 		return "", false
 	}
 
-	v, err := i.src.Next()
-	if err != nil {
-		i.err = err
-		if err == gcpiterator.Done {
-			i.err = i.Close()
+	for {
+		v, err := i.src.Next()
+		if err != nil {
+			i.err = err
+			if err == gcpiterator.Done {
+				i.err = i.Close()
+			}
+			return "", false
+		}
+		if name := path.Base(v.GetName()); strings.HasPrefix(name, i.prefix) {
+			return name, true
 		}
-		return "", false
 	}
-	return path.Base(v.GetName()), true
 }
 
 func (i *iterator) Close() error {
@@ -0,0 +1,184 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package gcp
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/minio/kes/internal/keystore/envelope"
+	"github.com/minio/kes/kv"
+	cloudkms "google.golang.org/api/cloudkms/v1"
+	"google.golang.org/api/option"
+)
+
+// KMSConfig is a structure containing configuration
+// options for connecting to GCP Cloud KMS.
+type KMSConfig struct {
+	// Endpoint is the GCP Cloud KMS endpoint.
+	// If empty, defaults to the GCP default endpoint.
+	Endpoint string
+
+	// Key is the resource name of the Cloud KMS key
+	// used to wrap data keys. For example:
+	//   projects/<project>/locations/<location>/keyRings/<key-ring>/cryptoKeys/<key>
+	//
+	// The key must be a symmetric ENCRYPT_DECRYPT key.
+	// Its protection level, e.g. HSM, is defined when
+	// creating the key.
+	Key string
+
+	// Credentials are the GCP credentials to
+	// access Cloud KMS.
+	Credentials Credentials
+
+	// Scopes are GCP OAuth2 scopes for accessing GCP APIs.
+	// If not set, defaults to the GCP default scopes.
+	Scopes []string
+}
+
+// KMS is a GCP Cloud KMS key that wraps data keys.
+type KMS struct {
+	service  *cloudkms.Service
+	endpoint string
+	key      string
+}
+
+var _ envelope.KeyWrapper = (*KMS)(nil) // compiler check
+
+// ConnectKMS connects and authenticates to GCP Cloud KMS and
+// checks that the configured key can be used to encrypt and
+// decrypt data keys.
+func ConnectKMS(ctx context.Context, c *KMSConfig) (*KMS, error) {
+	if c.Key == "" {
+		return nil, errors.New("gcp: no Cloud KMS key specified")
+	}
+	parts := strings.Split(c.Key, "/")
+	if len(parts) != 8 || parts[0] != "projects" || parts[2] != "locations" || parts[4] != "keyRings" || parts[6] != "cryptoKeys" {
+		return nil, fmt.Errorf("gcp: invalid Cloud KMS key '%s': key must be of the form 'projects/*/locations/*/keyRings/*/cryptoKeys/*'", c.Key)
+	}
+
+	const DefaultEndpoint = "https://cloudkms.googleapis.com/" // From the GCP SDK
+	var (
+		options  []option.ClientOption
+		endpoint = DefaultEndpoint
+	)
+	if c.Endpoint != "" {
+		options = append(options, option.WithEndpoint(c.Endpoint))
+		endpoint = c.Endpoint
+	}
+
+	// As for the SecretManager, we only pass credentials to the
+	// GCP client if they are not empty. Otherwise, the GCP SDK
+	// picks up the credentials provided by the environment.
+	credentials := c.Credentials
+	if credentials != (Credentials{}) {
+		credentials.projectID = parts[1]
+		if credentials.Client == "" {
+			return nil, errors.New("gcp: no client email provided")
+		}
+		if credentials.ClientID == "" {
+			return nil, errors.New("gcp: no client ID provided")
+		}
+		if credentials.Key == "" {
+			return nil, errors.New("gcp: no client private key provided")
+		}
+		if credentials.KeyID == "" {
+			return nil, errors.New("gcp: no client private key ID provided")
+		}
+
+		credentialsJSON, err := credentials.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, option.WithCredentialsJSON(credentialsJSON))
+	}
+	if len(c.Scopes) != 0 {
+		options = append(options, option.WithScopes(c.Scopes...))
+	}
+
+	service, err := cloudkms.NewService(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+	key, err := service.Projects.Locations.KeyRings.CryptoKeys.Get(c.Key).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("gcp: failed to fetch Cloud KMS key '%s': %v", c.Key, err)
+	}
+	if key.Purpose != "ENCRYPT_DECRYPT" {
+		return nil, fmt.Errorf("gcp: invalid Cloud KMS key '%s': key purpose is '%s' but must be 'ENCRYPT_DECRYPT'", c.Key, key.Purpose)
+	}
+	return &KMS{
+		service:  service,
+		endpoint: endpoint,
+		key:      c.Key,
+	}, nil
+}
+
+// Status returns the current state of GCP Cloud KMS.
+// In particular, whether it is reachable and the
+// network latency.
+func (k *KMS) Status(ctx context.Context) (kv.State, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.endpoint, nil)
+	if err != nil {
+		return kv.State{}, err
+	}
+
+	start := time.Now()
+	if _, err = http.DefaultClient.Do(req); err != nil {
+		return kv.State{}, &kv.Unreachable{Err: err}
+	}
+	return kv.State{
+		Latency: time.Since(start),
+	}, nil
+}
+
+// Wrap encrypts the plaintext with the Cloud KMS key.
+// The associated data is authenticated by Cloud KMS.
+func (k *KMS) Wrap(ctx context.Context, plaintext, associatedData []byte) ([]byte, error) {
+	resp, err := k.service.Projects.Locations.KeyRings.CryptoKeys.Encrypt(k.key, &cloudkms.EncryptRequest{
+		Plaintext:                   base64.StdEncoding.EncodeToString(plaintext),
+		AdditionalAuthenticatedData: base64.StdEncoding.EncodeToString(associatedData),
+	}).Context(ctx).Do()
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("gcp: failed to wrap data key: %v", err)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(resp.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("gcp: failed to wrap data key: invalid ciphertext: %v", err)
+	}
+	return ciphertext, nil
+}
+
+// Unwrap decrypts the ciphertext with the Cloud KMS key.
+//
+// The ciphertext may have been encrypted with any
+// enabled version of the Cloud KMS key. Hence, the
+// Cloud KMS key can be rotated.
+func (k *KMS) Unwrap(ctx context.Context, ciphertext, associatedData []byte) ([]byte, error) {
+	resp, err := k.service.Projects.Locations.KeyRings.CryptoKeys.Decrypt(k.key, &cloudkms.DecryptRequest{
+		Ciphertext:                  base64.StdEncoding.EncodeToString(ciphertext),
+		AdditionalAuthenticatedData: base64.StdEncoding.EncodeToString(associatedData),
+	}).Context(ctx).Do()
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("gcp: failed to unwrap data key: %v", err)
+	}
+	plaintext, err := base64.StdEncoding.DecodeString(resp.Plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("gcp: failed to unwrap data key: invalid plaintext: %v", err)
+	}
+	return plaintext, nil
+}
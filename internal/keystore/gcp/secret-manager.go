@@ -5,11 +5,13 @@
 package gcp
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"net/http"
 	"path"
+	"strings"
 	"time"
 
 	"github.com/minio/kes-go"
@@ -17,9 +19,11 @@ import (
 	"google.golang.org/api/option"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 
 	secretmanager "cloud.google.com/go/secretmanager/apiv1"
 	secretmanagerpb "cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"github.com/minio/kes/internal/keystore/batch"
 )
 
 // Store is a GCP SecretManager secret store.
@@ -28,6 +32,11 @@ type Store struct {
 	config *Config
 }
 
+// versionLabel is the label of a secret that contains the
+// secret version holding the current value. Secrets without
+// this label hold their value in the first version.
+const versionLabel = "kes-version"
+
 var _ kv.Store[string, []byte] = (*Store)(nil) // compiler check
 
 // Connect connects and authenticates to a GCP SecretManager
@@ -126,6 +135,9 @@ func (s *Store) Status(ctx context.Context) (kv.State, error) {
 // secret itself and then adding a secret version with some payload
 // data. The payload data contains the actual value.
 func (s *Store) Create(ctx context.Context, name string, value []byte) error {
+	if err := validName(name); err != nil {
+		return err
+	}
 	secret, err := s.client.CreateSecret(ctx, &secretmanagerpb.CreateSecretRequest{
 		Parent:   path.Join("projects", s.config.ProjectID),
 		SecretId: name,
@@ -173,10 +185,109 @@ func (s *Store) Set(ctx context.Context, name string, value []byte) error {
 	return s.Create(ctx, name, value)
 }
 
+// Update replaces the value of the given key with newValue
+// if and only if its current value is equal to oldValue.
+//
+// Update adds a new secret version and points the secret's
+// version label to it. The label is only changed if the
+// secret has not been modified concurrently, based on its
+// etag. Hence, concurrent updates of multiple KES servers
+// are serialized by SecretManager.
+func (s *Store) Update(ctx context.Context, name string, oldValue, newValue []byte) error {
+	if err := validName(name); err != nil {
+		return err
+	}
+	secret, err := s.client.GetSecret(ctx, &secretmanagerpb.GetSecretRequest{
+		Name: path.Join("projects", s.config.ProjectID, "secrets", name),
+	})
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+		if status.Code(err) == codes.NotFound {
+			return kes.ErrKeyNotFound
+		}
+		return fmt.Errorf("gcp: failed to update '%s': %v", name, err)
+	}
+	value, err := s.access(ctx, name, currentVersion(secret))
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(value, oldValue) {
+		return kv.ErrConflict
+	}
+
+	version, err := s.client.AddSecretVersion(ctx, &secretmanagerpb.AddSecretVersionRequest{
+		Parent: secret.Name,
+		Payload: &secretmanagerpb.SecretPayload{
+			Data: newValue,
+		},
+	})
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+		return fmt.Errorf("gcp: failed to update '%s': %v", name, err)
+	}
+
+	labels := make(map[string]string, len(secret.Labels)+1)
+	for k, v := range secret.Labels {
+		labels[k] = v
+	}
+	labels[versionLabel] = path.Base(version.Name)
+	_, err = s.client.UpdateSecret(ctx, &secretmanagerpb.UpdateSecretRequest{
+		Secret: &secretmanagerpb.Secret{
+			Name:   secret.Name,
+			Etag:   secret.Etag,
+			Labels: labels,
+		},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"labels"}},
+	})
+	if err != nil {
+		// The new version is not referenced by the secret. Try
+		// to destroy it even if the ctx has been canceled.
+		s.client.DestroySecretVersion(context.Background(), &secretmanagerpb.DestroySecretVersionRequest{
+			Name: version.Name,
+		})
+
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+		if code := status.Code(err); code == codes.Aborted || code == codes.FailedPrecondition {
+			return kv.ErrConflict
+		}
+		if status.Code(err) == codes.NotFound {
+			return kes.ErrKeyNotFound
+		}
+		return fmt.Errorf("gcp: failed to update '%s': %v", name, err)
+	}
+	return nil
+}
+
 // Get returns the value associated with the given key.
 func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
+	if err := validName(name); err != nil {
+		return nil, err
+	}
+	secret, err := s.client.GetSecret(ctx, &secretmanagerpb.GetSecretRequest{
+		Name: path.Join("projects", s.config.ProjectID, "secrets", name),
+	})
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+		if status.Code(err) == codes.NotFound {
+			return nil, kes.ErrKeyNotFound
+		}
+		return nil, fmt.Errorf("gcp: failed to read '%s': %v", name, err)
+	}
+	return s.access(ctx, name, currentVersion(secret))
+}
+
+// access returns the value of the given secret version.
+func (s *Store) access(ctx context.Context, name, version string) ([]byte, error) {
 	result, err := s.client.AccessSecretVersion(ctx, &secretmanagerpb.AccessSecretVersionRequest{
-		Name: path.Join("projects", s.config.ProjectID, "secrets", name, "versions", "1"),
+		Name: path.Join("projects", s.config.ProjectID, "secrets", name, "versions", version),
 	})
 	if err != nil {
 		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
@@ -190,15 +301,35 @@ func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
 	return result.Payload.Data, nil
 }
 
+// currentVersion returns the secret version that holds the
+// current value of the secret. It is the first version unless
+// the value has been updated.
+func currentVersion(secret *secretmanagerpb.Secret) string {
+	if version, ok := secret.Labels[versionLabel]; ok && version != "" {
+		return version
+	}
+	return "1"
+}
+
+// GetMany returns the values associated with the given keys. GCP
+// SecretManager has no batch API for secret versions. Hence,
+// GetMany fetches up to batch.Concurrency keys concurrently.
+func (s *Store) GetMany(ctx context.Context, names []string) ([][]byte, []error) {
+	return batch.Get[string, []byte](ctx, s, names)
+}
+
 // Delete remove the key-value pair from GCP SecretManager.
 //
 // Delete will remove all versions of the GCP secret. Even
-// though CreateKey will create only one version and fails
-// if the secret already exists a user may create more secrets
-// versions through e.g. the GCP CLI. However, KES does not
-// support multiple secret versions and expects a different
-// mechanism for "key-rotation".
+// though Create will create only one version and fails if
+// the secret already exists, Update and users, e.g. through
+// the GCP CLI, may add more secret versions. However, KES
+// only reads the version referenced by the secret's version
+// label, or the first version if not present.
 func (s *Store) Delete(ctx context.Context, name string) error {
+	if err := validName(name); err != nil {
+		return err
+	}
 	err := s.client.DeleteSecret(ctx, &secretmanagerpb.DeleteSecretRequest{
 		Name: path.Join("projects", s.config.ProjectID, "secrets", name),
 	})
@@ -214,13 +345,35 @@ func (s *Store) Delete(ctx context.Context, name string) error {
 	return nil
 }
 
+// DeleteMany deletes the given keys. It deletes up to
+// batch.Concurrency secrets concurrently since GCP
+// SecretManager has no batch delete API.
+func (s *Store) DeleteMany(ctx context.Context, names []string) []error {
+	return batch.Delete[string, []byte](ctx, s, names)
+}
+
 // List returns a new Iterator over the names of
-// all stored keys.
-func (s *Store) List(ctx context.Context) (kv.Iter[string], error) {
+// all stored keys that start with the given prefix.
+func (s *Store) List(ctx context.Context, prefix string) (kv.Iter[string], error) {
 	location := path.Join("projects", s.config.ProjectID)
 	return &iterator{
 		src: s.client.ListSecrets(ctx, &secretmanagerpb.ListSecretsRequest{
 			Parent: location,
 		}),
+		prefix: prefix,
 	}, nil
 }
+
+// errHierarchicalName is returned when a key name
+// contains a '/' path separator.
+var errHierarchicalName = kes.NewError(http.StatusBadRequest, "gcp: hierarchical key names are not supported")
+
+// validName returns an error if the name is a hierarchical
+// name, like 'payments/db-key'. Secret IDs must not contain
+// '/' since it separates the segments of a resource name.
+func validName(name string) error {
+	if strings.ContainsRune(name, '/') {
+		return errHierarchicalName
+	}
+	return nil
+}
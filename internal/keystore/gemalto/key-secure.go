@@ -26,6 +26,8 @@ import (
 	"aead.dev/mem"
 	"github.com/minio/kes-go"
 	xhttp "github.com/minio/kes/internal/http"
+	"github.com/minio/kes/internal/https"
+	"github.com/minio/kes/internal/keystore/batch"
 	"github.com/minio/kes/kv"
 )
 
@@ -52,6 +54,18 @@ type Config struct {
 	// instance. If empty, the host's root CA set is used.
 	CAPath string
 
+	// PrivateKey and Certificate are optional paths to a
+	// TLS private key and certificate for mTLS authentication
+	// to the KeySecure instance. The certificate is reloaded
+	// once either file changes.
+	PrivateKey  string
+	Certificate string
+
+	// ServerName is an optional TLS server name (SNI) used
+	// to verify the KeySecure TLS certificate. If empty, the
+	// endpoint host name is used.
+	ServerName string
+
 	// Login credentials are used to authenticate to the
 	// KeySecure instance and obtain a short-lived authentication
 	// token.
@@ -69,22 +83,32 @@ var _ kv.Store[string, []byte] = (*Store)(nil)
 // Connect returns a Store to a Gemalto KeySecure
 // server using the given config.
 func Connect(ctx context.Context, config *Config) (c *Store, err error) {
-	var rootCAs *x509.CertPool
+	if (config.PrivateKey == "") != (config.Certificate == "") {
+		return nil, errors.New("gemalto: TLS private key and certificate must be specified both")
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName: config.ServerName,
+	}
 	if config.CAPath != "" {
-		rootCAs, err = loadCustomCAs(config.CAPath)
+		tlsConfig.RootCAs, err = loadCustomCAs(config.CAPath)
 		if err != nil {
 			return nil, err
 		}
 	}
+	if config.Certificate != "" {
+		tlsConfig.GetClientCertificate, err = https.ClientCertificate(config.Certificate, config.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("gemalto: failed to load TLS client certificate: %v", err)
+		}
+	}
 
 	client := &client{
 		Retry: xhttp.Retry{
 			Client: http.Client{
 				Transport: &http.Transport{
-					TLSClientConfig: &tls.Config{
-						RootCAs: rootCAs,
-					},
-					Proxy: http.ProxyFromEnvironment,
+					TLSClientConfig: tlsConfig,
+					Proxy:           http.ProxyFromEnvironment,
 					DialContext: (&net.Dialer{
 						Timeout:   10 * time.Second,
 						KeepAlive: 10 * time.Second,
@@ -130,6 +154,9 @@ func (s *Store) Status(ctx context.Context) (kv.State, error) {
 // if the given key does not exist. If such an entry already exists
 // it returns kes.ErrKeyExists.
 func (s *Store) Create(ctx context.Context, name string, value []byte) error {
+	if err := validName(name); err != nil {
+		return err
+	}
 	type Request struct {
 		Type  string `json:"dataType"`
 		Value string `json:"material"`
@@ -182,9 +209,19 @@ func (s *Store) Set(ctx context.Context, name string, value []byte) error {
 	return s.Create(ctx, name, value)
 }
 
+// Update returns kv.ErrNotSupported. KeySecure secrets
+// cannot be modified and deleting and re-creating the
+// secret may lose the key.
+func (s *Store) Update(context.Context, string, []byte, []byte) error {
+	return kv.ErrNotSupported
+}
+
 // Get returns the value associated with the given key.
 // If no entry for the key exists it returns kes.ErrKeyNotFound.
 func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
+	if err := validName(name); err != nil {
+		return nil, err
+	}
 	type Response struct {
 		Value string `json:"material"`
 	}
@@ -226,9 +263,19 @@ func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
 	return []byte(response.Value), nil
 }
 
+// GetMany returns the values associated with the given keys. It
+// sends up to batch.Concurrency requests concurrently since
+// KeySecure has no batch API.
+func (s *Store) GetMany(ctx context.Context, names []string) ([][]byte, []error) {
+	return batch.Get[string, []byte](ctx, s, names)
+}
+
 // Delete removes a the value associated with the given key
 // from Gemalto, if it exists.
 func (s *Store) Delete(ctx context.Context, name string) error {
+	if err := validName(name); err != nil {
+		return err
+	}
 	url := fmt.Sprintf("%s/api/v1/vault/secrets/%s?type=name", s.config.Endpoint, name)
 	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, url, nil)
 	if err != nil {
@@ -265,9 +312,16 @@ func (s *Store) Delete(ctx context.Context, name string) error {
 	return nil
 }
 
+// DeleteMany deletes the given keys. KeySecure has no batch API.
+// Hence, DeleteMany sends up to batch.Concurrency requests
+// concurrently.
+func (s *Store) DeleteMany(ctx context.Context, names []string) []error {
+	return batch.Delete[string, []byte](ctx, s, names)
+}
+
 // List returns a new Iterator over the names of
-// all stored keys.
-func (s *Store) List(ctx context.Context) (kv.Iter[string], error) {
+// all stored keys that start with the given prefix.
+func (s *Store) List(ctx context.Context, prefix string) (kv.Iter[string], error) {
 	// Response is the JSON response returned by KeySecure.
 	// It only contains the fields that we need to implement
 	// paginated listing. The raw response contains much more
@@ -347,6 +401,9 @@ func (s *Store) List(ctx context.Context) (kv.Iter[string], error) {
 				break
 			}
 			for _, v := range response.Resources {
+				if !strings.HasPrefix(v.Name, prefix) {
+					continue
+				}
 				select {
 				case values <- v.Name:
 				case <-ctx.Done():
@@ -488,3 +545,18 @@ func loadCustomCAs(path string) (*x509.CertPool, error) {
 	}
 	return rootCAs, nil
 }
+
+// errHierarchicalName is returned when a key name
+// contains a '/' path separator.
+var errHierarchicalName = kes.NewError(http.StatusBadRequest, "gemalto: hierarchical key names are not supported")
+
+// validName returns an error if the name is a hierarchical
+// name, like 'payments/db-key'. KeySecure addresses secrets
+// by name as URL path segment. Hence, names must not contain
+// a '/'.
+func validName(name string) error {
+	if strings.ContainsRune(name, '/') {
+		return errHierarchicalName
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package keystore
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/minio/kes/kv"
+)
+
+// Health levels of a kv.Store.
+const (
+	// Healthy indicates that the kv.Store serves
+	// all requests.
+	Healthy = "healthy"
+
+	// Degraded indicates that the kv.Store serves
+	// requests but is either read-only or slow.
+	Degraded = "degraded"
+
+	// Unhealthy indicates that the kv.Store does
+	// not serve requests.
+	Unhealthy = "unhealthy"
+)
+
+// SlowLatency is the latency above which a kv.Store
+// is considered degraded. It is compared to the 90th
+// latency percentile.
+const SlowLatency = 1 * time.Second
+
+// Health describes the health of the kv.Store
+// underlying a Cache.
+type Health struct {
+	// Level is the health level of the kv.Store.
+	// Either Healthy, Degraded or Unhealthy.
+	Level string
+
+	// Reachable indicates whether the kv.Store
+	// is reachable over the network.
+	Reachable bool
+
+	// Available indicates whether the kv.Store
+	// is reachable and ready to serve requests.
+	Available bool
+
+	// Authenticated indicates whether the kv.Store
+	// accepts the credentials of the server.
+	Authenticated bool
+
+	// ReadOnly indicates whether the kv.Store
+	// rejects any modification.
+	ReadOnly bool
+
+	// Latency is the most recent latency to the
+	// kv.Store. LatencyP50, LatencyP90 and LatencyP99
+	// are the latency percentiles of the most recent
+	// status checks.
+	Latency    time.Duration
+	LatencyP50 time.Duration
+	LatencyP90 time.Duration
+	LatencyP99 time.Duration
+
+	// Err is the error returned by the most recent
+	// status check, if any.
+	Err error
+}
+
+// Health checks the status of the underlying kv.Store
+// and returns its health.
+func (c *Cache) Health(ctx context.Context) Health {
+	state, err := c.Status(ctx)
+	if err != nil {
+		_, unreachable := kv.IsUnreachable(err)
+		return Health{
+			Level:     Unhealthy,
+			Reachable: !unreachable,
+			Err:       err,
+		}
+	}
+
+	h := Health{
+		Level:         Healthy,
+		Reachable:     true,
+		Available:     true,
+		Authenticated: !state.Unauthenticated,
+		ReadOnly:      state.ReadOnly,
+		Latency:       state.Latency,
+	}
+	h.LatencyP50, h.LatencyP90, h.LatencyP99 = c.latency.Percentiles()
+	switch {
+	case !h.Authenticated:
+		h.Level = Unhealthy
+	case h.ReadOnly || h.LatencyP90 > SlowLatency:
+		h.Level = Degraded
+	}
+	return h
+}
+
+// latencyWindow keeps the most recent latency
+// samples and computes percentiles over them.
+type latencyWindow struct {
+	lock    sync.Mutex
+	samples [128]time.Duration
+	n       int // Total number of samples added
+}
+
+// Add adds the latency sample to the window. If the
+// window is full, the oldest sample gets replaced.
+func (w *latencyWindow) Add(latency time.Duration) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.samples[w.n%len(w.samples)] = latency
+	w.n++
+}
+
+// Percentiles returns the 50th, 90th and 99th latency
+// percentiles of the samples within the window.
+func (w *latencyWindow) Percentiles() (p50, p90, p99 time.Duration) {
+	w.lock.Lock()
+	n := w.n
+	if n > len(w.samples) {
+		n = len(w.samples)
+	}
+	samples := make([]time.Duration, n)
+	copy(samples, w.samples[:n])
+	w.lock.Unlock()
+
+	if n == 0 {
+		return 0, 0, 0
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	percentile := func(p int) time.Duration {
+		return samples[(n*p+99)/100-1] // Nearest-rank method
+	}
+	return percentile(50), percentile(90), percentile(99)
+}
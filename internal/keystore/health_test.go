@@ -0,0 +1,87 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package keystore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/minio/kes/internal/keystore/mem"
+	"github.com/minio/kes/kv"
+)
+
+func TestLatencyWindow(t *testing.T) {
+	var w latencyWindow
+	if p50, p90, p99 := w.Percentiles(); p50 != 0 || p90 != 0 || p99 != 0 {
+		t.Fatalf("Empty window: got '%v %v %v' - want '0 0 0'", p50, p90, p99)
+	}
+
+	// Add more samples than the window can hold. Only
+	// the most recent ones must be considered.
+	for i := 0; i < 1000; i++ {
+		w.Add(time.Hour)
+	}
+	for i := 1; i <= 100; i++ {
+		w.Add(time.Duration(i) * time.Millisecond)
+	}
+	for i := 0; i < 28; i++ {
+		w.Add(0)
+	}
+
+	p50, p90, p99 := w.Percentiles()
+	if p50 != 36*time.Millisecond {
+		t.Fatalf("Invalid 50th percentile: got '%v' - want '%v'", p50, 36*time.Millisecond)
+	}
+	if p90 != 88*time.Millisecond {
+		t.Fatalf("Invalid 90th percentile: got '%v' - want '%v'", p90, 88*time.Millisecond)
+	}
+	if p99 != 99*time.Millisecond {
+		t.Fatalf("Invalid 99th percentile: got '%v' - want '%v'", p99, 99*time.Millisecond)
+	}
+}
+
+func TestCacheHealth(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for i, test := range cacheHealthTests {
+		cache := NewCache(ctx, &statusStore{state: test.State, err: test.Err}, &CacheConfig{})
+		health := cache.Health(ctx)
+		cache.Stop()
+
+		if health.Level != test.Level {
+			t.Fatalf("Test %d: invalid health level: got '%s' - want '%s'", i, health.Level, test.Level)
+		}
+		if health.Reachable != test.Reachable {
+			t.Fatalf("Test %d: invalid reachability: got '%v' - want '%v'", i, health.Reachable, test.Reachable)
+		}
+	}
+}
+
+var cacheHealthTests = []struct {
+	State     kv.State
+	Err       error
+	Level     string
+	Reachable bool
+}{
+	{State: kv.State{Latency: time.Millisecond}, Level: Healthy, Reachable: true},          // 0
+	{State: kv.State{Latency: 2 * time.Second}, Level: Degraded, Reachable: true},          // 1
+	{State: kv.State{ReadOnly: true}, Level: Degraded, Reachable: true},                    // 2
+	{State: kv.State{Unauthenticated: true}, Level: Unhealthy, Reachable: true},            // 3
+	{Err: &kv.Unavailable{Err: errors.New("sealed")}, Level: Unhealthy, Reachable: true},   // 4
+	{Err: &kv.Unreachable{Err: errors.New("timeout")}, Level: Unhealthy, Reachable: false}, // 5
+}
+
+// statusStore is a kv.Store that reports a fixed status.
+type statusStore struct {
+	mem.Store
+
+	state kv.State
+	err   error
+}
+
+func (s *statusStore) Status(context.Context) (kv.State, error) { return s.state, s.err }
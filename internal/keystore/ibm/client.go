@@ -0,0 +1,244 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package ibm
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"aead.dev/mem"
+	xhttp "github.com/minio/kes/internal/http"
+)
+
+// authToken is an IBM Cloud IAM access token.
+// It can be used to authenticate API requests.
+type authToken struct {
+	Type   string
+	Value  string
+	Expiry time.Duration
+}
+
+// String returns the string representation of
+// the authentication token.
+func (t *authToken) String() string { return fmt.Sprintf("%s %s", t.Type, t.Value) }
+
+// client is a Key Protect REST API client
+// responsible for fetching and renewing IAM
+// access tokens.
+type client struct {
+	xhttp.Retry
+
+	lock  sync.Mutex
+	token authToken
+}
+
+// Authenticate tries to obtain a new IAM access token from
+// the given IAM endpoint by presenting the login's API key.
+//
+// If the login specifies a service ID, Authenticate verifies
+// that the API key belongs to this service ID.
+//
+// Authenticate should be called to obtain the first access
+// token. This token can then be renewed via RenewAuthToken.
+func (c *client) Authenticate(ctx context.Context, endpoint string, login Credentials) error {
+	type Response struct {
+		Type   string `json:"token_type"`
+		Token  string `json:"access_token"`
+		Expiry int64  `json:"expires_in"` // IAM returns expiry in seconds
+	}
+
+	body := url.Values{}
+	body.Set("grant_type", "urn:ibm:params:oauth:grant-type:apikey")
+	body.Set("apikey", login.APIKey)
+
+	url := fmt.Sprintf("%s/identity/token", endpoint)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, xhttp.RetryReader(strings.NewReader(body.Encode())))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", resp.Status, parseIAMError(resp))
+	}
+
+	const MaxSize = 1 * mem.MiB // An access token response should not exceed 1 MiB
+	var response Response
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(&response); err != nil {
+		return err
+	}
+	if response.Token == "" {
+		return errors.New("server response does not contain an access token")
+	}
+	if response.Type != "Bearer" {
+		return fmt.Errorf("unexpected access token type '%s'", response.Type)
+	}
+	if response.Expiry <= 0 {
+		return fmt.Errorf("invalid access token expiry '%d'", response.Expiry)
+	}
+	if login.ServiceID != "" {
+		subject, err := tokenSubject(response.Token)
+		if err != nil {
+			return err
+		}
+		if subject != login.ServiceID {
+			return fmt.Errorf("API key does not belong to service ID '%s'", login.ServiceID)
+		}
+	}
+
+	c.lock.Lock()
+	c.token = authToken{
+		Type:   response.Type,
+		Value:  response.Token,
+		Expiry: time.Duration(response.Expiry) * time.Second,
+	}
+	c.lock.Unlock()
+	return nil
+}
+
+// RenewAuthToken tries to renew the client's access token
+// before it expires. It blocks until <-ctx.Done() completes.
+//
+// Before calling RenewAuthToken the client should already have
+// an access token. Therefore, RenewAuthToken should be called
+// only after Authenticate.
+//
+// If RenewAuthToken fails to renew the client's access token
+// then it keeps retrying and waits for the given login.Retry
+// delay between each retry attempt.
+//
+// If login.Retry is 0 then RenewAuthToken uses a reasonable
+// default retry delay.
+func (c *client) RenewAuthToken(ctx context.Context, endpoint string, login Credentials) {
+	if login.Retry == 0 {
+		login.Retry = 5 * time.Second
+	}
+	var (
+		timer *time.Timer
+		err   error
+	)
+	for {
+		if err != nil {
+			timer = time.NewTimer(login.Retry)
+		} else {
+			c.lock.Lock()
+			timer = time.NewTimer(c.token.Expiry / 2)
+			c.lock.Unlock()
+		}
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			err = c.Authenticate(ctx, endpoint, login)
+			timer.Stop()
+		}
+	}
+}
+
+// AuthToken returns an access token that can be used
+// to authenticate API requests to Key Protect.
+//
+// It should be used as HTTP Authorization header value.
+func (c *client) AuthToken() string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.token.String()
+}
+
+// tokenSubject returns the subject claim of the given
+// IAM access token. For service IDs, the subject is
+// the service ID, e.g. ServiceId-<UUID>.
+//
+// It does not verify the token signature. The token
+// has just been issued by IAM via a TLS connection.
+func tokenSubject(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", errors.New("malformed access token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("malformed access token: %v", err)
+	}
+
+	var claims struct {
+		Subject string `json:"sub"`
+	}
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return "", fmt.Errorf("malformed access token: %v", err)
+	}
+	return claims.Subject, nil
+}
+
+// parseIAMError returns the error message of an
+// IBM Cloud IAM API error response.
+func parseIAMError(resp *http.Response) string {
+	const MaxSize = 1 * mem.MiB
+
+	body, err := io.ReadAll(mem.LimitReader(resp.Body, MaxSize))
+	if err != nil || len(body) == 0 {
+		return http.StatusText(resp.StatusCode)
+	}
+
+	var response struct {
+		Code    string `json:"errorCode"`
+		Message string `json:"errorMessage"`
+	}
+	if err = json.Unmarshal(body, &response); err == nil && response.Message != "" {
+		return response.Message
+	}
+	return strings.TrimSpace(string(body))
+}
+
+// parseServerError returns the error message of a
+// Key Protect API error response.
+func parseServerError(resp *http.Response) string {
+	const MaxSize = 1 * mem.MiB
+
+	// Key Protect API errors contain a JSON body with
+	// one error resource. Each error resource contains
+	// a generic error message and, optionally, a list
+	// of reasons describing the error in more detail.
+	body, err := io.ReadAll(mem.LimitReader(resp.Body, MaxSize))
+	if err != nil || len(body) == 0 {
+		return http.StatusText(resp.StatusCode)
+	}
+
+	var response struct {
+		Resources []struct {
+			Message string `json:"errorMsg"`
+			Reasons []struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"reasons"`
+		} `json:"resources"`
+	}
+	if err = json.Unmarshal(body, &response); err != nil || len(response.Resources) == 0 {
+		return strings.TrimSpace(string(body))
+	}
+	if r := response.Resources[0]; len(r.Reasons) > 0 && r.Reasons[0].Message != "" {
+		return r.Reasons[0].Message
+	}
+	return response.Resources[0].Message
+}
@@ -0,0 +1,42 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package ibm
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+var tokenSubjectTests = []struct {
+	Token      string
+	Subject    string
+	ShouldFail bool
+}{
+	{ // 0
+		Token:   "eyJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString([]byte(`{"iam_id":"iam-ServiceId-7f4c2a1e","sub":"ServiceId-7f4c2a1e"}`)) + ".c2lnbmF0dXJl",
+		Subject: "ServiceId-7f4c2a1e",
+	},
+	{ // 1
+		Token:   "eyJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString([]byte(`{"iam_id":"IBMid-550006JKXX"}`)) + ".c2lnbmF0dXJl",
+		Subject: "",
+	},
+	{Token: "not-a-jwt", ShouldFail: true},                             // 2
+	{Token: "eyJhbGciOiJSUzI1NiJ9.!!!.c2lnbmF0dXJl", ShouldFail: true}, // 3
+}
+
+func TestTokenSubject(t *testing.T) {
+	for i, test := range tokenSubjectTests {
+		subject, err := tokenSubject(test.Token)
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to parse token: %v", i, err)
+		}
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: parsing token should have failed", i)
+		}
+		if subject != test.Subject {
+			t.Fatalf("Test %d: got subject '%s' - want '%s'", i, subject, test.Subject)
+		}
+	}
+}
@@ -0,0 +1,454 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package ibm implements a key store that fetches/stores
+// cryptographic keys as IBM Key Protect or Hyper Protect
+// Crypto Services standard keys.
+package ibm
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+	xhttp "github.com/minio/kes/internal/http"
+	"github.com/minio/kes/internal/https"
+	"github.com/minio/kes/internal/keystore/batch"
+	"github.com/minio/kes/kv"
+)
+
+// DefaultIAMEndpoint is the public IBM Cloud IAM endpoint.
+const DefaultIAMEndpoint = "https://iam.cloud.ibm.com"
+
+// Credentials represents an IBM Cloud API key that can
+// be used to obtain a short-lived IAM access token.
+type Credentials struct {
+	// APIKey is an IBM Cloud API key of either a
+	// user or a service ID.
+	APIKey string
+
+	// ServiceID is an optional service ID, e.g.
+	// ServiceId-<UUID>. If not empty, the API key
+	// must belong to this service ID.
+	ServiceID string
+
+	Retry time.Duration // The time to wait before trying to re-authenticate
+}
+
+// Config is a structure containing configuration
+// options for connecting to a Key Protect or Hyper
+// Protect Crypto Services instance.
+type Config struct {
+	// Endpoint is the Key Protect or Hyper Protect Crypto
+	// Services API endpoint, e.g.
+	// https://us-south.kms.cloud.ibm.com
+	Endpoint string
+
+	// InstanceID is the ID of the Key Protect or Hyper
+	// Protect Crypto Services instance.
+	InstanceID string
+
+	// IAMEndpoint is the IBM Cloud IAM endpoint used to
+	// obtain access tokens. If empty, DefaultIAMEndpoint
+	// is used.
+	IAMEndpoint string
+
+	// CAPath is a path to the root CA certificate(s)
+	// used to verify the TLS certificate of the API
+	// endpoint. If empty, the host's root CA set is used.
+	CAPath string
+
+	// Login credentials are used to authenticate to IAM
+	// and obtain a short-lived access token.
+	Login Credentials
+}
+
+// Store is an IBM Key Protect secret store.
+//
+// Each key is stored as extractable standard key. The
+// key name is also used as alias such that keys can be
+// fetched and deleted by name.
+type Store struct {
+	config Config
+	client *client
+}
+
+var _ kv.Store[string, []byte] = (*Store)(nil)
+
+// Connect returns a Store to a Key Protect or Hyper Protect
+// Crypto Services instance using the given config.
+func Connect(ctx context.Context, config *Config) (*Store, error) {
+	if config.Endpoint == "" {
+		return nil, errors.New("ibm: endpoint is empty")
+	}
+	if config.InstanceID == "" {
+		return nil, errors.New("ibm: instance ID is empty")
+	}
+	if config.Login.APIKey == "" {
+		return nil, errors.New("ibm: API key is empty")
+	}
+
+	tlsConfig := &tls.Config{}
+	if config.CAPath != "" {
+		pool, err := https.CertPoolFromFile(config.CAPath)
+		if err != nil {
+			return nil, fmt.Errorf("ibm: failed to load CA certificate: %v", err)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	c := *config
+	c.Endpoint = strings.TrimSuffix(c.Endpoint, "/")
+	if c.IAMEndpoint == "" {
+		c.IAMEndpoint = DefaultIAMEndpoint
+	}
+	c.IAMEndpoint = strings.TrimSuffix(c.IAMEndpoint, "/")
+
+	client := &client{
+		Retry: xhttp.Retry{
+			Client: http.Client{
+				Transport: &http.Transport{
+					TLSClientConfig: tlsConfig,
+					Proxy:           http.ProxyFromEnvironment,
+					DialContext: (&net.Dialer{
+						Timeout:   10 * time.Second,
+						KeepAlive: 10 * time.Second,
+						DualStack: true,
+					}).DialContext,
+					ForceAttemptHTTP2:     true,
+					MaxIdleConns:          100,
+					IdleConnTimeout:       30 * time.Second,
+					TLSHandshakeTimeout:   10 * time.Second,
+					ExpectContinueTimeout: 1 * time.Second,
+				},
+			},
+		},
+	}
+	if err := client.Authenticate(ctx, c.IAMEndpoint, c.Login); err != nil {
+		return nil, fmt.Errorf("ibm: failed to authenticate: %v", err)
+	}
+	go client.RenewAuthToken(context.Background(), c.IAMEndpoint, c.Login)
+	return &Store{
+		config: c,
+		client: client,
+	}, nil
+}
+
+// Status returns the current state of the Key Protect instance.
+// In particular, whether it is reachable and the network latency.
+func (s *Store) Status(ctx context.Context) (kv.State, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.config.Endpoint, nil)
+	if err != nil {
+		return kv.State{}, err
+	}
+
+	start := time.Now()
+	resp, err := s.client.Client.Do(req)
+	if err != nil {
+		return kv.State{}, &kv.Unreachable{Err: err}
+	}
+	resp.Body.Close()
+
+	return kv.State{
+		Latency: time.Since(start),
+	}, nil
+}
+
+// Create creates the given key-value pair at Key Protect if and
+// only if the given key does not exist. If such an entry already
+// exists it returns kes.ErrKeyExists.
+func (s *Store) Create(ctx context.Context, name string, value []byte) error {
+	type Resource struct {
+		Type        string   `json:"type"`
+		Name        string   `json:"name"`
+		Aliases     []string `json:"aliases"`
+		Extractable bool     `json:"extractable"`
+		Payload     string   `json:"payload"`
+	}
+	type Request struct {
+		Metadata struct {
+			Type  string `json:"collectionType"`
+			Total int    `json:"collectionTotal"`
+		} `json:"metadata"`
+		Resources []Resource `json:"resources"`
+	}
+
+	switch _, err := s.Get(ctx, name); {
+	case err == nil:
+		return kes.ErrKeyExists
+	case !errors.Is(err, kes.ErrKeyNotFound):
+		return err
+	}
+
+	var request Request
+	request.Metadata.Type = keyType
+	request.Metadata.Total = 1
+	request.Resources = []Resource{{
+		Type:        keyType,
+		Name:        name,
+		Aliases:     []string{name},
+		Extractable: true,
+		Payload:     base64.StdEncoding.EncodeToString(value),
+	}}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("ibm: failed to create key '%s': %v", name, err)
+	}
+
+	url := fmt.Sprintf("%s/api/v2/keys", s.config.Endpoint)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, xhttp.RetryReader(bytes.NewReader(body)))
+	if err != nil {
+		return fmt.Errorf("ibm: failed to create key '%s': %v", name, err)
+	}
+	s.setHeaders(req)
+	req.Header.Set("Content-Type", keyType)
+	req.Header.Set("Prefer", "return=minimal")
+
+	resp, err := s.client.Do(req)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("ibm: failed to create key '%s': %v", name, err)
+	}
+	defer resp.Body.Close()
+
+	// Key Protect returns 409 Conflict if a key with
+	// the same alias already exists.
+	if resp.StatusCode == http.StatusConflict {
+		return kes.ErrKeyExists
+	}
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("ibm: failed to create key '%s': %s (%d)", name, parseServerError(resp), resp.StatusCode)
+	}
+	return nil
+}
+
+// Set creates the given key-value pair at Key Protect if and
+// only if the given key does not exist. If such an entry already
+// exists it returns kes.ErrKeyExists.
+func (s *Store) Set(ctx context.Context, name string, value []byte) error {
+	return s.Create(ctx, name, value)
+}
+
+// Update returns kv.ErrNotSupported. Key Protect standard
+// keys cannot be modified and the alias of a deleted key
+// may not be available again.
+func (s *Store) Update(context.Context, string, []byte, []byte) error {
+	return kv.ErrNotSupported
+}
+
+// Get returns the value associated with the given key.
+// If no entry for the key exists it returns kes.ErrKeyNotFound.
+func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
+	type Response struct {
+		Resources []struct {
+			Payload string `json:"payload"`
+		} `json:"resources"`
+	}
+
+	url := fmt.Sprintf("%s/api/v2/keys/%s", s.config.Endpoint, url.PathEscape(name))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ibm: failed to access key '%s': %v", name, err)
+	}
+	s.setHeaders(req)
+
+	resp, err := s.client.Do(req)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ibm: failed to access key '%s': %v", name, err)
+	}
+	defer resp.Body.Close()
+
+	// Key Protect returns 404 NotFound if no key with the
+	// alias exists and 410 Gone if the key has been deleted.
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return nil, kes.ErrKeyNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ibm: failed to access key '%s': %s (%d)", name, parseServerError(resp), resp.StatusCode)
+	}
+
+	const MaxSize = 2 * mem.MiB
+	var response Response
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(&response); err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("ibm: failed to read key '%s': %v", name, err)
+	}
+	if len(response.Resources) == 0 {
+		return nil, kes.ErrKeyNotFound
+	}
+	value, err := base64.StdEncoding.DecodeString(response.Resources[0].Payload)
+	if err != nil {
+		return nil, fmt.Errorf("ibm: failed to read key '%s': %v", name, err)
+	}
+	return value, nil
+}
+
+// GetMany returns the values associated with the given keys. IBM
+// Key Protect has no batch API for unwrapping keys. Hence,
+// GetMany fetches up to batch.Concurrency keys concurrently.
+func (s *Store) GetMany(ctx context.Context, names []string) ([][]byte, []error) {
+	return batch.Get[string, []byte](ctx, s, names)
+}
+
+// Delete removes a the value associated with the given key
+// from Key Protect, if it exists.
+func (s *Store) Delete(ctx context.Context, name string) error {
+	url := fmt.Sprintf("%s/api/v2/keys/%s", s.config.Endpoint, url.PathEscape(name))
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, url, nil)
+	if err != nil {
+		return fmt.Errorf("ibm: failed to delete key '%s': %v", name, err)
+	}
+	s.setHeaders(req)
+
+	resp, err := s.client.Do(req)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("ibm: failed to delete key '%s': %v", name, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound, http.StatusGone:
+		return nil
+	default:
+		return fmt.Errorf("ibm: failed to delete key '%s': %s (%d)", name, parseServerError(resp), resp.StatusCode)
+	}
+}
+
+// DeleteMany deletes the given keys. It deletes up to
+// batch.Concurrency keys concurrently since IBM Key Protect has
+// no batch delete API.
+func (s *Store) DeleteMany(ctx context.Context, names []string) []error {
+	return batch.Delete[string, []byte](ctx, s, names)
+}
+
+// List returns a new Iterator over the names of
+// all stored keys that start with the given prefix.
+//
+// It only lists extractable standard keys, i.e. keys
+// that may have been created by the Store.
+func (s *Store) List(ctx context.Context, prefix string) (kv.Iter[string], error) {
+	type Response struct {
+		Resources []struct {
+			Name string `json:"name"`
+		} `json:"resources"`
+	}
+
+	var cancel context.CancelCauseFunc
+	ctx, cancel = context.WithCancelCause(ctx)
+	values := make(chan string, 10)
+
+	go func() {
+		defer close(values)
+
+		const Limit = 200
+		for offset := 0; ; offset += Limit {
+			url := fmt.Sprintf("%s/api/v2/keys?extractable=true&limit=%d&offset=%d", s.config.Endpoint, Limit, offset)
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			if err != nil {
+				cancel(fmt.Errorf("ibm: failed to list keys: %v", err))
+				return
+			}
+			s.setHeaders(req)
+
+			resp, err := s.client.Do(req)
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				cancel(err)
+				return
+			}
+			if err != nil {
+				cancel(fmt.Errorf("ibm: failed to list keys: %v", err))
+				return
+			}
+			if resp.StatusCode != http.StatusOK {
+				cancel(fmt.Errorf("ibm: failed to list keys: %s (%d)", parseServerError(resp), resp.StatusCode))
+				resp.Body.Close()
+				return
+			}
+
+			const MaxBody = 32 * mem.MiB // A page should not be larger than 32 MiB.
+			var response Response
+			err = json.NewDecoder(mem.LimitReader(resp.Body, MaxBody)).Decode(&response)
+			resp.Body.Close()
+			if err != nil {
+				if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+					cancel(err)
+				} else {
+					cancel(fmt.Errorf("ibm: failed to list keys: %v", err))
+				}
+				return
+			}
+
+			for _, v := range response.Resources {
+				if !strings.HasPrefix(v.Name, prefix) {
+					continue
+				}
+				select {
+				case values <- v.Name:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if len(response.Resources) < Limit {
+				return
+			}
+		}
+	}()
+	return &iter{
+		ch:     values,
+		ctx:    ctx,
+		cancel: cancel,
+	}, nil
+}
+
+// keyType is the Key Protect key resource type.
+const keyType = "application/vnd.ibm.kms.key+json"
+
+// setHeaders sets the authentication and instance
+// headers required by all Key Protect API requests.
+func (s *Store) setHeaders(req *http.Request) {
+	req.Header.Set("Authorization", s.client.AuthToken())
+	req.Header.Set("Bluemix-Instance", s.config.InstanceID)
+	req.Header.Set("Accept", "application/json")
+}
+
+type iter struct {
+	ch     <-chan string
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+}
+
+func (i *iter) Next() (string, bool) {
+	select {
+	case v, ok := <-i.ch:
+		return v, ok
+	case <-i.ctx.Done():
+		return "", false
+	}
+}
+
+func (i *iter) Close() error {
+	i.cancel(context.Canceled)
+	return context.Cause(i.ctx)
+}
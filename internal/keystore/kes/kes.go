@@ -9,10 +9,12 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/minio/kes-go"
 	"github.com/minio/kes/internal/https"
+	"github.com/minio/kes/internal/keystore/batch"
 	"github.com/minio/kes/kv"
 )
 
@@ -22,8 +24,9 @@ type Config struct {
 	// Endpoints contains one or multiple KES
 	// server endpoints.
 	//
-	// A Conn will automatically load balance
-	// between multiple endpoints.
+	// With multiple endpoints, requests are sent
+	// to the fastest endpoint and fail over to the
+	// remaining ones if it becomes unreachable.
 	Endpoints []string
 
 	// Enclave is an optional KES enclave name.
@@ -77,6 +80,14 @@ func Connect(ctx context.Context, config *Config) (*Store, error) {
 		enclave: config.Enclave,
 	}
 	store.client.Endpoints = config.Endpoints
+	if len(config.Endpoints) > 1 {
+		store.client.Endpoints = config.Endpoints[:1]
+		store.client.HTTPClient.Transport = &https.FailoverTransport{
+			Transport:     store.client.HTTPClient.Transport,
+			Endpoints:     config.Endpoints,
+			ProbeInterval: time.Minute,
+		}
+	}
 
 	if _, err := store.Status(ctx); err != nil {
 		return nil, err
@@ -129,6 +140,13 @@ func (s *Store) Set(ctx context.Context, name string, value []byte) error {
 	return s.Create(ctx, name, value)
 }
 
+// Update returns kv.ErrNotSupported. KES secrets cannot
+// be modified and deleting and re-creating the secret
+// may lose the key.
+func (s *Store) Update(context.Context, string, []byte, []byte) error {
+	return kv.ErrNotSupported
+}
+
 // Get returns the value associated with the given name.
 // If no entry for the key exists it returns kes.ErrKeyNotFound.
 func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
@@ -140,6 +158,13 @@ func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
 	return secret, err
 }
 
+// GetMany returns the values associated with the given keys. It
+// sends up to batch.Concurrency requests concurrently since the
+// KES secret API has no batch operations.
+func (s *Store) GetMany(ctx context.Context, names []string) ([][]byte, []error) {
+	return batch.Get[string, []byte](ctx, s, names)
+}
+
 // Delete removes a the value associated with the given name
 // from KES, if it exists. If no such entry exists it returns
 // kes.ErrKeyNotFound.
@@ -152,23 +177,42 @@ func (s *Store) Delete(ctx context.Context, name string) error {
 	return err
 }
 
-// List returns a new kms.Iter over all stored entries.
-func (s *Store) List(ctx context.Context) (kv.Iter[string], error) {
+// DeleteMany deletes the given keys. The KES secret API has no
+// batch operations. Hence, DeleteMany sends up to
+// batch.Concurrency requests concurrently.
+func (s *Store) DeleteMany(ctx context.Context, names []string) []error {
+	return batch.Delete[string, []byte](ctx, s, names)
+}
+
+// List returns a new kms.Iter over all stored entries
+// that start with the given prefix.
+//
+// The KES server filters the secrets by the prefix,
+// unless the prefix contains glob pattern characters.
+func (s *Store) List(ctx context.Context, prefix string) (kv.Iter[string], error) {
+	pattern := "*"
+	if prefix != "" && !strings.ContainsAny(prefix, `*?[\`) {
+		pattern = prefix + "**"
+	}
+
 	enclave := s.client.Enclave(s.enclave)
-	i, err := enclave.ListSecrets(ctx, "*")
+	i, err := enclave.ListSecrets(ctx, pattern)
 	if err != nil {
 		return nil, err
 	}
-	return &iter{i}, nil
+	return &iter{SecretIter: i, prefix: prefix}, nil
 }
 
 type iter struct {
 	*kes.SecretIter
+	prefix string
 }
 
 func (i *iter) Next() (string, bool) {
-	if i.SecretIter.Next() {
-		return i.Name(), true
+	for i.SecretIter.Next() {
+		if name := i.Name(); strings.HasPrefix(name, i.prefix) {
+			return name, true
+		}
 	}
 	return "", false
 }
@@ -6,7 +6,9 @@
 package mem
 
 import (
+	"bytes"
 	"context"
+	"strings"
 	"sync"
 
 	"github.com/minio/kes-go"
@@ -52,6 +54,25 @@ func (s *Store) Set(ctx context.Context, name string, value []byte) error {
 	return s.Create(ctx, name, value)
 }
 
+// Update replaces the value of the given key with newValue
+// if and only if its current value is equal to oldValue. If
+// no entry for the given name exists it returns
+// kes.ErrKeyNotFound.
+func (s *Store) Update(_ context.Context, name string, oldValue, newValue []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	value, ok := s.store[name]
+	if !ok {
+		return kes.ErrKeyNotFound
+	}
+	if !bytes.Equal(value, oldValue) {
+		return kv.ErrConflict
+	}
+	s.store[name] = newValue
+	return nil
+}
+
 // Delete removes the key with the given value, if it exists.
 func (s *Store) Delete(_ context.Context, name string) error {
 	s.lock.Lock()
@@ -61,6 +82,17 @@ func (s *Store) Delete(_ context.Context, name string) error {
 	return nil
 }
 
+// DeleteMany removes the given keys, if they exist.
+func (s *Store) DeleteMany(_ context.Context, names []string) []error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, name := range names {
+		delete(s.store, name)
+	}
+	return make([]error, len(names))
+}
+
 // Get returns the key associated with the given name. If no
 // entry for this name exists it returns kes.ErrKeyNotFound.
 func (s *Store) Get(_ context.Context, name string) ([]byte, error) {
@@ -74,14 +106,37 @@ func (s *Store) Get(_ context.Context, name string) ([]byte, error) {
 	return k, nil
 }
 
-// List returns a new iterator over the metadata of all stored keys.
-func (s *Store) List(context.Context) (kv.Iter[string], error) {
+// GetMany returns the keys associated with the given names.
+// The i-th error is kes.ErrKeyNotFound if no entry for the
+// i-th name exists.
+func (s *Store) GetMany(_ context.Context, names []string) ([][]byte, []error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	values := make([][]byte, len(names))
+	errs := make([]error, len(names))
+	for i, name := range names {
+		k, ok := s.store[name]
+		if !ok {
+			errs[i] = kes.ErrKeyNotFound
+			continue
+		}
+		values[i] = k
+	}
+	return values, errs
+}
+
+// List returns a new iterator over the metadata of all stored keys
+// that start with the given prefix.
+func (s *Store) List(_ context.Context, prefix string) (kv.Iter[string], error) {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
 
 	names := make([]string, 0, len(s.store))
 	for name := range s.store {
-		names = append(names, name)
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
 	}
 	return &iterator{
 		values: names,
@@ -0,0 +1,425 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package oci
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"aead.dev/mem"
+	xhttp "github.com/minio/kes/internal/http"
+)
+
+// signer provides the key used to sign OCI API requests.
+type signer interface {
+	// SigningKey returns the key ID and RSA private key
+	// for signing the next request.
+	SigningKey(ctx context.Context) (string, *rsa.PrivateKey, error)
+}
+
+type client struct {
+	xhttp.Retry
+	Signer signer
+}
+
+// Send sends a signed request with the given method to the
+// given URL. If body is not nil, it is sent JSON-encoded.
+func (c *client) Send(ctx context.Context, method, url string, body any) (*http.Response, error) {
+	var content []byte
+	if body != nil {
+		var err error
+		if content, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = xhttp.RetryReader(bytes.NewReader(content))
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = int64(len(content))
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	keyID, key, err := c.Signer.SigningKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = signRequest(req, content, keyID, key); err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
+
+// signRequest signs the request as specified by the OCI
+// request signature scheme - a variant of the HTTP signatures
+// draft. Requests with a body also sign the body's SHA-256
+// checksum.
+//
+// Ref: https://docs.oracle.com/iaas/Content/API/Concepts/signingrequests.htm
+func signRequest(req *http.Request, body []byte, keyID string, key *rsa.PrivateKey) error {
+	req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	headers := []string{"date", "(request-target)", "host"}
+
+	switch req.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		if req.Header.Get("Content-Type") == "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		checksum := sha256.Sum256(body)
+		req.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		req.Header.Set("X-Content-Sha256", base64.StdEncoding.EncodeToString(checksum[:]))
+		headers = append(headers, "content-length", "content-type", "x-content-sha256")
+	}
+
+	var s strings.Builder
+	for i, h := range headers {
+		if i > 0 {
+			s.WriteByte('\n')
+		}
+		switch h {
+		case "(request-target)":
+			s.WriteString(h + ": " + strings.ToLower(req.Method) + " " + req.URL.RequestURI())
+		case "host":
+			s.WriteString(h + ": " + req.URL.Host)
+		default:
+			s.WriteString(h + ": " + req.Header.Get(h))
+		}
+	}
+	digest := sha256.Sum256([]byte(s.String()))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf(
+		`Signature version="1",headers="%s",keyId="%s",algorithm="rsa-sha256",signature="%s"`,
+		strings.Join(headers, " "), keyID, base64.StdEncoding.EncodeToString(signature),
+	))
+	return nil
+}
+
+// apiKey is a signer using an OCI user API signing key.
+type apiKey struct {
+	keyID string
+	key   *rsa.PrivateKey
+}
+
+func newAPIKey(tenancyID, userID, fingerprint string, pemKey []byte) (*apiKey, error) {
+	if tenancyID == "" {
+		return nil, errors.New("oci: no tenancy ID specified")
+	}
+	if userID == "" {
+		return nil, errors.New("oci: no user ID specified")
+	}
+	if fingerprint == "" {
+		return nil, errors.New("oci: no API key fingerprint specified")
+	}
+	key, err := parsePrivateKey(pemKey)
+	if err != nil {
+		return nil, fmt.Errorf("oci: invalid API signing key: %v", err)
+	}
+	return &apiKey{
+		keyID: tenancyID + "/" + userID + "/" + fingerprint,
+		key:   key,
+	}, nil
+}
+
+func (k *apiKey) SigningKey(context.Context) (string, *rsa.PrivateKey, error) {
+	return k.keyID, k.key, nil
+}
+
+// metadataEndpoint is the OCI instance metadata service.
+const metadataEndpoint = "http://169.254.169.254/opc/v2"
+
+// instancePrincipal is a signer using the identity of the
+// OCI compute instance. It exchanges the instance certificate
+// for a short-lived security token, bound to an ephemeral
+// session key, and renews the token before it expires.
+type instancePrincipal struct {
+	client *client
+	region string
+
+	lock       sync.Mutex
+	token      string
+	expiry     time.Time
+	sessionKey *rsa.PrivateKey
+}
+
+func newInstancePrincipal(ctx context.Context, c *client) (*instancePrincipal, error) {
+	region, err := fetchMetadata(ctx, c, "instance/canonicalRegionName")
+	if err != nil {
+		return nil, fmt.Errorf("oci: failed to fetch instance region: %v", err)
+	}
+	p := &instancePrincipal{
+		client: c,
+		region: strings.TrimSpace(string(region)),
+	}
+	if err = p.refresh(ctx); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *instancePrincipal) SigningKey(ctx context.Context) (string, *rsa.PrivateKey, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	// Renew the token a bit before it expires to
+	// avoid requests failing due to clock skew.
+	if time.Until(p.expiry) < 5*time.Minute {
+		if err := p.refresh(ctx); err != nil {
+			return "", nil, err
+		}
+	}
+	return "ST$" + p.token, p.sessionKey, nil
+}
+
+// refresh obtains a new security token from the OCI
+// federation endpoint using the instance certificate.
+func (p *instancePrincipal) refresh(ctx context.Context) error {
+	type Request struct {
+		Certificate   string   `json:"certificate"`
+		PublicKey     string   `json:"publicKey"`
+		Intermediates []string `json:"intermediateCertificates"`
+	}
+	type Response struct {
+		Token string `json:"token"`
+	}
+
+	certPEM, err := fetchMetadata(ctx, p.client, "identity/cert.pem")
+	if err != nil {
+		return fmt.Errorf("oci: failed to fetch instance certificate: %v", err)
+	}
+	keyPEM, err := fetchMetadata(ctx, p.client, "identity/key.pem")
+	if err != nil {
+		return fmt.Errorf("oci: failed to fetch instance private key: %v", err)
+	}
+	intermediatePEM, err := fetchMetadata(ctx, p.client, "identity/intermediate.pem")
+	if err != nil {
+		return fmt.Errorf("oci: failed to fetch intermediate certificate: %v", err)
+	}
+
+	cert, err := parseCertificate(certPEM)
+	if err != nil {
+		return fmt.Errorf("oci: invalid instance certificate: %v", err)
+	}
+	intermediate, err := parseCertificate(intermediatePEM)
+	if err != nil {
+		return fmt.Errorf("oci: invalid intermediate certificate: %v", err)
+	}
+	instanceKey, err := parsePrivateKey(keyPEM)
+	if err != nil {
+		return fmt.Errorf("oci: invalid instance private key: %v", err)
+	}
+	tenancyID, err := tenancyOf(cert)
+	if err != nil {
+		return err
+	}
+
+	sessionKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return err
+	}
+	publicKey, err := x509.MarshalPKIXPublicKey(&sessionKey.PublicKey)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(Request{
+		Certificate:   base64.StdEncoding.EncodeToString(cert.Raw),
+		PublicKey:     base64.StdEncoding.EncodeToString(publicKey),
+		Intermediates: []string{base64.StdEncoding.EncodeToString(intermediate.Raw)},
+	})
+	if err != nil {
+		return err
+	}
+	url := "https://auth." + p.region + ".oraclecloud.com/v1/x509"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, xhttp.RetryReader(bytes.NewReader(body)))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = int64(len(body))
+	if err = signRequest(req, body, tenancyID+"/fed-x509/"+fingerprint(cert), instanceKey); err != nil {
+		return err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("oci: failed to obtain security token: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("oci: failed to obtain security token: %v", parseErrorResponse(resp))
+	}
+	var response Response
+	if err = decodeResponse(resp, &response); err != nil {
+		return fmt.Errorf("oci: failed to obtain security token: %v", err)
+	}
+	expiry, err := tokenExpiry(response.Token)
+	if err != nil {
+		return fmt.Errorf("oci: invalid security token: %v", err)
+	}
+
+	p.token = response.Token
+	p.expiry = expiry
+	p.sessionKey = sessionKey
+	return nil
+}
+
+// fetchMetadata fetches the given path from the OCI
+// instance metadata service.
+func fetchMetadata(ctx context.Context, c *client, path string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadataEndpoint+"/"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer Oracle")
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(resp.Status)
+	}
+	return io.ReadAll(mem.LimitReader(resp.Body, 1*mem.MiB))
+}
+
+// tenancyOf returns the tenancy OCID from the subject of
+// an OCI instance certificate.
+func tenancyOf(cert *x509.Certificate) (string, error) {
+	const Prefix = "opc-tenant:"
+
+	names := append(cert.Subject.OrganizationalUnit, cert.Subject.Organization...)
+	for _, name := range names {
+		if strings.HasPrefix(name, Prefix) {
+			return strings.TrimPrefix(name, Prefix), nil
+		}
+	}
+	return "", errors.New("oci: invalid instance certificate: no tenancy ID found")
+}
+
+// fingerprint returns the colon-separated SHA-1
+// fingerprint of the certificate.
+func fingerprint(cert *x509.Certificate) string {
+	sum := sha1.Sum(cert.Raw)
+
+	parts := make([]string, 0, len(sum))
+	for _, b := range sum {
+		parts = append(parts, strings.ToUpper(hex.EncodeToString([]byte{b})))
+	}
+	return strings.Join(parts, ":")
+}
+
+// tokenExpiry returns the expiry of the JWT security
+// token. It does not verify the token.
+func tokenExpiry(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, errors.New("malformed token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, err
+	}
+	var claims struct {
+		Expiry int64 `json:"exp"`
+	}
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}, err
+	}
+	if claims.Expiry == 0 {
+		return time.Time{}, errors.New("no expiry")
+	}
+	return time.Unix(claims.Expiry, 0), nil
+}
+
+func parseCertificate(b []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func parsePrivateKey(b []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("not a RSA private key")
+	}
+	return rsaKey, nil
+}
+
+// parseErrorResponse returns an error containing
+// the response status code and the OCI error code
+// and message, if present.
+func parseErrorResponse(resp *http.Response) error {
+	type Response struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	var response Response
+	if err := json.NewDecoder(mem.LimitReader(resp.Body, 1*mem.MiB)).Decode(&response); err != nil || response.Message == "" {
+		return errors.New(resp.Status)
+	}
+	return fmt.Errorf("%s (%s)", response.Message, response.Code)
+}
+
+func decodeResponse(resp *http.Response, v any) error {
+	const MaxSize = 10 * mem.MiB
+	limit := mem.Size(resp.ContentLength)
+	if limit < 0 || limit > MaxSize {
+		limit = MaxSize
+	}
+	return json.NewDecoder(mem.LimitReader(resp.Body, limit)).Decode(v)
+}
+
+// endpoint returns an endpoint URL starting with the
+// given endpoint followed by the path elements.
+//
+// The path elements will not be URL-escaped.
+func endpoint(endpoint string, elems ...string) string {
+	endpoint = strings.TrimSuffix(strings.TrimSpace(endpoint), "/")
+	if len(elems) == 0 {
+		return endpoint
+	}
+	return endpoint + "/" + strings.TrimPrefix(path.Join(elems...), "/")
+}
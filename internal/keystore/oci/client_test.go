@@ -0,0 +1,88 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package oci
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignRequest(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+
+	body := []byte(`{"secretName":"my-key"}`)
+	req, err := http.NewRequest(http.MethodPost, "https://vaults.us-ashburn-1.oci.oraclecloud.com/20180608/secrets?limit=1", nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	if err = signRequest(req, body, "tenancy/user/fingerprint", key); err != nil {
+		t.Fatalf("Failed to sign request: %v", err)
+	}
+
+	checksum := sha256.Sum256(body)
+	if h := req.Header.Get("X-Content-Sha256"); h != base64.StdEncoding.EncodeToString(checksum[:]) {
+		t.Fatalf("Invalid content checksum: got '%s'", h)
+	}
+
+	auth := req.Header.Get("Authorization")
+	const Headers = `headers="date (request-target) host content-length content-type x-content-sha256"`
+	if !strings.Contains(auth, Headers) {
+		t.Fatalf("Invalid signed headers: got '%s'", auth)
+	}
+	if !strings.Contains(auth, `keyId="tenancy/user/fingerprint"`) {
+		t.Fatalf("Invalid key ID: got '%s'", auth)
+	}
+
+	i := strings.Index(auth, `signature="`)
+	if i < 0 {
+		t.Fatalf("No signature found: got '%s'", auth)
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSuffix(auth[i+len(`signature="`):], `"`))
+	if err != nil {
+		t.Fatalf("Failed to decode signature: %v", err)
+	}
+	signingString := strings.Join([]string{
+		"date: " + req.Header.Get("Date"),
+		"(request-target): post /20180608/secrets?limit=1",
+		"host: vaults.us-ashburn-1.oci.oraclecloud.com",
+		"content-length: 23",
+		"content-type: application/json",
+		"x-content-sha256: " + req.Header.Get("X-Content-Sha256"),
+	}, "\n")
+	digest := sha256.Sum256([]byte(signingString))
+	if err = rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+		t.Fatalf("Invalid signature: %v", err)
+	}
+}
+
+func TestTokenExpiry(t *testing.T) {
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+	token := encode(`{"alg":"RS256"}`) + "." + encode(`{"exp":1700000000}`) + ".signature"
+	expiry, err := tokenExpiry(token)
+	if err != nil {
+		t.Fatalf("Failed to parse token: %v", err)
+	}
+	if want := time.Unix(1700000000, 0); !expiry.Equal(want) {
+		t.Fatalf("Expiry mismatch: got '%v' - want '%v'", expiry, want)
+	}
+
+	if _, err = tokenExpiry(encode(`{"alg":"RS256"}`) + "." + encode(`{}`) + ".signature"); err == nil {
+		t.Fatal("Parsing a token without expiry should have failed")
+	}
+	if _, err = tokenExpiry("not-a-token"); err == nil {
+		t.Fatal("Parsing a malformed token should have failed")
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package oci
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultProfile is the default profile of
+// an OCI configuration file.
+const DefaultProfile = "DEFAULT"
+
+// Profile is a profile of an OCI SDK and CLI
+// configuration file.
+type Profile struct {
+	Region string      // The OCI region identifier, e.g. us-ashburn-1
+	Login  Credentials // The API signing key credentials
+}
+
+// ReadConfigFile reads the OCI SDK and CLI configuration
+// file and returns the given profile. If filename is empty,
+// it reads ~/.oci/config. If profile is empty, it returns
+// the DEFAULT profile.
+//
+// Like the OCI SDK, it uses the entries of the DEFAULT
+// profile as defaults for all other profiles.
+//
+// Encrypted API signing keys, i.e. profiles with a
+// pass_phrase, are not supported.
+func ReadConfigFile(filename, profile string) (*Profile, error) {
+	if filename == "" {
+		filename = "~/.oci/config"
+	}
+	if profile == "" {
+		profile = DefaultProfile
+	}
+	filename, err := expandHome(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	sections := map[string]map[string]string{}
+	var section map[string]string
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			if _, ok := sections[name]; !ok {
+				sections[name] = map[string]string{}
+			}
+			section = sections[name]
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || section == nil {
+			return nil, fmt.Errorf("oci: invalid config file '%s': line %d: invalid entry", filename, n)
+		}
+		section[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	entries, ok := sections[profile]
+	if !ok {
+		return nil, fmt.Errorf("oci: invalid config file '%s': profile '%s' not found", filename, profile)
+	}
+	get := func(key string) string {
+		if v, ok := entries[key]; ok {
+			return v
+		}
+		return sections[DefaultProfile][key]
+	}
+	if get("pass_phrase") != "" {
+		return nil, errors.New("oci: encrypted API signing keys are not supported")
+	}
+
+	p := &Profile{
+		Region: get("region"),
+		Login: Credentials{
+			TenancyID:   get("tenancy"),
+			UserID:      get("user"),
+			Fingerprint: get("fingerprint"),
+			PrivateKey:  get("key_file"),
+		},
+	}
+	if p.Login.TenancyID == "" {
+		return nil, fmt.Errorf("oci: invalid config file '%s': profile '%s' has no tenancy", filename, profile)
+	}
+	if p.Login.UserID == "" {
+		return nil, fmt.Errorf("oci: invalid config file '%s': profile '%s' has no user", filename, profile)
+	}
+	if p.Login.Fingerprint == "" {
+		return nil, fmt.Errorf("oci: invalid config file '%s': profile '%s' has no fingerprint", filename, profile)
+	}
+	if p.Login.PrivateKey == "" {
+		return nil, fmt.Errorf("oci: invalid config file '%s': profile '%s' has no key_file", filename, profile)
+	}
+	if p.Login.PrivateKey, err = expandHome(p.Login.PrivateKey); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// expandHome replaces a leading ~ of path with
+// the current user's home directory.
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("oci: failed to expand '%s': %v", path, err)
+	}
+	return filepath.Join(home, path[1:]), nil
+}
@@ -0,0 +1,63 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package oci
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadConfigFile(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatalf("Failed to determine home directory: %v", err)
+	}
+
+	var readConfigFileTests = []struct {
+		Profile    string
+		Want       Profile
+		ShouldFail bool
+	}{
+		{ // 0
+			Profile: "",
+			Want: Profile{
+				Region: "us-ashburn-1",
+				Login: Credentials{
+					TenancyID:   "ocid1.tenancy.oc1..aaaaaaaaexample",
+					UserID:      "ocid1.user.oc1..aaaaaaaadefault",
+					Fingerprint: "12:34:56:78:90:ab:cd:ef:12:34:56:78:90:ab:cd:ef",
+					PrivateKey:  "/etc/kes/oci/default.pem",
+				},
+			},
+		},
+		{ // 1
+			Profile: "KES",
+			Want: Profile{
+				Region: "eu-frankfurt-1",
+				Login: Credentials{
+					TenancyID:   "ocid1.tenancy.oc1..aaaaaaaaexample",
+					UserID:      "ocid1.user.oc1..aaaaaaaakes",
+					Fingerprint: "12:34:56:78:90:ab:cd:ef:12:34:56:78:90:ab:cd:ef",
+					PrivateKey:  filepath.Join(home, ".oci/kes.pem"),
+				},
+			},
+		},
+		{Profile: "ENCRYPTED", ShouldFail: true}, // 2
+		{Profile: "MISSING", ShouldFail: true},   // 3
+	}
+	for i, test := range readConfigFileTests {
+		profile, err := ReadConfigFile("testdata/config", test.Profile)
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to read config file: %v", i, err)
+		}
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: reading config file should have failed", i)
+		}
+		if err == nil && *profile != test.Want {
+			t.Fatalf("Test %d: got profile '%+v' - want '%+v'", i, *profile, test.Want)
+		}
+	}
+}
@@ -0,0 +1,554 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package oci implements a key store that stores keys
+// as secrets on Oracle Cloud Infrastructure (OCI) Vault.
+package oci
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/minio/kes-go"
+	xhttp "github.com/minio/kes/internal/http"
+	"github.com/minio/kes/internal/keystore/batch"
+	"github.com/minio/kes/internal/keystore/cas"
+	"github.com/minio/kes/kv"
+)
+
+// Credentials are OCI API signing key credentials
+// of an OCI user.
+type Credentials struct {
+	TenancyID   string // The OCID of the tenancy
+	UserID      string // The OCID of the user
+	Fingerprint string // The fingerprint of the API signing key
+
+	// PrivateKey is the PEM-encoded RSA API signing key
+	// or a path to a file containing the PEM-encoded key.
+	PrivateKey string
+}
+
+// Config is a structure containing configuration
+// options for connecting to an OCI Vault.
+type Config struct {
+	// Region is the OCI region identifier, e.g.
+	// us-ashburn-1. When using instance principals,
+	// Region defaults to the region of the instance.
+	Region string
+
+	// CompartmentID is the OCID of the compartment
+	// that contains the vault and all secrets.
+	CompartmentID string
+
+	// VaultID is the OCID of the vault.
+	VaultID string
+
+	// KeyID is the OCID of the vault master encryption
+	// key used to encrypt the secrets.
+	KeyID string
+
+	// DeletionPeriod is the time secrets remain pending
+	// for deletion before OCI Vault deletes them. OCI
+	// Vault requires at least 1 day. If zero, the OCI
+	// default of 30 days is used.
+	DeletionPeriod time.Duration
+
+	// Login are the API signing key credentials. They
+	// are ignored if InstancePrincipal is true or a
+	// ConfigFile is specified.
+	Login Credentials
+
+	// ConfigFile is an optional path to an OCI SDK and
+	// CLI configuration file, e.g. ~/.oci/config. If not
+	// empty, the API signing key credentials and, if no
+	// Region is specified, the region are read from the
+	// configuration file Profile.
+	ConfigFile string
+
+	// Profile is the configuration file profile. If
+	// empty, DefaultProfile is used.
+	Profile string
+
+	// InstancePrincipal enables authentication via the
+	// OCI instance principal of the compute instance.
+	InstancePrincipal bool
+}
+
+// Store is an OCI Vault secret store.
+type Store struct {
+	config Config
+	client *client
+
+	locks cas.Locker
+}
+
+var _ kv.Store[string, []byte] = (*Store)(nil)
+
+// Connect returns a Store to an OCI Vault using the given config.
+func Connect(ctx context.Context, config *Config) (*Store, error) {
+	if config.CompartmentID == "" {
+		return nil, errors.New("oci: no compartment ID specified")
+	}
+	if config.VaultID == "" {
+		return nil, errors.New("oci: no vault ID specified")
+	}
+	if config.KeyID == "" {
+		return nil, errors.New("oci: no master encryption key ID specified")
+	}
+	if config.DeletionPeriod != 0 && config.DeletionPeriod < 24*time.Hour {
+		return nil, errors.New("oci: deletion period must be at least 1 day")
+	}
+
+	c := &client{
+		Retry: xhttp.Retry{
+			Client: http.Client{
+				Transport: &http.Transport{
+					Proxy: http.ProxyFromEnvironment,
+					DialContext: (&net.Dialer{
+						Timeout:   10 * time.Second,
+						KeepAlive: 10 * time.Second,
+					}).DialContext,
+					ForceAttemptHTTP2:     true,
+					MaxIdleConns:          100,
+					IdleConnTimeout:       30 * time.Second,
+					TLSHandshakeTimeout:   10 * time.Second,
+					ExpectContinueTimeout: 1 * time.Second,
+				},
+			},
+		},
+	}
+
+	config = config.clone()
+	if config.InstancePrincipal {
+		principal, err := newInstancePrincipal(ctx, c)
+		if err != nil {
+			return nil, err
+		}
+		if config.Region == "" {
+			config.Region = principal.region
+		}
+		c.Signer = principal
+	} else {
+		if config.ConfigFile != "" {
+			profile, err := ReadConfigFile(config.ConfigFile, config.Profile)
+			if err != nil {
+				return nil, err
+			}
+			if config.Region == "" {
+				config.Region = profile.Region
+			}
+			config.Login = profile.Login
+		}
+		if config.Region == "" {
+			return nil, errors.New("oci: no region specified")
+		}
+		key := config.Login.PrivateKey
+		if !strings.HasPrefix(strings.TrimSpace(key), "-----BEGIN") {
+			b, err := os.ReadFile(key)
+			if err != nil {
+				return nil, fmt.Errorf("oci: failed to read API signing key: %v", err)
+			}
+			key = string(b)
+		}
+		signer, err := newAPIKey(config.Login.TenancyID, config.Login.UserID, config.Login.Fingerprint, []byte(key))
+		if err != nil {
+			return nil, err
+		}
+		c.Signer = signer
+	}
+	return &Store{
+		config: *config,
+		client: c,
+	}, nil
+}
+
+// Status returns the current state of the OCI Vault.
+// In particular, whether it is reachable and the
+// network latency.
+func (s *Store) Status(ctx context.Context) (kv.State, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, s.vaultsEndpoint(), nil)
+	if err != nil {
+		return kv.State{}, err
+	}
+
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return kv.State{}, &kv.Unreachable{Err: err}
+	}
+	resp.Body.Close()
+	return kv.State{
+		Latency: time.Since(start),
+	}, nil
+}
+
+// Create creates the given key-value pair as OCI Vault
+// secret if and only if no secret with the given name
+// exists. If such a secret exists it returns
+// kes.ErrKeyExists.
+//
+// OCI Vault does not allow creating a secret while a
+// secret with the same name is pending deletion. In this
+// case, Create returns an error since the deleted secret
+// cannot be purged before its deletion period elapses.
+func (s *Store) Create(ctx context.Context, name string, value []byte) error {
+	type (
+		Content struct {
+			Type    string `json:"contentType"`
+			Content string `json:"content"`
+		}
+		Request struct {
+			CompartmentID string  `json:"compartmentId"`
+			VaultID       string  `json:"vaultId"`
+			KeyID         string  `json:"keyId"`
+			Name          string  `json:"secretName"`
+			Content       Content `json:"secretContent"`
+		}
+	)
+
+	secret, err := s.lookup(ctx, name)
+	if err != nil {
+		return fmt.Errorf("oci: failed to create '%s': %v", name, err)
+	}
+	if secret != nil {
+		if secret.State == statePendingDeletion {
+			return fmt.Errorf("oci: failed to create '%s': a secret with the same name is pending deletion until %s", name, secret.DeletionTime.Format(time.RFC3339))
+		}
+		return kes.ErrKeyExists
+	}
+
+	resp, err := s.client.Send(ctx, http.MethodPost, s.vaultsEndpoint("secrets"), Request{
+		CompartmentID: s.config.CompartmentID,
+		VaultID:       s.config.VaultID,
+		KeyID:         s.config.KeyID,
+		Name:          name,
+		Content: Content{
+			Type:    "BASE64",
+			Content: base64.StdEncoding.EncodeToString(value),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("oci: failed to create '%s': %v", name, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusConflict:
+		return kes.ErrKeyExists
+	default:
+		return fmt.Errorf("oci: failed to create '%s': %v", name, parseErrorResponse(resp))
+	}
+}
+
+// Set creates the given key-value pair as OCI Vault secret
+// if and only if no secret with the given name exists. If
+// such a secret exists it returns kes.ErrKeyExists.
+func (s *Store) Set(ctx context.Context, name string, value []byte) error {
+	return s.Create(ctx, name, value)
+}
+
+// Update replaces the value of the given key with newValue
+// if and only if its current value is equal to oldValue.
+//
+// OCI Vault does not support compare-and-swap. Hence, Update
+// adds a new current secret version while holding a lock for
+// the key. Updates of other KES servers may race.
+func (s *Store) Update(ctx context.Context, name string, oldValue, newValue []byte) error {
+	type (
+		Content struct {
+			Type    string `json:"contentType"`
+			Content string `json:"content"`
+			Stage   string `json:"stage"`
+		}
+		Request struct {
+			Content Content `json:"secretContent"`
+		}
+	)
+	return s.locks.Update(ctx, s, name, oldValue, newValue, func(ctx context.Context, name string, _, value []byte) error {
+		secret, err := s.lookup(ctx, name)
+		if err != nil {
+			return fmt.Errorf("oci: failed to update '%s': %v", name, err)
+		}
+		if secret == nil || secret.State == statePendingDeletion {
+			return kes.ErrKeyNotFound
+		}
+
+		resp, err := s.client.Send(ctx, http.MethodPut, s.vaultsEndpoint("secrets", secret.ID), Request{
+			Content: Content{
+				Type:    "BASE64",
+				Content: base64.StdEncoding.EncodeToString(value),
+				Stage:   "CURRENT",
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("oci: failed to update '%s': %v", name, err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("oci: failed to update '%s': %v", name, parseErrorResponse(resp))
+		}
+		return nil
+	})
+}
+
+// Get returns the value associated with the given key.
+// If no active secret for the given key exists it returns
+// kes.ErrKeyNotFound. Secrets pending deletion are treated
+// as deleted.
+func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
+	type Response struct {
+		Content struct {
+			Type    string `json:"contentType"`
+			Content string `json:"content"`
+		} `json:"secretBundleContent"`
+	}
+
+	secret, err := s.lookup(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("oci: failed to get '%s': %v", name, err)
+	}
+	if secret == nil || secret.State == statePendingDeletion {
+		return nil, kes.ErrKeyNotFound
+	}
+
+	resp, err := s.client.Send(ctx, http.MethodGet, s.secretsEndpoint("secretbundles", secret.ID), nil)
+	if err != nil {
+		return nil, fmt.Errorf("oci: failed to get '%s': %v", name, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, kes.ErrKeyNotFound
+	default:
+		return nil, fmt.Errorf("oci: failed to get '%s': %v", name, parseErrorResponse(resp))
+	}
+
+	var response Response
+	if err = decodeResponse(resp, &response); err != nil {
+		return nil, fmt.Errorf("oci: failed to get '%s': %v", name, err)
+	}
+	if response.Content.Type != "BASE64" {
+		return nil, fmt.Errorf("oci: failed to get '%s': unsupported content type '%s'", name, response.Content.Type)
+	}
+	value, err := base64.StdEncoding.DecodeString(response.Content.Content)
+	if err != nil {
+		return nil, fmt.Errorf("oci: failed to get '%s': %v", name, err)
+	}
+	return value, nil
+}
+
+// GetMany returns the values associated with the given keys. OCI
+// Vault has no batch API for secret bundles. Hence, GetMany
+// fetches up to batch.Concurrency keys concurrently.
+func (s *Store) GetMany(ctx context.Context, names []string) ([][]byte, []error) {
+	return batch.Get[string, []byte](ctx, s, names)
+}
+
+// Delete schedules the deletion of the secret with the
+// given name. The secret remains pending for deletion for
+// the configured deletion period and can be recovered by
+// an OCI Vault administrator within this time.
+//
+// If no active secret with the given name exists, Delete
+// returns no error.
+func (s *Store) Delete(ctx context.Context, name string) error {
+	type Request struct {
+		DeletionTime *time.Time `json:"timeOfDeletion,omitempty"`
+	}
+
+	secret, err := s.lookup(ctx, name)
+	if err != nil {
+		return fmt.Errorf("oci: failed to delete '%s': %v", name, err)
+	}
+	if secret == nil || secret.State == statePendingDeletion {
+		return nil
+	}
+
+	var req Request
+	if s.config.DeletionPeriod > 0 {
+		t := time.Now().UTC().Add(s.config.DeletionPeriod)
+		req.DeletionTime = &t
+	}
+	resp, err := s.client.Send(ctx, http.MethodPost, s.vaultsEndpoint("secrets", secret.ID, "actions", "scheduleDeletion"), req)
+	if err != nil {
+		return fmt.Errorf("oci: failed to delete '%s': %v", name, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNotFound:
+		return nil
+	default:
+		return fmt.Errorf("oci: failed to delete '%s': %v", name, parseErrorResponse(resp))
+	}
+}
+
+// DeleteMany schedules the deletion of the given keys. It
+// deletes up to batch.Concurrency keys concurrently since OCI
+// Vault has no batch API for secrets.
+func (s *Store) DeleteMany(ctx context.Context, names []string) []error {
+	return batch.Delete[string, []byte](ctx, s, names)
+}
+
+// List returns a new Iterator over the names of all
+// active secrets that start with the given prefix.
+func (s *Store) List(ctx context.Context, prefix string) (kv.Iter[string], error) {
+	var cancel context.CancelCauseFunc
+	ctx, cancel = context.WithCancelCause(ctx)
+	values := make(chan string, 10)
+
+	go func() {
+		defer close(values)
+
+		var page string
+		for {
+			query := s.query()
+			query.Set("lifecycleState", stateActive)
+			query.Set("limit", "100")
+			if page != "" {
+				query.Set("page", page)
+			}
+
+			var secrets []secretSummary
+			var err error
+			secrets, page, err = s.listSecrets(ctx, query)
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				cancel(err)
+				break
+			}
+			if err != nil {
+				cancel(fmt.Errorf("oci: failed to list keys: %v", err))
+				break
+			}
+			for _, secret := range secrets {
+				if !strings.HasPrefix(secret.Name, prefix) {
+					continue
+				}
+				select {
+				case values <- secret.Name:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if page == "" {
+				break
+			}
+		}
+	}()
+	return &iter{
+		ch:     values,
+		ctx:    ctx,
+		cancel: cancel,
+	}, nil
+}
+
+// OCI Vault secret lifecycle states.
+const (
+	stateActive          = "ACTIVE"
+	statePendingDeletion = "PENDING_DELETION"
+	stateDeleted         = "DELETED"
+)
+
+type secretSummary struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"secretName"`
+	State        string    `json:"lifecycleState"`
+	DeletionTime time.Time `json:"timeOfDeletion"`
+}
+
+// lookup returns the summary of the secret with the given
+// name or nil if no such secret exists. Since OCI Vault
+// secret names are unique within a vault, there is at most
+// one secret that is not deleted.
+func (s *Store) lookup(ctx context.Context, name string) (*secretSummary, error) {
+	query := s.query()
+	query.Set("name", name)
+
+	secrets, _, err := s.listSecrets(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	for i := range secrets {
+		if secrets[i].Name == name && secrets[i].State != stateDeleted {
+			return &secrets[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// listSecrets returns a page of secret summaries matching
+// the given query and a token for the next page, if any.
+func (s *Store) listSecrets(ctx context.Context, query url.Values) ([]secretSummary, string, error) {
+	resp, err := s.client.Send(ctx, http.MethodGet, s.vaultsEndpoint("secrets")+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", parseErrorResponse(resp)
+	}
+	var secrets []secretSummary
+	if err = decodeResponse(resp, &secrets); err != nil {
+		return nil, "", err
+	}
+	return secrets, resp.Header.Get("opc-next-page"), nil
+}
+
+func (s *Store) query() url.Values {
+	query := url.Values{}
+	query.Set("compartmentId", s.config.CompartmentID)
+	query.Set("vaultId", s.config.VaultID)
+	return query
+}
+
+// vaultsEndpoint returns the URL of the OCI Vault
+// management API followed by the path elements.
+func (s *Store) vaultsEndpoint(elems ...string) string {
+	return endpoint("https://vaults."+s.config.Region+".oci.oraclecloud.com/20180608", elems...)
+}
+
+// secretsEndpoint returns the URL of the OCI Vault
+// secret retrieval API followed by the path elements.
+func (s *Store) secretsEndpoint(elems ...string) string {
+	return endpoint("https://secrets.vaults."+s.config.Region+".oci.oraclecloud.com/20190301", elems...)
+}
+
+func (c *Config) clone() *Config {
+	clone := *c
+	return &clone
+}
+
+type iter struct {
+	ch     <-chan string
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+}
+
+func (i *iter) Next() (string, bool) {
+	select {
+	case v, ok := <-i.ch:
+		return v, ok
+	case <-i.ctx.Done():
+		return "", false
+	}
+}
+
+func (i *iter) Close() error {
+	i.cancel(context.Canceled)
+	return context.Cause(i.ctx)
+}
@@ -0,0 +1,499 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package onepassword implements a key store that stores
+// keys as items of a 1Password vault using the 1Password
+// Connect server API.
+package onepassword
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+	xhttp "github.com/minio/kes/internal/http"
+	"github.com/minio/kes/internal/https"
+	"github.com/minio/kes/internal/keystore/batch"
+	"github.com/minio/kes/internal/keystore/cas"
+	"github.com/minio/kes/kv"
+)
+
+// DefaultTag is the default tag of 1Password
+// items created by the Store.
+const DefaultTag = "kes"
+
+// Config is a structure containing configuration
+// options for connecting to a 1Password Connect server.
+type Config struct {
+	// Endpoint is the 1Password Connect server URL.
+	Endpoint string
+
+	// Token is the 1Password Connect access token. The
+	// token must have read and write access to the vault.
+	Token string
+
+	// VaultID is the UUID of the 1Password vault
+	// that contains the keys.
+	VaultID string
+
+	// Tag is the tag of 1Password items created by the
+	// Store. Only items with this tag are considered keys.
+	// If empty, defaults to DefaultTag.
+	Tag string
+
+	// CAPath is an optional path to the root CA certificate(s)
+	// used to verify the TLS certificate of the 1Password
+	// Connect server. If empty, the host's root CA set is used.
+	CAPath string
+}
+
+// Store is a 1Password Connect secret store.
+//
+// Each key is stored as 1Password password item whose
+// title is the key name and whose password is the
+// base64-encoded value. 1Password does not enforce
+// unique item titles. Hence, keys should only be
+// created through KES.
+type Store struct {
+	config Config
+	client xhttp.Retry
+
+	locks cas.Locker
+}
+
+var _ kv.Store[string, []byte] = (*Store)(nil)
+
+// Connect returns a Store to a 1Password Connect server
+// using the given config.
+func Connect(ctx context.Context, config *Config) (*Store, error) {
+	if config.Endpoint == "" {
+		return nil, errors.New("onepassword: endpoint is empty")
+	}
+	if config.Token == "" {
+		return nil, errors.New("onepassword: access token is empty")
+	}
+	if config.VaultID == "" {
+		return nil, errors.New("onepassword: vault ID is empty")
+	}
+
+	var err error
+	tlsConfig := &tls.Config{}
+	if config.CAPath != "" {
+		tlsConfig.RootCAs, err = https.CertPoolFromFile(config.CAPath)
+		if err != nil {
+			return nil, fmt.Errorf("onepassword: failed to load CA certificate: %v", err)
+		}
+	}
+
+	c := *config
+	c.Endpoint = strings.TrimSuffix(c.Endpoint, "/")
+	if c.Tag == "" {
+		c.Tag = DefaultTag
+	}
+	s := &Store{
+		config: c,
+		client: xhttp.Retry{
+			Client: http.Client{
+				Transport: &http.Transport{
+					TLSClientConfig: tlsConfig,
+					Proxy:           http.ProxyFromEnvironment,
+					DialContext: (&net.Dialer{
+						Timeout:   10 * time.Second,
+						KeepAlive: 10 * time.Second,
+					}).DialContext,
+					ForceAttemptHTTP2:     true,
+					MaxIdleConns:          100,
+					IdleConnTimeout:       30 * time.Second,
+					TLSHandshakeTimeout:   10 * time.Second,
+					ExpectContinueTimeout: 1 * time.Second,
+				},
+			},
+		},
+	}
+
+	// Check that the token can access the vault.
+	resp, err := s.send(ctx, http.MethodGet, "/v1/vaults/"+url.PathEscape(c.VaultID), nil)
+	if err != nil {
+		return nil, fmt.Errorf("onepassword: failed to access vault '%s': %v", c.VaultID, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("onepassword: failed to access vault '%s': %s (%d)", c.VaultID, parseServerError(resp), resp.StatusCode)
+	}
+	return s, nil
+}
+
+// Status returns the current state of the 1Password Connect
+// server. In particular, whether it is reachable and the
+// network latency.
+func (s *Store) Status(ctx context.Context) (kv.State, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.config.Endpoint+"/heartbeat", nil)
+	if err != nil {
+		return kv.State{}, err
+	}
+
+	start := time.Now()
+	resp, err := s.client.Client.Do(req)
+	if err != nil {
+		return kv.State{}, &kv.Unreachable{Err: err}
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return kv.State{}, &kv.Unavailable{Err: fmt.Errorf("onepassword: connect server is not available: %s (%d)", resp.Status, resp.StatusCode)}
+	}
+	return kv.State{
+		Latency: time.Since(start),
+	}, nil
+}
+
+// Create creates the given key-value pair as 1Password
+// item if and only if no item for the given key exists.
+// If such an item already exists it returns kes.ErrKeyExists.
+func (s *Store) Create(ctx context.Context, name string, value []byte) error {
+	switch _, err := s.lookup(ctx, name); {
+	case err == nil:
+		return kes.ErrKeyExists
+	case !errors.Is(err, kes.ErrKeyNotFound):
+		return err
+	}
+
+	body, err := json.Marshal(item{
+		Vault:    vaultRef{ID: s.config.VaultID},
+		Title:    name,
+		Category: "PASSWORD",
+		Tags:     []string{s.config.Tag},
+		Fields: []field{{
+			ID:      "password",
+			Type:    "CONCEALED",
+			Purpose: "PASSWORD",
+			Label:   "password",
+			Value:   base64.StdEncoding.EncodeToString(value), // Values may not be valid UTF-8
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("onepassword: failed to create key '%s': %v", name, err)
+	}
+	resp, err := s.send(ctx, http.MethodPost, s.itemsPath(), body)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("onepassword: failed to create key '%s': %v", name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("onepassword: failed to create key '%s': %s (%d)", name, parseServerError(resp), resp.StatusCode)
+	}
+	return nil
+}
+
+// Set creates the given key-value pair as 1Password
+// item if and only if no item for the given key exists.
+// If such an item already exists it returns kes.ErrKeyExists.
+func (s *Store) Set(ctx context.Context, name string, value []byte) error {
+	return s.Create(ctx, name, value)
+}
+
+// Update replaces the value of the given key with newValue
+// if and only if its current value is equal to oldValue.
+//
+// 1Password items do not have a compare-and-swap primitive.
+// Hence, Update patches the password field of the item while
+// holding a lock for the key. Updates of other KES servers
+// may race.
+func (s *Store) Update(ctx context.Context, name string, oldValue, newValue []byte) error {
+	return s.locks.Update(ctx, s, name, oldValue, newValue, func(ctx context.Context, name string, _, value []byte) error {
+		type Operation struct {
+			Op    string `json:"op"`
+			Path  string `json:"path"`
+			Value string `json:"value"`
+		}
+
+		id, err := s.lookup(ctx, name)
+		if err != nil {
+			return err
+		}
+		body, err := json.Marshal([]Operation{{
+			Op:    "replace",
+			Path:  "/fields/password/value",
+			Value: base64.StdEncoding.EncodeToString(value),
+		}})
+		if err != nil {
+			return fmt.Errorf("onepassword: failed to update key '%s': %v", name, err)
+		}
+		resp, err := s.send(ctx, http.MethodPatch, s.itemsPath()+"/"+url.PathEscape(id), body)
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("onepassword: failed to update key '%s': %v", name, err)
+		}
+		defer resp.Body.Close()
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+			return nil
+		case http.StatusNotFound:
+			return kes.ErrKeyNotFound
+		default:
+			return fmt.Errorf("onepassword: failed to update key '%s': %s (%d)", name, parseServerError(resp), resp.StatusCode)
+		}
+	})
+}
+
+// Get returns the value associated with the given key.
+// If no entry for the key exists it returns kes.ErrKeyNotFound.
+func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
+	id, err := s.lookup(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.send(ctx, http.MethodGet, s.itemsPath()+"/"+url.PathEscape(id), nil)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("onepassword: failed to access key '%s': %v", name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, kes.ErrKeyNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("onepassword: failed to access key '%s': %s (%d)", name, parseServerError(resp), resp.StatusCode)
+	}
+
+	const MaxSize = 2 * mem.MiB
+	var response item
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(&response); err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("onepassword: failed to read key '%s': %v", name, err)
+	}
+	for _, f := range response.Fields {
+		if f.Purpose == "PASSWORD" {
+			value, err := base64.StdEncoding.DecodeString(f.Value)
+			if err != nil {
+				return nil, fmt.Errorf("onepassword: failed to read key '%s': invalid value: %v", name, err)
+			}
+			return value, nil
+		}
+	}
+	return nil, fmt.Errorf("onepassword: failed to read key '%s': item has no password field", name)
+}
+
+// GetMany returns the values associated with the given keys. It
+// sends up to batch.Concurrency requests concurrently since
+// 1Password Connect has no batch API for items.
+func (s *Store) GetMany(ctx context.Context, names []string) ([][]byte, []error) {
+	return batch.Get[string, []byte](ctx, s, names)
+}
+
+// Delete removes the 1Password item of the given key.
+// It returns kes.ErrKeyNotFound if no such item exists.
+func (s *Store) Delete(ctx context.Context, name string) error {
+	id, err := s.lookup(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.send(ctx, http.MethodDelete, s.itemsPath()+"/"+url.PathEscape(id), nil)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("onepassword: failed to delete key '%s': %v", name, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNoContent, http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return kes.ErrKeyNotFound
+	default:
+		return fmt.Errorf("onepassword: failed to delete key '%s': %s (%d)", name, parseServerError(resp), resp.StatusCode)
+	}
+}
+
+// DeleteMany deletes the given keys. 1Password Connect has no
+// batch API for items. Hence, DeleteMany sends up to
+// batch.Concurrency requests concurrently.
+func (s *Store) DeleteMany(ctx context.Context, names []string) []error {
+	return batch.Delete[string, []byte](ctx, s, names)
+}
+
+// List returns a new Iterator over the names of
+// all stored keys that start with the given prefix.
+//
+// It only lists items with the configured tag.
+func (s *Store) List(ctx context.Context, prefix string) (kv.Iter[string], error) {
+	items, err := s.list(ctx, "")
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("onepassword: failed to list keys: %v", err)
+	}
+
+	names := make([]string, 0, len(items))
+	for _, item := range items {
+		if strings.HasPrefix(item.Title, prefix) {
+			names = append(names, item.Title)
+		}
+	}
+	return &iter{names: names}, nil
+}
+
+// lookup returns the ID of the 1Password item of the
+// given key. It returns kes.ErrKeyNotFound if no such
+// item exists.
+func (s *Store) lookup(ctx context.Context, name string) (string, error) {
+	items, err := s.list(ctx, fmt.Sprintf("title eq %q", name))
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return "", err
+		}
+		return "", fmt.Errorf("onepassword: failed to access key '%s': %v", name, err)
+	}
+	for _, item := range items {
+		if item.Title == name {
+			return item.ID, nil
+		}
+	}
+	return "", kes.ErrKeyNotFound
+}
+
+// list returns all items with the configured tag that
+// match the filter. If filter is empty, all tagged items
+// are returned.
+func (s *Store) list(ctx context.Context, filter string) ([]item, error) {
+	path := s.itemsPath()
+	if filter != "" {
+		path += "?filter=" + url.QueryEscape(filter)
+	}
+	resp, err := s.send(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s (%d)", parseServerError(resp), resp.StatusCode)
+	}
+
+	const MaxBody = 32 * mem.MiB
+	var items []item
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxBody)).Decode(&items); err != nil {
+		return nil, err
+	}
+
+	tagged := items[:0]
+	for _, item := range items {
+		for _, tag := range item.Tags {
+			if tag == s.config.Tag {
+				tagged = append(tagged, item)
+				break
+			}
+		}
+	}
+	return tagged, nil
+}
+
+// send sends an authenticated request with the given
+// method and JSON body to the path of the 1Password
+// Connect server.
+func (s *Store) send(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = xhttp.RetryReader(bytes.NewReader(body))
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.config.Endpoint+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.config.Token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return s.client.Do(req)
+}
+
+func (s *Store) itemsPath() string {
+	return "/v1/vaults/" + url.PathEscape(s.config.VaultID) + "/items"
+}
+
+// item is a 1Password item.
+//
+// Ref: https://developer.1password.com/docs/connect/connect-api-reference#item-object
+type item struct {
+	ID       string   `json:"id,omitempty"`
+	Title    string   `json:"title"`
+	Vault    vaultRef `json:"vault"`
+	Category string   `json:"category,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Fields   []field  `json:"fields,omitempty"`
+}
+
+type vaultRef struct {
+	ID string `json:"id"`
+}
+
+type field struct {
+	ID      string `json:"id,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Purpose string `json:"purpose,omitempty"`
+	Label   string `json:"label,omitempty"`
+	Value   string `json:"value,omitempty"`
+}
+
+// parseServerError returns the error message of
+// a 1Password Connect error response.
+func parseServerError(resp *http.Response) string {
+	const MaxSize = 1 * mem.MiB
+
+	body, err := io.ReadAll(mem.LimitReader(resp.Body, MaxSize))
+	if err != nil || len(body) == 0 {
+		return http.StatusText(resp.StatusCode)
+	}
+	var response struct {
+		Message string `json:"message"`
+	}
+	if err = json.Unmarshal(body, &response); err == nil && response.Message != "" {
+		return response.Message
+	}
+	return strings.TrimSpace(string(body))
+}
+
+type iter struct {
+	names []string
+}
+
+func (i *iter) Next() (string, bool) {
+	if len(i.names) == 0 {
+		return "", false
+	}
+	name := i.names[0]
+	i.names = i.names[1:]
+	return name, true
+}
+
+func (*iter) Close() error { return nil }
@@ -0,0 +1,163 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package onepassword
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/minio/kes/internal/keystore/conformance"
+)
+
+func TestConformance(t *testing.T) {
+	const (
+		Token   = "my-token"
+		VaultID = "ytrfte14kw1uex5txaore1emkz"
+	)
+	server := httptest.NewServer(newConnectServer(Token, VaultID))
+	defer server.Close()
+
+	store, err := Connect(context.Background(), &Config{
+		Endpoint: server.URL,
+		Token:    Token,
+		VaultID:  VaultID,
+	})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+
+	report, err := conformance.Run(context.Background(), store)
+	if err != nil {
+		t.Fatalf("Failed to run conformance tests: %v", err)
+	}
+	for _, result := range report.Results {
+		if !result.Passed() {
+			t.Errorf("Test '%s' failed: %v", result.Name, result.Err)
+		}
+	}
+}
+
+func TestConnectUnauthorized(t *testing.T) {
+	server := httptest.NewServer(newConnectServer("my-token", "my-vault"))
+	defer server.Close()
+
+	_, err := Connect(context.Background(), &Config{
+		Endpoint: server.URL,
+		Token:    "invalid-token",
+		VaultID:  "my-vault",
+	})
+	if err == nil {
+		t.Fatal("Connecting with an invalid token succeeded")
+	}
+}
+
+// connectServer is a minimal in-memory 1Password Connect server.
+type connectServer struct {
+	token, vault string
+
+	lock  sync.Mutex
+	items map[string]item
+	next  int
+}
+
+func newConnectServer(token, vault string) *connectServer {
+	return &connectServer{
+		token: token,
+		vault: vault,
+		items: map[string]item{},
+	}
+}
+
+func (s *connectServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/heartbeat" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer "+s.token {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]any{"status": 401, "message": "Invalid token signature"})
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	prefix := "/v1/vaults/" + s.vault
+	switch path := r.URL.Path; {
+	case path == prefix && r.Method == http.MethodGet:
+		json.NewEncoder(w).Encode(map[string]string{"id": s.vault})
+	case path == prefix+"/items" && r.Method == http.MethodGet:
+		var title string
+		if filter := r.URL.Query().Get("filter"); filter != "" {
+			title, _ = strconv.Unquote(strings.TrimPrefix(filter, "title eq "))
+		}
+		items := []item{}
+		for _, item := range s.items {
+			if title == "" || item.Title == title {
+				item.Fields = nil
+				items = append(items, item)
+			}
+		}
+		json.NewEncoder(w).Encode(items)
+	case path == prefix+"/items" && r.Method == http.MethodPost:
+		var item item
+		if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.next++
+		item.ID = strconv.Itoa(s.next)
+		s.items[item.ID] = item
+		json.NewEncoder(w).Encode(item)
+	case strings.HasPrefix(path, prefix+"/items/"):
+		id := strings.TrimPrefix(path, prefix+"/items/")
+		item, ok := s.items[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			json.NewEncoder(w).Encode(item)
+		case http.MethodPatch:
+			var ops []struct {
+				Op    string `json:"op"`
+				Path  string `json:"path"`
+				Value string `json:"value"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&ops); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			for _, op := range ops {
+				if op.Op != "replace" || !strings.HasPrefix(op.Path, "/fields/") || !strings.HasSuffix(op.Path, "/value") {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				fieldID := strings.TrimSuffix(strings.TrimPrefix(op.Path, "/fields/"), "/value")
+				for i := range item.Fields {
+					if item.Fields[i].ID == fieldID {
+						item.Fields[i].Value = op.Value
+					}
+				}
+			}
+			s.items[id] = item
+			json.NewEncoder(w).Encode(item)
+		case http.MethodDelete:
+			delete(s.items, id)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
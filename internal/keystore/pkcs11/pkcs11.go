@@ -0,0 +1,429 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package pkcs11 implements a key store that stores keys
+// as data objects on a PKCS #11 token, e.g. a hardware
+// security module (HSM).
+//
+// Each key is encrypted with an AES wrapping key, that
+// never leaves the token, before it is stored.
+//
+// The PKCS #11 module is loaded dynamically at runtime.
+// Hence, the package requires cgo and is not supported
+// on Windows.
+package pkcs11
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/keystore/batch"
+	"github.com/minio/kes/internal/keystore/cas"
+	"github.com/minio/kes/kv"
+)
+
+// Config is a structure containing configuration
+// options for connecting to a PKCS #11 token.
+type Config struct {
+	// Module is the path to the PKCS #11 module,
+	// i.e. the shared library provided by the HSM
+	// vendor, e.g. /usr/lib/softhsm/libsofthsm2.so.
+	Module string
+
+	// Slot is the ID of the slot containing the token.
+	// It is ignored if TokenLabel is not empty or
+	// AnyToken is true.
+	Slot uint
+
+	// TokenLabel is an optional label of the token.
+	// If not empty, the token is selected by its label
+	// instead of its slot ID.
+	TokenLabel string
+
+	// AnyToken selects the first slot containing a token.
+	// It is useful for modules that only provide a single
+	// token, e.g. AWS CloudHSM. It is ignored if TokenLabel
+	// is not empty.
+	AnyToken bool
+
+	// PIN is the user PIN used to log into the token.
+	PIN string
+
+	// WrappingKey is the label of the AES key on the
+	// token used to encrypt and decrypt keys.
+	WrappingKey string
+
+	// Application is the application of the data
+	// objects that contain keys. It is used to
+	// separate multiple KES deployments sharing the
+	// same token. If empty, defaults to "kes".
+	Application string
+
+	// Retry is the number of times an operation is retried
+	// after re-opening the session if the session has been
+	// closed, e.g. due to an HSM restart or a failover within
+	// an HSM cluster. If <= 0, an operation is retried once.
+	Retry int
+
+	// RetryDelay is the time to wait before re-opening the
+	// session again if a previous attempt has failed.
+	RetryDelay time.Duration
+}
+
+// Store is a PKCS #11 key store.
+type Store struct {
+	config Config
+
+	lock  sync.Mutex
+	token token
+
+	locks cas.Locker
+}
+
+var _ kv.Store[string, []byte] = (*Store)(nil)
+
+// Connect loads the PKCS #11 module, logs into the token
+// and returns a new Store.
+func Connect(ctx context.Context, config *Config) (*Store, error) {
+	if config.Module == "" {
+		return nil, errors.New("pkcs11: no module specified")
+	}
+	if config.WrappingKey == "" {
+		return nil, errors.New("pkcs11: no wrapping key specified")
+	}
+
+	c := *config
+	if c.Application == "" {
+		c.Application = "kes"
+	}
+	token, err := openToken(&c)
+	if err != nil {
+		return nil, err
+	}
+	s := &Store{
+		config: c,
+		token:  token,
+	}
+	if _, err = s.wrappingKey(); err != nil {
+		token.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Status returns the current state of the PKCS #11 token.
+// In particular, whether the session is still valid and
+// the latency of the token.
+func (s *Store) Status(context.Context) (kv.State, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	start := time.Now()
+	if err := s.retry(s.token.Ping); err != nil {
+		return kv.State{}, &kv.Unreachable{Err: err}
+	}
+	return kv.State{
+		Latency: time.Since(start),
+	}, nil
+}
+
+// Create encrypts the given value with the wrapping key and
+// stores it as data object on the token if and only if no
+// such object exists. If such an object already exists it
+// returns kes.ErrKeyExists.
+func (s *Store) Create(_ context.Context, name string, value []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.retry(func() error {
+		objects, err := s.token.FindObjects(s.dataTemplate(name))
+		if err != nil {
+			return fmt.Errorf("pkcs11: failed to create key '%s': %w", name, err)
+		}
+		if len(objects) > 0 {
+			return kes.ErrKeyExists
+		}
+
+		ciphertext, err := s.encrypt(name, value)
+		if err != nil {
+			return err
+		}
+
+		template := append(s.dataTemplate(name),
+			attribute{Type: attrToken, Value: true},
+			attribute{Type: attrPrivate, Value: true},
+			attribute{Type: attrValue, Value: ciphertext},
+		)
+		if _, err = s.token.CreateObject(template); err != nil {
+			return fmt.Errorf("pkcs11: failed to create key '%s': %w", name, err)
+		}
+		return nil
+	})
+}
+
+// Set encrypts the given value with the wrapping key and
+// stores it as data object on the token if and only if no
+// such object exists. If such an object already exists it
+// returns kes.ErrKeyExists.
+func (s *Store) Set(ctx context.Context, name string, value []byte) error {
+	return s.Create(ctx, name, value)
+}
+
+// Update replaces the value of the given key with newValue
+// if and only if its current value is equal to oldValue.
+//
+// Update encrypts newValue with the wrapping key and replaces
+// the value of the data object while holding a lock for the
+// key. Updates of other KES servers sharing the token may race.
+func (s *Store) Update(ctx context.Context, name string, oldValue, newValue []byte) error {
+	return s.locks.Update(ctx, s, name, oldValue, newValue, func(_ context.Context, name string, _, value []byte) error {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		return s.retry(func() error {
+			objects, err := s.token.FindObjects(s.dataTemplate(name))
+			if err != nil {
+				return fmt.Errorf("pkcs11: failed to update key '%s': %w", name, err)
+			}
+			if len(objects) == 0 {
+				return kes.ErrKeyNotFound
+			}
+			if len(objects) > 1 {
+				return fmt.Errorf("pkcs11: failed to update key '%s': multiple data objects found", name)
+			}
+
+			ciphertext, err := s.encrypt(name, value)
+			if err != nil {
+				return err
+			}
+			if err = s.token.SetAttributes(objects[0], []attribute{{Type: attrValue, Value: ciphertext}}); err != nil {
+				return fmt.Errorf("pkcs11: failed to update key '%s': %w", name, err)
+			}
+			return nil
+		})
+	})
+}
+
+// Get returns the value associated with the given key.
+// If no entry for the key exists it returns kes.ErrKeyNotFound.
+func (s *Store) Get(_ context.Context, name string) ([]byte, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var value []byte
+	err := s.retry(func() error {
+		objects, err := s.token.FindObjects(s.dataTemplate(name))
+		if err != nil {
+			return fmt.Errorf("pkcs11: failed to access key '%s': %w", name, err)
+		}
+		if len(objects) == 0 {
+			return kes.ErrKeyNotFound
+		}
+		if len(objects) > 1 {
+			return fmt.Errorf("pkcs11: failed to access key '%s': multiple data objects found", name)
+		}
+
+		ciphertext, err := s.token.GetAttribute(objects[0], attrValue)
+		if err != nil {
+			return fmt.Errorf("pkcs11: failed to access key '%s': %w", name, err)
+		}
+		if len(ciphertext) < ivSize {
+			return fmt.Errorf("pkcs11: failed to access key '%s': invalid ciphertext", name)
+		}
+		key, err := s.wrappingKey()
+		if err != nil {
+			return err
+		}
+		value, err = s.token.DecryptGCM(key, ciphertext[:ivSize], []byte(name), ciphertext[ivSize:])
+		if err != nil {
+			return fmt.Errorf("pkcs11: failed to decrypt key '%s': %w", name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// GetMany returns the values associated with the given keys.
+// PKCS #11 has no batch operations for data objects. Hence,
+// GetMany fetches up to batch.Concurrency keys concurrently.
+func (s *Store) GetMany(ctx context.Context, names []string) ([][]byte, []error) {
+	return batch.Get[string, []byte](ctx, s, names)
+}
+
+// Delete removes a the value associated with the given key
+// from the token, if it exists.
+func (s *Store) Delete(_ context.Context, name string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.retry(func() error {
+		objects, err := s.token.FindObjects(s.dataTemplate(name))
+		if err != nil {
+			return fmt.Errorf("pkcs11: failed to delete key '%s': %w", name, err)
+		}
+		for _, object := range objects {
+			if err = s.token.DestroyObject(object); err != nil {
+				return fmt.Errorf("pkcs11: failed to delete key '%s': %w", name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteMany deletes the given keys. It deletes up to
+// batch.Concurrency keys concurrently since PKCS #11 has no
+// batch operations for data objects.
+func (s *Store) DeleteMany(ctx context.Context, names []string) []error {
+	return batch.Delete[string, []byte](ctx, s, names)
+}
+
+// List returns a new Iterator over the names of
+// all stored keys that start with the given prefix.
+func (s *Store) List(ctx context.Context, prefix string) (kv.Iter[string], error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var names []string
+	err := s.retry(func() error {
+		objects, err := s.token.FindObjects([]attribute{
+			{Type: attrClass, Value: classData},
+			{Type: attrApplication, Value: s.config.Application},
+		})
+		if err != nil {
+			return fmt.Errorf("pkcs11: failed to list keys: %w", err)
+		}
+
+		names = make([]string, 0, len(objects))
+		for _, object := range objects {
+			label, err := s.token.GetAttribute(object, attrLabel)
+			if err != nil {
+				return fmt.Errorf("pkcs11: failed to list keys: %w", err)
+			}
+			if name := string(label); strings.HasPrefix(name, prefix) {
+				names = append(names, name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &iter{
+		names: names,
+		ctx:   ctx,
+	}, nil
+}
+
+// Close logs out of the token and unloads the
+// PKCS #11 module.
+func (s *Store) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.token.Close()
+}
+
+// ivSize is the size of the AES-GCM IV prepended
+// to every encrypted key.
+const ivSize = 12
+
+// encrypt encrypts the value of the named key with the
+// wrapping key and returns the IV and ciphertext.
+func (s *Store) encrypt(name string, value []byte) ([]byte, error) {
+	key, err := s.wrappingKey()
+	if err != nil {
+		return nil, err
+	}
+	var iv [ivSize]byte
+	if _, err = rand.Read(iv[:]); err != nil {
+		return nil, fmt.Errorf("pkcs11: failed to encrypt key '%s': %w", name, err)
+	}
+	ciphertext, err := s.token.EncryptGCM(key, iv[:], []byte(name), value)
+	if err != nil {
+		return nil, fmt.Errorf("pkcs11: failed to encrypt key '%s': %w", name, err)
+	}
+	return append(iv[:], ciphertext...), nil
+}
+
+// dataTemplate returns the attribute template of the
+// data object that contains the given key.
+func (s *Store) dataTemplate(name string) []attribute {
+	return []attribute{
+		{Type: attrClass, Value: classData},
+		{Type: attrApplication, Value: s.config.Application},
+		{Type: attrLabel, Value: name},
+	}
+}
+
+// wrappingKey returns the handle of the AES wrapping key.
+func (s *Store) wrappingKey() (objectHandle, error) {
+	objects, err := s.token.FindObjects([]attribute{
+		{Type: attrClass, Value: classSecretKey},
+		{Type: attrKeyType, Value: keyTypeAES},
+		{Type: attrLabel, Value: s.config.WrappingKey},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("pkcs11: failed to find wrapping key '%s': %w", s.config.WrappingKey, err)
+	}
+	if len(objects) == 0 {
+		return 0, fmt.Errorf("pkcs11: wrapping key '%s' not found", s.config.WrappingKey)
+	}
+	if len(objects) > 1 {
+		return 0, fmt.Errorf("pkcs11: multiple wrapping keys '%s' found", s.config.WrappingKey)
+	}
+	return objects[0], nil
+}
+
+// retry calls f and, if f fails because the session has
+// been closed, e.g. due to an HSM restart, re-opens the
+// session and calls f again - up to Config.Retry times.
+func (s *Store) retry(f func() error) error {
+	err := f()
+	if !errors.Is(err, errSessionClosed) {
+		return err
+	}
+
+	retry := s.config.Retry
+	if retry <= 0 {
+		retry = 1
+	}
+	for i := 0; i < retry; i++ {
+		if i > 0 && s.config.RetryDelay > 0 {
+			time.Sleep(s.config.RetryDelay)
+		}
+		if rerr := s.token.Reopen(); rerr != nil {
+			err = fmt.Errorf("pkcs11: failed to re-open session: %v", rerr)
+			continue
+		}
+		if err = f(); !errors.Is(err, errSessionClosed) {
+			return err
+		}
+	}
+	return err
+}
+
+type iter struct {
+	names []string
+	ctx   context.Context
+}
+
+func (i *iter) Next() (string, bool) {
+	if len(i.names) == 0 || i.ctx.Err() != nil {
+		return "", false
+	}
+	name := i.names[0]
+	i.names = i.names[1:]
+	return name, true
+}
+
+func (i *iter) Close() error { return i.ctx.Err() }
@@ -0,0 +1,375 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package pkcs11
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/kv"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+
+	if err := store.Create(ctx, "my-key", []byte("secret")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if err := store.Create(ctx, "my-key", []byte("secret")); !errors.Is(err, kes.ErrKeyExists) {
+		t.Fatalf("Creating an existing key succeeded: got '%v' - want '%v'", err, kes.ErrKeyExists)
+	}
+	if err := store.Create(ctx, "my-key-2", []byte("secret-2")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+
+	value, err := store.Get(ctx, "my-key")
+	if err != nil {
+		t.Fatalf("Failed to get key: %v", err)
+	}
+	if !bytes.Equal(value, []byte("secret")) {
+		t.Fatalf("Invalid key value: got '%s' - want '%s'", value, "secret")
+	}
+	if _, err = store.Get(ctx, "my-key-3"); !errors.Is(err, kes.ErrKeyNotFound) {
+		t.Fatalf("Getting a non-existing key succeeded: got '%v' - want '%v'", err, kes.ErrKeyNotFound)
+	}
+
+	iter, err := store.List(ctx, "")
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	var names []string
+	for name, ok := iter.Next(); ok; name, ok = iter.Next() {
+		names = append(names, name)
+	}
+	if err = iter.Close(); err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	sort.Strings(names)
+	if len(names) != 2 || names[0] != "my-key" || names[1] != "my-key-2" {
+		t.Fatalf("Invalid key listing: got '%v' - want '%v'", names, []string{"my-key", "my-key-2"})
+	}
+
+	if err = store.Delete(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to delete key: %v", err)
+	}
+	if _, err = store.Get(ctx, "my-key"); !errors.Is(err, kes.ErrKeyNotFound) {
+		t.Fatalf("Getting a deleted key succeeded: got '%v' - want '%v'", err, kes.ErrKeyNotFound)
+	}
+}
+
+func TestStoreEncryption(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	token := store.token.(*fakeToken)
+
+	if err := store.Create(ctx, "my-key", []byte("secret")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if err := store.Create(ctx, "my-key-2", []byte("secret-2")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+
+	// The key must not be stored in plaintext and a ciphertext
+	// must not be decryptable under a different key name.
+	var objects [2]*fakeObject
+	for _, object := range token.objects {
+		switch object.Find(attrLabel) {
+		case "my-key":
+			objects[0] = object
+		case "my-key-2":
+			objects[1] = object
+		}
+	}
+	if bytes.Contains(objects[0].Find(attrValue).([]byte), []byte("secret")) {
+		t.Fatal("Key is stored in plaintext")
+	}
+	objects[0].Set(attrValue, objects[1].Find(attrValue))
+	if _, err := store.Get(ctx, "my-key"); err == nil {
+		t.Fatal("Decrypting a key with a ciphertext of another key succeeded")
+	}
+}
+
+func TestStoreUpdate(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	token := store.token.(*fakeToken)
+
+	if err := store.Create(ctx, "my-key", []byte("secret")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	objects := len(token.objects)
+	if err := store.Update(ctx, "my-key", []byte("other"), []byte("secret-2")); !errors.Is(err, kv.ErrConflict) {
+		t.Fatalf("Updating with wrong old value succeeded: got '%v' - want '%v'", err, kv.ErrConflict)
+	}
+	if err := store.Update(ctx, "my-key-2", []byte("secret"), []byte("secret-2")); !errors.Is(err, kes.ErrKeyNotFound) {
+		t.Fatalf("Updating a non-existing key succeeded: got '%v' - want '%v'", err, kes.ErrKeyNotFound)
+	}
+	if err := store.Update(ctx, "my-key", []byte("secret"), []byte("secret-2")); err != nil {
+		t.Fatalf("Failed to update key: %v", err)
+	}
+	if len(token.objects) != objects {
+		t.Fatalf("Invalid number of objects: got %d - want %d", len(token.objects), objects)
+	}
+
+	value, err := store.Get(ctx, "my-key")
+	if err != nil {
+		t.Fatalf("Failed to get key: %v", err)
+	}
+	if !bytes.Equal(value, []byte("secret-2")) {
+		t.Fatalf("Invalid key value: got '%s' - want '%s'", value, "secret-2")
+	}
+}
+
+func TestStoreReopen(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	token := store.token.(*fakeToken)
+
+	if err := store.Create(ctx, "my-key", []byte("secret")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+
+	token.closed = true
+	if _, err := store.Get(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to get key after session has been closed: %v", err)
+	}
+	if token.reopened != 1 {
+		t.Fatalf("Session has not been re-opened: got %d - want %d", token.reopened, 1)
+	}
+
+	token.closed = true
+	if _, err := store.Status(ctx); err != nil {
+		t.Fatalf("Failed to get status after session has been closed: %v", err)
+	}
+	if token.reopened != 2 {
+		t.Fatalf("Session has not been re-opened: got %d - want %d", token.reopened, 2)
+	}
+}
+
+func TestStoreRetry(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	token := store.token.(*fakeToken)
+
+	if err := store.Create(ctx, "my-key", []byte("secret")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+
+	token.closed, token.unavail = true, 2
+	if _, err := store.Get(ctx, "my-key"); err == nil {
+		t.Fatal("Get should have failed since the session cannot be re-opened")
+	}
+
+	store.config.Retry = 3
+	token.closed, token.unavail = true, 2
+	if _, err := store.Get(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to get key after session has been re-opened: %v", err)
+	}
+}
+
+func TestStoreGeneratedIV(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	store.token.(*fakeToken).generateIV = true
+
+	if err := store.Create(ctx, "my-key", []byte("secret")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	value, err := store.Get(ctx, "my-key")
+	if err != nil {
+		t.Fatalf("Failed to get key: %v", err)
+	}
+	if string(value) != "secret" {
+		t.Fatalf("Invalid key value: got '%s' - want '%s'", value, "secret")
+	}
+}
+
+func newTestStore(t *testing.T) *Store {
+	const WrappingKey = "kes-wrapping-key"
+
+	block, err := aes.NewCipher(make([]byte, 32))
+	if err != nil {
+		t.Fatalf("Failed to create AES cipher: %v", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatalf("Failed to create AES-GCM: %v", err)
+	}
+
+	token := &fakeToken{aead: aead}
+	token.CreateObject([]attribute{
+		{Type: attrClass, Value: classSecretKey},
+		{Type: attrKeyType, Value: keyTypeAES},
+		{Type: attrLabel, Value: WrappingKey},
+	})
+	s := &Store{
+		config: Config{WrappingKey: WrappingKey, Application: "kes"},
+		token:  token,
+	}
+	if _, err = s.wrappingKey(); err != nil {
+		t.Fatalf("Failed to find wrapping key: %v", err)
+	}
+	return s
+}
+
+// fakeToken is an in-memory token that has a single
+// AES wrapping key.
+type fakeToken struct {
+	aead    cipher.AEAD
+	objects map[objectHandle]*fakeObject
+	next    objectHandle
+
+	closed     bool // Whether the session has been closed
+	reopened   int  // Number of times the session has been re-opened
+	unavail    int  // Number of times re-opening the session fails
+	generateIV bool // Whether the token generates the IV, like AWS CloudHSM
+}
+
+type fakeObject []attribute
+
+func (o fakeObject) Find(typ attributeType) any {
+	for _, a := range o {
+		if a.Type == typ {
+			return a.Value
+		}
+	}
+	return nil
+}
+
+func (o fakeObject) Set(typ attributeType, value any) {
+	for i := range o {
+		if o[i].Type == typ {
+			o[i].Value = value
+		}
+	}
+}
+
+func (o fakeObject) Matches(template []attribute) bool {
+	for _, a := range template {
+		v := o.Find(a.Type)
+		if b, ok := v.([]byte); ok {
+			v = string(b)
+		}
+		if v != a.Value {
+			return false
+		}
+	}
+	return true
+}
+
+var _ token = (*fakeToken)(nil)
+
+func (t *fakeToken) FindObjects(template []attribute) ([]objectHandle, error) {
+	if t.closed {
+		return nil, errSessionClosed
+	}
+	var handles []objectHandle
+	for handle, object := range t.objects {
+		if object.Matches(template) {
+			handles = append(handles, handle)
+		}
+	}
+	return handles, nil
+}
+
+func (t *fakeToken) CreateObject(template []attribute) (objectHandle, error) {
+	if t.closed {
+		return 0, errSessionClosed
+	}
+	if t.objects == nil {
+		t.objects = map[objectHandle]*fakeObject{}
+	}
+	object := append(fakeObject{}, template...)
+
+	t.next++
+	t.objects[t.next] = &object
+	return t.next, nil
+}
+
+func (t *fakeToken) DestroyObject(object objectHandle) error {
+	if t.closed {
+		return errSessionClosed
+	}
+	delete(t.objects, object)
+	return nil
+}
+
+func (t *fakeToken) GetAttribute(object objectHandle, typ attributeType) ([]byte, error) {
+	if t.closed {
+		return nil, errSessionClosed
+	}
+	o, ok := t.objects[object]
+	if !ok {
+		return nil, errors.New("pkcs11: CKR_OBJECT_HANDLE_INVALID")
+	}
+	switch v := o.Find(typ).(type) {
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	default:
+		return nil, errors.New("pkcs11: CKR_ATTRIBUTE_TYPE_INVALID")
+	}
+}
+
+func (t *fakeToken) SetAttributes(object objectHandle, template []attribute) error {
+	if t.closed {
+		return errSessionClosed
+	}
+	o, ok := t.objects[object]
+	if !ok {
+		return errors.New("pkcs11: CKR_OBJECT_HANDLE_INVALID")
+	}
+	for _, a := range template {
+		o.Set(a.Type, a.Value)
+	}
+	return nil
+}
+
+func (t *fakeToken) EncryptGCM(_ objectHandle, iv, associatedData, plaintext []byte) ([]byte, error) {
+	if t.closed {
+		return nil, errSessionClosed
+	}
+	if t.generateIV {
+		if _, err := rand.Read(iv); err != nil {
+			return nil, err
+		}
+	}
+	return t.aead.Seal(nil, iv, plaintext, associatedData), nil
+}
+
+func (t *fakeToken) DecryptGCM(_ objectHandle, iv, associatedData, ciphertext []byte) ([]byte, error) {
+	if t.closed {
+		return nil, errSessionClosed
+	}
+	return t.aead.Open(nil, iv, ciphertext, associatedData)
+}
+
+func (t *fakeToken) Ping() error {
+	if t.closed {
+		return errSessionClosed
+	}
+	return nil
+}
+
+func (t *fakeToken) Reopen() error {
+	if t.unavail > 0 {
+		t.unavail--
+		return errors.New("pkcs11: token not available")
+	}
+	t.closed = false
+	t.reopened++
+	return nil
+}
+
+func (t *fakeToken) Close() error { return nil }
@@ -0,0 +1,156 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package pkcs11
+
+import (
+	"errors"
+	"fmt"
+)
+
+// token is a session to a PKCS #11 token. Its methods
+// must not be called concurrently.
+type token interface {
+	// FindObjects returns all objects matching the
+	// given attribute template.
+	FindObjects(template []attribute) ([]objectHandle, error)
+
+	// CreateObject creates a new object with the
+	// given attributes.
+	CreateObject(template []attribute) (objectHandle, error)
+
+	// DestroyObject destroys the given object.
+	DestroyObject(object objectHandle) error
+
+	// GetAttribute returns the value of the
+	// object's attribute.
+	GetAttribute(object objectHandle, typ attributeType) ([]byte, error)
+
+	// SetAttributes modifies the given attributes
+	// of the object.
+	SetAttributes(object objectHandle, template []attribute) error
+
+	// EncryptGCM encrypts the plaintext with the given
+	// AES key using AES-GCM and returns the ciphertext
+	// with the authentication tag appended.
+	//
+	// Some tokens, e.g. AWS CloudHSM, generate the IV
+	// themselves. Such tokens overwrite iv with the IV
+	// used for encryption.
+	EncryptGCM(key objectHandle, iv, associatedData, plaintext []byte) ([]byte, error)
+
+	// DecryptGCM decrypts the ciphertext, that contains
+	// the authentication tag, with the given AES key
+	// using AES-GCM.
+	DecryptGCM(key objectHandle, iv, associatedData, ciphertext []byte) ([]byte, error)
+
+	// Ping checks whether the session is still valid.
+	Ping() error
+
+	// Reopen opens a new session and logs in again.
+	Reopen() error
+
+	// Close closes the session and unloads the module.
+	Close() error
+}
+
+type (
+	objectHandle  uint
+	attributeType uint
+)
+
+// attribute is a PKCS #11 object attribute. Its value
+// is either a bool, an uint, a string or a []byte.
+type attribute struct {
+	Type  attributeType
+	Value any
+}
+
+// PKCS #11 object attributes.
+const (
+	attrClass       attributeType = 0x0000 // CKA_CLASS
+	attrToken       attributeType = 0x0001 // CKA_TOKEN
+	attrPrivate     attributeType = 0x0002 // CKA_PRIVATE
+	attrLabel       attributeType = 0x0003 // CKA_LABEL
+	attrApplication attributeType = 0x0010 // CKA_APPLICATION
+	attrValue       attributeType = 0x0011 // CKA_VALUE
+	attrKeyType     attributeType = 0x0100 // CKA_KEY_TYPE
+)
+
+// PKCS #11 object classes and key types.
+const (
+	classData      uint = 0x0000 // CKO_DATA
+	classSecretKey uint = 0x0004 // CKO_SECRET_KEY
+	keyTypeAES     uint = 0x001F // CKK_AES
+)
+
+// errSessionClosed is returned by a token if its session
+// is no longer valid, e.g. due to an HSM restart.
+var errSessionClosed = errors.New("pkcs11: session closed")
+
+// returnValue is a PKCS #11 function return value (CK_RV).
+type returnValue uint
+
+// PKCS #11 return values.
+const (
+	rvOK                         returnValue = 0x0000 // CKR_OK
+	rvDeviceError                returnValue = 0x0030 // CKR_DEVICE_ERROR
+	rvDeviceRemoved              returnValue = 0x0032 // CKR_DEVICE_REMOVED
+	rvSessionClosed              returnValue = 0x00B0 // CKR_SESSION_CLOSED
+	rvSessionHandleInvalid       returnValue = 0x00B3 // CKR_SESSION_HANDLE_INVALID
+	rvTokenNotPresent            returnValue = 0x00E0 // CKR_TOKEN_NOT_PRESENT
+	rvUserAlreadyLoggedIn        returnValue = 0x0100 // CKR_USER_ALREADY_LOGGED_IN
+	rvUserNotLoggedIn            returnValue = 0x0101 // CKR_USER_NOT_LOGGED_IN
+	rvCryptokiAlreadyInitialized returnValue = 0x0191 // CKR_CRYPTOKI_ALREADY_INITIALIZED
+)
+
+var returnValueNames = map[returnValue]string{
+	0x0005: "CKR_GENERAL_ERROR",
+	0x0006: "CKR_FUNCTION_FAILED",
+	0x0007: "CKR_ARGUMENTS_BAD",
+	0x0012: "CKR_ATTRIBUTE_TYPE_INVALID",
+	0x0013: "CKR_ATTRIBUTE_VALUE_INVALID",
+	0x0030: "CKR_DEVICE_ERROR",
+	0x0031: "CKR_DEVICE_MEMORY",
+	0x0032: "CKR_DEVICE_REMOVED",
+	0x0040: "CKR_ENCRYPTED_DATA_INVALID",
+	0x0060: "CKR_KEY_HANDLE_INVALID",
+	0x0068: "CKR_KEY_FUNCTION_NOT_PERMITTED",
+	0x0070: "CKR_MECHANISM_INVALID",
+	0x0071: "CKR_MECHANISM_PARAM_INVALID",
+	0x0082: "CKR_OBJECT_HANDLE_INVALID",
+	0x00A0: "CKR_PIN_INCORRECT",
+	0x00A4: "CKR_PIN_LOCKED",
+	0x00B0: "CKR_SESSION_CLOSED",
+	0x00B3: "CKR_SESSION_HANDLE_INVALID",
+	0x00B5: "CKR_SESSION_READ_ONLY",
+	0x00C0: "CKR_SIGNATURE_INVALID",
+	0x00D1: "CKR_TEMPLATE_INCONSISTENT",
+	0x00E0: "CKR_TOKEN_NOT_PRESENT",
+	0x00E2: "CKR_TOKEN_WRITE_PROTECTED",
+	0x0101: "CKR_USER_NOT_LOGGED_IN",
+	0x0150: "CKR_BUFFER_TOO_SMALL",
+	0x0190: "CKR_CRYPTOKI_NOT_INITIALIZED",
+}
+
+// err returns the error for the return value. It returns
+// nil for CKR_OK and an error wrapping errSessionClosed
+// if the session is no longer valid.
+func (rv returnValue) err() error {
+	switch rv {
+	case rvOK:
+		return nil
+	case rvDeviceError, rvDeviceRemoved, rvSessionClosed, rvSessionHandleInvalid, rvTokenNotPresent, rvUserNotLoggedIn:
+		return fmt.Errorf("%w: %s", errSessionClosed, rv)
+	default:
+		return errors.New("pkcs11: " + rv.String())
+	}
+}
+
+func (rv returnValue) String() string {
+	if name, ok := returnValueNames[rv]; ok {
+		return name
+	}
+	return fmt.Sprintf("CKR_0x%08X", uint(rv))
+}
@@ -0,0 +1,569 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+//go:build cgo && !windows
+
+package pkcs11
+
+/*
+#cgo linux LDFLAGS: -ldl
+
+#include <dlfcn.h>
+#include <stdlib.h>
+
+typedef unsigned char CK_BYTE;
+typedef unsigned long CK_ULONG;
+typedef CK_ULONG      CK_RV;
+typedef CK_ULONG      CK_SLOT_ID;
+typedef CK_ULONG      CK_SESSION_HANDLE;
+typedef CK_ULONG      CK_OBJECT_HANDLE;
+
+typedef struct {
+	CK_BYTE major;
+	CK_BYTE minor;
+} CK_VERSION;
+
+typedef struct {
+	CK_ULONG type;
+	void     *pValue;
+	CK_ULONG ulValueLen;
+} CK_ATTRIBUTE;
+
+typedef struct {
+	CK_ULONG mechanism;
+	void     *pParameter;
+	CK_ULONG ulParameterLen;
+} CK_MECHANISM;
+
+typedef struct {
+	CK_BYTE  *pIv;
+	CK_ULONG ulIvLen;
+	CK_ULONG ulIvBits;
+	CK_BYTE  *pAAD;
+	CK_ULONG ulAADLen;
+	CK_ULONG ulTagBits;
+} CK_GCM_PARAMS;
+
+typedef struct {
+	void     *CreateMutex;
+	void     *DestroyMutex;
+	void     *LockMutex;
+	void     *UnlockMutex;
+	CK_ULONG flags;
+	void     *pReserved;
+} CK_C_INITIALIZE_ARGS;
+
+typedef struct {
+	CK_SLOT_ID slotID;
+	CK_ULONG   state;
+	CK_ULONG   flags;
+	CK_ULONG   ulDeviceError;
+} CK_SESSION_INFO;
+
+// CK_FUNCTION_LIST as defined by PKCS #11 v2.40. Only the
+// functions used by KES are typed. The remaining entries
+// only preserve the layout.
+typedef struct {
+	CK_VERSION version;
+	CK_RV (*C_Initialize)(void *);
+	CK_RV (*C_Finalize)(void *);
+	void *C_GetInfo;
+	void *C_GetFunctionList;
+	CK_RV (*C_GetSlotList)(CK_BYTE, CK_SLOT_ID *, CK_ULONG *);
+	void *C_GetSlotInfo;
+	CK_RV (*C_GetTokenInfo)(CK_SLOT_ID, void *);
+	void *C_GetMechanismList;
+	void *C_GetMechanismInfo;
+	void *C_InitToken;
+	void *C_InitPIN;
+	void *C_SetPIN;
+	CK_RV (*C_OpenSession)(CK_SLOT_ID, CK_ULONG, void *, void *, CK_SESSION_HANDLE *);
+	CK_RV (*C_CloseSession)(CK_SESSION_HANDLE);
+	void *C_CloseAllSessions;
+	CK_RV (*C_GetSessionInfo)(CK_SESSION_HANDLE, CK_SESSION_INFO *);
+	void *C_GetOperationState;
+	void *C_SetOperationState;
+	CK_RV (*C_Login)(CK_SESSION_HANDLE, CK_ULONG, CK_BYTE *, CK_ULONG);
+	CK_RV (*C_Logout)(CK_SESSION_HANDLE);
+	CK_RV (*C_CreateObject)(CK_SESSION_HANDLE, CK_ATTRIBUTE *, CK_ULONG, CK_OBJECT_HANDLE *);
+	void *C_CopyObject;
+	CK_RV (*C_DestroyObject)(CK_SESSION_HANDLE, CK_OBJECT_HANDLE);
+	void *C_GetObjectSize;
+	CK_RV (*C_GetAttributeValue)(CK_SESSION_HANDLE, CK_OBJECT_HANDLE, CK_ATTRIBUTE *, CK_ULONG);
+	CK_RV (*C_SetAttributeValue)(CK_SESSION_HANDLE, CK_OBJECT_HANDLE, CK_ATTRIBUTE *, CK_ULONG);
+	CK_RV (*C_FindObjectsInit)(CK_SESSION_HANDLE, CK_ATTRIBUTE *, CK_ULONG);
+	CK_RV (*C_FindObjects)(CK_SESSION_HANDLE, CK_OBJECT_HANDLE *, CK_ULONG, CK_ULONG *);
+	CK_RV (*C_FindObjectsFinal)(CK_SESSION_HANDLE);
+	CK_RV (*C_EncryptInit)(CK_SESSION_HANDLE, CK_MECHANISM *, CK_OBJECT_HANDLE);
+	CK_RV (*C_Encrypt)(CK_SESSION_HANDLE, CK_BYTE *, CK_ULONG, CK_BYTE *, CK_ULONG *);
+	void *C_EncryptUpdate;
+	void *C_EncryptFinal;
+	CK_RV (*C_DecryptInit)(CK_SESSION_HANDLE, CK_MECHANISM *, CK_OBJECT_HANDLE);
+	CK_RV (*C_Decrypt)(CK_SESSION_HANDLE, CK_BYTE *, CK_ULONG, CK_BYTE *, CK_ULONG *);
+} CK_FUNCTION_LIST;
+
+static CK_RV get_function_list(void *sym, CK_FUNCTION_LIST **fl) {
+	return ((CK_RV (*)(CK_FUNCTION_LIST **))sym)(fl);
+}
+
+static CK_RV initialize(CK_FUNCTION_LIST *fl) {
+	CK_C_INITIALIZE_ARGS args = {0};
+	args.flags = 0x2; // CKF_OS_LOCKING_OK
+	return fl->C_Initialize(&args);
+}
+
+static CK_RV finalize(CK_FUNCTION_LIST *fl) { return fl->C_Finalize(NULL); }
+
+static CK_RV get_slot_list(CK_FUNCTION_LIST *fl, CK_SLOT_ID *slots, CK_ULONG *n) {
+	return fl->C_GetSlotList(1, slots, n);
+}
+
+static CK_RV get_token_info(CK_FUNCTION_LIST *fl, CK_SLOT_ID slot, void *info) {
+	return fl->C_GetTokenInfo(slot, info);
+}
+
+static CK_RV open_session(CK_FUNCTION_LIST *fl, CK_SLOT_ID slot, CK_SESSION_HANDLE *session) {
+	return fl->C_OpenSession(slot, 0x6, NULL, NULL, session); // CKF_SERIAL_SESSION | CKF_RW_SESSION
+}
+
+static CK_RV close_session(CK_FUNCTION_LIST *fl, CK_SESSION_HANDLE session) {
+	return fl->C_CloseSession(session);
+}
+
+static CK_RV get_session_info(CK_FUNCTION_LIST *fl, CK_SESSION_HANDLE session, CK_SESSION_INFO *info) {
+	return fl->C_GetSessionInfo(session, info);
+}
+
+static CK_RV login(CK_FUNCTION_LIST *fl, CK_SESSION_HANDLE session, CK_BYTE *pin, CK_ULONG pinLen) {
+	return fl->C_Login(session, 1, pin, pinLen); // CKU_USER
+}
+
+static CK_RV logout(CK_FUNCTION_LIST *fl, CK_SESSION_HANDLE session) {
+	return fl->C_Logout(session);
+}
+
+static CK_RV create_object(CK_FUNCTION_LIST *fl, CK_SESSION_HANDLE session, CK_ATTRIBUTE *attrs, CK_ULONG n, CK_OBJECT_HANDLE *object) {
+	return fl->C_CreateObject(session, attrs, n, object);
+}
+
+static CK_RV destroy_object(CK_FUNCTION_LIST *fl, CK_SESSION_HANDLE session, CK_OBJECT_HANDLE object) {
+	return fl->C_DestroyObject(session, object);
+}
+
+static CK_RV get_attribute_value(CK_FUNCTION_LIST *fl, CK_SESSION_HANDLE session, CK_OBJECT_HANDLE object, CK_ATTRIBUTE *attr) {
+	return fl->C_GetAttributeValue(session, object, attr, 1);
+}
+
+static CK_RV set_attribute_value(CK_FUNCTION_LIST *fl, CK_SESSION_HANDLE session, CK_OBJECT_HANDLE object, CK_ATTRIBUTE *attrs, CK_ULONG n) {
+	return fl->C_SetAttributeValue(session, object, attrs, n);
+}
+
+static CK_RV find_objects_init(CK_FUNCTION_LIST *fl, CK_SESSION_HANDLE session, CK_ATTRIBUTE *attrs, CK_ULONG n) {
+	return fl->C_FindObjectsInit(session, attrs, n);
+}
+
+static CK_RV find_objects(CK_FUNCTION_LIST *fl, CK_SESSION_HANDLE session, CK_OBJECT_HANDLE *objects, CK_ULONG max, CK_ULONG *n) {
+	return fl->C_FindObjects(session, objects, max, n);
+}
+
+static CK_RV find_objects_final(CK_FUNCTION_LIST *fl, CK_SESSION_HANDLE session) {
+	return fl->C_FindObjectsFinal(session);
+}
+
+static CK_RV encrypt(CK_FUNCTION_LIST *fl, CK_SESSION_HANDLE session, CK_MECHANISM *mech, CK_OBJECT_HANDLE key, CK_BYTE *in, CK_ULONG inLen, CK_BYTE *out, CK_ULONG *outLen) {
+	CK_RV rv = fl->C_EncryptInit(session, mech, key);
+	if (rv != 0) {
+		return rv;
+	}
+	return fl->C_Encrypt(session, in, inLen, out, outLen);
+}
+
+static CK_RV decrypt(CK_FUNCTION_LIST *fl, CK_SESSION_HANDLE session, CK_MECHANISM *mech, CK_OBJECT_HANDLE key, CK_BYTE *in, CK_ULONG inLen, CK_BYTE *out, CK_ULONG *outLen) {
+	CK_RV rv = fl->C_DecryptInit(session, mech, key);
+	if (rv != 0) {
+		return rv;
+	}
+	return fl->C_Decrypt(session, in, inLen, out, outLen);
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unsafe"
+)
+
+// openToken loads the PKCS #11 module, opens a session
+// to the token and logs in as user.
+func openToken(config *Config) (token, error) {
+	path := C.CString(config.Module)
+	defer C.free(unsafe.Pointer(path))
+
+	lib := C.dlopen(path, C.RTLD_NOW|C.RTLD_LOCAL)
+	if lib == nil {
+		return nil, fmt.Errorf("pkcs11: failed to load module '%s': %s", config.Module, C.GoString(C.dlerror()))
+	}
+	t := &cToken{
+		lib: lib,
+		pin: config.PIN,
+	}
+	if err := t.init(config); err != nil {
+		t.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+// cToken is a token implementation that calls
+// into a dynamically loaded PKCS #11 module.
+type cToken struct {
+	lib      unsafe.Pointer
+	fl       *C.CK_FUNCTION_LIST
+	finalize bool // Whether we initialized the module
+
+	slot     C.CK_SLOT_ID
+	session  C.CK_SESSION_HANDLE
+	loggedIn bool
+	pin      string
+}
+
+var _ token = (*cToken)(nil)
+
+func (t *cToken) init(config *Config) error {
+	name := C.CString("C_GetFunctionList")
+	defer C.free(unsafe.Pointer(name))
+
+	sym := C.dlsym(t.lib, name)
+	if sym == nil {
+		return fmt.Errorf("pkcs11: module '%s' is not a PKCS #11 module", config.Module)
+	}
+	var fl *C.CK_FUNCTION_LIST
+	if err := returnValue(C.get_function_list(sym, &fl)).err(); err != nil {
+		return fmt.Errorf("pkcs11: failed to load module '%s': %w", config.Module, err)
+	}
+	t.fl = fl
+
+	switch rv := returnValue(C.initialize(t.fl)); rv {
+	case rvOK:
+		t.finalize = true
+	case rvCryptokiAlreadyInitialized:
+	default:
+		return fmt.Errorf("pkcs11: failed to initialize module '%s': %w", config.Module, rv.err())
+	}
+
+	t.slot = C.CK_SLOT_ID(config.Slot)
+	if config.TokenLabel != "" || config.AnyToken {
+		slot, err := t.findSlot(config.TokenLabel)
+		if err != nil {
+			return err
+		}
+		t.slot = slot
+	}
+	return t.Reopen()
+}
+
+// findSlot returns the slot containing the token
+// with the given label. If label is empty, it returns
+// the first slot containing a token.
+func (t *cToken) findSlot(label string) (C.CK_SLOT_ID, error) {
+	var n C.CK_ULONG
+	if err := returnValue(C.get_slot_list(t.fl, nil, &n)).err(); err != nil {
+		return 0, fmt.Errorf("pkcs11: failed to list slots: %w", err)
+	}
+	if n == 0 {
+		return 0, errors.New("pkcs11: no token present")
+	}
+
+	var mem cMemory
+	defer mem.Free()
+
+	slots := unsafe.Slice((*C.CK_SLOT_ID)(mem.Alloc(int(n)*C.sizeof_CK_SLOT_ID)), int(n))
+	if err := returnValue(C.get_slot_list(t.fl, &slots[0], &n)).err(); err != nil {
+		return 0, fmt.Errorf("pkcs11: failed to list slots: %w", err)
+	}
+	if label == "" {
+		return slots[0], nil
+	}
+
+	// CK_TOKEN_INFO starts with the 32 byte label padded
+	// with blank characters. We don't care about the rest.
+	const TokenInfoSize, LabelSize = 512, 32
+	info := mem.Alloc(TokenInfoSize)
+	for _, slot := range slots[:n] {
+		if err := returnValue(C.get_token_info(t.fl, slot, info)).err(); err != nil {
+			return 0, fmt.Errorf("pkcs11: failed to read token info of slot '%d': %w", slot, err)
+		}
+		if strings.TrimRight(C.GoStringN((*C.char)(info), LabelSize), " \x00") == label {
+			return slot, nil
+		}
+	}
+	return 0, fmt.Errorf("pkcs11: token '%s' not found", label)
+}
+
+func (t *cToken) FindObjects(template []attribute) ([]objectHandle, error) {
+	var mem cMemory
+	defer mem.Free()
+
+	attrs, n := mem.Template(template)
+	if err := returnValue(C.find_objects_init(t.fl, t.session, attrs, n)).err(); err != nil {
+		return nil, err
+	}
+	defer C.find_objects_final(t.fl, t.session)
+
+	const BatchSize = 64
+	batch := unsafe.Slice((*C.CK_OBJECT_HANDLE)(mem.Alloc(BatchSize*C.sizeof_CK_OBJECT_HANDLE)), BatchSize)
+
+	var objects []objectHandle
+	for {
+		var count C.CK_ULONG
+		if err := returnValue(C.find_objects(t.fl, t.session, &batch[0], BatchSize, &count)).err(); err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			return objects, nil
+		}
+		for _, object := range batch[:count] {
+			objects = append(objects, objectHandle(object))
+		}
+	}
+}
+
+func (t *cToken) CreateObject(template []attribute) (objectHandle, error) {
+	var mem cMemory
+	defer mem.Free()
+
+	var object C.CK_OBJECT_HANDLE
+	attrs, n := mem.Template(template)
+	if err := returnValue(C.create_object(t.fl, t.session, attrs, n, &object)).err(); err != nil {
+		return 0, err
+	}
+	return objectHandle(object), nil
+}
+
+func (t *cToken) DestroyObject(object objectHandle) error {
+	return returnValue(C.destroy_object(t.fl, t.session, C.CK_OBJECT_HANDLE(object))).err()
+}
+
+func (t *cToken) GetAttribute(object objectHandle, typ attributeType) ([]byte, error) {
+	var mem cMemory
+	defer mem.Free()
+
+	attr := (*C.CK_ATTRIBUTE)(mem.Alloc(C.sizeof_CK_ATTRIBUTE))
+	attr._type = C.CK_ULONG(typ)
+	if err := returnValue(C.get_attribute_value(t.fl, t.session, C.CK_OBJECT_HANDLE(object), attr)).err(); err != nil {
+		return nil, err
+	}
+	if attr.ulValueLen == ^C.CK_ULONG(0) { // CK_UNAVAILABLE_INFORMATION
+		return nil, fmt.Errorf("pkcs11: attribute '0x%X' is not available", uint(typ))
+	}
+	if attr.ulValueLen == 0 {
+		return []byte{}, nil
+	}
+
+	attr.pValue = mem.Alloc(int(attr.ulValueLen))
+	if err := returnValue(C.get_attribute_value(t.fl, t.session, C.CK_OBJECT_HANDLE(object), attr)).err(); err != nil {
+		return nil, err
+	}
+	return C.GoBytes(attr.pValue, C.int(attr.ulValueLen)), nil
+}
+
+func (t *cToken) SetAttributes(object objectHandle, template []attribute) error {
+	var mem cMemory
+	defer mem.Free()
+
+	attrs, n := mem.Template(template)
+	return returnValue(C.set_attribute_value(t.fl, t.session, C.CK_OBJECT_HANDLE(object), attrs, n)).err()
+}
+
+func (t *cToken) EncryptGCM(key objectHandle, iv, associatedData, plaintext []byte) ([]byte, error) {
+	var mem cMemory
+	defer mem.Free()
+
+	var (
+		mechanism = mem.GCM(iv, associatedData)
+		in        = (*C.CK_BYTE)(mem.Bytes(plaintext))
+		outLen    = C.CK_ULONG(len(plaintext) + gcmTagSize)
+		out       = (*C.CK_BYTE)(mem.Alloc(int(outLen)))
+	)
+	rv := C.encrypt(t.fl, t.session, mechanism, C.CK_OBJECT_HANDLE(key), in, C.CK_ULONG(len(plaintext)), out, &outLen)
+	if err := returnValue(rv).err(); err != nil {
+		return nil, err
+	}
+
+	// Tokens that generate the IV themselves write it
+	// into the IV buffer of the GCM parameters.
+	params := (*C.CK_GCM_PARAMS)(mechanism.pParameter)
+	copy(iv, unsafe.Slice((*byte)(unsafe.Pointer(params.pIv)), len(iv)))
+	return C.GoBytes(unsafe.Pointer(out), C.int(outLen)), nil
+}
+
+func (t *cToken) DecryptGCM(key objectHandle, iv, associatedData, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < gcmTagSize {
+		return nil, errors.New("pkcs11: ciphertext is too short")
+	}
+
+	var mem cMemory
+	defer mem.Free()
+
+	var (
+		mechanism = mem.GCM(iv, associatedData)
+		in        = (*C.CK_BYTE)(mem.Bytes(ciphertext))
+		outLen    = C.CK_ULONG(len(ciphertext))
+		out       = (*C.CK_BYTE)(mem.Alloc(int(outLen)))
+	)
+	rv := C.decrypt(t.fl, t.session, mechanism, C.CK_OBJECT_HANDLE(key), in, C.CK_ULONG(len(ciphertext)), out, &outLen)
+	if err := returnValue(rv).err(); err != nil {
+		return nil, err
+	}
+	return C.GoBytes(unsafe.Pointer(out), C.int(outLen)), nil
+}
+
+func (t *cToken) Ping() error {
+	const StateRWUserFunctions = 3 // CKS_RW_USER_FUNCTIONS
+
+	var mem cMemory
+	defer mem.Free()
+
+	info := (*C.CK_SESSION_INFO)(mem.Alloc(C.sizeof_CK_SESSION_INFO))
+	if err := returnValue(C.get_session_info(t.fl, t.session, info)).err(); err != nil {
+		return err
+	}
+	if info.state != StateRWUserFunctions {
+		return fmt.Errorf("%w: not logged in", errSessionClosed)
+	}
+	return nil
+}
+
+func (t *cToken) Reopen() error {
+	if t.loggedIn {
+		C.close_session(t.fl, t.session)
+		t.loggedIn = false
+	}
+
+	var session C.CK_SESSION_HANDLE
+	if err := returnValue(C.open_session(t.fl, t.slot, &session)).err(); err != nil {
+		return fmt.Errorf("pkcs11: failed to open session: %w", err)
+	}
+
+	var mem cMemory
+	defer mem.Free()
+
+	pin := (*C.CK_BYTE)(mem.Bytes([]byte(t.pin)))
+	switch rv := returnValue(C.login(t.fl, session, pin, C.CK_ULONG(len(t.pin)))); rv {
+	case rvOK, rvUserAlreadyLoggedIn:
+	default:
+		C.close_session(t.fl, session)
+		return fmt.Errorf("pkcs11: failed to login: %w", rv.err())
+	}
+	t.session, t.loggedIn = session, true
+	return nil
+}
+
+func (t *cToken) Close() error {
+	var err error
+	if t.loggedIn {
+		C.logout(t.fl, t.session)
+		err = returnValue(C.close_session(t.fl, t.session)).err()
+		t.loggedIn = false
+	}
+	if t.finalize {
+		C.finalize(t.fl)
+		t.finalize = false
+	}
+	if t.lib != nil {
+		C.dlclose(t.lib)
+		t.lib = nil
+	}
+	return err
+}
+
+// gcmTagSize is the size of the AES-GCM authentication tag.
+const gcmTagSize = 16
+
+// cMemory keeps track of C memory passed to the PKCS #11
+// module. Go memory must not be passed since attribute
+// templates and mechanism parameters contain pointers.
+type cMemory []unsafe.Pointer
+
+// Alloc allocates n zeroed bytes of C memory.
+func (m *cMemory) Alloc(n int) unsafe.Pointer {
+	if n == 0 {
+		n = 1
+	}
+	p := C.calloc(1, C.size_t(n))
+	if p == nil {
+		panic("pkcs11: out of memory")
+	}
+	*m = append(*m, p)
+	return p
+}
+
+// Bytes copies b into C memory.
+func (m *cMemory) Bytes(b []byte) unsafe.Pointer {
+	p := m.Alloc(len(b))
+	if len(b) > 0 {
+		copy(unsafe.Slice((*byte)(p), len(b)), b)
+	}
+	return p
+}
+
+// Template converts the attributes into a CK_ATTRIBUTE array.
+func (m *cMemory) Template(template []attribute) (*C.CK_ATTRIBUTE, C.CK_ULONG) {
+	attrs := unsafe.Slice((*C.CK_ATTRIBUTE)(m.Alloc(len(template)*C.sizeof_CK_ATTRIBUTE)), len(template))
+	for i, a := range template {
+		attrs[i]._type = C.CK_ULONG(a.Type)
+		switch v := a.Value.(type) {
+		case bool:
+			p := (*C.CK_BYTE)(m.Alloc(1))
+			if v {
+				*p = 1
+			}
+			attrs[i].pValue, attrs[i].ulValueLen = unsafe.Pointer(p), 1
+		case uint:
+			p := (*C.CK_ULONG)(m.Alloc(C.sizeof_CK_ULONG))
+			*p = C.CK_ULONG(v)
+			attrs[i].pValue, attrs[i].ulValueLen = unsafe.Pointer(p), C.sizeof_CK_ULONG
+		case string:
+			attrs[i].pValue, attrs[i].ulValueLen = m.Bytes([]byte(v)), C.CK_ULONG(len(v))
+		case []byte:
+			attrs[i].pValue, attrs[i].ulValueLen = m.Bytes(v), C.CK_ULONG(len(v))
+		default:
+			panic(fmt.Sprintf("pkcs11: invalid attribute value type %T", v))
+		}
+	}
+	if len(attrs) == 0 {
+		return nil, 0
+	}
+	return &attrs[0], C.CK_ULONG(len(attrs))
+}
+
+// GCM returns a CKM_AES_GCM mechanism with the given
+// IV and associated data.
+func (m *cMemory) GCM(iv, associatedData []byte) *C.CK_MECHANISM {
+	const MechanismAESGCM = 0x1087 // CKM_AES_GCM
+
+	params := (*C.CK_GCM_PARAMS)(m.Alloc(C.sizeof_CK_GCM_PARAMS))
+	params.pIv = (*C.CK_BYTE)(m.Bytes(iv))
+	params.ulIvLen = C.CK_ULONG(len(iv))
+	params.ulIvBits = C.CK_ULONG(8 * len(iv))
+	params.pAAD = (*C.CK_BYTE)(m.Bytes(associatedData))
+	params.ulAADLen = C.CK_ULONG(len(associatedData))
+	params.ulTagBits = 8 * gcmTagSize
+
+	mechanism := (*C.CK_MECHANISM)(m.Alloc(C.sizeof_CK_MECHANISM))
+	mechanism.mechanism = MechanismAESGCM
+	mechanism.pParameter = unsafe.Pointer(params)
+	mechanism.ulParameterLen = C.sizeof_CK_GCM_PARAMS
+	return mechanism
+}
+
+// Free releases all allocated C memory.
+func (m *cMemory) Free() {
+	for _, p := range *m {
+		C.free(p)
+	}
+	*m = nil
+}
@@ -0,0 +1,13 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+//go:build !cgo || windows
+
+package pkcs11
+
+import "errors"
+
+func openToken(*Config) (token, error) {
+	return nil, errors.New("pkcs11: PKCS #11 requires cgo and is not supported on this platform")
+}
@@ -0,0 +1,178 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package keystore
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/key"
+	"github.com/minio/kes/internal/log"
+)
+
+// PoolConfig is a structure containing Pool
+// configuration options.
+type PoolConfig struct {
+	// Prefix is the name prefix of the pool's keys.
+	// Each key is named by the prefix followed by
+	// a random suffix.
+	Prefix string
+
+	// Size is the number of unclaimed keys the
+	// Pool tries to keep available.
+	Size int
+
+	// Algorithm is the encryption algorithm of
+	// the pool's keys.
+	Algorithm kes.KeyAlgorithm
+
+	// Owner is the identity that creates the
+	// pool's keys.
+	Owner kes.Identity
+}
+
+// NewPool returns a new Pool that creates keys
+// at the given Cache.
+//
+// The Pool does not create any keys until it is
+// started.
+func NewPool(keys *Cache, config *PoolConfig) *Pool {
+	return &Pool{
+		keys:   keys,
+		config: *config,
+		refill: make(chan struct{}, 1),
+	}
+}
+
+// A Pool pre-creates keys in the background such
+// that bursts of key creations can be served without
+// waiting for the kv.Store.
+//
+// Unclaimed keys are only tracked in memory. Keys
+// created by a Pool that have not been claimed when
+// the server stops remain at the kv.Store as regular
+// keys but are not handed out again.
+type Pool struct {
+	keys   *Cache
+	config PoolConfig
+
+	lock   sync.Mutex
+	names  []string
+	refill chan struct{}
+}
+
+// Prefix returns the name prefix of the Pool's keys.
+func (p *Pool) Prefix() string { return p.config.Prefix }
+
+// Len returns the number of unclaimed keys.
+func (p *Pool) Len() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return len(p.names)
+}
+
+// Start creates keys in the background until the
+// Pool contains the configured number of unclaimed
+// keys. Once keys get claimed, it creates new ones.
+//
+// Start returns immediately. The Pool stops creating
+// keys once the ctx.Done channel returns.
+func (p *Pool) Start(ctx context.Context) {
+	go p.fill(ctx)
+}
+
+// Claim hands out the name of an unclaimed key and
+// removes it from the Pool. Each key is handed out
+// at most once.
+//
+// If the Pool has no unclaimed keys, Claim creates
+// a new key.
+func (p *Pool) Claim(ctx context.Context) (string, error) {
+	p.lock.Lock()
+	var name string
+	if n := len(p.names); n > 0 {
+		name, p.names = p.names[n-1], p.names[:n-1]
+	}
+	p.lock.Unlock()
+
+	select {
+	case p.refill <- struct{}{}:
+	default:
+	}
+	if name != "" {
+		return name, nil
+	}
+	return p.create(ctx)
+}
+
+// fill creates keys until the Pool is full and waits
+// for keys being claimed. If a key cannot be created,
+// it retries after a short delay.
+func (p *Pool) fill(ctx context.Context) {
+	const RetryDelay = 5 * time.Second
+
+	for {
+		for p.Len() < p.config.Size {
+			name, err := p.create(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				log.Printf("keystore: failed to fill key pool '%s': %v", p.config.Prefix, err)
+
+				timer := time.NewTimer(RetryDelay)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				case <-timer.C:
+				}
+				continue
+			}
+
+			p.lock.Lock()
+			p.names = append(p.names, name)
+			p.lock.Unlock()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.refill:
+		}
+	}
+}
+
+// create creates a new key with a random name
+// suffix and returns its name.
+func (p *Pool) create(ctx context.Context) (string, error) {
+	const MaxAttempts = 3
+
+	var suffix [8]byte
+	for i := 0; ; i++ {
+		if _, err := rand.Read(suffix[:]); err != nil {
+			return "", err
+		}
+		k, err := key.Random(p.config.Algorithm, p.config.Owner)
+		if err != nil {
+			return "", err
+		}
+
+		name := p.config.Prefix + hex.EncodeToString(suffix[:])
+		err = p.keys.Create(ctx, name, k)
+		if err == nil {
+			return name, nil
+		}
+		if !errors.Is(err, kes.ErrKeyExists) || i+1 >= MaxAttempts {
+			return "", err
+		}
+	}
+}
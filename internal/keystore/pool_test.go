@@ -0,0 +1,73 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package keystore
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/keystore/mem"
+)
+
+func TestPool(t *testing.T) {
+	const (
+		Prefix = "pool-"
+		Size   = 4
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	keys := NewCache(ctx, &mem.Store{}, &CacheConfig{})
+	pool := NewPool(keys, &PoolConfig{
+		Prefix:    Prefix,
+		Size:      Size,
+		Algorithm: kes.AES256_GCM_SHA256,
+	})
+
+	// Claiming from an empty pool creates a key on demand.
+	name, err := pool.Claim(ctx)
+	if err != nil {
+		t.Fatalf("Failed to claim key from empty pool: %v", err)
+	}
+	if _, err = keys.Get(ctx, name); err != nil {
+		t.Fatalf("Claimed key '%s' does not exist: %v", name, err)
+	}
+
+	pool.Start(ctx)
+	waitForPool(t, pool, Size)
+
+	claimed := map[string]bool{name: true}
+	for i := 0; i < 2*Size; i++ {
+		name, err := pool.Claim(ctx)
+		if err != nil {
+			t.Fatalf("Failed to claim key: %v", err)
+		}
+		if !strings.HasPrefix(name, Prefix) {
+			t.Fatalf("Claimed key '%s' does not have prefix '%s'", name, Prefix)
+		}
+		if claimed[name] {
+			t.Fatalf("Key '%s' has been claimed twice", name)
+		}
+		claimed[name] = true
+		if _, err = keys.Get(ctx, name); err != nil {
+			t.Fatalf("Claimed key '%s' does not exist: %v", name, err)
+		}
+	}
+	waitForPool(t, pool, Size)
+}
+
+// waitForPool waits until the pool contains n unclaimed keys.
+func waitForPool(t *testing.T, pool *Pool, n int) {
+	deadline := time.Now().Add(5 * time.Second)
+	for pool.Len() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Pool has not been filled: got %d keys - want %d", pool.Len(), n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
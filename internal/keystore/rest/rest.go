@@ -0,0 +1,547 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package rest implements a key store that fetches/stores
+// cryptographic keys at a generic REST API. The requests
+// sent to the API are described by templates.
+package rest
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+	xhttp "github.com/minio/kes/internal/http"
+	"github.com/minio/kes/internal/https"
+	"github.com/minio/kes/internal/keystore/batch"
+	"github.com/minio/kes/kv"
+)
+
+// Request describes an HTTP request sent to the REST API.
+//
+// The Path and Body are text/template templates that
+// are executed with the key name as {{ .Name }} and,
+// for create requests, the base64-encoded key value
+// as {{ .Value }}. Besides the text/template builtin
+// functions, e.g. urlquery, the templates can use:
+//   - json:       encodes its argument as JSON string.
+//   - pathescape: escapes its argument as URL path segment.
+type Request struct {
+	// Method is the HTTP method of the request. If
+	// empty, defaults to POST for create, GET for get
+	// and list, and DELETE for delete requests.
+	Method string
+
+	// Path is the URL path template, optionally followed
+	// by a query, relative to the endpoint.
+	Path string
+
+	// Body is the optional request body template.
+	Body string
+}
+
+// Config is a structure containing configuration
+// options for connecting to a REST API.
+type Config struct {
+	// Endpoint is the REST API base URL.
+	Endpoint string
+
+	// Header contains HTTP headers that are sent with
+	// every request, e.g. an Authorization header.
+	Header map[string]string
+
+	// Create, Get, Delete and List describe the requests
+	// for creating, fetching, deleting and listing keys.
+	//
+	// The create request should fail with 409 Conflict if
+	// the key already exists. The get and delete requests
+	// must fail with 404 Not Found if the key does not exist.
+	Create Request
+	Get    Request
+	Delete Request
+	List   Request
+
+	// ValuePath is the dot-separated path of the key value
+	// within the JSON response of the get request, e.g.
+	// "data.value". If empty, the entire response body is
+	// considered the key value.
+	ValuePath string
+
+	// NamesPath is the dot-separated path of the JSON array
+	// of key names within the response of the list request,
+	// e.g. "names". If empty, the response body has to be
+	// a JSON array.
+	NamesPath string
+
+	// NameField is the field of a JSON object that contains
+	// the key name. It must be set if the list response
+	// contains an array of objects instead of strings.
+	NameField string
+
+	// CAPath is an optional path to the root CA certificate(s)
+	// used to verify the TLS certificate of the REST API. If
+	// empty, the host's root CA set is used.
+	CAPath string
+
+	// PrivateKey and Certificate are optional paths to a
+	// TLS private key and certificate for mTLS authentication
+	// to the REST API.
+	PrivateKey  string
+	Certificate string
+}
+
+// Store is a generic REST API secret store.
+//
+// Key values are sent to and received from the REST
+// API base64-encoded.
+type Store struct {
+	config Config
+	client xhttp.Retry
+
+	create, get, delete, list *request
+}
+
+var _ kv.Store[string, []byte] = (*Store)(nil)
+
+// Connect returns a Store to a REST API using the
+// given config.
+//
+// It does not send any request to the REST API. Hence,
+// a successful Connect does not imply that the API is
+// reachable or that the request templates are correct.
+func Connect(_ context.Context, config *Config) (*Store, error) {
+	if config.Endpoint == "" {
+		return nil, errors.New("rest: endpoint is empty")
+	}
+	if (config.PrivateKey == "") != (config.Certificate == "") {
+		return nil, errors.New("rest: TLS private key and certificate must be specified both")
+	}
+
+	create, err := parseRequest("create", http.MethodPost, config.Create)
+	if err != nil {
+		return nil, err
+	}
+	get, err := parseRequest("get", http.MethodGet, config.Get)
+	if err != nil {
+		return nil, err
+	}
+	del, err := parseRequest("delete", http.MethodDelete, config.Delete)
+	if err != nil {
+		return nil, err
+	}
+	list, err := parseRequest("list", http.MethodGet, config.List)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{}
+	if config.CAPath != "" {
+		tlsConfig.RootCAs, err = https.CertPoolFromFile(config.CAPath)
+		if err != nil {
+			return nil, fmt.Errorf("rest: failed to load CA certificate: %v", err)
+		}
+	}
+	if config.Certificate != "" {
+		tlsConfig.GetClientCertificate, err = https.ClientCertificate(config.Certificate, config.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("rest: failed to load TLS client certificate: %v", err)
+		}
+	}
+
+	c := *config
+	c.Endpoint = strings.TrimSuffix(c.Endpoint, "/")
+	return &Store{
+		config: c,
+		client: xhttp.Retry{
+			Client: http.Client{
+				Transport: &http.Transport{
+					TLSClientConfig: tlsConfig,
+					Proxy:           http.ProxyFromEnvironment,
+					DialContext: (&net.Dialer{
+						Timeout:   10 * time.Second,
+						KeepAlive: 10 * time.Second,
+					}).DialContext,
+					ForceAttemptHTTP2:     true,
+					MaxIdleConns:          100,
+					IdleConnTimeout:       30 * time.Second,
+					TLSHandshakeTimeout:   10 * time.Second,
+					ExpectContinueTimeout: 1 * time.Second,
+				},
+			},
+		},
+		create: create,
+		get:    get,
+		delete: del,
+		list:   list,
+	}, nil
+}
+
+// Status returns the current state of the REST API.
+// In particular, whether it is reachable and the
+// network latency.
+func (s *Store) Status(ctx context.Context) (kv.State, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.config.Endpoint, nil)
+	if err != nil {
+		return kv.State{}, err
+	}
+
+	start := time.Now()
+	resp, err := s.client.Client.Do(req)
+	if err != nil {
+		return kv.State{}, &kv.Unreachable{Err: err}
+	}
+	resp.Body.Close()
+
+	return kv.State{
+		Latency: time.Since(start),
+	}, nil
+}
+
+// Create creates the given key-value pair at the REST API
+// if and only if the given key does not exist. If such an
+// entry already exists it returns kes.ErrKeyExists.
+//
+// Since many REST APIs overwrite existing entries, Create
+// first checks whether the key exists.
+func (s *Store) Create(ctx context.Context, name string, value []byte) error {
+	switch _, err := s.Get(ctx, name); {
+	case err == nil:
+		return kes.ErrKeyExists
+	case !errors.Is(err, kes.ErrKeyNotFound):
+		return err
+	}
+
+	resp, err := s.send(ctx, s.create, name, base64.StdEncoding.EncodeToString(value))
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("rest: failed to create key '%s': %v", name, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusConflict:
+		return kes.ErrKeyExists
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return fmt.Errorf("rest: failed to create key '%s': %s (%d)", name, parseServerError(resp), resp.StatusCode)
+	}
+	return nil
+}
+
+// Set creates the given key-value pair at the REST API
+// if and only if the given key does not exist. If such
+// an entry already exists it returns kes.ErrKeyExists.
+func (s *Store) Set(ctx context.Context, name string, value []byte) error {
+	return s.Create(ctx, name, value)
+}
+
+// Update returns kv.ErrNotSupported. The REST API has no
+// request to replace an entry in place and deleting and
+// re-creating the entry may lose the key.
+func (s *Store) Update(context.Context, string, []byte, []byte) error {
+	return kv.ErrNotSupported
+}
+
+// Get returns the value associated with the given key.
+// If no entry for the key exists it returns kes.ErrKeyNotFound.
+func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
+	resp, err := s.send(ctx, s.get, name, "")
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("rest: failed to access key '%s': %v", name, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, kes.ErrKeyNotFound
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return nil, fmt.Errorf("rest: failed to access key '%s': %s (%d)", name, parseServerError(resp), resp.StatusCode)
+	}
+
+	const MaxSize = 2 * mem.MiB
+	body, err := io.ReadAll(mem.LimitReader(resp.Body, MaxSize))
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("rest: failed to read key '%s': %v", name, err)
+	}
+
+	encValue := strings.TrimSpace(string(body))
+	if s.config.ValuePath != "" {
+		v, err := lookup(body, s.config.ValuePath)
+		if err != nil {
+			return nil, fmt.Errorf("rest: failed to read key '%s': %v", name, err)
+		}
+		var ok bool
+		if encValue, ok = v.(string); !ok {
+			return nil, fmt.Errorf("rest: failed to read key '%s': '%s' is not a string", name, s.config.ValuePath)
+		}
+	}
+	value, err := base64.StdEncoding.DecodeString(encValue)
+	if err != nil {
+		return nil, fmt.Errorf("rest: failed to read key '%s': invalid value: %v", name, err)
+	}
+	return value, nil
+}
+
+// GetMany returns the values associated with the given keys. A
+// generic REST API has no known batch operations. Hence, GetMany
+// fetches up to batch.Concurrency keys concurrently.
+func (s *Store) GetMany(ctx context.Context, names []string) ([][]byte, []error) {
+	return batch.Get[string, []byte](ctx, s, names)
+}
+
+// Delete removes the value associated with the given key
+// from the REST API, if it exists. If no such entry exists
+// it returns kes.ErrKeyNotFound.
+func (s *Store) Delete(ctx context.Context, name string) error {
+	resp, err := s.send(ctx, s.delete, name, "")
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("rest: failed to delete key '%s': %v", name, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return kes.ErrKeyNotFound
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return fmt.Errorf("rest: failed to delete key '%s': %s (%d)", name, parseServerError(resp), resp.StatusCode)
+	}
+	return nil
+}
+
+// DeleteMany deletes the given keys. It deletes up to
+// batch.Concurrency keys concurrently since a generic REST API
+// has no known batch operations.
+func (s *Store) DeleteMany(ctx context.Context, names []string) []error {
+	return batch.Delete[string, []byte](ctx, s, names)
+}
+
+// List returns a new Iterator over the names of
+// all stored keys that start with the given prefix.
+//
+// The list request must return all key names at
+// once. Paginated responses are not supported.
+func (s *Store) List(ctx context.Context, prefix string) (kv.Iter[string], error) {
+	resp, err := s.send(ctx, s.list, "", "")
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("rest: failed to list keys: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("rest: failed to list keys: %s (%d)", parseServerError(resp), resp.StatusCode)
+	}
+
+	const MaxBody = 32 * mem.MiB
+	body, err := io.ReadAll(mem.LimitReader(resp.Body, MaxBody))
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("rest: failed to list keys: %v", err)
+	}
+	v, err := lookup(body, s.config.NamesPath)
+	if err != nil {
+		return nil, fmt.Errorf("rest: failed to list keys: %v", err)
+	}
+	elements, ok := v.([]any)
+	if !ok {
+		return nil, errors.New("rest: failed to list keys: response does not contain a JSON array")
+	}
+
+	names := make([]string, 0, len(elements))
+	for _, element := range elements {
+		if s.config.NameField != "" {
+			obj, ok := element.(map[string]any)
+			if !ok {
+				return nil, errors.New("rest: failed to list keys: array element is not a JSON object")
+			}
+			element = obj[s.config.NameField]
+		}
+		name, ok := element.(string)
+		if !ok {
+			return nil, errors.New("rest: failed to list keys: key name is not a string")
+		}
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	return &iter{names: names}, nil
+}
+
+// send executes the request templates for the given
+// key name and value and sends the resulting request
+// to the REST API.
+func (s *Store) send(ctx context.Context, r *request, name, value string) (*http.Response, error) {
+	data := struct {
+		Name  string
+		Value string
+	}{
+		Name:  name,
+		Value: value,
+	}
+
+	var path strings.Builder
+	if err := r.Path.Execute(&path, data); err != nil {
+		return nil, err
+	}
+	var body io.Reader
+	if r.Body != nil {
+		var buf bytes.Buffer
+		if err := r.Body.Execute(&buf, data); err != nil {
+			return nil, err
+		}
+		body = xhttp.RetryReader(bytes.NewReader(buf.Bytes()))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, r.Method, s.config.Endpoint+path.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range s.config.Header {
+		req.Header.Set(k, v)
+	}
+	if body != nil && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return s.client.Do(req)
+}
+
+// request is a parsed Request.
+type request struct {
+	Method string
+	Path   *template.Template
+	Body   *template.Template // Optional
+}
+
+// funcs are the functions available to request templates,
+// in addition to the text/template builtin functions.
+var funcs = template.FuncMap{
+	"json": func(s string) (string, error) {
+		b, err := json.Marshal(s)
+		return string(b), err
+	},
+	"pathescape": url.PathEscape,
+}
+
+// parseRequest parses the path and body templates of
+// the given request. If the request has no method, it
+// uses the given default method.
+func parseRequest(op, method string, r Request) (*request, error) {
+	if r.Method == "" {
+		r.Method = method
+	}
+	if r.Path == "" {
+		return nil, fmt.Errorf("rest: no URL path for %s requests specified", op)
+	}
+	if !strings.HasPrefix(r.Path, "/") {
+		r.Path = "/" + r.Path
+	}
+
+	path, err := template.New(op).Funcs(funcs).Option("missingkey=error").Parse(r.Path)
+	if err != nil {
+		return nil, fmt.Errorf("rest: invalid URL path for %s requests: %v", op, err)
+	}
+	req := &request{
+		Method: strings.ToUpper(r.Method),
+		Path:   path,
+	}
+	if r.Body != "" {
+		req.Body, err = template.New(op).Funcs(funcs).Option("missingkey=error").Parse(r.Body)
+		if err != nil {
+			return nil, fmt.Errorf("rest: invalid body for %s requests: %v", op, err)
+		}
+	}
+	return req, nil
+}
+
+// lookup parses the JSON document and returns the value
+// at the given dot-separated path. If path is empty, it
+// returns the entire document.
+func lookup(document []byte, path string) (any, error) {
+	var v any
+	if err := json.Unmarshal(document, &v); err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return v, nil
+	}
+	for _, field := range strings.Split(path, ".") {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("'%s' not found in response", path)
+		}
+		if v, ok = obj[field]; !ok {
+			return nil, fmt.Errorf("'%s' not found in response", path)
+		}
+	}
+	return v, nil
+}
+
+// parseServerError returns the error message of
+// a REST API error response.
+func parseServerError(resp *http.Response) string {
+	const MaxSize = 1 * mem.MiB
+
+	body, err := io.ReadAll(mem.LimitReader(resp.Body, MaxSize))
+	if err != nil || len(body) == 0 {
+		return http.StatusText(resp.StatusCode)
+	}
+	var response struct {
+		Message  string   `json:"message"`
+		Messages []string `json:"messages"`
+		Error    string   `json:"error"`
+	}
+	if err = json.Unmarshal(body, &response); err == nil {
+		switch {
+		case response.Message != "":
+			return response.Message
+		case len(response.Messages) > 0:
+			return strings.Join(response.Messages, "; ")
+		case response.Error != "":
+			return response.Error
+		}
+	}
+	return strings.TrimSpace(string(body))
+}
+
+type iter struct {
+	names []string
+}
+
+func (i *iter) Next() (string, bool) {
+	if len(i.names) == 0 {
+		return "", false
+	}
+	name := i.names[0]
+	i.names = i.names[1:]
+	return name, true
+}
+
+func (*iter) Close() error { return nil }
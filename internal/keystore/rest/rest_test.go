@@ -0,0 +1,192 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/minio/kes/internal/keystore/conformance"
+)
+
+func TestConformance(t *testing.T) {
+	const Token = "Bearer dp.st.prd.my-token"
+	server := httptest.NewServer(newSecretServer(Token))
+	defer server.Close()
+
+	// The templates resemble the Doppler secrets API.
+	store, err := Connect(context.Background(), &Config{
+		Endpoint: server.URL,
+		Header:   map[string]string{"Authorization": Token},
+		Create: Request{
+			Method: http.MethodPost,
+			Path:   "/v3/configs/config/secrets?project=kes&config=prd",
+			Body:   `{"secrets":{ {{json .Name}}: {{json .Value}} }}`,
+		},
+		Get: Request{
+			Method: http.MethodGet,
+			Path:   "/v3/configs/config/secret?project=kes&config=prd&name={{urlquery .Name}}",
+		},
+		Delete: Request{
+			Method: http.MethodDelete,
+			Path:   "/v3/configs/config/secret?project=kes&config=prd&name={{urlquery .Name}}",
+		},
+		List: Request{
+			Method: http.MethodGet,
+			Path:   "/v3/configs/config/secrets?project=kes&config=prd",
+		},
+		ValuePath: "value.raw",
+		NamesPath: "secrets",
+		NameField: "name",
+	})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+
+	report, err := conformance.Run(context.Background(), store)
+	if err != nil {
+		t.Fatalf("Failed to run conformance tests: %v", err)
+	}
+	for _, result := range report.Results {
+		if !result.Passed() {
+			t.Errorf("Test '%s' failed: %v", result.Name, result.Err)
+		}
+	}
+}
+
+var connectTests = []struct {
+	Config     Config
+	ShouldFail bool
+}{
+	{ // 0
+		Config: Config{
+			Endpoint: "https://secrets.example.com",
+			Create:   Request{Method: "put", Path: "secrets/{{pathescape .Name}}", Body: "{{.Value}}"},
+			Get:      Request{Method: "get", Path: "secrets/{{pathescape .Name}}"},
+			Delete:   Request{Method: "delete", Path: "secrets/{{pathescape .Name}}"},
+			List:     Request{Method: "get", Path: "secrets"},
+		},
+	},
+	{ // 1
+		Config: Config{
+			Endpoint: "https://secrets.example.com",
+			Create:   Request{Method: "put", Path: "secrets/{{pathescape .Name}}", Body: "{{.Value}}"},
+			Get:      Request{Method: "get", Path: "secrets/{{pathescape .Name}}"},
+			Delete:   Request{Method: "delete"}, // No path
+			List:     Request{Method: "get", Path: "secrets"},
+		},
+		ShouldFail: true,
+	},
+	{ // 2
+		Config: Config{
+			Endpoint: "https://secrets.example.com",
+			Create:   Request{Method: "put", Path: "secrets/{{pathescape .Name}}", Body: "{{.Value"}, // Invalid template
+			Get:      Request{Method: "get", Path: "secrets/{{pathescape .Name}}"},
+			Delete:   Request{Method: "delete", Path: "secrets/{{pathescape .Name}}"},
+			List:     Request{Method: "get", Path: "secrets"},
+		},
+		ShouldFail: true,
+	},
+	{ // 3
+		Config: Config{
+			Endpoint: "https://secrets.example.com",
+			Create:   Request{Method: "put", Path: "secrets/{{base64 .Name}}", Body: "{{.Value}}"}, // Unknown function
+			Get:      Request{Method: "get", Path: "secrets/{{pathescape .Name}}"},
+			Delete:   Request{Method: "delete", Path: "secrets/{{pathescape .Name}}"},
+			List:     Request{Method: "get", Path: "secrets"},
+		},
+		ShouldFail: true,
+	},
+}
+
+func TestConnect(t *testing.T) {
+	for i, test := range connectTests {
+		_, err := Connect(context.Background(), &test.Config)
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: should fail but succeeded", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to connect: %v", i, err)
+		}
+	}
+}
+
+// secretServer is a minimal in-memory secret store
+// that resembles the Doppler secrets API. Like Doppler,
+// it overwrites existing secrets.
+type secretServer struct {
+	token string
+
+	lock    sync.Mutex
+	secrets map[string]string
+}
+
+func newSecretServer(token string) *secretServer {
+	return &secretServer{
+		token:   token,
+		secrets: map[string]string{},
+	}
+}
+
+func (s *secretServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != s.token {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]any{"messages": []string{"Invalid auth token"}, "success": false})
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	switch {
+	case r.URL.Path == "/v3/configs/config/secrets" && r.Method == http.MethodPost:
+		var request struct {
+			Secrets map[string]string `json:"secrets"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for name, value := range request.Secrets {
+			s.secrets[name] = value
+		}
+		w.WriteHeader(http.StatusOK)
+	case r.URL.Path == "/v3/configs/config/secrets" && r.Method == http.MethodGet:
+		type secret struct {
+			Name string `json:"name"`
+		}
+		secrets := []secret{}
+		for name := range s.secrets {
+			secrets = append(secrets, secret{Name: name})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"secrets": secrets})
+	case r.URL.Path == "/v3/configs/config/secret":
+		name := r.URL.Query().Get("name")
+		value, ok := s.secrets[name]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]any{"messages": []string{"Could not find requested secret"}, "success": false})
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			json.NewEncoder(w).Encode(map[string]any{
+				"name":  name,
+				"value": map[string]string{"raw": value, "computed": value},
+			})
+		case http.MethodDelete:
+			delete(s.secrets, name)
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
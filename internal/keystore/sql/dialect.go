@@ -0,0 +1,121 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"context"
+	gosql "database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+// dialect describes the differences between
+// the supported SQL databases.
+type dialect struct {
+	// Driver is the name of the database/sql driver.
+	Driver string
+
+	// Placeholder returns the n-th query
+	// parameter placeholder, starting at 1.
+	Placeholder func(n int) string
+
+	// IsDuplicate reports whether err is a unique
+	// constraint violation.
+	IsDuplicate func(err error) bool
+
+	// Migrations are the schema migrations. The i-th
+	// migration migrates the key table from version i
+	// to i+1. The table name is passed as format
+	// argument. Each migration must be idempotent.
+	Migrations []string
+}
+
+var dialects = map[string]*dialect{
+	"postgres": {
+		Driver:      "postgres",
+		Placeholder: func(n int) string { return "$" + strconv.Itoa(n) },
+		IsDuplicate: func(err error) bool {
+			var pqErr *pq.Error
+			return errors.As(err, &pqErr) && pqErr.Code == "23505" // unique_violation
+		},
+		Migrations: []string{
+			`CREATE TABLE IF NOT EXISTS %[1]s (
+				name       VARCHAR(255) PRIMARY KEY,
+				value      BYTEA        NOT NULL,
+				created_at TIMESTAMP    NOT NULL
+			)`,
+		},
+	},
+	"mysql": {
+		Driver:      "mysql",
+		Placeholder: func(int) string { return "?" },
+		IsDuplicate: func(err error) bool {
+			var mysqlErr *mysql.MySQLError
+			return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 // ER_DUP_ENTRY
+		},
+		Migrations: []string{
+			`CREATE TABLE IF NOT EXISTS %[1]s (
+				name       VARCHAR(255)    NOT NULL PRIMARY KEY,
+				value      VARBINARY(8192) NOT NULL,
+				created_at DATETIME        NOT NULL
+			) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin`,
+		},
+	},
+}
+
+// migrate migrates the key table to the latest schema
+// version. The current version of each table is tracked
+// in the kes_migrations table.
+//
+// Multiple KES servers may migrate the same table
+// concurrently. Hence, each migration must be idempotent.
+func migrate(ctx context.Context, db *gosql.DB, d *dialect, table string) error {
+	const CreateMigrations = `CREATE TABLE IF NOT EXISTS kes_migrations (
+		table_name VARCHAR(255) NOT NULL,
+		version    INTEGER      NOT NULL,
+		PRIMARY KEY (table_name, version)
+	)`
+	if _, err := db.ExecContext(ctx, CreateMigrations); err != nil {
+		return fmt.Errorf("sql: failed to create migrations table: %v", err)
+	}
+
+	var (
+		queryVersion = "SELECT COALESCE(MAX(version), 0) FROM kes_migrations WHERE table_name = " + d.Placeholder(1)
+		queryInsert  = "INSERT INTO kes_migrations (table_name, version) VALUES (" + d.Placeholder(1) + ", " + d.Placeholder(2) + ")"
+	)
+	var version int
+	if err := db.QueryRowContext(ctx, queryVersion, table).Scan(&version); err != nil {
+		return fmt.Errorf("sql: failed to read schema version of '%s': %v", table, err)
+	}
+	if version > len(d.Migrations) {
+		return fmt.Errorf("sql: schema version '%d' of '%s' is not supported: latest version is '%d'", version, table, len(d.Migrations))
+	}
+
+	for ; version < len(d.Migrations); version++ {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("sql: failed to migrate '%s' to version '%d': %v", table, version+1, err)
+		}
+		if _, err = tx.ExecContext(ctx, fmt.Sprintf(d.Migrations[version], table)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("sql: failed to migrate '%s' to version '%d': %v", table, version+1, err)
+		}
+		if _, err = tx.ExecContext(ctx, queryInsert, table, version+1); err != nil {
+			tx.Rollback()
+			if d.IsDuplicate(err) {
+				continue // Another KES server has applied the migration concurrently
+			}
+			return fmt.Errorf("sql: failed to migrate '%s' to version '%d': %v", table, version+1, err)
+		}
+		if err = tx.Commit(); err != nil {
+			return fmt.Errorf("sql: failed to migrate '%s' to version '%d': %v", table, version+1, err)
+		}
+	}
+	return nil
+}
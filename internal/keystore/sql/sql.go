@@ -0,0 +1,462 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package sql implements a key store that stores keys
+// in a single table of a PostgreSQL or MySQL database.
+//
+// Each key is encrypted with an AES-256 encryption key
+// before it is stored. Hence, database administrators
+// and backups only see encrypted keys.
+package sql
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	gosql "database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/kv"
+)
+
+// DefaultTable is the default name of the table
+// that contains the keys.
+const DefaultTable = "kes_keys"
+
+// MaxBatchSize is the maximum number of keys
+// fetched or deleted with a single query.
+const MaxBatchSize = 500
+
+// Config is a structure containing configuration
+// options for connecting to a SQL database.
+type Config struct {
+	// Driver is the database driver. Either
+	// "postgres" or "mysql".
+	Driver string
+
+	// DSN is the driver-specific data source name,
+	// e.g. postgres://user:password@db:5432/kes or
+	// user:password@tcp(db:3306)/kes.
+	DSN string
+
+	// Table is the name of the table containing the
+	// keys. It is created if it does not exist. If
+	// empty, DefaultTable is used.
+	Table string
+
+	// EncryptionKey is the 256 bit AES key used to
+	// encrypt keys before they are stored.
+	EncryptionKey []byte
+
+	// MaxOpenConns is the maximum number of open
+	// connections. If <= 0, the number of open
+	// connections is not limited.
+	MaxOpenConns int
+
+	// MaxIdleConns is the maximum number of idle
+	// connections. If 0, defaults to 2. If < 0,
+	// idle connections are not retained.
+	MaxIdleConns int
+
+	// ConnMaxLifetime is the maximum amount of time
+	// a connection may be reused. If <= 0,
+	// connections are reused forever.
+	ConnMaxLifetime time.Duration
+}
+
+// Store is a SQL database key store.
+type Store struct {
+	db      *gosql.DB
+	dialect *dialect
+	aead    cipher.AEAD
+	table   string
+
+	queryCreate string
+	queryGet    string
+	queryUpdate string
+	queryDelete string
+	queryList   string
+}
+
+var _ kv.Store[string, []byte] = (*Store)(nil)
+
+// Connect connects to the SQL database, migrates the
+// key table to the latest schema and returns a new Store.
+func Connect(ctx context.Context, config *Config) (*Store, error) {
+	d, ok := dialects[config.Driver]
+	if !ok {
+		return nil, fmt.Errorf("sql: unsupported driver '%s'", config.Driver)
+	}
+	if config.DSN == "" {
+		return nil, errors.New("sql: no data source name specified")
+	}
+	table := config.Table
+	if table == "" {
+		table = DefaultTable
+	}
+	if !isValidTable(table) {
+		return nil, fmt.Errorf("sql: invalid table name '%s'", table)
+	}
+	if len(config.EncryptionKey) != 32 {
+		return nil, errors.New("sql: invalid encryption key: key must be 256 bits long")
+	}
+
+	block, err := aes.NewCipher(config.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := gosql.Open(d.Driver, config.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("sql: failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(config.MaxOpenConns)
+	if config.MaxIdleConns != 0 {
+		db.SetMaxIdleConns(config.MaxIdleConns)
+	}
+	db.SetConnMaxLifetime(config.ConnMaxLifetime)
+
+	if err = db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("sql: failed to connect to database: %v", err)
+	}
+	if err = migrate(ctx, db, d, table); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{
+		db:          db,
+		dialect:     d,
+		aead:        aead,
+		table:       table,
+		queryCreate: fmt.Sprintf("INSERT INTO %s (name, value, created_at) VALUES (%s, %s, %s)", table, d.Placeholder(1), d.Placeholder(2), d.Placeholder(3)),
+		queryGet:    fmt.Sprintf("SELECT value FROM %s WHERE name = %s", table, d.Placeholder(1)),
+		queryUpdate: fmt.Sprintf("UPDATE %s SET value = %s WHERE name = %s AND value = %s", table, d.Placeholder(1), d.Placeholder(2), d.Placeholder(3)),
+		queryDelete: fmt.Sprintf("DELETE FROM %s WHERE name = %s", table, d.Placeholder(1)),
+		queryList:   fmt.Sprintf("SELECT name FROM %s WHERE name LIKE %s ESCAPE '!' ORDER BY name", table, d.Placeholder(1)),
+	}, nil
+}
+
+// Status returns the current state of the SQL database.
+// In particular, whether it is reachable and the network
+// latency.
+func (s *Store) Status(ctx context.Context) (kv.State, error) {
+	start := time.Now()
+	if err := s.db.PingContext(ctx); err != nil {
+		return kv.State{}, &kv.Unreachable{Err: err}
+	}
+	return kv.State{
+		Latency: time.Since(start),
+	}, nil
+}
+
+// Create encrypts the given value and inserts it into the
+// key table if and only if no entry with the given name
+// exists. If such an entry exists it returns kes.ErrKeyExists.
+func (s *Store) Create(ctx context.Context, name string, value []byte) error {
+	ciphertext, err := s.encrypt(name, value)
+	if err != nil {
+		return fmt.Errorf("sql: failed to encrypt key '%s': %v", name, err)
+	}
+
+	_, err = s.db.ExecContext(ctx, s.queryCreate, name, ciphertext, time.Now().UTC())
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if s.dialect.IsDuplicate(err) {
+		return kes.ErrKeyExists
+	}
+	if err != nil {
+		return fmt.Errorf("sql: failed to create key '%s': %v", name, err)
+	}
+	return nil
+}
+
+// Set encrypts the given value and inserts it into the
+// key table if and only if no entry with the given name
+// exists. If such an entry exists it returns kes.ErrKeyExists.
+func (s *Store) Set(ctx context.Context, name string, value []byte) error {
+	return s.Create(ctx, name, value)
+}
+
+// Update replaces the value of the given key with newValue
+// if and only if its current value is equal to oldValue. If
+// no entry for the key exists it returns kes.ErrKeyNotFound.
+//
+// Since values are encrypted with a random nonce, Update
+// compares the decrypted values and replaces the entry if
+// and only if its ciphertext has not changed in between.
+func (s *Store) Update(ctx context.Context, name string, oldValue, newValue []byte) error {
+	value, ciphertext, err := s.get(ctx, name)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(value, oldValue) {
+		return kv.ErrConflict
+	}
+
+	newCiphertext, err := s.encrypt(name, newValue)
+	if err != nil {
+		return fmt.Errorf("sql: failed to encrypt key '%s': %v", name, err)
+	}
+	result, err := s.db.ExecContext(ctx, s.queryUpdate, newCiphertext, name, ciphertext)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("sql: failed to update key '%s': %v", name, err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("sql: failed to update key '%s': %v", name, err)
+	}
+	if n == 0 {
+		// The entry has been modified or deleted concurrently.
+		if _, _, err = s.get(ctx, name); err != nil {
+			return err
+		}
+		return kv.ErrConflict
+	}
+	return nil
+}
+
+// Get returns the value associated with the given key.
+// If no entry for the key exists it returns kes.ErrKeyNotFound.
+func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
+	value, _, err := s.get(ctx, name)
+	return value, err
+}
+
+// GetMany returns the values associated with the given keys.
+// It fetches up to MaxBatchSize keys with a single query.
+func (s *Store) GetMany(ctx context.Context, names []string) ([][]byte, []error) {
+	values := make([][]byte, len(names))
+	errs := make([]error, len(names))
+	for i := 0; i < len(names); i += MaxBatchSize {
+		chunk := names[i:]
+		if len(chunk) > MaxBatchSize {
+			chunk = chunk[:MaxBatchSize]
+		}
+
+		ciphertexts, err := s.getMany(ctx, chunk)
+		for j, name := range chunk {
+			ciphertext, ok := ciphertexts[name]
+			switch {
+			case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+				errs[i+j] = err
+			case err != nil:
+				errs[i+j] = fmt.Errorf("sql: failed to access key '%s': %v", name, err)
+			case !ok:
+				errs[i+j] = kes.ErrKeyNotFound
+			default:
+				value, dErr := s.decrypt(name, ciphertext)
+				if dErr != nil {
+					errs[i+j] = fmt.Errorf("sql: failed to decrypt key '%s': %v", name, dErr)
+					continue
+				}
+				values[i+j] = value
+			}
+		}
+	}
+	return values, errs
+}
+
+// getMany returns the ciphertexts of the given keys
+// that exist within the key table.
+func (s *Store) getMany(ctx context.Context, names []string) (map[string][]byte, error) {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf("SELECT name, value FROM %s WHERE name IN (%s)", s.table, s.placeholders(len(names))), args(names)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ciphertexts := make(map[string][]byte, len(names))
+	for rows.Next() {
+		var (
+			name       string
+			ciphertext []byte
+		)
+		if err = rows.Scan(&name, &ciphertext); err != nil {
+			return nil, err
+		}
+		ciphertexts[name] = ciphertext
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return ciphertexts, nil
+}
+
+// get returns the value associated with the given key
+// and its ciphertext.
+func (s *Store) get(ctx context.Context, name string) ([]byte, []byte, error) {
+	var ciphertext []byte
+	err := s.db.QueryRowContext(ctx, s.queryGet, name).Scan(&ciphertext)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, nil, err
+	}
+	if errors.Is(err, gosql.ErrNoRows) {
+		return nil, nil, kes.ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("sql: failed to access key '%s': %v", name, err)
+	}
+
+	value, err := s.decrypt(name, ciphertext)
+	if err != nil {
+		return nil, nil, fmt.Errorf("sql: failed to decrypt key '%s': %v", name, err)
+	}
+	return value, ciphertext, nil
+}
+
+// Delete removes a the value associated with the given key
+// from the key table, if it exists.
+func (s *Store) Delete(ctx context.Context, name string) error {
+	_, err := s.db.ExecContext(ctx, s.queryDelete, name)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("sql: failed to delete key '%s': %v", name, err)
+	}
+	return nil
+}
+
+// DeleteMany removes the values associated with the given
+// keys from the key table, if they exist. It deletes up to
+// MaxBatchSize keys with a single query.
+func (s *Store) DeleteMany(ctx context.Context, names []string) []error {
+	errs := make([]error, len(names))
+	for i := 0; i < len(names); i += MaxBatchSize {
+		chunk := names[i:]
+		if len(chunk) > MaxBatchSize {
+			chunk = chunk[:MaxBatchSize]
+		}
+
+		_, err := s.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE name IN (%s)", s.table, s.placeholders(len(chunk))), args(chunk)...)
+		if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("sql: failed to delete keys: %v", err)
+		}
+		for j := range chunk {
+			errs[i+j] = err
+		}
+	}
+	return errs
+}
+
+// List returns a new Iterator over the names of
+// all stored keys that start with the given prefix.
+// The database filters the keys by prefix.
+func (s *Store) List(ctx context.Context, prefix string) (kv.Iter[string], error) {
+	rows, err := s.db.QueryContext(ctx, s.queryList, likePrefix(prefix))
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sql: failed to list keys: %v", err)
+	}
+	return &iter{rows: rows}, nil
+}
+
+// Close closes the database and all its connections.
+func (s *Store) Close() error { return s.db.Close() }
+
+// encrypt encrypts the value of the given key with
+// AES-256-GCM and returns the nonce and ciphertext.
+// The key name is authenticated as associated data
+// such that values cannot be swapped between keys.
+func (s *Store) encrypt(name string, value []byte) ([]byte, error) {
+	nonce := make([]byte, s.aead.NonceSize(), s.aead.NonceSize()+len(value)+s.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return s.aead.Seal(nonce, nonce, value, []byte(name)), nil
+}
+
+// decrypt decrypts the nonce and ciphertext
+// produced by encrypt.
+func (s *Store) decrypt(name string, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < s.aead.NonceSize() {
+		return nil, errors.New("ciphertext is too short")
+	}
+	nonce, ciphertext := ciphertext[:s.aead.NonceSize()], ciphertext[s.aead.NonceSize():]
+	return s.aead.Open(nil, nonce, ciphertext, []byte(name))
+}
+
+// placeholders returns a comma-separated list of
+// n query parameter placeholders.
+func (s *Store) placeholders(n int) string {
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		if i > 1 {
+			b.WriteString(", ")
+		}
+		b.WriteString(s.dialect.Placeholder(i))
+	}
+	return b.String()
+}
+
+// likePrefix returns a LIKE pattern that matches all
+// names starting with prefix. It escapes the LIKE
+// wildcards with '!'.
+func likePrefix(prefix string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(prefix) + "%"
+}
+
+// args returns the names as query arguments.
+func args(names []string) []any {
+	args := make([]any, 0, len(names))
+	for _, name := range names {
+		args = append(args, name)
+	}
+	return args
+}
+
+var tableRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,62}$`)
+
+// isValidTable reports whether table is a valid, unquoted
+// table name. Since the table name is part of SQL queries
+// it must not contain any special characters.
+func isValidTable(table string) bool { return tableRegex.MatchString(table) }
+
+type iter struct {
+	rows *gosql.Rows
+	err  error
+}
+
+func (i *iter) Next() (string, bool) {
+	if i.err != nil || !i.rows.Next() {
+		return "", false
+	}
+
+	var name string
+	if i.err = i.rows.Scan(&name); i.err != nil {
+		return "", false
+	}
+	return name, true
+}
+
+func (i *iter) Close() error {
+	if err := i.rows.Close(); err != nil && i.err == nil {
+		i.err = err
+	}
+	if err := i.rows.Err(); err != nil && i.err == nil {
+		i.err = err
+	}
+	if i.err != nil && !errors.Is(i.err, context.Canceled) && !errors.Is(i.err, context.DeadlineExceeded) {
+		return fmt.Errorf("sql: failed to list keys: %v", i.err)
+	}
+	return i.err
+}
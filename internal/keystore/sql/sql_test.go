@@ -0,0 +1,75 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"testing"
+)
+
+func TestEncrypt(t *testing.T) {
+	block, err := aes.NewCipher(make([]byte, 32))
+	if err != nil {
+		t.Fatalf("Failed to create AES cipher: %v", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatalf("Failed to create AES-GCM: %v", err)
+	}
+	s := &Store{aead: aead}
+
+	value := []byte("secret")
+	ciphertext, err := s.encrypt("my-key", value)
+	if err != nil {
+		t.Fatalf("Failed to encrypt key: %v", err)
+	}
+	if bytes.Contains(ciphertext, value) {
+		t.Fatal("Key is stored in plaintext")
+	}
+
+	plaintext, err := s.decrypt("my-key", ciphertext)
+	if err != nil {
+		t.Fatalf("Failed to decrypt key: %v", err)
+	}
+	if !bytes.Equal(plaintext, value) {
+		t.Fatalf("Invalid plaintext: got '%s' - want '%s'", plaintext, value)
+	}
+	if _, err = s.decrypt("my-key-2", ciphertext); err == nil {
+		t.Fatal("Decrypting a key with a ciphertext of another key succeeded")
+	}
+}
+
+var isValidTableTests = []struct {
+	Table string
+	Valid bool
+}{
+	{Table: "kes_keys", Valid: true},                // 0
+	{Table: "_keys2", Valid: true},                  // 1
+	{Table: "", Valid: false},                       // 2
+	{Table: "2keys", Valid: false},                  // 3
+	{Table: "kes.keys", Valid: false},               // 4
+	{Table: "keys; DROP TABLE users", Valid: false}, // 5
+	{Table: "kes-keys", Valid: false},               // 6
+	{Table: string(make([]byte, 64)), Valid: false}, // 7
+}
+
+func TestIsValidTable(t *testing.T) {
+	for i, test := range isValidTableTests {
+		if valid := isValidTable(test.Table); valid != test.Valid {
+			t.Fatalf("Test %d: got '%v' - want '%v'", i, valid, test.Valid)
+		}
+	}
+}
+
+func TestPlaceholder(t *testing.T) {
+	if p := dialects["postgres"].Placeholder(2); p != "$2" {
+		t.Fatalf("Invalid postgres placeholder: got '%s' - want '%s'", p, "$2")
+	}
+	if p := dialects["mysql"].Placeholder(2); p != "?" {
+		t.Fatalf("Invalid mysql placeholder: got '%s' - want '%s'", p, "?")
+	}
+}
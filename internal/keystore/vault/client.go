@@ -6,11 +6,21 @@ package vault
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"os"
 	"path"
+	"strings"
 	"sync/atomic"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
 	vaultapi "github.com/hashicorp/vault/api"
 )
 
@@ -21,6 +31,8 @@ type client struct {
 	*vaultapi.Client
 
 	sealed uint32 // Atomic bool: sealed == 0 is false, sealed == 1 is true
+
+	unauthenticated uint32 // Atomic bool: 1 if the most recent re-authentication failed
 }
 
 // Sealed returns true if the most recently fetched vault
@@ -34,6 +46,11 @@ type client struct {
 // Sealed returns false.
 func (c *client) Sealed() bool { return atomic.LoadUint32(&c.sealed) == 1 }
 
+// Unauthenticated returns true if the most recent attempt
+// to re-authenticate, once the client token could not be
+// renewed anymore, failed.
+func (c *client) Unauthenticated() bool { return atomic.LoadUint32(&c.unauthenticated) == 1 }
+
 // CheckStatus keeps fetching the vault health status every delay
 // unit of time until  <-ctx.Done() returns.
 //
@@ -106,35 +123,116 @@ func (c *client) AuthenticateWithAppRole(login AppRole) authFunc {
 	}
 }
 
+// AuthenticateWithK8S tries to fetch a auth. token with an associated
+// TTL from the vault server by using the login Kubernetes credentials.
+//
+// If login.JWT is empty, the JWT is read from login.JWTFile on every
+// authentication attempt. Hence, a rotated projected service account
+// token is used once the previous one expires.
 func (c *client) AuthenticateWithK8S(login Kubernetes) authFunc {
 	return func() (token string, ttl time.Duration, err error) {
-		secret, err := c.Logical().Write(path.Join("auth", login.Engine, "login"), map[string]interface{}{
+		jwt := login.JWT
+		if jwt == "" {
+			b, err := os.ReadFile(login.JWTFile)
+			if err != nil {
+				return token, ttl, fmt.Errorf("vault: failed to read kubernetes JWT: %v", err)
+			}
+			jwt = strings.TrimSpace(string(b))
+		}
+		return c.login(path.Join("auth", login.Engine, "login"), map[string]interface{}{
 			"role": login.Role,
-			"jwt":  login.JWT,
+			"jwt":  jwt,
 		})
-		if err != nil || secret == nil {
-			// The Vault SDK eventually returns no error but also no
-			// secret. In this case have to return a (not very helpful)
-			// error to signal that the authentication failed - for some
-			// (unknown) reason.
-			if err == nil {
-				err = errors.New("vault: authentication failed: SDK returned no error but also no token")
-			}
-			return token, ttl, err
+	}
+}
+
+// AuthenticateWithAWS tries to fetch a auth. token with an associated
+// TTL from the vault server by using the AWS IAM method.
+//
+// It signs a STS GetCallerIdentity request with the AWS credentials
+// and passes the signed request to Vault which executes it to verify
+// the AWS identity. The credentials are fetched on every authentication
+// attempt such that rotated credentials, e.g. of an EC2 instance role,
+// are used.
+func (c *client) AuthenticateWithAWS(login AWSIAM) authFunc {
+	return func() (token string, ttl time.Duration, err error) {
+		region := login.Region
+		if region == "" {
+			region = "us-east-1"
 		}
-		token, err = secret.TokenID()
+		config := aws.NewConfig().WithRegion(region)
+		if login.AccessKey != "" || login.SecretKey != "" {
+			config = config.WithCredentials(credentials.NewStaticCredentials(login.AccessKey, login.SecretKey, login.SessionToken))
+		}
+		session, err := session.NewSession(config)
 		if err != nil {
-			return token, ttl, err
+			return token, ttl, fmt.Errorf("vault: failed to create AWS session: %v", err)
 		}
 
-		ttl, err = secret.TokenTTL()
+		req, _ := sts.New(session).GetCallerIdentityRequest(&sts.GetCallerIdentityInput{})
+		if login.ServerID != "" {
+			req.HTTPRequest.Header.Set("X-Vault-AWS-IAM-Server-ID", login.ServerID)
+		}
+		if err = req.Sign(); err != nil {
+			return token, ttl, fmt.Errorf("vault: failed to sign AWS STS request: %v", err)
+		}
+		headers, err := json.Marshal(req.HTTPRequest.Header)
 		if err != nil {
-			return token, ttl, err
+			return token, ttl, fmt.Errorf("vault: failed to encode AWS STS request: %v", err)
 		}
-		return token, ttl, nil
+		body, err := io.ReadAll(req.HTTPRequest.Body)
+		if err != nil {
+			return token, ttl, fmt.Errorf("vault: failed to encode AWS STS request: %v", err)
+		}
+		return c.login(path.Join("auth", login.Engine, "login"), map[string]interface{}{
+			"role":                    login.Role,
+			"iam_http_request_method": req.HTTPRequest.Method,
+			"iam_request_url":         base64.StdEncoding.EncodeToString([]byte(req.HTTPRequest.URL.String())),
+			"iam_request_headers":     base64.StdEncoding.EncodeToString(headers),
+			"iam_request_body":        base64.StdEncoding.EncodeToString(body),
+		})
+	}
+}
+
+// AuthenticateWithCert tries to fetch a auth. token with an associated
+// TTL from the vault server by using the TLS client certificate of the
+// client.
+func (c *client) AuthenticateWithCert(login Cert) authFunc {
+	return func() (token string, ttl time.Duration, err error) {
+		data := map[string]interface{}{}
+		if login.Name != "" {
+			data["name"] = login.Name
+		}
+		return c.login(path.Join("auth", login.Engine, "login"), data)
 	}
 }
 
+// login writes the data to the given login path and returns
+// the auth. token and its TTL from the Vault response.
+func (c *client) login(location string, data map[string]interface{}) (token string, ttl time.Duration, err error) {
+	secret, err := c.Logical().Write(location, data)
+	if err != nil || secret == nil {
+		// The Vault SDK eventually returns no error but also no
+		// secret. In this case have to return a (not very helpful)
+		// error to signal that the authentication failed - for some
+		// (unknown) reason.
+		if err == nil {
+			err = errors.New("vault: authentication failed: SDK returned no error but also no token")
+		}
+		return token, ttl, err
+	}
+	token, err = secret.TokenID()
+	if err != nil {
+		return token, ttl, err
+	}
+
+	ttl, err = secret.TokenTTL()
+	if err != nil {
+		return token, ttl, err
+	}
+	return token, ttl, nil
+}
+
 // authFunc implements a Vault authentication method.
 //
 // It returns a Vault authentication token and its
@@ -202,6 +300,7 @@ func (c *client) RenewToken(ctx context.Context, authenticate authFunc, ttl, ret
 			)
 			token, ttl, err = authenticate()
 			if err != nil {
+				atomic.StoreUint32(&c.unauthenticated, 1)
 				ttl = 0 // On error, set the TTL again to 0 to re-auth. again.
 				timer.Reset(retry)
 				select {
@@ -212,6 +311,7 @@ func (c *client) RenewToken(ctx context.Context, authenticate authFunc, ttl, ret
 				continue
 			}
 			c.SetToken(token) // SetToken is safe to call from different go routines
+			atomic.StoreUint32(&c.unauthenticated, 0)
 		}
 
 		// Now the client has a token with a non-zero TTL
@@ -243,3 +343,28 @@ func (c *client) RenewToken(ctx context.Context, authenticate authFunc, ttl, ret
 		ttl = 0
 	}
 }
+
+// DiscoverKVVersion returns the API version of the K/V engine
+// mounted at the given path within the client's namespace.
+//
+// It uses the sys/internal/ui/mounts API that is accessible
+// by any token that has some capabilities on the mount path.
+func (c *client) DiscoverKVVersion(ctx context.Context, engine string) (string, error) {
+	secret, err := c.Logical().ReadWithContext(ctx, path.Join("sys/internal/ui/mounts", engine))
+	if err != nil {
+		return "", err
+	}
+	if secret == nil || secret.Data == nil {
+		return "", errors.New("vault: no mount information available for '" + engine + "'")
+	}
+
+	if typ, ok := secret.Data["type"].(string); ok && typ != "kv" && typ != "generic" {
+		return "", errors.New("vault: '" + engine + "' is not a K/V engine but '" + typ + "'")
+	}
+	if options, ok := secret.Data["options"].(map[string]interface{}); ok {
+		if version, ok := options["version"].(string); ok && version == "2" {
+			return APIv2, nil
+		}
+	}
+	return APIv1, nil
+}
@@ -31,6 +31,14 @@ const (
 	// EngineKubernetes is the Hashicorp Vault default Kubernetes
 	// authentication engine path.
 	EngineKubernetes = "kubernetes"
+
+	// EngineAWS is the Hashicorp Vault default AWS authentication
+	// engine path.
+	EngineAWS = "aws"
+
+	// EngineCert is the Hashicorp Vault default TLS certificate
+	// authentication engine path.
+	EngineCert = "cert"
 )
 
 // AppRole contains authentication information
@@ -77,6 +85,80 @@ type Kubernetes struct {
 	// JWT is the issued authentication token.
 	JWT string
 
+	// JWTFile is the path to a file containing the
+	// authentication token, e.g. a projected service
+	// account token. The file is read on every login
+	// such that a rotated token is picked up.
+	//
+	// It is ignored if JWT is not empty.
+	JWTFile string
+
+	// Retry is the duration after which another
+	// authentication attempt is performed once
+	// an authentication attempt failed.
+	Retry time.Duration
+}
+
+// AWSIAM contains authentication information
+// for the Hashicorp Vault AWS authentication
+// API using the IAM method.
+//
+// Ref: https://www.vaultproject.io/api/auth/aws
+type AWSIAM struct {
+	// Engine is the authentication engine path
+	//
+	// Hashicorp Vault allows multiple engines of the
+	// same type mounted at the same time and/or engines
+	// mounted at arbitrary paths.
+	Engine string
+
+	// Role is the name of the Vault AWS auth role.
+	Role string
+
+	// ServerID is an optional value of the
+	// X-Vault-AWS-IAM-Server-ID header. It has to
+	// match the server ID configured at Vault, if any.
+	ServerID string
+
+	// Region is the AWS STS region. If empty,
+	// defaults to the global us-east-1 endpoint.
+	Region string
+
+	// AccessKey, SecretKey and SessionToken are
+	// optional AWS credentials. If empty, the
+	// credentials are fetched from the environment,
+	// the shared credentials file or the EC2/ECS
+	// instance metadata service.
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+
+	// Retry is the duration after which another
+	// authentication attempt is performed once
+	// an authentication attempt failed.
+	Retry time.Duration
+}
+
+// Cert contains authentication information
+// for the Hashicorp Vault TLS certificate
+// authentication API.
+//
+// The client authenticates with the mTLS
+// client certificate of the Config.
+//
+// Ref: https://www.vaultproject.io/api/auth/cert
+type Cert struct {
+	// Engine is the authentication engine path
+	//
+	// Hashicorp Vault allows multiple engines of the
+	// same type mounted at the same time and/or engines
+	// mounted at arbitrary paths.
+	Engine string
+
+	// Name is an optional name of the certificate
+	// role. If empty, Vault tries all roles.
+	Name string
+
 	// Retry is the duration after which another
 	// authentication attempt is performed once
 	// an authentication attempt failed.
@@ -98,7 +180,10 @@ type Config struct {
 
 	// APIVersion is the API version of the K/V engine.
 	//
-	// If empty, it defaults to APIv1.
+	// If empty, the API version is discovered from the
+	// engine mount within the Namespace. If the mount
+	// information is not accessible, it defaults to
+	// APIv1.
 	//
 	// Ref: https://www.vaultproject.io/docs/secrets/kv
 	APIVersion string
@@ -117,6 +202,21 @@ type Config struct {
 	// from and stored within this prefix.
 	Prefix string
 
+	// SoftDelete controls whether keys are deleted softly.
+	// It requires a K/V v2 engine.
+	//
+	// If true, deleting a key only deletes its current
+	// version such that it can be recovered (undeleted)
+	// until it gets purged (destroyed). Otherwise, all
+	// versions and the metadata of a key are deleted
+	// permanently.
+	SoftDelete bool
+
+	// CustomMetadata is an optional set of key-value pairs
+	// attached as custom metadata to every key created on
+	// the K/V store. It requires a K/V v2 engine.
+	CustomMetadata map[string]string
+
 	// AppRole contains the Vault AppRole authentication
 	// credentials.
 	AppRole AppRole
@@ -125,6 +225,16 @@ type Config struct {
 	// credentials.
 	K8S Kubernetes
 
+	// AWS contains the Vault AWS IAM authentication
+	// credentials. If nil, AWS IAM authentication is
+	// disabled.
+	AWS *AWSIAM
+
+	// Cert contains the Vault TLS certificate
+	// authentication configuration. If nil, TLS
+	// certificate authentication is disabled.
+	Cert *Cert
+
 	// StatusPingAfter is the duration after which
 	// the KeyStore will check the status of the Vault
 	// server. Particularly, this status information
@@ -132,6 +242,12 @@ type Config struct {
 	// has been sealed resp. unsealed again.
 	StatusPingAfter time.Duration
 
+	// WatchInterval is the interval at which the Store
+	// polls Vault for keys that have been created, changed
+	// or deleted by other KES servers. If zero, the Store
+	// does not watch its keys.
+	WatchInterval time.Duration
+
 	// Path to the mTLS client private key to authenticate to
 	// the Vault server.
 	PrivateKey string
@@ -145,12 +261,18 @@ type Config struct {
 	// host's root CA set is used.
 	CAPath string
 
+	// ServerName is an optional TLS server name (SNI) used
+	// to verify the TLS certificate of the Vault server. If
+	// empty, the endpoint host name is used.
+	ServerName string
+
 	lock sync.RWMutex
 }
 
-// Clone returns a shallow clone of c or nil if c is
-// nil. It is safe to clone a Config that is being used
-// concurrently.
+// Clone returns a clone of c or nil if c is nil. The
+// clone does not share the custom metadata and the AWS
+// and TLS certificate auth. configs with c. It is safe
+// to clone a Config that is being used concurrently.
 func (c *Config) Clone() *Config {
 	if c == nil {
 		return nil
@@ -158,17 +280,40 @@ func (c *Config) Clone() *Config {
 
 	c.lock.RLock()
 	defer c.lock.RUnlock()
+
+	var metadata map[string]string
+	if c.CustomMetadata != nil {
+		metadata = make(map[string]string, len(c.CustomMetadata))
+		for k, v := range c.CustomMetadata {
+			metadata[k] = v
+		}
+	}
+	var aws *AWSIAM
+	if c.AWS != nil {
+		clone := *c.AWS
+		aws = &clone
+	}
+	var cert *Cert
+	if c.Cert != nil {
+		clone := *c.Cert
+		cert = &clone
+	}
 	return &Config{
 		Endpoint:        c.Endpoint,
 		Engine:          c.Engine,
 		APIVersion:      c.APIVersion,
 		Namespace:       c.Namespace,
 		Prefix:          c.Prefix,
+		SoftDelete:      c.SoftDelete,
+		CustomMetadata:  metadata,
 		AppRole:         c.AppRole,
 		K8S:             c.K8S,
+		AWS:             aws,
+		Cert:            cert,
 		StatusPingAfter: c.StatusPingAfter,
 		PrivateKey:      c.PrivateKey,
 		Certificate:     c.Certificate,
 		CAPath:          c.CAPath,
+		ServerName:      c.ServerName,
 	}
 }
@@ -5,28 +5,69 @@
 package vault
 
 import (
-	"fmt"
+	"context"
 	"strings"
 
 	"github.com/minio/kes/kv"
 )
 
+// iterator traverses the keys that start with a prefix.
+// It lists folders, like 'payments/', once it reaches
+// them and only if they may contain keys with the prefix.
+//
+// If skipDeleted is set, it reads the metadata of each
+// key once it reaches it and skips deleted keys.
 type iterator struct {
-	values []interface{}
+	ctx         context.Context
+	store       *Store
+	prefix      string
+	skipDeleted bool
+
+	entries []string // Listed entries not returned yet
+	folders []string // Folders not listed yet
+	err     error
 }
 
 var _ kv.Iter[string] = (*iterator)(nil)
 
 func (i *iterator) Next() (string, bool) {
-	for len(i.values) > 0 {
-		v := fmt.Sprint(i.values[0])
-		i.values = i.values[1:]
+	for {
+		for len(i.entries) > 0 {
+			name := i.entries[0]
+			i.entries = i.entries[1:]
 
-		if !strings.HasSuffix(v, "/") { // Ignore prefixes; only iterator over actual entries
-			return v, true
+			if strings.HasSuffix(name, "/") {
+				if strings.HasPrefix(name, i.prefix) || strings.HasPrefix(i.prefix, name) {
+					i.folders = append(i.folders, name)
+				}
+				continue
+			}
+			if !strings.HasPrefix(name, i.prefix) {
+				continue
+			}
+			if i.skipDeleted {
+				meta, err := i.store.readMetadata(i.ctx, name)
+				if err != nil {
+					i.entries, i.folders, i.err = nil, nil, err
+					return "", false
+				}
+				if !meta.Exists || meta.Deleted() || meta.Destroyed {
+					continue
+				}
+			}
+			return name, true
+		}
+		if len(i.folders) == 0 || i.err != nil {
+			return "", false
 		}
+
+		folder := i.folders[len(i.folders)-1]
+		i.folders = i.folders[:len(i.folders)-1]
+		i.entries, i.err = i.store.listFolder(i.ctx, folder)
 	}
-	return "", false
 }
 
-func (*iterator) Close() error { return nil }
+func (i *iterator) Close() error {
+	i.entries, i.folders = nil, nil
+	return i.err
+}
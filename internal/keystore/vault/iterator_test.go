@@ -0,0 +1,165 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	vaultapi "github.com/hashicorp/vault/api"
+)
+
+// testFolders contains the entries of the folders
+// of a K/V v1 engine at the prefix 'kes'.
+var testFolders = map[string][]string{
+	"/v1/kv/kes":               {"my-key", "payments/", "users/"},
+	"/v1/kv/kes/payments":      {"my-key", "prod/"},
+	"/v1/kv/kes/payments/prod": {"db-key", "db-key-2"},
+	"/v1/kv/kes/users":         {"alice"},
+}
+
+var listTests = []struct {
+	Prefix  string
+	Names   []string
+	Folders int32 // Number of listed folders
+}{
+	{ // 0
+		Prefix:  "",
+		Names:   []string{"my-key", "payments/my-key", "payments/prod/db-key", "payments/prod/db-key-2", "users/alice"},
+		Folders: 4,
+	},
+	{ // 1
+		Prefix:  "pay",
+		Names:   []string{"payments/my-key", "payments/prod/db-key", "payments/prod/db-key-2"},
+		Folders: 3,
+	},
+	{ // 2
+		Prefix:  "payments/prod/",
+		Names:   []string{"payments/prod/db-key", "payments/prod/db-key-2"},
+		Folders: 1,
+	},
+	{ // 3
+		Prefix:  "payments/prod/db-key-",
+		Names:   []string{"payments/prod/db-key-2"},
+		Folders: 1,
+	},
+	{ // 4
+		Prefix:  "unknown/",
+		Names:   nil,
+		Folders: 1,
+	},
+}
+
+func TestList(t *testing.T) {
+	var listed int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&listed, 1)
+		entries, ok := testFolders[strings.TrimSuffix(r.URL.Path, "/")]
+		if r.Method != "LIST" || !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"keys": entries}})
+	}))
+	defer server.Close()
+
+	config := vaultapi.DefaultConfig()
+	config.Address = server.URL
+	vaultClient, err := vaultapi.NewClient(config)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	store := &Store{
+		client: &client{Client: vaultClient},
+		config: &Config{Engine: "kv", Prefix: "kes", APIVersion: APIv1},
+	}
+
+	for i, test := range listTests {
+		atomic.StoreInt32(&listed, 0)
+		names, err := store.list(context.Background(), test.Prefix)
+		if err != nil {
+			t.Fatalf("Test %d: failed to list keys: %v", i, err)
+		}
+		sort.Strings(names)
+		if strings.Join(names, ",") != strings.Join(test.Names, ",") {
+			t.Fatalf("Test %d: got %v - want %v", i, names, test.Names)
+		}
+		if n := atomic.LoadInt32(&listed); n != test.Folders {
+			t.Fatalf("Test %d: listed %d folders - want %d", i, n, test.Folders)
+		}
+	}
+}
+
+func TestListSoftDelete(t *testing.T) {
+	const (
+		Active  = `{"data":{"current_version":1,"versions":{"1":{"deletion_time":"","destroyed":false}}}}`
+		Deleted = `{"data":{"current_version":1,"versions":{"1":{"deletion_time":"2023-01-12T08:15:00Z","destroyed":false}}}}`
+	)
+	folders := map[string][]string{
+		"/v1/kv/metadata/kes":          {"my-key", "deleted-key", "payments/"},
+		"/v1/kv/metadata/kes/payments": {"db-key"},
+	}
+	metadata := map[string]string{
+		"/v1/kv/metadata/kes/my-key":          Active,
+		"/v1/kv/metadata/kes/deleted-key":     Deleted,
+		"/v1/kv/metadata/kes/payments/db-key": Active,
+	}
+
+	var reads int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if entries, ok := folders[strings.TrimSuffix(r.URL.Path, "/")]; ok && r.Method == "LIST" {
+			json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"keys": entries}})
+			return
+		}
+		if meta, ok := metadata[r.URL.Path]; ok && r.Method == http.MethodGet {
+			atomic.AddInt32(&reads, 1)
+			w.Write([]byte(meta))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	config := vaultapi.DefaultConfig()
+	config.Address = server.URL
+	vaultClient, err := vaultapi.NewClient(config)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	store := &Store{
+		client: &client{Client: vaultClient},
+		config: &Config{Engine: "kv", Prefix: "kes", APIVersion: APIv2, SoftDelete: true},
+	}
+
+	iter, err := store.List(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	defer iter.Close()
+	if n := atomic.LoadInt32(&reads); n != 0 {
+		t.Fatalf("List read metadata of %d keys before iterating", n)
+	}
+
+	var names []string
+	for name, ok := iter.Next(); ok; name, ok = iter.Next() {
+		names = append(names, name)
+	}
+	if err = iter.Close(); err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	sort.Strings(names)
+	if want := []string{"my-key", "payments/db-key"}; strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Fatalf("got %v - want %v", names, want)
+	}
+	if n := atomic.LoadInt32(&reads); n != 3 {
+		t.Fatalf("Read metadata of %d entries - want 3", n)
+	}
+}
@@ -0,0 +1,249 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	vaultapi "github.com/hashicorp/vault/api"
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/kv"
+)
+
+// RecoverableStore is a Hashicorp Vault secret store that
+// deletes keys softly. It requires a K/V v2 engine.
+//
+// Deleting a key only deletes its current version. A
+// deleted key can be recovered (undeleted) until it gets
+// purged. Purging a key destroys all its versions and
+// its metadata permanently.
+type RecoverableStore struct {
+	*Store
+}
+
+var (
+	_ kv.Store[string, []byte] = (*RecoverableStore)(nil)
+	_ kv.Recoverer[string]     = (*RecoverableStore)(nil)
+)
+
+// Recoverable returns a RecoverableStore if s deletes
+// keys softly. Otherwise, it returns s.
+func (s *Store) Recoverable() kv.Store[string, []byte] {
+	if s.config.APIVersion == APIv2 && s.config.SoftDelete {
+		return &RecoverableStore{Store: s}
+	}
+	return s
+}
+
+// ListDeleted returns a new Iterator over all keys whose
+// current version has been deleted but not destroyed.
+func (s *RecoverableStore) ListDeleted(ctx context.Context) (kv.Iter[kv.Deleted[string]], error) {
+	if s.client.Sealed() {
+		return nil, errSealed
+	}
+
+	names, err := s.list(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	var deleted []kv.Deleted[string]
+	for _, name := range names {
+		meta, err := s.readMetadata(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if !meta.Exists || !meta.Deleted() {
+			continue
+		}
+		deleted = append(deleted, kv.Deleted[string]{
+			Key:       name,
+			DeletedAt: meta.DeletedAt,
+		})
+	}
+	return &deletedIter{values: deleted}, nil
+}
+
+// Recover undeletes the current version of the deleted
+// key. It returns kes.ErrKeyNotFound if no such deleted
+// key exists and kes.ErrKeyExists if the key is not
+// deleted.
+func (s *RecoverableStore) Recover(ctx context.Context, name string) error {
+	if s.client.Sealed() {
+		return errSealed
+	}
+
+	meta, err := s.readMetadata(ctx, name)
+	if err != nil {
+		return err
+	}
+	if !meta.Exists || meta.Destroyed {
+		return kes.ErrKeyNotFound
+	}
+	if !meta.Deleted() {
+		return kes.ErrKeyExists
+	}
+
+	// See: https://www.vaultproject.io/api/secret/kv/kv-v2#undelete-secret-versions
+	location := path.Join(s.config.Engine, "undelete", s.config.Prefix, name) // /<engine>/undelete/<location>/<name>
+	req := s.client.Client.NewRequest(http.MethodPost, "/v1/"+location)
+	if err = req.SetJSONBody(map[string]interface{}{
+		"versions": []int{meta.CurrentVersion},
+	}); err != nil {
+		return fmt.Errorf("vault: failed to recover '%s': %v", location, err)
+	}
+	return s.send(ctx, req, "recover", location)
+}
+
+// Purge destroys all versions and the metadata of the
+// deleted key permanently. It returns kes.ErrKeyNotFound
+// if no such deleted key exists.
+func (s *RecoverableStore) Purge(ctx context.Context, name string) error {
+	if s.client.Sealed() {
+		return errSealed
+	}
+
+	meta, err := s.readMetadata(ctx, name)
+	if err != nil {
+		return err
+	}
+	if !meta.Exists || !(meta.Deleted() || meta.Destroyed) {
+		return kes.ErrKeyNotFound
+	}
+
+	// See: https://www.vaultproject.io/api/secret/kv/kv-v2#delete-metadata-and-all-versions
+	location := path.Join(s.config.Engine, "metadata", s.config.Prefix, name) // /<engine>/metadata/<location>/<name>
+	req := s.client.Client.NewRequest(http.MethodDelete, "/v1/"+location)
+	return s.send(ctx, req, "purge", location)
+}
+
+// metadata is the metadata of a K/V v2 entry and
+// its current version.
+type metadata struct {
+	Exists         bool
+	CurrentVersion int
+	DeletedAt      time.Time // Zero, if the current version is not deleted
+	Destroyed      bool      // Whether the current version is destroyed
+}
+
+// Deleted reports whether the current version of
+// the entry is deleted but not destroyed.
+func (m *metadata) Deleted() bool { return !m.DeletedAt.IsZero() && !m.Destroyed }
+
+// readMetadata returns the metadata of the K/V v2 entry
+// with the given name.
+func (s *Store) readMetadata(ctx context.Context, name string) (metadata, error) {
+	// See: https://www.vaultproject.io/api/secret/kv/kv-v2#read-secret-metadata
+	location := path.Join(s.config.Engine, "metadata", s.config.Prefix, name) // /<engine>/metadata/<location>/<name>
+	secret, err := s.client.Logical().ReadWithContext(ctx, location)
+	if err != nil {
+		return metadata{}, fmt.Errorf("vault: failed to read '%s': %v", location, err)
+	}
+	if secret == nil || len(secret.Data) == 0 {
+		return metadata{}, nil
+	}
+	return parseMetadata(secret)
+}
+
+// writeMetadata attaches the custom metadata to the K/V v2
+// entry with the given name. The entry does not have to
+// exist.
+func (s *Store) writeMetadata(ctx context.Context, name string, custom map[string]string) error {
+	// See: https://www.vaultproject.io/api/secret/kv/kv-v2#create-update-metadata
+	location := path.Join(s.config.Engine, "metadata", s.config.Prefix, name) // /<engine>/metadata/<location>/<name>
+	req := s.client.Client.NewRequest(http.MethodPost, "/v1/"+location)
+	if err := req.SetJSONBody(map[string]interface{}{
+		"custom_metadata": custom,
+	}); err != nil {
+		return fmt.Errorf("vault: failed to write metadata '%s': %v", location, err)
+	}
+	return s.send(ctx, req, "write metadata", location)
+}
+
+// send sends the request to Vault and expects either
+// an HTTP 204 No Content or 200 OK response.
+func (s *Store) send(ctx context.Context, req *vaultapi.Request, op, location string) error {
+	resp, err := s.client.Client.RawRequestWithContext(ctx, req)
+	if err != nil {
+		return fmt.Errorf("vault: failed to %s '%s': %v", op, location, err)
+	}
+	if resp != nil && resp.Body != nil {
+		defer resp.Body.Close()
+	}
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		if _, err = vaultapi.ParseSecret(resp.Body); err != nil {
+			return fmt.Errorf("vault: failed to %s '%s': %v", op, location, err)
+		}
+		return fmt.Errorf("vault: failed to %s '%s': server responded with: %s (%d)", op, location, resp.Status, resp.StatusCode)
+	}
+	return nil
+}
+
+// parseMetadata parses the K/V v2 metadata response.
+//
+// Ref: https://www.vaultproject.io/api/secret/kv/kv-v2#sample-response-2
+func parseMetadata(secret *vaultapi.Secret) (metadata, error) {
+	current, err := parseVersion(secret.Data["current_version"])
+	if err != nil {
+		return metadata{}, fmt.Errorf("vault: invalid K/V v2 metadata: invalid 'current_version' entry: %v", err)
+	}
+	meta := metadata{
+		Exists:         true,
+		CurrentVersion: current,
+	}
+
+	versions, ok := secret.Data["versions"].(map[string]interface{})
+	if !ok {
+		return meta, nil
+	}
+	version, ok := versions[strconv.Itoa(current)].(map[string]interface{})
+	if !ok {
+		return meta, nil
+	}
+	if deletedAt, ok := version["deletion_time"].(string); ok && deletedAt != "" {
+		if meta.DeletedAt, err = time.Parse(time.RFC3339Nano, deletedAt); err != nil {
+			return metadata{}, fmt.Errorf("vault: invalid K/V v2 metadata: invalid 'deletion_time' entry: %v", err)
+		}
+	}
+	if destroyed, ok := version["destroyed"].(bool); ok {
+		meta.Destroyed = destroyed
+	}
+	return meta, nil
+}
+
+// parseVersion parses a K/V v2 version number.
+// The Vault SDK decodes JSON numbers as json.Number.
+func parseVersion(v interface{}) (int, error) {
+	switch v := v.(type) {
+	case fmt.Stringer:
+		return strconv.Atoi(v.String())
+	case float64:
+		return int(v), nil
+	case int:
+		return v, nil
+	default:
+		return 0, errors.New("not a number")
+	}
+}
+
+type deletedIter struct {
+	values []kv.Deleted[string]
+}
+
+func (i *deletedIter) Next() (kv.Deleted[string], bool) {
+	if len(i.values) == 0 {
+		return kv.Deleted[string]{}, false
+	}
+	v := i.values[0]
+	i.values = i.values[1:]
+	return v, true
+}
+
+func (*deletedIter) Close() error { return nil }
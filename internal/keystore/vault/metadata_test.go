@@ -0,0 +1,52 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package vault
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	vaultapi "github.com/hashicorp/vault/api"
+)
+
+func TestParseMetadata(t *testing.T) {
+	for i, test := range parseMetadataTests {
+		secret, err := vaultapi.ParseSecret(strings.NewReader(test.Response))
+		if err != nil {
+			t.Fatalf("Test %d: failed to parse response: %v", i, err)
+		}
+		meta, err := parseMetadata(secret)
+		if err != nil {
+			t.Fatalf("Test %d: failed to parse metadata: %v", i, err)
+		}
+		if meta != test.Metadata {
+			t.Fatalf("Test %d: got '%+v' - want '%+v'", i, meta, test.Metadata)
+		}
+		if meta.Deleted() != test.Deleted {
+			t.Fatalf("Test %d: got deleted '%v' - want '%v'", i, meta.Deleted(), test.Deleted)
+		}
+	}
+}
+
+var parseMetadataTests = []struct {
+	Response string
+	Metadata metadata
+	Deleted  bool
+}{
+	{ // 0
+		Response: `{"data":{"current_version":1,"versions":{"1":{"created_time":"2023-01-12T08:13:41.134Z","deletion_time":"","destroyed":false}}}}`,
+		Metadata: metadata{Exists: true, CurrentVersion: 1},
+	},
+	{ // 1
+		Response: `{"data":{"current_version":2,"versions":{"1":{"deletion_time":"","destroyed":false},"2":{"deletion_time":"2023-01-12T08:15:00.5Z","destroyed":false}}}}`,
+		Metadata: metadata{Exists: true, CurrentVersion: 2, DeletedAt: time.Date(2023, 1, 12, 8, 15, 0, 500000000, time.UTC)},
+		Deleted:  true,
+	},
+	{ // 2
+		Response: `{"data":{"current_version":1,"versions":{"1":{"deletion_time":"2023-01-12T08:15:00Z","destroyed":true}}}}`,
+		Metadata: metadata{Exists: true, CurrentVersion: 1, DeletedAt: time.Date(2023, 1, 12, 8, 15, 0, 0, time.UTC), Destroyed: true},
+	},
+}
@@ -13,17 +13,22 @@
 package vault
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"path"
+	"strings"
 	"time"
 
 	"aead.dev/mem"
 	vaultapi "github.com/hashicorp/vault/api"
 	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/https"
+	"github.com/minio/kes/internal/keystore/batch"
+	"github.com/minio/kes/internal/keystore/cas"
 	"github.com/minio/kes/kv"
 )
 
@@ -31,6 +36,8 @@ import (
 type Store struct {
 	client *client
 	config *Config
+
+	locks cas.Locker // Serializes K/V v1 updates
 }
 
 // Connect connects to a Hashicorp Vault server with
@@ -41,9 +48,6 @@ func Connect(ctx context.Context, c *Config) (*Store, error) {
 	if c.Engine == "" {
 		c.Engine = EngineKV
 	}
-	if c.APIVersion == "" {
-		c.APIVersion = APIv1
-	}
 	if c.AppRole.Retry == 0 {
 		c.AppRole.Retry = 5 * time.Second
 	}
@@ -56,6 +60,26 @@ func Connect(ctx context.Context, c *Config) (*Store, error) {
 	if c.K8S.Retry == 0 {
 		c.K8S.Retry = 5 * time.Second
 	}
+	if c.AWS != nil {
+		aws := *c.AWS // Don't modify the caller's AWS config
+		if aws.Engine == "" {
+			aws.Engine = EngineAWS
+		}
+		if aws.Retry == 0 {
+			aws.Retry = 5 * time.Second
+		}
+		c.AWS = &aws
+	}
+	if c.Cert != nil {
+		cert := *c.Cert // Don't modify the caller's cert config
+		if cert.Engine == "" {
+			cert.Engine = EngineCert
+		}
+		if cert.Retry == 0 {
+			cert.Retry = 5 * time.Second
+		}
+		c.Cert = &cert
+	}
 	if c.StatusPingAfter == 0 {
 		c.StatusPingAfter = 15 * time.Second
 	}
@@ -63,19 +87,55 @@ func Connect(ctx context.Context, c *Config) (*Store, error) {
 	if c.Endpoint == "" {
 		return nil, fmt.Errorf("vault: endpoint is empty")
 	}
-	if c.APIVersion != APIv1 && c.APIVersion != APIv2 {
+	if c.APIVersion != "" && c.APIVersion != APIv1 && c.APIVersion != APIv2 {
 		return nil, fmt.Errorf("vault: invalid engine API version '%s'", c.APIVersion)
 	}
-	if (c.AppRole.ID == "" || c.AppRole.Secret == "") && (c.K8S.JWT == "" || c.K8S.Role == "") {
+	if c.APIVersion == APIv1 && c.SoftDelete {
+		return nil, errors.New("vault: soft delete requires a K/V v2 engine")
+	}
+	if c.APIVersion == APIv1 && len(c.CustomMetadata) > 0 {
+		return nil, errors.New("vault: custom metadata requires a K/V v2 engine")
+	}
+
+	var methods []string
+	if c.AppRole.ID != "" || c.AppRole.Secret != "" {
+		if c.AppRole.ID == "" || c.AppRole.Secret == "" {
+			return nil, errors.New("vault: approle authentication requires a role ID and secret ID")
+		}
+		methods = append(methods, "approle")
+	}
+	if c.K8S.Role != "" || c.K8S.JWT != "" || c.K8S.JWTFile != "" {
+		if c.K8S.Role == "" || (c.K8S.JWT == "" && c.K8S.JWTFile == "") {
+			return nil, errors.New("vault: kubernetes authentication requires a role and a JWT")
+		}
+		methods = append(methods, "kubernetes")
+	}
+	if c.AWS != nil {
+		if c.AWS.Role == "" {
+			return nil, errors.New("vault: aws authentication requires a role")
+		}
+		methods = append(methods, "aws")
+	}
+	if c.Cert != nil {
+		if c.Certificate == "" {
+			return nil, errors.New("vault: cert authentication requires a TLS client certificate")
+		}
+		methods = append(methods, "cert")
+	}
+	switch len(methods) {
+	case 0:
 		return nil, errors.New("vault: no authentication method specified")
+	case 1:
+	default:
+		return nil, fmt.Errorf("vault: ambigious authentication: %s method specified", strings.Join(methods, " and "))
 	}
-	if (c.AppRole.ID != "" || c.AppRole.Secret != "") && (c.K8S.JWT != "" || c.K8S.Role != "") {
-		return nil, errors.New("vault: ambigious authentication: approle and kubernetes method specified")
+
+	if (c.PrivateKey == "") != (c.Certificate == "") {
+		return nil, errors.New("vault: TLS private key and certificate must be specified both")
 	}
 
 	tlsConfig := &vaultapi.TLSConfig{
-		ClientKey:  c.PrivateKey,
-		ClientCert: c.Certificate,
+		TLSServerName: c.ServerName,
 	}
 	if c.CAPath != "" {
 		stat, err := os.Stat(c.CAPath)
@@ -91,7 +151,19 @@ func Connect(ctx context.Context, c *Config) (*Store, error) {
 
 	config := vaultapi.DefaultConfig()
 	config.Address = c.Endpoint
-	config.ConfigureTLS(tlsConfig)
+	if err := config.ConfigureTLS(tlsConfig); err != nil {
+		return nil, fmt.Errorf("vault: invalid TLS config: %v", err)
+	}
+	if c.Certificate != "" {
+		// The Vault client loads a client certificate only once.
+		// Hence, we set our own client certificate loader that
+		// reloads the certificate once it has been rotated.
+		getClientCertificate, err := https.ClientCertificate(c.Certificate, c.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("vault: failed to load TLS client certificate: %v", err)
+		}
+		config.HttpClient.Transport.(*http.Transport).TLSClientConfig.GetClientCertificate = getClientCertificate
+	}
 	vaultClient, err := vaultapi.NewClient(config)
 	if err != nil {
 		return nil, err
@@ -111,18 +183,24 @@ func Connect(ctx context.Context, c *Config) (*Store, error) {
 		authenticate authFunc
 		retry        time.Duration
 	)
-	switch {
-	case c.AppRole.ID != "" || c.AppRole.Secret != "":
+	switch methods[0] {
+	case "approle":
 		authenticate, retry = client.AuthenticateWithAppRole(c.AppRole), c.AppRole.Retry
-	case c.K8S.Role != "" || c.K8S.JWT != "":
-		jwt, err := os.ReadFile(c.K8S.JWT) // The JWT may be a file path containing the actaul token
-		if err != nil && !errors.Is(err, os.ErrNotExist) {
-			return nil, err
-		}
-		if err == nil {
-			c.K8S.JWT = string(jwt)
+	case "kubernetes":
+		if c.K8S.JWT != "" {
+			jwt, err := os.ReadFile(c.K8S.JWT) // The JWT may be a file path containing the actaul token
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return nil, err
+			}
+			if err == nil {
+				c.K8S.JWT = string(jwt)
+			}
 		}
 		authenticate, retry = client.AuthenticateWithK8S(c.K8S), c.K8S.Retry
+	case "aws":
+		authenticate, retry = client.AuthenticateWithAWS(*c.AWS), c.AWS.Retry
+	case "cert":
+		authenticate, retry = client.AuthenticateWithCert(*c.Cert), c.Cert.Retry
 	}
 
 	token, ttl, err := authenticate()
@@ -131,6 +209,18 @@ func Connect(ctx context.Context, c *Config) (*Store, error) {
 	}
 	client.SetToken(token)
 
+	if c.APIVersion == "" {
+		// The mount information is only accessible once
+		// authenticated. If it is not accessible, e.g. due
+		// to missing permissions, we fall back to K/V v1.
+		if c.APIVersion, err = client.DiscoverKVVersion(ctx, c.Engine); err != nil {
+			c.APIVersion = APIv1
+		}
+		if c.APIVersion != APIv2 && (c.SoftDelete || len(c.CustomMetadata) > 0) {
+			return nil, fmt.Errorf("vault: '%s' is not a K/V v2 engine: soft delete and custom metadata require a K/V v2 engine", c.Engine)
+		}
+	}
+
 	go client.CheckStatus(ctx, c.StatusPingAfter)
 	go client.RenewToken(ctx, authenticate, ttl, retry)
 	return &Store{
@@ -169,7 +259,10 @@ func (s *Store) Status(ctx context.Context) (kv.State, error) {
 		case health.Sealed:
 			return kv.State{}, &kv.Unavailable{Err: errSealed}
 		default:
-			return kv.State{Latency: time.Since(start)}, nil
+			return kv.State{
+				Latency:         time.Since(start),
+				Unauthenticated: s.client.Unauthenticated(),
+			}, nil
 		}
 	}
 	if errors.Is(err, context.Canceled) && errors.Is(err, context.DeadlineExceeded) {
@@ -252,6 +345,26 @@ func (s *Store) Create(ctx context.Context, name string, value []byte) error {
 		return fmt.Errorf("vault: failed to create '%s': %v", location, err)
 	}
 
+	if s.config.APIVersion == APIv2 && s.config.SoftDelete {
+		// A K/V v2 entry, whose current version has been deleted,
+		// still exists. It has to be recovered or purged first.
+		meta, err := s.readMetadata(ctx, name)
+		if err != nil {
+			return err
+		}
+		if meta.Exists && meta.Deleted() {
+			return fmt.Errorf("vault: failed to create '%s': %w: either recover or purge '%s'", location, kv.ErrDeleted, name)
+		}
+	}
+	if s.config.APIVersion == APIv2 && len(s.config.CustomMetadata) > 0 {
+		// Metadata can be written before the entry exists.
+		// Writing it first ensures that a key never exists
+		// without its custom metadata.
+		if err := s.writeMetadata(ctx, name, s.config.CustomMetadata); err != nil {
+			return err
+		}
+	}
+
 	// Finally, we create the value since it seems that it
 	// doesn't exist. However, this is just an assumption since
 	// another key server may have created that key in the meantime.
@@ -311,11 +424,87 @@ func (s *Store) Set(ctx context.Context, name string, value []byte) error {
 	return s.Create(ctx, name, value)
 }
 
+// Update replaces the value of the given key with newValue
+// if and only if its current value is equal to oldValue. If
+// no entry for the key exists it returns kes.ErrKeyNotFound.
+//
+// With K/V v2, Update uses the check-and-set option such
+// that the entry is only replaced if its current version
+// has not changed in between. K/V v1 does not support
+// check-and-set. Hence, concurrent updates by multiple
+// KES servers may race.
+func (s *Store) Update(ctx context.Context, name string, oldValue, newValue []byte) error {
+	if s.client.Sealed() {
+		return errSealed
+	}
+	if s.config.APIVersion != APIv2 {
+		// See: https://www.vaultproject.io/api/secret/kv/kv-v1#create-update-secret
+		location := path.Join(s.config.Engine, s.config.Prefix, name) // /<engine>/<location>/<name>
+		return s.locks.Update(ctx, s, name, oldValue, newValue, func(ctx context.Context, name string, _, value []byte) error {
+			req := s.client.Client.NewRequest(http.MethodPut, "/v1/"+location)
+			if err := req.SetJSONBody(map[string]interface{}{name: string(value)}); err != nil {
+				return fmt.Errorf("vault: failed to update '%s': %v", location, err)
+			}
+			return s.send(ctx, req, "update", location)
+		})
+	}
+
+	value, version, err := s.get(name)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(value, oldValue) {
+		return kv.ErrConflict
+	}
+
+	// See: https://www.vaultproject.io/api/secret/kv/kv-v2#create-update-secret
+	location := path.Join(s.config.Engine, "data", s.config.Prefix, name) // /<engine>/data/<location>/<name>
+	req := s.client.Client.NewRequest(http.MethodPut, "/v1/"+location)
+	if err = req.SetJSONBody(map[string]interface{}{
+		"options": map[string]interface{}{
+			"cas": version, // Only write if the current version has not changed.
+		},
+		"data": map[string]interface{}{
+			name: string(newValue),
+		},
+	}); err != nil {
+		return fmt.Errorf("vault: failed to update '%s': %v", location, err)
+	}
+	resp, err := s.client.Client.RawRequestWithContext(ctx, req)
+	if resp != nil && resp.Body != nil {
+		defer resp.Body.Close()
+	}
+	if err != nil {
+		if strings.Contains(err.Error(), "check-and-set parameter did not match") {
+			return kv.ErrConflict
+		}
+		return fmt.Errorf("vault: failed to update '%s': %v", location, err)
+	}
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("vault: failed to update '%s': server responded with: %s (%d)", location, resp.Status, resp.StatusCode)
+	}
+	return nil
+}
+
 // Get returns the value associated with the given key.
 // If no entry for the key exists it returns kes.ErrKeyNotFound.
 func (s *Store) Get(_ context.Context, name string) ([]byte, error) {
+	value, _, err := s.get(name)
+	return value, err
+}
+
+// GetMany returns the values associated with the given keys. It
+// sends up to batch.Concurrency requests concurrently since
+// Vault has no batch API for K/V secrets.
+func (s *Store) GetMany(ctx context.Context, names []string) ([][]byte, []error) {
+	return batch.Get[string, []byte](ctx, s, names)
+}
+
+// get returns the value associated with the given key
+// and, for K/V v2, the version of the value.
+func (s *Store) get(name string) ([]byte, int, error) {
 	if s.client.Sealed() {
-		return nil, errSealed
+		return nil, 0, errSealed
 	}
 
 	var location string
@@ -331,44 +520,57 @@ func (s *Store) Get(_ context.Context, name string) ([]byte, error) {
 		// Vault will not return an error if e.g. the key existed but has
 		// been deleted. However, it will return (nil, nil) in this case.
 		if err == nil && entry == nil {
-			return nil, kes.ErrKeyNotFound
+			return nil, 0, kes.ErrKeyNotFound
 		}
-		return nil, fmt.Errorf("vault: failed to read '%s': %v", location, err)
+		return nil, 0, fmt.Errorf("vault: failed to read '%s': %v", location, err)
 	}
 
+	var version int
 	data := entry.Data
 	if s.config.APIVersion == APIv2 { // See: https://www.vaultproject.io/api/secret/kv/kv-v2#sample-response-1 (differs from v1 format)
+		if meta, ok := entry.Data["metadata"].(map[string]interface{}); ok {
+			if version, err = parseVersion(meta["version"]); err != nil {
+				return nil, 0, fmt.Errorf("vault: failed to read '%s': invalid K/V v2 format: invalid 'version' entry: %v", location, err)
+			}
+		}
 		v, ok := entry.Data["data"]
 		if !ok || v == nil {
-			return nil, fmt.Errorf("vault: failed to read '%s': invalid K/V v2 format: missing 'data' entry", location)
+			return nil, 0, fmt.Errorf("vault: failed to read '%s': invalid K/V v2 format: missing 'data' entry", location)
 		}
 		data, ok = v.(map[string]interface{})
 		if !ok || data == nil {
-			return nil, fmt.Errorf("vault: failed to read '%s': invalid K/V v2 format: invalid 'data' entry", location)
+			return nil, 0, fmt.Errorf("vault: failed to read '%s': invalid K/V v2 format: invalid 'data' entry", location)
 		}
 	}
 
 	// Verify that we got a well-formed response from Vault
 	v, ok := data[name]
 	if !ok || v == nil {
-		return nil, fmt.Errorf("vault: failed to read '%s': entry exists but no secret key is present", location)
+		return nil, 0, fmt.Errorf("vault: failed to read '%s': entry exists but no secret key is present", location)
 	}
 	value, ok := v.(string)
 	if !ok {
-		return nil, fmt.Errorf("vault: failed to read '%s': invalid K/V format", location)
+		return nil, 0, fmt.Errorf("vault: failed to read '%s': invalid K/V format", location)
 	}
-	return []byte(value), nil
+	return []byte(value), version, nil
 }
 
 // Delete removes a the value associated with the given key
 // from Vault, if it exists.
+//
+// If keys are deleted softly, Delete only deletes the
+// current version of the key. Otherwise, it deletes
+// all versions and the metadata of the key.
 func (s *Store) Delete(ctx context.Context, name string) error {
 	if s.client.Sealed() {
 		return errSealed
 	}
 
 	var location string
-	if s.config.APIVersion == APIv2 {
+	if s.config.APIVersion == APIv2 && s.config.SoftDelete {
+		// See: https://www.vaultproject.io/api/secret/kv/kv-v2#delete-latest-version-of-secret
+		location = path.Join(s.config.Engine, "data", s.config.Prefix, name) // /<engine>/data/<location>/<name>
+	} else if s.config.APIVersion == APIv2 {
 		// See: https://www.vaultproject.io/api/secret/kv/kv-v2#delete-metadata-and-all-versions
 		location = path.Join(s.config.Engine, "metadata", s.config.Prefix, name) // /<engine>/metadata/<location>/<name>
 	} else {
@@ -400,13 +602,76 @@ func (s *Store) Delete(ctx context.Context, name string) error {
 	return nil
 }
 
+// DeleteMany deletes the given keys. Vault has no batch API for
+// K/V secrets. Hence, DeleteMany sends up to batch.Concurrency
+// requests concurrently.
+func (s *Store) DeleteMany(ctx context.Context, names []string) []error {
+	return batch.Delete[string, []byte](ctx, s, names)
+}
+
 // List returns a new Iterator over the names of
-// all stored keys.
-func (s *Store) List(ctx context.Context) (kv.Iter[string], error) {
+// all stored keys that start with the given prefix.
+//
+// Vault lists the entries of one folder at a time.
+// The Iterator descends into the folders of keys
+// with hierarchical names, like 'payments/db-key',
+// as it traverses them.
+//
+// If keys are deleted softly, the Iterator reads
+// the metadata of each key as it traverses it and
+// skips any deleted key.
+func (s *Store) List(ctx context.Context, prefix string) (kv.Iter[string], error) {
 	if s.client.Sealed() {
 		return nil, errSealed
 	}
 
+	// Start at the deepest folder that contains
+	// all keys with the given prefix.
+	folder := prefix[:strings.LastIndexByte(prefix, '/')+1]
+	entries, err := s.listFolder(ctx, folder)
+	if err != nil {
+		return nil, err
+	}
+	return &iterator{
+		ctx:     ctx,
+		store:   s,
+		prefix:  prefix,
+		entries: entries,
+
+		// A K/V v2 listing contains all entries with metadata,
+		// including those whose current version is deleted.
+		skipDeleted: s.config.APIVersion == APIv2 && s.config.SoftDelete,
+	}, nil
+}
+
+// list returns the names of all keys that start
+// with the given prefix.
+func (s *Store) list(ctx context.Context, prefix string) ([]string, error) {
+	entries, err := s.listFolder(ctx, prefix[:strings.LastIndexByte(prefix, '/')+1])
+	if err != nil {
+		return nil, err
+	}
+	iter := &iterator{
+		ctx:     ctx,
+		store:   s,
+		prefix:  prefix,
+		entries: entries,
+	}
+
+	var names []string
+	for name, ok := iter.Next(); ok; name, ok = iter.Next() {
+		names = append(names, name)
+	}
+	if err = iter.Close(); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// listFolder returns the names of all entries within
+// the given folder at the K/V engine prefix. Folders
+// within the folder end with a '/'.
+func (s *Store) listFolder(ctx context.Context, folder string) ([]string, error) {
 	// We don't use the Vault SDK vault.Logical.List(string) API
 	// here since the SDK does not allow us to specify a context.
 	// However, if the client closes the connection (or a timeout
@@ -416,16 +681,19 @@ func (s *Store) List(ctx context.Context) (kv.Iter[string], error) {
 	var location string
 	if s.config.APIVersion == APIv2 {
 		// See: https://www.vaultproject.io/api/secret/kv/kv-v2#list-secrets
-		location = path.Join(s.config.Engine, "metadata", s.config.Prefix)
+		location = path.Join(s.config.Engine, "metadata", s.config.Prefix, folder)
 	} else {
 		// See: https://www.vaultproject.io/api/secret/kv/kv-v1#list-secrets
-		location = path.Join(s.config.Engine, s.config.Prefix)
+		location = path.Join(s.config.Engine, s.config.Prefix, folder)
 	}
 
 	r := s.client.NewRequest("LIST", "/v1/"+location)
 	r.Params.Set("list", "true")
 
 	resp, err := s.client.RawRequestWithContext(ctx, r)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil, nil // Vault responds with 404 when the folder is empty or does not exist.
+	}
 	if err != nil {
 		return nil, fmt.Errorf("vault: failed to list '%s': %v", location, err)
 	}
@@ -441,7 +709,7 @@ func (s *Store) List(ctx context.Context) (kv.Iter[string], error) {
 		return nil, fmt.Errorf("vault: failed to list '%s': %v", location, err)
 	}
 	if secret == nil { // The secret may be nil even when there was no error.
-		return &iterator{}, nil // We return an empty iterator in this case.
+		return nil, nil // We return an empty listing in this case.
 	}
 
 	// Vault returns a generic map that should contain
@@ -452,5 +720,9 @@ func (s *Store) List(ctx context.Context) (kv.Iter[string], error) {
 	if !ok {
 		return nil, fmt.Errorf("vault: failed to list '%s': invalid key listing format", location)
 	}
-	return &iterator{values: values}, nil
+	names := make([]string, 0, len(values))
+	for _, value := range values {
+		names = append(names, folder+fmt.Sprint(value))
+	}
+	return names, nil
 }
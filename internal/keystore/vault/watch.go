@@ -0,0 +1,105 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package vault
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/kv"
+)
+
+var _ kv.Watcher[string] = (*Store)(nil)
+
+// Watch polls Vault every Config.WatchInterval and calls f
+// with the name of every key that has been created, changed
+// or deleted since the previous poll, e.g. by another KES
+// server sharing the same K/V engine. It calls f with an
+// empty name if any key may have changed while Vault was
+// not reachable.
+//
+// Vault does not provide a change notification API for the
+// K/V engine. Hence, each poll lists and reads all keys at
+// the K/V prefix. Watch returns immediately if no watch
+// interval has been configured.
+func (s *Store) Watch(ctx context.Context, f func(name string)) error {
+	if s.config.WatchInterval <= 0 {
+		return nil
+	}
+
+	ticker := time.NewTicker(s.config.WatchInterval)
+	defer ticker.Stop()
+
+	var (
+		snapshot map[string][sha256.Size]byte
+		failed   bool
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		next, err := s.snapshot(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			failed = true // Keep the previous snapshot and try again
+			continue
+		}
+		if failed && snapshot != nil {
+			f("")
+		} else {
+			diff(snapshot, next, f)
+		}
+		snapshot, failed = next, false
+	}
+}
+
+// snapshot returns the SHA-256 checksums of the values of
+// all keys at the K/V prefix.
+func (s *Store) snapshot(ctx context.Context) (map[string][sha256.Size]byte, error) {
+	names, err := s.list(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	values, errs := s.GetMany(ctx, names)
+
+	snapshot := make(map[string][sha256.Size]byte, len(names))
+	for i, name := range names {
+		if err = errs[i]; err != nil {
+			if errors.Is(err, kes.ErrKeyNotFound) { // Deleted after listing
+				continue
+			}
+			return nil, err
+		}
+		snapshot[name] = sha256.Sum256(values[i])
+	}
+	return snapshot, nil
+}
+
+// diff calls f with the name of every key that has been
+// created, changed or deleted between the old and the new
+// snapshot. It does not call f if there is no old snapshot.
+func diff(old, new map[string][sha256.Size]byte, f func(string)) {
+	if old == nil {
+		return
+	}
+	for name, sum := range new {
+		if oldSum, ok := old[name]; !ok || oldSum != sum {
+			f(name)
+		}
+	}
+	for name := range old {
+		if _, ok := new[name]; !ok {
+			f(name)
+		}
+	}
+}
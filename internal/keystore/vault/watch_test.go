@@ -0,0 +1,64 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package vault
+
+import (
+	"crypto/sha256"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	sum := func(s string) [sha256.Size]byte { return sha256.Sum256([]byte(s)) }
+
+	for i, test := range diffTests {
+		var old, new map[string][sha256.Size]byte
+		if test.Old != nil {
+			old = make(map[string][sha256.Size]byte, len(test.Old))
+			for name, value := range test.Old {
+				old[name] = sum(value)
+			}
+		}
+		new = make(map[string][sha256.Size]byte, len(test.New))
+		for name, value := range test.New {
+			new[name] = sum(value)
+		}
+
+		var changed []string
+		diff(old, new, func(name string) { changed = append(changed, name) })
+		sort.Strings(changed)
+
+		if got := strings.Join(changed, ","); got != test.Changed {
+			t.Fatalf("Test %d: got '%s' - want '%s'", i, got, test.Changed)
+		}
+	}
+}
+
+var diffTests = []struct {
+	Old, New map[string]string
+	Changed  string
+}{
+	{ // 0
+		Old:     nil,
+		New:     map[string]string{"my-key": "my-value"},
+		Changed: "",
+	},
+	{ // 1
+		Old:     map[string]string{"my-key": "my-value"},
+		New:     map[string]string{"my-key": "my-value"},
+		Changed: "",
+	},
+	{ // 2
+		Old:     map[string]string{"my-key": "my-value", "my-key-2": "my-value"},
+		New:     map[string]string{"my-key": "new-value", "my-key-3": "my-value"},
+		Changed: "my-key,my-key-2,my-key-3",
+	},
+	{ // 3
+		Old:     map[string]string{},
+		New:     map[string]string{"my-key": "my-value"},
+		Changed: "my-key",
+	},
+}
@@ -11,6 +11,8 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/minio/kes/internal/cpu"
+	"github.com/minio/kes/kv"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/expfmt"
 )
@@ -229,6 +231,173 @@ func (m *Metrics) Latency(h http.Handler) http.Handler {
 	})
 }
 
+// RegisterPool registers metrics about the number of active,
+// queued and rejected operations as well as the total wait
+// time of the given pool. The metrics are labeled with the
+// given pool name.
+//
+// RegisterPool does nothing if the pool is nil.
+func (m *Metrics) RegisterPool(name string, pool *cpu.Pool) {
+	if pool == nil {
+		return
+	}
+	labels := prometheus.Labels{"pool": name}
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   "kes",
+		Subsystem:   "crypto",
+		Name:        "pool_workers",
+		Help:        "The max. number of concurrent crypto operations.",
+		ConstLabels: labels,
+	}, func() float64 { return float64(pool.Workers()) }))
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   "kes",
+		Subsystem:   "crypto",
+		Name:        "pool_active",
+		Help:        "Number of crypto operations that are currently running.",
+		ConstLabels: labels,
+	}, func() float64 { return float64(pool.Active()) }))
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   "kes",
+		Subsystem:   "crypto",
+		Name:        "pool_queued",
+		Help:        "Number of crypto operations that are waiting for a worker.",
+		ConstLabels: labels,
+	}, func() float64 { return float64(pool.Queued()) }))
+	m.registry.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Namespace:   "kes",
+		Subsystem:   "crypto",
+		Name:        "pool_rejected",
+		Help:        "Number of crypto operations that have been rejected since the queue was full.",
+		ConstLabels: labels,
+	}, func() float64 { return float64(pool.Rejected()) }))
+	m.registry.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Namespace:   "kes",
+		Subsystem:   "crypto",
+		Name:        "pool_wait_time",
+		Help:        "Total time in seconds crypto operations have been waiting for a worker.",
+		ConstLabels: labels,
+	}, func() float64 { return pool.WaitTime().Seconds() }))
+}
+
+// RegisterCache registers metrics about the number of
+// keystore cache hits and misses counted by stats.
+//
+// RegisterCache does nothing if stats is nil.
+func (m *Metrics) RegisterCache(stats *kv.CacheStats) {
+	if stats == nil {
+		return
+	}
+	m.registry.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Namespace: "kes",
+		Subsystem: "keystore",
+		Name:      "cache_hits",
+		Help:      "Number of keystore lookups that have been served from the keystore cache.",
+	}, func() float64 { return float64(stats.Hits()) }))
+	m.registry.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Namespace: "kes",
+		Subsystem: "keystore",
+		Name:      "cache_misses",
+		Help:      "Number of keystore lookups that have not been served from the keystore cache.",
+	}, func() float64 { return float64(stats.Misses()) }))
+}
+
+// RegisterReadOnly registers a metric that reports whether
+// the server is in read-only mode. The metric is 1 when
+// readOnly returns true and 0 otherwise.
+func (m *Metrics) RegisterReadOnly(readOnly func() bool) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: "kes",
+		Subsystem: "system",
+		Name:      "read_only",
+		Help:      "Indicates whether the server is in read-only mode.",
+	}, func() float64 {
+		if readOnly() {
+			return 1
+		}
+		return 0
+	}))
+}
+
+// RegisterCertificate registers a metric that reports the
+// expiry of the server's TLS certificate as seconds since
+// the Unix epoch. The metric is 0 when notAfter returns the
+// zero time.
+func (m *Metrics) RegisterCertificate(notAfter func() time.Time) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: "kes",
+		Subsystem: "tls",
+		Name:      "certificate_not_after",
+		Help:      "The expiry date of the server's TLS certificate in seconds since the Unix epoch.",
+	}, func() float64 {
+		t := notAfter()
+		if t.IsZero() {
+			return 0
+		}
+		return float64(t.Unix())
+	}))
+}
+
+// RegisterRateLimit registers a metric that reports the number
+// of requests rejected since an identity or enclave exceeded
+// its rate limit. The 'scope' label is either "identity" or
+// "enclave".
+func (m *Metrics) RegisterRateLimit(rejected func() (identity, enclave uint64)) {
+	m.registry.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Namespace:   "kes",
+		Subsystem:   "http",
+		Name:        "request_rate_limited",
+		Help:        "Number of requests rejected since the rate limit has been exceeded.",
+		ConstLabels: prometheus.Labels{"scope": "identity"},
+	}, func() float64 { n, _ := rejected(); return float64(n) }))
+	m.registry.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Namespace:   "kes",
+		Subsystem:   "http",
+		Name:        "request_rate_limited",
+		Help:        "Number of requests rejected since the rate limit has been exceeded.",
+		ConstLabels: prometheus.Labels{"scope": "enclave"},
+	}, func() float64 { _, n := rejected(); return float64(n) }))
+}
+
+// RegisterRequestLimit registers metrics that report the
+// max. number of requests served concurrently and the number
+// of requests waiting, if pool is not nil, and the number of
+// requests shed since the server has been busy.
+func (m *Metrics) RegisterRequestLimit(pool *cpu.Pool, shed func() uint64) {
+	if pool != nil {
+		m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: "kes",
+			Subsystem: "http",
+			Name:      "request_concurrency_limit",
+			Help:      "The max. number of requests served concurrently.",
+		}, func() float64 { return float64(pool.Workers()) }))
+		m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: "kes",
+			Subsystem: "http",
+			Name:      "request_queued",
+			Help:      "Number of requests that are currently waiting to be served.",
+		}, func() float64 { return float64(pool.Queued()) }))
+	}
+	m.registry.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Namespace: "kes",
+		Subsystem: "http",
+		Name:      "request_shed",
+		Help:      "Number of requests rejected since too many requests have been served concurrently.",
+	}, func() float64 { return float64(shed()) }))
+}
+
+// RegisterRevocationFailures registers a metric that reports
+// the number of client certificate revocation checks that
+// failed since the revocation status could not be determined,
+// e.g. since a CRL could not be fetched.
+func (m *Metrics) RegisterRevocationFailures(failures func() uint64) {
+	m.registry.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Namespace: "kes",
+		Subsystem: "tls",
+		Name:      "revocation_check_failures",
+		Help:      "Number of client certificate revocation checks that failed.",
+	}, func() float64 { return float64(failures()) }))
+}
+
 // ErrorEventCounter returns an io.Writer that increments
 // the error event log counter on each write call.
 //
@@ -8,6 +8,7 @@ import (
 	"runtime/debug"
 	"strings"
 	"sync"
+	"time"
 )
 
 // BuildInfo contains build information
@@ -15,6 +16,7 @@ import (
 type BuildInfo struct {
 	Version  string
 	CommitID string
+	Date     time.Time // Commit time; zero if unknown
 }
 
 // BinaryInfo returns the BuildInfo of the
@@ -50,6 +52,9 @@ func readBinaryInfo() BuildInfo {
 	for _, setting := range info.Settings {
 		if setting.Key == GitTimeKey {
 			binaryInfo.Version = strings.ReplaceAll(setting.Value, ":", "-")
+			if date, err := time.Parse(time.RFC3339, setting.Value); err == nil {
+				binaryInfo.Date = date.UTC()
+			}
 		}
 		if setting.Key == GitRevisionKey {
 			binaryInfo.CommitID = setting.Value
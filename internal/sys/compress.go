@@ -0,0 +1,97 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package sys
+
+import (
+	"errors"
+	"strings"
+
+	"aead.dev/mem"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression is a compression algorithm applied to
+// entries before they get sealed and written to the
+// storage backend.
+//
+// Compressed entries start with a header identifying the
+// compression algorithm. Entries without such a header
+// are considered uncompressed. Hence, entries are always
+// decompressed transparently on read, regardless of the
+// Compression used for writing.
+type Compression string
+
+// All supported compression algorithms.
+const (
+	// NoCompression disables the compression of entries.
+	NoCompression Compression = ""
+
+	// Zstd compresses entries using Zstandard.
+	Zstd Compression = "zstd"
+)
+
+// ParseCompression parses s as compression algorithm.
+// It ignores the case of s.
+func ParseCompression(s string) (Compression, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "off", "none":
+		return NoCompression, nil
+	case string(Zstd):
+		return Zstd, nil
+	default:
+		return "", errors.New("sys: invalid compression algorithm '" + s + "'")
+	}
+}
+
+// Entry headers of compressed entries. All entries are
+// gob-encoded before compression. Since a gob stream
+// never starts with a zero byte, the header can be
+// distinguished from the uncompressed entries.
+const (
+	headerMagic = 0x00
+	headerZstd  = 0x01
+)
+
+// Shared zstd encoder and decoder. Both are safe for
+// concurrent use when only using EncodeAll/DecodeAll.
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+	zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0), zstd.WithDecoderMaxMemory(uint64(1*mem.MiB)))
+)
+
+// compress compresses the plaintext using the given
+// compression algorithm and prefixes the result with
+// the corresponding entry header.
+//
+// It returns the plaintext as is if c is NoCompression.
+func compress(c Compression, plaintext []byte) ([]byte, error) {
+	switch c {
+	case NoCompression:
+		return plaintext, nil
+	case Zstd:
+		dst := make([]byte, 2, 2+len(plaintext))
+		dst[0], dst[1] = headerMagic, headerZstd
+		return zstdEncoder.EncodeAll(plaintext, dst), nil
+	default:
+		return nil, errors.New("sys: invalid compression algorithm '" + string(c) + "'")
+	}
+}
+
+// decompress decompresses the entry if it starts with an
+// entry header. Otherwise, it returns the entry as is.
+func decompress(entry []byte) ([]byte, error) {
+	if len(entry) == 0 || entry[0] != headerMagic {
+		return entry, nil
+	}
+	if len(entry) < 2 {
+		return nil, errors.New("sys: invalid entry header")
+	}
+	switch entry[1] {
+	case headerZstd:
+		return zstdDecoder.DecodeAll(entry[2:], nil)
+	default:
+		return nil, errors.New("sys: invalid entry header: unknown compression algorithm")
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package sys
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/minio/kes/internal/auth"
+)
+
+var compressTests = []struct {
+	Compression Compression
+}{
+	{Compression: NoCompression},
+	{Compression: Zstd},
+}
+
+func TestCompress(t *testing.T) {
+	plaintext, err := auth.Policy{
+		Allow: []string{"/v1/key/create/*", "/v1/key/generate/*", "/v1/key/decrypt/*"},
+		Deny:  []string{"/v1/key/create/my-key*"},
+	}.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Failed to encode policy: %v", err)
+	}
+
+	for i, test := range compressTests {
+		entry, err := compress(test.Compression, plaintext)
+		if err != nil {
+			t.Fatalf("Test %d: failed to compress entry: %v", i, err)
+		}
+		if test.Compression == NoCompression && !bytes.Equal(entry, plaintext) {
+			t.Fatalf("Test %d: uncompressed entry does not match plaintext", i)
+		}
+
+		decompressed, err := decompress(entry)
+		if err != nil {
+			t.Fatalf("Test %d: failed to decompress entry: %v", i, err)
+		}
+		if !bytes.Equal(decompressed, plaintext) {
+			t.Fatalf("Test %d: decompressed entry does not match plaintext", i)
+		}
+
+		var policy auth.Policy
+		if err = policy.UnmarshalBinary(decompressed); err != nil {
+			t.Fatalf("Test %d: failed to decode policy: %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package sys
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/key"
+)
+
+// HKDF_SHA256 is the key derivation function used to
+// derive keys from an Enclave's keys.
+const HKDF_SHA256 = "HKDF-SHA256"
+
+// A CryptoPolicy restricts the cryptographic algorithms and
+// key sizes an Enclave's keys may use. It is enforced when
+// keys are created and whenever keys are used. Hence, keys
+// that have been created before a policy has been changed
+// cannot be used unless they comply with the new policy.
+//
+// The zero CryptoPolicy does not restrict any algorithm or
+// key size.
+type CryptoPolicy struct {
+	// Algorithms are the approved encryption algorithms
+	// of symmetric keys. If empty, all algorithms are
+	// approved.
+	Algorithms []key.Algorithm
+
+	// MinKeySize is the min. size of symmetric keys, ECDSA
+	// keys and derived keys in bits. If zero, keys of any
+	// size are accepted.
+	MinKeySize int
+
+	// MinRSAKeySize is the min. size of RSA keys in bits.
+	// If zero, RSA keys of any size are accepted.
+	MinRSAKeySize int
+
+	// KDFs are the approved key derivation functions, like
+	// HKDF-SHA256. If empty, all functions are approved.
+	KDFs []string
+}
+
+// IsZero reports whether the CryptoPolicy does
+// not restrict any algorithm or key size.
+func (p *CryptoPolicy) IsZero() bool {
+	return len(p.Algorithms) == 0 && p.MinKeySize == 0 && p.MinRSAKeySize == 0 && len(p.KDFs) == 0
+}
+
+// Verify returns an error if the CryptoPolicy contains
+// unsupported algorithms or invalid key sizes.
+func (p *CryptoPolicy) Verify() error {
+	for _, a := range p.Algorithms {
+		if kes.KeyAlgorithm(a) == kes.KeyAlgorithmUndefined || key.Len(kes.KeyAlgorithm(a)) <= 0 {
+			return kes.NewError(http.StatusBadRequest, fmt.Sprintf("invalid crypto policy: unsupported algorithm '%v'", a))
+		}
+	}
+	for _, kdf := range p.KDFs {
+		if !strings.EqualFold(kdf, HKDF_SHA256) {
+			return kes.NewError(http.StatusBadRequest, fmt.Sprintf("invalid crypto policy: unsupported key derivation function '%s'", kdf))
+		}
+	}
+	if p.MinKeySize < 0 || p.MinKeySize > 8*key.MaxDeriveLen {
+		return kes.NewError(http.StatusBadRequest, "invalid crypto policy: invalid min. key size")
+	}
+	if p.MinRSAKeySize < 0 || p.MinRSAKeySize > 4096 {
+		return kes.NewError(http.StatusBadRequest, "invalid crypto policy: invalid min. RSA key size")
+	}
+	return nil
+}
+
+// Algorithm returns the algorithm new symmetric keys should
+// use. It returns the preferred algorithm if it is approved
+// and the first approved algorithm otherwise.
+func (p *CryptoPolicy) Algorithm(preferred kes.KeyAlgorithm) kes.KeyAlgorithm {
+	if len(p.Algorithms) == 0 || p.approved(preferred) {
+		return preferred
+	}
+	return kes.KeyAlgorithm(p.Algorithms[0])
+}
+
+// VerifyKey returns an error if the key does not comply
+// with the CryptoPolicy.
+//
+// Symmetric keys without an explicit algorithm are only
+// accepted if the policy approves all algorithms since
+// their algorithm depends on the server's hardware.
+func (p *CryptoPolicy) VerifyKey(k *key.Key) error {
+	switch t := k.Type(); t {
+	case key.Symmetric:
+		if len(p.Algorithms) > 0 && !p.approved(k.Algorithm()) {
+			return kes.NewError(http.StatusForbidden, fmt.Sprintf("crypto policy: key algorithm '%v' is not approved", key.Algorithm(k.Algorithm())))
+		}
+		if size := k.Size(); size < p.MinKeySize {
+			return kes.NewError(http.StatusForbidden, fmt.Sprintf("crypto policy: key size %d is below the min. key size %d", size, p.MinKeySize))
+		}
+	case key.RSA2048, key.RSA3072, key.RSA4096:
+		if size := k.Size(); size < p.MinRSAKeySize {
+			return kes.NewError(http.StatusForbidden, fmt.Sprintf("crypto policy: RSA key size %d is below the min. RSA key size %d", size, p.MinRSAKeySize))
+		}
+	default:
+		if size := k.Size(); size < p.MinKeySize {
+			return kes.NewError(http.StatusForbidden, fmt.Sprintf("crypto policy: %v key size %d is below the min. key size %d", t, size, p.MinKeySize))
+		}
+	}
+	return nil
+}
+
+// VerifyDerive returns an error if deriving a key of the
+// given length, in bytes, using the key derivation function
+// does not comply with the CryptoPolicy.
+func (p *CryptoPolicy) VerifyDerive(kdf string, length int) error {
+	if len(p.KDFs) > 0 {
+		var approved bool
+		for _, v := range p.KDFs {
+			if strings.EqualFold(v, kdf) {
+				approved = true
+				break
+			}
+		}
+		if !approved {
+			return kes.NewError(http.StatusForbidden, fmt.Sprintf("crypto policy: key derivation function '%s' is not approved", kdf))
+		}
+	}
+	if size := 8 * length; size < p.MinKeySize {
+		return kes.NewError(http.StatusForbidden, fmt.Sprintf("crypto policy: derived key size %d is below the min. key size %d", size, p.MinKeySize))
+	}
+	return nil
+}
+
+func (p *CryptoPolicy) approved(a kes.KeyAlgorithm) bool {
+	for _, v := range p.Algorithms {
+		if kes.KeyAlgorithm(v) == a {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package sys
+
+import (
+	"context"
+	"testing"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/key"
+)
+
+var cryptoPolicyVerifyKeyTests = []struct {
+	Policy     CryptoPolicy
+	Type       key.Type
+	Algorithm  kes.KeyAlgorithm
+	ShouldFail bool
+}{
+	{Policy: CryptoPolicy{}, Algorithm: kes.AES256_GCM_SHA256},                                                                  // 0
+	{Policy: CryptoPolicy{}, Algorithm: kes.XCHACHA20_POLY1305},                                                                 // 1
+	{Policy: CryptoPolicy{}, Algorithm: kes.KeyAlgorithmUndefined},                                                              // 2
+	{Policy: CryptoPolicy{Algorithms: []key.Algorithm{key.Algorithm(kes.AES256_GCM_SHA256)}}, Algorithm: kes.AES256_GCM_SHA256}, // 3
+	{ // 4
+		Policy:     CryptoPolicy{Algorithms: []key.Algorithm{key.Algorithm(kes.AES256_GCM_SHA256)}},
+		Algorithm:  kes.XCHACHA20_POLY1305,
+		ShouldFail: true,
+	},
+	{ // 5
+		Policy:     CryptoPolicy{Algorithms: []key.Algorithm{key.Algorithm(kes.AES256_GCM_SHA256)}},
+		Algorithm:  kes.KeyAlgorithmUndefined,
+		ShouldFail: true,
+	},
+	{Policy: CryptoPolicy{MinKeySize: 256}, Algorithm: kes.AES256_GCM_SHA256},                   // 6
+	{Policy: CryptoPolicy{MinKeySize: 384}, Algorithm: kes.AES256_GCM_SHA256, ShouldFail: true}, // 7
+
+	{Policy: CryptoPolicy{MinRSAKeySize: 2048}, Type: key.RSA2048},                                                  // 8
+	{Policy: CryptoPolicy{MinRSAKeySize: 3072}, Type: key.RSA2048, ShouldFail: true},                                // 9
+	{Policy: CryptoPolicy{MinKeySize: 512}, Type: key.RSA2048},                                                      // 10
+	{Policy: CryptoPolicy{MinKeySize: 256}, Type: key.ECDSAP256},                                                    // 11
+	{Policy: CryptoPolicy{MinKeySize: 384}, Type: key.ECDSAP256, ShouldFail: true},                                  // 12
+	{Policy: CryptoPolicy{Algorithms: []key.Algorithm{key.Algorithm(kes.XCHACHA20_POLY1305)}}, Type: key.ECDSAP384}, // 13
+}
+
+func TestCryptoPolicyVerifyKey(t *testing.T) {
+	for i, test := range cryptoPolicyVerifyKeyTests {
+		var (
+			k   key.Key
+			err error
+		)
+		if test.Type.IsAsymmetric() {
+			k, err = key.RandomAsymmetric(test.Type, "")
+		} else {
+			k, err = key.Random(test.Algorithm, "")
+		}
+		if err != nil {
+			t.Fatalf("Test %d: failed to generate key: %v", i, err)
+		}
+
+		err = test.Policy.VerifyKey(&k)
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: policy accepted key but should have rejected it", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: policy rejected key: %v", i, err)
+		}
+	}
+}
+
+var cryptoPolicyVerifyDeriveTests = []struct {
+	Policy     CryptoPolicy
+	KDF        string
+	Length     int
+	ShouldFail bool
+}{
+	{Policy: CryptoPolicy{}, KDF: HKDF_SHA256, Length: 16},                                           // 0
+	{Policy: CryptoPolicy{KDFs: []string{"hkdf-sha256"}}, KDF: HKDF_SHA256, Length: 32},              // 1
+	{Policy: CryptoPolicy{KDFs: []string{HKDF_SHA256}}, KDF: "PBKDF2", Length: 32, ShouldFail: true}, // 2
+	{Policy: CryptoPolicy{MinKeySize: 256}, KDF: HKDF_SHA256, Length: 32},                            // 3
+	{Policy: CryptoPolicy{MinKeySize: 256}, KDF: HKDF_SHA256, Length: 16, ShouldFail: true},          // 4
+}
+
+func TestCryptoPolicyVerifyDerive(t *testing.T) {
+	for i, test := range cryptoPolicyVerifyDeriveTests {
+		err := test.Policy.VerifyDerive(test.KDF, test.Length)
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: policy accepted key derivation but should have rejected it", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: policy rejected key derivation: %v", i, err)
+		}
+	}
+}
+
+func TestCryptoPolicyCreateKey(t *testing.T) {
+	const (
+		Enclave = "tenant-1"
+		Admin   = kes.Identity("3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22")
+	)
+	ctx := context.Background()
+
+	rootKey, err := key.Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to generate root key: %v", err)
+	}
+	vault := NewVault(NewVaultFS(t.TempDir(), rootKey, NoCompression))
+	if _, err = vault.CreateEnclave(ctx, Enclave, Admin); err != nil {
+		t.Fatalf("Failed to create enclave: %v", err)
+	}
+	if _, err = vault.UpdateEnclave(ctx, Enclave, func(info *EnclaveInfo) error {
+		info.CryptoPolicy = CryptoPolicy{Algorithms: []key.Algorithm{key.Algorithm(kes.AES256_GCM_SHA256)}}
+		return nil
+	}); err != nil {
+		t.Fatalf("Failed to update enclave: %v", err)
+	}
+	enclave, err := vault.GetEnclave(ctx, Enclave)
+	if err != nil {
+		t.Fatalf("Failed to get enclave: %v", err)
+	}
+
+	if a := enclave.CryptoPolicy().Algorithm(kes.XCHACHA20_POLY1305); a != kes.AES256_GCM_SHA256 {
+		t.Fatalf("Invalid default algorithm: got '%v' - want '%v'", a, kes.AES256_GCM_SHA256)
+	}
+	aesKey, err := key.Random(kes.AES256_GCM_SHA256, Admin)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	if err = enclave.CreateKey(ctx, "aes-key", aesKey); err != nil {
+		t.Fatalf("Failed to create approved key: %v", err)
+	}
+	chachaKey, err := key.Random(kes.XCHACHA20_POLY1305, Admin)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	if err = enclave.CreateKey(ctx, "chacha-key", chachaKey); err == nil {
+		t.Fatal("Created key with an algorithm that is not approved")
+	}
+}
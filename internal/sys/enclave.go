@@ -55,6 +55,75 @@ type EnclaveInfo struct {
 
 	// CreatedBy is the identity that created the Enclave.
 	CreatedBy kes.Identity
+
+	// Previous contains the root encryption keys the Enclave
+	// used before its re-encryption has been started. It is
+	// nil unless a re-encryption is in progress.
+	Previous *EnclaveInfo
+
+	// KeyRetention is the time deleted keys are retained
+	// in the Enclave's key trash before they get purged.
+	// If zero, deleted keys are purged immediately.
+	KeyRetention time.Duration
+
+	// AuditHold controls whether the Enclave runs in
+	// compliance mode. In compliance mode, deleted keys
+	// are kept under audit hold until the KeyRetention
+	// has passed and cannot be purged before. Once
+	// enabled, neither the audit hold can be disabled
+	// nor the KeyRetention reduced.
+	AuditHold bool
+
+	// MaxKeys is the max. number of keys the Enclave can
+	// contain. If zero, the number of keys is not limited.
+	MaxKeys int
+
+	// MaxIdentities is the max. number of identities the
+	// Enclave can contain, not counting the Enclave admin.
+	// If zero, the number of identities is not limited.
+	MaxIdentities int
+
+	// RequestRate is the max. number of requests per second
+	// the Enclave serves. If zero, the request rate is not
+	// limited.
+	RequestRate float64
+
+	// AuditRetention is the number of days audit statistics
+	// of the Enclave are retained. If zero, the server's
+	// default retention applies.
+	AuditRetention int
+
+	// AuditLegalHold controls whether audit statistics of
+	// the Enclave are under legal hold. Under legal hold,
+	// audit statistics are retained beyond the AuditRetention
+	// and cannot be purged until the legal hold is released.
+	AuditLegalHold bool
+
+	// Description is an optional, human-readable description
+	// of the Enclave.
+	Description string
+
+	// Labels are optional name-value pairs attached to
+	// the Enclave, e.g. to identify its tenant.
+	Labels map[string]string
+
+	// DefaultPolicy is the policy identities get assigned
+	// to when created without specifying a policy. If empty,
+	// identities have to be created with a policy.
+	DefaultPolicy string
+
+	// CryptoPolicy restricts the cryptographic algorithms
+	// and key sizes the Enclave's keys may use.
+	CryptoPolicy CryptoPolicy
+
+	// Suspended controls whether the Enclave is suspended.
+	// A suspended Enclave rejects all requests but retains
+	// its keys, policies and identities until resumed.
+	Suspended bool
+
+	// SuspendedAt is the point in time when the Enclave
+	// has been suspended.
+	SuspendedAt time.Time
 }
 
 // MarshalBinary returns the EnclaveInfo's binary representation.
@@ -67,6 +136,26 @@ func (e EnclaveInfo) MarshalBinary() ([]byte, error) {
 		IdentityKey key.Key
 		CreatedAt   time.Time
 		CreatedBy   kes.Identity
+		Previous    *EnclaveInfo
+
+		KeyRetention time.Duration
+		AuditHold    bool
+
+		MaxKeys       int
+		MaxIdentities int
+		RequestRate   float64
+
+		AuditRetention int
+		AuditLegalHold bool
+
+		Description string
+		Labels      map[string]string
+
+		DefaultPolicy string
+		CryptoPolicy  CryptoPolicy
+
+		Suspended   bool
+		SuspendedAt time.Time
 	}
 
 	var buffer bytes.Buffer
@@ -86,6 +175,26 @@ func (e *EnclaveInfo) UnmarshalBinary(b []byte) error {
 		IdentityKey key.Key
 		CreatedAt   time.Time
 		CreatedBy   kes.Identity
+		Previous    *EnclaveInfo
+
+		KeyRetention time.Duration
+		AuditHold    bool
+
+		MaxKeys       int
+		MaxIdentities int
+		RequestRate   float64
+
+		AuditRetention int
+		AuditLegalHold bool
+
+		Description string
+		Labels      map[string]string
+
+		DefaultPolicy string
+		CryptoPolicy  CryptoPolicy
+
+		Suspended   bool
+		SuspendedAt time.Time
 	}
 
 	var value GOB
@@ -99,9 +208,31 @@ func (e *EnclaveInfo) UnmarshalBinary(b []byte) error {
 	e.IdentityKey = value.IdentityKey
 	e.CreatedAt = value.CreatedAt
 	e.CreatedBy = value.CreatedBy
+	e.Previous = value.Previous
+	e.KeyRetention = value.KeyRetention
+	e.AuditHold = value.AuditHold
+	e.MaxKeys = value.MaxKeys
+	e.MaxIdentities = value.MaxIdentities
+	e.RequestRate = value.RequestRate
+	e.AuditRetention = value.AuditRetention
+	e.AuditLegalHold = value.AuditLegalHold
+	e.Description = value.Description
+	e.Labels = value.Labels
+	e.DefaultPolicy = value.DefaultPolicy
+	e.CryptoPolicy = value.CryptoPolicy
+	e.Suspended = value.Suspended
+	e.SuspendedAt = value.SuspendedAt
 	return nil
 }
 
+// ErrIdentityExists is returned when creating an
+// identity that exists already.
+var ErrIdentityExists = kes.NewError(http.StatusConflict, "identity already exists")
+
+// ErrEnclaveSuspended is returned by a suspended Enclave
+// when verifying requests.
+var ErrEnclaveSuspended = kes.NewError(http.StatusForbidden, "enclave is suspended")
+
 // NewEnclave returns a new Enclave with the
 // given key store, policy set and identity set.
 func NewEnclave(keys KeyFS, secrets SecretFS, policies PolicyFS, identities IdentityFS) *Enclave {
@@ -127,6 +258,17 @@ type Enclave struct {
 	identities IdentityFS
 	lock       sync.RWMutex
 
+	keyRetention time.Duration
+	auditHold    bool
+
+	maxKeys       int
+	maxIdentities int
+	requests      *requestLimit
+
+	defaultPolicy string
+	crypto        CryptoPolicy
+	suspended     bool
+
 	cacheLock     sync.Mutex
 	admin         kes.Identity
 	keyCache      map[string]key.Key
@@ -152,20 +294,156 @@ func (e *Enclave) Status(context.Context) (kv.State, error) { return kv.State{},
 // CreateKey stores the given key if and only if no entry with
 // the given name exists.
 //
-// It returns kes.ErrKeyExists if such an entry exists.
+// It returns kes.ErrKeyExists if such an entry exists and
+// ErrKeyQuota if the Enclave contains the max. number of keys.
+// It returns an error if the key does not comply with the
+// Enclave's CryptoPolicy.
 func (e *Enclave) CreateKey(ctx context.Context, name string, key key.Key) error {
 	if _, ok := e.keyCache[name]; ok {
 		return kes.ErrKeyExists
 	}
+	if err := e.crypto.VerifyKey(&key); err != nil {
+		return err
+	}
+	if err := e.verifyKeyQuota(ctx); err != nil {
+		return err
+	}
 	return e.keys.CreateKey(ctx, name, key)
 }
 
-// DeleteKey deletes the key associated with the given name.
+// SetKey replaces the key associated with the given name.
+//
+// It returns kes.ErrKeyNotFound if no such entry exists.
+func (e *Enclave) SetKey(ctx context.Context, name string, key key.Key) error {
+	delete(e.keyCache, name)
+	return e.keys.SetKey(ctx, name, key)
+}
+
+// KeyRetention returns the time deleted keys are retained
+// in the Enclave's key trash. If zero, deleted keys are
+// purged immediately.
+func (e *Enclave) KeyRetention() time.Duration { return e.keyRetention }
+
+// AuditHold reports whether deleted keys are kept under
+// audit hold until the Enclave's key retention has passed.
+func (e *Enclave) AuditHold() bool { return e.auditHold }
+
+// DeleteKey deletes the key with the given name. If the
+// Enclave retains deleted keys, DeleteKey moves the key
+// to the key trash from where it can be restored until
+// it gets purged.
+//
+// DeleteKey also tries to purge all keys from the key trash
+// that have exceeded the Enclave's key retention.
+//
+// It returns kes.ErrKeyNotFound if no such entry exists.
 func (e *Enclave) DeleteKey(ctx context.Context, name string) error {
 	delete(e.keyCache, name)
-	return e.keys.DeleteKey(ctx, name)
+
+	var err error
+	if e.keyRetention > 0 {
+		err = e.keys.TrashKey(ctx, name)
+	} else {
+		err = e.keys.DeleteKey(ctx, name)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Purging expired keys is best effort. The key has been
+	// deleted already and any key that cannot be purged now
+	// gets purged on its next access.
+	e.PurgeExpiredKeys(ctx)
+	return nil
+}
+
+// RestoreKey restores the deleted key with the given name
+// from the key trash.
+//
+// It returns kes.ErrKeyNotFound if no such key is in the
+// key trash, kes.ErrKeyExists if a key with the same name
+// exists and ErrKeyQuota if the Enclave contains the max.
+// number of keys.
+func (e *Enclave) RestoreKey(ctx context.Context, name string) error {
+	if _, err := e.GetDeletedKey(ctx, name); err != nil {
+		return err
+	}
+	if err := e.verifyKeyQuota(ctx); err != nil {
+		return err
+	}
+	delete(e.keyCache, name)
+	return e.keys.RestoreKey(ctx, name)
+}
+
+// GetDeletedKey returns the deleted key with the given name
+// from the key trash. If the key has exceeded the Enclave's
+// key retention, GetDeletedKey purges it.
+//
+// It returns kes.ErrKeyNotFound if no such key is in the
+// key trash.
+func (e *Enclave) GetDeletedKey(ctx context.Context, name string) (DeletedKey, error) {
+	deleted, err := e.keys.GetDeletedKey(ctx, name)
+	if err != nil {
+		return DeletedKey{}, err
+	}
+	if !time.Now().Before(deleted.PurgeAt(e.keyRetention)) {
+		if err = e.keys.PurgeKey(ctx, name); err != nil && !errors.Is(err, kes.ErrKeyNotFound) {
+			return DeletedKey{}, err
+		}
+		return DeletedKey{}, kes.ErrKeyNotFound
+	}
+	return deleted, nil
+}
+
+// PurgeKey deletes the key with the given name from the
+// key trash permanently.
+//
+// It returns kes.ErrKeyNotFound if no such key is in the
+// key trash and ErrAuditHold if the key is still under
+// audit hold.
+func (e *Enclave) PurgeKey(ctx context.Context, name string) error {
+	if e.auditHold {
+		if _, err := e.GetDeletedKey(ctx, name); err != nil {
+			return err
+		}
+		return ErrAuditHold
+	}
+	return e.keys.PurgeKey(ctx, name)
 }
 
+// PurgeExpiredKeys purges all keys from the key trash that
+// have exceeded the Enclave's key retention.
+func (e *Enclave) PurgeExpiredKeys(ctx context.Context) error {
+	iter, err := e.keys.ListDeletedKeys(ctx)
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+
+	var names []string
+	for name, ok := iter.Next(); ok; name, ok = iter.Next() {
+		names = append(names, name)
+	}
+	if err = iter.Close(); err != nil {
+		return err
+	}
+	for _, name := range names {
+		if _, err = e.GetDeletedKey(ctx, name); err != nil && !errors.Is(err, kes.ErrKeyNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListDeletedKeys returns an iterator over the names
+// of all keys in the key trash.
+func (e *Enclave) ListDeletedKeys(ctx context.Context) (kv.Iter[string], error) {
+	return e.keys.ListDeletedKeys(ctx)
+}
+
+// CryptoPolicy returns the Enclave's CryptoPolicy.
+func (e *Enclave) CryptoPolicy() *CryptoPolicy { return &e.crypto }
+
 // GetKey returns the key associated with the given name.
 //
 // It returns kes.ErrKeyNotFound if no such entry exists.
@@ -198,6 +476,28 @@ func (e *Enclave) ListKeys(ctx context.Context) (kv.Iter[string], error) {
 	return e.keys.ListKeys(ctx)
 }
 
+// isEmpty reports whether the Enclave contains neither keys
+// nor deleted keys that have not exceeded the key retention.
+func (e *Enclave) isEmpty(ctx context.Context) (bool, error) {
+	if err := e.PurgeExpiredKeys(ctx); err != nil {
+		return false, err
+	}
+	for _, list := range []func(context.Context) (kv.Iter[string], error){e.ListKeys, e.ListDeletedKeys} {
+		iter, err := list(ctx)
+		if err != nil {
+			return false, err
+		}
+		_, ok := iter.Next()
+		if err = iter.Close(); err != nil {
+			return false, err
+		}
+		if ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
 // CreateSecret stores the given secret if and only if no entry with
 // the given name exists.
 //
@@ -336,8 +636,40 @@ func (e *Enclave) SetAdmin(ctx context.Context, admin kes.Identity) error {
 	return nil
 }
 
-// AssignPolicy assigns the policy to the identity.
-func (e *Enclave) AssignPolicy(ctx context.Context, policy string, identity kes.Identity) error {
+// AddAdmin adds the identity as additional admin of the
+// Enclave. The identity must not be an existing identity.
+func (e *Enclave) AddAdmin(ctx context.Context, identity, createdBy kes.Identity) error {
+	admin, err := e.Admin(ctx)
+	if err != nil {
+		return err
+	}
+	if identity == admin {
+		return kes.NewError(http.StatusConflict, "identity already exists")
+	}
+	if err = e.verifyIdentityQuota(ctx, identity); err != nil {
+		return err
+	}
+
+	delete(e.identityCache, identity)
+	return e.identities.AddAdmin(ctx, identity, createdBy)
+}
+
+// RemoveAdmin removes the additional Enclave admin. The
+// Enclave admin itself cannot be removed.
+func (e *Enclave) RemoveAdmin(ctx context.Context, identity kes.Identity) error {
+	delete(e.identityCache, identity)
+	return e.identities.RemoveAdmin(ctx, identity)
+}
+
+// AssignPolicy assigns the policy to the identity. The
+// identity expires at the given point in time unless
+// expiresAt is zero. Any existing labels of the identity
+// are replaced by the given labels.
+//
+// It returns ErrIdentityQuota if the identity does not
+// exist and the Enclave contains the max. number of
+// identities.
+func (e *Enclave) AssignPolicy(ctx context.Context, policy string, identity kes.Identity, expiresAt time.Time, labels map[string]string) error {
 	admin, err := e.Admin(ctx)
 	if err != nil {
 		return err
@@ -345,9 +677,66 @@ func (e *Enclave) AssignPolicy(ctx context.Context, policy string, identity kes.
 	if identity == admin {
 		return kes.NewError(http.StatusBadRequest, "cannot assign policy to admin")
 	}
+	if info, err := e.GetIdentity(ctx, identity); err == nil && info.IsAdmin {
+		return kes.NewError(http.StatusBadRequest, "cannot assign policy to admin")
+	}
+	if err = e.verifyIdentityQuota(ctx, identity); err != nil {
+		return err
+	}
+
+	delete(e.identityCache, identity)
+	return e.identities.AssignPolicy(ctx, policy, identity, expiresAt, labels)
+}
+
+// CreateIdentity creates the identity and assigns it to the
+// policy. If policy is empty, the identity is assigned to the
+// Enclave's default policy. The identity expires at the given
+// point in time unless expiresAt is zero.
+//
+// Unlike AssignPolicy, CreateIdentity does not modify existing
+// identities. It returns ErrIdentityExists if the identity
+// exists already and kes.ErrPolicyNotFound if the policy does
+// not exist.
+func (e *Enclave) CreateIdentity(ctx context.Context, policy string, identity kes.Identity, expiresAt time.Time, labels map[string]string) error {
+	if policy == "" {
+		policy = e.defaultPolicy
+	}
+	if policy == "" {
+		return kes.NewError(http.StatusBadRequest, "no policy specified and enclave has no default policy")
+	}
+
+	admin, err := e.Admin(ctx)
+	if err != nil {
+		return err
+	}
+	if identity == admin {
+		return ErrIdentityExists
+	}
+	if _, err = e.GetIdentity(ctx, identity); err == nil {
+		return ErrIdentityExists
+	}
+	if !errors.Is(err, kes.ErrIdentityNotFound) {
+		return err
+	}
+	if _, err = e.GetPolicy(ctx, policy); err != nil {
+		return err
+	}
+	return e.AssignPolicy(ctx, policy, identity, expiresAt, labels)
+}
+
+// RenewIdentity changes when the given identity expires
+// without changing the policy it is assigned to.
+func (e *Enclave) RenewIdentity(ctx context.Context, identity kes.Identity, expiresAt time.Time) error {
+	admin, err := e.Admin(ctx)
+	if err != nil {
+		return err
+	}
+	if identity == admin {
+		return kes.NewError(http.StatusBadRequest, "cannot renew admin")
+	}
 
 	delete(e.identityCache, identity)
-	return e.identities.AssignPolicy(ctx, policy, identity)
+	return e.identities.RenewIdentity(ctx, identity, expiresAt)
 }
 
 // DeleteIdentity deletes the given identity.
@@ -359,6 +748,9 @@ func (e *Enclave) DeleteIdentity(ctx context.Context, identity kes.Identity) err
 	if identity == admin {
 		return kes.NewError(http.StatusBadRequest, "cannot delete admin")
 	}
+	if info, err := e.GetIdentity(ctx, identity); err == nil && info.IsAdmin {
+		return kes.NewError(http.StatusBadRequest, "cannot delete admin")
+	}
 
 	delete(e.identityCache, identity)
 	return e.identities.DeleteIdentity(ctx, identity)
@@ -396,7 +788,56 @@ func (e *Enclave) ListIdentities(ctx context.Context) (auth.IdentityIterator, er
 
 // VerifyRequest verifies the given request is allowed
 // based on the policies and identities within the Enclave.
+//
+// It returns ErrEnclaveSuspended if the Enclave is suspended
+// and ErrRequestQuota if the Enclave has exceeded its request
+// rate.
 func (e *Enclave) VerifyRequest(r *http.Request) error {
+	if e.suspended {
+		return ErrEnclaveSuspended
+	}
+	if err := e.verifyRequest(r); err != nil {
+		return err
+	}
+	return e.requests.take(time.Now())
+}
+
+// VerifyRequests verifies that each of the given requests
+// is allowed based on the policies and identities within
+// the Enclave. It returns one error per request.
+//
+// All requests count as a single request towards the
+// Enclave's request rate. Hence, it should be used to
+// verify the individual operations of one bulk request.
+//
+// It returns ErrEnclaveSuspended if the Enclave is suspended
+// and ErrRequestQuota if the Enclave has exceeded its request
+// rate.
+func (e *Enclave) VerifyRequests(reqs []*http.Request) ([]error, error) {
+	if e.suspended {
+		return nil, ErrEnclaveSuspended
+	}
+
+	var allowed bool
+	errs := make([]error, 0, len(reqs))
+	for _, r := range reqs {
+		err := e.verifyRequest(r)
+		if err == nil {
+			allowed = true
+		}
+		errs = append(errs, err)
+	}
+	if allowed {
+		if err := e.requests.take(time.Now()); err != nil {
+			return nil, err
+		}
+	}
+	return errs, nil
+}
+
+// verifyRequest verifies the given request is allowed
+// based on the policies and identities within the Enclave.
+func (e *Enclave) verifyRequest(r *http.Request) error {
 	if r.TLS == nil {
 		return kes.NewError(http.StatusBadRequest, "insecure connection: TLS required")
 	}
@@ -413,17 +854,27 @@ func (e *Enclave) VerifyRequest(r *http.Request) error {
 			peerCertificates = append(peerCertificates, cert)
 		}
 	}
-	if len(peerCertificates) == 0 {
-		return kes.NewError(http.StatusBadRequest, "no client certificate is present")
-	}
 	if len(peerCertificates) > 1 {
 		return kes.NewError(http.StatusBadRequest, "too many client certificates are present")
 	}
 
-	var (
-		h        = sha256.Sum256(peerCertificates[0].RawSubjectPublicKeyInfo)
+	var identity kes.Identity
+	if len(peerCertificates) == 1 {
+		h := sha256.Sum256(peerCertificates[0].RawSubjectPublicKeyInfo)
 		identity = kes.Identity(hex.EncodeToString(h[:]))
-	)
+	} else {
+		apiKeyIdentity, ok, err := auth.APIKeyIdentity(r)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return kes.NewError(http.StatusBadRequest, "no client certificate is present")
+		}
+		identity = apiKeyIdentity
+	}
+	if _, ok := auth.Impersonator(r); ok {
+		identity = auth.Identify(r)
+	}
 	info, err := e.GetIdentity(r.Context(), identity)
 	if errors.Is(err, kes.ErrIdentityNotFound) {
 		return kes.ErrNotAllowed
@@ -434,6 +885,9 @@ func (e *Enclave) VerifyRequest(r *http.Request) error {
 	if info.IsAdmin {
 		return nil
 	}
+	if info.Expired() {
+		return auth.ErrIdentityExpired
+	}
 
 	policy, err := e.GetPolicy(r.Context(), info.Policy)
 	if errors.Is(err, kes.ErrPolicyNotFound) {
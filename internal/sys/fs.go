@@ -11,6 +11,9 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
+	"sync"
+	"time"
 
 	"aead.dev/mem"
 	"github.com/minio/kes-go"
@@ -49,10 +52,44 @@ type VaultFS interface {
 	// It returns ErrEnclaveNotFound if no such enclave exists.
 	GetEnclaveInfo(ctx context.Context, name string) (EnclaveInfo, error)
 
+	// UpdateEnclave applies the update to the information about
+	// the specified enclave and persists the result. The update
+	// must not modify the enclave name or root encryption keys.
+	//
+	// It returns ErrEnclaveNotFound if no such enclave exists.
+	UpdateEnclave(ctx context.Context, name string, update func(*EnclaveInfo) error) (EnclaveInfo, error)
+
 	// DeleteEnclave deletes the specified enclave.
 	//
 	// It returns ErrEnclaveNotFound if no such enclave exists.
 	DeleteEnclave(ctx context.Context, name string) error
+
+	// RenameEnclave renames the specified enclave. The enclave
+	// keeps its root encryption keys and entries.
+	//
+	// It returns ErrEnclaveNotFound if no such enclave exists
+	// and ErrEnclaveExists if an enclave with the new name
+	// exists already.
+	RenameEnclave(ctx context.Context, name, newName string) error
+
+	// RotateEnclaveKeys replaces the root encryption keys of the
+	// specified enclave with new random keys. The previous keys
+	// are kept as EnclaveInfo.Previous until ReencryptEnclave
+	// completes.
+	//
+	// If a re-encryption is in progress already, RotateEnclaveKeys
+	// returns the enclave info without rotating the keys again.
+	RotateEnclaveKeys(ctx context.Context, name string) (EnclaveInfo, error)
+
+	// ReencryptEnclave re-encrypts all entries of the specified
+	// enclave that are still encrypted with the previous root keys
+	// and discards the previous keys once done. It holds the locker
+	// while re-encrypting a single entry and reports the number of
+	// processed entries to progress.
+	//
+	// ReencryptEnclave can be resumed after an interruption by
+	// calling it again.
+	ReencryptEnclave(ctx context.Context, name string, locker sync.Locker, progress func(done, total int)) error
 }
 
 // KeyFS provides access to cryptographic keys within a particular
@@ -64,6 +101,11 @@ type KeyFS interface {
 	// It returns ErrKeyExists if such a key already exists.
 	CreateKey(ctx context.Context, name string, key key.Key) error
 
+	// SetKey replaces the existing entry for the given key.
+	//
+	// It returns ErrKeyNotFound if no such key exists.
+	SetKey(ctx context.Context, name string, key key.Key) error
+
 	// GetKey returns the requested key.
 	//
 	// It returns ErrKeyNotFound if no such key exists.
@@ -76,6 +118,36 @@ type KeyFS interface {
 
 	// ListKeys returns an iterator over all key entries.
 	ListKeys(ctx context.Context) (kv.Iter[string], error)
+
+	// TrashKey moves the specified key to the key trash.
+	// A key in the trash can be restored until it gets
+	// purged. It replaces any key with the same name that
+	// is already in the trash.
+	//
+	// It returns ErrKeyNotFound if no such key exists.
+	TrashKey(ctx context.Context, name string) error
+
+	// RestoreKey moves the specified key from the key trash
+	// back to the key store.
+	//
+	// It returns ErrKeyNotFound if no such key is in the trash
+	// and ErrKeyExists if a key with the same name exists.
+	RestoreKey(ctx context.Context, name string) error
+
+	// GetDeletedKey returns the specified key from the key trash.
+	//
+	// It returns ErrKeyNotFound if no such key is in the trash.
+	GetDeletedKey(ctx context.Context, name string) (DeletedKey, error)
+
+	// PurgeKey deletes the specified key from the key trash
+	// permanently.
+	//
+	// It returns ErrKeyNotFound if no such key is in the trash.
+	PurgeKey(ctx context.Context, name string) error
+
+	// ListDeletedKeys returns an iterator over all keys in
+	// the key trash.
+	ListDeletedKeys(ctx context.Context) (kv.Iter[string], error)
 }
 
 // SecretFS provides access to secrets within a particular
@@ -133,10 +205,33 @@ type IdentityFS interface {
 	// that is already assigned to a policy.
 	SetAdmin(ctx context.Context, admin kes.Identity) error
 
+	// AddAdmin adds the given identity as additional enclave
+	// admin. Additional admins have the same privileges as the
+	// enclave admin but can be removed again.
+	//
+	// The identity must not be an existing identity.
+	AddAdmin(ctx context.Context, identity, createdBy kes.Identity) error
+
+	// RemoveAdmin removes the given additional enclave admin.
+	//
+	// It returns an error if the identity is the enclave
+	// admin or not an admin at all.
+	RemoveAdmin(ctx context.Context, identity kes.Identity) error
+
 	// AssignPolicy assigns the policy to the given identity.
 	//
 	// No policy must be assigned to the admin identity.
-	AssignPolicy(ctx context.Context, policy string, identity kes.Identity) error
+	// The identity expires at the given point in time
+	// unless expiresAt is zero. The labels are stored
+	// alongside the identity.
+	AssignPolicy(ctx context.Context, policy string, identity kes.Identity, expiresAt time.Time, labels map[string]string) error
+
+	// RenewIdentity changes when the given identity expires.
+	// The identity remains assigned to its policy. If expiresAt
+	// is zero, the identity never expires.
+	//
+	// It returns ErrIdentityNotFound if no such identity exists.
+	RenewIdentity(ctx context.Context, identity kes.Identity, expiresAt time.Time) error
 
 	// GetIdentity returns identity information for the given identity,
 	// including the admin identity information.
@@ -164,7 +259,52 @@ func valid(name string) error {
 	return nil
 }
 
-func createFile(filename string, key key.Key, plaintext, associatedData []byte) error {
+// validPath is like valid but accepts hierarchical names,
+// like 'payments/prod/db-key', that contain '/' separators.
+func validPath(name string) error {
+	for _, c := range name {
+		if c == '.' || c == '\\' || c == '%' {
+			return fmt.Errorf("sys: path contains invalid character %c", c)
+		}
+	}
+	return nil
+}
+
+// fileName returns the name of the file that stores the
+// entry with the given hierarchical name. Entries are
+// stored within one directory. Hence, the '/' separators
+// get escaped.
+func fileName(name string) string { return strings.ReplaceAll(name, "/", "%2F") }
+
+// entryName returns the hierarchical name of the entry
+// stored in the given file. It is the inverse of fileName.
+func entryName(filename string) string { return strings.ReplaceAll(filename, "%2F", "/") }
+
+// keyRing is the root encryption key of an enclave store.
+//
+// While an enclave is re-encrypted, some entries may still
+// be encrypted with the previous root key. Hence, a keyRing
+// falls back to the previous key, if present, when decryption
+// with the current key fails. New entries are always encrypted
+// with the current key.
+type keyRing struct {
+	key.Key
+	previous *key.Key
+}
+
+// Unwrap decrypts the ciphertext with the current key or,
+// if that fails, with the previous key.
+func (k keyRing) Unwrap(ciphertext, associatedData []byte) ([]byte, error) {
+	plaintext, err := k.Key.Unwrap(ciphertext, associatedData)
+	if err != nil && k.previous != nil {
+		if p, pErr := k.previous.Unwrap(ciphertext, associatedData); pErr == nil {
+			return p, nil
+		}
+	}
+	return plaintext, err
+}
+
+func createFile(filename string, key keyRing, plaintext, associatedData []byte) error {
 	file, err := os.OpenFile(filename, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
 	if err != nil {
 		return err
@@ -189,7 +329,25 @@ func createFile(filename string, key key.Key, plaintext, associatedData []byte)
 	return file.Close()
 }
 
-func readFile(filename string, key key.Key, limit mem.Size, associatedData []byte) ([]byte, error) {
+// replaceFile writes the plaintext encrypted to the given file.
+// It replaces the file atomically, if it exists, by writing to
+// the tmp file first.
+func replaceFile(filename, tmpFile string, key keyRing, plaintext, associatedData []byte) error {
+	if err := os.Remove(tmpFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := createFile(tmpFile, key, plaintext, associatedData); err != nil {
+		os.Remove(tmpFile)
+		return err
+	}
+	if err := os.Rename(tmpFile, filename); err != nil {
+		os.Remove(tmpFile)
+		return err
+	}
+	return nil
+}
+
+func readFile(filename string, key keyRing, limit mem.Size, associatedData []byte) ([]byte, error) {
 	file, err := os.Open(filename)
 	if err != nil {
 		return nil, err
@@ -248,7 +406,7 @@ func (i *iter) Next() bool {
 
 func (i *iter) Name() string {
 	if len(i.names) > 0 {
-		return i.names[0]
+		return entryName(i.names[0])
 	}
 	return ""
 }
@@ -42,9 +42,39 @@ type InitConfig struct {
 
 	VerifyClientCerts yml.Bool
 
+	APIKeys yml.Bool
+
 	ProxyIdentities []yml.Identity
 
 	ProxyClientCert yml.String
+
+	Compression yml.String
+
+	// SNI maps TLS server names to enclaves. The map
+	// keys are the server names.
+	SNI map[string]SNIConfig
+
+	// ReceiptKey is an optional path to the private key
+	// used to sign decrypt receipts. ReceiptPassword is
+	// an optional password to decrypt the private key.
+	ReceiptKey      yml.String
+	ReceiptPassword yml.String
+}
+
+// SNIConfig contains the configuration for one TLS
+// server name (SNI).
+type SNIConfig struct {
+	// Enclave is the enclave that requests sent to
+	// the server name operate within.
+	Enclave yml.String `yaml:"enclave"`
+
+	// PrivateKey and Certificate are an optional TLS
+	// private key and certificate for the server name.
+	// If empty, the server's default TLS certificate
+	// is used.
+	PrivateKey  yml.String `yaml:"key,omitempty"`
+	Certificate yml.String `yaml:"cert,omitempty"`
+	Password    yml.String `yaml:"password,omitempty"`
 }
 
 // ReadInitConfig reads and parses the InitConfig YAML representation
@@ -61,6 +91,8 @@ func ReadInitConfig(filename string) (*InitConfig, error) {
 
 		Address yml.String `yaml:"address"`
 
+		Compression yml.String `yaml:"compression,omitempty"`
+
 		TLS struct {
 			PrivateKey  yml.String `yaml:"key"`
 			Certificate yml.String `yaml:"cert"`
@@ -73,8 +105,15 @@ func ReadInitConfig(filename string) (*InitConfig, error) {
 			} `yaml:"proxy"`
 			Client struct {
 				VerifyCerts yml.Bool `yaml:"verify_cert"`
+				APIKeys     yml.Bool `yaml:"api_key"`
 			} `yaml:"client"`
+			SNI map[string]SNIConfig `yaml:"sni,omitempty"`
 		} `yaml:"tls"`
+
+		Receipts struct {
+			PrivateKey yml.String `yaml:"key,omitempty"`
+			Password   yml.String `yaml:"password,omitempty"`
+		} `yaml:"receipts,omitempty"`
 	}
 	var config YAML
 	if err := yaml.NewDecoder(f).Decode(&config); err != nil {
@@ -86,14 +125,22 @@ func ReadInitConfig(filename string) (*InitConfig, error) {
 	if config.Address.Value() == "" {
 		config.Address.Set("[::]:7373")
 	}
+	if _, err := sys.ParseCompression(config.Compression.Value()); err != nil {
+		return nil, err
+	}
 	return &InitConfig{
 		Address:           config.Address,
 		PrivateKey:        config.TLS.PrivateKey,
 		Certificate:       config.TLS.Certificate,
 		Password:          config.TLS.Password,
 		VerifyClientCerts: config.TLS.Client.VerifyCerts,
+		APIKeys:           config.TLS.Client.APIKeys,
 		ProxyIdentities:   config.TLS.Proxy.Identity,
 		ProxyClientCert:   config.TLS.Proxy.Header.ClientCert,
+		Compression:       config.Compression,
+		SNI:               config.TLS.SNI,
+		ReceiptKey:        config.Receipts.PrivateKey,
+		ReceiptPassword:   config.Receipts.Password,
 	}, nil
 }
 
@@ -111,6 +158,8 @@ func WriteInitConfig(filename string, config *InitConfig) error {
 
 		Address yml.String `yaml:"address"`
 
+		Compression yml.String `yaml:"compression,omitempty"`
+
 		TLS struct {
 			PrivateKey  yml.String `yaml:"key"`
 			Certificate yml.String `yaml:"cert"`
@@ -123,20 +172,32 @@ func WriteInitConfig(filename string, config *InitConfig) error {
 			} `yaml:"proxy"`
 			Client struct {
 				VerifyCerts yml.Bool `yaml:"verify_cert"`
+				APIKeys     yml.Bool `yaml:"api_key"`
 			} `yaml:"client"`
+			SNI map[string]SNIConfig `yaml:"sni,omitempty"`
 		} `yaml:"tls"`
+
+		Receipts struct {
+			PrivateKey yml.String `yaml:"key,omitempty"`
+			Password   yml.String `yaml:"password,omitempty"`
+		} `yaml:"receipts,omitempty"`
 	}
 
 	c := YAML{
-		Version: "1",
-		Address: config.Address,
+		Version:     "1",
+		Address:     config.Address,
+		Compression: config.Compression,
 	}
 	c.TLS.PrivateKey = config.PrivateKey
 	c.TLS.Certificate = config.Certificate
 	c.TLS.Password = config.Password
 	c.TLS.Client.VerifyCerts = config.VerifyClientCerts
+	c.TLS.Client.APIKeys = config.APIKeys
 	c.TLS.Proxy.Identity = config.ProxyIdentities
 	c.TLS.Proxy.Header.ClientCert = config.ProxyClientCert
+	c.TLS.SNI = config.SNI
+	c.Receipts.PrivateKey = config.ReceiptKey
+	c.Receipts.Password = config.ReceiptPassword
 	return yaml.NewEncoder(f).Encode(c)
 }
 
@@ -146,6 +207,10 @@ func WriteInitConfig(filename string, config *InitConfig) error {
 // It returns an initialized Vault and a set of UnsealKeys to
 // unseal the Vault in the future.
 func Init(path string, init *InitConfig, seal *SealConfig) (*sys.Vault, []sys.UnsealKey, error) {
+	compression, err := sys.ParseCompression(init.Compression.Value())
+	if err != nil {
+		return nil, nil, err
+	}
 	algorithm := kes.AES256_GCM_SHA256
 	if !fips.Enabled && !cpu.HasAESGCM() {
 		algorithm = kes.XCHACHA20_POLY1305
@@ -165,12 +230,17 @@ func Init(path string, init *InitConfig, seal *SealConfig) (*sys.Vault, []sys.Un
 	if err != nil {
 		return nil, nil, err
 	}
-	return sys.NewVault(sys.NewVaultFS(path, rootKey)), unsealKeys, nil
+	return sys.NewVault(sys.NewVaultFS(path, rootKey, compression)), unsealKeys, nil
 }
 
 // Open returns a new Vault that reads its initial and seal configuration
 // from config files within the given path.
 func Open(path string) (*sys.Vault, error) {
+	compression, err := readCompression(filepath.Join(path, ".init"))
+	if err != nil {
+		return nil, err
+	}
+
 	stanzaBytes, err := os.ReadFile(filepath.Join(path, ".unseal"))
 	if err != nil {
 		return nil, err
@@ -187,7 +257,32 @@ func Open(path string) (*sys.Vault, error) {
 	if err := rootKey.UnmarshalBinary(rootKeyBytes); err != nil {
 		return nil, err
 	}
-	return sys.NewVault(sys.NewVaultFS(path, rootKey)), nil
+	return sys.NewVault(sys.NewVaultFS(path, rootKey, compression)), nil
+}
+
+// readCompression returns the compression algorithm specified
+// in the init config file. Entries are decompressed on read
+// regardless of the compression algorithm. Hence, it only parses
+// the compression and returns sys.NoCompression if the file does
+// not exist or cannot be parsed, e.g. since it has been written
+// by another version. Otherwise, such data directories could not
+// be opened anymore.
+func readCompression(filename string) (sys.Compression, error) {
+	b, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return sys.NoCompression, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var config struct {
+		Compression yml.String `yaml:"compression"`
+	}
+	if err = yaml.Unmarshal(b, &config); err != nil {
+		return sys.NoCompression, nil
+	}
+	return sys.ParseCompression(config.Compression.Value())
 }
 
 func initFS(path string) error {
@@ -25,18 +25,23 @@ import (
 // reads/writes identities from/to the given
 // directory path and en/decrypts them
 // with the given encryption key.
-func NewIdentityFS(filename string, key key.Key) IdentityFS {
+//
+// Entries are compressed with the given compression
+// algorithm before they get encrypted.
+func NewIdentityFS(filename string, key key.Key, compression Compression) IdentityFS {
 	return &identityFS{
-		rootDir: filename,
-		rootKey: key,
+		rootDir:     filename,
+		rootKey:     keyRing{Key: key},
+		compression: compression,
 	}
 }
 
 var _ IdentityFS = (*identityFS)(nil)
 
 type identityFS struct {
-	rootDir string
-	rootKey key.Key
+	rootDir     string
+	rootKey     keyRing
+	compression Compression
 }
 
 func (fs *identityFS) Admin(_ context.Context) (kes.Identity, error) {
@@ -73,6 +78,9 @@ func (fs *identityFS) Admin(_ context.Context) (kes.Identity, error) {
 	if err != nil {
 		return "", err
 	}
+	if plaintext, err = decompress(plaintext); err != nil {
+		return "", err
+	}
 
 	var info auth.IdentityInfo
 	if err = info.UnmarshalBinary(plaintext); err != nil {
@@ -140,6 +148,9 @@ func (fs *identityFS) SetAdmin(_ context.Context, admin kes.Identity) error {
 	if err != nil {
 		return err
 	}
+	if plaintext, err = compress(fs.compression, plaintext); err != nil {
+		return err
+	}
 	ciphertext, err := fs.rootKey.Wrap(plaintext, []byte(path.Join(AdminDir, admin.String())))
 	if err != nil {
 		return err
@@ -166,11 +177,69 @@ func (fs *identityFS) SetAdmin(_ context.Context, admin kes.Identity) error {
 	return nil
 }
 
-func (fs *identityFS) AssignPolicy(_ context.Context, policy string, identity kes.Identity) error {
+func (fs *identityFS) AddAdmin(ctx context.Context, identity, createdBy kes.Identity) error {
+	if err := valid(identity.String()); err != nil {
+		return err
+	}
+	if _, err := fs.GetIdentity(ctx, identity); err == nil {
+		return kes.NewError(http.StatusConflict, "identity already exists")
+	} else if !errors.Is(err, kes.ErrIdentityNotFound) {
+		return err
+	}
+	return fs.writeIdentity(identity, auth.IdentityInfo{
+		IsAdmin:   true,
+		CreatedAt: time.Now().UTC(),
+		CreatedBy: createdBy,
+	})
+}
+
+func (fs *identityFS) RemoveAdmin(ctx context.Context, identity kes.Identity) error {
+	admin, err := fs.Admin(ctx)
+	if err != nil {
+		return err
+	}
+	if identity == admin {
+		return kes.NewError(http.StatusBadRequest, "cannot remove enclave admin")
+	}
+	info, err := fs.GetIdentity(ctx, identity)
+	if err != nil {
+		return err
+	}
+	if !info.IsAdmin {
+		return kes.NewError(http.StatusBadRequest, "identity is not an admin")
+	}
+	return fs.DeleteIdentity(ctx, identity)
+}
+
+func (fs *identityFS) AssignPolicy(_ context.Context, policy string, identity kes.Identity, expiresAt time.Time, labels map[string]string) error {
 	if err := valid(identity.String()); err != nil {
 		return err
 	}
+	return fs.writeIdentity(identity, auth.IdentityInfo{
+		Policy:    policy,
+		IsAdmin:   false,
+		CreatedAt: time.Now().UTC(),
+		CreatedBy: "", // TODO
+		ExpiresAt: expiresAt,
+		Labels:    labels,
+	})
+}
 
+func (fs *identityFS) RenewIdentity(ctx context.Context, identity kes.Identity, expiresAt time.Time) error {
+	info, err := fs.GetIdentity(ctx, identity)
+	if err != nil {
+		return err
+	}
+	if info.IsAdmin {
+		return kes.NewError(http.StatusBadRequest, "cannot renew admin")
+	}
+	info.ExpiresAt = expiresAt
+	return fs.writeIdentity(identity, info)
+}
+
+// writeIdentity writes the identity info of the given
+// identity, replacing any existing one.
+func (fs *identityFS) writeIdentity(identity kes.Identity, info auth.IdentityInfo) error {
 	// First, write the identity to a temporary file
 	// with a filename that cannot be a client-specified
 	// identity - i.e. contains invalid character ('.').
@@ -186,16 +255,13 @@ func (fs *identityFS) AssignPolicy(_ context.Context, policy string, identity ke
 	}
 	defer file.Close()
 
-	info := auth.IdentityInfo{
-		Policy:    policy,
-		IsAdmin:   false,
-		CreatedAt: time.Now().UTC(),
-		CreatedBy: "", // TODO
-	}
 	plaintext, err := info.MarshalBinary()
 	if err != nil {
 		return err
 	}
+	if plaintext, err = compress(fs.compression, plaintext); err != nil {
+		return err
+	}
 	ciphertext, err := fs.rootKey.Wrap(plaintext, []byte(identity.String()))
 	if err != nil {
 		return err
@@ -266,6 +332,9 @@ func (fs *identityFS) GetIdentity(_ context.Context, identity kes.Identity) (aut
 	if err != nil {
 		return auth.IdentityInfo{}, err
 	}
+	if plaintext, err = decompress(plaintext); err != nil {
+		return auth.IdentityInfo{}, err
+	}
 
 	var info auth.IdentityInfo
 	if err = info.UnmarshalBinary(plaintext); err != nil {
@@ -0,0 +1,122 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package sys
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/key"
+)
+
+func TestRenewIdentity(t *testing.T) {
+	const (
+		Policy   = "my-policy"
+		Identity = kes.Identity("5f2f4ef3e0e340a07fc330f58ef0a1c4d661e564ab10795f9231f75fcfe572f1")
+		Unknown  = kes.Identity("3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22")
+	)
+	ctx := context.Background()
+
+	rootKey, err := key.Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to generate root key: %v", err)
+	}
+	fs := NewIdentityFS(t.TempDir(), rootKey, NoCompression)
+
+	expiresAt := time.Now().Add(-time.Minute).UTC()
+	if err = fs.AssignPolicy(ctx, Policy, Identity, expiresAt, map[string]string{"team": "storage"}); err != nil {
+		t.Fatalf("Failed to assign policy: %v", err)
+	}
+	info, err := fs.GetIdentity(ctx, Identity)
+	if err != nil {
+		t.Fatalf("Failed to get identity: %v", err)
+	}
+	if !info.Expired() {
+		t.Fatalf("Identity should have expired at '%v'", info.ExpiresAt)
+	}
+
+	renewedAt := time.Now().Add(time.Hour).UTC()
+	if err = fs.RenewIdentity(ctx, Identity, renewedAt); err != nil {
+		t.Fatalf("Failed to renew identity: %v", err)
+	}
+	renewed, err := fs.GetIdentity(ctx, Identity)
+	if err != nil {
+		t.Fatalf("Failed to get identity: %v", err)
+	}
+	if renewed.Expired() {
+		t.Fatalf("Identity has expired after renewal: expires at '%v'", renewed.ExpiresAt)
+	}
+	if !renewed.ExpiresAt.Equal(renewedAt) {
+		t.Fatalf("Expiry mismatch: got '%v' - want '%v'", renewed.ExpiresAt, renewedAt)
+	}
+	if renewed.Policy != info.Policy || !renewed.CreatedAt.Equal(info.CreatedAt) {
+		t.Fatalf("Renewal changed identity: got '%v' - want '%v'", renewed, info)
+	}
+	if renewed.Labels["team"] != "storage" {
+		t.Fatalf("Renewal changed labels: got '%v' - want '%v'", renewed.Labels, info.Labels)
+	}
+
+	if err = fs.RenewIdentity(ctx, Unknown, renewedAt); !errors.Is(err, kes.ErrIdentityNotFound) {
+		t.Fatalf("Renewing unknown identity: got '%v' - want '%v'", err, kes.ErrIdentityNotFound)
+	}
+}
+
+func TestAddRemoveAdmin(t *testing.T) {
+	const (
+		Admin    = kes.Identity("5f2f4ef3e0e340a07fc330f58ef0a1c4d661e564ab10795f9231f75fcfe572f1")
+		Operator = kes.Identity("3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22")
+		User     = kes.Identity("736bf58626441e3e134a2daf2e6a8441b40e1abc0eac510878168c8aac9f2b0b")
+	)
+	ctx := context.Background()
+
+	rootKey, err := key.Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to generate root key: %v", err)
+	}
+	fs := NewIdentityFS(t.TempDir(), rootKey, NoCompression)
+	if err = fs.SetAdmin(ctx, Admin); err != nil {
+		t.Fatalf("Failed to set admin: %v", err)
+	}
+	if err = fs.AssignPolicy(ctx, "my-policy", User, time.Time{}, nil); err != nil {
+		t.Fatalf("Failed to assign policy: %v", err)
+	}
+
+	if err = fs.AddAdmin(ctx, Operator, Admin); err != nil {
+		t.Fatalf("Failed to add admin: %v", err)
+	}
+	info, err := fs.GetIdentity(ctx, Operator)
+	if err != nil {
+		t.Fatalf("Failed to get identity: %v", err)
+	}
+	if !info.IsAdmin || info.CreatedBy != Admin {
+		t.Fatalf("Invalid admin: got '%v'", info)
+	}
+	if admin, err := fs.Admin(ctx); err != nil || admin != Admin {
+		t.Fatalf("Enclave admin changed: got '%v' - want '%v'", admin, Admin)
+	}
+
+	if err = fs.AddAdmin(ctx, Operator, Admin); err == nil {
+		t.Fatal("Adding an existing admin should fail")
+	}
+	if err = fs.AddAdmin(ctx, User, Admin); err == nil {
+		t.Fatal("Adding an existing identity as admin should fail")
+	}
+	if err = fs.RemoveAdmin(ctx, Admin); err == nil {
+		t.Fatal("Removing the enclave admin should fail")
+	}
+	if err = fs.RemoveAdmin(ctx, User); err == nil {
+		t.Fatal("Removing a non-admin identity should fail")
+	}
+
+	if err = fs.RemoveAdmin(ctx, Operator); err != nil {
+		t.Fatalf("Failed to remove admin: %v", err)
+	}
+	if _, err = fs.GetIdentity(ctx, Operator); !errors.Is(err, kes.ErrIdentityNotFound) {
+		t.Fatalf("Removed admin still exists: got '%v' - want '%v'", err, kes.ErrIdentityNotFound)
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package sys
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/key"
+)
+
+func TestCreateIdentity(t *testing.T) {
+	const (
+		Enclave   = "tenant-1"
+		Admin     = kes.Identity("3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22")
+		Identity1 = kes.Identity("a4b6b8f4d2b1b4d8b3c6c8e3f1a5b6c7d8e9f0a1b2c3d4e5f6a7b8c9d0e1f2a3")
+		Identity2 = kes.Identity("b4b6b8f4d2b1b4d8b3c6c8e3f1a5b6c7d8e9f0a1b2c3d4e5f6a7b8c9d0e1f2a3")
+	)
+	ctx := context.Background()
+
+	rootKey, err := key.Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to generate root key: %v", err)
+	}
+	vault := NewVault(NewVaultFS(t.TempDir(), rootKey, NoCompression))
+	if _, err = vault.CreateEnclave(ctx, Enclave, Admin); err != nil {
+		t.Fatalf("Failed to create enclave: %v", err)
+	}
+	enclave, err := vault.GetEnclave(ctx, Enclave)
+	if err != nil {
+		t.Fatalf("Failed to get enclave: %v", err)
+	}
+	for _, name := range []string{"default-policy", "other-policy"} {
+		if err = enclave.SetPolicy(ctx, name, auth.Policy{CreatedBy: Admin}); err != nil {
+			t.Fatalf("Failed to create policy '%s': %v", name, err)
+		}
+	}
+
+	if err = enclave.CreateIdentity(ctx, "", Identity1, time.Time{}, nil); err == nil {
+		t.Fatal("Created identity without policy and default policy")
+	}
+	if _, err = vault.UpdateEnclave(ctx, Enclave, func(info *EnclaveInfo) error {
+		info.DefaultPolicy = "default-policy"
+		return nil
+	}); err != nil {
+		t.Fatalf("Failed to update enclave: %v", err)
+	}
+	if enclave, err = vault.GetEnclave(ctx, Enclave); err != nil {
+		t.Fatalf("Failed to get enclave: %v", err)
+	}
+
+	if err = enclave.CreateIdentity(ctx, "", Identity1, time.Time{}, nil); err != nil {
+		t.Fatalf("Failed to create identity: %v", err)
+	}
+	if err = enclave.CreateIdentity(ctx, "other-policy", Identity2, time.Time{}, nil); err != nil {
+		t.Fatalf("Failed to create identity: %v", err)
+	}
+	if err = enclave.CreateIdentity(ctx, "other-policy", Identity1, time.Time{}, nil); !errors.Is(err, ErrIdentityExists) {
+		t.Fatalf("Created existing identity: %v", err)
+	}
+	if err = enclave.CreateIdentity(ctx, "", Admin, time.Time{}, nil); !errors.Is(err, ErrIdentityExists) {
+		t.Fatalf("Created enclave admin as identity: %v", err)
+	}
+	if err = enclave.CreateIdentity(ctx, "unknown-policy", "c4b6b8f4d2b1b4d8b3c6c8e3f1a5b6c7d8e9f0a1b2c3d4e5f6a7b8c9d0e1f2a3", time.Time{}, nil); !errors.Is(err, kes.ErrPolicyNotFound) {
+		t.Fatalf("Created identity with non-existing policy: %v", err)
+	}
+
+	for identity, policy := range map[kes.Identity]string{Identity1: "default-policy", Identity2: "other-policy"} {
+		info, err := enclave.GetIdentity(ctx, identity)
+		if err != nil {
+			t.Fatalf("Failed to get identity '%s': %v", identity, err)
+		}
+		if info.Policy != policy {
+			t.Fatalf("Identity '%s' assigned to wrong policy: got '%s' - want '%s'", identity, info.Policy, policy)
+		}
+	}
+}
@@ -10,7 +10,10 @@ import (
 	"errors"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"aead.dev/mem"
 	"github.com/minio/kes-go"
@@ -22,23 +25,46 @@ import (
 // reads/writes keys from/to the given
 // directory path and en/decrypts them
 // with the given encryption key.
-func NewKeyFS(filename string, key key.Key) KeyFS {
+//
+// Entries are compressed with the given compression
+// algorithm before they get encrypted.
+func NewKeyFS(filename string, key key.Key, compression Compression) KeyFS {
 	return &keyFS{
-		rootDir: filename,
-		rootKey: key,
+		rootDir:     filename,
+		rootKey:     keyRing{Key: key},
+		compression: compression,
 	}
 }
 
 type keyFS struct {
-	rootDir string
-	rootKey key.Key
+	rootDir     string
+	rootKey     keyRing
+	compression Compression
 }
 
 func (fs *keyFS) CreateKey(_ context.Context, name string, key key.Key) error {
-	if err := valid(name); err != nil {
+	if err := validPath(name); err != nil {
 		return err
 	}
+	return fs.writeKey(name, key)
+}
 
+func (fs *keyFS) SetKey(_ context.Context, name string, key key.Key) error {
+	if err := validPath(name); err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(fs.rootDir, fileName(name))); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return kes.ErrKeyNotFound
+		}
+		return err
+	}
+	return fs.writeKey(name, key)
+}
+
+// writeKey writes the key to the file with the given
+// name. It replaces the file if it exists already.
+func (fs *keyFS) writeKey(name string, key key.Key) error {
 	// First, we write the key to a temporary file.
 	// The tmp file name contains a character ('.')
 	// that is not allowed for client-specified key names.
@@ -58,6 +84,9 @@ func (fs *keyFS) CreateKey(_ context.Context, name string, key key.Key) error {
 	if err != nil {
 		return err
 	}
+	if plaintext, err = compress(fs.compression, plaintext); err != nil {
+		return err
+	}
 	ciphertext, err := fs.rootKey.Wrap(plaintext, []byte(name))
 	if err != nil {
 		return err
@@ -77,7 +106,7 @@ func (fs *keyFS) CreateKey(_ context.Context, name string, key key.Key) error {
 		return err
 	}
 
-	if err = os.Rename(filename, filepath.Join(fs.rootDir, name)); err != nil {
+	if err = os.Rename(filename, filepath.Join(fs.rootDir, fileName(name))); err != nil {
 		os.Remove(filename)
 		return err
 	}
@@ -85,10 +114,10 @@ func (fs *keyFS) CreateKey(_ context.Context, name string, key key.Key) error {
 }
 
 func (fs *keyFS) GetKey(_ context.Context, name string) (key.Key, error) {
-	if err := valid(name); err != nil {
+	if err := validPath(name); err != nil {
 		return key.Key{}, err
 	}
-	filename := filepath.Join(fs.rootDir, name)
+	filename := filepath.Join(fs.rootDir, fileName(name))
 	file, err := os.Open(filename)
 	if errors.Is(err, os.ErrNotExist) {
 		return key.Key{}, kes.ErrKeyNotFound
@@ -106,6 +135,9 @@ func (fs *keyFS) GetKey(_ context.Context, name string) (key.Key, error) {
 	if err != nil {
 		return key.Key{}, err
 	}
+	if plaintext, err = decompress(plaintext); err != nil {
+		return key.Key{}, err
+	}
 
 	var k key.Key
 	if err = k.UnmarshalBinary(plaintext); err != nil {
@@ -115,10 +147,10 @@ func (fs *keyFS) GetKey(_ context.Context, name string) (key.Key, error) {
 }
 
 func (fs *keyFS) DeleteKey(_ context.Context, name string) error {
-	if err := valid(name); err != nil {
+	if err := validPath(name); err != nil {
 		return err
 	}
-	err := os.Remove(filepath.Join(fs.rootDir, name))
+	err := os.Remove(filepath.Join(fs.rootDir, fileName(name)))
 	if errors.Is(err, os.ErrNotExist) {
 		return kes.ErrKeyNotFound
 	}
@@ -136,6 +168,106 @@ func (fs *keyFS) ListKeys(ctx context.Context) (kv.Iter[string], error) {
 	}, nil
 }
 
+// keyTrashDir is the directory, within the key store, that
+// contains deleted keys. Its name contains a '.' and, hence,
+// cannot be a key name.
+const keyTrashDir = ".deleted"
+
+func (fs *keyFS) TrashKey(ctx context.Context, name string) error {
+	k, err := fs.GetKey(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := DeletedKey{Key: k, DeletedAt: time.Now().UTC()}.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	if plaintext, err = compress(fs.compression, plaintext); err != nil {
+		return err
+	}
+
+	trashDir := filepath.Join(fs.rootDir, keyTrashDir)
+	if err = os.MkdirAll(trashDir, 0o755); err != nil {
+		return err
+	}
+	err = replaceFile(
+		filepath.Join(trashDir, fileName(name)),
+		filepath.Join(trashDir, ".deleted.tmp"),
+		fs.rootKey,
+		plaintext,
+		[]byte(path.Join(keyTrashDir, name)),
+	)
+	if err != nil {
+		return err
+	}
+	return fs.DeleteKey(ctx, name)
+}
+
+func (fs *keyFS) RestoreKey(ctx context.Context, name string) error {
+	deleted, err := fs.GetDeletedKey(ctx, name)
+	if err != nil {
+		return err
+	}
+	if _, err = os.Stat(filepath.Join(fs.rootDir, fileName(name))); err == nil {
+		return kes.ErrKeyExists
+	}
+	if err = fs.writeKey(name, deleted.Key); err != nil {
+		return err
+	}
+	return fs.PurgeKey(ctx, name)
+}
+
+func (fs *keyFS) GetDeletedKey(_ context.Context, name string) (DeletedKey, error) {
+	if err := validPath(name); err != nil {
+		return DeletedKey{}, err
+	}
+
+	filename := filepath.Join(fs.rootDir, keyTrashDir, fileName(name))
+	plaintext, err := readFile(filename, fs.rootKey, key.MaxSize, []byte(path.Join(keyTrashDir, name)))
+	if errors.Is(err, os.ErrNotExist) {
+		return DeletedKey{}, kes.ErrKeyNotFound
+	}
+	if err != nil {
+		return DeletedKey{}, err
+	}
+	if plaintext, err = decompress(plaintext); err != nil {
+		return DeletedKey{}, err
+	}
+
+	var deleted DeletedKey
+	if err = deleted.UnmarshalBinary(plaintext); err != nil {
+		return DeletedKey{}, err
+	}
+	return deleted, nil
+}
+
+func (fs *keyFS) PurgeKey(_ context.Context, name string) error {
+	if err := validPath(name); err != nil {
+		return err
+	}
+	err := os.Remove(filepath.Join(fs.rootDir, keyTrashDir, fileName(name)))
+	if errors.Is(err, os.ErrNotExist) {
+		return kes.ErrKeyNotFound
+	}
+	return err
+}
+
+func (fs *keyFS) ListDeletedKeys(ctx context.Context) (kv.Iter[string], error) {
+	trashDir := filepath.Join(fs.rootDir, keyTrashDir)
+	if err := os.MkdirAll(trashDir, 0o755); err != nil {
+		return nil, err
+	}
+	file, err := os.Open(trashDir)
+	if err != nil {
+		return nil, err
+	}
+	return &keyIterator{
+		ctx: ctx,
+		dir: file,
+	}, nil
+}
+
 type keyIterator struct {
 	ctx   context.Context
 	dir   *os.File
@@ -145,45 +277,37 @@ type keyIterator struct {
 }
 
 func (i *keyIterator) Next() (string, bool) {
-	const TmpFile = ".key.tmp"
-	if len(i.names) > 0 {
-		if i.names[0] == TmpFile {
+	for {
+		for len(i.names) > 0 {
+			name := i.names[0]
 			i.names = i.names[1:]
+
+			// Temp. files and directories, like the key trash,
+			// contain a '.' and, hence, are not keys.
+			if !strings.ContainsRune(name, '.') {
+				return entryName(name), true
+			}
+		}
+		if i.err != nil {
+			return "", false
 		}
-	}
-	if len(i.names) > 0 {
-		v := i.names[0]
-		i.names = i.names[1:]
-		return v, true
-	}
-	if i.err != nil {
-		return "", false
-	}
 
-	select {
-	case <-i.ctx.Done():
-		i.err = i.ctx.Err()
-		return "", false
-	default:
-	}
+		select {
+		case <-i.ctx.Done():
+			i.err = i.ctx.Err()
+			return "", false
+		default:
+		}
 
-	const N = 250
-	i.names, i.err = i.dir.Readdirnames(N)
-	if i.err != nil && i.err != io.EOF {
-		return "", false
-	}
-	if len(i.names) == 0 && i.err == io.EOF {
-		return "", false
-	}
-	if i.names[0] == TmpFile {
-		i.names = i.names[1:]
-	}
-	if len(i.names) > 0 {
-		v := i.names[0]
-		i.names = i.names[1:]
-		return v, true
+		const N = 250
+		i.names, i.err = i.dir.Readdirnames(N)
+		if i.err != nil && i.err != io.EOF {
+			return "", false
+		}
+		if len(i.names) == 0 {
+			return "", false
+		}
 	}
-	return "", false
 }
 
 func (i *keyIterator) Name() string { return i.next }
@@ -22,20 +22,25 @@ import (
 // reads/writes policies from/to the given
 // directory path and en/decrypts them with
 // the given encryption key.
-func NewPolicyFS(filename string, key key.Key) PolicyFS {
+//
+// Entries are compressed with the given compression
+// algorithm before they get encrypted.
+func NewPolicyFS(filename string, key key.Key, compression Compression) PolicyFS {
 	return &policyFS{
-		rootDir: filename,
-		rootKey: key,
+		rootDir:     filename,
+		rootKey:     keyRing{Key: key},
+		compression: compression,
 	}
 }
 
 type policyFS struct {
-	rootDir string
-	rootKey key.Key
+	rootDir     string
+	rootKey     keyRing
+	compression Compression
 }
 
 func (fs *policyFS) SetPolicy(_ context.Context, name string, policy auth.Policy) error {
-	if err := valid(name); err != nil {
+	if err := validPath(name); err != nil {
 		return err
 	}
 
@@ -58,6 +63,9 @@ func (fs *policyFS) SetPolicy(_ context.Context, name string, policy auth.Policy
 	if err != nil {
 		return err
 	}
+	if plaintext, err = compress(fs.compression, plaintext); err != nil {
+		return err
+	}
 	ciphertext, err := fs.rootKey.Wrap(plaintext, []byte(name))
 	if err != nil {
 		return err
@@ -77,7 +85,7 @@ func (fs *policyFS) SetPolicy(_ context.Context, name string, policy auth.Policy
 		return err
 	}
 
-	if err = os.Rename(filename, filepath.Join(fs.rootDir, name)); err != nil {
+	if err = os.Rename(filename, filepath.Join(fs.rootDir, fileName(name))); err != nil {
 		os.Remove(filename)
 		return err
 	}
@@ -85,11 +93,11 @@ func (fs *policyFS) SetPolicy(_ context.Context, name string, policy auth.Policy
 }
 
 func (fs *policyFS) GetPolicy(_ context.Context, name string) (auth.Policy, error) {
-	if err := valid(name); err != nil {
+	if err := validPath(name); err != nil {
 		return auth.Policy{}, err
 	}
 
-	filename := filepath.Join(fs.rootDir, name)
+	filename := filepath.Join(fs.rootDir, fileName(name))
 	file, err := os.Open(filename)
 	if errors.Is(err, os.ErrNotExist) {
 		return auth.Policy{}, kes.ErrPolicyNotFound
@@ -109,6 +117,9 @@ func (fs *policyFS) GetPolicy(_ context.Context, name string) (auth.Policy, erro
 	if err != nil {
 		return auth.Policy{}, err
 	}
+	if plaintext, err = decompress(plaintext); err != nil {
+		return auth.Policy{}, err
+	}
 	var policy auth.Policy
 	if err = policy.UnmarshalBinary(plaintext); err != nil {
 		return auth.Policy{}, err
@@ -117,11 +128,11 @@ func (fs *policyFS) GetPolicy(_ context.Context, name string) (auth.Policy, erro
 }
 
 func (fs *policyFS) DeletePolicy(_ context.Context, name string) error {
-	if err := valid(name); err != nil {
+	if err := validPath(name); err != nil {
 		return err
 	}
 
-	err := os.Remove(filepath.Join(fs.rootDir, name))
+	err := os.Remove(filepath.Join(fs.rootDir, fileName(name)))
 	if errors.Is(err, os.ErrNotExist) {
 		return kes.ErrPolicyNotFound
 	}
@@ -149,7 +160,7 @@ type policyIterator struct {
 
 func (i *policyIterator) Next() bool {
 	if len(i.names) > 0 {
-		i.next, i.names = i.names[0], i.names[1:]
+		i.next, i.names = entryName(i.names[0]), i.names[1:]
 		return true
 	}
 	if i.err != nil {
@@ -171,7 +182,7 @@ func (i *policyIterator) Next() bool {
 	if len(i.names) == 0 && i.err == io.EOF {
 		return false
 	}
-	i.next, i.names = i.names[0], i.names[1:]
+	i.next, i.names = entryName(i.names[0]), i.names[1:]
 	return true
 }
 
@@ -0,0 +1,14 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+//go:build !minimal
+// +build !minimal
+
+package sys
+
+// Minimal reports whether the binary has been built
+// with the 'minimal' build profile. Minimal builds
+// do not support some keystores and never produce
+// colored terminal output.
+const Minimal = false
@@ -0,0 +1,176 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package sys
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/minio/kes-go"
+)
+
+// Errors returned when an Enclave has exceeded one of its quotas.
+var (
+	// ErrKeyQuota is returned when a key cannot be created
+	// since the Enclave contains the max. number of keys.
+	ErrKeyQuota = kes.NewError(http.StatusForbidden, "enclave key quota exceeded")
+
+	// ErrIdentityQuota is returned when an identity cannot be
+	// assigned since the Enclave contains the max. number of
+	// identities.
+	ErrIdentityQuota = kes.NewError(http.StatusForbidden, "enclave identity quota exceeded")
+
+	// ErrRequestQuota is returned when a request is rejected
+	// since the Enclave has exceeded its request rate.
+	ErrRequestQuota = kes.NewError(http.StatusTooManyRequests, "enclave request quota exceeded")
+)
+
+// requestLimit is a token bucket that limits the request
+// rate of an Enclave. It holds one second worth of requests
+// such that an Enclave can send short bursts of requests.
+//
+// A nil requestLimit does not limit any requests.
+type requestLimit struct {
+	rate float64
+
+	lock   sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// newRequestLimit returns a new requestLimit for the
+// given rate, in requests per second. It returns nil
+// if rate <= 0.
+func newRequestLimit(rate float64) *requestLimit {
+	if rate <= 0 {
+		return nil
+	}
+	return &requestLimit{
+		rate:   rate,
+		tokens: math.Max(1, rate),
+	}
+}
+
+// take takes one token from the bucket. It returns
+// ErrRequestQuota if no token is available.
+func (l *requestLimit) take(now time.Time) error {
+	if l == nil {
+		return nil
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	burst := math.Max(1, l.rate)
+	if !l.last.IsZero() {
+		l.tokens = math.Min(burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+
+	if l.tokens < 1 {
+		return ErrRequestQuota
+	}
+	l.tokens--
+	return nil
+}
+
+// countKeys returns the number of keys within the
+// Enclave. It stops counting once it has reached max.
+func (e *Enclave) countKeys(ctx context.Context, max int) (int, error) {
+	iter, err := e.ListKeys(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer iter.Close()
+
+	var n int
+	for ; n < max; n++ {
+		if _, ok := iter.Next(); !ok {
+			break
+		}
+	}
+	return n, iter.Close()
+}
+
+// countIdentities returns the number of identities within
+// the Enclave. It stops counting once it has reached max.
+func (e *Enclave) countIdentities(ctx context.Context, max int) (int, error) {
+	iter, err := e.ListIdentities(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer iter.Close()
+
+	var n int
+	for ; n < max && iter.Next(); n++ {
+	}
+	return n, iter.Close()
+}
+
+// verifyKeyQuota returns ErrKeyQuota if the Enclave
+// cannot contain any additional key.
+func (e *Enclave) verifyKeyQuota(ctx context.Context) error {
+	if e.maxKeys <= 0 {
+		return nil
+	}
+	n, err := e.countKeys(ctx, e.maxKeys)
+	if err != nil {
+		return err
+	}
+	if n >= e.maxKeys {
+		return ErrKeyQuota
+	}
+	return nil
+}
+
+// KeyQuotaRemaining returns how many keys can be created
+// within the Enclave until it has reached its key quota.
+// It returns false if the Enclave has no key quota.
+func (e *Enclave) KeyQuotaRemaining(ctx context.Context) (int, bool, error) {
+	if e.maxKeys <= 0 {
+		return 0, false, nil
+	}
+	n, err := e.countKeys(ctx, e.maxKeys)
+	if err != nil {
+		return 0, false, err
+	}
+	return e.maxKeys - n, true, nil
+}
+
+// verifyIdentityQuota returns ErrIdentityQuota if the
+// Enclave cannot contain the identity in addition to
+// its existing identities.
+func (e *Enclave) verifyIdentityQuota(ctx context.Context, identity kes.Identity) error {
+	if e.maxIdentities <= 0 {
+		return nil
+	}
+	if _, err := e.GetIdentity(ctx, identity); err == nil {
+		return nil // Existing identities don't count against the quota
+	}
+	n, err := e.countIdentities(ctx, e.maxIdentities)
+	if err != nil {
+		return err
+	}
+	if n >= e.maxIdentities {
+		return ErrIdentityQuota
+	}
+	return nil
+}
+
+// Usage returns the number of keys and identities within
+// the Enclave. Like the identity quota, the number of
+// identities does not include the Enclave admin.
+func (e *Enclave) Usage(ctx context.Context) (keys, identities int, err error) {
+	if keys, err = e.countKeys(ctx, math.MaxInt); err != nil {
+		return 0, 0, err
+	}
+	if identities, err = e.countIdentities(ctx, math.MaxInt); err != nil {
+		return 0, 0, err
+	}
+	return keys, identities, nil
+}
@@ -0,0 +1,171 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package sys
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/key"
+)
+
+func TestEnclaveQuota(t *testing.T) {
+	const (
+		Enclave = "tenant-1"
+		Admin   = kes.Identity("3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22")
+	)
+	ctx := context.Background()
+
+	rootKey, err := key.Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to generate root key: %v", err)
+	}
+	vault := NewVault(NewVaultFS(t.TempDir(), rootKey, NoCompression))
+	if _, err = vault.CreateEnclave(ctx, Enclave, Admin); err != nil {
+		t.Fatalf("Failed to create enclave: %v", err)
+	}
+	if _, err = vault.UpdateEnclave(ctx, Enclave, func(info *EnclaveInfo) error {
+		info.KeyRetention = time.Hour
+		info.MaxKeys = 2
+		info.MaxIdentities = 1
+		return nil
+	}); err != nil {
+		t.Fatalf("Failed to update enclave: %v", err)
+	}
+	enclave, err := vault.GetEnclave(ctx, Enclave)
+	if err != nil {
+		t.Fatalf("Failed to get enclave: %v", err)
+	}
+
+	dataKey, err := key.Random(kes.AES256_GCM_SHA256, Admin)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	for i, name := range []string{"key-1", "key-2"} {
+		if err = enclave.CreateKey(ctx, name, dataKey); err != nil {
+			t.Fatalf("Failed to create key '%s': %v", name, err)
+		}
+		remaining, ok, err := enclave.KeyQuotaRemaining(ctx)
+		if err != nil || !ok {
+			t.Fatalf("Failed to get remaining key quota: %v", err)
+		}
+		if remaining != 1-i {
+			t.Fatalf("Remaining key quota mismatch: got %d - want %d", remaining, 1-i)
+		}
+	}
+	if err = enclave.CreateKey(ctx, "key-3", dataKey); !errors.Is(err, ErrKeyQuota) {
+		t.Fatalf("Created key beyond key quota: %v", err)
+	}
+	if err = enclave.DeleteKey(ctx, "key-1"); err != nil {
+		t.Fatalf("Failed to delete key: %v", err)
+	}
+	if err = enclave.CreateKey(ctx, "key-3", dataKey); err != nil {
+		t.Fatalf("Failed to create key after deleting a key: %v", err)
+	}
+	if err = enclave.RestoreKey(ctx, "key-1"); !errors.Is(err, ErrKeyQuota) {
+		t.Fatalf("Restored key beyond key quota: %v", err)
+	}
+
+	// The enclave admin does not count against the identity quota.
+	const (
+		Identity1 = kes.Identity("a4b6b8f4d2b1b4d8b3c6c8e3f1a5b6c7d8e9f0a1b2c3d4e5f6a7b8c9d0e1f2a3")
+		Identity2 = kes.Identity("b4b6b8f4d2b1b4d8b3c6c8e3f1a5b6c7d8e9f0a1b2c3d4e5f6a7b8c9d0e1f2a3")
+	)
+	if err = enclave.AssignPolicy(ctx, "my-policy", Identity1, time.Time{}, nil); err != nil {
+		t.Fatalf("Failed to assign identity: %v", err)
+	}
+	if err = enclave.AssignPolicy(ctx, "my-policy", Identity2, time.Time{}, nil); !errors.Is(err, ErrIdentityQuota) {
+		t.Fatalf("Assigned identity beyond identity quota: %v", err)
+	}
+	if err = enclave.AssignPolicy(ctx, "other-policy", Identity1, time.Time{}, nil); err != nil {
+		t.Fatalf("Failed to re-assign existing identity: %v", err)
+	}
+
+	keys, identities, err := enclave.Usage(ctx)
+	if err != nil {
+		t.Fatalf("Failed to compute enclave usage: %v", err)
+	}
+	if keys != 2 || identities != 1 {
+		t.Fatalf("Usage mismatch: got %d keys and %d identities - want 2 and 1", keys, identities)
+	}
+}
+
+func TestRequestLimit(t *testing.T) {
+	if err := newRequestLimit(0).take(time.Now()); err != nil {
+		t.Fatalf("Unlimited request rate rejected request: %v", err)
+	}
+
+	now := time.Now()
+	limit := newRequestLimit(2)
+	for i := 0; i < 2; i++ {
+		if err := limit.take(now); err != nil {
+			t.Fatalf("Request %d: rejected request within burst: %v", i, err)
+		}
+	}
+	if err := limit.take(now); !errors.Is(err, ErrRequestQuota) {
+		t.Fatalf("Accepted request beyond request rate: %v", err)
+	}
+	if err := limit.take(now.Add(500 * time.Millisecond)); err != nil {
+		t.Fatalf("Rejected request after refill: %v", err)
+	}
+}
+
+func TestEnclaveVerifyRequests(t *testing.T) {
+	const Enclave = "tenant-1"
+	ctx := context.Background()
+
+	apiKey, err := kes.GenerateAPIKey(nil)
+	if err != nil {
+		t.Fatalf("Failed to generate API key: %v", err)
+	}
+	rootKey, err := key.Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to generate root key: %v", err)
+	}
+	vault := NewVault(NewVaultFS(t.TempDir(), rootKey, NoCompression))
+	if _, err = vault.CreateEnclave(ctx, Enclave, apiKey.Identity()); err != nil {
+		t.Fatalf("Failed to create enclave: %v", err)
+	}
+	if _, err = vault.UpdateEnclave(ctx, Enclave, func(info *EnclaveInfo) error {
+		info.RequestRate = 1
+		return nil
+	}); err != nil {
+		t.Fatalf("Failed to update enclave: %v", err)
+	}
+	enclave, err := vault.GetEnclave(ctx, Enclave)
+	if err != nil {
+		t.Fatalf("Failed to get enclave: %v", err)
+	}
+
+	reqs := make([]*http.Request, 0, 3)
+	for _, name := range []string{"key-1", "key-2", "key-3"} {
+		req := httptest.NewRequest(http.MethodPost, "/v1/key/create/"+name, nil)
+		req.TLS = &tls.ConnectionState{}
+		req.Header.Set("Authorization", "Bearer "+apiKey.String())
+		reqs = append(reqs, auth.WithAPIKeys(req))
+	}
+
+	// All requests count as a single request. Hence, the
+	// second call exceeds the request rate of the enclave.
+	errs, err := enclave.VerifyRequests(reqs)
+	if err != nil {
+		t.Fatalf("Failed to verify requests: %v", err)
+	}
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("Request %d: admin request has been rejected: %v", i, err)
+		}
+	}
+	if _, err = enclave.VerifyRequests(reqs); !errors.Is(err, ErrRequestQuota) {
+		t.Fatalf("Verified requests beyond request quota: %v", err)
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package sys
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/minio/kes-go"
+)
+
+// Re-encryption job states.
+const (
+	ReencryptRunning     = "running"
+	ReencryptCompleted   = "completed"
+	ReencryptFailed      = "failed"
+	ReencryptInterrupted = "interrupted"
+)
+
+// ReencryptStatus describes the progress of an
+// enclave re-encryption job.
+type ReencryptStatus struct {
+	Enclave    string
+	State      string
+	Total      int // Number of entries to re-encrypt
+	Done       int // Number of entries processed so far
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Err        string
+}
+
+// reencryptJob re-encrypts all entries of an enclave
+// with new enclave root keys in the background.
+type reencryptJob struct {
+	cancel context.CancelFunc
+
+	lock   sync.Mutex
+	status ReencryptStatus
+}
+
+func (j *reencryptJob) Status() ReencryptStatus {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	return j.status
+}
+
+func (j *reencryptJob) progress(done, total int) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	j.status.Done, j.status.Total = done, total
+}
+
+func (j *reencryptJob) finish(err error) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	j.status.FinishedAt = time.Now().UTC()
+	if err != nil {
+		j.status.State = ReencryptFailed
+		j.status.Err = err.Error()
+	} else {
+		j.status.State = ReencryptCompleted
+	}
+}
+
+// ReencryptEnclave replaces the root encryption keys of the
+// given enclave with new keys and starts re-encrypting all
+// enclave entries in the background. The enclave remains
+// available during the re-encryption.
+//
+// If a previous re-encryption of the enclave got interrupted,
+// e.g. by a restart, ReencryptEnclave resumes it instead of
+// rotating the keys again.
+//
+// It returns an error if a re-encryption of the enclave is
+// running already.
+func (v *Vault) ReencryptEnclave(ctx context.Context, name string) (ReencryptStatus, error) {
+	if name == "" {
+		name = DefaultEnclaveName
+	}
+	if v.sealed {
+		return ReencryptStatus{}, kes.ErrSealed
+	}
+
+	v.jobLock.Lock()
+	defer v.jobLock.Unlock()
+
+	if job, ok := v.jobs[name]; ok && job.Status().State == ReencryptRunning {
+		return ReencryptStatus{}, kes.NewError(http.StatusConflict, "enclave re-encryption is already in progress")
+	}
+	if _, err := v.fs.RotateEnclaveKeys(ctx, name); err != nil {
+		return ReencryptStatus{}, err
+	}
+
+	// Drop the cached enclave such that new entries get
+	// encrypted with the new root keys.
+	delete(v.enclaves, name)
+	enclave, err := v.GetEnclave(ctx, name)
+	if err != nil {
+		return ReencryptStatus{}, err
+	}
+
+	jobCtx, cancel := context.WithCancel(context.Background())
+	job := &reencryptJob{
+		cancel: cancel,
+		status: ReencryptStatus{
+			Enclave:   name,
+			State:     ReencryptRunning,
+			StartedAt: time.Now().UTC(),
+		},
+	}
+	if v.jobs == nil {
+		v.jobs = map[string]*reencryptJob{}
+	}
+	v.jobs[name] = job
+
+	// The job locks the Vault for reads and the enclave for
+	// writes while re-encrypting a single entry - just like
+	// any API operation modifying the enclave.
+	locker := &reencryptLocker{
+		vault:   v.RLocker(),
+		enclave: enclave.Locker(),
+	}
+	go func() {
+		defer cancel()
+
+		err := v.fs.ReencryptEnclave(jobCtx, name, locker, job.progress)
+		if err == nil {
+			// Evict the enclave from the cache to discard
+			// the previous root keys.
+			v.lock.Lock()
+			delete(v.enclaves, name)
+			v.lock.Unlock()
+		}
+		job.finish(err)
+	}()
+	return job.Status(), nil
+}
+
+// ReencryptionStatus returns the status of the latest
+// re-encryption of the given enclave.
+//
+// If no re-encryption job is known but the enclave still
+// has previous root keys, e.g. due to a restart, it reports
+// the re-encryption as interrupted.
+func (v *Vault) ReencryptionStatus(ctx context.Context, name string) (ReencryptStatus, error) {
+	if name == "" {
+		name = DefaultEnclaveName
+	}
+	if v.sealed {
+		return ReencryptStatus{}, kes.ErrSealed
+	}
+
+	v.jobLock.Lock()
+	job, ok := v.jobs[name]
+	v.jobLock.Unlock()
+	if ok {
+		return job.Status(), nil
+	}
+
+	info, err := v.fs.GetEnclaveInfo(ctx, name)
+	if err != nil {
+		return ReencryptStatus{}, err
+	}
+	if info.Previous == nil {
+		return ReencryptStatus{}, kes.NewError(http.StatusNotFound, "no enclave re-encryption found")
+	}
+	return ReencryptStatus{
+		Enclave: name,
+		State:   ReencryptInterrupted,
+	}, nil
+}
+
+// cancelJobs stops all running re-encryption jobs.
+func (v *Vault) cancelJobs() {
+	v.jobLock.Lock()
+	defer v.jobLock.Unlock()
+
+	for _, job := range v.jobs {
+		job.cancel()
+	}
+	v.jobs = map[string]*reencryptJob{}
+}
+
+// cancelJob stops the re-encryption job of the given
+// enclave, if any.
+func (v *Vault) cancelJob(name string) {
+	v.jobLock.Lock()
+	defer v.jobLock.Unlock()
+
+	if job, ok := v.jobs[name]; ok {
+		job.cancel()
+		delete(v.jobs, name)
+	}
+}
+
+// reencryptLocker locks the Vault for reads and
+// the enclave for writes, in that order.
+type reencryptLocker struct {
+	vault   sync.Locker
+	enclave sync.Locker
+}
+
+func (l *reencryptLocker) Lock() {
+	l.vault.Lock()
+	l.enclave.Lock()
+}
+
+func (l *reencryptLocker) Unlock() {
+	l.enclave.Unlock()
+	l.vault.Unlock()
+}
@@ -0,0 +1,129 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package sys
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/key"
+	"github.com/minio/kes/internal/secret"
+)
+
+func TestReencryptEnclave(t *testing.T) {
+	const (
+		Enclave = "tenant-1"
+		Admin   = kes.Identity("3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22")
+		User    = kes.Identity("5f2f4ef3e0e340a07fc330f58ef0a1c4d661e564ab10795f9231f75fcfe572f1")
+	)
+	ctx := context.Background()
+
+	rootKey, err := key.Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to generate root key: %v", err)
+	}
+	dir := t.TempDir()
+	vaultFS := NewVaultFS(dir, rootKey, NoCompression)
+	vault := NewVault(vaultFS)
+
+	oldInfo, err := vault.CreateEnclave(ctx, Enclave, Admin)
+	if err != nil {
+		t.Fatalf("Failed to create enclave: %v", err)
+	}
+	enclave, err := vault.GetEnclave(ctx, Enclave)
+	if err != nil {
+		t.Fatalf("Failed to get enclave: %v", err)
+	}
+	dataKey, err := key.Random(kes.AES256_GCM_SHA256, Admin)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	if err = enclave.CreateKey(ctx, "my-key", dataKey); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if err = enclave.CreateSecret(ctx, "my-secret", secret.NewSecret([]byte("Hello World"), Admin)); err != nil {
+		t.Fatalf("Failed to create secret: %v", err)
+	}
+	if err = enclave.SetPolicy(ctx, "my-policy", auth.Policy{Allow: []string{"/v1/key/create/*"}}); err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+	if err = enclave.AssignPolicy(ctx, "my-policy", User, time.Time{}, nil); err != nil {
+		t.Fatalf("Failed to assign policy: %v", err)
+	}
+
+	if _, err = vault.ReencryptEnclave(ctx, Enclave); err != nil {
+		t.Fatalf("Failed to start re-encryption: %v", err)
+	}
+	var status ReencryptStatus
+	for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if status, err = vault.ReencryptionStatus(ctx, Enclave); err != nil {
+			t.Fatalf("Failed to get re-encryption status: %v", err)
+		}
+		if status.State != ReencryptRunning {
+			break
+		}
+	}
+	if status.State != ReencryptCompleted {
+		t.Fatalf("Re-encryption did not complete: state '%s' - error: %s", status.State, status.Err)
+	}
+	if status.Total != 5 || status.Done != status.Total { // key, secret, policy, identity and admin
+		t.Fatalf("Invalid progress: got %d/%d - want 5/5", status.Done, status.Total)
+	}
+
+	// Read all entries with a fresh VaultFS to ensure that
+	// nothing depends on cached state.
+	info, err := NewVaultFS(dir, rootKey, NoCompression).GetEnclaveInfo(ctx, Enclave)
+	if err != nil {
+		t.Fatalf("Failed to get enclave info: %v", err)
+	}
+	if info.Previous != nil {
+		t.Fatal("Previous root keys have not been discarded")
+	}
+	if info.KeyStoreKey.Equal(oldInfo.KeyStoreKey) {
+		t.Fatal("Enclave root keys have not been rotated")
+	}
+
+	enclave, err = NewVaultFS(dir, rootKey, NoCompression).GetEnclave(ctx, Enclave)
+	if err != nil {
+		t.Fatalf("Failed to get enclave: %v", err)
+	}
+	k, err := enclave.GetKey(ctx, "my-key")
+	if err != nil {
+		t.Fatalf("Failed to read key: %v", err)
+	}
+	if !k.Equal(dataKey) {
+		t.Fatal("Key mismatch after re-encryption")
+	}
+	s, err := enclave.GetSecret(ctx, "my-secret")
+	if err != nil {
+		t.Fatalf("Failed to read secret: %v", err)
+	}
+	if !bytes.Equal(s.Bytes(), []byte("Hello World")) {
+		t.Fatal("Secret mismatch after re-encryption")
+	}
+	if _, err = enclave.GetPolicy(ctx, "my-policy"); err != nil {
+		t.Fatalf("Failed to read policy: %v", err)
+	}
+	if _, err = enclave.GetIdentity(ctx, User); err != nil {
+		t.Fatalf("Failed to read identity: %v", err)
+	}
+	if admin, err := enclave.Admin(ctx); err != nil || admin != Admin {
+		t.Fatalf("Failed to read admin: got '%s' - want '%s': %v", admin, Admin, err)
+	}
+
+	ciphertext, err := os.ReadFile(filepath.Join(dir, "enclave", Enclave, "key", "my-key"))
+	if err != nil {
+		t.Fatalf("Failed to read key file: %v", err)
+	}
+	if _, err = oldInfo.KeyStoreKey.Unwrap(ciphertext, []byte("my-key")); err == nil {
+		t.Fatal("Key is still encrypted with the previous root key")
+	}
+}
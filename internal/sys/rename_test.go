@@ -0,0 +1,101 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package sys
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/key"
+)
+
+func TestRenameEnclave(t *testing.T) {
+	const Admin = kes.Identity("3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22")
+	ctx := context.Background()
+
+	rootKey, err := key.Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to generate root key: %v", err)
+	}
+	rootDir := t.TempDir()
+	vault := NewVault(NewVaultFS(rootDir, rootKey, NoCompression))
+	for _, name := range []string{DefaultEnclaveName, "tenant-1", "tenant-2"} {
+		if _, err = vault.CreateEnclave(ctx, name, Admin); err != nil {
+			t.Fatalf("Failed to create enclave '%s': %v", name, err)
+		}
+	}
+	if _, err = vault.UpdateEnclave(ctx, "tenant-1", func(info *EnclaveInfo) error {
+		info.Description = "Tenant 1"
+		info.Labels = map[string]string{"tier": "gold"}
+		return nil
+	}); err != nil {
+		t.Fatalf("Failed to update enclave: %v", err)
+	}
+	enclave, err := vault.GetEnclave(ctx, "tenant-1")
+	if err != nil {
+		t.Fatalf("Failed to get enclave: %v", err)
+	}
+	dataKey, err := key.Random(kes.AES256_GCM_SHA256, Admin)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	if err = enclave.CreateKey(ctx, "my-key", dataKey); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+
+	if err = vault.RenameEnclave(ctx, DefaultEnclaveName, "tenant-3"); err == nil {
+		t.Fatal("Renamed default enclave")
+	}
+	if err = vault.RenameEnclave(ctx, "tenant-1", "tenant-2"); !errors.Is(err, kes.ErrEnclaveExists) {
+		t.Fatalf("Renamed enclave to existing enclave: %v", err)
+	}
+	if err = vault.RenameEnclave(ctx, "tenant-1", "tenant-3"); err != nil {
+		t.Fatalf("Failed to rename enclave: %v", err)
+	}
+
+	if _, err = vault.GetEnclave(ctx, "tenant-1"); !errors.Is(err, kes.ErrEnclaveNotFound) {
+		t.Fatalf("Renamed enclave still exists under its previous name: %v", err)
+	}
+	info, err := vault.GetEnclaveInfo(ctx, "tenant-3")
+	if err != nil {
+		t.Fatalf("Failed to get enclave info: %v", err)
+	}
+	if info.Name != "tenant-3" {
+		t.Fatalf("Invalid enclave name: got '%s' - want 'tenant-3'", info.Name)
+	}
+	if info.Description != "Tenant 1" || info.Labels["tier"] != "gold" {
+		t.Fatalf("Enclave metadata has not been retained: got '%s' %v", info.Description, info.Labels)
+	}
+	if enclave, err = vault.GetEnclave(ctx, "tenant-3"); err != nil {
+		t.Fatalf("Failed to get renamed enclave: %v", err)
+	}
+	if admin, err := enclave.Admin(ctx); err != nil || admin != Admin {
+		t.Fatalf("Invalid enclave admin: got '%s' - want '%s': %v", admin, Admin, err)
+	}
+	k, err := enclave.GetKey(ctx, "my-key")
+	if err != nil {
+		t.Fatalf("Failed to get key of renamed enclave: %v", err)
+	}
+	if !k.Equal(dataKey) {
+		t.Fatal("Key of renamed enclave does not match")
+	}
+
+	// A rename file left behind by an interrupted
+	// rename must be discarded.
+	renameFile := filepath.Join(rootDir, "enclave", "tenant-2", ".enclave.rename")
+	if err = os.WriteFile(renameFile, []byte("interrupted"), 0o600); err != nil {
+		t.Fatalf("Failed to create rename file: %v", err)
+	}
+	if _, err = vault.GetEnclaveInfo(ctx, "tenant-2"); err != nil {
+		t.Fatalf("Failed to get enclave info after interrupted rename: %v", err)
+	}
+	if _, err = os.Stat(renameFile); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Rename file has not been discarded: %v", err)
+	}
+}
@@ -19,28 +19,36 @@ import (
 // reads/writes secrets from/to the given
 // directory path and en/decrypts them with
 // the given encryption key.
-func NewSecretFS(filename string, key key.Key) SecretFS {
+//
+// Entries are compressed with the given compression
+// algorithm before they get encrypted.
+func NewSecretFS(filename string, key key.Key, compression Compression) SecretFS {
 	return &secretFS{
-		rootDir: filename,
-		rootKey: key,
+		rootDir:     filename,
+		rootKey:     keyRing{Key: key},
+		compression: compression,
 	}
 }
 
 type secretFS struct {
-	rootDir string
-	rootKey key.Key
+	rootDir     string
+	rootKey     keyRing
+	compression Compression
 }
 
 func (fs *secretFS) CreateSecret(_ context.Context, name string, secret secret.Secret) error {
-	if err := valid(name); err != nil {
+	if err := validPath(name); err != nil {
 		return err
 	}
 	plaintext, err := secret.MarshalBinary()
 	if err != nil {
 		return err
 	}
+	if plaintext, err = compress(fs.compression, plaintext); err != nil {
+		return err
+	}
 
-	filename := filepath.Join(fs.rootDir, name)
+	filename := filepath.Join(fs.rootDir, fileName(name))
 	err = createFile(filename, fs.rootKey, plaintext, []byte(name))
 	if errors.Is(err, os.ErrExist) {
 		return kes.ErrSecretExists
@@ -52,11 +60,11 @@ func (fs *secretFS) CreateSecret(_ context.Context, name string, secret secret.S
 }
 
 func (fs *secretFS) GetSecret(_ context.Context, name string) (sec secret.Secret, err error) {
-	if err = valid(name); err != nil {
+	if err = validPath(name); err != nil {
 		return sec, err
 	}
 
-	filename := filepath.Join(fs.rootDir, name)
+	filename := filepath.Join(fs.rootDir, fileName(name))
 	plaintext, err := readFile(filename, fs.rootKey, secret.MaxSize, []byte(name))
 	if errors.Is(err, os.ErrNotExist) {
 		return sec, kes.ErrSecretNotFound
@@ -64,6 +72,9 @@ func (fs *secretFS) GetSecret(_ context.Context, name string) (sec secret.Secret
 	if err != nil {
 		return sec, err
 	}
+	if plaintext, err = decompress(plaintext); err != nil {
+		return sec, err
+	}
 
 	if err = sec.UnmarshalBinary(plaintext); err != nil {
 		return sec, err
@@ -72,11 +83,11 @@ func (fs *secretFS) GetSecret(_ context.Context, name string) (sec secret.Secret
 }
 
 func (fs *secretFS) DeleteSecret(_ context.Context, name string) error {
-	if err := valid(name); err != nil {
+	if err := validPath(name); err != nil {
 		return err
 	}
 
-	err := os.Remove(filepath.Join(fs.rootDir, name))
+	err := os.Remove(filepath.Join(fs.rootDir, fileName(name)))
 	if errors.Is(err, os.ErrNotExist) {
 		return kes.ErrSecretNotFound
 	}
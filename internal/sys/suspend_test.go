@@ -0,0 +1,83 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package sys
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/key"
+)
+
+func TestSuspendEnclave(t *testing.T) {
+	const (
+		Enclave = "tenant-1"
+		Admin   = kes.Identity("3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22")
+	)
+	ctx := context.Background()
+
+	rootKey, err := key.Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to generate root key: %v", err)
+	}
+	vault := NewVault(NewVaultFS(t.TempDir(), rootKey, NoCompression))
+	for _, name := range []string{DefaultEnclaveName, Enclave} {
+		if _, err = vault.CreateEnclave(ctx, name, Admin); err != nil {
+			t.Fatalf("Failed to create enclave '%s': %v", name, err)
+		}
+	}
+	enclave, err := vault.GetEnclave(ctx, Enclave)
+	if err != nil {
+		t.Fatalf("Failed to get enclave: %v", err)
+	}
+	dataKey, err := key.Random(kes.AES256_GCM_SHA256, Admin)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	if err = enclave.CreateKey(ctx, "my-key", dataKey); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+
+	if err = vault.SuspendEnclave(ctx, DefaultEnclaveName); err == nil {
+		t.Fatal("Suspended default enclave")
+	}
+	if err = vault.SuspendEnclave(ctx, Enclave); err != nil {
+		t.Fatalf("Failed to suspend enclave: %v", err)
+	}
+	info, err := vault.GetEnclaveInfo(ctx, Enclave)
+	if err != nil {
+		t.Fatalf("Failed to get enclave info: %v", err)
+	}
+	if !info.Suspended || info.SuspendedAt.IsZero() {
+		t.Fatal("Enclave info does not indicate that the enclave is suspended")
+	}
+	if enclave, err = vault.GetEnclave(ctx, Enclave); err != nil {
+		t.Fatalf("Failed to get enclave: %v", err)
+	}
+	req := httptest.NewRequest("GET", "/v1/key/describe/my-key", nil)
+	if err = enclave.VerifyRequest(req); !errors.Is(err, ErrEnclaveSuspended) {
+		t.Fatalf("Suspended enclave accepted request: got '%v' - want '%v'", err, ErrEnclaveSuspended)
+	}
+
+	if err = vault.ResumeEnclave(ctx, Enclave); err != nil {
+		t.Fatalf("Failed to resume enclave: %v", err)
+	}
+	if enclave, err = vault.GetEnclave(ctx, Enclave); err != nil {
+		t.Fatalf("Failed to get enclave: %v", err)
+	}
+	if err = enclave.VerifyRequest(req); errors.Is(err, ErrEnclaveSuspended) {
+		t.Fatal("Resumed enclave is still suspended")
+	}
+	k, err := enclave.GetKey(ctx, "my-key")
+	if err != nil {
+		t.Fatalf("Failed to get key of resumed enclave: %v", err)
+	}
+	if !k.Equal(dataKey) {
+		t.Fatal("Key of resumed enclave does not match")
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package sys
+
+import (
+	"bytes"
+	"encoding/gob"
+	"net/http"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/key"
+)
+
+// ErrAuditHold is returned when an operation would destroy
+// keys that are still under audit hold.
+var ErrAuditHold = kes.NewError(http.StatusForbidden, "key is under audit hold")
+
+// DeletedKey is a key that has been deleted but is
+// retained in the key trash of an enclave until it
+// gets purged.
+type DeletedKey struct {
+	// Key is the deleted key.
+	Key key.Key
+
+	// DeletedAt is the point in time when the key
+	// got deleted.
+	DeletedAt time.Time
+}
+
+// PurgeAt returns the point in time when the deleted key
+// gets purged given the enclave's key retention.
+func (d *DeletedKey) PurgeAt(retention time.Duration) time.Time {
+	return d.DeletedAt.Add(retention)
+}
+
+// MarshalBinary returns the DeletedKey's binary representation.
+func (d DeletedKey) MarshalBinary() ([]byte, error) {
+	type GOB struct {
+		Key       key.Key
+		DeletedAt time.Time
+	}
+
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(GOB(d)); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// UnmarshalBinary unmarshals the DeletedKey's binary representation.
+func (d *DeletedKey) UnmarshalBinary(b []byte) error {
+	type GOB struct {
+		Key       key.Key
+		DeletedAt time.Time
+	}
+
+	var value GOB
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&value); err != nil {
+		return err
+	}
+	d.Key = value.Key
+	d.DeletedAt = value.DeletedAt
+	return nil
+}
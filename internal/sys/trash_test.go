@@ -0,0 +1,193 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package sys
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/key"
+)
+
+func TestKeyTrash(t *testing.T) {
+	const (
+		Enclave = "tenant-1"
+		Admin   = kes.Identity("3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22")
+	)
+	ctx := context.Background()
+
+	rootKey, err := key.Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to generate root key: %v", err)
+	}
+	vault := NewVault(NewVaultFS(t.TempDir(), rootKey, NoCompression))
+	if _, err = vault.CreateEnclave(ctx, Enclave, Admin); err != nil {
+		t.Fatalf("Failed to create enclave: %v", err)
+	}
+	if _, err = vault.UpdateEnclave(ctx, Enclave, func(info *EnclaveInfo) error {
+		info.KeyRetention = time.Hour
+		return nil
+	}); err != nil {
+		t.Fatalf("Failed to update enclave: %v", err)
+	}
+	enclave, err := vault.GetEnclave(ctx, Enclave)
+	if err != nil {
+		t.Fatalf("Failed to get enclave: %v", err)
+	}
+	if enclave.KeyRetention() != time.Hour {
+		t.Fatalf("Key retention mismatch: got '%v' - want '%v'", enclave.KeyRetention(), time.Hour)
+	}
+
+	dataKey, err := key.Random(kes.AES256_GCM_SHA256, Admin)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	if err = enclave.CreateKey(ctx, "my-key", dataKey); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if err = enclave.DeleteKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to delete key: %v", err)
+	}
+	if _, err = enclave.GetKey(ctx, "my-key"); !errors.Is(err, kes.ErrKeyNotFound) {
+		t.Fatalf("Deleted key is still accessible: %v", err)
+	}
+
+	iter, err := enclave.ListKeys(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	if name, ok := iter.Next(); ok {
+		t.Fatalf("Listed deleted key or key trash: '%s'", name)
+	}
+	iter.Close()
+
+	deleted, err := enclave.GetDeletedKey(ctx, "my-key")
+	if err != nil {
+		t.Fatalf("Failed to get deleted key: %v", err)
+	}
+	if !deleted.Key.Equal(dataKey) {
+		t.Fatal("Deleted key does not match original key")
+	}
+
+	// Restoring a deleted key must fail if a key with the
+	// same name has been created in the meantime.
+	if err = enclave.CreateKey(ctx, "my-key", dataKey); err != nil {
+		t.Fatalf("Failed to re-create key: %v", err)
+	}
+	if err = enclave.RestoreKey(ctx, "my-key"); !errors.Is(err, kes.ErrKeyExists) {
+		t.Fatalf("Restoring key: got err '%v' - want '%v'", err, kes.ErrKeyExists)
+	}
+	if err = enclave.keys.DeleteKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to delete key: %v", err)
+	}
+
+	if err = enclave.RestoreKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to restore key: %v", err)
+	}
+	if _, err = enclave.GetKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to get restored key: %v", err)
+	}
+	if _, err = enclave.GetDeletedKey(ctx, "my-key"); !errors.Is(err, kes.ErrKeyNotFound) {
+		t.Fatalf("Restored key is still in the key trash: %v", err)
+	}
+
+	// Once the retention is reduced, deleted keys get purged.
+	if err = enclave.DeleteKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to delete key: %v", err)
+	}
+	if _, err = vault.UpdateEnclave(ctx, Enclave, func(info *EnclaveInfo) error {
+		info.KeyRetention = 0
+		return nil
+	}); err != nil {
+		t.Fatalf("Failed to update enclave: %v", err)
+	}
+	if enclave, err = vault.GetEnclave(ctx, Enclave); err != nil {
+		t.Fatalf("Failed to get enclave: %v", err)
+	}
+	if err = enclave.RestoreKey(ctx, "my-key"); !errors.Is(err, kes.ErrKeyNotFound) {
+		t.Fatalf("Restoring expired key: got err '%v' - want '%v'", err, kes.ErrKeyNotFound)
+	}
+}
+
+func TestAuditHold(t *testing.T) {
+	const (
+		Enclave = "tenant-1"
+		Admin   = kes.Identity("3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22")
+	)
+	ctx := context.Background()
+
+	rootKey, err := key.Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to generate root key: %v", err)
+	}
+	vault := NewVault(NewVaultFS(t.TempDir(), rootKey, NoCompression))
+	if _, err = vault.CreateEnclave(ctx, Enclave, Admin); err != nil {
+		t.Fatalf("Failed to create enclave: %v", err)
+	}
+	if _, err = vault.UpdateEnclave(ctx, Enclave, func(info *EnclaveInfo) error {
+		info.AuditHold = true
+		return nil
+	}); err == nil {
+		t.Fatal("Enabled audit hold without key retention")
+	}
+	if _, err = vault.UpdateEnclave(ctx, Enclave, func(info *EnclaveInfo) error {
+		info.KeyRetention = time.Hour
+		info.AuditHold = true
+		return nil
+	}); err != nil {
+		t.Fatalf("Failed to update enclave: %v", err)
+	}
+
+	enclave, err := vault.GetEnclave(ctx, Enclave)
+	if err != nil {
+		t.Fatalf("Failed to get enclave: %v", err)
+	}
+	dataKey, err := key.Random(kes.AES256_GCM_SHA256, Admin)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	if err = enclave.CreateKey(ctx, "my-key", dataKey); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if err = enclave.DeleteKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to delete key: %v", err)
+	}
+	if err = enclave.PurgeKey(ctx, "my-key"); !errors.Is(err, ErrAuditHold) {
+		t.Fatalf("Purging key: got err '%v' - want '%v'", err, ErrAuditHold)
+	}
+	if err = vault.DeleteEnclave(ctx, Enclave); !errors.Is(err, ErrAuditHold) {
+		t.Fatalf("Deleting enclave: got err '%v' - want '%v'", err, ErrAuditHold)
+	}
+
+	for _, update := range []func(*EnclaveInfo){
+		func(info *EnclaveInfo) { info.AuditHold = false },
+		func(info *EnclaveInfo) { info.KeyRetention = time.Minute },
+		func(info *EnclaveInfo) { info.KeyRetention = 0 },
+	} {
+		update := update
+		if _, err = vault.UpdateEnclave(ctx, Enclave, func(info *EnclaveInfo) error {
+			update(info)
+			return nil
+		}); !errors.Is(err, ErrAuditHold) {
+			t.Fatalf("Updating enclave: got err '%v' - want '%v'", err, ErrAuditHold)
+		}
+	}
+	if _, err = vault.UpdateEnclave(ctx, Enclave, func(info *EnclaveInfo) error {
+		info.KeyRetention = 2 * time.Hour
+		return nil
+	}); err != nil {
+		t.Fatalf("Failed to extend key retention: %v", err)
+	}
+
+	if enclave, err = vault.GetEnclave(ctx, Enclave); err != nil {
+		t.Fatalf("Failed to get enclave: %v", err)
+	}
+	if err = enclave.RestoreKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to restore key: %v", err)
+	}
+}
@@ -10,7 +10,10 @@ import (
 	"errors"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"aead.dev/mem"
@@ -24,16 +27,21 @@ import (
 // reads/writes enclaves from/to the given
 // directory path and en/decrypts them
 // with the given encryption key.
-func NewVaultFS(filename string, key key.Key) VaultFS {
+//
+// Entries are compressed with the given compression
+// algorithm before they get encrypted.
+func NewVaultFS(filename string, key key.Key, compression Compression) VaultFS {
 	return &vaultFS{
-		rootDir: filename,
-		rootKey: key,
+		rootDir:     filename,
+		rootKey:     key,
+		compression: compression,
 	}
 }
 
 type vaultFS struct {
-	rootDir string
-	rootKey key.Key
+	rootDir     string
+	rootKey     key.Key
+	compression Compression
 }
 
 func (v *vaultFS) Seal(context.Context) error {
@@ -125,7 +133,7 @@ func (v *vaultFS) CreateEnclave(ctx context.Context, name string, admin kes.Iden
 		return EnclaveInfo{}, err
 	}
 
-	identityFS := NewIdentityFS(filepath.Join(enclavePath, "identity"), identityKey)
+	identityFS := NewIdentityFS(filepath.Join(enclavePath, "identity"), identityKey, v.compression)
 	if err = identityFS.SetAdmin(ctx, admin); err != nil {
 		return EnclaveInfo{}, err
 	}
@@ -143,6 +151,9 @@ func (v *vaultFS) CreateEnclave(ctx context.Context, name string, admin kes.Iden
 	if err != nil {
 		return EnclaveInfo{}, err
 	}
+	if plaintext, err = compress(v.compression, plaintext); err != nil {
+		return EnclaveInfo{}, err
+	}
 	ciphertext, err := v.rootKey.Wrap(plaintext, []byte(name))
 	if err != nil {
 		return EnclaveInfo{}, err
@@ -153,40 +164,230 @@ func (v *vaultFS) CreateEnclave(ctx context.Context, name string, admin kes.Iden
 	return info, nil
 }
 
-func (v *vaultFS) GetEnclave(_ context.Context, name string) (*Enclave, error) {
-	if err := valid(name); err != nil {
-		return nil, err
+func (v *vaultFS) RotateEnclaveKeys(ctx context.Context, name string) (EnclaveInfo, error) {
+	info, err := v.GetEnclaveInfo(ctx, name)
+	if err != nil {
+		return EnclaveInfo{}, err
+	}
+	if info.Previous != nil { // Resume the ongoing re-encryption
+		return info, nil
 	}
 
-	enclavePath := filepath.Join(v.rootDir, "enclave", name)
-	file, err := os.Open(filepath.Join(enclavePath, ".enclave"))
-	if errors.Is(err, os.ErrNotExist) {
-		return nil, kes.ErrEnclaveNotFound
+	algorithm := info.KeyStoreKey.Algorithm()
+	if algorithm == kes.KeyAlgorithmUndefined {
+		algorithm = kes.AES256_GCM_SHA256
+	}
+	previous := info
+	info.Previous = &previous
+	if info.KeyStoreKey, err = key.Random(algorithm, v.rootKey.CreatedBy()); err != nil {
+		return EnclaveInfo{}, err
+	}
+	if info.SecretKey, err = key.Random(algorithm, v.rootKey.CreatedBy()); err != nil {
+		return EnclaveInfo{}, err
+	}
+	if info.PolicyKey, err = key.Random(algorithm, v.rootKey.CreatedBy()); err != nil {
+		return EnclaveInfo{}, err
 	}
+	if info.IdentityKey, err = key.Random(algorithm, v.rootKey.CreatedBy()); err != nil {
+		return EnclaveInfo{}, err
+	}
+	if err = v.writeEnclaveInfo(info); err != nil {
+		return EnclaveInfo{}, err
+	}
+	return info, nil
+}
+
+func (v *vaultFS) ReencryptEnclave(ctx context.Context, name string, locker sync.Locker, progress func(done, total int)) error {
+	locker.Lock()
+	info, err := v.GetEnclaveInfo(ctx, name)
+	locker.Unlock()
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer file.Close()
+	if info.Previous == nil {
+		return nil
+	}
+
+	// Each enclave store directory contains one file per entry.
+	// Client-specified entry names never contain a '.'. Hence,
+	// files containing a '.' are either temporary files or, in
+	// case of the key and identity store, the key trash and
+	// admin directory.
+	enclavePath := filepath.Join(v.rootDir, "enclave", name)
+	stores := []struct {
+		Dir     string
+		Prefix  string // Prefix of the associated data
+		TmpFile string
+		Key     keyRing
+	}{
+		{Dir: "key", TmpFile: ".key.tmp", Key: keyRing{info.KeyStoreKey, &info.Previous.KeyStoreKey}},
+		{Dir: filepath.Join("key", keyTrashDir), Prefix: keyTrashDir, TmpFile: ".deleted.tmp", Key: keyRing{info.KeyStoreKey, &info.Previous.KeyStoreKey}},
+		{Dir: "secret", TmpFile: ".secret.tmp", Key: keyRing{info.SecretKey, &info.Previous.SecretKey}},
+		{Dir: "policy", TmpFile: "policy.tmp", Key: keyRing{info.PolicyKey, &info.Previous.PolicyKey}},
+		{Dir: "identity", TmpFile: ".identity.tmp", Key: keyRing{info.IdentityKey, &info.Previous.IdentityKey}},
+		{Dir: filepath.Join("identity", ".admin"), Prefix: ".admin", TmpFile: ".admin.tmp", Key: keyRing{info.IdentityKey, &info.Previous.IdentityKey}},
+	}
+
+	entries := make([][]string, len(stores))
+	var total int
+	for i, store := range stores {
+		dir, err := os.Open(filepath.Join(enclavePath, store.Dir))
+		if errors.Is(err, os.ErrNotExist) && store.Prefix == keyTrashDir {
+			continue // The key trash is created on the first soft-delete
+		}
+		if err != nil {
+			return err
+		}
+		names, err := dir.Readdirnames(-1)
+		dir.Close()
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			if !strings.ContainsRune(name, '.') {
+				entries[i] = append(entries[i], name)
+			}
+		}
+		total += len(entries[i])
+	}
+
+	var done int
+	progress(done, total)
+	for i, store := range stores {
+		for _, entry := range entries[i] {
+			if err = ctx.Err(); err != nil {
+				return err
+			}
 
+			locker.Lock()
+			err = reencryptFile(
+				filepath.Join(enclavePath, store.Dir, entry),
+				filepath.Join(enclavePath, store.Dir, store.TmpFile),
+				store.Key,
+				[]byte(path.Join(store.Prefix, entryName(entry))),
+			)
+			locker.Unlock()
+			if err != nil {
+				return err
+			}
+
+			done++
+			progress(done, total)
+		}
+	}
+
+	locker.Lock()
+	defer locker.Unlock()
+
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	info.Previous = nil
+	return v.writeEnclaveInfo(info)
+}
+
+// reencryptFile re-encrypts the file with the current key
+// of the keyRing if it is still encrypted with the previous
+// key. It replaces the file atomically using the tmp file.
+//
+// Files that no longer exist are skipped.
+func reencryptFile(filename, tmpFile string, key keyRing, associatedData []byte) error {
 	const MaxSize = 1 * mem.MiB
+	file, err := os.Open(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
 	var ciphertext bytes.Buffer
-	if _, err = io.Copy(&ciphertext, mem.LimitReader(file, MaxSize)); err != nil {
-		return nil, err
+	_, err = io.Copy(&ciphertext, mem.LimitReader(file, MaxSize))
+	file.Close()
+	if err != nil {
+		return err
 	}
-	plaintext, err := v.rootKey.Unwrap(ciphertext.Bytes(), []byte(name))
+
+	if _, err = key.Key.Unwrap(ciphertext.Bytes(), associatedData); err == nil {
+		return nil // Already encrypted with the current key
+	}
+	plaintext, err := key.Unwrap(ciphertext.Bytes(), associatedData)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	var info EnclaveInfo
-	if err = info.UnmarshalBinary(plaintext); err != nil {
+	return replaceFile(filename, tmpFile, key, plaintext, associatedData)
+}
+
+// writeEnclaveInfo replaces the enclave's info file atomically.
+func (v *vaultFS) writeEnclaveInfo(info EnclaveInfo) error {
+	enclavePath := filepath.Join(v.rootDir, "enclave", info.Name)
+
+	plaintext, err := info.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	if plaintext, err = compress(v.compression, plaintext); err != nil {
+		return err
+	}
+	ciphertext, err := v.rootKey.Wrap(plaintext, []byte(info.Name))
+	if err != nil {
+		return err
+	}
+
+	tmpFile := filepath.Join(enclavePath, ".enclave.tmp")
+	if err = os.WriteFile(tmpFile, ciphertext, 0o600); err != nil {
+		os.Remove(tmpFile)
+		return err
+	}
+	if err = os.Rename(tmpFile, filepath.Join(enclavePath, ".enclave")); err != nil {
+		os.Remove(tmpFile)
+		return err
+	}
+	return nil
+}
+
+func (v *vaultFS) GetEnclave(ctx context.Context, name string) (*Enclave, error) {
+	info, err := v.GetEnclaveInfo(ctx, name)
+	if err != nil {
 		return nil, err
 	}
 
-	keyFS := NewKeyFS(filepath.Join(enclavePath, "key"), info.KeyStoreKey)
-	secretFS := NewSecretFS(filepath.Join(enclavePath, "secret"), info.SecretKey)
-	policyFS := NewPolicyFS(filepath.Join(enclavePath, "policy"), info.PolicyKey)
-	identityFS := NewIdentityFS(filepath.Join(enclavePath, "identity"), info.IdentityKey)
-	return NewEnclave(keyFS, secretFS, policyFS, identityFS), nil
+	enclavePath := filepath.Join(v.rootDir, "enclave", name)
+	keyFS := &keyFS{
+		rootDir:     filepath.Join(enclavePath, "key"),
+		rootKey:     keyRing{Key: info.KeyStoreKey},
+		compression: v.compression,
+	}
+	secretFS := &secretFS{
+		rootDir:     filepath.Join(enclavePath, "secret"),
+		rootKey:     keyRing{Key: info.SecretKey},
+		compression: v.compression,
+	}
+	policyFS := &policyFS{
+		rootDir:     filepath.Join(enclavePath, "policy"),
+		rootKey:     keyRing{Key: info.PolicyKey},
+		compression: v.compression,
+	}
+	identityFS := &identityFS{
+		rootDir:     filepath.Join(enclavePath, "identity"),
+		rootKey:     keyRing{Key: info.IdentityKey},
+		compression: v.compression,
+	}
+	if prev := info.Previous; prev != nil {
+		keyFS.rootKey.previous = &prev.KeyStoreKey
+		secretFS.rootKey.previous = &prev.SecretKey
+		policyFS.rootKey.previous = &prev.PolicyKey
+		identityFS.rootKey.previous = &prev.IdentityKey
+	}
+	enclave := NewEnclave(keyFS, secretFS, policyFS, identityFS)
+	enclave.keyRetention = info.KeyRetention
+	enclave.auditHold = info.AuditHold
+	enclave.maxKeys = info.MaxKeys
+	enclave.maxIdentities = info.MaxIdentities
+	enclave.requests = newRequestLimit(info.RequestRate)
+	enclave.defaultPolicy = info.DefaultPolicy
+	enclave.crypto = info.CryptoPolicy
+	enclave.suspended = info.Suspended
+	return enclave, nil
 }
 
 func (v *vaultFS) GetEnclaveInfo(_ context.Context, name string) (EnclaveInfo, error) {
@@ -195,6 +396,9 @@ func (v *vaultFS) GetEnclaveInfo(_ context.Context, name string) (EnclaveInfo, e
 	}
 
 	enclavePath := filepath.Join(v.rootDir, "enclave", name)
+	if err := v.completeRename(name); err != nil {
+		return EnclaveInfo{}, err
+	}
 	file, err := os.Open(filepath.Join(enclavePath, ".enclave"))
 	if errors.Is(err, os.ErrNotExist) {
 		return EnclaveInfo{}, kes.ErrEnclaveNotFound
@@ -213,6 +417,9 @@ func (v *vaultFS) GetEnclaveInfo(_ context.Context, name string) (EnclaveInfo, e
 	if err != nil {
 		return EnclaveInfo{}, err
 	}
+	if plaintext, err = decompress(plaintext); err != nil {
+		return EnclaveInfo{}, err
+	}
 	var info EnclaveInfo
 	if err = info.UnmarshalBinary(plaintext); err != nil {
 		return EnclaveInfo{}, err
@@ -220,6 +427,92 @@ func (v *vaultFS) GetEnclaveInfo(_ context.Context, name string) (EnclaveInfo, e
 	return info, nil
 }
 
+func (v *vaultFS) UpdateEnclave(ctx context.Context, name string, update func(*EnclaveInfo) error) (EnclaveInfo, error) {
+	info, err := v.GetEnclaveInfo(ctx, name)
+	if err != nil {
+		return EnclaveInfo{}, err
+	}
+	if err = update(&info); err != nil {
+		return EnclaveInfo{}, err
+	}
+	if err = v.writeEnclaveInfo(info); err != nil {
+		return EnclaveInfo{}, err
+	}
+	return info, nil
+}
+
+func (v *vaultFS) RenameEnclave(ctx context.Context, name, newName string) error {
+	if err := valid(newName); err != nil {
+		return err
+	}
+	info, err := v.GetEnclaveInfo(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	newPath := filepath.Join(v.rootDir, "enclave", newName)
+	if _, err = os.Stat(newPath); err == nil {
+		return kes.ErrEnclaveExists
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	// The enclave info is bound to the enclave name. Hence,
+	// renaming an enclave writes the info for the new name
+	// to a separate file before renaming the enclave directory.
+	// An interrupted rename is completed, or discarded, the
+	// next time the enclave info is read. See: completeRename.
+	info.Name = newName
+	plaintext, err := info.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	if plaintext, err = compress(v.compression, plaintext); err != nil {
+		return err
+	}
+	ciphertext, err := v.rootKey.Wrap(plaintext, []byte(newName))
+	if err != nil {
+		return err
+	}
+
+	enclavePath := filepath.Join(v.rootDir, "enclave", name)
+	renameFile := filepath.Join(enclavePath, ".enclave.rename")
+	if err = os.WriteFile(renameFile, ciphertext, 0o600); err != nil {
+		os.Remove(renameFile)
+		return err
+	}
+	if err = os.Rename(enclavePath, newPath); err != nil {
+		os.Remove(renameFile)
+		return err
+	}
+	return os.Rename(filepath.Join(newPath, ".enclave.rename"), filepath.Join(newPath, ".enclave"))
+}
+
+// completeRename completes or discards an interrupted
+// rename of the enclave with the given name.
+//
+// If the enclave directory contains enclave info for its
+// current name, the directory has been renamed and the info
+// replaces the one of the previous name. Otherwise, the
+// directory has not been renamed and the info is discarded.
+func (v *vaultFS) completeRename(name string) error {
+	enclavePath := filepath.Join(v.rootDir, "enclave", name)
+	renameFile := filepath.Join(enclavePath, ".enclave.rename")
+
+	ciphertext, err := os.ReadFile(renameFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, err = v.rootKey.Unwrap(ciphertext, []byte(name)); err != nil {
+		return os.Remove(renameFile)
+	}
+	return os.Rename(renameFile, filepath.Join(enclavePath, ".enclave"))
+}
+
 func (v *vaultFS) DeleteEnclave(_ context.Context, name string) error {
 	if err := valid(name); err != nil {
 		return err
@@ -8,6 +8,7 @@ import (
 	"context"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/minio/kes-go"
 )
@@ -32,6 +33,9 @@ type Vault struct {
 	admin     kes.Identity
 	sealed    bool
 	enclaves  map[string]*Enclave
+
+	jobLock sync.Mutex
+	jobs    map[string]*reencryptJob
 }
 
 // Locker returns a sync.Locker that locks the Vault for writes.
@@ -49,6 +53,7 @@ func (v *Vault) Seal(ctx context.Context) error {
 	if err := v.fs.Seal(ctx); err != nil {
 		return err
 	}
+	v.cancelJobs()
 	v.admin = ""
 	v.enclaves = map[string]*Enclave{}
 	v.sealed = true
@@ -156,9 +161,112 @@ func (v *Vault) GetEnclaveInfo(ctx context.Context, name string) (EnclaveInfo, e
 	return v.fs.GetEnclaveInfo(ctx, name)
 }
 
-// DeleteEnclave deletes the enclave with the given name.
+// UpdateEnclave applies the update to the information about
+// the enclave with the given name and persists the result.
+// The update must not modify the enclave name or root keys.
+//
+// It returns ErrEnclaveNotFound if no such enclave exists and
+// ErrAuditHold if the update tries to disable the enclave's
+// audit hold or to reduce its key retention.
+func (v *Vault) UpdateEnclave(ctx context.Context, name string, update func(*EnclaveInfo) error) (EnclaveInfo, error) {
+	if name == "" {
+		name = DefaultEnclaveName
+	}
+
+	if v.sealed {
+		return EnclaveInfo{}, kes.ErrSealed
+	}
+	info, err := v.fs.UpdateEnclave(ctx, name, func(info *EnclaveInfo) error {
+		auditHold, retention := info.AuditHold, info.KeyRetention
+		if err := update(info); err != nil {
+			return err
+		}
+		if auditHold && (!info.AuditHold || info.KeyRetention < retention) {
+			return ErrAuditHold
+		}
+		if info.AuditHold && info.KeyRetention <= 0 {
+			return kes.NewError(http.StatusBadRequest, "audit hold requires a key retention")
+		}
+		return nil
+	})
+	if err != nil {
+		return EnclaveInfo{}, err
+	}
+	delete(v.enclaves, name)
+	return info, nil
+}
+
+// SuspendEnclave suspends the enclave with the given name.
+// A suspended enclave rejects all requests with
+// ErrEnclaveSuspended but retains its data until resumed.
+// Suspending a suspended enclave has no effect.
+//
+// It returns ErrEnclaveNotFound if no such enclave exists.
+// The default enclave cannot be suspended.
+func (v *Vault) SuspendEnclave(ctx context.Context, name string) error {
+	if name == "" || name == DefaultEnclaveName {
+		return kes.NewError(http.StatusBadRequest, "cannot suspend the default enclave")
+	}
+	_, err := v.UpdateEnclave(ctx, name, func(info *EnclaveInfo) error {
+		if !info.Suspended {
+			info.Suspended, info.SuspendedAt = true, time.Now().UTC()
+		}
+		return nil
+	})
+	return err
+}
+
+// ResumeEnclave resumes the suspended enclave with the given
+// name. Resuming an enclave that is not suspended has no
+// effect.
 //
 // It returns ErrEnclaveNotFound if no such enclave exists.
+func (v *Vault) ResumeEnclave(ctx context.Context, name string) error {
+	_, err := v.UpdateEnclave(ctx, name, func(info *EnclaveInfo) error {
+		info.Suspended, info.SuspendedAt = false, time.Time{}
+		return nil
+	})
+	return err
+}
+
+// RenameEnclave renames the enclave with the given name.
+//
+// It returns ErrEnclaveNotFound if no such enclave exists and
+// ErrEnclaveExists if an enclave with the new name exists.
+// The default enclave and enclaves that are, or have been
+// interrupted while, being re-encrypted cannot be renamed.
+func (v *Vault) RenameEnclave(ctx context.Context, name, newName string) error {
+	if name == "" {
+		name = DefaultEnclaveName
+	}
+
+	if v.sealed {
+		return kes.ErrSealed
+	}
+	if name == DefaultEnclaveName {
+		return kes.NewError(http.StatusBadRequest, "cannot rename the default enclave")
+	}
+	if newName == "" || newName == name {
+		return kes.NewError(http.StatusBadRequest, "invalid enclave name")
+	}
+	info, err := v.fs.GetEnclaveInfo(ctx, name)
+	if err != nil {
+		return err
+	}
+	if info.Previous != nil {
+		return kes.NewError(http.StatusConflict, "cannot rename enclave while re-encryption is in progress")
+	}
+
+	delete(v.enclaves, name)
+	delete(v.jobs, name)
+	return v.fs.RenameEnclave(ctx, name, newName)
+}
+
+// DeleteEnclave deletes the enclave with the given name.
+//
+// It returns ErrEnclaveNotFound if no such enclave exists and
+// ErrAuditHold if the enclave is under audit hold and still
+// contains keys.
 func (v *Vault) DeleteEnclave(ctx context.Context, name string) error {
 	if name == "" {
 		name = DefaultEnclaveName
@@ -167,6 +275,20 @@ func (v *Vault) DeleteEnclave(ctx context.Context, name string) error {
 	if v.sealed {
 		return kes.ErrSealed
 	}
+	enclave, err := v.GetEnclave(ctx, name)
+	if err != nil {
+		return err
+	}
+	if enclave.AuditHold() {
+		empty, err := enclave.isEmpty(ctx)
+		if err != nil {
+			return err
+		}
+		if !empty {
+			return ErrAuditHold
+		}
+	}
+	v.cancelJob(name)
 	delete(v.enclaves, name)
 	return v.fs.DeleteEnclave(ctx, name)
 }
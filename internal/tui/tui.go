@@ -0,0 +1,58 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+//go:build !minimal
+// +build !minimal
+
+// Package tui provides styles for rendering terminal output.
+//
+// By default, it is a thin wrapper around lipgloss. Binaries
+// built with the 'minimal' build tag use a plain-text
+// implementation that does not depend on lipgloss and never
+// emits terminal escape sequences.
+package tui
+
+import (
+	"os"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// Styles and colors provided by lipgloss.
+type (
+	Style         = lipgloss.Style
+	Color         = lipgloss.Color
+	AdaptiveColor = lipgloss.AdaptiveColor
+	TerminalColor = lipgloss.TerminalColor
+	Border        = lipgloss.Border
+	Position      = lipgloss.Position
+)
+
+// Center aligns text horizontally in the center.
+const Center = lipgloss.Center
+
+// NewStyle returns a new, empty Style.
+func NewStyle() Style { return lipgloss.NewStyle() }
+
+// RoundedBorder returns a border with rounded corners.
+func RoundedBorder() Border { return lipgloss.RoundedBorder() }
+
+// EnableColors enables colored output even if no
+// interactive terminal has been detected.
+func EnableColors() {
+	if lipgloss.ColorProfile() == termenv.Ascii {
+		lipgloss.SetColorProfile(termenv.ANSI256)
+	}
+}
+
+// DisableColors disables colored output.
+func DisableColors() { lipgloss.SetColorProfile(termenv.Ascii) }
+
+func init() {
+	// Respect the NO_COLOR convention. See: https://no-color.org
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		DisableColors()
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+//go:build minimal
+// +build minimal
+
+package tui
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// TerminalColor is a color that can be applied to a Style.
+// Minimal builds ignore all colors.
+type TerminalColor interface{ terminalColor() }
+
+// Color is a color specified as hex or ANSI value.
+type Color string
+
+func (Color) terminalColor() {}
+
+// AdaptiveColor is a color that depends on whether
+// the terminal has a light or dark background.
+type AdaptiveColor struct {
+	Light string
+	Dark  string
+}
+
+func (AdaptiveColor) terminalColor() {}
+
+// Border is a border around a Style. Minimal builds
+// do not render borders.
+type Border struct{}
+
+// RoundedBorder returns a border with rounded corners.
+func RoundedBorder() Border { return Border{} }
+
+// Position is a horizontal alignment.
+type Position float64
+
+// Center aligns text horizontally in the center.
+const Center Position = 0.5
+
+// Style renders plain text. It only applies width
+// constraints and ignores all colors and decorations.
+type Style struct {
+	width    int
+	maxWidth int
+	inline   bool
+}
+
+// NewStyle returns a new, empty Style.
+func NewStyle() Style { return Style{} }
+
+func (s Style) Bold(bool) Style                         { return s }
+func (s Style) Faint(bool) Style                        { return s }
+func (s Style) Italic(bool) Style                       { return s }
+func (s Style) Underline(bool) Style                    { return s }
+func (s Style) UnderlineSpaces(bool) Style              { return s }
+func (s Style) Foreground(TerminalColor) Style          { return s }
+func (s Style) Background(TerminalColor) Style          { return s }
+func (s Style) Border(Border, ...bool) Style            { return s }
+func (s Style) BorderForeground(...TerminalColor) Style { return s }
+func (s Style) Align(Position) Style                    { return s }
+func (s Style) Width(n int) Style                       { s.width = n; return s }
+func (s Style) MaxWidth(n int) Style                    { s.maxWidth = n; return s }
+func (s Style) Inline(inline bool) Style                { s.inline = inline; return s }
+func (s Style) Copy() Style                             { return s }
+
+// Render applies the Style to the given strings
+// and returns the result.
+func (s Style) Render(strs ...string) string {
+	str := strings.Join(strs, " ")
+	if s.inline {
+		str = strings.ReplaceAll(str, "\n", "")
+	}
+	if s.width <= 0 && s.maxWidth <= 0 {
+		return str
+	}
+
+	lines := strings.Split(str, "\n")
+	for i, line := range lines {
+		if n := utf8.RuneCountInString(line); n < s.width {
+			line += strings.Repeat(" ", s.width-n)
+		}
+		if s.maxWidth > 0 && utf8.RuneCountInString(line) > s.maxWidth {
+			line = string([]rune(line)[:s.maxWidth])
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// EnableColors does nothing since minimal builds
+// never produce colored output.
+func EnableColors() {}
+
+// DisableColors does nothing since minimal builds
+// never produce colored output.
+func DisableColors() {}
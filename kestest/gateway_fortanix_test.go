@@ -37,6 +37,7 @@ func TestGatewayFortanix(t *testing.T) {
 	t.Run("APIs", func(t *testing.T) { testAPIs(ctx, store, t) })
 	t.Run("CreateKey", func(t *testing.T) { testCreateKey(ctx, store, t) })
 	t.Run("ImportKey", func(t *testing.T) { testImportKey(ctx, store, t) })
+	t.Run("BulkKey", func(t *testing.T) { testBulkKey(ctx, store, t) })
 	t.Run("GenerateKey", func(t *testing.T) { testGenerateKey(ctx, store, t) })
 	t.Run("EncryptKey", func(t *testing.T) { testEncryptKey(ctx, store, t) })
 	t.Run("DecryptKey", func(t *testing.T) { testDecryptKey(ctx, store, t) })
@@ -44,4 +45,5 @@ func TestGatewayFortanix(t *testing.T) {
 	t.Run("DescribePolicy", func(t *testing.T) { testDescribePolicy(ctx, store, t) })
 	t.Run("GetPolicy", func(t *testing.T) { testGetPolicy(ctx, store, t) })
 	t.Run("SelfDescribe", func(t *testing.T) { testSelfDescribe(ctx, store, t) })
+	t.Run("Impersonate", func(t *testing.T) { testImpersonate(ctx, store, t) })
 }
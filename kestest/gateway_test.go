@@ -10,14 +10,18 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"sort"
 	"strconv"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/auth"
 	"github.com/minio/kes/kestest"
 	"github.com/minio/kes/kv"
 )
@@ -28,20 +32,41 @@ var gatewayAPIs = map[string]struct {
 	Timeout time.Duration
 }{
 	"/version":    {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
+	"/v1/version": {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
 	"/v1/ready":   {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
+	"/v1/health":  {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
+	"/healthz":    {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
+	"/readyz":     {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
 	"/v1/status":  {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
 	"/v1/metrics": {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
 	"/v1/api":     {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
 
 	"/v1/key/create/":       {Method: http.MethodPost, MaxBody: 0, Timeout: 15 * time.Second},
 	"/v1/key/import/":       {Method: http.MethodPost, MaxBody: 1 << 20, Timeout: 15 * time.Second},
+	"/v1/key/claim/":        {Method: http.MethodPost, MaxBody: 0, Timeout: 15 * time.Second},
 	"/v1/key/describe/":     {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
+	"/v1/key/expire/":       {Method: http.MethodPost, MaxBody: 1 << 10, Timeout: 15 * time.Second},
 	"/v1/key/list/":         {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
 	"/v1/key/delete/":       {Method: http.MethodDelete, MaxBody: 0, Timeout: 15 * time.Second},
+	"/v1/key/restore/":      {Method: http.MethodPost, MaxBody: 0, Timeout: 15 * time.Second},
+	"/v1/key/purge/":        {Method: http.MethodDelete, MaxBody: 0, Timeout: 15 * time.Second},
+	"/v1/key/deleted/":      {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
 	"/v1/key/generate/":     {Method: http.MethodPost, MaxBody: 1 << 20, Timeout: 15 * time.Second},
 	"/v1/key/encrypt/":      {Method: http.MethodPost, MaxBody: 1 << 20, Timeout: 15 * time.Second},
 	"/v1/key/decrypt/":      {Method: http.MethodPost, MaxBody: 1 << 20, Timeout: 15 * time.Second},
 	"/v1/key/bulk/decrypt/": {Method: http.MethodPost, MaxBody: 1 << 20, Timeout: 15 * time.Second},
+	"/v1/key/sign/":         {Method: http.MethodPost, MaxBody: 1 << 20, Timeout: 15 * time.Second},
+	"/v1/key/verify/":       {Method: http.MethodPost, MaxBody: 1 << 20, Timeout: 15 * time.Second},
+	"/v1/key/public/":       {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
+	"/v1/key/export/":       {Method: http.MethodPost, MaxBody: 64 << 10, Timeout: 15 * time.Second},
+	"/v1/key/hmac/":         {Method: http.MethodPost, MaxBody: 1 << 20, Timeout: 15 * time.Second},
+	"/v1/key/derive/":       {Method: http.MethodPost, MaxBody: 1 << 20, Timeout: 15 * time.Second},
+
+	"/v1/key/bulk/create/": {Method: http.MethodPost, MaxBody: 1 << 20, Timeout: time.Minute},
+	"/v1/key/bulk/delete/": {Method: http.MethodDelete, MaxBody: 1 << 20, Timeout: time.Minute},
+
+	"/v1/key/ceremony/begin/":      {Method: http.MethodPost, MaxBody: 1 << 10, Timeout: 15 * time.Second},
+	"/v1/key/ceremony/contribute/": {Method: http.MethodPost, MaxBody: 1 << 10, Timeout: 15 * time.Second},
 
 	"/v1/policy/describe/": {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
 	"/v1/policy/read/":     {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
@@ -53,6 +78,15 @@ var gatewayAPIs = map[string]struct {
 
 	"/v1/log/error": {Method: http.MethodGet, MaxBody: 0, Timeout: 0},
 	"/v1/log/audit": {Method: http.MethodGet, MaxBody: 0, Timeout: 0},
+	"/v1/log/stats": {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
+	"/v1/log/tls":   {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
+
+	"/v1/maintenance/read-only": {Method: http.MethodPost, MaxBody: 1 << 10, Timeout: 15 * time.Second},
+
+	"/v1/drill/start": {Method: http.MethodPost, MaxBody: 1 << 10, Timeout: 15 * time.Second},
+	"/v1/drill/stop":  {Method: http.MethodPost, MaxBody: 0, Timeout: 15 * time.Second},
+
+	"/v1/admin/reload": {Method: http.MethodPost, MaxBody: 0, Timeout: 30 * time.Second},
 }
 
 func testMetrics(ctx context.Context, store kv.Store[string, []byte], t *testing.T) {
@@ -123,6 +157,9 @@ var createKeyTests = []struct {
 		ShouldFail: true,
 		Err:        kes.ErrKeyExists,
 	},
+	{ // 2
+		Name: "payments/prod/db-key",
+	},
 }
 
 func testCreateKey(ctx context.Context, store kv.Store[string, []byte], t *testing.T) {
@@ -146,6 +183,82 @@ func testCreateKey(ctx context.Context, store kv.Store[string, []byte], t *testi
 	}
 }
 
+var bulkKeyTests = []struct {
+	Method  string
+	Path    string
+	Names   []string
+	Results []int
+}{
+	{ // 0
+		Method:  http.MethodPost,
+		Path:    "/v1/key/bulk/create/",
+		Names:   []string{"my-key", "my-key1", "my-key2"},
+		Results: []int{http.StatusOK, http.StatusOK, http.StatusOK},
+	},
+	{ // 1
+		Method:  http.MethodPost,
+		Path:    "/v1/key/bulk/create/",
+		Names:   []string{"my-key", "my-key3", "my.key"},
+		Results: []int{kes.ErrKeyExists.Status(), http.StatusOK, http.StatusBadRequest},
+	},
+	{ // 2
+		Method:  http.MethodDelete,
+		Path:    "/v1/key/bulk/delete/",
+		Names:   []string{"my-key", "my-key1", "my-key2", "my-key3"},
+		Results: []int{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusOK},
+	},
+}
+
+func testBulkKey(ctx context.Context, store kv.Store[string, []byte], t *testing.T) {
+	server := kestest.NewGateway(store)
+	defer server.Close()
+	client := server.Client()
+
+	defer clean(ctx, client, t)
+
+	type Response struct {
+		Name    string `json:"name"`
+		Status  int    `json:"status"`
+		Message string `json:"message"`
+	}
+	for i, test := range bulkKeyTests {
+		body, err := json.Marshal(test.Names)
+		if err != nil {
+			t.Fatalf("Test %d: failed to encode request: %v", i, err)
+		}
+		req, err := http.NewRequestWithContext(ctx, test.Method, client.Endpoints[0]+test.Path, bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("Test %d: failed to create request: %v", i, err)
+		}
+		resp, err := client.HTTPClient.Do(req)
+		if err != nil {
+			t.Fatalf("Test %d: failed to send request: %v", i, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			t.Fatalf("Test %d: status mismatch: got '%d' - want '%d'", i, resp.StatusCode, http.StatusOK)
+		}
+
+		var responses []Response
+		err = json.NewDecoder(resp.Body).Decode(&responses)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("Test %d: failed to decode response: %v", i, err)
+		}
+		if len(responses) != len(test.Results) {
+			t.Fatalf("Test %d: got %d results - want %d", i, len(responses), len(test.Results))
+		}
+		for j, response := range responses {
+			if response.Name != test.Names[j] {
+				t.Fatalf("Test %d: key %d: name mismatch: got '%s' - want '%s'", i, j, response.Name, test.Names[j])
+			}
+			if response.Status != test.Results[j] {
+				t.Fatalf("Test %d: key %d: status mismatch: got '%d' - want '%d': %s", i, j, response.Status, test.Results[j], response.Message)
+			}
+		}
+	}
+}
+
 var importKeyTests = []struct {
 	Name       string
 	Key        []byte
@@ -238,6 +351,9 @@ func testGenerateKey(ctx context.Context, store kv.Store[string, []byte], t *tes
 			if !bytes.Equal(dek.Plaintext, plaintext) {
 				t.Fatalf("Test %d: decryption failed: got %x - want %x", i, plaintext, dek.Plaintext)
 			}
+			if _, err = client.Decrypt(ctx, KeyName, dek.Ciphertext, append(test.Context, 0)); err != kes.ErrDecrypt {
+				t.Fatalf("Test %d: decrypting with different context: got err '%v' - want '%v'", i, err, kes.ErrDecrypt)
+			}
 		}
 	}
 }
@@ -287,6 +403,9 @@ func testEncryptKey(ctx context.Context, store kv.Store[string, []byte], t *test
 			if !bytes.Equal(test.Plaintext, plaintext) {
 				t.Fatalf("Test %d: decryption failed: got %x - want %x", i, plaintext, test.Plaintext)
 			}
+			if _, err = client.Decrypt(ctx, KeyName, ciphertext, append(test.Context, 0)); err != kes.ErrDecrypt {
+				t.Fatalf("Test %d: decrypting with different context: got err '%v' - want '%v'", i, err, kes.ErrDecrypt)
+			}
 		}
 	}
 }
@@ -596,6 +715,215 @@ func testSelfDescribe(ctx context.Context, store kv.Store[string, []byte], t *te
 	}
 }
 
+func testImpersonate(ctx context.Context, store kv.Store[string, []byte], t *testing.T) {
+	server := kestest.NewGateway(store)
+	defer server.Close()
+
+	cert := server.IssueClientCertificate("impersonate test")
+	identity := kestest.Identify(&cert)
+	server.Policy().Allow("impersonate-test", "/v1/identity/self/describe")
+	server.Policy().Assign("impersonate-test", identity)
+
+	client := server.Client()
+	client.HTTPClient.Transport = &impersonateTransport{
+		Transport: client.HTTPClient.Transport,
+		Identity:  identity,
+	}
+	info, _, err := client.DescribeSelf(ctx)
+	if err != nil {
+		t.Fatalf("Failed to self-describe impersonated identity: %v", err)
+	}
+	if info.Identity != identity {
+		t.Fatalf("Identity mismatch: got '%v' - want '%v'", info.Identity, identity)
+	}
+	var kesErr kes.Error
+	if err = client.CreateKey(ctx, "impersonate-test"); !errors.As(err, &kesErr) || kesErr.Status() != http.StatusForbidden {
+		t.Fatalf("Impersonated identity should not be allowed to create keys: got '%v' - want status '%d'", err, http.StatusForbidden)
+	}
+	server.Policy().Allow("impersonate-test", "/v1/identity/self/describe", "/v1/key/create/*")
+	if err = client.CreateKey(ctx, "impersonate-test"); !errors.As(err, &kesErr) || kesErr.Status() != http.StatusForbidden {
+		t.Fatalf("Admin should not be allowed to create keys on behalf of other identities: got '%v' - want status '%d'", err, http.StatusForbidden)
+	}
+
+	client = kes.NewClientWithConfig(server.URL, &tls.Config{
+		RootCAs:      server.CAs(),
+		Certificates: []tls.Certificate{cert},
+	})
+	client.HTTPClient.Transport = &impersonateTransport{
+		Transport: client.HTTPClient.Transport,
+		Identity:  server.Policy().Admin(),
+	}
+	if _, _, err = client.DescribeSelf(ctx); err == nil {
+		t.Fatal("Identity should not be allowed to impersonate the admin")
+	}
+}
+
+// impersonateTransport sends requests on behalf of Identity.
+type impersonateTransport struct {
+	Transport http.RoundTripper
+	Identity  kes.Identity
+}
+
+func (t *impersonateTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set(auth.ImpersonateHeader, t.Identity.String())
+	return t.Transport.RoundTrip(req)
+}
+
+func testRecoverKey(ctx context.Context, store kv.Store[string, []byte], t *testing.T) {
+	server := kestest.NewGateway(store)
+	defer server.Close()
+
+	client := server.Client()
+	defer clean(ctx, client, t)
+
+	do := func(method, path string) int {
+		req, err := http.NewRequestWithContext(ctx, method, server.URL+path, nil)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		resp, err := client.HTTPClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	const KeyName = "recover-test"
+	if err := client.CreateKey(ctx, KeyName); err != nil {
+		t.Fatalf("Failed to create key '%s': %v", KeyName, err)
+	}
+	if err := client.DeleteKey(ctx, KeyName); err != nil {
+		t.Fatalf("Failed to delete key '%s': %v", KeyName, err)
+	}
+
+	if _, ok := store.(kv.Recoverer[string]); !ok {
+		if status := do(http.MethodPost, "/v1/key/restore/"+KeyName); status != http.StatusNotImplemented {
+			t.Fatalf("Restoring key '%s' should have failed: got status '%d' - want '%d'", KeyName, status, http.StatusNotImplemented)
+		}
+		return
+	}
+
+	err := client.CreateKey(ctx, KeyName)
+	if kerr, ok := err.(kes.Error); !ok || kerr.Status() != http.StatusConflict {
+		t.Fatalf("Creating deleted key '%s' should have failed: got '%v' - want status '%d'", KeyName, err, http.StatusConflict)
+	}
+	if status := do(http.MethodGet, "/v1/key/deleted/*"); status != http.StatusOK {
+		t.Fatalf("Failed to list deleted keys: got status '%d' - want '%d'", status, http.StatusOK)
+	}
+	if status := do(http.MethodPost, "/v1/key/restore/"+KeyName); status != http.StatusOK {
+		t.Fatalf("Failed to restore key '%s': got status '%d' - want '%d'", KeyName, status, http.StatusOK)
+	}
+	if _, err = client.DescribeKey(ctx, KeyName); err != nil {
+		t.Fatalf("Failed to describe restored key '%s': %v", KeyName, err)
+	}
+
+	if err = client.DeleteKey(ctx, KeyName); err != nil {
+		t.Fatalf("Failed to delete key '%s': %v", KeyName, err)
+	}
+	if status := do(http.MethodDelete, "/v1/key/purge/"+KeyName); status != http.StatusOK {
+		t.Fatalf("Failed to purge key '%s': got status '%d' - want '%d'", KeyName, status, http.StatusOK)
+	}
+	if status := do(http.MethodDelete, "/v1/key/purge/"+KeyName); status != http.StatusNotFound {
+		t.Fatalf("Purging key '%s' twice should have failed: got status '%d' - want '%d'", KeyName, status, http.StatusNotFound)
+	}
+	if err = client.CreateKey(ctx, KeyName); err != nil {
+		t.Fatalf("Failed to create purged key '%s': %v", KeyName, err)
+	}
+}
+
+// softDeleteStore wraps a kv.Store and deletes entries
+// softly, like Azure KeyVault or AWS SecretsManager.
+type softDeleteStore struct {
+	kv.Store[string, []byte]
+
+	lock    sync.Mutex
+	deleted map[string][]byte
+}
+
+var _ kv.Recoverer[string] = (*softDeleteStore)(nil)
+
+func (s *softDeleteStore) Create(ctx context.Context, name string, value []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.deleted[name]; ok {
+		return kv.ErrDeleted
+	}
+	return s.Store.Create(ctx, name, value)
+}
+
+func (s *softDeleteStore) Delete(ctx context.Context, name string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	value, err := s.Store.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	if err = s.Store.Delete(ctx, name); err != nil {
+		return err
+	}
+	if s.deleted == nil {
+		s.deleted = map[string][]byte{}
+	}
+	s.deleted[name] = value
+	return nil
+}
+
+func (s *softDeleteStore) ListDeleted(context.Context) (kv.Iter[kv.Deleted[string]], error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	names := make([]string, 0, len(s.deleted))
+	for name := range s.deleted {
+		names = append(names, name)
+	}
+	return &deletedIter{names: names}, nil
+}
+
+func (s *softDeleteStore) Recover(ctx context.Context, name string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	value, ok := s.deleted[name]
+	if !ok {
+		return kes.ErrKeyNotFound
+	}
+	if err := s.Store.Create(ctx, name, value); err != nil {
+		return err
+	}
+	delete(s.deleted, name)
+	return nil
+}
+
+func (s *softDeleteStore) Purge(_ context.Context, name string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.deleted[name]; !ok {
+		return kes.ErrKeyNotFound
+	}
+	delete(s.deleted, name)
+	return nil
+}
+
+type deletedIter struct {
+	names []string
+}
+
+func (i *deletedIter) Next() (kv.Deleted[string], bool) {
+	if len(i.names) == 0 {
+		return kv.Deleted[string]{}, false
+	}
+	name := i.names[0]
+	i.names = i.names[1:]
+	return kv.Deleted[string]{Key: name}, true
+}
+
+func (*deletedIter) Close() error { return nil }
+
 func testingContext(t *testing.T) (context.Context, context.CancelFunc) {
 	deadline, ok := t.Deadline()
 	if ok {
@@ -628,7 +956,7 @@ func mustDecodeB64(s string) []byte {
 }
 
 func clean(ctx context.Context, client *kes.Client, t *testing.T) {
-	iter, err := client.ListKeys(ctx, "*")
+	iter, err := client.ListKeys(ctx, "**")
 	if err != nil {
 		t.Fatalf("Cleanup: failed to list keys: %v", err)
 	}
@@ -0,0 +1,414 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kv
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CacheConfig is a structure containing Cache
+// configuration options.
+type CacheConfig struct {
+	// Size is the maximum number of entries kept
+	// in the Cache. Once the Cache is full, the
+	// least recently used entry gets evicted.
+	//
+	// The zero value means the number of entries
+	// is not limited.
+	Size int
+
+	// Expiry is the time period entries remain,
+	// at most, in the Cache.
+	//
+	// The zero value means entries never expire.
+	Expiry time.Duration
+
+	// NegativeExpiry is the time period the Cache
+	// remembers that an entry does not exist.
+	//
+	// The zero value means non-existing entries
+	// are not cached.
+	NegativeExpiry time.Duration
+
+	// NotExists reports whether an error returned
+	// by the underlying Store's Get method means
+	// that no such entry exists.
+	//
+	// If nil, only ErrNotExists is considered.
+	NotExists func(error) bool
+
+	// Stats, if not nil, counts the Cache hits and
+	// misses. Multiple Caches may share the same
+	// CacheStats.
+	Stats *CacheStats
+}
+
+// CacheStats counts the hits and misses of one or
+// multiple Caches. It is safe for concurrent use.
+type CacheStats struct {
+	hits, misses atomic.Uint64
+}
+
+// Hits returns the number of lookups served
+// from the cache.
+func (s *CacheStats) Hits() uint64 { return s.hits.Load() }
+
+// Misses returns the number of lookups that had
+// to be served by the underlying Store.
+func (s *CacheStats) Misses() uint64 { return s.misses.Load() }
+
+// NewCache returns a new Cache wrapping the store.
+func NewCache[K ~string, V any](store Store[K, V], config *CacheConfig) *Cache[K, V] {
+	c := &Cache[K, V]{
+		store:   store,
+		config:  *config,
+		entries: map[K]*list.Element{},
+	}
+	if c.config.NotExists == nil {
+		c.config.NotExists = func(err error) bool { return errors.Is(err, ErrNotExists) }
+	}
+	if c.config.Stats == nil {
+		c.config.Stats = &CacheStats{}
+	}
+	return c
+}
+
+// A Cache is a write-through Store that keeps recently
+// used entries in memory. Entries are evicted once they
+// expire or, if the Cache is full, in least recently
+// used order.
+//
+// A Cache also remembers, for a limited time, that an
+// entry does not exist such that repeated lookups of
+// non-existing entries don't reach the underlying Store.
+//
+// Entries modified by other clients of the underlying
+// Store remain cached until they expire unless the
+// Cache gets notified through its Watch method.
+type Cache[K ~string, V any] struct {
+	store  Store[K, V]
+	config CacheConfig
+
+	lock    sync.Mutex
+	lru     list.List // The front element is the most recently used one
+	entries map[K]*list.Element
+
+	// gen is incremented whenever an entry gets
+	// modified or evicted. It prevents caching values
+	// fetched before a concurrent modification.
+	gen uint64
+}
+
+var (
+	_ Store[string, []byte] = (*Cache[string, []byte])(nil)
+	_ Watcher[string]       = (*Cache[string, []byte])(nil)
+)
+
+// cacheEntry is a cached value or, if Err is not
+// nil, a cached lookup of a non-existing entry.
+type cacheEntry[K ~string, V any] struct {
+	Key       K
+	Value     V
+	Err       error
+	ExpiresAt time.Time // Zero, if the entry never expires
+}
+
+// Stats returns the CacheStats of the Cache.
+func (c *Cache[K, V]) Stats() *CacheStats { return c.config.Stats }
+
+// Recoverable returns a RecoverableCache if the underlying
+// Store is a Recoverer. Otherwise, it returns c.
+func (c *Cache[K, V]) Recoverable() Store[K, V] {
+	if _, ok := c.store.(Recoverer[K]); ok {
+		return &RecoverableCache[K, V]{Cache: c}
+	}
+	return c
+}
+
+// Status returns the current state of the underlying Store.
+func (c *Cache[K, V]) Status(ctx context.Context) (State, error) {
+	return c.store.Status(ctx)
+}
+
+// Create creates a new entry at the underlying Store
+// and adds it to the Cache.
+func (c *Cache[K, V]) Create(ctx context.Context, key K, value V) error {
+	if err := c.store.Create(ctx, key, value); err != nil {
+		c.evict(key)
+		return err
+	}
+	c.add(key, value)
+	return nil
+}
+
+// Set creates the entry at the underlying Store
+// and adds it to the Cache.
+func (c *Cache[K, V]) Set(ctx context.Context, key K, value V) error {
+	if err := c.store.Set(ctx, key, value); err != nil {
+		c.evict(key)
+		return err
+	}
+	c.add(key, value)
+	return nil
+}
+
+// Get returns the value associated with the given key.
+// It only fetches the value from the underlying Store
+// if it isn't in the Cache.
+func (c *Cache[K, V]) Get(ctx context.Context, key K) (V, error) {
+	if value, err, ok := c.lookup(key); ok {
+		c.config.Stats.hits.Add(1)
+		return value, err
+	}
+	c.config.Stats.misses.Add(1)
+
+	gen := c.generation()
+	value, err := c.store.Get(ctx, key)
+	c.fill(gen, key, value, err)
+	return value, err
+}
+
+// GetMany returns the values associated with the given
+// keys. It only fetches the values from the underlying
+// Store that aren't in the Cache.
+func (c *Cache[K, V]) GetMany(ctx context.Context, keys []K) ([]V, []error) {
+	var (
+		values = make([]V, len(keys))
+		errs   = make([]error, len(keys))
+
+		missing []K
+		indices []int
+	)
+	for i, key := range keys {
+		if value, err, ok := c.lookup(key); ok {
+			c.config.Stats.hits.Add(1)
+			values[i], errs[i] = value, err
+			continue
+		}
+		c.config.Stats.misses.Add(1)
+		missing = append(missing, key)
+		indices = append(indices, i)
+	}
+	if len(missing) == 0 {
+		return values, errs
+	}
+
+	gen := c.generation()
+	fetched, fetchErrs := c.store.GetMany(ctx, missing)
+	for j, i := range indices {
+		values[i], errs[i] = fetched[j], fetchErrs[j]
+		c.fill(gen, missing[j], fetched[j], fetchErrs[j])
+	}
+	return values, errs
+}
+
+// Update replaces the value of the given key at the
+// underlying Store if its current value is equal to
+// oldValue and updates the Cache.
+func (c *Cache[K, V]) Update(ctx context.Context, key K, oldValue, newValue V) error {
+	if err := c.store.Update(ctx, key, oldValue, newValue); err != nil {
+		c.evict(key)
+		return err
+	}
+	c.add(key, newValue)
+	return nil
+}
+
+// Delete deletes the entry from the underlying
+// Store and evicts it from the Cache.
+func (c *Cache[K, V]) Delete(ctx context.Context, key K) error {
+	err := c.store.Delete(ctx, key)
+	c.evict(key)
+	return err
+}
+
+// DeleteMany deletes the entries from the underlying
+// Store and evicts them from the Cache.
+func (c *Cache[K, V]) DeleteMany(ctx context.Context, keys []K) []error {
+	errs := c.store.DeleteMany(ctx, keys)
+	for _, key := range keys {
+		c.evict(key)
+	}
+	return errs
+}
+
+// List returns an Iter enumerating the keys of the
+// underlying Store that start with the given prefix.
+// Listings are not cached.
+func (c *Cache[K, V]) List(ctx context.Context, prefix K) (Iter[K], error) {
+	return c.store.List(ctx, prefix)
+}
+
+// Watch evicts entries from the Cache once the underlying
+// Store reports that they have changed and calls f, if not
+// nil, for every change event.
+//
+// Watch returns immediately if the underlying Store is not
+// a Watcher.
+func (c *Cache[K, V]) Watch(ctx context.Context, f func(K)) error {
+	w, ok := c.store.(Watcher[K])
+	if !ok {
+		return nil
+	}
+	return w.Watch(ctx, func(key K) {
+		var zero K
+		if key == zero {
+			c.evictAll()
+		} else {
+			c.evict(key)
+		}
+		if f != nil {
+			f(key)
+		}
+	})
+}
+
+// lookup returns the cached value, or the cached error,
+// for the given key and reports whether the key is in
+// the Cache.
+func (c *Cache[K, V]) lookup(key K) (V, error, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, nil, false
+	}
+	entry := elem.Value.(*cacheEntry[K, V])
+	if !entry.ExpiresAt.IsZero() && time.Now().After(entry.ExpiresAt) {
+		c.remove(elem)
+		var zero V
+		return zero, nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return entry.Value, entry.Err, true
+}
+
+// generation returns the current generation of the Cache.
+func (c *Cache[K, V]) generation() uint64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.gen
+}
+
+// fill adds the value, or the error if it indicates that no
+// such entry exists, fetched from the underlying Store to the
+// Cache. It does nothing if the Cache has been modified since
+// the given generation since the value may be stale already.
+func (c *Cache[K, V]) fill(gen uint64, key K, value V, err error) {
+	if err != nil && (c.config.NegativeExpiry <= 0 || !c.config.NotExists(err)) {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.gen != gen {
+		return
+	}
+	if err != nil {
+		var zero V
+		c.set(key, zero, err, c.config.NegativeExpiry)
+	} else {
+		c.set(key, value, nil, c.config.Expiry)
+	}
+}
+
+// add adds the key-value pair to the Cache.
+func (c *Cache[K, V]) add(key K, value V) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.gen++
+	c.set(key, value, nil, c.config.Expiry)
+}
+
+// evict removes the given key from the Cache.
+func (c *Cache[K, V]) evict(key K) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.gen++
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+}
+
+// evictAll removes all entries from the Cache.
+func (c *Cache[K, V]) evictAll() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.gen++
+	c.lru.Init()
+	c.entries = map[K]*list.Element{}
+}
+
+// set adds or replaces the Cache entry for the given key
+// and evicts the least recently used entries if the Cache
+// is full. The caller must hold the lock.
+func (c *Cache[K, V]) set(key K, value V, err error, expiry time.Duration) {
+	entry := &cacheEntry[K, V]{
+		Key:   key,
+		Value: value,
+		Err:   err,
+	}
+	if expiry > 0 {
+		entry.ExpiresAt = time.Now().Add(expiry)
+	}
+
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	for c.config.Size > 0 && c.lru.Len() > c.config.Size {
+		c.remove(c.lru.Back())
+	}
+}
+
+// remove removes the element from the Cache. The
+// caller must hold the lock.
+func (c *Cache[K, V]) remove(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*cacheEntry[K, V]).Key)
+}
+
+// RecoverableCache is a Cache wrapping a Store that
+// deletes entries softly.
+type RecoverableCache[K ~string, V any] struct {
+	*Cache[K, V]
+}
+
+var _ Recoverer[string] = (*RecoverableCache[string, []byte])(nil)
+
+// ListDeleted returns an Iter enumerating the deleted
+// entries of the underlying Store.
+func (c *RecoverableCache[K, V]) ListDeleted(ctx context.Context) (Iter[Deleted[K]], error) {
+	return c.store.(Recoverer[K]).ListDeleted(ctx)
+}
+
+// Recover recovers the deleted entry at the underlying
+// Store and evicts it from the Cache.
+func (c *RecoverableCache[K, V]) Recover(ctx context.Context, key K) error {
+	err := c.store.(Recoverer[K]).Recover(ctx, key)
+	c.evict(key)
+	return err
+}
+
+// Purge purges the deleted entry at the underlying
+// Store and evicts it from the Cache.
+func (c *RecoverableCache[K, V]) Purge(ctx context.Context, key K) error {
+	err := c.store.(Recoverer[K]).Purge(ctx, key)
+	c.evict(key)
+	return err
+}
@@ -0,0 +1,127 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kv_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/keystore/mem"
+	"github.com/minio/kes/kv"
+)
+
+func TestCacheGet(t *testing.T) {
+	ctx := context.Background()
+	store := &mem.Store{}
+	cache := kv.NewCache[string, []byte](store, &kv.CacheConfig{})
+
+	if err := cache.Create(ctx, "my-key", []byte("my-value")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		value, err := cache.Get(ctx, "my-key")
+		if err != nil {
+			t.Fatalf("Failed to get key: %v", err)
+		}
+		if !bytes.Equal(value, []byte("my-value")) {
+			t.Fatalf("Invalid value: got '%s' - want '%s'", value, "my-value")
+		}
+	}
+	if hits, misses := cache.Stats().Hits(), cache.Stats().Misses(); hits != 3 || misses != 0 {
+		t.Fatalf("Invalid cache stats: got %d hits and %d misses - want 3 hits and 0 misses", hits, misses)
+	}
+
+	// Modify the entry behind the cache's back. The cache
+	// must return the cached value until it gets notified.
+	if err := store.Update(ctx, "my-key", []byte("my-value"), []byte("new-value")); err != nil {
+		t.Fatalf("Failed to update key: %v", err)
+	}
+	if value, _ := cache.Get(ctx, "my-key"); !bytes.Equal(value, []byte("my-value")) {
+		t.Fatalf("Invalid value: got '%s' - want '%s'", value, "my-value")
+	}
+	if err := cache.Delete(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to delete key: %v", err)
+	}
+	if _, err := cache.Get(ctx, "my-key"); !errors.Is(err, kes.ErrKeyNotFound) {
+		t.Fatalf("Getting deleted key: got '%v' - want '%v'", err, kes.ErrKeyNotFound)
+	}
+}
+
+func TestCacheNegative(t *testing.T) {
+	ctx := context.Background()
+	store := &mem.Store{}
+	cache := kv.NewCache[string, []byte](store, &kv.CacheConfig{
+		NegativeExpiry: time.Hour,
+		NotExists:      func(err error) bool { return errors.Is(err, kes.ErrKeyNotFound) },
+	})
+
+	for i := 0; i < 2; i++ {
+		if _, err := cache.Get(ctx, "my-key"); !errors.Is(err, kes.ErrKeyNotFound) {
+			t.Fatalf("Getting non-existing key: got '%v' - want '%v'", err, kes.ErrKeyNotFound)
+		}
+	}
+	if hits, misses := cache.Stats().Hits(), cache.Stats().Misses(); hits != 1 || misses != 1 {
+		t.Fatalf("Invalid cache stats: got %d hits and %d misses - want 1 hit and 1 miss", hits, misses)
+	}
+
+	// Creating the entry through the cache must
+	// replace the negative cache entry.
+	if err := cache.Create(ctx, "my-key", []byte("my-value")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if _, err := cache.Get(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to get key: %v", err)
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	ctx := context.Background()
+	store := &mem.Store{}
+	cache := kv.NewCache[string, []byte](store, &kv.CacheConfig{
+		Size: 2,
+	})
+
+	for _, name := range []string{"my-key-1", "my-key-2", "my-key-3"} {
+		if err := cache.Create(ctx, name, []byte(name)); err != nil {
+			t.Fatalf("Failed to create key: %v", err)
+		}
+	}
+	for _, name := range []string{"my-key-3", "my-key-2", "my-key-1"} {
+		if _, err := cache.Get(ctx, name); err != nil {
+			t.Fatalf("Failed to get key: %v", err)
+		}
+	}
+	if hits, misses := cache.Stats().Hits(), cache.Stats().Misses(); hits != 2 || misses != 1 {
+		t.Fatalf("Invalid cache stats: got %d hits and %d misses - want 2 hits and 1 miss", hits, misses)
+	}
+}
+
+func TestCacheExpiry(t *testing.T) {
+	ctx := context.Background()
+	store := &mem.Store{}
+	cache := kv.NewCache[string, []byte](store, &kv.CacheConfig{
+		Expiry: 10 * time.Millisecond,
+	})
+
+	if err := cache.Create(ctx, "my-key", []byte("my-value")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if err := store.Update(ctx, "my-key", []byte("my-value"), []byte("new-value")); err != nil {
+		t.Fatalf("Failed to update key: %v", err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	value, err := cache.Get(ctx, "my-key")
+	if err != nil {
+		t.Fatalf("Failed to get key: %v", err)
+	}
+	if !bytes.Equal(value, []byte("new-value")) {
+		t.Fatalf("Expired entry has not been evicted: got '%s' - want '%s'", value, "new-value")
+	}
+}
@@ -20,13 +20,34 @@ var (
 	// ErrNotExists is returned by a Store when trying to
 	// access an entry but the key does not exist.
 	ErrNotExists = errors.New("kv: key does not exist")
+
+	// ErrDeleted is returned by a Store when trying to
+	// access or create an entry but the key has been
+	// deleted and is still recoverable. Such an entry
+	// has to be either recovered or purged before its
+	// key can be used again.
+	ErrDeleted = errors.New("kv: key is deleted but recoverable")
+
+	// ErrConflict is returned by a Store when trying to
+	// update an entry but its current value does not
+	// match the expected value. Usually, the entry has
+	// been modified concurrently.
+	ErrConflict = errors.New("kv: value has been modified concurrently")
+
+	// ErrNotSupported is returned by a Store when trying
+	// to update an entry but the Store cannot overwrite
+	// entries without deleting them first.
+	ErrNotSupported = errors.New("kv: operation not supported")
 )
 
 // Store stores key-value pairs.
 //
+// Keys are strings, or types based on strings,
+// such that a Store can list keys by prefix.
+//
 // Multiple goroutines may invoke methods
 // on a Store simultaneously.
-type Store[K comparable, V any] interface {
+type Store[K ~string, V any] interface {
 	// Status returns the current state of the
 	// Store or an error explaining why fetching
 	// status information failed.
@@ -65,6 +86,26 @@ type Store[K comparable, V any] interface {
 	// entry exists.
 	Get(context.Context, K) (V, error)
 
+	// GetMany returns the values associated with
+	// the given keys. The i-th value and the i-th
+	// error belong to the i-th key.
+	//
+	// The i-th error is ErrNotExists if no entry
+	// for the i-th key exists.
+	GetMany(context.Context, []K) ([]V, []error)
+
+	// Update replaces the value associated with
+	// the given key with the new value if and
+	// only if the current value is equal to the
+	// old value (compare-and-swap).
+	//
+	// It returns ErrNotExists if no such entry
+	// exists and ErrConflict if the current
+	// value is not equal to the old value.
+	// Stores that cannot replace entries in
+	// place return ErrNotSupported.
+	Update(ctx context.Context, key K, oldValue, newValue V) error
+
 	// Delete deletes the key and the associated
 	// value from the storage.
 	//
@@ -72,14 +113,104 @@ type Store[K comparable, V any] interface {
 	// entry exists.
 	Delete(context.Context, K) error
 
-	// List returns an Iter enumerating the stored
-	// entries.
-	List(context.Context) (Iter[K], error)
+	// DeleteMany deletes the given keys and the
+	// associated values from the storage. The
+	// i-th error belongs to the i-th key.
+	//
+	// The i-th error is ErrNotExists if no entry
+	// for the i-th key exists.
+	DeleteMany(context.Context, []K) []error
+
+	// List returns an Iter enumerating the keys
+	// of all stored entries that start with the
+	// given prefix. An empty prefix matches all
+	// keys.
+	//
+	// Stores should filter keys at the storage,
+	// if supported, such that listing a subset
+	// of keys does not require to fetch all keys.
+	List(ctx context.Context, prefix K) (Iter[K], error)
 }
 
 // State describes the state of a Store.
+//
+// A Store that is not reachable or not available
+// reports an Unreachable or Unavailable error from
+// its Status method instead. A State describes a
+// Store that is reachable but may still be unable
+// to serve all requests.
 type State struct {
 	// Latency is the connection latency
 	// to the Store.
 	Latency time.Duration
+
+	// Unauthenticated indicates that the Store
+	// is reachable but rejects requests since
+	// the client is not or no longer authenticated,
+	// e.g. because its credentials have expired.
+	Unauthenticated bool
+
+	// ReadOnly indicates that the Store serves
+	// read requests but rejects any modification.
+	ReadOnly bool
+}
+
+// A Recoverer is a Store that deletes entries softly.
+//
+// A softly deleted entry is not returned by the Store's
+// Get or List methods but remains recoverable until it
+// gets purged - either explicitly or once a retention
+// period, defined by the storage, has elapsed.
+type Recoverer[K comparable] interface {
+	// ListDeleted returns an Iter enumerating
+	// the deleted but recoverable entries.
+	ListDeleted(context.Context) (Iter[Deleted[K]], error)
+
+	// Recover recovers the deleted entry with
+	// the given key.
+	//
+	// It returns ErrNotExists if no such
+	// deleted entry exists.
+	Recover(context.Context, K) error
+
+	// Purge deletes the deleted entry with the
+	// given key permanently.
+	//
+	// It returns ErrNotExists if no such
+	// deleted entry exists.
+	Purge(context.Context, K) error
+}
+
+// Deleted describes a deleted but recoverable entry.
+type Deleted[K comparable] struct {
+	// Key is the key of the deleted entry.
+	Key K
+
+	// DeletedAt is the point in time when the
+	// entry has been deleted. The zero value
+	// indicates that it is not known.
+	DeletedAt time.Time
+
+	// PurgeAt is the point in time when the
+	// entry gets purged. The zero value
+	// indicates that it is not known.
+	PurgeAt time.Time
+}
+
+// A Watcher is a Store that notifies about entries
+// that have been created, changed or deleted - for
+// example by other clients sharing the same storage.
+type Watcher[K comparable] interface {
+	// Watch calls f with the key of every created,
+	// changed or deleted entry until the ctx is done.
+	//
+	// Watch calls f with the zero key if any entry
+	// may have changed, for example when change
+	// events have been lost while the watch was
+	// interrupted.
+	//
+	// Watch blocks until the ctx is done or watching
+	// fails permanently. It returns nil immediately
+	// if the Store cannot watch its entries.
+	Watch(ctx context.Context, f func(K)) error
 }